@@ -2,22 +2,61 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/martinshumberto/sync-manager/agent/internal/storage"
+	"github.com/martinshumberto/sync-manager/agent/internal/bandwidth"
+	agent_config "github.com/martinshumberto/sync-manager/agent/internal/config"
+	"github.com/martinshumberto/sync-manager/agent/internal/drives"
+	"github.com/martinshumberto/sync-manager/agent/internal/network"
+	"github.com/martinshumberto/sync-manager/agent/internal/peer"
+	"github.com/martinshumberto/sync-manager/agent/internal/power"
+	"github.com/martinshumberto/sync-manager/agent/internal/priority"
+	"github.com/martinshumberto/sync-manager/agent/internal/runs"
+	"github.com/martinshumberto/sync-manager/agent/internal/staging"
 	sync_manager "github.com/martinshumberto/sync-manager/agent/internal/sync"
 	"github.com/martinshumberto/sync-manager/agent/internal/uploader"
+	"github.com/martinshumberto/sync-manager/common/apiclient"
 	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/control"
+	"github.com/martinshumberto/sync-manager/common/excludes"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/identity"
+	"github.com/martinshumberto/sync-manager/common/instance"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/martinshumberto/sync-manager/common/telemetry"
+	"github.com/martinshumberto/sync-manager/common/tokens"
+	"github.com/martinshumberto/sync-manager/common/transport"
+	"github.com/martinshumberto/sync-manager/common/trigger"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// configCheckInterval is how often the agent looks for changes to its configuration file
+const configCheckInterval = 5 * time.Second
+
+// syncRequestInterval is how often the agent looks for syncs requested through the CLI
+const syncRequestInterval = time.Second
+
 // Version information (will be set during build)
 var (
 	Version   = "dev"
@@ -28,6 +67,9 @@ func main() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
+	// The agent has no user preferences: --lang or the environment picks its language
+	i18n.Set(i18n.Resolve(i18n.LangFromArgs(os.Args[1:]), ""))
+
 	log.Info().
 		Str("version", Version).
 		Str("build_time", BuildTime).
@@ -53,45 +95,206 @@ func main() {
 
 	setLogLevel(cfg.LogLevel)
 
+	// A second agent on the same configuration would upload everything twice
+	lock := acquireInstanceLock(cfg)
+	defer lock.Release()
+	go func() {
+		if err := lock.Run(ctx); err != nil {
+			log.Error().Err(err).Msg("Another agent took over this configuration, shutting down")
+			cancel()
+		}
+	}()
+
+	// Lower the priority before any thread starts scanning, so they all inherit it
+	if err := priority.Apply(cfg.Priority.Level); err != nil {
+		log.Warn().Err(err).Str("level", cfg.Priority.Level).Msg("Failed to lower process priority")
+	} else if cfg.Priority.Level != common_config.PriorityNormal {
+		log.Info().Str("level", cfg.Priority.Level).Msg("Running at lowered CPU and I/O priority")
+	}
+	priority.SetHashThrottle(cfg.Priority.HashThrottleBytes)
+	applyMemoryLimit(cfg.Resources.MemoryLimit)
+
+	shutdownTelemetry, err := telemetry.Setup(cfg.Telemetry, Version)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize telemetry")
+	}
+	if cfg.Telemetry.Enabled {
+		log.Info().Str("endpoint", cfg.Telemetry.Endpoint).Msg("Exporting traces")
+	}
+
 	store, err := createStorage(cfg)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize storage")
 	}
 
+	if cfg.StorageMiddleware.Metrics.Enabled {
+		go serveMetrics(ctx, cfg.StorageMiddleware.Metrics.Listen)
+	}
+
 	uploaderInstance := uploader.NewUploader(store, cfg)
+	addResourceGauges(uploaderInstance)
+
+	// Large files are uploaded in parts recorded on disk, so a restart resumes them
+	if partsDir, err := uploader.DefaultPartsDir(); err != nil {
+		log.Warn().Err(err).Msg("Failed to resolve part store directory, uploading files whole")
+	} else if parts, err := uploader.NewPartStore(partsDir); err != nil {
+		log.Warn().Err(err).Msg("Failed to open part store, uploading files whole")
+	} else {
+		uploaderInstance.SetPartStore(parts)
+	}
 
 	syncManager, err := sync_manager.NewManager(cfg, store, uploaderInstance)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to create sync manager")
 	}
 
+	// Temporary copies go to a capped staging area rather than filling the system disk
+	area, err := openStaging(cfg.Cache)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to open staging area, using the system temporary directory")
+	} else {
+		syncManager.SetStaging(area)
+		log.Info().Str("dir", area.Dir()).Int64("max_bytes", cfg.Cache.MaxBytes).Msg("Staging area ready")
+	}
+
+	// Merge the global and per-device exclude rules the CLI keeps in its database
+	if dsn, err := cfg.DatabaseDSN(); err == nil {
+		excludeSource := excludes.NewSource(dsn, cfg.DeviceID)
+		defer excludeSource.Close()
+		syncManager.SetExcludeSource(excludeSource.Merge)
+
+		// Keep the summary of each folder sync for 'sync-manager last-run'
+		runStore := runs.NewStore(dsn, cfg.DeviceID)
+		defer runStore.Close()
+		syncManager.SetRunRecorder(runStore.Record)
+	}
+
+	// Pause transfers while the storage is unreachable and catch up once it returns
+	monitor := network.NewMonitor(network.StorageProbe(store), network.DefaultInterval)
+	monitor.OnChange(uploaderInstance.SetOnline)
+	monitor.OnChange(syncManager.SetOnline)
+	uploaderInstance.SetConnectivityCheck(monitor.Check)
+
+	// Record the traffic of each folder per day and total the month against the cap
+	var meter *bandwidth.Meter
+	if dsn, err := cfg.DatabaseDSN(); err == nil {
+		meter = bandwidth.NewMeter(dsn, cfg.DeviceID, cfg.Bandwidth.MonthlyCapBytes)
+		defer meter.Close()
+	}
+	detect := func(ctx context.Context) power.Conditions {
+		conditions := power.Detect(ctx)
+		conditions.Processes = power.DetectProcesses(ctx, cfg.Power.Processes)
+		if meter != nil {
+			conditions.CapReached = meter.CapReached()
+		}
+		return conditions
+	}
+
+	// Apply the battery, metered connection and running program policies and the bandwidth
+	// cap, refreshing the heartbeat so status shows it
+	policy := power.NewMonitor(cfg.Power, detect, power.DefaultInterval)
+	policy.OnChange(uploaderInstance.SetRestriction)
+	policy.OnChange(syncManager.SetRestriction)
+	refreshHeartbeat := make(chan struct{}, 1)
+	policy.OnChange(func(power.Restriction) {
+		select {
+		case refreshHeartbeat <- struct{}{}:
+		default:
+		}
+	})
+
+	// Fetch files from the user's other devices on the local network before the storage
+	var lan *lanSync
+	if cfg.LAN.Enabled {
+		lan = startLAN(ctx, cfg, syncManager)
+	}
+
+	// Compare the clock with the storage server's before the first sync compares modification times
+	checkClock(ctx, cfg, syncManager)
+
 	uploaderInstance.Start()
 	if err := syncManager.Start(); err != nil {
 		log.Fatal().Err(err).Msg("Failed to start sync manager")
 	}
 
+	apiClient := connectServer(ctx, cfg)
+	if apiClient != nil {
+		syncManager.SetEventRecorder(serverEventRecorder(ctx, apiClient))
+	}
+	go runHeartbeat(ctx, cfg, apiClient, policy, refreshHeartbeat)
+	go publishProgress(ctx, uploaderInstance.Progress())
+	go publishStats(ctx, syncManager.Stats())
+	if meter != nil {
+		go meter.Run(ctx, syncManager.Stats(), bandwidth.DefaultInterval)
+	}
+	requests := &requestLog{}
+	go publishStatus(ctx, syncManager, store, requests)
+	go watchSyncRequests(ctx, syncManager, requests)
+	go monitor.Run(ctx)
+	go policy.Run(ctx)
+
+	// Mirror folders onto removable drives as they are plugged in
+	if removable := drives.Drives(cfg); len(removable) > 0 {
+		mirror := func(ctx context.Context, folderID string) error { return syncManager.SyncNow(ctx, folderID, false) }
+		watcher := drives.NewWatcher(store, removable, mirror, func() bool {
+			snap := uploaderInstance.Progress().Snapshot()
+			return len(snap.Active) == 0 && snap.FilesDone+snap.FilesFailed >= snap.FilesTotal
+		})
+		go watcher.Run(ctx)
+	}
+
+	// Apply concurrency, bandwidth and folder changes without a restart, on SIGHUP, when the
+	// file changes or when the CLI changes a setting through the control API
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	configPath := common_config.ConfigFileUsed()
+	settings := newAgentSettings(ctx, cfg)
+	go watchConfig(ctx, configPath, hup, settings.changes, func() {
+		if cfg := reloadConfig(configPath, store, uploaderInstance, syncManager, lan, meter, area); cfg != nil {
+			settings.setCurrent(cfg)
+		}
+	})
+	go serveControl(ctx, cfg, configPath, settings)
+
 	log.Info().Msg("Sync Manager Agent started successfully")
 
-	fmt.Println("Sync Manager Agent")
+	i18n.Println("Sync Manager Agent")
 	fmt.Println("---------------")
-	fmt.Println("Agent is running in the background.")
-	fmt.Println("Monitoring and syncing folders according to configuration.")
-	fmt.Println("Use the CLI to manage synced folders and view status.")
-	fmt.Println("Press Ctrl+C to exit.")
+	i18n.Println("Agent is running in the background.")
+	i18n.Println("Monitoring and syncing folders according to configuration.")
+	i18n.Println("Use the CLI to manage synced folders and view status.")
+	i18n.Println("Press Ctrl+C to exit.")
 
 	<-ctx.Done()
 
 	log.Info().Msg("Shutting down sync manager")
 	syncManager.Stop()
 
+	// Flush the spans of the last sync before exiting
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTelemetry(flushCtx); err != nil {
+		log.Warn().Err(err).Msg("Failed to flush traces")
+	}
+	cancelFlush()
+
 	log.Info().Msg("Shutdown complete")
 }
 
 func loadConfiguration() (*common_config.Config, error) {
 	configPath := ""
+	profilePath := ""
 
 	if envPath := os.Getenv("SYNC_MANAGER_CONFIG"); envPath != "" {
 		configPath = envPath
+	} else {
+		profile, path, err := common_config.ResolveProfile(common_config.ProfileFromArgs(os.Args[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to select profile: %w", err)
+		}
+		if profile != common_config.DefaultProfile {
+			log.Info().Str("profile", profile).Msg("Using configuration profile")
+		}
+		configPath, profilePath = path, path
 	}
 
 	cfg, err := common_config.LoadConfig(configPath)
@@ -111,12 +314,13 @@ func loadConfiguration() (*common_config.Config, error) {
 			}
 		}
 
-		configDir, err := os.UserConfigDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get user config directory: %w", err)
+		// A named profile keeps its own device identity in its own file
+		savePath := profilePath
+		if savePath == "" {
+			if savePath, err = common_config.GetConfigPath(); err != nil {
+				return nil, fmt.Errorf("failed to get default config path: %w", err)
+			}
 		}
-
-		savePath := filepath.Join(configDir, "sync-manager", "sync-manager.yaml")
 		if err := common_config.SaveConfig(cfg, savePath); err != nil {
 			log.Warn().Err(err).Msg("Failed to save configuration")
 		}
@@ -125,6 +329,688 @@ func loadConfiguration() (*common_config.Config, error) {
 	return cfg, nil
 }
 
+// acquireInstanceLock takes the instance lock of the configuration, exiting with an explanation
+// when another agent holds it. --takeover takes it from that agent, which then stops.
+func acquireInstanceLock(cfg *common_config.Config) *instance.Lock {
+	configPath := common_config.ConfigFileUsed()
+	if configPath == "" {
+		var err error
+		if configPath, err = common_config.GetConfigPath(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to get config path")
+		}
+	}
+	dsn, err := cfg.DatabaseDSN()
+	if err != nil {
+		dsn = ""
+	}
+
+	lock, err := instance.Acquire(instance.LockPath(configPath), dsn, cfg.DeviceID, instance.TakeoverFromArgs(os.Args[1:]))
+	var locked *instance.LockedError
+	if errors.As(err, &locked) {
+		i18n.Fprintf(os.Stderr, "Another agent is already running on this configuration: %s, last seen %s ago.\n", locked.Holder, time.Since(locked.Holder.Heartbeat).Round(time.Second))
+		i18n.Fprintf(os.Stderr, "Stop it first, or start this agent with %s if that one is stuck.\n", instance.TakeoverFlag)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to take the instance lock")
+	}
+	if lock.Previous != nil {
+		log.Warn().Str("holder", lock.Previous.String()).Msg("Took the instance lock over from another agent")
+	}
+	return lock
+}
+
+// watchConfig calls reload when the file at path changes or a signal arrives on hup, until ctx is cancelled
+func watchConfig(ctx context.Context, path string, hup <-chan os.Signal, changes <-chan settingChange, reload func()) {
+	modTime := func() time.Time {
+		if path == "" {
+			return time.Time{}
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+
+	ticker := time.NewTicker(configCheckInterval)
+	defer ticker.Stop()
+
+	last := modTime()
+	for {
+		select {
+		case <-ticker.C:
+			if current := modTime(); !current.Equal(last) {
+				last = current
+				reload()
+			}
+		case <-hup:
+			log.Info().Msg("Received SIGHUP, reloading configuration")
+			last = modTime()
+			reload()
+		case change := <-changes:
+			// The file is saved once and applied right away, not again when its change is seen
+			err := changeSettings(path, change.settings)
+			if err == nil {
+				last = modTime()
+				reload()
+			}
+			change.done <- err
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reloadConfig reads the configuration again and applies the settings that can change at runtime,
+// returning it. An invalid file is ignored so a half-written edit does not disturb running
+// transfers, and nil is returned.
+func reloadConfig(path string, store storage.Storage, up *uploader.Uploader, manager sync_manager.Manager, lan *lanSync, meter *bandwidth.Meter, area *staging.Area) *common_config.Config {
+	cfg, err := common_config.LoadConfig(path)
+	if err != nil {
+		log.Warn().Err(err).Msg("Ignoring invalid configuration change")
+		return nil
+	}
+
+	up.SetConcurrency(cfg.MaxConcurrency, cfg.Concurrency)
+	up.SetThrottle(cfg.ThrottleBytes)
+	up.SetFiles(cfg.Files)
+	up.SetScheduling(cfg.Scheduling)
+	priority.SetHashThrottle(cfg.Priority.HashThrottleBytes)
+	applyMemoryLimit(cfg.Resources.MemoryLimit)
+	routeFolders(store, cfg)
+	if lan != nil {
+		lan.source.SetFolders(lanFolders(cfg))
+		lan.trust.SetPeers(cfg.LAN.Peers)
+	}
+	manager.ApplyFolders(cfg.SyncFolders, cfg.NestedFolders)
+	if meter != nil {
+		meter.SetCap(cfg.Bandwidth.MonthlyCapBytes)
+	}
+	area.SetMaxBytes(cfg.Cache.MaxBytes)
+
+	log.Info().
+		Int("max_concurrency", cfg.MaxConcurrency).
+		Str("concurrency_mode", cfg.Concurrency.Mode).
+		Int64("throttle_bytes", cfg.ThrottleBytes).
+		Int64("hash_throttle_bytes", cfg.Priority.HashThrottleBytes).
+		Int("folders", len(cfg.SyncFolders)).
+		Msg("Configuration reloaded")
+	return cfg
+}
+
+// settingChange is a change of settings through the control API, whose outcome is sent on done
+type settingChange struct {
+	settings []control.Setting
+	done     chan error
+}
+
+// agentSettings is the configuration the control API reads and changes: reads answer from the
+// configuration last applied, and changes go through the loop watching the file
+type agentSettings struct {
+	ctx     context.Context
+	changes chan settingChange
+
+	mu      sync.Mutex
+	current *common_config.Config
+}
+
+// newAgentSettings returns the settings of the agent running cfg, changed until ctx is cancelled
+func newAgentSettings(ctx context.Context, cfg *common_config.Config) *agentSettings {
+	return &agentSettings{ctx: ctx, changes: make(chan settingChange), current: cfg}
+}
+
+// setCurrent records the configuration applied by a reload
+func (s *agentSettings) setCurrent(cfg *common_config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = cfg
+}
+
+// Get returns a setting of the configuration last applied
+func (s *agentSettings) Get(key, target string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current.Get(key, target)
+}
+
+// Set changes settings in the configuration file and applies them, once the whole
+// configuration was checked
+func (s *agentSettings) Set(settings []control.Setting) error {
+	change := settingChange{settings: settings, done: make(chan error, 1)}
+	select {
+	case s.changes <- change:
+		return <-change.done
+	case <-s.ctx.Done():
+		return i18n.Errorf("agent is shutting down")
+	}
+}
+
+// changeSettings changes settings of the configuration file at path, saving it only when the
+// resulting configuration is valid. The file is read again so settings written as references
+// stay references.
+func changeSettings(path string, settings []control.Setting) error {
+	if path == "" {
+		return i18n.Errorf("agent is running without a configuration file")
+	}
+	cfg, err := common_config.LoadConfig(path)
+	if err != nil {
+		return i18n.Errorf("configuration file is invalid: %w", err)
+	}
+	for _, setting := range settings {
+		if err := cfg.Set(setting.Key, setting.Value, setting.Target); err != nil {
+			return err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if err := common_config.SaveConfig(cfg, path); err != nil {
+		return i18n.Errorf("failed to save configuration: %w", err)
+	}
+	log.Info().Int("settings", len(settings)).Msg("Configuration changed through the control API")
+	return nil
+}
+
+// serveControl serves the control API on the socket of the configuration at path until ctx
+// is cancelled, to callers with an API token of the database of cfg. Without it the CLI saves
+// changes to the file, applied when it is next read.
+func serveControl(ctx context.Context, cfg *common_config.Config, path string, settings *agentSettings) {
+	if path == "" {
+		return
+	}
+	dsn, err := cfg.DatabaseDSN()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to start control API: no database to check API tokens against")
+		return
+	}
+	verifier := tokens.NewVerifier(dsn)
+	defer verifier.Close()
+
+	listener, err := control.Listen(control.SocketPath(path))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to start control API")
+		return
+	}
+	if err := control.Serve(ctx, listener, settings, verifier.Authenticate); err != nil {
+		log.Error().Err(err).Msg("Control API stopped")
+	}
+}
+
+// openStaging opens the staging area of the configuration, in the default directory unless
+// one is set. A directory changed in the configuration takes effect after a restart.
+func openStaging(cfg common_config.CacheConfig) (*staging.Area, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		var err error
+		if dir, err = staging.DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+	return staging.New(dir, cfg.MaxBytes)
+}
+
+// routeFolders points the storage router at the targets of the reloaded folders. Targets
+// added since the agent started only take effect after a restart.
+func routeFolders(store storage.Storage, cfg *common_config.Config) {
+	router, ok := store.(*storage.Router)
+	if ok {
+		router.SetFolders(storage.FolderTargets(cfg))
+	}
+	for _, target := range cfg.Targets {
+		if (ok && router.Target(target.Name) == nil) || (!ok && len(cfg.Targets) > 1) {
+			log.Warn().Str("target", target.Name).Msg("Storage targets changed, restart the agent to sync to the new ones")
+			return
+		}
+	}
+}
+
+// connectServer returns a client for the coordination server and keeps its device token fresh.
+// It returns nil when no server is configured or the device is not logged in.
+func connectServer(ctx context.Context, cfg *common_config.Config) *apiclient.Client {
+	if cfg.ApiEndpoint == "" {
+		return nil
+	}
+
+	credentialsPath, err := apiclient.DefaultCredentialsPath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get credentials path")
+		return nil
+	}
+
+	client, err := apiclient.NewClient(cfg.ApiEndpoint, credentialsPath)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load device credentials")
+		return nil
+	}
+
+	if !transport.IsDefault(cfg.HTTP) {
+		httpTransport, err := transport.New(cfg.HTTP)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to configure server connection")
+			return nil
+		}
+		client.SetTransport(httpTransport)
+	}
+
+	if client.Credentials() == nil {
+		log.Info().Msg("Device is not logged in, run 'sync-manager login' to connect it to the server")
+		return nil
+	}
+
+	client.StartTokenRefresher(ctx, time.Hour)
+	return client
+}
+
+// serverEventRecorder returns a recorder posting sync events to the server in the
+// background, so a slow or unreachable server never holds up a sync
+func serverEventRecorder(ctx context.Context, client *apiclient.Client) sync_manager.EventRecorder {
+	return func(folderID string, event models.CreateSyncEventRequest) {
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+			if err := client.RecordSyncEvent(ctx, folderID, event); err != nil {
+				log.Warn().Err(err).Str("folder", folderID).Str("event", event.EventType).Msg("Failed to record sync event")
+			}
+		}()
+	}
+}
+
+// runHeartbeat records the agent's last-seen time locally and on the server until ctx is cancelled.
+// A value on refresh rewrites the local heartbeat early so status picks up policy changes.
+func runHeartbeat(ctx context.Context, cfg *common_config.Config, client *apiclient.Client, policy *power.Monitor, refresh <-chan struct{}) {
+	path, err := heartbeat.DefaultPath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get heartbeat path, heartbeat disabled")
+		return
+	}
+
+	ticker := time.NewTicker(heartbeat.Interval)
+	defer ticker.Stop()
+
+	sendToServer := true
+	for {
+		hb := &heartbeat.Heartbeat{
+			DeviceID:    cfg.DeviceID,
+			DeviceName:  cfg.DeviceName,
+			Version:     Version,
+			PID:         os.Getpid(),
+			Time:        time.Now(),
+			Restriction: policy.Restriction().String(),
+		}
+		if err := heartbeat.Write(path, hb); err != nil {
+			log.Warn().Err(err).Msg("Failed to write heartbeat")
+		}
+
+		if client != nil && sendToServer {
+			if err := client.Heartbeat(ctx, Version); err != nil && ctx.Err() == nil {
+				log.Warn().Err(err).Msg("Failed to send heartbeat to server")
+			}
+		}
+
+		select {
+		case <-ticker.C:
+			sendToServer = true
+		case <-refresh:
+			// Only the local record changes; the server keeps its regular interval
+			sendToServer = false
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publishProgress writes the transfer progress for the CLI while transfers run, until ctx is cancelled
+func publishProgress(ctx context.Context, tracker *progress.Tracker) {
+	path, err := progress.DefaultPath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get progress path, progress reporting disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// Write once at startup so a stale file from an earlier run is replaced
+	wasIdle := false
+	for {
+		snap := tracker.Snapshot()
+		if !snap.Idle() || !wasIdle {
+			if err := progress.Write(path, snap); err != nil {
+				log.Warn().Err(err).Msg("Failed to write progress")
+			}
+		}
+		wasIdle = snap.Idle()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publishStats writes the transfer totals and rates for the CLI until ctx is cancelled.
+// While nothing is transferred the file is only rewritten every heartbeat interval.
+func publishStats(ctx context.Context, registry *stats.Registry) {
+	path, err := stats.DefaultPath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get stats path, stats reporting disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var lastWrite time.Time
+	for {
+		snap := registry.Snapshot()
+		if snap.Global.Active() || time.Since(lastWrite) >= heartbeat.Interval {
+			if err := stats.Write(path, snap); err != nil {
+				log.Warn().Err(err).Msg("Failed to write stats")
+			}
+			lastWrite = time.Now()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publishStatus writes the state of each folder and the outcome of the last requested sync
+// for the CLI until ctx is cancelled. The file is rewritten when they change, and every
+// heartbeat interval otherwise.
+func publishStatus(ctx context.Context, manager sync_manager.Manager, store storage.Storage, requests *requestLog) {
+	path, err := status.DefaultPath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get status path, status reporting disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var (
+		last          map[string]status.Folder
+		lastOperation *status.Operation
+		lastEndpoints map[string]string
+		lastRequest   *status.Request
+		lastWrite     time.Time
+	)
+	for {
+		snap := manager.FolderStatus()
+		if endpoints := storage.ActiveEndpoints(store); len(endpoints) > 0 {
+			snap.Endpoints = endpoints
+		}
+		snap.Request = requests.last()
+		changed := !reflect.DeepEqual(snap.Folders, last) || !reflect.DeepEqual(snap.Operation, lastOperation) ||
+			!reflect.DeepEqual(snap.Endpoints, lastEndpoints) || !reflect.DeepEqual(snap.Request, lastRequest)
+		if changed || time.Since(lastWrite) >= heartbeat.Interval {
+			if err := status.Write(path, snap); err != nil {
+				log.Warn().Err(err).Msg("Failed to write status")
+			}
+			last, lastOperation, lastEndpoints, lastRequest = snap.Folders, snap.Operation, snap.Endpoints, snap.Request
+			lastWrite = time.Now()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// requestLog keeps the outcome of the last sync requested through the CLI, which 'sync-now'
+// waits for
+type requestLog struct {
+	mu      sync.Mutex
+	request *status.Request
+}
+
+// finish records the end of the request made at requestedAt. A request that ends after a
+// later one, which restarted it, does not replace it.
+func (l *requestLog) finish(requestedAt time.Time, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.request != nil && l.request.RequestedAt.After(requestedAt) {
+		return
+	}
+	l.request = &status.Request{RequestedAt: requestedAt, FinishedAt: time.Now()}
+	if err != nil {
+		l.request.Error = err.Error()
+	}
+}
+
+// last returns the outcome of the last request that ended, nil before any did
+func (l *requestLog) last() *status.Request {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.request == nil {
+		return nil
+	}
+	request := *l.request
+	return &request
+}
+
+// watchSyncRequests runs the syncs requested through the CLI until ctx is cancelled, recording
+// the outcome of each in requests. Each request runs in the background so a later one can
+// restart it.
+func watchSyncRequests(ctx context.Context, manager sync_manager.Manager, requests *requestLog) {
+	path, err := trigger.DefaultPath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get sync request path, syncs on request disabled")
+		return
+	}
+
+	ticker := time.NewTicker(syncRequestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		req, err := trigger.Take(path)
+		if err != nil {
+			log.Warn().Err(err).Msg("Ignoring invalid sync request")
+			continue
+		}
+		if req == nil {
+			continue
+		}
+
+		folderIDs := req.Folders
+		if req.All {
+			folderIDs = []string{""}
+		}
+		// Subtrees of folders synced as a whole are covered by their sync
+		pathFolders := make([]string, 0, len(req.Paths))
+		for folderID := range req.Paths {
+			if !req.All && !slices.Contains(req.Folders, folderID) {
+				pathFolders = append(pathFolders, folderID)
+			}
+		}
+		sort.Strings(pathFolders)
+		go func() {
+			var failed error
+			fail := func(err error, folderID string) {
+				if err != nil && ctx.Err() == nil {
+					log.Error().Err(err).Str("folder", folderID).Msg("Requested sync failed")
+					if failed == nil {
+						failed = err
+					}
+				}
+			}
+			// Only the first sync restarts the running one, the others queue behind it
+			restart := req.Restart
+			for _, folderID := range folderIDs {
+				fail(manager.SyncNow(ctx, folderID, restart), folderID)
+				restart = false
+			}
+			for _, folderID := range pathFolders {
+				for _, relPath := range req.Paths[folderID] {
+					fail(manager.SyncPath(ctx, folderID, relPath, restart), folderID)
+					restart = false
+				}
+			}
+			if ctx.Err() == nil {
+				requests.finish(req.RequestedAt, failed)
+			}
+		}()
+	}
+}
+
+// lanSync is the part of LAN sync a configuration reload updates
+type lanSync struct {
+	source *peer.FolderSource
+	trust  *peer.Trust
+}
+
+// startLAN serves this device's files to its trusted peers and lets the sync manager fetch
+// from them. It returns nil if the server could not start.
+func startLAN(ctx context.Context, cfg *common_config.Config, manager sync_manager.Manager) *lanSync {
+	identityPath, err := identity.DefaultPath()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start LAN sync")
+		return nil
+	}
+	cert, err := identity.Load(identityPath, cfg.DeviceID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start LAN sync")
+		return nil
+	}
+	if len(cfg.LAN.Peers) == 0 {
+		log.Warn().Msg("No devices are trusted for LAN sync yet, run 'sync-manager lan trust' on each device")
+	}
+
+	listener, err := net.Listen("tcp", cfg.LAN.Listen)
+	if err != nil {
+		log.Error().Err(err).Str("address", cfg.LAN.Listen).Msg("Failed to start LAN sync")
+		return nil
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	trust := peer.NewTrust(cert, cfg.LAN.Peers)
+	source := peer.NewFolderSource(lanFolders(cfg))
+	server := peer.NewServer(trust, source)
+	go func() {
+		if err := server.Serve(ctx, listener); err != nil {
+			log.Error().Err(err).Msg("LAN file server failed")
+		}
+	}()
+
+	discovery := peer.NewDiscovery(cfg.DeviceID, port)
+	go func() {
+		if err := discovery.Run(ctx); err != nil {
+			log.Warn().Err(err).Msg("LAN discovery stopped")
+		}
+	}()
+
+	manager.SetPeers(peer.NewClient(trust, discovery, cfg.LAN.Timeout))
+
+	log.Info().
+		Str("address", listener.Addr().String()).
+		Str("fingerprint", identity.Fingerprint(cert.Certificate[0])).
+		Msg("Serving files to devices on the local network")
+	return &lanSync{source: source, trust: trust}
+}
+
+// lanFolders returns the local roots of the folders served to peers, along with the file of
+// single-file folders, by the storage prefix of their files. Backup folders are left out since their remote objects are snapshot
+// chunks, not files.
+func lanFolders(cfg *common_config.Config) map[string]agent_config.SyncFolder {
+	folders := make(map[string]agent_config.SyncFolder)
+	for _, folder := range cfg.SyncFolders {
+		if !folder.Enabled || folder.Mode == common_config.FolderModeBackup {
+			continue
+		}
+		var roots []agent_config.FolderRoot
+		for _, root := range folder.Roots {
+			roots = append(roots, agent_config.FolderRoot(root))
+		}
+		folders[folder.KeyPrefix()] = agent_config.SyncFolder{LocalPath: folder.Path, Roots: roots, File: folder.File}
+	}
+	return folders
+}
+
+// applyMemoryLimit makes the garbage collector keep the heap under limit bytes. A limit of 0
+// leaves the runtime default, so removing the limit takes effect after a restart.
+func applyMemoryLimit(limit int64) {
+	if limit > 0 {
+		debug.SetMemoryLimit(limit)
+		log.Info().Int64("memory_limit", limit).Msg("Memory limit set")
+	}
+}
+
+// checkClock reads the time of the storage server, warning when the clock of this machine is
+// off by more than the configured maximum, and has the sync manager allow for the difference
+func checkClock(ctx context.Context, cfg *common_config.Config, sm sync_manager.Manager) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	skew, err := storage.ClockSkew(ctx, cfg.DefaultTarget())
+	if errors.Is(err, storage.ErrNoServerClock) {
+		return
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read the storage server's time, comparing modification times as they are")
+		return
+	}
+	sm.SetClockSkew(skew)
+	if skew.Abs() > cfg.Clock.MaxSkew {
+		log.Warn().
+			Dur("skew", skew).
+			Dur("max_skew", cfg.Clock.MaxSkew).
+			Msg("The clock of this machine is off from the storage server's; set it right, modification times are compared allowing for the difference meanwhile")
+		return
+	}
+	log.Debug().Dur("skew", skew).Msg("Clock checked against the storage server")
+}
+
+// addResourceGauges reports the memory of the agent and the fill of its upload queue along
+// with the storage metrics
+func addResourceGauges(up *uploader.Uploader) {
+	storage.DefaultMetrics.AddGauge("sync_manager_agent_heap_bytes", "Bytes of allocated heap objects.", func() int64 {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		return int64(mem.HeapAlloc)
+	})
+	storage.DefaultMetrics.AddGauge("sync_manager_agent_memory_limit_bytes", "Soft memory limit of the agent.", func() int64 {
+		return debug.SetMemoryLimit(-1)
+	})
+	storage.DefaultMetrics.AddGauge("sync_manager_upload_queue_length", "Uploads waiting for a worker.", func() int64 {
+		length, _ := up.QueueLength()
+		return int64(length)
+	})
+	storage.DefaultMetrics.AddGauge("sync_manager_upload_queue_capacity", "Uploads the queue holds before scans wait for room.", func() int64 {
+		_, capacity := up.QueueLength()
+		return int64(capacity)
+	})
+}
+
+// serveMetrics serves the storage metrics for Prometheus on addr until ctx is cancelled
+func serveMetrics(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", storage.DefaultMetrics)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	log.Info().Str("address", addr).Msg("Serving storage metrics")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error().Err(err).Msg("Metrics server failed")
+	}
+}
+
 // createStorage creates a storage implementation based on configuration
 func createStorage(cfg *common_config.Config) (storage.Storage, error) {
 	return storage.StorageFactory(cfg)
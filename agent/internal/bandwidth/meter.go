@@ -0,0 +1,189 @@
+// Package bandwidth records how many bytes each folder transfers per day in the shared
+// database and totals the month against the monthly cap of the device.
+package bandwidth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/database"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/rs/zerolog/log"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultInterval is how often the transfers are written to the database
+const DefaultInterval = time.Minute
+
+// DayFormat is the layout of BandwidthUsage.Day
+const DayFormat = "2006-01-02"
+
+// transferred is a byte count in each direction
+type transferred struct {
+	up, down int64
+}
+
+// Meter turns the transfer totals of the agent into daily rows of the database. Totals are
+// read from stats snapshots, so only what changed since the previous write is added.
+type Meter struct {
+	dsn      string
+	deviceID string
+	now      func() time.Time
+
+	mu         sync.Mutex
+	db         *gorm.DB
+	last       map[string]transferred // Folder totals at the previous write
+	capBytes   int64
+	monthBytes int64 // Traffic of the device in the current month, as of the last write
+}
+
+// NewMeter creates a meter writing to the database at dsn on behalf of deviceID
+func NewMeter(dsn, deviceID string, capBytes int64) *Meter {
+	return &Meter{dsn: dsn, deviceID: deviceID, capBytes: capBytes, now: time.Now, last: make(map[string]transferred)}
+}
+
+// SetCap changes the monthly cap in bytes, 0 for none
+func (m *Meter) SetCap(capBytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.capBytes = capBytes
+}
+
+// CapReached reports whether the device used up its monthly cap as of the last write
+func (m *Meter) CapReached() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.capBytes > 0 && m.monthBytes >= m.capBytes
+}
+
+// Run writes the transfers of registry every interval until ctx is cancelled, then once more
+func (m *Meter) Run(ctx context.Context, registry *stats.Registry, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Record(registry.Snapshot()); err != nil {
+			log.Warn().Err(err).Msg("Failed to record bandwidth usage")
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if err := m.Record(registry.Snapshot()); err != nil {
+				log.Warn().Err(err).Msg("Failed to record bandwidth usage")
+			}
+			return
+		}
+	}
+}
+
+// Record adds what each folder transferred since the previous call to today's rows, then
+// totals the month of the device. A failed write is retried whole by the next call.
+func (m *Meter) Record(snap stats.Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	db, err := m.open()
+	if err != nil {
+		return err
+	}
+
+	now := m.now()
+	day := now.Format(DayFormat)
+	current := make(map[string]transferred, len(snap.Folders))
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for folderID, totals := range snap.Folders {
+			total := transferred{totals.BytesUploaded, totals.BytesDownloaded}
+			current[folderID] = total
+			previous := m.last[folderID]
+			up, down := total.up-previous.up, total.down-previous.down
+			if up <= 0 && down <= 0 {
+				continue
+			}
+
+			usage := models.BandwidthUsage{Day: day, FolderID: folderID, DeviceID: m.deviceID, BytesUploaded: up, BytesDownloaded: down}
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "day"}, {Name: "folder_id"}, {Name: "device_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"bytes_uploaded":   gorm.Expr("bandwidth_usages.bytes_uploaded + ?", up),
+					"bytes_downloaded": gorm.Expr("bandwidth_usages.bytes_downloaded + ?", down),
+					"updated_at":       now,
+				}),
+			}).Create(&usage).Error
+			if err != nil {
+				return fmt.Errorf("failed to record bandwidth usage: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.last = current
+
+	var month int64
+	err = db.Model(&models.BandwidthUsage{}).
+		Select("COALESCE(SUM(bytes_uploaded + bytes_downloaded), 0)").
+		Where("device_id = ? AND day >= ? AND day <= ?", m.deviceID, now.Format("2006-01")+"-01", now.Format("2006-01")+"-31").
+		Scan(&month).Error
+	if err != nil {
+		return fmt.Errorf("failed to total monthly bandwidth: %w", err)
+	}
+	wasReached := m.capBytes > 0 && m.monthBytes >= m.capBytes
+	m.monthBytes = month
+	if m.capBytes > 0 && month >= m.capBytes && !wasReached {
+		log.Warn().Int64("used", month).Int64("cap", m.capBytes).Msg("Monthly bandwidth cap reached, pausing transfers until next month")
+	}
+	return nil
+}
+
+// Close closes the database when it was opened
+func (m *Meter) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.db == nil {
+		return nil
+	}
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	m.db = nil
+	return sqlDB.Close()
+}
+
+// open opens the database and creates the usage table when missing. mu must be held.
+func (m *Meter) open() (*gorm.DB, error) {
+	if m.db != nil {
+		return m.db, nil
+	}
+
+	dialector, driver, err := database.Dialector(m.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == database.SQLite {
+		// The CLI may be writing at the same time
+		dialector = sqlite.Open("file:" + m.dsn + "?_busy_timeout=5000")
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.AutoMigrate(&models.BandwidthUsage{}); err != nil {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		return nil, fmt.Errorf("failed to create bandwidth table: %w", err)
+	}
+	m.db = db
+	return db, nil
+}
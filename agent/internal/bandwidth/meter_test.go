@@ -0,0 +1,65 @@
+package bandwidth
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeterRecordsDailyUsage(t *testing.T) {
+	meter := NewMeter(filepath.Join(t.TempDir(), "sync-manager.db"), "desktop", 10000)
+	defer meter.Close()
+	day := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	meter.now = func() time.Time { return day }
+
+	registry := stats.NewRegistry()
+	registry.Uploaded("docs", 3000)
+	registry.Downloaded("photos", 1000)
+	assert.NoError(t, meter.Record(registry.Snapshot()))
+	assert.False(t, meter.CapReached())
+
+	// Only what was transferred since the last write is added, to the day it is written on
+	registry.Uploaded("docs", 2000)
+	assert.NoError(t, meter.Record(registry.Snapshot()))
+	day = day.AddDate(0, 0, 1)
+	registry.Downloaded("docs", 500)
+	assert.NoError(t, meter.Record(registry.Snapshot()))
+
+	var rows []models.BandwidthUsage
+	assert.NoError(t, meter.db.Order("day, folder_id").Find(&rows).Error)
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, models.BandwidthUsage{Day: "2024-06-10", FolderID: "docs", DeviceID: "desktop", BytesUploaded: 5000}, clearTimes(rows[0]))
+		assert.Equal(t, models.BandwidthUsage{Day: "2024-06-10", FolderID: "photos", DeviceID: "desktop", BytesDownloaded: 1000}, clearTimes(rows[1]))
+		assert.Equal(t, models.BandwidthUsage{Day: "2024-06-11", FolderID: "docs", DeviceID: "desktop", BytesDownloaded: 500}, clearTimes(rows[2]))
+	}
+
+	// Other devices and months do not count towards the cap
+	assert.NoError(t, meter.db.Create(&models.BandwidthUsage{Day: "2024-06-11", FolderID: "docs", DeviceID: "laptop", BytesUploaded: 50000}).Error)
+	assert.NoError(t, meter.db.Create(&models.BandwidthUsage{Day: "2024-05-31", FolderID: "docs", DeviceID: "desktop", BytesUploaded: 50000}).Error)
+	registry.Uploaded("docs", 3400)
+	assert.NoError(t, meter.Record(registry.Snapshot()))
+	assert.False(t, meter.CapReached())
+
+	registry.Uploaded("docs", 100)
+	assert.NoError(t, meter.Record(registry.Snapshot()))
+	assert.True(t, meter.CapReached())
+
+	// A new month starts from zero, and a higher cap lifts the pause
+	meter.SetCap(20000)
+	assert.False(t, meter.CapReached())
+	meter.SetCap(10000)
+	day = time.Date(2024, 7, 1, 0, 5, 0, 0, time.Local)
+	assert.NoError(t, meter.Record(registry.Snapshot()))
+	assert.False(t, meter.CapReached())
+}
+
+// clearTimes drops the fields the database sets, for comparisons
+func clearTimes(usage models.BandwidthUsage) models.BandwidthUsage {
+	usage.ID = 0
+	usage.UpdatedAt = time.Time{}
+	return usage
+}
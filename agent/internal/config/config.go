@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 // SyncFolder represents a folder to be synchronized
@@ -13,13 +14,97 @@ type SyncFolder struct {
 	LocalPath       string   `json:"local_path"`
 	RemotePath      string   `json:"remote_path"`
 	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
+	TwoWaySync      bool     `json:"two_way_sync,omitempty"` // Also apply changes made on other devices
 	Enabled         bool     `json:"enabled"`
+	Paused          bool     `json:"paused,omitempty"`
+	// Priority orders the uploads of the folder against those of others, lower numbers first
+	Priority int `json:"priority,omitempty"`
+	// IntervalMinutes overrides the global sync interval when greater than zero
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+	// Mode is "mirror" (default) or "backup"
+	Mode      string          `json:"mode,omitempty"`
+	Retention RetentionConfig `json:"retention"`
+	Mirror    MirrorConfig    `json:"mirror"`
+	// StorageClass selects the storage class of uploaded objects, empty for the bucket default
+	StorageClass string `json:"storage_class,omitempty"`
+	// PackSmallFiles packs the files of a backup folder up to this many bytes into shared
+	// objects, zero to store each file as its own object
+	PackSmallFiles int64 `json:"pack_small_files,omitempty"`
+	// Roots adds local directories to the folder, each synced under its prefix of the remote folder
+	Roots []FolderRoot `json:"roots,omitempty"`
+	// ConflictPolicy decides which copy wins when a file changed on both sides, "keep-both" when empty
+	ConflictPolicy string `json:"conflict_policy,omitempty"`
+	// InUseTimeoutSeconds bounds how long the upload of a file in use waits, zero for the default
+	// and negative to upload files in use right away
+	InUseTimeoutSeconds int `json:"in_use_timeout_seconds,omitempty"`
+	// File limits the folder to the file of that name directly under LocalPath, empty to sync everything
+	File string `json:"file,omitempty"`
+	// InitialMerge is the conflict policy for the files already on both sides on the first sync,
+	// ConflictPolicy when empty
+	InitialMerge string `json:"initial_merge,omitempty"`
+	// Subscribe makes the folder a read-only copy of the remote folder: remote changes are
+	// downloaded and local ones never uploaded
+	Subscribe bool `json:"subscribe,omitempty"`
+	// LocalChanges is what a subscribed folder does with files changed locally: "revert"
+	// (default) or "flag"
+	LocalChanges string `json:"local_changes,omitempty"`
+	// ArchiveAfterDays replaces files uploaded and unmodified for that many days with
+	// placeholders, zero to keep every file local
+	ArchiveAfterDays int `json:"archive_after_days,omitempty"`
+	// Schedule is a cron expression of when the folder syncs, overriding IntervalMinutes
+	Schedule string `json:"schedule,omitempty"`
+	// AgeFilter limits the files uploaded by when they were last modified
+	AgeFilter AgeFilter `json:"age_filter"`
+	// Hooks are shell commands run before and after each sync of the folder
+	Hooks FolderHooks `json:"hooks"`
+	// Checksum is the algorithm hashing the files of the folder, "sha256" when empty
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// FolderHooks are the shell commands run around the syncs of a folder
+type FolderHooks struct {
+	PreSync  Hook `json:"pre_sync"`
+	PostSync Hook `json:"post_sync"`
+}
+
+// Hook is a shell command with its timeout, zero for the default, and failure policy
+type Hook struct {
+	Command   string        `json:"command,omitempty"`
+	Timeout   time.Duration `json:"timeout,omitempty"`
+	OnFailure string        `json:"on_failure,omitempty"` // "abort" or "continue", empty for the hook's default
+}
+
+// AgeFilter limits the files of a folder that are uploaded by their modification time
+type AgeFilter struct {
+	MinAge        time.Duration `json:"min_age,omitempty"`        // Files modified more recently wait until they are this old
+	MaxAge        time.Duration `json:"max_age,omitempty"`        // Files last modified longer ago are left out, zero for no limit
+	ModifiedSince string        `json:"modified_since,omitempty"` // Files last modified before this date are left out
+}
+
+// MirrorConfig controls whether a one-way mirror folder removes remote files deleted locally
+type MirrorConfig struct {
+	DeleteOrphans bool `json:"delete_orphans,omitempty"`
+	Trash         bool `json:"trash,omitempty"`
+	MaxDelete     int  `json:"max_delete,omitempty"` // Zero uses the default limit, negative removes any number
+	// MaxDeletePercent limits removals as a percentage of the remote files, with the same zero and negative values
+	MaxDeletePercent int `json:"max_delete_percent,omitempty"`
+}
+
+// RetentionConfig controls which backup snapshots are kept. Zero values keep everything.
+type RetentionConfig struct {
+	KeepLast    int `json:"keep_last,omitempty"`
+	KeepDaily   int `json:"keep_daily,omitempty"`
+	KeepWeekly  int `json:"keep_weekly,omitempty"`
+	KeepMonthly int `json:"keep_monthly,omitempty"`
 }
 
 // SyncConfig contains synchronization settings
 type SyncConfig struct {
 	IntervalMinutes int  `json:"interval_minutes"`
 	AutoSync        bool `json:"auto_sync"`
+	// FullScanMinutes is how often periodic syncs walk a folder the watcher saw no change
+	// in, zero to walk on every sync
+	FullScanMinutes int `json:"full_scan_minutes,omitempty"`
 }
 
 // ServerConfig contains settings for connecting to the server
@@ -31,9 +116,10 @@ type ServerConfig struct {
 
 // Config represents the application configuration
 type Config struct {
-	Server  ServerConfig          `json:"server"`
-	Sync    SyncConfig            `json:"sync"`
-	Folders map[string]SyncFolder `json:"folders"`
+	DeviceID string                `json:"device_id,omitempty"`
+	Server   ServerConfig          `json:"server"`
+	Sync     SyncConfig            `json:"sync"`
+	Folders  map[string]SyncFolder `json:"folders"`
 
 	filePath string
 	mu       sync.RWMutex
@@ -48,6 +134,7 @@ func DefaultConfig() *Config {
 		Sync: SyncConfig{
 			IntervalMinutes: 15,
 			AutoSync:        true,
+			FullScanMinutes: 24 * 60,
 		},
 		Folders: make(map[string]SyncFolder),
 	}
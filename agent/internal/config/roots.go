@@ -0,0 +1,72 @@
+package config
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FolderRoot is a local directory of a folder whose files are stored under Prefix
+// in the remote folder. The folder's own path is the root with an empty prefix.
+type FolderRoot struct {
+	Path   string `json:"path"`
+	Prefix string `json:"prefix"`
+}
+
+// AllRoots returns every local root of the folder, its own path first
+func (f SyncFolder) AllRoots() []FolderRoot {
+	return append([]FolderRoot{{Path: f.LocalPath}}, f.Roots...)
+}
+
+// ResolveRoot returns the root holding a local path and the path's slash-separated key
+// relative to the folder, with the root's prefix applied. The most specific root wins, and
+// files of a root under the prefix of another root are hidden by it, so ok is false for them.
+func ResolveRoot(roots []FolderRoot, localPath string) (root FolderRoot, key string, ok bool) {
+	var rel string
+	for _, r := range roots {
+		relPath, err := filepath.Rel(filepath.Clean(r.Path), filepath.Clean(localPath))
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) || filepath.IsAbs(relPath) {
+			continue
+		}
+		if !ok || len(r.Path) > len(root.Path) {
+			root, rel, ok = r, relPath, true
+		}
+	}
+	if !ok {
+		return FolderRoot{}, "", false
+	}
+
+	key = path.Join(root.Prefix, filepath.ToSlash(rel))
+	if key == "." {
+		key = ""
+	}
+	if owner := rootForKey(roots, key); owner.Prefix != root.Prefix {
+		return FolderRoot{}, "", false
+	}
+	return root, key, true
+}
+
+// RootPath returns the local path of a slash-separated key relative to the folder
+func RootPath(roots []FolderRoot, key string) string {
+	root := rootForKey(roots, key)
+	rel := strings.TrimPrefix(strings.TrimPrefix(key, root.Prefix), "/")
+	return filepath.Join(root.Path, filepath.FromSlash(rel))
+}
+
+// rootForKey returns the root whose prefix holds key, or the first root when none does
+func rootForKey(roots []FolderRoot, key string) FolderRoot {
+	for _, r := range roots {
+		if r.Prefix != "" && (key == r.Prefix || strings.HasPrefix(key, r.Prefix+"/")) {
+			return r
+		}
+	}
+	if len(roots) == 0 {
+		return FolderRoot{}
+	}
+	return roots[0]
+}
+
+// Tracks reports whether the folder syncs a key, which for a single-file folder is only its file
+func (f SyncFolder) Tracks(key string) bool {
+	return f.File == "" || key == f.File
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRoot(t *testing.T) {
+	docs := filepath.Join("/home", "ana", "Documents")
+	desktop := filepath.Join("/home", "ana", "Desktop")
+	roots := SyncFolder{LocalPath: docs, Roots: []FolderRoot{{Path: desktop, Prefix: "Desktop"}}}.AllRoots()
+
+	root, key, ok := ResolveRoot(roots, filepath.Join(docs, "a", "notes.txt"))
+	assert.True(t, ok)
+	assert.Equal(t, docs, root.Path)
+	assert.Equal(t, "a/notes.txt", key)
+
+	root, key, ok = ResolveRoot(roots, filepath.Join(desktop, "todo.txt"))
+	assert.True(t, ok)
+	assert.Equal(t, "Desktop", root.Prefix)
+	assert.Equal(t, "Desktop/todo.txt", key)
+
+	// The Desktop root hides a directory of the same name in the main root
+	_, _, ok = ResolveRoot(roots, filepath.Join(docs, "Desktop", "todo.txt"))
+	assert.False(t, ok)
+
+	_, _, ok = ResolveRoot(roots, filepath.Join("/home", "ana", "Documents2", "x.txt"))
+	assert.False(t, ok)
+}
+
+func TestRootPath(t *testing.T) {
+	docs := filepath.Join("/home", "ana", "Documents")
+	desktop := filepath.Join("/home", "ana", "Desktop")
+	roots := SyncFolder{LocalPath: docs, Roots: []FolderRoot{{Path: desktop, Prefix: "Desktop"}}}.AllRoots()
+
+	assert.Equal(t, filepath.Join(docs, "a", "notes.txt"), RootPath(roots, "a/notes.txt"))
+	assert.Equal(t, filepath.Join(desktop, "todo.txt"), RootPath(roots, "Desktop/todo.txt"))
+	assert.Equal(t, filepath.Join(docs, "Desktops.txt"), RootPath(roots, "Desktops.txt"))
+}
@@ -0,0 +1,149 @@
+// Package drives watches the removable drives storage targets mirror folders to. Folders
+// sync when their drive is plugged in, and the user is told once the mirror is complete so
+// the drive can be unplugged.
+package drives
+
+import (
+	"context"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultInterval is how often the drives are checked
+	DefaultInterval = 5 * time.Second
+	// idleInterval is how often the uploads are checked while waiting for a mirror to finish
+	idleInterval = time.Second
+)
+
+// Drive is a storage target on a removable drive and the folders syncing to it
+type Drive struct {
+	Target  string
+	Folders []string // IDs of the folders
+	key     string   // Key of the first folder, routed to the drive
+	present bool
+}
+
+// Drives returns the targets of cfg on removable drives that folders sync to
+func Drives(cfg *config.Config) []*Drive {
+	var drives []*Drive
+	byTarget := make(map[string]*Drive)
+	for i := range cfg.SyncFolders {
+		folder := &cfg.SyncFolders[i]
+		target := cfg.FolderTarget(folder)
+		if target == nil || target.Type != config.TargetLocal || (!target.Local.Removable && target.Local.VolumeUUID == "") {
+			continue
+		}
+		drive, ok := byTarget[target.Name]
+		if !ok {
+			drive = &Drive{Target: target.Name, key: folder.KeyPrefix() + "/"}
+			byTarget[target.Name] = drive
+			drives = append(drives, drive)
+		}
+		drive.Folders = append(drive.Folders, folder.ID)
+	}
+	return drives
+}
+
+// Watcher syncs the folders of a drive when it is plugged in and notifies the user once
+// the uploads to it finished. While a drive is away, syncs of its folders are skipped and
+// their uploads wait in the queue.
+type Watcher struct {
+	store    storage.Storage
+	drives   []*Drive
+	sync     func(ctx context.Context, folderID string) error
+	idle     func() bool
+	notify   func(title, message string)
+	interval time.Duration
+}
+
+// NewWatcher creates a watcher for drives of store. sync syncs a folder and idle reports
+// whether every queued upload finished.
+func NewWatcher(store storage.Storage, drives []*Drive, sync func(ctx context.Context, folderID string) error, idle func() bool) *Watcher {
+	w := &Watcher{
+		store:    store,
+		drives:   drives,
+		sync:     sync,
+		idle:     idle,
+		notify:   notify,
+		interval: DefaultInterval,
+	}
+	// Drives plugged in when the agent starts sync with the first full sync
+	for _, drive := range drives {
+		drive.present = storage.Present(store, drive.key) == nil
+	}
+	return w
+}
+
+// Run checks the drives periodically until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(w.interval):
+			w.Check(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Check looks for drives plugged in or unplugged since the last check, mirroring the
+// folders of those plugged in
+func (w *Watcher) Check(ctx context.Context) {
+	for _, drive := range w.drives {
+		err := storage.Present(w.store, drive.key)
+		present := err == nil
+		if present == drive.present {
+			continue
+		}
+		drive.present = present
+
+		if !present {
+			log.Warn().Err(err).Str("target", drive.Target).Msg("Drive unplugged, queuing changes until it is mounted again")
+			continue
+		}
+		if err := w.mirror(ctx, drive); err != nil && ctx.Err() == nil {
+			w.notify(i18n.Sprintf("Sync Manager"), i18n.Sprintf("Mirror to %s failed: %v", drive.Target, err))
+		}
+	}
+}
+
+// mirror syncs the folders of a drive that was plugged in and notifies the user once their
+// uploads finished
+func (w *Watcher) mirror(ctx context.Context, drive *Drive) error {
+	log.Info().Str("target", drive.Target).Strs("folders", drive.Folders).Msg("Drive mounted, mirroring folders")
+	var failed error
+	for _, folderID := range drive.Folders {
+		if err := w.sync(ctx, folderID); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Error().Err(err).Str("target", drive.Target).Str("folder_id", folderID).Msg("Failed to mirror folder to drive")
+			if failed == nil {
+				failed = err
+			}
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+
+	for !w.idle() {
+		select {
+		case <-time.After(idleInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := storage.Present(w.store, drive.key); err != nil {
+		return err
+	}
+
+	log.Info().Str("target", drive.Target).Msg("Mirror to drive complete")
+	w.notify(i18n.Sprintf("Sync Manager"), i18n.Sprintf("Mirror to %s complete, the drive can be unplugged", drive.Target))
+	return nil
+}
@@ -0,0 +1,89 @@
+package drives
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDrive is storage on a drive plugged in and out by the test
+type fakeDrive struct {
+	*storage.MemoryStorage
+	mounted bool
+}
+
+func (d *fakeDrive) Present() error {
+	if !d.mounted {
+		return storage.ErrDriveAbsent
+	}
+	return nil
+}
+
+func TestDrives(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Targets = append(cfg.Targets,
+		config.StorageTarget{Name: "usb", Type: config.TargetLocal, Local: config.LocalConfig{RootDir: "/media/usb", Removable: true}},
+		config.StorageTarget{Name: "nas", Type: config.TargetLocal, Local: config.LocalConfig{RootDir: "/mnt/nas"}},
+	)
+	cfg.SyncFolders = []config.SyncFolder{
+		{ID: "photos", Target: "usb", RemotePrefix: "pictures"},
+		{ID: "music", Target: "nas"},
+		{ID: "docs", Target: "usb"},
+		{ID: "notes"},
+	}
+
+	// Only targets on removable drives with folders are watched
+	drives := Drives(cfg)
+	if assert.Len(t, drives, 1) {
+		assert.Equal(t, "usb", drives[0].Target)
+		assert.Equal(t, []string{"photos", "docs"}, drives[0].Folders)
+		assert.Equal(t, "pictures/", drives[0].key)
+	}
+
+	// A volume UUID alone makes a target removable
+	cfg.Targets[2].Local.VolumeUUID = "0f3a-55c1"
+	assert.Len(t, Drives(cfg), 2)
+}
+
+func TestWatcherMirrorsPluggedDrives(t *testing.T) {
+	ctx := context.Background()
+	drive := &fakeDrive{MemoryStorage: storage.NewMemoryStorage(&storage.MemoryConfig{})}
+	var synced, notes []string
+	var syncErr error
+	idle := 0
+	watcher := NewWatcher(drive, []*Drive{{Target: "usb", Folders: []string{"docs", "photos"}, key: "docs/"}},
+		func(ctx context.Context, folderID string) error {
+			synced = append(synced, folderID)
+			return syncErr
+		},
+		func() bool {
+			idle++
+			return true
+		})
+	watcher.notify = func(title, message string) { notes = append(notes, message) }
+
+	// Nothing happens until the drive is plugged in
+	watcher.Check(ctx)
+	assert.Empty(t, synced)
+
+	// Plugging it in mirrors its folders and tells once the uploads finished
+	drive.mounted = true
+	watcher.Check(ctx)
+	watcher.Check(ctx)
+	assert.Equal(t, []string{"docs", "photos"}, synced)
+	assert.Equal(t, 1, idle)
+	assert.Equal(t, []string{"Mirror to usb complete, the drive can be unplugged"}, notes)
+
+	// Unplugging it is only logged, and a failed mirror is reported
+	drive.mounted = false
+	watcher.Check(ctx)
+	syncErr = errors.New("disk full")
+	drive.mounted = true
+	watcher.Check(ctx)
+	assert.Equal(t, []string{"docs", "photos", "docs", "photos"}, synced)
+	assert.Equal(t, []string{"Mirror to usb complete, the drive can be unplugged", "Mirror to usb failed: disk full"}, notes)
+}
@@ -0,0 +1,18 @@
+//go:build darwin
+
+package drives
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/rs/zerolog/log"
+)
+
+// notify shows a notification through osascript
+func notify(title, message string) {
+	script := fmt.Sprintf("display notification %q with title %q", message, title)
+	if err := exec.Command("osascript", "-e", script).Run(); err != nil {
+		log.Debug().Err(err).Msg("Failed to show desktop notification")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux
+
+package drives
+
+import (
+	"os/exec"
+
+	"github.com/rs/zerolog/log"
+)
+
+// notify shows a desktop notification through notify-send
+func notify(title, message string) {
+	if err := exec.Command("notify-send", "--app-name=sync-manager", title, message).Run(); err != nil {
+		log.Debug().Err(err).Msg("Failed to show desktop notification")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package drives
+
+import "github.com/rs/zerolog/log"
+
+// notify logs the notification, as there is no desktop notification tool to call
+func notify(title, message string) {
+	log.Info().Str("title", title).Msg(message)
+}
@@ -0,0 +1,104 @@
+// Package hooks runs the shell commands folders configure around their syncs, such as a
+// database dump written into the folder before it syncs or a notification once it is done
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// outputLimit is how much of the output of a hook is kept for the log and its error
+const outputLimit = 4 << 10
+
+// Hook is a command run in the shell: sh on Unix systems, cmd on Windows
+type Hook struct {
+	Name    string        // Which hook runs, such as pre_sync, for logs and errors
+	Command string        // Shell command line
+	Dir     string        // Working directory, the agent's own when empty
+	Env     []string      // KEY=value pairs added to the agent's environment
+	Timeout time.Duration // Time the command gets before it is killed, none when zero
+}
+
+// Run runs a hook and waits for it. A command that exits with a non-zero status, or does not
+// finish in time, fails with the last lines of its output.
+func Run(ctx context.Context, hook Hook) error {
+	if hook.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hook.Timeout)
+		defer cancel()
+	}
+
+	cmd := shell(ctx, hook.Command)
+	cmd.Dir = hook.Dir
+	cmd.Env = append(os.Environ(), hook.Env...)
+	output := &tail{limit: outputLimit}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Children the command started must not keep the hook waiting on its output once killed
+	cmd.WaitDelay = time.Second
+
+	started := time.Now()
+	err := cmd.Run()
+	logged := log.Debug()
+	if err != nil {
+		logged = log.Warn()
+	}
+	logged.Str("hook", hook.Name).
+		Dur("duration", time.Since(started)).
+		Str("output", output.String()).
+		Err(err).
+		Msg("Ran hook")
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s hook timed out after %s%s", hook.Name, hook.Timeout, lastLine(output.String()))
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook failed: %w%s", hook.Name, err, lastLine(output.String()))
+	}
+	return nil
+}
+
+// shell returns the command running line in the shell of the system
+func shell(ctx context.Context, line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", line)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", line)
+}
+
+// lastLine formats the last non-empty line of output for an error, empty when there is none
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if last == "" {
+		return ""
+	}
+	return ": " + last
+}
+
+// tail keeps the last limit bytes written to it
+type tail struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.buf.Write(p)
+	if extra := t.buf.Len() - t.limit; extra > 0 {
+		t.buf.Next(extra)
+	}
+	return len(p), nil
+}
+
+func (t *tail) String() string {
+	return t.buf.String()
+}
@@ -0,0 +1,47 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are written for sh")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// The command runs in dir with the variables of the run
+	err := Run(ctx, Hook{Name: "pre_sync", Command: `echo "$SYNC_MANAGER_FOLDER_ID" > dump.txt`, Dir: dir, Env: []string{"SYNC_MANAGER_FOLDER_ID=docs"}})
+	assert.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "dump.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "docs\n", string(data))
+
+	// A failure carries the last line of the output
+	err = Run(ctx, Hook{Name: "pre_sync", Command: "echo starting; echo 'connection refused' >&2; exit 3"})
+	assert.ErrorContains(t, err, "pre_sync hook failed: exit status 3: connection refused")
+
+	// A command still running at the timeout is killed
+	started := time.Now()
+	err = Run(ctx, Hook{Name: "post_sync", Command: "sleep 10", Timeout: 100 * time.Millisecond})
+	assert.ErrorContains(t, err, "post_sync hook timed out after 100ms")
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
+
+func TestTailKeepsLastBytes(t *testing.T) {
+	output := &tail{limit: 8}
+	output.Write([]byte("first line\n"))
+	output.Write([]byte("last\n"))
+	assert.Equal(t, "ne\nlast\n", output.String())
+	assert.Equal(t, ": last", lastLine(output.String()))
+	assert.Empty(t, lastLine(strings.Repeat(" ", 3)))
+}
@@ -0,0 +1,290 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/stats"
+)
+
+// MetadataVersionVector is the object metadata key holding the encoded version vector
+const MetadataVersionVector = "version_vector"
+
+// MetadataDeviceID is the object metadata key holding the device that wrote the object
+const MetadataDeviceID = "device_id"
+
+// Entry is the last known state of a single file within a synced folder
+type Entry struct {
+	Path       string        `json:"path"`
+	LocalPath  string        `json:"local_path,omitempty"`
+	Size       int64         `json:"size"`
+	ModTime    time.Time     `json:"mod_time"`
+	Hash       string        `json:"hash,omitempty"`
+	Version    VersionVector `json:"version"`
+	RemoteETag string        `json:"remote_etag,omitempty"`
+	RemoteSize int64         `json:"remote_size,omitempty"` // Size of the copy last uploaded or downloaded
+	RemoteHash string        `json:"remote_hash,omitempty"` // Hash of that copy, tagged with its algorithm as by checksum.Algorithm.Tag
+	Pending    bool          `json:"pending,omitempty"`
+	Deleted    bool          `json:"deleted,omitempty"`
+	Dir        bool          `json:"dir,omitempty"`
+	Mode       os.FileMode   `json:"mode,omitempty"`     // Permission bits, recorded for directories
+	Archived   bool          `json:"archived,omitempty"` // Replaced on disk by a placeholder, the content is only remote
+	Online     bool          `json:"online,omitempty"`   // Skipped as a cloud placeholder, uploaded once its content is on disk
+}
+
+// LocalRelPath returns the path of the file on the local filesystem, relative to the folder root
+func (e Entry) LocalRelPath() string {
+	if e.LocalPath != "" {
+		return e.LocalPath
+	}
+	return e.Path
+}
+
+// Index is the on-disk manifest of a synced folder
+type Index struct {
+	FolderID string            `json:"folder_id"`
+	Entries  map[string]*Entry `json:"entries"`
+
+	// Rollup aggregates the files of Entries, kept up to date as they change
+	Rollup stats.FolderSummary `json:"summary"`
+
+	filePath string
+	mu       sync.RWMutex
+}
+
+// DefaultDir returns the default directory where folder indexes are stored
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "index"), nil
+}
+
+// Load reads the index for a folder from dir, returning an empty index if none exists yet
+func Load(dir, folderID string) (*Index, error) {
+	filePath := filepath.Join(dir, folderID+".json")
+
+	idx := &Index{
+		FolderID: folderID,
+		Entries:  make(map[string]*Entry),
+		filePath: filePath,
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse index: %w", err)
+	}
+	if idx.Entries == nil {
+		idx.Entries = make(map[string]*Entry)
+	}
+	idx.filePath = filePath
+	idx.mergeSplitEntries()
+	idx.rebuildSummary()
+
+	return idx, nil
+}
+
+// Save writes the index back to disk atomically
+func (i *Index) Save() error {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if i.filePath == "" {
+		return fmt.Errorf("index file path not set")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+
+	tempFile := i.filePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	if err := os.Rename(tempFile, i.filePath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace index: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a copy of the entry for a relative path
+func (i *Index) Get(path string) (Entry, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	entry, ok := i.Entries[path]
+	if !ok {
+		return Entry{}, false
+	}
+
+	entryCopy := *entry
+	entryCopy.Version = entry.Version.Copy()
+	return entryCopy, true
+}
+
+// Put stores or replaces the entry for its path
+func (i *Index) Put(entry Entry) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if entry.Version == nil {
+		entry.Version = VersionVector{}
+	}
+	previous := i.Entries[entry.Path]
+	i.Entries[entry.Path] = &entry
+	i.account(previous, &entry)
+}
+
+// Remove deletes the entry for a relative path
+func (i *Index) Remove(path string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	previous := i.Entries[path]
+	delete(i.Entries, path)
+	i.account(previous, nil)
+}
+
+// Paths returns all relative paths tracked by the index
+func (i *Index) Paths() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	paths := make([]string, 0, len(i.Entries))
+	for path := range i.Entries {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// PendingFiles returns how many files have a version that has not reached the remote yet
+func (i *Index) PendingFiles() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	n := 0
+	for _, entry := range i.Entries {
+		if entry.Pending && !entry.Deleted && !entry.Dir {
+			n++
+		}
+	}
+	return n
+}
+
+// RecordLocalChange bumps the version of path for deviceID if the file differs from the index.
+// localPath is the name found on disk, which may use a different Unicode normalization than path.
+// It returns the resulting entry and whether a change was recorded.
+func (i *Index) RecordLocalChange(deviceID, path, localPath string, size int64, modTime time.Time) (Entry, bool) {
+	return i.recordLocal(deviceID, Entry{Path: path, LocalPath: localPath, Size: size, ModTime: modTime})
+}
+
+// Touch records a new modification time for the file at path whose content is still the
+// copy last synced, keeping its version, so it is not taken as changed again
+func (i *Index) Touch(path, localPath string, modTime time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	entry, ok := i.Entries[path]
+	if !ok {
+		return
+	}
+	if localPath == path {
+		localPath = ""
+	}
+	entry.LocalPath = localPath
+	entry.ModTime = modTime
+}
+
+// RecordLocalDir bumps the version of the directory at path for deviceID if its metadata differs from the index
+func (i *Index) RecordLocalDir(deviceID, path, localPath string, mode os.FileMode, modTime time.Time) (Entry, bool) {
+	return i.recordLocal(deviceID, Entry{Path: path, LocalPath: localPath, ModTime: modTime, Dir: true, Mode: mode.Perm()})
+}
+
+// recordLocal bumps the version of an entry found on disk unless the index already has it
+func (i *Index) recordLocal(deviceID string, found Entry) (Entry, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if found.LocalPath == found.Path {
+		found.LocalPath = ""
+	}
+
+	entry, exists := i.Entries[found.Path]
+	if exists && !entry.Deleted && entry.Dir == found.Dir && entry.Size == found.Size && entry.Mode == found.Mode && entry.ModTime.Equal(found.ModTime) {
+		entry.LocalPath = found.LocalPath
+		entryCopy := *entry
+		entryCopy.Version = entry.Version.Copy()
+		return entryCopy, false
+	}
+
+	var version VersionVector
+	if exists {
+		version = entry.Version
+	}
+
+	updated := &found
+	updated.Version = version.Increment(deviceID)
+	updated.Pending = true
+	if exists {
+		updated.RemoteETag = entry.RemoteETag
+		updated.RemoteSize = entry.RemoteSize
+		updated.RemoteHash = entry.RemoteHash
+	}
+	i.Entries[found.Path] = updated
+	i.account(entry, updated)
+
+	entryCopy := *updated
+	entryCopy.Version = updated.Version.Copy()
+	return entryCopy, true
+}
+
+// mergeSplitEntries folds entries recorded under a non-canonical Unicode form into their canonical key
+func (i *Index) mergeSplitEntries() {
+	for path, entry := range i.Entries {
+		key := NormalizeKey(path)
+		if key == path {
+			continue
+		}
+
+		delete(i.Entries, path)
+		if entry.LocalPath == "" {
+			entry.LocalPath = path
+		}
+		entry.Path = key
+
+		existing, ok := i.Entries[key]
+		if !ok {
+			i.Entries[key] = entry
+			continue
+		}
+
+		// Keep the most recently modified copy but remember every device's changes
+		merged := existing
+		if entry.ModTime.After(existing.ModTime) {
+			merged = entry
+		}
+		merged.Version = existing.Version.Merge(entry.Version)
+		merged.Pending = true
+		i.Entries[key] = merged
+	}
+}
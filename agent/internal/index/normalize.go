@@ -0,0 +1,31 @@
+package index
+
+import (
+	"runtime"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeKey converts a relative path to the canonical (NFC) form used for storage keys and index entries
+func NormalizeKey(path string) string {
+	return norm.NFC.String(path)
+}
+
+// IsNormalized reports whether path is already in canonical form
+func IsNormalized(path string) bool {
+	return norm.NFC.IsNormalString(path)
+}
+
+// LocalForm converts a canonical path to the form preferred by the local filesystem.
+// macOS (HFS+ and most tooling on APFS) expects decomposed names; everything else uses NFC.
+func LocalForm(path string) string {
+	return localFormFor(runtime.GOOS, path)
+}
+
+// localFormFor returns the preferred path form for the given operating system
+func localFormFor(goos, path string) string {
+	if goos == "darwin" {
+		return norm.NFD.String(path)
+	}
+	return norm.NFC.String(path)
+}
@@ -0,0 +1,41 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeKey(t *testing.T) {
+	nfc := "café/résumé.txt"
+	nfd := "cafe\u0301/re\u0301sume\u0301.txt"
+
+	assert.Equal(t, nfc, NormalizeKey(nfd))
+	assert.Equal(t, nfc, NormalizeKey(nfc))
+	assert.True(t, IsNormalized(nfc))
+	assert.False(t, IsNormalized(nfd))
+
+	assert.Equal(t, nfd, localFormFor("darwin", nfc))
+	assert.Equal(t, nfc, localFormFor("linux", nfd))
+}
+
+func TestLoadMergesSplitEntries(t *testing.T) {
+	dir := t.TempDir()
+	nfc := "café.txt"
+	nfd := "cafe\u0301.txt"
+
+	idx, err := Load(dir, "docs")
+	assert.NoError(t, err)
+	idx.Put(Entry{Path: nfc, Version: VersionVector{"linux": 2}})
+	idx.Put(Entry{Path: nfd, Version: VersionVector{"mac": 1}})
+	assert.NoError(t, idx.Save())
+
+	reloaded, err := Load(dir, "docs")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{nfc}, reloaded.Paths())
+
+	entry, ok := reloaded.Get(nfc)
+	assert.True(t, ok)
+	assert.True(t, entry.Pending)
+	assert.Equal(t, VersionVector{"linux": 2, "mac": 1}, entry.Version)
+}
@@ -0,0 +1,120 @@
+package index
+
+import (
+	"sort"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/stats"
+)
+
+// Summary returns the file count, total size, largest files and last change of the folder
+func (i *Index) Summary() stats.FolderSummary {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.Rollup.Copy()
+}
+
+// counted reports whether an entry is a file present in the folder
+func counted(e *Entry) bool {
+	return e != nil && !e.Deleted && !e.Dir
+}
+
+// archived reports whether an entry is a file of the folder whose local copy was archived
+func archived(e *Entry) bool {
+	return counted(e) && e.Archived
+}
+
+// account moves the summary from previous to updated, either of which may be nil, once an
+// entry was added, replaced or removed. A file leaving a full list of the largest files is
+// replaced by going over the entries again. mu must be held.
+func (i *Index) account(previous, updated *Entry) {
+	if archived(previous) {
+		i.Rollup.Archived--
+		i.Rollup.ArchivedBytes -= previous.Size
+	}
+	if archived(updated) {
+		i.Rollup.Archived++
+		i.Rollup.ArchivedBytes += updated.Size
+	}
+
+	wasCounted, isCounted := counted(previous), counted(updated)
+	if !wasCounted && !isCounted {
+		return
+	}
+	if wasCounted && isCounted && previous.Size == updated.Size && previous.ModTime.Equal(updated.ModTime) {
+		return
+	}
+
+	rescan := false
+	if wasCounted {
+		i.Rollup.Files--
+		i.Rollup.Bytes -= previous.Size
+		for n, file := range i.Rollup.Largest {
+			if file.Path == previous.Path {
+				rescan = len(i.Rollup.Largest) == stats.TopFiles
+				i.Rollup.Largest = append(i.Rollup.Largest[:n], i.Rollup.Largest[n+1:]...)
+				break
+			}
+		}
+	}
+	if isCounted {
+		i.Rollup.Files++
+		i.Rollup.Bytes += updated.Size
+	}
+	i.Rollup.LastChange = time.Now()
+
+	if rescan {
+		i.Rollup.Largest = i.largest()
+	} else if isCounted {
+		i.Rollup.Largest = insertLargest(i.Rollup.Largest, stats.FileSize{Path: updated.Path, Size: updated.Size})
+	}
+}
+
+// rebuildSummary computes the summary from every entry, for an index read from disk. mu must be held.
+func (i *Index) rebuildSummary() {
+	lastChange := i.Rollup.LastChange
+	i.Rollup = stats.FolderSummary{LastChange: lastChange}
+	for _, entry := range i.Entries {
+		if counted(entry) {
+			i.Rollup.Files++
+			i.Rollup.Bytes += entry.Size
+		}
+		if archived(entry) {
+			i.Rollup.Archived++
+			i.Rollup.ArchivedBytes += entry.Size
+		}
+	}
+	i.Rollup.Largest = i.largest()
+}
+
+// largest returns the largest files of the index, largest first. mu must be held.
+func (i *Index) largest() []stats.FileSize {
+	var files []stats.FileSize
+	for _, entry := range i.Entries {
+		if counted(entry) {
+			files = insertLargest(files, stats.FileSize{Path: entry.Path, Size: entry.Size})
+		}
+	}
+	return files
+}
+
+// insertLargest adds file to a list of the largest files, largest first and ties by path,
+// keeping at most stats.TopFiles of them
+func insertLargest(files []stats.FileSize, file stats.FileSize) []stats.FileSize {
+	at := sort.Search(len(files), func(n int) bool {
+		if files[n].Size != file.Size {
+			return files[n].Size < file.Size
+		}
+		return files[n].Path > file.Path
+	})
+	if at >= stats.TopFiles {
+		return files
+	}
+	files = append(files, stats.FileSize{})
+	copy(files[at+1:], files[at:])
+	files[at] = file
+	if len(files) > stats.TopFiles {
+		files = files[:stats.TopFiles]
+	}
+	return files
+}
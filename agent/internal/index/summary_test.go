@@ -0,0 +1,87 @@
+package index
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummaryFollowsEntries(t *testing.T) {
+	dir := t.TempDir()
+	idx, err := Load(dir, "docs")
+	assert.NoError(t, err)
+	modTime := time.Now()
+
+	// More files than the list of the largest holds
+	for n := 1; n <= stats.TopFiles+2; n++ {
+		idx.RecordLocalChange("desktop", fmt.Sprintf("file-%02d.bin", n), "", int64(n*100), modTime)
+	}
+	idx.RecordLocalDir("desktop", "photos", "", 0755, modTime)
+	summary := idx.Summary()
+	assert.Equal(t, int64(stats.TopFiles+2), summary.Files)
+	assert.Equal(t, int64(7800), summary.Bytes)
+	assert.Len(t, summary.Largest, stats.TopFiles)
+	assert.Equal(t, stats.FileSize{Path: "file-12.bin", Size: 1200}, summary.Largest[0])
+	assert.Equal(t, stats.FileSize{Path: "file-03.bin", Size: 300}, summary.Largest[stats.TopFiles-1])
+	assert.False(t, summary.LastChange.IsZero())
+
+	// Removing one of the largest brings the next one into the list
+	idx.Remove("file-12.bin")
+	summary = idx.Summary()
+	assert.Equal(t, int64(stats.TopFiles+1), summary.Files)
+	assert.Equal(t, "file-11.bin", summary.Largest[0].Path)
+	assert.Equal(t, "file-02.bin", summary.Largest[stats.TopFiles-1].Path)
+
+	// A file that grows moves up; one marked deleted stops counting
+	idx.RecordLocalChange("desktop", "file-01.bin", "", 5000, modTime.Add(time.Second))
+	entry, _ := idx.Get("file-05.bin")
+	entry.Deleted = true
+	idx.Put(entry)
+	summary = idx.Summary()
+	assert.Equal(t, int64(stats.TopFiles), summary.Files)
+	assert.Equal(t, int64(7800-1200+4900-500), summary.Bytes)
+	assert.Equal(t, stats.FileSize{Path: "file-01.bin", Size: 5000}, summary.Largest[0])
+	for _, file := range summary.Largest {
+		assert.NotEqual(t, "file-05.bin", file.Path)
+	}
+
+	// Reading the index back gives the same figures
+	assert.NoError(t, idx.Save())
+	loaded, err := Load(dir, "docs")
+	assert.NoError(t, err)
+	assert.Equal(t, summary.Files, loaded.Summary().Files)
+	assert.Equal(t, summary.Bytes, loaded.Summary().Bytes)
+	assert.Equal(t, summary.Largest, loaded.Summary().Largest)
+	assert.True(t, summary.LastChange.Equal(loaded.Summary().LastChange))
+}
+
+func TestSummaryCountsArchivedFiles(t *testing.T) {
+	dir := t.TempDir()
+	idx, err := Load(dir, "docs")
+	assert.NoError(t, err)
+	modTime := time.Now()
+	idx.RecordLocalChange("desktop", "video.mp4", "", 4000, modTime)
+	idx.RecordLocalChange("desktop", "notes.txt", "", 100, modTime)
+
+	// Archived files still belong to the folder, and count as freed space
+	entry, _ := idx.Get("video.mp4")
+	entry.Archived = true
+	idx.Put(entry)
+	summary := idx.Summary()
+	assert.Equal(t, int64(2), summary.Files)
+	assert.Equal(t, int64(1), summary.Archived)
+	assert.Equal(t, int64(4000), summary.ArchivedBytes)
+
+	assert.NoError(t, idx.Save())
+	loaded, err := Load(dir, "docs")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4000), loaded.Summary().ArchivedBytes)
+
+	// A change to the file brings it back on disk
+	idx.RecordLocalChange("desktop", "video.mp4", "", 4500, modTime.Add(time.Second))
+	assert.Zero(t, idx.Summary().Archived)
+	assert.Zero(t, idx.Summary().ArchivedBytes)
+}
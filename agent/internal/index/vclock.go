@@ -0,0 +1,151 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Ordering describes how two version vectors relate to each other
+type Ordering int
+
+const (
+	// Equal means both vectors describe the same version
+	Equal Ordering = iota
+	// Before means the first vector is an ancestor of the second
+	Before
+	// After means the first vector descends from the second
+	After
+	// Concurrent means neither vector descends from the other (a conflict)
+	Concurrent
+)
+
+// String returns a readable name for the ordering
+func (o Ordering) String() string {
+	switch o {
+	case Equal:
+		return "equal"
+	case Before:
+		return "before"
+	case After:
+		return "after"
+	case Concurrent:
+		return "concurrent"
+	default:
+		return "unknown"
+	}
+}
+
+// VersionVector maps a device ID to the number of changes that device has made to a file
+type VersionVector map[string]uint64
+
+// Copy returns an independent copy of the vector
+func (v VersionVector) Copy() VersionVector {
+	c := make(VersionVector, len(v))
+	for device, counter := range v {
+		c[device] = counter
+	}
+	return c
+}
+
+// Increment returns a copy of the vector with the counter for deviceID bumped by one
+func (v VersionVector) Increment(deviceID string) VersionVector {
+	c := v.Copy()
+	c[deviceID]++
+	return c
+}
+
+// Merge returns the element-wise maximum of both vectors
+func (v VersionVector) Merge(other VersionVector) VersionVector {
+	c := v.Copy()
+	for device, counter := range other {
+		if counter > c[device] {
+			c[device] = counter
+		}
+	}
+	return c
+}
+
+// Compare determines the causal relationship between v and other
+func (v VersionVector) Compare(other VersionVector) Ordering {
+	vGreater := false
+	otherGreater := false
+
+	for device, counter := range v {
+		if counter > other[device] {
+			vGreater = true
+		} else if counter < other[device] {
+			otherGreater = true
+		}
+	}
+	for device, counter := range other {
+		if _, ok := v[device]; !ok && counter > 0 {
+			otherGreater = true
+		}
+	}
+
+	switch {
+	case vGreater && otherGreater:
+		return Concurrent
+	case vGreater:
+		return After
+	case otherGreater:
+		return Before
+	default:
+		return Equal
+	}
+}
+
+// IsEmpty reports whether the vector has no recorded changes
+func (v VersionVector) IsEmpty() bool {
+	for _, counter := range v {
+		if counter > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode serializes the vector for storage in object metadata
+func (v VersionVector) Encode() string {
+	if len(v) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// String returns a stable, human-readable representation of the vector
+func (v VersionVector) String() string {
+	devices := make([]string, 0, len(v))
+	for device := range v {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+
+	parts := make([]string, 0, len(devices))
+	for _, device := range devices {
+		parts = append(parts, fmt.Sprintf("%s:%d", device, v[device]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// DecodeVersionVector parses a vector previously produced by Encode
+func DecodeVersionVector(s string) (VersionVector, error) {
+	if s == "" {
+		return VersionVector{}, nil
+	}
+
+	var v VersionVector
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("invalid version vector: %w", err)
+	}
+	if v == nil {
+		v = VersionVector{}
+	}
+	return v, nil
+}
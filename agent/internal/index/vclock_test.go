@@ -0,0 +1,77 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionVectorCompare(t *testing.T) {
+	a := VersionVector{"laptop": 2, "desktop": 1}
+
+	assert.Equal(t, Equal, a.Compare(VersionVector{"laptop": 2, "desktop": 1}))
+	assert.Equal(t, After, a.Compare(VersionVector{"laptop": 1, "desktop": 1}))
+	assert.Equal(t, Before, a.Compare(a.Increment("desktop")))
+	assert.Equal(t, After, a.Compare(VersionVector{}))
+	assert.Equal(t, Concurrent, a.Increment("laptop").Compare(a.Increment("desktop")))
+}
+
+func TestVersionVectorMerge(t *testing.T) {
+	a := VersionVector{"laptop": 3, "desktop": 1}
+	b := VersionVector{"desktop": 2, "phone": 1}
+
+	merged := a.Merge(b)
+
+	assert.Equal(t, VersionVector{"laptop": 3, "desktop": 2, "phone": 1}, merged)
+	assert.Equal(t, After, merged.Compare(a))
+	assert.Equal(t, After, merged.Compare(b))
+	// Merge must not modify the receiver
+	assert.Equal(t, uint64(1), a["desktop"])
+}
+
+func TestVersionVectorEncodeDecode(t *testing.T) {
+	v := VersionVector{"laptop": 3, "desktop": 1}
+
+	decoded, err := DecodeVersionVector(v.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, v, decoded)
+
+	empty, err := DecodeVersionVector("")
+	assert.NoError(t, err)
+	assert.True(t, empty.IsEmpty())
+
+	_, err = DecodeVersionVector("not-json")
+	assert.Error(t, err)
+
+	assert.Equal(t, "{desktop:1, laptop:3}", v.String())
+}
+
+func TestIndexRecordLocalChange(t *testing.T) {
+	dir := t.TempDir()
+	idx, err := Load(dir, "docs")
+	assert.NoError(t, err)
+
+	modTime := time.Now().Truncate(time.Second)
+
+	entry, changed := idx.RecordLocalChange("laptop", "a.txt", "a.txt", 10, modTime)
+	assert.True(t, changed)
+	assert.True(t, entry.Pending)
+	assert.Equal(t, VersionVector{"laptop": 1}, entry.Version)
+
+	_, changed = idx.RecordLocalChange("laptop", "a.txt", "a.txt", 10, modTime)
+	assert.False(t, changed)
+
+	entry, changed = idx.RecordLocalChange("laptop", "a.txt", "a.txt", 12, modTime)
+	assert.True(t, changed)
+	assert.Equal(t, VersionVector{"laptop": 2}, entry.Version)
+
+	assert.NoError(t, idx.Save())
+
+	reloaded, err := Load(dir, "docs")
+	assert.NoError(t, err)
+	saved, ok := reloaded.Get("a.txt")
+	assert.True(t, ok)
+	assert.Equal(t, VersionVector{"laptop": 2}, saved.Version)
+	assert.Equal(t, int64(12), saved.Size)
+}
@@ -0,0 +1,105 @@
+package inuse
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// accessModes masks the access mode in the open flags of a file descriptor
+const accessModes = 03
+
+// Detector returns the files other processes hold open for writing, by absolute path
+type Detector func() (map[string]bool, error)
+
+// procOpenForWrite reads the file descriptors of every process under a procfs root and
+// returns the regular files opened write-only or read-write. Processes that cannot be
+// read, such as those of other users, are skipped.
+func procOpenForWrite(root string, self int) (map[string]bool, error) {
+	processes, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]bool)
+	for _, process := range processes {
+		pid, err := strconv.Atoi(process.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		fdDir := filepath.Join(root, process.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !filepath.IsAbs(target) {
+				// Sockets, pipes and anonymous files are not paths
+				continue
+			}
+			if writable(filepath.Join(root, process.Name(), "fdinfo", fd.Name())) {
+				files[target] = true
+			}
+		}
+	}
+	return files, nil
+}
+
+// writable reads the flags line of an fdinfo file, written in octal
+func writable(fdinfo string) bool {
+	data, err := os.ReadFile(fdinfo)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		value, ok := strings.CutPrefix(line, "flags:")
+		if !ok {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 64)
+		if err != nil {
+			return false
+		}
+		mode := flags & accessModes
+		return mode == uint64(os.O_WRONLY) || mode == uint64(os.O_RDWR)
+	}
+	return false
+}
+
+// Writers answers whether files are open for writing from a single snapshot, taken the
+// first time it is asked so a whole scan costs one detection at most
+type Writers struct {
+	detect Detector
+	once   sync.Once
+	files  map[string]bool
+}
+
+// NewWriters returns Writers taking its snapshot with detect
+func NewWriters(detect Detector) *Writers {
+	return &Writers{detect: detect}
+}
+
+// Has reports whether path is open for writing by another process. Paths are compared
+// once resolved, since the kernel reports the real location of each file.
+func (w *Writers) Has(path string) bool {
+	w.once.Do(func() {
+		if w.detect == nil {
+			return
+		}
+		// Without a snapshot only the size and modification time checks remain
+		w.files, _ = w.detect()
+	})
+	if len(w.files) == 0 {
+		return false
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return w.files[path]
+}
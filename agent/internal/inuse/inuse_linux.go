@@ -0,0 +1,10 @@
+//go:build linux
+
+package inuse
+
+import "os"
+
+// OpenForWrite lists the files open for writing from /proc
+func OpenForWrite() (map[string]bool, error) {
+	return procOpenForWrite("/proc", os.Getpid())
+}
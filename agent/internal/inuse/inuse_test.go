@@ -0,0 +1,49 @@
+package inuse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeFD adds a file descriptor to a fake procfs tree
+func fakeFD(t *testing.T, root, pid, fd, target, flags string) {
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, pid, "fd"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, pid, "fdinfo"), 0755))
+	assert.NoError(t, os.Symlink(target, filepath.Join(root, pid, "fd", fd)))
+	info := "pos:\t0\nflags:\t" + flags + "\nmnt_id:\t25\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(root, pid, "fdinfo", fd), []byte(info), 0644))
+}
+
+func TestProcOpenForWrite(t *testing.T) {
+	root := t.TempDir()
+	fakeFD(t, root, "100", "3", "/home/ana/report.docx", "0100002") // read-write
+	fakeFD(t, root, "100", "4", "/home/ana/notes.txt", "0100000")   // read-only
+	fakeFD(t, root, "200", "1", "/home/ana/video.mp4", "02101001")  // write-only, append
+	fakeFD(t, root, "200", "5", "socket:[12345]", "02")
+	fakeFD(t, root, "300", "3", "/home/ana/own.log", "01")
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "sys"), 0755))
+
+	files, err := procOpenForWrite(root, 300)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"/home/ana/report.docx": true, "/home/ana/video.mp4": true}, files)
+}
+
+func TestWritersDetectOnce(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.docx")
+	assert.NoError(t, os.WriteFile(path, []byte("draft"), 0644))
+	resolved, err := filepath.EvalSymlinks(path)
+	assert.NoError(t, err)
+
+	calls := 0
+	writers := NewWriters(func() (map[string]bool, error) {
+		calls++
+		return map[string]bool{resolved: true}, nil
+	})
+	assert.True(t, writers.Has(path))
+	assert.False(t, writers.Has(filepath.Join(dir, "other.txt")))
+	assert.Equal(t, 1, calls)
+}
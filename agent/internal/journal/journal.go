@@ -0,0 +1,209 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of journaled operations
+const (
+	Upload   = "upload"   // A local file queued for upload to Key
+	Download = "download" // A remote file being written to the temporary file at Path
+	Delete   = "delete"   // A remote file to remove, copied under Trash first when set
+)
+
+// Op is an operation that changes a file on one side of a folder
+type Op struct {
+	Kind      string    `json:"kind"`
+	FolderID  string    `json:"folder_id"`
+	Key       string    `json:"key"`
+	Path      string    `json:"path,omitempty"`  // Local file uploaded, or temporary file downloaded to
+	Trash     string    `json:"trash,omitempty"` // Key the deleted file is copied to first
+	StartedAt time.Time `json:"started_at"`
+}
+
+// id identifies an operation: a newer one of the same kind on the same key replaces it
+func (op Op) id() string {
+	return op.Kind + " " + op.Key
+}
+
+// record is a line of the journal file
+type record struct {
+	Done bool `json:"done,omitempty"` // The operation finished; only Kind and Key are set
+	Op
+}
+
+// Journal is a write-ahead log of the operations in progress. Each one is written before it
+// starts and marked done once it ends, so after a crash the agent knows which were cut short.
+// Methods on a nil Journal do nothing, for managers running without one.
+type Journal struct {
+	path string
+	file *os.File
+	open map[string]Op
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the default location of the journal
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "journal.log"), nil
+}
+
+// Open reads the journal at path and returns it along with the operations a previous run left
+// unfinished, oldest first. Those stay in the journal until marked done, so a crash during
+// recovery finds them again. A line cut short by a crash is ignored.
+func Open(path string) (*Journal, []Op, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	open := make(map[string]Op)
+	if file, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var rec record
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+				continue
+			}
+			if rec.Done {
+				delete(open, rec.id())
+			} else {
+				open[rec.id()] = rec.Op
+			}
+		}
+		file.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	j := &Journal{path: path, open: open}
+	if err := j.compact(); err != nil {
+		return nil, nil, err
+	}
+
+	pending := make([]Op, 0, len(open))
+	for _, op := range open {
+		pending = append(pending, op)
+	}
+	sort.Slice(pending, func(a, b int) bool { return pending[a].StartedAt.Before(pending[b].StartedAt) })
+	return j, pending, nil
+}
+
+// Begin records an operation before it starts
+func (j *Journal) Begin(op Op) error {
+	if j == nil {
+		return nil
+	}
+	if op.StartedAt.IsZero() {
+		op.StartedAt = time.Now()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.open[op.id()] = op
+	return j.append(record{Op: op})
+}
+
+// Done records that an operation of kind on key finished, whether it succeeded or not
+func (j *Journal) Done(kind, key string) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	op := Op{Kind: kind, Key: key}
+	if _, ok := j.open[op.id()]; !ok {
+		return nil
+	}
+	delete(j.open, op.id())
+
+	// Start the file over once nothing is in progress, so it does not grow forever
+	if len(j.open) == 0 {
+		return j.compact()
+	}
+	return j.append(record{Done: true, Op: op})
+}
+
+// Close closes the journal file, keeping the operations still in progress for the next run
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// append writes a record and flushes it to disk before the operation goes ahead. mu must be held.
+func (j *Journal) append(rec record) error {
+	if j.file == nil {
+		file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open journal: %w", err)
+		}
+		j.file = file
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to flush journal: %w", err)
+	}
+	return nil
+}
+
+// compact rewrites the journal with only the operations in progress. mu must be held.
+func (j *Journal) compact() error {
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+
+	tempFile := j.path + ".tmp"
+	file, err := os.Create(tempFile)
+	if err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+	for _, op := range j.open {
+		if err := encoder.Encode(record{Op: op}); err != nil {
+			file.Close()
+			os.Remove(tempFile)
+			return fmt.Errorf("failed to write journal: %w", err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to flush journal: %w", err)
+	}
+	file.Close()
+
+	if err := os.Rename(tempFile, j.path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalKeepsUnfinishedOperations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+
+	j, pending, err := Open(path)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	assert.NoError(t, j.Begin(Op{Kind: Upload, FolderID: "docs", Key: "docs/a.txt", Path: "/home/a.txt"}))
+	assert.NoError(t, j.Begin(Op{Kind: Download, FolderID: "docs", Key: "docs/b.txt", Path: "/home/.sync-manager-1.tmp"}))
+	assert.NoError(t, j.Begin(Op{Kind: Delete, FolderID: "docs", Key: "docs/c.txt"}))
+	assert.NoError(t, j.Done(Download, "docs/b.txt"))
+
+	// A newer operation on the same key replaces the earlier one
+	assert.NoError(t, j.Begin(Op{Kind: Upload, FolderID: "docs", Key: "docs/a.txt", Path: "/home/a2.txt"}))
+	assert.NoError(t, j.Close())
+
+	// The agent crashed: the next run finds what was left in progress, even past a torn last line
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	_, err = file.WriteString(`{"done":true,"kind":"del`)
+	assert.NoError(t, err)
+	file.Close()
+
+	j, pending, err = Open(path)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 2) {
+		assert.Equal(t, Delete, pending[0].Kind)
+		assert.Equal(t, "docs/c.txt", pending[0].Key)
+		assert.Equal(t, Upload, pending[1].Kind)
+		assert.Equal(t, "/home/a2.txt", pending[1].Path)
+	}
+
+	// Recovered operations are marked done, after which the file starts over
+	assert.NoError(t, j.Done(Delete, "docs/c.txt"))
+	assert.NoError(t, j.Done(Upload, "docs/a.txt"))
+	assert.NoError(t, j.Done(Upload, "docs/missing.txt"))
+	assert.NoError(t, j.Close())
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+
+	_, pending, err = Open(path)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestNilJournal(t *testing.T) {
+	var j *Journal
+	assert.NoError(t, j.Begin(Op{Kind: Upload, Key: "docs/a.txt"}))
+	assert.NoError(t, j.Done(Upload, "docs/a.txt"))
+	assert.NoError(t, j.Close())
+}
@@ -0,0 +1,135 @@
+package network
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultInterval is how often the storage is probed while online
+	DefaultInterval = 30 * time.Second
+	// OfflineInterval is how often the storage is probed while offline, so transfers resume quickly
+	OfflineInterval = 5 * time.Second
+	// ProbeTimeout bounds a single probe
+	ProbeTimeout = 10 * time.Second
+
+	// probeKey is looked up on the remote; it does not need to exist
+	probeKey = ".sync-manager/probe"
+)
+
+// Prober checks whether the remote storage can be reached
+type Prober func(ctx context.Context) error
+
+// StorageProbe returns a prober that asks the storage for a small object.
+// It bypasses storage middlewares so cached or retried answers cannot hide an outage.
+func StorageProbe(store storage.Storage) Prober {
+	for next := storage.Unwrap(store); next != nil; next = storage.Unwrap(store) {
+		store = next
+	}
+	return func(ctx context.Context) error {
+		_, err := store.FileExists(ctx, probeKey)
+		return err
+	}
+}
+
+// Monitor tracks whether the remote storage is reachable and notifies handlers when that changes
+type Monitor struct {
+	probe    Prober
+	interval time.Duration
+	online   bool
+	since    time.Time
+	handlers []func(online bool)
+	mu       sync.Mutex
+}
+
+// NewMonitor creates a monitor that assumes the storage is reachable until a probe fails
+func NewMonitor(probe Prober, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Monitor{
+		probe:    probe,
+		interval: interval,
+		online:   true,
+		since:    time.Now(),
+	}
+}
+
+// OnChange registers a handler called with the new state on every transition
+func (m *Monitor) OnChange(handler func(online bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Online reports the state seen by the last probe
+func (m *Monitor) Online() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.online
+}
+
+// Since returns when the current state began
+func (m *Monitor) Since() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.since
+}
+
+// Check probes the storage now and returns whether it is reachable.
+// Handlers run before Check returns when the state changed.
+func (m *Monitor) Check(ctx context.Context) bool {
+	probeCtx, cancel := context.WithTimeout(ctx, ProbeTimeout)
+	err := m.probe(probeCtx)
+	cancel()
+
+	// A probe cut short by shutdown says nothing about the network
+	if ctx.Err() != nil {
+		return m.Online()
+	}
+
+	online := err == nil
+
+	m.mu.Lock()
+	changed := online != m.online
+	if changed {
+		m.online = online
+		m.since = time.Now()
+	}
+	handlers := append([]func(bool){}, m.handlers...)
+	m.mu.Unlock()
+
+	if changed {
+		if online {
+			log.Info().Msg("Storage reachable again, resuming transfers")
+		} else {
+			log.Warn().Err(err).Msg("Storage unreachable, pausing transfers until the connection returns")
+		}
+		for _, handler := range handlers {
+			handler(online)
+		}
+	}
+
+	return online
+}
+
+// Run probes the storage periodically until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	for {
+		wait := m.interval
+		if !m.Check(ctx) && OfflineInterval < wait {
+			wait = OfflineInterval
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitorTransitions(t *testing.T) {
+	var failing atomic.Bool
+	monitor := NewMonitor(func(ctx context.Context) error {
+		if failing.Load() {
+			return errors.New("dial tcp: network is unreachable")
+		}
+		return nil
+	}, time.Minute)
+
+	var changes []bool
+	monitor.OnChange(func(online bool) { changes = append(changes, online) })
+
+	// Starts online and only notifies on transitions
+	assert.True(t, monitor.Online())
+	assert.True(t, monitor.Check(context.Background()))
+	assert.Empty(t, changes)
+
+	failing.Store(true)
+	assert.False(t, monitor.Check(context.Background()))
+	assert.False(t, monitor.Check(context.Background()))
+	assert.False(t, monitor.Online())
+	assert.Equal(t, []bool{false}, changes)
+
+	failing.Store(false)
+	assert.True(t, monitor.Check(context.Background()))
+	assert.Equal(t, []bool{false, true}, changes)
+
+	// A probe interrupted by shutdown keeps the previous state
+	failing.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(t, monitor.Check(ctx))
+	assert.Equal(t, []bool{false, true}, changes)
+}
+
+func TestStorageProbe(t *testing.T) {
+	store, err := storage.NewLocalStorage(&storage.LocalConfig{RootDir: t.TempDir()})
+	assert.NoError(t, err)
+
+	// The probe object does not need to exist
+	assert.NoError(t, StorageProbe(store)(context.Background()))
+}
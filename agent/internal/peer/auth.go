@@ -0,0 +1,113 @@
+package peer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/identity"
+)
+
+// Trust authenticates both ends of a peer connection over TLS. Each device has its own
+// certificate and key, and the user trusts the devices of their account by the fingerprints
+// of their certificates, so no secret is shared between devices or sent over the network.
+type Trust struct {
+	cert tls.Certificate
+
+	mu    sync.RWMutex
+	peers map[string]string // Certificate fingerprints of the trusted devices, by device ID
+	now   func() time.Time
+}
+
+// NewTrust creates the trust of a device holding cert, accepting the devices in peers
+func NewTrust(cert tls.Certificate, peers map[string]string) *Trust {
+	t := &Trust{cert: cert, now: time.Now}
+	t.SetPeers(peers)
+	return t
+}
+
+// SetPeers replaces the trusted devices, after a configuration change
+func (t *Trust) SetPeers(peers map[string]string) {
+	trusted := make(map[string]string, len(peers))
+	for deviceID, fingerprint := range peers {
+		trusted[deviceID] = fingerprint
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.peers = trusted
+}
+
+// Trusted reports whether a device is one of the trusted peers
+func (t *Trust) Trusted(deviceID string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.peers[deviceID]
+	return ok
+}
+
+// verify checks the certificate presented by the other end of a connection against the
+// fingerprint trusted for the device it names, and returns that device
+func (t *Trust) verify(rawCerts [][]byte) (string, error) {
+	if len(rawCerts) == 0 {
+		return "", errors.New("no certificate presented")
+	}
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid certificate: %w", err)
+	}
+	device := cert.Subject.CommonName
+
+	t.mu.RLock()
+	want, ok := t.peers[device]
+	t.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("device %q is not trusted", device)
+	}
+	if identity.Fingerprint(rawCerts[0]) != want {
+		return "", fmt.Errorf("certificate of device %q does not match its trusted fingerprint", device)
+	}
+	if now := t.now(); now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return "", fmt.Errorf("certificate of device %q is expired or not yet valid", device)
+	}
+	return device, nil
+}
+
+// verifyCertificate is the VerifyPeerCertificate hook of both ends. The certificates are
+// self-signed, so they are pinned by fingerprint instead of checked against a CA.
+func (t *Trust) verifyCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	_, err := t.verify(rawCerts)
+	return err
+}
+
+// serverConfig is the TLS configuration of the file server, which requires a trusted client certificate
+func (t *Trust) serverConfig() *tls.Config {
+	return &tls.Config{
+		Certificates:          []tls.Certificate{t.cert},
+		ClientAuth:            tls.RequireAnyClientCert,
+		MinVersion:            tls.VersionTLS13,
+		VerifyPeerCertificate: t.verifyCertificate,
+	}
+}
+
+// clientConfig is the TLS configuration of the client, which only talks to trusted servers.
+// Which trusted device answered is checked against the peer asked once the response arrives.
+func (t *Trust) clientConfig() *tls.Config {
+	return &tls.Config{
+		Certificates:          []tls.Certificate{t.cert},
+		MinVersion:            tls.VersionTLS13,
+		InsecureSkipVerify:    true, // Replaced by verifyCertificate, which pins the trusted fingerprints
+		VerifyPeerCertificate: t.verifyCertificate,
+	}
+}
+
+// peerDevice returns the device at the other end of an authenticated connection
+func peerDevice(state *tls.ConnectionState) (string, error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return "", errors.New("connection is not authenticated")
+	}
+	return state.PeerCertificates[0].Subject.CommonName, nil
+}
@@ -0,0 +1,114 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrUnavailable is returned when no peer could provide a file
+var ErrUnavailable = errors.New("no peer has the file")
+
+// Peers lists the devices currently reachable on the local network
+type Peers interface {
+	Peers() []Peer
+}
+
+// Client fetches files from peers, moving on to the next peer when one fails
+type Client struct {
+	trust *Trust
+	peers Peers
+	http  *http.Client
+}
+
+// NewClient creates a client that fetches from the peers of list trusted by trust.
+// timeout bounds how long a peer may take to start answering.
+func NewClient(trust *Trust, list Peers, timeout time.Duration) *Client {
+	return &Client{
+		trust: trust,
+		peers: list,
+		http: &http.Client{Transport: &http.Transport{
+			DialContext:           (&net.Dialer{Timeout: timeout}).DialContext,
+			TLSClientConfig:       trust.clientConfig(),
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+		}},
+	}
+}
+
+// Fetch writes the content of the file stored under key, which must hash to the given hash,
+// tagged with its algorithm as the index records it, into w. A transfer interrupted on one peer continues from the same offset on
+// the next. It returns ErrUnavailable, wrapped, when the file could not be fetched whole;
+// the caller then discards what was written and falls back to the storage backend.
+func (c *Client) Fetch(ctx context.Context, key, hash string, w io.Writer) (int64, error) {
+	if hash == "" {
+		return 0, ErrUnavailable
+	}
+
+	alg := checksum.Of(hash)
+	hasher := alg.New()
+	var written int64
+	for _, p := range c.peers.Peers() {
+		if ctx.Err() != nil {
+			return written, ctx.Err()
+		}
+		if !c.trust.Trusted(p.DeviceID) {
+			continue
+		}
+
+		n, err := c.fetchFrom(ctx, p, key, hash, written, io.MultiWriter(w, hasher))
+		written += n
+		if err != nil {
+			log.Debug().Err(err).Str("peer", p.DeviceID).Str("key", key).Msg("Peer could not provide file")
+			continue
+		}
+
+		if alg.Tag(hasher.Sum(nil)) != hash {
+			return written, fmt.Errorf("%w: content from peers does not match the stored hash", ErrUnavailable)
+		}
+		log.Debug().Str("peer", p.DeviceID).Str("key", key).Int64("size", written).Msg("Fetched file from peer")
+		return written, nil
+	}
+
+	return written, ErrUnavailable
+}
+
+// fetchFrom copies the file from one peer, starting at offset
+func (c *Client) fetchFrom(ctx context.Context, p Peer, key, hash string, offset int64, w io.Writer) (int64, error) {
+	target := url.URL{Scheme: "https", Host: p.Addr, Path: filesPath + key, RawQuery: url.Values{"hash": {hash}}.Encode()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Another trusted device may have taken over the address the peer announced
+	if device, err := peerDevice(resp.TLS); err != nil || device != p.DeviceID {
+		return 0, fmt.Errorf("peer at %s is not device %s", p.Addr, p.DeviceID)
+	}
+
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+	case offset == 0 && resp.StatusCode == http.StatusOK:
+	default:
+		return 0, fmt.Errorf("peer answered %s", resp.Status)
+	}
+
+	return io.Copy(w, resp.Body)
+}
@@ -0,0 +1,224 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// mdnsAddress is the IPv4 multicast group and port of mDNS
+	mdnsAddress = "224.0.0.251:5353"
+	// serviceName is the DNS-SD service the agents announce
+	serviceName = "_sync-manager._tcp.local."
+	// AnnounceInterval is how often the agent announces itself; peers not heard from in three intervals are dropped
+	AnnounceInterval = time.Minute
+)
+
+// Peer is another device of the user found on the local network
+type Peer struct {
+	DeviceID string
+	Addr     string // Host and port of the peer's file server
+	LastSeen time.Time
+}
+
+// Discovery announces this device over mDNS and keeps track of the peers announcing themselves
+type Discovery struct {
+	deviceID string
+	port     int
+	interval time.Duration
+
+	mu    sync.Mutex
+	peers map[string]Peer
+	now   func() time.Time
+}
+
+// NewDiscovery creates a discovery for a device whose file server listens on port
+func NewDiscovery(deviceID string, port int) *Discovery {
+	return &Discovery{
+		deviceID: deviceID,
+		port:     port,
+		interval: AnnounceInterval,
+		peers:    make(map[string]Peer),
+		now:      time.Now,
+	}
+}
+
+// Peers returns the peers heard from recently, most recent first
+func (d *Discovery) Peers() []Peer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	peers := make([]Peer, 0, len(d.peers))
+	for id, p := range d.peers {
+		if now.Sub(p.LastSeen) > 3*d.interval {
+			delete(d.peers, id)
+			continue
+		}
+		peers = append(peers, p)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].LastSeen.After(peers[j].LastSeen) })
+	return peers
+}
+
+// Run joins the mDNS group, asks for peers and announces this device until ctx is cancelled
+func (d *Discovery) Run(ctx context.Context) error {
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+	if err != nil {
+		return fmt.Errorf("failed to resolve mDNS address: %w", err)
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("failed to join mDNS group: %w", err)
+	}
+	defer conn.Close()
+
+	send := func(packet []byte, err error) {
+		if err == nil {
+			_, err = conn.WriteToUDP(packet, group)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Debug().Err(err).Msg("Failed to send mDNS packet")
+		}
+	}
+
+	go func() {
+		send(query())
+		send(d.announcement(d.interval * 2))
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// Say goodbye so peers stop trying this device right away
+				send(d.announcement(0))
+				conn.Close()
+				return
+			case <-ticker.C:
+				send(d.announcement(d.interval * 2))
+			}
+		}
+	}()
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read mDNS packet: %w", err)
+		}
+		if d.handle(buf[:n], from.IP) {
+			send(d.announcement(d.interval * 2))
+		}
+	}
+}
+
+// handle records the peers announced in an mDNS packet from ip.
+// It reports whether the packet asked for the service, so this device should announce itself.
+func (d *Discovery) handle(packet []byte, ip net.IP) bool {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(packet); err != nil {
+		return false
+	}
+
+	if !msg.Header.Response {
+		for _, q := range msg.Questions {
+			if q.Type == dnsmessage.TypePTR && strings.EqualFold(q.Name.String(), serviceName) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ports := make(map[string]uint16)
+	devices := make(map[string]string)
+	ttls := make(map[string]uint32)
+	for _, r := range append(msg.Answers, msg.Additionals...) {
+		name := strings.ToLower(r.Header.Name.String())
+		if !strings.HasSuffix(name, "."+serviceName) {
+			continue
+		}
+		switch body := r.Body.(type) {
+		case *dnsmessage.SRVResource:
+			ports[name] = body.Port
+			ttls[name] = r.Header.TTL
+		case *dnsmessage.TXTResource:
+			for _, txt := range body.TXT {
+				if device, ok := strings.CutPrefix(txt, "device="); ok {
+					devices[name] = device
+				}
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for name, device := range devices {
+		port, ok := ports[name]
+		if !ok || device == d.deviceID {
+			continue
+		}
+		if ttls[name] == 0 {
+			delete(d.peers, device)
+			continue
+		}
+		if _, known := d.peers[device]; !known {
+			log.Info().Str("peer", device).Str("address", ip.String()).Msg("Found device on the local network")
+		}
+		d.peers[device] = Peer{
+			DeviceID: device,
+			Addr:     net.JoinHostPort(ip.String(), strconv.Itoa(int(port))),
+			LastSeen: d.now(),
+		}
+	}
+	return false
+}
+
+// announcement builds the mDNS response advertising this device, valid for ttl; a zero ttl withdraws it
+func (d *Discovery) announcement(ttl time.Duration) ([]byte, error) {
+	instance, err := dnsmessage.NewName(d.deviceID + "." + serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device ID for mDNS: %w", err)
+	}
+	host, err := dnsmessage.NewName(d.deviceID + ".local.")
+	if err != nil {
+		return nil, fmt.Errorf("invalid device ID for mDNS: %w", err)
+	}
+	service := dnsmessage.MustNewName(serviceName)
+
+	header := func(name dnsmessage.Name, kind dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: kind, Class: dnsmessage.ClassINET, TTL: uint32(ttl.Seconds())}
+	}
+
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: []dnsmessage.Resource{
+			{Header: header(service, dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: instance}},
+			{Header: header(instance, dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Target: host, Port: uint16(d.port)}},
+			{Header: header(instance, dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{"device=" + d.deviceID, "version=1"}}},
+		},
+	}
+	return msg.Pack()
+}
+
+// query builds the mDNS question asking every device of the service to announce itself
+func query() ([]byte, error) {
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{
+			{Name: dnsmessage.MustNewName(serviceName), Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET},
+		},
+	}
+	return msg.Pack()
+}
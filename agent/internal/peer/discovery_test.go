@@ -0,0 +1,58 @@
+package peer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoveryRecordsAnnouncedPeers(t *testing.T) {
+	laptop := NewDiscovery("laptop", 21028)
+	desktop := NewDiscovery("desktop", 21029)
+
+	packet, err := desktop.announcement(2 * time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, laptop.handle(packet, net.ParseIP("192.168.1.20")))
+
+	peers := laptop.Peers()
+	assert.Len(t, peers, 1)
+	assert.Equal(t, "desktop", peers[0].DeviceID)
+	assert.Equal(t, "192.168.1.20:21029", peers[0].Addr)
+
+	// A device ignores its own announcements
+	packet, err = laptop.announcement(2 * time.Minute)
+	assert.NoError(t, err)
+	laptop.handle(packet, net.ParseIP("192.168.1.10"))
+	assert.Len(t, laptop.Peers(), 1)
+
+	// A goodbye removes the peer right away
+	packet, err = desktop.announcement(0)
+	assert.NoError(t, err)
+	laptop.handle(packet, net.ParseIP("192.168.1.20"))
+	assert.Empty(t, laptop.Peers())
+}
+
+func TestDiscoveryAnswersQueries(t *testing.T) {
+	laptop := NewDiscovery("laptop", 21028)
+	packet, err := query()
+	assert.NoError(t, err)
+	assert.True(t, laptop.handle(packet, net.ParseIP("192.168.1.20")))
+
+	assert.False(t, laptop.handle([]byte("not dns"), net.ParseIP("192.168.1.20")))
+}
+
+func TestDiscoveryForgetsSilentPeers(t *testing.T) {
+	now := time.Now()
+	laptop := NewDiscovery("laptop", 21028)
+	laptop.now = func() time.Time { return now }
+
+	packet, err := NewDiscovery("desktop", 21028).announcement(2 * time.Minute)
+	assert.NoError(t, err)
+	laptop.handle(packet, net.ParseIP("192.168.1.20"))
+	assert.Len(t, laptop.Peers(), 1)
+
+	now = now.Add(3*AnnounceInterval + time.Second)
+	assert.Empty(t, laptop.Peers())
+}
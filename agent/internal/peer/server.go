@@ -0,0 +1,181 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/config"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/rs/zerolog/log"
+)
+
+// filesPath is the URL path files are served under, followed by their storage key
+const filesPath = "/v1/files/"
+
+// ErrNotFound is returned by a Source that has no file with the requested key and hash
+var ErrNotFound = errors.New("file not available")
+
+// Source opens local copies of remote files for peers
+type Source interface {
+	// Open returns the local file stored under key if its content has the given hash, tagged
+	// with its algorithm as the index records it
+	Open(key, hash string) (*os.File, error)
+}
+
+// Server serves local files over TLS to the peers trusted by this device
+type Server struct {
+	source Source
+	server *http.Server
+}
+
+// NewServer creates a server for the files of source, accepting the devices of trust
+func NewServer(trust *Trust, source Source) *Server {
+	s := &Server{source: source}
+	mux := http.NewServeMux()
+	mux.HandleFunc(filesPath, s.serveFile)
+	s.server = &http.Server{Handler: mux, TLSConfig: trust.serverConfig(), ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// Serve accepts peer connections on listener until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(shutdownCtx)
+	}()
+
+	if err := s.server.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve peers: %w", err)
+	}
+	return nil
+}
+
+// serveFile sends a file to a peer, supporting ranges so an interrupted transfer can continue elsewhere
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The TLS handshake already refused devices that are not trusted
+	device, err := peerDevice(r.TLS)
+	if err != nil {
+		log.Warn().Err(err).Str("remote", r.RemoteAddr).Msg("Rejected peer request")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, filesPath)
+	hash := r.URL.Query().Get("hash")
+	file, err := s.source.Open(key, hash)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Warn().Err(err).Str("key", key).Msg("Failed to open file for peer")
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	log.Debug().Str("peer", device).Str("key", key).Msg("Serving file to peer")
+	http.ServeContent(w, r, path.Base(key), info.ModTime(), file)
+}
+
+// FolderSource serves the files of the synced folders, found through their roots.
+// A single-file folder only serves its file.
+type FolderSource struct {
+	mu      sync.RWMutex
+	folders map[string]config.SyncFolder
+	hashes  map[string]fileHash // Hashes by local path, reused while size and time are unchanged
+}
+
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// NewFolderSource creates a source for folders, keyed by the storage prefix of their files
+func NewFolderSource(folders map[string]config.SyncFolder) *FolderSource {
+	return &FolderSource{folders: folders, hashes: make(map[string]fileHash)}
+}
+
+// SetFolders replaces the folders served, after a configuration change
+func (s *FolderSource) SetFolders(folders map[string]config.SyncFolder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.folders = folders
+}
+
+// Open implements Source
+func (s *FolderSource) Open(key, hash string) (*os.File, error) {
+	prefix, rel, ok := strings.Cut(key, "/")
+	if !ok || rel == "" || hash == "" || path.Clean(rel) != rel || rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil, ErrNotFound
+	}
+
+	s.mu.RLock()
+	folder, ok := s.folders[prefix]
+	s.mu.RUnlock()
+	if !ok || !folder.Tracks(rel) {
+		return nil, ErrNotFound
+	}
+
+	file, err := os.Open(config.RootPath(folder.AllRoots(), rel))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		file.Close()
+		return nil, ErrNotFound
+	}
+
+	current, err := s.hash(file, info, checksum.Of(hash))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if current != hash {
+		file.Close()
+		return nil, ErrNotFound
+	}
+	return file, nil
+}
+
+// hash returns the hash of file with alg, leaving it positioned at the start
+func (s *FolderSource) hash(file *os.File, info os.FileInfo, alg checksum.Algorithm) (string, error) {
+	s.mu.RLock()
+	cached, ok := s.hashes[file.Name()]
+	s.mu.RUnlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) && checksum.Of(cached.hash) == alg {
+		return cached.hash, nil
+	}
+
+	hash, err := checksum.File(alg, file, info.Size(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	s.mu.Lock()
+	s.hashes[file.Name()] = fileHash{size: info.Size(), modTime: info.ModTime(), hash: hash}
+	s.mu.Unlock()
+	return hash, nil
+}
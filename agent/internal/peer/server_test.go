@@ -0,0 +1,211 @@
+package peer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/config"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticPeers []Peer
+
+func (p staticPeers) Peers() []Peer { return p }
+
+func sha(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// testDevices holds the identities of the devices of a test, created on first use
+type testDevices struct {
+	t     *testing.T
+	certs map[string]tls.Certificate
+}
+
+func newTestDevices(t *testing.T) *testDevices {
+	return &testDevices{t: t, certs: make(map[string]tls.Certificate)}
+}
+
+func (d *testDevices) cert(deviceID string) tls.Certificate {
+	if cert, ok := d.certs[deviceID]; ok {
+		return cert
+	}
+	cert, err := identity.Load(filepath.Join(d.t.TempDir(), "identity.pem"), deviceID)
+	assert.NoError(d.t, err)
+	d.certs[deviceID] = cert
+	return cert
+}
+
+// trust returns the trust of deviceID accepting the devices in peers
+func (d *testDevices) trust(deviceID string, peers ...string) *Trust {
+	fingerprints := make(map[string]string)
+	for _, p := range peers {
+		fingerprints[p] = identity.Fingerprint(d.cert(p).Certificate[0])
+	}
+	return NewTrust(d.cert(deviceID), fingerprints)
+}
+
+// serve starts a TLS server for handler with the configuration of server and returns its peer entry
+func serve(t *testing.T, deviceID string, server *Server, handler http.Handler) Peer {
+	ts := httptest.NewUnstartedServer(handler)
+	ts.TLS = server.server.TLSConfig
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return Peer{DeviceID: deviceID, Addr: ts.Listener.Addr().String(), LastSeen: time.Now()}
+}
+
+// newPeer serves the folder docs of a device from a temporary directory holding files
+func newPeer(t *testing.T, trust *Trust, deviceID string, files map[string]string) Peer {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	server := NewServer(trust, NewFolderSource(map[string]config.SyncFolder{"docs": {LocalPath: dir}}))
+	return serve(t, deviceID, server, server.server.Handler)
+}
+
+func TestClientFetchesFromPeer(t *testing.T) {
+	devices := newTestDevices(t)
+	desktop := newPeer(t, devices.trust("desktop", "laptop"), "desktop", map[string]string{"notes/a b.txt": "hello from desktop"})
+	client := NewClient(devices.trust("laptop", "desktop"), staticPeers{desktop}, time.Second)
+
+	var buf bytes.Buffer
+	n, err := client.Fetch(context.Background(), "docs/notes/a b.txt", sha("hello from desktop"), &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(18), n)
+	assert.Equal(t, "hello from desktop", buf.String())
+}
+
+func TestClientSkipsPeersWithoutTheContent(t *testing.T) {
+	devices := newTestDevices(t)
+	stale := newPeer(t, devices.trust("old-laptop", "laptop"), "old-laptop", map[string]string{"a.txt": "old version"})
+	current := newPeer(t, devices.trust("desktop", "laptop"), "desktop", map[string]string{"a.txt": "new version"})
+	client := NewClient(devices.trust("laptop", "old-laptop", "desktop"), staticPeers{stale, current}, time.Second)
+
+	var buf bytes.Buffer
+	_, err := client.Fetch(context.Background(), "docs/a.txt", sha("new version"), &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "new version", buf.String())
+
+	_, err = client.Fetch(context.Background(), "docs/a.txt", sha("never written"), &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestClientAndServerAuthenticateEachOther(t *testing.T) {
+	devices := newTestDevices(t)
+	files := map[string]string{"a.txt": "secret"}
+	fetch := func(client *Client) error {
+		_, err := client.Fetch(context.Background(), "docs/a.txt", sha("secret"), &bytes.Buffer{})
+		return err
+	}
+
+	// A server that does not trust the client refuses the connection
+	desktop := newPeer(t, devices.trust("desktop"), "desktop", files)
+	assert.ErrorIs(t, fetch(NewClient(devices.trust("laptop", "desktop"), staticPeers{desktop}, time.Second)), ErrUnavailable)
+
+	// A client does not talk to a server it does not trust, nor to one answering as another device
+	desktop = newPeer(t, devices.trust("desktop", "laptop"), "desktop", files)
+	assert.ErrorIs(t, fetch(NewClient(devices.trust("laptop"), staticPeers{desktop}, time.Second)), ErrUnavailable)
+	impostor := newPeer(t, devices.trust("phone", "laptop"), "phone", files)
+	impostor.DeviceID = "desktop"
+	assert.ErrorIs(t, fetch(NewClient(devices.trust("laptop", "desktop", "phone"), staticPeers{impostor}, time.Second)), ErrUnavailable)
+
+	// A device whose certificate changed is no longer trusted under the old fingerprint
+	replaced := newTestDevices(t)
+	forged := newPeer(t, replaced.trust("desktop", "laptop"), "desktop", files)
+	trust := devices.trust("laptop")
+	trust.SetPeers(map[string]string{"desktop": identity.Fingerprint(devices.cert("desktop").Certificate[0])})
+	assert.ErrorIs(t, fetch(NewClient(trust, staticPeers{forged}, time.Second)), ErrUnavailable)
+
+	// The client trusts the desktop once its fingerprint is set
+	assert.NoError(t, fetch(NewClient(trust, staticPeers{desktop}, time.Second)))
+}
+
+func TestClientResumesOnAnotherPeer(t *testing.T) {
+	devices := newTestDevices(t)
+	content := "0123456789abcdefghijklmnopqrstuvwxyz"
+	hash := sha(content)
+
+	// The first peer drops the connection halfway through
+	flakyServer := NewServer(devices.trust("flaky", "laptop"), nil)
+	flaky := serve(t, "flaky", flakyServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write([]byte(content[:10]))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+
+	desktop := newPeer(t, devices.trust("desktop", "laptop"), "desktop", map[string]string{"a.txt": content})
+	client := NewClient(devices.trust("laptop", "flaky", "desktop"), staticPeers{flaky, desktop}, time.Second)
+
+	var buf bytes.Buffer
+	n, err := client.Fetch(context.Background(), "docs/a.txt", hash, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.String())
+}
+
+func TestFolderSourceOpen(t *testing.T) {
+	main, desktop := t.TempDir(), t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(main, "a.txt"), []byte("main"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(desktop, "b.txt"), []byte("desktop"), 0644))
+	source := NewFolderSource(map[string]config.SyncFolder{
+		"docs": {LocalPath: main, Roots: []config.FolderRoot{{Path: desktop, Prefix: "Desktop"}}},
+	})
+
+	file, err := source.Open("docs/Desktop/b.txt", sha("desktop"))
+	assert.NoError(t, err)
+	file.Close()
+
+	// Folders hashing with BLAKE3 ask for the same content by its BLAKE3 hash
+	blake, err := checksum.Sum(checksum.BLAKE3, strings.NewReader("desktop"))
+	assert.NoError(t, err)
+	file, err = source.Open("docs/Desktop/b.txt", blake)
+	assert.NoError(t, err)
+	file.Close()
+
+	for _, key := range []string{"docs/a.txt", "docs/../a.txt", "docs/Desktop/../../a.txt", "other/a.txt", "docs/missing.txt", "docs/Desktop"} {
+		_, err := source.Open(key, sha("desktop"))
+		assert.ErrorIs(t, err, ErrNotFound, key)
+	}
+
+	// Changed content is no longer served under the old hash
+	file, err = source.Open("docs/a.txt", sha("main"))
+	assert.NoError(t, err)
+	file.Close()
+	assert.NoError(t, os.WriteFile(filepath.Join(main, "a.txt"), []byte("changed"), 0644))
+	assert.NoError(t, os.Chtimes(filepath.Join(main, "a.txt"), time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	_, err = source.Open("docs/a.txt", sha("main"))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFolderSourceServesOnlyTheFileOfSingleFileFolders(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "todo.txt"), []byte("todo"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "private.txt"), []byte("private"), 0644))
+	source := NewFolderSource(map[string]config.SyncFolder{"todo": {LocalPath: dir, File: "todo.txt"}})
+
+	file, err := source.Open("todo/todo.txt", sha("todo"))
+	assert.NoError(t, err)
+	file.Close()
+
+	_, err = source.Open("todo/private.txt", sha("private"))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,61 @@
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// commandTimeout bounds the OS tools run to detect the conditions
+const commandTimeout = 5 * time.Second
+
+// sysfsOnBattery reads the Linux power supply class under root. The device is on battery
+// when no external supply is online and a battery is discharging.
+func sysfsOnBattery(root string) bool {
+	supplies, err := os.ReadDir(root)
+	if err != nil {
+		return false
+	}
+
+	discharging := false
+	for _, supply := range supplies {
+		dir := filepath.Join(root, supply.Name())
+		switch readTrimmed(filepath.Join(dir, "type")) {
+		case "Mains", "USB", "USB_C":
+			if readTrimmed(filepath.Join(dir, "online")) == "1" {
+				return false
+			}
+		case "Battery":
+			if readTrimmed(filepath.Join(dir, "status")) == "Discharging" {
+				discharging = true
+			}
+		}
+	}
+
+	return discharging
+}
+
+// parseNetworkManagerMetered reads the NetworkManager Metered property as printed by
+// busctl, e.g. "u 1". Values 1 (yes) and 3 (guessed yes) mean metered.
+func parseNetworkManagerMetered(output string) bool {
+	fields := strings.Fields(output)
+	if len(fields) != 2 || fields[0] != "u" {
+		return false
+	}
+	return fields[1] == "1" || fields[1] == "3"
+}
+
+// parsePmsetOnBattery reads the output of macOS "pmset -g batt"
+func parsePmsetOnBattery(output string) bool {
+	return strings.Contains(output, "'Battery Power'")
+}
+
+// readTrimmed returns the trimmed contents of a small file, or an empty string on error
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,21 @@
+//go:build darwin
+
+package power
+
+import (
+	"context"
+	"os/exec"
+)
+
+// Detect asks pmset for the power source. macOS has no public tool reporting
+// metered connections, so they are never detected.
+func Detect(ctx context.Context) Conditions {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "pmset", "-g", "batt").Output()
+	if err != nil {
+		return Conditions{}
+	}
+	return Conditions{OnBattery: parsePmsetOnBattery(string(output))}
+}
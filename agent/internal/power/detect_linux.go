@@ -0,0 +1,30 @@
+//go:build linux
+
+package power
+
+import (
+	"context"
+	"os/exec"
+)
+
+// Detect reads the power supply from sysfs and asks NetworkManager whether the connection is metered
+func Detect(ctx context.Context) Conditions {
+	return Conditions{
+		OnBattery: sysfsOnBattery("/sys/class/power_supply"),
+		Metered:   networkManagerMetered(ctx),
+	}
+}
+
+// networkManagerMetered returns false when NetworkManager is not available
+func networkManagerMetered(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "busctl", "get-property",
+		"org.freedesktop.NetworkManager", "/org/freedesktop/NetworkManager",
+		"org.freedesktop.NetworkManager", "Metered").Output()
+	if err != nil {
+		return false
+	}
+	return parseNetworkManagerMetered(string(output))
+}
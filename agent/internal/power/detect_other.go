@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package power
+
+import "context"
+
+// Detect reports no conditions on platforms without a supported detection method
+func Detect(ctx context.Context) Conditions {
+	return Conditions{}
+}
@@ -0,0 +1,193 @@
+package power
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultInterval is how often the power source, connection and running programs are checked
+const DefaultInterval = time.Minute
+
+// Conditions describes the device state the transfer policy reacts to
+type Conditions struct {
+	OnBattery  bool // Running on battery rather than external power
+	Metered    bool // The active connection is marked as metered
+	CapReached bool // The device used up its monthly bandwidth cap
+	// Processes are the programs of PowerConfig.Processes that are running
+	Processes []string
+}
+
+// Restriction is the limit applied to transfers for the current conditions
+type Restriction struct {
+	Paused        bool     // No transfers at all
+	ThrottleBytes int64    // Upload bandwidth limit in bytes/sec, 0 for none
+	MaxFileSize   int64    // Largest file transferred, 0 for any size
+	Reasons       []string // Conditions that caused the restriction
+}
+
+// Active reports whether transfers are limited in any way
+func (r Restriction) Active() bool {
+	return r.Paused || r.ThrottleBytes > 0 || r.MaxFileSize > 0
+}
+
+// Equal reports whether two restrictions limit transfers the same way
+func (r Restriction) Equal(other Restriction) bool {
+	return r.Paused == other.Paused && r.ThrottleBytes == other.ThrottleBytes && r.MaxFileSize == other.MaxFileSize &&
+		strings.Join(r.Reasons, ",") == strings.Join(other.Reasons, ",")
+}
+
+// String describes the restriction for status output, empty when transfers are unrestricted
+func (r Restriction) String() string {
+	if !r.Active() {
+		return ""
+	}
+
+	var limits []string
+	if r.Paused {
+		limits = append(limits, "paused")
+	} else {
+		if r.ThrottleBytes > 0 {
+			limits = append(limits, fmt.Sprintf("throttled to %s/s", formatBytes(r.ThrottleBytes)))
+		}
+		if r.MaxFileSize > 0 {
+			limits = append(limits, fmt.Sprintf("limited to files up to %s", formatBytes(r.MaxFileSize)))
+		}
+	}
+
+	return fmt.Sprintf("%s (%s)", strings.Join(limits, ", "), strings.Join(r.Reasons, ", "))
+}
+
+// Evaluate combines the policies of every condition that holds, keeping the strictest limits
+func Evaluate(cfg config.PowerConfig, conditions Conditions) Restriction {
+	var r Restriction
+
+	apply := func(policy config.ConditionPolicy, reason string) {
+		switch policy.Action {
+		case config.PolicyPause:
+			r.Paused = true
+		case config.PolicyThrottle:
+			if r.ThrottleBytes == 0 || policy.ThrottleBytes < r.ThrottleBytes {
+				r.ThrottleBytes = policy.ThrottleBytes
+			}
+		case config.PolicySmallFiles:
+			if r.MaxFileSize == 0 || policy.MaxFileSize < r.MaxFileSize {
+				r.MaxFileSize = policy.MaxFileSize
+			}
+		default:
+			return
+		}
+		r.Reasons = append(r.Reasons, reason)
+	}
+
+	if conditions.OnBattery {
+		apply(cfg.OnBattery, "on battery")
+	}
+	if conditions.Metered {
+		apply(cfg.OnMetered, "metered connection")
+	}
+	if len(conditions.Processes) > 0 {
+		apply(cfg.OnProcesses, "running "+strings.Join(conditions.Processes, ", "))
+	}
+	if conditions.CapReached {
+		apply(config.ConditionPolicy{Action: config.PolicyPause}, "monthly bandwidth cap reached")
+	}
+
+	return r
+}
+
+// Monitor periodically detects the device conditions and notifies handlers when the restriction changes
+type Monitor struct {
+	cfg         config.PowerConfig
+	detect      func(ctx context.Context) Conditions
+	interval    time.Duration
+	restriction Restriction
+	handlers    []func(Restriction)
+	mu          sync.Mutex
+}
+
+// NewMonitor creates a monitor applying cfg to the conditions reported by detect
+func NewMonitor(cfg config.PowerConfig, detect func(ctx context.Context) Conditions, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Monitor{
+		cfg:      cfg,
+		detect:   detect,
+		interval: interval,
+	}
+}
+
+// OnChange registers a handler called with the new restriction on every change
+func (m *Monitor) OnChange(handler func(Restriction)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Restriction returns the restriction found by the last check
+func (m *Monitor) Restriction() Restriction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.restriction
+}
+
+// Check detects the conditions now and applies the matching restriction
+func (m *Monitor) Check(ctx context.Context) Restriction {
+	restriction := Evaluate(m.cfg, m.detect(ctx))
+
+	m.mu.Lock()
+	changed := !restriction.Equal(m.restriction)
+	m.restriction = restriction
+	handlers := append([]func(Restriction){}, m.handlers...)
+	m.mu.Unlock()
+
+	if changed {
+		if restriction.Active() {
+			log.Info().Str("restriction", restriction.String()).Msg("Restricting transfers")
+		} else {
+			log.Info().Msg("Transfer restrictions lifted")
+		}
+		for _, handler := range handlers {
+			handler(restriction)
+		}
+	}
+
+	return restriction
+}
+
+// Run checks the conditions periodically until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.Check(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// formatBytes formats a byte count using binary units
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,153 @@
+package power
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	cfg := config.PowerConfig{
+		OnBattery: config.ConditionPolicy{Action: config.PolicyThrottle, ThrottleBytes: 200 * 1024},
+		OnMetered: config.ConditionPolicy{Action: config.PolicySmallFiles, MaxFileSize: 1024 * 1024},
+	}
+
+	assert.False(t, Evaluate(cfg, Conditions{}).Active())
+	assert.Equal(t, "", Evaluate(cfg, Conditions{}).String())
+
+	r := Evaluate(cfg, Conditions{OnBattery: true})
+	assert.Equal(t, int64(200*1024), r.ThrottleBytes)
+	assert.Equal(t, "throttled to 200.0 KiB/s (on battery)", r.String())
+
+	// Both conditions combine their limits
+	r = Evaluate(cfg, Conditions{OnBattery: true, Metered: true})
+	assert.Equal(t, int64(200*1024), r.ThrottleBytes)
+	assert.Equal(t, int64(1024*1024), r.MaxFileSize)
+	assert.Equal(t, "throttled to 200.0 KiB/s, limited to files up to 1.0 MiB (on battery, metered connection)", r.String())
+
+	// Pausing overrides the other limits in the description
+	cfg.OnMetered = config.ConditionPolicy{Action: config.PolicyPause}
+	r = Evaluate(cfg, Conditions{OnBattery: true, Metered: true})
+	assert.True(t, r.Paused)
+	assert.Equal(t, "paused (on battery, metered connection)", r.String())
+
+	// Conditions without a policy add no reason
+	cfg.OnBattery = config.ConditionPolicy{Action: config.PolicyNone}
+	assert.Equal(t, []string{"metered connection"}, Evaluate(cfg, Conditions{OnBattery: true, Metered: true}).Reasons)
+
+	// The monthly cap always pauses, whatever the power policies
+	r = Evaluate(config.PowerConfig{}, Conditions{CapReached: true})
+	assert.True(t, r.Paused)
+	assert.Equal(t, "paused (monthly bandwidth cap reached)", r.String())
+}
+
+func TestMonitorNotifiesChanges(t *testing.T) {
+	conditions := Conditions{}
+	cfg := config.PowerConfig{OnBattery: config.ConditionPolicy{Action: config.PolicyPause}}
+	monitor := NewMonitor(cfg, func(context.Context) Conditions { return conditions }, 0)
+
+	var changes []Restriction
+	monitor.OnChange(func(r Restriction) { changes = append(changes, r) })
+
+	monitor.Check(context.Background())
+	assert.Empty(t, changes)
+
+	conditions.OnBattery = true
+	monitor.Check(context.Background())
+	monitor.Check(context.Background())
+	assert.Len(t, changes, 1)
+	assert.True(t, monitor.Restriction().Paused)
+
+	conditions.OnBattery = false
+	monitor.Check(context.Background())
+	assert.Len(t, changes, 2)
+	assert.False(t, changes[1].Active())
+}
+
+func TestSysfsOnBattery(t *testing.T) {
+	root := t.TempDir()
+	supply := func(name string, files map[string]string) {
+		dir := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(dir, 0755))
+		for file, content := range files {
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content+"\n"), 0644))
+		}
+	}
+
+	// A desktop without a battery is never on battery
+	assert.False(t, sysfsOnBattery(root))
+	assert.False(t, sysfsOnBattery(filepath.Join(root, "missing")))
+
+	supply("BAT0", map[string]string{"type": "Battery", "status": "Discharging"})
+	assert.True(t, sysfsOnBattery(root))
+
+	supply("AC", map[string]string{"type": "Mains", "online": "1"})
+	assert.False(t, sysfsOnBattery(root))
+}
+
+func TestParseDetectionOutput(t *testing.T) {
+	assert.True(t, parseNetworkManagerMetered("u 1\n"))
+	assert.True(t, parseNetworkManagerMetered("u 3\n"))
+	assert.False(t, parseNetworkManagerMetered("u 4\n"))
+	assert.False(t, parseNetworkManagerMetered(""))
+
+	assert.True(t, parsePmsetOnBattery("Now drawing from 'Battery Power'\n -InternalBattery-0 (id=1)\t85%; discharging"))
+	assert.False(t, parsePmsetOnBattery("Now drawing from 'AC Power'"))
+}
+
+func TestEvaluateProcesses(t *testing.T) {
+	cfg := config.PowerConfig{
+		Processes:   []string{"ffmpeg", "steam"},
+		OnProcesses: config.ConditionPolicy{Action: config.PolicyPause},
+	}
+	assert.False(t, Evaluate(cfg, Conditions{}).Active())
+
+	r := Evaluate(cfg, Conditions{Processes: []string{"ffmpeg", "steam"}})
+	assert.True(t, r.Paused)
+	assert.Equal(t, "paused (running ffmpeg, steam)", r.String())
+
+	// Running programs can throttle instead, combining with the other conditions
+	cfg.OnProcesses = config.ConditionPolicy{Action: config.PolicyThrottle, ThrottleBytes: 1024}
+	cfg.OnBattery = config.ConditionPolicy{Action: config.PolicyThrottle, ThrottleBytes: 4096}
+	r = Evaluate(cfg, Conditions{OnBattery: true, Processes: []string{"steam"}})
+	assert.Equal(t, int64(1024), r.ThrottleBytes)
+	assert.Equal(t, []string{"on battery", "running steam"}, r.Reasons)
+}
+
+func TestMatchPrograms(t *testing.T) {
+	running := []string{"bash", "FFmpeg.exe", "/Applications/Steam.app/Contents/MacOS/steam_osx"}
+	assert.Equal(t, []string{"ffmpeg"}, matchPrograms(running, []string{"ffmpeg", "steam", "obs"}))
+	assert.Equal(t, []string{"steam_osx.exe", "BASH"}, matchPrograms(running, []string{"steam_osx.exe", "BASH"}))
+	assert.Empty(t, DetectProcesses(context.Background(), nil))
+}
+
+func TestParseProcessLists(t *testing.T) {
+	assert.Equal(t, []string{"/sbin/launchd", "/usr/bin/ffmpeg"}, parsePsPrograms("/sbin/launchd\n  /usr/bin/ffmpeg  \n\n"))
+	tasklist := "\"System Idle Process\",\"0\",\"Services\",\"0\",\"8 K\"\r\n\"ffmpeg.exe\",\"4242\",\"Console\",\"1\",\"20,480 K\"\r\n"
+	assert.Equal(t, []string{"System Idle Process", "ffmpeg.exe"}, parseTasklistPrograms(tasklist))
+}
+
+func TestProcPrograms(t *testing.T) {
+	root := t.TempDir()
+	write := func(pid, name, content string) {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, pid), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, pid, name), []byte(content), 0644))
+	}
+	// The command line has the full name that comm cuts to 15 characters
+	write("42", "cmdline", "/opt/tools/handbrake-encoder\x00--preset\x00")
+	write("42", "comm", "handbrake-encod\n")
+	// Kernel threads only have comm
+	write("2", "comm", "kthreadd\n")
+	write("self", "comm", "bash\n")
+
+	programs, err := procPrograms(root)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"handbrake-encoder", "handbrake-encod", "kthreadd"}, programs)
+
+	_, err = procPrograms(filepath.Join(root, "missing"))
+	assert.Error(t, err)
+}
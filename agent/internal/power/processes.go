@@ -0,0 +1,110 @@
+package power
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DetectProcesses returns the programs of names that are running, in the order of names.
+// The process table cannot always be read, in which case none are reported.
+func DetectProcesses(ctx context.Context, names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	running, err := runningPrograms(ctx)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to list running processes")
+		return nil
+	}
+	return matchPrograms(running, names)
+}
+
+// matchPrograms returns the names found among the running programs
+func matchPrograms(running []string, names []string) []string {
+	seen := make(map[string]bool, len(running))
+	for _, program := range running {
+		seen[programName(program)] = true
+	}
+
+	var found []string
+	for _, name := range names {
+		if seen[programName(name)] {
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+// programName reduces a program path or name to the form names are compared in: the base
+// name in lower case, without a .exe suffix
+func programName(program string) string {
+	name := strings.ToLower(strings.TrimSpace(program))
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// procPrograms lists the programs of the processes under root, a /proc file system. The
+// name in comm is cut to 15 characters, so the first argument of each command line is
+// listed too.
+func procPrograms(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var programs []string
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+			if arg0, _, _ := bytes.Cut(cmdline, []byte{0}); len(arg0) > 0 {
+				programs = append(programs, filepath.Base(string(arg0)))
+			}
+		}
+		// Kernel threads have no command line, and a process may have renamed itself
+		if comm := readTrimmed(filepath.Join(dir, "comm")); comm != "" {
+			programs = append(programs, comm)
+		}
+	}
+	return programs, nil
+}
+
+// parsePsPrograms reads the output of "ps -axo comm=", one program, often a full path, per line
+func parsePsPrograms(output string) []string {
+	var programs []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			programs = append(programs, line)
+		}
+	}
+	return programs
+}
+
+// parseTasklistPrograms reads the output of Windows "tasklist /fo csv /nh", whose first
+// column is the image name
+func parseTasklistPrograms(output string) []string {
+	reader := csv.NewReader(strings.NewReader(output))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil
+	}
+	var programs []string
+	for _, record := range records {
+		if len(record) > 0 && record[0] != "" {
+			programs = append(programs, record[0])
+		}
+	}
+	return programs
+}
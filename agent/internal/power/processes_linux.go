@@ -0,0 +1,10 @@
+//go:build linux
+
+package power
+
+import "context"
+
+// runningPrograms reads the programs of the processes from /proc
+func runningPrograms(ctx context.Context) ([]string, error) {
+	return procPrograms("/proc")
+}
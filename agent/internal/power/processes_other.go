@@ -0,0 +1,20 @@
+//go:build !linux && !windows
+
+package power
+
+import (
+	"context"
+	"os/exec"
+)
+
+// runningPrograms asks ps for the program of every process
+func runningPrograms(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "ps", "-axo", "comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parsePsPrograms(string(output)), nil
+}
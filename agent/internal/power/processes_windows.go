@@ -0,0 +1,20 @@
+//go:build windows
+
+package power
+
+import (
+	"context"
+	"os/exec"
+)
+
+// runningPrograms asks tasklist for the image name of every process
+func runningPrograms(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "tasklist", "/fo", "csv", "/nh").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseTasklistPrograms(string(output)), nil
+}
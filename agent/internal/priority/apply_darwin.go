@@ -0,0 +1,30 @@
+package priority
+
+import (
+	"fmt"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"golang.org/x/sys/unix"
+)
+
+// Values of setpriority(2) that move a process to the background QoS
+const (
+	prioDarwinProcess = 4
+	prioDarwinBG      = 0x1000
+)
+
+// apply lowers the nice value, or moves the process to the background QoS, where macOS
+// throttles both its CPU and its disk access
+func apply(level string) error {
+	if level == config.PriorityIdle {
+		if err := unix.Setpriority(prioDarwinProcess, 0, prioDarwinBG); err != nil {
+			return fmt.Errorf("failed to move to the background: %w", err)
+		}
+		return nil
+	}
+
+	if err := unix.Setpriority(unix.PRIO_PROCESS, 0, 10); err != nil {
+		return fmt.Errorf("failed to set CPU priority: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package priority
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"golang.org/x/sys/unix"
+)
+
+// I/O scheduling values of ioprio_set(2)
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioClassBE    = 2 // Best effort, with levels from 0 (highest) to 7
+	ioprioClassIdle  = 3 // Only when no other process uses the disk
+)
+
+// apply sets the nice value and I/O class of every thread of the process. Linux keeps both
+// per thread; threads started later inherit them from the thread creating them.
+func apply(level string) error {
+	nice, ioprio := 10, ioprioClassBE<<ioprioClassShift|7
+	if level == config.PriorityIdle {
+		nice, ioprio = 19, ioprioClassIdle<<ioprioClassShift
+	}
+
+	threads, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return fmt.Errorf("failed to list threads: %w", err)
+	}
+	for _, thread := range threads {
+		tid, err := strconv.Atoi(thread.Name())
+		if err != nil {
+			continue
+		}
+		if err := unix.Setpriority(unix.PRIO_PROCESS, tid, nice); err != nil {
+			return fmt.Errorf("failed to set CPU priority: %w", err)
+		}
+		if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(ioprio)); errno != 0 {
+			return fmt.Errorf("failed to set I/O priority: %w", errno)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package priority
+
+// apply reports ErrUnsupported on platforms without a supported method
+func apply(level string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,25 @@
+package priority
+
+import (
+	"fmt"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"golang.org/x/sys/windows"
+)
+
+// apply lowers the priority class of the process. The idle level enters background mode
+// instead, in which Windows gives the process the lowest CPU, I/O and memory priority.
+func apply(level string) error {
+	process := windows.CurrentProcess()
+	if level == config.PriorityIdle {
+		if err := windows.SetPriorityClass(process, windows.PROCESS_MODE_BACKGROUND_BEGIN); err != nil {
+			return fmt.Errorf("failed to enter background mode: %w", err)
+		}
+		return nil
+	}
+
+	if err := windows.SetPriorityClass(process, windows.BELOW_NORMAL_PRIORITY_CLASS); err != nil {
+		return fmt.Errorf("failed to set CPU priority: %w", err)
+	}
+	return nil
+}
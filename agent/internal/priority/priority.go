@@ -0,0 +1,97 @@
+package priority
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+)
+
+// ErrUnsupported is returned by Apply on platforms without a way to lower the priority
+var ErrUnsupported = errors.New("the process priority cannot be changed on this platform")
+
+// hashChunk is the most a throttled hash reads at once, so the limit is kept smoothly
+const hashChunk = 64 << 10
+
+// hashing caps the reads of every file hashed by the agent
+var hashing = &Limiter{}
+
+// Apply lowers the CPU and I/O priority of the agent process to level, one of the
+// config.Priority values. Priorities only go down: raising them back takes a restart.
+func Apply(level string) error {
+	if level == "" || level == config.PriorityNormal {
+		return nil
+	}
+	if err := config.ValidatePriority(level); err != nil {
+		return err
+	}
+	return apply(level)
+}
+
+// SetHashThrottle changes the hashing limit in bytes/sec, 0 for none. Hashes already
+// running follow the new limit.
+func SetHashThrottle(bytesPerSec int64) {
+	hashing.SetRate(bytesPerSec)
+}
+
+// HashReader wraps the reader of a file being hashed, so that all hashing together stays
+// under the hashing limit
+func HashReader(reader io.Reader) io.Reader {
+	return hashing.Reader(reader)
+}
+
+// Limiter caps the combined throughput of the readers it wraps
+type Limiter struct {
+	rate int64     // Bytes/sec, 0 for no limit
+	next time.Time // When the bytes read so far have been paid for
+	mu   sync.Mutex
+}
+
+// SetRate changes the limit in bytes/sec, 0 for none
+func (l *Limiter) SetRate(bytesPerSec int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = bytesPerSec
+}
+
+// Reader returns reader with its reads counted against the limit
+func (l *Limiter) Reader(reader io.Reader) io.Reader {
+	return &limitedReader{reader: reader, limiter: l}
+}
+
+// wait sleeps until n more bytes fit in the limit
+func (l *Limiter) wait(n int) {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	l.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+// limitedReader is a reader whose reads wait for the limit of its Limiter
+type limitedReader struct {
+	reader  io.Reader
+	limiter *Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > hashChunk {
+		p = p[:hashChunk]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.limiter.wait(n)
+	}
+	return n, err
+}
@@ -0,0 +1,42 @@
+package priority
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterSharesRateAcrossReaders(t *testing.T) {
+	limiter := &Limiter{}
+	limiter.SetRate(1 << 20)
+
+	// Two readers of 256 KiB share 1 MiB/s, so reading both takes about half a second
+	start := time.Now()
+	done := make(chan int64, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			n, _ := io.Copy(io.Discard, limiter.Reader(bytes.NewReader(make([]byte, 256<<10))))
+			done <- n
+		}()
+	}
+	assert.Equal(t, int64(256<<10), <-done)
+	assert.Equal(t, int64(256<<10), <-done)
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	// Without a limit reads go straight through
+	limiter.SetRate(0)
+	start = time.Now()
+	_, err := io.Copy(io.Discard, limiter.Reader(bytes.NewReader(make([]byte, 4<<20))))
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestApplyNormalLeavesPriority(t *testing.T) {
+	assert.NoError(t, Apply(""))
+	assert.NoError(t, Apply(config.PriorityNormal))
+	assert.Error(t, Apply("realtime"))
+}
@@ -0,0 +1,110 @@
+// Package runs keeps the summary of each sync run of the agent in the shared database, where
+// the CLI reads the last ones.
+package runs
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/martinshumberto/sync-manager/common/database"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultKeep is how many runs of each folder are kept
+const DefaultKeep = 100
+
+// Store writes sync runs to the database on behalf of a device
+type Store struct {
+	dsn      string
+	deviceID string
+	keep     int
+
+	mu sync.Mutex
+	db *gorm.DB
+}
+
+// NewStore creates a store writing to the database at dsn on behalf of deviceID
+func NewStore(dsn, deviceID string) *Store {
+	return &Store{dsn: dsn, deviceID: deviceID, keep: DefaultKeep}
+}
+
+// Record adds a run of the device, forgetting the oldest runs of its folder past the ones kept
+func (s *Store) Record(run models.SyncRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	run.ID = 0
+	run.DeviceID = s.deviceID
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&run).Error; err != nil {
+			return fmt.Errorf("failed to record sync run: %w", err)
+		}
+
+		var kept []uint
+		err := tx.Model(&models.SyncRun{}).
+			Where("folder_id = ? AND device_id = ?", run.FolderID, s.deviceID).
+			Order("started_at DESC, id DESC").
+			Limit(s.keep).
+			Pluck("id", &kept).Error
+		if err != nil {
+			return fmt.Errorf("failed to list sync runs: %w", err)
+		}
+		err = tx.Where("folder_id = ? AND device_id = ? AND id NOT IN ?", run.FolderID, s.deviceID, kept).
+			Delete(&models.SyncRun{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to remove old sync runs: %w", err)
+		}
+		return nil
+	})
+}
+
+// Close closes the database when it was opened
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	s.db = nil
+	return sqlDB.Close()
+}
+
+// open opens the database and creates the runs table when missing. mu must be held.
+func (s *Store) open() (*gorm.DB, error) {
+	if s.db != nil {
+		return s.db, nil
+	}
+
+	dialector, driver, err := database.Dialector(s.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == database.SQLite {
+		// The CLI may be reading at the same time
+		dialector = sqlite.Open("file:" + s.dsn + "?_busy_timeout=5000")
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.AutoMigrate(&models.SyncRun{}); err != nil {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		return nil, fmt.Errorf("failed to create sync runs table: %w", err)
+	}
+	s.db = db
+	return db, nil
+}
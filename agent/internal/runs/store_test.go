@@ -0,0 +1,33 @@
+package runs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreKeepsLastRunsPerFolder(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "sync-manager.db"), "desktop")
+	defer store.Close()
+	store.keep = 2
+
+	start := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		started := start.Add(time.Duration(i) * time.Hour)
+		assert.NoError(t, store.Record(models.SyncRun{FolderID: "docs", StartedAt: started, FinishedAt: started.Add(time.Minute), FilesUploaded: int64(i)}))
+	}
+	assert.NoError(t, store.Record(models.SyncRun{FolderID: "photos", StartedAt: start}))
+
+	var runs []models.SyncRun
+	assert.NoError(t, store.db.Order("folder_id, started_at").Find(&runs).Error)
+	if assert.Len(t, runs, 3) {
+		assert.Equal(t, int64(1), runs[0].FilesUploaded)
+		assert.Equal(t, int64(2), runs[1].FilesUploaded)
+		assert.Equal(t, "photos", runs[2].FolderID)
+		assert.Equal(t, "desktop", runs[2].DeviceID)
+		assert.Equal(t, time.Minute, runs[1].Duration())
+	}
+}
@@ -0,0 +1,326 @@
+// Package staging manages the directory the agent writes temporary copies of files to, such
+// as the output of transforms before an upload or remote objects being copied, so they never
+// fill the system disk. The space taken is capped: files being written or read stay, while
+// released files are kept for reuse until room is needed, the least recently used going first.
+package staging
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrFull is returned, wrapped, when a file does not fit in the staging area even after every
+// released file was evicted
+var ErrFull = errors.New("staging area is full")
+
+// Usage is the accounting of a staging area
+type Usage struct {
+	Bytes     int64 // Space taken by staged files, including what writers reserved
+	MaxBytes  int64 // Cap on Bytes, 0 for none
+	Files     int   // Files staged, in use or not
+	InUse     int   // Files being written or read
+	Evictions int64 // Released files removed to make room since the area was opened
+}
+
+// entry is a staged file
+type entry struct {
+	key  string
+	path string
+	size int64         // Bytes accounted for, reserved or written
+	refs int           // Open Files on it
+	lru  *list.Element // Position among the released files, nil while in use
+}
+
+// Area is a staging directory with a size cap. A nil Area stages files in the system
+// temporary directory, without a cap, removing them once closed.
+type Area struct {
+	dir string
+
+	mu        sync.Mutex
+	maxBytes  int64
+	used      int64
+	entries   map[string]*entry
+	released  *list.List // Entries no File has open, most recently used first
+	evictions int64
+}
+
+// DefaultDir returns the staging directory used when the configuration sets none
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "staging"), nil
+}
+
+// New opens the staging area kept in dir, capped at maxBytes, 0 for no cap. Files left by a
+// previous run are removed, as nothing records what they held.
+func New(dir string, maxBytes int64) (*Area, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	leftovers, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staging directory: %w", err)
+	}
+	for _, leftover := range leftovers {
+		if err := os.RemoveAll(filepath.Join(dir, leftover.Name())); err != nil {
+			return nil, fmt.Errorf("failed to clear staging directory: %w", err)
+		}
+	}
+
+	return &Area{
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*entry),
+		released: list.New(),
+	}, nil
+}
+
+// Dir returns the directory of the staging area
+func (a *Area) Dir() string {
+	if a == nil {
+		return os.TempDir()
+	}
+	return a.dir
+}
+
+// SetMaxBytes changes the cap of the staging area, evicting released files above it
+func (a *Area) SetMaxBytes(maxBytes int64) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.maxBytes = maxBytes
+	a.evict(0)
+}
+
+// Usage returns the accounting of the staging area
+func (a *Area) Usage() Usage {
+	if a == nil {
+		return Usage{}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return Usage{
+		Bytes:     a.used,
+		MaxBytes:  a.maxBytes,
+		Files:     len(a.entries),
+		InUse:     len(a.entries) - a.released.Len(),
+		Evictions: a.evictions,
+	}
+}
+
+// Create stages a new file for key, reserving size bytes for it; writing more reserves the
+// rest as it goes. A released file staged under the same key is replaced. Close keeps the
+// file for Open, Remove discards it.
+func (a *Area) Create(key string, size int64) (*File, error) {
+	if a == nil {
+		file, err := os.CreateTemp("", "sync-manager-stage-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create staging file: %w", err)
+		}
+		return &File{file: file}, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if old, ok := a.entries[key]; ok {
+		if old.refs > 0 {
+			return nil, fmt.Errorf("staging file %s is in use", key)
+		}
+		a.drop(old)
+	}
+	if err := a.reserve(size); err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(a.dir, "stage-*")
+	if err != nil {
+		a.used -= size
+		return nil, fmt.Errorf("failed to create staging file: %w", err)
+	}
+	e := &entry{key: key, path: file.Name(), size: size, refs: 1}
+	a.entries[key] = e
+	return &File{file: file, area: a, entry: e}, nil
+}
+
+// Open returns the file staged for key, read-only, if it is still kept. The file cannot be
+// evicted until it is closed.
+func (a *Area) Open(key string) (*File, bool) {
+	if a == nil {
+		return nil, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e, ok := a.entries[key]
+	if !ok || e.lru == nil {
+		return nil, false
+	}
+	file, err := os.Open(e.path)
+	if err != nil {
+		a.drop(e)
+		return nil, false
+	}
+	a.released.Remove(e.lru)
+	e.lru = nil
+	e.refs++
+	return &File{file: file, area: a, entry: e, readOnly: true}, true
+}
+
+// reserve makes room for size more bytes, evicting released files. The caller holds a.mu.
+func (a *Area) reserve(size int64) error {
+	a.evict(size)
+	if a.maxBytes > 0 && a.used+size > a.maxBytes {
+		return fmt.Errorf("%w: %d bytes needed, %d of %d bytes in use", ErrFull, size, a.used, a.maxBytes)
+	}
+	a.used += size
+	return nil
+}
+
+// evict removes released files, least recently used first, until size more bytes fit under
+// the cap. The caller holds a.mu.
+func (a *Area) evict(size int64) {
+	if a.maxBytes <= 0 {
+		return
+	}
+	for a.used+size > a.maxBytes && a.released.Len() > 0 {
+		e := a.released.Back().Value.(*entry)
+		log.Debug().Str("key", e.key).Int64("size", e.size).Msg("Evicting staged file")
+		a.drop(e)
+		a.evictions++
+	}
+}
+
+// drop forgets a released entry and deletes its file. The caller holds a.mu.
+func (a *Area) drop(e *entry) {
+	if e.lru != nil {
+		a.released.Remove(e.lru)
+		e.lru = nil
+	}
+	delete(a.entries, e.key)
+	a.used -= e.size
+	if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+		log.Warn().Err(err).Str("path", e.path).Msg("Failed to remove staged file")
+	}
+}
+
+// release gives back a File on e, accounting for the size it was left with. The last one to
+// go keeps the file for reuse unless discard is set.
+func (a *Area) release(e *entry, size int64, discard bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if size >= 0 {
+		a.used += size - e.size
+		e.size = size
+	}
+	e.refs--
+	if e.refs > 0 {
+		return
+	}
+	if discard {
+		a.drop(e)
+		return
+	}
+	e.lru = a.released.PushFront(e)
+	a.evict(0)
+}
+
+// File is a file in a staging area. Writes past its reservation take more of the area.
+type File struct {
+	file     *os.File
+	area     *Area
+	entry    *entry
+	readOnly bool
+	end      int64 // Largest offset written
+	closed   bool
+}
+
+// Name returns the path of the file
+func (f *File) Name() string {
+	return f.file.Name()
+}
+
+// Read reads from the file
+func (f *File) Read(p []byte) (int, error) {
+	return f.file.Read(p)
+}
+
+// Seek moves the offset of the next read or write
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	return f.file.Seek(offset, whence)
+}
+
+// Write writes to the file, reserving room in the staging area for bytes past what the file
+// already holds. A write that does not fit fails with ErrFull before anything is written.
+func (f *File) Write(p []byte) (int, error) {
+	if f.area != nil && !f.readOnly {
+		offset, err := f.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		if err := f.grow(offset + int64(len(p))); err != nil {
+			return 0, err
+		}
+	}
+	return f.file.Write(p)
+}
+
+// grow reserves room for the file to reach end bytes
+func (f *File) grow(end int64) error {
+	f.area.mu.Lock()
+	defer f.area.mu.Unlock()
+
+	if extra := end - f.entry.size; extra > 0 {
+		if err := f.area.reserve(extra); err != nil {
+			return err
+		}
+		f.entry.size += extra
+	}
+	f.end = max(f.end, end)
+	return nil
+}
+
+// Close closes the file, keeping it staged for Open until room is needed
+func (f *File) Close() error {
+	return f.close(false)
+}
+
+// Remove closes the file and discards it once no other File has it open
+func (f *File) Remove() error {
+	return f.close(true)
+}
+
+func (f *File) close(discard bool) error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	err := f.file.Close()
+
+	if f.area == nil {
+		os.Remove(f.file.Name())
+		return err
+	}
+	size := int64(-1)
+	if !f.readOnly {
+		// The reservation shrinks to what was written
+		size = f.end
+		if info, statErr := os.Stat(f.file.Name()); statErr == nil {
+			size = info.Size()
+		}
+	}
+	f.area.release(f.entry, size, discard || (err != nil && !f.readOnly))
+	return err
+}
@@ -0,0 +1,103 @@
+package staging
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stage(t *testing.T, area *Area, key, content string) {
+	t.Helper()
+	file, err := area.Create(key, 0)
+	assert.NoError(t, err)
+	_, err = io.Copy(file, strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+}
+
+func TestAreaEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "stage-old"), []byte("leftover"), 0600))
+	area, err := New(dir, 10)
+	assert.NoError(t, err)
+
+	// Files of a previous run are cleared
+	_, err = os.Stat(filepath.Join(dir, "stage-old"))
+	assert.True(t, os.IsNotExist(err))
+
+	stage(t, area, "a", "aaaa")
+	stage(t, area, "b", "bbbb")
+	assert.Equal(t, Usage{Bytes: 8, MaxBytes: 10, Files: 2}, area.Usage())
+
+	// Reading a makes b the least recently used, so b goes to make room for c
+	file, ok := area.Open("a")
+	assert.True(t, ok)
+	data, err := io.ReadAll(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaa", string(data))
+	assert.NoError(t, file.Close())
+
+	stage(t, area, "c", "cccc")
+	_, ok = area.Open("b")
+	assert.False(t, ok)
+	assert.Equal(t, Usage{Bytes: 8, MaxBytes: 10, Files: 2, Evictions: 1}, area.Usage())
+
+	// Removed files are not kept
+	file, ok = area.Open("c")
+	assert.True(t, ok)
+	assert.NoError(t, file.Remove())
+	_, ok = area.Open("c")
+	assert.False(t, ok)
+	assert.Equal(t, int64(4), area.Usage().Bytes)
+
+	// Lowering the cap evicts what no longer fits
+	area.SetMaxBytes(2)
+	assert.Equal(t, Usage{MaxBytes: 2, Evictions: 2}, area.Usage())
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestAreaRefusesWritesOverTheCap(t *testing.T) {
+	area, err := New(t.TempDir(), 8)
+	assert.NoError(t, err)
+
+	// Files in use are never evicted, so a write that does not fit fails
+	writer, err := area.Create("big", 6)
+	assert.NoError(t, err)
+	_, err = area.Create("other", 4)
+	assert.ErrorIs(t, err, ErrFull)
+
+	_, err = writer.Write([]byte("123456"))
+	assert.NoError(t, err)
+	_, err = writer.Write([]byte("789"))
+	assert.ErrorIs(t, err, ErrFull)
+	assert.Equal(t, Usage{Bytes: 6, MaxBytes: 8, Files: 1, InUse: 1}, area.Usage())
+
+	// Rewriting bytes already held takes no more room
+	_, err = writer.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	_, err = writer.Write([]byte("abc"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Remove())
+	assert.Equal(t, Usage{MaxBytes: 8}, area.Usage())
+}
+
+func TestNilAreaUsesTempDir(t *testing.T) {
+	var area *Area
+	file, err := area.Create("copy", 0)
+	assert.NoError(t, err)
+	_, err = file.Write([]byte("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.TempDir(), filepath.Dir(file.Name()))
+
+	assert.NoError(t, file.Close())
+	_, err = os.Stat(file.Name())
+	assert.True(t, os.IsNotExist(err))
+	_, ok := area.Open("copy")
+	assert.False(t, ok)
+}
@@ -0,0 +1,56 @@
+package sync
+
+import (
+	"os"
+	"time"
+
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/rs/zerolog/log"
+)
+
+// outsideWindow reports whether a file last modified at modTime is left out by the max_age and
+// modified_since filters of folder. Such files are neither uploaded nor taken as deleted, so
+// their remote copies stay as they are.
+func (folder *FolderSync) outsideWindow(modTime, now time.Time) bool {
+	if maxAge := folder.AgeFilter.MaxAge; maxAge > 0 && now.Sub(modTime) > maxAge {
+		return true
+	}
+	// The configuration was validated when loaded
+	since, _ := commonconfig.ParseModifiedSince(folder.AgeFilter.ModifiedSince)
+	return modTime.Before(since)
+}
+
+// tooRecent reports whether the upload of the file at path waits for the min_age of its
+// folder. The wait is recorded as a deferred upload, so watchInUse queues the file once it is
+// old enough, and the in-use timeout does not cut it short.
+func (sm *SyncManager) tooRecent(folder *FolderSync, key deferredKey, path string) bool {
+	minAge := folder.AgeFilter.MinAge
+	if minAge <= 0 {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	now := time.Now()
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	deferred, waiting := sm.deferred[key]
+	if now.Sub(info.ModTime()) >= minAge {
+		// From here on the file is only held back while it is in use
+		if waiting && deferred.young {
+			delete(sm.deferred, key)
+		}
+		return false
+	}
+
+	if !waiting {
+		log.Debug().Str("file", key.path).Dur("min_age", minAge).Msg("File modified too recently, deferring upload")
+		deferred = deferredUpload{since: now}
+	}
+	deferred.young = true
+	deferred.changed, deferred.size, deferred.modTime = now, info.Size(), info.ModTime()
+	sm.deferred[key] = deferred
+	return true
+}
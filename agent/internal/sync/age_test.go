@@ -0,0 +1,92 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/config"
+	"github.com/martinshumberto/sync-manager/agent/internal/inuse"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutsideWindow(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	folder := &FolderSync{AgeFilter: config.AgeFilter{MaxAge: 7 * day}}
+	assert.False(t, folder.outsideWindow(now.Add(-6*day), now))
+	assert.True(t, folder.outsideWindow(now.Add(-8*day), now))
+
+	folder.AgeFilter = config.AgeFilter{ModifiedSince: "2024-06-10"}
+	assert.False(t, folder.outsideWindow(time.Date(2024, 6, 10, 0, 0, 0, 0, time.Local), now))
+	assert.True(t, folder.outsideWindow(time.Date(2024, 6, 9, 23, 59, 0, 0, time.Local), now))
+
+	// Without filters every file is in
+	assert.False(t, (&FolderSync{}).outsideWindow(time.Time{}, now))
+}
+
+func TestAgeFilterLeavesOldFilesAlone(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	_, err := remote.UploadFile(ctx, "docs/old.txt", strings.NewReader("remote copy"), map[string]string{})
+	assert.NoError(t, err)
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{
+		ID:        "docs",
+		Path:      t.TempDir(),
+		Enabled:   true,
+		Mirror:    commonconfig.MirrorConfig{DeleteOrphans: true, MaxDeletePercent: -1},
+		AgeFilter: commonconfig.AgeFilter{MaxAge: 24 * time.Hour},
+	}}
+	dir := cfg.SyncFolders[0].Path
+	for name, age := range map[string]time.Duration{"old.txt": 48 * time.Hour, "recent.txt": time.Hour} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(name), 0644))
+		modTime := time.Now().Add(-age)
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	manager := newConfiguredManager(t, cfg, remote)
+	folder := manager.folders["docs"]
+	assert.Equal(t, 24*time.Hour, folder.AgeFilter.MaxAge)
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+
+	// Only the recent file is picked up, and the remote copy of the old one is not an orphan
+	idx, err := manager.folderIndex("docs")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"recent.txt"}, idx.Paths())
+	assert.Contains(t, remoteKeys(t, remote, "docs/"), "docs/old.txt")
+}
+
+func TestMinAgeDefersRecentFiles(t *testing.T) {
+	manager, folder, idx := newVersionedManager(t, &versionedStorage{})
+	manager.openForWrite = func() (map[string]bool, error) { return nil, nil }
+	writers := func() *inuse.Writers { return inuse.NewWriters(manager.openForWrite) }
+	folder.AgeFilter = config.AgeFilter{MinAge: time.Minute}
+	folder.InUseTimeout = -1
+
+	// A file just written waits for the min age, even where files in use do not wait
+	recordFile(t, manager, idx, folder, "draft.txt", "draft")
+	entry, _ := idx.Get("draft.txt")
+	assert.False(t, manager.uploadReady(folder, entry, writers()))
+	key := deferredKey{folderID: folder.ID, path: "draft.txt"}
+	assert.True(t, manager.deferred[key].young)
+
+	// Nor does the in-use timeout cut the wait short
+	folder.InUseTimeout = time.Second
+	deferred := manager.deferred[key]
+	deferred.since = time.Now().Add(-time.Hour)
+	manager.deferred[key] = deferred
+	assert.False(t, manager.uploadReady(folder, entry, writers()))
+
+	// Once old enough it goes up
+	past := time.Now().Add(-2 * time.Minute)
+	assert.NoError(t, os.Chtimes(filepath.Join(folder.Path, "draft.txt"), past, past))
+	assert.True(t, manager.uploadReady(folder, entry, writers()))
+	assert.Empty(t, manager.deferred)
+}
@@ -0,0 +1,138 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/watcher"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/cloudfile"
+	"github.com/martinshumberto/sync-manager/common/placeholder"
+	"github.com/martinshumberto/sync-manager/common/telemetry"
+	"github.com/rs/zerolog/log"
+)
+
+// day is the unit of the archive policy of a folder
+const day = 24 * time.Hour
+
+// archiveFiles replaces the local copy of the files of a folder that were uploaded and left
+// unmodified for folder.ArchiveAfter with placeholders, freeing their disk space. Files come
+// back with 'sync-manager fetch', or when a newer version is downloaded.
+func (sm *SyncManager) archiveFiles(ctx context.Context, folder *FolderSync, idx *index.Index) {
+	sm.mu.RLock()
+	paused := sm.paused
+	sm.mu.RUnlock()
+	if paused {
+		return
+	}
+
+	ctx, span := telemetry.Tracer().Start(ctx, "sync.archive")
+	defer span.End()
+
+	cutoff := time.Now().Add(-folder.ArchiveAfter)
+	excluded := sm.excludePatterns(folder)
+	var files, freed int64
+	for _, relPath := range idx.Paths() {
+		if ctx.Err() != nil {
+			break
+		}
+		entry, ok := idx.Get(relPath)
+		if !ok || entry.Pending || entry.Deleted || entry.Dir || entry.Archived || entry.RemoteHash == "" ||
+			entry.RemoteSize != entry.Size || !entry.ModTime.Before(cutoff) ||
+			strings.Contains(relPath, "\n") || watcher.ShouldExclude(relPath, excluded) {
+			continue
+		}
+
+		archived, err := sm.archiveFile(ctx, folder, idx, entry)
+		if err != nil {
+			log.Warn().Err(err).Str("folder", folder.ID).Str("file", relPath).Msg("Failed to archive file")
+			continue
+		}
+		if archived {
+			files++
+			freed += entry.Size
+		}
+	}
+	span.SetAttributes(telemetry.FilesKey.Int64(files))
+
+	if files > 0 {
+		log.Info().
+			Str("folder", folder.ID).
+			Int64("files", files).
+			Int64("bytes_freed", freed).
+			Msg("Archived files to free disk space")
+	}
+}
+
+// archiveFile replaces one file with its placeholder once both the local file and its remote
+// copy are checked to still be the content last uploaded, as the remote copy becomes the
+// only one. It reports whether the file was archived.
+func (sm *SyncManager) archiveFile(ctx context.Context, folder *FolderSync, idx *index.Index, entry index.Entry) (bool, error) {
+	localPath := folder.localPath(entry.LocalRelPath())
+	info, err := os.Lstat(localPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+		// Changed since it was recorded, which the next scan picks up
+		return false, nil
+	}
+	if cloudfile.Dehydrated(info) {
+		// Its content is already only online, with its cloud provider
+		return false, nil
+	}
+
+	stub := placeholder.Placeholder{FolderID: folder.ID, Path: entry.Path, Size: entry.Size, Hash: entry.RemoteHash, ModTime: entry.ModTime}
+	if entry.Size <= int64(len(stub.Encode())) {
+		return false, nil
+	}
+
+	_, metadata, err := sm.storage.GetFileInfo(ctx, folder.key(entry.Path))
+	if err != nil {
+		return false, fmt.Errorf("failed to get remote file info: %w", err)
+	}
+	if checksum.FromMetadata(metadata) != entry.RemoteHash {
+		return false, nil
+	}
+	if hash, err := fileHash(localPath, checksum.Of(entry.RemoteHash)); err != nil || hash != entry.RemoteHash {
+		return false, err
+	}
+
+	// Marked first, so the watcher event of the placeholder is not taken as a change
+	entry.Archived = true
+	idx.Put(entry)
+	if err := placeholder.Write(localPath, stub); err != nil {
+		entry.Archived = false
+		idx.Put(entry)
+		return false, err
+	}
+	return true, nil
+}
+
+// archivedContent reports whether the file at relPath is still the placeholder of an archived
+// file. A file fetched or written in its place is no longer archived, and is then compared
+// with the copy last synced like any other file.
+func (sm *SyncManager) archivedContent(idx *index.Index, relPath, localRel string, info os.FileInfo) (index.Entry, bool) {
+	entry, ok := idx.Get(relPath)
+	if !ok || !entry.Archived {
+		return index.Entry{}, false
+	}
+
+	sm.mu.RLock()
+	folder := sm.folders[idx.FolderID]
+	sm.mu.RUnlock()
+	if folder == nil {
+		return index.Entry{}, false
+	}
+
+	if info.Size() <= placeholder.MaxSize {
+		if stub, err := placeholder.Read(folder.localPath(localRel)); err == nil && stub.Hash == entry.RemoteHash {
+			return entry, true
+		}
+	}
+
+	entry.Archived = false
+	idx.Put(entry)
+	return index.Entry{}, false
+}
@@ -0,0 +1,93 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/placeholder"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveReplacesOldFilesWithPlaceholders(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	video := strings.Repeat("frame", 2000)
+	for name, content := range map[string]string{"video.mp4": video, "notes.txt": "short"} {
+		_, err := remote.UploadFile(ctx, "docs/"+name, strings.NewReader(content), map[string]string{
+			index.MetadataDeviceID:      "laptop",
+			index.MetadataVersionVector: index.VersionVector{"laptop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true, ArchiveAfterDays: 30}}
+	manager := newConfiguredManager(t, cfg, remote)
+	folder := manager.folders["docs"]
+	assert.Equal(t, 30*day, folder.ArchiveAfter)
+
+	// Nothing is old enough yet
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+	videoPath := filepath.Join(cfg.SyncFolders[0].Path, "video.mp4")
+	assertFile(t, videoPath, video)
+
+	// Once it is, the video becomes a placeholder; the note is smaller than one and stays
+	folder.ArchiveAfter = time.Nanosecond
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+	stub, err := placeholder.Read(videoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "docs", stub.FolderID)
+	assert.Equal(t, "video.mp4", stub.Path)
+	assert.Equal(t, int64(len(video)), stub.Size)
+	assertFile(t, filepath.Join(cfg.SyncFolders[0].Path, "notes.txt"), "short")
+
+	idx, err := manager.folderIndex("docs")
+	assert.NoError(t, err)
+	summary := idx.Summary()
+	assert.Equal(t, int64(2), summary.Files)
+	assert.Equal(t, int64(1), summary.Archived)
+	assert.Equal(t, int64(len(video)), summary.ArchivedBytes)
+
+	// The placeholder is not a local change to upload
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+	assert.Zero(t, idx.PendingFiles())
+	entry, _ := idx.Get("video.mp4")
+	assert.True(t, entry.Archived)
+
+	// Fetched back, the file is local again without being uploaded, and stays so for the
+	// days of the policy
+	folder.ArchiveAfter = 30 * day
+	assert.NoError(t, os.WriteFile(videoPath, []byte(video), 0644))
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+	entry, _ = idx.Get("video.mp4")
+	assert.False(t, entry.Archived)
+	assert.False(t, entry.Pending)
+	assert.Zero(t, idx.Summary().Archived)
+}
+
+func TestArchiveKeepsFilesNotUploaded(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, ArchiveAfterDays: 1}}
+	manager := newConfiguredManager(t, cfg, remote)
+	folder := manager.folders["docs"]
+	folder.ArchiveAfter = time.Nanosecond
+
+	// A local file whose upload has not finished is never archived
+	path := filepath.Join(cfg.SyncFolders[0].Path, "draft.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Repeat("draft", 1000)), 0644))
+	old := time.Now().Add(-48 * time.Hour)
+	assert.NoError(t, os.Chtimes(path, old, old))
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+
+	_, err := placeholder.Read(path)
+	assert.ErrorIs(t, err, placeholder.ErrNotPlaceholder)
+}
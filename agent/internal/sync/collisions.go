@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/rs/zerolog/log"
+)
+
+// caseInsensitive reports whether the filesystem holding dir treats names that differ only in
+// case as the same file, as macOS and Windows do by default. It is a variable so tests can
+// stand in for such a filesystem.
+var caseInsensitive = func(dir string) bool {
+	probe, err := os.CreateTemp(dir, ".sync-manager-case-*.tmp")
+	if err != nil {
+		return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+	}
+	name := probe.Name()
+	probe.Close()
+	defer os.Remove(name)
+
+	_, err = os.Stat(filepath.Join(dir, strings.ToUpper(filepath.Base(name))))
+	return err == nil
+}
+
+// caseCollisions groups the remote keys of a folder whose names differ only in case. On a
+// case-insensitive filesystem downloading them would write every one to the same file, so
+// they are left out of the sync until all but one is renamed. Each group is sorted, and the
+// groups are sorted by their first key.
+func caseCollisions(keys []string) [][]string {
+	byFolded := make(map[string][]string)
+	for _, key := range keys {
+		folded := strings.ToLower(key)
+		byFolded[folded] = append(byFolded[folded], key)
+	}
+
+	var groups [][]string
+	for _, group := range byFolded {
+		if len(group) > 1 {
+			sort.Strings(group)
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
+
+// skipCaseCollisions records the case collisions among the remote keys of a folder and returns
+// the keys that collide, which are not downloaded. A collision is reported as an event
+// when first found, and published in the folder status for as long as it lasts.
+func (sm *SyncManager) skipCaseCollisions(folder *FolderSync, keys []string) map[string]bool {
+	var groups [][]string
+	if caseInsensitive(folder.Path) {
+		groups = caseCollisions(keys)
+	}
+
+	sm.mu.Lock()
+	known := make(map[string]bool, len(folder.collisions))
+	for _, group := range folder.collisions {
+		known[strings.Join(group, "\x00")] = true
+	}
+	folder.collisions = groups
+	sm.mu.Unlock()
+
+	skipped := make(map[string]bool)
+	for _, group := range groups {
+		for _, key := range group {
+			skipped[key] = true
+		}
+		if known[strings.Join(group, "\x00")] {
+			continue
+		}
+		log.Warn().Str("folder", folder.ID).Strs("files", group).Msg("Remote files differ only in case, not downloading them")
+		sm.recordEvent(folder.ID, group[0], models.SyncEventCaseCollision, models.CaseCollisionDetails{Paths: group})
+	}
+	return skipped
+}
@@ -0,0 +1,73 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaseCollisions(t *testing.T) {
+	groups := caseCollisions([]string{"README.md", "b/x.txt", "Readme.md", "B/X.txt", "notes.txt", "readme.md"})
+	assert.Equal(t, [][]string{{"B/X.txt", "b/x.txt"}, {"README.md", "Readme.md", "readme.md"}}, groups)
+	assert.Empty(t, caseCollisions([]string{"a.txt", "b.txt"}))
+}
+
+func TestNewManagerSkipsCaseCollisions(t *testing.T) {
+	insensitive := caseInsensitive
+	caseInsensitive = func(dir string) bool { return true }
+	t.Cleanup(func() { caseInsensitive = insensitive })
+
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for _, name := range []string{"Readme.md", "README.md", "notes.txt"} {
+		_, err := remote.UploadFile(ctx, "docs/"+name, strings.NewReader(name), map[string]string{
+			index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}}
+	dir := cfg.SyncFolders[0].Path
+	manager := newConfiguredManager(t, cfg, remote)
+
+	var events []models.CreateSyncEventRequest
+	manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+		events = append(events, event)
+	})
+
+	// Syncing twice downloads the other files, leaves the colliding ones out and reports them once
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, manager.syncFolder(ctx, manager.folders["docs"]))
+	}
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "notes.txt", entries[0].Name())
+	}
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.SyncEventCaseCollision, events[0].EventType)
+		var details models.CaseCollisionDetails
+		assert.NoError(t, json.Unmarshal([]byte(events[0].Details), &details))
+		assert.Equal(t, []string{"README.md", "Readme.md"}, details.Paths)
+	}
+	assert.Equal(t, [][]string{{"README.md", "Readme.md"}}, manager.FolderStatus().Folders["docs"].Collisions)
+
+	// Once one of them is gone the other one syncs
+	assert.NoError(t, remote.DeleteFile(ctx, "docs/README.md"))
+	assert.NoError(t, manager.syncFolder(ctx, manager.folders["docs"]))
+	data, err := os.ReadFile(filepath.Join(dir, "Readme.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Readme.md", string(data))
+	assert.Empty(t, manager.FolderStatus().Folders["docs"].Collisions)
+}
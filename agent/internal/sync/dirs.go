@@ -0,0 +1,119 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// recordLocalDir bumps this device's counter for a directory whose metadata changed on disk
+func (sm *SyncManager) recordLocalDir(idx *index.Index, relPath, localRel string, info os.FileInfo) (index.Entry, bool) {
+	entry, changed := idx.RecordLocalDir(sm.deviceID, relPath, localRel, info.Mode(), info.ModTime())
+	if changed {
+		log.Debug().
+			Str("dir", relPath).
+			Str("version", entry.Version.String()).
+			Msg("Local directory change recorded")
+	}
+	return entry, changed
+}
+
+// uploadDir stores the marker of a directory along with its metadata and version vector.
+// Markers are empty, so they are written directly instead of going through the upload queue.
+func (sm *SyncManager) uploadDir(ctx context.Context, folder *FolderSync, idx *index.Index, entry index.Entry) error {
+	metadata := map[string]string{
+		"source_folder":             folder.Path,
+		"upload_time":               time.Now().Format(time.RFC3339),
+		storage.MetadataDirModTime:  entry.ModTime.UTC().Format(time.RFC3339),
+		storage.MetadataDirMode:     strconv.FormatUint(uint64(entry.Mode.Perm()), 8),
+		index.MetadataDeviceID:      sm.deviceID,
+		index.MetadataVersionVector: entry.Version.Encode(),
+	}
+	if folder.StorageClass != "" {
+		metadata[storage.MetadataStorageClass] = folder.StorageClass
+	}
+
+	key := folder.key(storage.DirMarkerKey(entry.Path))
+	if _, err := sm.storage.UploadFile(ctx, key, bytes.NewReader(nil), metadata); err != nil {
+		return fmt.Errorf("failed to upload directory marker: %w", err)
+	}
+
+	// Only clear the flag if the directory was not changed again meanwhile
+	if current, ok := idx.Get(entry.Path); ok && current.Version.Compare(entry.Version) == index.Equal {
+		current.Pending = false
+		idx.Put(current)
+	}
+	return nil
+}
+
+// reconcileDir applies a remote directory marker to the local folder using its version vector
+func (sm *SyncManager) reconcileDir(ctx context.Context, folder *FolderSync, idx *index.Index, relPath string, remoteFile storage.FileInfo) error {
+	localRel := index.LocalForm(relPath)
+	if entry, ok := idx.Get(relPath); ok {
+		localRel = entry.LocalRelPath()
+	}
+	localPath := folder.localPath(localRel)
+
+	_, metadata, err := sm.storage.GetFileInfo(ctx, remoteFile.Key)
+	if err != nil {
+		return fmt.Errorf("failed to get remote directory info: %w", err)
+	}
+
+	entry, remoteVersion, ordering := compareRemote(idx, relPath, localPath, metadata)
+	switch ordering {
+	case index.Equal, index.Before:
+		entry.RemoteETag = remoteFile.ETag
+		idx.Put(entry)
+		return nil
+
+	case index.Concurrent:
+		// Directories have no content to conflict over: keep the local metadata and publish it again
+		entry.Version = entry.Version.Merge(remoteVersion).Increment(sm.deviceID)
+		entry.RemoteETag = remoteFile.ETag
+		entry.Pending = true
+		idx.Put(entry)
+		return nil
+	}
+
+	mode := storage.DirMode(metadata)
+	if err := os.MkdirAll(localPath, mode); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Chmod(localPath, mode); err != nil {
+		log.Warn().Err(err).Str("dir", localPath).Msg("Failed to set directory permissions")
+	}
+	if modTime, err := time.Parse(time.RFC3339, metadataValue(metadata, storage.MetadataDirModTime)); err == nil {
+		if err := os.Chtimes(localPath, modTime, modTime); err != nil {
+			log.Warn().Err(err).Str("dir", localPath).Msg("Failed to set directory modification time")
+		}
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat directory: %w", err)
+	}
+
+	if localRel == relPath {
+		localRel = ""
+	}
+
+	idx.Put(index.Entry{
+		Path:       relPath,
+		LocalPath:  localRel,
+		ModTime:    info.ModTime(),
+		Dir:        true,
+		Mode:       info.Mode().Perm(),
+		Version:    remoteVersion,
+		RemoteETag: remoteFile.ETag,
+	})
+
+	log.Debug().Str("dir", relPath).Str("version", remoteVersion.String()).Msg("Directory created from remote")
+	return nil
+}
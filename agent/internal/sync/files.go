@@ -0,0 +1,141 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/uploader"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/sparse"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// taskPath returns the path relative to its folder of the file an upload task carries
+func (sm *SyncManager) taskPath(task uploader.UploadTask) string {
+	sm.mu.RLock()
+	folder := sm.folders[task.FolderID]
+	sm.mu.RUnlock()
+	if folder == nil {
+		return strings.TrimPrefix(task.Key, task.FolderID+"/")
+	}
+	return strings.TrimPrefix(task.Key, folder.keyPrefix())
+}
+
+// skipUpload handles a file the uploader left out by policy, because it is too large, sparse
+// under the skip policy or a cloud placeholder. Its current version stops being pending, so it is not
+// queued again on every sync, until it changes and may fit the policy.
+func (sm *SyncManager) skipUpload(result uploader.UploadResult) {
+	folderID := result.Task.FolderID
+	relPath := sm.taskPath(result.Task)
+	sm.stats.Skipped(folderID)
+
+	var tooLarge *uploader.FileTooLargeError
+	var placeholder *uploader.PlaceholderError
+	if errors.As(result.Error, &tooLarge) {
+		log.Error().
+			Str("file", relPath).
+			Int64("size", tooLarge.Size).
+			Int64("limit", tooLarge.Limit).
+			Msg("File exceeds the maximum upload size, skipping it")
+		sm.stats.Failed(folderID)
+		sm.recordEvent(folderID, relPath, models.SyncEventTooLarge, models.TooLargeDetails{Size: tooLarge.Size, Limit: tooLarge.Limit})
+	} else if errors.As(result.Error, &placeholder) {
+		log.Warn().
+			Str("file", relPath).
+			Int64("size", placeholder.Size).
+			Msg("Skipping cloud placeholder, its content is not on disk; make it available offline or set files.hydrate_placeholders")
+		sm.recordEvent(folderID, relPath, models.SyncEventCloudPlaceholder, models.CloudPlaceholderDetails{Size: placeholder.Size})
+	} else {
+		log.Warn().Str("file", relPath).Msg("Skipping sparse file")
+	}
+
+	idx, err := sm.folderIndex(folderID)
+	if err != nil {
+		log.Error().Err(err).Str("folder", folderID).Msg("Failed to load folder index")
+		return
+	}
+	entry, ok := idx.Get(relPath)
+	if !ok {
+		return
+	}
+	skipped, err := index.DecodeVersionVector(result.Task.Metadata[index.MetadataVersionVector])
+	if err != nil || skipped.Compare(entry.Version) != index.Equal {
+		return
+	}
+
+	entry.Pending = false
+	entry.Online = placeholder != nil
+	idx.Put(entry)
+	if err := idx.Save(); err != nil {
+		log.Error().Err(err).Str("folder", folderID).Msg("Failed to save folder index")
+	}
+}
+
+// vanishedUpload handles a file removed between being queued and uploaded. Folders that
+// propagate deletions remove its remote copy, as the next scan would; others stop waiting to
+// upload it. Nothing is done when the file changed again or is back on disk.
+func (sm *SyncManager) vanishedUpload(result uploader.UploadResult) {
+	folderID := result.Task.FolderID
+	relPath := sm.taskPath(result.Task)
+	log.Debug().Str("file", relPath).Str("folder", folderID).Msg("File removed before its upload")
+
+	sm.mu.RLock()
+	folder := sm.folders[folderID]
+	ctx := sm.ctx
+	sm.mu.RUnlock()
+	if folder == nil {
+		return
+	}
+	if _, err := os.Lstat(result.Task.FilePath); err == nil {
+		return
+	}
+
+	idx, err := sm.folderIndex(folderID)
+	if err != nil {
+		log.Error().Err(err).Str("folder", folderID).Msg("Failed to load folder index")
+		return
+	}
+	entry, ok := idx.Get(relPath)
+	if !ok {
+		return
+	}
+	queued, err := index.DecodeVersionVector(result.Task.Metadata[index.MetadataVersionVector])
+	if err != nil || queued.Compare(entry.Version) != index.Equal {
+		return
+	}
+
+	if folder.Mirror.DeleteOrphans && !folder.TwoWaySync {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if err := sm.removeOrphan(ctx, folder.ID, result.Task.Key, folderTrash(folder), relPath); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Error().Err(err).Str("key", result.Task.Key).Msg("Failed to remove remote copy of removed file")
+			sm.stats.Failed(folderID)
+			return
+		}
+		log.Info().Str("file", relPath).Str("folder", folderID).Msg("Removed remote copy of file deleted before its upload")
+		sm.stats.Deleted(folderID)
+		idx.Remove(relPath)
+	} else {
+		entry.Pending = false
+		idx.Put(entry)
+	}
+	if err := idx.Save(); err != nil {
+		log.Error().Err(err).Str("folder", folderID).Msg("Failed to save folder index")
+	}
+}
+
+// restoreHoles turns the zero ranges of a downloaded sparse file back into holes. The file is
+// kept as it is where the platform or filesystem cannot punch holes.
+func restoreHoles(path string) {
+	released, err := sparse.Punch(path)
+	if err != nil {
+		log.Debug().Err(err).Str("file", path).Msg("Sparse file downloaded without holes")
+		return
+	}
+	log.Debug().Str("file", path).Int64("released", released).Msg("Restored holes of sparse file")
+}
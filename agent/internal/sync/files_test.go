@@ -0,0 +1,184 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/uploader"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/sparse"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkippedUploadIsNotQueuedAgain(t *testing.T) {
+	manager, folder, idx := newVersionedManager(t, &versionedStorage{objects: map[string]remoteObject{}})
+	var events []models.CreateSyncEventRequest
+	manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+		events = append(events, event)
+	})
+
+	recordFile(t, manager, idx, folder, "disk.img", "0123456789")
+	entry, _ := idx.Get("disk.img")
+	assert.True(t, entry.Pending)
+
+	task := uploader.UploadTask{
+		Key:      "docs/disk.img",
+		FolderID: folder.ID,
+		Metadata: map[string]string{index.MetadataVersionVector: entry.Version.Encode()},
+	}
+	manager.handleUploadResult(uploader.UploadResult{Task: task, Error: &uploader.FileTooLargeError{Size: 10, Limit: 4}})
+
+	// The file waits for its next change instead of failing on every sync
+	entry, _ = idx.Get("disk.img")
+	assert.False(t, entry.Pending)
+	assert.Equal(t, int64(1), manager.Stats().Snapshot().Folders["docs"].Errors)
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, models.SyncEventTooLarge, events[0].EventType)
+	assert.Equal(t, "disk.img", events[0].RelativePath)
+	var details models.TooLargeDetails
+	assert.NoError(t, json.Unmarshal([]byte(events[0].Details), &details))
+	assert.Equal(t, models.TooLargeDetails{Size: 10, Limit: 4}, details)
+
+	// Once it changes it is pending again
+	recordFile(t, manager, idx, folder, "disk.img", "0123")
+	entry, _ = idx.Get("disk.img")
+	assert.True(t, entry.Pending)
+}
+
+func TestSkippedPlaceholderIsUploadedOnceOffline(t *testing.T) {
+	manager, folder, idx := newVersionedManager(t, &versionedStorage{objects: map[string]remoteObject{}})
+	var events []models.CreateSyncEventRequest
+	manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+		events = append(events, event)
+	})
+
+	recordFile(t, manager, idx, folder, "video.mp4", "0123456789")
+	entry, _ := idx.Get("video.mp4")
+	task := uploader.UploadTask{
+		Key:      "docs/video.mp4",
+		FolderID: folder.ID,
+		Metadata: map[string]string{index.MetadataVersionVector: entry.Version.Encode()},
+	}
+	manager.handleUploadResult(uploader.UploadResult{Task: task, Error: &uploader.PlaceholderError{Path: "video.mp4", Size: 10}})
+
+	entry, _ = idx.Get("video.mp4")
+	assert.False(t, entry.Pending)
+	assert.True(t, entry.Online)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.SyncEventCloudPlaceholder, events[0].EventType)
+		assert.JSONEq(t, `{"size":10}`, events[0].Details)
+	}
+
+	// Made available offline, its content is on disk without the file changing: the next
+	// scan uploads it with the version it was skipped at
+	info, err := os.Stat(filepath.Join(folder.Path, "video.mp4"))
+	assert.NoError(t, err)
+	queued, changed := manager.recordLocalChange(idx, "video.mp4", "video.mp4", info)
+	assert.True(t, changed)
+	assert.True(t, queued.Pending)
+	assert.False(t, queued.Online)
+	assert.Equal(t, index.Equal, queued.Version.Compare(entry.Version))
+}
+
+func TestVanishedUploadPropagatesDeletion(t *testing.T) {
+	remote := &versionedStorage{objects: map[string]remoteObject{"docs/notes.txt": {data: []byte("draft")}}}
+	manager, folder, idx := newVersionedManager(t, remote)
+	manager.folders[folder.ID] = folder
+
+	vanish := func(name string) index.Entry {
+		recordFile(t, manager, idx, folder, name, "final")
+		entry, _ := idx.Get(name)
+		assert.NoError(t, os.Remove(filepath.Join(folder.Path, name)))
+		task := uploader.UploadTask{
+			Key:      "docs/" + name,
+			FolderID: folder.ID,
+			FilePath: filepath.Join(folder.Path, name),
+			Metadata: map[string]string{index.MetadataVersionVector: entry.Version.Encode()},
+		}
+		manager.handleUploadResult(uploader.UploadResult{Task: task, Error: fmt.Errorf("%w: %s", uploader.ErrFileVanished, task.FilePath)})
+		entry, _ = idx.Get(name)
+		return entry
+	}
+
+	// A two-way folder stops waiting to upload the file, leaving the deletion to its next sync
+	entry := vanish("notes.txt")
+	assert.False(t, entry.Pending)
+	assert.Contains(t, remote.objects, "docs/notes.txt")
+
+	// A mirror folder removing orphans removes the remote copy right away
+	folder.TwoWaySync = false
+	folder.Mirror.DeleteOrphans = true
+	vanish("notes.txt")
+	assert.NotContains(t, remote.objects, "docs/notes.txt")
+	_, ok := idx.Get("notes.txt")
+	assert.False(t, ok)
+}
+
+func TestNewManagerAppliesFilePolicies(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	cfg := commonconfig.DefaultConfig()
+	cfg.Files = commonconfig.FilesConfig{MaxFileSize: 16 * sparse.MinHoleSize, Sparse: commonconfig.SparseTransfer}
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true}}
+
+	// Both files are mostly holes, so only their size sets them apart
+	for name, size := range map[string]int64{"disk.img": 8 * sparse.MinHoleSize, "backup.img": 32 * sparse.MinHoleSize} {
+		file, err := os.Create(filepath.Join(cfg.SyncFolders[0].Path, name))
+		assert.NoError(t, err)
+		_, err = file.WriteAt([]byte("data"), 0)
+		assert.NoError(t, err)
+		assert.NoError(t, file.Truncate(size))
+		assert.NoError(t, file.Close())
+		past := time.Now().Add(-time.Hour)
+		assert.NoError(t, os.Chtimes(file.Name(), past, past))
+	}
+
+	up := uploader.NewUploader(remote, cfg)
+	up.Start()
+	defer up.Stop()
+	manager := newConfiguredManagerWithUploader(t, cfg, remote, up)
+	var events []models.CreateSyncEventRequest
+	manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+		events = append(events, event)
+	})
+
+	assert.NoError(t, manager.syncFolder(ctx, manager.folders["docs"]))
+	for i := 0; i < 2; i++ {
+		select {
+		case result := <-up.Results():
+			manager.handleUploadResult(result)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for uploads")
+		}
+	}
+
+	// The file above the configured limit is left out and reported
+	assert.Equal(t, []string{"docs/disk.img"}, remoteKeys(t, remote, "docs/"))
+	assert.Len(t, events, 1)
+	assert.Equal(t, models.SyncEventTooLarge, events[0].EventType)
+	assert.Equal(t, "backup.img", events[0].RelativePath)
+	idx, err := manager.folderIndex("docs")
+	assert.NoError(t, err)
+	entry, _ := idx.Get("backup.img")
+	assert.False(t, entry.Pending)
+
+	info, err := os.Stat(filepath.Join(cfg.SyncFolders[0].Path, "disk.img"))
+	assert.NoError(t, err)
+	if !sparse.IsSparse(info) {
+		t.Skip("filesystem does not report sparse files")
+	}
+
+	// The sparse file is marked so other devices restore its holes
+	_, metadata, err := remote.GetFileInfo(ctx, "docs/disk.img")
+	assert.NoError(t, err)
+	assert.Equal(t, "true", metadata[sparse.MetadataKey])
+}
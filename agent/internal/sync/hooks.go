@@ -0,0 +1,97 @@
+package sync
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/config"
+	"github.com/martinshumberto/sync-manager/agent/internal/hooks"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/rs/zerolog/log"
+)
+
+// preSyncHook runs the pre_sync hook of a folder before a sync. Unless the hook is set to
+// continue, its failure is returned and the sync is skipped.
+func (sm *SyncManager) preSyncHook(ctx context.Context, r *folderRun, folder *FolderSync) error {
+	sm.mu.RLock()
+	hook := folder.Hooks.PreSync
+	sm.mu.RUnlock()
+
+	return sm.runHook(ctx, "pre_sync", hook, commonconfig.HookAbort, folder, runEnv(r.run))
+}
+
+// postSyncHook runs the post_sync hook of a folder once a sync ended with err, with the
+// summary of the run. It returns the error the sync ends with: err, or the failure of the hook
+// when the sync succeeded and the hook is set to abort.
+func (sm *SyncManager) postSyncHook(ctx context.Context, r *folderRun, folder *FolderSync, err error) error {
+	sm.mu.RLock()
+	hook := folder.Hooks.PostSync
+	sm.mu.RUnlock()
+	if hook.Command == "" || ctx.Err() != nil {
+		return err
+	}
+
+	run := sm.summarizeRun(ctx, r, folder, err)
+	env := append(runEnv(run),
+		"SYNC_MANAGER_STATUS="+run.Status,
+		"SYNC_MANAGER_ERROR="+run.Error,
+		"SYNC_MANAGER_DURATION="+strconv.FormatFloat(run.Duration().Seconds(), 'f', 3, 64),
+		"SYNC_MANAGER_FILES_UPLOADED="+strconv.FormatInt(run.FilesUploaded, 10),
+		"SYNC_MANAGER_FILES_DOWNLOADED="+strconv.FormatInt(run.FilesDownloaded, 10),
+		"SYNC_MANAGER_FILES_DELETED="+strconv.FormatInt(run.FilesDeleted, 10),
+		"SYNC_MANAGER_BYTES_UPLOADED="+strconv.FormatInt(run.BytesUploaded, 10),
+		"SYNC_MANAGER_BYTES_DOWNLOADED="+strconv.FormatInt(run.BytesDownloaded, 10),
+		"SYNC_MANAGER_ERRORS="+strconv.FormatInt(run.Errors, 10),
+		"SYNC_MANAGER_CONFLICTS="+strconv.FormatInt(run.Conflicts, 10),
+	)
+	if hookErr := sm.runHook(ctx, "post_sync", hook, commonconfig.HookContinue, folder, env); hookErr != nil && err == nil {
+		return hookErr
+	}
+	return err
+}
+
+// runHook runs a hook of a folder, in the folder, with its timeout. A failure is returned under
+// the abort policy, which applies when the hook sets none and fallback is HookAbort, and logged
+// otherwise.
+func (sm *SyncManager) runHook(ctx context.Context, name string, hook config.Hook, fallback string, folder *FolderSync, env []string) error {
+	if hook.Command == "" || ctx.Err() != nil {
+		return nil
+	}
+
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = commonconfig.DefaultHookTimeout
+	}
+	policy := hook.OnFailure
+	if policy == "" {
+		policy = fallback
+	}
+
+	log.Info().Str("folder", folder.ID).Str("hook", name).Msg("Running hook")
+	err := hooks.Run(ctx, hooks.Hook{
+		Name:    name,
+		Command: hook.Command,
+		Dir:     folder.Path,
+		Env:     append(env, "SYNC_MANAGER_HOOK="+name, "SYNC_MANAGER_FOLDER_PATH="+folder.Path),
+		Timeout: timeout,
+	})
+	if err == nil {
+		return nil
+	}
+	if policy == commonconfig.HookContinue {
+		log.Warn().Err(err).Str("folder", folder.ID).Msg("Hook failed, continuing")
+		return nil
+	}
+	return err
+}
+
+// runEnv returns the variables describing a run to its hooks
+func runEnv(run models.SyncRun) []string {
+	return []string{
+		"SYNC_MANAGER_FOLDER_ID=" + run.FolderID,
+		"SYNC_MANAGER_RUN_KIND=" + run.Kind,
+		"SYNC_MANAGER_STARTED_AT=" + run.StartedAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,70 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/config"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncRunsFolderHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are written for sh")
+	}
+	ctx := context.Background()
+	report := filepath.Join(t.TempDir(), "report.txt")
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{
+		ID:      "db",
+		Path:    t.TempDir(),
+		Enabled: true,
+		Hooks: commonconfig.FolderHooks{
+			PreSync:  commonconfig.Hook{Command: `echo "dump of $SYNC_MANAGER_FOLDER_ID" > dump.sql`},
+			PostSync: commonconfig.Hook{Command: `echo "$SYNC_MANAGER_HOOK $SYNC_MANAGER_STATUS $SYNC_MANAGER_ERRORS" > ` + report},
+		},
+	}}
+	manager := newConfiguredManager(t, cfg, storage.NewMemoryStorage(&storage.MemoryConfig{}))
+	var runs []models.SyncRun
+	manager.SetRunRecorder(func(run models.SyncRun) error {
+		runs = append(runs, run)
+		return nil
+	})
+	folder := manager.folders["db"]
+
+	// The dump the pre_sync hook writes is part of the sync, and post_sync gets its result
+	assert.NoError(t, manager.syncFolders(ctx, status.FullSync, []*FolderSync{folder}))
+	idx, err := manager.folderIndex("db")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dump.sql"}, idx.Paths())
+	assertFile(t, report, "post_sync succeeded 0\n")
+
+	// A failed pre_sync hook skips the sync, unless it is set to continue
+	folder.Hooks.PreSync = config.Hook{Command: "echo 'pg_dump: connection refused' >&2; exit 1"}
+	assert.NoError(t, os.Remove(report))
+	assert.NoError(t, manager.syncFolders(ctx, status.FullSync, []*FolderSync{folder}))
+	if assert.Len(t, runs, 2) {
+		assert.Equal(t, models.SyncRunFailed, runs[1].Status)
+		assert.Contains(t, runs[1].Error, "pre_sync hook failed: exit status 1: pg_dump: connection refused")
+	}
+	assert.NoFileExists(t, report)
+
+	folder.Hooks.PreSync.OnFailure = commonconfig.HookContinue
+	assert.NoError(t, manager.syncFolders(ctx, status.FullSync, []*FolderSync{folder}))
+	assert.Equal(t, models.SyncRunSucceeded, runs[2].Status)
+	assertFile(t, report, "post_sync succeeded 0\n")
+
+	// A failed post_sync hook set to abort marks the run failed
+	folder.Hooks.PostSync = config.Hook{Command: "exit 2", OnFailure: commonconfig.HookAbort}
+	assert.NoError(t, manager.syncFolders(ctx, status.FullSync, []*FolderSync{folder}))
+	assert.Equal(t, models.SyncRunFailed, runs[3].Status)
+	assert.Contains(t, runs[3].Error, "post_sync hook failed")
+}
@@ -0,0 +1,57 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultFullScanInterval is how often a folder is walked in full even though the watcher
+// saw nothing change in it, to catch changes it missed
+const DefaultFullScanInterval = 24 * time.Hour
+
+// markDirty records that a folder changed locally, so the next periodic sync walks it
+func (sm *SyncManager) markDirty(folder *FolderSync) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	folder.dirty = true
+}
+
+// markAllDirty records that changes may have gone unseen in every folder, as when the
+// watcher dropped events
+func (sm *SyncManager) markAllDirty() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for _, folder := range sm.folders {
+		folder.dirty = true
+	}
+}
+
+// needsScan reports whether a sync of kind has to walk a folder. Syncs on demand always do;
+// a periodic one only when the watcher saw a change, the folder was never walked or its
+// last walk is older than the full scan interval. Without a watcher every sync walks.
+func (sm *SyncManager) needsScan(kind string, folder *FolderSync, now time.Time) bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if kind != status.Periodic || sm.watcher == nil || folder.Mode == commonconfig.FolderModeBackup {
+		return true
+	}
+	if folder.dirty || folder.lastScan.IsZero() || sm.fullScanInterval <= 0 {
+		return true
+	}
+	return now.Sub(folder.lastScan) >= sm.fullScanInterval
+}
+
+// syncFolderAs syncs a folder, or its subtree at scope when set, for a sync of kind, skipping
+// the walk when nothing changed locally
+func (sm *SyncManager) syncFolderAs(ctx context.Context, kind string, folder *FolderSync, scope string) error {
+	scan := scope != "" || sm.needsScan(kind, folder, time.Now())
+	if !scan {
+		log.Debug().Str("folder", folder.ID).Msg("No local changes seen, skipping the folder walk")
+	}
+	return sm.reconcileFolder(ctx, folder, scan, scope)
+}
@@ -0,0 +1,90 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/watcher"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeriodicSyncWalksOnlyDirtyFolders(t *testing.T) {
+	ctx := context.Background()
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true}}
+	manager := newConfiguredManager(t, cfg, storage.NewMemoryStorage(&storage.MemoryConfig{}))
+	manager.watcher = &mockWatcher{}
+	folder := manager.folders["docs"]
+	assert.Equal(t, 24*time.Hour, manager.fullScanInterval)
+
+	indexed := func(name string) bool {
+		idx, err := manager.folderIndex("docs")
+		assert.NoError(t, err)
+		_, ok := idx.Get(name)
+		return ok
+	}
+	write := func(name string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, name), []byte(name), 0644))
+	}
+
+	// A folder never walked is walked by the first periodic sync
+	write("a.txt")
+	assert.NoError(t, manager.syncFolders(ctx, status.Periodic, []*FolderSync{folder}))
+	assert.True(t, indexed("a.txt"))
+
+	// Without watcher events a periodic sync skips the walk, but a sync on demand does not
+	write("b.txt")
+	assert.NoError(t, manager.syncFolders(ctx, status.Periodic, []*FolderSync{folder}))
+	assert.False(t, indexed("b.txt"))
+	assert.NoError(t, manager.syncFolders(ctx, status.FullSync, []*FolderSync{folder}))
+	assert.True(t, indexed("b.txt"))
+
+	// Any event marks the folder dirty, even one the handler does not act on
+	write("c.txt")
+	manager.handleFileEvent(ctx, Event{Type: watcher.EventDelete, Path: filepath.Join(folder.Path, "gone.txt")})
+	assert.NoError(t, manager.syncFolders(ctx, status.Periodic, []*FolderSync{folder}))
+	assert.True(t, indexed("c.txt"))
+
+	// Dropped events and a walk older than the full scan interval force one too
+	write("d.txt")
+	manager.handleFileEvent(ctx, Event{Type: watcher.EventOverflow})
+	assert.NoError(t, manager.syncFolders(ctx, status.Periodic, []*FolderSync{folder}))
+	assert.True(t, indexed("d.txt"))
+
+	write("e.txt")
+	folder.lastScan = time.Now().Add(-25 * time.Hour)
+	assert.NoError(t, manager.syncFolders(ctx, status.Periodic, []*FolderSync{folder}))
+	assert.True(t, indexed("e.txt"))
+}
+
+func TestMoveEventMarksBothFolders(t *testing.T) {
+	ctx := context.Background()
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{
+		{ID: "docs", Path: t.TempDir(), Enabled: true},
+		{ID: "archive", Path: t.TempDir(), Enabled: true},
+	}
+	manager := newConfiguredManager(t, cfg, storage.NewMemoryStorage(&storage.MemoryConfig{}))
+	manager.watcher = &mockWatcher{}
+	docs, archive := manager.folders["docs"], manager.folders["archive"]
+
+	// A file moved between folders is recorded at its new path, and the folder it left is
+	// walked on its next sync
+	to := filepath.Join(archive.Path, "report.txt")
+	assert.NoError(t, os.WriteFile(to, []byte("report"), 0644))
+	manager.handleFileEvent(ctx, Event{Type: watcher.EventMove, Path: to, OldPath: filepath.Join(docs.Path, "report.txt")})
+
+	idx, err := manager.folderIndex("archive")
+	assert.NoError(t, err)
+	entry, ok := idx.Get("report.txt")
+	assert.True(t, ok)
+	assert.True(t, entry.Pending)
+	assert.True(t, docs.dirty)
+	assert.True(t, archive.dirty)
+}
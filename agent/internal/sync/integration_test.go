@@ -0,0 +1,220 @@
+//go:build integration
+
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/uploader"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// The tests in this file sync real folders against MinIO. They run with
+//
+//	go test -tags integration ./agent/internal/sync/
+//
+// starting a throwaway MinIO container with docker, or against the server at
+// SYNC_MANAGER_TEST_MINIO when set (host:port, minioadmin credentials unless
+// SYNC_MANAGER_TEST_MINIO_USER and SYNC_MANAGER_TEST_MINIO_PASSWORD say otherwise).
+
+// minioImage is the image started when no server is given
+const minioImage = "minio/minio:latest"
+
+// startMinIO returns the endpoint and credentials of a MinIO server, starting a container removed
+// when the test ends. The test is skipped when there is neither a server nor docker.
+func startMinIO(t *testing.T) (endpoint, user, password string) {
+	user, password = "minioadmin", "minioadmin"
+	if endpoint = os.Getenv("SYNC_MANAGER_TEST_MINIO"); endpoint != "" {
+		if value := os.Getenv("SYNC_MANAGER_TEST_MINIO_USER"); value != "" {
+			user = value
+		}
+		if value := os.Getenv("SYNC_MANAGER_TEST_MINIO_PASSWORD"); value != "" {
+			password = value
+		}
+		return endpoint, user, password
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available and SYNC_MANAGER_TEST_MINIO is not set")
+	}
+	out, err := exec.Command("docker", "run", "-d", "--rm", "-p", "127.0.0.1::9000",
+		"-e", "MINIO_ROOT_USER="+user, "-e", "MINIO_ROOT_PASSWORD="+password,
+		minioImage, "server", "/data").Output()
+	if err != nil {
+		t.Fatalf("failed to start MinIO: %v", err)
+	}
+	container := strings.TrimSpace(string(out))
+	t.Cleanup(func() { exec.Command("docker", "rm", "-f", container).Run() })
+
+	out, err = exec.Command("docker", "port", container, "9000/tcp").Output()
+	if err != nil {
+		t.Fatalf("failed to find the MinIO port: %v", err)
+	}
+	endpoint, _, _ = strings.Cut(strings.TrimSpace(string(out)), "\n")
+
+	// The server answers its health check once it accepts requests
+	deadline := time.Now().Add(time.Minute)
+	for {
+		resp, err := http.Get("http://" + endpoint + "/minio/health/live")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return endpoint, user, password
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("MinIO at %s did not become ready", endpoint)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// newMinIOStorage returns the storage of a new bucket on a MinIO server, emptied when the test ends
+func newMinIOStorage(t *testing.T) storage.Storage {
+	endpoint, user, password := startMinIO(t)
+	remote, err := storage.NewMinioStorage(&storage.MinioConfig{
+		Endpoint:  endpoint,
+		Bucket:    fmt.Sprintf("sync-manager-%d", time.Now().UnixNano()),
+		AccessKey: user,
+		SecretKey: password,
+	})
+	if err != nil {
+		t.Fatalf("failed to open MinIO bucket: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		files, _ := remote.ListFiles(ctx, "")
+		for _, file := range files {
+			remote.DeleteFile(ctx, file.Key)
+		}
+	})
+	return remote
+}
+
+// newDevice returns the engine of a device syncing folder from a temporary directory, with a
+// running uploader whose results the test hands to it
+func newDevice(t *testing.T, remote storage.Storage, deviceID string, folder commonconfig.SyncFolder) (*SyncManager, *uploader.Uploader) {
+	cfg := commonconfig.DefaultConfig()
+	cfg.DeviceID = deviceID
+	folder.Path = t.TempDir()
+	folder.Enabled = true
+	cfg.SyncFolders = []commonconfig.SyncFolder{folder}
+
+	up := uploader.NewUploader(remote, cfg)
+	up.Start()
+	t.Cleanup(up.Stop)
+	return newConfiguredManagerWithUploader(t, cfg, remote, up), up
+}
+
+// syncAndUpload syncs a folder and waits for the uploads it queued to finish
+func syncAndUpload(t *testing.T, manager *SyncManager, up *uploader.Uploader, folderID string) {
+	t.Helper()
+	assert.NoError(t, manager.syncFolder(context.Background(), manager.folders[folderID]))
+
+	idx, err := manager.folderIndex(folderID)
+	assert.NoError(t, err)
+	for idx.PendingFiles() > 0 {
+		select {
+		case result := <-up.Results():
+			assert.NoError(t, result.Error)
+			manager.handleUploadResult(result)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("timed out waiting for %d uploads", idx.PendingFiles())
+		}
+	}
+}
+
+// writeSettled writes a file dated in the past, so its upload is not held back as still being written
+func writeSettled(t *testing.T, path, content string) {
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(path, past, past))
+}
+
+// remoteContent returns the content of a remote file
+func remoteContent(t *testing.T, remote storage.Storage, key string) string {
+	var buf bytes.Buffer
+	_, err := remote.DownloadFile(context.Background(), key, &buf, "")
+	assert.NoError(t, err)
+	return buf.String()
+}
+
+func TestIntegrationMirrorFolder(t *testing.T) {
+	remote := newMinIOStorage(t)
+	manager, up := newDevice(t, remote, "laptop", commonconfig.SyncFolder{
+		ID:     "docs",
+		Mirror: commonconfig.MirrorConfig{DeleteOrphans: true, MaxDeletePercent: -1},
+	})
+	dir := manager.folders["docs"].Path
+
+	// Created files are uploaded
+	writeSettled(t, filepath.Join(dir, "notes.txt"), "first draft")
+	writeSettled(t, filepath.Join(dir, "todo.txt"), "buy milk")
+	syncAndUpload(t, manager, up, "docs")
+	assert.Equal(t, []string{"docs/notes.txt", "docs/todo.txt"}, remoteKeys(t, remote, "docs/"))
+	assert.Equal(t, "first draft", remoteContent(t, remote, "docs/notes.txt"))
+
+	// A modified file replaces the remote copy
+	writeSettled(t, filepath.Join(dir, "notes.txt"), "second draft, longer")
+	syncAndUpload(t, manager, up, "docs")
+	assert.Equal(t, "second draft, longer", remoteContent(t, remote, "docs/notes.txt"))
+
+	// A renamed file is uploaded under its new name and the old one removed
+	assert.NoError(t, os.Rename(filepath.Join(dir, "todo.txt"), filepath.Join(dir, "done.txt")))
+	syncAndUpload(t, manager, up, "docs")
+	assert.Equal(t, []string{"docs/done.txt", "docs/notes.txt"}, remoteKeys(t, remote, "docs/"))
+	assert.Equal(t, "buy milk", remoteContent(t, remote, "docs/done.txt"))
+
+	// A deleted file is removed remotely
+	assert.NoError(t, os.Remove(filepath.Join(dir, "notes.txt")))
+	syncAndUpload(t, manager, up, "docs")
+	assert.Equal(t, []string{"docs/done.txt"}, remoteKeys(t, remote, "docs/"))
+}
+
+func TestIntegrationTwoWayFolders(t *testing.T) {
+	remote := newMinIOStorage(t)
+	folder := commonconfig.SyncFolder{ID: "docs", TwoWaySync: true}
+	laptop, laptopUp := newDevice(t, remote, "laptop", folder)
+	desktop, desktopUp := newDevice(t, remote, "desktop", folder)
+	laptopDir, desktopDir := laptop.folders["docs"].Path, desktop.folders["docs"].Path
+
+	// A file created on one device reaches the other
+	writeSettled(t, filepath.Join(laptopDir, "notes.txt"), "from laptop")
+	syncAndUpload(t, laptop, laptopUp, "docs")
+	syncAndUpload(t, desktop, desktopUp, "docs")
+	assertFile(t, filepath.Join(desktopDir, "notes.txt"), "from laptop")
+
+	// And its changes come back
+	writeSettled(t, filepath.Join(desktopDir, "notes.txt"), "edited on desktop")
+	syncAndUpload(t, desktop, desktopUp, "docs")
+	syncAndUpload(t, laptop, laptopUp, "docs")
+	assertFile(t, filepath.Join(laptopDir, "notes.txt"), "edited on desktop")
+
+	// Concurrent edits keep the local copy and set the remote one aside on the device syncing last
+	writeSettled(t, filepath.Join(desktopDir, "notes.txt"), "desktop again")
+	writeSettled(t, filepath.Join(laptopDir, "notes.txt"), "laptop again, concurrently")
+	syncAndUpload(t, desktop, desktopUp, "docs")
+	syncAndUpload(t, laptop, laptopUp, "docs")
+	assertFile(t, filepath.Join(laptopDir, "notes.txt"), "laptop again, concurrently")
+	matches, err := filepath.Glob(filepath.Join(laptopDir, "notes (conflict from desktop *).txt"))
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assertFile(t, matches[0], "desktop again")
+	}
+
+	// The copy that won descends from both edits, so the other device takes it
+	assert.Equal(t, "laptop again, concurrently", remoteContent(t, remote, "docs/notes.txt"))
+	syncAndUpload(t, desktop, desktopUp, "docs")
+	assertFile(t, filepath.Join(desktopDir, "notes.txt"), "laptop again, concurrently")
+}
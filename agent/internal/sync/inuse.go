@@ -0,0 +1,192 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/inuse"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/rs/zerolog/log"
+)
+
+// inUseCheckInterval is how often deferred uploads are checked again
+const inUseCheckInterval = 5 * time.Second
+
+// settleTime is how long a file must keep its size and modification time before it is uploaded
+const settleTime = 2 * time.Second
+
+// deferredKey identifies a file whose upload is deferred
+type deferredKey struct {
+	folderID string
+	path     string
+}
+
+// deferredUpload is a file whose upload waits for it to stop changing
+type deferredUpload struct {
+	since   time.Time // When the upload was first held back
+	changed time.Time // When the size or modification time was last seen changing
+	size    int64
+	modTime time.Time
+	young   bool // Held back by the min_age of the folder rather than for being in use
+}
+
+// inUseTimeout returns how long the uploads of a folder wait for files in use
+func inUseTimeout(folder *FolderSync) time.Duration {
+	if folder.InUseTimeout == 0 {
+		return commonconfig.DefaultInUseTimeout
+	}
+	return folder.InUseTimeout
+}
+
+// inUseTimeoutSeconds converts an in-use timeout to the whole seconds kept in the agent
+// configuration, rounding away from zero so a sub-second value keeps its meaning
+func inUseTimeoutSeconds(timeout time.Duration) int {
+	seconds := timeout / time.Second
+	switch {
+	case timeout%time.Second > 0:
+		seconds++
+	case timeout%time.Second < 0:
+		seconds--
+	}
+	return int(seconds)
+}
+
+// uploadReady reports whether a file can be uploaded. A file younger than the min_age of its
+// folder waits until it is old enough. A file modified within the settle time or held open for
+// writing by another process is deferred until it settles, or until the in-use timeout of its
+// folder elapses and it is uploaded as it is.
+func (sm *SyncManager) uploadReady(folder *FolderSync, entry index.Entry, writers *inuse.Writers) bool {
+	key := deferredKey{folderID: folder.ID, path: entry.Path}
+	path := folder.localPath(entry.LocalRelPath())
+	if sm.tooRecent(folder, key, path) {
+		return false
+	}
+
+	timeout := inUseTimeout(folder)
+	if timeout < 0 {
+		return true
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		// The uploader reports files that are gone by the time they are read
+		sm.forgetDeferred(key)
+		return true
+	}
+
+	now := time.Now()
+	settled := now.Sub(info.ModTime()) >= settleTime
+
+	sm.mu.Lock()
+	deferred, waiting := sm.deferred[key]
+	if waiting {
+		// Once deferred, the file is judged on what was observed, not on its clock
+		if info.Size() != deferred.size || !info.ModTime().Equal(deferred.modTime) {
+			deferred.size, deferred.modTime, deferred.changed = info.Size(), info.ModTime(), now
+			sm.deferred[key] = deferred
+		}
+		settled = now.Sub(deferred.changed) >= settleTime
+	}
+	sm.mu.Unlock()
+
+	if settled && !writers.Has(path) {
+		if waiting {
+			sm.forgetDeferred(key)
+			log.Info().Str("file", entry.Path).Dur("waited", now.Sub(deferred.since)).Msg("File no longer in use, uploading")
+		}
+		return true
+	}
+
+	if !waiting {
+		deferred = deferredUpload{since: now, changed: now, size: info.Size(), modTime: info.ModTime()}
+		log.Info().Str("file", entry.Path).Dur("timeout", timeout).Msg("File is in use, deferring upload")
+	}
+	if now.Sub(deferred.since) >= timeout {
+		sm.forgetDeferred(key)
+		log.Warn().Str("file", entry.Path).Dur("timeout", timeout).Msg("File still in use after timeout, uploading it anyway")
+		return true
+	}
+
+	sm.mu.Lock()
+	sm.deferred[key] = deferred
+	sm.mu.Unlock()
+	return false
+}
+
+// forgetDeferred drops a deferred upload
+func (sm *SyncManager) forgetDeferred(key deferredKey) {
+	sm.mu.Lock()
+	delete(sm.deferred, key)
+	sm.mu.Unlock()
+}
+
+// watchInUse queues the deferred uploads as their files settle
+func (sm *SyncManager) watchInUse(ctx context.Context) {
+	ticker := time.NewTicker(inUseCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sm.retryDeferred(ctx)
+		case <-sm.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// retryDeferred checks every deferred upload again, queueing the files that settled.
+// Uploads of folders that were paused or disabled are dropped and left to the next scan.
+func (sm *SyncManager) retryDeferred(ctx context.Context) {
+	sm.mu.RLock()
+	byFolder := make(map[string][]string)
+	for key := range sm.deferred {
+		byFolder[key.folderID] = append(byFolder[key.folderID], key.path)
+	}
+	sm.mu.RUnlock()
+	if len(byFolder) == 0 {
+		return
+	}
+
+	writers := inuse.NewWriters(sm.openForWrite)
+	for folderID, paths := range byFolder {
+		sm.mu.RLock()
+		folder, ok := sm.folders[folderID]
+		active := ok && folder.Enabled && !folder.Paused
+		sm.mu.RUnlock()
+
+		idx, err := sm.folderIndex(folderID)
+		if !active || err != nil {
+			for _, path := range paths {
+				sm.forgetDeferred(deferredKey{folderID: folderID, path: path})
+			}
+			continue
+		}
+
+		for _, path := range paths {
+			entry, ok := idx.Get(path)
+			if !ok || !entry.Pending || entry.Deleted {
+				sm.forgetDeferred(deferredKey{folderID: folderID, path: path})
+				continue
+			}
+			if !sm.uploadReady(folder, entry, writers) {
+				continue
+			}
+
+			// Record what the file settled to, so the upload carries its final version
+			if info, err := os.Stat(folder.localPath(entry.LocalRelPath())); err == nil {
+				entry, _ = sm.recordLocalChange(idx, path, entry.LocalRelPath(), info)
+			}
+			if err := sm.queueUpload(ctx, folder, entry); err != nil {
+				log.Error().Err(err).Str("path", path).Msg("Failed to queue file for upload")
+			}
+		}
+
+		if err := idx.Save(); err != nil {
+			log.Error().Err(err).Str("folder", folderID).Msg("Failed to save folder index")
+		}
+	}
+}
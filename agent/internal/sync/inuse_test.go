@@ -0,0 +1,115 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/inuse"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadReadyDefersFilesInUse(t *testing.T) {
+	manager, folder, idx := newVersionedManager(t, &versionedStorage{})
+	writing := map[string]bool{}
+	manager.openForWrite = func() (map[string]bool, error) { return writing, nil }
+	writers := func() *inuse.Writers { return inuse.NewWriters(manager.openForWrite) }
+
+	// An untouched file goes up right away
+	recordFile(t, manager, idx, folder, "old.txt", "old")
+	old := filepath.Join(folder.Path, "old.txt")
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(old, past, past))
+	entry, _ := idx.Get("old.txt")
+	assert.True(t, manager.uploadReady(folder, entry, writers()))
+
+	// A file another process holds open for writing waits, however old its modification time
+	resolved, err := filepath.EvalSymlinks(old)
+	assert.NoError(t, err)
+	writing[resolved] = true
+	assert.False(t, manager.uploadReady(folder, entry, writers()))
+	delete(writing, resolved)
+
+	// Once deferred, it goes up after keeping its size and modification time for the settle time
+	key := deferredKey{folderID: folder.ID, path: "old.txt"}
+	manager.deferred[key] = deferredUpload{since: time.Now(), changed: time.Now().Add(-settleTime), size: 3, modTime: past}
+	assert.True(t, manager.uploadReady(folder, entry, writers()))
+	assert.Empty(t, manager.deferred)
+
+	// A file just written waits, and the wait starts again whenever it changes
+	recordFile(t, manager, idx, folder, "new.txt", "draft")
+	entry, _ = idx.Get("new.txt")
+	assert.False(t, manager.uploadReady(folder, entry, writers()))
+	key = deferredKey{folderID: folder.ID, path: "new.txt"}
+	deferred := manager.deferred[key]
+	deferred.changed = time.Now().Add(-settleTime)
+	manager.deferred[key] = deferred
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, "new.txt"), []byte("draft, longer"), 0644))
+	assert.False(t, manager.uploadReady(folder, entry, writers()))
+
+	// It is uploaded anyway once the folder's timeout elapses
+	deferred = manager.deferred[key]
+	deferred.since = time.Now().Add(-time.Minute)
+	manager.deferred[key] = deferred
+	folder.InUseTimeout = time.Minute
+	assert.True(t, manager.uploadReady(folder, entry, writers()))
+	assert.Empty(t, manager.deferred)
+
+	// A negative timeout disables the check
+	folder.InUseTimeout = -1
+	recordFile(t, manager, idx, folder, "fresh.txt", "fresh")
+	entry, _ = idx.Get("fresh.txt")
+	assert.True(t, manager.uploadReady(folder, entry, writers()))
+}
+
+func TestRetryDeferredDropsInactiveFolders(t *testing.T) {
+	manager, folder, idx := newVersionedManager(t, &versionedStorage{})
+	manager.openForWrite = func() (map[string]bool, error) { return nil, nil }
+	manager.folders[folder.ID] = folder
+	folder.Paused = true
+
+	recordFile(t, manager, idx, folder, "notes.txt", "notes")
+	entry, _ := idx.Get("notes.txt")
+	assert.False(t, manager.uploadReady(folder, entry, inuse.NewWriters(manager.openForWrite)))
+	assert.Len(t, manager.deferred, 1)
+
+	manager.retryDeferred(context.Background())
+	assert.Empty(t, manager.deferred)
+}
+
+func TestNewManagerDefersFilesInUse(t *testing.T) {
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{
+		{ID: "docs", Path: t.TempDir(), Enabled: true, InUseTimeout: 30 * time.Minute},
+		{ID: "logs", Path: t.TempDir(), Enabled: true, InUseTimeout: -time.Millisecond},
+	}
+	manager := newConfiguredManager(t, cfg, storage.NewMemoryStorage(&storage.MemoryConfig{}))
+
+	assert.Equal(t, 30*time.Minute, manager.folders["docs"].InUseTimeout)
+	assert.Equal(t, -time.Second, manager.folders["logs"].InUseTimeout)
+
+	// Both folders hold a file another process is still writing
+	writing := map[string]bool{}
+	for _, folder := range cfg.SyncFolders {
+		path := filepath.Join(folder.Path, "report.docx")
+		assert.NoError(t, os.WriteFile(path, []byte("draft"), 0644))
+		past := time.Now().Add(-time.Hour)
+		assert.NoError(t, os.Chtimes(path, past, past))
+		resolved, err := filepath.EvalSymlinks(path)
+		assert.NoError(t, err)
+		writing[resolved] = true
+	}
+	manager.openForWrite = func() (map[string]bool, error) { return writing, nil }
+
+	ctx := context.Background()
+	assert.NoError(t, manager.syncFolder(ctx, manager.folders["docs"]))
+	assert.NoError(t, manager.syncFolder(ctx, manager.folders["logs"]))
+
+	// Only the folder that waits for files in use holds its upload back
+	assert.Contains(t, manager.deferred, deferredKey{folderID: "docs", path: "report.docx"})
+	assert.NotContains(t, manager.deferred, deferredKey{folderID: "logs", path: "report.docx"})
+}
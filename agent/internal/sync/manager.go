@@ -2,7 +2,10 @@ package sync
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -12,10 +15,32 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/martinshumberto/sync-manager/agent/internal/config"
-	"github.com/martinshumberto/sync-manager/agent/internal/storage"
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/inuse"
+	"github.com/martinshumberto/sync-manager/agent/internal/journal"
+	"github.com/martinshumberto/sync-manager/agent/internal/power"
+	"github.com/martinshumberto/sync-manager/agent/internal/priority"
+	"github.com/martinshumberto/sync-manager/agent/internal/staging"
 	"github.com/martinshumberto/sync-manager/agent/internal/uploader"
 	"github.com/martinshumberto/sync-manager/agent/internal/watcher"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/cloudfile"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/cron"
+	"github.com/martinshumberto/sync-manager/common/diskspace"
+	"github.com/martinshumberto/sync-manager/common/download"
+	"github.com/martinshumberto/sync-manager/common/guard"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/snapshot"
+	"github.com/martinshumberto/sync-manager/common/sparse"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/martinshumberto/sync-manager/common/telemetry"
+	"github.com/martinshumberto/sync-manager/common/watchlimit"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // EventType is a temporary type to work around the compilation error
@@ -25,6 +50,7 @@ type EventType = watcher.EventType
 type Event struct {
 	Type      EventType
 	Path      string
+	OldPath   string // Path the file was moved from, for watcher.EventMove
 	Timestamp time.Time
 }
 
@@ -42,8 +68,13 @@ const (
 	SyncStateError SyncState = "error"
 	// SyncStatePaused indicates that synchronization is paused
 	SyncStatePaused SyncState = "paused"
+	// SyncStateOffline means the remote storage cannot be reached
+	SyncStateOffline SyncState = "offline"
 )
 
+// reconcileBatch is how many remote changes are reconciled between saves of the index
+const reconcileBatch = 1000
+
 // SyncStats tracks statistics about the sync process
 type SyncStats struct {
 	TotalFiles      int64
@@ -57,20 +88,64 @@ type SyncStats struct {
 	Version         string
 }
 
+// folderWatcher is the subset of the file watcher used by the sync manager
+type folderWatcher interface {
+	WatchPath(path string, recursive bool, excludePatterns []string) error
+	Start()
+	Stop() error
+	AddFolder(path string, excludePatterns []string) error
+	RemoveFolder(path string) error
+}
+
+// PeerFetcher fetches files from other devices on the local network
+type PeerFetcher interface {
+	Fetch(ctx context.Context, key, hash string, w io.Writer) (int64, error)
+}
+
 // SyncManager manages the synchronization between the local file system and the remote storage
 type SyncManager struct {
 	uploader     *uploader.Uploader
+	downloader   *download.Downloader
 	storage      storage.Storage
-	watcher      *watcher.FileWatcher // Use concrete type instead of interface
+	watcher      folderWatcher
 	config       *config.Config
-	stats        SyncStats
+	stats        *stats.Registry
+	version      string
 	state        SyncState
 	deviceID     string
 	syncInterval time.Duration
-	stopChan     chan struct{}
-	cancel       context.CancelFunc
-	folders      map[string]*FolderSync
-	mu           sync.RWMutex
+	// fullScanInterval bounds how long a periodic sync trusts the watcher and skips walking a
+	// folder; zero walks on every sync
+	fullScanInterval time.Duration
+	stopChan         chan struct{}
+	ctx              context.Context
+	cancel           context.CancelFunc
+	offline          bool
+	paused           bool // Transfers are paused by the power policy or the bandwidth cap
+	folders          map[string]*FolderSync
+	indexDir         string
+	guardPath        string
+	spacePath        string
+	journalPath      string
+	journal          *journal.Journal // Operations in progress, nil until the manager starts
+	watchesPath      string
+	watches          *watchlimit.State // File watch usage last recorded, guarded by watchesMu
+	watchesMu        sync.Mutex
+	freeSpace        func(path string) (uint64, error)
+	shortages        map[string]diskspace.Shortage // Folders whose remote changes wait for disk space
+	peers            PeerFetcher
+	excludes         ExcludeSource
+	events           EventRecorder
+	runs             RunRecorder
+	staging          *staging.Area // Where temporary copies are written, the system temp directory when nil
+	clockSkew        time.Duration // How far the local clock is ahead of the storage server's
+	openForWrite     inuse.Detector
+	deferred         map[deferredKey]deferredUpload // Uploads waiting for files in use to settle
+	indexes          map[string]*index.Index
+	operation        *operation // Sync running, nil while none is
+	stateHandlers    []func(folderID string, state SyncState)
+	reschedule       chan struct{}
+	mu               sync.RWMutex
 }
 
 // FolderSync manages synchronization for a specific folder
@@ -81,43 +156,182 @@ type FolderSync struct {
 	LastSync        time.Time
 	TwoWaySync      bool
 	Enabled         bool
+	Paused          bool
+	Priority        int           // Lower numbers upload first, see uploader.UploadTask
+	Interval        time.Duration // Zero means the global sync interval
+	Mode            string        // commonconfig.FolderModeMirror or FolderModeBackup
+	Retention       snapshot.Policy
+	StorageClass    string              // Storage class of uploaded objects, empty for the bucket default
+	PackSmallFiles  int64               // Backup mode: largest file packed with others into one object, zero to not pack
+	Roots           []config.FolderRoot // Extra local directories, each synced under its prefix
+	Mirror          config.MirrorConfig // Removal of remote files deleted locally, for one-way folders
+	ConflictPolicy  string              // commonconfig.ConflictKeepBoth (default) or another conflict policy
+	InUseTimeout    time.Duration       // Longest wait for files in use, zero for the default, negative to not wait
+	File            string              // Name of the only file synced from Path, empty for a whole folder
+	InitialMerge    string              // Conflict policy of the first sync, ConflictPolicy when empty
+	Subscribe       bool                // Only download: the remote folder belongs to another device
+	LocalChanges    string              // What a subscribed folder does with local changes, revert when empty
+	ArchiveAfter    time.Duration       // Age of unmodified uploaded files replaced by placeholders, zero to keep them
+	RemotePath      string              // Storage prefix of the folder's files, the folder ID when empty
+	Schedule        *cron.Schedule      // When the folder syncs, overriding Interval; nil to sync every interval
+	AgeFilter       config.AgeFilter    // Which files are uploaded by their modification time
+	Hooks           config.FolderHooks  // Commands run before and after each sync run
+	Checksum        checksum.Algorithm  // Hash of the folder's uploads, checksum.SHA256 when empty
+
+	merging     bool       // Set during the first sync, which resolves conflicts by InitialMerge
+	collisions  [][]string // Remote files left out for differing only in case, see skipCaseCollisions
+	lastAttempt time.Time
+	lastScan    time.Time // When the folder was last walked in full
+	scanned     int64     // Files found by the walk of the current run
+	queued      int64     // Uploads queued by the current run
+	dirty       bool      // The watcher saw a change since the last walk
+	state       SyncState // What the folder is doing, reported by FolderStatus
+	lastError   string    // Why the last sync failed, empty when it succeeded
+}
+
+// roots returns every local root of the folder, its own path first
+func (f *FolderSync) roots() []config.FolderRoot {
+	return config.SyncFolder{LocalPath: f.Path, Roots: f.Roots}.AllRoots()
+}
+
+// tracks reports whether a key of the folder is synced, which for a single-file folder is only its file
+func (f *FolderSync) tracks(key string) bool {
+	return config.SyncFolder{File: f.File}.Tracks(key)
+}
+
+// downloads reports whether the folder applies remote changes, as two-way and subscribed folders do
+func (f *FolderSync) downloads() bool {
+	return f.TwoWaySync || f.Subscribe
+}
+
+// keyPrefix returns the storage prefix every key of the folder starts with, ending in a slash
+func (f *FolderSync) keyPrefix() string {
+	if f.RemotePath != "" {
+		return f.RemotePath + "/"
+	}
+	return f.ID + "/"
+}
+
+// scopePrefix returns the storage prefix of the keys of the subtree at scope, that of the
+// whole folder when scope is empty
+func (f *FolderSync) scopePrefix(scope string) string {
+	if scope == "" {
+		return f.keyPrefix()
+	}
+	return f.key(scope) + "/"
+}
+
+// key returns the storage key of a slash-separated path relative to the folder
+func (f *FolderSync) key(relPath string) string {
+	return f.keyPrefix() + relPath
+}
+
+// localPath returns the local path of a slash-separated path relative to the folder
+func (f *FolderSync) localPath(relPath string) string {
+	return config.RootPath(f.roots(), relPath)
 }
 
 // NewSyncManager creates a new sync manager
 func NewSyncManager(cfg *config.Config, storage storage.Storage, uploader *uploader.Uploader) (*SyncManager, error) {
 	// Generate a Device ID if it doesn't exist
-	deviceID := generateRandomID()
+	deviceID := cfg.DeviceID
+	if deviceID == "" {
+		deviceID = generateRandomID()
+		cfg.DeviceID = deviceID
+	}
+
+	indexDir, err := index.DefaultDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve index directory: %w", err)
+	}
+
+	guardPath, err := guard.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve deletion guard path: %w", err)
+	}
+
+	spacePath, err := diskspace.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve disk space state path: %w", err)
+	}
+
+	journalPath, err := journal.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve journal path: %w", err)
+	}
+
+	watchesPath, err := watchlimit.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve watch state path: %w", err)
+	}
 
 	sm := &SyncManager{
-		uploader:     uploader,
-		storage:      storage,
-		config:       cfg,
-		state:        SyncStateIdle,
-		deviceID:     deviceID,
-		syncInterval: time.Duration(cfg.Sync.IntervalMinutes) * time.Minute,
-		stopChan:     make(chan struct{}),
-		folders:      make(map[string]*FolderSync),
-		stats: SyncStats{
-			StartTime: time.Now(),
-			Version:   "1.0.0", // Default version
-		},
+		uploader:         uploader,
+		downloader:       download.NewDownloader(storage, nil),
+		storage:          storage,
+		config:           cfg,
+		state:            SyncStateIdle,
+		deviceID:         deviceID,
+		syncInterval:     time.Duration(cfg.Sync.IntervalMinutes) * time.Minute,
+		fullScanInterval: time.Duration(cfg.Sync.FullScanMinutes) * time.Minute,
+		stopChan:         make(chan struct{}),
+		folders:          make(map[string]*FolderSync),
+		indexDir:         indexDir,
+		guardPath:        guardPath,
+		spacePath:        spacePath,
+		journalPath:      journalPath,
+		watchesPath:      watchesPath,
+		freeSpace:        diskspace.Available,
+		shortages:        make(map[string]diskspace.Shortage),
+		indexes:          make(map[string]*index.Index),
+		reschedule:       make(chan struct{}, 1),
+		openForWrite:     inuse.OpenForWrite,
+		deferred:         make(map[deferredKey]deferredUpload),
+		stats:            stats.NewRegistry(),
+		version:          "1.0.0", // Default version
 	}
 
 	// Initialize folders from config
 	for id, folder := range cfg.GetAllFolders() {
-		sm.folders[id] = &FolderSync{
-			ID:              id,
-			Path:            folder.LocalPath,
-			ExcludePatterns: folder.ExcludePatterns,
-			LastSync:        time.Time{}, // Never synced
-			TwoWaySync:      false,       // Default to one-way sync
-			Enabled:         folder.Enabled,
-		}
+		sm.folders[id] = newFolderSync(id, folder)
 	}
 
 	return sm, nil
 }
 
+// newFolderSync returns the folder configured as folder, never synced yet
+func newFolderSync(id string, folder config.SyncFolder) *FolderSync {
+	return &FolderSync{
+		ID:              id,
+		Path:            folder.LocalPath,
+		ExcludePatterns: folder.ExcludePatterns,
+		LastSync:        time.Time{}, // Never synced
+		TwoWaySync:      folder.TwoWaySync,
+		Enabled:         folder.Enabled,
+		Paused:          folder.Paused,
+		Priority:        folder.Priority,
+		Interval:        time.Duration(folder.IntervalMinutes) * time.Minute,
+		Mode:            folder.Mode,
+		Retention:       snapshot.Policy(folder.Retention),
+		StorageClass:    folder.StorageClass,
+		PackSmallFiles:  folder.PackSmallFiles,
+		Roots:           folder.Roots,
+		Mirror:          folder.Mirror,
+		ConflictPolicy:  folder.ConflictPolicy,
+		InUseTimeout:    time.Duration(folder.InUseTimeoutSeconds) * time.Second,
+		File:            folder.File,
+		InitialMerge:    folder.InitialMerge,
+		Subscribe:       folder.Subscribe,
+		LocalChanges:    folder.LocalChanges,
+		ArchiveAfter:    time.Duration(folder.ArchiveAfterDays) * day,
+		RemotePath:      folder.RemotePath,
+		Schedule:        parseSchedule(id, folder.Schedule),
+		AgeFilter:       folder.AgeFilter,
+		Hooks:           folder.Hooks,
+		Checksum:        checksum.Algorithm(folder.Checksum),
+	}
+}
+
 // Start starts the sync manager
 func (sm *SyncManager) Start() error {
 	log.Info().Msg("Starting sync manager")
@@ -125,24 +339,46 @@ func (sm *SyncManager) Start() error {
 	// Create a context for all sync operations
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Store the cancel function to be used when stopping
+	// Store the context for catch-up syncs and the cancel function to be used when stopping
+	sm.mu.Lock()
+	sm.ctx = ctx
 	sm.cancel = cancel
+	sm.mu.Unlock()
+
+	// Operations a crash cut short are recovered before the first sync
+	interrupted := sm.openJournal()
 
 	// Start file watcher
 	fw, err := watcher.NewFileWatcher()
 	if err != nil {
 		return fmt.Errorf("failed to create file watcher: %w", err)
 	}
+
+	// Directories past the system's watch limit are polled, and the CLI told about it
+	fw.SetUsageHandler(sm.recordWatches)
+
+	// Add handler for file events
+	fw.AddHandler(func(event watcher.Event) {
+		sm.handleFileEvent(ctx, Event{
+			Path:      event.Path,
+			OldPath:   event.OldPath,
+			Type:      event.Type,
+			Timestamp: event.Timestamp,
+		})
+	})
 	sm.watcher = fw
 
 	// Watch all enabled folders
 	sm.mu.RLock()
 	for _, folder := range sm.folders {
-		if folder.Enabled {
-			if err := sm.watcher.WatchPath(folder.Path, true, folder.ExcludePatterns); err != nil {
-				log.Error().Err(err).Str("path", folder.Path).Msg("Failed to watch folder")
+		if !folder.Enabled {
+			continue
+		}
+		for _, root := range folder.roots() {
+			if err := sm.watcher.WatchPath(root.Path, true, sm.mergeExcludes(sm.excludes, folder)); err != nil {
+				log.Error().Err(err).Str("path", root.Path).Msg("Failed to watch folder")
 			} else {
-				log.Info().Str("path", folder.Path).Msg("Started watching folder")
+				log.Info().Str("path", root.Path).Str("prefix", root.Prefix).Msg("Started watching folder")
 			}
 		}
 	}
@@ -151,22 +387,28 @@ func (sm *SyncManager) Start() error {
 	// Start the file watcher
 	sm.watcher.Start()
 
-	// Add handler for file events
-	sm.watcher.AddHandler(func(event watcher.Event) {
-		sm.handleFileEvent(ctx, Event{
-			Path:      event.Path,
-			Type:      event.Type,
-			Timestamp: event.Timestamp,
-		})
-	})
+	// Track upload results so the index knows what reached the remote
+	go sm.processUploadResults(ctx)
 
 	// Start periodic sync
 	go sm.periodicSync(ctx)
 
+	// Resume the downloads held back for lack of disk space once it is freed
+	go sm.watchSpace(ctx)
+
+	// Upload the files that were in use once they settle
+	go sm.watchInUse(ctx)
+
+	// Abort the multipart uploads left unfinished for too long
+	go sm.cleanupUploads(ctx)
+
 	// Run initial scan if enabled
-	if sm.config.Sync.AutoSync {
-		go sm.FullSync(ctx)
-	}
+	go func() {
+		sm.recoverOperations(ctx, interrupted)
+		if sm.config.Sync.AutoSync {
+			sm.FullSync(ctx)
+		}
+	}()
 
 	return nil
 }
@@ -183,6 +425,14 @@ func (sm *SyncManager) Stop() error {
 	// Close stop channel
 	close(sm.stopChan)
 
+	// Operations still running are kept in the journal for the next start
+	sm.mu.RLock()
+	j := sm.journal
+	sm.mu.RUnlock()
+	if err := j.Close(); err != nil {
+		log.Warn().Err(err).Msg("Failed to close operation journal")
+	}
+
 	// Stop watcher
 	if sm.watcher != nil {
 		return sm.watcher.Stop()
@@ -191,274 +441,1571 @@ func (sm *SyncManager) Stop() error {
 	return nil
 }
 
-// FullSync performs a full sync of all enabled folders
+// FullSync performs a full sync of all enabled, unpaused folders
 func (sm *SyncManager) FullSync(ctx context.Context) error {
+	log.Info().Msg("Starting full sync")
+	return sm.runSync(ctx, status.FullSync, sm.activeFolders(), false)
+}
+
+// activeFolders returns the enabled, unpaused folders
+func (sm *SyncManager) activeFolders() []*FolderSync {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	folders := make([]*FolderSync, 0, len(sm.folders))
+	for _, folder := range sm.folders {
+		if folder.Enabled && !folder.Paused {
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
+
+// syncFolders syncs the given folders one after another, stopping early when ctx is cancelled.
+// Callers go through runSync, so only one pass runs at a time.
+func (sm *SyncManager) syncFolders(ctx context.Context, kind string, folders []*FolderSync) error {
+	return sm.syncScoped(ctx, kind, folders, "")
+}
+
+// syncScoped is syncFolders limited to the subtree at scope of each folder when scope is set
+func (sm *SyncManager) syncScoped(ctx context.Context, kind string, folders []*FolderSync, scope string) error {
 	sm.mu.Lock()
 	sm.state = SyncStateScanning
 	sm.mu.Unlock()
 
-	log.Info().Msg("Starting full sync")
-
 	defer func() {
 		sm.mu.Lock()
 		sm.state = SyncStateIdle
 		sm.mu.Unlock()
 	}()
 
-	sm.mu.RLock()
-	folders := make([]*FolderSync, 0, len(sm.folders))
-	for _, folder := range sm.folders {
-		if folder.Enabled {
-			folders = append(folders, folder)
+	for i, folder := range folders {
+		sm.operationProgress(folder.ID, i)
+		// A folder on an unplugged drive is skipped rather than failed, and syncs once the drive is back
+		if err := storage.Present(sm.storage, folder.keyPrefix()); err != nil {
+			log.Info().Err(err).Str("folder", folder.Path).Msg("Drive of the folder is not mounted, skipping sync")
+			continue
 		}
-	}
-	sm.mu.RUnlock()
-
-	for _, folder := range folders {
-		if err := sm.syncFolder(ctx, folder); err != nil {
+		run := sm.startRun(kind, folder)
+		err := sm.preSyncHook(ctx, run, folder)
+		if err == nil {
+			err = sm.syncFolderAs(ctx, kind, folder, scope)
+			err = sm.postSyncHook(ctx, run, folder, err)
+		}
+		sm.finishRun(ctx, run, folder, err)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Info().Str("folder", folder.Path).Msg("Sync cancelled")
+				return ctx.Err()
+			}
 			log.Error().Err(err).Str("folder", folder.Path).Msg("Failed to sync folder")
-			sm.stats.Errors++
+			sm.stats.Failed(folder.ID)
 			continue
 		}
+		if scope == "" {
+			sm.stats.Synced(folder.ID, time.Now())
+		}
+		if idx, err := sm.folderIndex(folder.ID); err == nil {
+			sm.stats.SetSummary(folder.ID, idx.Summary())
+		}
 	}
+	sm.operationProgress("", len(folders))
 
-	sm.mu.Lock()
-	sm.stats.LastSyncTime = time.Now()
-	sm.mu.Unlock()
+	sm.stats.Synced("", time.Now())
+	totals := sm.stats.Snapshot().Global
 
 	log.Info().
-		Int64("uploaded", sm.stats.FilesUploaded).
-		Int64("bytes_uploaded", sm.stats.BytesUploaded).
-		Msg("Full sync completed")
+		Int64("uploaded", totals.FilesUploaded).
+		Int64("bytes_uploaded", totals.BytesUploaded).
+		Int("folders", len(folders)).
+		Msg("Sync completed")
 
 	return nil
 }
 
-// syncFolder syncs a specific folder
+// syncFolder syncs a specific folder, walking it in full
 func (sm *SyncManager) syncFolder(ctx context.Context, folder *FolderSync) error {
-	log.Info().Str("folder", folder.Path).Msg("Syncing folder")
+	return sm.reconcileFolder(ctx, folder, true, "")
+}
+
+// reconcileFolder syncs a folder, walking its roots for local changes when scan is set.
+// Without it only remote changes and uploads still pending are handled, which is all a
+// folder needs when the watcher saw nothing change in it. When scope is set only the
+// subtree at that path is walked, listed and reconciled, leaving the rest of the folder
+// for its next sync.
+func (sm *SyncManager) reconcileFolder(ctx context.Context, folder *FolderSync, scan bool, scope string) (err error) {
+	log.Info().Str("folder", folder.Path).Bool("scan", scan).Str("scope", scope).Msg("Syncing folder")
+
+	ctx, span := telemetry.Tracer().Start(ctx, "sync.folder", trace.WithAttributes(telemetry.FolderIDKey.String(folder.ID)))
+	defer func() { telemetry.End(span, err) }()
 
 	sm.mu.Lock()
 	sm.state = SyncStateSyncing
+	folder.lastAttempt = time.Now()
+	sm.setFolderState(folder, SyncStateScanning)
 	sm.mu.Unlock()
 
-	// Walk through all files in the folder
-	err := filepath.Walk(folder.Path, func(path string, info os.FileInfo, err error) error {
+	// The folder ends idle, or in error with the failure kept for the status. A cancelled
+	// sync is not a failure and keeps the last one.
+	defer func() {
+		sm.mu.Lock()
+		defer sm.mu.Unlock()
+		if err != nil && ctx.Err() != nil {
+			sm.setFolderState(folder, SyncStateIdle)
+			return
+		}
+		folder.lastError = ""
 		if err != nil {
+			folder.lastError = err.Error()
+			sm.setFolderState(folder, SyncStateError)
+			return
+		}
+		sm.setFolderState(folder, SyncStateIdle)
+	}()
+
+	if folder.Mode == commonconfig.FolderModeBackup {
+		return sm.backupFolder(ctx, folder)
+	}
+
+	idx, err := sm.folderIndex(folder.ID)
+	if err != nil {
+		return err
+	}
+
+	// A two-way folder whose index is still empty has never synced, so the files already on
+	// both sides are merged rather than treated as changed on both
+	sm.mu.Lock()
+	folder.merging = folder.downloads() && len(idx.Paths()) == 0
+	sm.mu.Unlock()
+	defer func() {
+		sm.mu.Lock()
+		folder.merging = false
+		sm.mu.Unlock()
+	}()
+
+	// Walk every root unless nothing changed locally since the last walk
+	var seen map[string]string
+	if scan {
+		if seen, err = sm.scanFolder(ctx, folder, idx, scope); err != nil {
 			return err
 		}
+	}
 
-		// Skip directories for now
-		if info.IsDir() {
-			return nil
+	// Set when remote changes were held back for lack of disk space
+	var spaceErr error
+
+	sm.mu.Lock()
+	sm.setFolderState(folder, SyncStateSyncing)
+	sm.mu.Unlock()
+
+	// If two-way sync is enabled, reconcile remote changes before uploading
+	if folder.downloads() {
+		downloadCtx, downloadSpan := telemetry.Tracer().Start(ctx, "sync.download")
+		err := sm.downloadFromRemote(downloadCtx, folder, idx, scope)
+		telemetry.End(downloadSpan, err)
+		if errors.Is(err, ErrInsufficientSpace) {
+			// Local changes still go up while the downloads wait for space
+			spaceErr = err
+		} else if err != nil {
+			return fmt.Errorf("failed to download from remote: %w", err)
+		}
+	}
+
+	if folder.Subscribe {
+		// A subscribed folder never uploads: its local changes are reverted or flagged instead,
+		// once the published copies they go back to could be downloaded
+		if spaceErr == nil {
+			sm.subscribedChanges(ctx, folder, idx, seen, scope)
 		}
+	} else {
+		sm.queuePending(ctx, folder, idx, scope)
+	}
 
-		// Check if the path matches any exclude patterns
-		relPath, err := filepath.Rel(folder.Path, path)
+	// Remove remote files that no longer exist locally, if the folder opted in
+	if scan && folder.Mirror.DeleteOrphans && !folder.TwoWaySync {
+		pruneCtx, pruneSpan := telemetry.Tracer().Start(ctx, "sync.prune")
+		err := sm.pruneOrphans(pruneCtx, folder, idx, seen, scope)
+		telemetry.End(pruneSpan, err)
 		if err != nil {
-			return err
+			log.Error().Err(err).Str("folder", folder.ID).Msg("Failed to remove remote orphans")
+			sm.stats.Failed(folder.ID)
+		}
+	}
+
+	// Free the disk space of files left unmodified, once their uploads are done
+	if folder.ArchiveAfter > 0 && scope == "" {
+		sm.archiveFiles(ctx, folder, idx)
+	}
+
+	if err := idx.Save(); err != nil {
+		log.Error().Err(err).Str("folder", folder.ID).Msg("Failed to save folder index")
+	}
+
+	if spaceErr != nil {
+		return spaceErr
+	}
+
+	// Update last sync time, which only a sync of the whole folder moves
+	if scope == "" {
+		sm.mu.Lock()
+		folder.LastSync = time.Now()
+		sm.mu.Unlock()
+	}
+
+	return nil
+}
+
+// queuePending queues every entry of a folder, or of its subtree at scope when set, whose
+// current version has not reached the remote yet
+func (sm *SyncManager) queuePending(ctx context.Context, folder *FolderSync, idx *index.Index, scope string) {
+	queueCtx, queueSpan := telemetry.Tracer().Start(ctx, "sync.queue")
+	defer queueSpan.End()
+
+	queued := 0
+	now := time.Now()
+	writers := inuse.NewWriters(sm.openForWrite)
+	for _, relPath := range idx.Paths() {
+		entry, ok := idx.Get(relPath)
+		if !ok || !entry.Pending || entry.Deleted || !inScope(relPath, scope) || (!entry.Dir && folder.outsideWindow(entry.ModTime, now)) {
+			continue
+		}
+		if entry.Dir {
+			if err := sm.uploadDir(queueCtx, folder, idx, entry); err != nil {
+				log.Error().Err(err).Str("path", relPath).Msg("Failed to upload directory")
+				sm.stats.Failed(folder.ID)
+			}
+			continue
+		}
+		if !sm.uploadReady(folder, entry, writers) {
+			continue
+		}
+		if err := sm.queueUpload(queueCtx, folder, entry); err != nil {
+			log.Error().Err(err).Str("path", relPath).Msg("Failed to queue file for upload")
+			continue
+		}
+		queued++
+	}
+	queueSpan.SetAttributes(telemetry.FilesKey.Int(queued))
+
+	sm.mu.Lock()
+	folder.queued += int64(queued)
+	sm.mu.Unlock()
+}
+
+// scanFolder walks every root of a folder, or only the subtree at scope when set, bumping the
+// versions of anything changed locally, and returns the keys found with the on-disk name seen
+// for each
+func (sm *SyncManager) scanFolder(ctx context.Context, folder *FolderSync, idx *index.Index, scope string) (map[string]string, error) {
+	// Track the on-disk name seen for each canonical key to catch NFC/NFD duplicates
+	seen := make(map[string]string)
+	var files int64
+
+	// Events from here on mark the folder dirty again, unless part of it is left unwalked
+	started := time.Now()
+	if scope == "" {
+		sm.mu.Lock()
+		folder.dirty = false
+		sm.mu.Unlock()
+	}
+
+	var err error
+
+	_, scanSpan := telemetry.Tracer().Start(ctx, "sync.scan")
+
+	// Walk through all files of every root, bumping versions of anything changed locally
+	excluded := sm.excludePatterns(folder)
+	roots := folder.roots()
+	for _, walk := range scopeWalks(roots, scope) {
+		// A subtree may only exist remotely, as after files were restored there
+		if _, statErr := os.Stat(walk.path); scope != "" && os.IsNotExist(statErr) {
+			continue
+		}
+		scanned := walk.root
+		err = filepath.Walk(walk.path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// Files are keyed relative to the folder, under the prefix of their root
+			root, localRel, ok := config.ResolveRoot(roots, path)
+			if !ok || root.Path != scanned.Path {
+				return nil
+			}
+
+			// Check if the path matches any exclude patterns
+			if watcher.ShouldExclude(localRel, excluded) {
+				return nil
+			}
+
+			key := index.NormalizeKey(localRel)
+
+			// A single-file folder only looks at its file, not at the rest of its directory
+			if !folder.tracks(key) {
+				if info.IsDir() && key != "" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// Directories are tracked too, so empty ones and their metadata reach other devices
+			if info.IsDir() {
+				if _, duplicate := seen[key]; key != "" && !duplicate {
+					seen[key] = localRel
+					sm.recordLocalDir(idx, key, localRel, info)
+				}
+				return nil
+			}
+
+			if other, duplicate := seen[key]; duplicate {
+				kept, err := sm.mergeSplitFile(folder, key, other, localRel)
+				if err != nil {
+					log.Error().Err(err).Str("file", key).Msg("Failed to merge files differing only in Unicode normalization")
+					return nil
+				}
+				seen[key] = kept
+				if info, err := os.Stat(folder.localPath(kept)); err == nil {
+					sm.recordLocalChange(idx, key, kept, info)
+				}
+				return nil
+			}
+
+			seen[key] = localRel
+			files++
+			// Files outside the age window of the folder are kept as they are on both sides
+			if !folder.outsideWindow(info.ModTime(), started) {
+				sm.recordLocalChange(idx, key, localRel, info)
+			}
+			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	scanSpan.SetAttributes(telemetry.FilesKey.Int(len(seen)))
+	telemetry.End(scanSpan, err)
+	if err != nil {
+		sm.markDirty(folder)
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	sm.mu.Lock()
+	if scope == "" {
+		folder.lastScan = started
+	}
+	folder.scanned = files
+	sm.mu.Unlock()
+	return seen, nil
+}
+
+// backupFolder stores a new snapshot of a backup-mode folder and applies its retention policy
+func (sm *SyncManager) backupFolder(ctx context.Context, folder *FolderSync) error {
+	sm.mu.Lock()
+	sm.setFolderState(folder, SyncStateSyncing)
+	sm.mu.Unlock()
+
+	repo := snapshot.NewRepository(sm.storage, folder.ID, sm.deviceID)
+	repo.SetStorageClass(folder.StorageClass)
+	repo.SetPacking(folder.PackSmallFiles)
+
+	createCtx, createSpan := telemetry.Tracer().Start(ctx, "snapshot.create")
+	manifest, err := repo.Create(createCtx, folder.Path, sm.excludePatterns(folder))
+	if err == nil {
+		createSpan.SetAttributes(telemetry.FilesKey.Int(len(manifest.Files)))
+	}
+	telemetry.End(createSpan, err)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	log.Info().
+		Str("folder", folder.ID).
+		Str("snapshot", manifest.ID).
+		Int("files", len(manifest.Files)).
+		Msg("Snapshot created")
+
+	pruneCtx, pruneSpan := telemetry.Tracer().Start(ctx, "snapshot.prune")
+	removed, err := repo.Prune(pruneCtx, folder.Retention)
+	telemetry.End(pruneSpan, err)
+	if err != nil {
+		// The snapshot itself succeeded; pruning is retried on the next run
+		log.Error().Err(err).Str("folder", folder.ID).Msg("Failed to prune snapshots")
+	} else if len(removed) > 0 {
+		log.Info().Str("folder", folder.ID).Int("removed", len(removed)).Msg("Pruned snapshots")
+	}
+
+	sm.mu.Lock()
+	folder.LastSync = time.Now()
+	sm.mu.Unlock()
+
+	return nil
+}
+
+// downloadFromRemote reconciles remote files with the local folder using version vectors,
+// only those of the subtree at scope when set
+func (sm *SyncManager) downloadFromRemote(ctx context.Context, folder *FolderSync, idx *index.Index, scope string) error {
+	sm.mu.RLock()
+	paused := sm.paused
+	sm.mu.RUnlock()
+	if paused {
+		log.Info().Str("folder", folder.Path).Msg("Transfers paused, remote changes wait")
+		return nil
+	}
+
+	log.Info().Str("folder", folder.Path).Msg("Downloading remote changes")
+	excluded := sm.excludePatterns(folder)
+
+	// Get remote file list for this folder
+	remoteFiles, err := sm.storage.ListFiles(ctx, folder.scopePrefix(scope))
+	if err != nil {
+		return fmt.Errorf("failed to list remote files: %w", err)
+	}
+
+	// Group objects by canonical key so names that only differ in normalization are treated as one file
+	remoteByKey := make(map[string][]storage.FileInfo)
+	var keys []string
+	for _, remoteFile := range remoteFiles {
+		// Key format is: remote-prefix/relative/path/to/file.ext
+		key := index.NormalizeKey(strings.TrimPrefix(remoteFile.Key, folder.keyPrefix()))
+		if !folder.tracks(key) {
+			continue
+		}
+		if _, ok := remoteByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		remoteByKey[key] = append(remoteByKey[key], remoteFile)
+	}
+
+	// Files that changed remotely, reconciled in parallel once the listing is processed
+	type change struct {
+		relPath string
+		file    storage.FileInfo
+	}
+	var (
+		dirs    []string
+		changes []change
+		files   []string
+	)
+	for _, relPath := range keys {
+		if _, ok := storage.MarkerDir(relPath); !ok && relPath != "" && !watcher.ShouldExclude(relPath, excluded) {
+			files = append(files, relPath)
+		}
+	}
+	collided := sm.skipCaseCollisions(folder, files)
+	for _, relPath := range keys {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// Process file
+		}
+
+		if dir, ok := storage.MarkerDir(relPath); ok {
+			dirs = append(dirs, dir)
+			continue
+		}
+
+		if relPath == "" || watcher.ShouldExclude(relPath, excluded) || collided[relPath] {
+			continue
+		}
+
+		remoteFile, duplicates := splitRemoteFiles(folder.key(relPath), remoteByKey[relPath])
+		if len(duplicates) > 0 {
+			sm.mergeSplitRemote(ctx, folder, idx, relPath, remoteFile, duplicates)
+		}
+
+		// Nothing changed remotely since we last reconciled this file
+		entry, known := idx.Get(relPath)
+		if known && remoteFile.ETag != "" && entry.RemoteETag == remoteFile.ETag {
+			continue
+		}
+
+		changes = append(changes, change{relPath: relPath, file: remoteFile})
+	}
+
+	// Check the whole download fits before starting, rather than failing file by file
+	var needed uint64
+	for _, c := range changes {
+		needed += uint64(max(c.file.Size, 0))
+	}
+	if needed > 0 {
+		if err := sm.checkSpace(folder, needed); err != nil {
+			return err
+		}
+	}
+
+	sm.mu.RLock()
+	downloader := sm.downloader
+	sm.mu.RUnlock()
+
+	// Changes are reconciled in batches, saving the index after each, so a large first sync
+	// resumes where it stopped and the files of finished batches are released
+	for len(changes) > 0 {
+		batch := changes[:min(len(changes), reconcileBatch)]
+		err = downloader.Each(ctx, len(batch), func(ctx context.Context, i int) {
+			if err := sm.reconcileFile(ctx, folder, idx, batch[i].relPath, batch[i].file); err != nil {
+				log.Error().Err(err).Str("file", batch[i].relPath).Msg("Failed to reconcile remote file")
+				sm.stats.Failed(folder.ID)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		clear(batch)
+		changes = changes[len(batch):]
+		if len(changes) > 0 {
+			if err := idx.Save(); err != nil {
+				log.Error().Err(err).Str("folder", folder.ID).Msg("Failed to save folder index")
+			}
+		}
+	}
+
+	// Directories go last, since writing the files inside them changes their modification time
+	for _, dir := range dirs {
+		if dir == "" || watcher.ShouldExclude(dir, excluded) {
+			continue
+		}
+
+		remoteFile := remoteByKey[storage.DirMarkerKey(dir)][0]
+		entry, known := idx.Get(dir)
+		if known && remoteFile.ETag != "" && entry.RemoteETag == remoteFile.ETag {
+			continue
+		}
+
+		if err := sm.reconcileDir(ctx, folder, idx, dir, remoteFile); err != nil {
+			log.Error().Err(err).Str("dir", dir).Msg("Failed to reconcile remote directory")
+			sm.stats.Failed(folder.ID)
+		}
+	}
+
+	return nil
+}
+
+// reconcileFile downloads a remote file and applies it according to its version vector
+func (sm *SyncManager) reconcileFile(ctx context.Context, folder *FolderSync, idx *index.Index, relPath string, remoteFile storage.FileInfo) error {
+	// Write to the name already on disk, or to the form the local filesystem prefers for new files
+	localRel := index.LocalForm(relPath)
+	if entry, ok := idx.Get(relPath); ok {
+		localRel = entry.LocalRelPath()
+	}
+	localPath := folder.localPath(localRel)
+
+	// Compare version vectors first so files we already have are not downloaded again
+	_, remoteMetadata, err := sm.storage.GetFileInfo(ctx, remoteFile.Key)
+	if err != nil {
+		return fmt.Errorf("failed to get remote file info: %w", err)
+	}
+	entry, remoteVersion, ordering := compareRemote(idx, relPath, localPath, remoteMetadata)
+	if ordering == index.Equal || ordering == index.Before {
+		entry.RemoteETag = remoteFile.ETag
+		idx.Put(entry)
+		return nil
+	}
+
+	// On the first sync a file already identical on both sides is not a conflict: it takes
+	// the remote version as it is
+	if hash := checksum.FromMetadata(remoteMetadata); ordering == index.Concurrent && folder.merging && hash != "" {
+		if localHash, err := fileHash(localPath, checksum.Of(hash)); err == nil && localHash == hash {
+			entry.Version = remoteVersion
+			entry.Hash, entry.RemoteHash = hash, hash
+			entry.RemoteETag, entry.RemoteSize = remoteFile.ETag, entry.Size
+			entry.Pending = false
+			idx.Put(entry)
+			return nil
+		}
+	}
+
+	// Ensure parent directory exists
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Download into a temporary file next to the target so it can be renamed atomically
+	tmpFile, err := os.CreateTemp(filepath.Dir(localPath), ".sync-manager-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	// A crash before the rename leaves the temporary file for the next start to remove
+	sm.beginOp(journal.Op{Kind: journal.Download, FolderID: folder.ID, Key: remoteFile.Key, Path: tmpPath})
+	defer sm.endOp(journal.Download, remoteFile.Key)
+
+	// Downloads share the uploader's tracker so progress covers both directions
+	tracker := sm.uploader.Progress()
+	tracker.Add(1, remoteFile.Size)
+	transfer := tracker.Start(relPath, remoteFile.Size)
+
+	metadata, err := sm.download(ctx, remoteFile, remoteMetadata, tmpFile, transfer)
+	tmpFile.Close() // Close the file regardless of error
+	transfer.Finish(err)
+	if err != nil {
+		tracker.Drop(1, remoteFile.Size)
+		var integrity *download.IntegrityError
+		if errors.As(err, &integrity) {
+			sm.recordEvent(folder.ID, relPath, models.SyncEventIntegrityFailure, models.IntegrityFailureDetails{
+				Key:      integrity.Key,
+				Expected: integrity.Expected,
+				Actual:   integrity.Actual,
+			})
+		}
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+
+	// The object may have changed since it was checked, so decide on what was downloaded
+	entry, remoteVersion, ordering = compareRemote(idx, relPath, localPath, metadata)
+	pending := false
+
+	switch ordering {
+	case index.Equal, index.Before:
+		// Local copy already contains this version
+		entry.RemoteETag = remoteFile.ETag
+		idx.Put(entry)
+		return nil
+
+	case index.Concurrent:
+		if folder.Subscribe {
+			// The publisher's copy replaces the local change, which is never uploaded
+			log.Info().Str("file", relPath).Msg("Replacing local change with the published file")
+			break
+		}
+
+		// Both sides changed independently: the folder's conflict policy picks the copy that wins
+		details := models.ConflictDetails{
+			Policy:        conflictPolicy(folder),
+			LocalVersion:  entry.Version.String(),
+			RemoteVersion: remoteVersion.String(),
+			RemoteDevice:  metadataValue(metadata, index.MetadataDeviceID),
+		}
+		sm.mu.RLock()
+		skew := sm.clockSkew
+		sm.mu.RUnlock()
+		details.Resolution = conflictWinner(details.Policy, localPath, remoteFile.LastModified, skew)
+
+		log.Warn().
+			Str("file", relPath).
+			Str("policy", details.Policy).
+			Str("resolution", details.Resolution).
+			Str("local_version", details.LocalVersion).
+			Str("remote_version", details.RemoteVersion).
+			Msg("Concurrent modification detected")
+
+		// Whichever copy wins is published again with a version that descends from both
+		merged := entry.Version.Merge(remoteVersion).Increment(sm.deviceID)
+
+		if details.Resolution != conflictRemote {
+			if details.Resolution == conflictBoth {
+				details.ConflictCopy = conflictFileName(localPath, details.RemoteDevice, remoteFile.LastModified)
+				if err := os.Rename(tmpPath, details.ConflictCopy); err != nil {
+					return fmt.Errorf("failed to write conflict copy: %w", err)
+				}
+			}
+			sm.recordConflict(folder.ID, relPath, details)
+
+			entry.Version = merged
+			entry.RemoteETag = remoteFile.ETag
+			entry.Pending = true
+			idx.Put(entry)
+			return nil
+		}
+
+		sm.recordConflict(folder.ID, relPath, details)
+		remoteVersion = merged
+		pending = true
+	}
+
+	// Remote version descends from ours (or we have nothing): replace the local file
+	log.Info().Str("file", relPath).Msg("Downloading file")
+
+	if metadataValue(metadata, sparse.MetadataKey) == "true" {
+		restoreHoles(tmpPath)
+	}
+
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		return fmt.Errorf("failed to replace local file: %w", err)
+	}
+
+	// Set file modification time to match remote
+	if err := os.Chtimes(localPath, remoteFile.LastModified, remoteFile.LastModified); err != nil {
+		log.Warn().Err(err).Str("file", localPath).Msg("Failed to set file modification time")
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat downloaded file: %w", err)
+	}
+
+	if localRel == relPath {
+		localRel = ""
+	}
+
+	idx.Put(index.Entry{
+		Path:       relPath,
+		LocalPath:  localRel,
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+		Hash:       checksum.FromMetadata(metadata),
+		Version:    remoteVersion,
+		RemoteETag: remoteFile.ETag,
+		RemoteSize: info.Size(),
+		RemoteHash: checksum.FromMetadata(metadata),
+		Pending:    pending,
+	})
+
+	sm.stats.Downloaded(folder.ID, remoteFile.Size)
+
+	log.Debug().
+		Str("file", relPath).
+		Int64("size", remoteFile.Size).
+		Str("version", remoteVersion.String()).
+		Msg("File downloaded successfully")
+
+	return nil
+}
+
+// download writes a remote file into file, from a peer on the local network when one has
+// the same content, otherwise from the storage backend through the download pool.
+// It returns the file's metadata.
+func (sm *SyncManager) download(ctx context.Context, remoteFile storage.FileInfo, metadata map[string]string, file *os.File, transfer *progress.File) (map[string]string, error) {
+	sm.mu.RLock()
+	peers, downloader := sm.peers, sm.downloader
+	sm.mu.RUnlock()
+
+	if hash := checksum.FromMetadata(metadata); peers != nil && hash != "" {
+		n, err := peers.Fetch(ctx, remoteFile.Key, hash, io.MultiWriter(file, transfer))
+		if err == nil {
+			return metadata, nil
+		}
+
+		// Start over from the storage backend, which rewrites the file from the beginning
+		log.Debug().Err(err).Str("key", remoteFile.Key).Msg("Falling back to storage for download")
+		transfer.Add(-n)
+	}
+
+	task := download.Task{Key: remoteFile.Key, Size: remoteFile.Size, Path: file.Name(), Checksum: checksum.Of(checksum.FromMetadata(metadata))}
+	return downloader.Fetch(ctx, task, transfer)
+}
+
+// compareRemote orders the version vector in remote metadata against the local copy of a file
+func compareRemote(idx *index.Index, relPath, localPath string, metadata map[string]string) (index.Entry, index.VersionVector, index.Ordering) {
+	remoteVersion, err := index.DecodeVersionVector(metadataValue(metadata, index.MetadataVersionVector))
+	if err != nil {
+		log.Warn().Err(err).Str("file", relPath).Msg("Ignoring invalid remote version vector")
+		remoteVersion = index.VersionVector{}
+	}
+
+	entry, known := idx.Get(relPath)
+	_, statErr := os.Stat(localPath)
+	localExists := statErr == nil
+
+	ordering := index.After
+	if localExists && known {
+		ordering = remoteVersion.Compare(entry.Version)
+	}
+
+	return entry, remoteVersion, ordering
+}
+
+// handleFileEvent handles a file event from the watcher
+func (sm *SyncManager) handleFileEvent(ctx context.Context, event Event) {
+	// The watcher lost events, so any folder may have changed unseen
+	if event.Type == watcher.EventOverflow {
+		log.Warn().Msg("File watcher dropped events, walking every folder on the next sync")
+		sm.markAllDirty()
+		return
+	}
+
+	// The folder a file was moved out of finds it gone on its next walk
+	if event.Type == watcher.EventMove {
+		if from, _ := sm.watchedFolder(event.OldPath); from != nil {
+			sm.markDirty(from)
+		}
+	}
+
+	folder, localRel := sm.watchedFolder(event.Path)
+	if folder == nil {
+		log.Debug().Str("path", event.Path).Msg("File event for path not in any watched folder")
+		return
+	}
+	sm.markDirty(folder)
+
+	log.Debug().
+		Str("path", event.Path).
+		Str("from", event.OldPath).
+		Str("op", fmt.Sprintf("%v", event.Type)).
+		Msg("Got file event")
+
+	switch event.Type {
+	// A file moved is taken as created at its new path, and its old path left like a removal
+	case watcher.EventCreate, watcher.EventUpdate, watcher.EventMove:
+		info, err := os.Stat(event.Path)
+		if err != nil {
+			return
+		}
+
+		idx, err := sm.folderIndex(folder.ID)
+		if err != nil {
+			log.Error().Err(err).Str("folder", folder.ID).Msg("Failed to load folder index")
+			return
+		}
+
+		if info.IsDir() {
+			if localRel == "" {
+				return
+			}
+			entry, changed := sm.recordLocalDir(idx, index.NormalizeKey(localRel), localRel, info)
+			if !changed {
+				return
+			}
+			if !folder.Subscribe {
+				if err := sm.uploadDir(ctx, folder, idx, entry); err != nil {
+					log.Error().Err(err).Str("path", event.Path).Msg("Failed to upload directory")
+				}
+			}
+			if err := idx.Save(); err != nil {
+				log.Error().Err(err).Str("folder", folder.ID).Msg("Failed to save folder index")
+			}
+			return
+		}
+
+		if folder.outsideWindow(info.ModTime(), time.Now()) {
+			return
+		}
+		entry, changed := sm.recordLocalChange(idx, index.NormalizeKey(localRel), localRel, info)
+		if !changed {
+			return
+		}
+
+		// Files still being written are picked up by watchInUse once they settle, and the next
+		// sync of a subscribed folder reverts or flags its local changes
+		if !folder.Subscribe && sm.uploadReady(folder, entry, inuse.NewWriters(sm.openForWrite)) {
+			if err := sm.queueUpload(ctx, folder, entry); err != nil {
+				log.Error().Err(err).Str("path", event.Path).Msg("Failed to queue file for upload")
+			}
+		}
+		if err := idx.Save(); err != nil {
+			log.Error().Err(err).Str("folder", folder.ID).Msg("Failed to save folder index")
+		}
+	case watcher.EventDelete, watcher.EventRename:
+		// Currently we don't handle remote deletes
+		log.Debug().Str("path", event.Path).Msg("File removal detected, currently not propagated to remote")
+	}
+}
+
+// watchedFolder returns the folder whose file events path belongs to, with the path of the
+// file within it, or nil when no enabled, unpaused folder tracks it
+func (sm *SyncManager) watchedFolder(path string) (*FolderSync, string) {
+	if path == "" {
+		return nil, ""
+	}
+
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for _, f := range sm.folders {
+		// Backup folders are captured as a whole by the scheduled snapshot
+		if f.Mode == commonconfig.FolderModeBackup || !f.Enabled || f.Paused {
+			continue
+		}
+		if _, key, ok := config.ResolveRoot(f.roots(), path); ok && f.tracks(index.NormalizeKey(key)) {
+			return f, key
+		}
+	}
+	return nil, ""
+}
+
+// folderIndex returns the index for a folder, loading it from disk on first use
+func (sm *SyncManager) folderIndex(folderID string) (*index.Index, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if idx, ok := sm.indexes[folderID]; ok {
+		return idx, nil
+	}
+
+	idx, err := index.Load(sm.indexDir, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index for folder %s: %w", folderID, err)
+	}
+	sm.indexes[folderID] = idx
+
+	return idx, nil
+}
+
+// recordLocalChange bumps this device's counter for a file that changed on disk. A file only
+// touched, whose content is still the copy last uploaded or downloaded, keeps its version and
+// is not uploaded again, and neither is the placeholder of an archived file. A cloud
+// placeholder made available offline is uploaded with the version it was skipped at.
+func (sm *SyncManager) recordLocalChange(idx *index.Index, relPath, localRel string, info os.FileInfo) (index.Entry, bool) {
+	if entry, ok := idx.Get(relPath); ok && entry.Online && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+		if cloudfile.Dehydrated(info) {
+			return entry, false
+		}
+		entry.Online, entry.Pending = false, true
+		idx.Put(entry)
+		return entry, true
+	}
+	if entry, ok := sm.archivedContent(idx, relPath, localRel, info); ok {
+		return entry, false
+	}
+	if entry, ok := sm.unchangedContent(idx, relPath, localRel, info); ok {
+		return entry, false
+	}
+
+	entry, changed := idx.RecordLocalChange(sm.deviceID, relPath, localRel, info.Size(), info.ModTime())
+	if changed {
+		log.Debug().
+			Str("file", relPath).
+			Str("version", entry.Version.String()).
+			Msg("Local change recorded")
+	}
+	return entry, changed
+}
+
+// unchangedContent reports whether a file whose modification time changed still holds the
+// content last synced, comparing its hash with the one in the index, and records the new time
+// when it does. Only synced files of the same size are hashed, and never cloud placeholders,
+// which reading would download.
+func (sm *SyncManager) unchangedContent(idx *index.Index, relPath, localRel string, info os.FileInfo) (index.Entry, bool) {
+	entry, ok := idx.Get(relPath)
+	if !ok || entry.Pending || entry.Deleted || entry.Dir || entry.RemoteHash == "" || cloudfile.Dehydrated(info) ||
+		entry.Size != info.Size() || entry.RemoteSize != info.Size() || entry.ModTime.Equal(info.ModTime()) {
+		return index.Entry{}, false
+	}
+
+	sm.mu.RLock()
+	folder := sm.folders[idx.FolderID]
+	sm.mu.RUnlock()
+	if folder == nil {
+		return index.Entry{}, false
+	}
+	hash, err := fileHash(folder.localPath(localRel), checksum.Of(entry.RemoteHash))
+	if err != nil || hash != entry.RemoteHash {
+		return index.Entry{}, false
+	}
+
+	idx.Touch(relPath, localRel, info.ModTime())
+	log.Debug().Str("file", relPath).Msg("File touched without changing, not uploading it again")
+	entry, _ = idx.Get(relPath)
+	return entry, true
+}
+
+// queueUpload queues a file for upload along with its version vector
+func (sm *SyncManager) queueUpload(ctx context.Context, folder *FolderSync, entry index.Entry) error {
+	task := uploader.UploadTask{
+		FilePath: folder.localPath(entry.LocalRelPath()),
+		Key:      folder.key(entry.Path),
+		FolderID: folder.ID,
+		Priority: folder.Priority,
+		Checksum: folder.Checksum,
+		Metadata: map[string]string{
+			"source_folder":             folder.Path,
+			"upload_time":               time.Now().Format(time.RFC3339),
+			index.MetadataDeviceID:      sm.deviceID,
+			index.MetadataVersionVector: entry.Version.Encode(),
+		},
+	}
+	if folder.StorageClass != "" {
+		task.Metadata[storage.MetadataStorageClass] = folder.StorageClass
+	}
+	if entry.RemoteHash != "" {
+		// Lets a file that only grew, such as a log, upload just the appended bytes
+		task.Base = uploader.Base{Size: entry.RemoteSize, Hash: entry.RemoteHash}
+	} else {
+		// No upload of the file is on record, but storage may hold it already, as when the
+		// index was lost
+		task.CheckRemote = true
+	}
+
+	// The upload stays in the journal until it succeeds, so an interrupted one is checked on the next start
+	sm.beginOp(journal.Op{Kind: journal.Upload, FolderID: folder.ID, Key: task.Key, Path: task.FilePath})
+	if err := sm.uploader.QueueUploadContext(ctx, task); err != nil {
+		sm.endOp(journal.Upload, task.Key)
+		return err
+	}
+	return nil
+}
+
+// mergeSplitFile resolves two local files whose names differ only in Unicode normalization.
+// Identical copies are collapsed; otherwise the non-preferred one is kept aside as a conflict copy.
+// It returns the relative path that remains tracked under key.
+func (sm *SyncManager) mergeSplitFile(folder *FolderSync, key, first, second string) (string, error) {
+	kept, duplicate := first, second
+	if second == index.LocalForm(key) {
+		kept, duplicate = second, first
+	}
+
+	keptPath := folder.localPath(kept)
+	duplicatePath := folder.localPath(duplicate)
+
+	keptHash, err := fileHash(keptPath, folder.Checksum)
+	if err != nil {
+		return "", err
+	}
+	duplicateHash, err := fileHash(duplicatePath, folder.Checksum)
+	if err != nil {
+		return "", err
+	}
+
+	if keptHash == duplicateHash {
+		log.Info().Str("file", key).Str("removed", duplicate).Msg("Removing duplicate file that differs only in Unicode normalization")
+		if err := os.Remove(duplicatePath); err != nil {
+			return "", fmt.Errorf("failed to remove duplicate: %w", err)
+		}
+		return kept, nil
+	}
+
+	info, err := os.Stat(duplicatePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat duplicate: %w", err)
+	}
+
+	conflictPath := conflictFileName(keptPath, sm.deviceID, info.ModTime())
+	log.Warn().Str("file", key).Str("conflict_copy", conflictPath).Msg("Files differ only in Unicode normalization, keeping both")
+	if err := os.Rename(duplicatePath, conflictPath); err != nil {
+		return "", fmt.Errorf("failed to rename duplicate: %w", err)
+	}
+
+	return kept, nil
+}
+
+// mergeSplitRemote resolves remote objects stored under a non-canonical form of a key once the
+// canonical one exists. A duplicate is removed when it holds the same content or a version the
+// canonical object already descends from; a diverged one is first downloaded as a conflict copy,
+// which the next sync uploads under its own key.
+func (sm *SyncManager) mergeSplitRemote(ctx context.Context, folder *FolderSync, idx *index.Index, relPath string, canonical storage.FileInfo, duplicates []storage.FileInfo) {
+	if canonical.Key != folder.key(relPath) {
+		// No canonical copy yet: make sure ours gets uploaded, duplicates are cleaned up next time
+		if entry, ok := idx.Get(relPath); ok {
+			entry.Pending = true
+			idx.Put(entry)
+		}
+		return
+	}
+
+	_, canonicalMetadata, err := sm.storage.GetFileInfo(ctx, canonical.Key)
+	if err != nil {
+		log.Error().Err(err).Str("key", canonical.Key).Msg("Failed to get remote file info")
+		return
+	}
+
+	for _, duplicate := range duplicates {
+		same, err := sm.sameRemoteFile(ctx, canonical.Key, canonicalMetadata, duplicate.Key)
+		if err != nil {
+			log.Error().Err(err).Str("key", duplicate.Key).Msg("Failed to compare remote duplicate")
+			continue
+		}
+
+		if same {
+			log.Info().Str("file", relPath).Str("key", duplicate.Key).Msg("Removing remote duplicate that differs only in Unicode normalization")
+		} else {
+			if err := sm.keepRemoteDuplicate(ctx, folder, idx, relPath, duplicate); err != nil {
+				log.Error().Err(err).Str("key", duplicate.Key).Msg("Failed to keep diverged remote duplicate")
+				continue
+			}
+		}
+
+		sm.beginOp(journal.Op{Kind: journal.Delete, FolderID: folder.ID, Key: duplicate.Key})
+		if err := sm.deleteRemote(ctx, duplicate.Key, ""); err != nil {
+			log.Error().Err(err).Str("key", duplicate.Key).Msg("Failed to remove remote duplicate")
+		}
+		sm.endOp(journal.Delete, duplicate.Key)
+	}
+}
+
+// sameRemoteFile reports whether the object under key can be dropped in favor of the canonical
+// one: both hold the same content, or the canonical version descends from the duplicate's
+func (sm *SyncManager) sameRemoteFile(ctx context.Context, canonicalKey string, canonicalMetadata map[string]string, key string) (bool, error) {
+	_, metadata, err := sm.storage.GetFileInfo(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to get remote file info: %w", err)
+	}
+
+	canonicalVersion, err := index.DecodeVersionVector(metadataValue(canonicalMetadata, index.MetadataVersionVector))
+	if err != nil {
+		canonicalVersion = index.VersionVector{}
+	}
+	version, err := index.DecodeVersionVector(metadataValue(metadata, index.MetadataVersionVector))
+	if err == nil && len(version) > 0 && version.Compare(canonicalVersion) == index.Before {
+		return true, nil
+	}
+
+	canonicalHash, err := sm.remoteHash(ctx, canonicalKey, canonicalMetadata, "")
+	if err != nil {
+		return false, err
+	}
+	hash, err := sm.remoteHash(ctx, key, metadata, checksum.Of(canonicalHash))
+	if err != nil {
+		return false, err
+	}
+	return hash == canonicalHash, nil
+}
+
+// remoteHash returns the hash of a remote object, read from its metadata or computed by
+// downloading it. With an algorithm, a hash the metadata holds with another one is computed again.
+func (sm *SyncManager) remoteHash(ctx context.Context, key string, metadata map[string]string, alg checksum.Algorithm) (string, error) {
+	if hash := checksum.FromMetadata(metadata); hash != "" && (alg == "" || checksum.Of(hash) == alg) {
+		return hash, nil
+	}
+
+	hash := alg.New()
+	if _, err := sm.storage.DownloadFile(ctx, key, hash, ""); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return alg.Tag(hash.Sum(nil)), nil
+}
+
+// keepRemoteDuplicate downloads a diverged remote duplicate next to the local file as a conflict copy
+func (sm *SyncManager) keepRemoteDuplicate(ctx context.Context, folder *FolderSync, idx *index.Index, relPath string, duplicate storage.FileInfo) error {
+	localRel := index.LocalForm(relPath)
+	if entry, ok := idx.Get(relPath); ok {
+		localRel = entry.LocalRelPath()
+	}
+	localPath := folder.localPath(localRel)
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(localPath), ".sync-manager-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+	sm.beginOp(journal.Op{Kind: journal.Download, FolderID: folder.ID, Key: duplicate.Key, Path: tmpPath})
+	defer sm.endOp(journal.Download, duplicate.Key)
+
+	metadata, err := sm.storage.DownloadFile(ctx, duplicate.Key, tmpFile, "")
+	tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to download duplicate: %w", err)
+	}
+
+	details := models.ConflictDetails{
+		Policy:        commonconfig.ConflictKeepBoth,
+		Resolution:    conflictBoth,
+		RemoteVersion: metadataValue(metadata, index.MetadataVersionVector),
+		RemoteDevice:  metadataValue(metadata, index.MetadataDeviceID),
+	}
+	details.ConflictCopy = conflictFileName(localPath, details.RemoteDevice, duplicate.LastModified)
+	if err := os.Rename(tmpPath, details.ConflictCopy); err != nil {
+		return fmt.Errorf("failed to write conflict copy: %w", err)
+	}
+
+	log.Warn().Str("file", relPath).Str("key", duplicate.Key).Str("conflict_copy", details.ConflictCopy).Msg("Remote copies differing only in Unicode normalization diverged, keeping both")
+	sm.recordConflict(folder.ID, relPath, details)
+	return nil
+}
+
+// processUploadResults marks uploaded versions as no longer pending
+func (sm *SyncManager) processUploadResults(ctx context.Context) {
+	results := sm.uploader.Results()
+
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				return
+			}
+			sm.handleUploadResult(result)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// handleUploadResult updates stats and the folder index after an upload attempt
+func (sm *SyncManager) handleUploadResult(result uploader.UploadResult) {
+	if result.Task.FolderID == "" {
+		return
+	}
+	if !result.Success {
+		if uploader.Skipped(result.Error) {
+			sm.endOp(journal.Upload, result.Task.Key)
+			sm.skipUpload(result)
+		} else if uploader.Vanished(result.Error) {
+			sm.endOp(journal.Upload, result.Task.Key)
+			sm.vanishedUpload(result)
+		}
+		return
+	}
+	sm.endOp(journal.Upload, result.Task.Key)
+
+	if !result.Unchanged {
+		sm.stats.Uploaded(result.Task.FolderID, result.Size-result.Offset)
+	}
+
+	idx, err := sm.folderIndex(result.Task.FolderID)
+	if err != nil {
+		log.Error().Err(err).Str("folder", result.Task.FolderID).Msg("Failed to load folder index")
+		return
+	}
+
+	relPath := sm.taskPath(result.Task)
+	entry, ok := idx.Get(relPath)
+	if !ok {
+		return
+	}
+
+	// Only clear the flag if the file was not changed again while uploading
+	uploaded, err := index.DecodeVersionVector(result.Task.Metadata[index.MetadataVersionVector])
+	if err != nil || uploaded.Compare(entry.Version) != index.Equal {
+		return
+	}
+
+	entry.Pending = false
+	entry.Hash = result.Hash
+	entry.RemoteSize = result.Size
+	entry.RemoteHash = result.Hash
+	idx.Put(entry)
+
+	if err := idx.Save(); err != nil {
+		log.Error().Err(err).Str("folder", result.Task.FolderID).Msg("Failed to save folder index")
+	}
+}
+
+// periodicSync syncs each folder when its interval elapses. Folders can
+// override the global interval, so instead of a single ticker the loop
+// sleeps until the next folder is due.
+func (sm *SyncManager) periodicSync(ctx context.Context) {
+	_, wait := sm.dueFolders(time.Now())
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			due, _ := sm.dueFolders(time.Now())
+			if state := sm.GetState(); len(due) > 0 && state != SyncStatePaused && state != SyncStateOffline {
+				if err := sm.runSync(ctx, status.Periodic, due, false); err != nil {
+					log.Error().Err(err).Msg("Periodic sync failed")
+				}
+			}
+		case <-sm.reschedule:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-sm.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		_, wait = sm.dueFolders(time.Now())
+		timer.Reset(wait)
+	}
+}
+
+// dueFolders returns the enabled, unpaused folders whose interval has elapsed or whose
+// schedule fired, and how long until the next folder becomes due
+func (sm *SyncManager) dueFolders(now time.Time) ([]*FolderSync, time.Duration) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	var due []*FolderSync
+	wait := sm.syncInterval
+	for _, folder := range sm.folders {
+		if !folder.Enabled || folder.Paused {
+			continue
 		}
 
-		if watcher.ShouldExclude(relPath, folder.ExcludePatterns) {
-			return nil
+		// Failed syncs wait a full interval, or their next scheduled time, before being retried
+		next := sm.nextSyncAfter(folder, sm.lastSyncAttempt(folder))
+		if next.IsZero() {
+			continue
 		}
-
-		// Queue the file for upload
-		if err := sm.uploader.QueueFile(path, folder.Path); err != nil {
-			log.Error().Err(err).Str("path", path).Msg("Failed to queue file for upload")
-			return nil // Continue with other files
+		if !next.After(now) {
+			due = append(due, folder)
+			if next = sm.nextSyncAfter(folder, now); next.IsZero() {
+				continue
+			}
+		}
+		if until := next.Sub(now); wait <= 0 || until < wait {
+			wait = until
 		}
+	}
 
-		return nil
-	})
+	if wait <= 0 {
+		wait = time.Minute
+	}
+	return due, wait
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to walk directory: %w", err)
+// folderInterval returns the sync interval of a folder
+func (sm *SyncManager) folderInterval(folder *FolderSync) time.Duration {
+	if folder.Interval > 0 {
+		return folder.Interval
 	}
+	return sm.syncInterval
+}
 
-	// Update last sync time
-	folder.LastSync = time.Now()
+// lastSyncAttempt returns when a folder last synced or tried to, the start of the agent
+// when it has not yet. Callers hold mu.
+func (sm *SyncManager) lastSyncAttempt(folder *FolderSync) time.Time {
+	last := folder.LastSync
+	if folder.lastAttempt.After(last) {
+		last = folder.lastAttempt
+	}
+	if last.IsZero() {
+		last = sm.stats.StartedAt()
+	}
+	return last
+}
 
-	// If two-way sync is enabled, download files from remote
-	if folder.TwoWaySync {
-		if err := sm.downloadFromRemote(ctx, folder); err != nil {
-			return fmt.Errorf("failed to download from remote: %w", err)
-		}
+// nextSyncAfter returns when a folder syncs next after t, by its schedule or its interval.
+// It returns the zero time when the folder never syncs on its own. Callers hold mu.
+func (sm *SyncManager) nextSyncAfter(folder *FolderSync, t time.Time) time.Time {
+	if folder.Schedule != nil {
+		return folder.Schedule.Next(t.Local())
+	}
+	interval := sm.folderInterval(folder)
+	if interval <= 0 {
+		return time.Time{}
 	}
+	return t.Add(interval)
+}
 
-	return nil
+// triggerReschedule wakes the periodic sync loop so it picks up new intervals
+func (sm *SyncManager) triggerReschedule() {
+	select {
+	case sm.reschedule <- struct{}{}:
+	default:
+	}
 }
 
-// downloadFromRemote downloads files from remote storage for two-way sync
-func (sm *SyncManager) downloadFromRemote(ctx context.Context, folder *FolderSync) error {
-	log.Info().Str("folder", folder.Path).Msg("Downloading remote changes")
+// GetSyncStats returns the current sync stats
+func (sm *SyncManager) GetSyncStats() SyncStats {
+	snap := sm.stats.Snapshot()
+	return SyncStats{
+		FilesUploaded:   snap.Global.FilesUploaded,
+		FilesDownloaded: snap.Global.FilesDownloaded,
+		BytesUploaded:   snap.Global.BytesUploaded,
+		BytesDownloaded: snap.Global.BytesDownloaded,
+		LastSyncTime:    snap.Global.LastSync,
+		Errors:          int(snap.Global.Errors),
+		StartTime:       snap.StartedAt,
+		Version:         sm.version,
+	}
+}
 
-	// Get remote file list for this folder
-	remoteFiles, err := sm.storage.ListFiles(ctx, folder.ID)
-	if err != nil {
-		return fmt.Errorf("failed to list remote files: %w", err)
+// Stats returns the registry counting the transfers of every folder
+func (sm *SyncManager) Stats() *stats.Registry {
+	return sm.stats
+}
+
+// GetState returns the current sync state
+func (sm *SyncManager) GetState() SyncState {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	if sm.offline && sm.state != SyncStatePaused {
+		return SyncStateOffline
 	}
+	return sm.state
+}
 
-	// Create a map of local files with their modification times for quick lookup
-	localFiles := make(map[string]time.Time)
-	err = filepath.Walk(folder.Path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Continue despite errors
-		}
+// AddStateHandler registers a function called whenever a folder changes state, such as when
+// a sync starts scanning it or fails. Handlers run on their own goroutine.
+func (sm *SyncManager) AddStateHandler(handler func(folderID string, state SyncState)) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.stateHandlers = append(sm.stateHandlers, handler)
+}
 
-		if !info.IsDir() {
-			relPath, err := filepath.Rel(folder.Path, path)
-			if err != nil {
-				return nil
-			}
+// setFolderState changes the state of a folder and notifies the handlers. mu must be held.
+func (sm *SyncManager) setFolderState(folder *FolderSync, state SyncState) {
+	folder.state = state
+	for _, handler := range sm.stateHandlers {
+		go handler(folder.ID, state)
+	}
+}
 
-			// Skip excluded files
-			if watcher.ShouldExclude(relPath, folder.ExcludePatterns) {
-				return nil
-			}
+// FolderStatus returns the live state of every folder, as published for the CLI
+func (sm *SyncManager) FolderStatus() status.Snapshot {
+	snap := sm.stats.Snapshot()
+	result := status.Snapshot{UpdatedAt: snap.UpdatedAt, Folders: make(map[string]status.Folder)}
 
-			localFiles[relPath] = info.ModTime()
+	sm.mu.RLock()
+	indexes := make(map[string]*index.Index, len(sm.indexes))
+	for id, folder := range sm.folders {
+		busy := folder.state == SyncStateScanning || folder.state == SyncStateSyncing
+		state := string(folder.state)
+		switch {
+		case !folder.Enabled:
+			state = status.Disabled
+		case folder.Paused && !busy:
+			state = status.Paused
+		case state == "":
+			state = status.Idle
 		}
-		return nil
-	})
-
-	if err != nil {
-		log.Warn().Err(err).Str("folder", folder.Path).Msg("Error scanning local folder")
+		var next time.Time
+		if folder.Enabled && !folder.Paused {
+			next = sm.nextSyncAfter(folder, sm.lastSyncAttempt(folder))
+		}
+		result.Folders[id] = status.Folder{
+			State:      state,
+			LastSync:   folder.LastSync,
+			NextSync:   next,
+			LastError:  folder.lastError,
+			BytesToday: snap.Folders[id].BytesToday,
+			Collisions: folder.collisions,
+		}
+		indexes[id] = sm.indexes[id]
 	}
+	sm.mu.RUnlock()
+	result.Operation = sm.Operation()
 
-	// Download files that are newer on remote or don't exist locally
-	for _, remoteFile := range remoteFiles {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			// Process file
+	// Indexes are only counted once loaded, which the first sync of a folder does
+	for id, idx := range indexes {
+		if idx == nil {
+			continue
 		}
+		folder := result.Folders[id]
+		folder.Pending = idx.PendingFiles()
+		result.Folders[id] = folder
+	}
+	return result
+}
 
-		// Extract relative path from remote file key
-		// Key format is typically: folderID/relative/path/to/file.ext
-		remotePath := strings.TrimPrefix(remoteFile.Key, folder.ID+"/")
-		localModTime, exists := localFiles[remotePath]
-
-		// Download file if it doesn't exist locally or is newer on remote
-		if !exists || remoteFile.LastModified.After(localModTime) {
-			localPath := filepath.Join(folder.Path, remotePath)
-
-			// Ensure parent directory exists
-			if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
-				log.Error().Err(err).Str("path", localPath).Msg("Failed to create directory")
-				continue
-			}
+// SetPeers makes downloads try devices on the local network before the storage backend
+func (sm *SyncManager) SetPeers(peers PeerFetcher) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.peers = peers
+}
 
-			log.Info().Str("file", remotePath).Msg("Downloading file")
+// SetDownloader replaces the pool used for downloads, e.g. to apply configured limits
+func (sm *SyncManager) SetDownloader(downloader *download.Downloader) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.downloader = downloader
+}
 
-			// Create file for writing
-			localFile, err := os.Create(localPath)
-			if err != nil {
-				log.Error().Err(err).Str("path", localPath).Msg("Failed to create local file")
-				sm.stats.Errors++
-				continue
-			}
+// SetStaging sets the staging area temporary copies of remote files are written to
+func (sm *SyncManager) SetStaging(area *staging.Area) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.staging = area
+}
 
-			// Download the file
-			_, err = sm.storage.DownloadFile(ctx, remoteFile.Key, localFile, "")
-			localFile.Close() // Close the file regardless of error
+// SetClockSkew sets how far the local clock is ahead of the storage server's, so local
+// modification times are compared with remote ones on the same clock
+func (sm *SyncManager) SetClockSkew(skew time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.clockSkew = skew
+}
 
-			if err != nil {
-				log.Error().Err(err).Str("file", remotePath).Msg("Failed to download file")
-				sm.stats.Errors++
-				continue
-			}
+// ExcludeSource merges the excludes of a folder with rules kept outside the configuration
+type ExcludeSource func(folderID string, patterns []string) ([]string, error)
 
-			// Update stats
-			sm.mu.Lock()
-			sm.stats.FilesDownloaded++
-			sm.stats.BytesDownloaded += remoteFile.Size
-			sm.mu.Unlock()
+// SetExcludeSource sets where the extra exclude rules of every folder come from
+func (sm *SyncManager) SetExcludeSource(source ExcludeSource) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.excludes = source
+}
 
-			// Set file modification time to match remote
-			if err := os.Chtimes(localPath, remoteFile.LastModified, remoteFile.LastModified); err != nil {
-				log.Warn().Err(err).Str("file", localPath).Msg("Failed to set file modification time")
-			}
+// EventRecorder records a sync event of a folder, typically on the coordination server
+type EventRecorder func(folderID string, event models.CreateSyncEventRequest)
 
-			log.Debug().
-				Str("file", remotePath).
-				Int64("size", remoteFile.Size).
-				Time("modified", remoteFile.LastModified).
-				Msg("File downloaded successfully")
-		}
-	}
+// SetEventRecorder sets where sync events such as resolved conflicts are recorded
+func (sm *SyncManager) SetEventRecorder(recorder EventRecorder) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.events = recorder
+}
 
-	return nil
+// recordConflict records how a conflict was resolved
+func (sm *SyncManager) recordConflict(folderID, relPath string, details models.ConflictDetails) {
+	sm.stats.Conflicted(folderID)
+	sm.recordEvent(folderID, relPath, models.SyncEventConflict, details)
 }
 
-// handleFileEvent handles a file event from the watcher
-func (sm *SyncManager) handleFileEvent(ctx context.Context, event Event) {
-	// Find the folder this file belongs to
-	var folderPath string
-	for _, folder := range sm.folders {
-		if event.Path != "" && isSubPath(folder.Path, event.Path) && folder.Enabled {
-			folderPath = folder.Path
-			break
-		}
+// recordEvent records a sync event of a file with its details encoded as JSON
+func (sm *SyncManager) recordEvent(folderID, relPath, eventType string, details any) {
+	sm.mu.RLock()
+	recorder := sm.events
+	sm.mu.RUnlock()
+	if recorder == nil {
+		return
 	}
 
-	if folderPath == "" {
-		log.Debug().Str("path", event.Path).Msg("File event for path not in any watched folder")
+	data, err := json.Marshal(details)
+	if err != nil {
+		log.Error().Err(err).Str("file", relPath).Str("event", eventType).Msg("Failed to encode event details")
 		return
 	}
+	recorder(folderID, models.CreateSyncEventRequest{
+		EventType:    eventType,
+		RelativePath: relPath,
+		Timestamp:    time.Now(),
+		Details:      string(data),
+	})
+}
 
-	log.Debug().
-		Str("path", event.Path).
-		Str("op", fmt.Sprintf("%v", event.Type)).
-		Msg("Got file event")
+// excludePatterns returns the patterns a folder excludes, merged with the rules of the
+// exclude source. The folder's own patterns are used when the rules cannot be read.
+func (sm *SyncManager) excludePatterns(folder *FolderSync) []string {
+	sm.mu.RLock()
+	source := sm.excludes
+	sm.mu.RUnlock()
 
-	switch event.Type {
-	case watcher.EventCreate:
-		if err := sm.uploader.QueueFile(event.Path, folderPath); err != nil {
-			log.Error().Err(err).Str("path", event.Path).Msg("Failed to queue file for upload")
-		}
-	case watcher.EventUpdate:
-		if err := sm.uploader.QueueFile(event.Path, folderPath); err != nil {
-			log.Error().Err(err).Str("path", event.Path).Msg("Failed to queue file for upload")
-		}
-	case watcher.EventDelete, watcher.EventRename:
-		// Currently we don't handle remote deletes
-		log.Debug().Str("path", event.Path).Msg("File removal detected, currently not propagated to remote")
+	return sm.mergeExcludes(source, folder)
+}
+
+// mergeExcludes is excludePatterns with the exclude source already read, for callers holding mu
+func (sm *SyncManager) mergeExcludes(source ExcludeSource, folder *FolderSync) []string {
+	if source == nil {
+		return folder.ExcludePatterns
+	}
+	merged, err := source(folder.ID, folder.ExcludePatterns)
+	if err != nil {
+		log.Warn().Err(err).Str("folder", folder.ID).Msg("Failed to read exclude rules, using the folder excludes")
+		return folder.ExcludePatterns
 	}
+	return merged
 }
 
-// periodicSync runs the sync operation periodically
-func (sm *SyncManager) periodicSync(ctx context.Context) {
-	ticker := time.NewTicker(sm.syncInterval)
-	defer ticker.Stop()
+// SetOnline records whether the remote storage is reachable. While offline scheduled
+// syncs are skipped and local changes wait in the upload queue; when the connection
+// returns a catch-up sync picks up anything that changed in the meantime.
+func (sm *SyncManager) SetOnline(online bool) {
+	sm.mu.Lock()
+	wasOffline := sm.offline
+	sm.offline = !online
+	ctx := sm.ctx
+	sm.mu.Unlock()
 
-	for {
-		select {
-		case <-ticker.C:
+	if online && wasOffline && ctx != nil && ctx.Err() == nil {
+		log.Info().Msg("Connection restored, running catch-up sync")
+		go func() {
 			if err := sm.FullSync(ctx); err != nil {
-				log.Error().Err(err).Msg("Periodic sync failed")
+				log.Error().Err(err).Msg("Catch-up sync failed")
 			}
-		case <-sm.stopChan:
-			return
-		case <-ctx.Done():
-			return
-		}
+		}()
 	}
 }
 
-// GetSyncStats returns the current sync stats
-func (sm *SyncManager) GetSyncStats() SyncStats {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-	return sm.stats
-}
+// SetRestriction applies the transfer restriction of the power policy and bandwidth cap to
+// downloads: while transfers are paused, remote changes are left for a sync after they resume.
+// Uploads are held back by the uploader.
+func (sm *SyncManager) SetRestriction(restriction power.Restriction) {
+	sm.mu.Lock()
+	resumed := sm.paused && !restriction.Paused
+	sm.paused = restriction.Paused
+	ctx := sm.ctx
+	sm.mu.Unlock()
 
-// GetState returns the current sync state
-func (sm *SyncManager) GetState() SyncState {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-	return sm.state
+	if resumed && ctx != nil && ctx.Err() == nil {
+		go func() {
+			if err := sm.FullSync(ctx); err != nil {
+				log.Error().Err(err).Msg("Sync after transfers resumed failed")
+			}
+		}()
+	}
 }
 
 // GetFolders returns the list of folders
@@ -473,17 +2020,30 @@ func (sm *SyncManager) GetFolders() []*FolderSync {
 	return folders
 }
 
-// SyncFolder syncs a specific folder by ID
+// SyncFolderByID syncs a specific folder by ID, joining a running sync that covers it
 func (sm *SyncManager) SyncFolderByID(ctx context.Context, folderID string) error {
+	folder, err := sm.syncableFolder(folderID)
+	if err != nil {
+		return err
+	}
+	return sm.runSync(ctx, status.FolderSync, []*FolderSync{folder}, false)
+}
+
+// syncableFolder returns a folder that can be synced on demand, which paused folders cannot
+func (sm *SyncManager) syncableFolder(folderID string) (*FolderSync, error) {
 	sm.mu.RLock()
 	folder, ok := sm.folders[folderID]
 	sm.mu.RUnlock()
 
 	if !ok {
-		return fmt.Errorf("folder with ID %s not found", folderID)
+		return nil, fmt.Errorf("folder with ID %s not found", folderID)
+	}
+
+	if folder.Paused {
+		return nil, fmt.Errorf("folder %s is paused", folderID)
 	}
 
-	return sm.syncFolder(ctx, folder)
+	return folder, nil
 }
 
 // AddFolder adds a new folder to be synced
@@ -513,8 +2073,8 @@ func (sm *SyncManager) AddFolder(folder *FolderSync) error {
 
 	// Check if already exists
 	for _, f := range sm.folders {
-		if f.Path == folder.Path {
-			return fmt.Errorf("folder already added with path: %s", folder.Path)
+		if f.Path == folder.Path && f.File == folder.File {
+			return fmt.Errorf("folder already added with path: %s", filepath.Join(folder.Path, folder.File))
 		}
 	}
 
@@ -523,17 +2083,38 @@ func (sm *SyncManager) AddFolder(folder *FolderSync) error {
 
 	// Add to watcher if enabled
 	if folder.Enabled && sm.watcher != nil {
-		if err := sm.watcher.AddFolder(folder.Path, folder.ExcludePatterns); err != nil {
-			return fmt.Errorf("failed to watch folder: %w", err)
+		if err := sm.watchFolder(folder); err != nil {
+			return err
 		}
 	}
 
 	// Update config
 	syncFolder := config.SyncFolder{
-		LocalPath:       folder.Path,
-		RemotePath:      folder.ID, // Usar ID como caminho remoto por padrão
-		ExcludePatterns: folder.ExcludePatterns,
-		Enabled:         folder.Enabled,
+		LocalPath:           folder.Path,
+		RemotePath:          strings.TrimSuffix(folder.keyPrefix(), "/"),
+		ExcludePatterns:     folder.ExcludePatterns,
+		TwoWaySync:          folder.TwoWaySync,
+		Enabled:             folder.Enabled,
+		Paused:              folder.Paused,
+		Priority:            folder.Priority,
+		IntervalMinutes:     int(folder.Interval / time.Minute),
+		Mode:                folder.Mode,
+		Retention:           config.RetentionConfig(folder.Retention),
+		StorageClass:        folder.StorageClass,
+		PackSmallFiles:      folder.PackSmallFiles,
+		Roots:               folder.Roots,
+		Mirror:              folder.Mirror,
+		ConflictPolicy:      folder.ConflictPolicy,
+		InUseTimeoutSeconds: inUseTimeoutSeconds(folder.InUseTimeout),
+		File:                folder.File,
+		InitialMerge:        folder.InitialMerge,
+		Subscribe:           folder.Subscribe,
+		LocalChanges:        folder.LocalChanges,
+		ArchiveAfterDays:    int(folder.ArchiveAfter / day),
+		Schedule:            scheduleExpr(folder.Schedule),
+		AgeFilter:           folder.AgeFilter,
+		Hooks:               folder.Hooks,
+		Checksum:            string(folder.Checksum),
 	}
 
 	sm.config.SetSyncFolder(folder.ID, syncFolder)
@@ -556,14 +2137,13 @@ func (sm *SyncManager) RemoveFolder(folderID string) error {
 
 	// Remove from watcher if it's running
 	if sm.watcher != nil && folder.Enabled {
-		if err := sm.watcher.RemoveFolder(folder.Path); err != nil {
-			log.Error().Err(err).Str("path", folder.Path).Msg("Failed to remove folder from watcher")
-			// Continue anyway
-		}
+		sm.unwatchFolder(folder)
 	}
 
 	// Remove from folders map
 	delete(sm.folders, folderID)
+	delete(sm.indexes, folderID)
+	sm.stats.RemoveFolder(folderID)
 
 	// Update config
 	sm.config.RemoveSyncFolder(folderID)
@@ -594,8 +2174,8 @@ func (sm *SyncManager) EnableFolder(folderID string) error {
 
 	// Add to watcher if it's running
 	if sm.watcher != nil {
-		if err := sm.watcher.AddFolder(folder.Path, folder.ExcludePatterns); err != nil {
-			return fmt.Errorf("failed to watch folder: %w", err)
+		if err := sm.watchFolder(folder); err != nil {
+			return err
 		}
 	}
 
@@ -631,10 +2211,7 @@ func (sm *SyncManager) DisableFolder(folderID string) error {
 
 	// Remove from watcher if it's running
 	if sm.watcher != nil {
-		if err := sm.watcher.RemoveFolder(folder.Path); err != nil {
-			log.Error().Err(err).Str("path", folder.Path).Msg("Failed to remove folder from watcher")
-			// Continue anyway
-		}
+		sm.unwatchFolder(folder)
 	}
 
 	// Update config
@@ -651,6 +2228,52 @@ func (sm *SyncManager) DisableFolder(folderID string) error {
 	return nil
 }
 
+// PauseFolder stops scheduled and event-driven syncs of a folder without
+// disabling it. Changes made while paused are picked up by the next sync after resuming.
+func (sm *SyncManager) PauseFolder(folderID string) error {
+	return sm.setFolderPaused(folderID, true)
+}
+
+// ResumeFolder resumes synchronization of a paused folder
+func (sm *SyncManager) ResumeFolder(folderID string) error {
+	return sm.setFolderPaused(folderID, false)
+}
+
+// setFolderPaused updates the paused flag of a folder and persists it
+func (sm *SyncManager) setFolderPaused(folderID string, paused bool) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	folder, ok := sm.folders[folderID]
+	if !ok {
+		return fmt.Errorf("folder with ID %s not found", folderID)
+	}
+
+	if folder.Paused == paused {
+		return nil
+	}
+
+	folder.Paused = paused
+	if !paused {
+		// Events are ignored while paused
+		folder.dirty = true
+	}
+
+	// Update config
+	if f, exists := sm.config.GetSyncFolder(folderID); exists {
+		f.Paused = paused
+		sm.config.SetSyncFolder(folderID, f)
+	}
+
+	// Save config
+	if err := config.SaveConfig(sm.config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	sm.triggerReschedule()
+	return nil
+}
+
 // UpdateFolder updates a folder's settings
 func (sm *SyncManager) UpdateFolder(folderID string, update *FolderSync) error {
 	sm.mu.Lock()
@@ -663,15 +2286,31 @@ func (sm *SyncManager) UpdateFolder(folderID string, update *FolderSync) error {
 
 	// Remove from watcher if it was enabled
 	if sm.watcher != nil && folder.Enabled {
-		if err := sm.watcher.RemoveFolder(folder.Path); err != nil {
-			log.Error().Err(err).Str("path", folder.Path).Msg("Failed to remove folder from watcher")
-			// Continue anyway
-		}
+		sm.unwatchFolder(folder)
 	}
 
 	// Update folder properties
 	folder.ExcludePatterns = update.ExcludePatterns
 	folder.TwoWaySync = update.TwoWaySync
+	folder.Interval = update.Interval
+	folder.Mode = update.Mode
+	folder.Retention = update.Retention
+	folder.StorageClass = update.StorageClass
+	folder.PackSmallFiles = update.PackSmallFiles
+	folder.Roots = update.Roots
+	folder.Mirror = update.Mirror
+	folder.ConflictPolicy = update.ConflictPolicy
+	folder.InUseTimeout = update.InUseTimeout
+	folder.File = update.File
+	folder.InitialMerge = update.InitialMerge
+	folder.Subscribe = update.Subscribe
+	folder.LocalChanges = update.LocalChanges
+	folder.ArchiveAfter = update.ArchiveAfter
+	folder.RemotePath = update.RemotePath
+	folder.Schedule = update.Schedule
+	folder.AgeFilter = update.AgeFilter
+	folder.Hooks = update.Hooks
+	folder.Checksum = update.Checksum
 
 	// Only update path if it's provided and different
 	if update.Path != "" && update.Path != folder.Path {
@@ -690,8 +2329,8 @@ func (sm *SyncManager) UpdateFolder(folderID string, update *FolderSync) error {
 
 	// Add back to watcher if enabled
 	if update.Enabled && sm.watcher != nil {
-		if err := sm.watcher.AddFolder(folder.Path, folder.ExcludePatterns); err != nil {
-			return fmt.Errorf("failed to watch folder: %w", err)
+		if err := sm.watchFolder(folder); err != nil {
+			return err
 		}
 	}
 
@@ -701,7 +2340,27 @@ func (sm *SyncManager) UpdateFolder(folderID string, update *FolderSync) error {
 	if f, exists := sm.config.GetSyncFolder(folderID); exists {
 		f.LocalPath = folder.Path
 		f.ExcludePatterns = folder.ExcludePatterns
+		f.TwoWaySync = folder.TwoWaySync
 		f.Enabled = folder.Enabled
+		f.IntervalMinutes = int(folder.Interval / time.Minute)
+		f.Mode = folder.Mode
+		f.Retention = config.RetentionConfig(folder.Retention)
+		f.StorageClass = folder.StorageClass
+		f.PackSmallFiles = folder.PackSmallFiles
+		f.Roots = folder.Roots
+		f.Mirror = folder.Mirror
+		f.ConflictPolicy = folder.ConflictPolicy
+		f.InUseTimeoutSeconds = inUseTimeoutSeconds(folder.InUseTimeout)
+		f.File = folder.File
+		f.InitialMerge = folder.InitialMerge
+		f.Subscribe = folder.Subscribe
+		f.LocalChanges = folder.LocalChanges
+		f.ArchiveAfterDays = int(folder.ArchiveAfter / day)
+		f.RemotePath = strings.TrimSuffix(folder.keyPrefix(), "/")
+		f.Schedule = scheduleExpr(folder.Schedule)
+		f.AgeFilter = folder.AgeFilter
+		f.Hooks = folder.Hooks
+		f.Checksum = string(folder.Checksum)
 		sm.config.SetSyncFolder(folderID, f)
 	}
 
@@ -710,18 +2369,59 @@ func (sm *SyncManager) UpdateFolder(folderID string, update *FolderSync) error {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
+	sm.triggerReschedule()
+	return nil
+}
+
+// watchFolder adds every root of a folder to the watcher, leaving out the directories the
+// folder excludes, rules of the exclude source included. mu must be held.
+func (sm *SyncManager) watchFolder(folder *FolderSync) error {
+	// Changes made while the folder was not watched were never seen
+	folder.dirty = true
+
+	// A single-file folder watches the directory holding its file, so a file replaced by a
+	// rename, as editors and password managers save, is still seen
+	if folder.File != "" {
+		if err := sm.watcher.WatchPath(folder.Path, false, folder.ExcludePatterns); err != nil {
+			return fmt.Errorf("failed to watch folder %s: %w", folder.Path, err)
+		}
+		return nil
+	}
+
+	excluded := sm.mergeExcludes(sm.excludes, folder)
+	for _, root := range folder.roots() {
+		if err := sm.watcher.AddFolder(root.Path, excluded); err != nil {
+			return fmt.Errorf("failed to watch folder %s: %w", root.Path, err)
+		}
+	}
 	return nil
 }
 
-// SyncNow triggers an immediate synchronization of all folders or a specific folder
-func (sm *SyncManager) SyncNow(ctx context.Context, folderID string) error {
-	if folderID != "" {
-		log.Info().Str("folder_id", folderID).Msg("Syncing specific folder")
-		return sm.SyncFolderByID(ctx, folderID)
+// unwatchFolder removes every root of a folder from the watcher
+func (sm *SyncManager) unwatchFolder(folder *FolderSync) {
+	for _, root := range folder.roots() {
+		if err := sm.watcher.RemoveFolder(root.Path); err != nil {
+			log.Error().Err(err).Str("path", root.Path).Msg("Failed to remove folder from watcher")
+			// Continue anyway
+		}
+	}
+}
+
+// SyncNow triggers an immediate synchronization of all folders or a specific folder.
+// A sync already running is joined when it covers them, or cancelled and started over
+// when restart is set.
+func (sm *SyncManager) SyncNow(ctx context.Context, folderID string, restart bool) error {
+	if folderID == "" {
+		log.Info().Bool("restart", restart).Msg("Syncing all folders")
+		return sm.runSync(ctx, status.FullSync, sm.activeFolders(), restart)
 	}
 
-	log.Info().Msg("Syncing all folders")
-	return sm.FullSync(ctx)
+	folder, err := sm.syncableFolder(folderID)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("folder_id", folderID).Bool("restart", restart).Msg("Syncing specific folder")
+	return sm.runSync(ctx, status.FolderSync, []*FolderSync{folder}, restart)
 }
 
 // ReloadConfiguration reloads the configuration from the config manager
@@ -748,26 +2448,51 @@ func (sm *SyncManager) ReloadConfiguration(ctx context.Context) error {
 		if existingFolder, exists := existingFolders[id]; exists {
 			// Update existing folder if needed
 			if existingFolder.Path != folderConfig.LocalPath ||
-				existingFolder.Enabled != folderConfig.Enabled {
+				existingFolder.Enabled != folderConfig.Enabled ||
+				existingFolder.File != folderConfig.File ||
+				!sameRoots(existingFolder.Roots, folderConfig.Roots) {
+
+				// Stop watching the old roots
+				if sm.watcher != nil && existingFolder.Enabled {
+					sm.unwatchFolder(existingFolder)
+				}
 
 				// Update folder properties
 				existingFolder.Path = folderConfig.LocalPath
 				existingFolder.ExcludePatterns = folderConfig.ExcludePatterns
 				existingFolder.Enabled = folderConfig.Enabled
+				existingFolder.Roots = folderConfig.Roots
+				existingFolder.File = folderConfig.File
 
-				// Update watcher if needed
-				if sm.watcher != nil {
-					if existingFolder.Enabled {
-						// Remove old path and add new one
-						sm.watcher.RemoveFolder(existingFolder.Path)
-						sm.watcher.AddFolder(folderConfig.LocalPath, folderConfig.ExcludePatterns)
-					} else {
-						// Just remove from watcher
-						sm.watcher.RemoveFolder(existingFolder.Path)
+				// Watch the new roots
+				if sm.watcher != nil && existingFolder.Enabled {
+					if err := sm.watchFolder(existingFolder); err != nil {
+						log.Error().Err(err).Str("folder", id).Msg("Failed to watch folder")
 					}
 				}
 			}
 
+			existingFolder.Paused = folderConfig.Paused
+			existingFolder.TwoWaySync = folderConfig.TwoWaySync
+			existingFolder.Priority = folderConfig.Priority
+			existingFolder.Interval = time.Duration(folderConfig.IntervalMinutes) * time.Minute
+			existingFolder.Mode = folderConfig.Mode
+			existingFolder.Retention = snapshot.Policy(folderConfig.Retention)
+			existingFolder.StorageClass = folderConfig.StorageClass
+			existingFolder.PackSmallFiles = folderConfig.PackSmallFiles
+			existingFolder.Mirror = folderConfig.Mirror
+			existingFolder.ConflictPolicy = folderConfig.ConflictPolicy
+			existingFolder.InUseTimeout = time.Duration(folderConfig.InUseTimeoutSeconds) * time.Second
+			existingFolder.InitialMerge = folderConfig.InitialMerge
+			existingFolder.Subscribe = folderConfig.Subscribe
+			existingFolder.LocalChanges = folderConfig.LocalChanges
+			existingFolder.ArchiveAfter = time.Duration(folderConfig.ArchiveAfterDays) * day
+			existingFolder.RemotePath = folderConfig.RemotePath
+			existingFolder.Schedule = parseSchedule(id, folderConfig.Schedule)
+			existingFolder.AgeFilter = folderConfig.AgeFilter
+			existingFolder.Hooks = folderConfig.Hooks
+			existingFolder.Checksum = checksum.Algorithm(folderConfig.Checksum)
+
 			// Remove from existing folders map
 			delete(existingFolders, id)
 		} else {
@@ -777,13 +2502,34 @@ func (sm *SyncManager) ReloadConfiguration(ctx context.Context) error {
 				Path:            folderConfig.LocalPath,
 				ExcludePatterns: folderConfig.ExcludePatterns,
 				LastSync:        time.Time{}, // Never synced
-				TwoWaySync:      false,       // Default to one-way sync
+				TwoWaySync:      folderConfig.TwoWaySync,
 				Enabled:         folderConfig.Enabled,
+				Paused:          folderConfig.Paused,
+				Priority:        folderConfig.Priority,
+				Interval:        time.Duration(folderConfig.IntervalMinutes) * time.Minute,
+				Mode:            folderConfig.Mode,
+				Retention:       snapshot.Policy(folderConfig.Retention),
+				StorageClass:    folderConfig.StorageClass,
+				PackSmallFiles:  folderConfig.PackSmallFiles,
+				Roots:           folderConfig.Roots,
+				Mirror:          folderConfig.Mirror,
+				ConflictPolicy:  folderConfig.ConflictPolicy,
+				InUseTimeout:    time.Duration(folderConfig.InUseTimeoutSeconds) * time.Second,
+				File:            folderConfig.File,
+				InitialMerge:    folderConfig.InitialMerge,
+				Subscribe:       folderConfig.Subscribe,
+				LocalChanges:    folderConfig.LocalChanges,
+				ArchiveAfter:    time.Duration(folderConfig.ArchiveAfterDays) * day,
+				RemotePath:      folderConfig.RemotePath,
+				Schedule:        parseSchedule(id, folderConfig.Schedule),
+				AgeFilter:       folderConfig.AgeFilter,
+				Hooks:           folderConfig.Hooks,
+				Checksum:        checksum.Algorithm(folderConfig.Checksum),
 			}
 
 			// Add to watcher if enabled
 			if folderConfig.Enabled && sm.watcher != nil {
-				if err := sm.watcher.AddFolder(folderConfig.LocalPath, folderConfig.ExcludePatterns); err != nil {
+				if err := sm.watchFolder(sm.folders[id]); err != nil {
 					log.Error().Err(err).Str("path", folderConfig.LocalPath).Msg("Failed to watch new folder")
 				}
 			}
@@ -793,7 +2539,7 @@ func (sm *SyncManager) ReloadConfiguration(ctx context.Context) error {
 	// Any folders still in existingFolders need to be removed
 	for id, folder := range existingFolders {
 		if sm.watcher != nil && folder.Enabled {
-			sm.watcher.RemoveFolder(folder.Path)
+			sm.unwatchFolder(folder)
 		}
 		delete(sm.folders, id)
 	}
@@ -804,8 +2550,10 @@ func (sm *SyncManager) ReloadConfiguration(ctx context.Context) error {
 		sm.syncInterval = newInterval
 		log.Info().Dur("interval", sm.syncInterval).Msg("Updated sync interval")
 	}
+	sm.fullScanInterval = time.Duration(newCfg.Sync.FullScanMinutes) * time.Minute
 
 	sm.mu.Unlock()
+	sm.triggerReschedule()
 
 	log.Info().Msg("Configuration reloaded successfully")
 	return nil
@@ -835,21 +2583,26 @@ func (sm *SyncManager) ResumeSync() {
 
 // Health returns the health status of the sync manager
 func (sm *SyncManager) Health() map[string]interface{} {
+	totals := sm.stats.Snapshot().Global
+
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
 	status := map[string]interface{}{
 		"state":            string(sm.state),
-		"uptime":           time.Since(sm.stats.StartTime).String(),
+		"online":           !sm.offline,
+		"uptime":           time.Since(sm.stats.StartedAt()).String(),
 		"folders_count":    len(sm.folders),
 		"enabled_folders":  0,
-		"last_sync":        sm.stats.LastSyncTime,
-		"files_uploaded":   sm.stats.FilesUploaded,
-		"files_downloaded": sm.stats.FilesDownloaded,
-		"bytes_uploaded":   sm.stats.BytesUploaded,
-		"bytes_downloaded": sm.stats.BytesDownloaded,
-		"errors":           sm.stats.Errors,
-		"version":          sm.stats.Version,
+		"last_sync":        totals.LastSync,
+		"files_uploaded":   totals.FilesUploaded,
+		"files_downloaded": totals.FilesDownloaded,
+		"bytes_uploaded":   totals.BytesUploaded,
+		"bytes_downloaded": totals.BytesDownloaded,
+		"errors":           totals.Errors,
+		"upload_rate":      totals.Rate(stats.Windows[0]).Upload,
+		"download_rate":    totals.Rate(stats.Windows[0]).Download,
+		"version":          sm.version,
 	}
 
 	// Count enabled folders
@@ -875,15 +2628,108 @@ func generateRandomID() string {
 	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
 
-// isSubPath checks if child is a subpath of parent
-func isSubPath(parent, child string) bool {
-	parent = filepath.Clean(parent)
-	child = filepath.Clean(child)
+// Conflict resolutions, recorded in the details of conflict events
+const (
+	conflictLocal  = "local"
+	conflictRemote = "remote"
+	conflictBoth   = "both"
+)
+
+// conflictPolicy returns the conflict policy of a folder, ConflictKeepBoth when none is set.
+// The first sync of a folder uses its initial merge strategy instead, when it has one.
+func conflictPolicy(folder *FolderSync) string {
+	if folder.merging && folder.InitialMerge != "" {
+		return folder.InitialMerge
+	}
+	if folder.ConflictPolicy == "" {
+		return commonconfig.ConflictKeepBoth
+	}
+	return folder.ConflictPolicy
+}
+
+// clockTolerance is how close two modification times are for the newest copy not to be told
+// apart: the server's time is known to the second, and some filesystems keep times to two
+const clockTolerance = 2 * time.Second
+
+// conflictWinner returns which copy of a conflicting file a policy keeps. The newest copy is
+// decided on modification times, the local one moved to the server's clock by skew; both are
+// kept when the times are within clockTolerance, and the remote one when the local file is gone.
+func conflictWinner(policy, localPath string, remoteModTime time.Time, skew time.Duration) string {
+	switch policy {
+	case commonconfig.ConflictPreferLocal:
+		return conflictLocal
+	case commonconfig.ConflictPreferRemote:
+		return conflictRemote
+	case commonconfig.ConflictPreferNewest:
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return conflictRemote
+		}
+		switch newer := remoteModTime.Sub(info.ModTime().Add(-skew)); {
+		case newer > clockTolerance:
+			return conflictRemote
+		case newer < -clockTolerance:
+			return conflictLocal
+		default:
+			return conflictBoth
+		}
+	default:
+		return conflictBoth
+	}
+}
+
+// conflictFileName builds the name of the copy kept when a file was modified concurrently
+func conflictFileName(path, deviceID string, modTime time.Time) string {
+	if deviceID == "" {
+		deviceID = "unknown"
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	return fmt.Sprintf("%s (conflict from %s %s)%s", base, deviceID, modTime.UTC().Format("2006-01-02 150405"), ext)
+}
+
+// splitRemoteFiles picks the object stored under the canonical key and returns the others as duplicates
+func splitRemoteFiles(canonicalKey string, files []storage.FileInfo) (storage.FileInfo, []storage.FileInfo) {
+	for i, file := range files {
+		if file.Key == canonicalKey {
+			duplicates := append(append([]storage.FileInfo{}, files[:i]...), files[i+1:]...)
+			return file, duplicates
+		}
+	}
+	return files[0], files[1:]
+}
 
-	relative, err := filepath.Rel(parent, child)
+// fileHash returns the hash of a file's contents with alg, as the index records it
+func fileHash(path string, alg checksum.Algorithm) (string, error) {
+	hash, err := checksum.OpenFile(alg, path, priority.HashReader)
 	if err != nil {
-		return false
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hash, nil
+}
+
+// metadataValue looks up an object metadata key, ignoring case differences introduced by providers
+func metadataValue(metadata map[string]string, key string) string {
+	if value, ok := metadata[key]; ok {
+		return value
+	}
+	for k, value := range metadata {
+		if strings.EqualFold(k, key) {
+			return value
+		}
 	}
+	return ""
+}
 
-	return relative != ".." && !filepath.IsAbs(relative)
+// sameRoots reports whether two lists of extra roots are equal
+func sameRoots(a, b []config.FolderRoot) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
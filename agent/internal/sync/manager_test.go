@@ -2,12 +2,30 @@ package sync
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/martinshumberto/sync-manager/agent/internal/config"
-	"github.com/martinshumberto/sync-manager/agent/internal/storage"
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/staging"
 	"github.com/martinshumberto/sync-manager/agent/internal/uploader"
+	"github.com/martinshumberto/sync-manager/agent/internal/watcher"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/cron"
+	"github.com/martinshumberto/sync-manager/common/diskspace"
+	"github.com/martinshumberto/sync-manager/common/download"
+	"github.com/martinshumberto/sync-manager/common/guard"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/snapshot"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/storage"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,6 +52,10 @@ func (m *mockStorage) FileExists(ctx context.Context, key string) (bool, error)
 	return true, nil
 }
 
+func (m *mockStorage) GetFileInfo(ctx context.Context, key string) (storage.FileInfo, map[string]string, error) {
+	return storage.FileInfo{Key: key}, map[string]string{}, nil
+}
+
 func (m *mockStorage) GetProvider() storage.StorageProvider {
 	return storage.ProviderLocal
 }
@@ -47,12 +69,15 @@ func (m *mockUploader) Start() {}
 
 func (m *mockUploader) Stop() {}
 
-func (m *mockUploader) QueueFile(path, folderPath string) error {
+func (m *mockUploader) QueueFile(path, folderPath, remotePrefix, rootPrefix string) error {
 	return nil
 }
 
 // mockWatcher is a mock of the FileWatcher for testing
-type mockWatcher struct{}
+type mockWatcher struct {
+	watched []string // Paths added with WatchPath or AddFolder
+	removed []string // Paths removed with RemoveFolder
+}
 
 func (m *mockWatcher) Start() {}
 
@@ -61,6 +86,7 @@ func (m *mockWatcher) Stop() error {
 }
 
 func (m *mockWatcher) WatchPath(path string, recursive bool, excludePatterns []string) error {
+	m.watched = append(m.watched, path)
 	return nil
 }
 
@@ -71,10 +97,12 @@ func (m *mockWatcher) RemovePath(path string) error {
 func (m *mockWatcher) AddHandler(handler interface{}) {}
 
 func (m *mockWatcher) AddFolder(path string, excludePatterns []string) error {
+	m.watched = append(m.watched, path)
 	return nil
 }
 
 func (m *mockWatcher) RemoveFolder(path string) error {
+	m.removed = append(m.removed, path)
 	return nil
 }
 
@@ -92,7 +120,7 @@ func TestNewSyncManager(t *testing.T) {
 }
 
 func TestAddFolder(t *testing.T) {
-	cfg := config.DefaultConfig()
+	cfg, _ := config.LoadConfig(filepath.Join(t.TempDir(), "config.json"))
 	mockStorage := &mockStorage{}
 	mockUploader := &mockUploader{}
 	manager, _ := NewSyncManager(cfg, mockStorage, &mockUploader.Uploader)
@@ -115,7 +143,7 @@ func TestAddFolder(t *testing.T) {
 }
 
 func TestRemoveFolder(t *testing.T) {
-	cfg := config.DefaultConfig()
+	cfg, _ := config.LoadConfig(filepath.Join(t.TempDir(), "config.json"))
 	mockStorage := &mockStorage{}
 	mockUploader := &mockUploader{}
 
@@ -141,29 +169,1508 @@ func TestRemoveFolder(t *testing.T) {
 }
 
 func TestEnableDisableFolder(t *testing.T) {
-	cfg := config.DefaultConfig()
+	cfg, _ := config.LoadConfig(filepath.Join(t.TempDir(), "config.json"))
 	mockStorage := &mockStorage{}
 	mockUploader := &mockUploader{}
 
 	manager, _ := NewSyncManager(cfg, mockStorage, &mockUploader.Uploader)
-	manager.watcher = &mockWatcher{}
+	watcher := &mockWatcher{}
+	manager.watcher = watcher
 
 	tmpFolder := t.TempDir()
+	desktop := t.TempDir()
 	folder := &FolderSync{
 		ID:              "test-folder",
 		Path:            tmpFolder,
 		Enabled:         false, // disabled by default
 		TwoWaySync:      false,
 		ExcludePatterns: []string{"*.tmp"},
+		Roots:           []config.FolderRoot{{Path: desktop, Prefix: "Desktop"}},
 	}
 
 	_ = manager.AddFolder(folder)
+	assert.Empty(t, watcher.watched)
 
+	// Every root is watched while the folder is enabled
 	err := manager.EnableFolder("test-folder")
 	assert.NoError(t, err)
 	assert.True(t, manager.folders["test-folder"].Enabled)
+	assert.True(t, cfg.Folders["test-folder"].Enabled)
+	assert.Equal(t, []string{tmpFolder, desktop}, watcher.watched)
 
 	err = manager.DisableFolder("test-folder")
 	assert.NoError(t, err)
 	assert.False(t, manager.folders["test-folder"].Enabled)
+	assert.False(t, cfg.Folders["test-folder"].Enabled)
+	assert.Equal(t, []string{tmpFolder, desktop}, watcher.removed)
+
+	// Toggling to the current state changes nothing
+	assert.NoError(t, manager.DisableFolder("test-folder"))
+	assert.Len(t, watcher.removed, 2)
+	assert.Error(t, manager.EnableFolder("missing"))
+	assert.Error(t, manager.DisableFolder("missing"))
+}
+
+func TestFolderStateHandlers(t *testing.T) {
+	ctx := context.Background()
+	manager, err := NewSyncManager(config.DefaultConfig(), storage.NewMemoryStorage(&storage.MemoryConfig{}), &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+
+	states := make(chan string, 16)
+	manager.AddStateHandler(func(folderID string, state SyncState) {
+		states <- folderID + ":" + string(state)
+	})
+	received := func(n int) []string {
+		var got []string
+		for i := 0; i < n; i++ {
+			select {
+			case state := <-states:
+				got = append(got, state)
+			case <-time.After(time.Second):
+				t.Fatalf("only %d of %d state changes received: %v", i, n, got)
+			}
+		}
+		return got
+	}
+
+	docs := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true}
+	assert.NoError(t, manager.syncFolder(ctx, docs))
+	assert.ElementsMatch(t, []string{"docs:scanning", "docs:syncing", "docs:idle"}, received(3))
+
+	broken := &FolderSync{ID: "broken", Path: filepath.Join(t.TempDir(), "missing"), Enabled: true}
+	assert.Error(t, manager.syncFolder(ctx, broken))
+	assert.ElementsMatch(t, []string{"broken:scanning", "broken:error"}, received(2))
+}
+
+func TestExcludeSourceMergesFolderExcludes(t *testing.T) {
+	manager, err := NewSyncManager(config.DefaultConfig(), &mockStorage{}, &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	folder := &FolderSync{ID: "docs", Path: t.TempDir(), ExcludePatterns: []string{"*.tmp"}}
+
+	var asked string
+	manager.SetExcludeSource(func(folderID string, patterns []string) ([]string, error) {
+		asked = folderID
+		return append(patterns, "*.iso"), nil
+	})
+	assert.Equal(t, []string{"*.tmp", "*.iso"}, manager.excludePatterns(folder))
+	assert.Equal(t, "docs", asked)
+
+	// The folder's own excludes still apply when the rules cannot be read
+	manager.SetExcludeSource(func(string, []string) ([]string, error) { return nil, errors.New("locked") })
+	assert.Equal(t, []string{"*.tmp"}, manager.excludePatterns(folder))
+}
+
+func TestPauseResumeFolder(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	cfg, _ := config.LoadConfig(cfgPath)
+	mockUploader := &mockUploader{}
+
+	manager, _ := NewSyncManager(cfg, &mockStorage{}, &mockUploader.Uploader)
+	manager.watcher = &mockWatcher{}
+
+	folder := &FolderSync{ID: "test-folder", Path: t.TempDir(), Enabled: true}
+	_ = manager.AddFolder(folder)
+
+	assert.NoError(t, manager.PauseFolder("test-folder"))
+	assert.True(t, manager.folders["test-folder"].Paused)
+
+	saved, ok := cfg.GetSyncFolder("test-folder")
+	assert.True(t, ok)
+	assert.True(t, saved.Paused)
+
+	// Paused folders are not synced on demand
+	assert.Error(t, manager.SyncFolderByID(context.Background(), "test-folder"))
+
+	assert.NoError(t, manager.ResumeFolder("test-folder"))
+	assert.False(t, manager.folders["test-folder"].Paused)
+
+	assert.Error(t, manager.PauseFolder("missing"))
+}
+
+func TestDueFoldersUsesPerFolderInterval(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Sync.IntervalMinutes = 15
+	mockUploader := &mockUploader{}
+
+	manager, _ := NewSyncManager(cfg, &mockStorage{}, &mockUploader.Uploader)
+
+	now := time.Now()
+	manager.folders = map[string]*FolderSync{
+		"global":  {ID: "global", Enabled: true, LastSync: now.Add(-10 * time.Minute)},
+		"fast":    {ID: "fast", Enabled: true, Interval: time.Minute, LastSync: now.Add(-2 * time.Minute)},
+		"paused":  {ID: "paused", Enabled: true, Paused: true, Interval: time.Minute, LastSync: now.Add(-time.Hour)},
+		"stopped": {ID: "stopped", Enabled: false, LastSync: now.Add(-time.Hour)},
+	}
+
+	due, wait := manager.dueFolders(now)
+	assert.Len(t, due, 1)
+	assert.Equal(t, "fast", due[0].ID)
+
+	// The fast folder is next due again in a minute
+	assert.Equal(t, time.Minute, wait)
+
+	// A failed attempt postpones the folder by a full interval
+	manager.folders["fast"].lastAttempt = now
+	due, wait = manager.dueFolders(now)
+	assert.Empty(t, due)
+	assert.Equal(t, time.Minute, wait)
+
+	manager.folders["fast"].Interval = 0
+	manager.folders["fast"].lastAttempt = time.Time{}
+	due, wait = manager.dueFolders(now)
+	assert.Empty(t, due)
+	assert.Equal(t, 5*time.Minute, wait)
+}
+
+func TestDueFoldersFollowsSchedule(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Sync.IntervalMinutes = 15
+	mockUploader := &mockUploader{}
+
+	manager, _ := NewSyncManager(cfg, &mockStorage{}, &mockUploader.Uploader)
+
+	nightly, err := cron.Parse("0 2 * * *")
+	assert.NoError(t, err)
+	now := time.Date(2026, 3, 10, 2, 0, 30, 0, time.Local)
+	manager.folders = map[string]*FolderSync{
+		"nightly": {ID: "nightly", Enabled: true, Schedule: nightly, LastSync: now.Add(-24 * time.Hour)},
+	}
+
+	// The schedule fired since the last sync; the loop still wakes every global interval
+	due, wait := manager.dueFolders(now)
+	assert.Len(t, due, 1)
+	assert.Equal(t, 15*time.Minute, wait)
+
+	// Once synced, it waits for the next night, and status shows when that is
+	manager.folders["nightly"].LastSync = now
+	due, _ = manager.dueFolders(now)
+	assert.Empty(t, due)
+	assert.Equal(t, time.Date(2026, 3, 11, 2, 0, 0, 0, time.Local), manager.FolderStatus().Folders["nightly"].NextSync)
+
+	manager.folders["nightly"].Paused = true
+	assert.True(t, manager.FolderStatus().Folders["nightly"].NextSync.IsZero())
+}
+
+// blobStorage is an in-memory storage keeping only object contents
+type blobStorage struct {
+	mockStorage
+	objects map[string][]byte
+}
+
+func (m *blobStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	data, err := io.ReadAll(reader)
+	m.objects[key] = data
+	return "", err
+}
+
+func (m *blobStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	_, err := writer.Write(m.objects[key])
+	return nil, err
+}
+
+func (m *blobStorage) DeleteFile(ctx context.Context, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *blobStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	_, ok := m.objects[key]
+	return ok, nil
+}
+
+func TestSyncFolderInBackupModeCreatesSnapshots(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	remote := &blobStorage{objects: make(map[string][]byte)}
+	mockUploader := &mockUploader{}
+
+	manager, err := NewSyncManager(cfg, remote, &mockUploader.Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+
+	folder := &FolderSync{
+		ID:        "photos",
+		Path:      t.TempDir(),
+		Enabled:   true,
+		Mode:      "backup",
+		Retention: snapshot.Policy{KeepLast: 2},
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, "a.jpg"), []byte("image"), 0644))
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, manager.syncFolder(context.Background(), folder))
+	}
+	assert.False(t, folder.LastSync.IsZero())
+
+	// Files are stored as snapshots, not mirrored under the folder key
+	_, mirrored := remote.objects["photos/a.jpg"]
+	assert.False(t, mirrored)
+
+	summaries, err := snapshot.NewRepository(remote, "photos", "laptop").List(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, summaries, 2)
+}
+
+func TestNewManagerRunsBackupFolders(t *testing.T) {
+	cfg := commonconfig.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	cfg.SyncFolders = []commonconfig.SyncFolder{{
+		ID:        "photos",
+		Path:      t.TempDir(),
+		Enabled:   true,
+		Mode:      commonconfig.FolderModeBackup,
+		Retention: commonconfig.RetentionConfig{KeepLast: 1},
+	}}
+	assert.NoError(t, os.WriteFile(filepath.Join(cfg.SyncFolders[0].Path, "a.jpg"), []byte("image"), 0644))
+	remote := &blobStorage{objects: make(map[string][]byte)}
+
+	manager, err := NewManager(cfg, remote, &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	sm := manager.(*ManagerWrapper).sm
+	sm.indexDir = t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, sm.syncFolder(context.Background(), sm.folders["photos"]))
+	}
+
+	summaries, err := snapshot.NewRepository(remote, "photos", "laptop").List(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, summaries, 1)
+}
+
+// remoteObject is a file stored in versionedStorage
+type remoteObject struct {
+	data     []byte
+	metadata map[string]string
+}
+
+// versionedStorage is an in-memory storage that keeps object metadata
+type versionedStorage struct {
+	mockStorage
+	objects   map[string]remoteObject
+	downloads int
+}
+
+func (m *versionedStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	m.downloads++
+	obj := m.objects[key]
+	_, err := writer.Write(obj.data)
+	return obj.metadata, err
+}
+
+func (m *versionedStorage) DeleteFile(ctx context.Context, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *versionedStorage) GetFileInfo(ctx context.Context, key string) (storage.FileInfo, map[string]string, error) {
+	obj, ok := m.objects[key]
+	if !ok {
+		return storage.FileInfo{}, nil, storage.ErrNotFound
+	}
+	return storage.FileInfo{Key: key, Size: int64(len(obj.data)), ETag: string(obj.data)}, obj.metadata, nil
+}
+
+func (m *versionedStorage) ListFiles(ctx context.Context, prefix string) ([]storage.FileInfo, error) {
+	var files []storage.FileInfo
+	for key, obj := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			files = append(files, storage.FileInfo{
+				Key:          key,
+				Size:         int64(len(obj.data)),
+				LastModified: time.Now(),
+				ETag:         string(obj.data),
+			})
+		}
+	}
+	return files, nil
+}
+
+func newVersionedManager(t *testing.T, remote *versionedStorage) (*SyncManager, *FolderSync, *index.Index) {
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	mockUploader := &mockUploader{}
+
+	manager, err := NewSyncManager(cfg, remote, &mockUploader.Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+
+	folder := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}
+
+	idx, err := manager.folderIndex(folder.ID)
+	assert.NoError(t, err)
+
+	return manager, folder, idx
+}
+
+func recordFile(t *testing.T, manager *SyncManager, idx *index.Index, folder *FolderSync, name, content string) {
+	path := filepath.Join(folder.Path, name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	manager.recordLocalChange(idx, name, name, info)
+}
+
+func TestDownloadFromRemoteDetectsConflict(t *testing.T) {
+	remote := &versionedStorage{objects: map[string]remoteObject{
+		"docs/notes.txt": {
+			data: []byte("edited on desktop"),
+			metadata: map[string]string{
+				index.MetadataDeviceID:      "desktop",
+				index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+			},
+		},
+	}}
+	manager, folder, idx := newVersionedManager(t, remote)
+	recordFile(t, manager, idx, folder, "notes.txt", "edited on laptop")
+
+	err := manager.downloadFromRemote(context.Background(), folder, idx, "")
+	assert.NoError(t, err)
+
+	// Local content is kept and the remote copy is set aside
+	data, _ := os.ReadFile(filepath.Join(folder.Path, "notes.txt"))
+	assert.Equal(t, "edited on laptop", string(data))
+
+	matches, _ := filepath.Glob(filepath.Join(folder.Path, "notes (conflict from desktop *).txt"))
+	assert.Len(t, matches, 1)
+	if len(matches) == 1 {
+		conflict, _ := os.ReadFile(matches[0])
+		assert.Equal(t, "edited on desktop", string(conflict))
+	}
+
+	entry, _ := idx.Get("notes.txt")
+	assert.True(t, entry.Pending)
+	assert.Equal(t, index.After, entry.Version.Compare(index.VersionVector{"desktop": 1, "laptop": 1}))
+}
+
+func TestDownloadFromRemoteAppliesConflictPolicy(t *testing.T) {
+	testCases := []struct {
+		name       string
+		policy     string
+		localAge   time.Duration
+		skew       time.Duration
+		content    string
+		resolution string
+		copies     int
+	}{
+		{name: "keep both by default", content: "edited on laptop", resolution: "both", copies: 1},
+		{name: "prefer local", policy: commonconfig.ConflictPreferLocal, content: "edited on laptop", resolution: "local"},
+		{name: "prefer remote", policy: commonconfig.ConflictPreferRemote, content: "edited on desktop", resolution: "remote"},
+		{name: "prefer newest remote", policy: commonconfig.ConflictPreferNewest, localAge: time.Hour, content: "edited on desktop", resolution: "remote"},
+		{name: "prefer newest local", policy: commonconfig.ConflictPreferNewest, localAge: -time.Hour, content: "edited on laptop", resolution: "local"},
+		{name: "prefer newest within tolerance", policy: commonconfig.ConflictPreferNewest, content: "edited on laptop", resolution: "both", copies: 1},
+		// The local clock is an hour ahead, so a local copy stamped half an hour in its future is older
+		{name: "prefer newest allowing for skew", policy: commonconfig.ConflictPreferNewest, localAge: -30 * time.Minute, skew: time.Hour, content: "edited on desktop", resolution: "remote"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			remote := &versionedStorage{objects: map[string]remoteObject{
+				"docs/notes.txt": {
+					data: []byte("edited on desktop"),
+					metadata: map[string]string{
+						index.MetadataDeviceID:      "desktop",
+						index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+					},
+				},
+			}}
+			manager, folder, idx := newVersionedManager(t, remote)
+			folder.ConflictPolicy = tc.policy
+			manager.SetClockSkew(tc.skew)
+			recordFile(t, manager, idx, folder, "notes.txt", "edited on laptop")
+			modTime := time.Now().Add(-tc.localAge)
+			assert.NoError(t, os.Chtimes(filepath.Join(folder.Path, "notes.txt"), modTime, modTime))
+
+			var events []models.CreateSyncEventRequest
+			manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+				assert.Equal(t, "docs", folderID)
+				events = append(events, event)
+			})
+
+			err := manager.downloadFromRemote(context.Background(), folder, idx, "")
+			assert.NoError(t, err)
+
+			data, _ := os.ReadFile(filepath.Join(folder.Path, "notes.txt"))
+			assert.Equal(t, tc.content, string(data))
+
+			matches, _ := filepath.Glob(filepath.Join(folder.Path, "*conflict*"))
+			assert.Len(t, matches, tc.copies)
+
+			// The copy that wins is uploaded again with a version descending from both sides
+			entry, _ := idx.Get("notes.txt")
+			assert.True(t, entry.Pending)
+			assert.Equal(t, index.After, entry.Version.Compare(index.VersionVector{"desktop": 1, "laptop": 1}))
+
+			if assert.Len(t, events, 1) {
+				assert.Equal(t, models.SyncEventConflict, events[0].EventType)
+				assert.Equal(t, "notes.txt", events[0].RelativePath)
+
+				var details models.ConflictDetails
+				assert.NoError(t, json.Unmarshal([]byte(events[0].Details), &details))
+				assert.Equal(t, tc.resolution, details.Resolution)
+				assert.Equal(t, "desktop", details.RemoteDevice)
+			}
+		})
+	}
+}
+
+func TestNewManagerRecordsConflictsByFolderPolicy(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	cfg := commonconfig.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	cfg.SyncFolders = []commonconfig.SyncFolder{{
+		ID:             "docs",
+		Path:           t.TempDir(),
+		Enabled:        true,
+		TwoWaySync:     true,
+		ConflictPolicy: commonconfig.ConflictPreferRemote,
+	}}
+	localPath := filepath.Join(cfg.SyncFolders[0].Path, "notes.txt")
+	assert.NoError(t, os.WriteFile(localPath, []byte("edited on laptop"), 0644))
+
+	manager, err := NewManager(cfg, remote, &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	var events []models.CreateSyncEventRequest
+	manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+		assert.Equal(t, "docs", folderID)
+		events = append(events, event)
+	})
+	sm := manager.(*ManagerWrapper).sm
+	sm.indexDir = t.TempDir()
+	assert.Equal(t, commonconfig.ConflictPreferRemote, sm.folders["docs"].ConflictPolicy)
+
+	// The laptop records its edit, then the desktop uploads a concurrent one
+	assert.NoError(t, sm.syncFolder(ctx, sm.folders["docs"]))
+	_, err = remote.UploadFile(ctx, "docs/notes.txt", strings.NewReader("edited on desktop"), map[string]string{
+		index.MetadataDeviceID:      "desktop",
+		index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sm.syncFolder(ctx, sm.folders["docs"]))
+
+	// The folder's policy keeps the remote copy and the resolution is recorded
+	data, err := os.ReadFile(localPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "edited on desktop", string(data))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.SyncEventConflict, events[0].EventType)
+		var details models.ConflictDetails
+		assert.NoError(t, json.Unmarshal([]byte(events[0].Details), &details))
+		assert.Equal(t, commonconfig.ConflictPreferRemote, details.Policy)
+		assert.Equal(t, "remote", details.Resolution)
+		assert.Equal(t, "desktop", details.RemoteDevice)
+	}
+}
+
+func TestNewManagerAppliesInitialMerge(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for name, content := range map[string]string{"notes.txt": "from desktop", "same.txt": "same"} {
+		_, err := remote.UploadFile(ctx, "docs/"+name, strings.NewReader(content), map[string]string{
+			index.MetadataDeviceID:      "desktop",
+			index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	cfg.SyncFolders = []commonconfig.SyncFolder{{
+		ID:           "docs",
+		Path:         t.TempDir(),
+		Enabled:      true,
+		TwoWaySync:   true,
+		InitialMerge: commonconfig.ConflictPreferRemote,
+	}}
+	dir := cfg.SyncFolders[0].Path
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("from laptop"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "same.txt"), []byte("same"), 0644))
+
+	sm := newConfiguredManager(t, cfg, remote)
+	var policies []string
+	sm.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+		var details models.ConflictDetails
+		assert.NoError(t, json.Unmarshal([]byte(event.Details), &details))
+		policies = append(policies, details.Policy)
+	})
+	folder := sm.folders["docs"]
+	assert.NoError(t, sm.syncFolder(ctx, folder))
+
+	// The first sync keeps the remote copy of the file that differs, and the identical one is not a conflict
+	data, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "from desktop", string(data))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	idx, err := sm.folderIndex("docs")
+	assert.NoError(t, err)
+	same, _ := idx.Get("same.txt")
+	assert.False(t, same.Pending)
+	assert.Equal(t, []string{commonconfig.ConflictPreferRemote}, policies)
+
+	// Later conflicts follow the folder's conflict policy
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("edited on laptop"), 0644))
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "notes.txt"), future, future))
+	_, err = remote.UploadFile(ctx, "docs/notes.txt", strings.NewReader("edited on desktop"), map[string]string{
+		index.MetadataDeviceID:      "desktop",
+		index.MetadataVersionVector: index.VersionVector{"desktop": 2}.Encode(),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sm.syncFolder(ctx, folder))
+	assert.Equal(t, []string{commonconfig.ConflictPreferRemote, commonconfig.ConflictKeepBoth}, policies)
+	entries, err = os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestRecordLocalChangeSkipsTouchedFiles(t *testing.T) {
+	manager, folder, idx := newVersionedManager(t, &versionedStorage{objects: map[string]remoteObject{}})
+	manager.folders[folder.ID] = folder
+	recordFile(t, manager, idx, folder, "notes.txt", "draft")
+
+	// The file was uploaded, its hash recorded with the algorithm of the folder
+	path := filepath.Join(folder.Path, "notes.txt")
+	hash, err := fileHash(path, checksum.BLAKE3)
+	assert.NoError(t, err)
+	entry, _ := idx.Get("notes.txt")
+	entry.Pending = false
+	entry.Hash, entry.RemoteHash, entry.RemoteSize = hash, hash, entry.Size
+	idx.Put(entry)
+
+	// Touching it keeps its version and does not upload it again
+	later := time.Now().Add(time.Hour)
+	assert.NoError(t, os.Chtimes(path, later, later))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	touched, changed := manager.recordLocalChange(idx, "notes.txt", "notes.txt", info)
+	assert.False(t, changed)
+	assert.False(t, touched.Pending)
+	assert.Equal(t, entry.Version, touched.Version)
+	assert.True(t, touched.ModTime.Equal(info.ModTime()))
+
+	// Changing its content, even at the same size, does
+	assert.NoError(t, os.WriteFile(path, []byte("final"), 0644))
+	info, err = os.Stat(path)
+	assert.NoError(t, err)
+	edited, changed := manager.recordLocalChange(idx, "notes.txt", "notes.txt", info)
+	assert.True(t, changed)
+	assert.True(t, edited.Pending)
+	assert.Equal(t, uint64(2), edited.Version["laptop"])
+}
+
+func TestDownloadFromRemoteAppliesNewerVersion(t *testing.T) {
+	remote := &versionedStorage{objects: map[string]remoteObject{
+		"docs/notes.txt": {
+			data: []byte("edited on desktop"),
+			metadata: map[string]string{
+				index.MetadataDeviceID:      "desktop",
+				index.MetadataVersionVector: index.VersionVector{"laptop": 1, "desktop": 1}.Encode(),
+			},
+		},
+	}}
+	manager, folder, idx := newVersionedManager(t, remote)
+	recordFile(t, manager, idx, folder, "notes.txt", "original")
+
+	err := manager.downloadFromRemote(context.Background(), folder, idx, "")
+	assert.NoError(t, err)
+
+	data, _ := os.ReadFile(filepath.Join(folder.Path, "notes.txt"))
+	assert.Equal(t, "edited on desktop", string(data))
+
+	entry, _ := idx.Get("notes.txt")
+	assert.False(t, entry.Pending)
+	assert.Equal(t, index.VersionVector{"laptop": 1, "desktop": 1}, entry.Version)
+
+	matches, _ := filepath.Glob(filepath.Join(folder.Path, "*conflict*"))
+	assert.Empty(t, matches)
+}
+
+func TestDownloadFromRemoteSkipsOlderVersion(t *testing.T) {
+	remote := &versionedStorage{objects: map[string]remoteObject{
+		"docs/notes.txt": {
+			data: []byte("stale"),
+			metadata: map[string]string{
+				index.MetadataVersionVector: index.VersionVector{"laptop": 1}.Encode(),
+			},
+		},
+	}}
+	manager, folder, idx := newVersionedManager(t, remote)
+	recordFile(t, manager, idx, folder, "notes.txt", "first")
+	recordFile(t, manager, idx, folder, "notes.txt", "second edit")
+
+	err := manager.downloadFromRemote(context.Background(), folder, idx, "")
+	assert.NoError(t, err)
+
+	data, _ := os.ReadFile(filepath.Join(folder.Path, "notes.txt"))
+	assert.Equal(t, "second edit", string(data))
+
+	entry, _ := idx.Get("notes.txt")
+	assert.Equal(t, "stale", entry.RemoteETag)
+
+	// The version vector was read from the object's metadata without downloading it
+	assert.Equal(t, 0, remote.downloads)
+}
+
+func TestDownloadFromRemoteWithMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for key, content := range map[string]string{"docs/notes.txt": "notes", "docs/sub/todo.txt": "todo", "other/skip.txt": "skip"} {
+		_, err := remote.UploadFile(ctx, key, strings.NewReader(content), map[string]string{
+			index.MetadataDeviceID:      "desktop",
+			index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	manager, err := NewSyncManager(cfg, remote, &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+	folder := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}
+	idx, err := manager.folderIndex(folder.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, manager.downloadFromRemote(ctx, folder, idx, ""))
+
+	data, _ := os.ReadFile(filepath.Join(folder.Path, "notes.txt"))
+	assert.Equal(t, "notes", string(data))
+	data, _ = os.ReadFile(filepath.Join(folder.Path, "sub", "todo.txt"))
+	assert.Equal(t, "todo", string(data))
+	_, err = os.Stat(filepath.Join(folder.Path, "skip.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	entry, ok := idx.Get("sub/todo.txt")
+	assert.True(t, ok)
+	assert.Equal(t, index.VersionVector{"desktop": 1}, entry.Version)
+
+	// Downloads are counted for the folder and globally
+	snap := manager.Stats().Snapshot()
+	assert.Equal(t, int64(2), snap.Folders["docs"].FilesDownloaded)
+	assert.Equal(t, int64(9), snap.Folders["docs"].BytesDownloaded)
+	assert.Equal(t, int64(2), manager.GetSyncStats().FilesDownloaded)
+}
+
+func TestNewManagerBuildsVersionedEngine(t *testing.T) {
+	cfg := commonconfig.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	cfg.Download.MaxConcurrency = 7
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}}
+
+	manager, err := NewManager(cfg, storage.NewMemoryStorage(&storage.MemoryConfig{}), &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+
+	sm := manager.(*ManagerWrapper).sm
+	assert.Equal(t, "laptop", sm.deviceID)
+	assert.True(t, sm.folders["docs"].TwoWaySync)
+	assert.Equal(t, 7, sm.downloader.MaxConcurrency())
+}
+
+func TestFolderStatus(t *testing.T) {
+	ctx := context.Background()
+	manager, err := NewSyncManager(config.DefaultConfig(), storage.NewMemoryStorage(&storage.MemoryConfig{}), &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+
+	docs := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true}
+	broken := &FolderSync{ID: "broken", Path: filepath.Join(t.TempDir(), "missing"), Enabled: true}
+	paused := &FolderSync{ID: "paused", Path: t.TempDir(), Enabled: true, Paused: true}
+	manager.folders = map[string]*FolderSync{"docs": docs, "broken": broken, "paused": paused}
+	assert.NoError(t, os.WriteFile(filepath.Join(docs.Path, "notes.txt"), []byte("notes"), 0644))
+
+	assert.NoError(t, manager.syncFolder(ctx, docs))
+	assert.Error(t, manager.syncFolder(ctx, broken))
+
+	folders := manager.FolderStatus().Folders
+	assert.Equal(t, status.Idle, folders["docs"].State)
+	assert.False(t, folders["docs"].LastSync.IsZero())
+	// The upload was queued but no result came back yet
+	assert.Equal(t, 1, folders["docs"].Pending)
+	assert.Equal(t, status.Error, folders["broken"].State)
+	assert.Contains(t, folders["broken"].LastError, "failed to walk directory")
+	assert.Equal(t, status.Paused, folders["paused"].State)
+}
+
+func TestSyncFolderMergesNormalizationDuplicates(t *testing.T) {
+	remote := &versionedStorage{objects: map[string]remoteObject{}}
+	manager, folder, idx := newVersionedManager(t, remote)
+	folder.TwoWaySync = false
+
+	nfc := "café.txt"
+	nfd := "cafe\u0301.txt"
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, nfc), []byte("same"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, nfd), []byte("same"), 0644))
+
+	err := manager.syncFolder(context.Background(), folder)
+	assert.NoError(t, err)
+
+	entries, _ := os.ReadDir(folder.Path)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, []string{nfc}, idx.Paths())
+}
+
+func TestDownloadFromRemoteMergesRemoteNormalizationDuplicates(t *testing.T) {
+	nfc := "café.txt"
+	nfd := "cafe\u0301.txt"
+	object := func(content string, version index.VersionVector) remoteObject {
+		return remoteObject{data: []byte(content), metadata: map[string]string{
+			index.MetadataDeviceID:      "desktop",
+			index.MetadataVersionVector: version.Encode(),
+		}}
+	}
+
+	canonical := object("same", index.VersionVector{"desktop": 2})
+	testCases := []struct {
+		name      string
+		duplicate remoteObject
+		copies    int
+	}{
+		{"identical content", object("same", index.VersionVector{"mac": 1}), 0},
+		{"superseded version", object("old", index.VersionVector{"desktop": 1}), 0},
+		{"diverged", object("other", index.VersionVector{"desktop": 1, "mac": 1}), 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			remote := &versionedStorage{objects: map[string]remoteObject{"docs/" + nfc: canonical, "docs/" + nfd: tc.duplicate}}
+			manager, folder, idx := newVersionedManager(t, remote)
+			var events []models.CreateSyncEventRequest
+			manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) { events = append(events, event) })
+
+			assert.NoError(t, manager.downloadFromRemote(context.Background(), folder, idx, ""))
+
+			// Only the canonical object is left, and a diverged duplicate is kept locally
+			_, kept := remote.objects["docs/"+nfd]
+			assert.False(t, kept)
+			matches, _ := filepath.Glob(filepath.Join(folder.Path, "caf* (conflict from desktop *).txt"))
+			assert.Len(t, matches, tc.copies)
+			assert.Len(t, events, tc.copies)
+			if tc.copies == 1 {
+				data, _ := os.ReadFile(matches[0])
+				assert.Equal(t, "other", string(data))
+			}
+		})
+	}
+}
+
+func TestSetOnlineRunsCatchUpSync(t *testing.T) {
+	cfg := config.DefaultConfig()
+	mockUploader := &mockUploader{}
+
+	manager, _ := NewSyncManager(cfg, &mockStorage{}, &mockUploader.Uploader)
+	manager.indexDir = t.TempDir()
+	manager.ctx = context.Background()
+
+	folder := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true}
+	manager.folders = map[string]*FolderSync{"docs": folder}
+
+	manager.SetOnline(false)
+	assert.Equal(t, SyncStateOffline, manager.GetState())
+	assert.Equal(t, false, manager.Health()["online"])
+
+	// Going back online scans the folders that may have changed meanwhile
+	manager.SetOnline(true)
+	assert.Eventually(t, func() bool {
+		manager.mu.RLock()
+		defer manager.mu.RUnlock()
+		return !folder.LastSync.IsZero()
+	}, time.Second, 10*time.Millisecond)
+	assert.NotEqual(t, SyncStateOffline, manager.GetState())
+
+	// Repeated online reports do not trigger another sync
+	last := folder.LastSync
+	manager.SetOnline(true)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, last, folder.LastSync)
+
+	// Pausing takes precedence over the network state
+	manager.SetOnline(false)
+	manager.PauseSync()
+	assert.Equal(t, SyncStatePaused, manager.GetState())
+}
+
+func TestSyncFolderWithMultipleRoots(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	_, err := remote.UploadFile(ctx, "docs/Desktop/remote.txt", strings.NewReader("remote"), map[string]string{
+		index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+	})
+	assert.NoError(t, err)
+
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	manager, err := NewSyncManager(cfg, remote, &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+
+	desktop := t.TempDir()
+	folder := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true,
+		Roots: []config.FolderRoot{{Path: desktop, Prefix: "Desktop"}}}
+	idx, err := manager.folderIndex(folder.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, "notes.txt"), []byte("notes"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(desktop, "todo.txt"), []byte("todo"), 0644))
+	// A subdirectory of the main root named like a prefix is hidden by that root
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder.Path, "Desktop"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, "Desktop", "hidden.txt"), []byte("hidden"), 0644))
+
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+
+	assert.ElementsMatch(t, []string{"Desktop", "Desktop/remote.txt", "Desktop/todo.txt", "notes.txt"}, idx.Paths())
+	data, err := os.ReadFile(filepath.Join(desktop, "remote.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "remote", string(data))
+
+	entry, _ := idx.Get("Desktop/todo.txt")
+	assert.Equal(t, filepath.Join(desktop, "todo.txt"), folder.localPath(entry.LocalRelPath()))
+}
+
+func TestSyncFolderWithSingleFile(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for key, content := range map[string]string{"keys/vault.kdbx": "remote vault", "keys/other.txt": "other"} {
+		_, err := remote.UploadFile(ctx, key, strings.NewReader(content), map[string]string{
+			index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	manager, err := NewSyncManager(config.DefaultConfig(), remote, &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+
+	folder := &FolderSync{ID: "keys", Path: t.TempDir(), File: "vault.kdbx", Enabled: true, TwoWaySync: true}
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, "notes.txt"), []byte("notes"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder.Path, "sub"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, "sub", "vault.kdbx"), []byte("nested"), 0644))
+
+	// Only the folder's file is downloaded and tracked, the rest of its directory is left alone
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+	idx, err := manager.folderIndex(folder.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vault.kdbx"}, idx.Paths())
+	data, err := os.ReadFile(filepath.Join(folder.Path, "vault.kdbx"))
+	assert.NoError(t, err)
+	assert.Equal(t, "remote vault", string(data))
+	_, err = os.Stat(filepath.Join(folder.Path, "other.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Events for other files of the directory are ignored
+	manager.folders[folder.ID] = folder
+	manager.handleFileEvent(ctx, Event{Type: watcher.EventCreate, Path: filepath.Join(folder.Path, "notes.txt")})
+	assert.Equal(t, []string{"vault.kdbx"}, idx.Paths())
+}
+
+func TestSyncFolderRemovesRemoteOrphans(t *testing.T) {
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		mirror  config.MirrorConfig
+		removed bool
+		trashed bool
+	}{
+		"disabled":          {mirror: config.MirrorConfig{}},
+		"delete":            {mirror: config.MirrorConfig{DeleteOrphans: true, MaxDeletePercent: -1}, removed: true},
+		"trash":             {mirror: config.MirrorConfig{DeleteOrphans: true, Trash: true, MaxDeletePercent: -1}, removed: true, trashed: true},
+		"above max delete":  {mirror: config.MirrorConfig{DeleteOrphans: true, MaxDelete: 1, MaxDeletePercent: -1}},
+		"above percent":     {mirror: config.MirrorConfig{DeleteOrphans: true}},
+		"unlimited deletes": {mirror: config.MirrorConfig{DeleteOrphans: true, MaxDelete: -1, MaxDeletePercent: -1}, removed: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+			manager, folder := newOrphanManager(t, remote, tc.mirror)
+
+			assert.NoError(t, manager.syncFolder(ctx, folder))
+
+			if tc.removed {
+				// Excluded files are left alone
+				assert.Equal(t, []string{"docs/cache.tmp", "docs/kept.txt"}, remoteKeys(t, remote, "docs/"))
+			} else {
+				assert.Len(t, remoteKeys(t, remote, "docs/"), 4)
+			}
+
+			trashed := remoteKeys(t, remote, trashPrefix+"/docs/")
+			if tc.trashed {
+				assert.Len(t, trashed, 2)
+				assert.True(t, strings.HasSuffix(trashed[0], "/gone.txt"))
+			} else {
+				assert.Empty(t, trashed)
+			}
+		})
+	}
+}
+
+func TestTrashCopiesGoThroughStaging(t *testing.T) {
+	ctx := context.Background()
+	mirror := config.MirrorConfig{DeleteOrphans: true, Trash: true, MaxDeletePercent: -1}
+
+	// The copies are staged and discarded once uploaded
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	manager, folder := newOrphanManager(t, remote, mirror)
+	area, err := staging.New(t.TempDir(), 1<<20)
+	assert.NoError(t, err)
+	manager.SetStaging(area)
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+	assert.Len(t, remoteKeys(t, remote, trashPrefix+"/docs/"), 2)
+	assert.Equal(t, staging.Usage{MaxBytes: 1 << 20}, area.Usage())
+
+	// A staging area too small for them keeps the orphans rather than deleting them uncopied
+	remote = storage.NewMemoryStorage(&storage.MemoryConfig{})
+	manager, folder = newOrphanManager(t, remote, mirror)
+	area, err = staging.New(t.TempDir(), 1)
+	assert.NoError(t, err)
+	manager.SetStaging(area)
+	manager.syncFolder(ctx, folder)
+	assert.Len(t, remoteKeys(t, remote, "docs/"), 4)
+	assert.Empty(t, remoteKeys(t, remote, trashPrefix+"/docs/"))
+}
+
+func TestNewManagerRemovesRemoteOrphans(t *testing.T) {
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for _, key := range []string{"docs/kept.txt", "docs/gone.txt", "docs/cache.tmp"} {
+		_, err := remote.UploadFile(context.Background(), key, strings.NewReader(key), map[string]string{})
+		assert.NoError(t, err)
+	}
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{
+		ID:      "docs",
+		Path:    t.TempDir(),
+		Enabled: true,
+		Exclude: []string{"*.tmp"},
+		Mirror:  commonconfig.MirrorConfig{DeleteOrphans: true, Trash: true, MaxDeletePercent: -1},
+	}}
+	assert.NoError(t, os.WriteFile(filepath.Join(cfg.SyncFolders[0].Path, "kept.txt"), []byte("kept"), 0644))
+
+	manager := newConfiguredManager(t, cfg, remote)
+	assert.NoError(t, manager.syncFolder(context.Background(), manager.folders["docs"]))
+
+	assert.Equal(t, []string{"docs/cache.tmp", "docs/kept.txt"}, remoteKeys(t, remote, "docs/"))
+	assert.Len(t, remoteKeys(t, remote, trashPrefix+"/docs/"), 1)
+}
+
+func TestNewManagerSyncsEmptyDirectories(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	modTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	newDevice := func(deviceID string) (*SyncManager, string) {
+		cfg := commonconfig.DefaultConfig()
+		cfg.DeviceID = deviceID
+		cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}}
+		return newConfiguredManager(t, cfg, remote), cfg.SyncFolders[0].Path
+	}
+
+	laptop, laptopPath := newDevice("laptop")
+	private := filepath.Join(laptopPath, "projects", "private")
+	assert.NoError(t, os.MkdirAll(private, 0700))
+	assert.NoError(t, os.Chmod(private, 0700))
+	assert.NoError(t, os.Chtimes(private, modTime, modTime))
+
+	assert.NoError(t, laptop.syncFolder(ctx, laptop.folders["docs"]))
+	assert.ElementsMatch(t, []string{"docs/projects/" + storage.DirMarker, "docs/projects/private/" + storage.DirMarker}, remoteKeys(t, remote, "docs/"))
+
+	// The other device recreates the empty directory with its mode and modification time
+	desktop, desktopPath := newDevice("desktop")
+	assert.NoError(t, desktop.syncFolder(ctx, desktop.folders["docs"]))
+
+	info, err := os.Stat(filepath.Join(desktopPath, "projects", "private"))
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	assert.Equal(t, modTime, info.ModTime().UTC())
+}
+
+// newConfiguredManager returns the engine NewManager builds for cfg, keeping its state files in temporary directories
+func newConfiguredManager(t *testing.T, cfg *commonconfig.Config, remote storage.Storage) *SyncManager {
+	return newConfiguredManagerWithUploader(t, cfg, remote, &(&mockUploader{}).Uploader)
+}
+
+// newConfiguredManagerWithUploader is newConfiguredManager queuing uploads on up
+func newConfiguredManagerWithUploader(t *testing.T, cfg *commonconfig.Config, remote storage.Storage, up *uploader.Uploader) *SyncManager {
+	manager, err := NewManager(cfg, remote, up)
+	assert.NoError(t, err)
+
+	sm := manager.(*ManagerWrapper).sm
+	sm.indexDir = t.TempDir()
+	sm.guardPath = filepath.Join(t.TempDir(), "deletion-guard.json")
+	sm.spacePath = filepath.Join(t.TempDir(), "disk-space.json")
+	sm.journalPath = filepath.Join(t.TempDir(), "journal.log")
+	sm.watchesPath = filepath.Join(t.TempDir(), "watches.json")
+	return sm
+}
+
+func TestDeletionGuardWaitsForApproval(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	manager, folder := newOrphanManager(t, remote, config.MirrorConfig{DeleteOrphans: true})
+
+	// Removing 2 of 3 remote files is over the default percentage, so the sync is held back
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+	assert.Len(t, remoteKeys(t, remote, "docs/"), 4)
+
+	state, err := guard.Read(manager.guardPath)
+	assert.NoError(t, err)
+	block, ok := state.Blocks["docs"]
+	assert.True(t, ok)
+	assert.Equal(t, 2, block.Deletions)
+	assert.Equal(t, 3, block.Total)
+	assert.Equal(t, int64(1), manager.Stats().Snapshot().Folders["docs"].Errors)
+
+	// Once forced, the next sync removes them and clears the block
+	_, err = guard.Approve(manager.guardPath, "docs", time.Now())
+	assert.NoError(t, err)
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+	assert.Equal(t, []string{"docs/cache.tmp", "docs/kept.txt"}, remoteKeys(t, remote, "docs/"))
+
+	state, err = guard.Read(manager.guardPath)
+	assert.NoError(t, err)
+	assert.Empty(t, state.Blocks)
+}
+
+func TestNewManagerGuardsMassDeletions(t *testing.T) {
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for _, key := range []string{"docs/kept.txt", "docs/gone.txt", "docs/old/gone.txt"} {
+		_, err := remote.UploadFile(context.Background(), key, strings.NewReader(key), map[string]string{})
+		assert.NoError(t, err)
+	}
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{
+		ID:      "docs",
+		Path:    t.TempDir(),
+		Enabled: true,
+		Mirror:  commonconfig.MirrorConfig{DeleteOrphans: true},
+	}}
+	assert.NoError(t, os.WriteFile(filepath.Join(cfg.SyncFolders[0].Path, "kept.txt"), []byte("kept"), 0644))
+
+	manager := newConfiguredManager(t, cfg, remote)
+	var events []models.CreateSyncEventRequest
+	manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+		events = append(events, event)
+	})
+
+	// Removing 2 of 3 remote files is over the default percentage, so nothing is removed
+	assert.NoError(t, manager.syncFolder(context.Background(), manager.folders["docs"]))
+	assert.Len(t, remoteKeys(t, remote, "docs/"), 3)
+
+	state, err := guard.Read(manager.guardPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, state.Blocks["docs"].Deletions)
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, models.SyncEventDeletionBlocked, events[0].EventType)
+	var details models.DeletionBlockedDetails
+	assert.NoError(t, json.Unmarshal([]byte(events[0].Details), &details))
+	assert.Equal(t, models.DeletionBlockedDetails{Deletions: 2, Total: 3}, details)
+}
+
+// newOrphanManager returns a manager syncing a folder that only holds kept.txt locally,
+// while the remote also has two orphans and an excluded file
+func newOrphanManager(t *testing.T, remote *storage.MemoryStorage, mirror config.MirrorConfig) (*SyncManager, *FolderSync) {
+	for _, key := range []string{"docs/kept.txt", "docs/gone.txt", "docs/old/gone.txt", "docs/cache.tmp"} {
+		_, err := remote.UploadFile(context.Background(), key, strings.NewReader(key), map[string]string{})
+		assert.NoError(t, err)
+	}
+
+	manager, err := NewSyncManager(config.DefaultConfig(), remote, &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+	manager.guardPath = filepath.Join(t.TempDir(), "deletion-guard.json")
+
+	folder := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true, ExcludePatterns: []string{"*.tmp"}, Mirror: mirror}
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, "kept.txt"), []byte("kept"), 0644))
+
+	return manager, folder
+}
+
+// remoteKeys lists the keys stored under prefix
+func remoteKeys(t *testing.T, remote storage.Storage, prefix string) []string {
+	files, err := remote.ListFiles(context.Background(), prefix)
+	assert.NoError(t, err)
+	var keys []string
+	for _, file := range files {
+		keys = append(keys, file.Key)
+	}
+	return keys
+}
+
+func TestSyncFolderSyncsEmptyDirectories(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	modTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	newManager := func(deviceID string) (*SyncManager, *FolderSync, *index.Index) {
+		cfg := config.DefaultConfig()
+		cfg.DeviceID = deviceID
+		manager, err := NewSyncManager(cfg, remote, &(&mockUploader{}).Uploader)
+		assert.NoError(t, err)
+		manager.indexDir = t.TempDir()
+		manager.guardPath = filepath.Join(t.TempDir(), "deletion-guard.json")
+		folder := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}
+		idx, err := manager.folderIndex(folder.ID)
+		assert.NoError(t, err)
+		return manager, folder, idx
+	}
+
+	laptop, laptopFolder, laptopIdx := newManager("laptop")
+	private := filepath.Join(laptopFolder.Path, "projects", "private")
+	assert.NoError(t, os.MkdirAll(private, 0700))
+	assert.NoError(t, os.Chmod(private, 0700))
+	assert.NoError(t, os.Chtimes(private, modTime, modTime))
+
+	assert.NoError(t, laptop.syncFolder(ctx, laptopFolder))
+
+	assert.ElementsMatch(t, []string{"docs/projects/" + storage.DirMarker, "docs/projects/private/" + storage.DirMarker}, remoteKeys(t, remote, "docs/"))
+	entry, ok := laptopIdx.Get("projects/private")
+	assert.True(t, ok)
+	assert.True(t, entry.Dir)
+	assert.False(t, entry.Pending)
+	assert.Equal(t, os.FileMode(0700), entry.Mode)
+
+	// Another device recreates the structure with the same metadata
+	desktop, desktopFolder, desktopIdx := newManager("desktop")
+	assert.NoError(t, desktop.syncFolder(ctx, desktopFolder))
+
+	info, err := os.Stat(filepath.Join(desktopFolder.Path, "projects", "private"))
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	assert.Equal(t, modTime, info.ModTime().UTC())
+
+	entry, ok = desktopIdx.Get("projects/private")
+	assert.True(t, ok)
+	assert.Equal(t, index.VersionVector{"laptop": 1}, entry.Version)
+
+	// Syncing again finds nothing new on either side
+	assert.NoError(t, desktop.syncFolder(ctx, desktopFolder))
+	entry, _ = desktopIdx.Get("projects/private")
+	assert.Equal(t, index.VersionVector{"laptop": 1}, entry.Version)
+	assert.False(t, entry.Pending)
+}
+
+func TestSyncFolderRemovesOrphanDirectories(t *testing.T) {
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	manager, folder := newOrphanManager(t, remote, config.MirrorConfig{DeleteOrphans: true, MaxDeletePercent: -1})
+	assert.NoError(t, os.Mkdir(filepath.Join(folder.Path, "empty"), 0755))
+
+	assert.NoError(t, manager.syncFolder(context.Background(), folder))
+	assert.Contains(t, remoteKeys(t, remote, "docs/"), "docs/empty/"+storage.DirMarker)
+
+	assert.NoError(t, os.Remove(filepath.Join(folder.Path, "empty")))
+	assert.NoError(t, manager.syncFolder(context.Background(), folder))
+	assert.NotContains(t, remoteKeys(t, remote, "docs/"), "docs/empty/"+storage.DirMarker)
+
+	idx, err := manager.folderIndex(folder.ID)
+	assert.NoError(t, err)
+	_, ok := idx.Get("empty")
+	assert.False(t, ok)
+}
+
+// fakePeers answers every fetch with content, failing with err after writing it
+type fakePeers struct {
+	content string
+	err     error
+	keys    []string
+}
+
+func (f *fakePeers) Fetch(ctx context.Context, key, hash string, w io.Writer) (int64, error) {
+	f.keys = append(f.keys, key)
+	n, _ := io.WriteString(w, f.content)
+	return int64(n), f.err
+}
+
+func TestDownloadTriesPeersFirst(t *testing.T) {
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		peers *fakePeers
+		want  string
+	}{
+		"from peer":    {peers: &fakePeers{content: "from peer"}, want: "from peer"},
+		"falls back":   {peers: &fakePeers{content: "partial", err: errors.New("peer went away")}, want: "from storage"},
+		"without peer": {want: "from storage"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+			_, err := remote.UploadFile(ctx, "docs/notes.txt", strings.NewReader("from storage"), map[string]string{
+				index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+			})
+			assert.NoError(t, err)
+
+			manager, err := NewSyncManager(config.DefaultConfig(), remote, &(&mockUploader{}).Uploader)
+			assert.NoError(t, err)
+			manager.indexDir = t.TempDir()
+			if tc.peers != nil {
+				manager.SetPeers(tc.peers)
+			}
+
+			folder := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}
+			assert.NoError(t, manager.syncFolder(ctx, folder))
+
+			data, err := os.ReadFile(filepath.Join(folder.Path, "notes.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, string(data))
+			if tc.peers != nil {
+				assert.Equal(t, []string{"docs/notes.txt"}, tc.peers.keys)
+			}
+		})
+	}
+}
+
+func TestNewManagerRoutesDownloadsThroughPeers(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for key, content := range map[string]string{"docs/notes.txt": "from storage", "docs/todo.txt": "from storage"} {
+		_, err := remote.UploadFile(ctx, key, strings.NewReader(content), map[string]string{
+			index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}}
+	manager, err := NewManager(cfg, remote, &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	peers := &fakePeers{content: "from peer"}
+	manager.SetPeers(peers)
+
+	sm := manager.(*ManagerWrapper).sm
+	sm.indexDir = t.TempDir()
+	assert.NoError(t, sm.syncFolder(ctx, sm.folders["docs"]))
+
+	// Every download went to the peers first, which had the content
+	assert.ElementsMatch(t, []string{"docs/notes.txt", "docs/todo.txt"}, peers.keys)
+	for _, name := range []string{"notes.txt", "todo.txt"} {
+		data, err := os.ReadFile(filepath.Join(cfg.SyncFolders[0].Path, name))
+		assert.NoError(t, err)
+		assert.Equal(t, "from peer", string(data))
+	}
+}
+
+func TestSyncFolderDownloadsThroughPool(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	files := map[string]string{
+		"docs/a.txt":       "first file",
+		"docs/b.txt":       "second file",
+		"docs/large/c.bin": strings.Repeat("0123456789", 10),
+	}
+	for key, content := range files {
+		_, err := remote.UploadFile(ctx, key, strings.NewReader(content), map[string]string{
+			index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	manager, err := NewSyncManager(config.DefaultConfig(), remote, &(&mockUploader{}).Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+
+	// Small chunks so the large file is fetched in parallel ranges
+	cfg := commonconfig.DefaultConfig()
+	cfg.Download.MaxConcurrency = 3
+	cfg.Download.ChunkSize = 16
+	manager.SetDownloader(download.NewDownloader(remote, cfg))
+
+	folder := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+
+	for key, content := range files {
+		data, err := os.ReadFile(filepath.Join(folder.Path, filepath.FromSlash(strings.TrimPrefix(key, "docs/"))))
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+	assert.Equal(t, int64(3), manager.Stats().Snapshot().Global.FilesDownloaded)
+}
+
+func TestSyncFolderWaitsForDiskSpace(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	_, err := remote.UploadFile(ctx, "docs/remote.txt", strings.NewReader("from another device"), map[string]string{
+		index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+	})
+	assert.NoError(t, err)
+
+	mockUploader := &mockUploader{}
+	manager, err := NewSyncManager(config.DefaultConfig(), remote, &mockUploader.Uploader)
+	assert.NoError(t, err)
+	manager.indexDir = t.TempDir()
+	manager.spacePath = filepath.Join(t.TempDir(), "disk-space.json")
+
+	var free uint64 = diskspace.Headroom
+	manager.freeSpace = func(path string) (uint64, error) { return free, nil }
+
+	folder := &FolderSync{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}
+	assert.NoError(t, os.WriteFile(filepath.Join(folder.Path, "local.txt"), []byte("local"), 0644))
+
+	// Nothing is downloaded, but the shortage is reported once for the folder
+	err = manager.syncFolder(ctx, folder)
+	assert.ErrorIs(t, err, ErrInsufficientSpace)
+	_, err = os.Stat(filepath.Join(folder.Path, "remote.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	state, err := diskspace.Read(manager.spacePath)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(len("from another device")), state.Shortages["docs"].Needed)
+	assert.Empty(t, manager.spaceFreed())
+
+	// Local changes still go up meanwhile
+	idx, err := manager.folderIndex("docs")
+	assert.NoError(t, err)
+	entry, ok := idx.Get("local.txt")
+	assert.True(t, ok)
+	assert.True(t, entry.Pending)
+
+	// Once space is freed the folder is synced again
+	free = 1 << 30
+	assert.Equal(t, []string{"docs"}, manager.spaceFreed())
+	assert.NoError(t, manager.syncFolder(ctx, folder))
+
+	data, err := os.ReadFile(filepath.Join(folder.Path, "remote.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "from another device", string(data))
+	state, err = diskspace.Read(manager.spacePath)
+	assert.NoError(t, err)
+	assert.Empty(t, state.Shortages)
+}
+
+func TestNewManagerReportsLowDiskSpace(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	_, err := remote.UploadFile(ctx, "docs/remote.txt", strings.NewReader("from another device"), map[string]string{
+		index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+	})
+	assert.NoError(t, err)
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}}
+	manager := newConfiguredManager(t, cfg, remote)
+	manager.freeSpace = func(path string) (uint64, error) { return diskspace.Headroom, nil }
+
+	var events []models.CreateSyncEventRequest
+	manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+		events = append(events, event)
+	})
+
+	// Syncing twice while the folder waits for space reports the shortage once
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, manager.syncFolder(ctx, manager.folders["docs"]), ErrInsufficientSpace)
+	}
+	_, err = os.Stat(filepath.Join(cfg.SyncFolders[0].Path, "remote.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, models.SyncEventLowDiskSpace, events[0].EventType)
+	var details models.LowDiskSpaceDetails
+	assert.NoError(t, json.Unmarshal([]byte(events[0].Details), &details))
+	assert.Equal(t, models.LowDiskSpaceDetails{Needed: uint64(len("from another device")), Available: diskspace.Headroom}, details)
+
+	state, err := diskspace.Read(manager.spacePath)
+	assert.NoError(t, err)
+	assert.Contains(t, state.Shortages, "docs")
+}
+
+func TestNewManagerSyncsSingleFileFolders(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for key, content := range map[string]string{"keys/vault.kdbx": "remote vault", "keys/other.txt": "other"} {
+		_, err := remote.UploadFile(ctx, key, strings.NewReader(content), map[string]string{
+			index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "keys", Path: t.TempDir(), File: "vault.kdbx", Enabled: true, TwoWaySync: true}}
+	assert.NoError(t, os.WriteFile(filepath.Join(cfg.SyncFolders[0].Path, "notes.txt"), []byte("notes"), 0644))
+
+	manager := newConfiguredManager(t, cfg, remote)
+	assert.Equal(t, "vault.kdbx", manager.folders["keys"].File)
+	assert.NoError(t, manager.syncFolder(ctx, manager.folders["keys"]))
+
+	// Only the folder's file is downloaded and tracked
+	idx, err := manager.folderIndex("keys")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vault.kdbx"}, idx.Paths())
+	data, err := os.ReadFile(filepath.Join(cfg.SyncFolders[0].Path, "vault.kdbx"))
+	assert.NoError(t, err)
+	assert.Equal(t, "remote vault", string(data))
+	_, err = os.Stat(filepath.Join(cfg.SyncFolders[0].Path, "other.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSyncFolderUsesRemotePrefix(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for key, content := range map[string]string{"team-docs/shared.txt": "shared", "docs/other.txt": "not ours"} {
+		_, err := remote.UploadFile(ctx, key, strings.NewReader(content), map[string]string{
+			index.MetadataDeviceID:      "laptop",
+			index.MetadataVersionVector: index.VersionVector{"laptop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true, RemotePrefix: "team-docs"}}
+	assert.NoError(t, os.WriteFile(filepath.Join(cfg.SyncFolders[0].Path, "notes.txt"), []byte("notes"), 0644))
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(cfg.SyncFolders[0].Path, "notes.txt"), past, past))
+
+	up := uploader.NewUploader(remote, cfg)
+	up.Start()
+	defer up.Stop()
+	manager := newConfiguredManagerWithUploader(t, cfg, remote, up)
+	assert.NoError(t, manager.syncFolder(ctx, manager.folders["docs"]))
+	select {
+	case result := <-up.Results():
+		manager.handleUploadResult(result)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the upload")
+	}
+
+	// Files go both ways under the prefix only; keys under the folder ID belong to no folder
+	assert.Equal(t, []string{"team-docs/notes.txt", "team-docs/shared.txt"}, remoteKeys(t, remote, "team-docs/"))
+	assertFile(t, filepath.Join(cfg.SyncFolders[0].Path, "shared.txt"), "shared")
+	_, err := os.Stat(filepath.Join(cfg.SyncFolders[0].Path, "other.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	idx, err := manager.folderIndex("docs")
+	assert.NoError(t, err)
+	entry, _ := idx.Get("notes.txt")
+	assert.False(t, entry.Pending)
+}
+
+func TestSyncSkipsFoldersOnUnpluggedDrives(t *testing.T) {
+	ctx := context.Background()
+	drive, err := storage.NewLocalStorage(&storage.LocalConfig{RootDir: filepath.Join(t.TempDir(), "usb"), Removable: true})
+	assert.NoError(t, err)
+	remote := storage.NewRouter(map[string]storage.Storage{
+		"default": storage.NewMemoryStorage(&storage.MemoryConfig{}),
+		"usb":     drive,
+	}, "default", map[string]string{"photos": "usb"})
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{
+		{ID: "docs", Path: t.TempDir(), Enabled: true},
+		{ID: "photos", Path: t.TempDir(), Enabled: true, Target: "usb"},
+	}
+	manager := newConfiguredManager(t, cfg, remote)
+	var runs []models.SyncRun
+	manager.SetRunRecorder(func(run models.SyncRun) error {
+		runs = append(runs, run)
+		return nil
+	})
+
+	// The folder on the drive neither syncs nor fails while it is away; the others sync
+	assert.NoError(t, manager.syncFolders(ctx, status.FullSync, []*FolderSync{manager.folders["docs"], manager.folders["photos"]}))
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, "docs", runs[0].FolderID)
+	}
+	assert.NotEqual(t, SyncStateError, manager.folders["photos"].state)
+	assert.True(t, manager.folders["photos"].lastAttempt.IsZero())
 }
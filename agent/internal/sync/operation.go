@@ -0,0 +1,133 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/rs/zerolog/log"
+)
+
+// operation is the sync the manager is running. Only one runs at a time, so full syncs,
+// periodic syncs and syncs on request never walk the same folders at once.
+type operation struct {
+	info   status.Operation
+	cancel context.CancelFunc
+	done   chan struct{} // Closed once the sync ends, after err is set
+	err    error
+}
+
+// covers reports whether the operation syncs every one of folders, or the subtree at path
+// of the one folder given when path is set. A path sync covers nothing but its subtree.
+func (op *operation) covers(folders []*FolderSync, path string) bool {
+	if op.info.Path != "" {
+		return path != "" && len(folders) == 1 && op.info.Folders[0] == folders[0].ID && inScope(path, op.info.Path)
+	}
+	for _, folder := range folders {
+		found := false
+		for _, id := range op.info.Folders {
+			if id == folder.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// runSync syncs folders as an operation of the given kind. A sync already running that covers
+// the folders is joined and its result returned; any other is waited for before starting,
+// or cancelled first when restart is set.
+func (sm *SyncManager) runSync(ctx context.Context, kind string, folders []*FolderSync, restart bool) error {
+	return sm.runScoped(ctx, kind, folders, "", restart)
+}
+
+// runScoped is runSync limited to the subtree at path of its one folder when path is set
+func (sm *SyncManager) runScoped(ctx context.Context, kind string, folders []*FolderSync, path string, restart bool) error {
+	for {
+		sm.mu.Lock()
+		running := sm.operation
+		if running == nil {
+			op, opCtx := sm.startOperation(ctx, kind, folders, path)
+			sm.mu.Unlock()
+			return sm.finishOperation(opCtx, op, folders)
+		}
+		// A periodic sync may skip walking folders, so it does not stand in for one on demand
+		joined := !restart && running.covers(folders, path) && (kind == status.Periodic || running.info.Kind != status.Periodic)
+		if restart {
+			log.Info().Str("kind", running.info.Kind).Msg("Cancelling the running sync to restart it")
+			running.cancel()
+		} else if !joined {
+			log.Info().Str("kind", running.info.Kind).Msg("Waiting for the running sync to finish")
+		}
+		sm.mu.Unlock()
+
+		select {
+		case <-running.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		// A sync cancelled by a restart did not cover the folders after all, so try again
+		if joined && !errors.Is(running.err, context.Canceled) {
+			return running.err
+		}
+		restart = false
+	}
+}
+
+// startOperation records a new running operation and returns the context cancelled to stop it.
+// The caller holds sm.mu.
+func (sm *SyncManager) startOperation(ctx context.Context, kind string, folders []*FolderSync, path string) (*operation, context.Context) {
+	ids := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		ids = append(ids, folder.ID)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	op := &operation{
+		info:   status.Operation{Kind: kind, Folders: ids, Path: path, StartedAt: time.Now()},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	sm.operation = op
+	return op, ctx
+}
+
+// finishOperation syncs the folders of op and clears it once they are done
+func (sm *SyncManager) finishOperation(ctx context.Context, op *operation, folders []*FolderSync) error {
+	err := sm.syncScoped(ctx, op.info.Kind, folders, op.info.Path)
+	op.cancel()
+
+	sm.mu.Lock()
+	op.err = err
+	sm.operation = nil
+	sm.mu.Unlock()
+	close(op.done)
+	return err
+}
+
+// operationProgress records which folder the running operation is syncing and how many are done
+func (sm *SyncManager) operationProgress(current string, done int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.operation != nil {
+		sm.operation.info.Current, sm.operation.info.Done = current, done
+	}
+}
+
+// Operation returns the sync that is running, or nil when none is
+func (sm *SyncManager) Operation() *status.Operation {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	if sm.operation == nil {
+		return nil
+	}
+	info := sm.operation.info
+	info.Folders = append([]string(nil), info.Folders...)
+	return &info
+}
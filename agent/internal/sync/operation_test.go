@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// gatedStorage is a memory storage whose listings wait for the gate to open, so a test can
+// act while a sync is running
+type gatedStorage struct {
+	*storage.MemoryStorage
+	gate     chan struct{}
+	listings atomic.Int32
+}
+
+func (s *gatedStorage) ListFiles(ctx context.Context, prefix string) ([]storage.FileInfo, error) {
+	s.listings.Add(1)
+	select {
+	case <-s.gate:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return s.MemoryStorage.ListFiles(ctx, prefix)
+}
+
+// newGatedManager returns the configured engine for one two-way folder on a gated storage
+func newGatedManager(t *testing.T) (*SyncManager, *gatedStorage) {
+	remote := &gatedStorage{MemoryStorage: storage.NewMemoryStorage(&storage.MemoryConfig{}), gate: make(chan struct{})}
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}}
+	return newConfiguredManager(t, cfg, remote), remote
+}
+
+// runAsync runs sync in the background, returning where its result arrives
+func runAsync(sync func() error) <-chan error {
+	result := make(chan error, 1)
+	go func() { result <- sync() }()
+	return result
+}
+
+func TestSyncNowJoinsRunningSync(t *testing.T) {
+	ctx := context.Background()
+	manager, remote := newGatedManager(t)
+
+	first := runAsync(func() error { return manager.SyncNow(ctx, "", false) })
+	assert.Eventually(t, func() bool { return remote.listings.Load() > 0 }, time.Second, 5*time.Millisecond)
+
+	// The running sync is published with its progress
+	op := manager.FolderStatus().Operation
+	if assert.NotNil(t, op) {
+		assert.Equal(t, status.FullSync, op.Kind)
+		assert.Equal(t, []string{"docs"}, op.Folders)
+		assert.Equal(t, "docs", op.Current)
+		assert.Equal(t, 0, op.Done)
+	}
+
+	// A request it covers joins it instead of walking the folder again
+	second := runAsync(func() error { return manager.SyncNow(ctx, "docs", false) })
+	time.Sleep(20 * time.Millisecond)
+	listings := remote.listings.Load()
+
+	close(remote.gate)
+	assert.NoError(t, <-first)
+	assert.NoError(t, <-second)
+	assert.Nil(t, manager.Operation())
+	assert.Equal(t, listings, remote.listings.Load())
+
+	// Once it ended the next request runs a sync of its own
+	assert.NoError(t, manager.SyncNow(ctx, "docs", false))
+	assert.Greater(t, remote.listings.Load(), listings)
+}
+
+func TestSyncNowRestartsRunningSync(t *testing.T) {
+	ctx := context.Background()
+	manager, remote := newGatedManager(t)
+
+	first := runAsync(func() error { return manager.SyncNow(ctx, "", false) })
+	assert.Eventually(t, func() bool { return remote.listings.Load() > 0 }, time.Second, 5*time.Millisecond)
+
+	// Restarting cancels the running sync, which is not reported as a failure
+	restarted := runAsync(func() error { return manager.SyncNow(ctx, "docs", true) })
+	assert.ErrorIs(t, <-first, context.Canceled)
+	assert.Eventually(t, func() bool {
+		op := manager.Operation()
+		return op != nil && op.Kind == status.FolderSync
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, manager.FolderStatus().Folders["docs"].LastError)
+
+	close(remote.gate)
+	assert.NoError(t, <-restarted)
+	assert.Nil(t, manager.Operation())
+	assert.Equal(t, status.Idle, manager.FolderStatus().Folders["docs"].State)
+}
+
+func TestPathSyncCoversOnlyItsSubtree(t *testing.T) {
+	ctx := context.Background()
+	manager, remote := newGatedManager(t)
+
+	first := runAsync(func() error { return manager.SyncPath(ctx, "docs", "photos", false) })
+	assert.Eventually(t, func() bool { return remote.listings.Load() > 0 }, time.Second, 5*time.Millisecond)
+	op := manager.Operation()
+	if assert.NotNil(t, op) {
+		assert.Equal(t, status.PathSync, op.Kind)
+		assert.Equal(t, "photos", op.Path)
+	}
+
+	// A subtree of it joins it, while the whole folder waits for it to end
+	nested := runAsync(func() error { return manager.SyncPath(ctx, "docs", "photos/2024", false) })
+	whole := runAsync(func() error { return manager.SyncNow(ctx, "docs", false) })
+	time.Sleep(20 * time.Millisecond)
+	listings := remote.listings.Load()
+
+	close(remote.gate)
+	assert.NoError(t, <-first)
+	assert.NoError(t, <-nested)
+	assert.NoError(t, <-whole)
+	assert.Greater(t, remote.listings.Load(), listings)
+}
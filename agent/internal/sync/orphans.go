@@ -0,0 +1,202 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/config"
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/journal"
+	"github.com/martinshumberto/sync-manager/agent/internal/watcher"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/guard"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// trashPrefix is the storage prefix under which removed orphans are kept when a folder trashes them
+const trashPrefix = ".trash"
+
+// pruneOrphans removes the remote files of a mirror folder, or of its subtree at scope when
+// set, that no longer exist locally. local holds the canonical keys found by the scan. When the orphans go over the folder's
+// limits nothing is removed, since that usually means the local folder is missing or was
+// emptied by mistake; the block is recorded for the CLI until the user forces it.
+func (sm *SyncManager) pruneOrphans(ctx context.Context, folder *FolderSync, idx *index.Index, local map[string]string, scope string) error {
+	remoteFiles, err := sm.storage.ListFiles(ctx, folder.scopePrefix(scope))
+	if err != nil {
+		return fmt.Errorf("failed to list remote files: %w", err)
+	}
+
+	excluded := sm.excludePatterns(folder)
+	var orphans []storage.FileInfo
+	total := 0
+	for _, remoteFile := range remoteFiles {
+		relPath := index.NormalizeKey(strings.TrimPrefix(remoteFile.Key, folder.keyPrefix()))
+		if relPath == "" || !folder.tracks(relPath) || watcher.ShouldExclude(relPath, excluded) {
+			continue
+		}
+		if dir, ok := storage.MarkerDir(relPath); ok {
+			relPath = dir
+		}
+		total++
+		if _, ok := local[relPath]; !ok {
+			orphans = append(orphans, remoteFile)
+		}
+	}
+	if len(orphans) == 0 {
+		// A block held for the rest of the folder stays until a sync of all of it
+		if scope != "" {
+			return nil
+		}
+		return sm.clearDeletionBlock(folder.ID)
+	}
+
+	if deletionLimits(folder.Mirror).Exceeded(len(orphans), total) {
+		approved, err := sm.deletionsApproved(folder.ID, len(orphans))
+		if err != nil {
+			return err
+		}
+		if !approved {
+			return sm.blockDeletions(folder.ID, len(orphans), total)
+		}
+		log.Warn().Str("folder", folder.ID).Int("orphans", len(orphans)).Msg("Removing remote files over the deletion limits as forced by the user")
+	}
+
+	trash := folderTrash(folder)
+	removed := 0
+	for _, orphan := range orphans {
+		relPath := strings.TrimPrefix(orphan.Key, folder.keyPrefix())
+		if err := sm.removeOrphan(ctx, folder.ID, orphan.Key, trash, relPath); err != nil {
+			log.Error().Err(err).Str("key", orphan.Key).Msg("Failed to remove remote orphan")
+			sm.stats.Failed(folder.ID)
+			continue
+		}
+		if dir, ok := storage.MarkerDir(relPath); ok {
+			relPath = dir
+		}
+		idx.Remove(index.NormalizeKey(relPath))
+		sm.stats.Deleted(folder.ID)
+		removed++
+	}
+
+	log.Info().
+		Str("folder", folder.ID).
+		Int("removed", removed).
+		Bool("trashed", trash != "").
+		Msg("Removed remote files deleted locally")
+
+	if scope != "" {
+		return nil
+	}
+	return sm.clearDeletionBlock(folder.ID)
+}
+
+// folderTrash returns the prefix the files a folder removes now are copied under, or "" when
+// the folder does not trash them
+func folderTrash(folder *FolderSync) string {
+	if !folder.Mirror.Trash {
+		return ""
+	}
+	return path.Join(trashPrefix, folder.ID, time.Now().UTC().Format("20060102-150405"))
+}
+
+// deletionLimits resolves the orphan removal limits of a folder
+func deletionLimits(mirror config.MirrorConfig) guard.Limits {
+	limits := guard.Limits{MaxFiles: mirror.MaxDelete, MaxPercent: mirror.MaxDeletePercent}
+	if limits.MaxFiles == 0 {
+		limits.MaxFiles = commonconfig.DefaultMaxDelete
+	}
+	if limits.MaxPercent == 0 {
+		limits.MaxPercent = commonconfig.DefaultMaxDeletePercent
+	}
+	return limits
+}
+
+// deletionsApproved reports whether the user forced the withheld deletions of a folder.
+// The approval only covers as many deletions as were reported to the user.
+func (sm *SyncManager) deletionsApproved(folderID string, deletions int) (bool, error) {
+	state, err := guard.Read(sm.guardPath)
+	if err != nil {
+		return false, err
+	}
+	block, ok := state.Blocks[folderID]
+	return ok && block.Approved() && deletions <= block.Deletions, nil
+}
+
+// blockDeletions records withheld deletions for the CLI and returns the error reported for the sync
+func (sm *SyncManager) blockDeletions(folderID string, deletions, total int) error {
+	block := guard.Block{FolderID: folderID, Deletions: deletions, Total: total, DetectedAt: time.Now()}
+	if err := guard.Record(sm.guardPath, block); err != nil {
+		log.Error().Err(err).Str("folder", folderID).Msg("Failed to record withheld deletions")
+	}
+
+	log.Error().
+		Str("folder", folderID).
+		Int("deletions", deletions).
+		Int("remote_files", total).
+		Int("percent", block.Percent()).
+		Msg("DELETION GUARD: refusing to remove remote files; check the folder and run 'sync-manager sync --force' to allow")
+	sm.recordEvent(folderID, "", models.SyncEventDeletionBlocked, models.DeletionBlockedDetails{Deletions: deletions, Total: total})
+
+	return fmt.Errorf("deletion guard: refusing to remove %d of %d remote files", deletions, total)
+}
+
+// clearDeletionBlock forgets the withheld deletions of a folder once they no longer apply
+func (sm *SyncManager) clearDeletionBlock(folderID string) error {
+	if err := guard.Clear(sm.guardPath, folderID); err != nil {
+		return fmt.Errorf("failed to clear deletion guard: %w", err)
+	}
+	return nil
+}
+
+// removeOrphan deletes a remote file of a folder, first copying it under trash when trash is set
+func (sm *SyncManager) removeOrphan(ctx context.Context, folderID, key, trash, relPath string) error {
+	trashKey := ""
+	if trash != "" {
+		trashKey = path.Join(trash, relPath)
+	}
+
+	sm.beginOp(journal.Op{Kind: journal.Delete, FolderID: folderID, Key: key, Trash: trashKey})
+	defer sm.endOp(journal.Delete, key)
+	return sm.deleteRemote(ctx, key, trashKey)
+}
+
+// copyObject copies a remote file and its metadata to another key through a file in the
+// staging area
+func (sm *SyncManager) copyObject(ctx context.Context, from, to string) error {
+	sm.mu.RLock()
+	area := sm.staging
+	sm.mu.RUnlock()
+
+	size := int64(0)
+	if info, _, err := sm.storage.GetFileInfo(ctx, from); err == nil {
+		size = info.Size
+	}
+	tmpFile, err := area.Create("copy:"+from, size)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer tmpFile.Remove()
+
+	metadata, err := sm.storage.DownloadFile(ctx, from, tmpFile, "")
+	if err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata["trashed_from"] = from
+	if _, err := sm.storage.UploadFile(ctx, to, tmpFile, metadata); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	return nil
+}
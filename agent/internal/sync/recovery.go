@@ -0,0 +1,222 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/journal"
+	"github.com/martinshumberto/sync-manager/agent/internal/uploader"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// openJournal opens the journal of operations in progress and returns the ones a previous
+// run left unfinished. Without a journal the agent still syncs, leaving interrupted
+// operations to the next scan.
+func (sm *SyncManager) openJournal() []journal.Op {
+	j, pending, err := journal.Open(sm.journalPath)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open operation journal")
+		return nil
+	}
+
+	sm.mu.Lock()
+	sm.journal = j
+	sm.mu.Unlock()
+	return pending
+}
+
+// beginOp records an operation in the journal before it starts
+func (sm *SyncManager) beginOp(op journal.Op) {
+	sm.mu.RLock()
+	j := sm.journal
+	sm.mu.RUnlock()
+
+	if err := j.Begin(op); err != nil {
+		log.Warn().Err(err).Str("kind", op.Kind).Str("key", op.Key).Msg("Failed to journal operation")
+	}
+}
+
+// endOp marks an operation of the journal as finished
+func (sm *SyncManager) endOp(kind, key string) {
+	sm.mu.RLock()
+	j := sm.journal
+	sm.mu.RUnlock()
+
+	if err := j.Done(kind, key); err != nil {
+		log.Warn().Err(err).Str("kind", kind).Str("key", key).Msg("Failed to journal operation")
+	}
+}
+
+// recoverOperations rolls the operations a crash cut short forward or back: temporary files
+// of downloads are removed, uploads are checked against the remote copy and queued again
+// when it is missing or incomplete, and deletions are carried out. An operation that cannot
+// be recovered now, such as while the storage is unreachable, is kept for the next start.
+func (sm *SyncManager) recoverOperations(ctx context.Context, ops []journal.Op) {
+	for _, op := range ops {
+		var err error
+		switch op.Kind {
+		case journal.Download:
+			err = sm.recoverDownload(op)
+		case journal.Upload:
+			err = sm.recoverUpload(ctx, op)
+		case journal.Delete:
+			err = sm.recoverDelete(ctx, op)
+		default:
+			sm.endOp(op.Kind, op.Key)
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("kind", op.Kind).Str("key", op.Key).Msg("Failed to recover interrupted operation")
+		}
+	}
+	if len(ops) > 0 {
+		log.Info().Int("operations", len(ops)).Msg("Recovered operations interrupted by the last shutdown")
+	}
+}
+
+// cleanupUploads aborts the multipart uploads of every folder left unfinished for longer than
+// uploader.StaleUploadAge, at start and then once a day
+func (sm *SyncManager) cleanupUploads(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		sm.abortStaleUploads(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// abortStaleUploads aborts the multipart uploads of every folder left unfinished for longer
+// than uploader.StaleUploadAge
+func (sm *SyncManager) abortStaleUploads(ctx context.Context) {
+	if sm.uploader == nil {
+		return
+	}
+
+	sm.mu.RLock()
+	prefixes := make(map[string]string, len(sm.folders))
+	for _, folder := range sm.folders {
+		prefixes[folder.keyPrefix()] = folder.ID
+	}
+	sm.mu.RUnlock()
+
+	for prefix, folderID := range prefixes {
+		aborted, err := sm.uploader.AbortStaleUploads(ctx, prefix, uploader.StaleUploadAge)
+		if err != nil {
+			log.Warn().Err(err).Str("folder", folderID).Msg("Failed to abort stale multipart uploads")
+			continue
+		}
+		if aborted > 0 {
+			log.Info().Str("folder", folderID).Int("uploads", aborted).Msg("Aborted stale multipart uploads")
+		}
+	}
+}
+
+// recoverDownload removes the temporary file of an interrupted download. The local file was
+// never replaced, so the next sync downloads it again.
+func (sm *SyncManager) recoverDownload(op journal.Op) error {
+	if err := os.Remove(op.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove temporary file: %w", err)
+	}
+	log.Debug().Str("key", op.Key).Str("path", op.Path).Msg("Removed temporary file of interrupted download")
+	sm.endOp(op.Kind, op.Key)
+	return nil
+}
+
+// recoverUpload checks the remote copy of an interrupted upload. One holding the local
+// content is taken as uploaded; a missing or partial one is uploaded whole again, unless
+// another device replaced it with a later version since.
+func (sm *SyncManager) recoverUpload(ctx context.Context, op journal.Op) error {
+	sm.mu.RLock()
+	folder := sm.folders[op.FolderID]
+	sm.mu.RUnlock()
+	if folder == nil {
+		sm.endOp(op.Kind, op.Key)
+		return nil
+	}
+
+	idx, err := sm.folderIndex(folder.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load folder index: %w", err)
+	}
+	relPath := strings.TrimPrefix(op.Key, folder.keyPrefix())
+	entry, ok := idx.Get(relPath)
+	info, statErr := os.Stat(op.Path)
+	if !ok || statErr != nil {
+		// The file is gone or no longer synced: the next scan handles it
+		sm.endOp(op.Kind, op.Key)
+		return nil
+	}
+	_, metadata, err := sm.storage.GetFileInfo(ctx, op.Key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to get remote file info: %w", err)
+	}
+	if err == nil {
+		remoteVersion, _ := index.DecodeVersionVector(metadataValue(metadata, index.MetadataVersionVector))
+		switch remoteVersion.Compare(entry.Version) {
+		case index.Equal:
+			if remoteHash := checksum.FromMetadata(metadata); remoteHash != "" {
+				localHash, err := fileHash(op.Path, checksum.Of(remoteHash))
+				if err != nil {
+					return fmt.Errorf("failed to hash local file: %w", err)
+				}
+				if localHash == remoteHash {
+					// The upload finished but its result was never recorded
+					entry.Pending = false
+					entry.Hash, entry.RemoteHash = localHash, localHash
+					entry.RemoteSize = info.Size()
+					idx.Put(entry)
+					sm.endOp(op.Kind, op.Key)
+					return idx.Save()
+				}
+			}
+		case index.After, index.Concurrent:
+			// The next sync reconciles the later remote version
+			sm.endOp(op.Kind, op.Key)
+			return nil
+		}
+	}
+
+	// Nothing, an older version or part of an append reached the remote: upload the whole file
+	log.Info().Str("file", relPath).Msg("Uploading file again after an interrupted upload")
+	entry.Pending = true
+	entry.RemoteHash, entry.RemoteSize = "", 0
+	idx.Put(entry)
+	if err := idx.Save(); err != nil {
+		return fmt.Errorf("failed to save folder index: %w", err)
+	}
+	return sm.queueUpload(ctx, folder, entry)
+}
+
+// recoverDelete carries out an interrupted deletion. A file already gone was deleted before the crash.
+func (sm *SyncManager) recoverDelete(ctx context.Context, op journal.Op) error {
+	if err := sm.deleteRemote(ctx, op.Key, op.Trash); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	sm.endOp(op.Kind, op.Key)
+	return nil
+}
+
+// deleteRemote deletes a remote file, first copying it to trashKey when set
+func (sm *SyncManager) deleteRemote(ctx context.Context, key, trashKey string) error {
+	if trashKey != "" {
+		if err := sm.copyObject(ctx, key, trashKey); err != nil {
+			return fmt.Errorf("failed to move file to trash: %w", err)
+		}
+	}
+
+	if err := sm.storage.DeleteFile(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/journal"
+	"github.com/martinshumberto/sync-manager/agent/internal/uploader"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRecoveryManager returns a manager syncing the folder "docs" whose journal holds ops, as left by a crash
+func newRecoveryManager(t *testing.T, remote storage.Storage, up *uploader.Uploader, ops ...journal.Op) (*SyncManager, []journal.Op) {
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true}}
+	var manager *SyncManager
+	if up != nil {
+		manager = newConfiguredManagerWithUploader(t, cfg, remote, up)
+	} else {
+		manager = newConfiguredManager(t, cfg, remote)
+	}
+
+	j, _, err := journal.Open(manager.journalPath)
+	assert.NoError(t, err)
+	for _, op := range ops {
+		assert.NoError(t, j.Begin(op))
+	}
+	assert.NoError(t, j.Close())
+
+	pending := manager.openJournal()
+	t.Cleanup(func() { manager.journal.Close() })
+	return manager, pending
+}
+
+// writeIndexedFile writes a local file of docs and records it in the index as changed locally
+func writeIndexedFile(t *testing.T, manager *SyncManager, relPath, content string) (string, index.Entry) {
+	localPath := filepath.Join(manager.folders["docs"].Path, relPath)
+	assert.NoError(t, os.WriteFile(localPath, []byte(content), 0644))
+
+	idx, err := manager.folderIndex("docs")
+	assert.NoError(t, err)
+	entry, _ := idx.RecordLocalChange("desktop", relPath, relPath, int64(len(content)), time.Now())
+	assert.NoError(t, idx.Save())
+	return localPath, entry
+}
+
+// journalPending returns the operations left in the manager's journal
+func journalPending(t *testing.T, manager *SyncManager) []journal.Op {
+	assert.NoError(t, manager.journal.Close())
+	j, pending, err := journal.Open(manager.journalPath)
+	assert.NoError(t, err)
+	manager.journal = j
+	return pending
+}
+
+func TestRecoverInterruptedOperations(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for _, key := range []string{"docs/gone.txt", "docs/uploaded.txt"} {
+		_, err := remote.UploadFile(ctx, key, strings.NewReader(key), map[string]string{})
+		assert.NoError(t, err)
+	}
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), ".sync-manager-*.tmp")
+	assert.NoError(t, err)
+	tmpFile.Close()
+	manager, pending := newRecoveryManager(t, remote, nil,
+		journal.Op{Kind: journal.Download, FolderID: "docs", Key: "docs/a.txt", Path: tmpFile.Name()},
+		journal.Op{Kind: journal.Delete, FolderID: "docs", Key: "docs/gone.txt", Trash: ".trash/docs/gone.txt"},
+		journal.Op{Kind: journal.Delete, FolderID: "docs", Key: "docs/deleted.txt"},
+	)
+
+	// The upload reached the remote but the agent stopped before recording it
+	localPath, entry := writeIndexedFile(t, manager, "uploaded.txt", "uploaded")
+	hash, err := fileHash(localPath, checksum.SHA256)
+	assert.NoError(t, err)
+	_, err = remote.UploadFile(ctx, "docs/uploaded.txt", strings.NewReader("uploaded"), map[string]string{
+		index.MetadataVersionVector: entry.Version.Encode(),
+		"hash_sha256":               hash,
+	})
+	assert.NoError(t, err)
+	pending = append(pending, journal.Op{Kind: journal.Upload, FolderID: "docs", Key: "docs/uploaded.txt", Path: localPath})
+	manager.beginOp(pending[len(pending)-1])
+
+	manager.recoverOperations(ctx, pending)
+
+	// The temporary file of the download is removed
+	_, err = os.Stat(tmpFile.Name())
+	assert.True(t, os.IsNotExist(err))
+
+	// The deletion is carried out, trash copy included, and one already done counts as finished
+	assert.Equal(t, []string{".trash/docs/gone.txt"}, remoteKeys(t, remote, ".trash/"))
+	found, err := remote.FileExists(ctx, "docs/gone.txt")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// The finished upload is recorded as such
+	idx, err := manager.folderIndex("docs")
+	assert.NoError(t, err)
+	recovered, _ := idx.Get("uploaded.txt")
+	assert.False(t, recovered.Pending)
+	assert.Equal(t, hash, recovered.RemoteHash)
+
+	assert.Empty(t, journalPending(t, manager))
+}
+
+func TestRecoverUploadsMissingFileAgain(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	cfg := commonconfig.DefaultConfig()
+	up := uploader.NewUploader(remote, cfg)
+	up.Start()
+	defer up.Stop()
+
+	manager, _ := newRecoveryManager(t, remote, up)
+	localPath, _ := writeIndexedFile(t, manager, "a.txt", "local")
+	op := journal.Op{Kind: journal.Upload, FolderID: "docs", Key: "docs/a.txt", Path: localPath}
+	manager.beginOp(op)
+
+	// Nothing reached the remote, so the file is uploaded again and stays journaled until it is
+	manager.recoverOperations(ctx, []journal.Op{op})
+	assert.Len(t, journalPending(t, manager), 1)
+
+	select {
+	case result := <-up.Results():
+		assert.True(t, result.Success)
+		manager.handleUploadResult(result)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for upload")
+	}
+
+	assert.Equal(t, []string{"docs/a.txt"}, remoteKeys(t, remote, "docs/"))
+	assert.Empty(t, journalPending(t, manager))
+}
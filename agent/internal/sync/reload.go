@@ -0,0 +1,129 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// ApplyFolders brings the synced folders in line with folders, as the CLI last saved them, so
+// adding, removing or changing a folder takes effect without restarting the agent. New folders
+// are watched and synced right away; a folder whose path cannot be read is left out and tried
+// again on the next call.
+func (sm *SyncManager) ApplyFolders(folders map[string]config.SyncFolder) {
+	current := sm.config.GetAllFolders()
+
+	for id := range current {
+		if _, ok := folders[id]; !ok {
+			sm.dropFolder(id)
+			log.Info().Str("folder", id).Msg("Stopped syncing removed folder")
+		}
+	}
+
+	var added []string
+	for id, folder := range folders {
+		previous, exists := current[id]
+		if exists && reflect.DeepEqual(previous, folder) {
+			continue
+		}
+		if err := sm.applyFolder(id, folder); err != nil {
+			log.Warn().Err(err).Str("folder", id).Msg("Failed to apply folder change")
+			continue
+		}
+		if exists {
+			log.Info().Str("folder", id).Msg("Applied folder settings")
+		} else {
+			log.Info().Str("folder", id).Str("path", folder.LocalPath).Msg("Started syncing new folder")
+			added = append(added, id)
+		}
+	}
+
+	sm.triggerReschedule()
+
+	sm.mu.RLock()
+	ctx := sm.ctx
+	sm.mu.RUnlock()
+	if ctx == nil {
+		return
+	}
+	for _, id := range added {
+		go func(id string) {
+			if err := sm.SyncFolderByID(ctx, id); err != nil {
+				log.Debug().Err(err).Str("folder", id).Msg("New folder not synced yet")
+			}
+		}(id)
+	}
+}
+
+// applyFolder adds a folder, or updates the settings of one already synced, watching its
+// roots again. What a folder learned while syncing, such as when it last synced, is kept.
+func (sm *SyncManager) applyFolder(id string, cfg config.SyncFolder) error {
+	info, err := os.Stat(cfg.LocalPath)
+	if err != nil {
+		return fmt.Errorf("folder path error: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("path is not a directory: %s", cfg.LocalPath)
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	updated := newFolderSync(id, cfg)
+	folder, exists := sm.folders[id]
+	if exists {
+		if folder.Enabled && sm.watcher != nil {
+			sm.unwatchFolder(folder)
+		}
+		folder.Path = updated.Path
+		folder.ExcludePatterns = updated.ExcludePatterns
+		folder.TwoWaySync = updated.TwoWaySync
+		folder.Enabled = updated.Enabled
+		folder.Paused = updated.Paused
+		folder.Interval = updated.Interval
+		folder.Mode = updated.Mode
+		folder.Retention = updated.Retention
+		folder.StorageClass = updated.StorageClass
+		folder.PackSmallFiles = updated.PackSmallFiles
+		folder.Roots = updated.Roots
+		folder.Mirror = updated.Mirror
+		folder.ConflictPolicy = updated.ConflictPolicy
+		folder.InUseTimeout = updated.InUseTimeout
+		folder.File = updated.File
+		folder.InitialMerge = updated.InitialMerge
+		folder.Subscribe = updated.Subscribe
+		folder.LocalChanges = updated.LocalChanges
+		folder.ArchiveAfter = updated.ArchiveAfter
+		folder.RemotePath = updated.RemotePath
+		folder.Schedule = updated.Schedule
+		folder.AgeFilter = updated.AgeFilter
+		folder.Hooks = updated.Hooks
+		folder.Checksum = updated.Checksum
+	} else {
+		folder = updated
+		sm.folders[id] = folder
+	}
+	sm.config.SetSyncFolder(id, cfg)
+
+	if folder.Enabled && sm.watcher != nil {
+		return sm.watchFolder(folder)
+	}
+	return nil
+}
+
+// dropFolder stops watching and syncing a folder
+func (sm *SyncManager) dropFolder(id string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if folder, ok := sm.folders[id]; ok && folder.Enabled && sm.watcher != nil {
+		sm.unwatchFolder(folder)
+	}
+	delete(sm.folders, id)
+	delete(sm.indexes, id)
+	sm.stats.RemoveFolder(id)
+	sm.config.RemoveSyncFolder(id)
+}
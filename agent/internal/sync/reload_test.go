@@ -0,0 +1,69 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyFoldersFollowsTheConfiguration(t *testing.T) {
+	docs := commonconfig.SyncFolder{ID: "docs", Path: t.TempDir(), Enabled: true}
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{docs}
+	sm := newConfiguredManager(t, cfg, storage.NewMemoryStorage(&storage.MemoryConfig{}))
+	manager := &ManagerWrapper{sm: sm}
+
+	lastSync := time.Now()
+	sm.folders["docs"].LastSync = lastSync
+
+	// A folder added by the CLI is picked up and a changed one takes its new settings
+	docs.TwoWaySync = true
+	docs.Interval = 10 * time.Minute
+	photos := commonconfig.SyncFolder{ID: "photos", Path: t.TempDir(), Enabled: true, Exclude: []string{"*.tmp"}}
+	manager.ApplyFolders([]commonconfig.SyncFolder{docs, photos}, commonconfig.NestingRefuse)
+
+	assert.Len(t, sm.folders, 2)
+	assert.True(t, sm.folders["docs"].TwoWaySync)
+	assert.Equal(t, 10*time.Minute, sm.folders["docs"].Interval)
+	assert.Equal(t, lastSync, sm.folders["docs"].LastSync)
+	assert.Equal(t, []string{"*.tmp"}, sm.folders["photos"].ExcludePatterns)
+	stored, ok := sm.config.GetSyncFolder("photos")
+	assert.True(t, ok)
+	assert.Equal(t, photos.Path, stored.LocalPath)
+
+	// A folder whose path is missing is left out until it can be read
+	missing := commonconfig.SyncFolder{ID: "missing", Path: filepath.Join(t.TempDir(), "gone"), Enabled: true}
+	manager.ApplyFolders([]commonconfig.SyncFolder{photos, missing}, commonconfig.NestingRefuse)
+
+	assert.Len(t, sm.folders, 1)
+	assert.Contains(t, sm.folders, "photos")
+	_, ok = sm.config.GetSyncFolder("docs")
+	assert.False(t, ok)
+	_, ok = sm.config.GetSyncFolder("missing")
+	assert.False(t, ok)
+}
+
+func TestApplyFoldersAppliesTheNestingPolicy(t *testing.T) {
+	docs := commonconfig.SyncFolder{ID: "docs", Path: t.TempDir(), Enabled: true, Exclude: []string{"*.tmp"}}
+	projects := commonconfig.SyncFolder{ID: "projects", Path: filepath.Join(docs.Path, "Projects"), Enabled: true}
+	assert.NoError(t, os.Mkdir(projects.Path, 0755))
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{docs, projects}
+
+	// By default the nested folder is refused and the outer one keeps syncing its files
+	sm := newConfiguredManager(t, cfg, storage.NewMemoryStorage(&storage.MemoryConfig{}))
+	manager := &ManagerWrapper{sm: sm}
+	assert.Len(t, sm.folders, 1)
+	assert.Contains(t, sm.folders, "docs")
+
+	// With the exclude policy both sync, the outer one leaving out the nested subtree
+	manager.ApplyFolders(cfg.SyncFolders, commonconfig.NestingExclude)
+	assert.Len(t, sm.folders, 2)
+	assert.Equal(t, []string{"*.tmp", "Projects/**"}, sm.folders["docs"].ExcludePatterns)
+	assert.Empty(t, sm.folders["projects"].ExcludePatterns)
+}
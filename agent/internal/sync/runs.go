@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/rs/zerolog/log"
+)
+
+// RunRecorder stores the summary of a sync run of a folder, typically in the local database
+type RunRecorder func(run models.SyncRun) error
+
+// SetRunRecorder sets where the summary of each folder sync is stored
+func (sm *SyncManager) SetRunRecorder(recorder RunRecorder) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.runs = recorder
+}
+
+// folderRun is a sync of a folder in progress, with the folder totals it started from
+type folderRun struct {
+	run  models.SyncRun
+	base stats.Totals
+}
+
+// startRun begins the summary of a sync of folder
+func (sm *SyncManager) startRun(kind string, folder *FolderSync) *folderRun {
+	sm.mu.Lock()
+	folder.scanned, folder.queued = 0, 0
+	sm.mu.Unlock()
+
+	return &folderRun{
+		run:  models.SyncRun{FolderID: folder.ID, Kind: kind, StartedAt: time.Now()},
+		base: sm.stats.Folder(folder.ID),
+	}
+}
+
+// finishRun completes the summary of a sync that ended with err and hands it to the run recorder
+func (sm *SyncManager) finishRun(ctx context.Context, r *folderRun, folder *FolderSync, err error) {
+	sm.mu.RLock()
+	recorder := sm.runs
+	sm.mu.RUnlock()
+	if recorder == nil {
+		return
+	}
+
+	if err := recorder(sm.summarizeRun(ctx, r, folder, err)); err != nil {
+		log.Warn().Err(err).Str("folder", folder.ID).Msg("Failed to record sync run")
+	}
+}
+
+// summarizeRun returns the summary of a sync that ended with err. Counts are what the folder
+// totals grew by since the sync started.
+func (sm *SyncManager) summarizeRun(ctx context.Context, r *folderRun, folder *FolderSync, err error) models.SyncRun {
+	sm.mu.RLock()
+	scanned, queued := folder.scanned, folder.queued
+	sm.mu.RUnlock()
+
+	run := r.run
+	run.FinishedAt = time.Now()
+	end := sm.stats.Folder(folder.ID)
+	run.FilesScanned = scanned
+	run.FilesQueued = queued
+	run.FilesUploaded = end.FilesUploaded - r.base.FilesUploaded
+	run.FilesDownloaded = end.FilesDownloaded - r.base.FilesDownloaded
+	run.FilesDeleted = end.FilesDeleted - r.base.FilesDeleted
+	run.FilesSkipped = end.FilesSkipped - r.base.FilesSkipped
+	run.BytesUploaded = end.BytesUploaded - r.base.BytesUploaded
+	run.BytesDownloaded = end.BytesDownloaded - r.base.BytesDownloaded
+	run.Errors = end.Errors - r.base.Errors
+	run.Conflicts = end.Conflicts - r.base.Conflicts
+
+	switch {
+	case err != nil && ctx.Err() != nil:
+		run.Status = models.SyncRunCancelled
+	case err != nil:
+		run.Status, run.Error = models.SyncRunFailed, err.Error()
+		run.Errors++
+	default:
+		run.Status = models.SyncRunSucceeded
+	}
+	return run
+}
@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncRecordsRunSummary(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	_, err := remote.UploadFile(ctx, "docs/shared.txt", strings.NewReader("shared"), map[string]string{
+		index.MetadataDeviceID:      "laptop",
+		index.MetadataVersionVector: index.VersionVector{"laptop": 1}.Encode(),
+	})
+	assert.NoError(t, err)
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(cfg.SyncFolders[0].Path, name), []byte(name), 0644))
+	}
+	manager := newConfiguredManager(t, cfg, remote)
+	var runs []models.SyncRun
+	manager.SetRunRecorder(func(run models.SyncRun) error {
+		runs = append(runs, run)
+		return nil
+	})
+
+	// Each folder of a sync gets its own summary of what that sync did
+	folder := manager.folders["docs"]
+	assert.NoError(t, manager.syncFolders(ctx, status.FullSync, []*FolderSync{folder}))
+	if assert.Len(t, runs, 1) {
+		run := runs[0]
+		assert.Equal(t, "docs", run.FolderID)
+		assert.Equal(t, status.FullSync, run.Kind)
+		assert.Equal(t, models.SyncRunSucceeded, run.Status)
+		assert.Equal(t, int64(2), run.FilesScanned)
+		assert.Equal(t, int64(1), run.FilesDownloaded)
+		assert.Equal(t, int64(len("shared")), run.BytesDownloaded)
+		assert.False(t, run.FinishedAt.Before(run.StartedAt))
+	}
+
+	// Counts start over with the next run
+	assert.NoError(t, manager.syncFolders(ctx, status.FullSync, []*FolderSync{folder}))
+	if assert.Len(t, runs, 2) {
+		assert.Zero(t, runs[1].FilesDownloaded)
+		assert.Equal(t, int64(3), runs[1].FilesScanned)
+	}
+
+	// A cancelled sync is recorded as such
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, manager.syncFolders(cancelled, status.FullSync, []*FolderSync{folder}))
+	if assert.Len(t, runs, 3) {
+		assert.Equal(t, models.SyncRunCancelled, runs[2].Status)
+	}
+}
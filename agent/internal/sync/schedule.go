@@ -0,0 +1,29 @@
+package sync
+
+import (
+	"github.com/martinshumberto/sync-manager/common/cron"
+	"github.com/rs/zerolog/log"
+)
+
+// parseSchedule returns the cron schedule a folder is configured with, nil when it has none.
+// An invalid expression, which the configuration rejects before it gets here, is ignored so
+// the folder keeps syncing every interval.
+func parseSchedule(folderID, expr string) *cron.Schedule {
+	if expr == "" {
+		return nil
+	}
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		log.Error().Err(err).Str("folder", folderID).Msg("Invalid folder schedule, syncing it every interval")
+		return nil
+	}
+	return schedule
+}
+
+// scheduleExpr returns the expression of a schedule, empty for none
+func scheduleExpr(schedule *cron.Schedule) string {
+	if schedule == nil {
+		return ""
+	}
+	return schedule.String()
+}
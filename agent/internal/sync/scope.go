@@ -0,0 +1,83 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/config"
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/watcher"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/rs/zerolog/log"
+)
+
+// SyncPath syncs only the subtree at relPath of a folder, a slash-separated path relative to
+// it: the subtree is walked, its remote files listed and reconciled, and the rest of the
+// folder left for its next sync. It runs as a sync on request, joining a running sync of the
+// whole folder or of a subtree holding relPath, or cancelling it first when restart is set.
+func (sm *SyncManager) SyncPath(ctx context.Context, folderID, relPath string, restart bool) error {
+	folder, err := sm.syncableFolder(folderID)
+	if err != nil {
+		return err
+	}
+	scope, err := folderScope(folder, relPath, sm.excludePatterns(folder))
+	if err != nil {
+		return err
+	}
+	log.Info().Str("folder_id", folderID).Str("path", scope).Bool("restart", restart).Msg("Syncing folder path")
+	return sm.runScoped(ctx, status.PathSync, []*FolderSync{folder}, scope, restart)
+}
+
+// folderScope returns the canonical key of the subtree at relPath of a folder, failing when
+// the folder cannot be synced in part or the subtree is not synced at all
+func folderScope(folder *FolderSync, relPath string, excluded []string) (string, error) {
+	if folder.Mode == commonconfig.FolderModeBackup {
+		return "", fmt.Errorf("folder %s keeps snapshots and only syncs as a whole", folder.ID)
+	}
+	if folder.File != "" {
+		return "", fmt.Errorf("folder %s syncs a single file and has no subdirectories", folder.ID)
+	}
+
+	cleaned := path.Clean(filepath.ToSlash(relPath))
+	if filepath.IsAbs(relPath) || path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("path %q is not a subdirectory of folder %s", relPath, folder.ID)
+	}
+	scope := index.NormalizeKey(cleaned)
+	if watcher.ShouldExclude(scope, excluded) {
+		return "", fmt.Errorf("path %s is excluded from folder %s", scope, folder.ID)
+	}
+	return scope, nil
+}
+
+// inScope reports whether the key relPath is in the subtree at scope, which every key is
+// when scope is empty
+func inScope(relPath, scope string) bool {
+	return scope == "" || relPath == scope || strings.HasPrefix(relPath, scope+"/")
+}
+
+// rootWalk is where the walk of a root of a folder starts
+type rootWalk struct {
+	root config.FolderRoot
+	path string
+}
+
+// scopeWalks returns the walks covering the subtree at scope of a folder with roots, every
+// root in full when scope is empty. Roots mounted inside the subtree are walked in full,
+// those holding it only below it, and the others not at all.
+func scopeWalks(roots []config.FolderRoot, scope string) []rootWalk {
+	walks := make([]rootWalk, 0, len(roots))
+	for _, root := range roots {
+		switch {
+		case inScope(root.Prefix, scope):
+			walks = append(walks, rootWalk{root: root, path: root.Path})
+		case inScope(scope, root.Prefix):
+			rel := strings.TrimPrefix(strings.TrimPrefix(scope, root.Prefix), "/")
+			walks = append(walks, rootWalk{root: root, path: filepath.Join(root.Path, filepath.FromSlash(rel))})
+		}
+	}
+	return walks
+}
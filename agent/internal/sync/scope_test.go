@@ -0,0 +1,97 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/config"
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncPathReconcilesOnlyTheSubtree(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for _, key := range []string{"docs/restored/remote.txt", "docs/other/remote.txt"} {
+		_, err := remote.UploadFile(ctx, key, strings.NewReader("from desktop"), map[string]string{
+			index.MetadataDeviceID:      "desktop",
+			index.MetadataVersionVector: index.VersionVector{"desktop": 1}.Encode(),
+		})
+		assert.NoError(t, err)
+	}
+
+	cfg := commonconfig.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true, TwoWaySync: true}}
+	dir := cfg.SyncFolders[0].Path
+	for _, name := range []string{"restored/local.txt", "other/local.txt"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+	}
+
+	manager := newConfiguredManager(t, cfg, remote)
+	folder := manager.folders["docs"]
+	folder.dirty = true
+	assert.NoError(t, manager.SyncPath(ctx, "docs", "restored/", false))
+
+	// Only the subtree was walked and downloaded
+	idx, err := manager.folderIndex("docs")
+	assert.NoError(t, err)
+	_, walked := idx.Get("restored/local.txt")
+	assert.True(t, walked)
+	_, walked = idx.Get("other/local.txt")
+	assert.False(t, walked)
+	assert.FileExists(t, filepath.Join(dir, "restored", "remote.txt"))
+	assert.NoFileExists(t, filepath.Join(dir, "other", "remote.txt"))
+
+	// The rest of the folder still waits for a sync of all of it
+	assert.True(t, folder.dirty)
+	assert.True(t, folder.LastSync.IsZero())
+	assert.True(t, folder.lastScan.IsZero())
+	assert.Nil(t, manager.Operation())
+
+	// A subtree missing locally is only reconciled from the remote
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "other")))
+	assert.NoError(t, manager.SyncPath(ctx, "docs", "other", false))
+	assert.FileExists(t, filepath.Join(dir, "other", "remote.txt"))
+
+	assert.Error(t, manager.SyncPath(ctx, "docs", "../etc", false))
+	assert.Error(t, manager.SyncPath(ctx, "docs", ".", false))
+	assert.Error(t, manager.SyncPath(ctx, "missing", "restored", false))
+}
+
+func TestFolderScope(t *testing.T) {
+	folder := &FolderSync{ID: "docs"}
+	scope, err := folderScope(folder, "a/./b/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "a/b", scope)
+
+	_, err = folderScope(folder, "/etc", nil)
+	assert.Error(t, err)
+	_, err = folderScope(folder, "a/../..", nil)
+	assert.Error(t, err)
+	_, err = folderScope(folder, "node_modules", []string{"node_modules"})
+	assert.Error(t, err)
+
+	backup := &FolderSync{ID: "docs", Mode: commonconfig.FolderModeBackup}
+	_, err = folderScope(backup, "a", nil)
+	assert.Error(t, err)
+}
+
+func TestScopeWalks(t *testing.T) {
+	roots := []config.FolderRoot{{Path: "/home/docs"}, {Path: "/mnt/photos", Prefix: "media/photos"}}
+
+	// Without a scope every root is walked in full
+	assert.Equal(t, []rootWalk{{root: roots[0], path: "/home/docs"}, {root: roots[1], path: "/mnt/photos"}}, scopeWalks(roots, ""))
+
+	// A root mounted inside the subtree is walked in full, one holding it only below it
+	assert.Equal(t, []rootWalk{{root: roots[0], path: filepath.Join("/home/docs", "media")}, {root: roots[1], path: "/mnt/photos"}}, scopeWalks(roots, "media"))
+	assert.Equal(t, []rootWalk{{root: roots[0], path: filepath.Join("/home/docs", "media/photos/2024")}, {root: roots[1], path: filepath.Join("/mnt/photos", "2024")}},
+		scopeWalks(roots, "media/photos/2024"))
+	assert.Equal(t, []rootWalk{{root: roots[0], path: filepath.Join("/home/docs", "notes")}}, scopeWalks(roots, "notes"))
+}
@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/diskspace"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrInsufficientSpace is returned when the remote changes of a folder do not fit on its disk
+var ErrInsufficientSpace = errors.New("not enough disk space for remote changes")
+
+// spaceCheckInterval is how often folders waiting for disk space are checked again
+const spaceCheckInterval = 30 * time.Second
+
+// checkSpace makes sure needed bytes fit on the disk of a folder. When they do not, the
+// shortage is recorded for the user and the folder is synced again once space is freed.
+func (sm *SyncManager) checkSpace(folder *FolderSync, needed uint64) error {
+	available, err := sm.freeSpace(folder.Path)
+	if err != nil {
+		// Without a measurement the downloads go ahead and fail on their own if the disk fills
+		log.Debug().Err(err).Str("folder", folder.ID).Msg("Could not measure free disk space")
+		return nil
+	}
+
+	if diskspace.Enough(available, needed) {
+		sm.mu.Lock()
+		_, waiting := sm.shortages[folder.ID]
+		delete(sm.shortages, folder.ID)
+		sm.mu.Unlock()
+
+		if waiting {
+			log.Info().Str("folder", folder.ID).Msg("Disk space available again, downloading remote changes")
+		}
+		if err := diskspace.Clear(sm.spacePath, folder.ID); err != nil {
+			log.Warn().Err(err).Msg("Failed to clear disk space warning")
+		}
+		return nil
+	}
+
+	shortage := diskspace.Shortage{
+		FolderID:   folder.ID,
+		Path:       folder.Path,
+		Needed:     needed,
+		Available:  available,
+		DetectedAt: time.Now(),
+	}
+	sm.mu.Lock()
+	_, waiting := sm.shortages[folder.ID]
+	sm.shortages[folder.ID] = shortage
+	sm.mu.Unlock()
+	if err := diskspace.Record(sm.spacePath, shortage); err != nil {
+		log.Warn().Err(err).Msg("Failed to record disk space warning")
+	}
+
+	log.Error().
+		Str("folder", folder.ID).
+		Uint64("needed", needed).
+		Uint64("available", available).
+		Msg("Not enough disk space to download remote changes, waiting for space")
+	// A folder still waiting for space was already reported
+	if !waiting {
+		sm.recordEvent(folder.ID, "", models.SyncEventLowDiskSpace, models.LowDiskSpaceDetails{Needed: needed, Available: available})
+	}
+	return fmt.Errorf("%w: %d bytes needed, %d available", ErrInsufficientSpace, needed, available)
+}
+
+// watchSpace syncs the folders waiting for disk space again as soon as their downloads fit
+func (sm *SyncManager) watchSpace(ctx context.Context) {
+	ticker := time.NewTicker(spaceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, folderID := range sm.spaceFreed() {
+				go func(folderID string) {
+					if err := sm.SyncFolderByID(ctx, folderID); err != nil {
+						log.Error().Err(err).Str("folder", folderID).Msg("Sync after freeing disk space failed")
+					}
+				}(folderID)
+			}
+		case <-sm.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// spaceFreed returns the folders whose downloads now fit, forgetting their shortage.
+// A sync that still finds too little space records it again.
+func (sm *SyncManager) spaceFreed() []string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	var ready []string
+	for folderID, shortage := range sm.shortages {
+		available, err := sm.freeSpace(shortage.Path)
+		if err != nil || !diskspace.Enough(available, shortage.Needed) {
+			continue
+		}
+		delete(sm.shortages, folderID)
+		ready = append(ready, folderID)
+	}
+	return ready
+}
@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/watcher"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Kinds of local change to a subscribed folder, as recorded in its sync events
+const (
+	changeAdded    = "added"
+	changeModified = "modified"
+	changeDeleted  = "deleted"
+)
+
+// subscribedChanges handles the files of a subscribed folder changed on this device, which
+// are never uploaded: depending on the folder's policy the published copy is restored or the
+// change is kept and recorded once. local holds the keys found by the scan, nil when the
+// folder was not walked, and is what reveals deleted files. Only the subtree at scope is looked
+// at when set.
+func (sm *SyncManager) subscribedChanges(ctx context.Context, folder *FolderSync, idx *index.Index, local map[string]string, scope string) {
+	sm.mu.RLock()
+	paused := sm.paused
+	sm.mu.RUnlock()
+	if paused {
+		return
+	}
+
+	excluded := sm.excludePatterns(folder)
+	for _, relPath := range idx.Paths() {
+		entry, ok := idx.Get(relPath)
+		if !ok || entry.Deleted || !inScope(relPath, scope) || watcher.ShouldExclude(relPath, excluded) {
+			continue
+		}
+
+		var change string
+		switch {
+		case entry.Dir:
+			// Directories only come from the publisher, so local metadata changes are dropped
+			if entry.Pending {
+				entry.Pending = false
+				idx.Put(entry)
+			}
+			continue
+		case entry.Pending && entry.RemoteETag == "":
+			change = changeAdded
+		case entry.Pending:
+			change = changeModified
+		case local != nil:
+			// Files downloaded since the scan are missing from it, so the disk has the last word
+			if _, found := local[relPath]; found {
+				continue
+			}
+			if _, err := os.Lstat(folder.localPath(entry.LocalRelPath())); err == nil {
+				continue
+			}
+			change = changeDeleted
+		default:
+			continue
+		}
+
+		if err := sm.localChange(ctx, folder, idx, entry, change); err != nil {
+			log.Error().Err(err).Str("folder", folder.ID).Str("file", relPath).Msg("Failed to revert local change")
+			sm.stats.Failed(folder.ID)
+		}
+	}
+}
+
+// localChange reverts or flags one local change to a subscribed folder
+func (sm *SyncManager) localChange(ctx context.Context, folder *FolderSync, idx *index.Index, entry index.Entry, change string) error {
+	policy := folder.LocalChanges
+	if policy == "" {
+		policy = commonconfig.LocalChangesRevert
+	}
+	sm.recordEvent(folder.ID, entry.Path, models.SyncEventLocalChange, models.LocalChangeDetails{Change: change, Policy: policy})
+
+	if policy == commonconfig.LocalChangesFlag {
+		// The change stays until the publisher replaces the file, and is only flagged once
+		log.Warn().Str("folder", folder.ID).Str("file", entry.Path).Str("change", change).Msg("Kept local change to a subscribed folder, it is not uploaded")
+		entry.Pending = false
+		entry.Deleted = change == changeDeleted
+		idx.Put(entry)
+		return nil
+	}
+
+	log.Info().Str("folder", folder.ID).Str("file", entry.Path).Str("change", change).Msg("Reverting local change to a subscribed folder")
+	remoteFile, _, err := sm.storage.GetFileInfo(ctx, folder.key(entry.Path))
+	if errors.Is(err, storage.ErrNotFound) {
+		// The publisher has no such file, so it only exists here
+		if err := os.Remove(folder.localPath(entry.LocalRelPath())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove local file: %w", err)
+		}
+		idx.Remove(entry.Path)
+		sm.stats.Deleted(folder.ID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get remote file info: %w", err)
+	}
+
+	// Without a local version the published one wins and replaces the file
+	entry.Version = index.VersionVector{}
+	idx.Put(entry)
+	return sm.reconcileFile(ctx, folder, idx, entry.Path, remoteFile)
+}
@@ -0,0 +1,110 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// publish uploads content as the publisher's version of a file of the folder "assets"
+func publish(t *testing.T, remote storage.Storage, name, content string, version uint64) {
+	_, err := remote.UploadFile(context.Background(), "assets/"+name, strings.NewReader(content), map[string]string{
+		index.MetadataDeviceID:      "publisher",
+		index.MetadataVersionVector: index.VersionVector{"publisher": version}.Encode(),
+	})
+	assert.NoError(t, err)
+}
+
+// newSubscribedManager returns a manager subscribed to "assets" with the given local changes
+// policy, after its first sync, and the events it records
+func newSubscribedManager(t *testing.T, remote storage.Storage, policy string) (*SyncManager, string, *[]models.LocalChangeDetails) {
+	cfg := commonconfig.DefaultConfig()
+	cfg.DeviceID = "kiosk"
+	cfg.SyncFolders = []commonconfig.SyncFolder{{ID: "assets", Path: t.TempDir(), Enabled: true, Subscribe: true, LocalChanges: policy}}
+	manager := newConfiguredManager(t, cfg, remote)
+
+	var changes []models.LocalChangeDetails
+	manager.SetEventRecorder(func(folderID string, event models.CreateSyncEventRequest) {
+		assert.Equal(t, models.SyncEventLocalChange, event.EventType)
+		var details models.LocalChangeDetails
+		assert.NoError(t, json.Unmarshal([]byte(event.Details), &details))
+		changes = append(changes, details)
+	})
+
+	assert.NoError(t, manager.syncFolder(context.Background(), manager.folders["assets"]))
+	return manager, cfg.SyncFolders[0].Path, &changes
+}
+
+func assertFile(t *testing.T, path, content string) {
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestSubscribedFolderRevertsLocalChanges(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	publish(t, remote, "logo.svg", "published logo", 1)
+	publish(t, remote, "fonts.css", "published fonts", 1)
+
+	manager, dir, changes := newSubscribedManager(t, remote, "")
+	assertFile(t, filepath.Join(dir, "logo.svg"), "published logo")
+
+	// Edited, added and deleted files go back to what the publisher has
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "logo.svg"), []byte("edited logo"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("local notes"), 0644))
+	assert.NoError(t, os.Remove(filepath.Join(dir, "fonts.css")))
+	assert.NoError(t, manager.syncFolder(ctx, manager.folders["assets"]))
+
+	assertFile(t, filepath.Join(dir, "logo.svg"), "published logo")
+	assertFile(t, filepath.Join(dir, "fonts.css"), "published fonts")
+	_, err := os.Stat(filepath.Join(dir, "notes.txt"))
+	assert.True(t, os.IsNotExist(err))
+	assert.ElementsMatch(t, []models.LocalChangeDetails{
+		{Change: changeModified, Policy: commonconfig.LocalChangesRevert},
+		{Change: changeAdded, Policy: commonconfig.LocalChangesRevert},
+		{Change: changeDeleted, Policy: commonconfig.LocalChangesRevert},
+	}, *changes)
+
+	// Nothing was uploaded, and the folder is settled
+	assert.Equal(t, []string{"assets/fonts.css", "assets/logo.svg"}, remoteKeys(t, remote, "assets/"))
+	assert.NoError(t, manager.syncFolder(ctx, manager.folders["assets"]))
+	assert.Len(t, *changes, 3)
+	idx, err := manager.folderIndex("assets")
+	assert.NoError(t, err)
+	assert.Zero(t, idx.PendingFiles())
+}
+
+func TestSubscribedFolderFlagsLocalChanges(t *testing.T) {
+	ctx := context.Background()
+	remote := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	publish(t, remote, "logo.svg", "published logo", 1)
+
+	manager, dir, changes := newSubscribedManager(t, remote, commonconfig.LocalChangesFlag)
+
+	// The edit is kept, recorded once and never uploaded
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "logo.svg"), []byte("edited logo"), 0644))
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, manager.syncFolder(ctx, manager.folders["assets"]))
+	}
+	assertFile(t, filepath.Join(dir, "logo.svg"), "edited logo")
+	assert.Equal(t, []models.LocalChangeDetails{{Change: changeModified, Policy: commonconfig.LocalChangesFlag}}, *changes)
+	_, metadata, err := remote.GetFileInfo(ctx, "assets/logo.svg")
+	assert.NoError(t, err)
+	assert.Equal(t, "publisher", metadataValue(metadata, index.MetadataDeviceID))
+
+	// Until the publisher changes the file, whose new version replaces the edit
+	publish(t, remote, "logo.svg", "new logo", 2)
+	assert.NoError(t, manager.syncFolder(ctx, manager.folders["assets"]))
+	assertFile(t, filepath.Join(dir, "logo.svg"), "new logo")
+	assert.Len(t, *changes, 1)
+}
@@ -0,0 +1,33 @@
+package sync
+
+import (
+	"slices"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/watchlimit"
+	"github.com/rs/zerolog/log"
+)
+
+// recordWatches stores the watcher's usage for the CLI when it changes, warning once when
+// the watches near the system limit. The watcher calls it while sm.mu may be held, so the
+// last usage is guarded by watchesMu instead.
+func (sm *SyncManager) recordWatches(usage watchlimit.State) {
+	sm.watchesMu.Lock()
+	defer sm.watchesMu.Unlock()
+
+	last := sm.watches
+	if last != nil && last.Watches == usage.Watches && last.Limit == usage.Limit && slices.Equal(last.Polled, usage.Polled) {
+		return
+	}
+	sm.watches = &usage
+
+	if usage.Near() && (last == nil || !last.Near()) {
+		log.Warn().Int("watches", usage.Watches).Int("limit", usage.Limit).
+			Msgf("Close to the file watch limit; raise it with: sudo sysctl %s=%d", watchlimit.Sysctl, usage.Suggested())
+	}
+
+	usage.UpdatedAt = time.Now()
+	if err := watchlimit.Write(sm.watchesPath, &usage); err != nil {
+		log.Warn().Err(err).Msg("Failed to record file watch usage")
+	}
+}
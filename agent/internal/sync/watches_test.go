@@ -0,0 +1,33 @@
+package sync
+
+import (
+	"testing"
+
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/martinshumberto/sync-manager/common/watchlimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordWatchesOnChange(t *testing.T) {
+	manager := newConfiguredManager(t, commonconfig.DefaultConfig(), storage.NewMemoryStorage(&storage.MemoryConfig{}))
+
+	manager.recordWatches(watchlimit.State{Watches: 950, Limit: 1000, Polled: []string{"/home/docs/node_modules"}})
+	state, err := watchlimit.Read(manager.watchesPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 950, state.Watches)
+	assert.Equal(t, []string{"/home/docs/node_modules"}, state.Polled)
+	recorded := state.UpdatedAt
+
+	// The same usage is not written again
+	manager.recordWatches(watchlimit.State{Watches: 950, Limit: 1000, Polled: []string{"/home/docs/node_modules"}})
+	state, err = watchlimit.Read(manager.watchesPath)
+	assert.NoError(t, err)
+	assert.True(t, recorded.Equal(state.UpdatedAt))
+
+	manager.recordWatches(watchlimit.State{Watches: 10, Limit: 1000})
+	state, err = watchlimit.Read(manager.watchesPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, state.Watches)
+	assert.Empty(t, state.Polled)
+}
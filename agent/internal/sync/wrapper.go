@@ -1,64 +1,131 @@
 package sync
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/martinshumberto/sync-manager/agent/internal/config"
-	"github.com/martinshumberto/sync-manager/agent/internal/storage"
-	"github.com/martinshumberto/sync-manager/agent/internal/syncmanager"
+	"github.com/martinshumberto/sync-manager/agent/internal/power"
+	"github.com/martinshumberto/sync-manager/agent/internal/staging"
 	"github.com/martinshumberto/sync-manager/agent/internal/uploader"
 	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/download"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
 )
 
 // Manager é uma interface que simplifica o acesso ao SyncManager
 type Manager interface {
 	Start() error
 	Stop()
+	SetOnline(online bool)
+	SetRestriction(restriction power.Restriction)
+	SetPeers(peers PeerFetcher)
+	SetExcludeSource(source ExcludeSource)
+	SetEventRecorder(recorder EventRecorder)
+	SetRunRecorder(recorder RunRecorder)
+	SetStaging(area *staging.Area)
+	SetClockSkew(skew time.Duration)
+	Stats() *stats.Registry
+	FolderStatus() status.Snapshot
+	SyncNow(ctx context.Context, folderID string, restart bool) error
+	SyncPath(ctx context.Context, folderID, relPath string, restart bool) error
+	ApplyFolders(folders []commonconfig.SyncFolder, nesting string)
 }
 
 // ManagerWrapper é um wrapper em torno do SyncManager
 type ManagerWrapper struct {
-	sm *syncmanager.SyncManager
+	sm *SyncManager
 }
 
 // NewManager cria uma nova instância do gerenciador de sincronização
 func NewManager(cfg interface{}, store storage.Storage, uploader *uploader.Uploader) (Manager, error) {
 	// Adaptação da configuração para o formato esperado pelo SyncManager
 	var internalCfg *config.Config
+	var downloader *download.Downloader
 
 	// Se for configuração comum, adaptar para configuração interna
 	if commonCfg, ok := cfg.(*commonconfig.Config); ok {
 		internalCfg = &config.Config{
+			DeviceID: commonCfg.DeviceID,
 			Sync: config.SyncConfig{
 				IntervalMinutes: int(commonCfg.SyncInterval.Minutes()),
 				AutoSync:        true,
+				FullScanMinutes: int(commonCfg.FullScanInterval.Minutes()),
 			},
 			Folders: make(map[string]config.SyncFolder),
 		}
 
 		// Converter pastas sincronizadas
-		for _, folder := range commonCfg.SyncFolders {
-			internalCfg.Folders[folder.ID] = config.SyncFolder{
-				LocalPath:       folder.Path,
-				RemotePath:      folder.ID, // Usar ID como caminho remoto por padrão
-				ExcludePatterns: folder.Exclude,
-				Enabled:         folder.Enabled,
-			}
+		for _, folder := range nestedFolders(commonCfg.SyncFolders, commonCfg.NestedFolders) {
+			internalCfg.Folders[folder.ID] = internalFolder(folder)
 		}
+
+		// Downloads follow the configured concurrency, bandwidth and chunk size
+		downloader = download.NewDownloader(store, commonCfg)
 	} else if agentCfg, ok := cfg.(*config.Config); ok {
 		// Usar a configuração interna diretamente
 		internalCfg = agentCfg
+	} else {
+		return nil, fmt.Errorf("unsupported configuration type %T", cfg)
 	}
 
 	// Criar o SyncManager usando a configuração interna
-	sm, err := syncmanager.NewSyncManager(internalCfg)
+	sm, err := NewSyncManager(internalCfg, store, uploader)
 	if err != nil {
 		return nil, err
 	}
+	if downloader != nil {
+		sm.SetDownloader(downloader)
+	}
 
 	return &ManagerWrapper{
 		sm: sm,
 	}, nil
 }
 
+// internalFolder adapta uma pasta da configuração comum para a configuração interna
+func internalFolder(folder commonconfig.SyncFolder) config.SyncFolder {
+	var roots []config.FolderRoot
+	for _, root := range folder.Roots {
+		roots = append(roots, config.FolderRoot(root))
+	}
+
+	return config.SyncFolder{
+		LocalPath:           folder.Path,
+		RemotePath:          folder.KeyPrefix(),
+		ExcludePatterns:     folder.Exclude,
+		TwoWaySync:          folder.TwoWaySync,
+		Enabled:             folder.Enabled,
+		Paused:              folder.Paused,
+		Priority:            folder.Priority,
+		IntervalMinutes:     int(folder.Interval.Minutes()),
+		Mode:                folder.Mode,
+		Retention:           config.RetentionConfig(folder.Retention),
+		Mirror:              config.MirrorConfig(folder.Mirror),
+		StorageClass:        folder.StorageClass,
+		PackSmallFiles:      folder.PackSmallFiles,
+		Roots:               roots,
+		ConflictPolicy:      folder.ConflictPolicy,
+		InUseTimeoutSeconds: inUseTimeoutSeconds(folder.InUseTimeout),
+		File:                folder.File,
+		InitialMerge:        folder.InitialMerge,
+		Subscribe:           folder.Subscribe,
+		LocalChanges:        folder.LocalChanges,
+		ArchiveAfterDays:    folder.ArchiveAfterDays,
+		Schedule:            folder.Schedule,
+		AgeFilter:           config.AgeFilter(folder.AgeFilter),
+		Hooks: config.FolderHooks{
+			PreSync:  config.Hook(folder.Hooks.PreSync),
+			PostSync: config.Hook(folder.Hooks.PostSync),
+		},
+		Checksum: folder.Checksum,
+	}
+}
+
 // Start inicia o gerenciador de sincronização
 func (m *ManagerWrapper) Start() error {
 	return m.sm.Start()
@@ -66,5 +133,94 @@ func (m *ManagerWrapper) Start() error {
 
 // Stop para o gerenciador de sincronização
 func (m *ManagerWrapper) Stop() {
-	m.sm.Stop()
+	if err := m.sm.Stop(); err != nil {
+		log.Error().Err(err).Msg("Failed to stop file watcher")
+	}
+}
+
+// SetOnline informa se o armazenamento remoto está acessível
+func (m *ManagerWrapper) SetOnline(online bool) {
+	m.sm.SetOnline(online)
+}
+
+// SetRestriction aplica aos downloads a restrição de transferências em vigor
+func (m *ManagerWrapper) SetRestriction(restriction power.Restriction) {
+	m.sm.SetRestriction(restriction)
+}
+
+// SetPeers define os dispositivos da rede local consultados antes do armazenamento remoto
+func (m *ManagerWrapper) SetPeers(peers PeerFetcher) {
+	m.sm.SetPeers(peers)
+}
+
+// SetExcludeSource define de onde vêm as regras de exclusão aplicadas a todas as pastas
+func (m *ManagerWrapper) SetExcludeSource(source ExcludeSource) {
+	m.sm.SetExcludeSource(source)
+}
+
+// SetEventRecorder define onde os eventos de sincronização são registrados
+func (m *ManagerWrapper) SetEventRecorder(recorder EventRecorder) {
+	m.sm.SetEventRecorder(recorder)
+}
+
+// SetRunRecorder define onde o resumo de cada sincronização de pasta é guardado
+func (m *ManagerWrapper) SetRunRecorder(recorder RunRecorder) {
+	m.sm.SetRunRecorder(recorder)
+}
+
+// SetStaging define a área de staging onde as cópias temporárias são gravadas
+func (m *ManagerWrapper) SetStaging(area *staging.Area) {
+	m.sm.SetStaging(area)
+}
+
+// SetClockSkew define quanto o relógio local está adiantado em relação ao do servidor de armazenamento
+func (m *ManagerWrapper) SetClockSkew(skew time.Duration) {
+	m.sm.SetClockSkew(skew)
+}
+
+// Stats retorna o registro com as estatísticas de transferência
+func (m *ManagerWrapper) Stats() *stats.Registry {
+	return m.sm.Stats()
+}
+
+// FolderStatus retorna o estado atual de cada pasta, publicado para a CLI
+func (m *ManagerWrapper) FolderStatus() status.Snapshot {
+	return m.sm.FolderStatus()
+}
+
+// SyncNow sincroniza uma pasta, ou todas quando folderID é vazio, sem sobrepor a sincronização em andamento
+func (m *ManagerWrapper) SyncNow(ctx context.Context, folderID string, restart bool) error {
+	return m.sm.SyncNow(ctx, folderID, restart)
+}
+
+// SyncPath sincroniza só a subárvore relPath de uma pasta, sem percorrer o resto dela
+func (m *ManagerWrapper) SyncPath(ctx context.Context, folderID, relPath string, restart bool) error {
+	return m.sm.SyncPath(ctx, folderID, relPath, restart)
+}
+
+// ApplyFolders aplica as pastas da configuração salva pela CLI sem reiniciar o agente,
+// tratando as pastas aninhadas conforme a política nesting
+func (m *ManagerWrapper) ApplyFolders(folders []commonconfig.SyncFolder, nesting string) {
+	folders = nestedFolders(folders, nesting)
+	internal := make(map[string]config.SyncFolder, len(folders))
+	for _, folder := range folders {
+		internal[folder.ID] = internalFolder(folder)
+	}
+	m.sm.ApplyFolders(internal)
+}
+
+// nestedFolders aplica a política de pastas aninhadas, para que nenhum arquivo seja enviado
+// por duas pastas, e registra as pastas recusadas ou excluídas da pasta que as contém
+func nestedFolders(folders []commonconfig.SyncFolder, nesting string) []commonconfig.SyncFolder {
+	resolved, overlaps := commonconfig.ResolveNesting(folders, nesting)
+	for _, overlap := range overlaps {
+		if nesting == commonconfig.NestingExclude && !overlap.Same() {
+			log.Info().Str("folder", overlap.Outer).Str("nested", overlap.Inner).Str("path", overlap.RelPath).
+				Msg("Excluding nested folder from the folder containing it")
+			continue
+		}
+		log.Error().Str("folder", overlap.Inner).Str("inside", overlap.Outer).Str("path", overlap.InnerPath).
+			Msg("Not syncing folder inside another one; its files sync with the outer folder. Set nested_folders to exclude to sync both")
+	}
+	return resolved
 }
@@ -0,0 +1,176 @@
+package uploader
+
+import (
+	"time"
+
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// tuneWindow is the shortest time over which the tuner measures the uploads before changing
+// the number of workers, so a change is judged on uploads started after it
+const tuneWindow = 2 * time.Second
+
+// Thresholds of the tuner's decisions
+const (
+	failureRate  = 0.1  // Share of failed uploads that halves the workers
+	gainRate     = 1.05 // Throughput rise over the last window that earns another worker
+	lossRate     = 0.9  // Throughput fall from the last window that takes one back
+	probeWindows = 5    // Windows with a steady throughput after which another worker is tried
+)
+
+// sample is what a finished upload tells the tuner
+type sample struct {
+	bytes     int64 // Bytes transferred
+	failed    bool  // The upload failed for a reason that may pass, not the file's own
+	throttled bool  // The storage asked for fewer requests
+}
+
+// tuner adjusts the number of upload workers by additive increase and multiplicative decrease:
+// one more while the throughput of the uploads keeps rising, half as many when the storage
+// throttles requests or too many fail, one less when adding a worker made things slower
+type tuner struct {
+	min, max int
+
+	started   time.Time // Start of the window the uploads are measured over
+	uploads   int
+	failures  int
+	bytes     int64
+	throttled bool
+	last      float64 // Throughput of the previous window in bytes/sec, 0 before there is one
+	steady    int     // Windows in a row that neither gained nor lost throughput
+}
+
+// newTuner returns a tuner keeping the workers between cfg.Min and cfg.Max
+func newTuner(cfg commonconfig.ConcurrencyConfig, now time.Time) *tuner {
+	t := &tuner{started: now}
+	t.setBounds(cfg)
+	return t
+}
+
+// setBounds changes the range the number of workers is kept in
+func (t *tuner) setBounds(cfg commonconfig.ConcurrencyConfig) {
+	t.min = max(cfg.Min, 1)
+	t.max = max(cfg.Max, t.min)
+}
+
+// clamp returns n within the bounds
+func (t *tuner) clamp(n int) int {
+	return min(max(n, t.min), t.max)
+}
+
+// record adds a finished upload to the window and returns how many workers should run.
+// A window closes once every worker finished an upload in it and tuneWindow passed, or on
+// throttling once tuneWindow passed, so at most one decision is taken per window.
+func (t *tuner) record(workers int, s sample, now time.Time) int {
+	t.uploads++
+	t.bytes += s.bytes
+	if s.failed {
+		t.failures++
+	}
+	t.throttled = t.throttled || s.throttled
+
+	elapsed := now.Sub(t.started)
+	if elapsed < tuneWindow || (t.uploads < workers && !t.throttled) {
+		return workers
+	}
+
+	n := workers
+	rate := float64(t.bytes) / elapsed.Seconds()
+	switch {
+	case t.throttled || float64(t.failures) > failureRate*float64(t.uploads):
+		// The throughput measured while the storage pushed back says nothing of the new count
+		n = workers / 2
+		rate = 0
+		t.steady = 0
+	case t.last == 0 || rate >= t.last*gainRate:
+		n = workers + 1
+		t.steady = 0
+	case rate < t.last*lossRate:
+		n = workers - 1
+		t.steady = 0
+	default:
+		// The link may have room again since the count settled
+		if t.steady++; t.steady >= probeWindows {
+			n = workers + 1
+			t.steady = 0
+		}
+	}
+
+	t.last = rate
+	t.started = now
+	t.uploads, t.failures, t.bytes, t.throttled = 0, 0, 0, false
+	return t.clamp(n)
+}
+
+// SetConcurrency applies the concurrency mode. In fixed mode n workers run. In adaptive mode
+// n is where the workers start, within the bounds of cfg, and the count then follows the
+// uploads; changing the bounds alone keeps the current count, moved within them.
+func (u *Uploader) SetConcurrency(n int, cfg commonconfig.ConcurrencyConfig) {
+	if cfg.Mode != commonconfig.ConcurrencyAdaptive {
+		u.mutex.Lock()
+		u.tuner = nil
+		u.mutex.Unlock()
+		u.SetMaxConcurrency(n)
+		return
+	}
+
+	u.mutex.Lock()
+	if u.tuner == nil {
+		u.tuner = newTuner(cfg, time.Now())
+	} else {
+		u.tuner.setBounds(cfg)
+		n = u.maxConcurrency
+	}
+	n = u.tuner.clamp(n)
+	u.mutex.Unlock()
+	u.SetMaxConcurrency(n)
+}
+
+// observe hands a finished upload to the tuner. It never blocks the worker: samples that
+// find the tuner busy are dropped.
+func (u *Uploader) observe(result UploadResult) {
+	s := sample{}
+	switch {
+	case !result.sent:
+		// Nothing was transferred, or the file could not be read
+		return
+	case result.Success:
+		s.bytes = result.Size - result.Offset
+	case storage.Retryable(result.Error):
+		s.failed = true
+		s.throttled = storage.Throttled(result.Error)
+	default:
+		// The storage refused the file itself
+		return
+	}
+
+	select {
+	case u.samples <- s:
+	default:
+	}
+}
+
+// tune applies the tuner's decisions on the samples of finished uploads until the uploader stops
+func (u *Uploader) tune() {
+	for {
+		select {
+		case s := <-u.samples:
+			u.mutex.Lock()
+			if u.running && u.tuner != nil {
+				if n := u.tuner.record(u.maxConcurrency, s, time.Now()); n != u.maxConcurrency {
+					log.Debug().
+						Int("from", u.maxConcurrency).
+						Int("to", n).
+						Msg("Adapting upload workers")
+					u.maxConcurrency = n
+					u.resizeLocked(n)
+				}
+			}
+			u.mutex.Unlock()
+		case <-u.ctx.Done():
+			return
+		}
+	}
+}
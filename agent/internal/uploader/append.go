@@ -0,0 +1,49 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// errNotAppended is returned by appendFile when the file has to be uploaded whole
+var errNotAppended = errors.New("file not appended")
+
+// appendFile uploads the bytes of a file after offset when the storage can append them to
+// the base of the task, after checking that the remote copy still is that base
+func (u *Uploader) appendFile(ctx context.Context, task UploadTask, file *os.File, offset, size int64, transfer *progress.File) (string, error) {
+	appender, ok := u.store.(storage.Appender)
+	if !ok {
+		return "", errNotAppended
+	}
+
+	info, metadata, err := u.store.GetFileInfo(ctx, task.Key)
+	if err != nil || info.Size != task.Base.Size || checksum.FromMetadata(metadata) != task.Base.Hash {
+		// Another device replaced the remote copy, or it is gone
+		return "", errNotAppended
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return "", errNotAppended
+	}
+
+	log.Info().
+		Str("path", task.FilePath).
+		Str("key", task.Key).
+		Int64("offset", offset).
+		Int64("appended", size-offset).
+		Msg("Appending to file")
+
+	reader := transfer.Reader(newThrottledReader(io.LimitReader(file, size-offset), u.throttle))
+	versionID, err := appender.AppendFile(ctx, task.Key, offset, reader, size-offset, task.Metadata)
+	if errors.Is(err, storage.ErrAppendUnsupported) {
+		log.Debug().Str("key", task.Key).Msg("Storage cannot append to file, uploading it whole")
+		return "", errNotAppended
+	}
+	return versionID, err
+}
@@ -0,0 +1,122 @@
+package uploader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/sparse"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrSparseSkipped is returned, wrapped, for sparse files under the skip policy
+var ErrSparseSkipped = errors.New("sparse file skipped")
+
+// ErrFileVanished is returned, wrapped, when the file of a task was removed before its upload
+var ErrFileVanished = errors.New("file removed before upload")
+
+// FileTooLargeError is returned for files above the upload size limit
+type FileTooLargeError struct {
+	Size  int64 // Size of the file
+	Limit int64 // Largest file that may be uploaded
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("file of %d bytes exceeds the maximum upload size of %d bytes", e.Size, e.Limit)
+}
+
+// PlaceholderError is returned for the placeholders cloud storage clients leave for files
+// kept online, unless they are to be hydrated
+type PlaceholderError struct {
+	Path string
+	Size int64 // Size of the content the placeholder stands for
+}
+
+func (e *PlaceholderError) Error() string {
+	return fmt.Sprintf("%s is a cloud placeholder whose content is not on disk", e.Path)
+}
+
+// Skipped reports whether an upload failed because the file is left out by policy.
+// Such uploads are not retried, since they fail the same way until the file changes.
+func Skipped(err error) bool {
+	var tooLarge *FileTooLargeError
+	var placeholder *PlaceholderError
+	return errors.As(err, &tooLarge) || errors.As(err, &placeholder) || errors.Is(err, ErrSparseSkipped)
+}
+
+// Vanished reports whether an upload failed because its file no longer exists. Such uploads
+// are dropped rather than retried.
+func Vanished(err error) bool {
+	return errors.Is(err, ErrFileVanished)
+}
+
+// SetFiles changes the sparse file and placeholder policies and the upload size limit
+func (u *Uploader) SetFiles(cfg commonconfig.FilesConfig) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.files = cfg
+}
+
+// maxFileSize returns the largest file that may be uploaded, zero for no limit. The configured
+// limit cannot go above what the storage backend accepts.
+func (u *Uploader) maxFileSize() int64 {
+	u.mutex.Lock()
+	limit := u.files.MaxFileSize
+	u.mutex.Unlock()
+
+	backend := storage.MaxObjectSize(u.store)
+	if limit < 0 {
+		return 0
+	}
+	if limit == 0 || (backend > 0 && limit > backend) {
+		return backend
+	}
+	return limit
+}
+
+// checkPlaceholder leaves out the placeholder of a file kept online by a cloud storage
+// client, before it is opened: reading it would download the file from the provider, and
+// many of them at once would fill the disk. Placeholders are uploaded when set to be hydrated.
+func (u *Uploader) checkPlaceholder(task UploadTask) error {
+	info, err := os.Lstat(task.FilePath)
+	if err != nil || !u.dehydrated(info) {
+		return nil
+	}
+
+	u.mutex.Lock()
+	hydrate := u.files.HydratePlaceholders
+	u.mutex.Unlock()
+	if !hydrate {
+		return &PlaceholderError{Path: task.FilePath, Size: info.Size()}
+	}
+	log.Info().Str("path", task.FilePath).Int64("size", info.Size()).Msg("Downloading cloud placeholder from its provider to upload it")
+	return nil
+}
+
+// checkFile applies the size limit and the sparse file policy to a file about to be uploaded.
+// It reports whether the file is uploaded as sparse, so downloads restore its holes.
+func (u *Uploader) checkFile(task UploadTask, info os.FileInfo) (bool, error) {
+	if limit := u.maxFileSize(); limit > 0 && info.Size() > limit {
+		return false, &FileTooLargeError{Size: info.Size(), Limit: limit}
+	}
+	if !sparse.IsSparse(info) {
+		return false, nil
+	}
+
+	u.mutex.Lock()
+	policy := u.files.Sparse
+	u.mutex.Unlock()
+
+	switch policy {
+	case commonconfig.SparseSkip:
+		return false, fmt.Errorf("%w: %s", ErrSparseSkipped, task.FilePath)
+	case commonconfig.SparseWarn:
+		log.Warn().Str("path", task.FilePath).Int64("size", info.Size()).Msg("Uploading sparse file in full")
+		return false, nil
+	default:
+		log.Debug().Str("path", task.FilePath).Int64("size", info.Size()).Msg("Uploading sparse file")
+		return true, nil
+	}
+}
@@ -0,0 +1,299 @@
+package uploader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultPartSize is the size of the parts files are uploaded in when the storage supports
+// multipart uploads and a part store is set. Smaller files are uploaded whole.
+const DefaultPartSize = 16 << 20
+
+// StaleUploadAge is how long a multipart upload may stay unfinished before it is aborted,
+// whichever device started it. No upload of a live device lasts that long.
+const StaleUploadAge = 7 * 24 * time.Hour
+
+// errNotMultipart is returned by uploadParts when the file has to be uploaded whole
+var errNotMultipart = errors.New("file not uploaded in parts")
+
+// PartStore keeps on disk the multipart uploads in progress and the parts each one
+// completed, so an upload cut short, even by a restart, continues from its last part
+type PartStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// partUpload is a multipart upload in progress, one file of the part store
+type partUpload struct {
+	Key       string         `json:"key"`
+	UploadID  string         `json:"upload_id"`
+	Hash      string         `json:"hash"` // Hash of the file the parts are read from
+	Size      int64          `json:"size"`
+	PartSize  int64          `json:"part_size"`
+	Parts     []storage.Part `json:"parts,omitempty"`
+	StartedAt time.Time      `json:"started_at"`
+}
+
+// uploaded returns how many bytes of the file the completed parts hold
+func (p *partUpload) uploaded() int64 {
+	var n int64
+	for _, part := range p.Parts {
+		n += part.Size
+	}
+	return n
+}
+
+// DefaultPartsDir returns the default location of the part store
+func DefaultPartsDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "uploads"), nil
+}
+
+// NewPartStore opens the part store kept in dir
+func NewPartStore(dir string) (*PartStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create part store directory: %w", err)
+	}
+	return &PartStore{dir: dir}, nil
+}
+
+// path returns the file of the upload of key
+func (s *PartStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the upload of key in progress, if any
+func (s *PartStore) get(key string) (*partUpload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var upload partUpload
+	if err := json.Unmarshal(data, &upload); err != nil || upload.Key != key {
+		return nil, false
+	}
+	return &upload, true
+}
+
+// put records an upload, replacing the file in one step so a crash leaves the last state whole
+func (s *PartStore) put(upload *partUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload state: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(upload.Key)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		os.Remove(path + ".tmp")
+		return fmt.Errorf("failed to replace upload state: %w", err)
+	}
+	return nil
+}
+
+// remove forgets the upload of key
+func (s *PartStore) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		log.Warn().Err(err).Str("key", key).Msg("Failed to remove upload state")
+	}
+}
+
+// list returns every upload recorded
+func (s *PartStore) list() []partUpload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, _ := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	uploads := make([]partUpload, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var upload partUpload
+		if json.Unmarshal(data, &upload) == nil {
+			uploads = append(uploads, upload)
+		}
+	}
+	return uploads
+}
+
+// SetPartStore sets where multipart uploads are recorded, so large files are uploaded in
+// parts that survive a restart. Without one, every file is uploaded whole.
+func (u *Uploader) SetPartStore(store *PartStore) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.parts = store
+}
+
+// partStore returns the part store, nil when none is set
+func (u *Uploader) partStore() *PartStore {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.parts
+}
+
+// partSizeFor returns the part size of a file, growing past the configured one for files
+// that would otherwise need more parts than the storage allows
+func (u *Uploader) partSizeFor(size int64) int64 {
+	partSize := u.partSize
+	if count := (size + partSize - 1) / partSize; count > storage.MaxParts {
+		partSize = (size + storage.MaxParts - 1) / storage.MaxParts
+	}
+	return partSize
+}
+
+// uploadParts uploads a file larger than a part as a multipart upload recorded in the part
+// store, continuing the one a previous attempt left for the same content. A failed attempt
+// keeps its parts for the next one; an upload the storage no longer knows starts over.
+func (u *Uploader) uploadParts(ctx context.Context, task UploadTask, file *os.File, size int64, hash string, transfer *progress.File) (string, error) {
+	parts := u.partStore()
+	multipart, ok := u.store.(storage.MultipartUploader)
+	if parts == nil || !ok || size <= u.partSize {
+		return "", errNotMultipart
+	}
+
+	upload, ok := parts.get(task.Key)
+	if ok && (upload.Hash != hash || upload.Size != size) {
+		// The file changed since, so its parts are of no use
+		u.abortUpload(ctx, multipart, *upload)
+		ok = false
+	}
+	if !ok {
+		uploadID, err := multipart.CreateMultipart(ctx, task.Key, task.Metadata)
+		if errors.Is(err, storage.ErrMultipartUnsupported) {
+			return "", errNotMultipart
+		}
+		if err != nil {
+			return "", err
+		}
+		upload = &partUpload{Key: task.Key, UploadID: uploadID, Hash: hash, Size: size, PartSize: u.partSizeFor(size), StartedAt: time.Now()}
+		if err := parts.put(upload); err != nil {
+			return "", err
+		}
+	}
+
+	offset := upload.uploaded()
+	log.Info().
+		Str("path", task.FilePath).
+		Str("key", task.Key).
+		Int64("size", size).
+		Int64("resumed_at", offset).
+		Msg("Uploading file in parts")
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to seek file: %w", err)
+	}
+	for offset < size {
+		partSize := min(upload.PartSize, size-offset)
+		number := len(upload.Parts) + 1
+		reader := transfer.Reader(newThrottledReader(io.LimitReader(file, partSize), u.throttle))
+		etag, err := multipart.UploadPart(ctx, task.Key, upload.UploadID, number, reader, partSize)
+		if err != nil {
+			return "", u.partFailed(parts, task.Key, err)
+		}
+		upload.Parts = append(upload.Parts, storage.Part{Number: number, ETag: etag, Size: partSize})
+		if err := parts.put(upload); err != nil {
+			return "", err
+		}
+		offset += partSize
+	}
+
+	versionID, err := multipart.CompleteMultipart(ctx, task.Key, upload.UploadID, upload.Parts)
+	if err != nil {
+		return "", u.partFailed(parts, task.Key, err)
+	}
+	parts.remove(task.Key)
+	return versionID, nil
+}
+
+// partFailed returns the error of a multipart upload request, forgetting the upload when the
+// storage no longer knows it so the next attempt starts over
+func (u *Uploader) partFailed(parts *PartStore, key string, err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		parts.remove(key)
+	}
+	return err
+}
+
+// abortUpload aborts a multipart upload and forgets it, leaving it for AbortStaleUploads
+// when the storage cannot be reached
+func (u *Uploader) abortUpload(ctx context.Context, multipart storage.MultipartUploader, upload partUpload) {
+	err := multipart.AbortMultipart(ctx, upload.Key, upload.UploadID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Warn().Err(err).Str("key", upload.Key).Str("upload", upload.UploadID).Msg("Failed to abort multipart upload")
+	}
+	u.partStore().remove(upload.Key)
+}
+
+// AbortStaleUploads aborts the multipart uploads of files under prefix left unfinished for
+// longer than maxAge, whose parts would otherwise take up space forever, and forgets the
+// recorded uploads the storage no longer knows. It returns how many uploads were aborted.
+func (u *Uploader) AbortStaleUploads(ctx context.Context, prefix string, maxAge time.Duration) (int, error) {
+	multipart, ok := u.store.(storage.MultipartUploader)
+	if !ok {
+		return 0, nil
+	}
+	listedAt := time.Now()
+	uploads, err := multipart.ListMultipart(ctx, prefix)
+	if errors.Is(err, storage.ErrMultipartUnsupported) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := listedAt.Add(-maxAge)
+	known := make(map[string]bool, len(uploads))
+	aborted := 0
+	for _, upload := range uploads {
+		if upload.Initiated.IsZero() || upload.Initiated.After(cutoff) {
+			known[upload.ID] = true
+			continue
+		}
+		if err := multipart.AbortMultipart(ctx, upload.Key, upload.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Warn().Err(err).Str("key", upload.Key).Str("upload", upload.ID).Msg("Failed to abort stale multipart upload")
+			continue
+		}
+		aborted++
+	}
+
+	if parts := u.partStore(); parts != nil {
+		for _, upload := range parts.list() {
+			// Uploads started after the listing are not in it yet
+			if strings.HasPrefix(upload.Key, prefix) && !known[upload.UploadID] && upload.StartedAt.Before(listedAt) {
+				parts.remove(upload.Key)
+			}
+		}
+	}
+	return aborted, nil
+}
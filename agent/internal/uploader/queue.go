@@ -0,0 +1,181 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// The queue holds at most one task per key. A file that changes again before its upload
+// starts replaces the waiting task instead of queuing another, and a retry is dropped once a
+// newer change of the file was queued, so an older version never lands over a newer one.
+//
+// The channel only counts the tasks waiting: a worker receiving from it runs whichever
+// waiting task the scheduling weights rate highest, see take. A task counts as waiting once
+// it is sent, or about to be; those still waiting for room in the channel are left alone, so
+// a worker always finds one to run.
+
+// ErrQueueFull is returned when a file is queued while the queue is full and the workers are
+// not taking tasks, because the uploader is paused or stopped. The file is queued again by
+// the next sync of its folder.
+var ErrQueueFull = errors.New("upload queue is full")
+
+// queueFullCheck is how often a file waiting for room in the queue checks that the workers
+// still take tasks
+const queueFullCheck = time.Second
+
+// waitRoom sends task to the full queue once a worker makes room. It gives up with
+// ErrQueueFull when the workers stop taking tasks, or with the error of ctx.
+func (u *Uploader) waitRoom(ctx context.Context, task UploadTask) error {
+	ticker := time.NewTicker(queueFullCheck)
+	defer ticker.Stop()
+	for {
+		sent, err := u.trySend(task)
+		if sent || err != nil {
+			return err
+		}
+		select {
+		case <-u.room:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// trySend sends task to the queue if it has room, failing with ErrQueueFull when the
+// workers do not take tasks. Stop closes the queue while holding mutex, so the send is safe.
+// The task becomes one for workers to take along with the send.
+func (u *Uploader) trySend(task UploadTask) (bool, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.netMu.Lock()
+	paused := u.resume != nil
+	u.netMu.Unlock()
+	if !u.running || paused {
+		return false, ErrQueueFull
+	}
+
+	u.queueMu.Lock()
+	defer u.queueMu.Unlock()
+	select {
+	case u.taskQueue <- task:
+		delete(u.unsent, task.Key)
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// madeRoom wakes a file waiting for room in the queue, after a worker took a task
+func (u *Uploader) madeRoom() {
+	select {
+	case u.room <- struct{}{}:
+	default:
+	}
+}
+
+// unqueue uncounts the task waiting for key, which could not be sent to the queue
+func (u *Uploader) unqueue(key string) {
+	u.queueMu.Lock()
+	defer u.queueMu.Unlock()
+	delete(u.unsent, key)
+	if task, ok := u.pending[key]; ok {
+		delete(u.pending, key)
+		u.Progress().Add(-1, -task.size)
+	}
+}
+
+// QueueLength returns how many uploads wait in the queue and how many it holds at most
+func (u *Uploader) QueueLength() (int, int) {
+	return len(u.taskQueue), cap(u.taskQueue)
+}
+
+// enqueueLocked records task as the one to run for its key and reports whether the caller
+// must send it to the queue. When a task for the key is already waiting the newer of the two
+// takes its place, the other is dropped, and nothing is sent. queueMu must be held.
+func (u *Uploader) enqueueLocked(task UploadTask) bool {
+	if u.pending == nil {
+		u.pending = make(map[string]UploadTask)
+		u.unsent = make(map[string]bool)
+		u.latest = make(map[string]uint64)
+	}
+	if task.seq < u.latest[task.Key] {
+		u.dropLocked(task)
+		return false
+	}
+	u.latest[task.Key] = task.seq
+
+	queued, ok := u.pending[task.Key]
+	u.pending[task.Key] = task
+	if !ok {
+		return true
+	}
+	u.dropLocked(queued)
+	return false
+}
+
+// dropLocked uncounts a task superseded by a newer change of its file. queueMu must be held.
+func (u *Uploader) dropLocked(task UploadTask) {
+	u.Progress().Add(-1, -task.size)
+	log.Debug().Str("path", task.FilePath).Str("key", task.Key).Msg("Upload superseded by a newer change")
+}
+
+// take returns the task to run for one received from the queue: of the tasks waiting, the
+// one the scheduling weights rate highest, the first queued among equals. Each is the newest
+// change of its file. Rating every waiting task costs little next to the upload that follows.
+func (u *Uploader) take(task UploadTask) UploadTask {
+	u.queueMu.Lock()
+	defer u.queueMu.Unlock()
+
+	now := time.Now()
+	var best UploadTask
+	var bestScore float64
+	found := false
+	for key, waiting := range u.pending {
+		if u.unsent[key] {
+			continue
+		}
+		rating := score(u.scheduling, waiting, now)
+		if !found || rating > bestScore || (rating == bestScore && waiting.seq < best.seq) {
+			best, bestScore, found = waiting, rating, true
+		}
+	}
+	if found {
+		delete(u.pending, best.Key)
+		return best
+	}
+	return task
+}
+
+// requeue puts a task taken from the queue back, waiting for room, unless a newer change of
+// its file was queued since. It returns false once the uploader stops.
+func (u *Uploader) requeue(task UploadTask) bool {
+	task.queuedAt = time.Now()
+	u.queueMu.Lock()
+	send := u.enqueueLocked(task)
+	u.queueMu.Unlock()
+	if !send {
+		return true
+	}
+
+	select {
+	case u.taskQueue <- task:
+		return true
+	case <-u.ctx.Done():
+		return false
+	}
+}
+
+// settle forgets the newest change queued for the key of a task that left the uploader, once
+// no newer one is queued
+func (u *Uploader) settle(task UploadTask) {
+	u.queueMu.Lock()
+	defer u.queueMu.Unlock()
+
+	if _, waiting := u.pending[task.Key]; !waiting && u.latest[task.Key] == task.seq {
+		delete(u.latest, task.Key)
+	}
+}
@@ -0,0 +1,41 @@
+package uploader
+
+import (
+	"math"
+	"time"
+
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+)
+
+// recencyHalfLife is how long after its last change the recency of a file counts half as much
+const recencyHalfLife = 10 * time.Minute
+
+// sizeScale is the log2 of the size, 1 TiB, from which files rate lowest for their size
+const sizeScale = 40
+
+// score rates how soon a task should start, higher first. Its size rates from 1 when empty
+// down to 0 at 1 TiB, each doubling costing the same; its recency from 1 when just modified,
+// halving every recencyHalfLife; its folder 1 at priority 1, the highest, 1/2 at 2 and so on.
+func score(weights commonconfig.SchedulingConfig, task UploadTask, now time.Time) float64 {
+	size := 1 - math.Min(math.Log2(float64(task.size)+1), sizeScale)/sizeScale
+
+	var recency float64
+	if !task.modTime.IsZero() {
+		age := max(now.Sub(task.modTime), 0)
+		recency = math.Exp2(-age.Seconds() / recencyHalfLife.Seconds())
+	}
+
+	folder := 1.0
+	if task.Priority > 1 {
+		folder = 1 / float64(task.Priority)
+	}
+
+	return weights.SizeWeight*size + weights.RecencyWeight*recency + weights.FolderWeight*folder
+}
+
+// SetScheduling changes the weights ordering the uploads waiting for a worker
+func (u *Uploader) SetScheduling(cfg commonconfig.SchedulingConfig) {
+	u.queueMu.Lock()
+	defer u.queueMu.Unlock()
+	u.scheduling = cfg
+}
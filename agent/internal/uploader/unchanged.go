@@ -0,0 +1,62 @@
+package uploader
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/martinshumberto/sync-manager/agent/internal/priority"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// remoteUnchanged reports whether storage already holds the content of a file checked with
+// CheckRemote, so it is not uploaded again. The hash recorded with the object is compared
+// first, the file being hashed again when the object was hashed with another algorithm;
+// objects uploaded by other tools are compared by the MD5 or CRC32C the backend computed,
+// which reads the file once more. Anything else uploads the file.
+func (u *Uploader) remoteUnchanged(ctx context.Context, task UploadTask, file *os.File, size int64, localHash string) bool {
+	info, metadata, err := u.store.GetFileInfo(ctx, task.Key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Debug().Err(err).Str("key", task.Key).Msg("Failed to check the remote copy, uploading the file")
+		}
+		return false
+	}
+	if info.Size != size {
+		return false
+	}
+	if remote := checksum.FromMetadata(metadata); remote != "" {
+		if alg := checksum.Of(remote); alg != checksum.Of(localHash) {
+			var err error
+			if localHash, err = checksum.File(alg, file, size, priority.HashReader); err != nil {
+				return false
+			}
+		}
+		return remote == localHash
+	}
+
+	var sum hash.Hash
+	remote := info.MD5
+	switch {
+	case info.MD5 != "":
+		sum = md5.New()
+	case info.CRC32C != "":
+		sum, remote = crc32.New(crc32.MakeTable(crc32.Castagnoli)), info.CRC32C
+	default:
+		return false
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	if _, err := io.Copy(sum, file); err != nil {
+		return false
+	}
+	return hex.EncodeToString(sum.Sum(nil)) == remote
+}
@@ -2,19 +2,28 @@ package uploader
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/martinshumberto/sync-manager/agent/internal/config"
-	"github.com/martinshumberto/sync-manager/agent/internal/storage"
+	"github.com/martinshumberto/sync-manager/agent/internal/index"
+	"github.com/martinshumberto/sync-manager/agent/internal/power"
+	"github.com/martinshumberto/sync-manager/agent/internal/priority"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/cloudfile"
 	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/sparse"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/martinshumberto/sync-manager/common/telemetry"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // UploadTask represents a file to be uploaded
@@ -22,10 +31,28 @@ type UploadTask struct {
 	FilePath    string            // Full path to the file on disk
 	Key         string            // Remote key for storage
 	FolderID    string            // ID of the synced folder
-	Priority    int               // Priority level (higher means more important)
+	Priority    int               // Priority of its folder, lower numbers upload first as with SyncFolder.Priority
 	Metadata    map[string]string // Additional metadata for the file
 	RetryCount  int               // Number of times this task has been retried
 	LastAttempt time.Time         // When the task was last attempted
+	Base        Base              // Copy already in storage, so a file that only grew uploads the new bytes
+	// CheckRemote skips the upload when storage already holds the same content, as after the
+	// index that recorded the upload was lost
+	CheckRemote bool
+	Checksum    checksum.Algorithm // Algorithm hashing the file, checksum.SHA256 when empty
+
+	size        int64             // Size of the file when it was queued
+	modTime     time.Time         // Modification time of the file when it was queued
+	seq         uint64            // Order in which the change was queued, newer changes are higher
+	queuedAt    time.Time         // When the task entered the queue
+	spanContext trace.SpanContext // Span that queued the task, parent of the upload spans
+}
+
+// Base is the content of a file last uploaded to storage. When the file only grew since,
+// and the storage can append, just the bytes after Size are uploaded.
+type Base struct {
+	Size int64  // Bytes in storage
+	Hash string // Hash of those bytes, as the index records it
 }
 
 // UploadResult represents the result of an upload operation
@@ -34,17 +61,46 @@ type UploadResult struct {
 	Success   bool       // Whether the upload was successful
 	Error     error      // Error if any occurred
 	VersionID string     // Version ID from the storage provider
-	Hash      string     // SHA256 hash of the file
+	Hash      string     // Hash of the file, tagged with its algorithm as by checksum.Algorithm.Tag
 	Size      int64      // Size of the file in bytes
+	Offset    int64      // Bytes kept from the base when only the rest was appended, zero for a full upload
+	Unchanged bool       // Storage already held the content, so nothing was transferred
+
+	sent bool // The upload reached the storage, so its outcome tells how the storage copes
 }
 
+// DriveRetryInterval is how often a file waiting for an unplugged drive checks whether it is back
+const DriveRetryInterval = 5 * time.Second
+
 // Uploader handles file uploads with concurrency control and throttling
 type Uploader struct {
 	store          storage.Storage
 	taskQueue      chan UploadTask
+	room           chan struct{} // Signalled when a worker takes a task, see waitRoom
 	resultChan     chan UploadResult
 	maxConcurrency int
-	throttleBytes  int64 // bytes per second, 0 for no throttling
+	tuner          *tuner      // Adjusts maxConcurrency to the uploads in adaptive mode, nil in fixed mode
+	samples        chan sample // Finished uploads, for the tuner
+	throttleBytes  int64       // bytes per second, 0 for no throttling
+	progress       *progress.Tracker
+	progressOnce   sync.Once
+	stops          []chan struct{}            // One per running worker, closed to retire it
+	offline        bool                       // The storage is unreachable
+	restriction    power.Restriction          // Limits from the battery and metered connection policy
+	resume         chan struct{}              // Closed when transfers may continue, nil while they run
+	deferred       []UploadTask               // Files above the policy's size limit, queued again once it is lifted
+	connectivity   func(context.Context) bool // Tells whether a failed upload was caused by the network
+	files          commonconfig.FilesConfig   // Sparse file and placeholder policies and upload size limit
+	dehydrated     func(os.FileInfo) bool     // Tells whether a file is a cloud placeholder
+	parts          *PartStore                 // Multipart uploads in progress, nil to upload files whole
+	partSize       int64                      // Size of the parts of a multipart upload
+	pending        map[string]UploadTask      // Task to run for each key waiting in the queue, see enqueueLocked
+	unsent         map[string]bool            // Keys of pending tasks still waiting for room in the queue
+	scheduling     commonconfig.SchedulingConfig
+	latest         map[string]uint64 // Newest change queued for each key still in the uploader
+	sequence       uint64            // Last seq handed out
+	queueMu        sync.Mutex
+	netMu          sync.Mutex
 	workers        sync.WaitGroup
 	mutex          sync.Mutex
 	ctx            context.Context
@@ -59,11 +115,24 @@ func NewUploader(store storage.Storage, cfg interface{}) *Uploader {
 	// Use default values if not specified
 	maxConcurrency := 4
 	var throttleBytes int64 = 0
+	files := commonconfig.FilesConfig{Sparse: commonconfig.SparseTransfer}
+	scheduling := commonconfig.DefaultConfig().Scheduling
+	queueSize := commonconfig.DefaultQueueSize
+	var adaptive *tuner
 
 	// Se a configuração for do tipo commonconfig.Config
 	if commCfg, ok := cfg.(*commonconfig.Config); ok {
 		maxConcurrency = commCfg.MaxConcurrency
 		throttleBytes = commCfg.ThrottleBytes
+		files = commCfg.Files
+		scheduling = commCfg.Scheduling
+		if commCfg.Resources.QueueSize > 0 {
+			queueSize = commCfg.Resources.QueueSize
+		}
+		if commCfg.Concurrency.Mode == commonconfig.ConcurrencyAdaptive {
+			adaptive = newTuner(commCfg.Concurrency, time.Now())
+			maxConcurrency = adaptive.clamp(maxConcurrency)
+		}
 	} else if _, ok := cfg.(*config.Config); ok {
 		// Para compatibilidade com o config interno
 		// Aqui podemos adicionar lógica específica se necessário
@@ -71,10 +140,17 @@ func NewUploader(store storage.Storage, cfg interface{}) *Uploader {
 
 	return &Uploader{
 		store:          store,
-		taskQueue:      make(chan UploadTask, 1000), // Buffer up to 1000 tasks
+		taskQueue:      make(chan UploadTask, queueSize),
+		room:           make(chan struct{}, 1),
 		resultChan:     make(chan UploadResult, 100),
 		maxConcurrency: maxConcurrency,
+		tuner:          adaptive,
+		samples:        make(chan sample, 64),
 		throttleBytes:  throttleBytes,
+		files:          files,
+		scheduling:     scheduling,
+		dehydrated:     cloudfile.Dehydrated,
+		partSize:       DefaultPartSize,
 		ctx:            ctx,
 		cancel:         cancel,
 	}
@@ -93,9 +169,49 @@ func (u *Uploader) Start() {
 	log.Info().Int("workers", u.maxConcurrency).Msg("Starting uploader")
 
 	// Start worker goroutines
-	for i := 0; i < u.maxConcurrency; i++ {
+	u.resizeLocked(u.maxConcurrency)
+	go u.tune()
+}
+
+// SetMaxConcurrency changes the number of upload workers. Extra workers finish their
+// current upload before exiting, so queued tasks are never dropped.
+func (u *Uploader) SetMaxConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if n == u.maxConcurrency {
+		return
+	}
+	log.Info().Int("from", u.maxConcurrency).Int("to", n).Msg("Resizing upload workers")
+	u.maxConcurrency = n
+	if u.running {
+		u.resizeLocked(n)
+	}
+}
+
+// SetThrottle changes the configured bandwidth limit in bytes/sec, 0 for none.
+// Uploads already in progress pick up the new limit.
+func (u *Uploader) SetThrottle(bytesPerSec int64) {
+	u.netMu.Lock()
+	defer u.netMu.Unlock()
+	u.throttleBytes = bytesPerSec
+}
+
+// resizeLocked starts or retires workers until n are running. mutex must be held.
+func (u *Uploader) resizeLocked(n int) {
+	for len(u.stops) < n {
+		stop := make(chan struct{})
+		u.stops = append(u.stops, stop)
 		u.workers.Add(1)
-		go u.worker(i)
+		go u.worker(len(u.stops)-1, stop)
+	}
+	for len(u.stops) > n {
+		close(u.stops[len(u.stops)-1])
+		u.stops = u.stops[:len(u.stops)-1]
 	}
 }
 
@@ -113,21 +229,212 @@ func (u *Uploader) Stop() {
 	close(u.taskQueue)
 	u.workers.Wait()
 	close(u.resultChan)
+	u.stops = nil
 	u.running = false
 }
 
+// Progress returns the tracker counting the uploader's transfers
+func (u *Uploader) Progress() *progress.Tracker {
+	u.progressOnce.Do(func() { u.progress = progress.NewTracker() })
+	return u.progress
+}
+
+// SetOnline pauses the workers while the storage is unreachable and resumes them when it returns
+func (u *Uploader) SetOnline(online bool) {
+	u.netMu.Lock()
+	defer u.netMu.Unlock()
+
+	u.offline = !online
+	u.updateGateLocked()
+}
+
+// SetRestriction applies the power policy: pausing, throttling or holding back large files
+func (u *Uploader) SetRestriction(restriction power.Restriction) {
+	u.netMu.Lock()
+	u.restriction = restriction
+	u.updateGateLocked()
+
+	// Release the files the new limit allows
+	var ready, held []UploadTask
+	for _, task := range u.deferred {
+		if restriction.MaxFileSize > 0 && task.size > restriction.MaxFileSize {
+			held = append(held, task)
+		} else {
+			ready = append(ready, task)
+		}
+	}
+	u.deferred = held
+	u.netMu.Unlock()
+
+	if len(ready) == 0 {
+		return
+	}
+
+	// Stop closes the queue while holding the mutex, so sends are safe while running
+	u.mutex.Lock()
+	var unsent []UploadTask
+	for i, task := range ready {
+		if !u.running {
+			unsent = ready[i:]
+			break
+		}
+		task.queuedAt = time.Now()
+		u.queueMu.Lock()
+		if u.enqueueLocked(task) {
+			select {
+			case u.taskQueue <- task:
+			default:
+				delete(u.pending, task.Key)
+				unsent = append(unsent, task)
+			}
+		}
+		u.queueMu.Unlock()
+	}
+	u.mutex.Unlock()
+
+	if len(unsent) > 0 {
+		u.netMu.Lock()
+		u.deferred = append(u.deferred, unsent...)
+		u.netMu.Unlock()
+	}
+}
+
+// SetConnectivityCheck installs the check run after a failed upload. When it reports
+// the storage offline the task is put back without using up one of its retries.
+func (u *Uploader) SetConnectivityCheck(check func(context.Context) bool) {
+	u.netMu.Lock()
+	defer u.netMu.Unlock()
+	u.connectivity = check
+}
+
+// updateGateLocked opens or closes the gate the workers wait on. netMu must be held.
+func (u *Uploader) updateGateLocked() {
+	blocked := u.offline || u.restriction.Paused
+	if blocked && u.resume == nil {
+		u.resume = make(chan struct{})
+	} else if !blocked && u.resume != nil {
+		close(u.resume)
+		u.resume = nil
+	}
+}
+
+// waitResume blocks while transfers are paused, returning false if the uploader is stopped meanwhile
+func (u *Uploader) waitResume() bool {
+	u.netMu.Lock()
+	resume := u.resume
+	u.netMu.Unlock()
+
+	if resume == nil {
+		return true
+	}
+
+	select {
+	case <-resume:
+		return true
+	case <-u.ctx.Done():
+		return false
+	}
+}
+
+// deferLarge holds back a file above the policy's size limit, reporting whether it did
+func (u *Uploader) deferLarge(task UploadTask) bool {
+	u.netMu.Lock()
+	defer u.netMu.Unlock()
+
+	limit := u.restriction.MaxFileSize
+	if limit <= 0 || task.size <= limit {
+		return false
+	}
+
+	log.Debug().
+		Str("path", task.FilePath).
+		Int64("size", task.size).
+		Int64("limit", limit).
+		Msg("Deferring large file until the transfer policy allows it")
+	u.deferred = append(u.deferred, task)
+	return true
+}
+
+// throttle returns the bandwidth limit in bytes/sec, the stricter of the configured and policy limits
+func (u *Uploader) throttle() int64 {
+	u.netMu.Lock()
+	defer u.netMu.Unlock()
+
+	limit := u.throttleBytes
+	if policy := u.restriction.ThrottleBytes; policy > 0 && (limit <= 0 || policy < limit) {
+		limit = policy
+	}
+	return limit
+}
+
+// waitDrive puts back a task whose drive is not mounted after DriveRetryInterval, without
+// using up one of its retries. It returns false if the uploader is stopped meanwhile.
+func (u *Uploader) waitDrive(task UploadTask, err error) bool {
+	log.Debug().Err(err).Str("path", task.FilePath).Msg("Drive of the upload is not mounted, waiting for it")
+	select {
+	case <-time.After(DriveRetryInterval):
+		return u.requeue(task)
+	case <-u.ctx.Done():
+		return false
+	}
+}
+
+// lostConnection reports whether the storage is unreachable after a failed upload
+func (u *Uploader) lostConnection() bool {
+	u.netMu.Lock()
+	check := u.connectivity
+	u.netMu.Unlock()
+
+	return check != nil && !check(u.ctx)
+}
+
 // QueueUpload adds a file to the upload queue
 func (u *Uploader) QueueUpload(task UploadTask) error {
-	select {
-	case u.taskQueue <- task:
+	return u.QueueUploadContext(context.Background(), task)
+}
+
+// QueueUploadContext adds a file to the upload queue, tracing the upload under the span in ctx
+func (u *Uploader) QueueUploadContext(ctx context.Context, task UploadTask) error {
+	task.spanContext = trace.SpanContextFromContext(ctx)
+	task.queuedAt = time.Now()
+
+	if info, err := os.Stat(task.FilePath); err == nil {
+		task.size = info.Size()
+		task.modTime = info.ModTime()
+	}
+
+	// Count the file before a worker can pick it up
+	u.Progress().Add(1, task.size)
+
+	u.queueMu.Lock()
+	u.sequence++
+	task.seq = u.sequence
+	if !u.enqueueLocked(task) {
+		u.queueMu.Unlock()
 		log.Debug().
 			Str("path", task.FilePath).
 			Str("key", task.Key).
-			Msg("Queued file for upload")
+			Msg("Replaced queued upload with the newer change")
 		return nil
+	}
+
+	select {
+	case u.taskQueue <- task:
+		u.queueMu.Unlock()
 	default:
-		return fmt.Errorf("upload queue is full")
+		// The scan waits for the workers to make room rather than holding every file in memory
+		u.unsent[task.Key] = true
+		u.queueMu.Unlock()
+		if err := u.waitRoom(ctx, task); err != nil {
+			u.unqueue(task.Key)
+			return err
+		}
 	}
+	log.Debug().
+		Str("path", task.FilePath).
+		Str("key", task.Key).
+		Msg("Queued file for upload")
+	return nil
 }
 
 // Results returns the channel where upload results are sent
@@ -135,12 +442,18 @@ func (u *Uploader) Results() <-chan UploadResult {
 	return u.resultChan
 }
 
-// QueueFile enfileira um arquivo para upload com base em seu caminho e pasta raiz
-func (u *Uploader) QueueFile(filePath, folderPath string) error {
+// QueueFile enfileira um arquivo para upload com base em seu caminho e pasta raiz.
+// remotePrefix é o prefixo da pasta no armazenamento e rootPrefix identifica a raiz dentro
+// da pasta lógica, vazio para a raiz principal.
+func (u *Uploader) QueueFile(filePath, folderPath, remotePrefix, rootPrefix string) error {
 	// Verificar se o uploader está rodando
 	if !u.running {
 		return fmt.Errorf("uploader is not running")
 	}
+	// Sem o prefixo da pasta, arquivos de pastas diferentes teriam a mesma chave
+	if remotePrefix == "" {
+		return fmt.Errorf("remote prefix is required")
+	}
 
 	// Obter o caminho relativo do arquivo em relação à pasta
 	relPath, err := filepath.Rel(folderPath, filePath)
@@ -148,9 +461,10 @@ func (u *Uploader) QueueFile(filePath, folderPath string) error {
 		return fmt.Errorf("failed to get relative path: %w", err)
 	}
 
-	// Construir a chave de armazenamento
-	// Usamos o folderPath como base para diferenciar diferentes pastas sincronizadas
-	storageKey := filepath.ToSlash(relPath)
+	// Construir a chave de armazenamento: <prefixo remoto>/<caminho relativo>
+	// Chaves sempre em NFC para que nomes vindos do macOS (NFD) não dupliquem arquivos
+	// Arquivos de raízes extras ficam sob o prefixo da raiz
+	storageKey := index.NormalizeKey(path.Join(remotePrefix, rootPrefix, filepath.ToSlash(relPath)))
 
 	// Criar a tarefa de upload
 	task := UploadTask{
@@ -163,25 +477,52 @@ func (u *Uploader) QueueFile(filePath, folderPath string) error {
 
 	// Adicionar metadados básicos
 	task.Metadata["source_folder"] = folderPath
+	if rootPrefix != "" {
+		task.Metadata["source_prefix"] = rootPrefix
+	}
 	task.Metadata["upload_time"] = time.Now().Format(time.RFC3339)
 
 	// Enfileirar a tarefa
 	return u.QueueUpload(task)
 }
 
-// worker processes upload tasks
-func (u *Uploader) worker(id int) {
+// worker processes upload tasks until the queue is closed or stop is closed
+func (u *Uploader) worker(id int, stop <-chan struct{}) {
 	defer u.workers.Done()
 
 	log.Debug().Int("worker_id", id).Msg("Upload worker started")
 
-	for task := range u.taskQueue {
+	for task, ok := u.next(stop); ok; task, ok = u.next(stop) {
+		task = u.take(task)
 		select {
 		case <-u.ctx.Done():
 			return
 		default:
+			if !u.waitResume() {
+				return
+			}
+			if u.deferLarge(task) {
+				continue
+			}
+			if err := storage.Present(u.store, task.Key); err != nil {
+				if !u.waitDrive(task, err) {
+					return
+				}
+				continue
+			}
+
 			result := u.processUpload(task)
 
+			// Failures caused by a dropped connection wait for it to return instead of counting as retries
+			if !result.Success && u.lostConnection() {
+				log.Debug().Str("path", task.FilePath).Msg("Upload interrupted by network loss, waiting to retry")
+				if !u.requeue(task) {
+					return
+				}
+				continue
+			}
+			u.observe(result)
+
 			// Send result
 			select {
 			case u.resultChan <- result:
@@ -190,8 +531,22 @@ func (u *Uploader) worker(id int) {
 				return
 			}
 
+			// Files left out by policy fail the same way until they change, and removed files
+			// never come back by waiting, so neither is retried
+			retry := !result.Success && task.RetryCount < 3 && !Skipped(result.Error) && !Vanished(result.Error)
+			if Vanished(result.Error) {
+				// Nothing left to transfer, so the file leaves the totals instead of counting as failed
+				log.Debug().Str("path", task.FilePath).Str("key", task.Key).Msg("File removed before upload, dropping task")
+				u.Progress().Add(-1, -task.size)
+			} else if !result.Success && !retry {
+				u.Progress().Drop(1, task.size)
+			}
+			if !retry {
+				u.settle(task)
+			}
+
 			// If the upload failed, retry it with exponential backoff
-			if !result.Success && task.RetryCount < 3 {
+			if retry {
 				backoff := time.Duration(1<<task.RetryCount) * time.Second
 				task.RetryCount++
 				task.LastAttempt = time.Now()
@@ -205,11 +560,8 @@ func (u *Uploader) worker(id int) {
 				// Wait for backoff period, but respect context cancellation
 				select {
 				case <-time.After(backoff):
-					// Try again
-					select {
-					case u.taskQueue <- task:
-						// Re-queued
-					case <-u.ctx.Done():
+					// Try again, unless the file changed again meanwhile
+					if !u.requeue(task) {
 						return
 					}
 				case <-u.ctx.Done():
@@ -222,15 +574,50 @@ func (u *Uploader) worker(id int) {
 	log.Debug().Int("worker_id", id).Msg("Upload worker stopped")
 }
 
+// next waits for a task, returning false once the queue is closed or the worker is retired
+func (u *Uploader) next(stop <-chan struct{}) (UploadTask, bool) {
+	select {
+	case <-stop:
+		return UploadTask{}, false
+	case task, ok := <-u.taskQueue:
+		u.madeRoom()
+		return task, ok
+	}
+}
+
 // processUpload handles a single upload task
-func (u *Uploader) processUpload(task UploadTask) UploadResult {
-	result := UploadResult{
+func (u *Uploader) processUpload(task UploadTask) (result UploadResult) {
+	result = UploadResult{
 		Task:    task,
 		Success: false,
 	}
 
+	// Every attempt gets its own span so retries show up separately
+	ctx, span := telemetry.Tracer().Start(trace.ContextWithSpanContext(u.ctx, task.spanContext), "upload.file",
+		trace.WithAttributes(
+			telemetry.FolderIDKey.String(task.FolderID),
+			telemetry.FileKey.String(task.Key),
+			telemetry.FileSizeKey.Int64(task.size),
+			telemetry.AttemptKey.Int(task.RetryCount+1),
+		))
+	defer func() { telemetry.End(span, result.Error) }()
+
+	if !task.queuedAt.IsZero() {
+		_, wait := telemetry.Tracer().Start(ctx, "upload.queue_wait", trace.WithTimestamp(task.queuedAt))
+		wait.End()
+	}
+
+	if err := u.checkPlaceholder(task); err != nil {
+		result.Error = err
+		return result
+	}
+
 	// Check if file exists
 	file, err := os.Open(task.FilePath)
+	if os.IsNotExist(err) {
+		result.Error = fmt.Errorf("%w: %s", ErrFileVanished, task.FilePath)
+		return result
+	}
 	if err != nil {
 		result.Error = fmt.Errorf("failed to open file: %w", err)
 		return result
@@ -250,17 +637,9 @@ func (u *Uploader) processUpload(task UploadTask) UploadResult {
 		return result
 	}
 
-	// Calculate hash
-	hash, err := calculateSHA256(file)
+	sparseFile, err := u.checkFile(task, fileInfo)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to calculate hash: %w", err)
-		return result
-	}
-	result.Hash = hash
-
-	// Reset file position
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		result.Error = fmt.Errorf("failed to reset file position: %w", err)
+		result.Error = err
 		return result
 	}
 
@@ -268,28 +647,78 @@ func (u *Uploader) processUpload(task UploadTask) UploadResult {
 	fileSize := fileInfo.Size()
 	result.Size = fileSize
 
+	// Calculate hash, along with the hash of the bytes the base may still hold
+	_, hashSpan := telemetry.Tracer().Start(ctx, "upload.hash")
+	prefixSize := int64(0)
+	if task.Base.Size > 0 && task.Base.Size < fileSize {
+		prefixSize = task.Base.Size
+	}
+	hash, prefixHash, err := calculateHash(file, fileSize, prefixSize, task.Checksum)
+	telemetry.End(hashSpan, err)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to calculate hash: %w", err)
+		return result
+	}
+	result.Hash = hash
+
+	// A file whose first bytes are still the base only had data appended
+	offset := int64(0)
+	if prefixSize > 0 && prefixHash == task.Base.Hash {
+		offset = prefixSize
+	}
+
 	if task.Metadata == nil {
 		task.Metadata = make(map[string]string)
 	}
 	task.Metadata["content_type"] = detectContentType(task.FilePath)
-	task.Metadata["hash_sha256"] = hash
+	checksum.SetMetadata(task.Metadata, hash)
 	task.Metadata["size"] = fmt.Sprintf("%d", fileSize)
 	task.Metadata["modified_time"] = fileInfo.ModTime().UTC().Format(time.RFC3339)
+	if sparseFile {
+		task.Metadata[sparse.MetadataKey] = "true"
+	}
 
-	// Create reader with throttling if needed
-	var reader io.Reader = file
-	if u.throttleBytes > 0 {
-		reader = newThrottledReader(file, u.throttleBytes)
+	if task.CheckRemote && offset == 0 && u.remoteUnchanged(ctx, task, file, fileSize, hash) {
+		log.Info().
+			Str("path", task.FilePath).
+			Str("key", task.Key).
+			Msg("Storage already holds the file, not uploading it again")
+		u.Progress().Start(task.Key, task.size).Finish(nil)
+		result.Success = true
+		result.Unchanged = true
+		return result
 	}
 
-	// Upload the file
-	log.Info().
-		Str("path", task.FilePath).
-		Str("key", task.Key).
-		Int64("size", fileSize).
-		Msg("Uploading file")
+	transfer := u.Progress().Start(task.Key, task.size)
+	transferCtx, transferSpan := telemetry.Tracer().Start(ctx, "upload.transfer")
+	result.sent = true
+
+	versionID, err := "", errNotAppended
+	if offset > 0 {
+		versionID, err = u.appendFile(transferCtx, task, file, offset, fileSize, transfer)
+		if err == nil {
+			result.Offset = offset
+		}
+	}
+	if errors.Is(err, errNotAppended) {
+		versionID, err = u.uploadParts(transferCtx, task, file, fileSize, hash, transfer)
+	}
+	if errors.Is(err, errNotMultipart) {
+		// Upload the file
+		log.Info().
+			Str("path", task.FilePath).
+			Str("key", task.Key).
+			Int64("size", fileSize).
+			Msg("Uploading file")
 
-	versionID, err := u.store.UploadFile(u.ctx, task.Key, reader, task.Metadata)
+		if _, err = file.Seek(0, io.SeekStart); err == nil {
+			// Throttle the reader, following limit changes while the upload runs
+			reader := transfer.Reader(newThrottledReader(file, u.throttle))
+			versionID, err = u.store.UploadFile(transferCtx, task.Key, reader, task.Metadata)
+		}
+	}
+	telemetry.End(transferSpan, err)
+	transfer.Finish(err)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to upload file: %w", err)
 		return result
@@ -308,14 +737,26 @@ func (u *Uploader) processUpload(task UploadTask) UploadResult {
 	return result
 }
 
-// calculateSHA256 calculates the SHA256 hash of a file
-func calculateSHA256(file *os.File) (string, error) {
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
+// calculateHash calculates the hash of the size bytes of a file with alg and, in the same read,
+// the hash of its first prefixSize bytes, empty when prefixSize is zero. Without a prefix a
+// large file may be hashed by several workers, see checksum.File.
+func calculateHash(file *os.File, size, prefixSize int64, alg checksum.Algorithm) (string, string, error) {
+	if prefixSize == 0 {
+		hash, err := checksum.File(alg, file, size, priority.HashReader)
+		return hash, "", err
 	}
 
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	reader := priority.HashReader(io.NewSectionReader(file, 0, size))
+	hash := alg.New()
+	if _, err := io.CopyN(hash, reader, prefixSize); err != nil {
+		return "", "", err
+	}
+	prefix := alg.Tag(hash.Sum(nil))
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", "", err
+	}
+
+	return alg.Tag(hash.Sum(nil)), prefix, nil
 }
 
 // detectContentType tries to detect the content type of a file
@@ -359,16 +800,17 @@ func detectContentType(filePath string) string {
 // ThrottledReader wraps an io.Reader with rate limiting
 type throttledReader struct {
 	reader        io.Reader
+	limit         func() int64 // Current limit in bytes/sec, 0 for none
 	bytesPerSec   int64
 	bytesThisSec  int64
 	lastTimestamp time.Time
 	mu            sync.Mutex
 }
 
-func newThrottledReader(reader io.Reader, bytesPerSec int64) *throttledReader {
+func newThrottledReader(reader io.Reader, limit func() int64) *throttledReader {
 	return &throttledReader{
 		reader:        reader,
-		bytesPerSec:   bytesPerSec,
+		limit:         limit,
 		lastTimestamp: time.Now(),
 	}
 }
@@ -377,6 +819,11 @@ func (t *throttledReader) Read(p []byte) (n int, err error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	t.bytesPerSec = t.limit()
+	if t.bytesPerSec <= 0 {
+		return t.reader.Read(p)
+	}
+
 	// If we've read too much this second, sleep
 	now := time.Now()
 	elapsed := now.Sub(t.lastTimestamp)
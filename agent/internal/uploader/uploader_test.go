@@ -1,12 +1,28 @@
 package uploader
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
-	"github.com/martinshumberto/sync-manager/agent/internal/storage"
+	"github.com/martinshumberto/sync-manager/agent/internal/power"
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/cloudfile"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/sparse"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/martinshumberto/sync-manager/common/telemetry"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // mockStorage implements the Storage interface for testing
@@ -32,6 +48,10 @@ func (m *mockStorage) FileExists(ctx context.Context, key string) (bool, error)
 	return true, nil
 }
 
+func (m *mockStorage) GetFileInfo(ctx context.Context, key string) (storage.FileInfo, map[string]string, error) {
+	return storage.FileInfo{Key: key}, map[string]string{}, nil
+}
+
 // GetProvider returns the storage provider type
 func (m *mockStorage) GetProvider() storage.StorageProvider {
 	return storage.ProviderLocal
@@ -73,6 +93,195 @@ func TestUploader_StartStop(t *testing.T) {
 	assert.False(t, uploader.running)
 }
 
+// readingStorage consumes uploads so the transferred bytes are counted
+type readingStorage struct {
+	mockStorage
+}
+
+func (r *readingStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	_, err := io.Copy(io.Discard, reader)
+	return "v1", err
+}
+
+func TestUploader_Progress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello world"), 0644))
+
+	uploader := NewUploaderWithConfig(&readingStorage{}, 1, 0)
+	uploader.Start()
+	defer uploader.Stop()
+
+	assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: path, Key: "docs/a.txt"}))
+	result := <-uploader.Results()
+	assert.True(t, result.Success)
+
+	snap := uploader.Progress().Snapshot()
+	assert.Equal(t, 1, snap.FilesTotal)
+	assert.Equal(t, 1, snap.FilesDone)
+	assert.Equal(t, int64(11), snap.BytesTotal)
+	assert.Equal(t, int64(11), snap.BytesDone)
+	assert.True(t, snap.Idle())
+}
+
+func TestUploader_SupersedesQueuedChanges(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+	}
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	uploader := NewUploaderWithConfig(store, 1, 0)
+
+	// A file changed three times before its upload starts is uploaded once, as last queued
+	for _, version := range []string{"1", "2", "3"} {
+		assert.NoError(t, uploader.QueueUpload(UploadTask{
+			FilePath: filepath.Join(dir, "a.txt"), Key: "docs/a.txt", Metadata: map[string]string{"version": version},
+		}))
+	}
+	assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: filepath.Join(dir, "b.txt"), Key: "docs/b.txt"}))
+	assert.Len(t, uploader.taskQueue, 2)
+	assert.Equal(t, 2, uploader.Progress().Snapshot().FilesTotal)
+
+	// A retry of an older change is dropped, as a newer one is queued
+	uploader.queueMu.Lock()
+	assert.False(t, uploader.enqueueLocked(UploadTask{FilePath: filepath.Join(dir, "a.txt"), Key: "docs/a.txt", seq: 1}))
+	assert.Equal(t, "3", uploader.pending["docs/a.txt"].Metadata["version"])
+	uploader.queueMu.Unlock()
+
+	uploader.Start()
+	defer uploader.Stop()
+	for range 2 {
+		result := <-uploader.Results()
+		assert.True(t, result.Success)
+	}
+	_, metadata, err := store.GetFileInfo(context.Background(), "docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", metadata["version"])
+}
+
+func TestUploader_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	path := filepath.Join(t.TempDir(), "a.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello world"), 0644))
+
+	uploader := NewUploaderWithConfig(&readingStorage{}, 1, 0)
+	ctx, parent := telemetry.Tracer().Start(context.Background(), "sync.queue")
+	assert.NoError(t, uploader.QueueUploadContext(ctx, UploadTask{FilePath: path, Key: "docs/a.txt", RetryCount: 1}))
+	parent.End()
+
+	result := uploader.processUpload(uploader.take(<-uploader.taskQueue))
+	assert.True(t, result.Success)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	assert.Len(t, spans, 5)
+
+	// The attempt hangs off the span that queued it, with one child per stage
+	upload := spans["upload.file"]
+	assert.Equal(t, parent.SpanContext().SpanID(), upload.Parent().SpanID())
+	assert.Contains(t, upload.Attributes(), telemetry.AttemptKey.Int(2))
+	assert.Contains(t, upload.Attributes(), telemetry.FileSizeKey.Int64(11))
+	for _, name := range []string{"upload.queue_wait", "upload.hash", "upload.transfer"} {
+		assert.Equal(t, upload.SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+	}
+}
+
+// flakyStorage fails uploads until the network is back
+type flakyStorage struct {
+	mockStorage
+	online  atomic.Bool
+	uploads atomic.Int32
+}
+
+func (f *flakyStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	f.uploads.Add(1)
+	if !f.online.Load() {
+		return "", errors.New("dial tcp: network is unreachable")
+	}
+	return "v1", nil
+}
+
+func TestUploader_Offline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+
+	store := &flakyStorage{}
+	uploader := NewUploaderWithConfig(store, 1, 0)
+	uploader.SetConnectivityCheck(func(ctx context.Context) bool {
+		online := store.online.Load()
+		uploader.SetOnline(online)
+		return online
+	})
+	uploader.Start()
+	defer uploader.Stop()
+
+	// The failed attempt pauses the uploader instead of using up a retry
+	assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: path, Key: "docs/a.txt"}))
+	assert.Eventually(t, func() bool { return store.uploads.Load() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(1), store.uploads.Load())
+	assert.Empty(t, uploader.Results())
+
+	// Reconnecting resumes the task as its first attempt
+	store.online.Store(true)
+	uploader.SetOnline(true)
+
+	select {
+	case result := <-uploader.Results():
+		assert.True(t, result.Success)
+		assert.Equal(t, 0, result.Task.RetryCount)
+	case <-time.After(time.Second):
+		t.Fatal("upload did not resume")
+	}
+	assert.Equal(t, 1, uploader.Progress().Snapshot().FilesDone)
+}
+
+func TestUploader_Restriction(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.txt")
+	large := filepath.Join(dir, "large.txt")
+	assert.NoError(t, os.WriteFile(small, []byte("hi"), 0644))
+	assert.NoError(t, os.WriteFile(large, make([]byte, 100), 0644))
+
+	uploader := NewUploaderWithConfig(&readingStorage{}, 1, 0)
+	uploader.SetRestriction(power.Restriction{MaxFileSize: 10, ThrottleBytes: 500})
+	assert.Equal(t, int64(500), uploader.throttle())
+	uploader.Start()
+	defer uploader.Stop()
+
+	// Only the small file goes out while large files are held back
+	assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: large, Key: "docs/large.txt"}))
+	assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: small, Key: "docs/small.txt"}))
+	result := <-uploader.Results()
+	assert.Equal(t, "docs/small.txt", result.Task.Key)
+
+	// Pausing holds everything, lifting the restriction releases the large file
+	uploader.SetRestriction(power.Restriction{Paused: true})
+	assert.False(t, func() bool {
+		select {
+		case <-uploader.Results():
+			return true
+		case <-time.After(20 * time.Millisecond):
+			return false
+		}
+	}())
+
+	uploader.SetRestriction(power.Restriction{})
+	select {
+	case result := <-uploader.Results():
+		assert.Equal(t, "docs/large.txt", result.Task.Key)
+		assert.True(t, result.Success)
+	case <-time.After(time.Second):
+		t.Fatal("deferred upload was not released")
+	}
+	assert.Equal(t, int64(0), uploader.throttle())
+}
+
 // NewUploaderWithConfig is a helper to create an uploader with specific values for testing
 func NewUploaderWithConfig(store storage.Storage, maxConcurrency int, throttleBytes int64) *Uploader {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -80,10 +289,730 @@ func NewUploaderWithConfig(store storage.Storage, maxConcurrency int, throttleBy
 	return &Uploader{
 		store:          store,
 		taskQueue:      make(chan UploadTask, 1000),
+		room:           make(chan struct{}, 1),
 		resultChan:     make(chan UploadResult, 100),
 		maxConcurrency: maxConcurrency,
 		throttleBytes:  throttleBytes,
+		dehydrated:     cloudfile.Dehydrated,
 		ctx:            ctx,
 		cancel:         cancel,
 	}
 }
+
+func TestUploader_RetriesAgainstMemoryStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+
+	var attempts atomic.Int32
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{Fault: func(op, key string) error {
+		if op == "upload" && attempts.Add(1) == 1 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	}})
+	uploader := NewUploaderWithConfig(store, 1, 0)
+	uploader.Start()
+	defer uploader.Stop()
+
+	// The first attempt fails and the retry stores the file
+	assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: path, Key: "docs/a.txt"}))
+	result := <-uploader.Results()
+	assert.False(t, result.Success)
+	select {
+	case result := <-uploader.Results():
+		assert.True(t, result.Success)
+		assert.Equal(t, 1, result.Task.RetryCount)
+		assert.Equal(t, []string{result.VersionID}, store.Versions("docs/a.txt"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload was not retried")
+	}
+
+	info, _, err := store.GetFileInfo(context.Background(), "docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), info.Size)
+}
+
+func TestUploader_AppendsToGrownFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "app.log")
+	assert.NoError(t, os.WriteFile(path, []byte("line 1\n"), 0644))
+
+	var uploads, appends atomic.Int32
+	memory := storage.NewMemoryStorage(&storage.MemoryConfig{Fault: func(op, key string) error {
+		switch op {
+		case "upload":
+			uploads.Add(1)
+		case "append":
+			appends.Add(1)
+		}
+		return nil
+	}})
+	// Appends reach the backend through the middlewares, which must not serve stale file info
+	store := storage.Chain(memory, storage.WithCache(time.Minute, 0), storage.WithRetry(storage.RetryPolicy{MaxAttempts: 2}))
+	uploader := NewUploaderWithConfig(store, 1, 0)
+
+	upload := func(base Base) UploadResult {
+		assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: path, Key: "logs/app.log", Base: base}))
+		return uploader.processUpload(uploader.take(<-uploader.taskQueue))
+	}
+	download := func() string {
+		var buf bytes.Buffer
+		_, err := store.DownloadFile(ctx, "logs/app.log", &buf, "")
+		assert.NoError(t, err)
+		return buf.String()
+	}
+
+	first := upload(Base{})
+	assert.True(t, first.Success)
+	assert.Equal(t, int64(0), first.Offset)
+
+	// Lines added to the end are appended to the remote copy
+	assert.NoError(t, os.WriteFile(path, []byte("line 1\nline 2\n"), 0644))
+	grown := upload(Base{Size: first.Size, Hash: first.Hash})
+	assert.True(t, grown.Success)
+	assert.Equal(t, int64(7), grown.Offset)
+	assert.Equal(t, "line 1\nline 2\n", download())
+	assert.Equal(t, int32(1), uploads.Load())
+	assert.Equal(t, int32(1), appends.Load())
+
+	_, metadata, err := store.GetFileInfo(ctx, "logs/app.log")
+	assert.NoError(t, err)
+	assert.Equal(t, grown.Hash, metadata["hash_sha256"])
+
+	// The cache does not hide the first append from the next one
+	assert.NoError(t, os.WriteFile(path, []byte("line 1\nline 2\nline 3\n"), 0644))
+	grown = upload(Base{Size: grown.Size, Hash: grown.Hash})
+	assert.True(t, grown.Success)
+	assert.Equal(t, int64(14), grown.Offset)
+	assert.Equal(t, int32(2), appends.Load())
+
+	// A file rewritten from the start is uploaded whole
+	assert.NoError(t, os.WriteFile(path, []byte("line 0\nline 2\nline 3\n"), 0644))
+	rewritten := upload(Base{Size: grown.Size, Hash: grown.Hash})
+	assert.True(t, rewritten.Success)
+	assert.Equal(t, int64(0), rewritten.Offset)
+	assert.Equal(t, "line 0\nline 2\nline 3\n", download())
+
+	// So is a file whose remote copy is no longer the base
+	assert.NoError(t, os.WriteFile(path, []byte("line 0\nline 2\nline 3\nline 4\n"), 0644))
+	stale := upload(Base{Size: grown.Size, Hash: grown.Hash})
+	assert.True(t, stale.Success)
+	assert.Equal(t, int64(0), stale.Offset)
+	assert.Equal(t, int32(3), uploads.Load())
+	assert.Equal(t, int32(2), appends.Load())
+}
+
+// blockingStorage holds every upload until release is closed and records how many run at once
+type blockingStorage struct {
+	mockStorage
+	release chan struct{}
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (b *blockingStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	n := b.running.Add(1)
+	defer b.running.Add(-1)
+	for {
+		peak := b.peak.Load()
+		if n <= peak || b.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	<-b.release
+	return "v1", nil
+}
+
+func TestUploader_DropsVanishedFiles(t *testing.T) {
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	uploader := NewUploaderWithConfig(store, 1, 0)
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("draft"), 0644))
+	assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: path, Key: "notes.txt"}))
+	assert.NoError(t, os.Remove(path))
+
+	uploader.Start()
+	defer uploader.Stop()
+
+	// The upload fails once and is not retried
+	select {
+	case result := <-uploader.Results():
+		assert.True(t, Vanished(result.Error))
+		assert.False(t, Skipped(result.Error))
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for upload result")
+	}
+	select {
+	case result := <-uploader.Results():
+		t.Fatalf("vanished file retried: %v", result.Error)
+	case <-time.After(1500 * time.Millisecond):
+	}
+	snap := uploader.Progress().Snapshot()
+	assert.Equal(t, 0, snap.FilesTotal)
+	assert.Equal(t, 0, snap.FilesFailed)
+}
+
+func TestUploader_AppliesFilePolicies(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	uploader := NewUploaderWithConfig(store, 1, 0)
+	upload := func(path string) UploadResult {
+		assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: path, Key: filepath.Base(path)}))
+		return uploader.processUpload(uploader.take(<-uploader.taskQueue))
+	}
+
+	// Files above the size limit are left out, and not retried
+	notes := filepath.Join(dir, "notes.txt")
+	assert.NoError(t, os.WriteFile(notes, []byte("0123456789"), 0644))
+	uploader.SetFiles(commonconfig.FilesConfig{MaxFileSize: 4})
+	result := upload(notes)
+	var tooLarge *FileTooLargeError
+	assert.ErrorAs(t, result.Error, &tooLarge)
+	assert.Equal(t, &FileTooLargeError{Size: 10, Limit: 4}, tooLarge)
+	assert.True(t, Skipped(result.Error))
+
+	uploader.SetFiles(commonconfig.FilesConfig{MaxFileSize: -1})
+	assert.True(t, upload(notes).Success)
+
+	// Cloud placeholders are left out before they are read, unless set to be hydrated
+	uploader.dehydrated = func(info os.FileInfo) bool { return info.Name() == "notes.txt" }
+	result = upload(notes)
+	var placeholder *PlaceholderError
+	if assert.ErrorAs(t, result.Error, &placeholder) {
+		assert.Equal(t, int64(10), placeholder.Size)
+	}
+	assert.True(t, Skipped(result.Error))
+	uploader.SetFiles(commonconfig.FilesConfig{MaxFileSize: -1, HydratePlaceholders: true})
+	assert.True(t, upload(notes).Success)
+	uploader.dehydrated = cloudfile.Dehydrated
+
+	disk := filepath.Join(dir, "disk.img")
+	file, err := os.Create(disk)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Truncate(8*sparse.MinHoleSize))
+	assert.NoError(t, file.Close())
+	info, err := os.Stat(disk)
+	assert.NoError(t, err)
+	if !sparse.IsSparse(info) {
+		t.Skip("filesystem does not report sparse files")
+	}
+
+	// Sparse files are skipped, uploaded in full, or marked so downloads restore their holes
+	uploader.SetFiles(commonconfig.FilesConfig{Sparse: commonconfig.SparseSkip})
+	result = upload(disk)
+	assert.ErrorIs(t, result.Error, ErrSparseSkipped)
+	assert.True(t, Skipped(result.Error))
+
+	uploader.SetFiles(commonconfig.FilesConfig{Sparse: commonconfig.SparseWarn})
+	assert.True(t, upload(disk).Success)
+	_, metadata, err := store.GetFileInfo(ctx, "disk.img")
+	assert.NoError(t, err)
+	assert.Empty(t, metadata[sparse.MetadataKey])
+
+	uploader.SetFiles(commonconfig.FilesConfig{Sparse: commonconfig.SparseTransfer})
+	assert.True(t, upload(disk).Success)
+	_, metadata, err = store.GetFileInfo(ctx, "disk.img")
+	assert.NoError(t, err)
+	assert.Equal(t, "true", metadata[sparse.MetadataKey])
+}
+
+func TestUploader_SetMaxConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	store := &blockingStorage{release: make(chan struct{})}
+	uploader := NewUploaderWithConfig(store, 1, 0)
+	uploader.Start()
+	defer uploader.Stop()
+
+	for i := 0; i < 6; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.txt", i))
+		assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+		assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: path, Key: fmt.Sprintf("docs/%d.txt", i)}))
+	}
+
+	// Growing the pool lets more uploads run at once
+	assert.Eventually(t, func() bool { return store.running.Load() == 1 }, time.Second, 5*time.Millisecond)
+	uploader.SetMaxConcurrency(3)
+	assert.Eventually(t, func() bool { return store.running.Load() == 3 }, time.Second, 5*time.Millisecond)
+
+	// Shrinking retires workers without dropping queued tasks
+	uploader.SetMaxConcurrency(1)
+	assert.Equal(t, 1, uploader.maxConcurrency)
+	close(store.release)
+	for i := 0; i < 6; i++ {
+		select {
+		case result := <-uploader.Results():
+			assert.True(t, result.Success)
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of 6 uploads finished", i)
+		}
+	}
+	assert.Equal(t, int32(3), store.peak.Load())
+
+	uploader.SetMaxConcurrency(0)
+	assert.Equal(t, 1, uploader.maxConcurrency)
+}
+
+func TestTuner(t *testing.T) {
+	start := time.Now()
+	tuner := newTuner(commonconfig.ConcurrencyConfig{Min: 2, Max: 8}, start)
+	now := start
+	// window records one upload of size bytes per worker over tuneWindow and returns the decision
+	window := func(workers int, size int64, err error) int {
+		n := workers
+		for i := 0; i < workers; i++ {
+			now = now.Add((tuneWindow + time.Duration(workers) - 1) / time.Duration(workers))
+			s := sample{bytes: size, failed: err != nil, throttled: storage.Throttled(err)}
+			if n = tuner.record(workers, s, now); n != workers {
+				return n
+			}
+		}
+		return n
+	}
+
+	// A window is not judged before every worker finished an upload and tuneWindow passed
+	assert.Equal(t, 4, tuner.record(4, sample{bytes: 1 << 20}, start.Add(3*tuneWindow)))
+	tuner = newTuner(commonconfig.ConcurrencyConfig{Min: 2, Max: 8}, start)
+	assert.Equal(t, 4, tuner.record(4, sample{bytes: 1 << 20}, start.Add(tuneWindow/2)))
+
+	// Workers are added one at a time while the throughput rises
+	now = start.Add(tuneWindow / 2)
+	assert.Equal(t, 5, window(4, 1<<20, nil))
+	assert.Equal(t, 6, window(5, 1<<20, nil))
+	// A steady throughput holds the count, then tries one more
+	for i := 0; i < probeWindows-1; i++ {
+		assert.Equal(t, 6, window(6, 1<<20*5/6, nil))
+	}
+	assert.Equal(t, 7, window(6, 1<<20*5/6, nil))
+	// A worker that made the uploads slower is taken back
+	assert.Equal(t, 6, window(7, 1<<19*5/7, nil))
+
+	// Throttling halves the workers at once, down to the minimum
+	assert.Equal(t, 3, window(6, 1<<20, errors.New("SlowDown: Please reduce your request rate. status code: 503")))
+	assert.Equal(t, 2, window(3, 1<<20, errors.New("503 Service Unavailable")))
+	// So do failures past the failure rate, while a throughput rise earns a worker again
+	assert.Equal(t, 2, window(2, 0, errors.New("connection reset by peer")))
+	assert.Equal(t, 3, window(2, 1<<20, nil))
+
+	// The count never goes past the maximum
+	tuner.last = 1
+	assert.Equal(t, 8, window(8, 1<<20, nil))
+}
+
+func TestUploader_SetConcurrency(t *testing.T) {
+	adaptive := commonconfig.ConcurrencyConfig{Mode: commonconfig.ConcurrencyAdaptive, Min: 2, Max: 6}
+	cfg := commonconfig.DefaultConfig()
+	cfg.MaxConcurrency = 10
+	cfg.Concurrency = adaptive
+	uploader := NewUploader(&mockStorage{}, cfg)
+	uploader.Start()
+	defer uploader.Stop()
+
+	// Adaptive workers start at max_concurrency, within the bounds
+	assert.NotNil(t, uploader.tuner)
+	assert.Equal(t, 6, uploader.maxConcurrency)
+
+	// Reloading keeps the count the tuner reached, moved within new bounds
+	uploader.mutex.Lock()
+	uploader.maxConcurrency = 5
+	uploader.resizeLocked(5)
+	uploader.mutex.Unlock()
+	uploader.SetConcurrency(10, adaptive)
+	assert.Equal(t, 5, uploader.maxConcurrency)
+	adaptive.Max = 3
+	uploader.SetConcurrency(10, adaptive)
+	assert.Equal(t, 3, uploader.maxConcurrency)
+
+	// Fixed mode runs max_concurrency workers again
+	uploader.SetConcurrency(4, commonconfig.ConcurrencyConfig{Mode: commonconfig.ConcurrencyFixed})
+	assert.Nil(t, uploader.tuner)
+	assert.Equal(t, 4, uploader.maxConcurrency)
+}
+
+func TestUploader_ObserveUploads(t *testing.T) {
+	uploader := NewUploader(&mockStorage{}, commonconfig.DefaultConfig())
+	next := func() (sample, bool) {
+		select {
+		case s := <-uploader.samples:
+			return s, true
+		default:
+			return sample{}, false
+		}
+	}
+
+	// Transfers count their bytes, appends only the new ones
+	uploader.observe(UploadResult{Success: true, Size: 100, Offset: 40, sent: true})
+	s, ok := next()
+	assert.True(t, ok)
+	assert.Equal(t, sample{bytes: 60}, s)
+
+	// Failures of the storage count, throttling apart
+	uploader.observe(UploadResult{Error: errors.New("failed to upload file: SlowDown"), sent: true})
+	s, _ = next()
+	assert.Equal(t, sample{failed: true, throttled: true}, s)
+	uploader.observe(UploadResult{Error: errors.New("failed to upload file: connection reset by peer"), sent: true})
+	s, _ = next()
+	assert.Equal(t, sample{failed: true}, s)
+
+	// Uploads that sent nothing or were refused for the file itself say nothing of the storage
+	uploader.observe(UploadResult{Success: true, Unchanged: true, Size: 100})
+	uploader.observe(UploadResult{Error: errors.New("failed to open file: permission denied")})
+	uploader.observe(UploadResult{Error: errors.New("failed to upload file: AccessDenied"), sent: true})
+	_, ok = next()
+	assert.False(t, ok)
+}
+
+func TestUploader_QueueBackpressure(t *testing.T) {
+	dir := t.TempDir()
+	store := &blockingStorage{release: make(chan struct{})}
+	uploader := NewUploaderWithConfig(store, 1, 0)
+	uploader.taskQueue = make(chan UploadTask, 1)
+	uploader.Start()
+	defer uploader.Stop()
+
+	var tasks []UploadTask
+	for i := 0; i < 3; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.txt", i))
+		assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+		tasks = append(tasks, UploadTask{FilePath: path, Key: fmt.Sprintf("docs/%d.txt", i)})
+	}
+
+	// The worker holds the first upload and the second fills the queue
+	assert.NoError(t, uploader.QueueUpload(tasks[0]))
+	assert.Eventually(t, func() bool { return store.running.Load() == 1 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, uploader.QueueUpload(tasks[1]))
+	length, capacity := uploader.QueueLength()
+	assert.Equal(t, 1, length)
+	assert.Equal(t, 1, capacity)
+
+	// The third waits for room instead of failing
+	queued := make(chan error, 1)
+	go func() { queued <- uploader.QueueUpload(tasks[2]) }()
+	select {
+	case err := <-queued:
+		t.Fatalf("queued into a full queue: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(store.release)
+	select {
+	case err := <-queued:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the queue never made room")
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case result := <-uploader.Results():
+			assert.True(t, result.Success)
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of 3 uploads finished", i)
+		}
+	}
+
+	// With no worker taking tasks a full queue refuses the file, left pending for the next sync
+	stopped := NewUploaderWithConfig(&mockStorage{}, 1, 0)
+	stopped.taskQueue = make(chan UploadTask, 1)
+	assert.NoError(t, stopped.QueueUpload(tasks[0]))
+	assert.ErrorIs(t, stopped.QueueUpload(tasks[1]), ErrQueueFull)
+	assert.Equal(t, 1, stopped.Progress().Snapshot().FilesTotal)
+}
+
+func TestUploader_SchedulesSmallAndRecentFilesFirst(t *testing.T) {
+	dir := t.TempDir()
+	cold := time.Now().Add(-24 * time.Hour)
+	files := []struct {
+		name     string
+		size     int64
+		modTime  time.Time
+		priority int
+	}{
+		{"backfill.iso", 4 << 30, cold, 1},
+		{"old.txt", 2 << 10, cold, 1},
+		{"archive.txt", 2 << 10, cold, 3},
+		{"report.docx", 300 << 10, time.Now(), 1},
+	}
+	var tasks []UploadTask
+	for _, file := range files {
+		path := filepath.Join(dir, file.name)
+		f, err := os.Create(path)
+		assert.NoError(t, err)
+		assert.NoError(t, f.Truncate(file.size))
+		assert.NoError(t, f.Close())
+		assert.NoError(t, os.Chtimes(path, file.modTime, file.modTime))
+		tasks = append(tasks, UploadTask{FilePath: path, Key: "docs/" + file.name, Priority: file.priority})
+	}
+
+	// queued returns the keys in the order the workers take them
+	queued := func(weights commonconfig.SchedulingConfig) []string {
+		uploader := NewUploaderWithConfig(&mockStorage{}, 1, 0)
+		uploader.SetScheduling(weights)
+		uploader.running = true
+		for _, task := range tasks {
+			assert.NoError(t, uploader.QueueUpload(task))
+		}
+		var keys []string
+		for range tasks {
+			keys = append(keys, uploader.take(<-uploader.taskQueue).Key)
+		}
+		return keys
+	}
+
+	// The document just saved goes first, then the small file before the huge cold one; the
+	// folder of lower priority comes after the rest
+	assert.Equal(t, []string{"docs/report.docx", "docs/old.txt", "docs/backfill.iso", "docs/archive.txt"}, queued(commonconfig.DefaultConfig().Scheduling))
+
+	// Without weights files upload in the order they were queued
+	assert.Equal(t, []string{"docs/backfill.iso", "docs/old.txt", "docs/archive.txt", "docs/report.docx"}, queued(commonconfig.SchedulingConfig{}))
+}
+
+// checksumStorage reports the checksum a backend computed for the objects it stores, without
+// the hash recorded by the uploader
+type checksumStorage struct {
+	*storage.MemoryStorage
+	md5 string
+}
+
+func (c *checksumStorage) GetFileInfo(ctx context.Context, key string) (storage.FileInfo, map[string]string, error) {
+	info, _, err := c.MemoryStorage.GetFileInfo(ctx, key)
+	info.MD5 = c.md5
+	return info, map[string]string{}, err
+}
+
+func TestUploader_SkipsContentAlreadyStored(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "a.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{Name: t.Name()})
+	uploader := NewUploaderWithConfig(store, 1, 0)
+	uploader.Start()
+	defer uploader.Stop()
+
+	upload := func(task UploadTask) UploadResult {
+		assert.NoError(t, uploader.QueueUpload(task))
+		select {
+		case result := <-uploader.Results():
+			assert.True(t, result.Success)
+			return result
+		case <-time.After(time.Second):
+			t.Fatal("upload did not finish")
+			return UploadResult{}
+		}
+	}
+
+	// Nothing to compare with: the file is uploaded
+	task := UploadTask{FilePath: path, Key: "docs/a.txt", CheckRemote: true}
+	assert.False(t, upload(task).Unchanged)
+	assert.Len(t, store.Versions("docs/a.txt"), 1)
+
+	// The same content, by the hash recorded with it, is not uploaded again
+	result := upload(task)
+	assert.True(t, result.Unchanged)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", result.Hash)
+	assert.Len(t, store.Versions("docs/a.txt"), 1)
+	snap := uploader.Progress().Snapshot()
+	assert.Equal(t, 1, snap.FilesDone)
+	assert.Equal(t, int64(0), snap.BytesDone)
+
+	// Without the check every upload goes through
+	assert.False(t, upload(UploadTask{FilePath: path, Key: "docs/a.txt"}).Unchanged)
+	assert.Len(t, store.Versions("docs/a.txt"), 2)
+
+	// Objects uploaded by other tools are compared by the checksum of the backend
+	checked := NewUploaderWithConfig(&checksumStorage{MemoryStorage: store, md5: "5d41402abc4b2a76b9719d911017c592"}, 1, 0)
+	checked.ctx = ctx
+	result = checked.processUpload(task)
+	assert.True(t, result.Unchanged)
+	checked.store = &checksumStorage{MemoryStorage: store, md5: "00000000000000000000000000000000"}
+	result = checked.processUpload(task)
+	assert.True(t, result.Success)
+	assert.False(t, result.Unchanged)
+	assert.Len(t, store.Versions("docs/a.txt"), 3)
+}
+
+func TestUploader_RecordsTheChecksumAlgorithm(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "a.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("abc"), 0644))
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{Name: t.Name()})
+	uploader := NewUploaderWithConfig(store, 1, 0)
+	uploader.ctx = ctx
+
+	result := uploader.processUpload(UploadTask{FilePath: path, Key: "docs/a.txt", Checksum: checksum.BLAKE3})
+	assert.True(t, result.Success)
+	assert.Equal(t, "blake3:6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85", result.Hash)
+	_, metadata, err := store.GetFileInfo(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "blake3", metadata[checksum.MetadataAlgorithm])
+	assert.Equal(t, result.Hash, checksum.FromMetadata(metadata))
+
+	// A folder back on SHA-256 still finds the content stored with a BLAKE3 hash
+	result = uploader.processUpload(UploadTask{FilePath: path, Key: "docs/a.txt", CheckRemote: true})
+	assert.True(t, result.Unchanged)
+	assert.Len(t, store.Versions("docs/a.txt"), 1)
+
+	// The bytes appended to a file are hashed with the algorithm of its base
+	assert.NoError(t, os.WriteFile(path, []byte("abcdef"), 0644))
+	result = uploader.processUpload(UploadTask{FilePath: path, Key: "docs/a.txt", Checksum: checksum.BLAKE3,
+		Base: Base{Size: 3, Hash: "blake3:6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"}})
+	assert.True(t, result.Success)
+	assert.Equal(t, int64(3), result.Offset)
+	_, metadata, err = store.GetFileInfo(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, result.Hash, checksum.FromMetadata(metadata))
+}
+
+func TestThrottledReaderFollowsLimit(t *testing.T) {
+	var limit atomic.Int64
+	reader := newThrottledReader(bytes.NewReader(make([]byte, 100)), limit.Load)
+
+	// Without a limit reads pass through
+	buf := make([]byte, 50)
+	n, err := reader.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, n)
+
+	// A new limit applies to the next read
+	limit.Store(10)
+	n, err = reader.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+}
+
+func TestUploader_QueueFileWithPrefix(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "sub", "todo.txt")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte("todo"), 0644))
+
+	uploader := NewUploaderWithConfig(&mockStorage{}, 1, 0)
+	uploader.Start()
+	defer uploader.Stop()
+
+	// Keys always start with the remote prefix of the folder
+	assert.Error(t, uploader.QueueFile(path, root, "", "Desktop"))
+
+	// Files of an extra root are stored under its prefix within the folder
+	assert.NoError(t, uploader.QueueFile(path, root, "docs", "Desktop"))
+	select {
+	case result := <-uploader.Results():
+		assert.True(t, result.Success)
+		assert.Equal(t, "docs/Desktop/sub/todo.txt", result.Task.Key)
+		assert.Equal(t, "Desktop", result.Task.Metadata["source_prefix"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("file was not uploaded")
+	}
+}
+
+func TestUploader_ResumesMultipartUploadAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	assert.NoError(t, os.WriteFile(path, []byte("0123456789abcdefghij"), 0644))
+	partsDir := t.TempDir()
+
+	var parts atomic.Int32
+	failAt := int32(3)
+	memory := storage.NewMemoryStorage(&storage.MemoryConfig{Fault: func(op, key string) error {
+		if op == "upload_part" && parts.Add(1) == failAt {
+			return errors.New("connection reset")
+		}
+		return nil
+	}})
+	store := storage.Chain(memory, storage.WithCache(time.Minute, 0))
+	newUploader := func() *Uploader {
+		uploader := NewUploaderWithConfig(store, 1, 0)
+		partStore, err := NewPartStore(partsDir)
+		assert.NoError(t, err)
+		uploader.SetPartStore(partStore)
+		uploader.partSize = 6
+		return uploader
+	}
+	upload := func(uploader *Uploader) UploadResult {
+		assert.NoError(t, uploader.QueueUpload(UploadTask{FilePath: path, Key: "videos/video.mp4"}))
+		return uploader.processUpload(uploader.take(<-uploader.taskQueue))
+	}
+
+	// The third part fails, after the first two were recorded
+	first := newUploader()
+	assert.False(t, upload(first).Success)
+	recorded, ok := first.parts.get("videos/video.mp4")
+	assert.True(t, ok)
+	assert.Len(t, recorded.Parts, 2)
+
+	// After a restart only the last two parts are uploaded
+	parts.Store(0)
+	failAt = 0
+	result := upload(newUploader())
+	assert.True(t, result.Success)
+	assert.Equal(t, int32(2), parts.Load())
+	var buf bytes.Buffer
+	metadata, err := store.DownloadFile(ctx, "videos/video.mp4", &buf, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789abcdefghij", buf.String())
+	assert.Equal(t, result.Hash, metadata["hash_sha256"])
+	_, ok = first.parts.get("videos/video.mp4")
+	assert.False(t, ok)
+	uploads, err := memory.ListMultipart(ctx, "videos/")
+	assert.NoError(t, err)
+	assert.Empty(t, uploads)
+
+	// Parts of a file changed since are dropped along with their upload
+	parts.Store(0)
+	failAt = 2
+	assert.False(t, upload(newUploader()).Success)
+	assert.NoError(t, os.WriteFile(path, []byte("a new video, longer than before"), 0644))
+	failAt = 0
+	assert.True(t, upload(newUploader()).Success)
+	uploads, err = memory.ListMultipart(ctx, "videos/")
+	assert.NoError(t, err)
+	assert.Empty(t, uploads)
+	buf.Reset()
+	_, err = store.DownloadFile(ctx, "videos/video.mp4", &buf, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "a new video, longer than before", buf.String())
+
+	// Small files are still uploaded whole
+	assert.NoError(t, os.WriteFile(path, []byte("tiny"), 0644))
+	parts.Store(0)
+	assert.True(t, upload(newUploader()).Success)
+	assert.Zero(t, parts.Load())
+}
+
+func TestUploader_AbortsStaleUploads(t *testing.T) {
+	ctx := context.Background()
+	memory := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	uploader := NewUploaderWithConfig(memory, 1, 0)
+	partStore, err := NewPartStore(t.TempDir())
+	assert.NoError(t, err)
+	uploader.SetPartStore(partStore)
+
+	stale, err := memory.CreateMultipart(ctx, "videos/old.mp4", nil)
+	assert.NoError(t, err)
+	_, err = memory.CreateMultipart(ctx, "photos/other.jpg", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, partStore.put(&partUpload{Key: "videos/old.mp4", UploadID: stale, StartedAt: time.Now().Add(-time.Hour)}))
+	assert.NoError(t, partStore.put(&partUpload{Key: "videos/gone.mp4", UploadID: "aborted elsewhere", StartedAt: time.Now().Add(-time.Hour)}))
+
+	// Recent uploads are left alone
+	aborted, err := uploader.AbortStaleUploads(ctx, "videos/", time.Hour)
+	assert.NoError(t, err)
+	assert.Zero(t, aborted)
+	_, ok := partStore.get("videos/old.mp4")
+	assert.True(t, ok)
+
+	// Uploads the storage no longer knows are forgotten either way
+	_, ok = partStore.get("videos/gone.mp4")
+	assert.False(t, ok)
+
+	// Old ones are aborted, only under the prefix
+	aborted, err = uploader.AbortStaleUploads(ctx, "videos/", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, aborted)
+	_, ok = partStore.get("videos/old.mp4")
+	assert.False(t, ok)
+	uploads, err := memory.ListMultipart(ctx, "")
+	assert.NoError(t, err)
+	if assert.Len(t, uploads, 1) {
+		assert.Equal(t, "photos/other.jpg", uploads[0].Key)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package watcher
+
+import "os"
+
+// fileIDOf is not available on this platform, so moves are paired by timing alone
+func fileIDOf(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
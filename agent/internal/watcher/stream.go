@@ -0,0 +1,321 @@
+package watcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// streamLatency is how long the system gathers the changes to a tree before reporting them,
+// so a burst of changes to a directory takes a single rescan
+const streamLatency = 200 * time.Millisecond
+
+// errNoStreams is returned where the system cannot watch a whole tree through one stream, so
+// each of its directories takes a watch of its own
+var errNoStreams = errors.New("tree streams are not supported")
+
+// dirChange is a directory whose entries changed, as a tree stream reports it
+type dirChange struct {
+	dir     string
+	subtree bool // Whether changes under its subdirectories may have gone unreported too
+}
+
+// streamBatch is the changes the stream of a root reported together
+type streamBatch struct {
+	root    string
+	changes []dirChange
+}
+
+// streamTree is a tree watched through a single stream, with the entries of each of its
+// directories as of their last rescan
+type streamTree struct {
+	stop func()
+	dirs map[string]map[string]fileStamp // Entries by path, keyed by the directory holding them
+}
+
+// newStreamTree takes the current files under root as the baseline of its stream
+func newStreamTree(root string, excludes []string, stop func()) *streamTree {
+	tree := &streamTree{stop: stop, dirs: map[string]map[string]fileStamp{root: {}}}
+	tree.put(scanTree(root, root, excludes))
+	return tree
+}
+
+// watchStream watches root and everything under it through one stream, however many
+// directories it holds. mu must be held.
+func (fw *FileWatcher) watchStream(root string) error {
+	if !fw.useStreams {
+		return errNoStreams
+	}
+	if _, ok := fw.streams[root]; ok {
+		return nil
+	}
+	stop, err := startStream(root, fw.batches)
+	if err != nil {
+		return err
+	}
+	fw.streams[root] = newStreamTree(root, fw.excludes[root], stop)
+	fw.watchedPaths[root] = true
+	return nil
+}
+
+// stopStreams stops the streams of dir and of the roots under it. mu must be held.
+func (fw *FileWatcher) stopStreams(dir string) {
+	for root, tree := range fw.streams {
+		if root == dir || isSubdirectory(root, dir) {
+			tree.stop()
+			delete(fw.streams, root)
+			delete(fw.watchedPaths, root)
+			log.Debug().Str("path", root).Msg("Stopped watching directory tree")
+		}
+	}
+}
+
+// rescan compares the directories a stream reported with their last rescan and emits an
+// event per changed file. The two halves of a move within the tree are usually reported in
+// the same batch, and are paired by inode.
+func (fw *FileWatcher) rescan(batch streamBatch) {
+	fw.mu.Lock()
+	tree, ok := fw.streams[batch.root]
+	if !ok {
+		fw.mu.Unlock()
+		return // No longer watched
+	}
+	excludes := fw.excludes[batch.root]
+	previous := make(map[string]fileStamp)
+	current := make(map[string]fileStamp)
+	for _, change := range coalesce(batch.changes) {
+		before, after := tree.rescanDir(batch.root, change, excludes)
+		// A directory found created by the rescan of its parent is already compared, and in
+		// the tree, by the time it is rescanned itself
+		for path, stamp := range before {
+			if _, seen := current[path]; !seen {
+				if _, ok := previous[path]; !ok {
+					previous[path] = stamp
+				}
+			}
+		}
+		for path, stamp := range after {
+			current[path] = stamp
+		}
+	}
+	fw.mu.Unlock()
+
+	for _, change := range pairMoves(previous, current, diffTrees(previous, current)) {
+		fw.emit(change)
+	}
+}
+
+// pairMoves turns a path deleted and one created with the same inode into a move, dropping
+// the events of the files a moved directory holds, which moved along with it. Files must
+// also keep their size and modification time, as a move does, since a file removed may
+// hand its inode to one created right after.
+func pairMoves(previous, current map[string]fileStamp, changes []Event) []Event {
+	created := make(map[fileID]string)
+	for _, change := range changes {
+		if stamp := current[change.Path]; change.Type == EventCreate && stamp.hasID {
+			created[stamp.id] = change.Path
+		}
+	}
+	moves := make(map[string]string) // New path by old
+	for _, change := range changes {
+		stamp := previous[change.Path]
+		if change.Type != EventDelete || !stamp.hasID {
+			continue
+		}
+		to, ok := created[stamp.id]
+		moved := current[to]
+		if ok && moved.dir == stamp.dir && (stamp.dir || (moved.size == stamp.size && moved.modTime.Equal(stamp.modTime))) {
+			moves[change.Path] = to
+		}
+	}
+	if len(moves) == 0 {
+		return changes
+	}
+
+	// movedFrom returns the other path of a file that moved along with one of dirs
+	movedFrom := func(path string, dirs map[string]string) (string, bool) {
+		for dir, other := range dirs {
+			if path != dir && isSubdirectory(path, dir) {
+				relPath, err := filepath.Rel(dir, path)
+				return filepath.Join(other, relPath), err == nil
+			}
+		}
+		return "", false
+	}
+	var nested []string
+	for from := range moves {
+		if _, ok := movedFrom(from, moves); ok {
+			nested = append(nested, from)
+		}
+	}
+	for _, from := range nested {
+		delete(moves, from)
+	}
+	targets := make(map[string]string, len(moves)) // Old path by new
+	for from, to := range moves {
+		targets[to] = from
+	}
+
+	paired := make([]Event, 0, len(changes))
+	for _, change := range changes {
+		switch change.Type {
+		case EventDelete:
+			if to, ok := moves[change.Path]; ok {
+				paired = append(paired, Event{Type: EventMove, Path: to, OldPath: change.Path})
+				continue
+			}
+			if _, ok := movedFrom(change.Path, moves); ok {
+				continue
+			}
+		case EventCreate:
+			if _, ok := targets[change.Path]; ok {
+				continue
+			}
+			// Unless it changed on the way
+			if from, ok := movedFrom(change.Path, targets); ok {
+				before, existed := previous[from]
+				stamp := current[change.Path]
+				if existed && before.size == stamp.size && before.modTime.Equal(stamp.modTime) {
+					continue
+				}
+			}
+		}
+		paired = append(paired, change)
+	}
+	return paired
+}
+
+// coalesce merges the changes reported for the same directory and drops those under a
+// directory whose whole subtree is rescanned anyway
+func coalesce(changes []dirChange) []dirChange {
+	subtree := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		subtree[change.dir] = subtree[change.dir] || change.subtree
+	}
+	dirs := make([]string, 0, len(subtree))
+	for dir := range subtree {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	merged := make([]dirChange, 0, len(dirs))
+	for _, dir := range dirs {
+		covered := false
+		for _, change := range merged {
+			if change.subtree && isSubdirectory(dir, change.dir) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			merged = append(merged, dirChange{dir: dir, subtree: subtree[dir]})
+		}
+	}
+	return merged
+}
+
+// rescanDir rescans a changed directory, along with its whole subtree when the change calls
+// for it, and returns its files as of the last rescan and as of now
+func (t *streamTree) rescanDir(root string, change dirChange, excludes []string) (previous, current map[string]fileStamp) {
+	if !isSubdirectory(change.dir, root) || excludedPath(root, change.dir, excludes) {
+		return nil, nil
+	}
+
+	previous = t.take(change.dir, change.subtree)
+	if change.subtree {
+		current = scanTree(root, change.dir, excludes)
+	} else {
+		current = scanDir(root, change.dir, excludes)
+
+		// A directory created or removed, as when a tree is moved in or out, is only reported
+		// as a change to its parent, so whatever it holds is compared too
+		var replaced []string
+		for path, stamp := range current {
+			if stamp.dir && !previous[path].dir {
+				replaced = append(replaced, path)
+			}
+		}
+		for path, stamp := range previous {
+			if stamp.dir && !current[path].dir {
+				replaced = append(replaced, path)
+			}
+		}
+		for _, dir := range replaced {
+			for path, stamp := range t.take(dir, true) {
+				previous[path] = stamp
+			}
+			for path, stamp := range scanTree(root, dir, excludes) {
+				current[path] = stamp
+			}
+		}
+	}
+
+	t.put(current)
+	return previous, current
+}
+
+// take removes the entries of dir from the tree and returns them, with those of the
+// directories under it when subtree is set
+func (t *streamTree) take(dir string, subtree bool) map[string]fileStamp {
+	taken := make(map[string]fileStamp)
+	for parent, entries := range t.dirs {
+		if parent != dir && (!subtree || !isSubdirectory(parent, dir)) {
+			continue
+		}
+		for path, stamp := range entries {
+			taken[path] = stamp
+		}
+		delete(t.dirs, parent)
+	}
+	return taken
+}
+
+// put adds files to the tree, under the directory holding each
+func (t *streamTree) put(files map[string]fileStamp) {
+	for path, stamp := range files {
+		parent := filepath.Dir(path)
+		if t.dirs[parent] == nil {
+			t.dirs[parent] = make(map[string]fileStamp)
+		}
+		t.dirs[parent][path] = stamp
+		if stamp.dir && t.dirs[path] == nil {
+			t.dirs[path] = make(map[string]fileStamp)
+		}
+	}
+}
+
+// scanDir returns the files and directories right under dir, leaving out those excluded from root
+func scanDir(root, dir string, excludes []string) map[string]fileStamp {
+	files := make(map[string]fileStamp)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return files
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if relPath, err := filepath.Rel(root, path); err == nil && ShouldExclude(relPath, excludes) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since it was listed
+		}
+		files[path] = stampOf(info)
+	}
+	return files
+}
+
+// excludedPath reports whether root excludes path or any directory holding it
+func excludedPath(root, path string, excludes []string) bool {
+	for path != root && isSubdirectory(path, root) {
+		if relPath, err := filepath.Rel(root, path); err == nil && ShouldExclude(relPath, excludes) {
+			return true
+		}
+		path = filepath.Dir(path)
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+//go:build darwin && cgo
+
+package watcher
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsevents"
+)
+
+// startStream watches the tree under root through an FSEvents stream, which takes no
+// descriptor per directory as kqueue does, and sends the directories it reports changed to
+// batches until the returned function stops it
+func startStream(root string, batches chan<- streamBatch) (func(), error) {
+	// FSEvents reports the paths symlinks resolve to, such as /private/var for /var
+	real, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &fsevents.EventStream{
+		Events:  make(chan []fsevents.Event),
+		Paths:   []string{real},
+		Latency: streamLatency,
+		Flags:   fsevents.WatchRoot,
+	}
+	if err := stream.Start(); err != nil {
+		return nil, err
+	}
+
+	quit, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		for {
+			select {
+			case events := <-stream.Events:
+				// Once stopping, events are still read so a callback in flight is not left blocked
+				select {
+				case batches <- streamBatch{root: root, changes: dirChanges(root, real, events)}:
+				case <-quit:
+				}
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		stream.Stop()
+		close(stopped)
+	}, nil
+}
+
+// dirChanges converts FSEvents events to the directories under root they report changed.
+// Events dropped, or coalesced by the system into their top directory, take a rescan of the
+// whole subtree.
+func dirChanges(root, real string, events []fsevents.Event) []dirChange {
+	changes := make([]dirChange, 0, len(events))
+	for _, event := range events {
+		if event.Flags&fsevents.HistoryDone != 0 {
+			continue
+		}
+
+		dir := filepath.Clean(event.Path)
+		if relPath, err := filepath.Rel(real, dir); err == nil && isSubdirectory(dir, real) {
+			dir = filepath.Join(root, relPath)
+		}
+		if event.Flags&fsevents.RootChanged != 0 {
+			dir = root
+		}
+		subtree := event.Flags&(fsevents.MustScanSubDirs|fsevents.KernelDropped|fsevents.UserDropped|fsevents.RootChanged) != 0
+		changes = append(changes, dirChange{dir: dir, subtree: subtree})
+	}
+	return changes
+}
@@ -0,0 +1,8 @@
+//go:build !darwin || !cgo
+
+package watcher
+
+// startStream is not available on this platform, so each directory is watched on its own
+func startStream(root string, batches chan<- streamBatch) (func(), error) {
+	return nil, errNoStreams
+}
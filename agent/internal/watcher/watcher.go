@@ -1,16 +1,29 @@
 package watcher
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/watchlimit"
 	"github.com/rs/zerolog/log"
 )
 
+// PollInterval is how often directories left without a watch are checked for changes
+const PollInterval = 30 * time.Second
+
+// renameWindow is how long a path renamed away waits for its new path to be created, the
+// two halves of a move within the watched tree, before it is taken as moved out of it
+const renameWindow = 100 * time.Millisecond
+
 // EventType represents the type of file system event
 type EventType int
 
@@ -21,8 +34,14 @@ const (
 	EventUpdate
 	// EventDelete is triggered when a file or directory is deleted
 	EventDelete
-	// EventRename is triggered when a file or directory is renamed
+	// EventRename is triggered when a file or directory is renamed away and its new path was
+	// not seen, as when it is moved out of the watched paths
 	EventRename
+	// EventOverflow is triggered when the system dropped events, so changes may have gone unseen
+	EventOverflow
+	// EventMove is triggered when a file or directory is moved within the watched paths, with
+	// both its old and new path
+	EventMove
 )
 
 // Aliases para compatibilidade com código existente
@@ -38,22 +57,71 @@ const (
 type Event struct {
 	Type      EventType
 	Path      string
+	OldPath   string // Path the file was moved from, for EventMove
 	Timestamp time.Time
 }
 
 // HandlerFunc is the function signature for event handlers
 type HandlerFunc = func(Event)
 
-// FileWatcher watches for file system changes
+// FileWatcher watches for file system changes. Where the system can, as FSEvents on macOS,
+// each tree watched recursively takes a single stream reporting the directories that changed,
+// which are rescanned; elsewhere each directory takes a watch. Once the system runs out of
+// watches, the directories left over are polled for changes instead, along with their
+// subdirectories.
 type FileWatcher struct {
 	watcher      *fsnotify.Watcher
 	watchedPaths map[string]bool
+	polled       map[string]*pollTree   // Directories polled for changes, by path
+	streams      map[string]*streamTree // Trees watched through a stream, by root
+	batches      chan streamBatch       // Changes reported by the streams
+	limit        int                    // Watches allowed per user, 0 when unknown
+	pollInterval time.Duration
 	handlers     []HandlerFunc
-	excludes     map[string][]string // Map of root path to exclude patterns
+	onUsage      func(watchlimit.State)
+	excludes     map[string][]string // Exclude patterns of each root watched recursively, by path
+	dirIDs       map[string]fileID   // Inode of each watched directory, to pair its moves
+	renames      []pendingRename     // Paths renamed away, oldest first; only touched by watch
+	useStreams   bool                // Whether trees watched recursively take a stream where the system has them
 	mu           sync.RWMutex
 	done         chan struct{}
 }
 
+// fileID tells a file apart from any other on the system, whatever its path
+type fileID struct {
+	dev, ino uint64
+}
+
+// pendingRename is a path renamed away whose new path has not been seen yet
+type pendingRename struct {
+	path  string
+	dir   bool   // Whether it was a watched directory
+	id    fileID // Inode of the directory, when hasID
+	hasID bool
+	at    time.Time
+}
+
+// pollTree is a directory polled for changes, with the files found under it on the last check
+type pollTree struct {
+	root  string // Watched root the directory belongs to, whose exclude patterns apply
+	files map[string]fileStamp
+}
+
+// fileStamp is what polling compares to tell that a file changed
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+	dir     bool
+	id      fileID // Inode, when hasID, to pair the two halves of a move
+	hasID   bool
+}
+
+// stampOf returns the stamp of a file
+func stampOf(info os.FileInfo) fileStamp {
+	id, hasID := fileIDOf(info)
+	return fileStamp{size: info.Size(), modTime: info.ModTime(), dir: info.IsDir(), id: id, hasID: hasID}
+}
+
 // NewFileWatcher creates a new file watcher
 func NewFileWatcher() (*FileWatcher, error) {
 	fsWatcher, err := fsnotify.NewWatcher()
@@ -61,17 +129,100 @@ func NewFileWatcher() (*FileWatcher, error) {
 		return nil, fmt.Errorf("failed to create file watcher: %w", err)
 	}
 
+	// The limit only tells ahead of time that watches ran out; adding one past it fails anyway
+	limit, err := watchlimit.Limit()
+	if err != nil && !errors.Is(err, watchlimit.ErrUnsupported) {
+		log.Debug().Err(err).Msg("Failed to read watch limit")
+	}
+
 	fw := &FileWatcher{
 		watcher:      fsWatcher,
 		watchedPaths: make(map[string]bool),
+		polled:       make(map[string]*pollTree),
+		streams:      make(map[string]*streamTree),
+		batches:      make(chan streamBatch),
+		limit:        limit,
+		pollInterval: PollInterval,
 		handlers:     make([]HandlerFunc, 0),
 		excludes:     make(map[string][]string),
+		dirIDs:       make(map[string]fileID),
+		useStreams:   true,
 		done:         make(chan struct{}),
 	}
 
 	return fw, nil
 }
 
+// SetUsageHandler registers a function told how many watches are used after folders are
+// watched or removed, and whenever a directory has to be polled
+func (fw *FileWatcher) SetUsageHandler(handler func(watchlimit.State)) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.onUsage = handler
+}
+
+// Usage returns the watches in use, the limit and the directories polled for lack of watches
+func (fw *FileWatcher) Usage() watchlimit.State {
+	fw.mu.RLock()
+	defer fw.mu.RUnlock()
+
+	return fw.usage()
+}
+
+// usage is Usage with mu held
+func (fw *FileWatcher) usage() watchlimit.State {
+	polled := make([]string, 0, len(fw.polled))
+	for dir := range fw.polled {
+		polled = append(polled, dir)
+	}
+	sort.Strings(polled)
+	return watchlimit.State{Watches: len(fw.watchedPaths), Limit: fw.limit, Polled: polled}
+}
+
+// reportUsage passes the usage to the usage handler, if any
+func (fw *FileWatcher) reportUsage() {
+	fw.mu.RLock()
+	handler := fw.onUsage
+	usage := fw.usage()
+	fw.mu.RUnlock()
+
+	if handler != nil {
+		handler(usage)
+	}
+}
+
+// addWatch watches dir, or polls it with its subdirectories when no watch is left for it.
+// It reports whether dir got a watch. mu must be held.
+func (fw *FileWatcher) addWatch(root, dir string) (bool, error) {
+	if fw.limit > 0 && len(fw.watchedPaths) >= fw.limit {
+		fw.poll(root, dir)
+		return false, nil
+	}
+
+	if err := fw.watcher.Add(dir); err != nil {
+		// ENOSPC is how inotify reports that the user has no watches left
+		if errors.Is(err, syscall.ENOSPC) {
+			fw.poll(root, dir)
+			return false, nil
+		}
+		return false, err
+	}
+	fw.watchedPaths[dir] = true
+	return true, nil
+}
+
+// poll starts polling dir, taking its current files as the baseline. mu must be held.
+func (fw *FileWatcher) poll(root, dir string) {
+	if _, ok := fw.polled[dir]; ok {
+		return
+	}
+
+	log.Warn().Str("path", dir).Int("watches", len(fw.watchedPaths)).Int("limit", fw.limit).
+		Msgf("Out of file watches, polling directory for changes every %s; raise %s to watch it instead", fw.pollInterval, watchlimit.Sysctl)
+	fw.polled[dir] = &pollTree{root: root, files: scanTree(root, dir, fw.excludes[root])}
+}
+
 // AddHandler registers a handler for file events
 func (fw *FileWatcher) AddHandler(handler HandlerFunc) {
 	fw.mu.Lock()
@@ -93,45 +244,24 @@ func (fw *FileWatcher) WatchPath(path string, recursive bool, excludePatterns []
 		return fmt.Errorf("failed to stat path: %w", err)
 	}
 
+	defer fw.reportUsage()
 	fw.mu.Lock()
 	defer fw.mu.Unlock()
 
-	// Store exclude patterns for this root
-	if len(excludePatterns) > 0 {
-		fw.excludes[absPath] = excludePatterns
-	}
-
 	if fileInfo.IsDir() && recursive {
-		// Watch all subdirectories as well
-		err = filepath.Walk(absPath, func(walkPath string, info os.FileInfo, err error) error {
-			if err != nil {
-				log.Warn().Err(err).Str("path", walkPath).Msg("Error walking directory")
-				return nil // Continue despite error
-			}
-
-			if !info.IsDir() {
-				return nil // Skip files
-			}
-
-			// Check if this directory should be excluded
-			if fw.shouldExclude(absPath, walkPath) {
-				log.Debug().Str("path", walkPath).Msg("Excluding directory from watch")
-				return filepath.SkipDir
-			}
-
-			if err := fw.watcher.Add(walkPath); err != nil {
-				log.Warn().Err(err).Str("path", walkPath).Msg("Failed to watch directory")
-				return nil // Continue despite error
-			}
-
-			fw.watchedPaths[walkPath] = true
-			log.Debug().Str("path", walkPath).Msg("Watching directory")
+		// Excluded directories are skipped as the tree is walked, so none of their
+		// subdirectories takes up a watch
+		fw.excludes[absPath] = excludePatterns
+		err := fw.watchStream(absPath)
+		if err == nil {
+			log.Debug().Str("path", absPath).Msg("Watching directory tree through a stream")
 			return nil
-		})
-
-		if err != nil {
-			return fmt.Errorf("failed to recursively watch path: %w", err)
 		}
+		if !errors.Is(err, errNoStreams) {
+			log.Warn().Err(err).Str("path", absPath).Msg("Failed to watch directory tree through a stream, watching each directory")
+		}
+		watched, skipped := fw.watchTree(absPath, absPath)
+		log.Debug().Str("path", absPath).Int("watched", watched).Int("excluded", skipped).Msg("Watching directory tree")
 	} else {
 		// Just watch this single path
 		if err := fw.watcher.Add(absPath); err != nil {
@@ -144,6 +274,57 @@ func (fw *FileWatcher) WatchPath(path string, recursive bool, excludePatterns []
 	return nil
 }
 
+// watchTree watches dir and the directories under it that root does not exclude, returning
+// how many got a watch and how many excluded directories were left out. mu must be held.
+func (fw *FileWatcher) watchTree(root, dir string) (watched, skipped int) {
+	filepath.Walk(dir, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Warn().Err(err).Str("path", walkPath).Msg("Error walking directory")
+			return nil // Continue despite error
+		}
+
+		if !info.IsDir() {
+			return nil // Skip files
+		}
+
+		// Check if this directory should be excluded
+		if fw.shouldExclude(root, walkPath) {
+			log.Debug().Str("path", walkPath).Msg("Excluding directory from watch")
+			skipped++
+			return filepath.SkipDir
+		}
+		if fw.watchedPaths[walkPath] {
+			return nil
+		}
+
+		ok, err := fw.addWatch(root, walkPath)
+		if err != nil {
+			log.Warn().Err(err).Str("path", walkPath).Msg("Failed to watch directory")
+			return nil // Continue despite error
+		}
+		if !ok {
+			return filepath.SkipDir // Polled along with its subdirectories
+		}
+		if id, ok := fileIDOf(info); ok {
+			fw.dirIDs[walkPath] = id
+		}
+		watched++
+		return nil
+	})
+	return watched, skipped
+}
+
+// rootOf returns the innermost root watched recursively that holds path. mu must be held.
+func (fw *FileWatcher) rootOf(path string) (string, bool) {
+	var found string
+	for root := range fw.excludes {
+		if isSubdirectory(path, root) && len(root) > len(found) {
+			found = root
+		}
+	}
+	return found, found != ""
+}
+
 // RemovePath stops watching a path
 func (fw *FileWatcher) RemovePath(path string) error {
 	absPath, err := filepath.Abs(path)
@@ -151,9 +332,12 @@ func (fw *FileWatcher) RemovePath(path string) error {
 		return fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
+	defer fw.reportUsage()
 	fw.mu.Lock()
 	defer fw.mu.Unlock()
 
+	fw.stopStreams(absPath)
+
 	// Remove this path and all subdirectories from the watch list
 	for watchedPath := range fw.watchedPaths {
 		if watchedPath == absPath || isSubdirectory(watchedPath, absPath) {
@@ -161,11 +345,18 @@ func (fw *FileWatcher) RemovePath(path string) error {
 				log.Warn().Err(err).Str("path", watchedPath).Msg("Failed to remove watch")
 			} else {
 				delete(fw.watchedPaths, watchedPath)
+				delete(fw.dirIDs, watchedPath)
 				log.Debug().Str("path", watchedPath).Msg("Stopped watching path")
 			}
 		}
 	}
 
+	for dir := range fw.polled {
+		if dir == absPath || isSubdirectory(dir, absPath) {
+			delete(fw.polled, dir)
+		}
+	}
+
 	// Remove exclude patterns for this root
 	delete(fw.excludes, absPath)
 
@@ -175,16 +366,26 @@ func (fw *FileWatcher) RemovePath(path string) error {
 // Start begins watching for file events
 func (fw *FileWatcher) Start() {
 	go fw.watch()
+	go fw.pollLoop()
 }
 
 // Stop stops watching for file events
 func (fw *FileWatcher) Stop() error {
 	close(fw.done)
+
+	fw.mu.Lock()
+	for root, tree := range fw.streams {
+		tree.stop()
+		delete(fw.streams, root)
+	}
+	fw.mu.Unlock()
+
 	return fw.watcher.Close()
 }
 
 // watch processes file events
 func (fw *FileWatcher) watch() {
+	var expire <-chan time.Time
 	for {
 		select {
 		case <-fw.done:
@@ -193,81 +394,239 @@ func (fw *FileWatcher) watch() {
 			if !ok {
 				return
 			}
-
-			// Convert fsnotify event to our event type
-			var eventType EventType
-			switch {
-			case event.Op&fsnotify.Create == fsnotify.Create:
-				eventType = EventCreate
-				// If it's a new directory, we need to watch it too if recursive
-				info, err := os.Stat(event.Name)
-				if err == nil && info.IsDir() {
-					fw.mu.Lock()
-					// Check for any root path this might belong to
-					for rootPath := range fw.excludes {
-						if isSubdirectory(event.Name, rootPath) && !fw.shouldExclude(rootPath, event.Name) {
-							if err := fw.watcher.Add(event.Name); err == nil {
-								fw.watchedPaths[event.Name] = true
-								log.Debug().Str("path", event.Name).Msg("Watching new directory")
-							}
-							break
-						}
-					}
-					fw.mu.Unlock()
-				}
-			case event.Op&fsnotify.Write == fsnotify.Write:
-				eventType = EventUpdate
-			case event.Op&fsnotify.Remove == fsnotify.Remove:
-				eventType = EventDelete
-				// Remove from watched paths
-				fw.mu.Lock()
-				delete(fw.watchedPaths, event.Name)
-				fw.mu.Unlock()
-			case event.Op&fsnotify.Rename == fsnotify.Rename:
-				eventType = EventRename
-				// Remove from watched paths
-				fw.mu.Lock()
-				delete(fw.watchedPaths, event.Name)
-				fw.mu.Unlock()
-			default:
-				continue // Skip other events
-			}
-
-			fw.mu.RLock()
-			handlers := make([]HandlerFunc, len(fw.handlers))
-			copy(handlers, fw.handlers)
-			fw.mu.RUnlock()
-
-			for _, handler := range handlers {
-				handler(Event{
-					Type:      eventType,
-					Path:      event.Name,
-					Timestamp: time.Now(),
-				})
-			}
-
+			fw.handle(event)
+		case batch := <-fw.batches:
+			fw.rescan(batch)
+		case now := <-expire:
+			expire = nil
+			fw.expireRenames(now)
 		case err, ok := <-fw.watcher.Errors:
 			if !ok {
 				return
 			}
 			log.Error().Err(err).Msg("Watcher error")
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				fw.emit(Event{Type: EventOverflow})
+			}
+		}
+
+		// Renames left unpaired are emitted once their window is over
+		if expire == nil && len(fw.renames) > 0 {
+			expire = time.After(time.Until(fw.renames[0].at.Add(renameWindow)))
+		}
+	}
+}
+
+// handle converts an fsnotify event to ours and emits it. A rename is held back until the
+// creation of its new path arrives, which fsnotify reports as a separate event, so both
+// paths are emitted together as a move.
+func (fw *FileWatcher) handle(event fsnotify.Event) {
+	// Left by watches dropped as their directory was renamed away
+	if event.Name == "" {
+		return
+	}
+
+	switch {
+	case event.Op&fsnotify.Create == fsnotify.Create:
+		info, err := os.Stat(event.Name)
+		from, moved := "", false
+		if err == nil {
+			from, moved = fw.pairRename(info)
+		}
+		if err == nil && info.IsDir() {
+			fw.watchNewDir(event.Name)
+		}
+		if moved {
+			fw.emit(Event{Type: EventMove, Path: event.Name, OldPath: from})
+			return
+		}
+		fw.emit(Event{Type: EventCreate, Path: event.Name})
+	case event.Op&fsnotify.Write == fsnotify.Write:
+		fw.emit(Event{Type: EventUpdate, Path: event.Name})
+	case event.Op&fsnotify.Remove == fsnotify.Remove:
+		// Remove from watched paths
+		fw.mu.Lock()
+		delete(fw.watchedPaths, event.Name)
+		delete(fw.dirIDs, event.Name)
+		fw.mu.Unlock()
+		fw.emit(Event{Type: EventDelete, Path: event.Name})
+	case event.Op&fsnotify.Rename == fsnotify.Rename:
+		fw.mu.Lock()
+		id, hasID := fw.dirIDs[event.Name]
+		rename := pendingRename{path: event.Name, dir: fw.watchedPaths[event.Name], id: id, hasID: hasID, at: time.Now()}
+		fw.forgetTree(event.Name)
+		fw.mu.Unlock()
+		fw.renames = append(fw.renames, rename)
+	}
+}
+
+// pairRename returns the oldest path renamed away that the file just created at info could
+// have come from, and stops holding it back. Directories are matched by inode where it is
+// known, files by timing alone.
+func (fw *FileWatcher) pairRename(info os.FileInfo) (string, bool) {
+	id, hasID := fileIDOf(info)
+	for i, rename := range fw.renames {
+		if rename.dir != info.IsDir() || (rename.hasID && hasID && rename.id != id) {
+			continue
 		}
+		fw.renames = append(fw.renames[:i], fw.renames[i+1:]...)
+		return rename.path, true
+	}
+	return "", false
+}
+
+// expireRenames emits the renames whose window is over as of now, their new path unseen
+func (fw *FileWatcher) expireRenames(now time.Time) {
+	for len(fw.renames) > 0 && !now.Before(fw.renames[0].at.Add(renameWindow)) {
+		rename := fw.renames[0]
+		fw.renames = fw.renames[1:]
+		fw.emit(Event{Type: EventRename, Path: rename.path})
 	}
 }
 
+// forgetTree drops the watches of a directory renamed away and of those under it, whose
+// paths no longer exist; its new path is watched afresh when it shows up. mu must be held.
+func (fw *FileWatcher) forgetTree(dir string) {
+	for watchedPath := range fw.watchedPaths {
+		if watchedPath == dir || isSubdirectory(watchedPath, dir) {
+			if err := fw.watcher.Remove(watchedPath); err != nil {
+				log.Debug().Err(err).Str("path", watchedPath).Msg("Failed to remove watch")
+			}
+			delete(fw.watchedPaths, watchedPath)
+			delete(fw.dirIDs, watchedPath)
+		}
+	}
+}
+
+// watchNewDir watches a directory created under a recursive root, along with whatever it
+// already holds, as when a tree is moved in, unless the root excludes it
+func (fw *FileWatcher) watchNewDir(dir string) {
+	fw.mu.Lock()
+	before := len(fw.polled)
+	if root, ok := fw.rootOf(dir); ok {
+		watched, skipped := fw.watchTree(root, dir)
+		if watched > 0 || skipped > 0 {
+			log.Debug().Str("path", dir).Int("watched", watched).Int("excluded", skipped).Msg("Watching new directory")
+		}
+	}
+	polled := len(fw.polled) > before
+	usage := fw.usage()
+	fw.mu.Unlock()
+	if polled || usage.Near() {
+		fw.reportUsage()
+	}
+}
+
+// emit passes an event to every handler
+func (fw *FileWatcher) emit(event Event) {
+	fw.mu.RLock()
+	handlers := make([]HandlerFunc, len(fw.handlers))
+	copy(handlers, fw.handlers)
+	fw.mu.RUnlock()
+
+	event.Timestamp = time.Now()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// pollLoop checks the polled directories for changes until the watcher stops
+func (fw *FileWatcher) pollLoop() {
+	ticker := time.NewTicker(fw.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fw.done:
+			return
+		case <-ticker.C:
+			fw.pollOnce()
+		}
+	}
+}
+
+// pollOnce compares each polled directory with its last check and emits an event per changed file
+func (fw *FileWatcher) pollOnce() {
+	fw.mu.RLock()
+	type check struct {
+		dir, root string
+		excludes  []string
+		files     map[string]fileStamp
+	}
+	checks := make([]check, 0, len(fw.polled))
+	for dir, tree := range fw.polled {
+		checks = append(checks, check{dir: dir, root: tree.root, excludes: fw.excludes[tree.root], files: tree.files})
+	}
+	fw.mu.RUnlock()
+
+	for _, c := range checks {
+		current := scanTree(c.root, c.dir, c.excludes)
+
+		fw.mu.Lock()
+		tree, ok := fw.polled[c.dir]
+		if ok {
+			tree.files = current
+		}
+		fw.mu.Unlock()
+		if !ok {
+			continue // No longer watched
+		}
+
+		for _, change := range diffTrees(c.files, current) {
+			fw.emit(change)
+		}
+	}
+}
+
+// scanTree returns the files and directories under dir, leaving out those excluded from root
+func scanTree(root, dir string, excludes []string) map[string]fileStamp {
+	files := make(map[string]fileStamp)
+	filepath.Walk(dir, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil || walkPath == dir {
+			return nil
+		}
+		if relPath, err := filepath.Rel(root, walkPath); err == nil && ShouldExclude(relPath, excludes) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files[walkPath] = stampOf(info)
+		return nil
+	})
+	return files
+}
+
+// diffTrees returns the events turning the files found on one check into those of the next, by path
+func diffTrees(previous, current map[string]fileStamp) []Event {
+	var changes []Event
+	for path, stamp := range current {
+		before, ok := previous[path]
+		switch {
+		case !ok:
+			changes = append(changes, Event{Type: EventCreate, Path: path})
+		case !stamp.dir && (stamp.size != before.size || !stamp.modTime.Equal(before.modTime)):
+			changes = append(changes, Event{Type: EventUpdate, Path: path})
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changes = append(changes, Event{Type: EventDelete, Path: path})
+		}
+	}
+	sort.Slice(changes, func(a, b int) bool { return changes[a].Path < changes[b].Path })
+	return changes
+}
+
 // ShouldExclude verifica se um caminho deve ser excluído com base em padrões de exclusão
 func ShouldExclude(path string, patterns []string) bool {
 	if len(patterns) == 0 {
 		return false
 	}
 
+	// Padrões inválidos não casam com nada; os terminados em /** excluem a subárvore
 	for _, pattern := range patterns {
-		matched, err := filepath.Match(pattern, path)
-		if err != nil {
-			// Se o padrão for inválido, ignoramos
-			continue
-		}
-		if matched {
+		if config.MatchExclude(pattern, path) {
 			return true
 		}
 	}
@@ -305,7 +664,7 @@ func isSubdirectory(child, parent string) bool {
 	if err != nil {
 		return false
 	}
-	return rel != ".." && !filepath.IsAbs(rel)
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
 }
 
 // WatchDirectory adds a directory to be watched (alias for WatchPath with recursive=true)
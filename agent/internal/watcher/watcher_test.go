@@ -0,0 +1,231 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/watchlimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcherPollsDirectoriesPastTheLimit(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "b", "b/c", "cache"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0755))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "b", "c", "old.txt"), []byte("old"), 0644))
+
+	fw, err := NewFileWatcher()
+	assert.NoError(t, err)
+	defer fw.Stop()
+	fw.useStreams = false // Each directory takes a watch, as where the system has no streams
+
+	var usages []watchlimit.State
+	fw.SetUsageHandler(func(usage watchlimit.State) { usages = append(usages, usage) })
+	var events []Event
+	fw.AddHandler(func(event Event) { events = append(events, event) })
+
+	// Only the root and the first directory fit, so the rest is polled along with its subdirectories
+	fw.limit = 2
+	assert.NoError(t, fw.WatchPath(root, true, []string{"cache"}))
+	if assert.Len(t, usages, 1) {
+		assert.Equal(t, 2, usages[0].Watches)
+		assert.Equal(t, []string{filepath.Join(root, "b")}, usages[0].Polled)
+		assert.True(t, usages[0].Near())
+	}
+
+	// Changes under a polled directory are found on the next check
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "b", "c", "new.txt"), []byte("new"), 0644))
+	assert.NoError(t, os.Remove(filepath.Join(root, "b", "c", "old.txt")))
+	fw.pollOnce()
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, Event{Type: EventCreate, Path: filepath.Join(root, "b", "c", "new.txt")}, Event{Type: events[0].Type, Path: events[0].Path})
+		assert.Equal(t, Event{Type: EventDelete, Path: filepath.Join(root, "b", "c", "old.txt")}, Event{Type: events[1].Type, Path: events[1].Path})
+	}
+	fw.pollOnce()
+	assert.Len(t, events, 2)
+
+	// Removing the folder stops polling it
+	assert.NoError(t, fw.RemovePath(root))
+	assert.Empty(t, fw.Usage().Polled)
+}
+
+func TestWatcherSkipsExcludedDirectories(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"src", "node_modules/a/b", "node_modules/c", "build/out"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0755))
+	}
+
+	fw, err := NewFileWatcher()
+	assert.NoError(t, err)
+	defer fw.Stop()
+	fw.useStreams = false // Each directory takes a watch, as where the system has no streams
+
+	// Excluded trees take up no watch at all
+	assert.NoError(t, fw.WatchPath(root, true, []string{"node_modules", "build"}))
+	assert.ElementsMatch(t, []string{root, filepath.Join(root, "src")}, fw.ListWatchedPaths())
+
+	// A tree created later is watched whole, except what the root excludes
+	other := t.TempDir()
+	assert.NoError(t, fw.WatchPath(other, true, nil))
+	fw.Start()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "lib", "util"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "build", "new"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(other, "docs"), 0755))
+	assert.Eventually(t, func() bool {
+		return len(fw.ListWatchedPaths()) == 6
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{
+		root, filepath.Join(root, "src"), filepath.Join(root, "lib"), filepath.Join(root, "lib", "util"),
+		other, filepath.Join(other, "docs"),
+	}, fw.ListWatchedPaths())
+}
+
+func TestWatcherPairsRenames(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "albums", "2024"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "draft.txt"), []byte("draft"), 0644))
+	outside := t.TempDir()
+
+	fw, err := NewFileWatcher()
+	assert.NoError(t, err)
+	defer fw.Stop()
+	fw.useStreams = false // Each directory takes a watch, as where the system has no streams
+
+	var mu sync.Mutex
+	var events []Event
+	fw.AddHandler(func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, Event{Type: event.Type, Path: event.Path, OldPath: event.OldPath})
+	})
+	received := func() []Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Event(nil), events...)
+	}
+	assert.NoError(t, fw.WatchPath(root, true, nil))
+	fw.Start()
+
+	// A file renamed within the tree is a single move with both paths
+	assert.NoError(t, os.Rename(filepath.Join(root, "draft.txt"), filepath.Join(root, "final.txt")))
+	assert.Eventually(t, func() bool { return len(received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, Event{Type: EventMove, Path: filepath.Join(root, "final.txt"), OldPath: filepath.Join(root, "draft.txt")}, received()[0])
+
+	// A directory too, and its tree is watched under the new path
+	assert.NoError(t, os.Rename(filepath.Join(root, "albums"), filepath.Join(root, "photos")))
+	assert.Eventually(t, func() bool { return len(received()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, Event{Type: EventMove, Path: filepath.Join(root, "photos"), OldPath: filepath.Join(root, "albums")}, received()[1])
+	assert.ElementsMatch(t, []string{root, filepath.Join(root, "photos"), filepath.Join(root, "photos", "2024")}, fw.ListWatchedPaths())
+
+	// A file moved out of the tree has no new path to pair with, and stays a rename
+	assert.NoError(t, os.Rename(filepath.Join(root, "final.txt"), filepath.Join(outside, "final.txt")))
+	assert.Eventually(t, func() bool { return len(received()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, Event{Type: EventRename, Path: filepath.Join(root, "final.txt")}, received()[2])
+}
+
+func TestPairRenameMatchesDirectoriesByInode(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "b"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(root, dir), 0755))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "file.txt"), nil, 0644))
+	dirInfo, err := os.Stat(filepath.Join(root, "b"))
+	assert.NoError(t, err)
+	fileInfo, err := os.Stat(filepath.Join(root, "file.txt"))
+	assert.NoError(t, err)
+
+	fw := &FileWatcher{}
+	other, _ := fileIDOf(fileInfo)
+	id, hasID := fileIDOf(dirInfo)
+	fw.renames = []pendingRename{
+		{path: "/old/a", dir: true, id: other, hasID: hasID},
+		{path: "/old/file.txt"},
+		{path: "/old/b", dir: true, id: id, hasID: hasID},
+	}
+
+	// Files only pair with files, directories with the one of the same inode
+	from, ok := fw.pairRename(fileInfo)
+	assert.True(t, ok)
+	assert.Equal(t, "/old/file.txt", from)
+	from, ok = fw.pairRename(dirInfo)
+	assert.True(t, ok)
+	if hasID {
+		assert.Equal(t, "/old/b", from)
+		assert.Len(t, fw.renames, 1)
+	}
+	_, ok = fw.pairRename(fileInfo)
+	assert.False(t, ok)
+
+	// Unpaired renames are emitted once their window is over
+	var expired []Event
+	fw.AddHandler(func(event Event) { expired = append(expired, event) })
+	fw.renames = []pendingRename{{path: "/old/a", at: time.Now()}}
+	fw.expireRenames(time.Now())
+	assert.Empty(t, expired)
+	fw.expireRenames(time.Now().Add(renameWindow))
+	if assert.Len(t, expired, 1) {
+		assert.Equal(t, EventRename, expired[0].Type)
+		assert.Equal(t, "/old/a", expired[0].Path)
+	}
+	assert.Empty(t, fw.renames)
+}
+
+func TestStreamRescansReportedDirectories(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"docs", "albums/2024", "cache"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0755))
+	}
+	for _, file := range []string{"docs/a.txt", "docs/b.txt", "albums/2024/beach.jpg", "cache/tmp"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(root, file), []byte(file), 0644))
+	}
+
+	fw := &FileWatcher{streams: make(map[string]*streamTree), excludes: map[string][]string{root: {"cache"}}}
+	fw.streams[root] = newStreamTree(root, fw.excludes[root], func() {})
+	var events []Event
+	fw.AddHandler(func(event Event) {
+		events = append(events, Event{Type: event.Type, Path: event.Path, OldPath: event.OldPath})
+	})
+	path := func(name string) string { return filepath.Join(root, name) }
+
+	// Changes to a directory, reported more than once, take a single rescan of its entries
+	assert.NoError(t, os.WriteFile(path("docs/a.txt"), []byte("changed"), 0644))
+	assert.NoError(t, os.Remove(path("docs/b.txt")))
+	assert.NoError(t, os.WriteFile(path("docs/c.txt"), nil, 0644))
+	fw.rescan(streamBatch{root: root, changes: []dirChange{{dir: path("docs")}, {dir: path("docs")}}})
+	assert.Equal(t, []Event{
+		{Type: EventUpdate, Path: path("docs/a.txt")},
+		{Type: EventDelete, Path: path("docs/b.txt")},
+		{Type: EventCreate, Path: path("docs/c.txt")},
+	}, events)
+
+	// A directory renamed is a single move, its files moving along with it
+	events = nil
+	assert.NoError(t, os.Rename(path("albums"), path("photos")))
+	fw.rescan(streamBatch{root: root, changes: []dirChange{{dir: root}}})
+	assert.Equal(t, []Event{{Type: EventMove, Path: path("photos"), OldPath: path("albums")}}, events)
+
+	// A tree moved in is reported as a change to its parent, and compared with all it holds
+	events = nil
+	incoming := filepath.Join(t.TempDir(), "music")
+	assert.NoError(t, os.MkdirAll(filepath.Join(incoming, "live"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(incoming, "live", "song.mp3"), nil, 0644))
+	assert.NoError(t, os.Rename(incoming, path("photos/2024/music")))
+	fw.rescan(streamBatch{root: root, changes: []dirChange{{dir: path("photos/2024")}, {dir: path("photos/2024/music")}}})
+	assert.Equal(t, []Event{
+		{Type: EventCreate, Path: path("photos/2024/music")},
+		{Type: EventCreate, Path: path("photos/2024/music/live")},
+		{Type: EventCreate, Path: path("photos/2024/music/live/song.mp3")},
+	}, events)
+
+	// Dropped events take a rescan of the whole subtree, which covers the directories under
+	// it; excluded directories are left out
+	events = nil
+	assert.NoError(t, os.Remove(path("photos/2024/music/live/song.mp3")))
+	assert.NoError(t, os.WriteFile(path("cache/other"), nil, 0644))
+	fw.rescan(streamBatch{root: root, changes: []dirChange{{dir: path("photos"), subtree: true}, {dir: path("photos/2024")}, {dir: path("cache")}}})
+	assert.Equal(t, []Event{{Type: EventDelete, Path: path("photos/2024/music/live/song.mp3")}}, events)
+}
@@ -11,8 +11,16 @@ import (
 	"github.com/martinshumberto/sync-manager/cli/internal/db"
 	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
 	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/apiclient"
 	"github.com/martinshumberto/sync-manager/common/config"
-	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/identity"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/snapshot"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/martinshumberto/sync-manager/common/transport"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -32,7 +40,8 @@ func main() {
 	// Load configuration
 	cfg, configPath, err := loadConfiguration()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to load configuration")
+		log.Error().Err(err).Msg("Failed to load configuration")
+		os.Exit(commands.ExitConfig)
 	}
 
 	// Save function to be used by commands
@@ -40,12 +49,13 @@ func main() {
 		return config.SaveConfig(cfg, configPath)
 	}
 
-	// Initialize database
-	dbPath, err := db.GetDefaultDBPath()
+	// Initialize database, the local SQLite file unless database.dsn names a central one
+	dsn, err := cfg.DatabaseDSN()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to get database path")
+		log.Error().Err(err).Msg("Failed to get database path")
+		os.Exit(commands.ExitConfig)
 	}
-	dbManager, err := db.NewManager(dbPath)
+	dbManager, err := db.NewManager(dsn)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize database")
 	}
@@ -56,21 +66,63 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to initialize database schema")
 	}
 
+	// Encrypt the sensitive columns with the key from the OS keychain
+	if cfg.Database.Encrypt {
+		if err := dbManager.EnableEncryption(db.KeychainStore{}); err != nil {
+			log.Fatal().Err(err).Msg("Failed to enable database encryption")
+		}
+	}
+
 	// Create repositories
 	folderRepo := repositories.NewFolderRepository(dbManager.GetDB())
 	userRepo := repositories.NewUserRepository(dbManager.GetDB())
+	deviceRepo := repositories.NewDeviceRepository(dbManager.GetDB())
+	excludeRepo := repositories.NewExcludeRepository(dbManager.GetDB())
+	tokenRepo := repositories.NewTokenRepository(dbManager.GetDB())
+	bandwidthRepo := repositories.NewBandwidthRepository(dbManager.GetDB())
+	runRepo := repositories.NewSyncRunRepository(dbManager.GetDB())
 
 	// Create services
 	folderService := services.NewFolderService(folderRepo, cfg)
+	heartbeatPath, _ := heartbeat.DefaultPath()
+	remote := serverClient(cfg)
+	deviceService := services.NewDeviceService(deviceRepo, cfg, heartbeatPath, remote)
+	excludeService := services.NewExcludeService(excludeRepo)
+
+	userService := services.NewUserService(userRepo)
+	tokenService := services.NewTokenService(tokenRepo)
+	bandwidthService := services.NewBandwidthService(bandwidthRepo)
+	runService := services.NewSyncRunService(runRepo)
 
 	// Create agent client
 	agentClient := client.NewAgentClient(cfg, configPath)
 
-	// Default user ID (in a real app, we'd have proper authentication)
-	defaultUserID := uint(1)
-
 	// Ensure a user exists
-	ensureDefaultUser(userRepo, defaultUserID)
+	if err := userService.EnsureDefaultUser(); err != nil {
+		log.Error().Err(err).Msg("Failed to create default user")
+	}
+
+	// Os comandos agem pelo usuário ativo; sem um escolhido, pelo usuário padrão
+	userID := activeUser(userService)
+
+	// O idioma vem de --lang, do ambiente ou da preferência do usuário, e precisa ser conhecido
+	// antes de os comandos serem montados para que a ajuda saia traduzida
+	lang := i18n.LangFromArgs(os.Args[1:])
+	if lang != "" && !i18n.Supported(lang) {
+		log.Warn().Str("lang", lang).Strs("supported", i18n.Languages()).Msg("Unsupported language; ignoring --lang")
+	}
+	preference, err := userService.Language(userID)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read the language preference")
+	}
+	i18n.Set(i18n.Resolve(lang, preference))
+
+	// A configuração é a fonte de verdade das pastas; o banco pode ter ficado para trás
+	if drifts, err := folderService.CheckFolders(userID); err != nil {
+		log.Warn().Err(err).Msg("Failed to compare folder records with the configuration")
+	} else if len(drifts) > 0 {
+		log.Warn().Int("folders", len(drifts)).Msg("Folder records in the database differ from the configuration; run 'sync-manager doctor --fix'")
+	}
 
 	// Define the root command
 	rootCmd := &cobra.Command{
@@ -83,22 +135,35 @@ securely store and sync your files across multiple devices using S3-compatible s
 It provides efficient, background synchronization with minimal resource usage.`,
 	}
 
+	// A flag é lida em loadConfiguration; aqui só é registrada para a ajuda e o parser
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile to use (default: the active profile)")
+	// Assim como --profile, --lang é lida antes de o parser rodar
+	rootCmd.PersistentFlags().String("lang", "", "Language of the output: en or pt (default: from the environment or 'user language')")
+	// Sem perguntas, para rodar em cron ou CI
+	commands.AddNonInteractiveFlag(rootCmd)
+
 	// Version command
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "version",
 		Short: "Print the version information",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("Sync Manager v%s (built %s)\n", Version, BuildTime)
+			i18n.Printf("Sync Manager v%s (built %s)\n", Version, BuildTime)
 		},
 	})
 
 	// Add commands
-	addCommands(rootCmd, cfg, configPath, saveConfig, agentClient, folderService, defaultUserID)
+	addCommands(rootCmd, cfg, configPath, saveConfig, agentClient, folderService, deviceService, excludeService, userService, tokenService, bandwidthService, runService, dbManager, remote, userID)
+
+	// Erros de flags e argumentos saem com o código de erro de configuração
+	commands.ClassifyUsageErrors(rootCmd)
+
+	// Traduz a ajuda de todos os comandos para o idioma escolhido
+	commands.Localize(rootCmd)
 
 	// Execute the command
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(commands.ExitCode(err))
 	}
 }
 
@@ -110,19 +175,26 @@ func loadConfiguration() (*config.Config, string, error) {
 	// Check for config path in environment variable
 	if envPath := os.Getenv("SYNC_MANAGER_CONFIG"); envPath != "" {
 		configPath = envPath
+	} else {
+		// O perfil precisa ser conhecido antes de o cobra analisar as flags
+		_, profilePath, err := config.ResolveProfile(config.ProfileFromArgs(os.Args[1:]))
+		if err != nil {
+			return nil, "", i18n.Errorf("failed to select profile: %w", err)
+		}
+		configPath = profilePath
 	}
 
 	// Try to load the configuration
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to load config: %w", err)
+		return nil, "", i18n.Errorf("failed to load config: %w", err)
 	}
 
 	// If no config path was specified and none was found, get the default path
 	if configPath == "" {
 		configPath, err = config.GetConfigPath()
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to get default config path: %w", err)
+			return nil, "", i18n.Errorf("failed to get default config path: %w", err)
 		}
 	}
 
@@ -152,58 +224,29 @@ func loadConfiguration() (*config.Config, string, error) {
 // addCommands adiciona todos os comandos ao rootCmd
 func addCommands(rootCmd *cobra.Command, cfg *config.Config, configPath string,
 	saveConfig func() error, agentClient *client.AgentClient,
-	folderService *services.FolderService, defaultUserID uint) {
+	folderService *services.FolderService, deviceService *services.DeviceService,
+	excludeService *services.ExcludeService, userService *services.UserService, tokenService *services.TokenService,
+	bandwidthService *services.BandwidthService, runService *services.SyncRunService, dbManager *db.Manager,
+	remote *apiclient.Client, userID uint) {
 
 	// Status command
-	statusCmd := &cobra.Command{
-		Use:   "status",
-		Short: "Show sync status of monitored folders",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// Check if agent is running
-			if err := agentClient.Health(); err != nil {
-				fmt.Println("Agent is not running. Start it with 'sync-manager start'.")
-				return nil
-			}
+	rootCmd.AddCommand(commands.CreateStatusCommand(cfg, agentClient))
 
-			// Get folders from database
-			folders, err := folderService.GetUserFolders(defaultUserID)
-			if err != nil {
-				return fmt.Errorf("failed to get folders: %w", err)
-			}
-
-			if len(folders) == 0 {
-				fmt.Println("No folders configured for synchronization.")
-				return nil
-			}
+	// Stats command
+	if statsPath, err := stats.DefaultPath(); err == nil {
+		rootCmd.AddCommand(commands.CreateStatsCommand(cfg, statsPath))
+	}
 
-			fmt.Println("Synchronization Status:")
-			fmt.Println("----------------------")
-
-			// Display folder status
-			for _, folder := range folders {
-				status := folder.Status
-				if status == "active" {
-					status = "Active"
-				} else {
-					status = "Disabled"
-				}
-
-				fmt.Printf("📂 %s (%s)\n", folder.Name, folder.FolderID)
-				fmt.Printf("   Status: %s\n", status)
-
-				// Find matching config folder to get the path
-				for _, configFolder := range cfg.SyncFolders {
-					if configFolder.ID == folder.FolderID {
-						fmt.Printf("   Path: %s\n", configFolder.Path)
-						break
-					}
-				}
-				fmt.Println()
-			}
-			return nil
-		},
+	// Top command
+	if progressPath, err := progress.DefaultPath(); err == nil {
+		rootCmd.AddCommand(commands.CreateTopCommand(progressPath))
 	}
-	rootCmd.AddCommand(statusCmd)
+
+	// Bandwidth command
+	rootCmd.AddCommand(commands.CreateBandwidthCommand(bandwidthService, cfg))
+
+	// Last run command
+	rootCmd.AddCommand(commands.CreateLastRunCommand(runService, cfg))
 
 	// Start command - starts the agent
 	startCmd := &cobra.Command{
@@ -226,62 +269,168 @@ func addCommands(rootCmd *cobra.Command, cfg *config.Config, configPath string,
 	rootCmd.AddCommand(stopCmd)
 
 	// Add folder management commands
-	folderCommands := commands.CreateFolderCommands(cfg, saveConfig, agentClient, folderService)
+	folderCommands := commands.CreateFolderCommands(cfg, saveConfig, agentClient, folderService, func() (storage.Storage, error) {
+		return storage.StorageFactory(cfg)
+	}, userID)
 	for _, cmd := range folderCommands {
 		rootCmd.AddCommand(cmd)
 	}
 
 	// Add configuration commands
-	configCommands := commands.CreateConfigCommands(cfg, saveConfig)
+	configCommands := commands.CreateConfigCommands(cfg, saveConfig, agentClient)
 	for _, cmd := range configCommands {
 		rootCmd.AddCommand(cmd)
 	}
 
 	// Add sync commands
-	syncCommands := commands.CreateSyncCommands(cfg, agentClient)
+	syncCommands := commands.CreateSyncCommands(cfg, agentClient, runService)
 	for _, cmd := range syncCommands {
 		rootCmd.AddCommand(cmd)
 	}
 
+	// Add conflict commands
+	for _, cmd := range commands.CreateConflictCommands(cfg, agentClient) {
+		rootCmd.AddCommand(cmd)
+	}
+
+	// Add the progress command; the other monitoring commands still simulate their output
+	for _, cmd := range commands.CreateMonitoringCommands(cfg, agentClient) {
+		if cmd.Name() == "progress" {
+			rootCmd.AddCommand(cmd)
+		}
+	}
+
 	// Add device commands
-	deviceCommands := commands.CreateDeviceCommands(cfg)
+	deviceCommands := commands.CreateDeviceCommands(cfg, saveConfig, deviceService, userID)
 	for _, cmd := range deviceCommands {
 		rootCmd.AddCommand(cmd)
 	}
 
+	// Add exclude rule commands
+	for _, cmd := range commands.CreateExcludeCommands(cfg, excludeService) {
+		rootCmd.AddCommand(cmd)
+	}
+
+	// Add snapshot commands
+	snapshotCommands := commands.CreateSnapshotCommands(cfg, func() (snapshot.ObjectStore, error) {
+		return storage.StorageFactory(cfg)
+	})
+	for _, cmd := range snapshotCommands {
+		rootCmd.AddCommand(cmd)
+	}
+
+	// Add restore commands
+	restoreCommands := commands.CreateRestoreCommands(cfg, func() (storage.Storage, error) {
+		return storage.StorageFactory(cfg)
+	})
+	for _, cmd := range restoreCommands {
+		rootCmd.AddCommand(cmd)
+	}
+
+	// Add storage commands
+	storageCommands := commands.CreateStorageCommands(cfg, saveConfig, func() (storage.Storage, error) {
+		return storage.StorageFactory(cfg)
+	})
+	for _, cmd := range storageCommands {
+		rootCmd.AddCommand(cmd)
+	}
+
+	// Add verify commands
+	verifyCommands := commands.CreateVerifyCommands(cfg, func() (storage.Storage, error) {
+		return storage.StorageFactory(cfg)
+	})
+	for _, cmd := range verifyCommands {
+		rootCmd.AddCommand(cmd)
+	}
+
+	// Add the command fetching archived files back
+	rootCmd.AddCommand(commands.CreateFetchCommand(cfg, func() (storage.Storage, error) {
+		return storage.StorageFactory(cfg)
+	}))
+
+	// Add login/logout commands
+	credentialsPath, err := apiclient.DefaultCredentialsPath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get credentials path")
+	} else {
+		authCommands := commands.CreateAuthCommands(cfg, saveConfig, credentialsPath, Version)
+		for _, cmd := range authCommands {
+			rootCmd.AddCommand(cmd)
+		}
+	}
+
+	// Add LAN pairing commands
+	identityPath, err := identity.DefaultPath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get device identity path")
+	} else {
+		for _, cmd := range commands.CreateLANCommands(cfg, saveConfig, identityPath) {
+			rootCmd.AddCommand(cmd)
+		}
+	}
+
+	// Add doctor command
+	rootCmd.AddCommand(commands.CreateDoctorCommand(cfg, configPath, agentClient, folderService, userID))
+
+	// Add user commands
+	rootCmd.AddCommand(commands.CreateUserCommand(userService, userID, config.SetActiveUser))
+
+	// Add API token commands
+	rootCmd.AddCommand(commands.CreateTokenCommand(tokenService, userID))
+
+	// Add sync event commands
+	rootCmd.AddCommand(commands.CreateEventsCommand(remote))
+
+	// Add database commands
+	rootCmd.AddCommand(commands.CreateDatabaseCommand(dbManager, db.KeychainStore{}))
+
 	// Add wizard command
 	wizardCmd := commands.CreateWizardCommand(cfg, saveConfig)
 	rootCmd.AddCommand(wizardCmd)
 }
 
-// ensureDefaultUser garante que um usuário padrão existe no banco de dados
-func ensureDefaultUser(userRepo *repositories.UserRepository, userID uint) {
-	// Verifica se o usuário já existe
-	_, err := userRepo.FindByID(userID)
-	if err == nil {
-		// Usuário já existe
-		return
+// serverClient retorna um cliente da API quando há um servidor configurado e o dispositivo está logado
+func serverClient(cfg *config.Config) *apiclient.Client {
+	if cfg.ApiEndpoint == "" {
+		return nil
 	}
 
-	// Cria um usuário padrão
-	hostname, _ := os.Hostname()
-	if hostname == "" {
-		hostname = "Default Device"
+	credentialsPath, err := apiclient.DefaultCredentialsPath()
+	if err != nil {
+		return nil
 	}
 
-	user := &models.User{
-		ID:           userID,
-		Email:        "user@localhost",
-		Name:         "Local User",
-		Status:       "active",
-		Verified:     true,
-		StorageQuota: 10737418240, // 10GB
+	client, err := apiclient.NewClient(cfg.ApiEndpoint, credentialsPath)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load device credentials")
+		return nil
+	}
+	if !transport.IsDefault(cfg.HTTP) {
+		httpTransport, err := transport.New(cfg.HTTP)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to configure server connection")
+			return nil
+		}
+		client.SetTransport(httpTransport)
+	}
+	if client.Credentials() == nil {
+		return nil
 	}
 
-	err = userRepo.Create(user)
+	return client
+}
+
+// activeUser retorna o usuário escolhido com 'user use', ou o usuário padrão
+func activeUser(userService *services.UserService) uint {
+	selected, err := config.ActiveUser()
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to create default user")
+		log.Warn().Err(err).Msg("Failed to read the active user, using the default user")
+	}
+	userID, err := userService.ResolveUser(selected)
+	if err != nil {
+		log.Warn().Err(err).Msg("Active user not found, using the default user")
 	}
+	return userID
 }
 
 // startAgent starts the sync agent
@@ -290,7 +439,7 @@ func startAgent() error {
 	// such as systemd, launchd, or a service manager.
 	// For now, just simulate starting the agent.
 
-	fmt.Println("Starting Sync Manager agent...")
+	i18n.Println("Starting Sync Manager agent...")
 
 	// For demo purposes, we'll just print a message
 	// In a real implementation, we would:
@@ -298,7 +447,7 @@ func startAgent() error {
 	// 2. Start the agent as a background service
 	// 3. Wait for it to initialize
 
-	fmt.Println("Agent started in the background.")
+	i18n.Println("Agent started in the background.")
 	return nil
 }
 
@@ -307,7 +456,7 @@ func stopAgent() error {
 	// In a real implementation, we would use a proper method to stop the agent
 	// For now, just simulate stopping the agent.
 
-	fmt.Println("Stopping Sync Manager agent...")
+	i18n.Println("Stopping Sync Manager agent...")
 
 	// For demo purposes, we'll just print a message
 	// In a real implementation, we would:
@@ -315,7 +464,7 @@ func stopAgent() error {
 	// 2. Send a signal to stop it gracefully
 	// 3. Wait for it to shut down
 
-	fmt.Println("Agent stopped.")
+	i18n.Println("Agent stopped.")
 	return nil
 }
 
@@ -1,29 +1,47 @@
 package client
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/control"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/i18n"
 	"github.com/martinshumberto/sync-manager/common/models"
-	"github.com/rs/zerolog/log"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/trigger"
 )
 
+// ErrAgentNotRunning is returned by Health when the agent is not running
+var ErrAgentNotRunning = i18n.NewError("agent is not running")
+
+// ErrNoToken is returned by the calls to the control API of the agent when no API token is set
+var ErrNoToken = i18n.NewError("no API token to reach the agent")
+
+// TokenEnv names the environment variable holding the API token the control API of the
+// agent is called with, one created by 'sync-manager token create'
+const TokenEnv = "SYNC_MANAGER_TOKEN"
+
 // AgentClient represents a client to communicate with the agent
 type AgentClient struct {
 	Config     *config.Config
 	ConfigPath string
+	Token      string // API token of the control API, empty when none is set
 }
 
-// NewAgentClient creates a new agent client
+// NewAgentClient creates a new agent client, authenticating with the token in TokenEnv
 func NewAgentClient(cfg *config.Config, configPath string) *AgentClient {
 	return &AgentClient{
 		Config:     cfg,
 		ConfigPath: configPath,
+		Token:      os.Getenv(TokenEnv),
 	}
 }
 
@@ -32,11 +50,11 @@ func (c *AgentClient) Health() error {
 	// Check if agent process is running
 	running, err := c.isAgentRunning()
 	if err != nil {
-		return fmt.Errorf("failed to check agent status: %w", err)
+		return i18n.Errorf("failed to check agent status: %w", err)
 	}
 
 	if !running {
-		return fmt.Errorf("agent is not running")
+		return ErrAgentNotRunning
 	}
 
 	return nil
@@ -59,41 +77,94 @@ func (c *AgentClient) GetFolders() ([]models.FolderResponse, error) {
 	return folders, nil
 }
 
-// GetStatus gets the agent status
-func (c *AgentClient) GetStatus() (interface{}, error) {
-	// In a real implementation, we would get status directly from agent
-	status := map[string]interface{}{
-		"running": true,
-		"version": "dev",
-		"folders": len(c.Config.SyncFolders),
+// GetStatus returns the folder states the agent publishes, or nil when the agent
+// has not published them recently
+func (c *AgentClient) GetStatus() (*status.Snapshot, error) {
+	path, err := status.DefaultPath()
+	if err != nil {
+		return nil, err
 	}
 
-	return status, nil
+	snap, err := status.Read(path)
+	if err != nil || snap == nil || !heartbeat.IsOnline(snap.UpdatedAt) {
+		return nil, err
+	}
+	return snap, nil
 }
 
-// TriggerSync requests the agent to start a sync operation
-func (c *AgentClient) TriggerSync(folderID string) error {
-	// In a real implementation, we might use a trigger file or IPC
-	// For now, we'll just log a message since we don't have LastSyncRequest in the config
+// TriggerSync asks the agent to sync a folder, or every folder when folderID is empty.
+// With restart a sync the agent is running is cancelled and started over instead of joined.
+func (c *AgentClient) TriggerSync(folderID string, restart bool) error {
+	if folderID != "" && !c.hasFolder(folderID) {
+		return i18n.Errorf("folder not found: %s", folderID)
+	}
 
-	if folderID == "" {
-		// Trigger sync for all folders
-		log.Info().Msg("Triggering sync for all folders")
-		// In a real implementation, we would communicate with the agent
-		return nil
+	path, err := trigger.DefaultPath()
+	if err != nil {
+		return err
 	}
+	return trigger.Add(path, folderID, restart, time.Now())
+}
 
-	// Find the folder
+// TriggerPathSync asks the agent to sync only the subtree at relPath of a folder, a path
+// relative to it. With restart a sync the agent is running is cancelled and started over.
+func (c *AgentClient) TriggerPathSync(folderID, relPath string, restart bool) error {
+	if !c.hasFolder(folderID) {
+		return i18n.Errorf("folder not found: %s", folderID)
+	}
+
+	path, err := trigger.DefaultPath()
+	if err != nil {
+		return err
+	}
+	return trigger.AddPath(path, folderID, relPath, restart, time.Now())
+}
+
+// GetSetting returns a setting of the configuration the agent runs with, as config get names
+// it. ErrAgentNotRunning is returned when no agent serves the configuration, and ErrNoToken
+// when there is no token to ask it with.
+func (c *AgentClient) GetSetting(key, target string) (string, error) {
+	if c.Token == "" {
+		return "", ErrNoToken
+	}
+	value, err := c.control().Get(context.Background(), key, target)
+	return value, controlError(err)
+}
+
+// SetSettings asks the agent to change settings, which it checks together against the whole
+// configuration, saves and applies at once. ErrAgentNotRunning is returned when no agent
+// serves the configuration, and ErrNoToken when there is no token to ask it with.
+func (c *AgentClient) SetSettings(settings ...control.Setting) error {
+	if c.Token == "" {
+		return ErrNoToken
+	}
+	return controlError(c.control().Set(context.Background(), settings...))
+}
+
+// control returns a client of the control API of the agent running the configuration
+func (c *AgentClient) control() *control.Client {
+	return control.NewClient(control.SocketPath(c.ConfigPath), c.Token)
+}
+
+// controlError returns the error of a call to the control API as the CLI reports it
+func controlError(err error) error {
+	switch {
+	case errors.Is(err, control.ErrUnavailable):
+		return ErrAgentNotRunning
+	case errors.Is(err, control.ErrUnauthorized):
+		return i18n.Errorf("the agent refused the API token in %s; create one with 'sync-manager token create'", TokenEnv)
+	}
+	return err
+}
+
+// hasFolder reports whether a folder is configured
+func (c *AgentClient) hasFolder(folderID string) bool {
 	for _, folder := range c.Config.SyncFolders {
 		if folder.ID == folderID {
-			log.Info().Str("folder", folder.Path).Msg("Triggering sync for folder")
-
-			// In a real implementation, we would modify the folder or use IPC
-			return nil
+			return true
 		}
 	}
-
-	return fmt.Errorf("folder not found: %s", folderID)
+	return false
 }
 
 // Helper method to check if agent is running
@@ -129,11 +200,6 @@ func (c *AgentClient) isAgentRunning() (bool, error) {
 }
 
 // Helper method to get the folder status
-func (c *AgentClient) getFolderStatus(folder config.SyncFolder) string {
-	if !folder.Enabled {
-		return "disabled"
-	}
-
-	// In a real implementation, we would get the actual status from the agent
-	return "active"
+func (c *AgentClient) getFolderStatus(folder config.SyncFolder) models.Status {
+	return models.FolderStatusOf(folder.Enabled, folder.Paused)
 }
@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/apiclient"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/transport"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// passwordEnv is the environment variable read when --password-stdin is not given
+const passwordEnv = "SYNC_MANAGER_PASSWORD"
+
+// CreateAuthCommands returns the login and logout commands
+func CreateAuthCommands(cfg *config.Config, saveFn func() error, credentialsPath string, version string) []*cobra.Command {
+	// Login command
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in and register this device",
+		Long: `Log in to the Sync Manager server and register this device.
+The device receives its own token, which is stored in the credentials file and
+rotated automatically by the agent before it expires.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			endpoint, _ := cmd.Flags().GetString("endpoint")
+			email, _ := cmd.Flags().GetString("email")
+			passwordStdin, _ := cmd.Flags().GetBool("password-stdin")
+
+			if endpoint != "" && endpoint != cfg.ApiEndpoint {
+				cfg.ApiEndpoint = endpoint
+				if err := saveFn(); err != nil {
+					return i18n.Errorf("failed to save configuration: %w", err)
+				}
+			}
+			if cfg.ApiEndpoint == "" {
+				return configError(i18n.Errorf("API endpoint is not configured, use --endpoint"))
+			}
+
+			reader := bufio.NewReader(cmd.InOrStdin())
+			var password string
+			if passwordStdin {
+				if email == "" {
+					return configError(i18n.Errorf("--password-stdin requires --email"))
+				}
+				line, _ := reader.ReadString('\n')
+				password = strings.TrimRight(line, "\r\n")
+			} else {
+				if email == "" {
+					email = prompt(cmd, reader, "Email: ")
+				}
+				password = os.Getenv(passwordEnv)
+				if password == "" {
+					password = promptPassword(cmd, reader, "Password: ")
+				}
+			}
+			if email == "" || password == "" {
+				return configError(i18n.Errorf("email and password are required"))
+			}
+
+			client, err := apiclient.NewClient(cfg.ApiEndpoint, credentialsPath)
+			if err != nil {
+				return err
+			}
+			if !transport.IsDefault(cfg.HTTP) {
+				httpTransport, err := transport.New(cfg.HTTP)
+				if err != nil {
+					return i18n.Errorf("failed to configure server connection: %w", err)
+				}
+				client.SetTransport(httpTransport)
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), time.Minute)
+			defer cancel()
+
+			login, err := client.Login(ctx, email, password)
+			if err != nil {
+				return err
+			}
+
+			deviceName := cfg.DeviceName
+			if deviceName == "" {
+				deviceName, _ = os.Hostname()
+			}
+
+			resp, err := client.RegisterDevice(ctx, login.Token.Token, email, models.DeviceRegistrationRequest{
+				DeviceID:      cfg.DeviceID,
+				Name:          deviceName,
+				Platform:      runtime.GOARCH,
+				OS:            runtime.GOOS,
+				ClientVersion: version,
+			})
+			if err != nil {
+				return err
+			}
+
+			i18n.Fprintf(cmd.OutOrStdout(), "Logged in as %s\n", email)
+			i18n.Fprintf(cmd.OutOrStdout(), "Device %s registered (token expires %s)\n",
+				deviceName, resp.ExpiresAt.Local().Format(time.RFC1123))
+			return nil
+		},
+	}
+
+	loginCmd.Flags().String("endpoint", "", "API endpoint of the Sync Manager server")
+	loginCmd.Flags().String("email", "", "Account email")
+	loginCmd.Flags().Bool("password-stdin", false, "Read the account password from standard input (or set "+passwordEnv+")")
+
+	// Logout command
+	logoutCmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove this device's stored token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := apiclient.DeleteCredentials(credentialsPath); err != nil {
+				return err
+			}
+			i18n.Fprintln(cmd.OutOrStdout(), "Logged out.")
+			return nil
+		},
+	}
+
+	return []*cobra.Command{loginCmd, logoutCmd}
+}
+
+// prompt prints a label and reads one line of input, none when not interactive
+func prompt(cmd *cobra.Command, reader *bufio.Reader, label string) string {
+	if nonInteractive(cmd) {
+		return ""
+	}
+	fmt.Fprint(cmd.OutOrStdout(), label)
+	line, _ := reader.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// promptPassword prints a label and reads a password without echoing it when the input is a
+// terminal, or one line of input otherwise. Nothing is read when not interactive.
+func promptPassword(cmd *cobra.Command, reader *bufio.Reader, label string) string {
+	if nonInteractive(cmd) {
+		return ""
+	}
+	file, ok := cmd.InOrStdin().(*os.File)
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		return prompt(cmd, reader, label)
+	}
+
+	fmt.Fprint(cmd.OutOrStdout(), label)
+	password, _ := term.ReadPassword(int(file.Fd()))
+	fmt.Fprintln(cmd.OutOrStdout())
+	return string(password)
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// CreateBandwidthCommand returns the command reporting the traffic the agent recorded for a month
+func CreateBandwidthCommand(bandwidthService *services.BandwidthService, cfg *config.Config) *cobra.Command {
+	bandwidthCmd := &cobra.Command{
+		Use:   "bandwidth",
+		Short: "Show bandwidth usage",
+		Long: `Shows how much each folder uploaded and downloaded in a month, as the agent recorded it.
+Use --daily to break the month down by day. With a monthly cap set (bandwidth.monthly_cap),
+the agent pauses syncing once the device's traffic reaches it, until the next month.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			month, _ := cmd.Flags().GetString("month")
+			daily, _ := cmd.Flags().GetBool("daily")
+			asJSON, _ := cmd.Flags().GetBool("json")
+			allDevices, _ := cmd.Flags().GetBool("all-devices")
+			if month == "" {
+				month = time.Now().Format("2006-01")
+			}
+
+			deviceID := cfg.DeviceID
+			if allDevices {
+				deviceID = ""
+			}
+			report, err := bandwidthService.MonthlyReport(month, deviceID, daily)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if asJSON {
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(report)
+			}
+
+			if len(report.Rows) == 0 {
+				i18n.Fprintf(out, "No traffic recorded for %s.\n", month)
+			} else {
+				table := tablewriter.NewWriter(out)
+				header := i18n.Strings("Folder", "Uploaded", "Downloaded")
+				if daily {
+					header = append([]string{i18n.T("Day")}, header...)
+				}
+				table.SetHeader(header)
+				for _, row := range report.Rows {
+					line := []string{row.FolderID, formatSize(row.BytesUploaded), formatSize(row.BytesDownloaded)}
+					if daily {
+						line = append([]string{row.Day}, line...)
+					}
+					table.Append(line)
+				}
+				footer := []string{i18n.T("Total"), formatSize(report.BytesUploaded), formatSize(report.BytesDownloaded)}
+				if daily {
+					footer = append([]string{""}, footer...)
+				}
+				table.SetFooter(footer)
+				table.Render()
+			}
+
+			if limit := cfg.Bandwidth.MonthlyCapBytes; limit > 0 && !allDevices {
+				i18n.Fprintf(out, "Monthly cap: %s of %s used (%.0f%%)\n",
+					formatSize(report.Total()), formatSize(limit), float64(report.Total())*100/float64(limit))
+			}
+			return nil
+		},
+	}
+	bandwidthCmd.Flags().String("month", "", "Month to report, as YYYY-MM (default: the current month)")
+	bandwidthCmd.Flags().Bool("daily", false, "Break the month down by day")
+	bandwidthCmd.Flags().Bool("json", false, "Print the report as JSON")
+	bandwidthCmd.Flags().Bool("all-devices", false, "Include the traffic of every device sharing the database")
+
+	return bandwidthCmd
+}
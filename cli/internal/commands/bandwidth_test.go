@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandwidthCommand(t *testing.T) {
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	defer dbManager.Close()
+	assert.NoError(t, dbManager.InitSchema())
+
+	// Tráfego registrado pelo agente deste e de outro dispositivo
+	for _, usage := range []models.BandwidthUsage{
+		{Day: "2024-06-01", FolderID: "docs", DeviceID: "laptop", BytesUploaded: 1024, BytesDownloaded: 2048},
+		{Day: "2024-06-02", FolderID: "docs", DeviceID: "laptop", BytesUploaded: 1024},
+		{Day: "2024-06-02", FolderID: "photos", DeviceID: "laptop", BytesDownloaded: 4096},
+		{Day: "2024-06-02", FolderID: "docs", DeviceID: "desktop", BytesUploaded: 8192},
+		{Day: "2024-07-01", FolderID: "docs", DeviceID: "laptop", BytesUploaded: 1 << 20},
+	} {
+		assert.NoError(t, dbManager.GetDB().Create(&usage).Error)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	cfg.Bandwidth.MonthlyCapBytes = 16384
+	bandwidthService := services.NewBandwidthService(repositories.NewBandwidthRepository(dbManager.GetDB()))
+	run := func(args ...string) (string, error) {
+		bandwidthCmd := CreateBandwidthCommand(bandwidthService, cfg)
+		var out bytes.Buffer
+		bandwidthCmd.SetOut(&out)
+		bandwidthCmd.SetArgs(args)
+		err := bandwidthCmd.Execute()
+		return out.String(), err
+	}
+
+	output, err := run("--month", "2024-06")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "photos")
+	assert.Contains(t, output, "Monthly cap: 8.0 KiB of 16.0 KiB used (50%)")
+
+	// Por dia, em JSON, e somando todos os dispositivos
+	output, err = run("--month", "2024-06", "--daily", "--json", "--all-devices")
+	assert.NoError(t, err)
+	var report services.BandwidthReport
+	assert.NoError(t, json.Unmarshal([]byte(output), &report))
+	assert.Equal(t, "2024-06", report.Month)
+	assert.Equal(t, []services.BandwidthRow{
+		{Day: "2024-06-01", FolderID: "docs", BytesUploaded: 1024, BytesDownloaded: 2048},
+		{Day: "2024-06-02", FolderID: "docs", BytesUploaded: 9216},
+		{Day: "2024-06-02", FolderID: "photos", BytesDownloaded: 4096},
+	}, report.Rows)
+	assert.Equal(t, int64(10240), report.BytesUploaded)
+
+	output, err = run("--month", "2024-05")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "No traffic recorded for 2024-05.")
+
+	_, err = run("--month", "june")
+	assert.Error(t, err)
+}
@@ -1,15 +1,22 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
-	"strconv"
+	"os"
+	"sort"
+	"strings"
 
+	"github.com/martinshumberto/sync-manager/cli/internal/client"
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/control"
+	"github.com/martinshumberto/sync-manager/common/database"
+	"github.com/martinshumberto/sync-manager/common/i18n"
 	"github.com/spf13/cobra"
 )
 
 // CreateConfigCommands returns the configuration-related commands
-func CreateConfigCommands(cfg *config.Config, saveFn func() error) []*cobra.Command {
+func CreateConfigCommands(cfg *config.Config, saveFn func() error, agentClient *client.AgentClient) []*cobra.Command {
 	// Config root command
 	configCmd := &cobra.Command{
 		Use:   "config",
@@ -21,29 +28,25 @@ func CreateConfigCommands(cfg *config.Config, saveFn func() error) []*cobra.Comm
 	configGetCmd := &cobra.Command{
 		Use:   "get [key]",
 		Short: "Display current configuration",
-		Long:  `Display the current configuration. If a key is provided, only that setting is shown.`,
+		Long: `Display the current configuration. If a key is provided, only that setting is shown.
+storage.* keys belong to the first storage target unless --target names another one.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				key := args[0]
-				// TODO: Implement fetching specific configuration values
-				switch key {
-				case "storage.provider":
-					fmt.Printf("%s: %s\n", key, cfg.StorageProvider)
-				case "storage.s3.bucket":
-					fmt.Printf("%s: %s\n", key, cfg.S3Config.Bucket)
-				case "storage.minio.bucket":
-					fmt.Printf("%s: %s\n", key, cfg.MinioConfig.Bucket)
-				case "storage.minio.endpoint":
-					fmt.Printf("%s: %s\n", key, cfg.MinioConfig.Endpoint)
-				case "storage.gcs.bucket":
-					fmt.Printf("%s: %s\n", key, cfg.GCSConfig.Bucket)
-				case "storage.local.root_dir":
-					fmt.Printf("%s: %s\n", key, cfg.LocalConfig.RootDir)
-				case "throttle.bandwidth":
-					fmt.Printf("%s: %d bytes/sec\n", key, cfg.ThrottleBytes)
-				default:
-					fmt.Printf("Unknown configuration key: %s\n", key)
+				target, _ := cmd.Flags().GetString("target")
+
+				// O agente em execução responde com a configuração que aplica
+				value, err := "", client.ErrAgentNotRunning
+				if agentClient != nil {
+					value, err = agentClient.GetSetting(key, target)
+				}
+				if errors.Is(err, client.ErrAgentNotRunning) || errors.Is(err, client.ErrNoToken) {
+					value, err = cfg.Get(key, target)
+				}
+				if err != nil {
+					return err
 				}
+				fmt.Printf("%s: %s\n", key, value)
 				return nil
 			}
 
@@ -53,75 +56,69 @@ func CreateConfigCommands(cfg *config.Config, saveFn func() error) []*cobra.Comm
 		},
 	}
 
+	configGetCmd.Flags().String("target", "", "Storage target the storage.* key belongs to (default: the first one)")
+
 	// Config set command
 	configSetCmd := &cobra.Command{
 		Use:   "set <key> <value>",
 		Short: "Set configuration value",
-		Long:  `Set a specific configuration value.`,
-		Args:  cobra.ExactArgs(2),
+		Long: `Set a specific configuration value, or several at once given as more key and value
+pairs. storage.* keys change the first storage target unless --target names another one;
+setting storage.provider on a target that does not exist yet adds it.
+
+A running agent, reached with the API token in SYNC_MANAGER_TOKEN, checks the change against
+the whole configuration, saves it and applies it right away; settings that only make sense
+together, like a new provider and its bucket, must be set at once. Without a running agent or
+a token the configuration file is changed, and a running agent applies it once it reads the
+file again.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 || len(args)%2 != 0 {
+				return i18n.Errorf("expected a key and a value, or several pairs of them")
+			}
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			key := args[0]
-			value := args[1]
-
-			// Set the appropriate configuration
-			switch key {
-			case "storage.provider":
-				// Verificar se o provedor é suportado
-				switch value {
-				case "s3", "minio", "gcs", "local":
-					cfg.StorageProvider = value
-				default:
-					return fmt.Errorf("unsupported storage provider: %s (supported: s3, minio, gcs, local)", value)
-				}
-			case "storage.s3.bucket":
-				cfg.S3Config.Bucket = value
-			case "storage.s3.region":
-				cfg.S3Config.Region = value
-			case "storage.s3.endpoint":
-				cfg.S3Config.Endpoint = value
-			case "storage.s3.access_key":
-				cfg.S3Config.AccessKey = value
-			case "storage.s3.secret_key":
-				cfg.S3Config.SecretKey = value
-			case "storage.minio.bucket":
-				cfg.MinioConfig.Bucket = value
-			case "storage.minio.endpoint":
-				cfg.MinioConfig.Endpoint = value
-			case "storage.minio.region":
-				cfg.MinioConfig.Region = value
-			case "storage.minio.access_key":
-				cfg.MinioConfig.AccessKey = value
-			case "storage.minio.secret_key":
-				cfg.MinioConfig.SecretKey = value
-			case "storage.gcs.bucket":
-				cfg.GCSConfig.Bucket = value
-			case "storage.gcs.project_id":
-				cfg.GCSConfig.ProjectID = value
-			case "storage.gcs.credentials_file":
-				cfg.GCSConfig.CredentialsFile = value
-			case "storage.local.root_dir":
-				cfg.LocalConfig.RootDir = value
-			case "throttle.bandwidth":
-				// This would need proper parsing for a number
-				bandwidth, err := strconv.ParseInt(value, 10, 64)
+			target, _ := cmd.Flags().GetString("target")
+			var settings []control.Setting
+			for i := 0; i < len(args); i += 2 {
+				settings = append(settings, control.Setting{Key: args[i], Value: args[i+1], Target: target})
+			}
+
+			// O agente valida, salva e aplica a alteração de uma vez
+			err := client.ErrAgentNotRunning
+			if agentClient != nil {
+				err = agentClient.SetSettings(settings...)
+			}
+			if !errors.Is(err, client.ErrAgentNotRunning) && !errors.Is(err, client.ErrNoToken) {
 				if err != nil {
-					return fmt.Errorf("invalid bandwidth value: %s (must be a number)", value)
+					return err
+				}
+				for _, setting := range settings {
+					i18n.Printf("Configuration %s set to %s and applied by the agent\n", setting.Key, setting.Value)
 				}
-				cfg.ThrottleBytes = bandwidth
-			default:
-				return fmt.Errorf("unknown configuration key: %s", key)
+				return nil
 			}
 
-			// Save the configuration
+			// Sem agente em execução, ou sem token para falar com ele, a alteração vai para o
+			// arquivo, que o agente relê
+			for _, setting := range settings {
+				if err := cfg.Set(setting.Key, setting.Value, setting.Target); err != nil {
+					return err
+				}
+			}
 			if err := saveFn(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
+				return i18n.Errorf("failed to save configuration: %w", err)
 			}
 
-			fmt.Printf("Configuration %s set to %s\n", key, value)
+			for _, setting := range settings {
+				i18n.Printf("Configuration %s set to %s\n", setting.Key, setting.Value)
+			}
 			return nil
 		},
 	}
 
+	configSetCmd.Flags().String("target", "", "Storage target the storage.* key changes (default: the first one)")
+
 	// Config reset command
 	configResetCmd := &cobra.Command{
 		Use:   "reset",
@@ -129,11 +126,8 @@ func CreateConfigCommands(cfg *config.Config, saveFn func() error) []*cobra.Comm
 		Long:  `Reset all configuration settings to their default values.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Ask for confirmation
-			fmt.Print("This will reset all configuration to default values. Continue? (y/n): ")
-			var response string
-			fmt.Scanln(&response)
-			if response != "y" && response != "Y" {
-				fmt.Println("Operation cancelled.")
+			if !confirm(cmd, "This will reset all configuration to default values. Continue? (y/n): ") {
+				i18n.Println("Operation cancelled.")
 				return nil
 			}
 
@@ -146,60 +140,376 @@ func CreateConfigCommands(cfg *config.Config, saveFn func() error) []*cobra.Comm
 
 			// Save the configuration
 			if err := saveFn(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
+				return i18n.Errorf("failed to save configuration: %w", err)
 			}
 
-			fmt.Println("Configuration reset to default values.")
+			i18n.Println("Configuration reset to default values.")
 			return nil
 		},
 	}
 
+	// Config export command
+	configExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export configuration as a portable bundle",
+		Long: `Write the folder definitions, excludes and storage settings to a YAML bundle
+that can be imported on another machine. Credentials are included in plain text
+unless --redact-secrets is given or a passphrase is provided to encrypt them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			redact, _ := cmd.Flags().GetBool("redact-secrets")
+			output, _ := cmd.Flags().GetString("output")
+
+			bundle, err := config.NewBundle(cfg, config.BundleOptions{
+				RedactSecrets: redact,
+				Passphrase:    bundlePassphrase(cmd),
+			})
+			if err != nil {
+				return i18n.Errorf("failed to create bundle: %w", err)
+			}
+
+			data, err := bundle.Marshal()
+			if err != nil {
+				return err
+			}
+
+			if output == "" || output == "-" {
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+
+			if err := os.WriteFile(output, data, 0600); err != nil {
+				return i18n.Errorf("failed to write bundle: %w", err)
+			}
+
+			i18n.Fprintf(cmd.ErrOrStderr(), "Configuration exported to %s\n", output)
+			return nil
+		},
+	}
+
+	configExportCmd.Flags().Bool("redact-secrets", false, "Leave credentials out of the bundle")
+	configExportCmd.Flags().String("passphrase", "", "Encrypt credentials with this passphrase (or set "+bundlePassphraseEnv+")")
+	configExportCmd.Flags().StringP("output", "o", "", "Write the bundle to a file instead of stdout")
+
+	// Config import command
+	configImportCmd := &cobra.Command{
+		Use:   "import <bundle-file>",
+		Short: "Import configuration from a bundle",
+		Long: `Apply a bundle created with 'config export'. Storage settings are replaced,
+folders are added or updated by ID and this device's identity is kept.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return i18n.Errorf("failed to read bundle: %w", err)
+			}
+
+			bundle, err := config.ParseBundle(data)
+			if err != nil {
+				return err
+			}
+
+			if err := bundle.Apply(cfg, bundlePassphrase(cmd)); err != nil {
+				return err
+			}
+
+			// Save the configuration
+			if err := saveFn(); err != nil {
+				return i18n.Errorf("failed to save configuration: %w", err)
+			}
+
+			i18n.Printf("Imported configuration with %d folder(s) and storage targets %s\n", len(bundle.Folders), strings.Join(cfg.TargetNames(), ", "))
+			if !bundle.HasSecrets() {
+				i18n.Println("The bundle has no credentials; set them with 'config set' before syncing.")
+			}
+			for _, folder := range bundle.Folders {
+				if _, err := os.Stat(folder.Path); err != nil {
+					i18n.Printf("Warning: folder %s (ID: %s) does not exist on this machine\n", folder.Path, folder.ID)
+				}
+			}
+			return nil
+		},
+	}
+
+	configImportCmd.Flags().String("passphrase", "", "Passphrase used to encrypt the bundle credentials (or set "+bundlePassphraseEnv+")")
+
 	// Add subcommands to config command
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configResetCmd)
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+	configCmd.AddCommand(createValidateCommand(cfg))
+	configCmd.AddCommand(createProfileCommand())
 
 	return []*cobra.Command{configCmd}
 }
 
+// createProfileCommand returns the command managing named configuration profiles
+func createProfileCommand() *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage configuration profiles",
+		Long: `Profiles are named configurations, each with its own storage, folders and
+device identity. Select one for a single command with --profile or the
+` + config.ProfileEnv + ` variable, or make it the default with 'config profile use'.`,
+	}
+
+	profileListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List configuration profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := config.ListProfiles()
+			if err != nil {
+				return err
+			}
+			active, err := config.ActiveProfile()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for _, name := range names {
+				marker := " "
+				if name == active {
+					marker = "*"
+				}
+				path, err := config.ProfilePath(name)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "%s %-16s %s\n", marker, name, path)
+			}
+			return nil
+		},
+	}
+
+	profileCreateCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a configuration profile",
+		Long: `Create a profile with the default settings. It gets its own device identity
+the first time it is used; configure it with 'sync-manager --profile <name> config set'.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := config.CreateProfile(args[0])
+			if err != nil {
+				return err
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "Profile %s created at %s\n", args[0], path)
+			return nil
+		},
+	}
+
+	profileUseCmd := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Make a profile the default",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.SetActiveProfile(args[0]); err != nil {
+				return err
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "Now using profile %s. Restart the agent to apply it.\n", args[0])
+			return nil
+		},
+	}
+
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	return profileCmd
+}
+
+// bundlePassphraseEnv is the environment variable read when --passphrase is not given
+const bundlePassphraseEnv = "SYNC_MANAGER_BUNDLE_PASSPHRASE"
+
+// bundlePassphrase returns the bundle passphrase from the flag or the environment
+func bundlePassphrase(cmd *cobra.Command) string {
+	if passphrase, _ := cmd.Flags().GetString("passphrase"); passphrase != "" {
+		return passphrase
+	}
+	return os.Getenv(bundlePassphraseEnv)
+}
+
 // DisplayConfig imprime a configuração atual
 func DisplayConfig(cfg *config.Config) {
-	fmt.Println("Current Configuration:")
+	i18n.Println("Current Configuration:")
 	fmt.Println("---------------------")
-	fmt.Printf("Device ID: %s\n", cfg.DeviceID)
-	fmt.Printf("Device Name: %s\n", cfg.DeviceName)
-	fmt.Printf("Storage Provider: %s\n", cfg.StorageProvider)
-
-	// Exibir detalhes específicos de acordo com o provedor de armazenamento
-	switch cfg.StorageProvider {
-	case "s3":
-		fmt.Println("\nS3 Storage Configuration:")
-		fmt.Printf("  Bucket: %s\n", cfg.S3Config.Bucket)
-		fmt.Printf("  Region: %s\n", cfg.S3Config.Region)
-		if cfg.S3Config.Endpoint != "" {
-			fmt.Printf("  Endpoint: %s\n", cfg.S3Config.Endpoint)
+	i18n.Printf("Device ID: %s\n", cfg.DeviceID)
+	i18n.Printf("Device Name: %s\n", cfg.DeviceName)
+	for i := range cfg.Targets {
+		displayTarget(&cfg.Targets[i], i == 0)
+	}
+
+	if transport := describeTransport(cfg.HTTP); transport != "" {
+		i18n.Printf("\nServer Connection: %s\n", transport)
+	}
+
+	i18n.Printf("\nMax Concurrency: %d\n", cfg.MaxConcurrency)
+	if cfg.Concurrency.Mode == config.ConcurrencyAdaptive {
+		i18n.Printf("Adaptive Concurrency: from %d, between %d and %d\n", cfg.MaxConcurrency, cfg.Concurrency.Min, cfg.Concurrency.Max)
+	}
+	i18n.Printf("Throttle Bandwidth: %d bytes/sec\n", cfg.ThrottleBytes)
+	i18n.Printf("Downloads: %d parallel, %d bytes/sec limit, %d byte chunks\n", cfg.Download.MaxConcurrency, cfg.Download.ThrottleBytes, cfg.Download.ChunkSize)
+	i18n.Printf("Sparse Files: %s\n", cfg.Files.Sparse)
+	i18n.Printf("Max File Size: %s\n", describeMaxFileSize(cfg.Files.MaxFileSize))
+	i18n.Printf("Cloud Placeholders: %s\n", describePlaceholders(cfg.Files.HydratePlaceholders))
+	i18n.Printf("Priority: %s, hashing limited to %d bytes/sec\n", cfg.Priority.Level, cfg.Priority.HashThrottleBytes)
+	i18n.Printf("Upload Order: size weight %g, recency weight %g, folder weight %g\n", cfg.Scheduling.SizeWeight, cfg.Scheduling.RecencyWeight, cfg.Scheduling.FolderWeight)
+	if cfg.Database.DSN != "" {
+		i18n.Printf("Database: %s (%s)\n", database.Redact(cfg.Database.DSN), database.Driver(cfg.Database.DSN))
+	} else {
+		i18n.Println("Database: local SQLite file")
+	}
+	i18n.Printf("Database Encryption: %v\n", cfg.Database.Encrypt)
+	if cfg.Bandwidth.MonthlyCapBytes > 0 {
+		i18n.Printf("Monthly Cap: %s\n", formatSize(cfg.Bandwidth.MonthlyCapBytes))
+	}
+	if cfg.Cache.Dir != "" {
+		i18n.Printf("Staging Area: %s, up to %s\n", cfg.Cache.Dir, formatSize(cfg.Cache.MaxBytes))
+	} else {
+		i18n.Printf("Staging Area: up to %s\n", formatSize(cfg.Cache.MaxBytes))
+	}
+	if dir, err := cfg.Plugins.Directory(); err == nil {
+		i18n.Printf("Storage Plugins: %s\n", dir)
+	}
+	i18n.Printf("On Battery: %s\n", describePowerPolicy(cfg.Power.OnBattery))
+	i18n.Printf("On Metered Connection: %s\n", describePowerPolicy(cfg.Power.OnMetered))
+	if len(cfg.Power.Processes) > 0 {
+		i18n.Printf("While Running %s: %s\n", strings.Join(cfg.Power.Processes, ", "), describePowerPolicy(cfg.Power.OnProcesses))
+	}
+	i18n.Printf("Sync Interval: %s\n", cfg.SyncInterval.String())
+	if cfg.FullScanInterval > 0 {
+		i18n.Printf("Full Scan Interval: %s\n", cfg.FullScanInterval.String())
+	} else {
+		i18n.Println("Full Scan Interval: every sync")
+	}
+	if cfg.LAN.Enabled {
+		i18n.Printf("LAN Sync: enabled on %s (%d trusted devices)\n", cfg.LAN.Listen, len(cfg.LAN.Peers))
+	} else {
+		i18n.Println("LAN Sync: disabled")
+	}
+}
+
+// displayTarget prints the settings of a storage target for its type
+func displayTarget(target *config.StorageTarget, isDefault bool) {
+	name := target.Name
+	if isDefault {
+		name += " " + i18n.T("(default)")
+	}
+	i18n.Printf("\nStorage Target %s: %s\n", name, target.Type)
+
+	// Exibir detalhes específicos de acordo com o tipo do destino
+	switch target.Type {
+	case config.TargetS3:
+		i18n.Printf("  Bucket: %s\n", target.S3.Bucket)
+		i18n.Printf("  Region: %s\n", target.S3.Region)
+		if target.S3.Endpoint != "" {
+			i18n.Printf("  Endpoint: %s\n", target.S3.Endpoint)
 		}
-		fmt.Printf("  Path Style: %v\n", cfg.S3Config.PathStyle)
-		fmt.Printf("  Use SSL: %v\n", cfg.S3Config.UseSSL)
-	case "minio":
-		fmt.Println("\nMinIO Storage Configuration:")
-		fmt.Printf("  Endpoint: %s\n", cfg.MinioConfig.Endpoint)
-		fmt.Printf("  Bucket: %s\n", cfg.MinioConfig.Bucket)
-		fmt.Printf("  Region: %s\n", cfg.MinioConfig.Region)
-		fmt.Printf("  Use SSL: %v\n", cfg.MinioConfig.UseSSL)
-	case "gcs":
-		fmt.Println("\nGoogle Cloud Storage Configuration:")
-		fmt.Printf("  Project ID: %s\n", cfg.GCSConfig.ProjectID)
-		fmt.Printf("  Bucket: %s\n", cfg.GCSConfig.Bucket)
-		if cfg.GCSConfig.CredentialsFile != "" {
-			fmt.Printf("  Credentials File: %s\n", cfg.GCSConfig.CredentialsFile)
+		i18n.Printf("  Path Style: %v\n", target.S3.PathStyle)
+		i18n.Printf("  Use SSL: %v\n", target.S3.UseSSL)
+		if len(target.S3.Failover) > 0 {
+			i18n.Printf("  Failover Endpoints: %s\n", strings.Join(target.S3.Failover, ", "))
+		}
+	case config.TargetMinio:
+		i18n.Printf("  Endpoint: %s\n", target.Minio.Endpoint)
+		i18n.Printf("  Bucket: %s\n", target.Minio.Bucket)
+		i18n.Printf("  Region: %s\n", target.Minio.Region)
+		i18n.Printf("  Use SSL: %v\n", target.Minio.UseSSL)
+		if len(target.Minio.Failover) > 0 {
+			i18n.Printf("  Failover Endpoints: %s\n", strings.Join(target.Minio.Failover, ", "))
+		}
+	case config.TargetGCS:
+		i18n.Printf("  Project ID: %s\n", target.GCS.ProjectID)
+		i18n.Printf("  Bucket: %s\n", target.GCS.Bucket)
+		if target.GCS.CredentialsFile != "" {
+			i18n.Printf("  Credentials File: %s\n", target.GCS.CredentialsFile)
+		}
+	case config.TargetLocal:
+		i18n.Printf("  Root Directory: %s\n", target.Local.RootDir)
+		if target.Local.VolumeUUID != "" {
+			i18n.Printf("  Removable Drive: volume %s\n", target.Local.VolumeUUID)
+		} else if target.Local.Removable {
+			i18n.Println("  Removable Drive: yes")
+		}
+	case config.TargetOneDrive:
+		i18n.Printf("  Client ID: %s\n", target.OneDrive.ClientID)
+		if target.OneDrive.Tenant != "" {
+			i18n.Printf("  Tenant: %s\n", target.OneDrive.Tenant)
+		}
+		if target.OneDrive.DriveID != "" {
+			i18n.Printf("  Drive ID: %s\n", target.OneDrive.DriveID)
+		}
+		i18n.Printf("  App Folder: %v\n", target.OneDrive.AppFolder)
+	case config.TargetMemory:
+		i18n.Println("  Contents are lost when the process exits")
+		if target.Memory.Name != "" {
+			i18n.Printf("  Name: %s\n", target.Memory.Name)
+		}
+		i18n.Printf("  Latency: %s\n", target.Memory.Latency)
+		i18n.Printf("  Error Rate: %g\n", target.Memory.ErrorRate)
+	default:
+		if target.IsPlugin() {
+			// Os valores podem ser segredos, então só os nomes são exibidos
+			settings := make([]string, 0, len(target.Plugin))
+			for setting := range target.Plugin {
+				settings = append(settings, setting)
+			}
+			sort.Strings(settings)
+			i18n.Printf("  Plugin Settings: %s\n", strings.Join(settings, ", "))
 		}
-	case "local":
-		fmt.Println("\nLocal Storage Configuration:")
-		fmt.Printf("  Root Directory: %s\n", cfg.LocalConfig.RootDir)
 	}
+	if transport := target.Transport(); transport != nil {
+		if description := describeTransport(*transport); description != "" {
+			i18n.Printf("  Transport: %s\n", description)
+		}
+	}
+}
+
+// describeTransport formats proxy and TLS settings for display, empty when they are the defaults
+func describeTransport(transport config.TransportConfig) string {
+	var parts []string
+	if transport.ProxyURL != "" {
+		parts = append(parts, i18n.Sprintf("proxy %s", transport.ProxyURL))
+	}
+	if transport.CACertFile != "" {
+		parts = append(parts, i18n.Sprintf("CA %s", transport.CACertFile))
+	}
+	if transport.InsecureSkipVerify {
+		parts = append(parts, i18n.T("certificate verification disabled"))
+	}
+	return strings.Join(parts, ", ")
+}
 
-	fmt.Printf("\nMax Concurrency: %d\n", cfg.MaxConcurrency)
-	fmt.Printf("Throttle Bandwidth: %d bytes/sec\n", cfg.ThrottleBytes)
-	fmt.Printf("Sync Interval: %s\n", cfg.SyncInterval.String())
+// describePowerPolicy formats a power policy for display
+func describePowerPolicy(policy config.ConditionPolicy) string {
+	switch policy.Action {
+	case config.PolicyThrottle:
+		return i18n.Sprintf("throttle to %d bytes/sec", policy.ThrottleBytes)
+	case config.PolicySmallFiles:
+		return i18n.Sprintf("only files up to %d bytes", policy.MaxFileSize)
+	case "":
+		return config.PolicyNone
+	default:
+		return policy.Action
+	}
+}
+
+// describePlaceholders returns what happens to cloud placeholders
+func describePlaceholders(hydrate bool) string {
+	if hydrate {
+		return i18n.T("downloaded and uploaded")
+	}
+	return i18n.T("skipped")
+}
+
+// describeMaxFileSize returns a readable form of the upload size limit
+func describeMaxFileSize(maxSize int64) string {
+	switch {
+	case maxSize == 0:
+		return i18n.T("storage limit")
+	case maxSize < 0:
+		return i18n.T("none")
+	default:
+		return i18n.Sprintf("%d bytes", maxSize)
+	}
 }
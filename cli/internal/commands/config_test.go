@@ -2,12 +2,19 @@ package commands
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/martinshumberto/sync-manager/cli/internal/client"
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/control"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,20 +22,22 @@ import (
 func TestDisplayConfig(t *testing.T) {
 	// Preparar uma configuração de teste
 	cfg := &config.Config{
-		DeviceID:        "test-device-id",
-		DeviceName:      "test-device",
-		LogLevel:        "info",
-		SyncInterval:    5 * time.Minute,
-		MaxConcurrency:  4,
-		ThrottleBytes:   0,
-		StorageProvider: "minio",
-		MinioConfig: config.MinioConfig{
-			Endpoint:  "localhost:9000",
-			Region:    "us-east-1",
-			Bucket:    "test-bucket",
-			AccessKey: "minioadmin",
-			SecretKey: "minioadmin",
-			UseSSL:    false,
+		DeviceID:       "test-device-id",
+		DeviceName:     "test-device",
+		LogLevel:       "info",
+		SyncInterval:   5 * time.Minute,
+		MaxConcurrency: 4,
+		ThrottleBytes:  0,
+		Targets: []config.StorageTarget{
+			{Name: "default", Type: "minio", Minio: config.MinioConfig{
+				Endpoint:  "localhost:9000",
+				Region:    "us-east-1",
+				Bucket:    "test-bucket",
+				AccessKey: "minioadmin",
+				SecretKey: "minioadmin",
+				UseSSL:    false,
+			}},
+			{Name: "nas", Type: "local", Local: config.LocalConfig{RootDir: "/mnt/nas"}},
 		},
 	}
 
@@ -52,6 +61,9 @@ func TestDisplayConfig(t *testing.T) {
 	assert.Contains(t, output, "minio")
 	assert.Contains(t, output, "localhost:9000")
 	assert.Contains(t, output, "test-bucket")
+	assert.Contains(t, output, "Storage Target default (default): minio")
+	assert.Contains(t, output, "Storage Target nas: local")
+	assert.Contains(t, output, "/mnt/nas")
 }
 
 func TestCreateConfigCommands(t *testing.T) {
@@ -66,7 +78,7 @@ func TestCreateConfigCommands(t *testing.T) {
 	}
 
 	// Criar os comandos
-	cmds := CreateConfigCommands(cfg, saveFn)
+	cmds := CreateConfigCommands(cfg, saveFn, nil)
 
 	// Verificar se criou pelo menos um comando
 	assert.Greater(t, len(cmds), 0)
@@ -90,23 +102,68 @@ func TestCreateConfigCommands(t *testing.T) {
 	assert.True(t, cmdNames["get [key]"])
 	assert.True(t, cmdNames["set <key> <value>"])
 	assert.True(t, cmdNames["reset"])
+	assert.True(t, cmdNames["export"])
+	assert.True(t, cmdNames["import <bundle-file>"])
+	assert.True(t, cmdNames["profile"])
+	assert.True(t, cmdNames["validate [config-file]"])
+}
+
+func TestConfigProfileCommands(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	var profileCmd *cobra.Command
+	for _, c := range CreateConfigCommands(config.DefaultConfig(), func() error { return nil }, nil)[0].Commands() {
+		if c.Use == "profile" {
+			profileCmd = c
+		}
+	}
+	assert.NotNil(t, profileCmd)
+
+	// Executar um subcomando de perfil capturando a saída
+	run := func(args ...string) (string, error) {
+		for _, c := range profileCmd.Commands() {
+			if c.Name() == args[0] {
+				var out bytes.Buffer
+				c.SetOut(&out)
+				err := c.RunE(c, args[1:])
+				return out.String(), err
+			}
+		}
+		return "", fmt.Errorf("unknown command %s", args[0])
+	}
+
+	out, err := run("create", "work")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Profile work created")
+
+	_, err = run("use", "missing")
+	assert.Error(t, err)
+
+	out, err = run("use", "work")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Now using profile work")
+
+	out, err = run("list")
+	assert.NoError(t, err)
+	assert.Regexp(t, `(?m)^  default `, out)
+	assert.Regexp(t, `(?m)^\* work `, out)
 }
 
 func TestConfigGetCommand(t *testing.T) {
 	// Preparar uma configuração de teste
 	cfg := &config.Config{
-		DeviceID:        "test-device-id",
-		DeviceName:      "test-device",
-		StorageProvider: "minio",
-		MinioConfig: config.MinioConfig{
-			Bucket: "test-bucket",
+		DeviceID:   "test-device-id",
+		DeviceName: "test-device",
+		Targets: []config.StorageTarget{
+			{Name: "default", Type: "minio", Minio: config.MinioConfig{Bucket: "test-bucket"}},
 		},
 	}
 
 	saveFn := func() error { return nil }
 
 	// Criar os comandos
-	cmds := CreateConfigCommands(cfg, saveFn)
+	cmds := CreateConfigCommands(cfg, saveFn, nil)
 	rootCmd := cmds[0]
 
 	// Encontrar o comando get
@@ -152,7 +209,7 @@ func TestConfigSetCommand(t *testing.T) {
 	}
 
 	// Criar os comandos
-	cmds := CreateConfigCommands(cfg, saveFn)
+	cmds := CreateConfigCommands(cfg, saveFn, nil)
 	rootCmd := cmds[0]
 
 	// Encontrar o comando set
@@ -171,16 +228,285 @@ func TestConfigSetCommand(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Verificar se a configuração foi alterada
-	assert.Equal(t, "local", cfg.StorageProvider)
+	assert.Equal(t, "local", cfg.Targets[0].Type)
 
 	// Verificar se a função de salvamento foi chamada
 	assert.Equal(t, 1, saveCount)
+
+	// A ação de limitar exige o limite configurado antes
+	assert.Error(t, setCmd.RunE(setCmd, []string{"power.metered.action", "throttle"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"power.metered.throttle", "102400"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"power.metered.action", "throttle"}))
+	assert.Equal(t, config.ConditionPolicy{Action: config.PolicyThrottle, ThrottleBytes: 102400}, cfg.Power.OnMetered)
+
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"power.battery.action", "pause"}))
+	assert.Equal(t, config.PolicyPause, cfg.Power.OnBattery.Action)
+	assert.Error(t, setCmd.RunE(setCmd, []string{"power.battery.action", "sleep"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"power.battery.max_file_size", "-1"}))
+	assert.Equal(t, 4, saveCount)
+
+	// Proxy e TLS por provedor e para o servidor
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.s3.proxy_url", "http://proxy.corp:3128"}))
+	assert.Equal(t, "http://proxy.corp:3128", cfg.Targets[0].S3.ProxyURL)
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.minio.insecure_skip_verify", "true"}))
+	assert.True(t, cfg.Targets[0].Minio.InsecureSkipVerify)
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"http.proxy_url", "socks5://127.0.0.1:1080"}))
+	assert.Equal(t, "socks5://127.0.0.1:1080", cfg.HTTP.ProxyURL)
+
+	// Valores inválidos não alteram a configuração
+	assert.Error(t, setCmd.RunE(setCmd, []string{"storage.gcs.proxy_url", "ftp://proxy"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"storage.gcs.ca_cert_file", "/missing/ca.pem"}))
+	assert.Empty(t, cfg.Targets[0].GCS.TransportConfig)
+	assert.Error(t, setCmd.RunE(setCmd, []string{"storage.local.proxy_url", "http://proxy"}))
+	assert.Equal(t, 7, saveCount)
+
+	// Sincronização pela rede local; os dispositivos confiáveis são definidos pelo comando lan
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"lan.enabled", "true"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"lan.timeout", "10s"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"lan.listen", "21028"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"lan.token", "a-long-shared-secret"}))
+	assert.Equal(t, config.LANConfig{Enabled: true, Listen: ":21028", Timeout: 10 * time.Second}, cfg.LAN)
+	assert.Equal(t, 9, saveCount)
+
+	// Limites dos downloads paralelos
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"download.concurrency", "8"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"download.bandwidth", "1048576"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"download.chunk_size", "16777216"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"download.concurrency", "0"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"download.chunk_size", "1024"}))
+	assert.Equal(t, config.DownloadConfig{MaxConcurrency: 8, ThrottleBytes: 1048576, ChunkSize: 16 << 20}, cfg.Download)
+	assert.Equal(t, 12, saveCount)
+
+	// Arquivos esparsos e tamanho máximo de upload
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"files.sparse", "skip"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"files.max_file_size", "1073741824"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"files.sparse", "compress"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"files.max_file_size", "1GB"}))
+	assert.Equal(t, config.FilesConfig{Sparse: config.SparseSkip, MaxFileSize: 1 << 30}, cfg.Files)
+	assert.Equal(t, 14, saveCount)
+
+	// Placeholders de provedores de nuvem só são baixados quando forçado
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"files.hydrate_placeholders", "true"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"files.hydrate_placeholders", "always"}))
+	assert.True(t, cfg.Files.HydratePlaceholders)
+	assert.Equal(t, 15, saveCount)
+
+	// Prioridade do agente e limite de leitura ao calcular hashes
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"priority.level", "idle"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"priority.hash_bandwidth", "52428800"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"priority.level", "realtime"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"priority.hash_bandwidth", "-1"}))
+	assert.Equal(t, config.PriorityConfig{Level: config.PriorityIdle, HashThrottleBytes: 50 << 20}, cfg.Priority)
+	assert.Equal(t, 17, saveCount)
+
+	// Criptografia das colunas sensíveis do banco local
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"database.encrypt", "true"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"database.encrypt", "sim"}))
+	assert.True(t, cfg.Database.Encrypt)
+	assert.Equal(t, 18, saveCount)
+
+	// Um banco central só é aceito quando o driver foi compilado e o DSN é válido
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"database.dsn", "/srv/sync-manager/shared.db"}))
+	assert.Equal(t, "/srv/sync-manager/shared.db", cfg.Database.DSN)
+	assert.Error(t, setCmd.RunE(setCmd, []string{"database.dsn", "mysql://db"}))
+	assert.Equal(t, "/srv/sync-manager/shared.db", cfg.Database.DSN)
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"database.dsn", ""}))
+	assert.Empty(t, cfg.Database.DSN)
+	assert.Equal(t, 20, saveCount)
+
+	// Limite mensal de tráfego, em bytes
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"bandwidth.monthly_cap", "1073741824"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"bandwidth.monthly_cap", "-1"}))
+	assert.Equal(t, int64(1<<30), cfg.Bandwidth.MonthlyCapBytes)
+	assert.Equal(t, 21, saveCount)
+
+	// Área de staging: diretório e tamanho máximo, que precisa ser positivo
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"cache.dir", "/var/tmp/sync-manager"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"cache.max_bytes", "536870912"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"cache.max_bytes", "0"}))
+	assert.Equal(t, config.CacheConfig{Dir: "/var/tmp/sync-manager", MaxBytes: 512 << 20}, cfg.Cache)
+	assert.Equal(t, 23, saveCount)
+
+	// Limite de memória (0 remove o limite) e tamanho da fila de upload
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"resources.memory_limit", "209715200"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"resources.memory_limit", "-1"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"resources.queue_size", "200"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"resources.queue_size", "0"}))
+	assert.Equal(t, config.ResourcesConfig{MemoryLimit: 200 << 20, QueueSize: 200}, cfg.Resources)
+	assert.Equal(t, 25, saveCount)
+
+	// Diferença de relógio tolerada antes do aviso
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"clock.max_skew", "30s"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"clock.max_skew", "0"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"clock.max_skew", "soon"}))
+	assert.Equal(t, 30*time.Second, cfg.Clock.MaxSkew)
+	assert.Equal(t, 26, saveCount)
+
+	// Pesos da ordem dos uploads; zero ignora o critério
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"scheduling.size_weight", "0.5"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"scheduling.recency_weight", "4"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"scheduling.folder_weight", "0"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"scheduling.folder_weight", "-1"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"scheduling.size_weight", "NaN"}))
+	assert.Equal(t, config.SchedulingConfig{SizeWeight: 0.5, RecencyWeight: 4}, cfg.Scheduling)
+	assert.Equal(t, 29, saveCount)
+
+	// --target escolhe o destino; definir o provedor de um destino novo o cria
+	assert.NoError(t, setCmd.Flags().Set("target", "nas"))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"storage.local.root_dir", "/mnt/nas"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.provider", "local"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.local.root_dir", "/mnt/nas"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.local.removable", "true"}))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"storage.local.removable", "sometimes"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.local.volume_uuid", "0f3a-55c1"}))
+	assert.NoError(t, setCmd.Flags().Set("target", ""))
+	assert.Equal(t, []string{"default", "nas"}, cfg.TargetNames())
+	assert.Equal(t, config.StorageTarget{Name: "nas", Type: "local", Local: config.LocalConfig{RootDir: "/mnt/nas", Removable: true, VolumeUUID: "0f3a-55c1"}}, cfg.Targets[1])
+	assert.Equal(t, 33, saveCount)
+
+	// Endpoints de failover são separados por vírgula; um valor vazio os remove
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.minio.failover_endpoints", "minio-2:9000, minio-3:9000"}))
+	assert.Equal(t, []string{"minio-2:9000", "minio-3:9000"}, cfg.Targets[0].Minio.Failover)
+	assert.Error(t, setCmd.RunE(setCmd, []string{"storage.minio.failover_endpoints", "minio-2:9000,minio-2:9000"}))
+	assert.Equal(t, []string{"minio-2:9000", "minio-3:9000"}, cfg.Targets[0].Minio.Failover)
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.minio.failover_endpoints", ""}))
+	assert.Empty(t, cfg.Targets[0].Minio.Failover)
+	assert.Equal(t, 35, saveCount)
+
+	// Pastas aninhadas são recusadas ou excluídas da pasta externa
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"nested_folders", "exclude"}))
+	assert.Equal(t, config.NestingExclude, cfg.NestedFolders)
+	assert.Error(t, setCmd.RunE(setCmd, []string{"nested_folders", "merge"}))
+	assert.Equal(t, 36, saveCount)
+
+	// Programas em execução pausam as transferências por padrão, ou as limitam
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"power.processes.names", "ffmpeg, steam,"}))
+	assert.Equal(t, []string{"ffmpeg", "steam"}, cfg.Power.Processes)
+	assert.Equal(t, config.PolicyPause, cfg.Power.OnProcesses.Action)
+	assert.Error(t, setCmd.RunE(setCmd, []string{"power.processes.action", "throttle"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"power.processes.throttle", "51200"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"power.processes.action", "throttle"}))
+	assert.Equal(t, config.ConditionPolicy{Action: config.PolicyThrottle, ThrottleBytes: 51200}, cfg.Power.OnProcesses)
+	assert.Equal(t, 39, saveCount)
+
+	// Provedores de plugins só são aceitos quando o plugin está instalado
+	if runtime.GOOS == "windows" {
+		return
+	}
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"plugins.dir", t.TempDir()}))
+	assert.NoError(t, os.WriteFile(filepath.Join(cfg.Plugins.Dir, config.PluginPrefix+"dropbox"), []byte("#!/bin/sh\n"), 0755))
+	assert.NoError(t, setCmd.Flags().Set("target", "cloud"))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"storage.provider", "box"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.provider", "dropbox"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.plugin.token", "${DROPBOX_TOKEN}"}))
+	assert.NoError(t, setCmd.Flags().Set("target", ""))
+	assert.Error(t, setCmd.RunE(setCmd, []string{"storage.plugin.token", "abc"}))
+	assert.Equal(t, config.StorageTarget{Name: "cloud", Type: "dropbox", Plugin: map[string]string{"token": "${DROPBOX_TOKEN}"}}, cfg.Targets[2])
+	assert.Equal(t, 42, saveCount)
+}
+
+// agentSettings faz o papel do agente em execução atrás da API de controle
+type agentSettings struct {
+	values  map[string]string
+	changes []control.Setting
+}
+
+func (a *agentSettings) Get(key, target string) (string, error) {
+	value, ok := a.values[key]
+	if !ok {
+		return "", fmt.Errorf("unknown configuration key: %s", key)
+	}
+	return value, nil
+}
+
+func (a *agentSettings) Set(settings []control.Setting) error {
+	for _, setting := range settings {
+		if _, ok := a.values[setting.Key]; !ok {
+			return fmt.Errorf("unknown configuration key: %s", setting.Key)
+		}
+	}
+	a.changes = append(a.changes, settings...)
+	return nil
+}
+
+func TestConfigCommandsUseRunningAgent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sockets Unix exigem um Windows recente")
+	}
+	cfg := config.DefaultConfig()
+	saveCount := 0
+	saveFn := func() error {
+		saveCount++
+		return nil
+	}
+
+	// Um agente executando a mesma configuração atende no socket ao lado do arquivo
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	agent := &agentSettings{values: map[string]string{"lan.listen": ":7777", "storage.provider": "local", "storage.s3.bucket": ""}}
+	listener, err := control.Listen(control.SocketPath(configPath))
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	auth := func(token string) error {
+		if token != "smt_ok" {
+			return errors.New("invalid or expired token")
+		}
+		return nil
+	}
+	go control.Serve(ctx, listener, agent, auth)
+	t.Setenv(client.TokenEnv, "smt_ok")
+	agentClient := client.NewAgentClient(cfg, configPath)
+
+	provider := cfg.DefaultTarget().Type
+	var getCmd, setCmd *cobra.Command
+	for _, c := range CreateConfigCommands(cfg, saveFn, agentClient)[0].Commands() {
+		switch c.Name() {
+		case "get":
+			getCmd = c
+		case "set":
+			setCmd = c
+		}
+	}
+
+	// A leitura mostra o valor com que o agente roda, não o do arquivo
+	output := captureStdout(func() { assert.NoError(t, getCmd.RunE(getCmd, []string{"lan.listen"})) })
+	assert.Equal(t, "lan.listen: :7777\n", output)
+
+	// As alterações vão juntas para o agente, que as valida e salva; a CLI não grava o arquivo
+	assert.NoError(t, setCmd.Args(setCmd, []string{"storage.provider", "s3", "storage.s3.bucket", "photos"}))
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"storage.provider", "s3", "storage.s3.bucket", "photos"}))
+	assert.Equal(t, []control.Setting{{Key: "storage.provider", Value: "s3"}, {Key: "storage.s3.bucket", Value: "photos"}}, agent.changes)
+	assert.Equal(t, provider, cfg.DefaultTarget().Type)
+	assert.Equal(t, 0, saveCount)
+
+	// As recusas do agente chegam ao usuário
+	assert.EqualError(t, setCmd.RunE(setCmd, []string{"lan.port", "7777"}), "unknown configuration key: lan.port")
+	assert.Error(t, setCmd.Args(setCmd, []string{"lan.listen"}))
+
+	// Um token recusado também chega ao usuário; sem token, a CLI usa o arquivo
+	agentClient.Token = "smt_revoked"
+	assert.ErrorContains(t, setCmd.RunE(setCmd, []string{"lan.listen", ":9999"}), client.TokenEnv)
+	agentClient.Token = ""
+	output = captureStdout(func() { assert.NoError(t, getCmd.RunE(getCmd, []string{"lan.listen"})) })
+	assert.Equal(t, "lan.listen: "+cfg.LAN.Listen+"\n", output)
+	assert.Len(t, agent.changes, 2)
+	agentClient.Token = "smt_ok"
+
+	// Sem o agente, a alteração vai para o arquivo
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(control.SocketPath(configPath))
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(t, setCmd.RunE(setCmd, []string{"lan.listen", ":8888"}))
+	assert.Equal(t, ":8888", cfg.LAN.Listen)
+	assert.Equal(t, 1, saveCount)
 }
 
 func TestConfigResetCommand(t *testing.T) {
 	// Preparar uma configuração modificada
 	cfg := config.DefaultConfig()
-	cfg.StorageProvider = "s3" // Alterar do padrão
+	cfg.Targets[0].Type = "s3" // Alterar do padrão
 
 	// Mock da função de salvamento
 	saveCount := 0
@@ -190,7 +516,7 @@ func TestConfigResetCommand(t *testing.T) {
 	}
 
 	// Criar os comandos
-	cmds := CreateConfigCommands(cfg, saveFn)
+	cmds := CreateConfigCommands(cfg, saveFn, nil)
 	rootCmd := cmds[0]
 
 	// Encontrar o comando reset
@@ -208,3 +534,57 @@ func TestConfigResetCommand(t *testing.T) {
 	// do usuário, mas podemos verificar se o código existe
 	assert.NotNil(t, resetCmd.RunE)
 }
+
+func TestConfigExportImportCommands(t *testing.T) {
+	// Configuração de origem com uma pasta e credenciais
+	source := config.DefaultConfig()
+	source.Targets = []config.StorageTarget{{Name: "default", Type: "local", Local: config.LocalConfig{RootDir: "/backup"}}}
+	source.SyncFolders = []config.SyncFolder{
+		{ID: "folder-1", Path: "/test/path", Enabled: true, Exclude: []string{".git"}},
+	}
+
+	findCmd := func(cfg *config.Config, saveFn func() error, use string) *cobra.Command {
+		for _, c := range CreateConfigCommands(cfg, saveFn, nil)[0].Commands() {
+			if c.Use == use {
+				return c
+			}
+		}
+		return nil
+	}
+
+	exportCmd := findCmd(source, func() error { return nil }, "export")
+	assert.NotNil(t, exportCmd)
+
+	bundlePath := filepath.Join(t.TempDir(), "bundle.yaml")
+	assert.NoError(t, exportCmd.Flags().Set("output", bundlePath))
+	assert.NoError(t, exportCmd.Flags().Set("redact-secrets", "true"))
+	assert.NoError(t, exportCmd.RunE(exportCmd, []string{}))
+
+	data, err := os.ReadFile(bundlePath)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "minioadmin")
+
+	// Importar em uma configuração nova
+	target := config.DefaultConfig()
+	target.DeviceID = "target-device"
+	saveCount := 0
+	importCmd := findCmd(target, func() error {
+		saveCount++
+		return nil
+	}, "import <bundle-file>")
+	assert.NotNil(t, importCmd)
+
+	old := os.Stdout
+	_, w, _ := os.Pipe()
+	os.Stdout = w
+	err = importCmd.RunE(importCmd, []string{bundlePath})
+	w.Close()
+	os.Stdout = old
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, saveCount)
+	assert.Equal(t, "target-device", target.DeviceID)
+	assert.Equal(t, source.Targets, target.Targets)
+	assert.Len(t, target.SyncFolders, 1)
+	assert.Equal(t, []string{".git"}, target.SyncFolders[0].Exclude)
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/client"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/spf13/cobra"
+)
+
+// conflictCopyPattern matches the names the agent gives the copies it keeps of conflicting files
+var conflictCopyPattern = regexp.MustCompile(` \(conflict from .+ \d{4}-\d{2}-\d{2} \d{6}\)`)
+
+// CreateConflictCommands returns the commands that show files the agent could not sync as they are
+func CreateConflictCommands(cfg *config.Config, agentClient *client.AgentClient) []*cobra.Command {
+	conflictsCmd := &cobra.Command{
+		Use:   "conflicts",
+		Short: "Inspect sync conflicts",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list [folder-id]",
+		Short: "List conflict copies and files left out of the sync",
+		Long: `List the conflict copies kept next to files changed on two devices, and the remote files
+the agent does not download because their names differ only in case, which a case-insensitive
+filesystem such as the macOS or Windows default would store as one file. Rename all but one
+of the colliding files on the device that created them to sync them again.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			folders := cfg.SyncFolders
+			if len(args) > 0 {
+				folder := findSyncFolder(cfg, args[0])
+				if folder == nil {
+					return i18n.Errorf("folder with ID %s not found", args[0])
+				}
+				folders = []config.SyncFolder{*folder}
+			}
+
+			live, _ := agentClient.GetStatus()
+			copies := make(map[string][]string)
+			for i := range folders {
+				found, err := ConflictCopies(&folders[i])
+				if err != nil {
+					return err
+				}
+				copies[folders[i].ID] = found
+			}
+
+			fmt.Print(RenderConflicts(folders, live, copies))
+			return nil
+		},
+	}
+
+	conflictsCmd.AddCommand(listCmd)
+	return []*cobra.Command{conflictsCmd}
+}
+
+// ConflictCopies returns the conflict copies in a folder, relative to it. A single-file folder
+// only has the copies of its file.
+func ConflictCopies(folder *config.SyncFolder) ([]string, error) {
+	relPaths, err := verifyPaths(folder, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fileCopy := strings.TrimSuffix(folder.File, path.Ext(folder.File)) + " (conflict from "
+	var copies []string
+	for _, relPath := range relPaths {
+		if folder.File != "" && !strings.HasPrefix(relPath, fileCopy) {
+			continue
+		}
+		if conflictCopyPattern.MatchString(path.Base(relPath)) {
+			copies = append(copies, relPath)
+		}
+	}
+	return copies, nil
+}
+
+// RenderConflicts lists the conflict copies and case collisions of each folder. live is nil
+// when the agent has not published its status recently, so collisions are unknown.
+func RenderConflicts(folders []config.SyncFolder, live *status.Snapshot, copies map[string][]string) string {
+	var b strings.Builder
+	total := 0
+	for _, folder := range folders {
+		var collisions [][]string
+		if live != nil {
+			collisions = live.Folders[folder.ID].Collisions
+		}
+		if len(copies[folder.ID]) == 0 && len(collisions) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "📂 %s\n", folder.ID)
+		for _, relPath := range copies[folder.ID] {
+			i18n.Fprintf(&b, "   conflict copy   %s\n", relPath)
+		}
+		for _, group := range collisions {
+			i18n.Fprintf(&b, "   case collision  %s (not downloaded)\n", strings.Join(group, ", "))
+		}
+		b.WriteString("\n")
+		total += len(copies[folder.ID]) + len(collisions)
+	}
+
+	if total == 0 {
+		b.WriteString(i18n.T("No conflicts.") + "\n")
+	}
+	if live == nil {
+		b.WriteString(i18n.T("The agent has not reported its folders recently, so case collisions are not listed.") + "\n")
+	}
+	return b.String()
+}
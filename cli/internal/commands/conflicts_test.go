@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictCopies(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	for _, name := range []string{
+		"notes.txt",
+		"notes (conflict from desktop 2026-01-02 150405).txt",
+		"sub/db (conflict from laptop 2026-01-02 150405).kdbx",
+		"sub/db.kdbx",
+		"conflict from desktop.txt",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(name), 0644))
+	}
+
+	copies, err := ConflictCopies(&config.SyncFolder{ID: "docs", Path: dir})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"notes (conflict from desktop 2026-01-02 150405).txt", "sub/db (conflict from laptop 2026-01-02 150405).kdbx"}, copies)
+
+	// Uma pasta de arquivo único só lista as cópias do seu arquivo
+	copies, err = ConflictCopies(&config.SyncFolder{ID: "db", Path: filepath.Join(dir, "sub"), File: "db.kdbx"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db (conflict from laptop 2026-01-02 150405).kdbx"}, copies)
+}
+
+func TestRenderConflicts(t *testing.T) {
+	folders := []config.SyncFolder{{ID: "docs"}, {ID: "photos"}, {ID: "music"}}
+	live := &status.Snapshot{
+		UpdatedAt: time.Now(),
+		Folders: map[string]status.Folder{
+			"photos": {Collisions: [][]string{{"IMG.jpg", "img.jpg"}}},
+		},
+	}
+	copies := map[string][]string{"docs": {"notes (conflict from desktop 2026-01-02 150405).txt"}}
+
+	out := RenderConflicts(folders, live, copies)
+	assert.Contains(t, out, "📂 docs\n   conflict copy   notes (conflict from desktop 2026-01-02 150405).txt\n")
+	assert.Contains(t, out, "📂 photos\n   case collision  IMG.jpg, img.jpg (not downloaded)\n")
+	assert.NotContains(t, out, "music")
+	assert.NotContains(t, out, "No conflicts")
+
+	// Sem o status do agente as colisões não são conhecidas
+	out = RenderConflicts(folders, nil, nil)
+	assert.Contains(t, out, "No conflicts.\n")
+	assert.Contains(t, out, "case collisions are not listed")
+}
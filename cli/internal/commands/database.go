@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/spf13/cobra"
+)
+
+// CreateDatabaseCommand returns the command maintaining the local database
+func CreateDatabaseCommand(dbManager *db.Manager, keys db.KeyStore) *cobra.Command {
+	dbCmd := &cobra.Command{
+		Use:   "db",
+		Short: "Maintain the local database",
+	}
+
+	rekeyCmd := &cobra.Command{
+		Use:   "rekey",
+		Short: "Encrypt the database with a new key",
+		Long: `Generates a new database key, encrypts the sensitive columns of every row with it,
+values still in plain text included, and replaces the key in the OS keychain.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !dbManager.Encrypted() {
+				return i18n.Errorf("database encryption is off; enable it with 'sync-manager config set database.encrypt true'")
+			}
+			rewritten, err := dbManager.Rekey(keys)
+			if err != nil {
+				return i18n.Errorf("failed to rekey database: %w", err)
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "Database encrypted with a new key (%d values rewritten).\n", rewritten)
+			return nil
+		},
+	}
+
+	maintainCmd := &cobra.Command{
+		Use:   "maintain",
+		Short: "Prune old records and compact the database",
+		Long: `Removes soft-deleted rows and sync events past their retention. On SQLite it then
+runs VACUUM to give the freed space back and PRAGMA integrity_check to find corruption.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deletedDays, _ := cmd.Flags().GetInt("deleted-days")
+			eventDays, _ := cmd.Flags().GetInt("event-days")
+			if deletedDays < 0 || eventDays < 0 {
+				return i18n.Errorf("retention days cannot be negative")
+			}
+
+			report, err := dbManager.Maintain(db.MaintenanceOptions{
+				DeletedRetention: time.Duration(deletedDays) * 24 * time.Hour,
+				EventRetention:   time.Duration(eventDays) * 24 * time.Hour,
+			})
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			i18n.Fprintf(out, "Pruned %d deleted rows and %d sync events.\n", report.DeletedRows, report.EventRows)
+			if report.Vacuumed {
+				i18n.Fprintln(out, "Vacuumed the database.")
+			}
+			if report.SizeBefore >= 0 && report.SizeAfter >= 0 {
+				i18n.Fprintf(out, "Size: %s before, %s after.\n", formatSize(report.SizeBefore), formatSize(report.SizeAfter))
+			}
+			if report.Integrity != "" {
+				i18n.Fprintf(out, "Integrity check: %s\n", report.Integrity)
+				if report.Integrity != "ok" {
+					return i18n.Errorf("the database is corrupt; restore it from a backup")
+				}
+			}
+			return nil
+		},
+	}
+	maintainCmd.Flags().Int("deleted-days", 30, "Days soft-deleted rows are kept, 0 to keep them all")
+	maintainCmd.Flags().Int("event-days", 90, "Days sync events are kept, 0 to keep them all")
+
+	dbCmd.AddCommand(rekeyCmd, maintainCmd)
+	return dbCmd
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryKeyStore guarda a chave do banco em memória, no lugar do chaveiro do sistema
+type memoryKeyStore struct {
+	key []byte
+}
+
+func (s *memoryKeyStore) Load() ([]byte, error) {
+	if s.key == nil {
+		return nil, db.ErrNoKey
+	}
+	return s.key, nil
+}
+
+func (s *memoryKeyStore) Save(key []byte) error {
+	s.key = key
+	return nil
+}
+
+// rawColumn lê uma coluna direto do banco, sem passar pela criptografia
+func rawColumn(t *testing.T, dbManager *db.Manager, table, column string, id uint) string {
+	var value string
+	assert.NoError(t, dbManager.GetDB().Table(table).Select(column).Where("id = ?", id).Row().Scan(&value))
+	return value
+}
+
+func TestDatabaseEncryptionAndRekey(t *testing.T) {
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbManager.Close() })
+	assert.NoError(t, dbManager.InitSchema())
+	keys := &memoryKeyStore{}
+	run := func(args ...string) (string, error) {
+		dbCmd := CreateDatabaseCommand(dbManager, keys)
+		var out bytes.Buffer
+		dbCmd.SetOut(&out)
+		dbCmd.SetArgs(args)
+		err := dbCmd.Execute()
+		return out.String(), err
+	}
+
+	// Sem criptografia ativa não há o que trocar
+	_, err = run("rekey")
+	assert.ErrorContains(t, err, "database.encrypt")
+
+	gorm := dbManager.GetDB()
+	legacy := &models.Folder{UserID: 1, FolderID: "docs", Name: "Tax returns"}
+	assert.NoError(t, gorm.Create(legacy).Error)
+
+	// A primeira ativação cria a chave; o que foi gravado antes continua legível
+	assert.NoError(t, dbManager.EnableEncryption(keys))
+	assert.Len(t, keys.key, db.KeyLength)
+	folder := &models.Folder{UserID: 1, FolderID: "photos", Name: "Family photos"}
+	assert.NoError(t, gorm.Create(folder).Error)
+	assert.Equal(t, "Family photos", folder.Name)
+	assert.Equal(t, "Tax returns", rawColumn(t, dbManager, "folders", "name", legacy.ID))
+	encrypted := rawColumn(t, dbManager, "folders", "name", folder.ID)
+	assert.True(t, strings.HasPrefix(encrypted, "enc:v1:"))
+	assert.NotContains(t, encrypted, "Family")
+	mapping := &models.DeviceFolder{DeviceID: 1, FolderID: folder.ID, LocalPath: "/home/ana/Pictures"}
+	assert.NoError(t, gorm.Create(mapping).Error)
+	assert.True(t, strings.HasPrefix(rawColumn(t, dbManager, "device_folders", "local_path", mapping.ID), "enc:v1:"))
+
+	var folders []models.Folder
+	assert.NoError(t, gorm.Order("id").Find(&folders).Error)
+	if assert.Len(t, folders, 2) {
+		assert.Equal(t, "Tax returns", folders[0].Name)
+		assert.Equal(t, "Family photos", folders[1].Name)
+	}
+	var mappings []models.DeviceFolder
+	assert.NoError(t, gorm.Find(&mappings).Error)
+	if assert.Len(t, mappings, 1) {
+		assert.Equal(t, "/home/ana/Pictures", mappings[0].LocalPath)
+	}
+
+	// A troca de chave cifra tudo de novo, inclusive o que ainda estava em texto puro
+	previousKey := keys.key
+	output, err := run("rekey")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "3 values rewritten")
+	assert.NotEqual(t, previousKey, keys.key)
+	assert.True(t, strings.HasPrefix(rawColumn(t, dbManager, "folders", "name", legacy.ID), "enc:v1:"))
+	assert.NotEqual(t, encrypted, rawColumn(t, dbManager, "folders", "name", folder.ID))
+
+	var stored models.DeviceFolder
+	assert.NoError(t, gorm.First(&stored, mapping.ID).Error)
+	assert.Equal(t, "/home/ana/Pictures", stored.LocalPath)
+	stored.SyncEnabled = false
+	assert.NoError(t, gorm.Save(&stored).Error)
+	assert.Equal(t, "/home/ana/Pictures", stored.LocalPath)
+
+	// Outro processo, aberto com a nova chave, lê os mesmos valores
+	reopened := &memoryKeyStore{key: keys.key}
+	assert.NoError(t, dbManager.EnableEncryption(reopened))
+	assert.NoError(t, gorm.First(&stored, mapping.ID).Error)
+	assert.Equal(t, "/home/ana/Pictures", stored.LocalPath)
+
+	// Com a chave errada a leitura falha em vez de devolver lixo
+	wrong, err := db.NewKey()
+	assert.NoError(t, err)
+	assert.NoError(t, dbManager.EnableEncryption(&memoryKeyStore{key: wrong}))
+	assert.ErrorIs(t, gorm.First(&stored, mapping.ID).Error, db.ErrWrongKey)
+}
+
+func TestDatabaseMaintain(t *testing.T) {
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbManager.Close() })
+	assert.NoError(t, dbManager.InitSchema())
+	gorm := dbManager.GetDB()
+
+	// Uma pasta removida há muito tempo, outra ontem e uma ativa
+	now := time.Now()
+	for i, deletedAt := range []time.Time{now.AddDate(0, 0, -40), now.AddDate(0, 0, -1), {}} {
+		folder := &models.Folder{UserID: 1, FolderID: fmt.Sprintf("folder-%d", i), Name: "folder"}
+		assert.NoError(t, gorm.Create(folder).Error)
+		if !deletedAt.IsZero() {
+			assert.NoError(t, gorm.Model(folder).UpdateColumn("deleted_at", deletedAt).Error)
+		}
+	}
+	for _, timestamp := range []time.Time{now.AddDate(0, 0, -100), now.AddDate(0, 0, -95), now} {
+		assert.NoError(t, gorm.Create(&models.SyncEvent{FolderID: 1, EventType: "upload", Timestamp: timestamp}).Error)
+	}
+
+	dbCmd := CreateDatabaseCommand(dbManager, &memoryKeyStore{})
+	var out bytes.Buffer
+	dbCmd.SetOut(&out)
+	dbCmd.SetArgs([]string{"maintain"})
+	assert.NoError(t, dbCmd.Execute())
+	assert.Contains(t, out.String(), "Pruned 1 deleted rows and 2 sync events.")
+	assert.Contains(t, out.String(), "Vacuumed the database.")
+	assert.Contains(t, out.String(), "Size: ")
+	assert.Contains(t, out.String(), "Integrity check: ok")
+
+	var folders, events int64
+	assert.NoError(t, gorm.Unscoped().Model(&models.Folder{}).Count(&folders).Error)
+	assert.NoError(t, gorm.Model(&models.SyncEvent{}).Count(&events).Error)
+	assert.Equal(t, int64(2), folders)
+	assert.Equal(t, int64(1), events)
+}
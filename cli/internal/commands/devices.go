@@ -1,17 +1,23 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
 // CreateDeviceCommands returns the device management commands
-func CreateDeviceCommands(cfg *config.Config) []*cobra.Command {
+func CreateDeviceCommands(cfg *config.Config, saveFn func() error, deviceService *services.DeviceService, userID uint) []*cobra.Command {
 	// Devices root command
 	devicesCmd := &cobra.Command{
 		Use:   "devices",
@@ -25,36 +31,30 @@ func CreateDeviceCommands(cfg *config.Config) []*cobra.Command {
 		Short: "List connected devices",
 		Long:  `Display a list of all devices connected to your account.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Println("Connected Devices:")
+			devices, err := deviceService.ListDevices(userID)
+			if err != nil {
+				return i18n.Errorf("failed to list devices: %w", err)
+			}
+
+			i18n.Println("Connected Devices:")
 			fmt.Println("-----------------")
 
-			// In a real implementation, we would fetch this from the server
-			// For now, we'll just display simulated data
 			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"Device ID", "Name", "Last Seen", "Status"})
-
-			// Current device
-			table.Append([]string{
-				cfg.DeviceID,
-				cfg.DeviceName + " (this device)",
-				"Now",
-				"Online",
-			})
-
-			// Simulated other devices
-			table.Append([]string{
-				"d8f3a1c2-5b6e-7d8f-9a0b-1c2d3e4f5a6b",
-				"John's Laptop",
-				"2 hours ago",
-				"Offline",
-			})
-
-			table.Append([]string{
-				"a1b2c3d4-e5f6-7a8b-9c0d-1e2f3a4b5c6d",
-				"Office Desktop",
-				"12 minutes ago",
-				"Online",
-			})
+			table.SetHeader(i18n.Strings("Device ID", "Name", "Last Seen", "Status"))
+
+			for _, device := range devices {
+				name := device.Name
+				if device.DeviceID == cfg.DeviceID {
+					name += " " + i18n.T("(this device)")
+				}
+
+				table.Append([]string{
+					device.DeviceID,
+					name,
+					formatLastSeen(device.LastSeenAt),
+					deviceStatus(device),
+				})
+			}
 
 			table.Render()
 			return nil
@@ -72,31 +72,31 @@ func CreateDeviceCommands(cfg *config.Config) []*cobra.Command {
 
 			// Check if trying to unlink current device
 			if deviceID == cfg.DeviceID {
-				return fmt.Errorf("cannot unlink the current device. Use 'reset' command instead if you want to reconfigure this device")
+				return i18n.Errorf("cannot unlink the current device. Use 'reset' command instead if you want to reconfigure this device")
 			}
 
-			// Ask for confirmation
-			fmt.Printf("Are you sure you want to unlink device %s? (y/n): ", deviceID)
-			var response string
-			fmt.Scanln(&response)
+			device, err := deviceService.GetDevice(userID, deviceID)
+			if errors.Is(err, services.ErrDeviceNotFound) {
+				return i18n.Errorf("device with ID %s not found", deviceID)
+			}
+			if err != nil {
+				return i18n.Errorf("failed to get device: %w", err)
+			}
 
-			if response != "y" && response != "Y" {
-				fmt.Println("Operation cancelled.")
+			// Ask for confirmation
+			if !confirm(cmd, "Are you sure you want to unlink device %s (%s)? (y/n): ", device.Name, deviceID) {
+				i18n.Println("Operation cancelled.")
 				return nil
 			}
 
-			fmt.Printf("Unlinking device %s...\n", deviceID)
+			i18n.Printf("Unlinking device %s...\n", deviceID)
 
-			// In a real implementation, we would:
-			// 1. Connect to the server
-			// 2. Remove the device authorization
-			// 3. Handle any cleanup
-
-			// Simulate processing
-			time.Sleep(1 * time.Second)
+			if err := deviceService.UnlinkDevice(userID, deviceID); err != nil {
+				return i18n.Errorf("failed to unlink device: %w", err)
+			}
 
-			fmt.Println("Device successfully unlinked.")
-			fmt.Println("This device will no longer be able to access your account or synchronize files.")
+			i18n.Println("Device successfully unlinked.")
+			i18n.Println("This device will no longer be able to access your account or synchronize files.")
 
 			return nil
 		},
@@ -114,20 +114,21 @@ func CreateDeviceCommands(cfg *config.Config) []*cobra.Command {
 
 			// Validate name
 			if newName == "" {
-				return fmt.Errorf("device name cannot be empty")
+				return i18n.Errorf("device name cannot be empty")
 			}
 
 			// Update the device name
 			cfg.DeviceName = newName
 
-			// In a real implementation, we would also:
-			// 1. Connect to the server
-			// 2. Update the device name in the remote database
-			// 3. Sync the changes to other devices
+			if err := saveFn(); err != nil {
+				return i18n.Errorf("failed to save configuration: %w", err)
+			}
 
-			fmt.Printf("Device renamed from '%s' to '%s'.\n", oldName, newName)
+			if err := deviceService.RenameDevice(userID, cfg.DeviceID, newName); err != nil {
+				return i18n.Errorf("failed to rename device: %w", err)
+			}
 
-			// Return the updated config to be saved
+			i18n.Printf("Device renamed from '%s' to '%s'.\n", oldName, newName)
 			return nil
 		},
 	}
@@ -139,72 +140,71 @@ func CreateDeviceCommands(cfg *config.Config) []*cobra.Command {
 		Long:  `Display detailed information about a specific device or the current device if no ID is provided.`,
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var deviceID string
-			var isCurrentDevice bool
-
-			// If no device ID is provided, use the current device
-			if len(args) == 0 {
-				deviceID = cfg.DeviceID
-				isCurrentDevice = true
-			} else {
+			deviceID := cfg.DeviceID
+			if len(args) > 0 {
 				deviceID = args[0]
-				isCurrentDevice = (deviceID == cfg.DeviceID)
 			}
+			isCurrentDevice := deviceID == cfg.DeviceID
 
-			// In a real implementation, we would fetch device details from the server
-			// For now, we'll display information for the current device and simulated data for others
+			device, err := deviceService.GetDevice(userID, deviceID)
+			if errors.Is(err, services.ErrDeviceNotFound) {
+				return i18n.Errorf("device with ID %s not found", deviceID)
+			}
+			if err != nil {
+				return i18n.Errorf("failed to get device: %w", err)
+			}
 
-			fmt.Println("Device Information:")
+			i18n.Println("Device Information:")
 			fmt.Println("------------------")
 
+			name := device.Name
 			if isCurrentDevice {
-				fmt.Printf("Device ID:      %s\n", cfg.DeviceID)
-				fmt.Printf("Name:           %s (this device)\n", cfg.DeviceName)
-				fmt.Printf("Status:         Online\n")
-				fmt.Printf("Last Seen:      Now\n")
-				fmt.Printf("Storage:        %s\n", cfg.StorageProvider)
-				fmt.Printf("Sync Interval:  %s\n", cfg.SyncInterval)
-				fmt.Printf("Sync Folders:   %d\n", len(cfg.SyncFolders))
-
-				// Display synced folders
-				if len(cfg.SyncFolders) > 0 {
-					fmt.Println("\nSynced Folders:")
-					table := tablewriter.NewWriter(os.Stdout)
-					table.SetHeader([]string{"ID", "Path", "Status"})
-
-					for _, folder := range cfg.SyncFolders {
-						status := "Enabled"
-						if !folder.Enabled {
-							status = "Disabled"
-						}
-
-						table.Append([]string{
-							folder.ID,
-							folder.Path,
-							status,
-						})
+				name += " " + i18n.T("(this device)")
+			}
+
+			i18n.Printf("Device ID:      %s\n", device.DeviceID)
+			i18n.Printf("Name:           %s\n", name)
+			i18n.Printf("Status:         %s\n", deviceStatus(*device))
+			i18n.Printf("Last Seen:      %s\n", formatLastSeen(device.LastSeenAt))
+			if device.OS != "" {
+				i18n.Printf("Platform:       %s/%s\n", device.OS, device.Platform)
+			}
+			if device.ClientVersion != "" {
+				i18n.Printf("Agent Version:  %s\n", device.ClientVersion)
+			}
+
+			if !isCurrentDevice {
+				return nil
+			}
+
+			targets := make([]string, 0, len(cfg.Targets))
+			for _, target := range cfg.Targets {
+				targets = append(targets, fmt.Sprintf("%s (%s)", target.Name, target.Type))
+			}
+			i18n.Printf("Storage:        %s\n", strings.Join(targets, ", "))
+			i18n.Printf("Sync Interval:  %s\n", cfg.SyncInterval)
+			i18n.Printf("Sync Folders:   %d\n", len(cfg.SyncFolders))
+
+			// Display synced folders
+			if len(cfg.SyncFolders) > 0 {
+				i18n.Println("\nSynced Folders:")
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader(i18n.Strings("ID", "Path", "Status"))
+
+				for _, folder := range cfg.SyncFolders {
+					status := i18n.T("Enabled")
+					if !folder.Enabled {
+						status = i18n.T("Disabled")
 					}
 
-					table.Render()
+					table.Append([]string{
+						folder.ID,
+						folder.Path,
+						status,
+					})
 				}
-			} else if deviceID == "d8f3a1c2-5b6e-7d8f-9a0b-1c2d3e4f5a6b" {
-				// Simulated device 1
-				fmt.Printf("Device ID:      %s\n", deviceID)
-				fmt.Printf("Name:           %s\n", "John's Laptop")
-				fmt.Printf("Status:         Offline\n")
-				fmt.Printf("Last Seen:      2 hours ago\n")
-				fmt.Printf("Storage:        minio\n")
-				fmt.Printf("Sync Folders:   2\n")
-			} else if deviceID == "a1b2c3d4-e5f6-7a8b-9c0d-1e2f3a4b5c6d" {
-				// Simulated device 2
-				fmt.Printf("Device ID:      %s\n", deviceID)
-				fmt.Printf("Name:           %s\n", "Office Desktop")
-				fmt.Printf("Status:         Online\n")
-				fmt.Printf("Last Seen:      12 minutes ago\n")
-				fmt.Printf("Storage:        minio\n")
-				fmt.Printf("Sync Folders:   3\n")
-			} else {
-				return fmt.Errorf("device with ID %s not found", deviceID)
+
+				table.Render()
 			}
 
 			return nil
@@ -219,3 +219,42 @@ func CreateDeviceCommands(cfg *config.Config) []*cobra.Command {
 
 	return []*cobra.Command{devicesCmd}
 }
+
+// deviceStatus describes whether a device is online based on its last heartbeat
+func deviceStatus(device models.DeviceResponse) string {
+	if device.Status != "" && device.Status != models.StatusActive {
+		return string(device.Status)
+	}
+	if heartbeat.IsOnline(device.LastSeenAt) {
+		return i18n.T("Online")
+	}
+	return i18n.T("Offline")
+}
+
+// formatLastSeen renders a last-seen time relative to now
+func formatLastSeen(t time.Time) string {
+	if t.IsZero() {
+		return i18n.T("Never")
+	}
+
+	elapsed := time.Since(t)
+	switch {
+	case elapsed < time.Minute:
+		return i18n.T("Just now")
+	case elapsed < time.Hour:
+		return i18n.Sprintf("%s ago", pluralize(int(elapsed/time.Minute), "minute"))
+	case elapsed < 24*time.Hour:
+		return i18n.Sprintf("%s ago", pluralize(int(elapsed/time.Hour), "hour"))
+	default:
+		return i18n.Sprintf("%s ago", pluralize(int(elapsed/(24*time.Hour)), "day"))
+	}
+}
+
+// pluralize formats a count with a singular or plural unit, translating the unit. The
+// catalogs hold the plural of each unit under its English plural.
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return "1 " + i18n.T(unit)
+	}
+	return fmt.Sprintf("%d %s", n, i18n.T(unit+"s"))
+}
@@ -3,14 +3,48 @@ package commands
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/models"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 )
 
+// newTestDeviceService cria um serviço de dispositivos com um banco de dados temporário
+func newTestDeviceService(t *testing.T, cfg *config.Config, heartbeatPath string) *services.DeviceService {
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbManager.Close() })
+	assert.NoError(t, dbManager.InitSchema())
+
+	deviceRepo := repositories.NewDeviceRepository(dbManager.GetDB())
+	return services.NewDeviceService(deviceRepo, cfg, heartbeatPath, nil)
+}
+
+// captureStdout executa fn e retorna o que foi escrito no stdout
+func captureStdout(fn func()) string {
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	fn()
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	return buf.String()
+}
+
 func TestCreateDeviceCommands(t *testing.T) {
 	// Preparar uma configuração de teste
 	cfg := config.DefaultConfig()
@@ -18,7 +52,7 @@ func TestCreateDeviceCommands(t *testing.T) {
 	cfg.DeviceName = "Test Device"
 
 	// Criar os comandos
-	cmds := CreateDeviceCommands(cfg)
+	cmds := CreateDeviceCommands(cfg, func() error { return nil }, newTestDeviceService(t, cfg, ""), 1)
 
 	// Verificar se criou pelo menos um comando
 	assert.Greater(t, len(cmds), 0)
@@ -60,7 +94,7 @@ func TestDeviceListCommand(t *testing.T) {
 	cfg.DeviceName = "Test Device"
 
 	// Criar os comandos
-	cmds := CreateDeviceCommands(cfg)
+	cmds := CreateDeviceCommands(cfg, func() error { return nil }, newTestDeviceService(t, cfg, ""), 1)
 	rootCmd := cmds[0]
 
 	// Encontrar o comando list
@@ -103,7 +137,6 @@ func TestDeviceInfoCommand(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.DeviceID = "test-device-id"
 	cfg.DeviceName = "Test Device"
-	cfg.StorageProvider = "minio"
 
 	// Adicionar uma pasta de sincronização para testes
 	cfg.SyncFolders = []config.SyncFolder{
@@ -115,7 +148,7 @@ func TestDeviceInfoCommand(t *testing.T) {
 	}
 
 	// Criar os comandos
-	cmds := CreateDeviceCommands(cfg)
+	cmds := CreateDeviceCommands(cfg, func() error { return nil }, newTestDeviceService(t, cfg, ""), 1)
 	rootCmd := cmds[0]
 
 	// Encontrar o comando info
@@ -147,7 +180,7 @@ func TestDeviceInfoCommand(t *testing.T) {
 		assert.Contains(t, output, "Device Information")
 		assert.Contains(t, output, cfg.DeviceID)
 		assert.Contains(t, output, cfg.DeviceName)
-		assert.Contains(t, output, cfg.StorageProvider)
+		assert.Contains(t, output, "default (minio)")
 		assert.Contains(t, output, "Synced Folders")
 		assert.Contains(t, output, "folder-1")
 	} else {
@@ -162,7 +195,7 @@ func TestDeviceRenameCommand(t *testing.T) {
 	cfg.DeviceName = "Original Name"
 
 	// Criar os comandos
-	cmds := CreateDeviceCommands(cfg)
+	cmds := CreateDeviceCommands(cfg, func() error { return nil }, newTestDeviceService(t, cfg, ""), 1)
 	rootCmd := cmds[0]
 
 	// Encontrar o comando rename
@@ -204,7 +237,7 @@ func TestDeviceUnlinkCommand(t *testing.T) {
 	cfg.DeviceID = "test-device-id"
 
 	// Criar os comandos
-	cmds := CreateDeviceCommands(cfg)
+	cmds := CreateDeviceCommands(cfg, func() error { return nil }, newTestDeviceService(t, cfg, ""), 1)
 	rootCmd := cmds[0]
 
 	// Encontrar o comando unlink
@@ -231,3 +264,68 @@ func TestDeviceUnlinkCommand(t *testing.T) {
 
 	// O teste de desconectar outro dispositivo não é possível pois exige entrada do usuário
 }
+
+func TestDeviceListUsesHeartbeatAndDatabase(t *testing.T) {
+	// Preparar uma configuração de teste
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "test-device-id"
+	cfg.DeviceName = "Test Device"
+
+	// O agente gravou um heartbeat recente
+	heartbeatPath := filepath.Join(t.TempDir(), "heartbeat.json")
+	assert.NoError(t, heartbeat.Write(heartbeatPath, &heartbeat.Heartbeat{
+		DeviceID: cfg.DeviceID,
+		Version:  "1.2.3",
+		Time:     time.Now(),
+	}))
+
+	deviceService := newTestDeviceService(t, cfg, heartbeatPath)
+
+	devices, err := deviceService.ListDevices(1)
+	assert.NoError(t, err)
+	assert.Len(t, devices, 1)
+	assert.Equal(t, "Online", deviceStatus(devices[0]))
+	assert.Equal(t, "1.2.3", devices[0].ClientVersion)
+
+	// Nenhum dispositivo simulado deve aparecer
+	cmds := CreateDeviceCommands(cfg, func() error { return nil }, deviceService, 1)
+	var listCmd *cobra.Command
+	for _, c := range cmds[0].Commands() {
+		if c.Use == "list" {
+			listCmd = c
+		}
+	}
+	output := captureStdout(func() {
+		assert.NoError(t, listCmd.RunE(listCmd, []string{}))
+	})
+	assert.Contains(t, output, "Test Device (this device)")
+	assert.NotContains(t, output, "John's Laptop")
+}
+
+func TestDeviceUnlinkRemovesRecord(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "test-device-id"
+	cfg.DeviceName = "Test Device"
+
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	defer dbManager.Close()
+	assert.NoError(t, dbManager.InitSchema())
+
+	deviceRepo := repositories.NewDeviceRepository(dbManager.GetDB())
+	assert.NoError(t, deviceRepo.Create(&models.Device{UserID: 1, DeviceID: "other-device", Name: "Other"}))
+
+	deviceService := services.NewDeviceService(deviceRepo, cfg, "", nil)
+
+	device, err := deviceService.GetDevice(1, "other-device")
+	assert.NoError(t, err)
+	assert.Equal(t, "Offline", deviceStatus(*device))
+
+	// Dispositivos de outros usuários não são visíveis
+	_, err = deviceService.GetDevice(2, "other-device")
+	assert.ErrorIs(t, err, services.ErrDeviceNotFound)
+
+	assert.NoError(t, deviceService.UnlinkDevice(1, "other-device"))
+	_, err = deviceService.GetDevice(1, "other-device")
+	assert.ErrorIs(t, err, services.ErrDeviceNotFound)
+}
@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/client"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/instance"
+	"github.com/martinshumberto/sync-manager/common/watchlimit"
+	"github.com/spf13/cobra"
+)
+
+// sysctlFile is where doctor suggests keeping a raised watch limit across reboots
+const sysctlFile = "/etc/sysctl.d/90-sync-manager.conf"
+
+// CreateDoctorCommand returns the doctor command, which checks the local setup and suggests fixes
+func CreateDoctorCommand(cfg *config.Config, configPath string, agentClient *client.AgentClient, folderService *services.FolderService, userID uint) *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the local setup and suggest fixes",
+		Long: `Check that the agent is running and that no other agent runs on the same configuration,
+that the folders it syncs exist, that the folder records in the database match the
+configuration, and that the system leaves the agent enough file watches. Directories past
+the watch limit are polled for changes, which is slower; doctor prints the sysctl commands
+that raise the limit.
+
+The configuration is what the agent syncs, so --fix changes the database to match it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			running := agentClient.Health() == nil
+			if !running {
+				i18n.Println("⚠ Agent is not running. Start it with 'sync-manager start'.")
+			} else {
+				i18n.Println("✓ Agent is running")
+			}
+
+			// O lock fica ao lado do arquivo que o agente carregou; o banco compartilhado
+			// mostra agentes de outras máquinas com a mesma configuração
+			lockConfig := config.ConfigFileUsed()
+			if lockConfig == "" {
+				lockConfig = configPath
+			}
+			dsn, _ := cfg.DatabaseDSN()
+			holders, err := instance.Holders(instance.LockPath(lockConfig), dsn, cfg.DeviceID)
+			if err != nil {
+				i18n.Printf("⚠ Instance lock: %v\n", err)
+			}
+			for _, line := range DiagnoseInstance(holders, running) {
+				fmt.Println(line)
+			}
+
+			for _, folder := range cfg.SyncFolders {
+				if _, err := os.Stat(folder.Path); err != nil {
+					i18n.Printf("⚠ Folder %s: %s cannot be read: %v\n", folder.ID, folder.Path, err)
+				}
+			}
+
+			fix, _ := cmd.Flags().GetBool("fix")
+			if err := checkFolderRecords(folderService, userID, fix); err != nil {
+				return err
+			}
+
+			var state *watchlimit.State
+			if path, err := watchlimit.DefaultPath(); err == nil {
+				state, _ = watchlimit.Read(path)
+			}
+			limit, err := watchlimit.Limit()
+			if err != nil && !errors.Is(err, watchlimit.ErrUnsupported) {
+				i18n.Printf("⚠ File watches: %v\n", err)
+			}
+			for _, line := range DiagnoseWatches(state, limit) {
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+
+	doctorCmd.Flags().Bool("fix", false, "Change the folder records in the database to match the configuration")
+
+	return doctorCmd
+}
+
+// checkFolderRecords reports the folders whose database records differ from the configuration,
+// repairing them when fix is set
+func checkFolderRecords(folderService *services.FolderService, userID uint, fix bool) error {
+	drifts, err := folderService.CheckFolders(userID)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		i18n.Println("✓ Folder records match the configuration")
+		return nil
+	}
+
+	for _, drift := range drifts {
+		i18n.Printf("⚠ Folder records: %s\n", drift.Describe())
+	}
+	if !fix {
+		i18n.Println("  Run 'sync-manager doctor --fix' to update the database from the configuration")
+		return nil
+	}
+
+	if err := folderService.RepairFolders(userID, drifts); err != nil {
+		return err
+	}
+	i18n.Printf("✓ Repaired %s to match the configuration\n", pluralize(len(drifts), "folder record"))
+	return nil
+}
+
+// DiagnoseInstance describes the agents holding the instance lock of the configuration, as
+// returned by instance.Holders, warning when more than one runs or the one holding it does
+// not answer
+func DiagnoseInstance(holders []instance.Holder, running bool) []string {
+	var alive []instance.Holder
+	for _, holder := range holders {
+		if holder.Alive() {
+			alive = append(alive, holder)
+		}
+	}
+
+	switch {
+	case len(alive) > 1:
+		lines := []string{"⚠ " + i18n.Sprintf("%d agents are running on this configuration and upload the same files:", len(alive))}
+		for _, holder := range alive {
+			lines = append(lines, "    "+holder.String())
+		}
+		return append(lines, "  "+i18n.Sprintf("Stop all but one, or restart one with %s so the others stop", instance.TakeoverFlag))
+	case len(alive) == 1 && !running:
+		return []string{
+			"⚠ " + i18n.Sprintf("Instance lock held by an agent that does not answer: %s", alive[0]),
+			"  " + i18n.Sprintf("If it is stuck, start the agent with %s", instance.TakeoverFlag),
+		}
+	case len(alive) == 1:
+		return []string{"✓ " + i18n.Sprintf("One agent holds the instance lock: %s", alive[0])}
+	case len(holders) > 0:
+		return []string{"✓ " + i18n.Sprintf("Instance lock left by a stopped agent (%s), the next agent takes it over", holders[0])}
+	}
+	return nil
+}
+
+// DiagnoseWatches describes the file watches the agent last reported against the current
+// limit, 0 when unknown, with the commands raising the limit when it is close or was reached
+func DiagnoseWatches(state *watchlimit.State, limit int) []string {
+	if state == nil || state.UpdatedAt.IsZero() {
+		return []string{"  " + i18n.T("File watches: the agent has not reported its usage yet")}
+	}
+
+	current := *state
+	if limit > 0 {
+		current.Limit = limit
+	}
+	usage := i18n.Sprintf("%d watches in use", current.Watches)
+	if current.Limit > 0 {
+		usage = i18n.Sprintf("%d of %d watches in use", current.Watches, current.Limit)
+	}
+	if !current.Near() && len(current.Polled) == 0 {
+		return []string{"✓ " + i18n.Sprintf("File watches: %s", usage)}
+	}
+
+	lines := []string{"⚠ " + i18n.Sprintf("File watches: %s", usage)}
+	if len(current.Polled) > 0 {
+		lines[0] += i18n.Sprintf(", %d directories polled for changes instead:", len(current.Polled))
+		for _, dir := range current.Polled {
+			lines = append(lines, "    "+dir)
+		}
+	}
+
+	// A limit raised since the agent ran out only helps once it watches its folders again
+	if limit > state.Limit && state.Limit > 0 && !current.Near() {
+		return append(lines, "  "+i18n.T("The limit was raised since; restart the agent to watch these directories"))
+	}
+
+	setting := fmt.Sprintf("%s=%d", watchlimit.Sysctl, current.Suggested())
+	return append(lines,
+		i18n.Sprintf("  Raise the limit:        sudo sysctl %s", setting),
+		i18n.Sprintf("  Keep it after reboots:  echo %s | sudo tee %s", setting, sysctlFile),
+		"  "+i18n.T("Then restart the agent so every directory is watched"))
+}
+
+// WatchWarnings returns the warnings about file watches the agent reported at watchesPath
+func WatchWarnings(watchesPath string) []string {
+	state, err := watchlimit.Read(watchesPath)
+	if err != nil {
+		return nil
+	}
+
+	if len(state.Polled) > 0 {
+		return []string{i18n.Sprintf("Out of file watches: %d directories are polled for changes instead; run 'sync-manager doctor' to raise the limit", len(state.Polled))}
+	}
+	if state.Near() {
+		return []string{i18n.Sprintf("File watches nearly exhausted: %d of %d in use; run 'sync-manager doctor' to raise the limit", state.Watches, state.Limit)}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/instance"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/watchlimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnoseWatches(t *testing.T) {
+	assert.Contains(t, DiagnoseWatches(nil, 8192)[0], "has not reported")
+
+	healthy := &watchlimit.State{Watches: 100, Limit: 8192, UpdatedAt: time.Now()}
+	assert.Equal(t, []string{"✓ File watches: 100 of 8192 watches in use"}, DiagnoseWatches(healthy, 8192))
+
+	// Sem watches suficientes, o doctor sugere o sysctl e como mantê-lo após reiniciar
+	exhausted := &watchlimit.State{Watches: 8192, Limit: 8192, Polled: []string{"/home/docs/node_modules"}, UpdatedAt: time.Now()}
+	lines := DiagnoseWatches(exhausted, 8192)
+	assert.Contains(t, lines[0], "8192 of 8192 watches in use, 1 directories polled")
+	assert.Equal(t, "    /home/docs/node_modules", lines[1])
+	assert.Contains(t, lines[2], "sudo sysctl fs.inotify.max_user_watches=524288")
+	assert.Contains(t, lines[3], "sudo tee /etc/sysctl.d/90-sync-manager.conf")
+
+	// Com o limite já aumentado, basta reiniciar o agente
+	lines = DiagnoseWatches(exhausted, 524288)
+	assert.Contains(t, lines[len(lines)-1], "restart the agent")
+	assert.NotContains(t, lines[len(lines)-1], "sysctl")
+}
+
+func TestWatchWarnings(t *testing.T) {
+	watchesPath := filepath.Join(t.TempDir(), "watches.json")
+	assert.Empty(t, WatchWarnings(watchesPath))
+
+	assert.NoError(t, watchlimit.Write(watchesPath, &watchlimit.State{Watches: 950, Limit: 1000}))
+	assert.Contains(t, WatchWarnings(watchesPath)[0], "950 of 1000 in use")
+
+	assert.NoError(t, watchlimit.Write(watchesPath, &watchlimit.State{Watches: 1000, Limit: 1000, Polled: []string{"/a", "/b"}}))
+	assert.Contains(t, WatchWarnings(watchesPath)[0], "2 directories are polled")
+}
+
+func TestCheckFolderRecords(t *testing.T) {
+	cfg := config.DefaultConfig()
+	folderService := newTestFolderService(t, cfg)
+	_, err := folderService.CreateFolderWithID("docs", 1, "docs", "/home/docs", false, 0, false)
+	assert.NoError(t, err)
+	_, err = folderService.CreateFolderWithID("old", 1, "old", "/home/old", false, 0, false)
+	assert.NoError(t, err)
+	_, err = folderService.CreateFolderWithID("photos", 1, "photos", "/home/photos", false, 0, false)
+	assert.NoError(t, err)
+
+	// A configuração foi editada à mão: uma pasta removida, uma pausada e outra adicionada
+	cfg.SyncFolders = []config.SyncFolder{
+		{ID: "docs", Path: "/home/docs", Enabled: true, Paused: true},
+		{ID: "music", Path: "/home/music", Enabled: true},
+		{ID: "photos", Path: "/home/photos", Enabled: true},
+	}
+	drifts, err := folderService.CheckFolders(1)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []services.FolderDrift{
+		{FolderID: "docs", Kind: services.DriftStatus, Expected: "paused", Actual: "active"},
+		{FolderID: "music", Kind: services.DriftMissingRecord},
+		{FolderID: "old", Kind: services.DriftStaleRecord},
+	}, drifts)
+
+	// Sem --fix nada muda
+	assert.NoError(t, checkFolderRecords(folderService, 1, false))
+	drifts, err = folderService.CheckFolders(1)
+	assert.NoError(t, err)
+	assert.Len(t, drifts, 3)
+
+	assert.NoError(t, checkFolderRecords(folderService, 1, true))
+	drifts, err = folderService.CheckFolders(1)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+	music, err := folderService.GetFolder(1, "music")
+	assert.NoError(t, err)
+	assert.Equal(t, "music", music.Name)
+
+	// Uma pasta configurada de novo recupera o registro excluído
+	cfg.SyncFolders = append(cfg.SyncFolders, config.SyncFolder{ID: "old", Path: "/home/old"})
+	assert.NoError(t, checkFolderRecords(folderService, 1, true))
+	old, err := folderService.GetFolder(1, "old")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusDisabled, old.Status)
+}
+
+func TestDiagnoseInstance(t *testing.T) {
+	assert.Empty(t, DiagnoseInstance(nil, false))
+
+	hostname, _ := os.Hostname()
+	running := instance.Holder{ID: "a", Hostname: hostname, PID: os.Getpid(), StartedAt: time.Now(), Heartbeat: time.Now()}
+	remote := instance.Holder{ID: "b", Hostname: "laptop.invalid", PID: 4242, StartedAt: time.Now(), Heartbeat: time.Now()}
+	stopped := instance.Holder{ID: "c", Hostname: hostname, PID: 4242, Heartbeat: time.Now().Add(-time.Hour)}
+
+	assert.Contains(t, DiagnoseInstance([]instance.Holder{running}, true)[0], "✓ One agent holds the instance lock: pid ")
+	assert.Contains(t, DiagnoseInstance([]instance.Holder{stopped}, false)[0], "the next agent takes it over")
+
+	// Um agente que não responde pode ser substituído com --takeover
+	lines := DiagnoseInstance([]instance.Holder{running}, false)
+	assert.Contains(t, lines[0], "⚠ Instance lock held by an agent that does not answer")
+	assert.Contains(t, lines[1], "--takeover")
+
+	// Dois agentes vivos na mesma configuração são listados
+	lines = DiagnoseInstance([]instance.Holder{running, remote, stopped}, true)
+	assert.Contains(t, lines[0], "⚠ 2 agents are running on this configuration")
+	assert.Contains(t, lines[2], "on laptop.invalid")
+	assert.Len(t, lines, 4)
+}
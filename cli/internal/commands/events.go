@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/apiclient"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/spf13/cobra"
+)
+
+// exportedEvent é uma linha da exportação de eventos, com a pasta identificada pelo seu ID
+type exportedEvent struct {
+	Folder       string          `json:"folder"`
+	ID           uint            `json:"id"`
+	DeviceID     uint            `json:"device_id,omitempty"`
+	EventType    string          `json:"event_type"`
+	RelativePath string          `json:"relative_path,omitempty"`
+	Timestamp    time.Time       `json:"timestamp"`
+	Details      json.RawMessage `json:"details,omitempty"`
+}
+
+// CreateEventsCommand returns the commands reading the sync events kept by the server. remote is
+// nil when no server is configured or the device is not logged in.
+func CreateEventsCommand(remote *apiclient.Client) *cobra.Command {
+	eventsCmd := &cobra.Command{
+		Use:   "events",
+		Short: "Read the sync events kept by the server",
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export sync events for external log systems",
+		Long: `Writes the sync events the server keeps for your folders, such as conflicts, blocked
+deletions and files left out, one JSON object per line, the oldest first within each folder.
+--since limits them to a recent period, given in days (30d), as a duration (12h) or as an
+RFC 3339 time. The server removes events past the retention of each folder.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			sinceValue, _ := cmd.Flags().GetString("since")
+			folderID, _ := cmd.Flags().GetString("folder")
+			outputPath, _ := cmd.Flags().GetString("output")
+
+			if format != "jsonl" {
+				return i18n.Errorf("unsupported export format: %s (supported: jsonl)", format)
+			}
+			var since time.Time
+			if sinceValue != "" {
+				var err error
+				if since, err = parseSince(sinceValue, time.Now()); err != nil {
+					return err
+				}
+			}
+			if remote == nil {
+				return i18n.Errorf("sync events are kept by the server; log in to it with 'sync-manager login' first")
+			}
+
+			ctx := context.Background()
+			folderIDs := []string{folderID}
+			if folderID == "" {
+				folders, err := remote.ListFolders(ctx)
+				if err != nil {
+					return i18n.Errorf("failed to list folders: %w", err)
+				}
+				folderIDs = folderIDs[:0]
+				for _, folder := range folders {
+					folderIDs = append(folderIDs, folder.FolderID)
+				}
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if outputPath != "" {
+				file, err := os.Create(outputPath)
+				if err != nil {
+					return i18n.Errorf("failed to create export file: %w", err)
+				}
+				defer file.Close()
+				out = file
+			}
+
+			encoder := json.NewEncoder(out)
+			exported := 0
+			for _, id := range folderIDs {
+				err := remote.WalkSyncEvents(ctx, id, since, func(event models.SyncEvent) error {
+					exported++
+					return encoder.Encode(exportEvent(id, event))
+				})
+				if err != nil {
+					return i18n.Errorf("failed to export the events of folder %s: %w", id, err)
+				}
+			}
+
+			if outputPath != "" {
+				i18n.Fprintf(cmd.ErrOrStderr(), "Exported %d sync events to %s\n", exported, outputPath)
+			}
+			return nil
+		},
+	}
+	exportCmd.Flags().String("format", "jsonl", "Output format: jsonl")
+	exportCmd.Flags().String("since", "", "Only export events recorded since then: days (30d), a duration (12h) or an RFC 3339 time")
+	exportCmd.Flags().String("folder", "", "Only export the events of this folder")
+	exportCmd.Flags().StringP("output", "o", "", "Write the events to this file instead of the standard output")
+
+	eventsCmd.AddCommand(exportCmd)
+	return eventsCmd
+}
+
+// exportEvent converte um evento para a exportação, mantendo os detalhes como JSON quando já o são
+func exportEvent(folderID string, event models.SyncEvent) exportedEvent {
+	exported := exportedEvent{
+		Folder:       folderID,
+		ID:           event.ID,
+		DeviceID:     event.DeviceID,
+		EventType:    event.EventType,
+		RelativePath: event.RelativePath,
+		Timestamp:    event.Timestamp,
+	}
+	if event.Details != "" {
+		if json.Valid([]byte(event.Details)) {
+			exported.Details = json.RawMessage(event.Details)
+		} else {
+			exported.Details, _ = json.Marshal(event.Details)
+		}
+	}
+	return exported
+}
+
+// parseSince retorna o início do período dado em dias (30d), como duração (12h) ou como horário RFC 3339
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	var period time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, i18n.Errorf("invalid --since %q: use days (30d), a duration (12h) or an RFC 3339 time", value)
+		}
+		period = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if period, err = time.ParseDuration(value); err != nil {
+			return time.Time{}, i18n.Errorf("invalid --since %q: use days (30d), a duration (12h) or an RFC 3339 time", value)
+		}
+	}
+	if period <= 0 {
+		return time.Time{}, i18n.Errorf("--since must be a positive period")
+	}
+	return now.Add(-period), nil
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/apiclient"
+	"github.com/martinshumberto/sync-manager/common/keychain"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// memorySecrets guarda os tokens de dispositivo em memória, no lugar do chaveiro do sistema
+type memorySecrets map[string][]byte
+
+func (s memorySecrets) Get(account string) ([]byte, error) {
+	secret, ok := s[account]
+	if !ok {
+		return nil, keychain.ErrNotFound
+	}
+	return secret, nil
+}
+
+func (s memorySecrets) Set(account string, secret []byte) error {
+	s[account] = secret
+	return nil
+}
+
+func (s memorySecrets) Delete(account string) error {
+	delete(s, account)
+	return nil
+}
+
+// eventsServer responde como o servidor com duas pastas, a primeira com eventos em duas páginas
+func eventsServer(t *testing.T, queries *[]string) *httptest.Server {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	pages := map[string][][]models.SyncEvent{
+		"docs": {
+			{{ID: 1, DeviceID: 2, EventType: models.SyncEventConflict, RelativePath: "a.txt", Timestamp: now, Details: `{"size":3}`}},
+			{{ID: 3, EventType: models.SyncEventTooLarge, RelativePath: "big.iso", Timestamp: now.Add(time.Hour), Details: "not json"}},
+		},
+		"photos": {{{ID: 2, EventType: models.SyncEventLowDiskSpace, Timestamp: now}}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/folders", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.NewSuccessResponse(http.StatusOK, "", []models.FolderResponse{{FolderID: "docs"}, {FolderID: "photos"}}))
+	})
+	mux.HandleFunc("GET /api/v1/folders/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.PathValue("id")+"?"+r.URL.RawQuery)
+		folderPages := pages[r.PathValue("id")]
+		page := 1
+		if r.URL.Query().Get("page") == "2" {
+			page = 2
+		}
+		json.NewEncoder(w).Encode(models.NewSuccessResponse(http.StatusOK, "", map[string]interface{}{
+			"items":       folderPages[page-1],
+			"total_pages": len(folderPages),
+		}))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEventsExportCommand(t *testing.T) {
+	var queries []string
+	server := eventsServer(t, &queries)
+
+	// Um dispositivo logado, com um token que ainda não precisa ser renovado, guardado fora do
+	// chaveiro do sistema
+	previous := apiclient.Secrets
+	apiclient.Secrets = memorySecrets{}
+	t.Cleanup(func() { apiclient.Secrets = previous })
+	credentialsPath := filepath.Join(t.TempDir(), "credentials.json")
+	assert.NoError(t, apiclient.SaveCredentials(credentialsPath, &apiclient.Credentials{
+		Endpoint: server.URL, DeviceID: "laptop", DeviceToken: "token", ExpiresAt: time.Now().Add(24 * time.Hour),
+	}))
+	remote, err := apiclient.NewClient(server.URL, credentialsPath)
+	assert.NoError(t, err)
+
+	exportCmd, _, err := CreateEventsCommand(remote).Find([]string{"export"})
+	assert.NoError(t, err)
+	var out bytes.Buffer
+	exportCmd.SetOut(&out)
+
+	// Todas as pastas, uma linha JSON por evento, página após página
+	assert.NoError(t, exportCmd.RunE(exportCmd, nil))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, []string{
+		`{"folder":"docs","id":1,"device_id":2,"event_type":"conflict","relative_path":"a.txt","timestamp":"2026-10-01T12:00:00Z","details":{"size":3}}`,
+		`{"folder":"docs","id":3,"event_type":"too_large","relative_path":"big.iso","timestamp":"2026-10-01T13:00:00Z","details":"not json"}`,
+		`{"folder":"photos","id":2,"event_type":"low_disk_space","timestamp":"2026-10-01T12:00:00Z"}`,
+	}, lines)
+	assert.Len(t, queries, 3)
+	assert.NotContains(t, queries[0], "since=")
+
+	// --since e --folder limitam o que é pedido ao servidor
+	queries = nil
+	outputPath := filepath.Join(t.TempDir(), "events.jsonl")
+	assert.NoError(t, exportCmd.Flags().Set("since", "2026-09-01T00:00:00Z"))
+	assert.NoError(t, exportCmd.Flags().Set("folder", "photos"))
+	assert.NoError(t, exportCmd.Flags().Set("output", outputPath))
+	assert.NoError(t, exportCmd.RunE(exportCmd, nil))
+	assert.Equal(t, []string{"photos?order=asc&page=1&page_size=500&since=2026-09-01T00%3A00%3A00Z"}, queries)
+	data, err := os.ReadFile(outputPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+
+	// Formatos e períodos desconhecidos são recusados antes de falar com o servidor
+	assert.NoError(t, exportCmd.Flags().Set("format", "csv"))
+	assert.Error(t, exportCmd.RunE(exportCmd, nil))
+	assert.NoError(t, exportCmd.Flags().Set("format", "jsonl"))
+	assert.NoError(t, exportCmd.Flags().Set("since", "last week"))
+	assert.Error(t, exportCmd.RunE(exportCmd, nil))
+
+	// Sem servidor não há eventos a exportar
+	offline, _, err := CreateEventsCommand(nil).Find([]string{"export"})
+	assert.NoError(t, err)
+	assert.Error(t, offline.RunE(offline, nil))
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 31, 12, 0, 0, 0, time.UTC)
+
+	since, err := parseSince("30d", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), since)
+
+	since, err = parseSince("12h", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-12*time.Hour), since)
+
+	since, err = parseSince("2026-01-02T03:04:05+02:00", now)
+	assert.NoError(t, err)
+	assert.True(t, since.Equal(time.Date(2026, 1, 2, 1, 4, 5, 0, time.UTC)))
+
+	for _, value := range []string{"0d", "-1h", "d", "yesterday"} {
+		_, err := parseSince(value, now)
+		assert.Error(t, err, value)
+	}
+}
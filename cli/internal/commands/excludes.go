@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/excludes"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// CreateExcludeCommands returns the commands managing the exclude rules kept in the database
+func CreateExcludeCommands(cfg *config.Config, excludeService *services.ExcludeService) []*cobra.Command {
+	excludesCmd := &cobra.Command{
+		Use:   "excludes",
+		Short: "Manage global and per-folder exclude rules",
+		Long: `Exclude rules are kept in the database and merged with the excludes of each
+folder when it is scanned. Global rules apply to every folder, folder rules to one
+folder. Add --device to limit a rule to this device, and --allow to keep syncing
+files matching a pattern that another rule excludes, on this device only.`,
+	}
+
+	// ruleFromFlags builds the rule described by the pattern and the scope flags
+	ruleFromFlags := func(cmd *cobra.Command, pattern string) (models.ExcludeRule, error) {
+		global, _ := cmd.Flags().GetBool("global")
+		folderID, _ := cmd.Flags().GetString("folder")
+		device, _ := cmd.Flags().GetBool("device")
+		allow, _ := cmd.Flags().GetBool("allow")
+
+		if global == (folderID != "") {
+			return models.ExcludeRule{}, errors.New(i18n.T("specify either --global or --folder <id>"))
+		}
+		rule := models.ExcludeRule{Pattern: pattern, FolderID: folderID, Allow: allow}
+		if device || allow {
+			rule.DeviceID = cfg.DeviceID
+		}
+		return rule, nil
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <pattern>",
+		Short: "Add an exclude rule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule, err := ruleFromFlags(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			if rule.FolderID != "" && findFolder(cfg, rule.FolderID) == nil {
+				i18n.Fprintf(cmd.OutOrStdout(), "Warning: folder %s is not configured on this device\n", rule.FolderID)
+			}
+
+			added, err := excludeService.Add(rule)
+			if err != nil {
+				return err
+			}
+			if !added {
+				i18n.Fprintf(cmd.OutOrStdout(), "Rule already exists: %s\n", describeRule(rule, cfg.DeviceID))
+				return nil
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "Added rule: %s\n", describeRule(rule, cfg.DeviceID))
+			return nil
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <pattern>",
+		Short: "Remove an exclude rule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule, err := ruleFromFlags(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			if err := excludeService.Remove(rule); err != nil {
+				if errors.Is(err, services.ErrExcludeNotFound) {
+					return i18n.Errorf("no rule matches: %s", describeRule(rule, cfg.DeviceID))
+				}
+				return err
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "Removed rule: %s\n", describeRule(rule, cfg.DeviceID))
+			return nil
+		},
+	}
+
+	for _, c := range []*cobra.Command{addCmd, removeCmd} {
+		c.Flags().Bool("global", false, "Apply the rule to every folder")
+		c.Flags().String("folder", "", "Apply the rule to one folder")
+		c.Flags().Bool("device", false, "Apply the rule on this device only")
+		c.Flags().Bool("allow", false, "Keep syncing files matching the pattern on this device")
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List exclude rules",
+		Long: `List the exclude rules. With --folder, only the rules used by that folder are
+listed, followed by the patterns it excludes on this device.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			global, _ := cmd.Flags().GetBool("global")
+			folderID, _ := cmd.Flags().GetString("folder")
+			if global && folderID != "" {
+				return errors.New(i18n.T("specify either --global or --folder <id>"))
+			}
+
+			rules, err := excludeService.List()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			table := tablewriter.NewWriter(out)
+			table.SetHeader(i18n.Strings("Pattern", "Scope", "Device", "Type"))
+			shown := 0
+			for _, rule := range rules {
+				if global && rule.FolderID != "" {
+					continue
+				}
+				if folderID != "" && !excludes.Applies(rule, folderID, rule.DeviceID) {
+					continue
+				}
+				table.Append(ruleRow(rule, cfg.DeviceID))
+				shown++
+			}
+			if shown == 0 {
+				i18n.Fprintln(out, "No exclude rules.")
+			} else {
+				table.Render()
+			}
+
+			if folderID != "" {
+				var own []string
+				if folder := findFolder(cfg, folderID); folder != nil {
+					own = folder.Exclude
+				}
+				effective, err := excludeService.Effective(folderID, cfg.DeviceID, own)
+				if err != nil {
+					return err
+				}
+				i18n.Fprintf(out, "\nExcluded in %s on this device: %s\n", folderID, strings.Join(effective, ", "))
+			}
+			return nil
+		},
+	}
+	listCmd.Flags().Bool("global", false, "List only the global rules")
+	listCmd.Flags().String("folder", "", "List the rules used by one folder")
+
+	excludesCmd.AddCommand(addCmd)
+	excludesCmd.AddCommand(removeCmd)
+	excludesCmd.AddCommand(listCmd)
+
+	return []*cobra.Command{excludesCmd}
+}
+
+// findFolder returns the configured folder with the given ID, nil when there is none
+func findFolder(cfg *config.Config, folderID string) *config.SyncFolder {
+	for i := range cfg.SyncFolders {
+		if cfg.SyncFolders[i].ID == folderID {
+			return &cfg.SyncFolders[i]
+		}
+	}
+	return nil
+}
+
+// ruleRow returns the table columns of a rule
+func ruleRow(rule models.ExcludeRule, deviceID string) []string {
+	scope := i18n.T("global")
+	if rule.FolderID != "" {
+		scope = i18n.Sprintf("folder %s", rule.FolderID)
+	}
+	device := i18n.T("all")
+	if rule.DeviceID == deviceID {
+		device = i18n.T("this device")
+	} else if rule.DeviceID != "" {
+		device = rule.DeviceID
+	}
+	kind := i18n.T("exclude")
+	if rule.Allow {
+		kind = i18n.T("allow")
+	}
+	return []string{rule.Pattern, scope, device, kind}
+}
+
+// describeRule returns a one-line description of a rule
+func describeRule(rule models.ExcludeRule, deviceID string) string {
+	row := ruleRow(rule, deviceID)
+	return fmt.Sprintf("%s %s (%s, %s)", row[3], row[0], row[1], row[2])
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExcludeCommands(t *testing.T) {
+	// Banco de dados temporário com as tabelas criadas
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbManager.Close() })
+	assert.NoError(t, dbManager.InitSchema())
+	excludeService := services.NewExcludeService(repositories.NewExcludeRepository(dbManager.GetDB()))
+
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	cfg.SyncFolders = []config.SyncFolder{{ID: "code", Path: "/src", Exclude: []string{".git"}}}
+
+	cmds := CreateExcludeCommands(cfg, excludeService)
+	assert.Len(t, cmds, 1)
+	assert.Equal(t, "excludes", cmds[0].Use)
+
+	// Executar um subcomando com flags novas a cada chamada
+	run := func(name string, flags map[string]string, args ...string) (string, error) {
+		for _, c := range CreateExcludeCommands(cfg, excludeService)[0].Commands() {
+			if c.Name() != name {
+				continue
+			}
+			for flag, value := range flags {
+				assert.NoError(t, c.Flags().Set(flag, value))
+			}
+			var out bytes.Buffer
+			c.SetOut(&out)
+			err := c.RunE(c, args)
+			return out.String(), err
+		}
+		t.Fatalf("unknown command %s", name)
+		return "", nil
+	}
+
+	_, err = run("add", nil, "*.tmp")
+	assert.Error(t, err, "a scope is required")
+	_, err = run("add", map[string]string{"global": "true"}, "[")
+	assert.Error(t, err, "invalid pattern")
+
+	out, err := run("add", map[string]string{"global": "true"}, "*.tmp")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Added rule: exclude *.tmp (global, all)")
+	out, err = run("add", map[string]string{"global": "true"}, "*.tmp")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Rule already exists")
+
+	_, err = run("add", map[string]string{"folder": "code"}, "node_modules")
+	assert.NoError(t, err)
+	_, err = run("add", map[string]string{"global": "true"}, "*.log")
+	assert.NoError(t, err)
+	out, err = run("add", map[string]string{"global": "true", "allow": "true"}, "*.log")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "allow *.log (global, this device)")
+	out, err = run("add", map[string]string{"folder": "photos"}, "*.raw")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Warning: folder photos is not configured")
+
+	out, err = run("list", map[string]string{"folder": "code"})
+	assert.NoError(t, err)
+	assert.Contains(t, out, "node_modules")
+	assert.NotContains(t, out, "*.raw")
+	assert.Contains(t, out, "Excluded in code on this device: .git, *.tmp, node_modules\n")
+
+	out, err = run("list", map[string]string{"global": "true"})
+	assert.NoError(t, err)
+	assert.Contains(t, out, "*.tmp")
+	assert.NotContains(t, out, "node_modules")
+
+	_, err = run("remove", map[string]string{"folder": "code"}, "*.tmp")
+	assert.Error(t, err)
+	out, err = run("remove", map[string]string{"global": "true"}, "*.tmp")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Removed rule")
+
+	rules, err := excludeService.List()
+	assert.NoError(t, err)
+	assert.Len(t, rules, 4)
+}
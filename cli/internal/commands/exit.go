@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the CLI, so scripts can tell failures apart
+const (
+	ExitOK               = 0
+	ExitFailure          = 1 // The command failed, or only part of its work succeeded
+	ExitConfig           = 2 // The configuration or the command line is invalid
+	ExitAgentUnreachable = 3 // The command needs the agent, which is not running
+)
+
+// ConfigError is a failure caused by the configuration or the command line rather than by
+// the work of the command
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// configError marks err as a configuration error
+func configError(err error) error {
+	return &ConfigError{Err: err}
+}
+
+// ExitCode returns the code the CLI exits with after a command returned err
+func ExitCode(err error) int {
+	var configErr *ConfigError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, client.ErrAgentNotRunning):
+		return ExitAgentUnreachable
+	case errors.As(err, &configErr):
+		return ExitConfig
+	default:
+		return ExitFailure
+	}
+}
+
+// ClassifyUsageErrors makes the errors of invalid flags and arguments given to root or any
+// command under it ConfigErrors
+func ClassifyUsageErrors(root *cobra.Command) {
+	root.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return configError(err)
+	})
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		if validate := cmd.Args; validate != nil {
+			cmd.Args = func(cmd *cobra.Command, args []string) error {
+				if err := validate(cmd, args); err != nil {
+					return configError(err)
+				}
+				return nil
+			}
+		}
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+}
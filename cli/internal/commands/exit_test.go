@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/client"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOK, ExitCode(nil))
+	assert.Equal(t, ExitFailure, ExitCode(errors.New("failed to fetch 2 files")))
+	assert.Equal(t, ExitConfig, ExitCode(i18n.Errorf("login: %w", configError(errors.New("email and password are required")))))
+	assert.Equal(t, ExitAgentUnreachable, ExitCode(i18n.Errorf("agent is not running: %w", client.ErrAgentNotRunning)))
+
+	// Flags e argumentos inválidos são erros de configuração
+	newRoot := func() *cobra.Command {
+		root := &cobra.Command{Use: "sync-manager", SilenceErrors: true, SilenceUsage: true}
+		root.AddCommand(&cobra.Command{Use: "fetch", Args: cobra.MinimumNArgs(1), RunE: func(cmd *cobra.Command, args []string) error { return nil }})
+		ClassifyUsageErrors(root)
+		return root
+	}
+	for _, args := range [][]string{{"fetch"}, {"fetch", "a", "--unknown"}} {
+		root := newRoot()
+		root.SetArgs(args)
+		assert.Equal(t, ExitConfig, ExitCode(root.Execute()), strings.Join(args, " "))
+	}
+	root := newRoot()
+	root.SetArgs([]string{"fetch", "a"})
+	assert.Equal(t, ExitOK, ExitCode(root.Execute()))
+}
+
+func TestNonInteractive(t *testing.T) {
+	cfg := config.DefaultConfig()
+	run := func(input string, args ...string) {
+		root := &cobra.Command{Use: "sync-manager"}
+		AddNonInteractiveFlag(root)
+		root.AddCommand(CreateConfigCommands(cfg, func() error { return nil }, nil)...)
+		root.SetIn(strings.NewReader(input))
+		root.SetArgs(args)
+		assert.NoError(t, root.Execute())
+	}
+
+	// Sem a flag, a confirmação é lida da entrada
+	cfg.MaxConcurrency = 12
+	run("n\n", "config", "reset")
+	assert.Equal(t, 12, cfg.MaxConcurrency)
+
+	// --yes e --non-interactive confirmam sem perguntar
+	for _, flag := range []string{"--yes", "--non-interactive", "-y"} {
+		cfg.MaxConcurrency = 12
+		run("", "config", "reset", flag)
+		assert.Equal(t, config.DefaultConfig().MaxConcurrency, cfg.MaxConcurrency, flag)
+	}
+}
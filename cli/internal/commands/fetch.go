@@ -0,0 +1,405 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/download"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/placeholder"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/spf13/cobra"
+)
+
+// archivedFile is a placeholder found on disk, with the file it stands in for
+type archivedFile struct {
+	path string
+	stub placeholder.Placeholder
+}
+
+// remoteFetch is a remote file matched by a fetch pattern and what fetching it does
+type remoteFetch struct {
+	relPath   string
+	localPath string
+	file      storage.FileInfo
+	action    string // One of the fetch actions
+	stub      *placeholder.Placeholder
+}
+
+// What fetching a remote file does to the local folder
+const (
+	fetchDownload  = "download"  // Nothing is there yet, or only a placeholder
+	fetchOverwrite = "overwrite" // A different local file is replaced, with --overwrite
+	fetchKeep      = "keep"      // A different local file is kept
+	fetchCurrent   = "current"   // The local file already matches
+)
+
+// CreateFetchCommand returns the command that downloads archived files back in place of
+// their placeholders, or remote files of a folder matching glob patterns
+func CreateFetchCommand(cfg *config.Config, openStorage func() (storage.Storage, error)) *cobra.Command {
+	fetchCmd := &cobra.Command{
+		Use:   "fetch <path>... | <folder-id> <pattern>...",
+		Short: "Download archived or remote files into a folder",
+		Long: `Folders with an archive policy (configure-folder --archive-after-days N) replace the
+files uploaded and left unmodified for N days with small placeholders, keeping their
+content only in storage. fetch downloads the given files back in place of their
+placeholders, or every archived file under a given directory. A fetched file counts
+as modified now, so it stays on disk for another N days.
+
+Given a folder ID followed by glob patterns, fetch downloads the remote files of that
+folder matching them right away, even into an upload-only folder. Patterns match paths
+relative to the folder, or file names when they have no slash, and a pattern matching a
+directory fetches everything under it. Quote them so the shell does not expand them.
+Local files that differ from the remote copy are kept unless --overwrite is given, and
+--dry-run only lists what would be downloaded.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			overwrite, _ := cmd.Flags().GetBool("overwrite")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			if len(args) >= 2 {
+				if folder := findSyncFolder(cfg, args[0]); folder != nil {
+					return fetchMatching(cfg, openStorage, folder, args[1:], overwrite, dryRun)
+				}
+			}
+
+			files, err := findArchived(args)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				i18n.Println("No archived files found.")
+				return nil
+			}
+
+			var fetched int64
+			if dryRun {
+				for _, file := range files {
+					fmt.Printf("  %-10s %s\n", i18n.T(fetchDownload), file.path)
+					fetched += file.stub.Size
+				}
+				i18n.Printf("Would fetch %s, %s to download\n", pluralize(len(files), "file"), formatSize(fetched))
+				return nil
+			}
+
+			store, err := openStorage()
+			if err != nil {
+				return i18n.Errorf("failed to open storage: %w", err)
+			}
+
+			ctx := context.Background()
+			for _, file := range files {
+				folder := findSyncFolder(cfg, file.stub.FolderID)
+				if folder == nil {
+					return i18n.Errorf("folder with ID %s not found", file.stub.FolderID)
+				}
+				downloader := download.NewDownloader(targetStore(store, folder.Target), cfg)
+				if err := fetchArchived(ctx, downloader, folder.Key(file.stub.Path), file); err != nil {
+					return err
+				}
+				fetched += file.stub.Size
+				i18n.Printf("Fetched %s (%s)\n", file.path, formatSize(file.stub.Size))
+			}
+			i18n.Printf("Fetched %s, %s downloaded\n", pluralize(len(files), "file"), formatSize(fetched))
+			return nil
+		},
+	}
+
+	fetchCmd.Flags().Bool("overwrite", false, "Replace local files that differ from the remote copy")
+	fetchCmd.Flags().Bool("dry-run", false, "Only list the files that would be downloaded")
+
+	return fetchCmd
+}
+
+// findArchived returns the placeholders named by args, walking the directories among them
+func findArchived(args []string) ([]archivedFile, error) {
+	var files []archivedFile
+	for _, arg := range args {
+		path, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, i18n.Errorf("failed to get absolute path: %w", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, i18n.Errorf("cannot access %s: %w", arg, err)
+		}
+
+		if !info.IsDir() {
+			stub, err := placeholder.Read(path)
+			if errors.Is(err, placeholder.ErrNotPlaceholder) {
+				return nil, i18n.Errorf("%s is not an archived file", arg)
+			}
+			if err != nil {
+				return nil, i18n.Errorf("failed to read %s: %w", arg, err)
+			}
+			files = append(files, archivedFile{path: path, stub: stub})
+			continue
+		}
+
+		err = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() || info.Size() > placeholder.MaxSize {
+				return nil
+			}
+			if stub, err := placeholder.Read(path); err == nil {
+				files = append(files, archivedFile{path: path, stub: stub})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, i18n.Errorf("failed to walk %s: %w", arg, err)
+		}
+	}
+	return files, nil
+}
+
+// fetchArchived downloads the content of an archived file from key, checked against the hash
+// of the placeholder, and puts it in place of the placeholder
+func fetchArchived(ctx context.Context, downloader *download.Downloader, key string, file archivedFile) error {
+	info, err := os.Stat(file.path)
+	if err != nil {
+		return i18n.Errorf("cannot access %s: %w", file.path, err)
+	}
+
+	tmpPath := file.path + ".fetch"
+	metadata, err := downloader.Fetch(ctx, download.Task{Key: key, Size: file.stub.Size, Path: tmpPath, Checksum: checksum.Of(file.stub.Hash)}, nil)
+	if err != nil {
+		os.Remove(tmpPath)
+		return i18n.Errorf("failed to download %s: %w", file.path, err)
+	}
+	if checksum.FromMetadata(metadata) != file.stub.Hash {
+		// Overwriting the placeholder with another version would look like a local change
+		os.Remove(tmpPath)
+		return i18n.Errorf("the remote copy of %s changed since it was archived", file.path)
+	}
+
+	if err := os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+		os.Remove(tmpPath)
+		return i18n.Errorf("failed to set permissions of %s: %w", file.path, err)
+	}
+	// The file keeps the modification time of the download, so it is not archived again
+	// before the folder's policy elapses
+	if err := os.Rename(tmpPath, file.path); err != nil {
+		os.Remove(tmpPath)
+		return i18n.Errorf("failed to replace placeholder of %s: %w", file.path, err)
+	}
+	return nil
+}
+
+// fetchMatching downloads the remote files of a folder matching patterns through the download pool
+func fetchMatching(cfg *config.Config, openStorage func() (storage.Storage, error), folder *config.SyncFolder, patterns []string, overwrite, dryRun bool) error {
+	if folder.Mode == config.FolderModeBackup {
+		return i18n.Errorf("folder %s is in backup mode; use 'snapshots %s' to inspect its snapshots", folder.ID, folder.ID)
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return i18n.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return i18n.Errorf("failed to open storage: %w", err)
+	}
+	store = targetStore(store, folder.Target)
+
+	ctx := context.Background()
+	plan, err := planFetch(ctx, store, folder, patterns, overwrite)
+	if err != nil {
+		return err
+	}
+
+	var todo []remoteFetch
+	var bytes int64
+	kept := 0
+	for _, item := range plan {
+		switch item.action {
+		case fetchCurrent:
+			continue
+		case fetchKeep:
+			kept++
+		default:
+			todo = append(todo, item)
+			bytes += item.file.Size
+		}
+		fmt.Printf("  %-10s %s\n", i18n.T(item.action), item.relPath)
+	}
+	if len(plan) == 0 {
+		i18n.Printf("No remote files of %s match %s\n", folder.ID, strings.Join(patterns, " "))
+		return nil
+	}
+	if kept > 0 {
+		i18n.Printf("Kept %s with local changes; use --overwrite to replace them\n", pluralize(kept, "file"))
+	}
+	if dryRun {
+		i18n.Printf("Would fetch %s, %s to download\n", pluralize(len(todo), "file"), formatSize(bytes))
+		return nil
+	}
+	if len(todo) == 0 {
+		i18n.Println("Nothing to fetch.")
+		return nil
+	}
+
+	downloader := download.NewDownloader(store, cfg)
+	var mu sync.Mutex
+	var failed []string
+	err = downloader.Each(ctx, len(todo), func(ctx context.Context, i int) {
+		item := todo[i]
+		var err error
+		if item.stub != nil {
+			err = fetchArchived(ctx, downloader, item.file.Key, archivedFile{path: item.localPath, stub: *item.stub})
+		} else {
+			err = fetchRemoteFile(ctx, downloader, item)
+		}
+		if err != nil {
+			mu.Lock()
+			failed = append(failed, item.relPath)
+			mu.Unlock()
+			i18n.Printf("Failed to fetch %s: %v\n", item.relPath, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return i18n.Errorf("failed to fetch %s", pluralize(len(failed), "file"))
+	}
+
+	i18n.Printf("Fetched %s, %s downloaded\n", pluralize(len(todo), "file"), formatSize(bytes))
+	return nil
+}
+
+// planFetch lists the remote files of a folder matching patterns, sorted by path, with what
+// fetching each one would do
+func planFetch(ctx context.Context, store storage.Storage, folder *config.SyncFolder, patterns []string, overwrite bool) ([]remoteFetch, error) {
+	prefix := folder.KeyPrefix() + "/"
+	files, err := store.ListFiles(ctx, prefix)
+	if err != nil {
+		return nil, i18n.Errorf("failed to list remote files: %w", err)
+	}
+
+	var plan []remoteFetch
+	for _, file := range files {
+		relPath := strings.TrimPrefix(filepath.ToSlash(file.Key), prefix)
+		if relPath == "" || relPath == file.Key {
+			continue
+		}
+		if _, ok := storage.MarkerDir(relPath); ok {
+			continue
+		}
+		if folder.File != "" && relPath != folder.File {
+			continue
+		}
+		if !fetchMatches(patterns, relPath) {
+			continue
+		}
+
+		item := remoteFetch{relPath: relPath, localPath: fetchLocalPath(folder, relPath), file: file}
+		item.action, item.stub, err = fetchAction(ctx, store, item, overwrite)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, item)
+	}
+
+	sort.Slice(plan, func(i, j int) bool { return plan[i].relPath < plan[j].relPath })
+	return plan, nil
+}
+
+// fetchAction decides what fetching a remote file does, by comparing it with the local file
+func fetchAction(ctx context.Context, store storage.Storage, item remoteFetch, overwrite bool) (string, *placeholder.Placeholder, error) {
+	info, err := os.Stat(item.localPath)
+	if os.IsNotExist(err) {
+		return fetchDownload, nil, nil
+	}
+	if err != nil {
+		return "", nil, i18n.Errorf("cannot access %s: %w", item.localPath, err)
+	}
+	if info.IsDir() {
+		return fetchKeep, nil, nil
+	}
+
+	if info.Size() <= placeholder.MaxSize {
+		if stub, err := placeholder.Read(item.localPath); err == nil {
+			return fetchDownload, &stub, nil
+		}
+	}
+
+	if info.Size() == item.file.Size {
+		_, metadata, err := store.GetFileInfo(ctx, item.file.Key)
+		if err != nil {
+			return "", nil, i18n.Errorf("failed to get remote info for %s: %w", item.relPath, err)
+		}
+		remoteHash := checksum.FromMetadata(metadata)
+		if localHash, err := fileHash(item.localPath, remoteHash); err == nil && remoteHash != "" && strings.EqualFold(localHash, remoteHash) {
+			return fetchCurrent, nil, nil
+		}
+	}
+	if overwrite {
+		return fetchOverwrite, nil, nil
+	}
+	return fetchKeep, nil, nil
+}
+
+// fetchMatches reports whether a relative path, or a directory above it, matches one of the
+// patterns. Patterns without a slash also match file names.
+func fetchMatches(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+		if !strings.Contains(pattern, "/") {
+			if matched, _ := path.Match(pattern, path.Base(relPath)); matched {
+				return true
+			}
+		}
+		for candidate := relPath; candidate != "."; candidate = path.Dir(candidate) {
+			if matched, _ := path.Match(pattern, candidate); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fetchLocalPath returns where a file of the folder lives locally, under the root whose
+// prefix it has
+func fetchLocalPath(folder *config.SyncFolder, relPath string) string {
+	for _, root := range folder.Roots {
+		if rest, ok := strings.CutPrefix(relPath, root.Prefix+"/"); ok {
+			return filepath.Join(root.Path, filepath.FromSlash(rest))
+		}
+	}
+	return filepath.Join(folder.Path, filepath.FromSlash(relPath))
+}
+
+// fetchRemoteFile downloads a remote file, whose hash the downloader verifies, over its local
+// path with the modification time of the remote copy, as the agent does
+func fetchRemoteFile(ctx context.Context, downloader *download.Downloader, item remoteFetch) error {
+	if err := os.MkdirAll(filepath.Dir(item.localPath), 0755); err != nil {
+		return err
+	}
+
+	tmpPath := item.localPath + ".fetch"
+	if _, err := downloader.Fetch(ctx, download.Task{Key: item.file.Key, Size: item.file.Size, Path: tmpPath}, nil); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, item.localPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if item.file.LastModified.IsZero() {
+		return nil
+	}
+	return os.Chtimes(item.localPath, item.file.LastModified, item.file.LastModified)
+}
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/placeholder"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchCommand(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	content := strings.Repeat("frame", 2000)
+	_, err := store.UploadFile(ctx, "docs/videos/clip.mp4", strings.NewReader(content), map[string]string{})
+	assert.NoError(t, err)
+	sum := sha256.Sum256([]byte(content))
+
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: dir, Enabled: true, ArchiveAfterDays: 30}}
+	fetchCmd := CreateFetchCommand(cfg, func() (storage.Storage, error) { return store, nil })
+
+	clip := filepath.Join(dir, "videos", "clip.mp4")
+	notes := filepath.Join(dir, "notes.txt")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(clip), 0755))
+	assert.NoError(t, os.WriteFile(clip, []byte("old"), 0644))
+	assert.NoError(t, os.WriteFile(notes, []byte("local notes"), 0644))
+	stub := placeholder.Placeholder{FolderID: "docs", Path: "videos/clip.mp4", Size: int64(len(content)), Hash: hex.EncodeToString(sum[:]), ModTime: time.Now().Add(-60 * 24 * time.Hour)}
+	assert.NoError(t, placeholder.Write(clip, stub))
+
+	// Só placeholders podem ser buscados
+	assert.ErrorContains(t, fetchCmd.RunE(fetchCmd, []string{notes}), "not an archived file")
+
+	// Um placeholder cujo arquivo remoto mudou é mantido
+	changed := stub
+	changed.Hash = "outro"
+	assert.NoError(t, placeholder.Write(clip, changed))
+	assert.ErrorContains(t, fetchCmd.RunE(fetchCmd, []string{clip}), "changed since it was archived")
+	_, err = placeholder.Read(clip)
+	assert.NoError(t, err)
+
+	// Buscar a pasta traz de volta todos os arquivos arquivados dentro dela
+	assert.NoError(t, placeholder.Write(clip, stub))
+	assert.NoError(t, fetchCmd.RunE(fetchCmd, []string{dir}))
+	data, err := os.ReadFile(clip)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	info, err := os.Stat(clip)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), info.ModTime(), time.Minute)
+
+	// Depois disso não há mais nada para buscar
+	assert.NoError(t, fetchCmd.RunE(fetchCmd, []string{dir}))
+}
+
+func TestFetchMatching(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for key, content := range map[string]string{
+		"docs/report.pdf":        "report",
+		"docs/photos/a.jpg":      "photo a",
+		"docs/photos/2024/b.jpg": "photo b",
+		"docs/notes.txt":         "remote notes",
+	} {
+		sum := sha256.Sum256([]byte(content))
+		_, err := store.UploadFile(ctx, key, strings.NewReader(content), map[string]string{
+			"hash_sha256": hex.EncodeToString(sum[:]),
+		})
+		assert.NoError(t, err)
+	}
+
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: dir, Enabled: true}}
+	notes := filepath.Join(dir, "notes.txt")
+	assert.NoError(t, os.WriteFile(notes, []byte("local notes"), 0644))
+
+	newCmd := func() *cobra.Command {
+		return CreateFetchCommand(cfg, func() (storage.Storage, error) { return store, nil })
+	}
+
+	// Padrões inválidos são recusados
+	fetchCmd := newCmd()
+	assert.ErrorContains(t, fetchCmd.RunE(fetchCmd, []string{"docs", "[a"}), "invalid pattern")
+
+	// --dry-run só lista o que seria baixado
+	assert.NoError(t, fetchCmd.Flags().Set("dry-run", "true"))
+	assert.NoError(t, fetchCmd.RunE(fetchCmd, []string{"docs", "*.jpg"}))
+	_, err := os.Stat(filepath.Join(dir, "photos"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Padrões sem barra casam com nomes de arquivo, mesmo em subpastas
+	fetchCmd = newCmd()
+	assert.NoError(t, fetchCmd.RunE(fetchCmd, []string{"docs", "*.jpg"}))
+	assertFileContent(t, filepath.Join(dir, "photos", "a.jpg"), "photo a")
+	assertFileContent(t, filepath.Join(dir, "photos", "2024", "b.jpg"), "photo b")
+	remote, _, err := store.GetFileInfo(ctx, "docs/photos/a.jpg")
+	assert.NoError(t, err)
+	info, err := os.Stat(filepath.Join(dir, "photos", "a.jpg"))
+	assert.NoError(t, err)
+	assert.True(t, remote.LastModified.Equal(info.ModTime()))
+	_, err = os.Stat(filepath.Join(dir, "report.pdf"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Arquivos locais diferentes são mantidos sem --overwrite
+	fetchCmd = newCmd()
+	assert.NoError(t, fetchCmd.RunE(fetchCmd, []string{"docs", "notes.txt", "report.*"}))
+	assertFileContent(t, notes, "local notes")
+	assertFileContent(t, filepath.Join(dir, "report.pdf"), "report")
+
+	fetchCmd = newCmd()
+	assert.NoError(t, fetchCmd.Flags().Set("overwrite", "true"))
+	assert.NoError(t, fetchCmd.RunE(fetchCmd, []string{"docs", "notes.txt"}))
+	assertFileContent(t, notes, "remote notes")
+
+	// Pastas de backup não são buscadas
+	cfg.SyncFolders[0].Mode = config.FolderModeBackup
+	assert.ErrorContains(t, fetchCmd.RunE(fetchCmd, []string{"docs", "*"}), "backup mode")
+}
+
+func assertFileContent(t *testing.T, path, content string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
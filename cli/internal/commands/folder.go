@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,38 +11,110 @@ import (
 	"github.com/martinshumberto/sync-manager/cli/internal/client"
 	"github.com/martinshumberto/sync-manager/cli/internal/services"
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/storage"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
-// CreateFolderCommands creates commands for folder management
-func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClient *client.AgentClient, folderService *services.FolderService) []*cobra.Command {
+// CreateFolderCommands creates commands for folder management. openStorage is used to
+// preview the initial merge of a folder joining remote content.
+func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClient *client.AgentClient, folderService *services.FolderService, openStorage func() (storage.Storage, error), userID uint) []*cobra.Command {
 	var cmds []*cobra.Command
 
 	// Add folder command
 	addCmd := &cobra.Command{
 		Use:   "add-folder [path]",
-		Short: "Add a folder to sync",
-		Args:  cobra.ExactArgs(1),
+		Short: "Add a folder, or a single file, to sync",
+		Long: `Add a folder, or a single file, to sync.
+
+To sync a folder that already has content with a folder synced from another device,
+pass that folder's ID with --folder-id along with --two-way. --initial-merge picks
+which copy wins for the files that differ on both sides during the first sync:
+keep-both, prefer-newest, prefer-local or prefer-remote. Files identical on both
+sides are left alone. The reconciliation plan is shown before the folder is added;
+--dry-run only shows it.
+
+--target picks the storage target the folder syncs to; without it the folder uses
+the first target in the configuration.
+
+--subscribe makes the folder a read-only copy of the remote folder another device
+publishes under the given prefix, its folder ID: published changes are downloaded
+and nothing is ever uploaded. --local-changes decides what happens to files changed
+here: revert (the default) restores the published copy and removes files added
+locally; flag keeps the change and records it until the publisher updates the file.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			path := args[0]
 			folderName, _ := cmd.Flags().GetString("name")
 			priority, _ := cmd.Flags().GetInt("priority")
 			twoWay, _ := cmd.Flags().GetBool("two-way")
+			excludePattern, _ := cmd.Flags().GetStringArray("exclude")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			mode, _ := cmd.Flags().GetString("mode")
+			storageClass, _ := cmd.Flags().GetString("storage-class")
+			folderID, _ := cmd.Flags().GetString("folder-id")
+			initialMerge, _ := cmd.Flags().GetString("initial-merge")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			targetName, _ := cmd.Flags().GetString("target")
+			subscribe, _ := cmd.Flags().GetString("subscribe")
+			localChanges, _ := cmd.Flags().GetString("local-changes")
+
+			if interval < 0 {
+				return i18n.Errorf("interval cannot be negative")
+			}
+			if err := validateFolderMode(mode); err != nil {
+				return err
+			}
+			target, err := folderTarget(cfg, targetName)
+			if err != nil {
+				return err
+			}
+			storageClass, err = validateStorageClass(target, storageClass)
+			if err != nil {
+				return err
+			}
+			if subscribe != "" || localChanges != "" {
+				// A subscribed folder takes the ID of the remote folder and downloads its changes
+				folderID, err = validateSubscribe(subscribe, folderID, localChanges, initialMerge, mode)
+				if err != nil {
+					return err
+				}
+				twoWay = true
+			}
+			if err := validateInitialMerge(cfg, folderID, initialMerge, twoWay, mode, dryRun); err != nil {
+				return err
+			}
 
 			// Check if the folder exists
 			info, err := os.Stat(path)
 			if err != nil {
-				return fmt.Errorf("cannot access folder %s: %w", path, err)
+				return i18n.Errorf("cannot access folder %s: %w", path, err)
 			}
-			if !info.IsDir() {
-				return fmt.Errorf("%s is not a directory", path)
+			if !info.IsDir() && !info.Mode().IsRegular() {
+				return i18n.Errorf("%s is not a directory or a regular file", path)
 			}
 
 			// Get absolute path
 			absPath, err := filepath.Abs(path)
 			if err != nil {
-				return fmt.Errorf("failed to get absolute path: %w", err)
+				return i18n.Errorf("failed to get absolute path: %w", err)
+			}
+
+			// A single file becomes a folder on its directory that only syncs that file
+			folderPath, file := absPath, ""
+			if !info.IsDir() {
+				folderPath, file = filepath.Dir(absPath), filepath.Base(absPath)
+				if mode == config.FolderModeBackup {
+					return i18n.Errorf("single-file folders cannot be backup folders")
+				}
+			}
+
+			// A folder inside another one, or containing one, would upload the same files twice
+			nested, err := nestedOverlaps(cfg, folderPath, file)
+			if err != nil {
+				return err
 			}
 
 			// If no name is provided, use the folder name
@@ -49,21 +122,74 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 				folderName = filepath.Base(absPath)
 			}
 
-			// Create folder in database
-			// In a real app, we'd get the current user's ID
-			folder, err := folderService.CreateFolder(1, folderName, absPath, false, priority, twoWay)
+			// Show how the first sync reconciles the content already on both sides
+			if initialMerge != "" {
+				if openStorage == nil {
+					return i18n.Errorf("storage is not available to preview the initial merge")
+				}
+				store, err := openStorage()
+				if err != nil {
+					return i18n.Errorf("failed to open storage: %w", err)
+				}
+				preview := &config.SyncFolder{ID: folderID, Path: folderPath, Exclude: excludePattern, File: file}
+				steps, err := MergePlan(context.Background(), targetStore(store, targetName), preview, initialMerge)
+				if err != nil {
+					return i18n.Errorf("failed to preview the initial merge: %w", err)
+				}
+				fmt.Print(RenderMergePlan(folderID, initialMerge, steps))
+				if dryRun {
+					return nil
+				}
+			}
+
+			// Create folder in database, owned by the active user
+			var folder *models.Folder
+			if folderID != "" {
+				folder, err = folderService.CreateFolderWithID(folderID, userID, folderName, folderPath, false, priority, twoWay)
+			} else {
+				folder, err = folderService.CreateFolder(userID, folderName, folderPath, false, priority, twoWay)
+			}
 			if err != nil {
-				return fmt.Errorf("failed to create folder in database: %w", err)
+				return i18n.Errorf("failed to create folder in database: %w", err)
+			}
+
+			for i := range cfg.SyncFolders {
+				if cfg.SyncFolders[i].ID == folder.FolderID {
+					if len(excludePattern) > 0 {
+						cfg.SyncFolders[i].Exclude = excludePattern
+					}
+					cfg.SyncFolders[i].Interval = interval
+					cfg.SyncFolders[i].Mode = mode
+					cfg.SyncFolders[i].StorageClass = storageClass
+					cfg.SyncFolders[i].File = file
+					cfg.SyncFolders[i].InitialMerge = initialMerge
+					cfg.SyncFolders[i].Target = targetName
+					cfg.SyncFolders[i].Subscribe = subscribe != ""
+					cfg.SyncFolders[i].LocalChanges = localChanges
+					break
+				}
 			}
 
 			// Save the configuration
 			if err := saveConfig(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
+				return i18n.Errorf("failed to save configuration: %w", err)
 			}
 
-			fmt.Printf("Folder added to sync list: %s\n", absPath)
-			fmt.Printf("Folder ID: %s\n", folder.FolderID)
-			fmt.Println("The agent will sync this folder when it's running.")
+			if file != "" {
+				i18n.Printf("File added to sync list: %s\n", absPath)
+			} else {
+				i18n.Printf("Folder added to sync list: %s\n", absPath)
+			}
+			i18n.Printf("Folder ID: %s\n", folder.FolderID)
+			for _, overlap := range nested {
+				if overlap.Inner == "" {
+					i18n.Printf("Folder %s contains it, so it leaves out %s\n", overlap.Outer, overlap.RelPath)
+				} else {
+					i18n.Printf("Folder %s is inside it, so this folder leaves out %s\n", overlap.Inner, overlap.RelPath)
+				}
+			}
+			warnArchiveClass(storageClass)
+			fmt.Println(FolderChangeNotice(agentClient))
 			return nil
 		},
 	}
@@ -71,6 +197,16 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 	addCmd.Flags().StringP("name", "n", "", "Folder name")
 	addCmd.Flags().IntP("priority", "p", 1, "Sync priority (lower numbers are higher priority)")
 	addCmd.Flags().BoolP("two-way", "t", false, "Enable two-way sync (changes on remote will be downloaded)")
+	addCmd.Flags().StringArrayP("exclude", "e", nil, "Exclude pattern (can be specified multiple times)")
+	addCmd.Flags().Duration("interval", 0, "Sync interval for this folder (e.g. 10m); defaults to the global interval")
+	addCmd.Flags().String("mode", config.FolderModeMirror, "Folder mode: mirror keeps the remote identical, backup stores a snapshot on every sync")
+	addCmd.Flags().String("storage-class", "", "Storage class for uploaded files (e.g. STANDARD_IA, GLACIER_IR, NEARLINE, ARCHIVE); defaults to the bucket's class")
+	addCmd.Flags().String("folder-id", "", "ID of a folder synced from another device, to sync its remote content with this folder")
+	addCmd.Flags().String("initial-merge", "", "Copy kept on the first sync for files that differ on both sides: keep-both, prefer-newest, prefer-local or prefer-remote; requires --folder-id")
+	addCmd.Flags().Bool("dry-run", false, "Only show the initial merge plan, without adding the folder")
+	addCmd.Flags().String("target", "", "Storage target the folder syncs to; defaults to the first configured target")
+	addCmd.Flags().String("subscribe", "", "Remote prefix (folder ID) of a folder published by another device, to keep a read-only copy of it")
+	addCmd.Flags().String("local-changes", "", "What a subscribed folder does with files changed locally: revert or flag; defaults to revert")
 
 	cmds = append(cmds, addCmd)
 
@@ -80,27 +216,25 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 		Short: "List all synchronized folders",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(cfg.SyncFolders) == 0 {
-				fmt.Println("No folders configured for synchronization.")
+				i18n.Println("No folders configured for synchronization.")
 				return nil
 			}
 
 			// Print as a table
 			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"ID", "Path", "Status", "Exclude Patterns"})
+			table.SetHeader(i18n.Strings("ID", "Path", "Status", "Interval", "Exclude Patterns"))
 
 			for _, folder := range cfg.SyncFolders {
-				status := "Enabled"
-				if !folder.Enabled {
-					status = "Disabled"
-				}
+				status := FolderStatusLabel(folder)
 				excludes := "-"
 				if len(folder.Exclude) > 0 {
 					excludes = strings.Join(folder.Exclude, ", ")
 				}
 				table.Append([]string{
 					folder.ID,
-					folder.Path,
+					FolderPathLabel(folder),
 					status,
+					FolderIntervalLabel(folder, cfg.SyncInterval),
 					excludes,
 				})
 			}
@@ -132,13 +266,13 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 			}
 
 			if folderIndex == -1 {
-				return fmt.Errorf("folder with ID %s not found", folderID)
+				return i18n.Errorf("folder with ID %s not found", folderID)
 			}
 
 			// Remove from database too
-			err := folderService.DeleteFolder(folderID)
+			err := folderService.DeleteFolder(userID, folderID)
 			if err != nil {
-				fmt.Printf("Warning: Failed to remove folder from database: %v\n", err)
+				i18n.Printf("Warning: Failed to remove folder from database: %v\n", err)
 				// Continue anyway to clean up the config
 			}
 
@@ -147,10 +281,11 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 
 			// Save the configuration
 			if err := saveConfig(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
+				return i18n.Errorf("failed to save configuration: %w", err)
 			}
 
-			fmt.Printf("Removed folder: %s (ID: %s)\n", folderPath, folderID)
+			i18n.Printf("Removed folder: %s (ID: %s)\n", folderPath, folderID)
+			fmt.Println(FolderChangeNotice(agentClient))
 			return nil
 		},
 	}
@@ -178,22 +313,23 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 			}
 
 			if !found {
-				return fmt.Errorf("folder with ID %s not found", folderID)
+				return i18n.Errorf("folder with ID %s not found", folderID)
 			}
 
 			// Update in database too
-			err := folderService.UpdateFolderStatus(folderID, true)
+			err := folderService.UpdateFolderStatus(userID, folderID, true)
 			if err != nil {
-				fmt.Printf("Warning: Failed to update folder status in database: %v\n", err)
+				i18n.Printf("Warning: Failed to update folder status in database: %v\n", err)
 				// Continue anyway to update the config
 			}
 
 			// Save the configuration
 			if err := saveConfig(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
+				return i18n.Errorf("failed to save configuration: %w", err)
 			}
 
-			fmt.Printf("Enabled synchronization for folder: %s (ID: %s)\n", folderPath, folderID)
+			i18n.Printf("Enabled synchronization for folder: %s (ID: %s)\n", folderPath, folderID)
+			fmt.Println(FolderChangeNotice(agentClient))
 			return nil
 		},
 	}
@@ -221,22 +357,23 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 			}
 
 			if !found {
-				return fmt.Errorf("folder with ID %s not found", folderID)
+				return i18n.Errorf("folder with ID %s not found", folderID)
 			}
 
 			// Update in database too
-			err := folderService.UpdateFolderStatus(folderID, false)
+			err := folderService.UpdateFolderStatus(userID, folderID, false)
 			if err != nil {
-				fmt.Printf("Warning: Failed to update folder status in database: %v\n", err)
+				i18n.Printf("Warning: Failed to update folder status in database: %v\n", err)
 				// Continue anyway to update the config
 			}
 
 			// Save the configuration
 			if err := saveConfig(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
+				return i18n.Errorf("failed to save configuration: %w", err)
 			}
 
-			fmt.Printf("Disabled synchronization for folder: %s (ID: %s)\n", folderPath, folderID)
+			i18n.Printf("Disabled synchronization for folder: %s (ID: %s)\n", folderPath, folderID)
+			fmt.Println(FolderChangeNotice(agentClient))
 			return nil
 		},
 	}
@@ -261,7 +398,7 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 			}
 
 			if folderIndex == -1 {
-				return fmt.Errorf("folder with ID %s not found", folderID)
+				return i18n.Errorf("folder with ID %s not found", folderID)
 			}
 
 			// Get the flags
@@ -269,17 +406,40 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 			twoWay, _ := cmd.Flags().GetBool("two-way")
 			priority, _ := cmd.Flags().GetInt("priority")
 			excludePattern, _ := cmd.Flags().GetStringArray("exclude")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			mode, _ := cmd.Flags().GetString("mode")
+			storageClass, _ := cmd.Flags().GetString("storage-class")
+			conflictPolicy, _ := cmd.Flags().GetString("conflict-policy")
+			targetName := cfg.SyncFolders[folderIndex].Target
+			if cmd.Flags().Changed("target") {
+				targetName, _ = cmd.Flags().GetString("target")
+			}
+
+			if interval < 0 {
+				return i18n.Errorf("interval cannot be negative")
+			}
+			if err := validateFolderMode(mode); err != nil {
+				return err
+			}
+			target, err := folderTarget(cfg, targetName)
+			if err != nil {
+				return err
+			}
+			storageClass, err = validateStorageClass(target, storageClass)
+			if err != nil {
+				return err
+			}
+			if err := config.ValidateConflictPolicy(conflictPolicy); err != nil {
+				return err
+			}
 
 			// Update the folder configuration
 			if name != "" {
 				// Update the name in the database too
-				status := "active"
-				if !cfg.SyncFolders[folderIndex].Enabled {
-					status = "disabled"
-				}
-				err := folderService.UpdateFolder(folderID, name, status, false)
+				status := models.FolderStatusOf(cfg.SyncFolders[folderIndex].Enabled, cfg.SyncFolders[folderIndex].Paused)
+				err := folderService.UpdateFolder(userID, folderID, name, status, false)
 				if err != nil {
-					fmt.Printf("Warning: Failed to update folder name in database: %v\n", err)
+					i18n.Printf("Warning: Failed to update folder name in database: %v\n", err)
 				}
 			}
 
@@ -295,12 +455,176 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 				cfg.SyncFolders[folderIndex].Exclude = excludePattern
 			}
 
+			if cmd.Flags().Changed("interval") {
+				cfg.SyncFolders[folderIndex].Interval = interval
+			}
+
+			if cmd.Flags().Changed("checksum") {
+				updated := cfg.SyncFolders[folderIndex]
+				updated.Checksum, _ = cmd.Flags().GetString("checksum")
+				if err := updated.ValidateChecksum(); err != nil {
+					return i18n.Errorf("invalid checksum: %w", err)
+				}
+				cfg.SyncFolders[folderIndex].Checksum = updated.Checksum
+			}
+
+			if cmd.Flags().Changed("mode") {
+				updated := cfg.SyncFolders[folderIndex]
+				updated.Mode = mode
+				if err := updated.ValidateFile(); err != nil {
+					return err
+				}
+				if err := updated.ValidateChecksum(); err != nil {
+					return i18n.Errorf("invalid checksum: %w", err)
+				}
+				cfg.SyncFolders[folderIndex].Mode = mode
+			}
+
+			if cmd.Flags().Changed("storage-class") {
+				cfg.SyncFolders[folderIndex].StorageClass = storageClass
+				warnArchiveClass(storageClass)
+			}
+
+			if cmd.Flags().Changed("conflict-policy") {
+				cfg.SyncFolders[folderIndex].ConflictPolicy = conflictPolicy
+			}
+
+			if targetName != cfg.SyncFolders[folderIndex].Target {
+				cfg.SyncFolders[folderIndex].Target = targetName
+				i18n.Printf("Warning: files already uploaded stay on the previous target; the next sync uploads the folder to %s.\n", target.Name)
+			}
+
+			if cmd.Flags().Changed("in-use-timeout") {
+				cfg.SyncFolders[folderIndex].InUseTimeout, _ = cmd.Flags().GetDuration("in-use-timeout")
+			}
+
+			if cmd.Flags().Changed("local-changes") {
+				cfg.SyncFolders[folderIndex].LocalChanges, _ = cmd.Flags().GetString("local-changes")
+			}
+
+			if cmd.Flags().Changed("archive-after-days") {
+				cfg.SyncFolders[folderIndex].ArchiveAfterDays, _ = cmd.Flags().GetInt("archive-after-days")
+			}
+
+			if cmd.Flags().Changed("schedule") {
+				updated := cfg.SyncFolders[folderIndex]
+				updated.Schedule, _ = cmd.Flags().GetString("schedule")
+				if err := updated.ValidateSchedule(); err != nil {
+					return i18n.Errorf("invalid schedule: %w", err)
+				}
+				cfg.SyncFolders[folderIndex].Schedule = updated.Schedule
+			}
+
+			if cmd.Flags().Changed("min-age") || cmd.Flags().Changed("max-age") || cmd.Flags().Changed("modified-since") {
+				updated := cfg.SyncFolders[folderIndex]
+				if cmd.Flags().Changed("min-age") {
+					updated.MinAge, _ = cmd.Flags().GetDuration("min-age")
+				}
+				if cmd.Flags().Changed("max-age") {
+					updated.MaxAge, _ = cmd.Flags().GetDuration("max-age")
+				}
+				if cmd.Flags().Changed("modified-since") {
+					updated.ModifiedSince, _ = cmd.Flags().GetString("modified-since")
+				}
+				if err := updated.ValidateAgeFilter(); err != nil {
+					return i18n.Errorf("invalid age filter: %w", err)
+				}
+				cfg.SyncFolders[folderIndex].AgeFilter = updated.AgeFilter
+			}
+
+			updated := cfg.SyncFolders[folderIndex]
+			for stage, hook := range map[string]*config.Hook{"pre-sync": &updated.Hooks.PreSync, "post-sync": &updated.Hooks.PostSync} {
+				if cmd.Flags().Changed(stage) {
+					hook.Command, _ = cmd.Flags().GetString(stage)
+				}
+				if cmd.Flags().Changed(stage + "-timeout") {
+					hook.Timeout, _ = cmd.Flags().GetDuration(stage + "-timeout")
+				}
+				if cmd.Flags().Changed(stage + "-on-failure") {
+					hook.OnFailure, _ = cmd.Flags().GetString(stage + "-on-failure")
+				}
+			}
+			if err := updated.ValidateHooks(); err != nil {
+				return i18n.Errorf("invalid hooks: %w", err)
+			}
+			cfg.SyncFolders[folderIndex].Hooks = updated.Hooks
+
+			if err := updateFolderRoots(cmd, &cfg.SyncFolders[folderIndex]); err != nil {
+				return err
+			}
+
+			mirror := &cfg.SyncFolders[folderIndex].Mirror
+			if cmd.Flags().Changed("delete-orphans") {
+				mirror.DeleteOrphans, _ = cmd.Flags().GetBool("delete-orphans")
+			}
+			if cmd.Flags().Changed("trash-orphans") {
+				mirror.Trash, _ = cmd.Flags().GetBool("trash-orphans")
+			}
+			if cmd.Flags().Changed("max-delete") {
+				mirror.MaxDelete, _ = cmd.Flags().GetInt("max-delete")
+			}
+			if cmd.Flags().Changed("max-delete-percent") {
+				mirror.MaxDeletePercent, _ = cmd.Flags().GetInt("max-delete-percent")
+			}
+
+			retention := &cfg.SyncFolders[folderIndex].Retention
+			if cmd.Flags().Changed("keep-last") {
+				retention.KeepLast, _ = cmd.Flags().GetInt("keep-last")
+			}
+			if cmd.Flags().Changed("keep-daily") {
+				retention.KeepDaily, _ = cmd.Flags().GetInt("keep-daily")
+			}
+			if cmd.Flags().Changed("keep-weekly") {
+				retention.KeepWeekly, _ = cmd.Flags().GetInt("keep-weekly")
+			}
+			if cmd.Flags().Changed("keep-monthly") {
+				retention.KeepMonthly, _ = cmd.Flags().GetInt("keep-monthly")
+			}
+
+			if err := cfg.SyncFolders[folderIndex].ValidateSubscribe(); err != nil {
+				return i18n.Errorf("invalid subscription: %w", err)
+			}
+			if err := cfg.SyncFolders[folderIndex].ValidateArchive(); err != nil {
+				return i18n.Errorf("invalid archive policy: %w", err)
+			}
+
+			if cmd.Flags().Changed("pack-small-files") {
+				cfg.SyncFolders[folderIndex].PackSmallFiles, _ = cmd.Flags().GetInt64("pack-small-files")
+			}
+			if err := cfg.SyncFolders[folderIndex].ValidatePacking(); err != nil {
+				return i18n.Errorf("invalid packing: %w", err)
+			}
+
+			if cmd.Flags().Changed("remote-prefix") {
+				if cmd.Flags().Changed("target") {
+					return i18n.Errorf("change --target and --remote-prefix separately")
+				}
+				updated := cfg.SyncFolders[folderIndex]
+				updated.RemotePrefix, _ = cmd.Flags().GetString("remote-prefix")
+				if err := updated.ValidateRemotePrefix(); err != nil {
+					return i18n.Errorf("invalid remote prefix: %w", err)
+				}
+				if updated.RemotePrefix == updated.ID {
+					updated.RemotePrefix = ""
+				}
+				folders := append([]config.SyncFolder{}, cfg.SyncFolders...)
+				folders[folderIndex] = updated
+				if err := config.ValidateKeyPrefixes(folders); err != nil {
+					return err
+				}
+				if err := moveRemoteFiles(openStorage, agentClient, &updated, cfg.SyncFolders[folderIndex].KeyPrefix()); err != nil {
+					return err
+				}
+				cfg.SyncFolders[folderIndex].RemotePrefix = updated.RemotePrefix
+			}
+
 			// Save the configuration
 			if err := saveConfig(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
+				return i18n.Errorf("failed to save configuration: %w", err)
 			}
 
-			fmt.Printf("Updated configuration for folder: %s (ID: %s)\n", cfg.SyncFolders[folderIndex].Path, folderID)
+			i18n.Printf("Updated configuration for folder: %s (ID: %s)\n", cfg.SyncFolders[folderIndex].Path, folderID)
+			fmt.Println(FolderChangeNotice(agentClient))
 			return nil
 		},
 	}
@@ -309,12 +633,356 @@ func CreateFolderCommands(cfg *config.Config, saveConfig func() error, agentClie
 	configureFolderCmd.Flags().BoolP("two-way", "t", false, "Enable two-way sync (changes on remote will be downloaded)")
 	configureFolderCmd.Flags().IntP("priority", "p", 0, "Sync priority (lower numbers are higher priority)")
 	configureFolderCmd.Flags().StringArrayP("exclude", "e", nil, "Exclude pattern (can be specified multiple times)")
+	configureFolderCmd.Flags().Duration("interval", 0, "Sync interval for this folder (e.g. 10m); 0 uses the global interval")
+	configureFolderCmd.Flags().String("schedule", "", "Cron expression of when the folder syncs (e.g. \"0 2 * * *\"), overriding the interval; empty syncs every interval")
+	configureFolderCmd.Flags().String("mode", "", "Folder mode: mirror or backup")
+	configureFolderCmd.Flags().String("target", "", "Storage target the folder syncs to; empty uses the first configured target")
+	configureFolderCmd.Flags().String("storage-class", "", "Storage class for files uploaded from now on; empty uses the bucket's class")
+	configureFolderCmd.Flags().String("conflict-policy", "", "Copy kept when a file changed on both sides: keep-both, prefer-local, prefer-remote or prefer-newest; empty uses keep-both")
+	configureFolderCmd.Flags().Duration("in-use-timeout", 0, i18n.Sprintf("Longest wait for files still being written before uploading them anyway (e.g. 30m); 0 uses %s, negative uploads them right away", config.DefaultInUseTimeout))
+	configureFolderCmd.Flags().String("local-changes", "", "Subscribed folders: what happens to files changed locally, revert or flag")
+	configureFolderCmd.Flags().StringArray("add-root", nil, "Add a local directory to the folder as PREFIX=PATH; its files are synced under PREFIX (can be specified multiple times)")
+	configureFolderCmd.Flags().StringArray("remove-root", nil, "Remove the extra root with the given prefix (can be specified multiple times)")
+	configureFolderCmd.Flags().Bool("delete-orphans", false, "Mirror mode: remove remote files that were deleted locally (one-way folders only)")
+	configureFolderCmd.Flags().Bool("trash-orphans", false, "Mirror mode: move removed remote files under .trash/<folder-id>/ instead of deleting them")
+	configureFolderCmd.Flags().Int("max-delete", 0, i18n.Sprintf("Mirror mode: hold back orphan removal until forced when more than N remote files would go; 0 uses %d, negative removes any number", config.DefaultMaxDelete))
+	configureFolderCmd.Flags().Int("max-delete-percent", 0, i18n.Sprintf("Mirror mode: hold back orphan removal until forced when more than N%% of the remote files would go; 0 uses %d, negative removes any share", config.DefaultMaxDeletePercent))
+	configureFolderCmd.Flags().Int("archive-after-days", 0, "Replace files uploaded and left unmodified for N days with placeholders to free disk space, fetched back with 'sync-manager fetch'; 0 keeps every file local")
+	configureFolderCmd.Flags().Duration("min-age", 0, "Wait until files are this old since their last change before uploading them (e.g. 30s); 0 uploads them once they settle")
+	configureFolderCmd.Flags().Duration("max-age", 0, "Leave out files last modified longer ago than this (e.g. 720h); 0 for no limit")
+	configureFolderCmd.Flags().String("modified-since", "", "Leave out files last modified before this date (YYYY-MM-DD or RFC 3339); empty for no limit")
+	configureFolderCmd.Flags().String("pre-sync", "", "Shell command run in the folder before each sync, e.g. to dump a database into it; empty runs none")
+	configureFolderCmd.Flags().Duration("pre-sync-timeout", 0, i18n.Sprintf("Time the pre-sync command gets before it is killed; 0 uses %s", config.DefaultHookTimeout))
+	configureFolderCmd.Flags().String("pre-sync-on-failure", "", "What a failed pre-sync command does: abort skips the sync (the default), continue syncs anyway")
+	configureFolderCmd.Flags().String("post-sync", "", "Shell command run in the folder once each sync ended, with its result in SYNC_MANAGER_* variables; empty runs none")
+	configureFolderCmd.Flags().Duration("post-sync-timeout", 0, i18n.Sprintf("Time the post-sync command gets before it is killed; 0 uses %s", config.DefaultHookTimeout))
+	configureFolderCmd.Flags().String("post-sync-on-failure", "", "What a failed post-sync command does: continue only logs it (the default), abort marks the sync failed")
+	configureFolderCmd.Flags().String("checksum", "", "Algorithm hashing the files uploaded from now on: sha256 or blake3, which spreads large files over every core; empty uses sha256")
+	configureFolderCmd.Flags().String("remote-prefix", "", "Storage prefix the folder's files are kept under, moving the files already uploaded there; empty uses the folder ID")
+	configureFolderCmd.Flags().Int("keep-last", 0, "Backup mode: keep the N most recent snapshots")
+	configureFolderCmd.Flags().Int64("pack-small-files", 0, "Backup mode: pack files of up to N bytes together into shared objects, cutting the requests of folders with many tiny files; 0 stores each file alone")
+	configureFolderCmd.Flags().Int("keep-daily", 0, "Backup mode: keep one snapshot for each of the last N days")
+	configureFolderCmd.Flags().Int("keep-weekly", 0, "Backup mode: keep one snapshot for each of the last N weeks")
+	configureFolderCmd.Flags().Int("keep-monthly", 0, "Backup mode: keep one snapshot for each of the last N months")
 
 	cmds = append(cmds, configureFolderCmd)
 
+	// Pause folder command
+	pauseFolderCmd := &cobra.Command{
+		Use:   "pause-folder [folder-id]",
+		Short: "Pause synchronization for a folder",
+		Long:  `Temporarily stop synchronizing a folder. Unlike disable-folder, the folder keeps its settings and is picked up again with resume-folder.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setFolderPaused(cfg, saveConfig, agentClient, folderService, userID, args[0], true)
+		},
+	}
+
+	cmds = append(cmds, pauseFolderCmd)
+
+	// Resume folder command
+	resumeFolderCmd := &cobra.Command{
+		Use:   "resume-folder [folder-id]",
+		Short: "Resume synchronization for a paused folder",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setFolderPaused(cfg, saveConfig, agentClient, folderService, userID, args[0], false)
+		},
+	}
+
+	cmds = append(cmds, resumeFolderCmd)
+
 	return cmds
 }
 
+// updateFolderRoots applies the --remove-root and --add-root flags to a folder
+func updateFolderRoots(cmd *cobra.Command, folder *config.SyncFolder) error {
+	removed, _ := cmd.Flags().GetStringArray("remove-root")
+	added, _ := cmd.Flags().GetStringArray("add-root")
+	if len(removed) == 0 && len(added) == 0 {
+		return nil
+	}
+
+	roots := folder.Roots
+	for _, prefix := range removed {
+		prefix = strings.Trim(filepath.ToSlash(prefix), "/")
+		found := false
+		for i, root := range roots {
+			if root.Prefix == prefix {
+				roots = append(roots[:i:i], roots[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return i18n.Errorf("folder %s has no root with prefix %s", folder.ID, prefix)
+		}
+	}
+
+	for _, spec := range added {
+		prefix, path, ok := strings.Cut(spec, "=")
+		if !ok || path == "" {
+			return i18n.Errorf("invalid root %q, expected PREFIX=PATH", spec)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return i18n.Errorf("cannot access folder %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			return i18n.Errorf("%s is not a directory", path)
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return i18n.Errorf("failed to get absolute path: %w", err)
+		}
+		roots = append(roots, config.FolderRoot{Path: absPath, Prefix: prefix})
+	}
+
+	updated := *folder
+	updated.Roots = roots
+	if err := updated.ValidateRoots(); err != nil {
+		return err
+	}
+	if err := updated.ValidateFile(); err != nil {
+		return err
+	}
+	folder.Roots = updated.Roots
+	return nil
+}
+
+// FolderPathLabel returns the local path of a folder, or of its file, followed by its extra roots
+func FolderPathLabel(folder config.SyncFolder) string {
+	lines := []string{folder.Path}
+	if folder.File != "" {
+		lines[0] = filepath.Join(folder.Path, folder.File)
+	}
+	for _, root := range folder.Roots {
+		lines = append(lines, fmt.Sprintf("%s/ ← %s", root.Prefix, root.Path))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// setFolderPaused pauses or resumes a folder and saves the configuration
+func setFolderPaused(cfg *config.Config, saveConfig func() error, agentClient *client.AgentClient, folderService *services.FolderService, userID uint, folderID string, paused bool) error {
+	folder := findSyncFolder(cfg, folderID)
+	if folder == nil {
+		return i18n.Errorf("folder with ID %s not found", folderID)
+	}
+
+	folder.Paused = paused
+
+	// Update in database too
+	if err := folderService.SetFolderPaused(userID, folderID, paused); err != nil {
+		i18n.Printf("Warning: Failed to update folder status in database: %v\n", err)
+		// Continue anyway to update the config
+	}
+
+	// Save the configuration
+	if err := saveConfig(); err != nil {
+		return i18n.Errorf("failed to save configuration: %w", err)
+	}
+
+	if paused {
+		i18n.Printf("Paused synchronization for folder: %s (ID: %s)\n", folder.Path, folderID)
+		i18n.Printf("Use 'sync-manager resume-folder %s' to resume it.\n", folderID)
+	} else {
+		i18n.Printf("Resumed synchronization for folder: %s (ID: %s)\n", folder.Path, folderID)
+	}
+	fmt.Println(FolderChangeNotice(agentClient))
+	return nil
+}
+
+// FolderChangeNotice tells when a saved folder change takes effect. The running agent
+// picks up the configuration file within seconds; a stopped one applies it when started.
+func FolderChangeNotice(agentClient *client.AgentClient) string {
+	if agentClient != nil && agentClient.Health() == nil {
+		return i18n.T("The running agent applies this change within a few seconds.")
+	}
+	return i18n.T("The agent is not running; the change takes effect when it starts ('sync-manager start').")
+}
+
+// moveRemoteFiles moves the files a folder kept under its previous remote prefix to its
+// current one. The agent must not upload to the folder meanwhile, so the folder has to be
+// paused or the agent stopped.
+func moveRemoteFiles(openStorage func() (storage.Storage, error), agentClient *client.AgentClient, folder *config.SyncFolder, previous string) error {
+	if folder.KeyPrefix() == previous {
+		return nil
+	}
+	if agentClient != nil && agentClient.Health() == nil && !folder.Paused {
+		return i18n.Errorf("pause the folder ('pause-folder %s') or stop the agent before changing its remote prefix", folder.ID)
+	}
+	if openStorage == nil {
+		return i18n.Errorf("storage is not available to move the remote files")
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return i18n.Errorf("failed to open storage: %w", err)
+	}
+	moved, err := storage.MovePrefix(context.Background(), targetStore(store, folder.Target), previous, folder.KeyPrefix())
+	if err != nil {
+		return i18n.Errorf("failed to move remote files: %w", err)
+	}
+	i18n.Printf("Moved %s from %s/ to %s/\n", pluralize(moved, "file"), previous, folder.KeyPrefix())
+	i18n.Println("Other devices syncing this folder must be given the same remote prefix.")
+	return nil
+}
+
+// validateInitialMerge checks the flags of a folder joining remote content: the folder must
+// not be configured yet, and an initial merge needs it and a two-way folder
+func validateInitialMerge(cfg *config.Config, folderID, initialMerge string, twoWay bool, mode string, dryRun bool) error {
+	if folderID != "" && findSyncFolder(cfg, folderID) != nil {
+		return i18n.Errorf("folder %s is already configured", folderID)
+	}
+	if initialMerge == "" {
+		if dryRun {
+			return i18n.Errorf("--dry-run requires --initial-merge")
+		}
+		return nil
+	}
+	if err := config.ValidateConflictPolicy(initialMerge); err != nil {
+		return i18n.Errorf("invalid initial merge: %w", err)
+	}
+	if folderID == "" {
+		return i18n.Errorf("--initial-merge requires --folder-id, the ID of the remote folder to merge with")
+	}
+	if !twoWay || mode == config.FolderModeBackup {
+		return i18n.Errorf("--initial-merge requires a two-way mirror folder")
+	}
+	return nil
+}
+
+// validateSubscribe checks the flags of a folder subscribed to a remote prefix and returns the
+// folder ID it takes, the prefix itself
+func validateSubscribe(prefix, folderID, localChanges, initialMerge, mode string) (string, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return "", i18n.Errorf("--local-changes requires --subscribe")
+	}
+	if strings.ContainsAny(prefix, `/\`) || prefix == "." || prefix == ".." {
+		return "", i18n.Errorf("remote prefix %s must be the ID of a single remote folder", prefix)
+	}
+	if folderID != "" && folderID != prefix {
+		return "", i18n.Errorf("--subscribe already names the remote folder, drop --folder-id")
+	}
+	if initialMerge != "" {
+		return "", i18n.Errorf("--initial-merge cannot be used with --subscribe, subscribed folders always keep the published copy")
+	}
+	subscribed := config.SyncFolder{Subscribe: true, Mode: mode, LocalChanges: localChanges}
+	if err := subscribed.ValidateSubscribe(); err != nil {
+		return "", i18n.Errorf("invalid subscription: %w", err)
+	}
+	return prefix, nil
+}
+
+// folderTarget returns the storage target named by a --target flag, the default one
+// when the flag is empty
+func folderTarget(cfg *config.Config, name string) (*config.StorageTarget, error) {
+	if name == "" {
+		return cfg.DefaultTarget(), nil
+	}
+	target := cfg.Target(name)
+	if target == nil {
+		return nil, i18n.Errorf("storage target %s not found (configured: %s)", name, strings.Join(cfg.TargetNames(), ", "))
+	}
+	return target, nil
+}
+
+// targetStore returns the storage of a target when store routes folders to several
+// targets, so a folder not saved yet reaches its own target
+func targetStore(store storage.Storage, name string) storage.Storage {
+	if router, ok := store.(*storage.Router); ok && name != "" {
+		if target := router.Target(name); target != nil {
+			return target
+		}
+	}
+	return store
+}
+
+// validateStorageClass checks a storage class flag against the type of the folder's target,
+// returning it in the provider's upper-case spelling; empty means the bucket default
+func validateStorageClass(target *config.StorageTarget, class string) (string, error) {
+	class = strings.ToUpper(strings.TrimSpace(class))
+	if err := storage.ValidateStorageClass(storage.StorageProvider(target.Type), class); err != nil {
+		return "", err
+	}
+	return class, nil
+}
+
+// warnArchiveClass explains the retrieval cost of classes that cannot be read directly
+func warnArchiveClass(class string) {
+	if storage.RequiresRestore(class) {
+		i18n.Printf("Warning: %s objects must be restored on the provider before they can be downloaded or restored.\n", class)
+	}
+}
+
+// nestedOverlaps returns the configured folders a new folder on path, or on its file of
+// path, would be inside or contain. The new folder has an empty ID in them. They are refused
+// unless the nested_folders policy makes the outer folder exclude the inner one; a folder on
+// the same directory as another is always refused.
+func nestedOverlaps(cfg *config.Config, path, file string) ([]config.FolderOverlap, error) {
+	folders := append(append([]config.SyncFolder(nil), cfg.SyncFolders...), config.SyncFolder{Path: path, File: file, Enabled: true})
+
+	var nested []config.FolderOverlap
+	for _, overlap := range config.FindOverlaps(folders) {
+		switch {
+		case overlap.Outer != "" && overlap.Inner != "":
+			continue
+		case overlap.Same():
+			return nil, configError(i18n.Errorf("%s is already synced by folder %s", overlap.InnerPath, overlap.Outer))
+		case cfg.NestedFolders != config.NestingExclude && overlap.Inner == "":
+			return nil, configError(i18n.Errorf("%s is inside folder %s (%s), which already syncs its files; run 'sync-manager config set nested_folders exclude' to sync it as a separate folder that %s leaves out",
+				overlap.InnerPath, overlap.Outer, overlap.OuterPath, overlap.Outer))
+		case cfg.NestedFolders != config.NestingExclude:
+			return nil, configError(i18n.Errorf("folder %s (%s) is inside %s and already syncs part of it; run 'sync-manager config set nested_folders exclude' to have the new folder leave it out",
+				overlap.Inner, overlap.InnerPath, overlap.OuterPath))
+		}
+		nested = append(nested, overlap)
+	}
+	return nested, nil
+}
+
+// validateFolderMode checks a folder mode flag; empty means the default mirror mode
+func validateFolderMode(mode string) error {
+	switch mode {
+	case "", config.FolderModeMirror, config.FolderModeBackup:
+		return nil
+	default:
+		return i18n.Errorf("invalid folder mode %q: must be %s or %s", mode, config.FolderModeMirror, config.FolderModeBackup)
+	}
+}
+
+// FolderStatusLabel describes whether a folder is enabled, paused or disabled
+func FolderStatusLabel(folder config.SyncFolder) string {
+	switch {
+	case !folder.Enabled:
+		return i18n.T("Disabled")
+	case folder.Paused:
+		return i18n.T("Paused")
+	default:
+		return i18n.T("Enabled")
+	}
+}
+
+// FolderIntervalLabel describes the sync interval of a folder, or the schedule replacing it
+func FolderIntervalLabel(folder config.SyncFolder, global time.Duration) string {
+	if folder.Schedule != "" {
+		return i18n.Sprintf("cron %s", folder.Schedule)
+	}
+	if folder.Interval > 0 {
+		return folder.Interval.String()
+	}
+	return i18n.Sprintf("%s (global)", global)
+}
+
+// subscribeLabel describes what a subscribed folder does with files changed locally
+func subscribeLabel(folder config.SyncFolder) string {
+	if folder.LocalChanges == config.LocalChangesFlag {
+		return i18n.T("flagged")
+	}
+	return i18n.T("reverted")
+}
+
 // generateFolderID generates a unique folder ID
 // This would be a more robust implementation in a real scenario
 func generateFolderID() string {
@@ -2,15 +2,34 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 )
 
+// newTestFolderService cria um serviço de pastas com um banco de dados temporário
+func newTestFolderService(t *testing.T, cfg *config.Config) *services.FolderService {
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbManager.Close() })
+	assert.NoError(t, dbManager.InitSchema())
+
+	folderRepo := repositories.NewFolderRepository(dbManager.GetDB())
+	return services.NewFolderService(folderRepo, cfg)
+}
+
 func TestCreateFolderCommands(t *testing.T) {
 	// Preparar uma configuração de teste
 	cfg := config.DefaultConfig()
@@ -23,10 +42,10 @@ func TestCreateFolderCommands(t *testing.T) {
 	}
 
 	// Criar os comandos
-	cmds := CreateFolderCommands(cfg, saveFn)
+	cmds := CreateFolderCommands(cfg, saveFn, nil, newTestFolderService(t, cfg), nil, 1)
 
-	// Verificar se criou pelo menos os 5 comandos esperados
-	assert.Equal(t, 5, len(cmds))
+	// Verificar se criou pelo menos os 8 comandos esperados
+	assert.Equal(t, 8, len(cmds))
 
 	// Verificar os nomes dos comandos
 	cmdNames := make(map[string]bool)
@@ -40,6 +59,9 @@ func TestCreateFolderCommands(t *testing.T) {
 	assert.True(t, cmdNames["remove-folder [folder-id]"])
 	assert.True(t, cmdNames["enable-folder [folder-id]"])
 	assert.True(t, cmdNames["disable-folder [folder-id]"])
+	assert.True(t, cmdNames["configure-folder [folder-id]"])
+	assert.True(t, cmdNames["pause-folder [folder-id]"])
+	assert.True(t, cmdNames["resume-folder [folder-id]"])
 }
 
 func TestFolderListCommand(t *testing.T) {
@@ -65,7 +87,7 @@ func TestFolderListCommand(t *testing.T) {
 	saveFn := func() error { return nil }
 
 	// Criar os comandos
-	cmds := CreateFolderCommands(cfg, saveFn)
+	cmds := CreateFolderCommands(cfg, saveFn, nil, newTestFolderService(t, cfg), nil, 1)
 
 	// Encontrar o comando list-folders
 	var listCmd *cobra.Command
@@ -113,7 +135,7 @@ func TestFolderAddCommand(t *testing.T) {
 	}
 
 	// Criar os comandos
-	cmds := CreateFolderCommands(cfg, saveFn)
+	cmds := CreateFolderCommands(cfg, saveFn, nil, newTestFolderService(t, cfg), nil, 1)
 
 	// Encontrar o comando add-folder
 	var addCmd *cobra.Command
@@ -164,7 +186,7 @@ func TestFolderRemoveCommand(t *testing.T) {
 	}
 
 	// Criar os comandos
-	cmds := CreateFolderCommands(cfg, saveFn)
+	cmds := CreateFolderCommands(cfg, saveFn, nil, newTestFolderService(t, cfg), nil, 1)
 
 	// Encontrar o comando remove-folder
 	var removeCmd *cobra.Command
@@ -208,7 +230,7 @@ func TestFolderEnableCommand(t *testing.T) {
 	}
 
 	// Criar os comandos
-	cmds := CreateFolderCommands(cfg, saveFn)
+	cmds := CreateFolderCommands(cfg, saveFn, nil, newTestFolderService(t, cfg), nil, 1)
 
 	// Encontrar o comando enable-folder
 	var enableCmd *cobra.Command
@@ -252,7 +274,7 @@ func TestFolderDisableCommand(t *testing.T) {
 	}
 
 	// Criar os comandos
-	cmds := CreateFolderCommands(cfg, saveFn)
+	cmds := CreateFolderCommands(cfg, saveFn, nil, newTestFolderService(t, cfg), nil, 1)
 
 	// Encontrar o comando disable-folder
 	var disableCmd *cobra.Command
@@ -275,3 +297,505 @@ func TestFolderDisableCommand(t *testing.T) {
 	// Verificar se a função de salvamento foi chamada
 	assert.Equal(t, 1, saveCount)
 }
+
+// Testa os comandos para pausar e retomar uma pasta
+func TestFolderPauseResumeCommands(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{
+		{
+			ID:       "folder-to-pause",
+			Path:     "/test/path-to-pause",
+			Enabled:  true,
+			Interval: 10 * time.Minute,
+		},
+	}
+
+	saveCount := 0
+	saveFn := func() error {
+		saveCount++
+		return nil
+	}
+
+	cmds := CreateFolderCommands(cfg, saveFn, nil, newTestFolderService(t, cfg), nil, 1)
+
+	commandsByUse := make(map[string]*cobra.Command)
+	for _, c := range cmds {
+		commandsByUse[c.Use] = c
+	}
+
+	pauseCmd := commandsByUse["pause-folder [folder-id]"]
+	assert.NoError(t, pauseCmd.RunE(pauseCmd, []string{"folder-to-pause"}))
+	assert.True(t, cfg.SyncFolders[0].Paused)
+
+	// Pausar não desabilita a pasta
+	assert.True(t, cfg.SyncFolders[0].Enabled)
+	assert.Equal(t, "Paused", FolderStatusLabel(cfg.SyncFolders[0]))
+	assert.Equal(t, "10m0s", FolderIntervalLabel(cfg.SyncFolders[0], cfg.SyncInterval))
+
+	resumeCmd := commandsByUse["resume-folder [folder-id]"]
+	assert.NoError(t, resumeCmd.RunE(resumeCmd, []string{"folder-to-pause"}))
+	assert.False(t, cfg.SyncFolders[0].Paused)
+	assert.Equal(t, 2, saveCount)
+
+	assert.Error(t, pauseCmd.RunE(pauseCmd, []string{"missing"}))
+}
+
+// Testa o aviso de quando uma mudança de pasta chega ao agente
+func TestFolderChangeNotice(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: "/test/docs", Enabled: true}}
+	cmds := CreateFolderCommands(cfg, func() error { return nil }, nil, newTestFolderService(t, cfg), nil, 1)
+
+	var disableCmd *cobra.Command
+	for _, c := range cmds {
+		if c.Use == "disable-folder [folder-id]" {
+			disableCmd = c
+		}
+	}
+
+	// Sem o agente rodando, a mudança fica para quando ele iniciar
+	output := captureStdout(func() {
+		assert.NoError(t, disableCmd.RunE(disableCmd, []string{"docs"}))
+	})
+	assert.Contains(t, output, "The agent is not running; the change takes effect when it starts")
+}
+
+func TestConfigureFolderRoots(t *testing.T) {
+	docs := t.TempDir()
+	desktop := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: docs, Enabled: true}}
+
+	folderService := newTestFolderService(t, cfg)
+	newConfigureCmd := func() *cobra.Command {
+		for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, folderService, nil, 1) {
+			if c.Use == "configure-folder [folder-id]" {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// Adicionar uma raiz extra sob o prefixo Desktop
+	configureCmd := newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("add-root", "/Desktop/="+desktop))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Equal(t, []config.FolderRoot{{Path: desktop, Prefix: "Desktop"}}, cfg.SyncFolders[0].Roots)
+	assert.Equal(t, docs+"\nDesktop/ ← "+desktop, FolderPathLabel(cfg.SyncFolders[0]))
+
+	// Prefixos repetidos são rejeitados sem alterar a configuração
+	assert.Error(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Len(t, cfg.SyncFolders[0].Roots, 1)
+
+	// Remover a raiz pelo prefixo
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("remove-root", "Desktop"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Empty(t, cfg.SyncFolders[0].Roots)
+}
+
+func TestConfigureFolderOrphanCleanup(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: "/test/docs", Enabled: true}}
+
+	var configureCmd *cobra.Command
+	for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, newTestFolderService(t, cfg), nil, 1) {
+		if c.Use == "configure-folder [folder-id]" {
+			configureCmd = c
+		}
+	}
+
+	assert.NoError(t, configureCmd.Flags().Set("delete-orphans", "true"))
+	assert.NoError(t, configureCmd.Flags().Set("trash-orphans", "true"))
+	assert.NoError(t, configureCmd.Flags().Set("max-delete", "20"))
+	assert.NoError(t, configureCmd.Flags().Set("max-delete-percent", "50"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+
+	assert.Equal(t, config.MirrorConfig{DeleteOrphans: true, Trash: true, MaxDelete: 20, MaxDeletePercent: 50}, cfg.SyncFolders[0].Mirror)
+}
+
+func TestConfigureFolderConflictPolicy(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: "/test/docs", Enabled: true}}
+
+	var configureCmd *cobra.Command
+	for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, newTestFolderService(t, cfg), nil, 1) {
+		if c.Use == "configure-folder [folder-id]" {
+			configureCmd = c
+		}
+	}
+
+	assert.NoError(t, configureCmd.Flags().Set("conflict-policy", config.ConflictPreferNewest))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Equal(t, config.ConflictPreferNewest, cfg.SyncFolders[0].ConflictPolicy)
+
+	// Unknown policies are rejected and leave the folder unchanged
+	assert.NoError(t, configureCmd.Flags().Set("conflict-policy", "prefer-oldest"))
+	assert.Error(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Equal(t, config.ConflictPreferNewest, cfg.SyncFolders[0].ConflictPolicy)
+}
+
+func TestConfigureFolderInUseTimeout(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: "/test/docs", Enabled: true}}
+
+	var configureCmd *cobra.Command
+	for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, newTestFolderService(t, cfg), nil, 1) {
+		if c.Use == "configure-folder [folder-id]" {
+			configureCmd = c
+		}
+	}
+
+	assert.NoError(t, configureCmd.Flags().Set("in-use-timeout", "30m"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Equal(t, 30*time.Minute, cfg.SyncFolders[0].InUseTimeout)
+}
+
+func TestFolderStorageTarget(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Targets = append(cfg.Targets, config.StorageTarget{Name: "offsite", Type: "gcs"})
+	dir := t.TempDir()
+
+	var addCmd, configureCmd *cobra.Command
+	for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, newTestFolderService(t, cfg), nil, 1) {
+		switch c.Use {
+		case "add-folder [path]":
+			addCmd = c
+		case "configure-folder [folder-id]":
+			configureCmd = c
+		}
+	}
+
+	// Destinos desconhecidos são recusados antes de adicionar a pasta
+	assert.NoError(t, addCmd.Flags().Set("target", "nas"))
+	assert.ErrorContains(t, addCmd.RunE(addCmd, []string{dir}), "storage target nas not found")
+	assert.Empty(t, cfg.SyncFolders)
+
+	// A classe de armazenamento é validada pelo tipo do destino da pasta
+	assert.NoError(t, addCmd.Flags().Set("target", "offsite"))
+	assert.NoError(t, addCmd.Flags().Set("storage-class", "nearline"))
+	assert.NoError(t, addCmd.RunE(addCmd, []string{dir}))
+	if assert.Len(t, cfg.SyncFolders, 1) {
+		assert.Equal(t, "offsite", cfg.SyncFolders[0].Target)
+		assert.Equal(t, "NEARLINE", cfg.SyncFolders[0].StorageClass)
+	}
+
+	// Um destino vazio volta ao destino padrão
+	folderID := cfg.SyncFolders[0].ID
+	assert.NoError(t, configureCmd.Flags().Set("target", ""))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{folderID}))
+	assert.Empty(t, cfg.SyncFolders[0].Target)
+	assert.Equal(t, cfg.DefaultTarget(), cfg.FolderTarget(&cfg.SyncFolders[0]))
+}
+
+func TestFolderAddSingleFile(t *testing.T) {
+	cfg := config.DefaultConfig()
+	dir := t.TempDir()
+	vault := filepath.Join(dir, "vault.kdbx")
+	assert.NoError(t, os.WriteFile(vault, []byte("segredo"), 0600))
+
+	var addCmd *cobra.Command
+	for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, newTestFolderService(t, cfg), nil, 1) {
+		if c.Use == "add-folder [path]" {
+			addCmd = c
+		}
+	}
+
+	// Um arquivo vira uma pasta no seu diretório que sincroniza somente ele
+	assert.NoError(t, addCmd.RunE(addCmd, []string{vault}))
+	assert.Len(t, cfg.SyncFolders, 1)
+	assert.Equal(t, dir, cfg.SyncFolders[0].Path)
+	assert.Equal(t, "vault.kdbx", cfg.SyncFolders[0].File)
+	assert.Equal(t, vault, FolderPathLabel(cfg.SyncFolders[0]))
+
+	// Pastas de arquivo único não podem ser de backup
+	assert.NoError(t, addCmd.Flags().Set("mode", config.FolderModeBackup))
+	assert.Error(t, addCmd.RunE(addCmd, []string{vault}))
+	assert.Len(t, cfg.SyncFolders, 1)
+}
+
+func TestFolderAddNested(t *testing.T) {
+	cfg := config.DefaultConfig()
+	docs := t.TempDir()
+	projects := filepath.Join(docs, "Projects")
+	assert.NoError(t, os.Mkdir(projects, 0755))
+
+	var addCmd *cobra.Command
+	for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, newTestFolderService(t, cfg), nil, 1) {
+		if c.Use == "add-folder [path]" {
+			addCmd = c
+		}
+	}
+	assert.NoError(t, addCmd.RunE(addCmd, []string{docs}))
+
+	// Por padrão, uma pasta dentro de outra é recusada, e a mesma pasta nunca é aceita duas vezes
+	err := addCmd.RunE(addCmd, []string{projects})
+	assert.Error(t, err)
+	assert.Equal(t, ExitConfig, ExitCode(err))
+	assert.Error(t, addCmd.RunE(addCmd, []string{docs}))
+	assert.Len(t, cfg.SyncFolders, 1)
+
+	// Com a política exclude, a pasta externa deixa a interna de fora
+	cfg.NestedFolders = config.NestingExclude
+	assert.NoError(t, addCmd.RunE(addCmd, []string{projects}))
+	assert.Len(t, cfg.SyncFolders, 2)
+	assert.Error(t, addCmd.RunE(addCmd, []string{docs}))
+	assert.Len(t, cfg.SyncFolders, 2)
+}
+
+func TestFolderAddInitialMerge(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "music", Path: t.TempDir(), Enabled: true}}
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	_, err := store.UploadFile(context.Background(), "docs/notes.txt", strings.NewReader("do desktop"), map[string]string{})
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("do laptop"), 0644))
+
+	var addCmd *cobra.Command
+	for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, newTestFolderService(t, cfg), func() (storage.Storage, error) { return store, nil }, 1) {
+		if c.Use == "add-folder [path]" {
+			addCmd = c
+		}
+	}
+
+	// A estratégia exige o ID da pasta remota e uma pasta bidirecional ainda não configurada
+	assert.NoError(t, addCmd.Flags().Set("initial-merge", config.ConflictPreferRemote))
+	assert.ErrorContains(t, addCmd.RunE(addCmd, []string{dir}), "--folder-id")
+	assert.NoError(t, addCmd.Flags().Set("folder-id", "music"))
+	assert.ErrorContains(t, addCmd.RunE(addCmd, []string{dir}), "already configured")
+	assert.NoError(t, addCmd.Flags().Set("folder-id", "docs"))
+	assert.ErrorContains(t, addCmd.RunE(addCmd, []string{dir}), "two-way")
+	assert.NoError(t, addCmd.Flags().Set("two-way", "true"))
+
+	// O dry-run só mostra o plano
+	assert.NoError(t, addCmd.Flags().Set("dry-run", "true"))
+	assert.NoError(t, addCmd.RunE(addCmd, []string{dir}))
+	assert.Len(t, cfg.SyncFolders, 1)
+
+	assert.NoError(t, addCmd.Flags().Set("dry-run", "false"))
+	assert.NoError(t, addCmd.RunE(addCmd, []string{dir}))
+	if assert.Len(t, cfg.SyncFolders, 2) {
+		assert.Equal(t, "docs", cfg.SyncFolders[1].ID)
+		assert.Equal(t, config.ConflictPreferRemote, cfg.SyncFolders[1].InitialMerge)
+		assert.True(t, cfg.SyncFolders[1].TwoWaySync)
+	}
+}
+
+func TestFolderAddSubscribe(t *testing.T) {
+	cfg := config.DefaultConfig()
+	dir := t.TempDir()
+
+	var addCmd *cobra.Command
+	for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, newTestFolderService(t, cfg), nil, 1) {
+		if c.Use == "add-folder [path]" {
+			addCmd = c
+		}
+	}
+
+	// A política de alterações locais só vale para pastas assinadas
+	assert.NoError(t, addCmd.Flags().Set("local-changes", config.LocalChangesFlag))
+	assert.ErrorContains(t, addCmd.RunE(addCmd, []string{dir}), "--subscribe")
+
+	// O prefixo é o ID de uma única pasta remota, que não aceita outro ID nem backup
+	assert.NoError(t, addCmd.Flags().Set("subscribe", "brand/assets"))
+	assert.ErrorContains(t, addCmd.RunE(addCmd, []string{dir}), "single remote folder")
+	assert.NoError(t, addCmd.Flags().Set("subscribe", "/assets/"))
+	assert.NoError(t, addCmd.Flags().Set("folder-id", "docs"))
+	assert.ErrorContains(t, addCmd.RunE(addCmd, []string{dir}), "--folder-id")
+	assert.NoError(t, addCmd.Flags().Set("folder-id", ""))
+	assert.NoError(t, addCmd.Flags().Set("mode", config.FolderModeBackup))
+	assert.ErrorContains(t, addCmd.RunE(addCmd, []string{dir}), "backup")
+	assert.NoError(t, addCmd.Flags().Set("mode", config.FolderModeMirror))
+	assert.Empty(t, cfg.SyncFolders)
+
+	assert.NoError(t, addCmd.RunE(addCmd, []string{dir}))
+	if assert.Len(t, cfg.SyncFolders, 1) {
+		assert.Equal(t, "assets", cfg.SyncFolders[0].ID)
+		assert.True(t, cfg.SyncFolders[0].Subscribe)
+		assert.True(t, cfg.SyncFolders[0].TwoWaySync)
+		assert.Equal(t, config.LocalChangesFlag, cfg.SyncFolders[0].LocalChanges)
+	}
+}
+
+func TestFolderConfigureRemotePrefix(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	for _, key := range []string{"docs/a.txt", "docs/sub/b.txt", "photos/c.jpg"} {
+		_, err := store.UploadFile(ctx, key, strings.NewReader(key), map[string]string{})
+		assert.NoError(t, err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true}, {ID: "photos", Path: t.TempDir(), Enabled: true}}
+	folderService := newTestFolderService(t, cfg)
+	newConfigureCmd := func() *cobra.Command {
+		for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, folderService, func() (storage.Storage, error) { return store, nil }, 1) {
+			if c.Use == "configure-folder [folder-id]" {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// O prefixo de outra pasta é recusado
+	configureCmd := newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("remote-prefix", "photos"))
+	assert.ErrorContains(t, configureCmd.RunE(configureCmd, []string{"docs"}), "both use the remote prefix")
+
+	// Os arquivos já enviados passam para o novo prefixo
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("remote-prefix", "/team-docs/"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Equal(t, "team-docs", cfg.SyncFolders[0].RemotePrefix)
+	files, err := store.ListFiles(ctx, "team-docs/")
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	files, err = store.ListFiles(ctx, "docs/")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	// Voltar ao ID da pasta traz os arquivos de volta
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("remote-prefix", "docs"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Empty(t, cfg.SyncFolders[0].RemotePrefix)
+	found, err := store.FileExists(ctx, "docs/sub/b.txt")
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestFolderConfigureSchedule(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true}}
+	folderService := newTestFolderService(t, cfg)
+	newConfigureCmd := func() *cobra.Command {
+		for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, folderService, nil, 1) {
+			if c.Use == "configure-folder [folder-id]" {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// Expressões inválidas são recusadas
+	configureCmd := newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("schedule", "0 25 * * *"))
+	assert.ErrorContains(t, configureCmd.RunE(configureCmd, []string{"docs"}), "invalid schedule")
+	assert.Empty(t, cfg.SyncFolders[0].Schedule)
+
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("schedule", " 0 2 * * * "))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Equal(t, "0 2 * * *", cfg.SyncFolders[0].Schedule)
+	assert.Equal(t, "cron 0 2 * * *", FolderIntervalLabel(cfg.SyncFolders[0], cfg.SyncInterval))
+
+	// Uma expressão vazia volta ao intervalo
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("schedule", ""))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Empty(t, cfg.SyncFolders[0].Schedule)
+}
+
+func TestFolderConfigureChecksum(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true}}
+	folderService := newTestFolderService(t, cfg)
+	newConfigureCmd := func() *cobra.Command {
+		for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, folderService, nil, 1) {
+			if c.Use == "configure-folder [folder-id]" {
+				return c
+			}
+		}
+		return nil
+	}
+
+	configureCmd := newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("checksum", "xxh128"))
+	assert.ErrorContains(t, configureCmd.RunE(configureCmd, []string{"docs"}), "invalid checksum")
+	assert.Empty(t, cfg.SyncFolders[0].Checksum)
+
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("checksum", "BLAKE3"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Equal(t, "blake3", cfg.SyncFolders[0].Checksum)
+
+	// Pastas de backup continuam com SHA-256
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("mode", config.FolderModeBackup))
+	assert.ErrorContains(t, configureCmd.RunE(configureCmd, []string{"docs"}), "backup folders")
+	assert.Empty(t, cfg.SyncFolders[0].Mode)
+
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("mode", config.FolderModeBackup))
+	assert.NoError(t, configureCmd.Flags().Set("checksum", "sha256"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Equal(t, config.FolderModeBackup, cfg.SyncFolders[0].Mode)
+}
+
+func TestFolderConfigureAgeFilter(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: t.TempDir(), Enabled: true}}
+	folderService := newTestFolderService(t, cfg)
+	newConfigureCmd := func() *cobra.Command {
+		for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, folderService, nil, 1) {
+			if c.Use == "configure-folder [folder-id]" {
+				return c
+			}
+		}
+		return nil
+	}
+
+	configureCmd := newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("min-age", "30s"))
+	assert.NoError(t, configureCmd.Flags().Set("modified-since", "2024-06-01"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"docs"}))
+	assert.Equal(t, config.AgeFilter{MinAge: 30 * time.Second, ModifiedSince: "2024-06-01"}, cfg.SyncFolders[0].AgeFilter)
+
+	// Os filtros são validados juntos com os já definidos, e nada muda quando falham
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("max-age", "10s"))
+	assert.ErrorContains(t, configureCmd.RunE(configureCmd, []string{"docs"}), "invalid age filter")
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("modified-since", "ontem"))
+	assert.ErrorContains(t, configureCmd.RunE(configureCmd, []string{"docs"}), "invalid age filter")
+	assert.Equal(t, config.AgeFilter{MinAge: 30 * time.Second, ModifiedSince: "2024-06-01"}, cfg.SyncFolders[0].AgeFilter)
+}
+
+func TestFolderConfigureHooks(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "db", Path: t.TempDir(), Enabled: true}}
+	folderService := newTestFolderService(t, cfg)
+	newConfigureCmd := func() *cobra.Command {
+		for _, c := range CreateFolderCommands(cfg, func() error { return nil }, nil, folderService, nil, 1) {
+			if c.Use == "configure-folder [folder-id]" {
+				return c
+			}
+		}
+		return nil
+	}
+
+	configureCmd := newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("pre-sync", "pg_dump app > app.sql"))
+	assert.NoError(t, configureCmd.Flags().Set("pre-sync-timeout", "30m"))
+	assert.NoError(t, configureCmd.Flags().Set("post-sync", "notify-send synced"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"db"}))
+	assert.Equal(t, config.FolderHooks{
+		PreSync:  config.Hook{Command: "pg_dump app > app.sql", Timeout: 30 * time.Minute},
+		PostSync: config.Hook{Command: "notify-send synced"},
+	}, cfg.SyncFolders[0].Hooks)
+
+	// Políticas de falha desconhecidas são recusadas sem mudar nada
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("post-sync-on-failure", "retry"))
+	assert.ErrorContains(t, configureCmd.RunE(configureCmd, []string{"db"}), "invalid hooks")
+	assert.Empty(t, cfg.SyncFolders[0].Hooks.PostSync.OnFailure)
+
+	// Um comando vazio remove o hook
+	configureCmd = newConfigureCmd()
+	assert.NoError(t, configureCmd.Flags().Set("pre-sync", ""))
+	assert.NoError(t, configureCmd.Flags().Set("post-sync-on-failure", "abort"))
+	assert.NoError(t, configureCmd.RunE(configureCmd, []string{"db"}))
+	assert.Empty(t, cfg.SyncFolders[0].Hooks.PreSync.Command)
+	assert.Equal(t, config.HookAbort, cfg.SyncFolders[0].Hooks.PostSync.OnFailure)
+}
@@ -1,11 +1,11 @@
 package commands
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
 	"github.com/spf13/cobra"
 )
 
@@ -17,32 +17,33 @@ func CreateInitCommand(cfg *config.Config, saveFn func() error) *cobra.Command {
 		Short: "Initialize sync-manager configuration",
 		Long:  `Initialize the basic configuration for sync-manager.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Println("Initializing sync-manager...")
+			i18n.Println("Initializing sync-manager...")
 
 			// Check if configuration looks initialized already
-			if cfg.DeviceID != "" && cfg.StorageProvider != "" && cfg.S3Config.Bucket != "" {
-				fmt.Println("Configuration appears to be already initialized.")
-				fmt.Println("Use 'sync-manager config' commands to modify settings or 'sync-manager wizard' for a guided setup.")
-				fmt.Println("To reset the configuration, use 'sync-manager config reset'.")
+			target := cfg.DefaultTarget()
+			if cfg.DeviceID != "" && target.Type != "" && target.S3.Bucket != "" {
+				i18n.Println("Configuration appears to be already initialized.")
+				i18n.Println("Use 'sync-manager config' commands to modify settings or 'sync-manager wizard' for a guided setup.")
+				i18n.Println("To reset the configuration, use 'sync-manager config reset'.")
 				return nil
 			}
 
 			// Basic configuration
-			fmt.Println("Setting up basic configuration...")
+			i18n.Println("Setting up basic configuration...")
 
 			// Keep device ID and name if already set
 			if cfg.DeviceID == "" || cfg.DeviceName == "" {
-				fmt.Println("Device not yet registered, generating device ID...")
+				i18n.Println("Device not yet registered, generating device ID...")
 				// DeviceID is already generated in main.go, we just need to inform the user
 			}
 
 			// Set basic defaults
-			if cfg.StorageProvider == "" {
-				cfg.StorageProvider = "s3"
+			if target.Type == "" {
+				target.Type = "s3"
 			}
 
-			if cfg.S3Config.Region == "" {
-				cfg.S3Config.Region = "us-east-1"
+			if target.S3.Region == "" {
+				target.S3.Region = "us-east-1"
 			}
 
 			if cfg.MaxConcurrency == 0 {
@@ -50,24 +51,22 @@ func CreateInitCommand(cfg *config.Config, saveFn func() error) *cobra.Command {
 			}
 
 			// Ask for S3 bucket if not set
-			if cfg.S3Config.Bucket == "" {
-				fmt.Print("Enter S3 bucket name (or press Enter to configure later): ")
-				var bucket string
-				fmt.Scanln(&bucket)
+			if target.S3.Bucket == "" {
+				i18n.Print("Enter S3 bucket name (or press Enter to configure later): ")
+				bucket := answer(cmd)
 
 				if bucket != "" {
-					cfg.S3Config.Bucket = bucket
+					target.S3.Bucket = bucket
 				} else {
-					fmt.Println("No bucket specified. You can configure it later with 'sync-manager config set storage.s3.bucket <name>'.")
+					i18n.Println("No bucket specified. You can configure it later with 'sync-manager config set storage.s3.bucket <name>'.")
 				}
 			}
 
 			// Create default sync directory if desired
-			fmt.Print("Create a default sync folder in your home directory? [Y/n]: ")
-			var createDefault string
-			fmt.Scanln(&createDefault)
+			i18n.Print("Create a default sync folder in your home directory? [Y/n]: ")
+			createDefault := answer(cmd)
 
-			if createDefault != "n" && createDefault != "N" {
+			if !i18n.No(createDefault) {
 				homeDir, err := os.UserHomeDir()
 				if err == nil {
 					syncDir := filepath.Join(homeDir, "Sync")
@@ -75,9 +74,9 @@ func CreateInitCommand(cfg *config.Config, saveFn func() error) *cobra.Command {
 					// Create directory if it doesn't exist
 					if _, err := os.Stat(syncDir); os.IsNotExist(err) {
 						if err := os.MkdirAll(syncDir, 0755); err != nil {
-							fmt.Printf("Failed to create sync directory: %v\n", err)
+							i18n.Printf("Failed to create sync directory: %v\n", err)
 						} else {
-							fmt.Printf("Created sync directory at: %s\n", syncDir)
+							i18n.Printf("Created sync directory at: %s\n", syncDir)
 
 							// Add to configuration
 							folderID := "default"
@@ -92,7 +91,7 @@ func CreateInitCommand(cfg *config.Config, saveFn func() error) *cobra.Command {
 							cfg.SyncFolders = append(cfg.SyncFolders, syncFolder)
 						}
 					} else {
-						fmt.Printf("Sync directory already exists at: %s\n", syncDir)
+						i18n.Printf("Sync directory already exists at: %s\n", syncDir)
 
 						// Add to configuration if not already there
 						found := false
@@ -121,12 +120,12 @@ func CreateInitCommand(cfg *config.Config, saveFn func() error) *cobra.Command {
 
 			// Save configuration
 			if err := saveFn(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
+				return i18n.Errorf("failed to save configuration: %w", err)
 			}
 
-			fmt.Println("\nInitialization complete!")
-			fmt.Println("For a more detailed setup, run 'sync-manager wizard'.")
-			fmt.Println("To start the sync agent, run 'sync-manager start'.")
+			i18n.Println("\nInitialization complete!")
+			i18n.Println("For a more detailed setup, run 'sync-manager wizard'.")
+			i18n.Println("To start the sync agent, run 'sync-manager start'.")
 
 			return nil
 		},
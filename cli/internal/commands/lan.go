@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/identity"
+	"github.com/spf13/cobra"
+)
+
+// CreateLANCommands returns the commands pairing this device with the user's other devices
+// for LAN sync. identityPath is where the device's certificate and key are kept.
+func CreateLANCommands(cfg *config.Config, saveFn func() error, identityPath string) []*cobra.Command {
+	lanCmd := &cobra.Command{
+		Use:   "lan",
+		Short: "Pair devices for LAN sync",
+		Long: `Devices on the same local network fetch files from each other over TLS. Each
+device has its own certificate, and only talks to the devices whose certificate
+fingerprints it trusts. Run 'lan id' on each device and 'lan trust' with the
+result on the others, then 'config set lan.enabled true'.`,
+	}
+
+	idCmd := &cobra.Command{
+		Use:   "id",
+		Short: "Show this device's ID and certificate fingerprint",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cfg.DeviceID == "" {
+				return i18n.Errorf("this device has no ID yet, run 'sync-manager init' first")
+			}
+			cert, err := identity.Load(identityPath, cfg.DeviceID)
+			if err != nil {
+				return err
+			}
+
+			fingerprint := identity.Fingerprint(cert.Certificate[0])
+			i18n.Fprintf(cmd.OutOrStdout(), "Device ID: %s\nFingerprint: %s\n\n", cfg.DeviceID, fingerprint)
+			i18n.Fprintf(cmd.OutOrStdout(), "On your other devices run:\n  sync-manager lan trust %s %s\n", cfg.DeviceID, fingerprint)
+			return nil
+		},
+	}
+
+	trustCmd := &cobra.Command{
+		Use:   "trust <device-id> <fingerprint>",
+		Short: "Trust another device for LAN sync",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deviceID := args[0]
+			if deviceID == cfg.DeviceID {
+				return i18n.Errorf("%s is this device", deviceID)
+			}
+			fingerprint, err := identity.NormalizeFingerprint(args[1])
+			if err != nil {
+				return err
+			}
+
+			if cfg.LAN.Peers == nil {
+				cfg.LAN.Peers = make(map[string]string)
+			}
+			cfg.LAN.Peers[deviceID] = fingerprint
+			if err := saveFn(); err != nil {
+				return i18n.Errorf("failed to save configuration: %w", err)
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "Device %s is trusted for LAN sync\n", deviceID)
+			return nil
+		},
+	}
+
+	untrustCmd := &cobra.Command{
+		Use:   "untrust <device-id>",
+		Short: "Stop trusting a device for LAN sync",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deviceID := args[0]
+			if _, ok := cfg.LAN.Peers[deviceID]; !ok {
+				return i18n.Errorf("device %s is not trusted", deviceID)
+			}
+
+			delete(cfg.LAN.Peers, deviceID)
+			if err := saveFn(); err != nil {
+				return i18n.Errorf("failed to save configuration: %w", err)
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "Device %s is no longer trusted for LAN sync\n", deviceID)
+			return nil
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the devices trusted for LAN sync",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(cfg.LAN.Peers) == 0 {
+				i18n.Fprintln(cmd.OutOrStdout(), "No devices are trusted for LAN sync")
+				return nil
+			}
+
+			deviceIDs := make([]string, 0, len(cfg.LAN.Peers))
+			for deviceID := range cfg.LAN.Peers {
+				deviceIDs = append(deviceIDs, deviceID)
+			}
+			sort.Strings(deviceIDs)
+			for _, deviceID := range deviceIDs {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s  %s\n", deviceID, cfg.LAN.Peers[deviceID])
+			}
+			return nil
+		},
+	}
+
+	lanCmd.AddCommand(idCmd, trustCmd, untrustCmd, listCmd)
+	return []*cobra.Command{lanCmd}
+}
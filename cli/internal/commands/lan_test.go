@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLANCommands(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	identityPath := filepath.Join(t.TempDir(), "device-identity.pem")
+	saveCount := 0
+	saveFn := func() error {
+		saveCount++
+		return nil
+	}
+
+	run := func(name string, args ...string) (string, error) {
+		for _, c := range CreateLANCommands(cfg, saveFn, identityPath)[0].Commands() {
+			if c.Name() != name {
+				continue
+			}
+			var out bytes.Buffer
+			c.SetOut(&out)
+			err := c.RunE(c, args)
+			return out.String(), err
+		}
+		t.Fatalf("unknown command %s", name)
+		return "", nil
+	}
+
+	// The identity is created on first use and shown with the command to run elsewhere
+	out, err := run("id")
+	assert.NoError(t, err)
+	cert, err := identity.Load(identityPath, "laptop")
+	assert.NoError(t, err)
+	fingerprint := identity.Fingerprint(cert.Certificate[0])
+	assert.Contains(t, out, "sync-manager lan trust laptop "+fingerprint)
+
+	// Fingerprints are normalized, and invalid ones or this device are refused
+	desktop := strings.Repeat("AB:", 31) + "AB"
+	_, err = run("trust", "desktop", desktop)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"desktop": strings.Repeat("ab", 32)}, cfg.LAN.Peers)
+	_, err = run("trust", "phone", "abcd")
+	assert.Error(t, err)
+	_, err = run("trust", "laptop", fingerprint)
+	assert.Error(t, err)
+	assert.Equal(t, 1, saveCount)
+
+	out, err = run("list")
+	assert.NoError(t, err)
+	assert.Equal(t, "desktop  "+strings.Repeat("ab", 32)+"\n", out)
+
+	_, err = run("untrust", "desktop")
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.LAN.Peers)
+	_, err = run("untrust", "desktop")
+	assert.Error(t, err)
+	assert.Equal(t, 2, saveCount)
+}
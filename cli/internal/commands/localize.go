@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Localize translates the help of cmd and its subcommands to the current language. Commands
+// describe themselves in English, which is the key into the catalogs, so this runs once they
+// are built and the language is known.
+func Localize(cmd *cobra.Command) {
+	cmd.Short = i18n.T(cmd.Short)
+	cmd.Long = i18n.T(cmd.Long)
+	cmd.Example = i18n.T(cmd.Example)
+
+	translate := func(flag *pflag.Flag) {
+		flag.Usage = i18n.T(flag.Usage)
+	}
+	cmd.Flags().VisitAll(translate)
+	cmd.PersistentFlags().VisitAll(translate)
+
+	for _, sub := range cmd.Commands() {
+		Localize(sub)
+	}
+}
@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalize(t *testing.T) {
+	i18n.Set(i18n.Portuguese)
+	t.Cleanup(func() { i18n.Set(i18n.English) })
+
+	rootCmd := &cobra.Command{Use: "sync-manager"}
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile to use (default: the active profile)")
+	userCmd := CreateUserCommand(nil, 1, nil)
+	rootCmd.AddCommand(userCmd)
+
+	Localize(rootCmd)
+
+	// Ajuda, flags e subcomandos saem no idioma escolhido
+	assert.Equal(t, "Gerenciar usuários locais", userCmd.Short)
+	assert.Equal(t, "Perfil de configuração a usar (padrão: o perfil ativo)", rootCmd.PersistentFlags().Lookup("profile").Usage)
+	languageCmd, _, err := rootCmd.Find([]string{"user", "language"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Exibir ou definir o idioma do usuário ativo", languageCmd.Short)
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/storage"
+)
+
+// What the first sync of a folder does with a file, by where the file is
+const (
+	MergeUpload     = "upload"      // Only local
+	MergeDownload   = "download"    // Only remote
+	MergeIdentical  = "identical"   // Same content on both sides
+	MergeKeepLocal  = "keep local"  // Different on both sides, the local copy wins
+	MergeKeepRemote = "keep remote" // Different on both sides, the remote copy wins
+	MergeKeepBoth   = "keep both"   // Different on both sides, the remote copy is saved as a conflict copy
+)
+
+// MergeStep is what the first sync of a folder does with one file
+type MergeStep struct {
+	Path   string
+	Action string
+}
+
+// MergePlan previews how the first sync of a two-way folder reconciles the files already on
+// both sides under an initial merge strategy, in path order. A file is identical when the
+// remote copy has the local file's hash, and prefer-newest compares modification times
+// like the agent, keeping the local copy on a tie.
+func MergePlan(ctx context.Context, store storage.Storage, folder *config.SyncFolder, strategy string) ([]MergeStep, error) {
+	localPaths, err := verifyPaths(folder, nil)
+	if err != nil {
+		return nil, err
+	}
+	local := make(map[string]bool, len(localPaths))
+	for _, relPath := range localPaths {
+		if folder.File == "" || relPath == folder.File {
+			local[relPath] = true
+		}
+	}
+
+	remoteFiles, err := store.ListFiles(ctx, folder.KeyPrefix()+"/")
+	if err != nil {
+		return nil, i18n.Errorf("failed to list remote files: %w", err)
+	}
+
+	var steps []MergeStep
+	for _, remoteFile := range remoteFiles {
+		relPath := strings.TrimPrefix(remoteFile.Key, folder.KeyPrefix()+"/")
+		if _, marker := storage.MarkerDir(relPath); marker || relPath == "" || verifyExcluded(relPath, folder.Exclude) {
+			continue
+		}
+		if folder.File != "" && relPath != folder.File {
+			continue
+		}
+		if !local[relPath] {
+			steps = append(steps, MergeStep{Path: relPath, Action: MergeDownload})
+			continue
+		}
+		delete(local, relPath)
+
+		action, err := mergeAction(ctx, store, folder, relPath, remoteFile, strategy)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, MergeStep{Path: relPath, Action: action})
+	}
+	for relPath := range local {
+		steps = append(steps, MergeStep{Path: relPath, Action: MergeUpload})
+	}
+
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Path < steps[j].Path })
+	return steps, nil
+}
+
+// mergeAction decides what happens to a file found on both sides
+func mergeAction(ctx context.Context, store storage.Storage, folder *config.SyncFolder, relPath string, remoteFile storage.FileInfo, strategy string) (string, error) {
+	_, metadata, err := store.GetFileInfo(ctx, remoteFile.Key)
+	if err != nil {
+		return "", i18n.Errorf("failed to get remote info for %s: %w", relPath, err)
+	}
+
+	localPath := filepath.Join(folder.Path, filepath.FromSlash(relPath))
+	if remoteHash := checksum.FromMetadata(metadata); remoteHash != "" {
+		localHash, err := fileHash(localPath, remoteHash)
+		if err != nil {
+			return "", i18n.Errorf("failed to hash %s: %w", relPath, err)
+		}
+		if strings.EqualFold(localHash, remoteHash) {
+			return MergeIdentical, nil
+		}
+	}
+
+	switch strategy {
+	case config.ConflictPreferLocal:
+		return MergeKeepLocal, nil
+	case config.ConflictPreferRemote:
+		return MergeKeepRemote, nil
+	case config.ConflictPreferNewest:
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return "", i18n.Errorf("failed to stat %s: %w", relPath, err)
+		}
+		if remoteFile.LastModified.After(info.ModTime()) {
+			return MergeKeepRemote, nil
+		}
+		return MergeKeepLocal, nil
+	default:
+		return MergeKeepBoth, nil
+	}
+}
+
+// RenderMergePlan summarizes a merge plan by action and lists the files that differ on both sides
+func RenderMergePlan(folderID, strategy string, steps []MergeStep) string {
+	counts := make(map[string]int)
+	for _, step := range steps {
+		counts[step.Action]++
+	}
+
+	var b strings.Builder
+	i18n.Fprintf(&b, "Initial merge of %s (%s):\n", folderID, strategy)
+	i18n.Fprintf(&b, "  %s only here, uploaded\n", pluralize(counts[MergeUpload], "file"))
+	i18n.Fprintf(&b, "  %s only remote, downloaded\n", pluralize(counts[MergeDownload], "file"))
+	i18n.Fprintf(&b, "  %s identical on both sides\n", pluralize(counts[MergeIdentical], "file"))
+	differ := counts[MergeKeepLocal] + counts[MergeKeepRemote] + counts[MergeKeepBoth]
+	i18n.Fprintf(&b, "  %s different on both sides\n", pluralize(differ, "file"))
+	for _, step := range steps {
+		switch step.Action {
+		case MergeKeepLocal, MergeKeepRemote, MergeKeepBoth:
+			fmt.Fprintf(&b, "    %-12s %s\n", i18n.T(step.Action), step.Path)
+		}
+	}
+	return b.String()
+}
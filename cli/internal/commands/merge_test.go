@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePlan(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	folder := &config.SyncFolder{ID: "docs", Path: t.TempDir(), Exclude: []string{"*.tmp"}}
+
+	local := func(name, content string, modTime time.Time) {
+		path := filepath.Join(folder.Path, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	remote := func(name, content string) {
+		_, err := store.UploadFile(ctx, "docs/"+name, strings.NewReader(content), map[string]string{})
+		assert.NoError(t, err)
+	}
+
+	// Os arquivos remotos são gravados agora: as cópias locais antigas perdem e as futuras ganham
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	local("here.txt", "only local", past)
+	remote("there.txt", "only remote")
+	local("same.txt", "same", past)
+	remote("same.txt", "same")
+	local("old.txt", "local", past)
+	remote("old.txt", "remote")
+	local("new.txt", "local", future)
+	remote("new.txt", "remote")
+	local("skip.tmp", "excluded", past)
+	remote("skip.tmp", "excluded too")
+
+	actions := func(strategy string) map[string]string {
+		steps, err := MergePlan(ctx, store, folder, strategy)
+		assert.NoError(t, err)
+		result := make(map[string]string)
+		for _, step := range steps {
+			result[step.Path] = step.Action
+		}
+		return result
+	}
+
+	assert.Equal(t, map[string]string{
+		"here.txt":  MergeUpload,
+		"there.txt": MergeDownload,
+		"same.txt":  MergeIdentical,
+		"old.txt":   MergeKeepRemote,
+		"new.txt":   MergeKeepLocal,
+	}, actions(config.ConflictPreferNewest))
+	assert.Equal(t, MergeKeepBoth, actions(config.ConflictKeepBoth)["old.txt"])
+	assert.Equal(t, MergeKeepLocal, actions(config.ConflictPreferLocal)["old.txt"])
+	assert.Equal(t, MergeKeepRemote, actions(config.ConflictPreferRemote)["new.txt"])
+
+	steps, err := MergePlan(ctx, store, folder, config.ConflictPreferNewest)
+	assert.NoError(t, err)
+	out := RenderMergePlan("docs", config.ConflictPreferNewest, steps)
+	assert.Contains(t, out, "  1 file only here, uploaded\n")
+	assert.Contains(t, out, "  1 file identical on both sides\n")
+	assert.Contains(t, out, "  2 files different on both sides\n")
+	assert.Contains(t, out, "    keep remote  old.txt\n")
+	assert.Contains(t, out, "    keep local   new.txt\n")
+}
@@ -1,13 +1,16 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"time"
 
 	"github.com/martinshumberto/sync-manager/cli/internal/client"
 	"github.com/martinshumberto/sync-manager/common/config"
-	"github.com/olekukonko/tablewriter"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/progress"
 	"github.com/spf13/cobra"
 )
 
@@ -23,12 +26,12 @@ func CreateMonitoringCommands(cfg *config.Config, agentClient *client.AgentClien
 			if agentClient != nil {
 				// Check if agent is running
 				if err := agentClient.Health(); err != nil {
-					return fmt.Errorf("agent is not running: %w", err)
+					return i18n.Errorf("agent is not running: %w", err)
 				}
 
 				// TODO: Implement real-time monitoring via the agent API
-				fmt.Println("Monitoring sync activity...")
-				fmt.Println("Press Ctrl+C to stop.")
+				i18n.Println("Monitoring sync activity...")
+				i18n.Println("Press Ctrl+C to stop.")
 
 				// Simulate monitoring
 				ticker := time.NewTicker(1 * time.Second)
@@ -37,71 +40,61 @@ func CreateMonitoringCommands(cfg *config.Config, agentClient *client.AgentClien
 				for {
 					select {
 					case <-ticker.C:
-						fmt.Println("Activity update would be shown here...")
+						i18n.Println("Activity update would be shown here...")
 					}
 				}
 			}
 
-			return fmt.Errorf("agent is not running, cannot monitor")
+			return i18n.Errorf("agent is not running, cannot monitor")
 		},
 	}
 
 	cmds = append(cmds, monitorCmd)
 
-	// Progress command - show detailed sync progress
+	// Progress command - show the agent's transfer progress
 	progressCmd := &cobra.Command{
 		Use:   "progress",
 		Short: "Show detailed synchronization progress",
-		Long:  `Display detailed progress information about the synchronization process.`,
+		Long: `Display the agent's current transfers: overall files and bytes, transfer rate and
+estimated time remaining, followed by the progress of each file being transferred.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(cfg.SyncFolders) == 0 {
-				fmt.Println("No folders configured for synchronization.")
-				return nil
+			path, err := progress.DefaultPath()
+			if err != nil {
+				return err
 			}
+			snapshot := agentSnapshot(path)
 
-			fmt.Println("Synchronization Progress:")
-			fmt.Println("------------------------")
-
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"Folder", "Status", "Progress", "Files Pending", "Last Error"})
-
-			// In a real implementation, we would fetch this data from the agent
-			// For now, we'll just display simulated data
-			for _, folder := range cfg.SyncFolders {
-				status := "Syncing"
-				progress := "75%"
-				filesPending := "12"
-				lastError := "-"
+			snap, err := snapshot()
+			if err != nil {
+				return err
+			}
+			if snap == nil {
+				i18n.Println("The agent has not reported any transfers yet.")
+				return nil
+			}
 
-				if !folder.Enabled {
-					status = "Disabled"
-					progress = "-"
-					filesPending = "-"
+			watch, _ := cmd.Flags().GetBool("watch")
+			if !watch {
+				if snap.Idle() {
+					printIdleProgress(snap)
+				} else {
+					fmt.Print(renderProgress(*snap))
 				}
-
-				table.Append([]string{
-					folder.Path,
-					status,
-					progress,
-					filesPending,
-					lastError,
-				})
+				printAgentRates()
+				return nil
 			}
 
-			table.Render()
-
-			fmt.Println("\nOverall Statistics:")
-			fmt.Println("Total Files Queued: 45")
-			fmt.Println("Files Uploaded: 33")
-			fmt.Println("Files Downloaded: 0")
-			fmt.Println("Bytes Transferred: 128.5 MB")
-			fmt.Println("Transfer Rate: 2.4 MB/s")
-			fmt.Println("Estimated Time Remaining: 5m 32s")
+			i18n.Println("Watching transfers, press Ctrl+C to stop.")
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
 
-			return nil
+			_, err = watchProgress(os.Stdout, snapshot, ctx.Done(), false)
+			return err
 		},
 	}
 
+	progressCmd.Flags().BoolP("watch", "w", false, "Keep refreshing until interrupted")
+
 	cmds = append(cmds, progressCmd)
 
 	// Logs command - show sync logs
@@ -118,9 +111,9 @@ func CreateMonitoringCommands(cfg *config.Config, agentClient *client.AgentClien
 			// 2. Read the last N lines
 			// 3. Optionally follow the file for new entries
 
-			fmt.Printf("Displaying last %d log entries", tail)
+			i18n.Printf("Displaying last %d log entries", tail)
 			if follow {
-				fmt.Println(" (following)")
+				i18n.Println(" (following)")
 			} else {
 				fmt.Println("")
 			}
@@ -149,7 +142,7 @@ func CreateMonitoringCommands(cfg *config.Config, agentClient *client.AgentClien
 
 			// Simulate following logs if requested
 			if follow {
-				fmt.Println("\nSimulating log following (will exit after 3 entries)...")
+				i18n.Println("\nSimulating log following (will exit after 3 entries)...")
 
 				// Display a few more entries with delays
 				time.Sleep(1 * time.Second)
@@ -178,7 +171,7 @@ func CreateMonitoringCommands(cfg *config.Config, agentClient *client.AgentClien
 		Short: "Check and repair synchronization state",
 		Long:  `Verify the synchronization state and attempt to repair any inconsistencies.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Println("Repairing synchronization state...")
+			i18n.Println("Repairing synchronization state...")
 
 			// In a real implementation, we would:
 			// 1. Check the local database/state
@@ -187,21 +180,21 @@ func CreateMonitoringCommands(cfg *config.Config, agentClient *client.AgentClien
 			// 4. Report results
 
 			// Simulate repair process
-			fmt.Println("Step 1/4: Checking local database...")
+			i18n.Println("Step 1/4: Checking local database...")
 			time.Sleep(500 * time.Millisecond)
 
-			fmt.Println("Step 2/4: Verifying against remote state...")
+			i18n.Println("Step 2/4: Verifying against remote state...")
 			time.Sleep(1 * time.Second)
 
-			fmt.Println("Step 3/4: Reconciling differences...")
+			i18n.Println("Step 3/4: Reconciling differences...")
 			time.Sleep(700 * time.Millisecond)
 
-			fmt.Println("Step 4/4: Updating local database...")
+			i18n.Println("Step 4/4: Updating local database...")
 			time.Sleep(500 * time.Millisecond)
 
-			fmt.Println("\nRepair complete.")
-			fmt.Println("Found and fixed 3 inconsistencies.")
-			fmt.Println("All folders are now in a consistent state.")
+			i18n.Println("\nRepair complete.")
+			i18n.Println("Found and fixed 3 inconsistencies.")
+			i18n.Println("All folders are now in a consistent state.")
 
 			return nil
 		},
@@ -215,17 +208,12 @@ func CreateMonitoringCommands(cfg *config.Config, agentClient *client.AgentClien
 		Short: "Reset local synchronization state",
 		Long:  `Reset the local synchronization state while preserving files and configuration.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Print("This will reset all synchronization state. Your files will not be deleted, but the agent will need to rescan everything. Continue? (y/n): ")
-
-			var response string
-			fmt.Scanln(&response)
-
-			if response != "y" && response != "Y" {
-				fmt.Println("Operation cancelled.")
+			if !confirm(cmd, "This will reset all synchronization state. Your files will not be deleted, but the agent will need to rescan everything. Continue? (y/n): ") {
+				i18n.Println("Operation cancelled.")
 				return nil
 			}
 
-			fmt.Println("Resetting synchronization state...")
+			i18n.Println("Resetting synchronization state...")
 
 			// In a real implementation, we would:
 			// 1. Stop the agent service
@@ -235,8 +223,8 @@ func CreateMonitoringCommands(cfg *config.Config, agentClient *client.AgentClien
 			// Simulate reset process
 			time.Sleep(2 * time.Second)
 
-			fmt.Println("Synchronization state has been reset.")
-			fmt.Println("The agent will perform a full scan on next start.")
+			i18n.Println("Synchronization state has been reset.")
+			i18n.Println("The agent will perform a full scan on next start.")
 
 			return nil
 		},
@@ -246,3 +234,17 @@ func CreateMonitoringCommands(cfg *config.Config, agentClient *client.AgentClien
 
 	return cmds
 }
+
+// printIdleProgress describes the last finished batch of transfers
+func printIdleProgress(snap *progress.Snapshot) {
+	i18n.Println("No transfers in progress.")
+	if snap.FilesTotal == 0 {
+		return
+	}
+
+	i18n.Printf("Last batch: %s transferred (%s), finished %s\n",
+		pluralize(snap.FilesDone, "file"), formatSize(snap.BytesDone), snap.UpdatedAt.Local().Format(time.RFC3339))
+	if snap.FilesFailed > 0 {
+		i18n.Printf("%s failed.\n", pluralize(snap.FilesFailed, "file"))
+	}
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/progress"
+)
+
+// progressBarWidth is the number of cells in the aggregate progress bar
+const progressBarWidth = 30
+
+// fileBarWidth is the number of cells in a per-file progress bar
+const fileBarWidth = 10
+
+// progressRefresh is how often live progress is redrawn
+const progressRefresh = 250 * time.Millisecond
+
+// renderProgress formats a progress snapshot: an aggregate line followed by one line per active transfer
+func renderProgress(snap progress.Snapshot) string {
+	var b strings.Builder
+
+	eta := "-"
+	if d := snap.ETA(); d > 0 {
+		eta = d.Round(time.Second).String()
+	}
+
+	i18n.Fprintf(&b, "%s %3.0f%%  %d/%d files  %s/%s  %s  ETA %s",
+		bar(snap.BytesDone, snap.BytesTotal, progressBarWidth), percent(snap.BytesDone, snap.BytesTotal),
+		snap.FilesDone, snap.FilesTotal, formatSize(snap.BytesDone), formatSize(snap.BytesTotal),
+		formatRate(snap.Rate), eta)
+	if snap.FilesFailed > 0 {
+		i18n.Fprintf(&b, "  %d failed", snap.FilesFailed)
+	}
+	b.WriteString("\n")
+
+	for _, file := range snap.Active {
+		fmt.Fprintf(&b, "  %s %3.0f%%  %s/%s  %s  %s\n",
+			bar(file.Done, file.Size, fileBarWidth), percent(file.Done, file.Size),
+			formatSize(file.Done), formatSize(file.Size), formatRate(file.Rate), file.Name)
+	}
+
+	return b.String()
+}
+
+// bar renders a text progress bar of width cells
+func bar(done, total int64, width int) string {
+	filled := int(percent(done, total) / 100 * float64(width))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", width-filled) + "]"
+}
+
+// percent returns how much of total is done, treating an empty total as complete
+func percent(done, total int64) float64 {
+	if total <= 0 {
+		return 100
+	}
+	if done >= total {
+		return 100
+	}
+	return float64(done) / float64(total) * 100
+}
+
+// formatRate renders a transfer rate in human-readable units
+func formatRate(bytesPerSec float64) string {
+	return formatSize(int64(bytesPerSec)) + "/s"
+}
+
+// liveView redraws a block of lines in place
+type liveView struct {
+	out   io.Writer
+	lines int
+}
+
+// draw replaces the previously drawn block with text
+func (v *liveView) draw(text string) {
+	if v.lines > 0 {
+		// Move the cursor up to the start of the block and clear everything below it
+		fmt.Fprintf(v.out, "\033[%dA\033[J", v.lines)
+	}
+	fmt.Fprint(v.out, text)
+	v.lines = strings.Count(text, "\n")
+}
+
+// watchProgress redraws the progress returned by snapshot until stop is closed or, when
+// untilIdle is set, until no transfers are left. It returns the last snapshot drawn.
+func watchProgress(out io.Writer, snapshot func() (*progress.Snapshot, error), stop <-chan struct{}, untilIdle bool) (*progress.Snapshot, error) {
+	view := &liveView{out: out}
+	ticker := time.NewTicker(progressRefresh)
+	defer ticker.Stop()
+
+	for {
+		snap, err := snapshot()
+		if err != nil {
+			return nil, err
+		}
+		if snap == nil && untilIdle {
+			return nil, nil
+		}
+		if snap != nil {
+			view.draw(renderProgress(*snap))
+			if untilIdle && snap.Idle() {
+				return snap, nil
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			// Draw the final state before returning
+			if snap, err = snapshot(); err == nil && snap != nil {
+				view.draw(renderProgress(*snap))
+			}
+			return snap, err
+		}
+	}
+}
+
+// trackerSnapshot adapts a local tracker for watchProgress
+func trackerSnapshot(tracker *progress.Tracker) func() (*progress.Snapshot, error) {
+	return func() (*progress.Snapshot, error) {
+		snap := tracker.Snapshot()
+		return &snap, nil
+	}
+}
+
+// agentSnapshot reads the progress the agent publishes at path for watchProgress.
+// It fails when the agent stops updating the file in the middle of a transfer.
+func agentSnapshot(path string) func() (*progress.Snapshot, error) {
+	return func() (*progress.Snapshot, error) {
+		snap, err := progress.Read(path)
+		if err != nil || snap == nil {
+			return snap, err
+		}
+		if !snap.Idle() && time.Since(snap.UpdatedAt) > heartbeat.OnlineThreshold {
+			return nil, i18n.Errorf("the agent has not reported progress since %s; it may have stopped", snap.UpdatedAt.Local().Format(time.RFC3339))
+		}
+		return snap, nil
+	}
+}
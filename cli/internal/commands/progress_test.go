@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderProgress(t *testing.T) {
+	snap := progress.Snapshot{
+		FilesTotal:  4,
+		FilesDone:   1,
+		FilesFailed: 1,
+		BytesTotal:  2048,
+		BytesDone:   1024,
+		Rate:        512,
+		Active: []progress.FileProgress{
+			{Name: "docs/report.pdf", Size: 1000, Done: 250, Rate: 100},
+		},
+	}
+
+	output := renderProgress(snap)
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	assert.Len(t, lines, 2)
+
+	// Linha agregada: barra, arquivos, bytes, taxa e tempo restante
+	assert.Contains(t, lines[0], "[###############...............]  50%")
+	assert.Contains(t, lines[0], "1/4 files")
+	assert.Contains(t, lines[0], "1.0 KiB/2.0 KiB")
+	assert.Contains(t, lines[0], "512 B/s")
+	assert.Contains(t, lines[0], "ETA 2s")
+	assert.Contains(t, lines[0], "1 failed")
+
+	// Uma linha por arquivo em transferência
+	assert.Contains(t, lines[1], "[##........]  25%")
+	assert.Contains(t, lines[1], "250 B/1000 B")
+	assert.Contains(t, lines[1], "100 B/s")
+	assert.Contains(t, lines[1], "docs/report.pdf")
+
+	// Sem taxa conhecida o tempo restante fica em branco
+	assert.Contains(t, renderProgress(progress.Snapshot{FilesTotal: 1, BytesTotal: 10}), "ETA -")
+}
+
+func TestWatchProgress(t *testing.T) {
+	// Um agente que terminou as transferências encerra a espera imediatamente
+	path := filepath.Join(t.TempDir(), "progress.json")
+	assert.NoError(t, progress.Write(path, progress.Snapshot{FilesTotal: 2, FilesDone: 2, BytesTotal: 10, BytesDone: 10, UpdatedAt: time.Now()}))
+
+	var out bytes.Buffer
+	snap, err := watchProgress(&out, agentSnapshot(path), nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, snap.FilesDone)
+	assert.Contains(t, out.String(), "2/2 files")
+
+	// Sem arquivo de progresso também não há o que esperar
+	snap, err = watchProgress(&out, agentSnapshot(filepath.Join(t.TempDir(), "missing.json")), nil, true)
+	assert.NoError(t, err)
+	assert.Nil(t, snap)
+
+	// Um agente que parou no meio de uma transferência é reportado
+	assert.NoError(t, progress.Write(path, progress.Snapshot{FilesTotal: 2, UpdatedAt: time.Now().Add(-time.Hour)}))
+	_, err = watchProgress(&out, agentSnapshot(path), nil, true)
+	assert.Error(t, err)
+
+	// Um tracker local é redesenhado até ser interrompido
+	tracker := progress.NewTracker()
+	tracker.Add(1, 5)
+	stop := make(chan struct{})
+	close(stop)
+	out.Reset()
+	snap, err = watchProgress(&out, trackerSnapshot(tracker), stop, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, snap.FilesTotal)
+	assert.Contains(t, out.String(), "\033[1A\033[J")
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// nonInteractiveFlag turns every prompt off, for scripts; --yes is another name for it
+const nonInteractiveFlag = "non-interactive"
+
+// AddNonInteractiveFlag registers --non-interactive, also spelled --yes or -y, on root and
+// every command under it
+func AddNonInteractiveFlag(root *cobra.Command) {
+	root.PersistentFlags().BoolP(nonInteractiveFlag, "y", false, "Never prompt, for scripts: questions take their default and confirmations are accepted (also --yes)")
+	root.SetGlobalNormalizationFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "yes" {
+			name = nonInteractiveFlag
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+// nonInteractive reports whether cmd runs with --non-interactive
+func nonInteractive(cmd *cobra.Command) bool {
+	flag := cmd.Flag(nonInteractiveFlag)
+	return flag != nil && flag.Value.String() == "true"
+}
+
+// answer reads the answer to a prompt, empty for its default when not interactive
+func answer(cmd *cobra.Command) string {
+	if nonInteractive(cmd) {
+		return ""
+	}
+	var response string
+	fmt.Fscanln(cmd.InOrStdin(), &response)
+	return response
+}
+
+// confirm asks a yes/no question and reports whether it was accepted, which it is without
+// asking when not interactive
+func confirm(cmd *cobra.Command, question string, args ...any) bool {
+	if nonInteractive(cmd) {
+		return true
+	}
+	i18n.Fprintf(cmd.OutOrStdout(), question, args...)
+	return i18n.Yes(answer(cmd))
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/download"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/restore"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/spf13/cobra"
+)
+
+// CreateRestoreCommands returns the disaster recovery commands
+func CreateRestoreCommands(cfg *config.Config, openStorage func() (storage.Storage, error)) []*cobra.Command {
+	// Restore folder command
+	restoreFolderCmd := &cobra.Command{
+		Use:   "restore-folder <folder-id> <target-dir>",
+		Short: "Restore the remote contents of a folder into a directory",
+		Long: `Download the complete remote contents of a folder into an empty directory, restoring
+modification times and permissions. Backup-mode folders restore their latest snapshot;
+--at restores the newest snapshot taken at or before the given time.
+
+An interrupted restore can be resumed by running the same command again.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			folder := findSyncFolder(cfg, args[0])
+			if folder == nil {
+				return i18n.Errorf("folder with ID %s not found", args[0])
+			}
+
+			target, err := filepath.Abs(args[1])
+			if err != nil {
+				return i18n.Errorf("failed to get absolute path: %w", err)
+			}
+
+			var opts restore.Options
+			opts.HardLinks, _ = cmd.Flags().GetBool("hard-links")
+			if at, _ := cmd.Flags().GetString("at"); at != "" {
+				if opts.At, err = restore.ParseTime(at); err != nil {
+					return err
+				}
+			}
+
+			store, err := openStorage()
+			if err != nil {
+				return i18n.Errorf("failed to open storage: %w", err)
+			}
+
+			// Several files download at once, within the configured download limits
+			opts.Downloader = download.NewDownloader(store, cfg)
+			if concurrency, _ := cmd.Flags().GetInt("concurrency"); concurrency > 0 {
+				opts.Downloader.SetMaxConcurrency(concurrency)
+			}
+
+			// Stop cleanly on Ctrl+C so the restore can be resumed
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			i18n.Printf("Restoring folder %s into %s...\n", folder.ID, target)
+
+			opts.Progress = progress.NewTracker()
+			done := make(chan struct{})
+			watched := make(chan struct{})
+			go func() {
+				defer close(watched)
+				watchProgress(os.Stdout, trackerSnapshot(opts.Progress), done, false)
+			}()
+
+			result, err := restore.Folder(ctx, store, *folder, cfg.DeviceID, target, opts)
+			close(done)
+			<-watched
+			if errors.Is(err, restore.ErrTargetNotEmpty) {
+				return i18n.Errorf("%s is not empty; restore into an empty directory", target)
+			}
+			if errors.Is(err, context.Canceled) {
+				return i18n.Errorf("restore interrupted; run the same command again to resume")
+			}
+			if err != nil {
+				return i18n.Errorf("failed to restore folder: %w", err)
+			}
+
+			if result.Source != restore.SourceCurrent {
+				i18n.Printf("Restored from snapshot %s.\n", result.Source)
+			}
+			if result.Resumed > 0 {
+				i18n.Printf("Skipped %s restored by an earlier run.\n", pluralize(result.Resumed, "file"))
+			}
+			i18n.Printf("Restored %s (%s).\n", pluralize(result.Files, "file"), formatSize(result.Bytes))
+			return nil
+		},
+	}
+
+	restoreFolderCmd.Flags().String("at", "", "Restore the snapshot taken at or before this time (RFC3339, \"2006-01-02 15:04\" or \"2006-01-02\")")
+	restoreFolderCmd.Flags().Bool("hard-links", false, "Recreate files that were hard links of each other as hard links (snapshot restores only)")
+	restoreFolderCmd.Flags().Int("concurrency", 0, "Number of parallel downloads (default: download.max_concurrency from the configuration)")
+
+	return []*cobra.Command{restoreFolderCmd}
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreFolderCommand(t *testing.T) {
+	// Preparar o armazenamento com o conteúdo espelhado de uma pasta
+	store, err := storage.NewLocalStorage(&storage.LocalConfig{RootDir: t.TempDir()})
+	assert.NoError(t, err)
+	_, err = store.UploadFile(context.Background(), "docs/notes.txt", bytes.NewReader([]byte("hello")), map[string]string{})
+	assert.NoError(t, err)
+
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "test-device"
+	cfg.SyncFolders = []config.SyncFolder{
+		{ID: "docs", Path: t.TempDir(), Enabled: true},
+	}
+
+	cmds := CreateRestoreCommands(cfg, func() (storage.Storage, error) { return store, nil })
+	assert.Equal(t, 1, len(cmds))
+	restoreCmd := cmds[0]
+	assert.Equal(t, "restore-folder", restoreCmd.Name())
+
+	// Restaurar em um diretório novo
+	target := filepath.Join(t.TempDir(), "recovered")
+	output := captureStdout(func() {
+		assert.NoError(t, restoreCmd.RunE(restoreCmd, []string{"docs", target}))
+	})
+	assert.Contains(t, output, "Restored 1 file")
+
+	data, err := os.ReadFile(filepath.Join(target, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	// Diretório não vazio, pasta inexistente e data inválida são recusados
+	assert.Error(t, restoreCmd.RunE(restoreCmd, []string{"docs", target}))
+	assert.Error(t, restoreCmd.RunE(restoreCmd, []string{"missing", t.TempDir()}))
+	assert.NoError(t, restoreCmd.Flags().Set("at", "yesterday"))
+	assert.Error(t, restoreCmd.RunE(restoreCmd, []string{"docs", t.TempDir()}))
+}
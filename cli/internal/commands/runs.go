@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/spf13/cobra"
+)
+
+// CreateLastRunCommand returns the command summarizing the last sync the agent ran of each folder
+func CreateLastRunCommand(runService *services.SyncRunService, cfg *config.Config) *cobra.Command {
+	lastRunCmd := &cobra.Command{
+		Use:   "last-run [folder-id]",
+		Short: "Show a summary of the last sync",
+		Long: `Shows what the last sync of each folder did, as the agent recorded it when the sync
+ended: how long it took, the files scanned, uploaded, downloaded, deleted and skipped, the
+bytes transferred, errors and conflicts. Uploads finish in the background, so the files a
+sync queued for upload may still be on their way when it ends.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			asJSON, _ := cmd.Flags().GetBool("json")
+
+			folderID := ""
+			if len(args) == 1 {
+				folderID = args[0]
+				if findSyncFolder(cfg, folderID) == nil {
+					return i18n.Errorf("folder with ID %s not found", folderID)
+				}
+			}
+
+			runs, err := runService.LastRuns(cfg.DeviceID, folderID)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if asJSON {
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(runs)
+			}
+
+			if len(runs) == 0 {
+				if folderID != "" {
+					i18n.Fprintf(out, "No sync of %s recorded yet.\n", folderID)
+				} else {
+					i18n.Fprintln(out, "No sync recorded yet.")
+				}
+				return nil
+			}
+			for i, run := range runs {
+				if i > 0 {
+					fmt.Fprintln(out)
+				}
+				printRun(out, run)
+			}
+			return nil
+		},
+	}
+	lastRunCmd.Flags().Bool("json", false, "Print the summaries as JSON")
+
+	return lastRunCmd
+}
+
+// syncKinds describes the kinds of sync a run may be
+var syncKinds = map[string]string{
+	status.FullSync:   "full sync",
+	status.FolderSync: "folder sync",
+	status.Periodic:   "periodic sync",
+	status.PathSync:   "path sync",
+}
+
+// printRun prints the summary of one sync run
+func printRun(out io.Writer, run models.SyncRun) {
+	kind, ok := syncKinds[run.Kind]
+	if !ok {
+		kind = "sync"
+	}
+	i18n.Fprintf(out, "Folder %s: %s %s\n", run.FolderID, i18n.T(kind), i18n.T(run.Status))
+	i18n.Fprintf(out, "  Started:    %s (took %s)\n", formatChange(run.StartedAt), run.Duration().Round(time.Second))
+	i18n.Fprintf(out, "  Scanned:    %s\n", pluralize(int(run.FilesScanned), "file"))
+	i18n.Fprintf(out, "  Uploaded:   %s, %s\n", pluralize(int(run.FilesUploaded), "file"), formatSize(run.BytesUploaded))
+	if run.FilesQueued > 0 {
+		i18n.Fprintf(out, "  Queued:     %s for upload\n", pluralize(int(run.FilesQueued), "file"))
+	}
+	i18n.Fprintf(out, "  Downloaded: %s, %s\n", pluralize(int(run.FilesDownloaded), "file"), formatSize(run.BytesDownloaded))
+	i18n.Fprintf(out, "  Deleted:    %s\n", pluralize(int(run.FilesDeleted), "file"))
+	i18n.Fprintf(out, "  Skipped:    %s\n", pluralize(int(run.FilesSkipped), "file"))
+	i18n.Fprintf(out, "  Conflicts:  %d\n", run.Conflicts)
+	i18n.Fprintf(out, "  Errors:     %d\n", run.Errors)
+	if run.Error != "" {
+		i18n.Fprintf(out, "  Error:      %s\n", run.Error)
+	}
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastRunCommand(t *testing.T) {
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	defer dbManager.Close()
+	assert.NoError(t, dbManager.InitSchema())
+
+	// Execuções registradas pelo agente deste e de outro dispositivo
+	start := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	for _, run := range []models.SyncRun{
+		{FolderID: "docs", DeviceID: "laptop", Kind: "periodic", Status: models.SyncRunSucceeded, StartedAt: start, FinishedAt: start.Add(time.Minute), FilesUploaded: 9},
+		{FolderID: "docs", DeviceID: "laptop", Kind: "full", Status: models.SyncRunSucceeded, StartedAt: start.Add(time.Hour), FinishedAt: start.Add(time.Hour + 90*time.Second),
+			FilesScanned: 120, FilesUploaded: 3, FilesQueued: 1, BytesUploaded: 2048, FilesDownloaded: 1, BytesDownloaded: 6, Conflicts: 1},
+		{FolderID: "photos", DeviceID: "laptop", Kind: "full", Status: models.SyncRunFailed, StartedAt: start, FinishedAt: start.Add(time.Second), Errors: 1, Error: "failed to walk directory"},
+		{FolderID: "docs", DeviceID: "desktop", Kind: "full", Status: models.SyncRunSucceeded, StartedAt: start.Add(2 * time.Hour), FinishedAt: start.Add(2 * time.Hour)},
+	} {
+		assert.NoError(t, dbManager.GetDB().Create(&run).Error)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "laptop"
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: t.TempDir()}, {ID: "photos", Path: t.TempDir()}, {ID: "music", Path: t.TempDir()}}
+	runService := services.NewSyncRunService(repositories.NewSyncRunRepository(dbManager.GetDB()))
+	run := func(args ...string) (string, error) {
+		lastRunCmd := CreateLastRunCommand(runService, cfg)
+		var out bytes.Buffer
+		lastRunCmd.SetOut(&out)
+		lastRunCmd.SetArgs(args)
+		err := lastRunCmd.Execute()
+		return out.String(), err
+	}
+
+	// Sem pasta, a última execução de cada uma
+	output, err := run()
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Folder docs: full sync succeeded")
+	assert.Contains(t, output, "(took 1m30s)")
+	assert.Contains(t, output, "Scanned:    120 files")
+	assert.Contains(t, output, "Uploaded:   3 files, 2.0 KiB")
+	assert.Contains(t, output, "Queued:     1 file for upload")
+	assert.Contains(t, output, "Conflicts:  1")
+	assert.Contains(t, output, "Folder photos: full sync failed")
+	assert.Contains(t, output, "Error:      failed to walk directory")
+
+	output, err = run("photos", "--json")
+	assert.NoError(t, err)
+	var runs []models.SyncRun
+	assert.NoError(t, json.Unmarshal([]byte(output), &runs))
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, "photos", runs[0].FolderID)
+		assert.Equal(t, int64(1), runs[0].Errors)
+	}
+
+	output, err = run("music")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "No sync of music recorded yet.")
+
+	_, err = run("videos")
+	assert.ErrorContains(t, err, "not found")
+}
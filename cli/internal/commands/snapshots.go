@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/snapshot"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// CreateSnapshotCommands returns the commands for backup-mode folder snapshots
+func CreateSnapshotCommands(cfg *config.Config, openStore func() (snapshot.ObjectStore, error)) []*cobra.Command {
+	// Snapshots root command
+	snapshotsCmd := &cobra.Command{
+		Use:   "snapshots",
+		Short: "Manage backup snapshots",
+		Long:  `List, restore and prune the point-in-time snapshots of folders in backup mode.`,
+	}
+
+	// Snapshots list command
+	listCmd := &cobra.Command{
+		Use:   "list <folder-id>",
+		Short: "List the snapshots of a folder",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := snapshotRepository(cfg, openStore, args[0])
+			if err != nil {
+				return err
+			}
+
+			summaries, err := repo.List(context.Background())
+			if err != nil {
+				return i18n.Errorf("failed to list snapshots: %w", err)
+			}
+
+			if len(summaries) == 0 {
+				i18n.Println("No snapshots found for this folder.")
+				return nil
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader(i18n.Strings("Snapshot ID", "Created", "Files", "Size"))
+
+			for _, summary := range summaries {
+				table.Append([]string{
+					summary.ID,
+					summary.CreatedAt.Local().Format(time.RFC3339),
+					strconv.Itoa(summary.Files),
+					formatSize(summary.Size),
+				})
+			}
+
+			table.Render()
+			return nil
+		},
+	}
+
+	// Snapshots restore command
+	restoreCmd := &cobra.Command{
+		Use:   "restore <folder-id> <snapshot-id> <target-dir>",
+		Short: "Restore a snapshot into a directory",
+		Long: `Download every file of a snapshot into the target directory, restoring permissions and modification times.
+With --hard-links, files that were hard links of each other are linked again instead of restored as copies.`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			folderID, snapshotID := args[0], args[1]
+
+			target, err := filepath.Abs(args[2])
+			if err != nil {
+				return i18n.Errorf("failed to get absolute path: %w", err)
+			}
+
+			repo, err := snapshotRepository(cfg, openStore, folderID)
+			if err != nil {
+				return err
+			}
+			if hardLinks, _ := cmd.Flags().GetBool("hard-links"); hardLinks {
+				repo.SetHardLinks(true)
+			}
+
+			i18n.Printf("Restoring snapshot %s into %s...\n", snapshotID, target)
+
+			restored, err := repo.Restore(context.Background(), snapshotID, target)
+			if errors.Is(err, snapshot.ErrNotFound) {
+				return i18n.Errorf("snapshot %s not found for folder %s", snapshotID, folderID)
+			}
+			if err != nil {
+				return i18n.Errorf("failed to restore snapshot: %w", err)
+			}
+
+			i18n.Printf("Restored %s.\n", pluralize(restored, "file"))
+			return nil
+		},
+	}
+
+	// Snapshots prune command
+	pruneCmd := &cobra.Command{
+		Use:   "prune <folder-id>",
+		Short: "Delete snapshots outside the retention policy",
+		Long: `Delete the snapshots that the folder's retention policy does not keep, along with
+any stored content no remaining snapshot uses. The --keep-* flags override the configured policy.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			folder := findSyncFolder(cfg, args[0])
+			if folder == nil {
+				return i18n.Errorf("folder with ID %s not found", args[0])
+			}
+
+			policy := snapshot.Policy(folder.Retention)
+			if cmd.Flags().Changed("keep-last") {
+				policy.KeepLast, _ = cmd.Flags().GetInt("keep-last")
+			}
+			if cmd.Flags().Changed("keep-daily") {
+				policy.KeepDaily, _ = cmd.Flags().GetInt("keep-daily")
+			}
+			if cmd.Flags().Changed("keep-weekly") {
+				policy.KeepWeekly, _ = cmd.Flags().GetInt("keep-weekly")
+			}
+			if cmd.Flags().Changed("keep-monthly") {
+				policy.KeepMonthly, _ = cmd.Flags().GetInt("keep-monthly")
+			}
+
+			if policy.IsZero() {
+				return i18n.Errorf("no retention policy configured for folder %s; use configure-folder or the --keep-* flags", folder.ID)
+			}
+
+			repo, err := snapshotRepository(cfg, openStore, folder.ID)
+			if err != nil {
+				return err
+			}
+
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			if dryRun {
+				summaries, err := repo.List(context.Background())
+				if err != nil {
+					return i18n.Errorf("failed to list snapshots: %w", err)
+				}
+				_, remove := policy.Apply(summaries)
+				for _, summary := range remove {
+					i18n.Printf("Would remove snapshot %s (%s)\n", summary.ID, summary.CreatedAt.Local().Format(time.RFC3339))
+				}
+				i18n.Printf("%s would be removed.\n", pluralize(len(remove), "snapshot"))
+				return nil
+			}
+
+			removed, err := repo.Prune(context.Background(), policy)
+			if err != nil {
+				return i18n.Errorf("failed to prune snapshots: %w", err)
+			}
+
+			for _, summary := range removed {
+				i18n.Printf("Removed snapshot %s (%s)\n", summary.ID, summary.CreatedAt.Local().Format(time.RFC3339))
+			}
+			i18n.Printf("%s removed.\n", pluralize(len(removed), "snapshot"))
+			return nil
+		},
+	}
+
+	restoreCmd.Flags().Bool("hard-links", false, "Recreate files that were hard links of each other as hard links")
+
+	pruneCmd.Flags().Int("keep-last", 0, "Keep the N most recent snapshots")
+	pruneCmd.Flags().Int("keep-daily", 0, "Keep the newest snapshot of each of the last N days")
+	pruneCmd.Flags().Int("keep-weekly", 0, "Keep the newest snapshot of each of the last N weeks")
+	pruneCmd.Flags().Int("keep-monthly", 0, "Keep the newest snapshot of each of the last N months")
+	pruneCmd.Flags().Bool("dry-run", false, "Show what would be removed without deleting anything")
+
+	snapshotsCmd.AddCommand(listCmd)
+	snapshotsCmd.AddCommand(restoreCmd)
+	snapshotsCmd.AddCommand(pruneCmd)
+
+	return []*cobra.Command{snapshotsCmd}
+}
+
+// snapshotRepository opens the snapshot repository of a configured folder
+func snapshotRepository(cfg *config.Config, openStore func() (snapshot.ObjectStore, error), folderID string) (*snapshot.Repository, error) {
+	folder := findSyncFolder(cfg, folderID)
+	if folder == nil {
+		return nil, i18n.Errorf("folder with ID %s not found", folderID)
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return nil, i18n.Errorf("failed to open storage: %w", err)
+	}
+
+	return snapshot.NewRepository(store, folder.ID, cfg.DeviceID), nil
+}
+
+// findSyncFolder returns the configured folder with the given ID
+func findSyncFolder(cfg *config.Config, folderID string) *config.SyncFolder {
+	for i := range cfg.SyncFolders {
+		if cfg.SyncFolders[i].ID == folderID {
+			return &cfg.SyncFolders[i]
+		}
+	}
+	return nil
+}
+
+// formatSize renders a byte count in human-readable units
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
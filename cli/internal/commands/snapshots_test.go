@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/snapshot"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// findSubcommand busca um subcomando pelo nome
+func findSubcommand(parent *cobra.Command, name string) *cobra.Command {
+	for _, c := range parent.Commands() {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestSnapshotCommands(t *testing.T) {
+	// Preparar uma pasta em modo backup com armazenamento local
+	folderPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(folderPath, "notes.txt"), []byte("v1"), 0644))
+
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "test-device"
+	cfg.SyncFolders = []config.SyncFolder{
+		{ID: "backup-folder", Path: folderPath, Enabled: true, Mode: config.FolderModeBackup},
+	}
+
+	store, err := storage.NewLocalStorage(&storage.LocalConfig{RootDir: t.TempDir()})
+	assert.NoError(t, err)
+	openStore := func() (snapshot.ObjectStore, error) { return store, nil }
+
+	// Criar dois snapshots como o agente faria
+	repo := snapshot.NewRepository(store, "backup-folder", cfg.DeviceID)
+	first, err := repo.Create(context.Background(), folderPath, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(folderPath, "notes.txt"), []byte("v2"), 0644))
+	_, err = repo.Create(context.Background(), folderPath, nil)
+	assert.NoError(t, err)
+
+	cmds := CreateSnapshotCommands(cfg, openStore)
+	assert.Equal(t, 1, len(cmds))
+	snapshotsCmd := cmds[0]
+
+	// Listar
+	listCmd := findSubcommand(snapshotsCmd, "list")
+	output := captureStdout(func() {
+		assert.NoError(t, listCmd.RunE(listCmd, []string{"backup-folder"}))
+	})
+	assert.Contains(t, output, first.ID)
+
+	// Restaurar o primeiro snapshot
+	target := t.TempDir()
+	restoreCmd := findSubcommand(snapshotsCmd, "restore")
+	output = captureStdout(func() {
+		assert.NoError(t, restoreCmd.RunE(restoreCmd, []string{"backup-folder", first.ID, target}))
+	})
+	assert.Contains(t, output, "Restored 1 file")
+
+	data, err := os.ReadFile(filepath.Join(target, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	assert.Error(t, restoreCmd.RunE(restoreCmd, []string{"backup-folder", "missing", target}))
+
+	// Sem política de retenção, prune exige flags
+	pruneCmd := findSubcommand(snapshotsCmd, "prune")
+	assert.Error(t, pruneCmd.RunE(pruneCmd, []string{"backup-folder"}))
+
+	assert.NoError(t, pruneCmd.Flags().Set("keep-last", "1"))
+	output = captureStdout(func() {
+		assert.NoError(t, pruneCmd.RunE(pruneCmd, []string{"backup-folder"}))
+	})
+	assert.Contains(t, output, "1 snapshot removed")
+
+	summaries, err := repo.List(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, summaries, 1)
+	assert.NotEqual(t, first.ID, summaries[0].ID)
+}
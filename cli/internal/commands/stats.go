@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// CreateStatsCommand returns the command showing the file counts, sizes and largest files
+// of each folder, as the agent recorded them on the last sync of the folder
+func CreateStatsCommand(cfg *config.Config, statsPath string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats [folder-id]",
+		Short: "Show folder statistics",
+		Long: `Shows how many files each folder holds, their total size and when one last changed,
+or for a single folder its largest files too. The figures are those of the last sync,
+so the folders are not scanned again.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snap, err := stats.Read(statsPath)
+			if err != nil {
+				return err
+			}
+			if snap == nil {
+				snap = &stats.Snapshot{}
+			}
+
+			if len(args) == 1 {
+				if findFolder(cfg, args[0]) == nil {
+					return i18n.Errorf("folder not found: %s", args[0])
+				}
+				printFolderStats(cmd.OutOrStdout(), args[0], snap)
+				return nil
+			}
+
+			if len(cfg.SyncFolders) == 0 {
+				i18n.Fprintln(cmd.OutOrStdout(), "No folders configured.")
+				return nil
+			}
+			table := tablewriter.NewWriter(cmd.OutOrStdout())
+			table.SetHeader(i18n.Strings("Folder", "Files", "Size", "Last Change"))
+			for _, folder := range cfg.SyncFolders {
+				summary, ok := snap.Summaries[folder.ID]
+				if !ok {
+					table.Append([]string{folder.ID, "-", "-", i18n.T("not synced yet")})
+					continue
+				}
+				table.Append([]string{folder.ID, strconv.FormatInt(summary.Files, 10), formatSize(summary.Bytes), formatChange(summary.LastChange)})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
+
+// printFolderStats prints the summary and transfers of one folder
+func printFolderStats(out io.Writer, folderID string, snap *stats.Snapshot) {
+	summary, ok := snap.Summaries[folderID]
+	if !ok {
+		i18n.Fprintf(out, "Folder %s has not been synced yet.\n", folderID)
+		return
+	}
+
+	i18n.Fprintf(out, "Folder: %s\n", folderID)
+	i18n.Fprintf(out, "Files: %d (%s)\n", summary.Files, formatSize(summary.Bytes))
+	i18n.Fprintf(out, "Last change: %s\n", formatChange(summary.LastChange))
+	if summary.Archived > 0 {
+		i18n.Fprintf(out, "Archived: %s, %s freed\n", pluralize(int(summary.Archived), "file"), formatSize(summary.ArchivedBytes))
+	}
+	if totals, ok := snap.Folders[folderID]; ok {
+		for _, line := range DescribeTotals(totals) {
+			fmt.Fprintln(out, line)
+		}
+	}
+	if len(summary.Largest) > 0 {
+		i18n.Fprintln(out, "\nLargest files:")
+		for _, file := range summary.Largest {
+			fmt.Fprintf(out, "  %10s  %s\n", formatSize(file.Size), file.Path)
+		}
+	}
+}
+
+// formatChange renders when a folder last changed
+func formatChange(t time.Time) string {
+	if t.IsZero() {
+		return i18n.T("unknown")
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// AgentStats returns the transfer stats the agent publishes at path, or nil when the
+// agent is not running or has not written them yet
+func AgentStats(path string) *stats.Snapshot {
+	snap, err := stats.Read(path)
+	if err != nil || snap == nil || !heartbeat.IsOnline(snap.UpdatedAt) {
+		return nil
+	}
+	return snap
+}
+
+// DescribeTotals returns the lines describing the transfers of a folder, or of every folder
+func DescribeTotals(totals stats.Totals) []string {
+	lines := []string{i18n.Sprintf("Transferred: ↑ %s (%s)  ↓ %s (%s)",
+		pluralize(int(totals.FilesUploaded), "file"), formatSize(totals.BytesUploaded),
+		pluralize(int(totals.FilesDownloaded), "file"), formatSize(totals.BytesDownloaded))}
+
+	if totals.Active() {
+		lines = append(lines, i18n.Sprintf("Rate: %s", describeRates(totals)))
+	}
+	if !totals.LastSync.IsZero() {
+		lines = append(lines, i18n.Sprintf("Last sync: %s", totals.LastSync.Local().Format(time.RFC3339)))
+	}
+	if totals.Errors > 0 {
+		lines = append(lines, i18n.Sprintf("Errors: %d", totals.Errors))
+	}
+	return lines
+}
+
+// describeRates renders the upload and download rates over each window
+func describeRates(totals stats.Totals) string {
+	var windows, up, down []string
+	for _, rate := range totals.Rates {
+		windows = append(windows, strings.TrimSuffix(rate.Window.String(), "0s"))
+		up = append(up, formatRate(rate.Upload))
+		down = append(down, formatRate(rate.Download))
+	}
+	return fmt.Sprintf("↑ %s  ↓ %s (%s)", strings.Join(up, " / "), strings.Join(down, " / "), strings.Join(windows, "/"))
+}
+
+// printAgentRates prints the agent's recent average transfer rates, if it transferred anything lately
+func printAgentRates() {
+	path, err := stats.DefaultPath()
+	if err != nil {
+		return
+	}
+	if snap := AgentStats(path); snap != nil && snap.Global.Active() {
+		i18n.Printf("Average rate: %s\n", describeRates(snap.Global))
+	}
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	assert.Nil(t, AgentStats(path))
+
+	registry := stats.NewRegistry()
+	registry.Uploaded("docs", 2048)
+	registry.Failed("docs")
+	snap := registry.Snapshot()
+	assert.NoError(t, stats.Write(path, snap))
+
+	read := AgentStats(path)
+	assert.NotNil(t, read)
+	assert.Equal(t, int64(2048), read.Folders["docs"].BytesUploaded)
+
+	// Estatísticas antigas indicam que o agente parou
+	snap.UpdatedAt = time.Now().Add(-time.Hour)
+	assert.NoError(t, stats.Write(path, snap))
+	assert.Nil(t, AgentStats(path))
+}
+
+func TestDescribeTotals(t *testing.T) {
+	totals := stats.Totals{
+		FilesUploaded:   3,
+		BytesUploaded:   3072,
+		FilesDownloaded: 1,
+		BytesDownloaded: 10,
+		Errors:          2,
+		Rates: []stats.Rate{
+			{Window: time.Minute, Upload: 2048},
+			{Window: 5 * time.Minute, Upload: 512},
+			{Window: 15 * time.Minute, Upload: 100},
+		},
+	}
+
+	lines := DescribeTotals(totals)
+	assert.Equal(t, []string{
+		"Transferred: ↑ 3 files (3.0 KiB)  ↓ 1 file (10 B)",
+		"Rate: ↑ 2.0 KiB/s / 512 B/s / 100 B/s  ↓ 0 B/s / 0 B/s / 0 B/s (1m/5m/15m)",
+		"Errors: 2",
+	}, lines)
+
+	// Sem transferências recentes a taxa é omitida
+	totals.Rates = nil
+	assert.Len(t, DescribeTotals(totals), 2)
+}
+
+func TestStatsCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{{ID: "docs", Path: "/home/docs"}, {ID: "photos", Path: "/home/photos"}}
+
+	registry := stats.NewRegistry()
+	registry.Uploaded("docs", 4096)
+	registry.SetSummary("docs", stats.FolderSummary{
+		Files:      2,
+		Bytes:      3 << 20,
+		Largest:    []stats.FileSize{{Path: "report.pdf", Size: 2 << 20}, {Path: "notes.txt", Size: 1 << 20}},
+		LastChange: time.Date(2024, 6, 1, 10, 30, 0, 0, time.Local),
+	})
+	assert.NoError(t, stats.Write(path, registry.Snapshot()))
+
+	run := func(args ...string) (string, error) {
+		statsCmd := CreateStatsCommand(cfg, path)
+		var out bytes.Buffer
+		statsCmd.SetOut(&out)
+		statsCmd.SetArgs(args)
+		err := statsCmd.Execute()
+		return out.String(), err
+	}
+
+	// Todas as pastas, inclusive as que ainda não sincronizaram
+	output, err := run()
+	assert.NoError(t, err)
+	assert.Contains(t, output, "3.0 MiB")
+	assert.Contains(t, output, "2024-06-01 10:30")
+	assert.Contains(t, output, "not synced yet")
+
+	// Uma pasta, com os maiores arquivos e as transferências
+	output, err = run("docs")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Files: 2 (3.0 MiB)")
+	assert.Contains(t, output, "Transferred: ↑ 1 file (4.0 KiB)")
+	assert.Regexp(t, `2\.0 MiB  report\.pdf\n\s+1\.0 MiB  notes\.txt`, output)
+
+	output, err = run("photos")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Folder photos has not been synced yet.")
+
+	_, err = run("music")
+	assert.ErrorContains(t, err, "folder not found")
+}
@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/client"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/diskspace"
+	"github.com/martinshumberto/sync-manager/common/guard"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/stats"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/martinshumberto/sync-manager/common/watchlimit"
+	"github.com/spf13/cobra"
+)
+
+// statusRefresh is how often status --watch redraws
+const statusRefresh = time.Second
+
+// CreateStatusCommand returns the status command, which shows the state the agent reports for each folder
+func CreateStatusCommand(cfg *config.Config, agentClient *client.AgentClient) *cobra.Command {
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show sync status of monitored folders",
+		Long: `Show the state the agent reports for each folder (idle, scanning, syncing, paused or error)
+with its last sync, the files waiting to be transferred, its last error and the bytes transferred today.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := agentClient.Health(); err != nil {
+				i18n.Println("Agent is not running. Start it with 'sync-manager start'.")
+				return nil
+			}
+
+			if len(cfg.SyncFolders) == 0 {
+				i18n.Println("No folders configured for synchronization.")
+				return nil
+			}
+
+			watch, _ := cmd.Flags().GetBool("watch")
+			if !watch {
+				fmt.Print(currentStatus(cfg, agentClient))
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			view := &liveView{out: os.Stdout}
+			ticker := time.NewTicker(statusRefresh)
+			defer ticker.Stop()
+			for {
+				view.draw(currentStatus(cfg, agentClient))
+
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		},
+	}
+
+	statusCmd.Flags().BoolP("watch", "w", false, "Keep refreshing until interrupted")
+
+	return statusCmd
+}
+
+// currentStatus renders what the agent last published
+func currentStatus(cfg *config.Config, agentClient *client.AgentClient) string {
+	folders, _ := agentClient.GetStatus()
+
+	var agentStats *stats.Snapshot
+	if statsPath, err := stats.DefaultPath(); err == nil {
+		agentStats = AgentStats(statsPath)
+	}
+
+	return RenderStatus(cfg, folders, agentStats, statusNotices())
+}
+
+// statusNotices returns the warnings about transfers the agent is holding back
+func statusNotices() []string {
+	var notices []string
+	if heartbeatPath, err := heartbeat.DefaultPath(); err == nil {
+		if restriction := TransferRestriction(heartbeatPath); restriction != "" {
+			notices = append(notices, "⚡ "+i18n.Sprintf("Transfers %s", restriction))
+		}
+	}
+	if guardPath, err := guard.DefaultPath(); err == nil {
+		for _, warning := range DeletionWarnings(guardPath) {
+			notices = append(notices, "⚠ "+warning)
+		}
+	}
+	if spacePath, err := diskspace.DefaultPath(); err == nil {
+		for _, warning := range DiskSpaceWarnings(spacePath) {
+			notices = append(notices, "⚠ "+warning)
+		}
+	}
+	if watchesPath, err := watchlimit.DefaultPath(); err == nil {
+		for _, warning := range WatchWarnings(watchesPath) {
+			notices = append(notices, "⚠ "+warning)
+		}
+	}
+	return notices
+}
+
+// RenderStatus formats the state of every configured folder. folders and agentStats
+// are nil when the agent has not published them recently.
+func RenderStatus(cfg *config.Config, folders *status.Snapshot, agentStats *stats.Snapshot, notices []string) string {
+	var b strings.Builder
+
+	title := i18n.T("Synchronization Status:")
+	b.WriteString(title + "\n")
+	b.WriteString(strings.Repeat("-", utf8.RuneCountInString(title)+1) + "\n")
+
+	for _, notice := range notices {
+		b.WriteString(notice + "\n")
+	}
+	if len(notices) > 0 {
+		b.WriteString("\n")
+	}
+
+	if agentStats != nil {
+		i18n.Fprintf(&b, "Agent running since %s\n", agentStats.StartedAt.Local().Format(time.RFC3339))
+		for _, line := range DescribeTotals(agentStats.Global) {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	if folders == nil {
+		b.WriteString(i18n.T("The agent has not reported the state of its folders yet.") + "\n\n")
+	} else if folders.Operation != nil {
+		b.WriteString(DescribeOperation(folders.Operation) + "\n\n")
+	}
+	if folders != nil && len(folders.Endpoints) > 0 {
+		for _, line := range describeEndpoints(cfg, folders.Endpoints) {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	for _, folder := range cfg.SyncFolders {
+		live, reported := status.Folder{}, false
+		if folders != nil {
+			live, reported = folders.Folders[folder.ID]
+		}
+
+		fmt.Fprintf(&b, "📂 %s\n", folder.ID)
+		i18n.Fprintf(&b, "   Path: %s\n", strings.ReplaceAll(FolderPathLabel(folder), "\n", "\n         "))
+		i18n.Fprintf(&b, "   State: %s\n", describeFolderState(folder, live, reported))
+		if reported {
+			lastSync := i18n.T("never")
+			if !live.LastSync.IsZero() {
+				lastSync = live.LastSync.Local().Format(time.RFC3339)
+			}
+			i18n.Fprintf(&b, "   Last sync: %s\n", lastSync)
+			if !live.NextSync.IsZero() {
+				i18n.Fprintf(&b, "   Next sync: %s\n", live.NextSync.Local().Format(time.RFC3339))
+			}
+			i18n.Fprintf(&b, "   Transferred today: %s\n", formatSize(live.BytesToday))
+			if live.LastError != "" {
+				i18n.Fprintf(&b, "   Last error: %s\n", live.LastError)
+			}
+			if len(live.Collisions) > 0 {
+				i18n.Fprintf(&b, "   Case collisions: %d, not downloaded (see 'sync-manager conflicts list')\n", len(live.Collisions))
+			}
+		}
+		i18n.Fprintf(&b, "   Interval: %s\n", FolderIntervalLabel(folder, cfg.SyncInterval))
+		if folder.Mode == config.FolderModeBackup {
+			b.WriteString("   " + i18n.T("Mode: backup (snapshots)") + "\n")
+		}
+		if folder.RemotePrefix != "" {
+			i18n.Fprintf(&b, "   Remote prefix: %s/\n", folder.RemotePrefix)
+		}
+		if folder.Subscribe {
+			i18n.Fprintf(&b, "   Subscribed: read-only, local changes %s\n", subscribeLabel(folder))
+		}
+		if folder.ConflictPolicy != "" && folder.ConflictPolicy != config.ConflictKeepBoth {
+			i18n.Fprintf(&b, "   Conflicts: %s\n", folder.ConflictPolicy)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// DescribeOperation returns a line describing the sync the agent is running and how far it got
+func DescribeOperation(op *status.Operation) string {
+	label := i18n.T("Full sync")
+	switch op.Kind {
+	case status.Periodic:
+		label = i18n.T("Scheduled sync")
+	case status.FolderSync:
+		label = i18n.Sprintf("Sync of %s", strings.Join(op.Folders, ", "))
+	case status.PathSync:
+		label = i18n.Sprintf("Sync of %s in %s", op.Path, strings.Join(op.Folders, ", "))
+	}
+
+	line := i18n.Sprintf("🔄 %s running since %s: %d/%d folders done", label, op.StartedAt.Local().Format(time.RFC3339), op.Done, len(op.Folders))
+	if op.Current != "" {
+		line += i18n.Sprintf(", syncing %s", op.Current)
+	}
+	return line
+}
+
+// describeEndpoints returns a line per target with failover endpoints, naming the endpoint
+// the agent uses and whether it is a failover one
+func describeEndpoints(cfg *config.Config, endpoints map[string]string) []string {
+	targets := make([]string, 0, len(endpoints))
+	for target := range endpoints {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	lines := make([]string, 0, len(targets))
+	for _, name := range targets {
+		line := i18n.Sprintf("🌐 Storage %s: endpoint %s", name, endpoints[name])
+		if target := cfg.Target(name); target != nil {
+			primary := target.S3.Endpoint
+			if target.Type == config.TargetMinio {
+				primary = target.Minio.Endpoint
+			}
+			if endpoints[name] != primary {
+				line += i18n.Sprintf(" (failover, primary %s is down)", primary)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// describeFolderState returns the state of a folder with its pending files. Folders the
+// agent did not report fall back to what the configuration says.
+func describeFolderState(folder config.SyncFolder, live status.Folder, reported bool) string {
+	state := live.State
+	if !reported {
+		switch {
+		case !folder.Enabled:
+			state = status.Disabled
+		case folder.Paused:
+			state = status.Paused
+		default:
+			state = "unknown"
+		}
+	}
+
+	label := i18n.T(strings.ToUpper(state[:1]) + state[1:])
+	if live.Pending > 0 {
+		label += i18n.Sprintf(", %s pending", pluralize(live.Pending, "file"))
+	}
+	return label
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderStatus(t *testing.T) {
+	cfg := &config.Config{
+		SyncInterval: 5 * time.Minute,
+		SyncFolders: []config.SyncFolder{
+			{ID: "docs", Path: "/home/user/docs", Enabled: true},
+			{ID: "photos", Path: "/home/user/photos", Enabled: true, Schedule: "0 2 * * *"},
+			{ID: "music", Path: "/home/user/music", Enabled: true, Paused: true},
+		},
+	}
+	nextSync := time.Date(2026, 3, 11, 2, 0, 0, 0, time.Local)
+	folders := &status.Snapshot{
+		UpdatedAt: time.Now(),
+		Folders: map[string]status.Folder{
+			"docs":   {State: status.Syncing, Pending: 3, BytesToday: 2048},
+			"photos": {State: status.Error, NextSync: nextSync, LastError: "permission denied", Collisions: [][]string{{"IMG.jpg", "img.jpg"}}},
+		},
+		Operation: &status.Operation{Kind: status.FullSync, Folders: []string{"docs", "photos"}, Current: "photos", Done: 1, StartedAt: time.Now()},
+	}
+
+	out := RenderStatus(cfg, folders, nil, []string{"⚡ Transfers paused"})
+	assert.Contains(t, out, ": 1/2 folders done, syncing photos\n")
+	assert.Contains(t, out, "⚡ Transfers paused\n")
+	assert.Contains(t, out, "   State: Syncing, 3 files pending\n")
+	assert.Contains(t, out, "   Last sync: never\n")
+	assert.Contains(t, out, "   Transferred today: 2.0 KiB\n")
+	assert.Contains(t, out, "   State: Error\n")
+	assert.Contains(t, out, "   Last error: permission denied\n")
+	assert.Contains(t, out, "   Case collisions: 1, not downloaded (see 'sync-manager conflicts list')\n")
+
+	// Pastas com agenda cron mostram a expressão e a próxima execução
+	assert.Contains(t, out, "   Interval: cron 0 2 * * *\n")
+	assert.Contains(t, out, "   Next sync: "+nextSync.Format(time.RFC3339)+"\n")
+
+	// Pastas que o agente não informou usam o estado da configuração
+	assert.Contains(t, out, "   State: Paused\n")
+
+	// Alvos com endpoints de failover mostram o endpoint em uso
+	cfg.Targets = []config.StorageTarget{
+		{Name: "backup", Type: config.TargetMinio, Minio: config.MinioConfig{Endpoint: "minio-1:9000", Failover: []string{"minio-2:9000"}}},
+		{Name: "archive", Type: config.TargetS3, S3: config.S3Config{Endpoint: "s3.eu-west-1.amazonaws.com", Failover: []string{"s3.eu-west-2.amazonaws.com"}}},
+	}
+	folders.Endpoints = map[string]string{"backup": "minio-2:9000", "archive": "s3.eu-west-1.amazonaws.com"}
+	out = RenderStatus(cfg, folders, nil, nil)
+	assert.Contains(t, out, "🌐 Storage archive: endpoint s3.eu-west-1.amazonaws.com\n🌐 Storage backup: endpoint minio-2:9000 (failover, primary minio-1:9000 is down)\n")
+
+	out = RenderStatus(cfg, nil, nil, nil)
+	assert.Contains(t, out, "The agent has not reported the state of its folders yet.")
+	assert.Contains(t, out, "   State: Unknown\n")
+}
+
+func TestDescribeOperation(t *testing.T) {
+	startedAt := time.Now()
+
+	op := &status.Operation{Kind: status.Periodic, Folders: []string{"docs"}, StartedAt: startedAt}
+	assert.Equal(t, "🔄 Scheduled sync running since "+startedAt.Format(time.RFC3339)+": 0/1 folders done", DescribeOperation(op))
+
+	op = &status.Operation{Kind: status.FolderSync, Folders: []string{"docs"}, Current: "docs", StartedAt: startedAt}
+	assert.Contains(t, DescribeOperation(op), "🔄 Sync of docs running since ")
+	assert.Contains(t, DescribeOperation(op), ", syncing docs")
+}
@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/spf13/cobra"
+)
+
+// CreateStorageCommands returns the commands that manage the storage bucket
+func CreateStorageCommands(cfg *config.Config, saveConfig func() error, openStorage func() (storage.Storage, error)) []*cobra.Command {
+	// Storage lifecycle command
+	lifecycleCmd := &cobra.Command{
+		Use:   "storage-lifecycle <folder-id>",
+		Short: "Apply a lifecycle policy to old versions of a folder's files",
+		Long: `Add a lifecycle rule to the bucket that moves replaced versions of a folder's files to a
+cheaper storage class and optionally deletes them later. Running the command again for
+the same folder replaces its rule. The bucket must have versioning enabled for old
+versions to be kept.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			folder := findSyncFolder(cfg, args[0])
+			if folder == nil {
+				return i18n.Errorf("folder with ID %s not found", args[0])
+			}
+
+			transitionDays, _ := cmd.Flags().GetInt("transition-days")
+			transitionClass, _ := cmd.Flags().GetString("transition-class")
+			expireDays, _ := cmd.Flags().GetInt("expire-days")
+
+			rule := storage.LifecycleRule{
+				ID:              "sync-manager-" + folder.ID,
+				Prefix:          folder.KeyPrefix() + "/",
+				TransitionDays:  transitionDays,
+				TransitionClass: strings.ToUpper(strings.TrimSpace(transitionClass)),
+				ExpireDays:      expireDays,
+			}
+			target := cfg.FolderTarget(folder)
+			if target == nil {
+				return i18n.Errorf("folder %s uses unknown storage target %s", folder.ID, folder.Target)
+			}
+			if err := rule.Validate(storage.StorageProvider(target.Type)); err != nil {
+				return err
+			}
+
+			store, err := openStorage()
+			if err != nil {
+				return i18n.Errorf("failed to open storage: %w", err)
+			}
+
+			manager, ok := store.(storage.LifecycleManager)
+			if !ok {
+				return i18n.Errorf("%s storage does not support lifecycle policies", target.Type)
+			}
+			if err := manager.ApplyLifecycle(context.Background(), rule); errors.Is(err, storage.ErrLifecycleUnsupported) {
+				return i18n.Errorf("%s storage does not support lifecycle policies", target.Type)
+			} else if err != nil {
+				return i18n.Errorf("failed to apply lifecycle policy: %w", err)
+			}
+
+			i18n.Printf("Lifecycle policy applied to %s:\n", rule.Prefix)
+			if rule.TransitionDays > 0 {
+				i18n.Printf("  Old versions move to %s after %s\n", rule.TransitionClass, pluralize(rule.TransitionDays, "day"))
+			}
+			if rule.ExpireDays > 0 {
+				i18n.Printf("  Old versions are deleted after %s\n", pluralize(rule.ExpireDays, "day"))
+			}
+			return nil
+		},
+	}
+
+	lifecycleCmd.Flags().Int("transition-days", 0, "Days after a version is replaced before it moves to --transition-class")
+	lifecycleCmd.Flags().String("transition-class", "", "Storage class old versions move to (e.g. GLACIER_IR, DEEP_ARCHIVE, COLDLINE)")
+	lifecycleCmd.Flags().Int("expire-days", 0, "Days after a version is replaced before it is deleted")
+
+	// Storage plugins command
+	pluginsCmd := &cobra.Command{
+		Use:   "storage-plugins",
+		Short: "List the installed storage plugins",
+		Long: `List the storage plugins found in the plugins directory. A plugin is an executable named
+sync-manager-storage-<type>; targets of that type are served by it, with the settings of
+their plugin block (config set --target <name> storage.plugin.<setting> <value>).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := cfg.Plugins.Directory()
+			if err != nil {
+				return err
+			}
+			names, err := cfg.Plugins.List()
+			if err != nil {
+				return err
+			}
+			if len(names) == 0 {
+				i18n.Printf("No storage plugins in %s\n", dir)
+				return nil
+			}
+
+			i18n.Printf("Storage plugins in %s:\n", dir)
+			for _, name := range names {
+				var targets []string
+				for _, target := range cfg.Targets {
+					if target.Type == name {
+						targets = append(targets, target.Name)
+					}
+				}
+				if len(targets) > 0 {
+					i18n.Printf("  %s (targets: %s)\n", name, strings.Join(targets, ", "))
+				} else {
+					i18n.Printf("  %s\n", name)
+				}
+			}
+			return nil
+		},
+	}
+
+	// Storage login command
+	loginCmd := &cobra.Command{
+		Use:   "storage-login [target]",
+		Short: "Sign in to a OneDrive storage target",
+		Long: `Sign in to the OneDrive of a storage target, the first one unless another is named. The
+command shows a code to enter at a Microsoft page, in any browser; once signed in there the
+agent keeps the sign-in in the token file of the target and renews it on its own. Sign in
+again when it was revoked or unused for 90 days.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := cfg.DefaultTarget()
+			if len(args) > 0 {
+				if target = cfg.Target(args[0]); target == nil {
+					return i18n.Errorf("storage target %s not found (configured: %s)", args[0], strings.Join(cfg.TargetNames(), ", "))
+				}
+			}
+			if target.Type != config.TargetOneDrive {
+				return i18n.Errorf("storage target %s is %s, which needs no sign-in", target.Name, target.Type)
+			}
+			if err := target.Validate(); err != nil {
+				return err
+			}
+			return loginOneDrive(context.Background(), target)
+		},
+	}
+
+	// Storage drive command
+	driveCmd := &cobra.Command{
+		Use:   "storage-drive [target]",
+		Short: "Mirror a local storage target onto a removable drive",
+		Long: `Mark a local storage target, the first one unless another is named, as a removable drive
+and label the drive mounted at its root directory with a new volume UUID, so the target only
+syncs to that drive. While it is unplugged, syncs of its folders are skipped and uploads wait
+in the queue; once it is mounted again the folders are mirrored to it and a notification
+tells when the drive can be unplugged. With --uuid the drive is recognised by its filesystem
+UUID instead (as listed by lsblk -f on Linux) and nothing is written to it.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := cfg.DefaultTarget()
+			if len(args) > 0 {
+				if target = cfg.Target(args[0]); target == nil {
+					return i18n.Errorf("storage target %s not found (configured: %s)", args[0], strings.Join(cfg.TargetNames(), ", "))
+				}
+			}
+			if target.Type != config.TargetLocal {
+				return i18n.Errorf("storage target %s is %s, not a local directory", target.Name, target.Type)
+			}
+			if err := target.Validate(); err != nil {
+				return err
+			}
+
+			updated := target.Local
+			updated.Removable = true
+			if updated.VolumeUUID, _ = cmd.Flags().GetString("uuid"); updated.VolumeUUID != "" {
+				// O disco precisa estar montado para confirmar que o UUID é o dele
+				local, err := storage.NewLocalStorage(storage.NewLocalConfigFromCommon(&updated))
+				if err != nil {
+					return err
+				}
+				if err := local.Present(); err != nil {
+					return i18n.Errorf("failed to check the drive: %w", err)
+				}
+			} else {
+				id, err := storage.LabelVolume(updated.RootDir)
+				if err != nil {
+					return i18n.Errorf("failed to label the drive: %w", err)
+				}
+				updated.VolumeUUID = id
+			}
+
+			target.Local = updated
+			if err := saveConfig(); err != nil {
+				return i18n.Errorf("failed to save configuration: %w", err)
+			}
+			i18n.Printf("Storage target %s mirrors to volume %s at %s\n", target.Name, updated.VolumeUUID, updated.RootDir)
+			return nil
+		},
+	}
+
+	driveCmd.Flags().String("uuid", "", "Recognise the drive by this filesystem UUID instead of labelling it")
+
+	return []*cobra.Command{lifecycleCmd, pluginsCmd, loginCmd, driveCmd}
+}
+
+// loginOneDrive signs in to the OneDrive of a target with a device code
+func loginOneDrive(ctx context.Context, target *config.StorageTarget) error {
+	oneDriveCfg, err := storage.NewOneDriveConfigFromCommon(&target.OneDrive, target.Name)
+	if err != nil {
+		return err
+	}
+	err = storage.OneDriveLogin(ctx, oneDriveCfg, func(verificationURI, userCode string) {
+		i18n.Printf("To sign in to OneDrive, open %s and enter the code %s\n", verificationURI, userCode)
+	})
+	if err != nil {
+		return err
+	}
+	i18n.Printf("Signed in to OneDrive for storage target %s\n", target.Name)
+	return nil
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageLifecycleCommand(t *testing.T) {
+	store, err := storage.NewLocalStorage(&storage.LocalConfig{RootDir: t.TempDir()})
+	assert.NoError(t, err)
+
+	cfg := config.DefaultConfig()
+	cfg.Targets[0].Type = "s3"
+	cfg.SyncFolders = []config.SyncFolder{
+		{ID: "docs", Path: t.TempDir(), Enabled: true},
+	}
+
+	cmds := CreateStorageCommands(cfg, func() error { return nil }, func() (storage.Storage, error) { return store, nil })
+	assert.Equal(t, 4, len(cmds))
+	lifecycleCmd := cmds[0]
+	assert.Equal(t, "storage-lifecycle", lifecycleCmd.Name())
+
+	// Regras incompletas e pastas inexistentes são recusadas antes de abrir o armazenamento
+	assert.Error(t, lifecycleCmd.RunE(lifecycleCmd, []string{"docs"}))
+	assert.Error(t, lifecycleCmd.RunE(lifecycleCmd, []string{"missing"}))
+
+	// O armazenamento local não suporta políticas de ciclo de vida
+	assert.NoError(t, lifecycleCmd.Flags().Set("transition-days", "30"))
+	assert.NoError(t, lifecycleCmd.Flags().Set("transition-class", "glacier_ir"))
+	err = lifecycleCmd.RunE(lifecycleCmd, []string{"docs"})
+	assert.ErrorContains(t, err, "does not support lifecycle policies")
+}
+
+func TestStoragePluginsCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by their .exe suffix")
+	}
+	cfg := config.DefaultConfig()
+	cfg.Plugins.Dir = t.TempDir()
+	cmds := CreateStorageCommands(cfg, func() error { return nil }, func() (storage.Storage, error) { return nil, nil })
+	pluginsCmd := cmds[1]
+	assert.Equal(t, "storage-plugins", pluginsCmd.Name())
+
+	output := captureStdout(func() { assert.NoError(t, pluginsCmd.RunE(pluginsCmd, nil)) })
+	assert.Contains(t, output, "No storage plugins in "+cfg.Plugins.Dir)
+
+	// Cada plugin é listado com os destinos que atende
+	for _, name := range []string{"dropbox", "b2"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(cfg.Plugins.Dir, config.PluginPrefix+name), []byte("#!/bin/sh\n"), 0755))
+	}
+	cfg.Targets = append(cfg.Targets, config.StorageTarget{Name: "cloud", Type: "dropbox"})
+	output = captureStdout(func() { assert.NoError(t, pluginsCmd.RunE(pluginsCmd, nil)) })
+	assert.Contains(t, output, "  b2\n  dropbox (targets: cloud)\n")
+}
+
+func TestStorageLoginCommand(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Targets = append(cfg.Targets, config.StorageTarget{Name: "office", Type: config.TargetOneDrive})
+	loginCmd := CreateStorageCommands(cfg, func() error { return nil }, func() (storage.Storage, error) { return nil, nil })[2]
+	assert.Equal(t, "storage-login", loginCmd.Name())
+
+	// Só destinos OneDrive configurados pedem login
+	assert.ErrorContains(t, loginCmd.RunE(loginCmd, nil), "storage target default is minio, which needs no sign-in")
+	assert.ErrorContains(t, loginCmd.RunE(loginCmd, []string{"missing"}), "storage target missing not found")
+	assert.ErrorContains(t, loginCmd.RunE(loginCmd, []string{"office"}), "OneDrive client ID is required")
+}
+
+func TestStorageDriveCommand(t *testing.T) {
+	cfg := config.DefaultConfig()
+	missing := filepath.Join(t.TempDir(), "backup")
+	cfg.Targets = append(cfg.Targets, config.StorageTarget{Name: "usb", Type: config.TargetLocal, Local: config.LocalConfig{RootDir: missing}})
+	saved := 0
+	driveCmd := CreateStorageCommands(cfg, func() error { saved++; return nil }, func() (storage.Storage, error) { return nil, nil })[3]
+	assert.Equal(t, "storage-drive", driveCmd.Name())
+
+	// Só destinos locais com o disco montado podem ser marcados
+	assert.ErrorContains(t, driveCmd.RunE(driveCmd, nil), "storage target default is minio, not a local directory")
+	assert.ErrorContains(t, driveCmd.RunE(driveCmd, []string{"missing"}), "storage target missing not found")
+	assert.ErrorContains(t, driveCmd.RunE(driveCmd, []string{"usb"}), "drive is not mounted: "+missing+" does not exist")
+	assert.NoError(t, driveCmd.Flags().Set("uuid", "0f3a-55c1"))
+	assert.ErrorContains(t, driveCmd.RunE(driveCmd, []string{"usb"}), "failed to check the drive")
+	assert.Equal(t, 0, saved)
+	assert.Equal(t, config.LocalConfig{RootDir: missing}, cfg.Targets[1].Local)
+}
+
+func TestValidateStorageClassFlag(t *testing.T) {
+	target := &config.StorageTarget{Name: "default", Type: "gcs"}
+
+	class, err := validateStorageClass(target, " nearline ")
+	assert.NoError(t, err)
+	assert.Equal(t, "NEARLINE", class)
+
+	_, err = validateStorageClass(target, "GLACIER")
+	assert.Error(t, err)
+
+	class, err = validateStorageClass(target, "")
+	assert.NoError(t, err)
+	assert.Empty(t, class)
+}
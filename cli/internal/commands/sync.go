@@ -2,39 +2,122 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/martinshumberto/sync-manager/cli/internal/client"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/diskspace"
+	"github.com/martinshumberto/sync-manager/common/guard"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/status"
 	"github.com/spf13/cobra"
 )
 
 // CreateSyncCommands creates commands for sync operations
-func CreateSyncCommands(cfg *config.Config, agentClient *client.AgentClient) []*cobra.Command {
+func CreateSyncCommands(cfg *config.Config, agentClient *client.AgentClient, runService *services.SyncRunService) []*cobra.Command {
 	var cmds []*cobra.Command
 
 	// Sync now command
 	syncNowCmd := &cobra.Command{
 		Use:   "sync-now [folder_id]",
 		Short: "Trigger an immediate sync for one or all folders",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Ask the agent to sync one folder, or all of them, right away. Only one sync runs at a
+time: a request covered by the running sync joins it, any other runs once it ends.
+With --restart the running sync is cancelled and started over instead.
+
+The command shows the progress of the sync until it ends and its uploads are done, then
+prints the summary of each folder it synced and fails if any of them did not succeed.
+With --detach it returns as soon as the agent has the request.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if agentClient != nil {
-				// Check if agent is running
-				if err := agentClient.Health(); err != nil {
-					return fmt.Errorf("agent is not running: %w", err)
+			if err := requireAgent(agentClient); err != nil {
+				return err
+			}
+
+			folderID := ""
+			if len(args) == 1 {
+				folderID = args[0]
+			}
+			restart, _ := cmd.Flags().GetBool("restart")
+			detach, _ := cmd.Flags().GetBool("detach")
+
+			if snap, _ := agentClient.GetStatus(); snap != nil && snap.Operation != nil {
+				fmt.Println(DescribeOperation(snap.Operation))
+				if restart {
+					i18n.Println("Cancelling it to start over.")
+				} else {
+					i18n.Println("The request joins it or runs once it ends, use --restart to cancel it instead.")
 				}
+			}
 
-				// TODO: Implement sync-now through the agent API
-				fmt.Println("Sync initiated through agent")
+			// A requisição gravada pelo agente nunca é anterior a este instante
+			requested := time.Now()
+			if err := agentClient.TriggerSync(folderID, restart); err != nil {
+				return i18n.Errorf("failed to trigger sync: %w", err)
+			}
+			if detach {
+				i18n.Println("Sync requested, follow it with 'sync-manager status --watch'.")
 				return nil
 			}
+			return awaitSyncRequest(cmd.OutOrStdout(), cfg, agentClient, runService, folderID, requested)
+		},
+	}
+	syncNowCmd.Flags().Bool("restart", false, "Cancel the sync the agent is running and start over")
+	syncNowCmd.Flags().Bool("detach", false, "Return once the sync is requested instead of following it")
 
-			return fmt.Errorf("agent is not running, cannot trigger sync")
+	// Sync path command - sync a subtree of a folder
+	syncPathCmd := &cobra.Command{
+		Use:   "sync-path <folder-id> <relative-subdir>",
+		Short: "Rescan and reconcile one subdirectory of a folder",
+		Long: `Ask the agent to sync only a subdirectory of a folder: it walks that subtree for local
+changes, lists the remote files under it and reconciles them, leaving the rest of the folder
+for its next sync. Useful after restoring files into a directory or fixing its excludes,
+without walking the whole folder.
+
+Like sync-now, it follows the sync until it ends and its uploads are done, then prints its
+summary. With --detach it returns as soon as the agent has the request.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			folderID, relPath := args[0], filepath.ToSlash(filepath.Clean(args[1]))
+			folder := findSyncFolder(cfg, folderID)
+			if folder == nil {
+				return configError(i18n.Errorf("folder with ID %s not found", folderID))
+			}
+			if filepath.IsAbs(args[1]) || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") {
+				return configError(i18n.Errorf("%s is not a subdirectory of the folder, give a path relative to %s", args[1], folder.Path))
+			}
+
+			if err := requireAgent(agentClient); err != nil {
+				return err
+			}
+
+			restart, _ := cmd.Flags().GetBool("restart")
+			detach, _ := cmd.Flags().GetBool("detach")
+
+			requested := time.Now()
+			if err := agentClient.TriggerPathSync(folderID, relPath, restart); err != nil {
+				return i18n.Errorf("failed to trigger sync: %w", err)
+			}
+			if detach {
+				i18n.Println("Sync requested, follow it with 'sync-manager status --watch'.")
+				return nil
+			}
+			return awaitSyncRequest(cmd.OutOrStdout(), cfg, agentClient, runService, folderID, requested)
 		},
 	}
+	syncPathCmd.Flags().Bool("restart", false, "Cancel the sync the agent is running and start over")
+	syncPathCmd.Flags().Bool("detach", false, "Return once the sync is requested instead of following it")
 
-	cmds = append(cmds, syncNowCmd)
+	cmds = append(cmds, syncNowCmd, syncPathCmd)
 
 	// Sync command - force immediate sync
 	syncCmd := &cobra.Command{
@@ -43,33 +126,46 @@ func CreateSyncCommands(cfg *config.Config, agentClient *client.AgentClient) []*
 		Long:  `Force immediate synchronization of all monitored folders.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(cfg.SyncFolders) == 0 {
-				fmt.Println("No folders configured for synchronization.")
+				i18n.Println("No folders configured for synchronization.")
 				return nil
 			}
 
-			fmt.Println("Initiating synchronization for all folders...")
+			if err := requireAgent(agentClient); err != nil {
+				return err
+			}
 
-			// In a real implementation, we would:
-			// 1. Connect to the agent service
-			// 2. Trigger a sync operation
-			// 3. Wait for it to complete or provide progress updates
+			force, _ := cmd.Flags().GetBool("force")
+
+			i18n.Println("Initiating synchronization for all folders...")
 
-			// Simulate sync process
 			for i, folder := range cfg.SyncFolders {
 				if !folder.Enabled {
-					fmt.Printf("Skipping disabled folder: %s\n", folder.Path)
+					i18n.Printf("Skipping disabled folder: %s\n", folder.Path)
 					continue
 				}
 
-				fmt.Printf("Synchronizing folder %d/%d: %s\n", i+1, len(cfg.SyncFolders), folder.Path)
-				// Simulate some processing time
-				time.Sleep(500 * time.Millisecond)
+				if force {
+					if err := forceDeletions(folder.ID); err != nil {
+						return err
+					}
+				}
+
+				if err := agentClient.TriggerSync(folder.ID, false); err != nil {
+					return i18n.Errorf("failed to trigger sync for %s: %w", folder.Path, err)
+				}
+				i18n.Printf("Synchronizing folder %d/%d: %s\n", i+1, len(cfg.SyncFolders), folder.Path)
+			}
+
+			if err := followAgentTransfers(); err != nil {
+				return err
 			}
 
-			fmt.Println("Synchronization complete.")
+			i18n.Println("Synchronization complete.")
+			printSyncWarnings()
 			return nil
 		},
 	}
+	syncCmd.Flags().Bool("force", false, "Allow remote deletions held back by the deletion guard")
 
 	// Sync-folder command - sync a specific folder
 	syncFolderCmd := &cobra.Command{
@@ -90,27 +186,38 @@ func CreateSyncCommands(cfg *config.Config, agentClient *client.AgentClient) []*
 			}
 
 			if targetFolder == nil {
-				return fmt.Errorf("folder not found in sync configuration: %s", targetPath)
+				return i18n.Errorf("folder not found in sync configuration: %s", targetPath)
 			}
 
 			if !targetFolder.Enabled {
-				return fmt.Errorf("folder is disabled: %s", targetPath)
+				return i18n.Errorf("folder is disabled: %s", targetPath)
+			}
+
+			if err := requireAgent(agentClient); err != nil {
+				return err
 			}
 
-			fmt.Printf("Synchronizing folder: %s\n", targetPath)
+			if force, _ := cmd.Flags().GetBool("force"); force {
+				if err := forceDeletions(targetFolder.ID); err != nil {
+					return err
+				}
+			}
 
-			// In a real implementation, we would:
-			// 1. Connect to the agent service
-			// 2. Trigger a sync operation for this specific folder
-			// 3. Wait for it to complete or provide progress updates
+			if err := agentClient.TriggerSync(targetFolder.ID, false); err != nil {
+				return i18n.Errorf("failed to trigger sync: %w", err)
+			}
+			i18n.Printf("Synchronizing folder: %s\n", targetPath)
 
-			// Simulate sync process
-			time.Sleep(1 * time.Second)
+			if err := followAgentTransfers(); err != nil {
+				return err
+			}
 
-			fmt.Println("Folder synchronization complete.")
+			i18n.Println("Folder synchronization complete.")
+			printSyncWarnings()
 			return nil
 		},
 	}
+	syncFolderCmd.Flags().Bool("force", false, "Allow remote deletions held back by the deletion guard")
 
 	// Pause command
 	pauseCmd := &cobra.Command{
@@ -118,8 +225,8 @@ func CreateSyncCommands(cfg *config.Config, agentClient *client.AgentClient) []*
 		Short: "Pause synchronization",
 		Long:  `Pause the synchronization process temporarily.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Println("Synchronization paused.")
-			fmt.Println("Use 'sync-manager resume' to resume synchronization.")
+			i18n.Println("Synchronization paused.")
+			i18n.Println("Use 'sync-manager resume' to resume synchronization.")
 			return nil
 		},
 	}
@@ -130,7 +237,7 @@ func CreateSyncCommands(cfg *config.Config, agentClient *client.AgentClient) []*
 		Short: "Resume synchronization",
 		Long:  `Resume previously paused synchronization.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Println("Synchronization resumed.")
+			i18n.Println("Synchronization resumed.")
 			return nil
 		},
 	}
@@ -139,3 +246,225 @@ func CreateSyncCommands(cfg *config.Config, agentClient *client.AgentClient) []*
 
 	return cmds
 }
+
+// requireAgent fails unless the agent is running
+func requireAgent(agentClient *client.AgentClient) error {
+	if agentClient == nil {
+		return i18n.Errorf("agent is not running, start it with 'sync-manager start'")
+	}
+	if err := agentClient.Health(); err != nil {
+		return i18n.Errorf("agent is not running: %w", err)
+	}
+	return nil
+}
+
+// TransferRestriction returns how the running agent's power policy limits transfers,
+// or an empty string when it does not
+func TransferRestriction(heartbeatPath string) string {
+	hb, err := heartbeat.Read(heartbeatPath)
+	if err != nil || hb == nil || !hb.Online() {
+		return ""
+	}
+	return hb.Restriction
+}
+
+// DeletionWarnings describes the remote deletions the agent is holding back, one line per folder
+func DeletionWarnings(guardPath string) []string {
+	state, err := guard.Read(guardPath)
+	if err != nil {
+		return nil
+	}
+
+	folderIDs := make([]string, 0, len(state.Blocks))
+	for folderID := range state.Blocks {
+		folderIDs = append(folderIDs, folderID)
+	}
+	sort.Strings(folderIDs)
+
+	var warnings []string
+	for _, folderID := range folderIDs {
+		block := state.Blocks[folderID]
+		warning := i18n.Sprintf("Deletion guard: %s would remove %d of %d remote files (%d%%), held back since %s; check the folder and run 'sync-manager sync --force' to allow",
+			block.FolderID, block.Deletions, block.Total, block.Percent(), block.DetectedAt.Local().Format(time.RFC3339))
+		if block.Approved() {
+			warning = i18n.Sprintf("Deletion guard: removal of %d remote files in %s allowed, applied on the next sync", block.Deletions, block.FolderID)
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// DiskSpaceWarnings describes the folders whose remote changes wait for disk space, one line per folder
+func DiskSpaceWarnings(spacePath string) []string {
+	state, err := diskspace.Read(spacePath)
+	if err != nil {
+		return nil
+	}
+
+	folderIDs := make([]string, 0, len(state.Shortages))
+	for folderID := range state.Shortages {
+		folderIDs = append(folderIDs, folderID)
+	}
+	sort.Strings(folderIDs)
+
+	var warnings []string
+	for _, folderID := range folderIDs {
+		shortage := state.Shortages[folderID]
+		warnings = append(warnings, i18n.Sprintf("Low disk space: %s needs %s for remote changes but %s has only %s free, waiting since %s; free up space and the downloads resume automatically",
+			shortage.FolderID, formatSize(int64(shortage.Needed+diskspace.Headroom)), shortage.Path, formatSize(int64(shortage.Available)), shortage.DetectedAt.Local().Format(time.RFC3339)))
+	}
+	return warnings
+}
+
+// printSyncWarnings prints the remote deletions the agent is holding back and the downloads waiting for disk space
+func printSyncWarnings() {
+	var warnings []string
+	if path, err := guard.DefaultPath(); err == nil {
+		warnings = append(warnings, DeletionWarnings(path)...)
+	}
+	if path, err := diskspace.DefaultPath(); err == nil {
+		warnings = append(warnings, DiskSpaceWarnings(path)...)
+	}
+	for _, warning := range warnings {
+		fmt.Printf("⚠ %s\n", warning)
+	}
+}
+
+// forceDeletions allows the remote deletions held back for a folder on its next sync
+func forceDeletions(folderID string) error {
+	path, err := guard.DefaultPath()
+	if err != nil {
+		return err
+	}
+	block, err := guard.Approve(path, folderID, time.Now())
+	if err != nil {
+		return i18n.Errorf("failed to allow deletions: %w", err)
+	}
+	if block != nil {
+		i18n.Printf("Allowing removal of %s in %s\n", pluralize(block.Deletions, "remote file"), folderID)
+	}
+	return nil
+}
+
+// awaitSyncRequest follows the sync of folderID, or of every folder when it is empty, requested
+// at requested until it ends, prints its summary and fails unless all of it succeeded
+func awaitSyncRequest(out io.Writer, cfg *config.Config, agentClient *client.AgentClient, runService *services.SyncRunService, folderID string, requested time.Time) error {
+	progressPath, err := progress.DefaultPath()
+	if err != nil {
+		return err
+	}
+	request, snap, err := followSyncRequest(out, agentClient.GetStatus, agentSnapshot(progressPath), requested)
+	if err != nil {
+		return err
+	}
+	if request.Error != "" {
+		return i18n.Errorf("sync failed: %s", request.Error)
+	}
+
+	var failed []string
+	if runService != nil {
+		if failed, err = printRequestRuns(out, runService, cfg.DeviceID, folderID, requested); err != nil {
+			return err
+		}
+	}
+	printSyncWarnings()
+	if len(failed) > 0 {
+		return i18n.Errorf("the sync of %s did not succeed", strings.Join(failed, ", "))
+	}
+	if snap != nil && snap.FilesFailed > 0 {
+		return i18n.Errorf("%s failed to transfer, see the agent logs", pluralize(snap.FilesFailed, "file"))
+	}
+	i18n.Fprintln(out, "Sync complete.")
+	return nil
+}
+
+// followSyncRequest shows the sync the agent runs and its transfers until the sync requested
+// at requested has ended and no transfers are left. It returns the outcome the agent recorded
+// for the request and the last transfer progress drawn. agentStatus returns nil once the agent
+// stops publishing its status.
+func followSyncRequest(out io.Writer, agentStatus func() (*status.Snapshot, error), snapshot func() (*progress.Snapshot, error), requested time.Time) (*status.Request, *progress.Snapshot, error) {
+	view := &liveView{out: out}
+	ticker := time.NewTicker(progressRefresh)
+	defer ticker.Stop()
+
+	var request *status.Request
+	for {
+		state, err := agentStatus()
+		if err != nil {
+			return nil, nil, err
+		}
+		if state == nil {
+			return nil, nil, i18n.Errorf("the agent stopped before the sync ended")
+		}
+		snap, err := snapshot()
+		if err != nil {
+			return nil, nil, err
+		}
+		// Requests merged with a later one end with it
+		if request == nil && state.Request != nil && !state.Request.RequestedAt.Before(requested) {
+			request = state.Request
+		}
+
+		var text string
+		if state.Operation != nil {
+			text = DescribeOperation(state.Operation) + "\n"
+		} else if request == nil {
+			text = i18n.T("Waiting for the agent to start the sync...") + "\n"
+		}
+		if snap != nil {
+			text += renderProgress(*snap)
+		}
+		view.draw(text)
+
+		if request != nil && (snap == nil || snap.Idle()) {
+			return request, snap, nil
+		}
+		<-ticker.C
+	}
+}
+
+// printRequestRuns prints the summary of the runs of a requested sync, those of folderID, or
+// of every folder when it is empty, that ended since requested. A run that started earlier
+// is one the request joined; periodic runs are not part of it. It returns the folders whose
+// run did not succeed.
+func printRequestRuns(out io.Writer, runService *services.SyncRunService, deviceID, folderID string, requested time.Time) ([]string, error) {
+	runs, err := runService.LastRuns(deviceID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	printed := 0
+	for _, run := range runs {
+		if run.FinishedAt.Before(requested) || run.Kind == status.Periodic {
+			continue
+		}
+		fmt.Fprintln(out)
+		printRun(out, run)
+		printed++
+		if run.Status != models.SyncRunSucceeded {
+			failed = append(failed, run.FolderID)
+		}
+	}
+	if printed == 0 {
+		i18n.Fprintln(out, "The agent recorded no sync of the requested folders.")
+	}
+	return failed, nil
+}
+
+// followAgentTransfers shows the agent's transfer progress until no transfers are left
+func followAgentTransfers() error {
+	path, err := progress.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	snap, err := watchProgress(os.Stdout, agentSnapshot(path), nil, true)
+	if err != nil {
+		return err
+	}
+	if snap != nil && snap.FilesFailed > 0 {
+		return i18n.Errorf("%s failed to transfer, see the agent logs", pluralize(snap.FilesFailed, "file"))
+	}
+	return nil
+}
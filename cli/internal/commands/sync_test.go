@@ -4,9 +4,20 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/diskspace"
+	"github.com/martinshumberto/sync-manager/common/guard"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/status"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 )
@@ -16,10 +27,10 @@ func TestCreateSyncCommands(t *testing.T) {
 	cfg := config.DefaultConfig()
 
 	// Criar os comandos
-	cmds := CreateSyncCommands(cfg)
+	cmds := CreateSyncCommands(cfg, nil, nil)
 
-	// Verificar se criou pelo menos 4 comandos
-	assert.Equal(t, 4, len(cmds))
+	// Verificar se criou pelo menos 6 comandos
+	assert.Equal(t, 6, len(cmds))
 
 	// Verificar os nomes dos comandos
 	cmdNames := make(map[string]bool)
@@ -27,6 +38,8 @@ func TestCreateSyncCommands(t *testing.T) {
 		cmdNames[c.Use] = true
 	}
 
+	assert.True(t, cmdNames["sync-now [folder_id]"])
+	assert.True(t, cmdNames["sync-path <folder-id> <relative-subdir>"])
 	assert.True(t, cmdNames["sync"])
 	assert.True(t, cmdNames["sync-folder <path>"])
 	assert.True(t, cmdNames["pause"])
@@ -46,8 +59,8 @@ func TestSyncCommand(t *testing.T) {
 		},
 	}
 
-	// Criar os comandos
-	cmds := CreateSyncCommands(cfg)
+	// Criar os comandos sem agente em execução
+	cmds := CreateSyncCommands(cfg, nil, nil)
 
 	// Encontrar o comando sync
 	var syncCmd *cobra.Command
@@ -60,26 +73,10 @@ func TestSyncCommand(t *testing.T) {
 
 	assert.NotNil(t, syncCmd)
 
-	// Redirecionando saída para captura
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	// Executar comando
+	// Sem o agente não há o que sincronizar nem progresso para mostrar
 	err := syncCmd.RunE(syncCmd, []string{})
-	assert.NoError(t, err)
-
-	w.Close()
-	os.Stdout = old
-
-	var buf bytes.Buffer
-	io.Copy(&buf, r)
-	output := buf.String()
-
-	// Verificar mensagens do comando
-	assert.Contains(t, output, "Initiating synchronization")
-	assert.Contains(t, output, "/test/path")
-	assert.Contains(t, output, "Synchronization complete")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "agent is not running")
 }
 
 func TestSyncFolderCommand(t *testing.T) {
@@ -95,8 +92,8 @@ func TestSyncFolderCommand(t *testing.T) {
 		},
 	}
 
-	// Criar os comandos
-	cmds := CreateSyncCommands(cfg)
+	// Criar os comandos sem agente em execução
+	cmds := CreateSyncCommands(cfg, nil, nil)
 
 	// Encontrar o comando sync-folder
 	var syncFolderCmd *cobra.Command
@@ -109,26 +106,15 @@ func TestSyncFolderCommand(t *testing.T) {
 
 	assert.NotNil(t, syncFolderCmd)
 
-	// Redirecionando saída para captura
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	// Executar comando
-	err := syncFolderCmd.RunE(syncFolderCmd, []string{"/test/path"})
-	assert.NoError(t, err)
-
-	w.Close()
-	os.Stdout = old
-
-	var buf bytes.Buffer
-	io.Copy(&buf, r)
-	output := buf.String()
+	// Pasta desconhecida
+	err := syncFolderCmd.RunE(syncFolderCmd, []string{"/other/path"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "folder not found")
 
-	// Verificar mensagens do comando
-	assert.Contains(t, output, "Synchronizing folder")
-	assert.Contains(t, output, "/test/path")
-	assert.Contains(t, output, "Folder synchronization complete")
+	// Pasta conhecida, mas sem o agente
+	err = syncFolderCmd.RunE(syncFolderCmd, []string{"/test/path"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "agent is not running")
 }
 
 func TestPauseCommand(t *testing.T) {
@@ -136,7 +122,7 @@ func TestPauseCommand(t *testing.T) {
 	cfg := config.DefaultConfig()
 
 	// Criar os comandos
-	cmds := CreateSyncCommands(cfg)
+	cmds := CreateSyncCommands(cfg, nil, nil)
 
 	// Encontrar o comando pause
 	var pauseCmd *cobra.Command
@@ -174,7 +160,7 @@ func TestResumeCommand(t *testing.T) {
 	cfg := config.DefaultConfig()
 
 	// Criar os comandos
-	cmds := CreateSyncCommands(cfg)
+	cmds := CreateSyncCommands(cfg, nil, nil)
 
 	// Encontrar o comando resume
 	var resumeCmd *cobra.Command
@@ -206,3 +192,126 @@ func TestResumeCommand(t *testing.T) {
 	// Verificar mensagens do comando
 	assert.Contains(t, output, "Synchronization resumed")
 }
+
+func TestTransferRestriction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heartbeat.json")
+
+	// Sem agente não há limitação
+	assert.Equal(t, "", TransferRestriction(path))
+
+	assert.NoError(t, heartbeat.Write(path, &heartbeat.Heartbeat{Time: time.Now(), Restriction: "paused (on battery)"}))
+	assert.Equal(t, "paused (on battery)", TransferRestriction(path))
+
+	// Um heartbeat antigo não reflete o estado atual
+	assert.NoError(t, heartbeat.Write(path, &heartbeat.Heartbeat{Time: time.Now().Add(-time.Hour), Restriction: "paused (on battery)"}))
+	assert.Equal(t, "", TransferRestriction(path))
+}
+
+func TestDeletionWarnings(t *testing.T) {
+	guardPath := filepath.Join(t.TempDir(), "deletion-guard.json")
+
+	// Sem arquivo de proteção não há avisos
+	assert.Empty(t, DeletionWarnings(guardPath))
+
+	detected := time.Now()
+	assert.NoError(t, guard.Record(guardPath, guard.Block{FolderID: "photos", Deletions: 40, Total: 100, DetectedAt: detected}))
+	assert.NoError(t, guard.Record(guardPath, guard.Block{FolderID: "docs", Deletions: 150, Total: 200, DetectedAt: detected}))
+
+	warnings := DeletionWarnings(guardPath)
+	assert.Len(t, warnings, 2)
+	assert.Contains(t, warnings[0], "docs would remove 150 of 200 remote files (75%)")
+	assert.Contains(t, warnings[0], "sync-manager sync --force")
+	assert.Contains(t, warnings[1], "photos would remove 40 of 100 remote files (40%)")
+
+	// Uma remoção liberada é mostrada como pendente para a próxima sincronização
+	_, err := guard.Approve(guardPath, "docs", detected.Add(time.Minute))
+	assert.NoError(t, err)
+	warnings = DeletionWarnings(guardPath)
+	assert.Contains(t, warnings[0], "removal of 150 remote files in docs allowed")
+}
+
+func TestDiskSpaceWarnings(t *testing.T) {
+	spacePath := filepath.Join(t.TempDir(), "disk-space.json")
+	assert.Empty(t, DiskSpaceWarnings(spacePath))
+
+	assert.NoError(t, diskspace.Record(spacePath, diskspace.Shortage{FolderID: "videos", Path: "/data/videos", Needed: 4 << 30, Available: 1 << 30, DetectedAt: time.Now()}))
+
+	// O aviso mostra o espaço exigido, incluindo a margem mantida livre
+	warnings := DiskSpaceWarnings(spacePath)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "videos needs 4.1 GiB")
+	assert.Contains(t, warnings[0], "/data/videos has only 1.0 GiB free")
+}
+
+func TestFollowSyncRequest(t *testing.T) {
+	requested := time.Now()
+	snapshots := []status.Snapshot{
+		// O agente ainda não pegou a requisição; a anterior terminou antes dela
+		{Request: &status.Request{RequestedAt: requested.Add(-time.Hour)}},
+		{Operation: &status.Operation{Kind: status.FolderSync, Folders: []string{"docs"}, StartedAt: requested}},
+		{Request: &status.Request{RequestedAt: requested.Add(time.Millisecond), FinishedAt: requested.Add(time.Second)}},
+	}
+	calls := 0
+	agentStatus := func() (*status.Snapshot, error) {
+		snap := snapshots[min(calls, len(snapshots)-1)]
+		calls++
+		return &snap, nil
+	}
+	// Os envios da sincronização terminam depois dela
+	transfers := 0
+	snapshot := func() (*progress.Snapshot, error) {
+		transfers++
+		if transfers <= 3 {
+			return &progress.Snapshot{FilesTotal: 2, FilesDone: 1, UpdatedAt: time.Now()}, nil
+		}
+		return &progress.Snapshot{FilesTotal: 2, FilesDone: 2, UpdatedAt: time.Now()}, nil
+	}
+
+	var out bytes.Buffer
+	request, snap, err := followSyncRequest(&out, agentStatus, snapshot, requested)
+	assert.NoError(t, err)
+	assert.Equal(t, requested.Add(time.Millisecond), request.RequestedAt)
+	assert.Equal(t, 2, snap.FilesDone)
+	assert.Equal(t, 4, calls)
+	assert.Contains(t, out.String(), "Waiting for the agent to start the sync...")
+	assert.Contains(t, out.String(), "Sync of docs running since")
+
+	// Um agente que para antes do fim é reportado
+	_, _, err = followSyncRequest(&out, func() (*status.Snapshot, error) { return nil, nil }, snapshot, requested)
+	assert.Error(t, err)
+}
+
+func TestPrintRequestRuns(t *testing.T) {
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	defer dbManager.Close()
+	assert.NoError(t, dbManager.InitSchema())
+
+	// A sincronização de docs começou antes da requisição, que se juntou a ela
+	requested := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	for _, run := range []models.SyncRun{
+		{FolderID: "docs", DeviceID: "laptop", Kind: status.FullSync, Status: models.SyncRunSucceeded, StartedAt: requested.Add(-time.Minute), FinishedAt: requested.Add(time.Minute), FilesUploaded: 3},
+		{FolderID: "photos", DeviceID: "laptop", Kind: status.FullSync, Status: models.SyncRunFailed, StartedAt: requested, FinishedAt: requested.Add(time.Second), Error: "failed to walk directory"},
+		{FolderID: "music", DeviceID: "laptop", Kind: status.FullSync, Status: models.SyncRunSucceeded, StartedAt: requested.Add(-time.Hour), FinishedAt: requested.Add(-time.Minute)},
+		{FolderID: "videos", DeviceID: "laptop", Kind: status.Periodic, Status: models.SyncRunFailed, StartedAt: requested, FinishedAt: requested.Add(time.Minute)},
+	} {
+		assert.NoError(t, dbManager.GetDB().Create(&run).Error)
+	}
+	runService := services.NewSyncRunService(repositories.NewSyncRunRepository(dbManager.GetDB()))
+
+	// Só as execuções terminadas depois da requisição fazem parte dela
+	var out bytes.Buffer
+	failed, err := printRequestRuns(&out, runService, "laptop", "", requested)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"photos"}, failed)
+	assert.Contains(t, out.String(), "Folder docs: full sync succeeded")
+	assert.Contains(t, out.String(), "Folder photos: full sync failed")
+	assert.NotContains(t, out.String(), "music")
+	assert.NotContains(t, out.String(), "videos")
+
+	out.Reset()
+	failed, err = printRequestRuns(&out, runService, "laptop", "music", requested)
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+	assert.Contains(t, out.String(), "The agent recorded no sync of the requested folders.")
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// CreateTokenCommand returns the command managing the API tokens of the active user
+func CreateTokenCommand(tokenService *services.TokenService, userID uint) *cobra.Command {
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage API tokens",
+		Long: `API tokens let scripts and CI jobs authenticate as the active user. Only a hash of
+each token is stored, so a token is shown once, when it is created.`,
+	}
+
+	tokenCreateCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an API token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString("name")
+			expires, _ := cmd.Flags().GetString("expires")
+			lifetime, err := parseTokenLifetime(expires)
+			if err != nil {
+				return err
+			}
+
+			token, err := tokenService.CreateToken(userID, name, lifetime)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			i18n.Fprintf(out, "Token %s created (ID: %d), expires %s:\n\n", token.Name, token.ID, token.ExpiresAt.Format("2006-01-02"))
+			fmt.Fprintf(out, "  %s\n\n", token.Token)
+			i18n.Fprintln(out, "Copy it now, it is not shown again.")
+			return nil
+		},
+	}
+	tokenCreateCmd.Flags().String("name", "", "Name of the token, such as the job using it")
+	tokenCreateCmd.Flags().String("expires", "90d", "How long the token is valid, in days (90d) or as a duration (12h)")
+	tokenCreateCmd.MarkFlagRequired("name")
+
+	tokenListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List API tokens",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tokens, err := tokenService.ListTokens(userID)
+			if err != nil {
+				return err
+			}
+			if len(tokens) == 0 {
+				i18n.Fprintln(cmd.OutOrStdout(), "No API tokens.")
+				return nil
+			}
+
+			now := time.Now()
+			table := tablewriter.NewWriter(cmd.OutOrStdout())
+			table.SetHeader(i18n.Strings("ID", "Name", "Created", "Expires", "Last Used", "Status"))
+			for _, token := range tokens {
+				table.Append([]string{
+					strconv.FormatUint(uint64(token.ID), 10),
+					token.Name,
+					token.CreatedAt.Format("2006-01-02"),
+					token.ExpiresAt.Format("2006-01-02"),
+					formatLastSeen(token.LastUsed),
+					i18n.T(services.TokenState(token, now)),
+				})
+			}
+			table.Render()
+			return nil
+		},
+	}
+
+	tokenRevokeCmd := &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke an API token",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 32)
+			if err != nil {
+				return i18n.Errorf("invalid token ID %q", args[0])
+			}
+			if err := tokenService.RevokeToken(userID, uint(id)); err != nil {
+				return err
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "Token %d revoked.\n", id)
+			return nil
+		},
+	}
+
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	return tokenCmd
+}
+
+// parseTokenLifetime parses a token lifetime given in days, such as 90d, or as a Go duration
+func parseTokenLifetime(value string) (time.Duration, error) {
+	var lifetime time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, i18n.Errorf("invalid token lifetime %q", value)
+		}
+		lifetime = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if lifetime, err = time.ParseDuration(value); err != nil {
+			return 0, i18n.Errorf("invalid token lifetime %q: use days (90d) or a duration (12h)", value)
+		}
+	}
+	if lifetime <= 0 {
+		return 0, i18n.Errorf("token lifetime must be positive")
+	}
+	return lifetime, nil
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/tokens"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestTokenService cria um serviço de tokens com um banco de dados temporário
+func newTestTokenService(t *testing.T) (*services.TokenService, *gorm.DB) {
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbManager.Close() })
+	assert.NoError(t, dbManager.InitSchema())
+
+	return services.NewTokenService(repositories.NewTokenRepository(dbManager.GetDB())), dbManager.GetDB()
+}
+
+func TestTokenCommands(t *testing.T) {
+	tokenService, database := newTestTokenService(t)
+	run := func(args ...string) (string, error) {
+		tokenCmd := CreateTokenCommand(tokenService, 1)
+		var out bytes.Buffer
+		tokenCmd.SetOut(&out)
+		tokenCmd.SetArgs(args)
+		err := tokenCmd.Execute()
+		return out.String(), err
+	}
+
+	output, err := run("create", "--name", "ci", "--expires", "30d")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Token ci created (ID: 1)")
+	var plain string
+	for _, field := range strings.Fields(output) {
+		if strings.HasPrefix(field, tokens.Prefix) {
+			plain = field
+		}
+	}
+
+	// Só o hash é guardado, e o token autentica o usuário que o criou
+	list, err := tokenService.ListTokens(1)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, models.HashToken(plain), list[0].Token)
+		assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), list[0].ExpiresAt, time.Minute)
+	}
+	token, err := tokens.Authenticate(database, plain, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), token.UserID)
+	_, err = tokens.Authenticate(database, tokens.Prefix+"unknown", time.Now())
+	assert.ErrorIs(t, err, tokens.ErrInvalid)
+
+	output, err = run("list")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "ci")
+	assert.Contains(t, output, "active")
+	assert.NotContains(t, output, plain)
+
+	// Outro usuário não revoga o token; o dono sim, e ele deixa de autenticar
+	assert.ErrorIs(t, tokenService.RevokeToken(2, 1), services.ErrTokenNotFound)
+	_, err = run("revoke", "1")
+	assert.NoError(t, err)
+	_, err = tokens.Authenticate(database, plain, time.Now())
+	assert.ErrorIs(t, err, tokens.ErrInvalid)
+	output, err = run("list")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "revoked")
+
+	_, err = run("create", "--name", "ci", "--expires", "soon")
+	assert.Error(t, err)
+	_, err = run("create", "--name", "ci", "--expires", "-1d")
+	assert.Error(t, err)
+}
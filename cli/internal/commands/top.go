@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/spf13/cobra"
+)
+
+// topRefresh is how often 'top' redraws, the rate the agent publishes its progress at
+const topRefresh = time.Second
+
+// CreateTopCommand returns the command showing which files, folders and extensions take the
+// most bytes and time of the agent's transfers, from the progress it publishes at progressPath
+func CreateTopCommand(progressPath string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Show the heaviest transfers live",
+		Long: `Shows, refreshed every second, the files of the current batch of transfers that moved
+the most bytes and took the longest, including those still transferring, the folders moving
+the most bytes, and the bytes and time of each file extension since the agent started.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshot := agentSnapshot(progressPath)
+			once, _ := cmd.Flags().GetBool("once")
+			if once {
+				snap, err := snapshot()
+				if err != nil {
+					return err
+				}
+				if snap == nil {
+					i18n.Fprintln(cmd.OutOrStdout(), "The agent has not reported any transfers yet.")
+					return nil
+				}
+				fmt.Fprint(cmd.OutOrStdout(), renderTop(*snap))
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			view := &liveView{out: cmd.OutOrStdout()}
+			ticker := time.NewTicker(topRefresh)
+			defer ticker.Stop()
+			for {
+				snap, err := snapshot()
+				if err != nil {
+					return err
+				}
+				if snap == nil {
+					view.draw(i18n.T("The agent has not reported any transfers yet.") + "\n")
+				} else {
+					view.draw(renderTop(*snap))
+				}
+
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		},
+	}
+
+	cmd.Flags().Bool("once", false, "Print the current view once instead of refreshing it")
+	return cmd
+}
+
+// renderTop formats the heaviest consumers of a progress snapshot
+func renderTop(snap progress.Snapshot) string {
+	var b strings.Builder
+
+	i18n.Fprintf(&b, "Batch started %s: %d/%d files, %s/%s, %s\n",
+		snap.StartedAt.Local().Format("15:04:05"), snap.FilesDone, snap.FilesTotal,
+		formatSize(snap.BytesDone), formatSize(snap.BytesTotal), formatRate(snap.Rate))
+
+	heaviest, slowest := topTransfers(snap)
+	i18n.Fprintln(&b, "\nMost bytes:")
+	writeTransfers(&b, heaviest)
+	i18n.Fprintln(&b, "\nLongest:")
+	writeTransfers(&b, slowest)
+
+	i18n.Fprintln(&b, "\nFolders:")
+	writeUsage(&b, snap.Folders)
+	i18n.Fprintln(&b, "\nExtensions since the agent started:")
+	writeUsage(&b, snap.Extensions)
+	return b.String()
+}
+
+// topTransfer is a transfer listed by 'top', finished or still running
+type topTransfer struct {
+	progress.Transfer
+	running bool
+}
+
+// topTransfers merges the transfers in progress into the heaviest and slowest finished ones
+func topTransfers(snap progress.Snapshot) (heaviest, slowest []topTransfer) {
+	var running []topTransfer
+	for _, file := range snap.Active {
+		transfer := progress.Transfer{Name: file.Name, Bytes: file.Done, Duration: snap.UpdatedAt.Sub(file.StartedAt)}
+		running = append(running, topTransfer{Transfer: transfer, running: true})
+	}
+
+	merge := func(finished []progress.Transfer, before func(a, b topTransfer) bool) []topTransfer {
+		merged := append([]topTransfer(nil), running...)
+		for _, transfer := range finished {
+			merged = append(merged, topTransfer{Transfer: transfer})
+		}
+		sort.SliceStable(merged, func(i, j int) bool { return before(merged[i], merged[j]) })
+		if len(merged) > progress.TopCount {
+			merged = merged[:progress.TopCount]
+		}
+		return merged
+	}
+	heaviest = merge(snap.Heaviest, func(a, b topTransfer) bool { return a.Bytes > b.Bytes })
+	slowest = merge(snap.Slowest, func(a, b topTransfer) bool { return a.Duration > b.Duration })
+	return heaviest, slowest
+}
+
+// writeTransfers writes one line per transfer
+func writeTransfers(b *strings.Builder, transfers []topTransfer) {
+	if len(transfers) == 0 {
+		i18n.Fprintln(b, "  none yet")
+		return
+	}
+	for _, transfer := range transfers {
+		name := transfer.Name
+		if transfer.running {
+			name += " " + i18n.T("(transferring)")
+		}
+		fmt.Fprintf(b, "  %10s  %8s  %s\n", formatSize(transfer.Bytes), formatDuration(transfer.Duration), name)
+	}
+}
+
+// writeUsage writes one line per folder or extension
+func writeUsage(b *strings.Builder, usage []progress.Usage) {
+	if len(usage) == 0 {
+		i18n.Fprintln(b, "  none yet")
+		return
+	}
+	for _, u := range usage {
+		name := u.Name
+		if name == "" {
+			name = i18n.T("(no extension)")
+		}
+		fmt.Fprintf(b, "  %10s  %8s  %-10s  %s\n", formatSize(u.Bytes), formatDuration(u.Duration), pluralize(u.Files, "file"), name)
+	}
+}
+
+// formatDuration renders a transfer time to the tenth of a second
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
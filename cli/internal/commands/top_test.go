@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTop(t *testing.T) {
+	now := time.Now()
+	snap := progress.Snapshot{
+		StartedAt:  now.Add(-time.Minute),
+		UpdatedAt:  now,
+		FilesTotal: 3,
+		FilesDone:  2,
+		Active:     []progress.FileProgress{{Name: "videos/trip.mp4", Size: 4096, Done: 3072, StartedAt: now.Add(-30 * time.Second)}},
+		Heaviest: []progress.Transfer{
+			{Name: "docs/report.pdf", Bytes: 2048, Duration: 2 * time.Second},
+			{Name: "docs/notes.txt", Bytes: 10, Duration: 40 * time.Second},
+		},
+		Slowest: []progress.Transfer{
+			{Name: "docs/notes.txt", Bytes: 10, Duration: 40 * time.Second},
+			{Name: "docs/report.pdf", Bytes: 2048, Duration: 2 * time.Second},
+		},
+		Folders:    []progress.Usage{{Name: "docs", Files: 2, Bytes: 2058, Duration: 42 * time.Second}},
+		Extensions: []progress.Usage{{Name: ".pdf", Files: 1, Bytes: 2048, Duration: 2 * time.Second}, {Files: 1, Bytes: 1}},
+	}
+
+	output := renderTop(snap)
+	assert.Contains(t, output, "2/3 files")
+
+	// A transferência em andamento entra nas listas com o que já transferiu
+	heaviest := strings.Split(strings.SplitN(output, "Most bytes:\n", 2)[1], "\n")
+	assert.Contains(t, heaviest[0], "videos/trip.mp4 (transferring)")
+	assert.Contains(t, heaviest[0], "3.0 KiB")
+	assert.Contains(t, heaviest[1], "docs/report.pdf")
+	slowest := strings.Split(strings.SplitN(output, "Longest:\n", 2)[1], "\n")
+	assert.Contains(t, slowest[0], "40s")
+	assert.Contains(t, slowest[0], "docs/notes.txt")
+	assert.Contains(t, slowest[1], "videos/trip.mp4")
+
+	assert.Contains(t, output, "2 files     docs")
+	assert.Contains(t, output, "(no extension)")
+
+	// Sem transferências as listas ficam vazias
+	empty := renderTop(progress.Snapshot{StartedAt: now, UpdatedAt: now})
+	assert.Equal(t, 4, strings.Count(empty, "none yet"))
+}
+
+func TestTopCommandOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+	cmd := CreateTopCommand(path)
+	assert.NoError(t, cmd.Flags().Set("once", "true"))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	assert.NoError(t, cmd.RunE(cmd, nil))
+	assert.Contains(t, out.String(), "not reported any transfers")
+
+	out.Reset()
+	assert.NoError(t, progress.Write(path, progress.Snapshot{FilesTotal: 1, FilesDone: 1, UpdatedAt: time.Now(),
+		Heaviest: []progress.Transfer{{Name: "docs/a.txt", Bytes: 5, Duration: time.Second}}}))
+	assert.NoError(t, cmd.RunE(cmd, nil))
+	assert.Contains(t, out.String(), "docs/a.txt")
+}
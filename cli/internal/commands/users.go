@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/spf13/cobra"
+)
+
+// CreateUserCommand returns the command managing the users of the local database.
+// setActive persists the user later commands act for.
+func CreateUserCommand(userService *services.UserService, activeUserID uint, setActive func(uint) error) *cobra.Command {
+	userCmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage local users",
+		Long: `Users keep separate folder records and devices in the local database, so
+several people can share a machine. Commands act for the active user, chosen with
+'user use'. The configuration file is not per user: give each user a profile of their own
+('config profile create') to keep their folders and storage apart as well.`,
+	}
+
+	userCreateCmd := &cobra.Command{
+		Use:   "create <email>",
+		Short: "Create a local user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString("name")
+			user, err := userService.CreateUser(args[0], name)
+			if err != nil {
+				return err
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "User %s created (ID: %d). Switch to it with 'sync-manager user use %s'.\n", user.Email, user.ID, user.Email)
+			return nil
+		},
+	}
+	userCreateCmd.Flags().StringP("name", "n", "", "Display name; defaults to the part of the email before '@'")
+
+	userListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List local users",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			users, err := userService.ListUsers()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for _, user := range users {
+				marker := " "
+				if user.ID == activeUserID {
+					marker = "*"
+				}
+				fmt.Fprintf(out, "%s %-4d %-32s %s\n", marker, user.ID, user.Email, user.Name)
+			}
+			return nil
+		},
+	}
+
+	userUseCmd := &cobra.Command{
+		Use:   "use <email|id>",
+		Short: "Make a user the active one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			user, err := userService.FindUser(args[0])
+			if err != nil {
+				return err
+			}
+			if err := setActive(user.ID); err != nil {
+				return err
+			}
+			i18n.Fprintf(cmd.OutOrStdout(), "Now using user %s.\n", user.Email)
+			return nil
+		},
+	}
+
+	userLanguageCmd := &cobra.Command{
+		Use:   "language [code|auto]",
+		Short: "Show or set the language of the active user",
+		Long: `Show or set the language the CLI talks to the active user in: en (English) or
+pt (Portuguese). 'auto' forgets the choice, so the language comes from the locale of the
+environment. --lang and the SYNC_MANAGER_LANG variable override it for a single command.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if len(args) == 0 {
+				lang, err := userService.Language(activeUserID)
+				if err != nil {
+					return err
+				}
+				if lang == "" {
+					i18n.Fprintf(out, "Language: %s (from the environment)\n", i18n.Current())
+				} else {
+					i18n.Fprintf(out, "Language: %s\n", lang)
+				}
+				return nil
+			}
+
+			lang := args[0]
+			if lang == "auto" {
+				lang = ""
+			}
+			if err := userService.SetLanguage(activeUserID, lang); err != nil {
+				return err
+			}
+			if lang == "" {
+				i18n.Fprintln(out, "The language now comes from the environment.")
+			} else {
+				i18n.Set(lang)
+				i18n.Fprintf(out, "Language set to %s.\n", i18n.Normalize(lang))
+			}
+			return nil
+		},
+	}
+
+	userCmd.AddCommand(userCreateCmd)
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userUseCmd)
+	userCmd.AddCommand(userLanguageCmd)
+	return userCmd
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/db"
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/cli/internal/services"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// runUserCommand executa um subcomando de user e retorna sua saída
+func runUserCommand(t *testing.T, userCmd *cobra.Command, args ...string) (string, error) {
+	var out bytes.Buffer
+	userCmd.SetOut(&out)
+	userCmd.SetArgs(args)
+	err := userCmd.Execute()
+	return out.String(), err
+}
+
+func TestUserCommands(t *testing.T) {
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	defer dbManager.Close()
+	assert.NoError(t, dbManager.InitSchema())
+
+	userService := services.NewUserService(repositories.NewUserRepository(dbManager.GetDB()))
+	assert.NoError(t, userService.EnsureDefaultUser())
+
+	var active uint
+	userCmd := CreateUserCommand(userService, services.DefaultUserID, func(id uint) error {
+		active = id
+		return nil
+	})
+
+	output, err := runUserCommand(t, userCmd, "create", "ana@example.com")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "User ana@example.com created (ID: 2)")
+	_, err = runUserCommand(t, userCmd, "create", "ana@example.com")
+	assert.ErrorContains(t, err, "already exists")
+	_, err = runUserCommand(t, userCmd, "create", "not-an-email")
+	assert.Error(t, err)
+
+	output, err = runUserCommand(t, userCmd, "list")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "* 1    user@localhost")
+	assert.Contains(t, output, "  2    ana@example.com                  ana")
+
+	_, err = runUserCommand(t, userCmd, "use", "ana@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), active)
+	_, err = runUserCommand(t, userCmd, "use", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, services.DefaultUserID, active)
+	_, err = runUserCommand(t, userCmd, "use", "bob@example.com")
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+
+	// O idioma escolhido fica nas preferências do usuário; auto volta ao do ambiente
+	t.Cleanup(func() { i18n.Set(i18n.English) })
+	output, err = runUserCommand(t, userCmd, "language")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "(from the environment)")
+	output, err = runUserCommand(t, userCmd, "language", "pt-BR")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Idioma definido como pt.")
+	lang, err := userService.Language(services.DefaultUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, "pt", lang)
+	_, err = runUserCommand(t, userCmd, "language", "fr")
+	assert.ErrorContains(t, err, "idioma não suportado")
+	_, err = runUserCommand(t, userCmd, "language", "auto")
+	assert.NoError(t, err)
+	lang, err = userService.Language(services.DefaultUserID)
+	assert.NoError(t, err)
+	assert.Empty(t, lang)
+
+	// Um usuário escolhido que não existe mais dá lugar ao usuário padrão
+	userID, err := userService.ResolveUser(2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), userID)
+	userID, err = userService.ResolveUser(9)
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+	assert.Equal(t, services.DefaultUserID, userID)
+}
+
+func TestUsersOnlySeeTheirOwnRecords(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DeviceID = "shared-device"
+	dbManager, err := db.NewManager(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	defer dbManager.Close()
+	assert.NoError(t, dbManager.InitSchema())
+
+	folderService := services.NewFolderService(repositories.NewFolderRepository(dbManager.GetDB()), cfg)
+	deviceService := services.NewDeviceService(repositories.NewDeviceRepository(dbManager.GetDB()), cfg, "", nil)
+
+	// Cada usuário só encontra as próprias pastas, mesmo com o mesmo ID de pasta
+	_, err = folderService.CreateFolderWithID("docs", 1, "docs", "/home/ana/docs", false, 0, false)
+	assert.NoError(t, err)
+	_, err = folderService.GetFolder(2, "docs")
+	assert.Error(t, err)
+	assert.Error(t, folderService.SetFolderPaused(2, "docs", true))
+	_, err = folderService.CreateFolderWithID("docs", 2, "docs", "/home/bob/docs", false, 0, false)
+	assert.NoError(t, err)
+	assert.NoError(t, folderService.DeleteFolder(2, "docs"))
+	folder, err := folderService.GetFolder(1, "docs")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), folder.UserID)
+
+	// Um dispositivo registrado por um usuário não é tomado por outro
+	_, err = deviceService.SyncCurrentDevice(1)
+	assert.NoError(t, err)
+	_, err = deviceService.SyncCurrentDevice(2)
+	assert.ErrorIs(t, err, services.ErrDeviceOtherUser)
+	devices, err := deviceService.ListDevices(1)
+	assert.NoError(t, err)
+	assert.Len(t, devices, 1)
+}
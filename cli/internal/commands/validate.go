@@ -0,0 +1,364 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/martinshumberto/sync-manager/common/transport"
+	"github.com/spf13/cobra"
+)
+
+// lintWalkLimit bounds the files and directories 'config validate' looks at in each folder
+// to check its exclude patterns; the patterns of larger folders are not checked
+const lintWalkLimit = 20000
+
+// lintProbeTimeout bounds the request checking that an endpoint answers
+const lintProbeTimeout = 5 * time.Second
+
+// ConfigWarning is a problem 'config validate' found in a configuration that loads, with
+// the change that fixes it
+type ConfigWarning struct {
+	Key     string // Setting or folder the warning is about
+	Message string
+	Fix     string
+}
+
+// createValidateCommand returns the command checking a configuration beyond what loading it does
+func createValidateCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [config-file]",
+		Short: "Check the configuration for likely mistakes",
+		Long: `Check the configuration beyond what loading it does: storage and API endpoints that do
+not answer, folders whose paths do not exist or overlap, exclude patterns that match no
+file or every file, and credentials written in plain text instead of ${VAR} or file:
+references. Each warning comes with the change that fixes it.
+
+Without an argument the configuration in use is checked. The command exits with 0 when
+there is nothing to report, 1 when there are warnings and 2 when the configuration
+cannot be loaded, so it can run in CI.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checked, path := cfg, config.ConfigFileUsed()
+			if len(args) == 1 {
+				loaded, err := config.LoadConfig(args[0])
+				if err != nil {
+					return configError(i18n.Errorf("invalid configuration %s: %w", args[0], err))
+				}
+				checked, path = loaded, args[0]
+			}
+
+			offline, _ := cmd.Flags().GetBool("offline")
+			warnings := LintConfig(cmd.Context(), checked, path, offline)
+			out := cmd.OutOrStdout()
+			if len(warnings) == 0 {
+				i18n.Fprintln(out, "✓ No problems found")
+				return nil
+			}
+			for _, warning := range warnings {
+				fmt.Fprintf(out, "⚠ %s: %s\n", warning.Key, warning.Message)
+				i18n.Fprintf(out, "  Fix: %s\n", warning.Fix)
+			}
+			return i18n.Errorf("the configuration has %d warning(s)", len(warnings))
+		},
+	}
+
+	cmd.Flags().Bool("offline", false, "Skip the checks that reach storage and API endpoints")
+	return cmd
+}
+
+// LintConfig checks cfg, loaded from the file at path, for settings that load but are likely
+// mistakes. The endpoints of the storage targets and of the API are only reached when
+// offline is false.
+func LintConfig(ctx context.Context, cfg *config.Config, path string, offline bool) []ConfigWarning {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var warnings []ConfigWarning
+	warnings = append(warnings, lintFolders(cfg)...)
+	warnings = append(warnings, lintSecrets(cfg, path)...)
+	if !offline {
+		warnings = append(warnings, lintEndpoints(ctx, cfg)...)
+	}
+	return warnings
+}
+
+// lintFolders checks the local paths and exclude patterns of the folders and of local targets
+func lintFolders(cfg *config.Config) []ConfigWarning {
+	var warnings []ConfigWarning
+	for _, folder := range cfg.SyncFolders {
+		key := "folder " + folder.ID
+		paths := []string{folder.Path}
+		for _, root := range folder.Roots {
+			paths = append(paths, root.Path)
+		}
+
+		var missing bool
+		for _, path := range paths {
+			if _, err := os.Stat(path); err != nil {
+				missing = true
+				warnings = append(warnings, ConfigWarning{
+					Key:     key,
+					Message: i18n.Sprintf("%s does not exist", path),
+					Fix:     i18n.Sprintf("create it, or remove the folder with 'sync-manager remove-folder %s'", folder.ID),
+				})
+			}
+		}
+		if !missing && folder.File == "" {
+			warnings = append(warnings, lintExcludes(key, folder, paths)...)
+		}
+	}
+
+	// The agent does not sync a folder inside another one unless nested_folders makes the
+	// outer folder leave it out
+	for _, overlap := range config.FindOverlaps(cfg.SyncFolders) {
+		switch {
+		case overlap.Same():
+			warnings = append(warnings, ConfigWarning{
+				Key:     "folder " + overlap.Inner,
+				Message: i18n.Sprintf("%s is also synced by folder %s, so the agent does not sync it", overlap.InnerPath, overlap.Outer),
+				Fix:     i18n.Sprintf("remove one of the folders with 'sync-manager remove-folder %s'", overlap.Inner),
+			})
+		case cfg.NestedFolders != config.NestingExclude:
+			warnings = append(warnings, ConfigWarning{
+				Key:     "folder " + overlap.Inner,
+				Message: i18n.Sprintf("%s is inside %s of folder %s, so the agent does not sync it", overlap.InnerPath, overlap.OuterPath, overlap.Outer),
+				Fix:     i18n.T("run 'sync-manager config set nested_folders exclude' to sync both, or remove the inner folder"),
+			})
+		}
+	}
+
+	for i, target := range cfg.Targets {
+		if target.Type != config.TargetLocal || target.Local.Removable || target.Local.VolumeUUID != "" {
+			continue
+		}
+		if _, err := os.Stat(target.Local.RootDir); err != nil {
+			warnings = append(warnings, ConfigWarning{
+				Key:     fmt.Sprintf("targets.%d.local.root_dir", i),
+				Message: i18n.Sprintf("%s does not exist", target.Local.RootDir),
+				Fix:     i18n.T("create the directory, or set removable: true if it is on a drive that can be unplugged"),
+			})
+		}
+	}
+	return warnings
+}
+
+// lintExcludes matches the exclude patterns of a folder against its files, as the agent
+// does while scanning, to find those matching nothing or every file
+func lintExcludes(key string, folder config.SyncFolder, roots []string) []ConfigWarning {
+	if len(folder.Exclude) == 0 {
+		return nil
+	}
+
+	var warnings []ConfigWarning
+	var valid []string
+	for _, pattern := range folder.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			warnings = append(warnings, ConfigWarning{
+				Key:     key,
+				Message: i18n.Sprintf("exclude pattern %q is invalid: %v", pattern, err),
+				Fix:     i18n.T("fix the pattern; the agent ignores it"),
+			})
+			continue
+		}
+		valid = append(valid, pattern)
+	}
+
+	matched := make(map[string]bool) // Patterns matching a file or a directory
+	matches := make(map[string]int)  // Files each pattern matches
+	var files, walked int
+	for _, root := range roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			if rel == "." {
+				return nil
+			}
+			walked++
+			if walked > lintWalkLimit {
+				return filepath.SkipAll
+			}
+			if !info.IsDir() {
+				files++
+			}
+			for _, pattern := range valid {
+				if config.MatchExclude(pattern, rel) {
+					matched[pattern] = true
+					if !info.IsDir() {
+						matches[pattern]++
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil || walked > lintWalkLimit {
+			return warnings
+		}
+	}
+
+	for _, pattern := range valid {
+		switch {
+		case !matched[pattern]:
+			warnings = append(warnings, ConfigWarning{
+				Key:     key,
+				Message: i18n.Sprintf("exclude pattern %q matches nothing", pattern),
+				Fix:     i18n.T("patterns match paths relative to the folder, like *.tmp or build/*; fix or remove it"),
+			})
+		case files > 0 && matches[pattern] == files:
+			warnings = append(warnings, ConfigWarning{
+				Key:     key,
+				Message: i18n.Sprintf("exclude pattern %q matches every file, so nothing is synced", pattern),
+				Fix:     i18n.T("narrow the pattern, or pause the folder with 'sync-manager pause-folder' instead"),
+			})
+		}
+	}
+	return warnings
+}
+
+// lintSecrets looks for credentials written in the configuration file rather than read
+// from the environment or another file
+func lintSecrets(cfg *config.Config, path string) []ConfigWarning {
+	secrets := make(map[string]string)
+	if cfg.ApiToken != "" {
+		secrets["api_token"] = cfg.ApiToken
+	}
+	for i, target := range cfg.Targets {
+		switch target.Type {
+		case config.TargetS3:
+			secrets[fmt.Sprintf("targets.%d.s3.secret_key", i)] = target.S3.SecretKey
+		case config.TargetMinio:
+			secrets[fmt.Sprintf("targets.%d.minio.secret_key", i)] = target.Minio.SecretKey
+		}
+		for name, value := range target.Plugin {
+			lower := strings.ToLower(name)
+			if strings.Contains(lower, "secret") || strings.Contains(lower, "password") || strings.Contains(lower, "token") {
+				secrets[fmt.Sprintf("targets.%d.plugin.%s", i, name)] = value
+			}
+		}
+	}
+
+	var plain []string
+	for key, value := range secrets {
+		if value != "" && !cfg.Referenced(key) {
+			plain = append(plain, key)
+		}
+	}
+	sort.Strings(plain)
+
+	var warnings []ConfigWarning
+	for _, key := range plain {
+		warnings = append(warnings, ConfigWarning{
+			Key:     key,
+			Message: i18n.T("credential is written in plain text in the configuration file"),
+			Fix:     i18n.T("set it to a ${VAR} reference to an environment variable, or to file:<path> of a file only you can read"),
+		})
+	}
+
+	// Anyone who can read the file can read the credentials in it
+	if len(plain) > 0 && path != "" && runtime.GOOS != "windows" {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0o077 != 0 {
+			warnings = append(warnings, ConfigWarning{
+				Key:     path,
+				Message: i18n.Sprintf("the file holds credentials and other users can read it (mode %v)", info.Mode().Perm()),
+				Fix:     i18n.Sprintf("run 'chmod 600 %s'", path),
+			})
+		}
+	}
+
+	// Keys sent without TLS can be read on the network
+	for i, target := range cfg.Targets {
+		endpoint, _ := storage.ServerURL(&target)
+		if !strings.HasPrefix(endpoint, "http://") || loopbackURL(endpoint) {
+			continue
+		}
+		if (target.Type == config.TargetS3 && target.S3.SecretKey != "") || (target.Type == config.TargetMinio && target.Minio.SecretKey != "") {
+			warnings = append(warnings, ConfigWarning{
+				Key:     fmt.Sprintf("targets.%d.%s.use_ssl", i, target.Type),
+				Message: i18n.Sprintf("credentials and files are sent to %s without encryption", endpoint),
+				Fix:     i18n.T("set use_ssl: true, or use an https:// endpoint"),
+			})
+		}
+	}
+	if cfg.ApiToken != "" && strings.HasPrefix(cfg.ApiEndpoint, "http://") && !loopbackURL(cfg.ApiEndpoint) {
+		warnings = append(warnings, ConfigWarning{
+			Key:     "api_endpoint",
+			Message: i18n.Sprintf("the API token is sent to %s without encryption", cfg.ApiEndpoint),
+			Fix:     i18n.T("use an https:// endpoint"),
+		})
+	}
+	return warnings
+}
+
+// loopbackURL reports whether a URL points at this machine, where plain HTTP is not exposed
+func loopbackURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// lintEndpoints checks that the servers of the storage targets and the API answer
+func lintEndpoints(ctx context.Context, cfg *config.Config) []ConfigWarning {
+	var warnings []ConfigWarning
+	for i, target := range cfg.Targets {
+		endpoint, transportConfig := storage.ServerURL(&target)
+		if endpoint == "" {
+			continue
+		}
+		if err := probeEndpoint(ctx, endpoint, transportConfig); err != nil {
+			warnings = append(warnings, ConfigWarning{
+				Key:     fmt.Sprintf("targets.%d (%s)", i, target.Name),
+				Message: i18n.Sprintf("%s does not answer: %v", endpoint, err),
+				Fix:     i18n.T("check the endpoint and the proxy settings of the target, or that the server is running"),
+			})
+		}
+	}
+	if cfg.ApiEndpoint != "" {
+		if err := probeEndpoint(ctx, cfg.ApiEndpoint, cfg.HTTP); err != nil {
+			warnings = append(warnings, ConfigWarning{
+				Key:     "api_endpoint",
+				Message: i18n.Sprintf("%s does not answer: %v", cfg.ApiEndpoint, err),
+				Fix:     i18n.T("check api_endpoint and the http proxy settings, or that the server is running"),
+			})
+		}
+	}
+	return warnings
+}
+
+// probeEndpoint sends a HEAD request to endpoint; any answer, even an error status, means
+// the server is reachable
+func probeEndpoint(ctx context.Context, endpoint string, transportConfig config.TransportConfig) error {
+	client, err := transport.NewClient(transportConfig, lintProbeTimeout)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
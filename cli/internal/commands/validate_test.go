@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// warningKeys resume os avisos como "chave: mensagem", para comparar sem depender da dica
+func warningKeys(warnings []ConfigWarning) []string {
+	var keys []string
+	for _, warning := range warnings {
+		keys = append(keys, warning.Key+": "+warning.Message)
+	}
+	return keys
+}
+
+func TestLintConfigFolders(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+	assert.NoError(t, os.MkdirAll(filepath.Join(docs, "notes"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(docs, "a.txt"), []byte("a"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(docs, "notes", "b.txt"), []byte("b"), 0644))
+
+	cfg := config.DefaultConfig()
+	cfg.Targets = []config.StorageTarget{{Name: "nas", Type: config.TargetLocal, Local: config.LocalConfig{RootDir: filepath.Join(dir, "nas")}}}
+	cfg.SyncFolders = []config.SyncFolder{
+		{ID: "docs", Path: docs, Enabled: true, Exclude: []string{"*.tmp", "*.txt", "notes/*", "["}},
+		{ID: "notes", Path: filepath.Join(docs, "notes"), Enabled: true},
+		{ID: "gone", Path: filepath.Join(dir, "gone")},
+	}
+
+	keys := warningKeys(LintConfig(context.Background(), cfg, "", true))
+	assert.Contains(t, keys, "folder gone: "+filepath.Join(dir, "gone")+" does not exist")
+	assert.Contains(t, keys, "targets.0.local.root_dir: "+filepath.Join(dir, "nas")+" does not exist")
+	assert.Contains(t, keys, "folder notes: "+filepath.Join(docs, "notes")+" is inside "+docs+" of folder docs, so the agent does not sync it")
+	assert.Contains(t, keys, `folder docs: exclude pattern "*.tmp" matches nothing`)
+	// *.txt só casa com arquivos da raiz, notes/* com os da subpasta: juntos excluem tudo, sozinhos não
+	for _, key := range keys {
+		assert.NotContains(t, key, `"*.txt"`)
+		assert.NotContains(t, key, `"notes/*"`)
+	}
+	assert.Len(t, filterPrefix(keys, `folder docs: exclude pattern "["`), 1)
+
+	// Um padrão que casa com todos os arquivos não deixa nada para sincronizar
+	cfg.SyncFolders = []config.SyncFolder{{ID: "notes", Path: filepath.Join(docs, "notes"), Exclude: []string{"*"}}}
+	keys = warningKeys(LintConfig(context.Background(), cfg, "", true))
+	assert.Contains(t, keys, `folder notes: exclude pattern "*" matches every file, so nothing is synced`)
+}
+
+// filterPrefix devolve as linhas que começam com prefix
+func filterPrefix(lines []string, prefix string) []string {
+	var filtered []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			filtered = append(filtered, line)
+		}
+	}
+	return filtered
+}
+
+func TestLintConfigSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("api_token: plain\n"), 0644))
+
+	cfg := config.DefaultConfig()
+	cfg.ApiToken = "plain"
+	cfg.ApiEndpoint = "http://sync.example.com"
+	cfg.Targets = []config.StorageTarget{
+		{Name: "s3", Type: config.TargetS3, S3: config.S3Config{Endpoint: "s3.example.com", Bucket: "b", SecretKey: "s3cr3t"}},
+		{Name: "dev", Type: config.TargetMinio, Minio: config.MinioConfig{Endpoint: "localhost:9000", Bucket: "b", SecretKey: "minioadmin"}},
+	}
+
+	keys := warningKeys(LintConfig(context.Background(), cfg, path, true))
+	assert.Contains(t, keys, "api_token: credential is written in plain text in the configuration file")
+	assert.Contains(t, keys, "targets.0.s3.secret_key: credential is written in plain text in the configuration file")
+	assert.Contains(t, keys, "targets.1.minio.secret_key: credential is written in plain text in the configuration file")
+	assert.Contains(t, keys, "targets.0.s3.use_ssl: credentials and files are sent to http://s3.example.com without encryption")
+	assert.Contains(t, keys, "api_endpoint: the API token is sent to http://sync.example.com without encryption")
+	// O MinIO local não sai da máquina, então HTTP simples não é um problema
+	assert.Empty(t, filterPrefix(keys, "targets.1.minio.use_ssl"))
+	if runtime.GOOS != "windows" {
+		assert.Len(t, filterPrefix(keys, path+": "), 1)
+	}
+}
+
+func TestLintConfigEndpoints(t *testing.T) {
+	// Qualquer resposta conta como alcançável, mesmo uma recusa
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	// Um endereço em que nada escuta
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closed := listener.Addr().String()
+	listener.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.ApiEndpoint = server.URL
+	cfg.Targets = []config.StorageTarget{
+		{Name: "up", Type: config.TargetMinio, Minio: config.MinioConfig{Endpoint: strings.TrimPrefix(server.URL, "http://"), Bucket: "b"}},
+		{Name: "down", Type: config.TargetMinio, Minio: config.MinioConfig{Endpoint: closed, Bucket: "b"}},
+	}
+
+	warnings := LintConfig(context.Background(), cfg, "", false)
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, "targets.1 (down)", warnings[0].Key)
+		assert.Contains(t, warnings[0].Message, "http://"+closed+" does not answer")
+	}
+
+	// Sem rede, os endereços não são verificados
+	assert.Empty(t, LintConfig(context.Background(), cfg, "", true))
+}
@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/placeholder"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/spf13/cobra"
+)
+
+// CreateVerifyCommands returns the command that checks local files against the remote copy
+func CreateVerifyCommands(cfg *config.Config, openStorage func() (storage.Storage, error)) []*cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:   "verify <folder-id> [path...]",
+		Short: "Check that the remote copy of a folder matches the local files",
+		Long: `Compare the local files of a folder with their remote copies without downloading them.
+Each file is checked with a single metadata request; files missing remotely or whose
+size or content hash differ are reported. Pass paths relative to the folder to check
+only those files.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			folder := findSyncFolder(cfg, args[0])
+			if folder == nil {
+				return i18n.Errorf("folder with ID %s not found", args[0])
+			}
+			if folder.Mode == config.FolderModeBackup {
+				return i18n.Errorf("folder %s is in backup mode; use 'snapshots %s' to inspect its snapshots", folder.ID, folder.ID)
+			}
+
+			relPaths, err := verifyPaths(folder, args[1:])
+			if err != nil {
+				return err
+			}
+
+			store, err := openStorage()
+			if err != nil {
+				return i18n.Errorf("failed to open storage: %w", err)
+			}
+
+			ctx := context.Background()
+			problems := 0
+			for _, relPath := range relPaths {
+				problem, err := verifyFile(ctx, store, folder, relPath)
+				if err != nil {
+					return err
+				}
+				if problem != "" {
+					problems++
+					fmt.Printf("  %-10s %s\n", problem, relPath)
+				}
+			}
+
+			i18n.Printf("Verified %s in %s: %d ok, %d differ\n",
+				pluralize(len(relPaths), "file"), folder.ID, len(relPaths)-problems, problems)
+			if problems > 0 {
+				return i18n.Errorf("verification failed for %s in %s", pluralize(problems, "file"), folder.ID)
+			}
+			return nil
+		},
+	}
+
+	return []*cobra.Command{verifyCmd}
+}
+
+// verifyPaths returns the slash-separated relative paths of the files to verify
+func verifyPaths(folder *config.SyncFolder, args []string) ([]string, error) {
+	var relPaths []string
+
+	if len(args) > 0 {
+		for _, arg := range args {
+			relPath := filepath.ToSlash(filepath.Clean(arg))
+			if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") || filepath.IsAbs(arg) {
+				return nil, i18n.Errorf("path %s must be relative to the folder", arg)
+			}
+			info, err := os.Stat(filepath.Join(folder.Path, filepath.FromSlash(relPath)))
+			if err != nil {
+				return nil, i18n.Errorf("failed to stat %s: %w", arg, err)
+			}
+			if info.IsDir() {
+				return nil, i18n.Errorf("%s is a directory", arg)
+			}
+			relPaths = append(relPaths, relPath)
+		}
+		return relPaths, nil
+	}
+
+	err := filepath.Walk(folder.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(folder.Path, path)
+		if err != nil {
+			return err
+		}
+		if verifyExcluded(relPath, folder.Exclude) {
+			return nil
+		}
+		relPaths = append(relPaths, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, i18n.Errorf("failed to walk folder %s: %w", folder.Path, err)
+	}
+	return relPaths, nil
+}
+
+// verifyExcluded reports whether a relative path matches one of the exclude patterns
+func verifyExcluded(relPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := filepath.Match(pattern, relPath); err == nil && matched {
+			return true
+		}
+		if matched, err := filepath.Match(pattern, filepath.Base(relPath)); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyFile compares one local file with its remote copy and describes the difference, if any
+func verifyFile(ctx context.Context, store storage.Storage, folder *config.SyncFolder, relPath string) (string, error) {
+	info, metadata, err := store.GetFileInfo(ctx, folder.Key(relPath))
+	if errors.Is(err, storage.ErrNotFound) {
+		return "missing", nil
+	}
+	if err != nil {
+		return "", i18n.Errorf("failed to get remote info for %s: %w", relPath, err)
+	}
+
+	localPath := filepath.Join(folder.Path, filepath.FromSlash(relPath))
+	localInfo, err := os.Stat(localPath)
+	if err != nil {
+		return "", i18n.Errorf("failed to stat %s: %w", relPath, err)
+	}
+
+	// An archived file is checked through the hash kept by its placeholder
+	if localInfo.Size() <= placeholder.MaxSize {
+		if stub, err := placeholder.Read(localPath); err == nil {
+			if !strings.EqualFold(stub.Hash, checksum.FromMetadata(metadata)) {
+				return "content", nil
+			}
+			return "", nil
+		}
+	}
+
+	remoteSize := info.Size
+	if size, err := strconv.ParseInt(metadataLookup(metadata, "size"), 10, 64); err == nil {
+		remoteSize = size
+	}
+	if remoteSize != localInfo.Size() {
+		return "size", nil
+	}
+
+	remoteHash := checksum.FromMetadata(metadata)
+	if remoteHash == "" {
+		return "", nil
+	}
+	localHash, err := fileHash(localPath, remoteHash)
+	if err != nil {
+		return "", i18n.Errorf("failed to hash %s: %w", relPath, err)
+	}
+	if !strings.EqualFold(localHash, remoteHash) {
+		return "content", nil
+	}
+	return "", nil
+}
+
+// metadataLookup returns a metadata value ignoring the case of its key
+func metadataLookup(metadata map[string]string, key string) string {
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// fileHash returns the hash of a file's contents with the algorithm of the remote hash it is
+// compared with, tagged with that algorithm as checksum.FromMetadata returns it
+func fileHash(path, remoteHash string) (string, error) {
+	return checksum.OpenFile(checksum.Of(remoteHash), path, nil)
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCommand(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStorage(&storage.LocalConfig{RootDir: t.TempDir()})
+	assert.NoError(t, err)
+
+	folderPath := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(folderPath, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write("a.txt", "hello")
+	write("sub/b.txt", "world")
+	write("skip.tmp", "ignored")
+
+	cfg := config.DefaultConfig()
+	cfg.SyncFolders = []config.SyncFolder{
+		{ID: "docs", Path: folderPath, Enabled: true, Exclude: []string{"*.tmp"}},
+		{ID: "backup", Path: t.TempDir(), Enabled: true, Mode: config.FolderModeBackup},
+	}
+
+	cmds := CreateVerifyCommands(cfg, func() (storage.Storage, error) { return store, nil })
+	assert.Equal(t, 1, len(cmds))
+	verifyCmd := cmds[0]
+	assert.Equal(t, "verify", verifyCmd.Name())
+
+	// Pastas desconhecidas ou em modo backup são recusadas
+	assert.Error(t, verifyCmd.RunE(verifyCmd, []string{"missing"}))
+	assert.ErrorContains(t, verifyCmd.RunE(verifyCmd, []string{"backup"}), "backup mode")
+
+	// Nada foi enviado ainda: todos os arquivos faltam no remoto
+	assert.ErrorContains(t, verifyCmd.RunE(verifyCmd, []string{"docs"}), "2 files")
+
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		content, _ := os.ReadFile(filepath.Join(folderPath, filepath.FromSlash(name)))
+		_, err := store.UploadFile(ctx, "docs/"+name, strings.NewReader(string(content)), map[string]string{})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, verifyCmd.RunE(verifyCmd, []string{"docs"}))
+
+	// Mesmo tamanho com conteúdo diferente é detectado pelo hash
+	write("a.txt", "HELLO")
+	assert.ErrorContains(t, verifyCmd.RunE(verifyCmd, []string{"docs"}), "1 file")
+
+	// Apenas os caminhos informados são verificados
+	assert.NoError(t, verifyCmd.RunE(verifyCmd, []string{"docs", "sub/b.txt"}))
+	assert.Error(t, verifyCmd.RunE(verifyCmd, []string{"docs", "../outside.txt"}))
+	assert.Error(t, verifyCmd.RunE(verifyCmd, []string{"docs", "sub"}))
+}
@@ -1,12 +1,14 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
 	"github.com/spf13/cobra"
 )
 
@@ -19,155 +21,140 @@ func CreateWizardCommand(cfg *config.Config, saveFn func() error) *cobra.Command
 		Long:  `Start an interactive configuration wizard to set up sync-manager.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			fmt.Println("===================================================")
-			fmt.Println("Welcome to the Sync Manager Configuration Wizard")
+			i18n.Println("Welcome to the Sync Manager Configuration Wizard")
 			fmt.Println("===================================================")
-			fmt.Println("This wizard will guide you through setting up Sync Manager.")
-			fmt.Println("Press Ctrl+C at any time to exit.")
+			i18n.Println("This wizard will guide you through setting up Sync Manager.")
+			i18n.Println("Press Ctrl+C at any time to exit.")
 			fmt.Println()
 
 			// In a real implementation, we would use a UI library like promptui
 			// For now, we'll simulate the interaction with simple fmt.Scan calls
 
 			// Step 1: Configure storage
-			fmt.Println("Step 1: Configure Storage")
+			i18n.Println("Step 1: Configure Storage")
 			fmt.Println("------------------------")
 
 			// Ask for storage provider
-			fmt.Println("Select storage provider:")
-			fmt.Println("1. MinIO (local development)")
-			fmt.Println("2. Amazon S3")
-			fmt.Println("3. Google Cloud Storage")
-			fmt.Println("4. Local filesystem")
-			fmt.Print("Enter choice [1]: ")
+			i18n.Println("Select storage provider:")
+			i18n.Println("1. MinIO (local development)")
+			i18n.Println("2. Amazon S3")
+			i18n.Println("3. Google Cloud Storage")
+			i18n.Println("4. Local filesystem")
+			i18n.Println("5. Microsoft OneDrive")
+			i18n.Print("Enter choice [1]: ")
 
-			var storageChoice string
-			fmt.Scanln(&storageChoice)
+			storageChoice := answer(cmd)
 
 			if storageChoice == "" {
 				storageChoice = "1"
 			}
 
 			// Set storage provider based on choice
+			target := cfg.DefaultTarget()
 			switch storageChoice {
 			case "1":
-				cfg.StorageProvider = "minio"
-				fmt.Println("\nConfiguring MinIO storage:")
+				target.Type = "minio"
+				i18n.Println("\nConfiguring MinIO storage:")
 
-				fmt.Print("Enter MinIO endpoint [localhost:9000]: ")
-				var endpoint string
-				fmt.Scanln(&endpoint)
+				i18n.Print("Enter MinIO endpoint [localhost:9000]: ")
+				endpoint := answer(cmd)
 				if endpoint == "" {
 					endpoint = "localhost:9000"
 				}
-				cfg.MinioConfig.Endpoint = endpoint
+				target.Minio.Endpoint = endpoint
 
-				fmt.Print("Enter MinIO region [us-east-1]: ")
-				var region string
-				fmt.Scanln(&region)
+				i18n.Print("Enter MinIO region [us-east-1]: ")
+				region := answer(cmd)
 				if region == "" {
 					region = "us-east-1"
 				}
-				cfg.MinioConfig.Region = region
+				target.Minio.Region = region
 
-				fmt.Print("Enter MinIO bucket [sync-manager]: ")
-				var bucket string
-				fmt.Scanln(&bucket)
+				i18n.Print("Enter MinIO bucket [sync-manager]: ")
+				bucket := answer(cmd)
 				if bucket == "" {
 					bucket = "sync-manager"
 				}
-				cfg.MinioConfig.Bucket = bucket
+				target.Minio.Bucket = bucket
 
-				fmt.Print("Enter MinIO access key [minioadmin]: ")
-				var accessKey string
-				fmt.Scanln(&accessKey)
+				i18n.Print("Enter MinIO access key [minioadmin]: ")
+				accessKey := answer(cmd)
 				if accessKey == "" {
 					accessKey = "minioadmin"
 				}
-				cfg.MinioConfig.AccessKey = accessKey
+				target.Minio.AccessKey = accessKey
 
-				fmt.Print("Enter MinIO secret key [minioadmin]: ")
-				var secretKey string
-				fmt.Scanln(&secretKey)
+				i18n.Print("Enter MinIO secret key [minioadmin]: ")
+				secretKey := answer(cmd)
 				if secretKey == "" {
 					secretKey = "minioadmin"
 				}
-				cfg.MinioConfig.SecretKey = secretKey
+				target.Minio.SecretKey = secretKey
 
-				fmt.Print("Use SSL? [y/N]: ")
-				var useSSL string
-				fmt.Scanln(&useSSL)
-				cfg.MinioConfig.UseSSL = useSSL == "y" || useSSL == "Y"
+				i18n.Print("Use SSL? [y/N]: ")
+				useSSL := answer(cmd)
+				target.Minio.UseSSL = i18n.Yes(useSSL)
 
-				fmt.Println("\nMinIO configuration complete!")
+				i18n.Println("\nMinIO configuration complete!")
 			case "2":
-				cfg.StorageProvider = "s3"
-				fmt.Println("\nConfiguring Amazon S3 storage:")
+				target.Type = "s3"
+				i18n.Println("\nConfiguring Amazon S3 storage:")
 
-				fmt.Print("Enter AWS region [us-east-1]: ")
-				var region string
-				fmt.Scanln(&region)
+				i18n.Print("Enter AWS region [us-east-1]: ")
+				region := answer(cmd)
 				if region == "" {
 					region = "us-east-1"
 				}
-				cfg.S3Config.Region = region
+				target.S3.Region = region
 
-				fmt.Print("Enter S3 bucket name: ")
-				var bucket string
-				fmt.Scanln(&bucket)
+				i18n.Print("Enter S3 bucket name: ")
+				bucket := answer(cmd)
 				if bucket != "" {
-					cfg.S3Config.Bucket = bucket
+					target.S3.Bucket = bucket
 				}
 
-				fmt.Print("Use a custom endpoint? (for compatible services) [y/N]: ")
-				var customEndpoint string
-				fmt.Scanln(&customEndpoint)
-
-				if customEndpoint == "y" || customEndpoint == "Y" {
-					fmt.Print("Enter endpoint URL: ")
-					var endpoint string
-					fmt.Scanln(&endpoint)
-					cfg.S3Config.Endpoint = endpoint
-
-					fmt.Print("Enter access key: ")
-					var accessKey string
-					fmt.Scanln(&accessKey)
-					cfg.S3Config.AccessKey = accessKey
-
-					fmt.Print("Enter secret key: ")
-					var secretKey string
-					fmt.Scanln(&secretKey)
-					cfg.S3Config.SecretKey = secretKey
-
-					fmt.Print("Use path style? [y/N]: ")
-					var pathStyle string
-					fmt.Scanln(&pathStyle)
-					cfg.S3Config.PathStyle = pathStyle == "y" || pathStyle == "Y"
+				i18n.Print("Use a custom endpoint? (for compatible services) [y/N]: ")
+				customEndpoint := answer(cmd)
+
+				if i18n.Yes(customEndpoint) {
+					i18n.Print("Enter endpoint URL: ")
+					endpoint := answer(cmd)
+					target.S3.Endpoint = endpoint
+
+					i18n.Print("Enter access key: ")
+					accessKey := answer(cmd)
+					target.S3.AccessKey = accessKey
+
+					i18n.Print("Enter secret key: ")
+					secretKey := answer(cmd)
+					target.S3.SecretKey = secretKey
+
+					i18n.Print("Use path style? [y/N]: ")
+					pathStyle := answer(cmd)
+					target.S3.PathStyle = i18n.Yes(pathStyle)
 				}
 
-				fmt.Println("\nS3 configuration complete!")
+				i18n.Println("\nS3 configuration complete!")
 			case "3":
-				cfg.StorageProvider = "gcs"
-				fmt.Println("\nConfiguring Google Cloud Storage:")
+				target.Type = "gcs"
+				i18n.Println("\nConfiguring Google Cloud Storage:")
 
-				fmt.Print("Enter GCS project ID: ")
-				var projectID string
-				fmt.Scanln(&projectID)
-				cfg.GCSConfig.ProjectID = projectID
+				i18n.Print("Enter GCS project ID: ")
+				projectID := answer(cmd)
+				target.GCS.ProjectID = projectID
 
-				fmt.Print("Enter GCS bucket name: ")
-				var bucket string
-				fmt.Scanln(&bucket)
-				cfg.GCSConfig.Bucket = bucket
+				i18n.Print("Enter GCS bucket name: ")
+				bucket := answer(cmd)
+				target.GCS.Bucket = bucket
 
-				fmt.Print("Enter path to credentials file (leave empty for default credentials): ")
-				var credentialsFile string
-				fmt.Scanln(&credentialsFile)
-				cfg.GCSConfig.CredentialsFile = credentialsFile
+				i18n.Print("Enter path to credentials file (leave empty for default credentials): ")
+				credentialsFile := answer(cmd)
+				target.GCS.CredentialsFile = credentialsFile
 
-				fmt.Println("\nGCS configuration complete!")
+				i18n.Println("\nGCS configuration complete!")
 			case "4":
-				cfg.StorageProvider = "local"
-				fmt.Println("\nConfiguring local filesystem storage:")
+				target.Type = "local"
+				i18n.Println("\nConfiguring local filesystem storage:")
 
 				// Determine default directory
 				homeDir, err := os.UserHomeDir()
@@ -176,37 +163,61 @@ func CreateWizardCommand(cfg *config.Config, saveFn func() error) *cobra.Command
 					defaultDir = "./sync-manager-data"
 				}
 
-				fmt.Printf("Enter root directory [%s]: ", defaultDir)
-				var rootDir string
-				fmt.Scanln(&rootDir)
+				i18n.Printf("Enter root directory [%s]: ", defaultDir)
+				rootDir := answer(cmd)
 				if rootDir == "" {
 					rootDir = defaultDir
 				}
-				cfg.LocalConfig.RootDir = rootDir
+				target.Local.RootDir = rootDir
 
 				// Create directory if it doesn't exist
 				if _, err := os.Stat(rootDir); os.IsNotExist(err) {
 					if err := os.MkdirAll(rootDir, 0755); err != nil {
-						fmt.Printf("Warning: Failed to create directory: %v\n", err)
+						i18n.Printf("Warning: Failed to create directory: %v\n", err)
 					} else {
-						fmt.Printf("Created storage directory at: %s\n", rootDir)
+						i18n.Printf("Created storage directory at: %s\n", rootDir)
 					}
 				}
 
-				fmt.Println("\nLocal storage configuration complete!")
+				i18n.Println("\nLocal storage configuration complete!")
+			case "5":
+				target.Type = "onedrive"
+				i18n.Println("\nConfiguring OneDrive storage:")
+
+				i18n.Print("Enter the application (client) ID of your Microsoft Entra app registration: ")
+				target.OneDrive.ClientID = answer(cmd)
+
+				i18n.Print("Enter the accounts to sign in with: consumers, organizations or a tenant ID [common]: ")
+				target.OneDrive.Tenant = answer(cmd)
+
+				i18n.Print("Keep files in the app folder instead of the whole drive? [Y/n]: ")
+				target.OneDrive.AppFolder = !i18n.No(answer(cmd))
+				if !target.OneDrive.AppFolder {
+					i18n.Print("Enter a drive ID (leave empty for your own drive): ")
+					target.OneDrive.DriveID = answer(cmd)
+				}
+
+				// O login precisa de alguém no navegador, então fica para depois sem interação
+				if target.OneDrive.ClientID == "" || nonInteractive(cmd) {
+					i18n.Println("Sign in later with: sync-manager storage-login")
+				} else if err := loginOneDrive(context.Background(), target); err != nil {
+					i18n.Printf("Warning: OneDrive sign-in failed: %v\n", err)
+					i18n.Println("Sign in later with: sync-manager storage-login")
+				}
+
+				i18n.Println("\nOneDrive configuration complete!")
 			default:
-				fmt.Println("Invalid choice. Using MinIO as default.")
-				cfg.StorageProvider = "minio"
+				i18n.Println("Invalid choice. Using MinIO as default.")
+				target.Type = "minio"
 			}
 
 			// Step 2: Configure sync settings
-			fmt.Println("\nStep 2: Configure Sync Settings")
+			i18n.Println("\nStep 2: Configure Sync Settings")
 			fmt.Println("------------------------------")
 
 			// Sync interval
-			fmt.Print("Enter sync interval in minutes [5]: ")
-			var intervalStr string
-			fmt.Scanln(&intervalStr)
+			i18n.Print("Enter sync interval in minutes [5]: ")
+			intervalStr := answer(cmd)
 
 			if intervalStr == "" {
 				cfg.SyncInterval = 5 * time.Minute
@@ -220,9 +231,8 @@ func CreateWizardCommand(cfg *config.Config, saveFn func() error) *cobra.Command
 			}
 
 			// Concurrency
-			fmt.Print("Enter max concurrent transfers [4]: ")
-			var concurrencyStr string
-			fmt.Scanln(&concurrencyStr)
+			i18n.Print("Enter max concurrent transfers [4]: ")
+			concurrencyStr := answer(cmd)
 
 			if concurrencyStr == "" {
 				cfg.MaxConcurrency = 4
@@ -236,9 +246,8 @@ func CreateWizardCommand(cfg *config.Config, saveFn func() error) *cobra.Command
 			}
 
 			// Bandwidth limit
-			fmt.Print("Enter bandwidth limit in KB/s (0 for unlimited) [0]: ")
-			var bandwidthStr string
-			fmt.Scanln(&bandwidthStr)
+			i18n.Print("Enter bandwidth limit in KB/s (0 for unlimited) [0]: ")
+			bandwidthStr := answer(cmd)
 
 			if bandwidthStr == "" {
 				cfg.ThrottleBytes = 0
@@ -249,17 +258,16 @@ func CreateWizardCommand(cfg *config.Config, saveFn func() error) *cobra.Command
 			}
 
 			// Step 3: Add folders
-			fmt.Println("\nStep 3: Add Folders to Sync")
+			i18n.Println("\nStep 3: Add Folders to Sync")
 			fmt.Println("---------------------------")
 
 			addMoreFolders := true
 			for addMoreFolders {
-				fmt.Print("Enter folder path to sync: ")
-				var folderPath string
-				fmt.Scanln(&folderPath)
+				i18n.Print("Enter folder path to sync: ")
+				folderPath := answer(cmd)
 
 				if folderPath == "" {
-					fmt.Println("No folder path entered. Skipping folder addition.")
+					i18n.Println("No folder path entered. Skipping folder addition.")
 					addMoreFolders = false
 					continue
 				}
@@ -279,26 +287,24 @@ func CreateWizardCommand(cfg *config.Config, saveFn func() error) *cobra.Command
 				// Check if folder exists
 				_, err := os.Stat(folderPath)
 				if os.IsNotExist(err) {
-					fmt.Printf("Folder %s does not exist. Do you want to create it? [Y/n]: ", folderPath)
-					var createFolder string
-					fmt.Scanln(&createFolder)
+					i18n.Printf("Folder %s does not exist. Do you want to create it? [Y/n]: ", folderPath)
+					createFolder := answer(cmd)
 
-					if createFolder != "n" && createFolder != "N" {
+					if !i18n.No(createFolder) {
 						if err := os.MkdirAll(folderPath, 0755); err != nil {
-							fmt.Printf("Failed to create folder: %v\n", err)
+							i18n.Printf("Failed to create folder: %v\n", err)
 							continue
 						}
-						fmt.Println("Folder created successfully.")
+						i18n.Println("Folder created successfully.")
 					} else {
-						fmt.Println("Folder creation skipped.")
+						i18n.Println("Folder creation skipped.")
 						continue
 					}
 				}
 
 				// Set up exclusion patterns
-				fmt.Print("Enter file patterns to exclude (comma-separated, e.g. *.tmp,*.bak): ")
-				var excludePatternsStr string
-				fmt.Scanln(&excludePatternsStr)
+				i18n.Print("Enter file patterns to exclude (comma-separated, e.g. *.tmp,*.bak): ")
+				excludePatternsStr := answer(cmd)
 
 				var excludePatterns []string
 				if excludePatternsStr != "" {
@@ -322,25 +328,24 @@ func CreateWizardCommand(cfg *config.Config, saveFn func() error) *cobra.Command
 				// Add to configuration
 				cfg.SyncFolders = append(cfg.SyncFolders, syncFolder)
 
-				fmt.Printf("Folder %s added successfully.\n", folderPath)
+				i18n.Printf("Folder %s added successfully.\n", folderPath)
 
 				// Ask if user wants to add more folders
-				fmt.Print("Do you want to add another folder? [Y/n]: ")
-				var addMore string
-				fmt.Scanln(&addMore)
+				i18n.Print("Do you want to add another folder? [Y/n]: ")
+				addMore := answer(cmd)
 
-				addMoreFolders = addMore != "n" && addMore != "N"
+				addMoreFolders = !i18n.No(addMore)
 			}
 
 			// Save configuration
 			if err := saveFn(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
+				return i18n.Errorf("failed to save configuration: %w", err)
 			}
 
-			fmt.Println("\nConfiguration complete!")
+			i18n.Println("\nConfiguration complete!")
 			fmt.Println("===================================================")
-			fmt.Println("Sync Manager has been successfully configured.")
-			fmt.Println("You can now start the sync agent with: sync-manager start")
+			i18n.Println("Sync Manager has been successfully configured.")
+			i18n.Println("You can now start the sync agent with: sync-manager start")
 			fmt.Println("===================================================")
 
 			return nil
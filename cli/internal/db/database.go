@@ -1,28 +1,39 @@
 package db
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/database"
+	"github.com/martinshumberto/sync-manager/common/i18n"
 	"github.com/martinshumberto/sync-manager/common/models"
 	"github.com/rs/zerolog/log"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 // Manager handles database operations for the CLI
 type Manager struct {
-	db *gorm.DB
+	db     *gorm.DB
+	driver string
+	cipher *columnCipher // Encrypts the sensitive columns, nil when they are stored as they are
 }
 
-// NewManager creates a new database manager
-func NewManager(dbPath string) (*Manager, error) {
+// NewManager creates a new database manager for dsn: the path of a SQLite file, or a
+// postgres:// or mysql:// URL of a central database
+func NewManager(dsn string) (*Manager, error) {
+	dialector, driver, err := database.Dialector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create directory if it doesn't exist
-	dbDir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dbDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	if driver == database.SQLite {
+		dbDir := filepath.Dir(dsn)
+		if err := os.MkdirAll(dbDir, 0755); err != nil {
+			return nil, i18n.Errorf("failed to create database directory: %w", err)
+		}
 	}
 
 	// Configure GORM logger
@@ -32,16 +43,21 @@ func NewManager(dbPath string) (*Manager, error) {
 	}
 
 	// Open database connection with GORM
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: gormLogger,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, i18n.Errorf("failed to open database: %w", err)
 	}
 
-	log.Info().Str("path", dbPath).Msg("Connected to SQLite database")
+	log.Info().Str("driver", driver).Str("dsn", database.Redact(dsn)).Msg("Connected to database")
+
+	return &Manager{db: db, driver: driver}, nil
+}
 
-	return &Manager{db: db}, nil
+// Driver returns the database driver in use, one of the database package's drivers
+func (m *Manager) Driver() string {
+	return m.driver
 }
 
 // Close closes the database connection
@@ -53,9 +69,16 @@ func (m *Manager) Close() error {
 	return sqlDB.Close()
 }
 
-// InitSchema initializes the database schema using GORM auto migrate
+// InitSchema initializes the database schema using GORM auto migrate, which creates the
+// columns with the types of the dialect in use
 func (m *Manager) InitSchema() error {
-	err := m.db.AutoMigrate(
+	migrator := m.db
+	if m.driver == database.MySQL {
+		// MyISAM has no transactions and utf8 stops at three bytes per character
+		migrator = m.db.Set("gorm:table_options", "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	}
+
+	err := migrator.AutoMigrate(
 		&models.User{},
 		&models.UserPreference{},
 		&models.Device{},
@@ -65,10 +88,14 @@ func (m *Manager) InitSchema() error {
 		&models.DeviceFolder{},
 		&models.FileVersion{},
 		&models.SyncEvent{},
+		&models.ExcludeRule{},
+		&models.BandwidthUsage{},
+		&models.SyncRun{},
+		&models.AgentInstance{},
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to migrate database schema: %w", err)
+		return i18n.Errorf("failed to migrate database schema: %w", err)
 	}
 
 	log.Info().Msg("Database schema initialized successfully")
@@ -82,10 +109,9 @@ func (m *Manager) GetDB() *gorm.DB {
 
 // GetDefaultDBPath returns the default path for the database file
 func GetDefaultDBPath() (string, error) {
-	configDir, err := os.UserConfigDir()
+	path, err := config.DatabasePath()
 	if err != nil {
-		return "", fmt.Errorf("failed to get user config directory: %w", err)
+		return "", i18n.Errorf("failed to get user config directory: %w", err)
 	}
-
-	return filepath.Join(configDir, "sync-manager", "sync-manager.db"), nil
+	return path, nil
 }
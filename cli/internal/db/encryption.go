@@ -0,0 +1,335 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/keychain"
+	"gorm.io/gorm"
+)
+
+// KeyLength is the size of a database key, for AES-256
+const KeyLength = 32
+
+// encryptedPrefix marks a column value encrypted with a database key, so values written
+// before encryption was turned on are still read as they are
+const encryptedPrefix = "enc:v1:"
+
+// encryptedColumns lists the sensitive columns encrypted, by table: the names and local paths
+// of the folders, which tell what the user keeps and where. Columns that are looked up by
+// value are left out, and API tokens are only stored hashed anyway; the device token is kept
+// in the keychain by apiclient.
+var encryptedColumns = map[string][]string{
+	"device_folders": {"local_path"},
+	"folders":        {"name"},
+}
+
+// ErrWrongKey is returned when a column was encrypted with another key
+var ErrWrongKey = i18n.NewError("database value cannot be decrypted with the database key")
+
+// KeyStore keeps the database key, as the OS keychain does
+type KeyStore interface {
+	// Load returns the stored key, or an error wrapping ErrNoKey when none is stored
+	Load() ([]byte, error)
+	// Save stores key, replacing the previous one
+	Save(key []byte) error
+}
+
+// ErrNoKey is returned by a KeyStore holding no key yet
+var ErrNoKey = i18n.NewError("no database key stored")
+
+// NewKey returns a random database key
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeyLength)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, i18n.Errorf("failed to generate database key: %w", err)
+	}
+	return key, nil
+}
+
+// columnCipher encrypts and decrypts column values with AES-GCM
+type columnCipher struct {
+	aead cipher.AEAD
+}
+
+// newColumnCipher returns a cipher using key
+func newColumnCipher(key []byte) (*columnCipher, error) {
+	if len(key) != KeyLength {
+		return nil, i18n.Errorf("invalid database key: %d bytes, expected %d", len(key), KeyLength)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, i18n.Errorf("invalid database key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, i18n.Errorf("invalid database key: %w", err)
+	}
+	return &columnCipher{aead: aead}, nil
+}
+
+// encrypt returns value encrypted. Empty and already encrypted values are returned as they are.
+func (c *columnCipher) encrypt(value string) (string, error) {
+	if value == "" || strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", i18n.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt returns value decrypted. Values that were never encrypted are returned as they are.
+func (c *columnCipher) decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrWrongKey
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+	return string(plain), nil
+}
+
+// EnableEncryption encrypts the sensitive columns with the key kept in store, creating and
+// saving one the first time. Rows written earlier stay readable and are encrypted the next
+// time they are saved, or all at once by Rekey.
+func (m *Manager) EnableEncryption(store KeyStore) error {
+	key, err := store.Load()
+	if errors.Is(err, ErrNoKey) {
+		if key, err = NewKey(); err != nil {
+			return err
+		}
+		if err := store.Save(key); err != nil {
+			return i18n.Errorf("failed to save database key: %w", err)
+		}
+	} else if err != nil {
+		return i18n.Errorf("failed to load database key: %w", err)
+	}
+
+	c, err := newColumnCipher(key)
+	if err != nil {
+		return err
+	}
+	if m.cipher == nil {
+		if err := m.registerEncryptionCallbacks(); err != nil {
+			return err
+		}
+	}
+	m.cipher = c
+	return nil
+}
+
+// Encrypted reports whether the sensitive columns are encrypted
+func (m *Manager) Encrypted() bool {
+	return m.cipher != nil
+}
+
+// Rekey encrypts the sensitive columns of every row, soft-deleted ones included, with a new
+// key and saves it in store, returning the number of values rewritten. Values still in plain
+// text are encrypted too. The rows are rewritten in one transaction, committed only once the
+// new key is saved, so a failure leaves the database readable with the previous key.
+func (m *Manager) Rekey(store KeyStore) (int, error) {
+	if m.cipher == nil {
+		return 0, errors.New(i18n.T("database encryption is not enabled"))
+	}
+	key, err := NewKey()
+	if err != nil {
+		return 0, err
+	}
+	next, err := newColumnCipher(key)
+	if err != nil {
+		return 0, err
+	}
+	previousKey, err := store.Load()
+	if err != nil {
+		return 0, i18n.Errorf("failed to load database key: %w", err)
+	}
+
+	rewritten, saved := 0, false
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		for table, columns := range encryptedColumns {
+			count, err := m.rekeyTable(tx, table, columns, next)
+			if err != nil {
+				return err
+			}
+			rewritten += count
+		}
+		if err := store.Save(key); err != nil {
+			return i18n.Errorf("failed to save database key: %w", err)
+		}
+		saved = true
+		return nil
+	})
+	if err != nil {
+		// The rows kept the previous key, so the keychain must too
+		if saved {
+			if restoreErr := store.Save(previousKey); restoreErr != nil {
+				return 0, i18n.Errorf("%w; restoring the previous key also failed: %v", err, restoreErr)
+			}
+		}
+		return 0, err
+	}
+	m.cipher = next
+	return rewritten, nil
+}
+
+// rekeyTable re-encrypts columns of every row of table with next
+func (m *Manager) rekeyTable(tx *gorm.DB, table string, columns []string, next *columnCipher) (int, error) {
+	var rows []map[string]interface{}
+	if err := tx.Table(table).Select(append([]string{"id"}, columns...)).Find(&rows).Error; err != nil {
+		return 0, i18n.Errorf("failed to read %s: %w", table, err)
+	}
+
+	rewritten := 0
+	for _, row := range rows {
+		updates := make(map[string]interface{})
+		for _, column := range columns {
+			value, _ := row[column].(string)
+			if value == "" {
+				continue
+			}
+			plain, err := m.cipher.decrypt(value)
+			if err != nil {
+				return 0, i18n.Errorf("failed to decrypt %s.%s of row %v: %w", table, column, row["id"], err)
+			}
+			if updates[column], err = next.encrypt(plain); err != nil {
+				return 0, err
+			}
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := tx.Table(table).Where("id = ?", row["id"]).UpdateColumns(updates).Error; err != nil {
+			return 0, i18n.Errorf("failed to update %s: %w", table, err)
+		}
+		rewritten += len(updates)
+	}
+	return rewritten, nil
+}
+
+// registerEncryptionCallbacks hooks the column encryption into GORM: values are encrypted
+// before rows are written and decrypted after they are written or read, so models always
+// hold plain text
+func (m *Manager) registerEncryptionCallbacks() error {
+	callbacks := m.db.Callback()
+	steps := []struct {
+		name     string
+		register func() error
+	}{
+		{"create encrypt", func() error {
+			return callbacks.Create().Before("gorm:create").Register("sync_manager:encrypt_columns", m.encryptColumns)
+		}},
+		{"create decrypt", func() error {
+			return callbacks.Create().After("gorm:create").Register("sync_manager:decrypt_columns", m.decryptColumns)
+		}},
+		{"update encrypt", func() error {
+			return callbacks.Update().Before("gorm:update").Register("sync_manager:encrypt_columns", m.encryptColumns)
+		}},
+		{"update decrypt", func() error {
+			return callbacks.Update().After("gorm:update").Register("sync_manager:decrypt_columns", m.decryptColumns)
+		}},
+		{"query decrypt", func() error {
+			return callbacks.Query().After("gorm:query").Register("sync_manager:decrypt_columns", m.decryptColumns)
+		}},
+	}
+	for _, step := range steps {
+		if err := step.register(); err != nil {
+			return i18n.Errorf("failed to register %s callback: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+// encryptColumns encrypts the sensitive columns of the rows a statement writes
+func (m *Manager) encryptColumns(tx *gorm.DB) {
+	m.transformColumns(tx, m.cipher.encrypt)
+}
+
+// decryptColumns decrypts the sensitive columns of the rows a statement wrote or read
+func (m *Manager) decryptColumns(tx *gorm.DB) {
+	m.transformColumns(tx, m.cipher.decrypt)
+}
+
+// transformColumns applies transform to the sensitive string fields of the statement's
+// model, whether it holds one row or a slice of them
+func (m *Manager) transformColumns(tx *gorm.DB, transform func(string) (string, error)) {
+	if tx.Error != nil || tx.Statement.Schema == nil || !tx.Statement.ReflectValue.IsValid() {
+		return
+	}
+	columns := encryptedColumns[tx.Statement.Schema.Table]
+	if len(columns) == 0 {
+		return
+	}
+
+	apply := func(row reflect.Value) {
+		row = reflect.Indirect(row)
+		if row.Kind() != reflect.Struct {
+			return
+		}
+		for _, column := range columns {
+			field := tx.Statement.Schema.LookUpField(column)
+			if field == nil {
+				continue
+			}
+			value, zero := field.ValueOf(tx.Statement.Context, row)
+			text, ok := value.(string)
+			if zero || !ok {
+				continue
+			}
+			transformed, err := transform(text)
+			if err != nil {
+				tx.AddError(fmt.Errorf("%s.%s: %w", tx.Statement.Schema.Table, column, err))
+				return
+			}
+			if err := field.Set(tx.Statement.Context, row, transformed); err != nil {
+				tx.AddError(err)
+				return
+			}
+		}
+	}
+
+	switch value := tx.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			apply(value.Index(i))
+		}
+	case reflect.Struct:
+		apply(value)
+	}
+}
+
+// keychainAccount names the database key in the OS keychain
+const keychainAccount = "database-key"
+
+// KeychainStore keeps the database key in the OS keychain
+type KeychainStore struct{}
+
+// Load returns the database key from the keychain
+func (KeychainStore) Load() ([]byte, error) {
+	key, err := keychain.Get(keychainAccount)
+	if errors.Is(err, keychain.ErrNotFound) {
+		return nil, ErrNoKey
+	}
+	return key, err
+}
+
+// Save stores the database key in the keychain
+func (KeychainStore) Save(key []byte) error {
+	return keychain.Set(keychainAccount, key)
+}
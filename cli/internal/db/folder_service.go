@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/martinshumberto/sync-manager/common/i18n"
 	"github.com/martinshumberto/sync-manager/common/models"
 )
 
@@ -34,16 +35,16 @@ func (s *FolderService) CreateFolder(userID int, name string, encryptionEnabled
 	result, err := s.db.Exec(
 		query,
 		userID, folderID, name, now, now,
-		"active", encryptionEnabled,
+		string(models.StatusActive), encryptionEnabled,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create folder: %w", err)
+		return nil, i18n.Errorf("failed to create folder: %w", err)
 	}
 
 	// Get the ID of the inserted folder
 	id, err := result.LastInsertId()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get folder ID: %w", err)
+		return nil, i18n.Errorf("failed to get folder ID: %w", err)
 	}
 
 	// Return the folder data
@@ -52,7 +53,7 @@ func (s *FolderService) CreateFolder(userID int, name string, encryptionEnabled
 		FolderID:          folderID,
 		Name:              name,
 		CreatedAt:         now,
-		Status:            "active",
+		Status:            models.StatusActive,
 		EncryptionEnabled: encryptionEnabled,
 	}, nil
 }
@@ -78,15 +79,15 @@ func (s *FolderService) GetFolder(folderID string) (*models.FolderResponse, erro
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("folder not found: %s", folderID)
+			return nil, i18n.Errorf("folder not found: %s", folderID)
 		}
-		return nil, fmt.Errorf("failed to get folder: %w", err)
+		return nil, i18n.Errorf("failed to get folder: %w", err)
 	}
 
 	// Parse the created_at timestamp
 	folder.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+		return nil, i18n.Errorf("failed to parse timestamp: %w", err)
 	}
 
 	return &folder, nil
@@ -102,7 +103,7 @@ func (s *FolderService) GetFolders(userID int) ([]models.FolderResponse, error)
 	`
 	rows, err := s.db.Query(query, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query folders: %w", err)
+		return nil, i18n.Errorf("failed to query folders: %w", err)
 	}
 	defer rows.Close()
 
@@ -119,13 +120,13 @@ func (s *FolderService) GetFolders(userID int) ([]models.FolderResponse, error)
 			&folder.EncryptionEnabled,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan folder: %w", err)
+			return nil, i18n.Errorf("failed to scan folder: %w", err)
 		}
 
 		// Parse the created_at timestamp
 		folder.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAt)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+			return nil, i18n.Errorf("failed to parse timestamp: %w", err)
 		}
 
 		folders = append(folders, folder)
@@ -133,7 +134,7 @@ func (s *FolderService) GetFolders(userID int) ([]models.FolderResponse, error)
 
 	// Check for errors from iterating over rows
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating folders: %w", err)
+		return nil, i18n.Errorf("error iterating folders: %w", err)
 	}
 
 	return folders, nil
@@ -152,7 +153,7 @@ func (s *FolderService) UpdateFolder(folderID string, name, status string, encry
 		folderID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update folder: %w", err)
+		return i18n.Errorf("failed to update folder: %w", err)
 	}
 
 	return nil
@@ -163,7 +164,7 @@ func (s *FolderService) DeleteFolder(folderID string) error {
 	query := `DELETE FROM folders WHERE folder_id = ?`
 	_, err := s.db.Exec(query, folderID)
 	if err != nil {
-		return fmt.Errorf("failed to delete folder: %w", err)
+		return i18n.Errorf("failed to delete folder: %w", err)
 	}
 
 	return nil
@@ -180,7 +181,7 @@ func (s *FolderService) AddDeviceFolder(
 	// Convert exclude patterns to a string array
 	excludePatternsSQL, err := stringArrayToSQL(excludePatterns)
 	if err != nil {
-		return fmt.Errorf("failed to convert exclude patterns: %w", err)
+		return i18n.Errorf("failed to convert exclude patterns: %w", err)
 	}
 
 	query := `
@@ -192,10 +193,10 @@ func (s *FolderService) AddDeviceFolder(
 	_, err = s.db.Exec(
 		query,
 		deviceID, folderID, localPath, true,
-		syncDirection, excludePatternsSQL, "active",
+		syncDirection, excludePatternsSQL, string(models.StatusActive),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to add folder to device: %w", err)
+		return i18n.Errorf("failed to add folder to device: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/database"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+)
+
+// softDeleted lists the models with soft-deleted rows, children before the rows they point to
+var softDeleted = []interface{}{
+	&models.FileVersion{},
+	&models.SyncEvent{},
+	&models.DeviceFolder{},
+	&models.Folder{},
+	&models.ApiToken{},
+	&models.DeviceToken{},
+	&models.Device{},
+	&models.UserPreference{},
+	&models.User{},
+}
+
+// MaintenanceOptions sets what Maintain removes. A zero retention keeps those rows.
+type MaintenanceOptions struct {
+	DeletedRetention time.Duration // Soft-deleted rows older than this are removed for good
+	EventRetention   time.Duration // Sync events older than this are removed
+}
+
+// MaintenanceReport tells what Maintain did
+type MaintenanceReport struct {
+	DeletedRows int64  // Soft-deleted rows removed
+	EventRows   int64  // Old sync events removed
+	SizeBefore  int64  // Database size in bytes before, -1 when the driver cannot tell
+	SizeAfter   int64  // Database size in bytes after, -1 when the driver cannot tell
+	Integrity   string // Result of the integrity check, empty when the driver has none
+	Vacuumed    bool   // The free pages were given back to the file system
+}
+
+// Maintain removes soft-deleted rows and sync events past their retention, then on SQLite
+// rebuilds the file to give back the space they held and checks its integrity
+func (m *Manager) Maintain(opts MaintenanceOptions) (*MaintenanceReport, error) {
+	report := &MaintenanceReport{SizeBefore: m.size()}
+	now := time.Now()
+
+	if opts.DeletedRetention > 0 {
+		cutoff := now.Add(-opts.DeletedRetention)
+		for _, model := range softDeleted {
+			result := m.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(model)
+			if result.Error != nil {
+				return nil, i18n.Errorf("failed to prune deleted rows: %w", result.Error)
+			}
+			report.DeletedRows += result.RowsAffected
+		}
+	}
+
+	if opts.EventRetention > 0 {
+		result := m.db.Unscoped().Where("timestamp < ?", now.Add(-opts.EventRetention)).Delete(&models.SyncEvent{})
+		if result.Error != nil {
+			return nil, i18n.Errorf("failed to prune sync events: %w", result.Error)
+		}
+		report.EventRows = result.RowsAffected
+	}
+
+	if m.driver == database.SQLite {
+		if err := m.db.Exec("VACUUM").Error; err != nil {
+			return nil, i18n.Errorf("failed to vacuum database: %w", err)
+		}
+		report.Vacuumed = true
+
+		var results []string
+		if err := m.db.Raw("PRAGMA integrity_check").Scan(&results).Error; err != nil {
+			return nil, i18n.Errorf("failed to check database integrity: %w", err)
+		}
+		report.Integrity = "ok"
+		if len(results) > 0 {
+			report.Integrity = strings.Join(results, "; ")
+		}
+	}
+
+	report.SizeAfter = m.size()
+	return report, nil
+}
+
+// size returns the size of the database in bytes, -1 when it cannot be read
+func (m *Manager) size() int64 {
+	var size int64
+	var err error
+	switch m.driver {
+	case database.SQLite:
+		err = m.db.Raw("SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&size).Error
+	case database.Postgres:
+		err = m.db.Raw("SELECT pg_database_size(current_database())").Scan(&size).Error
+	case database.MySQL:
+		err = m.db.Raw("SELECT COALESCE(SUM(data_length + index_length), 0) FROM information_schema.tables WHERE table_schema = DATABASE()").Scan(&size).Error
+	default:
+		return -1
+	}
+	if err != nil {
+		return -1
+	}
+	return size
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/gorm"
+)
+
+// BandwidthRepository lê o tráfego diário que o agente registra no banco de dados
+type BandwidthRepository struct {
+	db *gorm.DB
+}
+
+// NewBandwidthRepository cria um novo repositório de uso de banda
+func NewBandwidthRepository(db *gorm.DB) *BandwidthRepository {
+	return &BandwidthRepository{db: db}
+}
+
+// FindByMonth busca o tráfego do mês (YYYY-MM) por dia e pasta; sem deviceID, de todos os dispositivos
+func (r *BandwidthRepository) FindByMonth(month, deviceID string) ([]models.BandwidthUsage, error) {
+	query := r.db.Where("day >= ? AND day <= ?", month+"-01", month+"-31")
+	if deviceID != "" {
+		query = query.Where("device_id = ?", deviceID)
+	}
+
+	var usages []models.BandwidthUsage
+	if err := query.Order("day, folder_id, device_id").Find(&usages).Error; err != nil {
+		return nil, err
+	}
+	return usages, nil
+}
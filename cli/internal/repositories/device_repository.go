@@ -22,26 +22,33 @@ func (r *DeviceRepository) Create(device *models.Device) error {
 	return r.db.Create(device).Error
 }
 
-// FindByID busca um dispositivo pelo ID
-func (r *DeviceRepository) FindByID(id uint) (*models.Device, error) {
+// FindByID busca um dispositivo de um usuário pelo ID
+func (r *DeviceRepository) FindByID(userID uint, id uint) (*models.Device, error) {
 	var device models.Device
-	err := r.db.First(&device, id).Error
+	err := r.db.Where("user_id = ?", userID).First(&device, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &device, nil
 }
 
-// FindByDeviceID busca um dispositivo pelo DeviceID
-func (r *DeviceRepository) FindByDeviceID(deviceID string) (*models.Device, error) {
+// FindByDeviceID busca um dispositivo de um usuário pelo DeviceID; dispositivos de outros usuários não são encontrados
+func (r *DeviceRepository) FindByDeviceID(userID uint, deviceID string) (*models.Device, error) {
 	var device models.Device
-	err := r.db.Where("device_id = ?", deviceID).First(&device).Error
+	err := r.db.Where("user_id = ? AND device_id = ?", userID, deviceID).First(&device).Error
 	if err != nil {
 		return nil, err
 	}
 	return &device, nil
 }
 
+// RegisteredToOtherUser indica se o DeviceID já pertence a outro usuário, sem expor o dispositivo
+func (r *DeviceRepository) RegisteredToOtherUser(userID uint, deviceID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Device{}).Where("device_id = ? AND user_id <> ?", deviceID, userID).Count(&count).Error
+	return count > 0, err
+}
+
 // FindByUserID busca todos os dispositivos de um usuário
 func (r *DeviceRepository) FindByUserID(userID uint) ([]models.Device, error) {
 	var devices []models.Device
@@ -57,10 +64,10 @@ func (r *DeviceRepository) Update(device *models.Device) error {
 	return r.db.Save(device).Error
 }
 
-// UpdateLastSeen atualiza o timestamp de última visualização de um dispositivo
-func (r *DeviceRepository) UpdateLastSeen(deviceID string) error {
+// UpdateLastSeen atualiza o timestamp de última visualização de um dispositivo de um usuário
+func (r *DeviceRepository) UpdateLastSeen(userID uint, deviceID string) error {
 	return r.db.Model(&models.Device{}).
-		Where("device_id = ?", deviceID).
+		Where("user_id = ? AND device_id = ?", userID, deviceID).
 		Update("last_seen_at", time.Now()).Error
 }
 
@@ -102,3 +109,20 @@ func (r *DeviceRepository) UpdateTokenLastUsed(tokenID uint) error {
 		Where("id = ?", tokenID).
 		Update("last_used", time.Now()).Error
 }
+
+// RevokeDeviceTokens revoga todos os tokens de um dispositivo
+func (r *DeviceRepository) RevokeDeviceTokens(deviceID uint) error {
+	return r.db.Model(&models.DeviceToken{}).
+		Where("device_id = ? AND revoked = ?", deviceID, false).
+		Update("revoked", true).Error
+}
+
+// DeleteWithFolders exclui um dispositivo e suas associações com pastas
+func (r *DeviceRepository) DeleteWithFolders(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("device_id = ?", id).Delete(&models.DeviceFolder{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Device{}, id).Error
+	})
+}
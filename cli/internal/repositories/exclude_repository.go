@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/gorm"
+)
+
+// ExcludeRepository gerencia as regras de exclusão guardadas no banco de dados
+type ExcludeRepository struct {
+	db *gorm.DB
+}
+
+// NewExcludeRepository cria um novo repositório de regras de exclusão
+func NewExcludeRepository(db *gorm.DB) *ExcludeRepository {
+	return &ExcludeRepository{db: db}
+}
+
+// Create cria uma nova regra de exclusão
+func (r *ExcludeRepository) Create(rule *models.ExcludeRule) error {
+	return r.db.Create(rule).Error
+}
+
+// FindAll busca todas as regras, na ordem em que foram criadas
+func (r *ExcludeRepository) FindAll() ([]models.ExcludeRule, error) {
+	var rules []models.ExcludeRule
+	err := r.db.Order("id").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// FindMatching busca a regra com o mesmo padrão e escopo, ou nil se não existir
+func (r *ExcludeRepository) FindMatching(rule models.ExcludeRule) (*models.ExcludeRule, error) {
+	var rules []models.ExcludeRule
+	err := r.db.Where("pattern = ? AND folder_id = ? AND device_id = ? AND allow = ?",
+		rule.Pattern, rule.FolderID, rule.DeviceID, rule.Allow).Limit(1).Find(&rules).Error
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	return &rules[0], nil
+}
+
+// Delete exclui uma regra
+func (r *ExcludeRepository) Delete(id uint) error {
+	return r.db.Delete(&models.ExcludeRule{}, id).Error
+}
@@ -1,8 +1,7 @@
 package repositories
 
 import (
-	"fmt"
-
+	"github.com/martinshumberto/sync-manager/common/i18n"
 	"github.com/martinshumberto/sync-manager/common/models"
 	"gorm.io/gorm"
 )
@@ -22,20 +21,20 @@ func (r *FolderRepository) Create(folder *models.Folder) error {
 	return r.db.Create(folder).Error
 }
 
-// FindByID busca uma pasta pelo ID
-func (r *FolderRepository) FindByID(id uint) (*models.Folder, error) {
+// FindByID busca uma pasta de um usuário pelo ID
+func (r *FolderRepository) FindByID(userID uint, id uint) (*models.Folder, error) {
 	var folder models.Folder
-	err := r.db.First(&folder, id).Error
+	err := r.db.Where("user_id = ?", userID).First(&folder, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &folder, nil
 }
 
-// FindByFolderID busca uma pasta pelo FolderID
-func (r *FolderRepository) FindByFolderID(folderID string) (*models.Folder, error) {
+// FindByFolderID busca uma pasta de um usuário pelo FolderID; pastas de outros usuários não são encontradas
+func (r *FolderRepository) FindByFolderID(userID uint, folderID string) (*models.Folder, error) {
 	var folder models.Folder
-	err := r.db.Where("folder_id = ?", folderID).First(&folder).Error
+	err := r.db.Where("user_id = ? AND folder_id = ?", userID, folderID).First(&folder).Error
 	if err != nil {
 		return nil, err
 	}
@@ -52,6 +51,22 @@ func (r *FolderRepository) FindByUserID(userID uint) ([]models.Folder, error) {
 	return folders, nil
 }
 
+// FindDeletedByFolderID busca uma pasta excluída (soft delete) de um usuário pelo FolderID
+func (r *FolderRepository) FindDeletedByFolderID(userID uint, folderID string) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.db.Unscoped().Where("user_id = ? AND folder_id = ? AND deleted_at IS NOT NULL", userID, folderID).First(&folder).Error
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// Restore desfaz a exclusão (soft delete) de uma pasta
+func (r *FolderRepository) Restore(folder *models.Folder) error {
+	folder.DeletedAt = gorm.DeletedAt{}
+	return r.db.Unscoped().Save(folder).Error
+}
+
 // Update atualiza uma pasta no banco de dados
 func (r *FolderRepository) Update(folder *models.Folder) error {
 	return r.db.Save(folder).Error
@@ -87,15 +102,15 @@ func (r *FolderRepository) DeleteDeviceFolder(deviceID uint, folderID uint) erro
 	return r.db.Where("device_id = ? AND folder_id = ?", deviceID, folderID).Delete(&models.DeviceFolder{}).Error
 }
 
-// FindWithPreloads carrega uma pasta com relacionamentos
-func (r *FolderRepository) FindWithPreloads(folderID string) (*models.Folder, error) {
+// FindWithPreloads carrega uma pasta de um usuário com relacionamentos
+func (r *FolderRepository) FindWithPreloads(userID uint, folderID string) (*models.Folder, error) {
 	var folder models.Folder
 	err := r.db.
 		Preload("DeviceFolders").
-		Where("folder_id = ?", folderID).
+		Where("user_id = ? AND folder_id = ?", userID, folderID).
 		First(&folder).Error
 	if err != nil {
-		return nil, fmt.Errorf("falha ao carregar pasta com preloads: %w", err)
+		return nil, i18n.Errorf("failed to load folder with preloads: %w", err)
 	}
 	return &folder, nil
 }
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/gorm"
+)
+
+// SyncRunRepository lê os resumos de sincronização que o agente registra no banco de dados
+type SyncRunRepository struct {
+	db *gorm.DB
+}
+
+// NewSyncRunRepository cria um novo repositório de execuções de sincronização
+func NewSyncRunRepository(db *gorm.DB) *SyncRunRepository {
+	return &SyncRunRepository{db: db}
+}
+
+// FindLatest busca a última execução de cada pasta do dispositivo, ou só a de folderID quando informado
+func (r *SyncRunRepository) FindLatest(deviceID, folderID string) ([]models.SyncRun, error) {
+	latest := r.db.Model(&models.SyncRun{}).Select("MAX(id)").Where("device_id = ?", deviceID)
+	if folderID != "" {
+		latest = latest.Where("folder_id = ?", folderID)
+	}
+
+	var runs []models.SyncRun
+	if err := r.db.Where("id IN (?)", latest.Group("folder_id")).Order("folder_id").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
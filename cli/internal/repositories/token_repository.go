@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/gorm"
+)
+
+// TokenRepository gerencia operações de banco de dados relacionadas a tokens de API
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenRepository cria um novo repositório de tokens de API
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// Create cria um novo token no banco de dados
+func (r *TokenRepository) Create(token *models.ApiToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByUserID busca todos os tokens de um usuário, em ordem de criação
+func (r *TokenRepository) FindByUserID(userID uint) ([]models.ApiToken, error) {
+	var tokens []models.ApiToken
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// FindByID busca um token de um usuário pelo ID
+func (r *TokenRepository) FindByID(userID uint, id uint) (*models.ApiToken, error) {
+	var token models.ApiToken
+	err := r.db.Where("user_id = ?", userID).First(&token, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Revoke revoga um token de um usuário
+func (r *TokenRepository) Revoke(userID uint, id uint) error {
+	return r.db.Model(&models.ApiToken{}).
+		Where("user_id = ? AND id = ?", userID, id).
+		Update("revoked", true).Error
+}
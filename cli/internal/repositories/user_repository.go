@@ -32,6 +32,16 @@ func (r *UserRepository) FindByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// FindAll busca todos os usuários, em ordem de criação
+func (r *UserRepository) FindAll() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Order("id").Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // FindByEmail busca um usuário pelo email
 func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 	var user models.User
@@ -59,6 +69,15 @@ func (r *UserRepository) Delete(id uint) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
 
+// FindUserPreferences busca as preferências de um usuário, sem criá-las quando não existem
+func (r *UserRepository) FindUserPreferences(userID uint) (*models.UserPreference, error) {
+	var preferences models.UserPreference
+	if err := r.db.Where("user_id = ?", userID).First(&preferences).Error; err != nil {
+		return nil, err
+	}
+	return &preferences, nil
+}
+
 // GetUserPreferences obtém as preferências de um usuário
 func (r *UserRepository) GetUserPreferences(userID uint) (*models.UserPreference, error) {
 	var preferences models.UserPreference
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+)
+
+// BandwidthRow é o tráfego de uma pasta num dia, ou no mês inteiro quando Day está vazio
+type BandwidthRow struct {
+	Day             string `json:"day,omitempty"`
+	FolderID        string `json:"folder_id"`
+	BytesUploaded   int64  `json:"bytes_uploaded"`
+	BytesDownloaded int64  `json:"bytes_downloaded"`
+}
+
+// BandwidthReport é o tráfego de um mês, por pasta ou por dia e pasta
+type BandwidthReport struct {
+	Month           string         `json:"month"`
+	Rows            []BandwidthRow `json:"rows"`
+	BytesUploaded   int64          `json:"bytes_uploaded"`
+	BytesDownloaded int64          `json:"bytes_downloaded"`
+}
+
+// Total retorna os bytes enviados e recebidos no mês
+func (r *BandwidthReport) Total() int64 {
+	return r.BytesUploaded + r.BytesDownloaded
+}
+
+// BandwidthService monta os relatórios de uso de banda
+type BandwidthService struct {
+	bandwidthRepo *repositories.BandwidthRepository
+}
+
+// NewBandwidthService cria um novo serviço de uso de banda
+func NewBandwidthService(bandwidthRepo *repositories.BandwidthRepository) *BandwidthService {
+	return &BandwidthService{bandwidthRepo: bandwidthRepo}
+}
+
+// MonthlyReport soma o tráfego do mês (YYYY-MM) de deviceID, ou de todos os dispositivos
+// quando vazio, por pasta ou, com daily, por dia e pasta
+func (s *BandwidthService) MonthlyReport(month, deviceID string, daily bool) (*BandwidthReport, error) {
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return nil, i18n.Errorf("invalid month %q: use YYYY-MM", month)
+	}
+
+	usages, err := s.bandwidthRepo.FindByMonth(month, deviceID)
+	if err != nil {
+		return nil, i18n.Errorf("failed to find bandwidth usage: %w", err)
+	}
+
+	report := &BandwidthReport{Month: month, Rows: []BandwidthRow{}}
+	rows := make(map[string]int)
+	for _, usage := range usages {
+		row := BandwidthRow{FolderID: usage.FolderID}
+		if daily {
+			row.Day = usage.Day
+		}
+		key := row.Day + "/" + row.FolderID
+		n, ok := rows[key]
+		if !ok {
+			n = len(report.Rows)
+			rows[key] = n
+			report.Rows = append(report.Rows, row)
+		}
+		report.Rows[n].BytesUploaded += usage.BytesUploaded
+		report.Rows[n].BytesDownloaded += usage.BytesDownloaded
+		report.BytesUploaded += usage.BytesUploaded
+		report.BytesDownloaded += usage.BytesDownloaded
+	}
+	return report, nil
+}
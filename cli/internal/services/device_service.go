@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/common/apiclient"
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/heartbeat"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/gorm"
+)
+
+// remoteTimeout limita a duração das chamadas ao servidor
+const remoteTimeout = 30 * time.Second
+
+// ErrDeviceNotFound é retornado quando o dispositivo não existe
+var ErrDeviceNotFound = i18n.NewError("device not found")
+
+// ErrDeviceOtherUser é retornado quando este dispositivo já está registrado para outro usuário
+var ErrDeviceOtherUser = i18n.NewError("this device is registered to another user; give each user a profile of its own with 'sync-manager config profile create'")
+
+// DeviceService lida com a lógica de negócios relacionada a dispositivos.
+// Quando há um servidor configurado e o dispositivo está logado, os dados vêm da API;
+// caso contrário, do banco de dados local.
+type DeviceService struct {
+	deviceRepo    *repositories.DeviceRepository
+	config        *config.Config
+	heartbeatPath string
+	remote        *apiclient.Client
+}
+
+// NewDeviceService cria um novo serviço de dispositivos. remote pode ser nil.
+func NewDeviceService(deviceRepo *repositories.DeviceRepository, config *config.Config, heartbeatPath string, remote *apiclient.Client) *DeviceService {
+	return &DeviceService{
+		deviceRepo:    deviceRepo,
+		config:        config,
+		heartbeatPath: heartbeatPath,
+		remote:        remote,
+	}
+}
+
+// IsRemote indica se os dispositivos vêm do servidor
+func (s *DeviceService) IsRemote() bool {
+	return s.remote != nil
+}
+
+// SyncCurrentDevice registra este dispositivo no banco local e atualiza o último heartbeat do agente
+func (s *DeviceService) SyncCurrentDevice(userID uint) (*models.Device, error) {
+	device, err := s.deviceRepo.FindByDeviceID(userID, s.config.DeviceID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, i18n.Errorf("failed to find current device: %w", err)
+	}
+	if device == nil {
+		// O DeviceID é único: outro usuário com a mesma configuração já o registrou
+		taken, err := s.deviceRepo.RegisteredToOtherUser(userID, s.config.DeviceID)
+		if err != nil {
+			return nil, i18n.Errorf("failed to find current device: %w", err)
+		}
+		if taken {
+			return nil, ErrDeviceOtherUser
+		}
+		device = &models.Device{
+			UserID:   userID,
+			DeviceID: s.config.DeviceID,
+			Status:   models.StatusActive,
+		}
+	}
+
+	device.Name = s.config.DeviceName
+	device.OS = runtime.GOOS
+	device.Platform = runtime.GOARCH
+
+	if s.heartbeatPath != "" {
+		hb, err := heartbeat.Read(s.heartbeatPath)
+		if err == nil && hb != nil && hb.DeviceID == s.config.DeviceID && hb.Time.After(device.LastSeenAt) {
+			device.LastSeenAt = hb.Time
+			device.ClientVersion = hb.Version
+		}
+	}
+
+	if device.ID == 0 {
+		err = s.deviceRepo.Create(device)
+	} else {
+		err = s.deviceRepo.Update(device)
+	}
+	if err != nil {
+		return nil, i18n.Errorf("failed to save current device: %w", err)
+	}
+
+	return device, nil
+}
+
+// ListDevices retorna os dispositivos do usuário
+func (s *DeviceService) ListDevices(userID uint) ([]models.DeviceResponse, error) {
+	if s.remote != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+		defer cancel()
+		return s.remote.ListDevices(ctx)
+	}
+
+	if _, err := s.SyncCurrentDevice(userID); err != nil {
+		return nil, err
+	}
+
+	devices, err := s.deviceRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, i18n.Errorf("failed to list devices: %w", err)
+	}
+
+	responses := make([]models.DeviceResponse, 0, len(devices))
+	for i := range devices {
+		responses = append(responses, deviceResponse(&devices[i]))
+	}
+	return responses, nil
+}
+
+// GetDevice busca um dispositivo do usuário pelo DeviceID
+func (s *DeviceService) GetDevice(userID uint, deviceID string) (*models.DeviceResponse, error) {
+	if s.remote != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+		defer cancel()
+		device, err := s.remote.GetDevice(ctx, deviceID)
+		var apiErr *apiclient.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == 404 {
+			return nil, ErrDeviceNotFound
+		}
+		return device, err
+	}
+
+	if deviceID == s.config.DeviceID {
+		if _, err := s.SyncCurrentDevice(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	device, err := s.findUserDevice(userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	response := deviceResponse(device)
+	return &response, nil
+}
+
+// RenameDevice altera o nome de um dispositivo
+func (s *DeviceService) RenameDevice(userID uint, deviceID, name string) error {
+	if s.remote != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+		defer cancel()
+		return s.remote.RenameDevice(ctx, deviceID, name)
+	}
+
+	device, err := s.findUserDevice(userID, deviceID)
+	if errors.Is(err, ErrDeviceNotFound) && deviceID == s.config.DeviceID {
+		// O dispositivo atual é registrado com o novo nome
+		_, err = s.SyncCurrentDevice(userID)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	device.Name = name
+	return s.deviceRepo.Update(device)
+}
+
+// UnlinkDevice remove um dispositivo da conta e revoga seus tokens
+func (s *DeviceService) UnlinkDevice(userID uint, deviceID string) error {
+	if s.remote != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+		defer cancel()
+		err := s.remote.UnlinkDevice(ctx, deviceID)
+		var apiErr *apiclient.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == 404 {
+			return ErrDeviceNotFound
+		}
+		return err
+	}
+
+	device, err := s.findUserDevice(userID, deviceID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.deviceRepo.RevokeDeviceTokens(device.ID); err != nil {
+		return i18n.Errorf("failed to revoke device tokens: %w", err)
+	}
+	if err := s.deviceRepo.DeleteWithFolders(device.ID); err != nil {
+		return i18n.Errorf("failed to delete device: %w", err)
+	}
+	return nil
+}
+
+// findUserDevice busca um dispositivo no banco local garantindo que pertence ao usuário
+func (s *DeviceService) findUserDevice(userID uint, deviceID string) (*models.Device, error) {
+	device, err := s.deviceRepo.FindByDeviceID(userID, deviceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, i18n.Errorf("failed to find device: %w", err)
+	}
+	return device, nil
+}
+
+// deviceResponse converte um dispositivo para sua representação de resposta
+func deviceResponse(device *models.Device) models.DeviceResponse {
+	return models.DeviceResponse{
+		ID:            device.ID,
+		DeviceID:      device.DeviceID,
+		Name:          device.Name,
+		LastSeenAt:    device.LastSeenAt,
+		Status:        device.Status,
+		ClientVersion: device.ClientVersion,
+		Platform:      device.Platform,
+		OS:            device.OS,
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/common/excludes"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+)
+
+// ErrExcludeNotFound é retornado ao remover uma regra que não existe
+var ErrExcludeNotFound = i18n.NewError("exclude rule not found")
+
+// ExcludeService lida com as regras de exclusão globais, por pasta e por dispositivo
+type ExcludeService struct {
+	excludeRepo *repositories.ExcludeRepository
+}
+
+// NewExcludeService cria um novo serviço de regras de exclusão
+func NewExcludeService(excludeRepo *repositories.ExcludeRepository) *ExcludeService {
+	return &ExcludeService{excludeRepo: excludeRepo}
+}
+
+// Add guarda uma regra, retornando false se uma regra igual já existir
+func (s *ExcludeService) Add(rule models.ExcludeRule) (bool, error) {
+	if rule.Pattern == "" {
+		return false, errors.New(i18n.T("exclude pattern is empty"))
+	}
+	if _, err := filepath.Match(rule.Pattern, ""); err != nil {
+		return false, i18n.Errorf("invalid exclude pattern %q: %w", rule.Pattern, err)
+	}
+	// Uma exceção vale só para um dispositivo, senão bastaria remover a regra
+	if rule.Allow && rule.DeviceID == "" {
+		return false, errors.New(i18n.T("an allow rule must be limited to a device"))
+	}
+
+	existing, err := s.excludeRepo.FindMatching(rule)
+	if err != nil {
+		return false, i18n.Errorf("failed to find exclude rule: %w", err)
+	}
+	if existing != nil {
+		return false, nil
+	}
+	if err := s.excludeRepo.Create(&rule); err != nil {
+		return false, i18n.Errorf("failed to create exclude rule: %w", err)
+	}
+	return true, nil
+}
+
+// Remove exclui a regra com o mesmo padrão e escopo
+func (s *ExcludeService) Remove(rule models.ExcludeRule) error {
+	existing, err := s.excludeRepo.FindMatching(rule)
+	if err != nil {
+		return i18n.Errorf("failed to find exclude rule: %w", err)
+	}
+	if existing == nil {
+		return ErrExcludeNotFound
+	}
+	if err := s.excludeRepo.Delete(existing.ID); err != nil {
+		return i18n.Errorf("failed to delete exclude rule: %w", err)
+	}
+	return nil
+}
+
+// List retorna todas as regras
+func (s *ExcludeService) List() ([]models.ExcludeRule, error) {
+	rules, err := s.excludeRepo.FindAll()
+	if err != nil {
+		return nil, i18n.Errorf("failed to list exclude rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Effective retorna os padrões usados por uma pasta neste dispositivo
+func (s *ExcludeService) Effective(folderID, deviceID string, folderPatterns []string) ([]string, error) {
+	rules, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return excludes.Merge(folderPatterns, rules, folderID, deviceID), nil
+}
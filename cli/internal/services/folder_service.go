@@ -1,12 +1,12 @@
 package services
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
 	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
 	"github.com/martinshumberto/sync-manager/common/models"
 )
 
@@ -27,14 +27,18 @@ func NewFolderService(folderRepo *repositories.FolderRepository, config *config.
 // CreateFolder cria uma nova pasta no banco de dados e na configuração
 func (s *FolderService) CreateFolder(userID uint, name string, path string, encryptionEnabled bool, priority int, twoWaySync bool) (*models.Folder, error) {
 	// Cria um ID único para a pasta
-	folderID := uuid.New().String()
+	return s.CreateFolderWithID(uuid.New().String(), userID, name, path, encryptionEnabled, priority, twoWaySync)
+}
 
+// CreateFolderWithID cria uma pasta com um ID já existente, como o de uma pasta remota
+// sincronizada por outro dispositivo
+func (s *FolderService) CreateFolderWithID(folderID string, userID uint, name string, path string, encryptionEnabled bool, priority int, twoWaySync bool) (*models.Folder, error) {
 	// Cria a pasta no banco de dados
 	folder := &models.Folder{
 		UserID:            userID,
 		FolderID:          folderID,
 		Name:              name,
-		Status:            "active",
+		Status:            models.StatusActive,
 		EncryptionEnabled: encryptionEnabled,
 		CreatedAt:         time.Now(),
 		UpdatedAt:         time.Now(),
@@ -42,7 +46,7 @@ func (s *FolderService) CreateFolder(userID uint, name string, path string, encr
 
 	err := s.folderRepo.Create(folder)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao criar pasta no banco de dados: %w", err)
+		return nil, i18n.Errorf("failed to create folder in the database: %w", err)
 	}
 
 	// Adiciona a pasta à configuração
@@ -60,9 +64,9 @@ func (s *FolderService) CreateFolder(userID uint, name string, path string, encr
 	return folder, nil
 }
 
-// GetFolder busca uma pasta pelo ID único
-func (s *FolderService) GetFolder(folderID string) (*models.Folder, error) {
-	return s.folderRepo.FindByFolderID(folderID)
+// GetFolder busca uma pasta do usuário pelo ID único
+func (s *FolderService) GetFolder(userID uint, folderID string) (*models.Folder, error) {
+	return s.folderRepo.FindByFolderID(userID, folderID)
 }
 
 // GetUserFolders busca todas as pastas de um usuário
@@ -71,11 +75,15 @@ func (s *FolderService) GetUserFolders(userID uint) ([]models.Folder, error) {
 }
 
 // UpdateFolder atualiza uma pasta no banco de dados e na configuração
-func (s *FolderService) UpdateFolder(folderID string, name, status string, encryptionEnabled bool) error {
+func (s *FolderService) UpdateFolder(userID uint, folderID string, name string, status models.Status, encryptionEnabled bool) error {
 	// Busca a pasta primeiro
-	folder, err := s.folderRepo.FindByFolderID(folderID)
+	folder, err := s.folderRepo.FindByFolderID(userID, folderID)
 	if err != nil {
-		return fmt.Errorf("erro ao buscar pasta para atualização: %w", err)
+		return i18n.Errorf("failed to find folder to update: %w", err)
+	}
+
+	if err := models.FolderStatuses.Transition(folder.Status, status); err != nil {
+		return i18n.Errorf("failed to update folder: %w", err)
 	}
 
 	// Atualiza os campos
@@ -87,13 +95,13 @@ func (s *FolderService) UpdateFolder(folderID string, name, status string, encry
 	// Salva no banco de dados
 	err = s.folderRepo.Update(folder)
 	if err != nil {
-		return fmt.Errorf("erro ao atualizar pasta no banco de dados: %w", err)
+		return i18n.Errorf("failed to update folder in the database: %w", err)
 	}
 
 	// Atualiza na configuração
 	for i, configFolder := range s.config.SyncFolders {
 		if configFolder.ID == folderID {
-			s.config.SyncFolders[i].Enabled = (status == "active")
+			s.config.SyncFolders[i].Enabled, s.config.SyncFolders[i].Paused = status.Flags()
 			break
 		}
 	}
@@ -104,17 +112,17 @@ func (s *FolderService) UpdateFolder(folderID string, name, status string, encry
 }
 
 // DeleteFolder remove uma pasta do banco de dados e da configuração
-func (s *FolderService) DeleteFolder(folderID string) error {
+func (s *FolderService) DeleteFolder(userID uint, folderID string) error {
 	// Busca a pasta primeiro
-	folder, err := s.folderRepo.FindByFolderID(folderID)
+	folder, err := s.folderRepo.FindByFolderID(userID, folderID)
 	if err != nil {
-		return fmt.Errorf("erro ao buscar pasta para exclusão: %w", err)
+		return i18n.Errorf("failed to find folder to delete: %w", err)
 	}
 
 	// Remove do banco de dados
 	err = s.folderRepo.Delete(folder.ID)
 	if err != nil {
-		return fmt.Errorf("erro ao excluir pasta do banco de dados: %w", err)
+		return i18n.Errorf("failed to delete folder from the database: %w", err)
 	}
 
 	// Remove da configuração
@@ -131,11 +139,11 @@ func (s *FolderService) DeleteFolder(folderID string) error {
 }
 
 // AssociateFolderWithDevice associa uma pasta a um dispositivo
-func (s *FolderService) AssociateFolderWithDevice(deviceID uint, folderID string, localPath string, syncDirection string, excludePatterns []string) error {
+func (s *FolderService) AssociateFolderWithDevice(userID uint, deviceID uint, folderID string, localPath string, syncDirection string, excludePatterns []string) error {
 	// Busca a pasta primeiro
-	folder, err := s.folderRepo.FindByFolderID(folderID)
+	folder, err := s.folderRepo.FindByFolderID(userID, folderID)
 	if err != nil {
-		return fmt.Errorf("erro ao buscar pasta para associação: %w", err)
+		return i18n.Errorf("failed to find folder to associate: %w", err)
 	}
 
 	// Cria a associação
@@ -147,7 +155,7 @@ func (s *FolderService) AssociateFolderWithDevice(deviceID uint, folderID string
 		SyncEnabled:     true,
 		SyncDirection:   syncDirection,
 		ExcludePatterns: excludePatternsArray,
-		Status:          "active",
+		Status:          models.StatusActive,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
@@ -161,26 +169,33 @@ func (s *FolderService) GetDeviceFolders(deviceID uint) ([]models.DeviceFolder,
 }
 
 // UpdateFolderStatus atualiza o status de uma pasta
-func (s *FolderService) UpdateFolderStatus(folderID string, enabled bool) error {
+func (s *FolderService) UpdateFolderStatus(userID uint, folderID string, enabled bool) error {
 	// Busca a pasta primeiro
-	folder, err := s.folderRepo.FindByFolderID(folderID)
+	folder, err := s.folderRepo.FindByFolderID(userID, folderID)
 	if err != nil {
-		return fmt.Errorf("erro ao buscar pasta para atualização de status: %w", err)
+		return i18n.Errorf("failed to find folder to update its status: %w", err)
 	}
 
-	// Atualiza o status
-	status := "active"
-	if !enabled {
-		status = "disabled"
+	// Atualiza o status, preservando a pausa da pasta
+	paused := false
+	for _, configFolder := range s.config.SyncFolders {
+		if configFolder.ID == folderID {
+			paused = configFolder.Paused
+			break
+		}
 	}
 
+	status := models.FolderStatusOf(enabled, paused)
+	if err := models.FolderStatuses.Transition(folder.Status, status); err != nil {
+		return i18n.Errorf("failed to update folder status: %w", err)
+	}
 	folder.Status = status
 	folder.UpdatedAt = time.Now()
 
 	// Salva no banco de dados
 	err = s.folderRepo.Update(folder)
 	if err != nil {
-		return fmt.Errorf("erro ao atualizar status da pasta no banco de dados: %w", err)
+		return i18n.Errorf("failed to update folder status in the database: %w", err)
 	}
 
 	// Atualiza na configuração
@@ -195,3 +210,35 @@ func (s *FolderService) UpdateFolderStatus(folderID string, enabled bool) error
 
 	return nil
 }
+
+// SetFolderPaused pausa ou retoma a sincronização de uma pasta sem desabilitá-la
+func (s *FolderService) SetFolderPaused(userID uint, folderID string, paused bool) error {
+	folder, err := s.folderRepo.FindByFolderID(userID, folderID)
+	if err != nil {
+		return i18n.Errorf("failed to find folder to pause: %w", err)
+	}
+
+	enabled, _ := folder.Status.Flags()
+	status := models.FolderStatusOf(enabled, paused)
+	if err := models.FolderStatuses.Transition(folder.Status, status); err != nil {
+		return i18n.Errorf("failed to update folder pause: %w", err)
+	}
+	folder.Status = status
+	folder.UpdatedAt = time.Now()
+
+	if err := s.folderRepo.Update(folder); err != nil {
+		return i18n.Errorf("failed to update folder pause in the database: %w", err)
+	}
+
+	// Atualiza na configuração
+	for i, configFolder := range s.config.SyncFolders {
+		if configFolder.ID == folderID {
+			s.config.SyncFolders[i].Paused = paused
+			break
+		}
+	}
+
+	// Nota: A configuração precisa ser salva pelo chamador
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+)
+
+// Tipos de divergência entre o banco de dados e a configuração
+const (
+	// DriftMissingRecord é uma pasta da configuração sem registro no banco
+	DriftMissingRecord = "missing-record"
+	// DriftStaleRecord é um registro do banco de uma pasta que não está mais na configuração
+	DriftStaleRecord = "stale-record"
+	// DriftStatus é um registro cujo status difere do estado da pasta na configuração
+	DriftStatus = "status"
+)
+
+// FolderDrift é uma divergência entre o registro de uma pasta no banco e a configuração
+type FolderDrift struct {
+	FolderID string
+	Kind     string        // Um dos valores Drift
+	Expected models.Status // Status segundo a configuração, para DriftStatus
+	Actual   models.Status // Status no banco, para DriftStatus
+}
+
+// Describe descreve a divergência para o usuário
+func (d FolderDrift) Describe() string {
+	switch d.Kind {
+	case DriftMissingRecord:
+		return i18n.Sprintf("folder %s is configured but missing from the database", d.FolderID)
+	case DriftStaleRecord:
+		return i18n.Sprintf("folder %s is in the database but no longer configured", d.FolderID)
+	default:
+		return i18n.Sprintf("folder %s is %s in the database but %s in the configuration", d.FolderID, d.Actual, d.Expected)
+	}
+}
+
+// CheckFolders compara as pastas do usuário no banco com as da configuração. A
+// configuração é a fonte de verdade, pois é o que o agente sincroniza.
+func (s *FolderService) CheckFolders(userID uint) ([]FolderDrift, error) {
+	records, err := s.folderRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, i18n.Errorf("failed to find folders in the database: %w", err)
+	}
+	byID := make(map[string]models.Folder, len(records))
+	for _, record := range records {
+		byID[record.FolderID] = record
+	}
+
+	var drifts []FolderDrift
+	configured := make(map[string]bool, len(s.config.SyncFolders))
+	for _, folder := range s.config.SyncFolders {
+		configured[folder.ID] = true
+		record, ok := byID[folder.ID]
+		if !ok {
+			drifts = append(drifts, FolderDrift{FolderID: folder.ID, Kind: DriftMissingRecord})
+			continue
+		}
+		if expected := models.FolderStatusOf(folder.Enabled, folder.Paused); record.Status != expected {
+			drifts = append(drifts, FolderDrift{FolderID: folder.ID, Kind: DriftStatus, Expected: expected, Actual: record.Status})
+		}
+	}
+	for _, record := range records {
+		if !configured[record.FolderID] {
+			drifts = append(drifts, FolderDrift{FolderID: record.FolderID, Kind: DriftStaleRecord})
+		}
+	}
+	return drifts, nil
+}
+
+// RepairFolders corrige as divergências encontradas por CheckFolders alterando o banco para
+// refletir a configuração. Registros de pastas removidas são excluídos com soft delete e
+// voltam se a pasta for configurada de novo.
+func (s *FolderService) RepairFolders(userID uint, drifts []FolderDrift) error {
+	for _, drift := range drifts {
+		var err error
+		switch drift.Kind {
+		case DriftMissingRecord:
+			err = s.restoreRecord(userID, drift.FolderID)
+		case DriftStaleRecord:
+			var record *models.Folder
+			if record, err = s.folderRepo.FindByFolderID(userID, drift.FolderID); err == nil {
+				err = s.folderRepo.Delete(record.ID)
+			}
+		case DriftStatus:
+			var record *models.Folder
+			if record, err = s.folderRepo.FindByFolderID(userID, drift.FolderID); err == nil {
+				record.Status = drift.Expected
+				record.UpdatedAt = time.Now()
+				err = s.folderRepo.Update(record)
+			}
+		}
+		if err != nil {
+			return i18n.Errorf("failed to fix folder %s: %w", drift.FolderID, err)
+		}
+	}
+	return nil
+}
+
+// restoreRecord cria o registro de uma pasta configurada, reaproveitando o que foi excluído
+func (s *FolderService) restoreRecord(userID uint, folderID string) error {
+	var folder *config.SyncFolder
+	for i := range s.config.SyncFolders {
+		if s.config.SyncFolders[i].ID == folderID {
+			folder = &s.config.SyncFolders[i]
+			break
+		}
+	}
+	if folder == nil {
+		return i18n.Errorf("folder %s is not in the configuration", folderID)
+	}
+	status := models.FolderStatusOf(folder.Enabled, folder.Paused)
+
+	if record, err := s.folderRepo.FindDeletedByFolderID(userID, folderID); err == nil {
+		record.Status = status
+		record.UpdatedAt = time.Now()
+		return s.folderRepo.Restore(record)
+	}
+
+	return s.folderRepo.Create(&models.Folder{
+		UserID:    userID,
+		FolderID:  folderID,
+		Name:      filepath.Base(folder.Path),
+		Status:    status,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+)
+
+// SyncRunService consulta os resumos das sincronizações do agente
+type SyncRunService struct {
+	runRepo *repositories.SyncRunRepository
+}
+
+// NewSyncRunService cria um novo serviço de execuções de sincronização
+func NewSyncRunService(runRepo *repositories.SyncRunRepository) *SyncRunService {
+	return &SyncRunService{runRepo: runRepo}
+}
+
+// LastRuns retorna a última sincronização de cada pasta de deviceID, ou só a de folderID
+func (s *SyncRunService) LastRuns(deviceID, folderID string) ([]models.SyncRun, error) {
+	runs, err := s.runRepo.FindLatest(deviceID, folderID)
+	if err != nil {
+		return nil, i18n.Errorf("failed to find sync runs: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/common/tokens"
+	"gorm.io/gorm"
+)
+
+// DefaultTokenLifetime é a validade de um token criado sem --expires
+const DefaultTokenLifetime = 90 * 24 * time.Hour
+
+// ErrTokenNotFound é retornado quando o token não existe ou é de outro usuário
+var ErrTokenNotFound = errors.New("token not found")
+
+// TokenService lida com os tokens de API dos usuários locais. Só o hash de cada token é
+// guardado; o token em si é mostrado uma única vez, ao ser criado. O agente os verifica com
+// o pacote tokens.
+type TokenService struct {
+	tokenRepo *repositories.TokenRepository
+}
+
+// NewTokenService cria um novo serviço de tokens de API
+func NewTokenService(tokenRepo *repositories.TokenRepository) *TokenService {
+	return &TokenService{tokenRepo: tokenRepo}
+}
+
+// CreateToken gera um token para o usuário, válido por lifetime, e o retorna em Token
+func (s *TokenService) CreateToken(userID uint, name string, lifetime time.Duration) (*models.ApiTokenResponse, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New(i18n.T("token name is empty"))
+	}
+	if lifetime <= 0 {
+		return nil, errors.New(i18n.T("token lifetime must be positive"))
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, i18n.Errorf("failed to generate token: %w", err)
+	}
+	plain := tokens.Prefix + hex.EncodeToString(buf)
+
+	now := time.Now()
+	token := &models.ApiToken{
+		UserID:    userID,
+		Token:     models.HashToken(plain),
+		Name:      name,
+		ExpiresAt: now.Add(lifetime),
+	}
+	if err := s.tokenRepo.Create(token); err != nil {
+		return nil, i18n.Errorf("failed to save token: %w", err)
+	}
+
+	return &models.ApiTokenResponse{
+		ID:        token.ID,
+		Name:      token.Name,
+		Token:     plain,
+		ExpiresAt: token.ExpiresAt,
+		CreatedAt: token.CreatedAt,
+	}, nil
+}
+
+// ListTokens retorna os tokens do usuário, revogados e expirados incluídos
+func (s *TokenService) ListTokens(userID uint) ([]models.ApiToken, error) {
+	tokens, err := s.tokenRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, i18n.Errorf("failed to list tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken revoga um token do usuário; ele deixa de autenticar na hora
+func (s *TokenService) RevokeToken(userID uint, id uint) error {
+	if _, err := s.tokenRepo.FindByID(userID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTokenNotFound
+		}
+		return i18n.Errorf("failed to find token: %w", err)
+	}
+	if err := s.tokenRepo.Revoke(userID, id); err != nil {
+		return i18n.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// TokenState descreve se um token ainda autentica: "active", "expired" ou "revoked"
+func TokenState(token models.ApiToken, now time.Time) string {
+	switch {
+	case token.Revoked:
+		return "revoked"
+	case !token.ExpiresAt.After(now):
+		return "expired"
+	default:
+		return "active"
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/cli/internal/repositories"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/gorm"
+)
+
+// DefaultUserID é o usuário criado na primeira execução, usado enquanto nenhum outro é escolhido
+const DefaultUserID uint = 1
+
+// ErrUserNotFound é retornado quando o usuário não existe
+var ErrUserNotFound = i18n.NewError("user not found")
+
+// UserService lida com os usuários do banco local. Cada usuário tem suas próprias pastas e
+// dispositivos; os repositórios só encontram os registros do usuário informado.
+type UserService struct {
+	userRepo *repositories.UserRepository
+}
+
+// NewUserService cria um novo serviço de usuários
+func NewUserService(userRepo *repositories.UserRepository) *UserService {
+	return &UserService{userRepo: userRepo}
+}
+
+// EnsureDefaultUser garante que o usuário padrão existe no banco de dados
+func (s *UserService) EnsureDefaultUser() error {
+	if _, err := s.userRepo.FindByID(DefaultUserID); err == nil {
+		return nil
+	}
+
+	user := &models.User{
+		ID:           DefaultUserID,
+		Email:        "user@localhost",
+		Name:         "Local User",
+		Status:       models.StatusActive,
+		Verified:     true,
+		StorageQuota: 10737418240, // 10GB
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return i18n.Errorf("failed to create default user: %w", err)
+	}
+	return nil
+}
+
+// CreateUser cria um usuário local identificado pelo email
+func (s *UserService) CreateUser(email, name string) (*models.User, error) {
+	email = strings.TrimSpace(email)
+	if !strings.Contains(email, "@") {
+		return nil, i18n.Errorf("invalid email %q", email)
+	}
+	if _, err := s.userRepo.FindByEmail(email); err == nil {
+		return nil, i18n.Errorf("user %s already exists", email)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, i18n.Errorf("failed to find user: %w", err)
+	}
+
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	user := &models.User{
+		Email:    email,
+		Name:     name,
+		Status:   models.StatusActive,
+		Verified: true,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, i18n.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// ListUsers retorna todos os usuários locais
+func (s *UserService) ListUsers() ([]models.User, error) {
+	users, err := s.userRepo.FindAll()
+	if err != nil {
+		return nil, i18n.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// FindUser busca um usuário pelo ID ou pelo email
+func (s *UserService) FindUser(ref string) (*models.User, error) {
+	var user *models.User
+	var err error
+	if id, parseErr := strconv.ParseUint(ref, 10, 32); parseErr == nil {
+		user, err = s.userRepo.FindByID(uint(id))
+	} else {
+		user, err = s.userRepo.FindByEmail(strings.TrimSpace(ref))
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, ref)
+	}
+	if err != nil {
+		return nil, i18n.Errorf("failed to find user: %w", err)
+	}
+	return user, nil
+}
+
+// ResolveUser retorna o usuário escolhido, ou o usuário padrão quando nenhum foi escolhido
+// ou o escolhido não existe mais
+func (s *UserService) ResolveUser(selected uint) (uint, error) {
+	if selected == 0 || selected == DefaultUserID {
+		return DefaultUserID, nil
+	}
+	if _, err := s.userRepo.FindByID(selected); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return DefaultUserID, fmt.Errorf("%w: %d", ErrUserNotFound, selected)
+		}
+		return DefaultUserID, i18n.Errorf("failed to find user: %w", err)
+	}
+	return selected, nil
+}
+
+// Language retorna o idioma preferido do usuário, ou "" quando ele não escolheu um e o
+// idioma vem do ambiente
+func (s *UserService) Language(userID uint) (string, error) {
+	preferences, err := s.userRepo.FindUserPreferences(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", i18n.Errorf("failed to find user preferences: %w", err)
+	}
+	return preferences.Language, nil
+}
+
+// SetLanguage guarda o idioma preferido do usuário; "" volta a usar o idioma do ambiente
+func (s *UserService) SetLanguage(userID uint, lang string) error {
+	lang = i18n.Normalize(lang)
+	if lang != "" && !i18n.Supported(lang) {
+		return i18n.Errorf("unsupported language %q (supported: %s)", lang, strings.Join(i18n.Languages(), ", "))
+	}
+
+	preferences, err := s.userRepo.GetUserPreferences(userID)
+	if err != nil {
+		return i18n.Errorf("failed to find user preferences: %w", err)
+	}
+	preferences.Language = lang
+	if err := s.userRepo.UpdateUserPreferences(preferences); err != nil {
+		return i18n.Errorf("failed to save user preferences: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/rs/zerolog/log"
+)
+
+// API paths used by agents and the CLI
+const (
+	PathLogin              = "/api/v1/auth/login"
+	PathRegisterDevice     = "/api/v1/devices/register"
+	PathRefreshDeviceToken = "/api/v1/devices/token/refresh"
+)
+
+// RefreshWindow is how long before expiry a device token is rotated
+const RefreshWindow = 24 * time.Hour
+
+// ErrNotLoggedIn is returned when a call needs a device token but none is stored
+var ErrNotLoggedIn = errors.New("device is not logged in, run 'sync-manager login'")
+
+// Client talks to the coordination server on behalf of a device
+type Client struct {
+	endpoint        string
+	httpClient      *http.Client
+	credentialsPath string
+	creds           *Credentials
+	mu              sync.Mutex
+}
+
+// NewClient creates a client for endpoint, loading stored credentials from credentialsPath
+func NewClient(endpoint, credentialsPath string) (*Client, error) {
+	creds, err := LoadCredentials(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		credentialsPath: credentialsPath,
+		creds:           creds,
+	}, nil
+}
+
+// SetTransport routes requests through rt, e.g. to reach the server through a proxy
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
+// Credentials returns a copy of the stored credentials, or nil when not logged in
+func (c *Client) Credentials() *Credentials {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.creds == nil {
+		return nil
+	}
+	creds := *c.creds
+	return &creds
+}
+
+// Login exchanges a user's email and password for an API token
+func (c *Client) Login(ctx context.Context, email, password string) (*models.LoginResponse, error) {
+	var resp models.LoginResponse
+	req := models.LoginRequest{Email: email, Password: password}
+	if err := c.call(ctx, http.MethodPost, PathLogin, "", req, &resp); err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+	return &resp, nil
+}
+
+// RegisterDevice registers this device using a user API token and stores the issued device token
+func (c *Client) RegisterDevice(ctx context.Context, apiToken, email string, req models.DeviceRegistrationRequest) (*models.DeviceRegistrationResponse, error) {
+	var resp models.DeviceRegistrationResponse
+	if err := c.call(ctx, http.MethodPost, PathRegisterDevice, apiToken, req, &resp); err != nil {
+		return nil, fmt.Errorf("device registration failed: %w", err)
+	}
+
+	deviceID := resp.Device.DeviceID
+	if deviceID == "" {
+		deviceID = req.DeviceID
+	}
+
+	if err := c.storeCredentials(&Credentials{
+		Endpoint:    c.endpoint,
+		UserEmail:   email,
+		DeviceID:    deviceID,
+		DeviceToken: resp.Token,
+		ExpiresAt:   resp.ExpiresAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// RefreshToken rotates the device token and stores the new one
+func (c *Client) RefreshToken(ctx context.Context) error {
+	c.mu.Lock()
+	creds := c.creds
+	c.mu.Unlock()
+
+	if creds == nil || creds.DeviceToken == "" {
+		return ErrNotLoggedIn
+	}
+
+	var resp models.DeviceTokenResponse
+	if err := c.call(ctx, http.MethodPost, PathRefreshDeviceToken, creds.DeviceToken, nil, &resp); err != nil {
+		return fmt.Errorf("failed to refresh device token: %w", err)
+	}
+
+	updated := *creds
+	updated.DeviceToken = resp.Token
+	updated.ExpiresAt = resp.ExpiresAt
+
+	log.Info().Time("expires_at", resp.ExpiresAt).Msg("Device token refreshed")
+	return c.storeCredentials(&updated)
+}
+
+// Do performs an authenticated call as this device, rotating the token first if it is about to expire.
+// body is JSON encoded and the response data is decoded into out when not nil.
+func (c *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	token, err := c.deviceToken(ctx)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, method, path, token, body, out)
+}
+
+// StartTokenRefresher rotates the device token in the background until ctx is cancelled
+func (c *Client) StartTokenRefresher(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := c.deviceToken(ctx); err != nil && !errors.Is(err, ErrNotLoggedIn) {
+				log.Warn().Err(err).Msg("Failed to refresh device token")
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Logout forgets the stored device token
+func (c *Client) Logout() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.creds = nil
+	return DeleteCredentials(c.credentialsPath)
+}
+
+// deviceToken returns a valid device token, refreshing it when it is close to expiry
+func (c *Client) deviceToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	creds := c.creds
+	c.mu.Unlock()
+
+	if creds == nil || creds.DeviceToken == "" {
+		return "", ErrNotLoggedIn
+	}
+
+	if creds.ExpiresWithin(RefreshWindow) {
+		if err := c.RefreshToken(ctx); err != nil {
+			if creds.Expired() {
+				return "", err
+			}
+			// The current token still works, try again later
+			log.Warn().Err(err).Msg("Device token refresh failed, using current token")
+			return creds.DeviceToken, nil
+		}
+		return c.Credentials().DeviceToken, nil
+	}
+
+	return creds.DeviceToken, nil
+}
+
+// storeCredentials persists credentials and keeps them in memory
+func (c *Client) storeCredentials(creds *Credentials) error {
+	if err := SaveCredentials(c.credentialsPath, creds); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.creds = creds
+	c.mu.Unlock()
+
+	return nil
+}
+
+// call sends a JSON request and unwraps the server's success/error envelope
+func (c *Client) call(ctx context.Context, method, path, token string, body, out interface{}) error {
+	if c.endpoint == "" {
+		return fmt.Errorf("API endpoint is not configured")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if creds := c.Credentials(); creds != nil && creds.DeviceID != "" {
+		req.Header.Set("X-Device-ID", creds.DeviceID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		var apiErr models.ErrorResponse
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return &APIError{StatusCode: resp.StatusCode, Message: apiErr.Message}
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response data: %w", err)
+	}
+
+	return nil
+}
+
+// APIError is an error returned by the server
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,116 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServer answers the auth endpoints and records the tokens it receives
+type fakeServer struct {
+	refreshes  int
+	lastAuth   string
+	lastDevice string
+}
+
+func (f *fakeServer) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(PathLogin, func(w http.ResponseWriter, r *http.Request) {
+		var req models.LoginRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.Password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(models.NewErrorResponse(http.StatusUnauthorized, "invalid credentials", nil))
+			return
+		}
+		json.NewEncoder(w).Encode(models.NewSuccessResponse(http.StatusOK, "", models.LoginResponse{
+			Token: models.ApiTokenResponse{Token: "api-token"},
+		}))
+	})
+
+	mux.HandleFunc(PathRegisterDevice, func(w http.ResponseWriter, r *http.Request) {
+		f.lastAuth = r.Header.Get("Authorization")
+		var req models.DeviceRegistrationRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		json.NewEncoder(w).Encode(models.NewSuccessResponse(http.StatusOK, "", models.DeviceRegistrationResponse{
+			Device:    models.Device{DeviceID: req.DeviceID, Name: req.Name},
+			Token:     "device-token-1",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}))
+	})
+
+	mux.HandleFunc(PathRefreshDeviceToken, func(w http.ResponseWriter, r *http.Request) {
+		f.lastAuth = r.Header.Get("Authorization")
+		f.refreshes++
+		json.NewEncoder(w).Encode(models.NewSuccessResponse(http.StatusOK, "", models.DeviceTokenResponse{
+			Token:     "device-token-2",
+			ExpiresAt: time.Now().Add(30 * 24 * time.Hour),
+		}))
+	})
+
+	mux.HandleFunc("/api/v1/ping", func(w http.ResponseWriter, r *http.Request) {
+		f.lastAuth = r.Header.Get("Authorization")
+		f.lastDevice = r.Header.Get("X-Device-ID")
+		json.NewEncoder(w).Encode(models.NewSuccessResponse(http.StatusOK, "", map[string]string{"pong": "ok"}))
+	})
+
+	return mux
+}
+
+func TestLoginRegisterAndRefresh(t *testing.T) {
+	useSecrets(t, memorySecrets{})
+	fake := &fakeServer{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	credsPath := filepath.Join(t.TempDir(), "credentials.json")
+	client, err := NewClient(server.URL, credsPath)
+	assert.NoError(t, err)
+	assert.Nil(t, client.Credentials())
+
+	_, err = client.Login(context.Background(), "user@example.com", "wrong")
+	assert.ErrorContains(t, err, "invalid credentials")
+
+	login, err := client.Login(context.Background(), "user@example.com", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "api-token", login.Token.Token)
+
+	_, err = client.RegisterDevice(context.Background(), login.Token.Token, "user@example.com", models.DeviceRegistrationRequest{
+		DeviceID: "device-1",
+		Name:     "laptop",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer api-token", fake.lastAuth)
+
+	saved, err := LoadCredentials(credsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "device-1", saved.DeviceID)
+	assert.Equal(t, "device-token-1", saved.DeviceToken)
+
+	// The token expires within the refresh window, so Do rotates it first
+	var out map[string]string
+	assert.NoError(t, client.Do(context.Background(), http.MethodGet, "/api/v1/ping", nil, &out))
+	assert.Equal(t, "ok", out["pong"])
+	assert.Equal(t, 1, fake.refreshes)
+	assert.Equal(t, "Bearer device-token-2", fake.lastAuth)
+	assert.Equal(t, "device-1", fake.lastDevice)
+
+	saved, err = LoadCredentials(credsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "device-token-2", saved.DeviceToken)
+
+	// A fresh token is used as is
+	assert.NoError(t, client.Do(context.Background(), http.MethodGet, "/api/v1/ping", nil, nil))
+	assert.Equal(t, 1, fake.refreshes)
+
+	assert.NoError(t, client.Logout())
+	assert.ErrorIs(t, client.Do(context.Background(), http.MethodGet, "/api/v1/ping", nil, nil), ErrNotLoggedIn)
+}
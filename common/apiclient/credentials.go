@@ -0,0 +1,153 @@
+package apiclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/keychain"
+)
+
+// Credentials holds the device token issued by the server. The token itself is kept in the
+// OS keychain; the file only holds it where the platform has no keychain.
+type Credentials struct {
+	Endpoint    string    `json:"endpoint"`
+	UserEmail   string    `json:"user_email,omitempty"`
+	DeviceID    string    `json:"device_id"`
+	DeviceToken string    `json:"device_token,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// SecretStore keeps device tokens out of the credentials file, as the OS keychain does
+type SecretStore interface {
+	// Get returns the secret of account, or an error wrapping keychain.ErrNotFound
+	Get(account string) ([]byte, error)
+	// Set stores secret for account, replacing the previous one
+	Set(account string, secret []byte) error
+	// Delete removes the secret of account, if any
+	Delete(account string) error
+}
+
+// keychainStore is the SecretStore of the OS keychain
+type keychainStore struct{}
+
+func (keychainStore) Get(account string) ([]byte, error) {
+	return keychain.Get(account)
+}
+
+func (keychainStore) Set(account string, secret []byte) error {
+	return keychain.Set(account, secret)
+}
+
+func (keychainStore) Delete(account string) error {
+	return keychain.Delete(account)
+}
+
+// Secrets keeps the device tokens. Tests replace it so they stay out of the user's keychain.
+var Secrets SecretStore = keychainStore{}
+
+// tokenAccount names the device token of the credentials file at path in the keychain, so
+// each configuration keeps its own
+func tokenAccount(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(path))
+	return "device-token-" + hex.EncodeToString(sum[:8])
+}
+
+// Expired reports whether the token is no longer valid
+func (c *Credentials) Expired() bool {
+	return !c.ExpiresAt.IsZero() && time.Now().After(c.ExpiresAt)
+}
+
+// ExpiresWithin reports whether the token expires within d
+func (c *Credentials) ExpiresWithin(d time.Duration) bool {
+	return !c.ExpiresAt.IsZero() && time.Until(c.ExpiresAt) < d
+}
+
+// DefaultCredentialsPath returns the default location of the credentials file
+func DefaultCredentialsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "credentials.json"), nil
+}
+
+// LoadCredentials reads credentials from path, returning nil if the device is not logged in
+func LoadCredentials(path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+
+	// Files written before the keychain was used, or where there is none, hold the token
+	if creds.DeviceToken == "" {
+		token, err := Secrets.Get(tokenAccount(path))
+		if err != nil && !errors.Is(err, keychain.ErrNotFound) && !errors.Is(err, keychain.ErrUnsupported) {
+			return nil, fmt.Errorf("failed to read device token: %w", err)
+		}
+		creds.DeviceToken = string(token)
+	}
+
+	return &creds, nil
+}
+
+// SaveCredentials stores the device token in the keychain and the rest of credentials in
+// path, readable only by the current user. Without a keychain the token goes to path too.
+func SaveCredentials(path string, creds *Credentials) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+
+	stored := *creds
+	err := Secrets.Set(tokenAccount(path), []byte(creds.DeviceToken))
+	switch {
+	case err == nil:
+		stored.DeviceToken = ""
+	case !errors.Is(err, keychain.ErrUnsupported):
+		return fmt.Errorf("failed to store device token: %w", err)
+	}
+
+	data, err := json.MarshalIndent(&stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace credentials: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCredentials removes the credentials file and the device token in the keychain
+func DeleteCredentials(path string) error {
+	if err := Secrets.Delete(tokenAccount(path)); err != nil && !errors.Is(err, keychain.ErrUnsupported) {
+		return fmt.Errorf("failed to remove device token: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove credentials: %w", err)
+	}
+	return nil
+}
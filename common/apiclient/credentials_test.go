@@ -0,0 +1,103 @@
+package apiclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/keychain"
+	"github.com/stretchr/testify/assert"
+)
+
+// memorySecrets keeps the device tokens in memory, in place of the OS keychain
+type memorySecrets map[string][]byte
+
+func (s memorySecrets) Get(account string) ([]byte, error) {
+	secret, ok := s[account]
+	if !ok {
+		return nil, keychain.ErrNotFound
+	}
+	return secret, nil
+}
+
+func (s memorySecrets) Set(account string, secret []byte) error {
+	s[account] = secret
+	return nil
+}
+
+func (s memorySecrets) Delete(account string) error {
+	delete(s, account)
+	return nil
+}
+
+// noKeychain is the SecretStore of a platform without a keychain
+type noKeychain struct{}
+
+func (noKeychain) Get(string) ([]byte, error) { return nil, keychain.ErrUnsupported }
+func (noKeychain) Set(string, []byte) error   { return keychain.ErrUnsupported }
+func (noKeychain) Delete(string) error        { return keychain.ErrUnsupported }
+
+// useSecrets replaces Secrets with store for the duration of the test
+func useSecrets(t *testing.T, store SecretStore) {
+	previous := Secrets
+	Secrets = store
+	t.Cleanup(func() { Secrets = previous })
+}
+
+func TestCredentialsKeepTokenInKeychain(t *testing.T) {
+	secrets := memorySecrets{}
+	useSecrets(t, secrets)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials.json")
+	creds := &Credentials{Endpoint: "https://sync.example.com", DeviceID: "laptop", DeviceToken: "device-secret", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// The token goes to the keychain, the rest to the file
+	assert.NoError(t, SaveCredentials(path, creds))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "device-secret")
+	assert.Len(t, secrets, 1)
+	assert.Equal(t, "device-secret", creds.DeviceToken)
+
+	loaded, err := LoadCredentials(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "laptop", loaded.DeviceID)
+	assert.Equal(t, "device-secret", loaded.DeviceToken)
+
+	// Another credentials file has its own token
+	other := filepath.Join(dir, "other.json")
+	assert.NoError(t, SaveCredentials(other, &Credentials{DeviceID: "desktop", DeviceToken: "other-secret"}))
+	loaded, err = LoadCredentials(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "device-secret", loaded.DeviceToken)
+
+	// Logging out forgets both
+	assert.NoError(t, DeleteCredentials(path))
+	assert.Len(t, secrets, 1)
+	loaded, err = LoadCredentials(path)
+	assert.NoError(t, err)
+	assert.Nil(t, loaded)
+
+	// A file written before the keychain was used still holds its token
+	assert.NoError(t, os.WriteFile(path, []byte(`{"device_id":"laptop","device_token":"old-secret"}`), 0600))
+	loaded, err = LoadCredentials(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "old-secret", loaded.DeviceToken)
+}
+
+func TestCredentialsWithoutKeychain(t *testing.T) {
+	useSecrets(t, noKeychain{})
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	// The file is the only place left for the token
+	assert.NoError(t, SaveCredentials(path, &Credentials{DeviceID: "laptop", DeviceToken: "device-secret"}))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "device-secret")
+
+	loaded, err := LoadCredentials(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "device-secret", loaded.DeviceToken)
+	assert.NoError(t, DeleteCredentials(path))
+}
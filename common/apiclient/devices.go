@@ -0,0 +1,51 @@
+package apiclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+)
+
+// PathDevices is the base path of the device endpoints
+const PathDevices = "/api/v1/devices"
+
+// Heartbeat reports that this device is alive
+func (c *Client) Heartbeat(ctx context.Context, clientVersion string) error {
+	req := models.DeviceHeartbeatRequest{ClientVersion: clientVersion}
+	return c.Do(ctx, http.MethodPost, PathDevices+"/heartbeat", req, nil)
+}
+
+// ListDevices returns the devices of the logged in user
+func (c *Client) ListDevices(ctx context.Context) ([]models.DeviceResponse, error) {
+	var devices []models.DeviceResponse
+	if err := c.Do(ctx, http.MethodGet, PathDevices, nil, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// GetDevice returns a single device of the logged in user
+func (c *Client) GetDevice(ctx context.Context, deviceID string) (*models.DeviceResponse, error) {
+	var device models.DeviceResponse
+	if err := c.Do(ctx, http.MethodGet, devicePath(deviceID), nil, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// RenameDevice changes the name of a device
+func (c *Client) RenameDevice(ctx context.Context, deviceID, name string) error {
+	return c.Do(ctx, http.MethodPut, devicePath(deviceID), models.UpdateDeviceRequest{Name: name}, nil)
+}
+
+// UnlinkDevice removes a device from the account and revokes its tokens
+func (c *Client) UnlinkDevice(ctx context.Context, deviceID string) error {
+	return c.Do(ctx, http.MethodDelete, devicePath(deviceID), nil, nil)
+}
+
+// devicePath returns the path of a single device
+func devicePath(deviceID string) string {
+	return PathDevices + "/" + url.PathEscape(deviceID)
+}
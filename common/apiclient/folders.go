@@ -0,0 +1,60 @@
+package apiclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+)
+
+// PathFolders is the base path of the folder endpoints
+const PathFolders = "/api/v1/folders"
+
+// syncEventPageSize is how many sync events WalkSyncEvents asks for at once, the most the server returns
+const syncEventPageSize = 500
+
+// ListFolders returns the folders of the logged in user
+func (c *Client) ListFolders(ctx context.Context) ([]models.FolderResponse, error) {
+	var folders []models.FolderResponse
+	if err := c.Do(ctx, http.MethodGet, PathFolders, nil, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// RecordSyncEvent records a synchronization event of a folder
+func (c *Client) RecordSyncEvent(ctx context.Context, folderID string, event models.CreateSyncEventRequest) error {
+	return c.Do(ctx, http.MethodPost, PathFolders+"/"+url.PathEscape(folderID)+"/events", event, nil)
+}
+
+// WalkSyncEvents calls fn with the sync events of a folder recorded since since, or all of them
+// when it is zero, the oldest first. It stops at the first error fn returns.
+func (c *Client) WalkSyncEvents(ctx context.Context, folderID string, since time.Time, fn func(models.SyncEvent) error) error {
+	query := url.Values{"order": {"asc"}, "page_size": {strconv.Itoa(syncEventPageSize)}}
+	if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339))
+	}
+
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var events struct {
+			Items      []models.SyncEvent `json:"items"`
+			TotalPages int                `json:"total_pages"`
+		}
+		path := PathFolders + "/" + url.PathEscape(folderID) + "/events?" + query.Encode()
+		if err := c.Do(ctx, http.MethodGet, path, nil, &events); err != nil {
+			return err
+		}
+		for _, event := range events.Items {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if page >= events.TotalPages {
+			return nil
+		}
+	}
+}
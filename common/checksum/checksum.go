@@ -0,0 +1,264 @@
+// Package checksum hashes file contents with the algorithm a folder is configured with and
+// records which one in object metadata and in the hashes kept by the index
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math/bits"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"lukechampine.com/blake3"
+	"lukechampine.com/blake3/guts"
+)
+
+// Algorithm names a content hash
+type Algorithm string
+
+// Hash algorithms of file contents
+const (
+	// SHA256 is the default, understood by every version of the agent and the CLI
+	SHA256 Algorithm = "sha256"
+	// BLAKE3 is several times faster and hashes a large file on every core
+	BLAKE3 Algorithm = "blake3"
+)
+
+// Algorithms lists the valid algorithms
+var Algorithms = []Algorithm{SHA256, BLAKE3}
+
+// MetadataAlgorithm is the object metadata key naming the algorithm of the hash uploaded with
+// it, stored under the key returned by MetadataKey. Objects without one carry a SHA-256.
+const MetadataAlgorithm = "hash_algorithm"
+
+// Workers is how many parts of a large file are hashed at once, one per core
+var Workers = runtime.NumCPU()
+
+// parallelMin is the smallest file hashed in parallel, below which starting workers costs more
+// than it saves
+const parallelMin = 4 << 20
+
+// Parse returns the algorithm named name, SHA256 when name is empty
+func Parse(name string) (Algorithm, error) {
+	if name == "" {
+		return SHA256, nil
+	}
+	for _, alg := range Algorithms {
+		if strings.EqualFold(name, string(alg)) {
+			return alg, nil
+		}
+	}
+	names := make([]string, len(Algorithms))
+	for i, alg := range Algorithms {
+		names[i] = string(alg)
+	}
+	return "", fmt.Errorf("invalid checksum algorithm %q: use %s", name, strings.Join(names, ", "))
+}
+
+// New returns a hash computing a, SHA-256 for an empty or unknown algorithm
+func (a Algorithm) New() hash.Hash {
+	if a == BLAKE3 {
+		return blake3.New(32, nil)
+	}
+	return sha256.New()
+}
+
+// MetadataKey returns the object metadata key holding the hex hash of the algorithm
+func (a Algorithm) MetadataKey() string {
+	if a == "" {
+		a = SHA256
+	}
+	return "hash_" + string(a)
+}
+
+// Tag returns the hash stored in the index for a sum computed with a: the hex sum prefixed
+// with the algorithm and a colon, or bare for SHA-256 so the hashes recorded before there was a
+// choice keep matching
+func (a Algorithm) Tag(sum []byte) string {
+	return a.tagHex(hex.EncodeToString(sum))
+}
+
+func (a Algorithm) tagHex(hexSum string) string {
+	if a == "" || a == SHA256 {
+		return hexSum
+	}
+	return string(a) + ":" + hexSum
+}
+
+// Of returns the algorithm of a hash returned by Tag, SHA256 for a bare one
+func Of(hash string) Algorithm {
+	if name, _, ok := strings.Cut(hash, ":"); ok {
+		return Algorithm(strings.ToLower(name))
+	}
+	return SHA256
+}
+
+// FromMetadata returns the hash of an object as Tag does, from the algorithm and hash keys of
+// its metadata, ignoring case differences introduced by providers. It is empty for objects
+// stored without a hash, or with a hash of an algorithm this version does not know.
+func FromMetadata(metadata map[string]string) string {
+	alg, err := Parse(lookup(metadata, MetadataAlgorithm))
+	if err != nil {
+		return ""
+	}
+	hexSum := lookup(metadata, alg.MetadataKey())
+	if hexSum == "" {
+		return ""
+	}
+	return alg.tagHex(hexSum)
+}
+
+// SetMetadata records a hash returned by Tag in object metadata
+func SetMetadata(metadata map[string]string, hash string) {
+	alg := Of(hash)
+	_, hexSum, ok := strings.Cut(hash, ":")
+	if !ok {
+		hexSum = hash
+	}
+	metadata[alg.MetadataKey()] = hexSum
+	if alg != SHA256 {
+		metadata[MetadataAlgorithm] = string(alg)
+	} else {
+		delete(metadata, MetadataAlgorithm)
+	}
+}
+
+// lookup returns a metadata value, matching its key without regard to case
+func lookup(metadata map[string]string, key string) string {
+	if value, ok := metadata[key]; ok {
+		return value
+	}
+	for k, value := range metadata {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Sum returns the hash of everything read from r, as Tag returns it
+func Sum(a Algorithm, r io.Reader) (string, error) {
+	h := a.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return a.Tag(h.Sum(nil)), nil
+}
+
+// File returns the hash of the size bytes of r, as Tag returns it. Each read goes through
+// wrap, when not nil, as with a throttle. Large files hashed with BLAKE3 are split into
+// subtrees read and hashed by Workers goroutines at once, BLAKE3 hashing its input as a
+// binary tree of 1 KiB chunks.
+func File(a Algorithm, r io.ReaderAt, size int64, wrap func(io.Reader) io.Reader) (string, error) {
+	if wrap == nil {
+		wrap = func(r io.Reader) io.Reader { return r }
+	}
+	if a != BLAKE3 || size < parallelMin || Workers < 2 {
+		return Sum(a, wrap(io.NewSectionReader(r, 0, size)))
+	}
+
+	// Whole subtrees of 2^level chunks are hashed apart, the last one keeping at least a byte
+	// back for the chunk that ends the file
+	level := 0
+	for int64(guts.ChunkSize)<<(level+1)*int64(Workers) <= size && level < 10 {
+		level++
+	}
+	segment := int64(guts.ChunkSize) << level
+	segments := int((size - 1) / segment)
+
+	cvs := make([][8]uint32, segments)
+	errs := make([]error, segments)
+	next := make(chan int)
+	var workers sync.WaitGroup
+	for w := 0; w < min(Workers, segments); w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			buf := make([]byte, segment)
+			for i := range next {
+				section := wrap(io.NewSectionReader(r, int64(i)*segment, segment))
+				if _, errs[i] = io.ReadFull(section, buf); errs[i] == nil {
+					cvs[i] = guts.ChainingValue(guts.CompressEigentree(buf, &guts.IV, uint64(i)<<level, 0))
+				}
+			}
+		}()
+	}
+	for i := 0; i < segments; i++ {
+		next <- i
+	}
+	close(next)
+	workers.Wait()
+
+	var tree blake3Tree
+	for i, cv := range cvs {
+		if errs[i] != nil {
+			return "", errs[i]
+		}
+		tree.push(cv, level)
+	}
+	rest := int64(segments) * segment
+	tail, err := io.ReadAll(wrap(io.NewSectionReader(r, rest, size-rest)))
+	if err != nil {
+		return "", err
+	}
+	return BLAKE3.Tag(tree.sum(tail)), nil
+}
+
+// blake3Tree merges the chaining values of BLAKE3 subtrees hashed apart, in input order,
+// as the hasher of the blake3 package does with its own
+type blake3Tree struct {
+	stack   [64][8]uint32 // Root of the subtree of each height still to be merged
+	counter uint64        // Chunks pushed, whose bits tell which heights are held
+}
+
+// push adds the chaining value of the next subtree, 2^height chunks long
+func (t *blake3Tree) push(cv [8]uint32, height int) {
+	i := height
+	for ; t.counter&(1<<i) != 0; i++ {
+		cv = guts.ChainingValue(guts.ParentNode(t.stack[i], cv, &guts.IV, 0))
+	}
+	t.stack[i] = cv
+	t.counter += 1 << height
+}
+
+// sum returns the 32-byte hash of the whole input, tail being the bytes after the subtrees
+// pushed, at least one
+func (t *blake3Tree) sum(tail []byte) []byte {
+	// Every chunk but the last is pushed; the last one is finished as part of the root
+	chunks := uint64((len(tail) - 1) / guts.ChunkSize)
+	for _, height := range guts.Eigentrees(t.counter, chunks) {
+		n := guts.ChunkSize << height
+		t.push(guts.ChainingValue(guts.CompressEigentree(tail[:n], &guts.IV, t.counter, 0)), height)
+		tail = tail[n:]
+	}
+
+	node := guts.CompressChunk(tail, &guts.IV, t.counter, 0)
+	for i := bits.TrailingZeros64(t.counter); i < bits.Len64(t.counter); i++ {
+		if t.counter&(1<<i) != 0 {
+			node = guts.ParentNode(t.stack[i], guts.ChainingValue(node), &guts.IV, 0)
+		}
+	}
+	node.Flags |= guts.FlagRoot
+	out := guts.WordsToBytes(guts.CompressNode(node))
+	return out[:32]
+}
+
+// OpenFile returns the hash of the file at path with algorithm a, see File
+func OpenFile(a Algorithm, path string, wrap func(io.Reader) io.Reader) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	return File(a, file, info.Size(), wrap)
+}
@@ -0,0 +1,142 @@
+package checksum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for name, want := range map[string]Algorithm{"": SHA256, "sha256": SHA256, "BLAKE3": BLAKE3} {
+		if got, err := Parse(name); err != nil || got != want {
+			t.Errorf("Parse(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := Parse("md5"); err == nil {
+		t.Error("Parse accepted md5")
+	}
+}
+
+func TestTagAndMetadata(t *testing.T) {
+	sha, err := Sum(SHA256, strings.NewReader("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" || Of(sha) != SHA256 {
+		t.Errorf("SHA-256 of abc = %q, want a bare hex hash", sha)
+	}
+	blake, err := Sum(Of("blake3:00"), strings.NewReader("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blake != "blake3:6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85" || Of(blake) != BLAKE3 {
+		t.Errorf("BLAKE3 of abc = %q, want it tagged", blake)
+	}
+
+	metadata := map[string]string{"hash_sha256": sha}
+	if got := FromMetadata(metadata); got != sha {
+		t.Errorf("FromMetadata of a SHA-256 object = %q, want %q", got, sha)
+	}
+	SetMetadata(metadata, blake)
+	if metadata["hash_blake3"] != strings.TrimPrefix(blake, "blake3:") || metadata[MetadataAlgorithm] != "blake3" {
+		t.Errorf("SetMetadata(%q) = %v", blake, metadata)
+	}
+	if got := FromMetadata(metadata); got != blake {
+		t.Errorf("FromMetadata after SetMetadata = %q, want %q", got, blake)
+	}
+	SetMetadata(metadata, sha)
+	if got := FromMetadata(metadata); got != sha {
+		t.Errorf("FromMetadata after setting a SHA-256 back = %q, want %q", got, sha)
+	}
+
+	// Providers may change the case of metadata keys
+	if got := FromMetadata(map[string]string{"Hash_Algorithm": "blake3", "Hash_Blake3": "ab"}); got != "blake3:ab" {
+		t.Errorf("FromMetadata with other case = %q", got)
+	}
+	if got := FromMetadata(map[string]string{MetadataAlgorithm: "xxh128", "hash_xxh128": "ab"}); got != "" {
+		t.Errorf("FromMetadata with an unknown algorithm = %q, want none", got)
+	}
+}
+
+func TestFileHashesInParallel(t *testing.T) {
+	defer func(workers int) { Workers = workers }(Workers)
+
+	for _, size := range []int{parallelMin - 1, parallelMin, parallelMin + 1, 3*parallelMin + 5000} {
+		input := vectorInput(size)
+		want, err := Sum(BLAKE3, bytes.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{1, 3, 8} {
+			Workers = workers
+			var reads atomic.Int32
+			got, err := File(BLAKE3, bytes.NewReader(input), int64(size), func(r io.Reader) io.Reader {
+				reads.Add(1)
+				return r
+			})
+			if err != nil || got != want {
+				t.Errorf("File of %d bytes on %d workers = %q, %v; want %q", size, workers, got, err, want)
+			}
+			if reads.Load() == 0 {
+				t.Errorf("File of %d bytes on %d workers did not wrap its reads", size, workers)
+			}
+		}
+	}
+}
+
+// vectorInput returns the input of the official BLAKE3 test vectors, bytes counting up modulo 251
+func vectorInput(n int) []byte {
+	input := make([]byte, n)
+	for i := range input {
+		input[i] = byte(i % 251)
+	}
+	return input
+}
+
+func TestBLAKE3Vectors(t *testing.T) {
+	tests := []struct {
+		input []byte
+		want  string
+	}{
+		{nil, "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{[]byte("abc"), "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+		{vectorInput(1), "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+		{vectorInput(1024), "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+		{vectorInput(1025), "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+	}
+	for _, tt := range tests {
+		h := BLAKE3.New()
+		h.Write(tt.input)
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.want {
+			t.Errorf("BLAKE3 of %d bytes = %s, want %s", len(tt.input), got, tt.want)
+		}
+	}
+}
+
+func TestBLAKE3Incremental(t *testing.T) {
+	input := vectorInput(9*1024 + 77)
+	whole := BLAKE3.New()
+	whole.Write(input)
+	want := whole.Sum(nil)
+
+	for _, step := range []int{1, 63, 64, 65, 1023, 1024, 1025, 4096} {
+		h := BLAKE3.New()
+		for rest := input; len(rest) > 0; {
+			n := min(step, len(rest))
+			h.Write(rest[:n])
+			rest = rest[n:]
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("writing %d bytes at a time: got %x, want %x", step, got, want)
+		}
+	}
+
+	whole.Reset()
+	whole.Write(input)
+	if got := whole.Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("after Reset: got %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+// Package cloudfile detects the placeholders cloud storage clients such as OneDrive, Dropbox
+// and iCloud leave on disk for files whose content stays online until it is opened
+package cloudfile
+
+import "os"
+
+// Windows file attributes of cloud files (Cloud Files API) and of other offline storage
+const (
+	attributeOffline            = 0x1000
+	attributeRecallOnOpen       = 0x40000
+	attributeRecallOnDataAccess = 0x400000
+)
+
+// flagDataless is the macOS stat flag of files whose content was evicted to iCloud Drive or
+// to another File Provider
+const flagDataless = 0x40000000
+
+// Dehydrated reports whether a file is a placeholder whose content is not on disk, so reading
+// it would download the file from its cloud provider. It is always false on platforms
+// without such placeholders.
+func Dehydrated(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	return dehydrated(info)
+}
+
+// recallAttributes reports whether Windows file attributes mark content that is fetched when
+// the file is opened or read
+func recallAttributes(attributes uint32) bool {
+	return attributes&(attributeOffline|attributeRecallOnOpen|attributeRecallOnDataAccess) != 0
+}
+
+// datalessFlags reports whether macOS stat flags mark a file whose content was evicted
+func datalessFlags(flags uint32) bool {
+	return flags&flagDataless != 0
+}
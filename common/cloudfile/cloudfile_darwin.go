@@ -0,0 +1,12 @@
+package cloudfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// dehydrated checks the dataless flag File Providers set on evicted files
+func dehydrated(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && datalessFlags(stat.Flags)
+}
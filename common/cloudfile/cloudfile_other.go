@@ -0,0 +1,10 @@
+//go:build !windows && !darwin
+
+package cloudfile
+
+import "os"
+
+// dehydrated is always false: cloud clients on this platform keep whole files on disk
+func dehydrated(info os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,27 @@
+package cloudfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDehydrated(t *testing.T) {
+	// A file written here holds its content
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+	info, err := os.Lstat(path)
+	assert.NoError(t, err)
+	assert.False(t, Dehydrated(info))
+
+	// OneDrive placeholders recall their content on access; pinned files do not
+	assert.True(t, recallAttributes(0x400000|0x20))
+	assert.True(t, recallAttributes(0x1000))
+	assert.False(t, recallAttributes(0x80000|0x20))
+
+	// iCloud marks evicted files dataless
+	assert.True(t, datalessFlags(0x40000000))
+	assert.False(t, datalessFlags(0x20))
+}
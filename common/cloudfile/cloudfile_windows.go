@@ -0,0 +1,12 @@
+package cloudfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// dehydrated checks the attributes the Cloud Files API sets on placeholders
+func dehydrated(info os.FileInfo) bool {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	return ok && recallAttributes(data.FileAttributes)
+}
@@ -0,0 +1,354 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// BundleVersion is the current format version of configuration bundles. Version 2 carries
+// named storage targets; bundles of version 1 are still applied.
+const BundleVersion = 2
+
+// Bundle is a portable snapshot of a configuration used to move a setup to another machine.
+// Device identity is intentionally left out so the new machine registers as its own device.
+type Bundle struct {
+	Version          int            `yaml:"version"`
+	ExportedAt       time.Time      `yaml:"exported_at"`
+	Settings         BundleSettings `yaml:"settings"`
+	Storage          BundleStorage  `yaml:"storage"`
+	Folders          []SyncFolder   `yaml:"folders"`
+	Secrets          *BundleSecrets `yaml:"secrets,omitempty"`
+	EncryptedSecrets string         `yaml:"encrypted_secrets,omitempty"`
+}
+
+// BundleSettings holds the general sync settings of a bundle
+type BundleSettings struct {
+	LogLevel       string `yaml:"log_level"`
+	SyncInterval   string `yaml:"sync_interval"`
+	MaxConcurrency int    `yaml:"max_concurrency"`
+	ThrottleBytes  int64  `yaml:"throttle_bytes"`
+	ApiEndpoint    string `yaml:"api_endpoint,omitempty"`
+}
+
+// BundleStorage holds the storage targets of a bundle, without credentials. Bundles of
+// version 1 hold the settings of a single provider instead, applied as the default target.
+type BundleStorage struct {
+	Targets []StorageTarget `yaml:"targets,omitempty"`
+
+	Provider string      `yaml:"provider,omitempty"`
+	S3       S3Config    `yaml:"s3,omitempty"`
+	Minio    MinioConfig `yaml:"minio,omitempty"`
+	GCS      GCSConfig   `yaml:"gcs,omitempty"`
+	Local    LocalConfig `yaml:"local,omitempty"`
+}
+
+// BundleSecrets holds the credentials carried by a bundle, by storage target. Bundles of
+// version 1 carry the S3 and MinIO keys of their single provider instead.
+type BundleSecrets struct {
+	Targets  map[string]TargetSecrets `yaml:"targets,omitempty"`
+	ApiToken string                   `yaml:"api_token,omitempty"`
+
+	S3AccessKey    string `yaml:"s3_access_key,omitempty"`
+	S3SecretKey    string `yaml:"s3_secret_key,omitempty"`
+	MinioAccessKey string `yaml:"minio_access_key,omitempty"`
+	MinioSecretKey string `yaml:"minio_secret_key,omitempty"`
+}
+
+// TargetSecrets holds the keys of an S3 or MinIO storage target
+type TargetSecrets struct {
+	AccessKey string `yaml:"access_key,omitempty"`
+	SecretKey string `yaml:"secret_key,omitempty"`
+}
+
+// credentials returns the keys of a target, for the types that have them
+func (t *StorageTarget) credentials() (accessKey, secretKey *string) {
+	switch t.Type {
+	case TargetS3:
+		return &t.S3.AccessKey, &t.S3.SecretKey
+	case TargetMinio:
+		return &t.Minio.AccessKey, &t.Minio.SecretKey
+	}
+	return nil, nil
+}
+
+// BundleOptions controls how secrets are written to a bundle
+type BundleOptions struct {
+	// RedactSecrets omits all credentials from the bundle
+	RedactSecrets bool
+	// Passphrase encrypts credentials when set
+	Passphrase string
+}
+
+// NewBundle builds a bundle from the given configuration
+func NewBundle(cfg *Config, opts BundleOptions) (*Bundle, error) {
+	bundle := &Bundle{
+		Version:    BundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Settings: BundleSettings{
+			LogLevel:       cfg.LogLevel,
+			SyncInterval:   cfg.SyncInterval.String(),
+			MaxConcurrency: cfg.MaxConcurrency,
+			ThrottleBytes:  cfg.ThrottleBytes,
+			ApiEndpoint:    cfg.ApiEndpoint,
+		},
+		Storage: BundleStorage{Targets: append([]StorageTarget{}, cfg.Targets...)},
+		Folders: append([]SyncFolder{}, cfg.SyncFolders...),
+	}
+
+	// Credentials never live in the storage targets of a bundle
+	secrets := &BundleSecrets{Targets: make(map[string]TargetSecrets), ApiToken: cfg.ApiToken}
+	for i := range bundle.Storage.Targets {
+		accessKey, secretKey := bundle.Storage.Targets[i].credentials()
+		if accessKey == nil {
+			continue
+		}
+		if *accessKey != "" || *secretKey != "" {
+			secrets.Targets[bundle.Storage.Targets[i].Name] = TargetSecrets{AccessKey: *accessKey, SecretKey: *secretKey}
+		}
+		*accessKey, *secretKey = "", ""
+	}
+
+	if opts.RedactSecrets {
+		return bundle, nil
+	}
+
+	if opts.Passphrase == "" {
+		bundle.Secrets = secrets
+		return bundle, nil
+	}
+
+	encrypted, err := encryptSecrets(secrets, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	bundle.EncryptedSecrets = encrypted
+
+	return bundle, nil
+}
+
+// Marshal serializes the bundle as YAML
+func (b *Bundle) Marshal() ([]byte, error) {
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	return data, nil
+}
+
+// ParseBundle reads a bundle previously produced by Marshal
+func ParseBundle(data []byte) (*Bundle, error) {
+	var bundle Bundle
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+
+	if bundle.Version == 0 {
+		return nil, fmt.Errorf("not a configuration bundle")
+	}
+	if bundle.Version > BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than supported version %d", bundle.Version, BundleVersion)
+	}
+
+	return &bundle, nil
+}
+
+// HasSecrets reports whether the bundle carries any credentials
+func (b *Bundle) HasSecrets() bool {
+	return b.Secrets != nil || b.EncryptedSecrets != ""
+}
+
+// IsEncrypted reports whether the bundle credentials are protected by a passphrase
+func (b *Bundle) IsEncrypted() bool {
+	return b.EncryptedSecrets != ""
+}
+
+// Apply copies the bundle settings, storage targets and folders into cfg. The existing
+// credentials of a target of the same name and type are kept when the bundle has none.
+// Folders are matched by ID.
+func (b *Bundle) Apply(cfg *Config, passphrase string) error {
+	secrets := b.Secrets
+	if b.IsEncrypted() {
+		if passphrase == "" {
+			return fmt.Errorf("bundle credentials are encrypted, a passphrase is required")
+		}
+		decrypted, err := decryptSecrets(b.EncryptedSecrets, passphrase)
+		if err != nil {
+			return err
+		}
+		secrets = decrypted
+	}
+
+	if b.Settings.SyncInterval != "" {
+		interval, err := time.ParseDuration(b.Settings.SyncInterval)
+		if err != nil {
+			return fmt.Errorf("invalid sync interval in bundle: %w", err)
+		}
+		cfg.SyncInterval = interval
+	}
+	if b.Settings.LogLevel != "" {
+		cfg.LogLevel = b.Settings.LogLevel
+	}
+	if b.Settings.MaxConcurrency > 0 {
+		cfg.MaxConcurrency = b.Settings.MaxConcurrency
+	}
+	cfg.ThrottleBytes = b.Settings.ThrottleBytes
+	if b.Settings.ApiEndpoint != "" {
+		cfg.ApiEndpoint = b.Settings.ApiEndpoint
+	}
+
+	targets, targetSecrets := b.targets(secrets)
+	for i := range targets {
+		accessKey, secretKey := targets[i].credentials()
+		if accessKey == nil {
+			continue
+		}
+		if keys, ok := targetSecrets[targets[i].Name]; ok {
+			*accessKey, *secretKey = keys.AccessKey, keys.SecretKey
+		} else if existing := cfg.Target(targets[i].Name); existing != nil && existing.Type == targets[i].Type {
+			existingAccess, existingSecret := existing.credentials()
+			*accessKey, *secretKey = *existingAccess, *existingSecret
+		}
+	}
+	if secrets != nil && secrets.ApiToken != "" {
+		cfg.ApiToken = secrets.ApiToken
+	}
+	cfg.Targets = targets
+
+	for _, folder := range b.Folders {
+		replaced := false
+		for i := range cfg.SyncFolders {
+			if cfg.SyncFolders[i].ID == folder.ID {
+				cfg.SyncFolders[i] = folder
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			cfg.SyncFolders = append(cfg.SyncFolders, folder)
+		}
+	}
+
+	return nil
+}
+
+// targets returns the storage targets of the bundle with the credentials carried for them.
+// The single provider of a version 1 bundle becomes the default target.
+func (b *Bundle) targets(secrets *BundleSecrets) ([]StorageTarget, map[string]TargetSecrets) {
+	targetSecrets := make(map[string]TargetSecrets)
+	if secrets != nil {
+		for name, keys := range secrets.Targets {
+			targetSecrets[name] = keys
+		}
+	}
+	if len(b.Storage.Targets) > 0 || b.Storage.Provider == "" {
+		return append([]StorageTarget{}, b.Storage.Targets...), targetSecrets
+	}
+
+	target := StorageTarget{
+		Name:  DefaultTargetName,
+		Type:  b.Storage.Provider,
+		S3:    b.Storage.S3,
+		Minio: b.Storage.Minio,
+		GCS:   b.Storage.GCS,
+		Local: b.Storage.Local,
+	}
+	if secrets != nil {
+		switch target.Type {
+		case TargetS3:
+			targetSecrets[target.Name] = TargetSecrets{AccessKey: secrets.S3AccessKey, SecretKey: secrets.S3SecretKey}
+		case TargetMinio:
+			targetSecrets[target.Name] = TargetSecrets{AccessKey: secrets.MinioAccessKey, SecretKey: secrets.MinioSecretKey}
+		}
+	}
+	return []StorageTarget{target}, targetSecrets
+}
+
+// encryptSecrets seals the secrets with AES-GCM using a key derived from the passphrase
+func encryptSecrets(secrets *BundleSecrets, passphrase string) (string, error) {
+	plaintext, err := yaml.Marshal(secrets)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := bundleCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nil, nonce, plaintext, nil)
+
+	payload := append(append(salt, nonce...), sealed...)
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// decryptSecrets opens secrets sealed by encryptSecrets
+func decryptSecrets(encoded, passphrase string) (*BundleSecrets, error) {
+	payload, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted secrets: %w", err)
+	}
+	if len(payload) < 16 {
+		return nil, fmt.Errorf("invalid encrypted secrets: payload too short")
+	}
+
+	salt := payload[:16]
+	gcm, err := bundleCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := payload[16:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted secrets: payload too short")
+	}
+
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets: wrong passphrase or corrupted bundle")
+	}
+
+	var secrets BundleSecrets
+	if err := yaml.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets: %w", err)
+	}
+
+	return &secrets, nil
+}
+
+// bundleCipher derives an AES-256-GCM cipher from a passphrase and salt
+func bundleCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return gcm, nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func bundleTestConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.DeviceID = "source-device"
+	cfg.SyncInterval = 10 * time.Minute
+	cfg.Targets = []StorageTarget{
+		{Name: DefaultTargetName, Type: TargetS3, S3: S3Config{Bucket: "backups", AccessKey: "AKIA123", SecretKey: "s3cr3t"}},
+		{Name: "nas", Type: TargetLocal, Local: LocalConfig{RootDir: "/mnt/nas"}},
+	}
+	cfg.ApiToken = "token"
+	cfg.SyncFolders = []SyncFolder{
+		{ID: "docs", Path: "/home/user/docs", Enabled: true, Exclude: []string{"*.tmp"}, TwoWaySync: true, Target: "nas"},
+	}
+	return cfg
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	bundle, err := NewBundle(bundleTestConfig(), BundleOptions{})
+	assert.NoError(t, err)
+
+	data, err := bundle.Marshal()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "source-device")
+
+	parsed, err := ParseBundle(data)
+	assert.NoError(t, err)
+
+	target := DefaultConfig()
+	target.DeviceID = "new-device"
+	assert.NoError(t, parsed.Apply(target, ""))
+
+	assert.Equal(t, "new-device", target.DeviceID)
+	assert.Equal(t, 10*time.Minute, target.SyncInterval)
+	assert.Equal(t, []string{DefaultTargetName, "nas"}, target.TargetNames())
+	assert.Equal(t, TargetS3, target.Targets[0].Type)
+	assert.Equal(t, "backups", target.Targets[0].S3.Bucket)
+	assert.Equal(t, "AKIA123", target.Targets[0].S3.AccessKey)
+	assert.Equal(t, "s3cr3t", target.Targets[0].S3.SecretKey)
+	assert.Equal(t, "/mnt/nas", target.Targets[1].Local.RootDir)
+	assert.Equal(t, "token", target.ApiToken)
+	assert.Len(t, target.SyncFolders, 1)
+	assert.Equal(t, []string{"*.tmp"}, target.SyncFolders[0].Exclude)
+	assert.True(t, target.SyncFolders[0].TwoWaySync)
+	assert.Equal(t, "nas", target.SyncFolders[0].Target)
+}
+
+func TestBundleRedactSecrets(t *testing.T) {
+	bundle, err := NewBundle(bundleTestConfig(), BundleOptions{RedactSecrets: true})
+	assert.NoError(t, err)
+	assert.False(t, bundle.HasSecrets())
+
+	data, err := bundle.Marshal()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cr3t")
+	assert.NotContains(t, string(data), "AKIA123")
+
+	// Credentials already present on the target are kept
+	target := DefaultConfig()
+	target.Targets[0] = StorageTarget{Name: DefaultTargetName, Type: TargetS3, S3: S3Config{SecretKey: "existing"}}
+	assert.NoError(t, bundle.Apply(target, ""))
+	assert.Equal(t, "backups", target.Targets[0].S3.Bucket)
+	assert.Equal(t, "existing", target.Targets[0].S3.SecretKey)
+}
+
+func TestBundleEncryptedSecrets(t *testing.T) {
+	bundle, err := NewBundle(bundleTestConfig(), BundleOptions{Passphrase: "correct horse"})
+	assert.NoError(t, err)
+	assert.True(t, bundle.IsEncrypted())
+
+	data, err := bundle.Marshal()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cr3t")
+
+	parsed, err := ParseBundle(data)
+	assert.NoError(t, err)
+
+	assert.Error(t, parsed.Apply(DefaultConfig(), ""))
+	assert.Error(t, parsed.Apply(DefaultConfig(), "wrong"))
+
+	target := DefaultConfig()
+	assert.NoError(t, parsed.Apply(target, "correct horse"))
+	assert.Equal(t, "s3cr3t", target.Targets[0].S3.SecretKey)
+}
+
+func TestApplyBundleVersion1(t *testing.T) {
+	bundle, err := ParseBundle([]byte(`version: 1
+storage:
+    provider: minio
+    minio:
+        endpoint: minio.lan:9000
+        bucket: backups
+secrets:
+    minio_access_key: admin
+    minio_secret_key: s3cr3t
+`))
+	assert.NoError(t, err)
+
+	// The single provider becomes the default target, with its keys
+	target := DefaultConfig()
+	assert.NoError(t, bundle.Apply(target, ""))
+	assert.Equal(t, []StorageTarget{{
+		Name:  DefaultTargetName,
+		Type:  TargetMinio,
+		Minio: MinioConfig{Endpoint: "minio.lan:9000", Bucket: "backups", AccessKey: "admin", SecretKey: "s3cr3t"},
+	}}, target.Targets)
+}
+
+func TestParseBundleRejectsUnknownData(t *testing.T) {
+	_, err := ParseBundle([]byte("device_id: abc\n"))
+	assert.Error(t, err)
+
+	_, err = ParseBundle([]byte("version: 99\n"))
+	assert.Error(t, err)
+}
@@ -2,15 +2,25 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	"github.com/martinshumberto/sync-manager/common/cron"
+	"github.com/martinshumberto/sync-manager/common/identity"
 	"github.com/spf13/viper"
 )
 
 // Config is the main configuration struct for CloudSync
 type Config struct {
+	// Schema version of the file the configuration was loaded from
+	ConfigVersion int `mapstructure:"config_version"`
+
 	// General settings
 	DeviceID   string `mapstructure:"device_id"`
 	DeviceName string `mapstructure:"device_name"`
@@ -21,96 +31,699 @@ type Config struct {
 	SyncInterval   time.Duration `mapstructure:"sync_interval"`
 	MaxConcurrency int           `mapstructure:"max_concurrency"`
 	ThrottleBytes  int64         `mapstructure:"throttle_bytes"`
+	// FullScanInterval is how often a folder is walked although the watcher saw no change
+	// in it; periodic syncs in between only check the remote. Zero walks on every sync.
+	FullScanInterval time.Duration `mapstructure:"full_scan_interval"`
 
-	// Storage settings
-	StorageProvider string      `mapstructure:"storage_provider"`
-	S3Config        S3Config    `mapstructure:"s3"`
-	MinioConfig     MinioConfig `mapstructure:"minio"`
-	GCSConfig       GCSConfig   `mapstructure:"gcs"`
-	LocalConfig     LocalConfig `mapstructure:"local"`
+	// Storage backends folders sync to, the first one for folders that do not name one
+	Targets []StorageTarget `mapstructure:"targets"`
 
 	// API settings
-	ApiEndpoint string `mapstructure:"api_endpoint"`
-	ApiToken    string `mapstructure:"api_token"`
+	ApiEndpoint string          `mapstructure:"api_endpoint"`
+	ApiToken    string          `mapstructure:"api_token"`
+	HTTP        TransportConfig `mapstructure:"http"` // Proxy and TLS for the coordination server
 
 	// Folders to sync
 	SyncFolders []SyncFolder `mapstructure:"sync_folders"`
+	// NestedFolders is what happens to a folder inside another one, NestingRefuse or NestingExclude
+	NestedFolders string `mapstructure:"nested_folders"`
+
+	// Tracing settings
+	Telemetry TelemetryConfig `mapstructure:"telemetry"`
+
+	// Transfer behavior on battery power and metered connections
+	Power PowerConfig `mapstructure:"power"`
+
+	// Wrappers applied around the storage backend
+	StorageMiddleware StorageMiddlewareConfig `mapstructure:"storage_middleware"`
+
+	// Transfers between devices on the same local network
+	LAN LANConfig `mapstructure:"lan"`
+
+	// Parallel downloads for two-way sync and restores
+	Download DownloadConfig `mapstructure:"download"`
+
+	// Handling of sparse and very large files
+	Files FilesConfig `mapstructure:"files"`
+
+	// CPU and I/O priority of the agent and its hashing speed
+	Priority PriorityConfig `mapstructure:"priority"`
+
+	// Protection of the local database
+	Database DatabaseConfig `mapstructure:"database"`
+
+	// Monthly transfer allowance
+	Bandwidth BandwidthConfig `mapstructure:"bandwidth"`
+
+	// Disk space for temporary copies of files
+	Cache CacheConfig `mapstructure:"cache"`
+
+	// Memory the agent may use and how many uploads it keeps queued
+	Resources ResourcesConfig `mapstructure:"resources"`
+
+	// Order in which queued uploads start
+	Scheduling SchedulingConfig `mapstructure:"scheduling"`
+
+	// Whether the number of upload workers stays at max_concurrency or follows the link
+	Concurrency ConcurrencyConfig `mapstructure:"concurrency"`
+
+	// How far the clock may be off from the storage server's before the agent warns
+	Clock ClockConfig `mapstructure:"clock"`
+
+	// Where storage plugins are discovered
+	Plugins PluginsConfig `mapstructure:"plugins"`
+
+	// Settings expanded from ${VAR} and file: references, by key
+	references map[string]reference
 }
 
 // S3Config holds S3-specific configuration
 type S3Config struct {
-	Endpoint  string `mapstructure:"endpoint"`
-	Region    string `mapstructure:"region"`
-	Bucket    string `mapstructure:"bucket"`
-	AccessKey string `mapstructure:"access_key"`
-	SecretKey string `mapstructure:"secret_key"`
-	UseSSL    bool   `mapstructure:"use_ssl"`
-	PathStyle bool   `mapstructure:"path_style"`
+	Endpoint  string `mapstructure:"endpoint" yaml:"endpoint,omitempty"`
+	Region    string `mapstructure:"region" yaml:"region"`
+	Bucket    string `mapstructure:"bucket" yaml:"bucket"`
+	AccessKey string `mapstructure:"access_key" yaml:"access_key,omitempty"`
+	SecretKey string `mapstructure:"secret_key" yaml:"secret_key,omitempty"`
+	UseSSL    bool   `mapstructure:"use_ssl" yaml:"use_ssl"`
+	PathStyle bool   `mapstructure:"path_style" yaml:"path_style"`
+	// Failover lists endpoints of replicas of the bucket, tried in order when the endpoint is down
+	Failover []string `mapstructure:"failover_endpoints" yaml:"failover_endpoints,omitempty"`
+
+	TransportConfig `mapstructure:",squash" yaml:",inline"`
 }
 
 // MinioConfig holds MinIO-specific configuration
 type MinioConfig struct {
-	Endpoint  string `mapstructure:"endpoint"`
-	Region    string `mapstructure:"region"`
-	Bucket    string `mapstructure:"bucket"`
-	AccessKey string `mapstructure:"access_key"`
-	SecretKey string `mapstructure:"secret_key"`
-	UseSSL    bool   `mapstructure:"use_ssl"`
+	Endpoint  string `mapstructure:"endpoint" yaml:"endpoint,omitempty"`
+	Region    string `mapstructure:"region" yaml:"region"`
+	Bucket    string `mapstructure:"bucket" yaml:"bucket"`
+	AccessKey string `mapstructure:"access_key" yaml:"access_key,omitempty"`
+	SecretKey string `mapstructure:"secret_key" yaml:"secret_key,omitempty"`
+	UseSSL    bool   `mapstructure:"use_ssl" yaml:"use_ssl"`
+	// Failover lists endpoints of replicas of the bucket, tried in order when the endpoint is down
+	Failover []string `mapstructure:"failover_endpoints" yaml:"failover_endpoints,omitempty"`
+
+	TransportConfig `mapstructure:",squash" yaml:",inline"`
 }
 
 // GCSConfig holds Google Cloud Storage specific configuration
 type GCSConfig struct {
-	ProjectID       string `mapstructure:"project_id"`
-	Bucket          string `mapstructure:"bucket"`
-	CredentialsFile string `mapstructure:"credentials_file"`
+	ProjectID       string `mapstructure:"project_id" yaml:"project_id"`
+	Bucket          string `mapstructure:"bucket" yaml:"bucket"`
+	CredentialsFile string `mapstructure:"credentials_file" yaml:"credentials_file,omitempty"`
+
+	TransportConfig `mapstructure:",squash" yaml:",inline"`
+}
+
+// TransportConfig holds the proxy and TLS settings of an HTTP client.
+// Without a proxy URL the HTTP(S)_PROXY environment variables apply.
+type TransportConfig struct {
+	ProxyURL           string `mapstructure:"proxy_url" yaml:"proxy_url,omitempty"`
+	CACertFile         string `mapstructure:"ca_cert_file" yaml:"ca_cert_file,omitempty"`                 // PEM bundle trusted in addition to the system roots
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify" yaml:"insecure_skip_verify,omitempty"` // Disables certificate checks; last resort only
+}
+
+// OneDriveConfig holds the settings of a OneDrive reached through Microsoft Graph. Files go to
+// the app folder, Apps/<app name>, unless AppFolder is off, then to the root of the drive.
+type OneDriveConfig struct {
+	ClientID  string `mapstructure:"client_id" yaml:"client_id"`             // Application (client) ID of the app registered in Microsoft Entra ID
+	Tenant    string `mapstructure:"tenant" yaml:"tenant,omitempty"`         // consumers, organizations, common or a tenant ID; common when empty
+	DriveID   string `mapstructure:"drive_id" yaml:"drive_id,omitempty"`     // Drive other than the signed-in user's own, such as a SharePoint library
+	AppFolder bool   `mapstructure:"app_folder" yaml:"app_folder"`           // Keep files in the app folder instead of the root of the drive
+	TokenFile string `mapstructure:"token_file" yaml:"token_file,omitempty"` // sync-manager/onedrive/<target>.json in the user config directory when empty
+
+	TransportConfig `mapstructure:",squash" yaml:",inline"`
+}
+
+// TokenPath returns the file the sign-in of the target named target is kept in
+func (o OneDriveConfig) TokenPath(target string) (string, error) {
+	if o.TokenFile != "" {
+		return o.TokenFile, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "onedrive", target+".json"), nil
 }
 
 // LocalConfig holds local filesystem storage configuration
 type LocalConfig struct {
-	RootDir string `mapstructure:"root_dir"`
+	RootDir    string `mapstructure:"root_dir" yaml:"root_dir"`
+	Removable  bool   `mapstructure:"removable" yaml:"removable,omitempty"`     // On a drive that can be unplugged: syncs wait while it is away
+	VolumeUUID string `mapstructure:"volume_uuid" yaml:"volume_uuid,omitempty"` // Filesystem or marker UUID the drive must have; implies removable
+}
+
+// MemoryConfig holds the in-memory storage configuration used by integration tests.
+// Its contents live only as long as the process.
+type MemoryConfig struct {
+	Name      string        `mapstructure:"name" yaml:"name,omitempty"`             // Storages opened with the same name share their contents
+	Latency   time.Duration `mapstructure:"latency" yaml:"latency,omitempty"`       // Delay added to every request
+	ErrorRate float64       `mapstructure:"error_rate" yaml:"error_rate,omitempty"` // Fraction of requests that fail, from 0 to 1
+}
+
+// TelemetryConfig controls OpenTelemetry tracing of the sync pipeline
+type TelemetryConfig struct {
+	Enabled     bool              `mapstructure:"enabled" yaml:"enabled"`
+	Endpoint    string            `mapstructure:"endpoint" yaml:"endpoint"` // OTLP/HTTP collector base URL
+	Headers     map[string]string `mapstructure:"headers" yaml:"headers,omitempty"`
+	ServiceName string            `mapstructure:"service_name" yaml:"service_name"`
+	SampleRatio float64           `mapstructure:"sample_ratio" yaml:"sample_ratio"` // Fraction of traces kept, from 0 to 1
+}
+
+// LANConfig lets devices on the same local network fetch files from each other before the storage backend.
+// Devices find each other over mDNS and only talk, over TLS, to the peers whose certificates they trust.
+type LANConfig struct {
+	Enabled bool              `mapstructure:"enabled" yaml:"enabled"`
+	Listen  string            `mapstructure:"listen" yaml:"listen"`         // Address the agent serves files to peers on
+	Peers   map[string]string `mapstructure:"peers" yaml:"peers,omitempty"` // Certificate fingerprints of the trusted devices, by device ID
+	Timeout time.Duration     `mapstructure:"timeout" yaml:"timeout"`       // Limit for a peer to start sending a file before falling back to storage
+}
+
+// DownloadConfig controls the download pool. Files are fetched in parallel, and large
+// files in parallel chunks when the storage backend supports ranged reads.
+type DownloadConfig struct {
+	MaxConcurrency int   `mapstructure:"max_concurrency" yaml:"max_concurrency"` // Requests in flight at once
+	ThrottleBytes  int64 `mapstructure:"throttle_bytes" yaml:"throttle_bytes"`   // Bandwidth limit in bytes/sec, 0 for none
+	ChunkSize      int64 `mapstructure:"chunk_size" yaml:"chunk_size"`           // Size of the ranges large files are split into
+}
+
+// DefaultDownloadChunkSize is the chunk size used when none is configured
+const DefaultDownloadChunkSize = 8 << 20
+
+// FilesConfig controls the upload of sparse files and cloud placeholders and caps the size of uploaded files
+type FilesConfig struct {
+	Sparse string `mapstructure:"sparse" yaml:"sparse"` // One of the Sparse values, SparseTransfer when empty
+	// MaxFileSize is the largest file uploaded, in bytes. Zero uses the largest object the
+	// storage backend accepts and a negative value uploads files of any size.
+	MaxFileSize int64 `mapstructure:"max_file_size" yaml:"max_file_size"`
+	// HydratePlaceholders uploads the placeholders OneDrive, Dropbox or iCloud leave for files
+	// kept online, downloading their content from the provider; they are skipped otherwise
+	HydratePlaceholders bool `mapstructure:"hydrate_placeholders" yaml:"hydrate_placeholders"`
+}
+
+// PriorityConfig keeps background syncs from slowing the machine down. Level lowers the
+// CPU and I/O priority of the agent when it starts; HashThrottleBytes caps how fast it
+// reads files to hash them.
+type PriorityConfig struct {
+	Level             string `mapstructure:"level" yaml:"level"`                             // One of the Priority values, PriorityNormal when empty
+	HashThrottleBytes int64  `mapstructure:"hash_throttle_bytes" yaml:"hash_throttle_bytes"` // Hashing limit in bytes/sec, 0 for none
+}
+
+// DatabaseConfig selects and protects the database of the CLI and the agent. DSN names a
+// central Postgres or MySQL database shared by several machines, the local SQLite file being
+// used when empty. With Encrypt set, the sensitive columns, the folder names and local paths,
+// are encrypted with a key kept in the OS keychain.
+type DatabaseConfig struct {
+	DSN     string `mapstructure:"dsn" yaml:"dsn,omitempty"` // postgres://… or mysql://… URL
+	Encrypt bool   `mapstructure:"encrypt" yaml:"encrypt"`
+}
+
+// DatabaseDSN returns the DSN of the configured database, the path of the local SQLite file
+// when none is set
+func (c *Config) DatabaseDSN() (string, error) {
+	if c.Database.DSN != "" {
+		return c.Database.DSN, nil
+	}
+	return DatabasePath()
+}
+
+// BandwidthConfig caps the traffic of the device. Once the bytes uploaded and downloaded in
+// the current calendar month reach MonthlyCapBytes, transfers pause until the next month.
+type BandwidthConfig struct {
+	MonthlyCapBytes int64 `mapstructure:"monthly_cap_bytes" yaml:"monthly_cap_bytes"` // 0 for no cap
+}
+
+// CacheConfig sets the staging area the agent writes temporary copies of files to, such as
+// the output of transforms before an upload. Copies no longer in use are kept for reuse until
+// the area reaches MaxBytes, the least recently used being removed first.
+type CacheConfig struct {
+	Dir      string `mapstructure:"dir" yaml:"dir,omitempty"` // sync-manager/staging in the user config directory when empty
+	MaxBytes int64  `mapstructure:"max_bytes" yaml:"max_bytes"`
+}
+
+// DefaultCacheMaxBytes is the size of the staging area when none is configured
+const DefaultCacheMaxBytes = 2 << 30
+
+// ResourcesConfig keeps the agent within its memory budget on very large folders. Scans wait
+// for room in the upload queue instead of queuing every changed file at once, and the garbage
+// collector works harder as the heap nears MemoryLimit.
+type ResourcesConfig struct {
+	MemoryLimit int64 `mapstructure:"memory_limit" yaml:"memory_limit"` // Soft limit of the agent's memory in bytes, 0 for none
+	QueueSize   int   `mapstructure:"queue_size" yaml:"queue_size"`     // Uploads waiting for a worker, applied on restart
+}
+
+// DefaultQueueSize is how many uploads wait for a worker when no queue size is configured
+const DefaultQueueSize = 1000
+
+// SchedulingConfig orders the uploads waiting for a worker, so a document just saved goes
+// ahead of a bulk backfill. Each file is rated for how small it is, how recently it changed
+// and the priority of its folder, each times its weight, and the highest rating starts
+// first. With every weight zero, files upload in the order they were queued.
+type SchedulingConfig struct {
+	SizeWeight    float64 `mapstructure:"size_weight" yaml:"size_weight"`
+	RecencyWeight float64 `mapstructure:"recency_weight" yaml:"recency_weight"`
+	FolderWeight  float64 `mapstructure:"folder_weight" yaml:"folder_weight"`
+}
+
+// ConcurrencyConfig picks how many uploads run at once. In ConcurrencyFixed mode it is
+// max_concurrency. In ConcurrencyAdaptive mode the uploader starts there and, between Min and
+// Max, adds a worker while the throughput keeps rising and halves them when the storage
+// throttles requests or uploads fail.
+type ConcurrencyConfig struct {
+	Mode string `mapstructure:"mode" yaml:"mode"` // One of ConcurrencyModes, ConcurrencyFixed when empty
+	Min  int    `mapstructure:"min" yaml:"min"`
+	Max  int    `mapstructure:"max" yaml:"max"`
+}
+
+// Concurrency modes
+const (
+	// ConcurrencyFixed runs max_concurrency upload workers
+	ConcurrencyFixed = "fixed"
+	// ConcurrencyAdaptive tunes the number of upload workers to the throughput and errors of uploads
+	ConcurrencyAdaptive = "adaptive"
+)
+
+// ConcurrencyModes lists the valid concurrency modes
+var ConcurrencyModes = []string{ConcurrencyFixed, ConcurrencyAdaptive}
+
+// Bounds of adaptive concurrency when none are configured
+const (
+	DefaultMinConcurrency = 1
+	DefaultMaxConcurrency = 16
+)
+
+// ValidateConcurrencyMode checks a concurrency mode, an empty one meaning ConcurrencyFixed
+func ValidateConcurrencyMode(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, valid := range ConcurrencyModes {
+		if mode == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid concurrency mode %q: use %s", mode, strings.Join(ConcurrencyModes, ", "))
+}
+
+// ClockConfig holds the clock check run when the agent starts. Modification times set on
+// this machine are compared with times set by the storage server, so the agent reads the
+// server's time, warns when the clocks are further apart than MaxSkew, and allows for the
+// difference whatever its size.
+type ClockConfig struct {
+	MaxSkew time.Duration `mapstructure:"max_skew" yaml:"max_skew"`
+}
+
+// DefaultMaxClockSkew is how far the clock may be off before the agent warns, when no maximum is configured
+const DefaultMaxClockSkew = time.Minute
+
+// Priority levels
+const (
+	// PriorityNormal runs the agent like any other process
+	PriorityNormal = "normal"
+	// PriorityLow lowers the CPU and I/O priority, so interactive programs come first
+	PriorityLow = "low"
+	// PriorityIdle only gives the agent CPU and disk time nothing else wants
+	PriorityIdle = "idle"
+)
+
+// PriorityLevels lists the valid priority levels
+var PriorityLevels = []string{PriorityNormal, PriorityLow, PriorityIdle}
+
+// ValidatePriority checks a priority level, an empty one meaning PriorityNormal
+func ValidatePriority(level string) error {
+	if level == "" {
+		return nil
+	}
+	for _, valid := range PriorityLevels {
+		if level == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid priority level %q: use %s", level, strings.Join(PriorityLevels, ", "))
+}
+
+// Sparse file policies
+const (
+	// SparseTransfer uploads sparse files and restores their holes when they are downloaded
+	SparseTransfer = "transfer"
+	// SparseWarn uploads sparse files like any other file, logging a warning
+	SparseWarn = "warn"
+	// SparseSkip leaves sparse files out of the sync
+	SparseSkip = "skip"
+)
+
+// SparsePolicies lists the valid sparse file policies
+var SparsePolicies = []string{SparseTransfer, SparseWarn, SparseSkip}
+
+// ValidateSparsePolicy checks a sparse file policy, an empty one meaning SparseTransfer
+func ValidateSparsePolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	for _, valid := range SparsePolicies {
+		if policy == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid sparse file policy %q: use %s", policy, strings.Join(SparsePolicies, ", "))
+}
+
+// StorageMiddlewareConfig selects the wrappers applied around every storage backend.
+// They run in a fixed order: logging, metrics, cache, retry, then the backend.
+type StorageMiddlewareConfig struct {
+	Logging bool                 `mapstructure:"logging" yaml:"logging"` // Log every storage request at debug level
+	Metrics StorageMetricsConfig `mapstructure:"metrics" yaml:"metrics"`
+	Cache   StorageCacheConfig   `mapstructure:"cache" yaml:"cache"`
+	Retry   StorageRetryConfig   `mapstructure:"retry" yaml:"retry"`
+}
+
+// StorageMetricsConfig exposes storage request metrics in the Prometheus text format
+type StorageMetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled" yaml:"enabled"`
+	Listen  string `mapstructure:"listen" yaml:"listen"` // Address the agent serves /metrics on
+}
+
+// StorageCacheConfig caches existence checks and listings for a short time
+type StorageCacheConfig struct {
+	Enabled    bool          `mapstructure:"enabled" yaml:"enabled"`
+	TTL        time.Duration `mapstructure:"ttl" yaml:"ttl"`
+	MaxEntries int           `mapstructure:"max_entries" yaml:"max_entries"`
+}
+
+// StorageRetryConfig retries failed storage requests with exponential backoff
+type StorageRetryConfig struct {
+	Enabled        bool          `mapstructure:"enabled" yaml:"enabled"`
+	MaxAttempts    int           `mapstructure:"max_attempts" yaml:"max_attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff" yaml:"max_backoff"`
 }
 
+// PowerConfig controls transfers while the device runs on battery, uses a metered connection
+// or runs programs that need the machine to themselves
+type PowerConfig struct {
+	OnBattery ConditionPolicy `mapstructure:"on_battery" yaml:"on_battery"`
+	OnMetered ConditionPolicy `mapstructure:"on_metered" yaml:"on_metered"`
+	// Processes names programs, such as ffmpeg or steam, while any of which OnProcesses
+	// applies. Names are compared with the program name of each process, ignoring case and
+	// a .exe suffix.
+	Processes   []string        `mapstructure:"processes" yaml:"processes,omitempty"`
+	OnProcesses ConditionPolicy `mapstructure:"on_processes" yaml:"on_processes"`
+}
+
+// ConditionPolicy is what the agent does with transfers while a condition holds
+type ConditionPolicy struct {
+	Action        string `mapstructure:"action" yaml:"action"`                 // One of the PolicyAction values
+	ThrottleBytes int64  `mapstructure:"throttle_bytes" yaml:"throttle_bytes"` // Bandwidth limit for PolicyThrottle, in bytes/sec
+	MaxFileSize   int64  `mapstructure:"max_file_size" yaml:"max_file_size"`   // Largest file transferred under PolicySmallFiles, in bytes
+}
+
+// Power policy actions
+const (
+	// PolicyNone leaves transfers unchanged
+	PolicyNone = "none"
+	// PolicyPause holds all transfers until the condition ends
+	PolicyPause = "pause"
+	// PolicyThrottle limits the upload bandwidth
+	PolicyThrottle = "throttle"
+	// PolicySmallFiles only transfers files up to MaxFileSize
+	PolicySmallFiles = "small-files"
+)
+
 // SyncFolder represents a folder to be synchronized
 type SyncFolder struct {
-	ID         string   `mapstructure:"id"`
-	Path       string   `mapstructure:"path"`
-	Enabled    bool     `mapstructure:"enabled"`
-	Exclude    []string `mapstructure:"exclude"`
-	Priority   int      `mapstructure:"priority"`
-	TwoWaySync bool     `mapstructure:"two_way_sync"`
+	ID         string          `mapstructure:"id" yaml:"id"`
+	Path       string          `mapstructure:"path" yaml:"path"`
+	Enabled    bool            `mapstructure:"enabled" yaml:"enabled"`
+	Exclude    []string        `mapstructure:"exclude" yaml:"exclude"`
+	Priority   int             `mapstructure:"priority" yaml:"priority"`
+	TwoWaySync bool            `mapstructure:"two_way_sync" yaml:"two_way_sync"`
+	Paused     bool            `mapstructure:"paused" yaml:"paused"`
+	Interval   time.Duration   `mapstructure:"interval" yaml:"interval"` // Overrides sync_interval when set
+	Mode       string          `mapstructure:"mode" yaml:"mode"`         // FolderModeMirror (default) or FolderModeBackup
+	Retention  RetentionConfig `mapstructure:"retention" yaml:"retention"`
+	Mirror     MirrorConfig    `mapstructure:"mirror" yaml:"mirror"`
+	// StorageClass selects the class of uploaded objects (e.g. STANDARD_IA, NEARLINE), empty for the bucket default
+	StorageClass string `mapstructure:"storage_class" yaml:"storage_class,omitempty"`
+	// PackSmallFiles makes a backup folder pack its files of up to this many bytes into shared
+	// pack objects, so millions of tiny files cost few requests. Zero stores each file alone.
+	PackSmallFiles int64 `mapstructure:"pack_small_files" yaml:"pack_small_files,omitempty"`
+	// Roots adds local directories to the folder, each synced under its prefix of the remote folder
+	Roots []FolderRoot `mapstructure:"roots" yaml:"roots,omitempty"`
+	// ConflictPolicy decides which copy wins when a file changed on both sides, ConflictKeepBoth when empty
+	ConflictPolicy string `mapstructure:"conflict_policy" yaml:"conflict_policy,omitempty"`
+	// InUseTimeout bounds how long the upload of a file still being written waits for it to
+	// settle. Zero uses DefaultInUseTimeout and a negative value uploads files in use right away.
+	InUseTimeout time.Duration `mapstructure:"in_use_timeout" yaml:"in_use_timeout,omitempty"`
+	// File makes a single-file folder syncing only the file of that name directly under Path
+	File string `mapstructure:"file" yaml:"file,omitempty"`
+	// InitialMerge is the conflict policy applied to the files already on both sides when a
+	// two-way folder syncs for the first time, ConflictPolicy when empty
+	InitialMerge string `mapstructure:"initial_merge" yaml:"initial_merge,omitempty"`
+	// Target names the storage target the folder syncs to, the first one when empty
+	Target string `mapstructure:"target" yaml:"target,omitempty"`
+	// Subscribe makes the folder a read-only copy of the remote folder another device publishes
+	// under ID: remote changes are downloaded and local ones are never uploaded
+	Subscribe bool `mapstructure:"subscribe" yaml:"subscribe,omitempty"`
+	// LocalChanges decides what a subscribed folder does with files changed on this device,
+	// LocalChangesRevert when empty
+	LocalChanges string `mapstructure:"local_changes" yaml:"local_changes,omitempty"`
+	// ArchiveAfterDays replaces the local copy of files uploaded and left unmodified for that
+	// many days with a placeholder, freeing their disk space. Zero keeps every file local.
+	ArchiveAfterDays int `mapstructure:"archive_after_days" yaml:"archive_after_days,omitempty"`
+	// RemotePrefix is the storage prefix the folder's files are kept under, the folder ID when
+	// empty. Changing it requires moving the existing files, as 'configure-folder' does.
+	RemotePrefix string `mapstructure:"remote_prefix" yaml:"remote_prefix,omitempty"`
+	// Schedule is a cron expression of when the folder syncs, in local time, such as "0 2 * * *"
+	// for every night at 2:00. It overrides Interval and the global sync_interval.
+	Schedule string `mapstructure:"schedule" yaml:"schedule,omitempty"`
+	// AgeFilter limits the files uploaded by when they were last modified
+	AgeFilter `mapstructure:",squash" yaml:",inline"`
+	// Hooks are shell commands run before and after each sync of the folder
+	Hooks FolderHooks `mapstructure:"hooks" yaml:"hooks,omitempty"`
+	// Checksum is the algorithm hashing the files of the folder, one of checksum.Algorithms,
+	// SHA-256 when empty. Hashes already recorded keep their algorithm until files change.
+	Checksum string `mapstructure:"checksum" yaml:"checksum,omitempty"`
+}
+
+// FolderHooks are the shell commands run around the syncs of a folder. Each runs in the folder
+// with SYNC_MANAGER_* variables describing the run, the post_sync one also getting its result.
+type FolderHooks struct {
+	PreSync  Hook `mapstructure:"pre_sync" yaml:"pre_sync,omitempty"`   // Before the sync, e.g. to dump a database into the folder
+	PostSync Hook `mapstructure:"post_sync" yaml:"post_sync,omitempty"` // Once the sync ended, successfully or not
+}
+
+// Hook is a shell command run by sh, or cmd on Windows. An empty command runs nothing.
+type Hook struct {
+	Command string        `mapstructure:"command" yaml:"command,omitempty"`
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout,omitempty"` // DefaultHookTimeout when zero
+	// OnFailure is one of the HookFailure values; pre_sync hooks abort and post_sync hooks
+	// continue when empty
+	OnFailure string `mapstructure:"on_failure" yaml:"on_failure,omitempty"`
+}
+
+// Hook failure policies
+const (
+	// HookAbort fails the sync: a failed pre_sync hook skips it, a failed post_sync hook
+	// records it as failed
+	HookAbort = "abort"
+	// HookContinue logs the failure and carries on as if the hook succeeded
+	HookContinue = "continue"
+)
+
+// HookFailurePolicies lists the valid hook failure policies
+var HookFailurePolicies = []string{HookAbort, HookContinue}
+
+// DefaultHookTimeout is how long a hook may run when it sets no timeout
+const DefaultHookTimeout = 10 * time.Minute
+
+// AgeFilter limits the files of a folder that are uploaded by their modification time. Files
+// it leaves out stay local and untouched on the storage, rather than counting as deleted.
+type AgeFilter struct {
+	// MinAge holds back files modified more recently until they are that old, so files still
+	// being written are not uploaded half-done
+	MinAge time.Duration `mapstructure:"min_age" yaml:"min_age,omitempty"`
+	// MaxAge leaves out files last modified longer ago, zero for no limit
+	MaxAge time.Duration `mapstructure:"max_age" yaml:"max_age,omitempty"`
+	// ModifiedSince leaves out files last modified before this date, as 2006-01-02 in local
+	// time or an RFC 3339 timestamp
+	ModifiedSince string `mapstructure:"modified_since" yaml:"modified_since,omitempty"`
+}
+
+// ParseModifiedSince parses the date of a modified_since filter, a day in local time or an
+// RFC 3339 timestamp. It returns the zero time for an empty value.
+func ParseModifiedSince(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD or an RFC 3339 timestamp", value)
+	}
+	return t, nil
+}
+
+// KeyPrefix returns the storage prefix of the folder's files, without a trailing slash
+func (folder SyncFolder) KeyPrefix() string {
+	if folder.RemotePrefix != "" {
+		return folder.RemotePrefix
+	}
+	return folder.ID
+}
+
+// Key returns the storage key of a slash-separated path relative to the folder
+func (folder SyncFolder) Key(relPath string) string {
+	return folder.KeyPrefix() + "/" + relPath
+}
+
+// FolderRoot is an extra local directory of a sync folder. Its files are stored under
+// Prefix, so ~/Desktop with prefix "Desktop" shares a remote folder with ~/Documents.
+type FolderRoot struct {
+	Path   string `mapstructure:"path" yaml:"path"`
+	Prefix string `mapstructure:"prefix" yaml:"prefix"`
+}
+
+// Folder modes
+const (
+	// FolderModeMirror keeps the remote copy identical to the folder
+	FolderModeMirror = "mirror"
+	// FolderModeBackup stores a point-in-time snapshot on every sync
+	FolderModeBackup = "backup"
+)
+
+// What a subscribed folder does with files changed on this device
+const (
+	// LocalChangesRevert restores the published copy of changed and deleted files and removes new ones
+	LocalChangesRevert = "revert"
+	// LocalChangesFlag keeps local changes, recording each one, until the publisher changes the file
+	LocalChangesFlag = "flag"
+)
+
+// Conflict policies
+const (
+	// ConflictKeepBoth keeps the local file and saves the remote one as a conflict copy
+	ConflictKeepBoth = "keep-both"
+	// ConflictPreferLocal keeps the local file and uploads it over the remote one
+	ConflictPreferLocal = "prefer-local"
+	// ConflictPreferRemote replaces the local file with the remote one
+	ConflictPreferRemote = "prefer-remote"
+	// ConflictPreferNewest keeps the most recently modified copy, the local one on a tie
+	ConflictPreferNewest = "prefer-newest"
+)
+
+// ConflictPolicies lists the valid conflict policies
+var ConflictPolicies = []string{ConflictKeepBoth, ConflictPreferLocal, ConflictPreferRemote, ConflictPreferNewest}
+
+// ValidateConflictPolicy checks a conflict policy, an empty one meaning ConflictKeepBoth
+func ValidateConflictPolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	for _, valid := range ConflictPolicies {
+		if policy == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid conflict policy %q: use %s", policy, strings.Join(ConflictPolicies, ", "))
+}
+
+// MirrorConfig controls whether a one-way mirror folder removes remote files that were
+// deleted locally. It is ignored by two-way and backup folders.
+type MirrorConfig struct {
+	DeleteOrphans bool `mapstructure:"delete_orphans" yaml:"delete_orphans"`
+	Trash         bool `mapstructure:"trash" yaml:"trash"` // Move orphans under .trash/<folder-id>/ instead of deleting them
+	// MaxDelete and MaxDeletePercent bound the orphans removed in one sync, as a count and as
+	// a percentage of the remote files. Above either limit nothing is removed until the user
+	// forces it. Zero uses the default and a negative value disables the limit.
+	MaxDelete        int `mapstructure:"max_delete" yaml:"max_delete"`
+	MaxDeletePercent int `mapstructure:"max_delete_percent" yaml:"max_delete_percent"`
+}
+
+// Default orphan removal limits of folders that do not set them
+const (
+	DefaultMaxDelete        = 100
+	DefaultMaxDeletePercent = 25
+)
+
+// DefaultInUseTimeout is how long uploads wait for files in use in folders that do not set it
+const DefaultInUseTimeout = 10 * time.Minute
+
+// RetentionConfig controls which backup snapshots are kept. Zero values keep everything.
+type RetentionConfig struct {
+	KeepLast    int `mapstructure:"keep_last" yaml:"keep_last"`
+	KeepDaily   int `mapstructure:"keep_daily" yaml:"keep_daily"`
+	KeepWeekly  int `mapstructure:"keep_weekly" yaml:"keep_weekly"`
+	KeepMonthly int `mapstructure:"keep_monthly" yaml:"keep_monthly"`
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		DeviceID:        "",
-		DeviceName:      "",
-		LogLevel:        "info",
-		LogPath:         "",
-		SyncInterval:    time.Minute * 5,
-		MaxConcurrency:  4,
-		ThrottleBytes:   0,       // no throttling by default
-		StorageProvider: "minio", // Default to MinIO for development
-		S3Config: S3Config{
-			Region:    "us-east-1",
-			UseSSL:    true,
-			PathStyle: false,
+		DeviceID:         "",
+		DeviceName:       "",
+		LogLevel:         "info",
+		LogPath:          "",
+		SyncInterval:     time.Minute * 5,
+		FullScanInterval: 24 * time.Hour,
+		MaxConcurrency:   4,
+		ThrottleBytes:    0, // no throttling by default
+		// Default to MinIO for development
+		Targets:       []StorageTarget{{Name: DefaultTargetName, Type: TargetMinio, Minio: defaultMinioConfig()}},
+		SyncFolders:   []SyncFolder{},
+		NestedFolders: NestingRefuse,
+		Telemetry: TelemetryConfig{
+			Enabled:     false,
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "sync-manager-agent",
+			SampleRatio: 1,
+		},
+		Power: PowerConfig{
+			OnBattery:   ConditionPolicy{Action: PolicyNone},
+			OnMetered:   ConditionPolicy{Action: PolicyNone},
+			OnProcesses: ConditionPolicy{Action: PolicyPause},
+		},
+		StorageMiddleware: StorageMiddlewareConfig{
+			Metrics: StorageMetricsConfig{Listen: "127.0.0.1:9464"},
+			Cache:   StorageCacheConfig{TTL: 30 * time.Second, MaxEntries: 10000},
+			Retry:   StorageRetryConfig{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second},
+		},
+		LAN: LANConfig{
+			Listen:  ":21028",
+			Timeout: 5 * time.Second,
+		},
+		Download: DownloadConfig{
+			MaxConcurrency: 4,
+			ChunkSize:      DefaultDownloadChunkSize,
+		},
+		Files: FilesConfig{
+			Sparse: SparseTransfer,
+		},
+		Priority: PriorityConfig{
+			Level: PriorityNormal,
 		},
-		MinioConfig: MinioConfig{
-			Endpoint:  "localhost:9000",
-			Region:    "us-east-1",
-			Bucket:    "sync-manager",
-			AccessKey: "minioadmin",
-			SecretKey: "minioadmin",
-			UseSSL:    false,
+		Cache: CacheConfig{
+			MaxBytes: DefaultCacheMaxBytes,
 		},
-		GCSConfig: GCSConfig{
-			Bucket: "",
+		Resources: ResourcesConfig{
+			QueueSize: DefaultQueueSize,
 		},
-		LocalConfig: LocalConfig{
-			RootDir: "",
+		Scheduling: SchedulingConfig{
+			SizeWeight:    1,
+			RecencyWeight: 2,
+			FolderWeight:  1,
+		},
+		Concurrency: ConcurrencyConfig{
+			Mode: ConcurrencyFixed,
+			Min:  DefaultMinConcurrency,
+			Max:  DefaultMaxConcurrency,
+		},
+		Clock: ClockConfig{
+			MaxSkew: DefaultMaxClockSkew,
 		},
-		SyncFolders: []SyncFolder{},
 	}
 }
 
@@ -118,39 +731,47 @@ func DefaultConfig() *Config {
 func LoadConfig(configPath string) (*Config, error) {
 	config := DefaultConfig()
 
-	viper.SetConfigName("cloudsync")
 	viper.SetConfigType("yaml")
 
-	// If config path is provided, use it
+	// If config path is not provided, look in the default locations under both names
+	if configPath == "" {
+		found, err := Discover()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find config: %w", err)
+		}
+		configPath = found
+	}
+
+	// It's okay if no config file exists, we'll use defaults
 	if configPath != "" {
 		viper.SetConfigFile(configPath)
-	} else {
-		// Otherwise use default locations
-		// 1. Current directory
-		viper.AddConfigPath(".")
-
-		// 2. User config directory
-		userConfigDir, err := os.UserConfigDir()
-		if err == nil {
-			viper.AddConfigPath(filepath.Join(userConfigDir, "cloudsync"))
+		if err := viper.ReadInConfig(); err != nil {
+			return nil, err
 		}
+	}
 
-		// 3. System config directories
-		viper.AddConfigPath("/etc/cloudsync")
+	// Upgrade files written with an older schema
+	settings := viper.AllSettings()
+	if err := upgrade(configPath, settings); err != nil {
+		return nil, fmt.Errorf("failed to upgrade config: %w", err)
 	}
 
-	// Read config file
-	if err := viper.ReadInConfig(); err != nil {
-		// It's okay if config file doesn't exist, we'll use defaults
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, err
-		}
+	// Expand environment and file references before decoding. The expanded values go
+	// through a separate instance so they never become overrides of the file.
+	references, err := interpolate(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand config references: %w", err)
+	}
+	expanded := viper.New()
+	if err := expanded.MergeConfigMap(settings); err != nil {
+		return nil, err
 	}
 
 	// Unmarshal into our config struct
-	if err := viper.Unmarshal(config); err != nil {
+	if err := expanded.Unmarshal(config); err != nil {
 		return nil, err
 	}
+	config.references = references
 
 	// Validate configuration
 	if err := validateConfig(config); err != nil {
@@ -160,45 +781,105 @@ func LoadConfig(configPath string) (*Config, error) {
 	return config, nil
 }
 
+// ConfigFileUsed returns the file the last LoadConfig read, empty when only defaults were used
+func ConfigFileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
 // SaveConfig saves the configuration to a file
 func SaveConfig(config *Config, path string) error {
 	// Set the config values in viper
+	viper.Set("config_version", CurrentConfigVersion)
 	viper.Set("device_id", config.DeviceID)
 	viper.Set("device_name", config.DeviceName)
 	viper.Set("log_level", config.LogLevel)
 	viper.Set("log_path", config.LogPath)
 	viper.Set("sync_interval", config.SyncInterval)
+	viper.Set("full_scan_interval", config.FullScanInterval)
 	viper.Set("max_concurrency", config.MaxConcurrency)
 	viper.Set("throttle_bytes", config.ThrottleBytes)
-	viper.Set("storage_provider", config.StorageProvider)
 	viper.Set("api_endpoint", config.ApiEndpoint)
 	viper.Set("api_token", config.ApiToken)
+	setTransport("http", config.HTTP)
 	viper.Set("sync_folders", config.SyncFolders)
+	viper.Set("nested_folders", config.NestedFolders)
+
+	// Storage targets, with the references their settings were expanded from
+	targets, err := targetSettings(config.Targets, config.references)
+	if err != nil {
+		return err
+	}
+	viper.Set("targets", targets)
+
+	// Telemetry config
+	viper.Set("telemetry.enabled", config.Telemetry.Enabled)
+	viper.Set("telemetry.endpoint", config.Telemetry.Endpoint)
+	viper.Set("telemetry.headers", config.Telemetry.Headers)
+	viper.Set("telemetry.service_name", config.Telemetry.ServiceName)
+	viper.Set("telemetry.sample_ratio", config.Telemetry.SampleRatio)
+
+	// Power config
+	viper.Set("power.on_battery", config.Power.OnBattery)
+	viper.Set("power.on_metered", config.Power.OnMetered)
+	viper.Set("power.processes", config.Power.Processes)
+	viper.Set("power.on_processes", config.Power.OnProcesses)
 
-	// S3 config
-	viper.Set("s3.endpoint", config.S3Config.Endpoint)
-	viper.Set("s3.region", config.S3Config.Region)
-	viper.Set("s3.bucket", config.S3Config.Bucket)
-	viper.Set("s3.access_key", config.S3Config.AccessKey)
-	viper.Set("s3.secret_key", config.S3Config.SecretKey)
-	viper.Set("s3.use_ssl", config.S3Config.UseSSL)
-	viper.Set("s3.path_style", config.S3Config.PathStyle)
-
-	// MinIO config
-	viper.Set("minio.endpoint", config.MinioConfig.Endpoint)
-	viper.Set("minio.region", config.MinioConfig.Region)
-	viper.Set("minio.bucket", config.MinioConfig.Bucket)
-	viper.Set("minio.access_key", config.MinioConfig.AccessKey)
-	viper.Set("minio.secret_key", config.MinioConfig.SecretKey)
-	viper.Set("minio.use_ssl", config.MinioConfig.UseSSL)
-
-	// GCS config
-	viper.Set("gcs.project_id", config.GCSConfig.ProjectID)
-	viper.Set("gcs.bucket", config.GCSConfig.Bucket)
-	viper.Set("gcs.credentials_file", config.GCSConfig.CredentialsFile)
-
-	// Local config
-	viper.Set("local.root_dir", config.LocalConfig.RootDir)
+	// Storage middleware config
+	viper.Set("storage_middleware", config.StorageMiddleware)
+
+	// LAN config
+	viper.Set("lan.enabled", config.LAN.Enabled)
+	viper.Set("lan.listen", config.LAN.Listen)
+	viper.Set("lan.peers", config.LAN.Peers)
+	viper.Set("lan.timeout", config.LAN.Timeout)
+
+	// Download config
+	viper.Set("download.max_concurrency", config.Download.MaxConcurrency)
+	viper.Set("download.throttle_bytes", config.Download.ThrottleBytes)
+	viper.Set("download.chunk_size", config.Download.ChunkSize)
+
+	// Files config
+	viper.Set("files.sparse", config.Files.Sparse)
+	viper.Set("files.max_file_size", config.Files.MaxFileSize)
+	viper.Set("files.hydrate_placeholders", config.Files.HydratePlaceholders)
+
+	// Priority config
+	viper.Set("priority.level", config.Priority.Level)
+	viper.Set("priority.hash_throttle_bytes", config.Priority.HashThrottleBytes)
+
+	// Database config
+	viper.Set("database.dsn", config.Database.DSN)
+	viper.Set("database.encrypt", config.Database.Encrypt)
+
+	// Bandwidth config
+	viper.Set("bandwidth.monthly_cap_bytes", config.Bandwidth.MonthlyCapBytes)
+
+	// Cache config
+	viper.Set("cache.dir", config.Cache.Dir)
+	viper.Set("cache.max_bytes", config.Cache.MaxBytes)
+
+	// Resources config
+	viper.Set("resources.memory_limit", config.Resources.MemoryLimit)
+	viper.Set("resources.queue_size", config.Resources.QueueSize)
+
+	// Scheduling config
+	viper.Set("scheduling.size_weight", config.Scheduling.SizeWeight)
+	viper.Set("scheduling.recency_weight", config.Scheduling.RecencyWeight)
+	viper.Set("scheduling.folder_weight", config.Scheduling.FolderWeight)
+
+	// Concurrency config
+	viper.Set("concurrency.mode", config.Concurrency.Mode)
+	viper.Set("concurrency.min", config.Concurrency.Min)
+	viper.Set("concurrency.max", config.Concurrency.Max)
+
+	// Clock config
+	viper.Set("clock.max_skew", config.Clock.MaxSkew)
+
+	// Plugins config
+	viper.Set("plugins.dir", config.Plugins.Dir)
+
+	// Keep references in the file instead of the values they expanded to
+	restoreReferences(config.references)
 
 	// If path is not provided, use the config file that was loaded
 	if path == "" {
@@ -207,15 +888,9 @@ func SaveConfig(config *Config, path string) error {
 
 	// If we still don't have a path, use default
 	if path == "" {
-		userConfigDir, err := os.UserConfigDir()
-		if err != nil {
+		if path, err = GetConfigPath(); err != nil {
 			return err
 		}
-		configDir := filepath.Join(userConfigDir, "cloudsync")
-		if err := os.MkdirAll(configDir, 0755); err != nil {
-			return err
-		}
-		path = filepath.Join(configDir, "cloudsync.yaml")
 	}
 
 	// Write the config file
@@ -223,54 +898,190 @@ func SaveConfig(config *Config, path string) error {
 }
 
 // validateConfig validates the configuration
+// Validate checks that the proxy URL is usable and the CA bundle exists
+func (t TransportConfig) Validate() error {
+	if t.ProxyURL != "" {
+		u, err := url.Parse(t.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("proxy URL must use http, https or socks5: %s", t.ProxyURL)
+		}
+		if u.Host == "" {
+			return fmt.Errorf("proxy URL has no host: %s", t.ProxyURL)
+		}
+	}
+	if t.CACertFile != "" {
+		if _, err := os.Stat(t.CACertFile); err != nil {
+			return fmt.Errorf("CA certificate file not found: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateStorageMiddleware checks the enabled middlewares have usable settings
+func validateStorageMiddleware(mw *StorageMiddlewareConfig) error {
+	if mw.Metrics.Enabled && mw.Metrics.Listen == "" {
+		return fmt.Errorf("metrics listen address is required when metrics are enabled")
+	}
+	if mw.Cache.Enabled && mw.Cache.TTL <= 0 {
+		return fmt.Errorf("cache ttl must be positive")
+	}
+	if mw.Retry.Enabled {
+		if mw.Retry.MaxAttempts < 1 {
+			return fmt.Errorf("retry max_attempts must be at least 1")
+		}
+		if mw.Retry.InitialBackoff <= 0 {
+			return fmt.Errorf("retry initial_backoff must be positive")
+		}
+		if mw.Retry.MaxBackoff < mw.Retry.InitialBackoff {
+			mw.Retry.MaxBackoff = mw.Retry.InitialBackoff
+		}
+	}
+	return nil
+}
+
+// setTransport sets the transport keys under prefix
+func setTransport(prefix string, transport TransportConfig) {
+	viper.Set(prefix+".proxy_url", transport.ProxyURL)
+	viper.Set(prefix+".ca_cert_file", transport.CACertFile)
+	viper.Set(prefix+".insecure_skip_verify", transport.InsecureSkipVerify)
+}
+
 func validateConfig(config *Config) error {
-	// Validate storage provider configuration based on selected provider
-	switch config.StorageProvider {
-	case "s3":
-		if config.S3Config.Bucket == "" {
-			return fmt.Errorf("S3 bucket is required")
-		}
-		// Only require access key and secret key if not using AWS environment credentials
-		if config.S3Config.Endpoint != "" {
-			if config.S3Config.AccessKey == "" {
-				return fmt.Errorf("S3 access key is required when using a custom endpoint")
-			}
-			if config.S3Config.SecretKey == "" {
-				return fmt.Errorf("S3 secret key is required when using a custom endpoint")
-			}
+	if err := validateTargets(config); err != nil {
+		return err
+	}
+
+	if config.Telemetry.Enabled && config.Telemetry.Endpoint == "" {
+		return fmt.Errorf("telemetry endpoint is required when telemetry is enabled")
+	}
+	if config.Telemetry.SampleRatio < 0 {
+		config.Telemetry.SampleRatio = 0
+	} else if config.Telemetry.SampleRatio > 1 {
+		config.Telemetry.SampleRatio = 1
+	}
+
+	for name, policy := range map[string]*ConditionPolicy{"on_battery": &config.Power.OnBattery, "on_metered": &config.Power.OnMetered, "on_processes": &config.Power.OnProcesses} {
+		if err := validatePolicy(policy); err != nil {
+			return fmt.Errorf("invalid power.%s policy: %w", name, err)
 		}
-	case "minio":
-		if config.MinioConfig.Bucket == "" {
-			return fmt.Errorf("MinIO bucket is required")
+	}
+
+	if err := config.HTTP.Validate(); err != nil {
+		return fmt.Errorf("invalid http transport settings: %w", err)
+	}
+
+	if err := ValidateSparsePolicy(config.Files.Sparse); err != nil {
+		return fmt.Errorf("invalid files.sparse: %w", err)
+	}
+	if config.Files.Sparse == "" {
+		config.Files.Sparse = SparseTransfer
+	}
+
+	if err := ValidatePriority(config.Priority.Level); err != nil {
+		return fmt.Errorf("invalid priority.level: %w", err)
+	}
+	if config.Priority.Level == "" {
+		config.Priority.Level = PriorityNormal
+	}
+	if config.Bandwidth.MonthlyCapBytes < 0 {
+		return fmt.Errorf("bandwidth.monthly_cap_bytes cannot be negative")
+	}
+	if config.Cache.MaxBytes <= 0 {
+		config.Cache.MaxBytes = DefaultCacheMaxBytes
+	}
+	if config.Resources.MemoryLimit < 0 {
+		return fmt.Errorf("resources.memory_limit cannot be negative")
+	}
+	if config.Resources.QueueSize <= 0 {
+		config.Resources.QueueSize = DefaultQueueSize
+	}
+	if config.Scheduling.SizeWeight < 0 || config.Scheduling.RecencyWeight < 0 || config.Scheduling.FolderWeight < 0 {
+		return fmt.Errorf("scheduling weights cannot be negative")
+	}
+	if config.Clock.MaxSkew <= 0 {
+		config.Clock.MaxSkew = DefaultMaxClockSkew
+	}
+	if config.Priority.HashThrottleBytes < 0 {
+		config.Priority.HashThrottleBytes = 0
+	}
+
+	if err := validateStorageMiddleware(&config.StorageMiddleware); err != nil {
+		return fmt.Errorf("invalid storage_middleware: %w", err)
+	}
+
+	if config.LAN.Enabled {
+		if config.LAN.Listen == "" {
+			return fmt.Errorf("lan.listen is required when LAN sync is enabled")
 		}
-		if config.MinioConfig.Endpoint == "" {
-			return fmt.Errorf("MinIO endpoint is required")
+	}
+	for deviceID, fingerprint := range config.LAN.Peers {
+		normalized, err := identity.NormalizeFingerprint(fingerprint)
+		if err != nil {
+			return fmt.Errorf("invalid LAN peer %s: %w", deviceID, err)
 		}
-		if config.MinioConfig.AccessKey == "" {
-			return fmt.Errorf("MinIO access key is required")
+		config.LAN.Peers[deviceID] = normalized
+	}
+
+	for i := range config.SyncFolders {
+		if err := config.SyncFolders[i].ValidateRoots(); err != nil {
+			return fmt.Errorf("invalid roots for folder %s: %w", config.SyncFolders[i].ID, err)
 		}
-		if config.MinioConfig.SecretKey == "" {
-			return fmt.Errorf("MinIO secret key is required")
+		if err := config.SyncFolders[i].ValidateFile(); err != nil {
+			return fmt.Errorf("invalid file for folder %s: %w", config.SyncFolders[i].ID, err)
 		}
-	case "gcs":
-		if config.GCSConfig.Bucket == "" {
-			return fmt.Errorf("GCS bucket is required")
+		if err := ValidateConflictPolicy(config.SyncFolders[i].ConflictPolicy); err != nil {
+			return fmt.Errorf("invalid folder %s: %w", config.SyncFolders[i].ID, err)
 		}
-		if config.GCSConfig.ProjectID == "" {
-			return fmt.Errorf("GCS project ID is required")
+		if err := ValidateConflictPolicy(config.SyncFolders[i].InitialMerge); err != nil {
+			return fmt.Errorf("invalid initial merge for folder %s: %w", config.SyncFolders[i].ID, err)
 		}
-	case "local":
-		if config.LocalConfig.RootDir == "" {
-			return fmt.Errorf("Local storage root directory is required")
+		if err := config.SyncFolders[i].ValidateSubscribe(); err != nil {
+			return fmt.Errorf("invalid subscription for folder %s: %w", config.SyncFolders[i].ID, err)
 		}
-	default:
-		return fmt.Errorf("unsupported storage provider: %s", config.StorageProvider)
+		if err := config.SyncFolders[i].ValidateArchive(); err != nil {
+			return fmt.Errorf("invalid archive policy for folder %s: %w", config.SyncFolders[i].ID, err)
+		}
+		if err := config.SyncFolders[i].ValidatePacking(); err != nil {
+			return fmt.Errorf("invalid packing for folder %s: %w", config.SyncFolders[i].ID, err)
+		}
+		if err := config.SyncFolders[i].ValidateRemotePrefix(); err != nil {
+			return fmt.Errorf("invalid remote prefix for folder %s: %w", config.SyncFolders[i].ID, err)
+		}
+		if err := config.SyncFolders[i].ValidateSchedule(); err != nil {
+			return fmt.Errorf("invalid schedule for folder %s: %w", config.SyncFolders[i].ID, err)
+		}
+		if err := config.SyncFolders[i].ValidateAgeFilter(); err != nil {
+			return fmt.Errorf("invalid age filter for folder %s: %w", config.SyncFolders[i].ID, err)
+		}
+		if err := config.SyncFolders[i].ValidateHooks(); err != nil {
+			return fmt.Errorf("invalid hooks for folder %s: %w", config.SyncFolders[i].ID, err)
+		}
+		if err := config.SyncFolders[i].ValidateChecksum(); err != nil {
+			return fmt.Errorf("invalid checksum for folder %s: %w", config.SyncFolders[i].ID, err)
+		}
+	}
+	if err := ValidateKeyPrefixes(config.SyncFolders); err != nil {
+		return err
+	}
+	if err := ValidateNestingPolicy(config.NestedFolders); err != nil {
+		return err
+	}
+	if config.NestedFolders == "" {
+		config.NestedFolders = NestingRefuse
 	}
 
 	// Ensure sync interval is reasonable
 	if config.SyncInterval < time.Second {
 		config.SyncInterval = time.Second
 	}
+	if config.FullScanInterval < 0 {
+		return fmt.Errorf("invalid full scan interval: %s", config.FullScanInterval)
+	}
 
 	// Ensure max concurrency is reasonable
 	if config.MaxConcurrency <= 0 {
@@ -278,19 +1089,273 @@ func validateConfig(config *Config) error {
 	} else if config.MaxConcurrency > 32 {
 		config.MaxConcurrency = 32
 	}
+	if config.Download.MaxConcurrency <= 0 {
+		config.Download.MaxConcurrency = 1
+	} else if config.Download.MaxConcurrency > 32 {
+		config.Download.MaxConcurrency = 32
+	}
+	if err := ValidateConcurrencyMode(config.Concurrency.Mode); err != nil {
+		return err
+	}
+	if config.Concurrency.Mode == "" {
+		config.Concurrency.Mode = ConcurrencyFixed
+	}
+	if config.Concurrency.Min <= 0 {
+		config.Concurrency.Min = DefaultMinConcurrency
+	}
+	if config.Concurrency.Max <= 0 {
+		config.Concurrency.Max = DefaultMaxConcurrency
+	} else if config.Concurrency.Max > 32 {
+		config.Concurrency.Max = 32
+	}
+	if config.Concurrency.Min > config.Concurrency.Max {
+		return fmt.Errorf("concurrency.min (%d) cannot be above concurrency.max (%d)", config.Concurrency.Min, config.Concurrency.Max)
+	}
+	if config.Download.ChunkSize <= 0 {
+		config.Download.ChunkSize = DefaultDownloadChunkSize
+	}
 
 	return nil
 }
 
 // GetConfigPath returns the default configuration path
 func GetConfigPath() (string, error) {
-	userConfigDir, err := os.UserConfigDir()
+	dir, err := configDir()
 	if err != nil {
 		return "", err
 	}
-	configDir := filepath.Join(userConfigDir, "cloudsync")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	return filepath.Join(dir, ConfigName+".yaml"), nil
+}
+
+// DatabasePath returns the path of the database the CLI keeps and the agent reads
+func DatabasePath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, "cloudsync.yaml"), nil
+	return filepath.Join(dir, ConfigName+".db"), nil
+}
+
+// ValidateRoots checks the extra roots of a folder, normalizing their prefixes to
+// slash-separated paths without leading or trailing slashes
+func (folder *SyncFolder) ValidateRoots() error {
+	if len(folder.Roots) > 0 && folder.Mode == FolderModeBackup {
+		return fmt.Errorf("backup folders cannot have extra roots")
+	}
+
+	prefixes := make(map[string]bool, len(folder.Roots))
+	for i := range folder.Roots {
+		root := &folder.Roots[i]
+		if root.Path == "" {
+			return fmt.Errorf("root path cannot be empty")
+		}
+		if filepath.Clean(root.Path) == filepath.Clean(folder.Path) {
+			return fmt.Errorf("root %s is the folder path", root.Path)
+		}
+
+		prefix := strings.Trim(filepath.ToSlash(root.Prefix), "/")
+		if prefix != "" {
+			prefix = path.Clean(prefix)
+		}
+		if prefix == "" || prefix == "." || prefix == ".." || strings.HasPrefix(prefix, "../") {
+			return fmt.Errorf("root %s needs a prefix inside the folder", root.Path)
+		}
+		for other := range prefixes {
+			if prefix == other || strings.HasPrefix(prefix, other+"/") || strings.HasPrefix(other, prefix+"/") {
+				return fmt.Errorf("root prefixes %q and %q overlap", other, prefix)
+			}
+		}
+		prefixes[prefix] = true
+		root.Prefix = prefix
+	}
+	return nil
+}
+
+// ValidateFile checks the file of a single-file folder, which must be a plain name inside
+// the folder path. Such a folder holds nothing else, so it cannot have roots or be a backup.
+func (folder *SyncFolder) ValidateFile() error {
+	if folder.File == "" {
+		return nil
+	}
+	if folder.File != filepath.Base(folder.File) || strings.ContainsAny(folder.File, `/\`) || folder.File == "." || folder.File == ".." {
+		return fmt.Errorf("file %q must be a name inside the folder path", folder.File)
+	}
+	if len(folder.Roots) > 0 {
+		return fmt.Errorf("single-file folders cannot have extra roots")
+	}
+	if folder.Mode == FolderModeBackup {
+		return fmt.Errorf("single-file folders cannot be backup folders")
+	}
+	return nil
+}
+
+// ValidateSubscribe checks the settings of a subscribed folder, which only ever downloads
+func (folder *SyncFolder) ValidateSubscribe() error {
+	switch folder.LocalChanges {
+	case "", LocalChangesRevert, LocalChangesFlag:
+	default:
+		return fmt.Errorf("invalid local changes policy %q: use %s or %s", folder.LocalChanges, LocalChangesRevert, LocalChangesFlag)
+	}
+	if !folder.Subscribe {
+		return nil
+	}
+	if folder.Mode == FolderModeBackup {
+		return fmt.Errorf("subscribed folders cannot be backup folders")
+	}
+	if folder.Mirror.DeleteOrphans {
+		return fmt.Errorf("subscribed folders cannot delete remote files")
+	}
+	return nil
+}
+
+// ValidateArchive checks the archive policy of a folder, which backup folders cannot use as
+// their snapshots read every file from disk
+func (folder *SyncFolder) ValidateArchive() error {
+	if folder.ArchiveAfterDays < 0 {
+		return fmt.Errorf("archive_after_days cannot be negative")
+	}
+	if folder.ArchiveAfterDays > 0 && folder.Mode == FolderModeBackup {
+		return fmt.Errorf("backup folders cannot archive files")
+	}
+	return nil
+}
+
+// MaxPackSmallFiles is the largest pack_small_files, keeping each pack to many files
+const MaxPackSmallFiles = 4 << 20
+
+// ValidatePacking checks the small-file packing of a folder, which only backup folders use:
+// mirror folders keep every file as its own object so other devices can read it
+func (folder *SyncFolder) ValidatePacking() error {
+	if folder.PackSmallFiles < 0 {
+		return fmt.Errorf("pack_small_files cannot be negative")
+	}
+	if folder.PackSmallFiles > MaxPackSmallFiles {
+		return fmt.Errorf("pack_small_files cannot exceed %d bytes", MaxPackSmallFiles)
+	}
+	if folder.PackSmallFiles > 0 && folder.Mode != FolderModeBackup {
+		return fmt.Errorf("only backup folders pack small files")
+	}
+	return nil
+}
+
+// ValidateRemotePrefix checks the remote prefix of a folder, normalizing it without leading or
+// trailing slashes. It must be a single segment, the first one of every key of the folder, and
+// cannot start with a dot as storage keeps those prefixes for snapshots and trash.
+func (folder *SyncFolder) ValidateRemotePrefix() error {
+	if folder.RemotePrefix == "" {
+		return nil
+	}
+	prefix := strings.Trim(filepath.ToSlash(folder.RemotePrefix), "/")
+	if prefix == "" || strings.Contains(prefix, "/") || strings.HasPrefix(prefix, ".") {
+		return fmt.Errorf("remote prefix %q must be a single name not starting with a dot", folder.RemotePrefix)
+	}
+	if folder.Mode == FolderModeBackup {
+		return fmt.Errorf("backup folders keep their snapshots under the folder ID")
+	}
+	if folder.Subscribe && prefix != folder.ID {
+		return fmt.Errorf("subscribed folders take the remote prefix as their ID")
+	}
+	folder.RemotePrefix = prefix
+	return nil
+}
+
+// ValidateSchedule checks the cron expression of a folder, trimming surrounding spaces
+func (folder *SyncFolder) ValidateSchedule() error {
+	folder.Schedule = strings.TrimSpace(folder.Schedule)
+	if folder.Schedule == "" {
+		return nil
+	}
+	_, err := cron.Parse(folder.Schedule)
+	return err
+}
+
+// ValidateAgeFilter checks the age filters of a folder, which backup folders cannot use as
+// each snapshot holds every file
+func (folder *SyncFolder) ValidateAgeFilter() error {
+	if folder.MinAge < 0 || folder.MaxAge < 0 {
+		return fmt.Errorf("min_age and max_age cannot be negative")
+	}
+	if folder.MaxAge > 0 && folder.MinAge >= folder.MaxAge {
+		return fmt.Errorf("min_age must be below max_age")
+	}
+	if _, err := ParseModifiedSince(folder.ModifiedSince); err != nil {
+		return err
+	}
+	folder.ModifiedSince = strings.TrimSpace(folder.ModifiedSince)
+	if folder.AgeFilter != (AgeFilter{}) && folder.Mode == FolderModeBackup {
+		return fmt.Errorf("backup folders cannot filter files by age")
+	}
+	return nil
+}
+
+// ValidateHooks checks the sync hooks of a folder
+func (folder *SyncFolder) ValidateHooks() error {
+	hooks := []struct {
+		name string
+		hook Hook
+	}{{"pre_sync", folder.Hooks.PreSync}, {"post_sync", folder.Hooks.PostSync}}
+	for _, h := range hooks {
+		if h.hook.Timeout < 0 {
+			return fmt.Errorf("%s timeout cannot be negative", h.name)
+		}
+		if h.hook.OnFailure != "" && !slices.Contains(HookFailurePolicies, h.hook.OnFailure) {
+			return fmt.Errorf("invalid %s failure policy %q: use %s", h.name, h.hook.OnFailure, strings.Join(HookFailurePolicies, ", "))
+		}
+	}
+	return nil
+}
+
+// ValidateChecksum checks the hash algorithm of a folder, normalizing its name. Backup folders
+// keep SHA-256, which addresses the objects their snapshots share.
+func (folder *SyncFolder) ValidateChecksum() error {
+	if folder.Checksum == "" {
+		return nil
+	}
+	alg, err := checksum.Parse(folder.Checksum)
+	if err != nil {
+		return err
+	}
+	if alg != checksum.SHA256 && folder.Mode == FolderModeBackup {
+		return fmt.Errorf("backup folders hash their files with %s", checksum.SHA256)
+	}
+	folder.Checksum = string(alg)
+	return nil
+}
+
+// ValidateKeyPrefixes checks that no two folders keep their files under the same storage
+// prefix, where each would take the other's files for its own. A folder ID counts as taken
+// even when the folder uses another prefix, since its snapshots and routing go by it.
+func ValidateKeyPrefixes(folders []SyncFolder) error {
+	owners := make(map[string]string, len(folders))
+	for _, folder := range folders {
+		owners[folder.ID] = folder.ID
+	}
+	for _, folder := range folders {
+		prefix := folder.KeyPrefix()
+		if owner, ok := owners[prefix]; ok && owner != folder.ID {
+			return fmt.Errorf("folders %s and %s both use the remote prefix %s", owner, folder.ID, prefix)
+		}
+		owners[prefix] = folder.ID
+	}
+	return nil
+}
+
+// validatePolicy checks a power policy, treating an empty action as PolicyNone
+func validatePolicy(policy *ConditionPolicy) error {
+	switch policy.Action {
+	case "":
+		policy.Action = PolicyNone
+	case PolicyNone, PolicyPause:
+	case PolicyThrottle:
+		if policy.ThrottleBytes <= 0 {
+			return fmt.Errorf("throttle_bytes must be positive")
+		}
+	case PolicySmallFiles:
+		if policy.MaxFileSize <= 0 {
+			return fmt.Errorf("max_file_size must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown action %q (supported: none, pause, throttle, small-files)", policy.Action)
+	}
+	return nil
 }
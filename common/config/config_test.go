@@ -0,0 +1,262 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRoots(t *testing.T) {
+	folder := SyncFolder{ID: "docs", Path: "/home/ana/Documents", Roots: []FolderRoot{
+		{Path: "/home/ana/Desktop", Prefix: "/Desktop/"},
+		{Path: "/home/ana/Pictures", Prefix: "media/pictures"},
+	}}
+	assert.NoError(t, folder.ValidateRoots())
+	assert.Equal(t, "Desktop", folder.Roots[0].Prefix)
+
+	for name, roots := range map[string][]FolderRoot{
+		"missing prefix":  {{Path: "/home/ana/Desktop"}},
+		"escaping prefix": {{Path: "/home/ana/Desktop", Prefix: "../Desktop"}},
+		"folder path":     {{Path: "/home/ana/Documents/", Prefix: "Documents"}},
+		"same prefix":     {{Path: "/a", Prefix: "x"}, {Path: "/b", Prefix: "x/"}},
+		"nested prefixes": {{Path: "/a", Prefix: "media"}, {Path: "/b", Prefix: "media/pictures"}},
+		"empty root path": {{Prefix: "x"}},
+	} {
+		folder := SyncFolder{ID: "docs", Path: "/home/ana/Documents", Roots: roots}
+		assert.Error(t, folder.ValidateRoots(), name)
+	}
+
+	backup := SyncFolder{ID: "docs", Path: "/home/ana/Documents", Mode: FolderModeBackup,
+		Roots: []FolderRoot{{Path: "/home/ana/Desktop", Prefix: "Desktop"}}}
+	assert.Error(t, backup.ValidateRoots())
+}
+
+func TestValidateSparsePolicy(t *testing.T) {
+	for _, policy := range append([]string{""}, SparsePolicies...) {
+		assert.NoError(t, ValidateSparsePolicy(policy), policy)
+	}
+	assert.Error(t, ValidateSparsePolicy("compress"))
+}
+
+func TestValidateFile(t *testing.T) {
+	folder := SyncFolder{ID: "keys", Path: "/home/ana/Secrets", File: "vault.kdbx"}
+	assert.NoError(t, folder.ValidateFile())
+	assert.NoError(t, (&SyncFolder{ID: "docs", Path: "/home/ana/Documents"}).ValidateFile())
+
+	for _, file := range []string{"sub/vault.kdbx", "..", "."} {
+		folder := SyncFolder{ID: "keys", Path: "/home/ana/Secrets", File: file}
+		assert.Error(t, folder.ValidateFile(), file)
+	}
+
+	withRoots := folder
+	withRoots.Roots = []FolderRoot{{Path: "/home/ana/Desktop", Prefix: "Desktop"}}
+	assert.Error(t, withRoots.ValidateFile())
+
+	backup := folder
+	backup.Mode = FolderModeBackup
+	assert.Error(t, backup.ValidateFile())
+}
+
+func TestValidateSubscribe(t *testing.T) {
+	folder := SyncFolder{ID: "assets", Path: "/srv/assets", Subscribe: true}
+	assert.NoError(t, folder.ValidateSubscribe())
+	for _, policy := range []string{LocalChangesRevert, LocalChangesFlag} {
+		folder.LocalChanges = policy
+		assert.NoError(t, folder.ValidateSubscribe(), policy)
+	}
+
+	folder.LocalChanges = "keep"
+	assert.Error(t, folder.ValidateSubscribe())
+
+	backup := SyncFolder{ID: "assets", Path: "/srv/assets", Subscribe: true, Mode: FolderModeBackup}
+	assert.Error(t, backup.ValidateSubscribe())
+	orphans := SyncFolder{ID: "assets", Path: "/srv/assets", Subscribe: true, Mirror: MirrorConfig{DeleteOrphans: true}}
+	assert.Error(t, orphans.ValidateSubscribe())
+}
+
+func TestValidateArchive(t *testing.T) {
+	folder := SyncFolder{ID: "docs", Path: "/srv/docs", ArchiveAfterDays: 30}
+	assert.NoError(t, folder.ValidateArchive())
+
+	folder.ArchiveAfterDays = -1
+	assert.Error(t, folder.ValidateArchive())
+
+	backup := SyncFolder{ID: "docs", Path: "/srv/docs", Mode: FolderModeBackup, ArchiveAfterDays: 30}
+	assert.Error(t, backup.ValidateArchive())
+	backup.ArchiveAfterDays = 0
+	assert.NoError(t, backup.ValidateArchive())
+}
+
+func TestValidatePacking(t *testing.T) {
+	backup := SyncFolder{ID: "docs", Path: "/srv/docs", Mode: FolderModeBackup, PackSmallFiles: 64 << 10}
+	assert.NoError(t, backup.ValidatePacking())
+
+	backup.PackSmallFiles = -1
+	assert.Error(t, backup.ValidatePacking())
+	backup.PackSmallFiles = MaxPackSmallFiles + 1
+	assert.Error(t, backup.ValidatePacking())
+
+	mirror := SyncFolder{ID: "docs", Path: "/srv/docs", PackSmallFiles: 64 << 10}
+	assert.ErrorContains(t, mirror.ValidatePacking(), "only backup folders")
+	mirror.PackSmallFiles = 0
+	assert.NoError(t, mirror.ValidatePacking())
+}
+
+func TestValidateRemotePrefix(t *testing.T) {
+	folder := SyncFolder{ID: "docs", Path: "/srv/docs", RemotePrefix: "/team-docs/"}
+	assert.NoError(t, folder.ValidateRemotePrefix())
+	assert.Equal(t, "team-docs", folder.RemotePrefix)
+	assert.Equal(t, "team-docs/2024/report.pdf", folder.Key("2024/report.pdf"))
+	assert.Equal(t, "docs/report.pdf", SyncFolder{ID: "docs"}.Key("report.pdf"))
+
+	for _, prefix := range []string{"team/docs", ".snapshots", "/"} {
+		invalid := SyncFolder{ID: "docs", Path: "/srv/docs", RemotePrefix: prefix}
+		assert.Error(t, invalid.ValidateRemotePrefix(), prefix)
+	}
+	backup := SyncFolder{ID: "docs", Path: "/srv/docs", Mode: FolderModeBackup, RemotePrefix: "team-docs"}
+	assert.Error(t, backup.ValidateRemotePrefix())
+
+	// Two folders cannot share a prefix, nor take the ID of another folder as theirs
+	assert.NoError(t, ValidateKeyPrefixes([]SyncFolder{{ID: "docs"}, {ID: "photos", RemotePrefix: "pictures"}}))
+	assert.Error(t, ValidateKeyPrefixes([]SyncFolder{{ID: "docs", RemotePrefix: "shared"}, {ID: "photos", RemotePrefix: "shared"}}))
+	assert.Error(t, ValidateKeyPrefixes([]SyncFolder{{ID: "docs"}, {ID: "photos", RemotePrefix: "docs"}}))
+}
+
+func TestValidateSchedule(t *testing.T) {
+	folder := SyncFolder{ID: "docs", Path: "/srv/docs", Schedule: " 0 2 * * * "}
+	assert.NoError(t, folder.ValidateSchedule())
+	assert.Equal(t, "0 2 * * *", folder.Schedule)
+	assert.NoError(t, (&SyncFolder{ID: "docs"}).ValidateSchedule())
+
+	cfg := DefaultConfig()
+	cfg.Targets = []StorageTarget{{Name: DefaultTargetName, Type: TargetMemory}}
+	cfg.SyncFolders = []SyncFolder{{ID: "docs", Path: "/srv/docs", Schedule: "every night"}}
+	assert.ErrorContains(t, validateConfig(cfg), "invalid schedule for folder docs")
+}
+
+func TestValidateAgeFilter(t *testing.T) {
+	folder := SyncFolder{ID: "docs", Path: "/srv/docs", AgeFilter: AgeFilter{MinAge: 30 * time.Second, MaxAge: 24 * time.Hour, ModifiedSince: " 2024-06-01 "}}
+	assert.NoError(t, folder.ValidateAgeFilter())
+	assert.Equal(t, "2024-06-01", folder.ModifiedSince)
+	since, err := ParseModifiedSince(folder.ModifiedSince)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local), since)
+	since, err = ParseModifiedSince("2024-06-01T12:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), since)
+
+	for _, filter := range []AgeFilter{
+		{MinAge: -time.Second},
+		{MinAge: time.Hour, MaxAge: time.Minute},
+		{ModifiedSince: "last june"},
+	} {
+		folder := SyncFolder{ID: "docs", AgeFilter: filter}
+		assert.Error(t, folder.ValidateAgeFilter(), filter)
+	}
+	backup := SyncFolder{ID: "docs", Mode: FolderModeBackup, AgeFilter: AgeFilter{MinAge: time.Minute}}
+	assert.ErrorContains(t, backup.ValidateAgeFilter(), "backup folders")
+
+	// The filters sit directly under the folder in the file
+	viper.Reset()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	cfg := DefaultConfig()
+	cfg.Targets = []StorageTarget{{Name: DefaultTargetName, Type: TargetMemory}}
+	cfg.SyncFolders = []SyncFolder{folder}
+	assert.NoError(t, SaveConfig(cfg, path))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "min_age: 30s")
+	loaded, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, folder.AgeFilter, loaded.SyncFolders[0].AgeFilter)
+}
+
+func TestValidateHooks(t *testing.T) {
+	folder := SyncFolder{ID: "db", Hooks: FolderHooks{
+		PreSync:  Hook{Command: "pg_dump app > app.sql", Timeout: time.Hour, OnFailure: HookContinue},
+		PostSync: Hook{Command: "notify-send synced", OnFailure: HookAbort},
+	}}
+	assert.NoError(t, folder.ValidateHooks())
+
+	folder.Hooks.PreSync.Timeout = -time.Second
+	assert.ErrorContains(t, folder.ValidateHooks(), "pre_sync timeout")
+	folder.Hooks.PreSync.Timeout = 0
+	folder.Hooks.PostSync.OnFailure = "retry"
+	assert.ErrorContains(t, folder.ValidateHooks(), "invalid post_sync failure policy")
+}
+
+func TestValidateChecksum(t *testing.T) {
+	folder := SyncFolder{ID: "videos", Checksum: "BLAKE3"}
+	assert.NoError(t, folder.ValidateChecksum())
+	assert.Equal(t, "blake3", folder.Checksum)
+
+	folder.Checksum = "xxh128"
+	assert.ErrorContains(t, folder.ValidateChecksum(), "invalid checksum algorithm")
+
+	folder = SyncFolder{ID: "backup", Mode: FolderModeBackup, Checksum: "blake3"}
+	assert.ErrorContains(t, folder.ValidateChecksum(), "backup folders")
+	folder.Checksum = "sha256"
+	assert.NoError(t, folder.ValidateChecksum())
+}
+
+func TestStoragePlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by their .exe suffix")
+	}
+	plugins := PluginsConfig{Dir: t.TempDir()}
+	for name, mode := range map[string]os.FileMode{PluginPrefix + "dropbox": 0755, PluginPrefix + "notes.txt": 0644, "other-tool": 0755} {
+		assert.NoError(t, os.WriteFile(filepath.Join(plugins.Dir, name), []byte("#!/bin/sh\n"), mode))
+	}
+
+	// Only executables named after the prefix are plugins
+	names, err := plugins.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dropbox"}, names)
+	path, err := plugins.Find("dropbox")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(plugins.Dir, PluginPrefix+"dropbox"), path)
+	_, err = plugins.Find("notes.txt")
+	assert.ErrorContains(t, err, "is not executable")
+	_, err = plugins.Find("../other-tool")
+	assert.ErrorContains(t, err, "invalid plugin name")
+
+	// Targets of a plugin type are valid once the plugin is installed
+	cfg := DefaultConfig()
+	cfg.Plugins = plugins
+	cfg.Targets = []StorageTarget{{Name: "default", Type: "dropbox", Plugin: map[string]string{"token": "secret"}}}
+	assert.NoError(t, validateConfig(cfg))
+	cfg.Targets[0].Type = "box"
+	assert.ErrorContains(t, validateConfig(cfg), "no storage plugin box in "+plugins.Dir)
+}
+
+func TestValidateConflictPolicy(t *testing.T) {
+	for _, policy := range append([]string{""}, ConflictPolicies...) {
+		assert.NoError(t, ValidateConflictPolicy(policy), policy)
+	}
+	assert.Error(t, ValidateConflictPolicy("prefer-oldest"))
+
+	cfg := DefaultConfig()
+	cfg.Targets = []StorageTarget{{Name: DefaultTargetName, Type: TargetMemory}}
+	cfg.SyncFolders = []SyncFolder{{ID: "docs", Path: "/home/ana/Documents", ConflictPolicy: "newest"}}
+	assert.Error(t, validateConfig(cfg))
+}
+
+func TestValidateFailoverEndpoints(t *testing.T) {
+	target := StorageTarget{Name: "backup", Type: TargetMinio, Minio: defaultMinioConfig()}
+	target.Minio.Failover = []string{"minio-2:9000", "minio-3:9000"}
+	assert.NoError(t, target.Validate())
+
+	target.Minio.Failover = []string{"minio-2:9000", ""}
+	assert.ErrorContains(t, target.Validate(), "cannot be empty")
+	target.Minio.Failover = []string{"minio-2:9000", target.Minio.Endpoint}
+	assert.ErrorContains(t, target.Validate(), "duplicate failover endpoint")
+
+	s3 := StorageTarget{Name: "aws", Type: TargetS3, S3: S3Config{Bucket: "b", Failover: []string{"s3.eu-west-1.amazonaws.com"}}}
+	assert.ErrorContains(t, s3.Validate(), "need a primary endpoint")
+}
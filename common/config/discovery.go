@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigName is the canonical name of the configuration directory and file
+const ConfigName = "sync-manager"
+
+// legacyConfigName is the name used by earlier versions, still read and migrated
+const legacyConfigName = "cloudsync"
+
+// baseConfigVersion is the schema version of files written before versions were recorded
+const baseConfigVersion = 1
+
+// configVersionKey is the top-level key holding the schema version of a file
+const configVersionKey = "config_version"
+
+// SearchPaths returns the configuration files LoadConfig looks for, in order: the
+// current directory, the user config directory and /etc, each under the canonical
+// name first and the legacy one second
+func SearchPaths() []string {
+	var dirs [][2]string
+	dirs = append(dirs, [2]string{".", "."})
+	if userConfigDir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, [2]string{filepath.Join(userConfigDir, ConfigName), filepath.Join(userConfigDir, legacyConfigName)})
+	}
+	dirs = append(dirs, [2]string{filepath.Join("/etc", ConfigName), filepath.Join("/etc", legacyConfigName)})
+
+	var paths []string
+	for _, dir := range dirs {
+		paths = append(paths,
+			filepath.Join(dir[0], ConfigName+".yaml"),
+			filepath.Join(dir[1], legacyConfigName+".yaml"))
+	}
+	return paths
+}
+
+// Discover migrates a legacy configuration of the user and returns the first file in
+// SearchPaths that exists, or an empty path when there is none
+func Discover() (string, error) {
+	if _, err := MigrateLegacy(); err != nil {
+		return "", err
+	}
+	for _, path := range SearchPaths() {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// MigrateLegacy moves the configuration, profiles and active profile of the user from the
+// legacy cloudsync directory to the canonical one, stamping the base schema version. The legacy
+// file is kept next to its old location with a .migrated suffix. It reports whether the
+// main configuration file was migrated.
+func MigrateLegacy() (bool, error) {
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return false, nil
+	}
+	legacyDir := filepath.Join(userConfigDir, legacyConfigName)
+	if _, err := os.Stat(legacyDir); os.IsNotExist(err) {
+		return false, nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return false, err
+	}
+
+	migrated, err := migrateLegacyFile(filepath.Join(legacyDir, legacyConfigName+".yaml"), filepath.Join(dir, ConfigName+".yaml"))
+	if err != nil {
+		return false, err
+	}
+
+	// Profiles and the active profile move as they are, unless the new location has them
+	for _, name := range []string{"profiles", activeProfileFile} {
+		from, to := filepath.Join(legacyDir, name), filepath.Join(dir, name)
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+		if _, err := os.Stat(to); err == nil {
+			continue
+		}
+		if err := os.Rename(from, to); err != nil {
+			return migrated, fmt.Errorf("failed to migrate %s: %w", from, err)
+		}
+	}
+	return migrated, nil
+}
+
+// migrateLegacyFile copies a legacy configuration file to the canonical path. A canonical
+// file without a schema version was never loaded by earlier versions, which only read the
+// legacy name, so it is set aside with a .bak suffix; a versioned one wins over the legacy file.
+func migrateLegacyFile(legacy, canonical string) (bool, error) {
+	data, err := os.ReadFile(legacy)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read legacy config: %w", err)
+	}
+	stamped, err := stampVersion(data)
+	if err != nil {
+		return false, fmt.Errorf("legacy config %s is not valid: %w", legacy, err)
+	}
+
+	if existing, err := os.ReadFile(canonical); err == nil {
+		version, err := fileVersion(existing)
+		if err == nil && version > 0 {
+			return false, nil
+		}
+		if err := os.Rename(canonical, canonical+".bak"); err != nil {
+			return false, fmt.Errorf("failed to set aside %s: %w", canonical, err)
+		}
+	}
+
+	tmp := canonical + ".tmp"
+	if err := os.WriteFile(tmp, stamped, 0600); err != nil {
+		return false, fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp, canonical); err != nil {
+		os.Remove(tmp)
+		return false, fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(legacy, legacy+".migrated"); err != nil {
+		return true, fmt.Errorf("failed to retire legacy config: %w", err)
+	}
+
+	log.Info().Str("from", legacy).Str("to", canonical).Msg("Migrated legacy configuration")
+	return true, nil
+}
+
+// fileVersion returns the schema version of a configuration file, zero when it has none
+func fileVersion(data []byte) (int, error) {
+	var settings struct {
+		Version int `yaml:"config_version"`
+	}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return 0, err
+	}
+	return settings.Version, nil
+}
+
+// stampVersion adds the base schema version to a file that has none, keeping the
+// rest of the file, comments included, as it is
+func stampVersion(data []byte) ([]byte, error) {
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+	if _, ok := settings[configVersionKey]; ok {
+		return data, nil
+	}
+	header := fmt.Sprintf("%s: %d\n", configVersionKey, baseConfigVersion)
+	return append([]byte(header), data...), nil
+}
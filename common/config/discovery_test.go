@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// useConfigHome points the user config directory at a temporary one
+func useConfigHome(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(ProfileEnv, "")
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+	return home
+}
+
+func TestLoadConfigMigratesLegacyConfig(t *testing.T) {
+	viper.Reset()
+	home := useConfigHome(t)
+	legacyDir := filepath.Join(home, "cloudsync")
+	assert.NoError(t, os.MkdirAll(filepath.Join(legacyDir, "profiles"), 0755))
+	legacy := filepath.Join(legacyDir, "cloudsync.yaml")
+	assert.NoError(t, os.WriteFile(legacy, []byte("# my settings\ndevice_id: laptop\nstorage_provider: memory\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(legacyDir, "profiles", "work.yaml"), nil, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(legacyDir, activeProfileFile), []byte("work\n"), 0644))
+
+	// A file saved under the new name was never read by earlier versions
+	canonical := filepath.Join(home, "sync-manager", "sync-manager.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(canonical), 0755))
+	assert.NoError(t, os.WriteFile(canonical, []byte("device_id: stray\n"), 0644))
+
+	cfg, err := LoadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, "laptop", cfg.DeviceID)
+	assert.Equal(t, CurrentConfigVersion, cfg.ConfigVersion)
+	assert.Equal(t, canonical, ConfigFileUsed())
+
+	// The migrated file is stamped with the base version, then upgraded
+	data, err := os.ReadFile(canonical + ".v1.bak")
+	assert.NoError(t, err)
+	assert.Equal(t, "config_version: 1\n# my settings\ndevice_id: laptop\nstorage_provider: memory\n", string(data))
+	_, err = os.Stat(legacy + ".migrated")
+	assert.NoError(t, err)
+	_, err = os.Stat(canonical + ".bak")
+	assert.NoError(t, err)
+
+	names, err := ListProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfile, "work"}, names)
+	active, err := ActiveProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, "work", active)
+
+	// A second load has nothing left to migrate
+	migrated, err := MigrateLegacy()
+	assert.NoError(t, err)
+	assert.False(t, migrated)
+}
+
+func TestMigrateLegacyKeepsVersionedConfig(t *testing.T) {
+	home := useConfigHome(t)
+	legacy := filepath.Join(home, "cloudsync", "cloudsync.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(legacy), 0755))
+	assert.NoError(t, os.WriteFile(legacy, []byte("device_id: old\n"), 0644))
+	canonical := filepath.Join(home, "sync-manager", "sync-manager.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(canonical), 0755))
+	assert.NoError(t, os.WriteFile(canonical, []byte("config_version: 1\ndevice_id: current\n"), 0644))
+
+	migrated, err := MigrateLegacy()
+	assert.NoError(t, err)
+	assert.False(t, migrated)
+	data, err := os.ReadFile(canonical)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "current")
+}
+
+func TestMigrateLegacyRejectsInvalidConfig(t *testing.T) {
+	home := useConfigHome(t)
+	legacy := filepath.Join(home, "cloudsync", "cloudsync.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(legacy), 0755))
+	assert.NoError(t, os.WriteFile(legacy, []byte("device_id: [unclosed\n"), 0644))
+
+	_, err := MigrateLegacy()
+	assert.Error(t, err)
+	_, err = os.Stat(legacy)
+	assert.NoError(t, err)
+}
+
+func TestLoadConfigFindsEitherName(t *testing.T) {
+	viper.Reset()
+	useConfigHome(t)
+
+	// Nothing to load: defaults only
+	cfg, err := LoadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultConfig().Targets, cfg.Targets)
+
+	assert.NoError(t, os.WriteFile("cloudsync.yaml", []byte("device_name: legacy\n"), 0644))
+	cfg, err = LoadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy", cfg.DeviceName)
+
+	assert.NoError(t, os.WriteFile("sync-manager.yaml", []byte("device_name: canonical\n"), 0644))
+	cfg, err = LoadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, "canonical", cfg.DeviceName)
+}
+
+func TestLoadConfigRejectsNewerVersion(t *testing.T) {
+	viper.Reset()
+	path := filepath.Join(t.TempDir(), "sync-manager.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("config_version: 99\n"), 0644))
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "newer than the supported version")
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// filePrefix marks a value read from a file, such as "file:/run/secrets/s3"
+const filePrefix = "file:"
+
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// reference is a setting whose value was expanded from the environment or a file.
+// SaveConfig writes Raw back while the setting still holds Value, so secrets stay out of the file.
+type reference struct {
+	Raw   string
+	Value string
+}
+
+// interpolate expands ${VAR} and file: references in settings in place, returning the
+// expanded settings by key. The settings of storage targets are keyed by their position,
+// as in targets.0.s3.secret_key; values inside other lists are taken literally.
+func interpolate(settings map[string]interface{}) (map[string]reference, error) {
+	references := make(map[string]reference)
+	var errs []error
+	var walk func(prefix string, settings map[string]interface{})
+	walk = func(prefix string, settings map[string]interface{}) {
+		for name, value := range settings {
+			key := prefix + name
+			switch v := value.(type) {
+			case map[string]interface{}:
+				walk(key+".", v)
+			case []interface{}:
+				if key != "targets" {
+					continue
+				}
+				for i, item := range v {
+					if target, ok := item.(map[string]interface{}); ok {
+						walk(fmt.Sprintf("%s.%d.", key, i), target)
+					}
+				}
+			case string:
+				expanded, err := expand(v)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("config key %s: %w", key, err))
+					continue
+				}
+				if expanded != v {
+					settings[name] = expanded
+					references[key] = reference{Raw: v, Value: expanded}
+				}
+			}
+		}
+	}
+	walk("", settings)
+
+	if len(errs) > 0 {
+		// Report every missing reference at once, in a stable order
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return nil, errors.Join(errs...)
+	}
+	return references, nil
+}
+
+// expand resolves a single value: a file: reference replaces the whole value with the
+// file contents, otherwise each ${VAR} is replaced by its environment variable
+func expand(value string) (string, error) {
+	if path, ok := strings.CutPrefix(value, filePrefix); ok {
+		if path == "" {
+			return "", errors.New("file reference has no path")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s%s: %w", filePrefix, path, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(value, func(match string) string {
+		name := envReference.FindStringSubmatch(match)[1]
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return env
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// Referenced reports whether the setting at key, such as api_token or targets.0.s3.secret_key,
+// was expanded from a ${VAR} or file: reference rather than written in the file
+func (c *Config) Referenced(key string) bool {
+	_, ok := c.references[key]
+	return ok
+}
+
+// restoreReferences puts the original references back into viper for the settings that
+// were not changed since they were loaded. Those of storage targets are restored by targetSettings.
+func restoreReferences(references map[string]reference) {
+	for key, ref := range references {
+		if strings.HasPrefix(key, "targets.") {
+			continue
+		}
+		if viper.GetString(key) == ref.Value {
+			viper.Set(key, ref.Raw)
+		}
+	}
+}
+
+// targetSettings returns the storage targets as written to a file, with the references
+// their unchanged settings were expanded from put back
+func targetSettings(targets []StorageTarget, references map[string]reference) ([]interface{}, error) {
+	data, err := yaml.Marshal(targets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode storage targets: %w", err)
+	}
+	settings := []interface{}{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to encode storage targets: %w", err)
+	}
+
+	for key, ref := range references {
+		path := strings.Split(key, ".")
+		if len(path) < 3 || path[0] != "targets" {
+			continue
+		}
+		i, err := strconv.Atoi(path[1])
+		if err != nil || i >= len(settings) {
+			continue
+		}
+		values, ok := settings[i].(map[string]interface{})
+		for _, name := range path[2 : len(path)-1] {
+			if !ok {
+				break
+			}
+			values, ok = values[name].(map[string]interface{})
+		}
+		if name := path[len(path)-1]; ok && values[name] == ref.Value {
+			values[name] = ref.Raw
+		}
+	}
+	return settings, nil
+}
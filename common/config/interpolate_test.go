@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigExpandsReferences(t *testing.T) {
+	viper.Reset()
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "s3-secret")
+	assert.NoError(t, os.WriteFile(secretPath, []byte("s3cr3t\n"), 0600))
+	t.Setenv("TEST_ACCESS_KEY", "AKIAEXAMPLE")
+	t.Setenv("TEST_BUCKET_ENV", "prod")
+
+	path := filepath.Join(dir, "cloudsync.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`storage_provider: s3
+s3:
+  bucket: backups-${TEST_BUCKET_ENV}
+  access_key: ${TEST_ACCESS_KEY}
+  secret_key: file:`+secretPath+`
+sync_folders:
+  - id: docs
+    path: /data/${TEST_BUCKET_ENV}
+`), 0600))
+
+	cfg, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "backups-prod", cfg.Targets[0].S3.Bucket)
+	assert.Equal(t, "AKIAEXAMPLE", cfg.Targets[0].S3.AccessKey)
+	assert.Equal(t, "s3cr3t", cfg.Targets[0].S3.SecretKey)
+	// Values inside lists other than the targets are taken literally
+	assert.Equal(t, "/data/${TEST_BUCKET_ENV}", cfg.SyncFolders[0].Path)
+	assert.True(t, cfg.Referenced("targets.0.s3.secret_key"))
+	assert.False(t, cfg.Referenced("targets.0.s3.region"))
+
+	// Saving keeps the references, except for values changed since loading
+	cfg.Targets[0].S3.Bucket = "archive"
+	assert.NoError(t, SaveConfig(cfg, path))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "${TEST_ACCESS_KEY}")
+	assert.Contains(t, string(data), "file:"+secretPath)
+	assert.NotContains(t, string(data), "s3cr3t")
+	assert.Contains(t, string(data), "archive")
+
+	// A changed variable is picked up on the next load
+	t.Setenv("TEST_ACCESS_KEY", "AKIAROTATED")
+	cfg, err = LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "AKIAROTATED", cfg.Targets[0].S3.AccessKey)
+}
+
+func TestLoadConfigReportsMissingReferences(t *testing.T) {
+	viper.Reset()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cloudsync.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`targets:
+  - name: offsite
+    type: s3
+    s3:
+      access_key: ${TEST_MISSING_KEY}
+      secret_key: file:`+filepath.Join(dir, "missing")+`
+`), 0600))
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "config key targets.0.s3.access_key: environment variable TEST_MISSING_KEY is not set")
+	assert.Contains(t, err.Error(), "config key targets.0.s3.secret_key: failed to read file:")
+}
+
+func TestExpand(t *testing.T) {
+	t.Setenv("TEST_HOST", "minio.local")
+	t.Setenv("TEST_EMPTY", "")
+
+	value, err := expand("https://${TEST_HOST}:9000")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://minio.local:9000", value)
+
+	value, err = expand("${TEST_EMPTY}")
+	assert.NoError(t, err)
+	assert.Empty(t, value)
+
+	value, err = expand("price $5 and $HOME")
+	assert.NoError(t, err)
+	assert.Equal(t, "price $5 and $HOME", value)
+
+	_, err = expand("file:")
+	assert.Error(t, err)
+}
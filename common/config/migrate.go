@@ -0,0 +1,324 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// migration upgrades a file from version From to From+1. Apply edits the top-level mapping of
+// the file in place, so comments and the order of the keys it does not touch are kept.
+type migration struct {
+	From        int
+	Description string
+	Apply       func(doc *yaml.Node)
+}
+
+// migrations is the upgrade pipeline, one step per schema version. A file at version N
+// goes through every step from N on, so a new schema version only appends a step.
+var migrations = []migration{
+	{From: 0, Description: "record the schema version", Apply: func(*yaml.Node) {}},
+	{From: 1, Description: "rename keys written without separators", Apply: renameLegacyKeys},
+	{From: 2, Description: "move the storage provider settings into named targets", Apply: moveStorageToTargets},
+}
+
+// CurrentConfigVersion is the schema version written to configuration files
+var CurrentConfigVersion = len(migrations)
+
+// settingsVersion returns the schema version recorded in settings, zero when there is none
+func settingsVersion(settings map[string]interface{}) (int, error) {
+	switch v := settings[configVersionKey].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("invalid %s %v", configVersionKey, v)
+	}
+}
+
+// migrateDocument runs the steps of the pipeline from version on the top-level mapping of
+// a file and records the current version
+func migrateDocument(doc *yaml.Node, version int) {
+	for _, m := range migrations {
+		if m.From >= version {
+			m.Apply(doc)
+		}
+	}
+	setVersion(doc, CurrentConfigVersion)
+}
+
+// migrateSettings runs the steps of the pipeline from version on settings read from a file
+// and records the current version
+func migrateSettings(settings map[string]interface{}, version int) error {
+	var doc yaml.Node
+	if err := doc.Encode(settings); err != nil {
+		return err
+	}
+	migrateDocument(&doc, version)
+
+	migrated := make(map[string]interface{})
+	if err := doc.Decode(&migrated); err != nil {
+		return err
+	}
+	for key := range settings {
+		delete(settings, key)
+	}
+	for key, value := range migrated {
+		settings[key] = value
+	}
+	return nil
+}
+
+// upgrade brings settings read from path to the current schema version. The file is
+// upgraded too, after a backup of the previous one is written next to it; when it cannot
+// be written the upgraded settings are still used.
+func upgrade(path string, settings map[string]interface{}) error {
+	version, err := settingsVersion(settings)
+	if err != nil {
+		return err
+	}
+	if version > CurrentConfigVersion {
+		return fmt.Errorf("config file version %d is newer than the supported version %d", version, CurrentConfigVersion)
+	}
+	if version == CurrentConfigVersion {
+		return nil
+	}
+
+	if err := migrateSettings(settings, version); err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+	if err := upgradeFile(path); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to upgrade config file, using the upgraded settings")
+		return nil
+	}
+
+	// Read the file again so later saves do not write the old keys back
+	return viper.ReadInConfig()
+}
+
+// upgradeFile rewrites a configuration file at the current schema version, keeping the
+// previous contents in <path>.v<version>.bak
+func upgradeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file yaml.Node
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	if file.Kind == 0 {
+		file = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	doc := file.Content[0]
+	if file.Kind != yaml.DocumentNode || doc.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file is not a mapping")
+	}
+
+	var settings map[string]interface{}
+	if err := doc.Decode(&settings); err != nil {
+		return err
+	}
+	version, err := settingsVersion(settings)
+	if err != nil {
+		return err
+	}
+	migrateDocument(doc, version)
+
+	upgraded, err := yaml.Marshal(&file)
+	if err != nil {
+		return err
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := os.WriteFile(backup, data, 0600); err != nil {
+		return fmt.Errorf("failed to back up config: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, upgraded, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	log.Info().Str("path", path).Str("backup", backup).Int("from", version).Int("to", CurrentConfigVersion).Msg("Upgraded config file")
+	return nil
+}
+
+// legacyKeys maps keys written by versions that saved the settings without separators
+// to their current names, by section ("" for the top level)
+var legacyKeys = map[string]map[string]string{
+	"": {
+		"deviceid": "device_id", "devicename": "device_name", "loglevel": "log_level", "logpath": "log_path",
+		"syncinterval": "sync_interval", "maxconcurrency": "max_concurrency", "throttlebytes": "throttle_bytes",
+		"storageprovider": "storage_provider", "syncfolders": "sync_folders",
+		"apiendpoint": "api_endpoint", "apitoken": "api_token",
+	},
+	"s3":    {"accesskey": "access_key", "secretkey": "secret_key", "usessl": "use_ssl", "pathstyle": "path_style"},
+	"minio": {"accesskey": "access_key", "secretkey": "secret_key", "usessl": "use_ssl"},
+	"gcs":   {"projectid": "project_id", "credentialsfile": "credentials_file"},
+	"local": {"rootdir": "root_dir"},
+}
+
+// renameLegacyKeys moves legacy keys to their current names. Those keys were never read,
+// so when the current key is also present it wins and the legacy one is dropped.
+func renameLegacyKeys(doc *yaml.Node) {
+	for section, keys := range legacyKeys {
+		values := doc
+		if section != "" {
+			nested := mappingValue(doc, section)
+			if nested == nil || nested.Kind != yaml.MappingNode {
+				continue
+			}
+			values = nested
+		}
+		for old, current := range keys {
+			i := mappingIndex(values, old)
+			if i < 0 {
+				continue
+			}
+			if mappingIndex(values, current) >= 0 {
+				values.Content = append(values.Content[:i], values.Content[i+2:]...)
+				continue
+			}
+			values.Content[i].Value = current
+		}
+	}
+}
+
+// legacyStorageKeys are the top-level keys that held the single storage provider and the
+// settings of each provider before named targets
+var legacyStorageKeys = []string{"storage_provider", TargetS3, TargetMinio, TargetGCS, TargetLocal, TargetMemory}
+
+// legacyStorageDefaults returns the settings a provider had before named targets when the
+// file left them out, nil for providers without defaults
+func legacyStorageDefaults(provider string) interface{} {
+	switch provider {
+	case TargetS3:
+		return S3Config{Region: "us-east-1", UseSSL: true}
+	case TargetMinio:
+		return defaultMinioConfig()
+	}
+	return nil
+}
+
+// moveStorageToTargets turns the storage provider of a file and the settings of that provider
+// into a target named DefaultTargetName, which the folders sync to as before. The settings of
+// the other providers were never used and are dropped. A file without storage settings is
+// left to the default targets.
+func moveStorageToTargets(doc *yaml.Node) {
+	provider, legacy := TargetMinio, false
+	for _, key := range legacyStorageKeys {
+		if i := mappingIndex(doc, key); i >= 0 {
+			legacy = true
+			if key == "storage_provider" {
+				provider = doc.Content[i+1].Value
+			}
+		}
+	}
+	if !legacy {
+		return
+	}
+
+	var targets *yaml.Node
+	if mappingIndex(doc, "targets") < 0 {
+		settings := mappingValue(doc, provider)
+		if settings == nil || settings.Kind != yaml.MappingNode {
+			settings = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		if defaults := legacyStorageDefaults(provider); defaults != nil {
+			var values yaml.Node
+			if err := values.Encode(defaults); err == nil {
+				for i := 0; i+1 < len(values.Content); i += 2 {
+					if mappingIndex(settings, values.Content[i].Value) < 0 {
+						settings.Content = append(settings.Content, values.Content[i], values.Content[i+1])
+					}
+				}
+			}
+		}
+
+		target := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, pair := range [][2]*yaml.Node{
+			{scalar("name"), scalar(DefaultTargetName)},
+			{scalar("type"), scalar(provider)},
+			{scalar(provider), settings},
+		} {
+			target.Content = append(target.Content, pair[0], pair[1])
+		}
+		targets = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{target}}
+	}
+
+	// The targets take the place and the comment of the first legacy key
+	var content []*yaml.Node
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if !isLegacyStorageKey(doc.Content[i].Value) {
+			content = append(content, doc.Content[i], doc.Content[i+1])
+		} else if targets != nil {
+			key := scalar("targets")
+			key.HeadComment = doc.Content[i].HeadComment
+			content = append(content, key, targets)
+			targets = nil
+		}
+	}
+	doc.Content = content
+}
+
+// isLegacyStorageKey reports whether a top-level key held storage settings before named targets
+func isLegacyStorageKey(key string) bool {
+	for _, legacy := range legacyStorageKeys {
+		if key == legacy {
+			return true
+		}
+	}
+	return false
+}
+
+// scalar returns a string node
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// mappingIndex returns the position of the node holding key in a mapping node, -1 when absent
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingValue returns the value of key in a mapping node, nil when absent
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndex(mapping, key); i >= 0 {
+		return mapping.Content[i+1]
+	}
+	return nil
+}
+
+// setVersion records the schema version in the top-level mapping of a file, adding it
+// first when the file has none, as stampVersion does
+func setVersion(doc *yaml.Node, version int) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	if i := mappingIndex(doc, configVersionKey); i >= 0 {
+		doc.Content[i+1] = value
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: configVersionKey}
+	doc.Content = append([]*yaml.Node{key, value}, doc.Content...)
+}
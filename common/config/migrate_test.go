@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigUpgradesOldSchema(t *testing.T) {
+	viper.Reset()
+	path := filepath.Join(t.TempDir(), "sync-manager.yaml")
+	old := `deviceid: test-device-id
+device_id: real-device
+storage_provider: minio
+minio:
+    endpoint: localhost:9000
+    bucket: sync-manager
+    accesskey: minioadmin
+    secretkey: minioadmin
+    usessl: true
+s3:
+    access_key: ""
+    accesskey: ignored
+`
+	assert.NoError(t, os.WriteFile(path, []byte(old), 0600))
+
+	cfg, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentConfigVersion, cfg.ConfigVersion)
+	assert.Equal(t, "real-device", cfg.DeviceID)
+	// The provider in use becomes the default target and the sections of the others are dropped
+	if assert.Len(t, cfg.Targets, 1) {
+		assert.Equal(t, DefaultTargetName, cfg.Targets[0].Name)
+		assert.Equal(t, TargetMinio, cfg.Targets[0].Type)
+		assert.Equal(t, "minioadmin", cfg.Targets[0].Minio.AccessKey)
+		assert.Equal(t, "minioadmin", cfg.Targets[0].Minio.SecretKey)
+		assert.True(t, cfg.Targets[0].Minio.UseSSL)
+		// Settings the file left out keep the defaults they had
+		assert.Equal(t, "us-east-1", cfg.Targets[0].Minio.Region)
+	}
+
+	// The previous file is kept as a backup
+	backup, err := os.ReadFile(path + ".v0.bak")
+	assert.NoError(t, err)
+	assert.Equal(t, old, string(backup))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "config_version: 3")
+	assert.NotContains(t, string(data), "storage_provider")
+	assert.NotContains(t, string(data), "accesskey")
+	assert.NotContains(t, string(data), "deviceid")
+
+	// Saving does not bring the old keys back and an up to date file is left alone
+	assert.NoError(t, SaveConfig(cfg, path))
+	data, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "accesskey")
+	assert.NoError(t, os.Remove(path+".v0.bak"))
+	_, err = LoadConfig(path)
+	assert.NoError(t, err)
+	matches, _ := filepath.Glob(path + ".v*.bak")
+	assert.Empty(t, matches)
+}
+
+func TestUpgradeFileKeepsCommentsAndOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-manager.yaml")
+	old := `# Settings of the laptop
+device_id: laptop
+loglevel: debug # while testing the new folder
+# Backups go to the NAS
+storage_provider: local
+local:
+    # Where the backups go
+    rootdir: /backup
+    root_dir: /current
+`
+	assert.NoError(t, os.WriteFile(path, []byte(old), 0600))
+	assert.NoError(t, upgradeFile(path))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `config_version: 3
+# Settings of the laptop
+device_id: laptop
+log_level: debug # while testing the new folder
+# Backups go to the NAS
+targets:
+    - name: default
+      type: local
+      local:
+        root_dir: /current
+`, string(data))
+}
+
+func TestMigrateSettings(t *testing.T) {
+	settings := map[string]interface{}{
+		"config_version":   1,
+		"loglevel":         "debug",
+		"storage_provider": "local",
+		"local":            map[string]interface{}{"rootdir": "/backup"},
+		"s3":               map[string]interface{}{"bucket": "unused"},
+	}
+	assert.NoError(t, migrateSettings(settings, 1))
+	assert.Equal(t, map[string]interface{}{
+		"config_version": CurrentConfigVersion,
+		"log_level":      "debug",
+		"targets": []interface{}{map[string]interface{}{
+			"name":  DefaultTargetName,
+			"type":  "local",
+			"local": map[string]interface{}{"root_dir": "/backup"},
+		}},
+	}, settings)
+
+	// Without storage settings the default targets apply
+	settings = map[string]interface{}{"config_version": 2, "log_level": "debug"}
+	assert.NoError(t, migrateSettings(settings, 2))
+	assert.Equal(t, map[string]interface{}{"config_version": CurrentConfigVersion, "log_level": "debug"}, settings)
+
+	_, err := settingsVersion(map[string]interface{}{"config_version": "two"})
+	assert.Error(t, err)
+}
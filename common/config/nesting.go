@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Policies for a folder whose directory is inside another folder's
+const (
+	// NestingRefuse does not sync the inner folder; the outer one keeps syncing its files
+	NestingRefuse = "refuse"
+	// NestingExclude syncs both folders, the outer one excluding the subtree of the inner one
+	NestingExclude = "exclude"
+)
+
+// NestingPolicies lists the valid nested folder policies
+var NestingPolicies = []string{NestingRefuse, NestingExclude}
+
+// ValidateNestingPolicy checks a nested folder policy, an empty one meaning NestingRefuse
+func ValidateNestingPolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	for _, valid := range NestingPolicies {
+		if policy == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid nested folder policy %q: use %s", policy, strings.Join(NestingPolicies, ", "))
+}
+
+// FolderOverlap is a folder whose files are also under a directory of another folder, so
+// both would upload them
+type FolderOverlap struct {
+	Outer     string // ID of the folder containing the other
+	Inner     string // ID of the folder inside it
+	OuterPath string // Directory of the outer folder, its path or one of its roots
+	InnerPath string // Directory, or file of a single-file folder, of the inner folder
+	// RelPath is InnerPath relative to OuterPath, slash-separated; "." when both folders
+	// have the same directory
+	RelPath string
+}
+
+// Same reports whether both folders sync the same directory, which no policy allows
+func (o FolderOverlap) Same() bool {
+	return o.RelPath == "."
+}
+
+// FindOverlaps returns the enabled folders inside, or on the same directory as, another
+// enabled one. Of two folders on the same directory the later one is the inner one.
+// Single-file folders contain no other folder, so they are only ever inner folders.
+func FindOverlaps(folders []SyncFolder) []FolderOverlap {
+	var overlaps []FolderOverlap
+	for i, outer := range folders {
+		if !outer.Enabled || outer.File != "" {
+			continue
+		}
+		dirs := []string{outer.Path}
+		for _, root := range outer.Roots {
+			dirs = append(dirs, root.Path)
+		}
+
+		for j, inner := range folders {
+			if i == j || inner.ID == outer.ID || !inner.Enabled {
+				continue
+			}
+			innerPath := filepath.Clean(inner.Path)
+			if inner.File != "" {
+				innerPath = filepath.Join(innerPath, inner.File)
+			}
+			for _, dir := range dirs {
+				rel, err := filepath.Rel(filepath.Clean(dir), innerPath)
+				if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+					continue
+				}
+				// The earlier of two folders on the same directory keeps it
+				if rel == "." && j < i {
+					continue
+				}
+				overlaps = append(overlaps, FolderOverlap{
+					Outer:     outer.ID,
+					Inner:     inner.ID,
+					OuterPath: dir,
+					InnerPath: innerPath,
+					RelPath:   filepath.ToSlash(rel),
+				})
+				break
+			}
+		}
+	}
+	return overlaps
+}
+
+// ResolveNesting applies a nested folder policy to the folders of a configuration.
+// It returns the folders to sync, without the inner folders the policy refuses and with the
+// outer folders excluding the subtrees of the inner folders it allows, and the overlaps
+// found. Folders on the same directory are refused whatever the policy.
+func ResolveNesting(folders []SyncFolder, policy string) ([]SyncFolder, []FolderOverlap) {
+	overlaps := FindOverlaps(folders)
+	if len(overlaps) == 0 {
+		return folders, nil
+	}
+
+	refused := make(map[string]bool)
+	excludes := make(map[string][]string)
+	for _, overlap := range overlaps {
+		if policy == NestingExclude && !overlap.Same() {
+			excludes[overlap.Outer] = append(excludes[overlap.Outer], SubtreePattern(overlap.RelPath))
+		} else {
+			refused[overlap.Inner] = true
+		}
+	}
+
+	resolved := make([]SyncFolder, 0, len(folders))
+	for _, folder := range folders {
+		if refused[folder.ID] {
+			continue
+		}
+		if extra := excludes[folder.ID]; len(extra) > 0 {
+			folder.Exclude = append(append([]string(nil), folder.Exclude...), extra...)
+		}
+		resolved = append(resolved, folder)
+	}
+	return resolved, overlaps
+}
+
+// MatchExclude reports whether an exclude pattern matches a path relative to its folder. A
+// pattern ending in /** matches the directory before it and everything under it; other
+// patterns match the whole path, as in filepath.Match.
+func MatchExclude(pattern, relPath string) bool {
+	if dir, ok := strings.CutSuffix(filepath.ToSlash(pattern), "/**"); ok {
+		for p := filepath.ToSlash(relPath); p != "" && p != "." && p != "/"; p = path.Dir(p) {
+			if matched, _ := path.Match(dir, p); matched || p == dir {
+				return true
+			}
+		}
+		return false
+	}
+	matched, err := filepath.Match(pattern, relPath)
+	return err == nil && matched
+}
+
+// SubtreePattern returns the exclude pattern of a slash-separated path relative to a folder
+// and everything under it
+func SubtreePattern(relPath string) string {
+	return path.Clean(relPath) + "/**"
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindOverlaps(t *testing.T) {
+	docs := filepath.Join("/home", "ana", "Documents")
+	folders := []SyncFolder{
+		{ID: "docs", Path: docs, Enabled: true},
+		{ID: "projects", Path: filepath.Join(docs, "Projects"), Enabled: true},
+		{ID: "again", Path: docs, Enabled: true},
+		{ID: "todo", Path: filepath.Join(docs, "Notes"), File: "todo.txt", Enabled: true},
+		{ID: "old", Path: filepath.Join(docs, "Old"), Enabled: false},
+		{ID: "music", Path: filepath.Join("/home", "ana", "Music"), Enabled: true},
+	}
+
+	overlaps := FindOverlaps(folders)
+	var found []string
+	for _, overlap := range overlaps {
+		found = append(found, overlap.Outer+">"+overlap.Inner+":"+overlap.RelPath)
+	}
+	// Of two folders on the same directory the later one is inside the earlier one; disabled
+	// and unrelated folders do not overlap
+	assert.Equal(t, []string{
+		"docs>projects:Projects", "docs>again:.", "docs>todo:Notes/todo.txt",
+		"again>projects:Projects", "again>todo:Notes/todo.txt",
+	}, found)
+	assert.True(t, overlaps[1].Same())
+}
+
+func TestResolveNesting(t *testing.T) {
+	docs := filepath.Join("/home", "ana", "Documents")
+	folders := []SyncFolder{
+		{ID: "docs", Path: docs, Enabled: true, Exclude: []string{"*.tmp"}},
+		{ID: "projects", Path: filepath.Join(docs, "Projects"), Enabled: true},
+		{ID: "again", Path: docs, Enabled: true},
+	}
+
+	resolved, overlaps := ResolveNesting(folders, NestingRefuse)
+	assert.Len(t, overlaps, 3)
+	assert.Len(t, resolved, 1)
+	assert.Equal(t, "docs", resolved[0].ID)
+	assert.Equal(t, []string{"*.tmp"}, resolved[0].Exclude)
+
+	// The outer folder excludes the nested one; the same directory is refused anyway
+	resolved, _ = ResolveNesting(folders, NestingExclude)
+	assert.Len(t, resolved, 2)
+	assert.Equal(t, []string{"*.tmp", "Projects/**"}, resolved[0].Exclude)
+	assert.Equal(t, "projects", resolved[1].ID)
+	assert.Equal(t, []string{"*.tmp"}, folders[0].Exclude)
+
+	assert.NoError(t, ValidateNestingPolicy(""))
+	assert.Error(t, ValidateNestingPolicy("merge"))
+}
+
+func TestMatchExclude(t *testing.T) {
+	assert.True(t, MatchExclude("*.tmp", "a.tmp"))
+	assert.False(t, MatchExclude("*.tmp", filepath.Join("dir", "a.tmp")))
+	assert.False(t, MatchExclude("[", "["))
+
+	// A subtree pattern matches the directory and everything under it
+	assert.True(t, MatchExclude("Projects/**", "Projects"))
+	assert.True(t, MatchExclude("Projects/**", filepath.Join("Projects", "app", "main.go")))
+	assert.True(t, MatchExclude("*/build/**", filepath.Join("app", "build", "out.bin")))
+	assert.False(t, MatchExclude("Projects/**", "ProjectsOld"))
+	assert.False(t, MatchExclude("Projects/**", filepath.Join("Other", "Projects")))
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the profile backed by the main configuration file
+const DefaultProfile = "default"
+
+// ProfileEnv is the environment variable selecting a profile when no flag is given
+const ProfileEnv = "SYNC_MANAGER_PROFILE"
+
+// activeProfileFile holds the name of the profile used when none is requested
+const activeProfileFile = "active-profile"
+
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$`)
+
+// ErrProfileNotFound is returned when a named profile has no configuration file
+var ErrProfileNotFound = errors.New("profile not found")
+
+// ValidateProfileName checks that a profile name is usable as a file name
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' or '_'", name)
+	}
+	return nil
+}
+
+// configDir returns the directory holding the configuration files, creating it if needed
+func configDir() (string, error) {
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(userConfigDir, ConfigName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// ProfilePath returns the configuration file of a profile
+func ProfilePath(name string) (string, error) {
+	if name == DefaultProfile {
+		return GetConfigPath()
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return "", err
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profiles", name+".yaml"), nil
+}
+
+// ListProfiles returns the default profile followed by the named profiles, sorted by name
+func ListProfiles() ([]string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "profiles"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".yaml")
+		if entry.IsDir() || !ok || ValidateProfileName(name) != nil || name == DefaultProfile {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...), nil
+}
+
+// ProfileExists reports whether a profile can be selected
+func ProfileExists(name string) (bool, error) {
+	if name == DefaultProfile {
+		return true, nil
+	}
+	path, err := ProfilePath(name)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateProfile creates the configuration file of a new profile and returns its path.
+// The device identity is left empty so the profile registers as its own device on first use.
+func CreateProfile(name string) (string, error) {
+	if name == DefaultProfile {
+		return "", fmt.Errorf("profile %q already exists", name)
+	}
+	path, err := ProfilePath(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("profile %q already exists", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create profiles directory: %w", err)
+	}
+	// Settings left out of the file take their defaults when the profile is loaded
+	header := fmt.Sprintf("# Sync Manager configuration for profile %q\n", name)
+	if err := os.WriteFile(path, []byte(header), 0644); err != nil {
+		return "", fmt.Errorf("failed to write profile: %w", err)
+	}
+	return path, nil
+}
+
+// ActiveProfile returns the profile selected with SetActiveProfile, or the default one
+func ActiveProfile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, activeProfileFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return DefaultProfile, nil
+		}
+		return "", fmt.Errorf("failed to read active profile: %w", err)
+	}
+	name := strings.TrimSpace(string(data))
+	if name == "" {
+		return DefaultProfile, nil
+	}
+	return name, nil
+}
+
+// SetActiveProfile makes name the profile used when none is requested
+func SetActiveProfile(name string) error {
+	exists, err := ProfileExists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, activeProfileFile), []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to save active profile: %w", err)
+	}
+	return nil
+}
+
+// ResolveProfile picks the profile to use: the requested name, then the ProfileEnv
+// variable, then the active profile. It returns the profile name and its configuration
+// file, which is empty for the default profile so LoadConfig searches the usual places.
+func ResolveProfile(requested string) (string, string, error) {
+	name := requested
+	if name == "" {
+		name = os.Getenv(ProfileEnv)
+	}
+	if name == "" {
+		active, err := ActiveProfile()
+		if err != nil {
+			return "", "", err
+		}
+		name = active
+	}
+	if name == DefaultProfile {
+		return name, "", nil
+	}
+
+	exists, err := ProfileExists(name)
+	if err != nil {
+		return "", "", err
+	}
+	if !exists {
+		return "", "", fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	path, err := ProfilePath(name)
+	if err != nil {
+		return "", "", err
+	}
+	return name, path, nil
+}
+
+// ProfileFromArgs returns the value of a --profile flag in args, which must be known
+// before the configuration is loaded and the commands are built
+func ProfileFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--profile="); ok {
+			return value
+		}
+		if arg == "--profile" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiles(t *testing.T) {
+	viper.Reset()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(ProfileEnv, "")
+
+	names, err := ListProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfile}, names)
+
+	// Without a selection the default profile searches the usual places
+	name, path, err := ResolveProfile("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultProfile, name)
+	assert.Empty(t, path)
+
+	workPath, err := CreateProfile("work")
+	assert.NoError(t, err)
+	_, err = CreateProfile("personal")
+	assert.NoError(t, err)
+	_, err = CreateProfile("work")
+	assert.Error(t, err)
+	_, err = CreateProfile("../escape")
+	assert.Error(t, err)
+
+	names, err = ListProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfile, "personal", "work"}, names)
+
+	// A new profile loads with the defaults and no device identity
+	cfg, err := LoadConfig(workPath)
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.DeviceID)
+	assert.Equal(t, DefaultConfig().Targets, cfg.Targets)
+
+	_, _, err = ResolveProfile("missing")
+	assert.True(t, errors.Is(err, ErrProfileNotFound))
+	assert.True(t, errors.Is(SetActiveProfile("missing"), ErrProfileNotFound))
+
+	// The flag wins over the environment, which wins over the active profile
+	assert.NoError(t, SetActiveProfile("work"))
+	name, path, err = ResolveProfile("")
+	assert.NoError(t, err)
+	assert.Equal(t, "work", name)
+	assert.Equal(t, workPath, path)
+
+	t.Setenv(ProfileEnv, "personal")
+	name, _, err = ResolveProfile("")
+	assert.NoError(t, err)
+	assert.Equal(t, "personal", name)
+
+	name, path, err = ResolveProfile(DefaultProfile)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultProfile, name)
+	assert.Empty(t, path)
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(filepath.Dir(workPath)), activeProfileFile))
+	assert.NoError(t, err)
+	assert.Equal(t, "work\n", string(data))
+}
+
+func TestProfileFromArgs(t *testing.T) {
+	assert.Equal(t, "work", ProfileFromArgs([]string{"--profile", "work", "status"}))
+	assert.Equal(t, "work", ProfileFromArgs([]string{"status", "--profile=work"}))
+	assert.Empty(t, ProfileFromArgs([]string{"status"}))
+	assert.Empty(t, ProfileFromArgs([]string{"--", "--profile", "work"}))
+}
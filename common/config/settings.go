@@ -0,0 +1,517 @@
+package config
+
+import (
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/database"
+	"github.com/martinshumberto/sync-manager/common/i18n"
+)
+
+// Get returns the value of a setting named by a dotted key, such as lan.listen, as shown to
+// the user. storage.* keys belong to the target named targetName, or the default one when empty.
+func (c *Config) Get(key, targetName string) (string, error) {
+	target, err := c.settingTarget(key, targetName, false)
+	if err != nil {
+		return "", err
+	}
+
+	switch key {
+	case "storage.provider":
+		return target.Type, nil
+	case "storage.s3.bucket":
+		return target.S3.Bucket, nil
+	case "storage.s3.failover_endpoints":
+		return strings.Join(target.S3.Failover, ","), nil
+	case "storage.minio.bucket":
+		return target.Minio.Bucket, nil
+	case "storage.minio.endpoint":
+		return target.Minio.Endpoint, nil
+	case "storage.minio.failover_endpoints":
+		return strings.Join(target.Minio.Failover, ","), nil
+	case "storage.gcs.bucket":
+		return target.GCS.Bucket, nil
+	case "storage.local.root_dir":
+		return target.Local.RootDir, nil
+	case "storage.local.removable":
+		return strconv.FormatBool(target.Local.Removable), nil
+	case "storage.local.volume_uuid":
+		return target.Local.VolumeUUID, nil
+	case "storage.onedrive.client_id":
+		return target.OneDrive.ClientID, nil
+	case "storage.onedrive.tenant":
+		return target.OneDrive.Tenant, nil
+	case "storage.onedrive.drive_id":
+		return target.OneDrive.DriveID, nil
+	case "storage.onedrive.app_folder":
+		return strconv.FormatBool(target.OneDrive.AppFolder), nil
+	case "throttle.bandwidth":
+		return i18n.Sprintf("%d bytes/sec", c.ThrottleBytes), nil
+	case "power.battery.action", "power.metered.action", "power.processes.action":
+		return c.powerPolicy(key).Action, nil
+	case "power.battery.throttle", "power.metered.throttle", "power.processes.throttle":
+		return i18n.Sprintf("%d bytes/sec", c.powerPolicy(key).ThrottleBytes), nil
+	case "power.battery.max_file_size", "power.metered.max_file_size", "power.processes.max_file_size":
+		return i18n.Sprintf("%d bytes", c.powerPolicy(key).MaxFileSize), nil
+	case "power.processes.names":
+		return strings.Join(c.Power.Processes, ","), nil
+	case "lan.enabled":
+		return strconv.FormatBool(c.LAN.Enabled), nil
+	case "lan.listen":
+		return c.LAN.Listen, nil
+	case "lan.timeout":
+		return c.LAN.Timeout.String(), nil
+	case "concurrency.mode":
+		return c.Concurrency.Mode, nil
+	case "concurrency.min":
+		return strconv.Itoa(c.Concurrency.Min), nil
+	case "concurrency.max":
+		return strconv.Itoa(c.Concurrency.Max), nil
+	case "download.concurrency":
+		return strconv.Itoa(c.Download.MaxConcurrency), nil
+	case "download.bandwidth":
+		return i18n.Sprintf("%d bytes/sec", c.Download.ThrottleBytes), nil
+	case "download.chunk_size":
+		return i18n.Sprintf("%d bytes", c.Download.ChunkSize), nil
+	case "files.sparse":
+		return c.Files.Sparse, nil
+	case "files.hydrate_placeholders":
+		return strconv.FormatBool(c.Files.HydratePlaceholders), nil
+	case "files.max_file_size":
+		return i18n.Sprintf("%d bytes", c.Files.MaxFileSize), nil
+	case "priority.level":
+		return c.Priority.Level, nil
+	case "priority.hash_bandwidth":
+		return i18n.Sprintf("%d bytes/sec", c.Priority.HashThrottleBytes), nil
+	case "bandwidth.monthly_cap":
+		return i18n.Sprintf("%d bytes", c.Bandwidth.MonthlyCapBytes), nil
+	case "cache.dir":
+		return c.Cache.Dir, nil
+	case "cache.max_bytes":
+		return i18n.Sprintf("%d bytes", c.Cache.MaxBytes), nil
+	case "resources.memory_limit":
+		return i18n.Sprintf("%d bytes", c.Resources.MemoryLimit), nil
+	case "resources.queue_size":
+		return strconv.Itoa(c.Resources.QueueSize), nil
+	case "scheduling.size_weight":
+		return strconv.FormatFloat(c.Scheduling.SizeWeight, 'g', -1, 64), nil
+	case "scheduling.recency_weight":
+		return strconv.FormatFloat(c.Scheduling.RecencyWeight, 'g', -1, 64), nil
+	case "scheduling.folder_weight":
+		return strconv.FormatFloat(c.Scheduling.FolderWeight, 'g', -1, 64), nil
+	case "clock.max_skew":
+		return c.Clock.MaxSkew.String(), nil
+	case "plugins.dir":
+		return c.Plugins.Dir, nil
+	case "nested_folders":
+		return c.NestedFolders, nil
+	case "database.dsn":
+		return database.Redact(c.Database.DSN), nil
+	case "database.encrypt":
+		return strconv.FormatBool(c.Database.Encrypt), nil
+	}
+
+	if setting, ok := strings.CutPrefix(key, "storage.plugin."); ok && setting != "" {
+		return target.Plugin[setting], nil
+	}
+	transport, setting := c.transportSetting(target, key)
+	switch {
+	case transport == nil:
+		return "", i18n.Errorf("unknown configuration key: %s", key)
+	case setting == "proxy_url":
+		return transport.ProxyURL, nil
+	case setting == "ca_cert_file":
+		return transport.CACertFile, nil
+	default:
+		return strconv.FormatBool(transport.InsecureSkipVerify), nil
+	}
+}
+
+// Set changes the setting named by a dotted key from its text form, rejecting values the
+// setting does not accept. storage.* keys change the target named targetName, or the default
+// one when empty; setting storage.provider on a target that does not exist yet adds it.
+// Only the setting is checked: the whole configuration is checked by Validate.
+func (c *Config) Set(key, value, targetName string) error {
+	target, err := c.settingTarget(key, targetName, true)
+	if err != nil {
+		return err
+	}
+
+	switch key {
+	case "storage.provider":
+		switch value {
+		case "s3", "minio", "gcs", "local", "onedrive":
+			target.Type = value
+		default:
+			// Other providers need an installed plugin
+			if _, err := c.Plugins.Find(value); err != nil {
+				return i18n.Errorf("unsupported storage provider: %s (supported: s3, minio, gcs, local, onedrive or an installed storage plugin)", value)
+			}
+			target.Type = value
+		}
+	case "storage.s3.bucket":
+		target.S3.Bucket = value
+	case "storage.s3.region":
+		target.S3.Region = value
+	case "storage.s3.endpoint":
+		target.S3.Endpoint = value
+	case "storage.s3.access_key":
+		target.S3.AccessKey = value
+	case "storage.s3.secret_key":
+		target.S3.SecretKey = value
+	case "storage.s3.failover_endpoints":
+		endpoints := splitEndpoints(value)
+		if err := ValidateFailover(target.S3.Endpoint, endpoints); err != nil {
+			return err
+		}
+		target.S3.Failover = endpoints
+	case "storage.minio.bucket":
+		target.Minio.Bucket = value
+	case "storage.minio.endpoint":
+		target.Minio.Endpoint = value
+	case "storage.minio.region":
+		target.Minio.Region = value
+	case "storage.minio.access_key":
+		target.Minio.AccessKey = value
+	case "storage.minio.secret_key":
+		target.Minio.SecretKey = value
+	case "storage.minio.failover_endpoints":
+		endpoints := splitEndpoints(value)
+		if err := ValidateFailover(target.Minio.Endpoint, endpoints); err != nil {
+			return err
+		}
+		target.Minio.Failover = endpoints
+	case "storage.gcs.bucket":
+		target.GCS.Bucket = value
+	case "storage.gcs.project_id":
+		target.GCS.ProjectID = value
+	case "storage.gcs.credentials_file":
+		target.GCS.CredentialsFile = value
+	case "storage.local.root_dir":
+		target.Local.RootDir = value
+	case "storage.local.removable":
+		removable, err := strconv.ParseBool(value)
+		if err != nil {
+			return i18n.Errorf("invalid boolean value: %s", value)
+		}
+		target.Local.Removable = removable
+	case "storage.local.volume_uuid":
+		target.Local.VolumeUUID = strings.TrimSpace(value)
+	case "storage.onedrive.client_id":
+		target.OneDrive.ClientID = value
+	case "storage.onedrive.tenant":
+		target.OneDrive.Tenant = value
+	case "storage.onedrive.drive_id":
+		target.OneDrive.DriveID = value
+	case "storage.onedrive.app_folder":
+		appFolder, err := strconv.ParseBool(value)
+		if err != nil {
+			return i18n.Errorf("invalid boolean value: %s", value)
+		}
+		target.OneDrive.AppFolder = appFolder
+	case "throttle.bandwidth":
+		bandwidth, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return i18n.Errorf("invalid bandwidth value: %s (must be a number)", value)
+		}
+		c.ThrottleBytes = bandwidth
+	case "power.processes.names":
+		var names []string
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		c.Power.Processes = names
+	case "power.battery.action", "power.metered.action", "power.processes.action":
+		switch value {
+		case PolicyNone, PolicyPause, PolicyThrottle, PolicySmallFiles:
+			policy := c.powerPolicy(key)
+			prefix := strings.TrimSuffix(key, "action")
+			// The action needs its limit, or the saved configuration would be invalid
+			if value == PolicyThrottle && policy.ThrottleBytes <= 0 {
+				return i18n.Errorf("set %sthrottle before using the throttle action", prefix)
+			}
+			if value == PolicySmallFiles && policy.MaxFileSize <= 0 {
+				return i18n.Errorf("set %smax_file_size before using the small-files action", prefix)
+			}
+			policy.Action = value
+		default:
+			return i18n.Errorf("unsupported power action: %s (supported: none, pause, throttle, small-files)", value)
+		}
+	case "power.battery.throttle", "power.metered.throttle", "power.processes.throttle":
+		bandwidth, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || bandwidth <= 0 {
+			return i18n.Errorf("invalid bandwidth value: %s (must be a positive number of bytes/sec)", value)
+		}
+		c.powerPolicy(key).ThrottleBytes = bandwidth
+	case "power.battery.max_file_size", "power.metered.max_file_size", "power.processes.max_file_size":
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			return i18n.Errorf("invalid file size: %s (must be a positive number of bytes)", value)
+		}
+		c.powerPolicy(key).MaxFileSize = size
+	case "lan.enabled":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return i18n.Errorf("invalid boolean value: %s", value)
+		}
+		c.LAN.Enabled = enabled
+	case "lan.listen":
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			return i18n.Errorf("invalid listen address: %s (use host:port or :port)", value)
+		}
+		c.LAN.Listen = value
+	case "lan.timeout":
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return i18n.Errorf("invalid timeout: %s (use a duration like 5s)", value)
+		}
+		c.LAN.Timeout = timeout
+	case "concurrency.mode":
+		if err := ValidateConcurrencyMode(value); err != nil {
+			return err
+		}
+		c.Concurrency.Mode = value
+	case "concurrency.min", "concurrency.max":
+		bound, err := strconv.Atoi(value)
+		if err != nil || bound < 1 || bound > 32 {
+			return i18n.Errorf("invalid concurrency: %s (must be between 1 and 32)", value)
+		}
+		if key == "concurrency.min" {
+			c.Concurrency.Min = bound
+		} else {
+			c.Concurrency.Max = bound
+		}
+	case "download.concurrency":
+		concurrency, err := strconv.Atoi(value)
+		if err != nil || concurrency < 1 || concurrency > 32 {
+			return i18n.Errorf("invalid concurrency: %s (must be between 1 and 32)", value)
+		}
+		c.Download.MaxConcurrency = concurrency
+	case "download.bandwidth":
+		bandwidth, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || bandwidth < 0 {
+			return i18n.Errorf("invalid bandwidth value: %s (must be a number, 0 for no limit)", value)
+		}
+		c.Download.ThrottleBytes = bandwidth
+	case "download.chunk_size":
+		chunkSize, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || chunkSize < 1<<20 {
+			return i18n.Errorf("invalid chunk size: %s (must be at least 1048576 bytes)", value)
+		}
+		c.Download.ChunkSize = chunkSize
+	case "files.sparse":
+		if err := ValidateSparsePolicy(value); err != nil {
+			return err
+		}
+		c.Files.Sparse = value
+	case "files.hydrate_placeholders":
+		hydrate, err := strconv.ParseBool(value)
+		if err != nil {
+			return i18n.Errorf("invalid boolean value: %s", value)
+		}
+		c.Files.HydratePlaceholders = hydrate
+	case "files.max_file_size":
+		maxSize, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return i18n.Errorf("invalid max file size: %s (bytes, 0 for the storage limit, negative for none)", value)
+		}
+		c.Files.MaxFileSize = maxSize
+	case "priority.level":
+		if err := ValidatePriority(value); err != nil {
+			return err
+		}
+		c.Priority.Level = value
+	case "priority.hash_bandwidth":
+		bandwidth, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || bandwidth < 0 {
+			return i18n.Errorf("invalid hashing bandwidth value: %s (must be a number, 0 for no limit)", value)
+		}
+		c.Priority.HashThrottleBytes = bandwidth
+	case "bandwidth.monthly_cap":
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 0 {
+			return i18n.Errorf("invalid monthly cap: %s (bytes, 0 for no cap)", value)
+		}
+		c.Bandwidth.MonthlyCapBytes = limit
+	case "cache.dir":
+		c.Cache.Dir = value
+	case "cache.max_bytes":
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			return i18n.Errorf("invalid staging area size: %s (must be a positive number of bytes)", value)
+		}
+		c.Cache.MaxBytes = size
+	case "resources.memory_limit":
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 0 {
+			return i18n.Errorf("invalid memory limit: %s (must be a number of bytes, 0 for none)", value)
+		}
+		c.Resources.MemoryLimit = limit
+	case "resources.queue_size":
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return i18n.Errorf("invalid upload queue size: %s (must be a positive number)", value)
+		}
+		c.Resources.QueueSize = size
+	case "scheduling.size_weight", "scheduling.recency_weight", "scheduling.folder_weight":
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return i18n.Errorf("invalid scheduling weight: %s (must be a number, 0 to ignore it)", value)
+		}
+		switch key {
+		case "scheduling.size_weight":
+			c.Scheduling.SizeWeight = weight
+		case "scheduling.recency_weight":
+			c.Scheduling.RecencyWeight = weight
+		default:
+			c.Scheduling.FolderWeight = weight
+		}
+	case "clock.max_skew":
+		skew, err := time.ParseDuration(value)
+		if err != nil || skew <= 0 {
+			return i18n.Errorf("invalid clock skew: %s (use a duration like 1m)", value)
+		}
+		c.Clock.MaxSkew = skew
+	case "plugins.dir":
+		c.Plugins.Dir = value
+	case "nested_folders":
+		if err := ValidateNestingPolicy(value); err != nil {
+			return err
+		}
+		c.NestedFolders = value
+	case "database.dsn":
+		if value != "" {
+			if _, _, err := database.Dialector(value); err != nil {
+				return err
+			}
+		}
+		c.Database.DSN = value
+	case "database.encrypt":
+		encrypt, err := strconv.ParseBool(value)
+		if err != nil {
+			return i18n.Errorf("invalid boolean value: %s", value)
+		}
+		c.Database.Encrypt = encrypt
+	default:
+		// Plugin settings are passed on as they are; an empty value removes one
+		if setting, ok := strings.CutPrefix(key, "storage.plugin."); ok && setting != "" {
+			if !target.IsPlugin() {
+				return i18n.Errorf("storage target %s is not served by a plugin", target.Name)
+			}
+			if value == "" {
+				delete(target.Plugin, setting)
+				break
+			}
+			if target.Plugin == nil {
+				target.Plugin = make(map[string]string)
+			}
+			target.Plugin[setting] = value
+			break
+		}
+		transport, setting := c.transportSetting(target, key)
+		if transport == nil {
+			return i18n.Errorf("unknown configuration key: %s", key)
+		}
+		updated := *transport
+		switch setting {
+		case "proxy_url":
+			updated.ProxyURL = value
+		case "ca_cert_file":
+			updated.CACertFile = value
+		case "insecure_skip_verify":
+			skip, err := strconv.ParseBool(value)
+			if err != nil {
+				return i18n.Errorf("invalid boolean value: %s", value)
+			}
+			updated.InsecureSkipVerify = skip
+		}
+		// Checked here, or the agent could not load the saved configuration
+		if err := updated.Validate(); err != nil {
+			return err
+		}
+		*transport = updated
+	}
+
+	return nil
+}
+
+// Validate checks the whole configuration, as LoadConfig does before returning it
+func (c *Config) Validate() error {
+	return validateConfig(c)
+}
+
+// settingTarget returns the target a storage.* key belongs to: the one named, or the default
+// one. When create is set, setting storage.provider on a target that does not exist adds it.
+func (c *Config) settingTarget(key, name string, create bool) (*StorageTarget, error) {
+	if name == "" {
+		return c.DefaultTarget(), nil
+	}
+	if target := c.Target(name); target != nil {
+		return target, nil
+	}
+	if key == "storage.provider" && create {
+		c.Targets = append(c.Targets, StorageTarget{Name: name})
+		return &c.Targets[len(c.Targets)-1], nil
+	}
+	return nil, i18n.Errorf("storage target %s not found (configured: %s)", name, strings.Join(c.TargetNames(), ", "))
+}
+
+// powerPolicy returns the policy changed by a power.battery.*, power.metered.* or
+// power.processes.* key
+func (c *Config) powerPolicy(key string) *ConditionPolicy {
+	switch {
+	case strings.HasPrefix(key, "power.battery."):
+		return &c.Power.OnBattery
+	case strings.HasPrefix(key, "power.processes."):
+		return &c.Power.OnProcesses
+	default:
+		return &c.Power.OnMetered
+	}
+}
+
+// transportSetting splits an http.* or storage.<provider>.* proxy/TLS key into the
+// settings it changes and the setting name, returning nil for other keys. Storage
+// keys change the settings of target.
+func (c *Config) transportSetting(target *StorageTarget, key string) (*TransportConfig, string) {
+	i := strings.LastIndex(key, ".")
+	if i < 0 {
+		return nil, ""
+	}
+
+	setting := key[i+1:]
+	switch setting {
+	case "proxy_url", "ca_cert_file", "insecure_skip_verify":
+	default:
+		return nil, ""
+	}
+
+	switch key[:i] {
+	case "http":
+		return &c.HTTP, setting
+	case "storage.s3":
+		return &target.S3.TransportConfig, setting
+	case "storage.minio":
+		return &target.Minio.TransportConfig, setting
+	case "storage.gcs":
+		return &target.GCS.TransportConfig, setting
+	case "storage.onedrive":
+		return &target.OneDrive.TransportConfig, setting
+	}
+	return nil, ""
+}
+
+// splitEndpoints parses a comma separated list of endpoints, where an empty value clears it
+func splitEndpoints(value string) []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(value, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAndSet(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Targets = []StorageTarget{{Name: DefaultTargetName, Type: TargetLocal, Local: LocalConfig{RootDir: "/srv/sync"}}}
+
+	assert.NoError(t, cfg.Set("lan.timeout", "20s", ""))
+	assert.Equal(t, 20*time.Second, cfg.LAN.Timeout)
+	value, err := cfg.Get("lan.timeout", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "20s", value)
+
+	assert.NoError(t, cfg.Set("download.bandwidth", "1024", ""))
+	value, err = cfg.Get("download.bandwidth", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "1024 bytes/sec", value)
+
+	// Adaptive concurrency is checked against its bounds as a whole
+	assert.NoError(t, cfg.Set("concurrency.mode", ConcurrencyAdaptive, ""))
+	assert.Error(t, cfg.Set("concurrency.mode", "auto", ""))
+	assert.NoError(t, cfg.Set("concurrency.min", "8", ""))
+	assert.NoError(t, cfg.Set("concurrency.max", "4", ""))
+	assert.Error(t, cfg.Validate())
+	assert.NoError(t, cfg.Set("concurrency.max", "12", ""))
+	assert.NoError(t, cfg.Validate())
+	value, err = cfg.Get("concurrency.max", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "12", value)
+	assert.Error(t, cfg.Set("concurrency.max", "64", ""))
+
+	// Values a setting does not accept leave it alone
+	assert.Error(t, cfg.Set("download.concurrency", "64", ""))
+	assert.Error(t, cfg.Set("lan.port", "7777", ""))
+	_, err = cfg.Get("lan.port", "")
+	assert.Error(t, err)
+
+	// A new target is added by its provider, and is invalid until it is complete
+	_, err = cfg.Get("storage.provider", "backup")
+	assert.Error(t, err)
+	assert.Error(t, cfg.Set("storage.s3.bucket", "photos", "backup"))
+	assert.NoError(t, cfg.Set("storage.provider", "s3", "backup"))
+	assert.Error(t, cfg.Validate())
+	assert.NoError(t, cfg.Set("storage.s3.bucket", "photos", "backup"))
+	assert.NoError(t, cfg.Validate())
+	value, err = cfg.Get("storage.s3.bucket", "backup")
+	assert.NoError(t, err)
+	assert.Equal(t, "photos", value)
+	value, err = cfg.Get("storage.provider", "")
+	assert.NoError(t, err)
+	assert.Equal(t, TargetLocal, value)
+}
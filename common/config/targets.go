@@ -0,0 +1,319 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DefaultTargetName names the target that files written before named targets are moved to
+const DefaultTargetName = "default"
+
+// Storage target types
+const (
+	TargetS3       = "s3"
+	TargetMinio    = "minio" // Local development
+	TargetGCS      = "gcs"
+	TargetLocal    = "local"
+	TargetMemory   = "memory" // In-process storage for integration tests
+	TargetOneDrive = "onedrive"
+)
+
+// TargetTypes lists the supported storage target types
+var TargetTypes = []string{TargetS3, TargetMinio, TargetGCS, TargetLocal, TargetMemory, TargetOneDrive}
+
+// StorageTarget is a named storage backend folders sync to. Its settings are in the block
+// of its type, such as s3 for an S3 bucket; blocks of other types are ignored. A type that
+// is not built in names a storage plugin, which gets the settings of the plugin block.
+type StorageTarget struct {
+	Name     string            `mapstructure:"name" yaml:"name"`
+	Type     string            `mapstructure:"type" yaml:"type"`
+	S3       S3Config          `mapstructure:"s3" yaml:"s3,omitempty"`
+	Minio    MinioConfig       `mapstructure:"minio" yaml:"minio,omitempty"`
+	GCS      GCSConfig         `mapstructure:"gcs" yaml:"gcs,omitempty"`
+	Local    LocalConfig       `mapstructure:"local" yaml:"local,omitempty"`
+	Memory   MemoryConfig      `mapstructure:"memory" yaml:"memory,omitempty"`
+	OneDrive OneDriveConfig    `mapstructure:"onedrive" yaml:"onedrive,omitempty"`
+	Plugin   map[string]string `mapstructure:"plugin" yaml:"plugin,omitempty"`
+}
+
+// PluginPrefix starts the file name of storage plugin executables: the plugin serving the
+// dropbox target type is sync-manager-storage-dropbox, with .exe on Windows
+const PluginPrefix = "sync-manager-storage-"
+
+// PluginsConfig sets where storage plugins are discovered
+type PluginsConfig struct {
+	Dir string `mapstructure:"dir" yaml:"dir,omitempty"` // sync-manager/plugins in the user config directory when empty
+}
+
+// Directory returns the directory plugins are discovered in
+func (p PluginsConfig) Directory() (string, error) {
+	if p.Dir != "" {
+		return p.Dir, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "plugins"), nil
+}
+
+// Find returns the executable of the plugin serving a target type
+func (p PluginsConfig) Find(name string) (string, error) {
+	dir, err := p.Directory()
+	if err != nil {
+		return "", err
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid plugin name: %q", name)
+	}
+
+	path := filepath.Join(dir, pluginFile(name))
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("no storage plugin %s in %s", name, dir)
+	}
+	if info.IsDir() || (runtime.GOOS != "windows" && info.Mode()&0111 == 0) {
+		return "", fmt.Errorf("storage plugin %s is not executable", path)
+	}
+	return path, nil
+}
+
+// List returns the names of the plugins in the directory, sorted. A missing directory has none.
+func (p PluginsConfig) List() ([]string, error) {
+	dir, err := p.Directory()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+		if ok && runtime.GOOS == "windows" {
+			name, ok = strings.CutSuffix(name, ".exe")
+		}
+		if !ok || name == "" || entry.IsDir() {
+			continue
+		}
+		if _, err := p.Find(name); err == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// pluginFile returns the file name of the executable of a plugin
+func pluginFile(name string) string {
+	if runtime.GOOS == "windows" {
+		return PluginPrefix + name + ".exe"
+	}
+	return PluginPrefix + name
+}
+
+// IsPlugin reports whether the target is served by a storage plugin rather than a built-in type
+func (t *StorageTarget) IsPlugin() bool {
+	return t.Type != "" && !slices.Contains(TargetTypes, t.Type)
+}
+
+// defaultMinioConfig returns the settings of the local MinIO server used for development
+func defaultMinioConfig() MinioConfig {
+	return MinioConfig{
+		Endpoint:  "localhost:9000",
+		Region:    "us-east-1",
+		Bucket:    "sync-manager",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+	}
+}
+
+// Transport returns the proxy and TLS settings of the target, nil for types without them
+func (t *StorageTarget) Transport() *TransportConfig {
+	switch t.Type {
+	case TargetS3:
+		return &t.S3.TransportConfig
+	case TargetMinio:
+		return &t.Minio.TransportConfig
+	case TargetGCS:
+		return &t.GCS.TransportConfig
+	case TargetOneDrive:
+		return &t.OneDrive.TransportConfig
+	}
+	return nil
+}
+
+// Validate checks the target has a name and the settings its type needs
+func (t *StorageTarget) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("storage target name is required")
+	}
+
+	switch t.Type {
+	case TargetS3:
+		if t.S3.Bucket == "" {
+			return fmt.Errorf("S3 bucket is required")
+		}
+		if t.S3.Region == "" {
+			t.S3.Region = "us-east-1"
+		}
+		// Only require access key and secret key if not using AWS environment credentials
+		if t.S3.Endpoint != "" {
+			if t.S3.AccessKey == "" {
+				return fmt.Errorf("S3 access key is required when using a custom endpoint")
+			}
+			if t.S3.SecretKey == "" {
+				return fmt.Errorf("S3 secret key is required when using a custom endpoint")
+			}
+		}
+		if len(t.S3.Failover) > 0 && t.S3.Endpoint == "" {
+			return fmt.Errorf("S3 failover endpoints need a primary endpoint")
+		}
+		if err := ValidateFailover(t.S3.Endpoint, t.S3.Failover); err != nil {
+			return err
+		}
+	case TargetMinio:
+		if t.Minio.Bucket == "" {
+			return fmt.Errorf("MinIO bucket is required")
+		}
+		if t.Minio.Endpoint == "" {
+			return fmt.Errorf("MinIO endpoint is required")
+		}
+		if t.Minio.AccessKey == "" {
+			return fmt.Errorf("MinIO access key is required")
+		}
+		if t.Minio.SecretKey == "" {
+			return fmt.Errorf("MinIO secret key is required")
+		}
+		if err := ValidateFailover(t.Minio.Endpoint, t.Minio.Failover); err != nil {
+			return err
+		}
+	case TargetGCS:
+		if t.GCS.Bucket == "" {
+			return fmt.Errorf("GCS bucket is required")
+		}
+		if t.GCS.ProjectID == "" {
+			return fmt.Errorf("GCS project ID is required")
+		}
+	case TargetLocal:
+		if t.Local.RootDir == "" {
+			return fmt.Errorf("Local storage root directory is required")
+		}
+	case TargetOneDrive:
+		if t.OneDrive.ClientID == "" {
+			return fmt.Errorf("OneDrive client ID is required")
+		}
+	case TargetMemory:
+		if t.Memory.Latency < 0 {
+			return fmt.Errorf("memory storage latency cannot be negative")
+		}
+		if t.Memory.ErrorRate < 0 || t.Memory.ErrorRate > 1 {
+			return fmt.Errorf("memory storage error_rate must be between 0 and 1")
+		}
+	default:
+		return fmt.Errorf("unsupported storage provider: %s", t.Type)
+	}
+
+	if transport := t.Transport(); transport != nil {
+		if err := transport.Validate(); err != nil {
+			return fmt.Errorf("invalid transport settings: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateFailover checks the failover endpoints are set and differ from each other and from
+// the primary endpoint
+func ValidateFailover(primary string, failover []string) error {
+	seen := map[string]bool{primary: true}
+	for _, endpoint := range failover {
+		if endpoint == "" {
+			return fmt.Errorf("failover endpoints cannot be empty")
+		}
+		if seen[endpoint] {
+			return fmt.Errorf("duplicate failover endpoint %s", endpoint)
+		}
+		seen[endpoint] = true
+	}
+	return nil
+}
+
+// DefaultTarget returns the first storage target, which folders without a target sync to.
+// A configuration without targets gets an empty one named DefaultTargetName, so the
+// storage settings always have somewhere to go.
+func (c *Config) DefaultTarget() *StorageTarget {
+	if len(c.Targets) == 0 {
+		c.Targets = append(c.Targets, StorageTarget{Name: DefaultTargetName})
+	}
+	return &c.Targets[0]
+}
+
+// Target returns the storage target with the given name, or nil when there is none
+func (c *Config) Target(name string) *StorageTarget {
+	for i := range c.Targets {
+		if c.Targets[i].Name == name {
+			return &c.Targets[i]
+		}
+	}
+	return nil
+}
+
+// FolderTarget returns the storage target a folder syncs to
+func (c *Config) FolderTarget(folder *SyncFolder) *StorageTarget {
+	if folder.Target == "" {
+		return c.DefaultTarget()
+	}
+	return c.Target(folder.Target)
+}
+
+// TargetNames lists the names of the storage targets, in order
+func (c *Config) TargetNames() []string {
+	names := make([]string, 0, len(c.Targets))
+	for _, target := range c.Targets {
+		names = append(names, target.Name)
+	}
+	return names
+}
+
+// validateTargets checks every target and that folders only reference targets that exist
+func validateTargets(config *Config) error {
+	if len(config.Targets) == 0 {
+		return fmt.Errorf("at least one storage target is required")
+	}
+
+	names := make(map[string]bool, len(config.Targets))
+	for i := range config.Targets {
+		target := &config.Targets[i]
+		err := target.Validate()
+		if target.IsPlugin() && target.Name != "" {
+			_, err = config.Plugins.Find(target.Type)
+		}
+		if err != nil {
+			if target.Name == "" {
+				return err
+			}
+			return fmt.Errorf("invalid storage target %s: %w", target.Name, err)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("duplicate storage target %s", target.Name)
+		}
+		names[target.Name] = true
+	}
+
+	for _, folder := range config.SyncFolders {
+		if folder.Target != "" && !names[folder.Target] {
+			return fmt.Errorf("folder %s uses unknown storage target %s (configured: %s)", folder.ID, folder.Target, strings.Join(config.TargetNames(), ", "))
+		}
+	}
+	return nil
+}
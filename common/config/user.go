@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// activeUserFile holds the ID of the CLI database user whose folders and devices commands use
+const activeUserFile = "active-user"
+
+// ActiveUser returns the user selected with SetActiveUser, or zero when none was
+func ActiveUser() (uint, error) {
+	dir, err := configDir()
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, activeUserFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read active user: %w", err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(text, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid active user %q: %w", text, err)
+	}
+	return uint(id), nil
+}
+
+// SetActiveUser makes id the user commands use from now on
+func SetActiveUser(id uint) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, activeUserFile), []byte(strconv.FormatUint(uint64(id), 10)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to save active user: %w", err)
+	}
+	return nil
+}
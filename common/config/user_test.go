@@ -0,0 +1,21 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActiveUser(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	id, err := ActiveUser()
+	assert.NoError(t, err)
+	assert.Zero(t, id)
+
+	assert.NoError(t, SetActiveUser(3))
+	id, err = ActiveUser()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), id)
+}
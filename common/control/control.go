@@ -0,0 +1,225 @@
+// Package control is the API through which the CLI reads and changes the configuration of a
+// running agent. The agent serves it over a Unix socket next to its configuration file, which
+// only the user owning the configuration can reach, and each request carries an API token.
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrUnavailable is returned by the client when no agent serves the socket
+var ErrUnavailable = errors.New("agent is not running")
+
+// ErrUnauthorized is returned by the client when the agent refuses its token
+var ErrUnauthorized = errors.New("agent refused the API token")
+
+// Authenticator returns an error unless token is a valid API token
+type Authenticator func(token string) error
+
+// requestTimeout bounds a request, which for a change includes saving and applying it
+const requestTimeout = 30 * time.Second
+
+// Config is the configuration of the running agent
+type Config interface {
+	// Get returns the value of a setting as the agent runs with it
+	Get(key, target string) (string, error)
+	// Set changes settings together, then checks, saves and applies the whole configuration.
+	// Settings refused, alone or together, leave the configuration as it was.
+	Set(settings []Setting) error
+}
+
+// Setting is a setting named by its dotted key, as config get and config set take it
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Target string `json:"target,omitempty"` // Storage target of a storage.* key, the default one when empty
+}
+
+// reply is the answer to a request: the value of a setting, or why it was refused
+type reply struct {
+	Value string `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// SocketPath returns the socket of the agent running the configuration at configPath, in a
+// directory of its own next to the file
+func SocketPath(configPath string) string {
+	return filepath.Join(configPath+".control", "agent.sock")
+}
+
+// Listen opens the socket at path, replacing one left by an agent that did not stop cleanly.
+// Its directory is only open to the current user, so the socket cannot be reached by others
+// while it still has the mode of the process umask.
+func Listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	// The directory may be left from an earlier run with a wider mode
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to restrict control socket directory: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict control socket: %w", err)
+	}
+	return listener, nil
+}
+
+// Serve answers requests on listener with cfg until ctx is cancelled. Requests without a
+// bearer token auth accepts are refused.
+func Serve(ctx context.Context, listener net.Listener, cfg Config, auth Authenticator) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		value, err := cfg.Get(r.URL.Query().Get("key"), r.URL.Query().Get("target"))
+		respond(w, value, err)
+	})
+	mux.HandleFunc("POST /config", func(w http.ResponseWriter, r *http.Request) {
+		var settings []Setting
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			respond(w, "", fmt.Errorf("invalid request: %w", err))
+			return
+		}
+		respond(w, "", cfg.Set(settings))
+	})
+
+	server := &http.Server{Handler: authenticate(mux, auth), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authenticate refuses requests whose bearer token auth does not accept
+func authenticate(next http.Handler, auth Authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(reply{Error: "missing API token"})
+			return
+		}
+		if err := auth(token); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(reply{Error: err.Error()})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// respond writes the reply to a request, refused with err when set
+func respond(w http.ResponseWriter, value string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(reply{Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(reply{Value: value})
+}
+
+// Client talks to the agent serving a control socket
+type Client struct {
+	http  *http.Client
+	token string
+}
+
+// NewClient returns a client of the agent serving the socket at path, authenticating with
+// the API token
+func NewClient(path, token string) *Client {
+	var dialer net.Dialer
+	return &Client{token: token, http: &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				conn, err := dialer.DialContext(ctx, "unix", path)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+				}
+				return conn, nil
+			},
+		},
+	}}
+}
+
+// Get returns the value of a setting as the agent runs with it
+func (c *Client) Get(ctx context.Context, key, target string) (string, error) {
+	query := url.Values{"key": {key}}
+	if target != "" {
+		query.Set("target", target)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://agent/config?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	return c.do(req)
+}
+
+// Set asks the agent to change settings, which it checks together, saves and applies at once
+func (c *Client) Set(ctx context.Context, settings ...Setting) error {
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://agent/config", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	_, err = c.do(req)
+	return err
+}
+
+// do sends a request and returns the value replied, or the error the agent refused it with
+func (c *Client) do(req *http.Request) (string, error) {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrUnavailable) {
+			return "", ErrUnavailable
+		}
+		return "", fmt.Errorf("failed to reach the agent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", ErrUnauthorized
+	}
+
+	var answer reply
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return "", fmt.Errorf("invalid reply from the agent: %w", err)
+	}
+	if answer.Error != "" {
+		return "", errors.New(answer.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("agent replied %s", resp.Status)
+	}
+	return answer.Value, nil
+}
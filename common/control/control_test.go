@@ -0,0 +1,104 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// settings is a configuration of plain values, keyed by target and key
+type settings map[string]string
+
+func (s settings) Get(key, target string) (string, error) {
+	value, ok := s[target+key]
+	if !ok {
+		return "", fmt.Errorf("unknown configuration key: %s", key)
+	}
+	return value, nil
+}
+
+func (s settings) Set(changes []Setting) error {
+	for _, change := range changes {
+		if _, ok := s[change.Target+change.Key]; !ok {
+			return fmt.Errorf("unknown configuration key: %s", change.Key)
+		}
+	}
+	for _, change := range changes {
+		s[change.Target+change.Key] = change.Value
+	}
+	return nil
+}
+
+func TestClientAndServer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets need a recent Windows")
+	}
+	path := SocketPath(filepath.Join(t.TempDir(), "config.yaml"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Without an agent the client says so, also when a stopped one left its socket
+	client := NewClient(path, "smt_ok")
+	_, err := client.Get(ctx, "lan.listen", "")
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	// A socket directory left with a wider mode is restricted again
+	cfg := settings{"lan.listen": ":7777", "backupstorage.provider": "local"}
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	listener, err := Listen(path)
+	assert.NoError(t, err)
+	listener.Close()
+	assert.NoError(t, os.WriteFile(path, nil, 0600))
+	assert.ErrorIs(t, client.Set(ctx, Setting{Key: "lan.listen", Value: ":8888"}), ErrUnavailable)
+
+	listener, err = Listen(path)
+	assert.NoError(t, err)
+	served := make(chan error, 1)
+	auth := func(token string) error {
+		if token != "smt_ok" {
+			return errors.New("invalid or expired token")
+		}
+		return nil
+	}
+	go func() { served <- Serve(ctx, listener, cfg, auth) }()
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	info, err = os.Stat(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	value, err := client.Get(ctx, "lan.listen", "")
+	assert.NoError(t, err)
+	assert.Equal(t, ":7777", value)
+	value, err = client.Get(ctx, "storage.provider", "backup")
+	assert.NoError(t, err)
+	assert.Equal(t, "local", value)
+
+	assert.NoError(t, client.Set(ctx, Setting{Key: "lan.listen", Value: ":8888"}, Setting{Key: "storage.provider", Value: "s3", Target: "backup"}))
+	assert.Equal(t, settings{"lan.listen": ":8888", "backupstorage.provider": "s3"}, cfg)
+
+	// Only callers with a token the agent accepts reach the configuration
+	for _, token := range []string{"", "smt_other"} {
+		_, err := NewClient(path, token).Get(ctx, "lan.listen", "")
+		assert.ErrorIs(t, err, ErrUnauthorized, token)
+		assert.ErrorIs(t, NewClient(path, token).Set(ctx, Setting{Key: "lan.listen", Value: ":1"}), ErrUnauthorized, token)
+	}
+	assert.Equal(t, ":8888", cfg["lan.listen"])
+
+	// Refusals come back with the agent's reason
+	err = client.Set(ctx, Setting{Key: "lan.listen", Value: ":9999"}, Setting{Key: "lan.port", Value: "8888"})
+	assert.EqualError(t, err, "unknown configuration key: lan.port")
+	assert.False(t, errors.Is(err, ErrUnavailable))
+	assert.Equal(t, ":8888", cfg["lan.listen"])
+
+	cancel()
+	assert.NoError(t, <-served)
+}
@@ -0,0 +1,65 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "@often"} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		assert.NoError(t, err)
+		return parsed
+	}
+	from := at("2024-06-10 12:34") // A Monday
+
+	for expr, want := range map[string]string{
+		"0 2 * * *":        "2024-06-11 02:00",
+		"*/15 * * * *":     "2024-06-10 12:45",
+		"5/20 * * * *":     "2024-06-10 12:45",
+		"30 9-17 * * 1-5":  "2024-06-10 13:30",
+		"0 9 * * sat,SUN":  "2024-06-15 09:00",
+		"0 0 * * 7":        "2024-06-16 00:00",
+		"0 0 1 jan *":      "2025-01-01 00:00",
+		"0 0 13 * 5":       "2024-06-13 00:00", // The 13th or any Friday, whichever comes first
+		"0 0 29 2 *":       "2028-02-29 00:00",
+		"@daily":           "2024-06-11 00:00",
+		"@hourly":          "2024-06-10 13:00",
+		"0,30 12 10 6 *":   "2025-06-10 12:00",
+		"34 12 10 6 *":     "2025-06-10 12:34",
+		" 0  2  *  *  * ":  "2024-06-11 02:00",
+		"0 12 10-11 6 mon": "2024-06-11 12:00",
+	} {
+		schedule, err := Parse(expr)
+		if assert.NoError(t, err, expr) {
+			assert.Equal(t, at(want), schedule.Next(from), expr)
+		}
+	}
+
+	// Schedules that never fire have no next time
+	never, err := Parse("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, never.Next(from).IsZero())
+	assert.Equal(t, "0 0 30 2 *", never.String())
+}
+
+func TestNextFollowsLocalTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	schedule, err := Parse("0 2 * * *")
+	assert.NoError(t, err)
+	next := schedule.Next(time.Date(2024, 6, 10, 12, 0, 0, 0, loc))
+	assert.Equal(t, time.Date(2024, 6, 11, 2, 0, 0, 0, loc), next)
+	assert.Equal(t, loc, next.Location())
+}
@@ -0,0 +1,77 @@
+// Package database resolves the database the CLI and the agent share: a local SQLite file
+// by default, or a central Postgres or MySQL server named by a DSN in the configuration.
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Database drivers
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+	MySQL    = "mysql"
+)
+
+// dialectors maps a driver name to a function building its gorm dialector from a DSN
+var dialectors = map[string]func(dsn string) (gorm.Dialector, error){
+	SQLite: func(dsn string) (gorm.Dialector, error) {
+		return sqlite.Open(strings.TrimPrefix(dsn, "sqlite://")), nil
+	},
+	Postgres: func(dsn string) (gorm.Dialector, error) {
+		return postgres.Open(dsn), nil
+	},
+	MySQL: func(dsn string) (gorm.Dialector, error) {
+		cfg, err := mysqlConfig(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return mysql.Open(cfg.FormatDSN()), nil
+	},
+}
+
+// Driver returns the driver of dsn: postgres:// and postgresql:// URLs are Postgres, mysql://
+// URLs MySQL, and anything else the path of a SQLite file
+func Driver(dsn string) string {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return Postgres
+	case strings.HasPrefix(dsn, "mysql://"):
+		return MySQL
+	default:
+		return SQLite
+	}
+}
+
+// Dialector returns the gorm dialector for dsn along with its driver
+func Dialector(dsn string) (gorm.Dialector, string, error) {
+	driver := Driver(dsn)
+	d, err := dialectors[driver](dsn)
+	if err != nil {
+		return nil, driver, fmt.Errorf("invalid %s DSN: %w", driver, err)
+	}
+	return d, driver, nil
+}
+
+// Redact returns dsn with its password masked, for logs and messages
+func Redact(dsn string) string {
+	scheme, rest, ok := strings.Cut(dsn, "://")
+	if !ok {
+		return dsn
+	}
+	at := strings.LastIndex(rest, "@")
+	if at < 0 {
+		return dsn
+	}
+	user, _, hasPassword := strings.Cut(rest[:at], ":")
+	if !hasPassword {
+		return dsn
+	}
+	return scheme + "://" + user + ":xxxxx" + rest[at:]
+}
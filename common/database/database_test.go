@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriver(t *testing.T) {
+	assert.Equal(t, SQLite, Driver("/home/ana/.config/sync-manager/config.db"))
+	assert.Equal(t, SQLite, Driver("sqlite:///tmp/sync.db"))
+	assert.Equal(t, Postgres, Driver("postgres://sync:secret@db:5432/sync_manager?sslmode=disable"))
+	assert.Equal(t, Postgres, Driver("postgresql://db/sync_manager"))
+	assert.Equal(t, MySQL, Driver("mysql://sync:secret@db:3306/sync_manager"))
+}
+
+func TestDialector(t *testing.T) {
+	dialector, driver, err := Dialector("/tmp/sync.db")
+	assert.NoError(t, err)
+	assert.Equal(t, SQLite, driver)
+	assert.Equal(t, "sqlite", dialector.Name())
+
+	dialector, driver, err = Dialector("postgres://sync:secret@db:5432/sync_manager")
+	assert.NoError(t, err)
+	assert.Equal(t, Postgres, driver)
+	assert.Equal(t, "postgres", dialector.Name())
+
+	dialector, driver, err = Dialector("mysql://sync:secret@db/sync_manager")
+	assert.NoError(t, err)
+	assert.Equal(t, MySQL, driver)
+	assert.Equal(t, "mysql", dialector.Name())
+
+	_, _, err = Dialector("mysql://sync@db")
+	assert.ErrorContains(t, err, "invalid mysql DSN")
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "postgres://sync:xxxxx@db:5432/sync_manager", Redact("postgres://sync:s3cr@t@db:5432/sync_manager"))
+	assert.Equal(t, "mysql://sync@db/sync_manager", Redact("mysql://sync@db/sync_manager"))
+	assert.Equal(t, "/tmp/sync.db", Redact("/tmp/sync.db"))
+}
@@ -0,0 +1,20 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLConfig(t *testing.T) {
+	cfg, err := mysqlConfig("mysql://sync:secret@db/sync_manager?tls=true&parseTime=false")
+	assert.NoError(t, err)
+	assert.Equal(t, "db:3306", cfg.Addr)
+	assert.Equal(t, "sync_manager", cfg.DBName)
+	assert.Equal(t, "secret", cfg.Passwd)
+	assert.True(t, cfg.ParseTime)
+	assert.Equal(t, "true", cfg.Params["tls"])
+
+	_, err = mysqlConfig("mysql://sync@db")
+	assert.Error(t, err)
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package diskspace
+
+// available reports ErrUnsupported on platforms without a supported method
+func available(path string) (uint64, error) {
+	return 0, ErrUnsupported
+}
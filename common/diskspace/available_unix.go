@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package diskspace
+
+import "syscall"
+
+// available returns the free bytes of the filesystem holding path through statfs
+func available(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
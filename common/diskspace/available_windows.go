@@ -0,0 +1,16 @@
+package diskspace
+
+import "golang.org/x/sys/windows"
+
+// available returns the free bytes of the volume holding path, within the user's quota
+func available(path string) (uint64, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(name, &free, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
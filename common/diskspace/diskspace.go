@@ -0,0 +1,133 @@
+package diskspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Headroom is the space left free on a disk after a planned download
+const Headroom = 100 << 20
+
+// ErrUnsupported is returned by Available on platforms without a way to query free space
+var ErrUnsupported = errors.New("free space cannot be measured on this platform")
+
+// Available returns the bytes free for the current user on the filesystem holding path.
+// A path that does not exist yet is measured at its nearest existing parent.
+func Available(path string) (uint64, error) {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		path = parent
+	}
+	return available(path)
+}
+
+// Enough reports whether needed bytes fit in available ones, keeping Headroom free
+func Enough(available, needed uint64) bool {
+	return needed+Headroom <= available
+}
+
+// Shortage is a folder whose remote changes were not downloaded for lack of disk space
+type Shortage struct {
+	FolderID   string    `json:"folder_id"`
+	Path       string    `json:"path"`
+	Needed     uint64    `json:"needed"`
+	Available  uint64    `json:"available"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// State holds the folders waiting for disk space. The agent writes it to the file at
+// DefaultPath and the CLI reads it to warn the user.
+type State struct {
+	Shortages map[string]Shortage `json:"shortages"`
+}
+
+// DefaultPath returns the default location of the disk space state
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "disk-space.json"), nil
+}
+
+// Read loads the state at path, returning an empty state if none was written
+func Read(path string) (*State, error) {
+	state := &State{Shortages: make(map[string]Shortage)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read disk space state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse disk space state: %w", err)
+	}
+	if state.Shortages == nil {
+		state.Shortages = make(map[string]Shortage)
+	}
+	return state, nil
+}
+
+// Write stores the state at path
+func Write(path string, state *State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create disk space state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal disk space state: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write disk space state: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace disk space state: %w", err)
+	}
+
+	return nil
+}
+
+// Record stores the shortage of a folder. A folder already short of space keeps its
+// detection time, so a long shortage is reported as a single event.
+func Record(path string, shortage Shortage) error {
+	state, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if previous, ok := state.Shortages[shortage.FolderID]; ok {
+		shortage.DetectedAt = previous.DetectedAt
+	}
+	state.Shortages[shortage.FolderID] = shortage
+	return Write(path, state)
+}
+
+// Clear forgets the shortage of a folder, if any
+func Clear(path, folderID string) error {
+	state, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := state.Shortages[folderID]; !ok {
+		return nil
+	}
+	delete(state.Shortages, folderID)
+	return Write(path, state)
+}
@@ -0,0 +1,45 @@
+package diskspace
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnough(t *testing.T) {
+	assert.True(t, Enough(Headroom+10, 10))
+	assert.False(t, Enough(Headroom+10, 11))
+	assert.False(t, Enough(0, 0))
+}
+
+func TestAvailableMeasuresExistingParent(t *testing.T) {
+	dir := t.TempDir()
+	free, err := Available(filepath.Join(dir, "not", "created", "yet"))
+	if err == ErrUnsupported {
+		t.Skip(err)
+	}
+	assert.NoError(t, err)
+	assert.Greater(t, free, uint64(0))
+}
+
+func TestRecordClear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk-space.json")
+	detected := time.Now().Add(-time.Hour).Round(time.Second)
+
+	assert.NoError(t, Record(path, Shortage{FolderID: "docs", Needed: 100, Available: 10, DetectedAt: detected}))
+	assert.NoError(t, Record(path, Shortage{FolderID: "docs", Needed: 200, Available: 5, DetectedAt: time.Now()}))
+
+	// A shortage that goes on keeps its first detection time and the latest figures
+	state, err := Read(path)
+	assert.NoError(t, err)
+	assert.True(t, detected.Equal(state.Shortages["docs"].DetectedAt))
+	assert.Equal(t, uint64(200), state.Shortages["docs"].Needed)
+
+	assert.NoError(t, Clear(path, "docs"))
+	assert.NoError(t, Clear(path, "photos"))
+	state, err = Read(path)
+	assert.NoError(t, err)
+	assert.Empty(t, state.Shortages)
+}
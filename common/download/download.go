@@ -0,0 +1,535 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// maxRetries is how many times a failed download is tried again, with exponential backoff
+const maxRetries = 3
+
+// stateSuffix names the file kept next to a resumable download, listing the chunks already written
+const stateSuffix = ".chunks"
+
+// IntegrityError reports downloaded content that does not hash to the hash stored with the
+// file, because it was corrupted in transit or the file changed during the download
+type IntegrityError struct {
+	Key      string
+	Expected string // Hash in the file's metadata, tagged with its algorithm as by checksum.Algorithm.Tag
+	Actual   string // Hash of the downloaded content with the same algorithm
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("downloaded content of %s does not match the stored hash (expected %s, got %s)", e.Key, e.Expected, e.Actual)
+}
+
+// Task is a remote file to download
+type Task struct {
+	Key  string // Remote key in storage
+	Size int64  // Size of the remote file, used to split it into chunks
+	Path string // File the content is written to
+
+	// Resume keeps the chunks of an interrupted download next to Path, so fetching
+	// the same content again later only downloads the missing ones
+	Resume bool
+	// Checksum is the algorithm the stored hash is expected to use, SHA-256 when empty. A file
+	// downloaded in a single request is hashed with it as it arrives, and read again from
+	// disk when the stored hash turns out to use another one.
+	Checksum checksum.Algorithm
+}
+
+// Downloader fetches files from storage with bounded concurrency and a shared bandwidth
+// limit. Large files are split into chunks fetched in parallel when the backend supports
+// ranged reads; a failed attempt is retried for the missing chunks only.
+type Downloader struct {
+	store          storage.Storage
+	maxConcurrency int
+	throttleBytes  int64 // bytes per second, 0 for no throttling
+	chunkSize      int64
+	rate           *rate
+	active         int           // Requests in flight
+	released       chan struct{} // Closed when a request finishes or the limit grows
+	mutex          sync.Mutex
+}
+
+// NewDownloader creates a downloader using the download settings of cfg, or defaults when cfg is nil
+func NewDownloader(store storage.Storage, cfg *commonconfig.Config) *Downloader {
+	settings := commonconfig.DefaultConfig().Download
+	if cfg != nil {
+		settings = cfg.Download
+	}
+	if settings.MaxConcurrency < 1 {
+		settings.MaxConcurrency = 1
+	}
+	if settings.ChunkSize <= 0 {
+		settings.ChunkSize = commonconfig.DefaultDownloadChunkSize
+	}
+
+	d := &Downloader{
+		store:          store,
+		maxConcurrency: settings.MaxConcurrency,
+		throttleBytes:  settings.ThrottleBytes,
+		chunkSize:      settings.ChunkSize,
+		released:       make(chan struct{}),
+	}
+	d.rate = &rate{limit: d.throttle}
+	return d
+}
+
+// MaxConcurrency returns how many requests may be in flight at once
+func (d *Downloader) MaxConcurrency() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.maxConcurrency
+}
+
+// SetMaxConcurrency changes how many requests may be in flight. Requests already
+// running above a lowered limit finish before new ones start.
+func (d *Downloader) SetMaxConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.maxConcurrency = n
+	d.signalLocked()
+}
+
+// SetThrottle changes the bandwidth limit in bytes/sec, 0 for none.
+// Downloads already in progress pick up the new limit.
+func (d *Downloader) SetThrottle(bytesPerSec int64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.throttleBytes = bytesPerSec
+}
+
+// throttle returns the bandwidth limit in bytes/sec
+func (d *Downloader) throttle() int64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.throttleBytes
+}
+
+// acquire waits for a free request slot
+func (d *Downloader) acquire(ctx context.Context) error {
+	for {
+		d.mutex.Lock()
+		if d.active < d.maxConcurrency {
+			d.active++
+			d.mutex.Unlock()
+			return nil
+		}
+		wait := d.released
+		d.mutex.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release frees a request slot
+func (d *Downloader) release() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.active--
+	d.signalLocked()
+}
+
+// signalLocked wakes the requests waiting for a slot. mutex must be held.
+func (d *Downloader) signalLocked() {
+	close(d.released)
+	d.released = make(chan struct{})
+}
+
+// Each calls fn for every index below n, running up to MaxConcurrency calls at once.
+// It returns once all calls have returned, or ctx's error if it was cancelled meanwhile.
+func (d *Downloader) Each(ctx context.Context, n int, fn func(ctx context.Context, i int)) error {
+	indexes := make(chan int)
+	var workers sync.WaitGroup
+	for w := 0; w < min(d.MaxConcurrency(), n); w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := range indexes {
+				fn(ctx, i)
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	workers.Wait()
+	return ctx.Err()
+}
+
+// Fetch downloads a file into task.Path, counting the bytes on transfer when it is not nil,
+// and returns the file's metadata. The content is checked against the hash stored with
+// the file, hashed as it streams in or, for chunked downloads, once every chunk is written;
+// a mismatch is retried like any other failure and returned as an *IntegrityError.
+func (d *Downloader) Fetch(ctx context.Context, task Task, transfer *progress.File) (map[string]string, error) {
+	// Chunks written by a failed attempt are kept for the next one
+	st := &chunkState{}
+	for attempt := 0; ; attempt++ {
+		metadata, err := d.attempt(ctx, task, st, transfer)
+		if err == nil {
+			return metadata, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt >= maxRetries || errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+
+		backoff := time.Duration(1<<attempt) * time.Second
+		log.Info().
+			Err(err).
+			Str("key", task.Key).
+			Int("retry", attempt+1).
+			Dur("backoff", backoff).
+			Msg("Scheduling download retry")
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt downloads a file once, in chunks when it is large enough and the backend allows it
+func (d *Downloader) attempt(ctx context.Context, task Task, st *chunkState, transfer *progress.File) (map[string]string, error) {
+	d.mutex.Lock()
+	chunkSize := d.chunkSize
+	d.mutex.Unlock()
+
+	ranged, ok := d.store.(storage.RangeDownloader)
+	if !ok || task.Size <= chunkSize {
+		return d.whole(ctx, task, transfer)
+	}
+	metadata, err := d.chunked(ctx, task, ranged, chunkSize, st, transfer)
+	if errors.Is(err, storage.ErrRangeUnsupported) {
+		// A middleware forwards ranges the backend beneath it cannot read
+		st.Done = nil
+		return d.whole(ctx, task, transfer)
+	}
+	return metadata, err
+}
+
+// whole downloads a file in a single request
+func (d *Downloader) whole(ctx context.Context, task Task, transfer *progress.File) (map[string]string, error) {
+	if err := d.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer d.release()
+
+	file, err := os.Create(task.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	counter := &countingWriter{}
+	alg := task.Checksum
+	if alg == "" {
+		alg = checksum.SHA256
+	}
+	hasher := alg.New()
+	writers := []io.Writer{file, counter, hasher}
+	if transfer != nil {
+		writers = append(writers, transfer)
+	}
+
+	metadata, err := d.store.DownloadFile(ctx, task.Key, d.rate.writer(io.MultiWriter(writers...)), "")
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write file: %w", closeErr)
+	}
+	if err == nil {
+		expected, actual := checksum.FromMetadata(metadata), alg.Tag(hasher.Sum(nil))
+		if expected != "" && checksum.Of(expected) != alg {
+			actual, err = checksum.OpenFile(checksum.Of(expected), task.Path, nil)
+		}
+		if err == nil {
+			err = verify(task.Key, expected, actual)
+		}
+	}
+	if err != nil && transfer != nil {
+		// The next attempt starts from zero
+		transfer.Add(-counter.n)
+	}
+	return metadata, err
+}
+
+// chunkState is the content of the state file of a resumable download
+type chunkState struct {
+	Key       string       `json:"key"`
+	Size      int64        `json:"size"`
+	Hash      string       `json:"hash"`
+	ChunkSize int64        `json:"chunk_size"`
+	Done      map[int]bool `json:"done"`
+}
+
+// matches reports whether st describes the same content as other
+func (st *chunkState) matches(other chunkState) bool {
+	return st.Done != nil && st.Key == other.Key && st.Size == other.Size && st.Hash == other.Hash && st.ChunkSize == other.ChunkSize
+}
+
+// chunked downloads the chunks of a file missing from st, in parallel
+func (d *Downloader) chunked(ctx context.Context, task Task, ranged storage.RangeDownloader, chunkSize int64, st *chunkState, transfer *progress.File) (map[string]string, error) {
+	_, metadata, err := d.store.GetFileInfo(ctx, task.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	hash := checksum.FromMetadata(metadata)
+	resumable := task.Resume && hash != ""
+	statePath := task.Path + stateSuffix
+
+	chunks := int((task.Size + chunkSize - 1) / chunkSize)
+	current := chunkState{Key: task.Key, Size: task.Size, Hash: hash, ChunkSize: chunkSize}
+	if !st.matches(current) {
+		// First attempt, or the file changed since the last one
+		*st = current
+		st.Done = make(map[int]bool)
+		if saved, ok := loadState(statePath); resumable && ok && saved.matches(current) {
+			st.Done = saved.Done
+			log.Debug().Str("key", task.Key).Int("chunks", len(st.Done)).Int("total", chunks).Msg("Resuming download")
+		}
+
+		// Chunks written by an earlier run were not counted yet
+		for i := range st.Done {
+			if transfer != nil && i < chunks {
+				transfer.Add(chunkLength(i, chunkSize, task.Size))
+			}
+		}
+	}
+
+	file, err := os.OpenFile(task.Path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+	if len(st.Done) == 0 {
+		if err := file.Truncate(0); err != nil {
+			return nil, fmt.Errorf("failed to reset file: %w", err)
+		}
+	}
+	if err := file.Truncate(task.Size); err != nil {
+		return nil, fmt.Errorf("failed to allocate file: %w", err)
+	}
+
+	var pending []int
+	for i := 0; i < chunks; i++ {
+		if !st.Done[i] {
+			pending = append(pending, i)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		workers  sync.WaitGroup
+	)
+	chunkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, i := range pending {
+		if err := d.acquire(chunkCtx); err != nil {
+			break
+		}
+		workers.Add(1)
+		go func(i int) {
+			defer workers.Done()
+			defer d.release()
+
+			offset, length := int64(i)*chunkSize, chunkLength(i, chunkSize, task.Size)
+			counter := &countingWriter{}
+			writers := []io.Writer{io.NewOffsetWriter(file, offset), counter}
+			if transfer != nil {
+				writers = append(writers, transfer)
+			}
+
+			err := ranged.DownloadRange(chunkCtx, task.Key, offset, length, d.rate.writer(io.MultiWriter(writers...)))
+			if err == nil && counter.n != length {
+				err = fmt.Errorf("chunk at %d has %d bytes, expected %d", offset, counter.n, length)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if transfer != nil {
+					transfer.Add(-counter.n)
+				}
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			st.Done[i] = true
+			if resumable {
+				saveState(statePath, *st)
+			}
+		}(i)
+	}
+	workers.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, fmt.Errorf("failed to download file: %w", firstErr)
+	}
+
+	if hash != "" {
+		actual, err := checksum.File(checksum.Of(hash), file, task.Size, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read downloaded file: %w", err)
+		}
+		if err := verify(task.Key, hash, actual); err != nil {
+			// A chunk was corrupted or the file changed while its chunks were downloaded: start over
+			os.Remove(statePath)
+			st.Done = nil
+			if transfer != nil {
+				transfer.Add(-task.Size)
+			}
+			return nil, err
+		}
+	}
+
+	os.Remove(statePath)
+	return metadata, nil
+}
+
+// verify returns an *IntegrityError when the downloaded content does not hash to the
+// stored hash. Files stored without one are not checked.
+func verify(key, expected, actual string) error {
+	if expected == "" || expected == actual {
+		return nil
+	}
+	log.Warn().Str("key", key).Str("expected", expected).Str("actual", actual).Msg("Downloaded content does not match the stored hash")
+	return &IntegrityError{Key: key, Expected: expected, Actual: actual}
+}
+
+// Resumable reports whether an interrupted download left chunks at path that a later Fetch with Resume continues
+func Resumable(path string) bool {
+	_, err := os.Stat(path + stateSuffix)
+	return err == nil
+}
+
+// chunkLength returns the length of chunk i of a file of the given size
+func chunkLength(i int, chunkSize, size int64) int64 {
+	return min(chunkSize, size-int64(i)*chunkSize)
+}
+
+// loadState reads the state file of a resumable download
+func loadState(path string) (chunkState, bool) {
+	var st chunkState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return st, false
+	}
+	if err := json.Unmarshal(data, &st); err != nil || st.Done == nil {
+		return st, false
+	}
+	return st, true
+}
+
+// saveState writes the state file of a resumable download. Failures only cost the ability to resume.
+func saveState(path string, st chunkState) {
+	data, err := json.Marshal(st)
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		log.Debug().Err(err).Str("path", path).Msg("Failed to save download state")
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// rate limits the bandwidth shared by every request of a downloader
+type rate struct {
+	limit func() int64 // Current limit in bytes/sec, 0 for none
+	start time.Time
+	used  int64
+	mu    sync.Mutex
+}
+
+// take waits until part of n bytes fits in the current second and returns how many may be sent
+func (r *rate) take(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit := r.limit()
+	if limit <= 0 {
+		return n
+	}
+
+	now := time.Now()
+	if now.Sub(r.start) >= time.Second {
+		r.start = now
+		r.used = 0
+	}
+	if r.used >= limit {
+		time.Sleep(time.Second - now.Sub(r.start))
+		r.start = time.Now()
+		r.used = 0
+	}
+
+	allowed := min(int64(n), limit-r.used)
+	r.used += allowed
+	return int(allowed)
+}
+
+// writer wraps w so writes through it respect the limit
+func (r *rate) writer(w io.Writer) io.Writer {
+	return &throttledWriter{writer: w, rate: r}
+}
+
+// throttledWriter splits writes to stay under a shared rate
+type throttledWriter struct {
+	writer io.Writer
+	rate   *rate
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		n, err := t.writer.Write(p[written : written+t.rate.take(len(p)-written)])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
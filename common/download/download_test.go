@@ -0,0 +1,334 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/checksum"
+	commonconfig "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDownloader creates a downloader splitting files into 4-byte chunks
+func newTestDownloader(store storage.Storage, concurrency int) *Downloader {
+	cfg := commonconfig.DefaultConfig()
+	cfg.Download.MaxConcurrency = concurrency
+	cfg.Download.ChunkSize = 4
+	return NewDownloader(store, cfg)
+}
+
+func TestFetchChunked(t *testing.T) {
+	ctx := context.Background()
+	var requests atomic.Int32
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{Fault: func(op, key string) error {
+		if op == "download" {
+			requests.Add(1)
+		}
+		return nil
+	}})
+	content := []byte("0123456789abcdefghij")
+	_, err := store.UploadFile(ctx, "docs/big.bin", bytes.NewReader(content), map[string]string{"device_id": "laptop"})
+	assert.NoError(t, err)
+
+	d := newTestDownloader(store, 3)
+	tracker := progress.NewTracker()
+	tracker.Add(1, int64(len(content)))
+	transfer := tracker.Start("docs/big.bin", int64(len(content)))
+
+	target := filepath.Join(t.TempDir(), "big.bin")
+	metadata, err := d.Fetch(ctx, Task{Key: "docs/big.bin", Size: int64(len(content)), Path: target}, transfer)
+	transfer.Finish(err)
+	assert.NoError(t, err)
+	assert.Equal(t, "laptop", metadata["device_id"])
+	assert.Equal(t, int32(5), requests.Load())
+	assert.Equal(t, int64(len(content)), tracker.Snapshot().BytesDone)
+
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// Small files go in a single request
+	_, err = store.UploadFile(ctx, "docs/small.txt", bytes.NewReader([]byte("tiny")), nil)
+	assert.NoError(t, err)
+	requests.Store(0)
+	_, err = d.Fetch(ctx, Task{Key: "docs/small.txt", Size: 4, Path: target}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestFetchRetriesMissingChunks(t *testing.T) {
+	ctx := context.Background()
+	var requests atomic.Int32
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{Fault: func(op, key string) error {
+		if op == "download" && requests.Add(1) == 2 {
+			return errors.New("connection reset")
+		}
+		return nil
+	}})
+	content := []byte("0123456789abcdef")
+	_, err := store.UploadFile(ctx, "big.bin", bytes.NewReader(content), nil)
+	assert.NoError(t, err)
+
+	d := newTestDownloader(store, 1)
+	target := filepath.Join(t.TempDir(), "big.bin")
+	_, err = d.Fetch(ctx, Task{Key: "big.bin", Size: int64(len(content)), Path: target}, nil)
+	assert.NoError(t, err)
+
+	// One failed chunk, fetched again alone: 4 chunks plus the retry
+	assert.Equal(t, int32(5), requests.Load())
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestFetchChunksThroughMiddlewares(t *testing.T) {
+	ctx := context.Background()
+	var requests atomic.Int32
+	memory := storage.NewMemoryStorage(&storage.MemoryConfig{Fault: func(op, key string) error {
+		if op == "download" && requests.Add(1) == 2 {
+			return errors.New("connection reset")
+		}
+		return nil
+	}})
+	content := []byte("0123456789abcdef")
+	_, err := memory.UploadFile(ctx, "big.bin", bytes.NewReader(content), nil)
+	assert.NoError(t, err)
+
+	// The failed chunk is retried by the middleware and counted in its metrics
+	metrics := storage.NewMetrics()
+	store := storage.Chain(memory,
+		storage.WithMetrics(metrics),
+		storage.WithRetry(storage.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+	)
+	target := filepath.Join(t.TempDir(), "big.bin")
+	_, err = newTestDownloader(store, 1).Fetch(ctx, Task{Key: "big.bin", Size: 16, Path: target}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), requests.Load())
+
+	var out bytes.Buffer
+	assert.NoError(t, metrics.WritePrometheus(&out))
+	assert.Contains(t, out.String(), `sync_manager_storage_bytes_total{provider="memory",operation="download_range"} 16`)
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// Without ranges beneath the middlewares the file is downloaded whole
+	requests.Store(10)
+	store = storage.Chain(plainStorage{memory}, storage.WithLogging())
+	_, err = newTestDownloader(store, 1).Fetch(ctx, Task{Key: "big.bin", Size: 16, Path: target}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(11), requests.Load())
+	data, err = os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestFetchResumesInterruptedDownload(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	content := []byte("0123456789abcdef")
+	_, err := store.UploadFile(ctx, "big.bin", bytes.NewReader(content), nil)
+	assert.NoError(t, err)
+	_, metadata, err := store.GetFileInfo(ctx, "big.bin")
+	assert.NoError(t, err)
+
+	// An earlier run wrote the first two chunks before stopping
+	target := filepath.Join(t.TempDir(), "big.bin")
+	partial := append([]byte("01234567"), make([]byte, 8)...)
+	assert.NoError(t, os.WriteFile(target, partial, 0644))
+	saveState(target+stateSuffix, chunkState{Key: "big.bin", Size: 16, Hash: metadata["hash_sha256"], ChunkSize: 4, Done: map[int]bool{0: true, 1: true}})
+
+	var requests atomic.Int32
+	d := newTestDownloader(&countingStorage{Storage: store, downloads: &requests}, 2)
+	_, err = d.Fetch(ctx, Task{Key: "big.bin", Size: 16, Path: target, Resume: true}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+	_, err = os.Stat(target + stateSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFetchRejectsMixedVersions(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	_, err := store.UploadFile(ctx, "big.bin", bytes.NewReader([]byte("0123456789abcdef")), nil)
+	assert.NoError(t, err)
+
+	// The file is replaced after the first chunk was read
+	var once sync.Once
+	replacing := &countingStorage{Storage: store, downloads: new(atomic.Int32), after: func() {
+		once.Do(func() {
+			store.UploadFile(ctx, "big.bin", bytes.NewReader([]byte("ABCDEFGHIJKLMNOP")), nil)
+		})
+	}}
+
+	d := newTestDownloader(replacing, 1)
+	target := filepath.Join(t.TempDir(), "big.bin")
+	_, err = d.Fetch(ctx, Task{Key: "big.bin", Size: 16, Path: target}, nil)
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, "ABCDEFGHIJKLMNOP", string(data))
+}
+
+func TestFetchVerifiesStreamedContent(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	_, err := store.UploadFile(ctx, "docs/notes.txt", bytes.NewReader([]byte("notes")), nil)
+	assert.NoError(t, err)
+
+	// The first download arrives corrupted and is fetched again
+	corrupting := &corruptingStorage{Storage: store, corrupt: 1}
+	d := newTestDownloader(corrupting, 1)
+	tracker := progress.NewTracker()
+	tracker.Add(1, 5)
+	transfer := tracker.Start("docs/notes.txt", 5)
+
+	target := filepath.Join(t.TempDir(), "notes.txt")
+	_, err = d.Fetch(ctx, Task{Key: "docs/notes.txt", Size: 5, Path: target}, transfer)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, corrupting.downloads)
+	assert.Equal(t, int64(5), tracker.Snapshot().BytesDone)
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, "notes", string(data))
+
+	// Content that never matches is rejected with the hashes involved
+	corrupting.corrupt = -1
+	_, err = d.attempt(ctx, Task{Key: "docs/notes.txt", Size: 5, Path: target}, &chunkState{}, nil)
+	var integrity *IntegrityError
+	if assert.ErrorAs(t, err, &integrity) {
+		assert.Equal(t, "docs/notes.txt", integrity.Key)
+		assert.NotEqual(t, integrity.Expected, integrity.Actual)
+	}
+}
+
+func TestFetchVerifiesOtherAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	upload := func(key, content string) string {
+		hash, err := checksum.Sum(checksum.BLAKE3, bytes.NewReader([]byte(content)))
+		assert.NoError(t, err)
+		metadata := map[string]string{}
+		checksum.SetMetadata(metadata, hash)
+		_, err = store.UploadFile(ctx, key, bytes.NewReader([]byte(content)), metadata)
+		assert.NoError(t, err)
+		return hash
+	}
+	upload("abc.txt", "abc")
+	upload("big.bin", "0123456789abcdef")
+
+	// Whether or not the algorithm was expected, single and chunked downloads check it
+	dir := t.TempDir()
+	corrupting := &corruptingStorage{Storage: store}
+	d := newTestDownloader(corrupting, 1)
+	for _, alg := range []checksum.Algorithm{checksum.BLAKE3, ""} {
+		_, err := d.Fetch(ctx, Task{Key: "abc.txt", Size: 3, Path: filepath.Join(dir, "abc.txt"), Checksum: alg}, nil)
+		assert.NoError(t, err, alg)
+	}
+	_, err := newTestDownloader(store, 2).Fetch(ctx, Task{Key: "big.bin", Size: 16, Path: filepath.Join(dir, "big.bin")}, nil)
+	assert.NoError(t, err)
+
+	corrupting.corrupt = -1
+	_, err = d.attempt(ctx, Task{Key: "abc.txt", Size: 3, Path: filepath.Join(dir, "abc.txt")}, &chunkState{}, nil)
+	var integrity *IntegrityError
+	if assert.ErrorAs(t, err, &integrity) {
+		assert.Equal(t, checksum.BLAKE3, checksum.Of(integrity.Expected))
+		assert.Equal(t, checksum.BLAKE3, checksum.Of(integrity.Actual))
+	}
+}
+
+func TestEachLimitsConcurrency(t *testing.T) {
+	d := newTestDownloader(storage.NewMemoryStorage(&storage.MemoryConfig{}), 3)
+
+	var running, peak atomic.Int32
+	var calls atomic.Int32
+	err := d.Each(context.Background(), 10, func(ctx context.Context, i int) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		calls.Add(1)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(10), calls.Load())
+	assert.Equal(t, int32(3), peak.Load())
+}
+
+func TestThrottle(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&storage.MemoryConfig{})
+	_, err := store.UploadFile(ctx, "big.bin", bytes.NewReader(make([]byte, 16)), nil)
+	assert.NoError(t, err)
+
+	// 8 bytes per second across every chunk: the second half waits for the next second
+	d := newTestDownloader(store, 4)
+	d.SetThrottle(8)
+	start := time.Now()
+	_, err = d.Fetch(ctx, Task{Key: "big.bin", Size: 16, Path: filepath.Join(t.TempDir(), "big.bin")}, nil)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+}
+
+// plainStorage hides the optional interfaces of the storage it embeds
+type plainStorage struct {
+	storage.Storage
+}
+
+// countingStorage counts ranged downloads, calling after when each one is done
+type countingStorage struct {
+	storage.Storage
+	downloads *atomic.Int32
+	after     func()
+}
+
+func (c *countingStorage) DownloadRange(ctx context.Context, key string, offset, length int64, w io.Writer) error {
+	c.downloads.Add(1)
+	err := c.Storage.(storage.RangeDownloader).DownloadRange(ctx, key, offset, length, w)
+	if c.after != nil {
+		c.after()
+	}
+	return err
+}
+
+// corruptingStorage flips the first byte of the next corrupt downloads, of every one when corrupt is negative
+type corruptingStorage struct {
+	storage.Storage
+	corrupt   int
+	downloads int
+}
+
+func (c *corruptingStorage) DownloadFile(ctx context.Context, key string, w io.Writer, versionID string) (map[string]string, error) {
+	c.downloads++
+	var buf bytes.Buffer
+	metadata, err := c.Storage.DownloadFile(ctx, key, &buf, versionID)
+	if err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	if c.corrupt != 0 && len(data) > 0 {
+		c.corrupt--
+		data[0] ^= 0xff
+	}
+	_, err = w.Write(data)
+	return metadata, err
+}
@@ -0,0 +1,162 @@
+// Package excludes merges the exclude rules kept in the shared database with the
+// excludes of each folder
+package excludes
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/database"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Applies reports whether a rule is used for a folder on a device
+func Applies(rule models.ExcludeRule, folderID, deviceID string) bool {
+	return (rule.FolderID == "" || rule.FolderID == folderID) &&
+		(rule.DeviceID == "" || rule.DeviceID == deviceID)
+}
+
+// Patterns returns the patterns the rules exclude for a folder on a device, in rule
+// order and without duplicates. Patterns of Allow rules are left out.
+func Patterns(rules []models.ExcludeRule, folderID, deviceID string) []string {
+	allowed := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Allow && Applies(rule, folderID, deviceID) {
+			allowed[rule.Pattern] = true
+		}
+	}
+
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Allow || !Applies(rule, folderID, deviceID) || allowed[rule.Pattern] || seen[rule.Pattern] {
+			continue
+		}
+		seen[rule.Pattern] = true
+		patterns = append(patterns, rule.Pattern)
+	}
+	return patterns
+}
+
+// Merge appends the patterns the rules exclude to the folder's own excludes. Allow rules
+// only lift patterns of other rules: the folder's own excludes always apply.
+func Merge(folderPatterns []string, rules []models.ExcludeRule, folderID, deviceID string) []string {
+	merged := append([]string(nil), folderPatterns...)
+	seen := make(map[string]bool)
+	for _, pattern := range folderPatterns {
+		seen[pattern] = true
+	}
+	for _, pattern := range Patterns(rules, folderID, deviceID) {
+		if !seen[pattern] {
+			merged = append(merged, pattern)
+		}
+	}
+	return merged
+}
+
+// cacheTTL is how long a Source reuses the rules it read, so checking every file event is cheap
+const cacheTTL = 5 * time.Second
+
+// Source reads the exclude rules of a device from the shared database. The database is
+// opened the first time it exists, so rules added after the agent started are seen.
+type Source struct {
+	path     string
+	deviceID string
+
+	mu       sync.Mutex
+	db       *gorm.DB
+	rules    []models.ExcludeRule
+	readAt   time.Time
+	cacheTTL time.Duration
+}
+
+// NewSource creates a source reading the database at path, or at the central database a
+// postgres:// or mysql:// DSN names
+func NewSource(path, deviceID string) *Source {
+	return &Source{path: path, deviceID: deviceID, cacheTTL: cacheTTL}
+}
+
+// Rules returns every rule that applies to the device, nil while the database does not exist
+func (s *Source) Rules() ([]models.ExcludeRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.readAt.IsZero() && time.Since(s.readAt) < s.cacheTTL {
+		return s.rules, nil
+	}
+
+	db, err := s.open()
+	if err != nil || db == nil {
+		return nil, err
+	}
+
+	var rules []models.ExcludeRule
+	err = db.Where("device_id = ? OR device_id = ''", s.deviceID).Order("id").Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exclude rules: %w", err)
+	}
+	s.rules, s.readAt = rules, time.Now()
+	return rules, nil
+}
+
+// Merge returns the excludes of a folder merged with the rules of the database
+func (s *Source) Merge(folderID string, folderPatterns []string) ([]string, error) {
+	rules, err := s.Rules()
+	if err != nil {
+		return folderPatterns, err
+	}
+	return Merge(folderPatterns, rules, folderID, s.deviceID), nil
+}
+
+// Close closes the database when it was opened
+func (s *Source) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	s.db = nil
+	return sqlDB.Close()
+}
+
+// open opens the database read-only, returning nil when it does not exist yet. The caller holds mu.
+func (s *Source) open() (*gorm.DB, error) {
+	if s.db != nil {
+		return s.db, nil
+	}
+
+	dialector, driver, err := database.Dialector(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if driver == database.SQLite {
+		if _, err := os.Stat(s.path); os.IsNotExist(err) {
+			return nil, nil
+		}
+		dialector = sqlite.Open("file:" + s.path + "?mode=ro")
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// The CLI creates the table the first time it runs after an upgrade
+	if !db.Migrator().HasTable(&models.ExcludeRule{}) {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		return nil, nil
+	}
+	s.db = db
+	return db, nil
+}
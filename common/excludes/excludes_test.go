@@ -0,0 +1,69 @@
+package excludes
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMerge(t *testing.T) {
+	rules := []models.ExcludeRule{
+		{Pattern: "*.tmp"},
+		{Pattern: "node_modules", FolderID: "code"},
+		{Pattern: "*.iso", DeviceID: "laptop"},
+		{Pattern: "*.log"},
+		{Pattern: "*.log", DeviceID: "desktop", Allow: true},
+		{Pattern: "build", FolderID: "docs"},
+		{Pattern: "*.tmp", FolderID: "code"},
+	}
+
+	assert.Equal(t, []string{".git", "*.tmp", "node_modules", "*.iso", "*.log"}, Merge([]string{".git"}, rules, "code", "laptop"))
+	// The allow rule lifts *.log on the desktop only
+	assert.Equal(t, []string{"*.tmp", "build"}, Merge(nil, rules, "docs", "desktop"))
+	// The folder's own excludes are kept even when a rule allows them
+	assert.Equal(t, []string{"*.log", "*.tmp"}, Merge([]string{"*.log"}, rules, "photos", "desktop"))
+}
+
+func TestSourceReadsDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-manager.db")
+
+	// No database yet: only the folder excludes
+	source := NewSource(path, "laptop")
+	defer source.Close()
+	patterns, err := source.Merge("code", []string{".git"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".git"}, patterns)
+
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.ExcludeRule{}))
+	assert.NoError(t, db.Create(&[]models.ExcludeRule{
+		{Pattern: "*.tmp"},
+		{Pattern: "*.iso", DeviceID: "desktop"},
+		{Pattern: "vendor", FolderID: "code", DeviceID: "laptop"},
+	}).Error)
+
+	patterns, err = source.Merge("code", []string{".git"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".git", "*.tmp", "vendor"}, patterns)
+
+	// Rules are reused for a short time, then read again
+	assert.NoError(t, db.Create(&models.ExcludeRule{Pattern: "*.bak"}).Error)
+	patterns, err = source.Merge("code", nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, patterns, "*.bak")
+
+	source.mu.Lock()
+	source.cacheTTL = 0
+	source.mu.Unlock()
+	patterns, err = source.Merge("code", nil)
+	assert.NoError(t, err)
+	assert.Contains(t, patterns, "*.bak")
+
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+}
@@ -0,0 +1,151 @@
+package guard
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Limits bound how many remote files one sync pass may delete. Zero or negative values disable a limit.
+type Limits struct {
+	MaxFiles   int // Most files deleted in one pass
+	MaxPercent int // Most files deleted in one pass, as a percentage of the remote files
+}
+
+// Exceeded reports whether deleting deletions of total remote files goes over the limits
+func (l Limits) Exceeded(deletions, total int) bool {
+	if l.MaxFiles > 0 && deletions > l.MaxFiles {
+		return true
+	}
+	return l.MaxPercent > 0 && total > 0 && deletions*100 > l.MaxPercent*total
+}
+
+// Block is a sync pass whose deletions were withheld because they went over the limits
+type Block struct {
+	FolderID   string    `json:"folder_id"`
+	Deletions  int       `json:"deletions"`
+	Total      int       `json:"total"`
+	DetectedAt time.Time `json:"detected_at"`
+	ApprovedAt time.Time `json:"approved_at,omitempty"` // Set by the CLI when the user forces the deletions
+}
+
+// Approved reports whether the user allowed the deletions after they were withheld
+func (b Block) Approved() bool {
+	return !b.ApprovedAt.IsZero() && !b.ApprovedAt.Before(b.DetectedAt)
+}
+
+// Percent returns the share of the remote files the pass would delete
+func (b Block) Percent() int {
+	if b.Total == 0 {
+		return 0
+	}
+	return b.Deletions * 100 / b.Total
+}
+
+// State holds the withheld deletions of every folder. The agent records blocks and the
+// CLI approves them, both through the file at DefaultPath.
+type State struct {
+	Blocks map[string]Block `json:"blocks"`
+}
+
+// DefaultPath returns the default location of the deletion guard state
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "deletion-guard.json"), nil
+}
+
+// Read loads the state at path, returning an empty state if none was written
+func Read(path string) (*State, error) {
+	state := &State{Blocks: make(map[string]Block)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read deletion guard: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse deletion guard: %w", err)
+	}
+	if state.Blocks == nil {
+		state.Blocks = make(map[string]Block)
+	}
+	return state, nil
+}
+
+// Write stores the state at path
+func Write(path string, state *State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create deletion guard directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal deletion guard: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write deletion guard: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace deletion guard: %w", err)
+	}
+
+	return nil
+}
+
+// Record stores a block for a folder. A block with the same deletions as the recorded one
+// keeps its detection time, so it is reported as a single event.
+func Record(path string, block Block) error {
+	state, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if previous, ok := state.Blocks[block.FolderID]; ok && previous.Deletions == block.Deletions {
+		block.DetectedAt = previous.DetectedAt
+	}
+	state.Blocks[block.FolderID] = block
+	return Write(path, state)
+}
+
+// Clear forgets the block of a folder, if any
+func Clear(path, folderID string) error {
+	state, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := state.Blocks[folderID]; !ok {
+		return nil
+	}
+	delete(state.Blocks, folderID)
+	return Write(path, state)
+}
+
+// Approve allows the withheld deletions of a folder on its next sync. It returns the
+// approved block, or nil when nothing was withheld.
+func Approve(path, folderID string, now time.Time) (*Block, error) {
+	state, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	block, ok := state.Blocks[folderID]
+	if !ok {
+		return nil, nil
+	}
+	block.ApprovedAt = now
+	state.Blocks[folderID] = block
+	if err := Write(path, state); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
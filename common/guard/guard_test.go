@@ -0,0 +1,65 @@
+package guard
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitsExceeded(t *testing.T) {
+	tests := []struct {
+		name      string
+		limits    Limits
+		deletions int
+		total     int
+		want      bool
+	}{
+		{"within limits", Limits{MaxFiles: 10, MaxPercent: 25}, 5, 100, false},
+		{"above max files", Limits{MaxFiles: 10, MaxPercent: 25}, 11, 100, true},
+		{"above max percent", Limits{MaxFiles: 10, MaxPercent: 25}, 3, 10, true},
+		{"at max percent", Limits{MaxPercent: 25}, 25, 100, false},
+		{"no limits", Limits{MaxFiles: -1, MaxPercent: -1}, 1000, 1000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.limits.Exceeded(tt.deletions, tt.total))
+		})
+	}
+}
+
+func TestRecordApproveClear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deletion-guard.json")
+	detected := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	block, err := Approve(path, "docs", detected)
+	assert.NoError(t, err)
+	assert.Nil(t, block)
+
+	assert.NoError(t, Record(path, Block{FolderID: "docs", Deletions: 30, Total: 100, DetectedAt: detected}))
+
+	// The same deletions seen again keep their detection time
+	assert.NoError(t, Record(path, Block{FolderID: "docs", Deletions: 30, Total: 100, DetectedAt: detected.Add(time.Hour)}))
+	state, err := Read(path)
+	assert.NoError(t, err)
+	assert.True(t, state.Blocks["docs"].DetectedAt.Equal(detected))
+	assert.False(t, state.Blocks["docs"].Approved())
+	assert.Equal(t, 30, state.Blocks["docs"].Percent())
+
+	block, err = Approve(path, "docs", detected.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, block.Approved())
+
+	// A different deletion count is a new block that needs a new approval
+	assert.NoError(t, Record(path, Block{FolderID: "docs", Deletions: 40, Total: 100, DetectedAt: detected.Add(time.Hour)}))
+	state, err = Read(path)
+	assert.NoError(t, err)
+	assert.False(t, state.Blocks["docs"].Approved())
+
+	assert.NoError(t, Clear(path, "docs"))
+	state, err = Read(path)
+	assert.NoError(t, err)
+	assert.Empty(t, state.Blocks)
+}
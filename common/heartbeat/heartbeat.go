@@ -0,0 +1,88 @@
+package heartbeat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Interval is how often the agent writes its heartbeat
+const Interval = 30 * time.Second
+
+// OnlineThreshold is how old a heartbeat may be for the device to count as online
+const OnlineThreshold = 3 * Interval
+
+// Heartbeat is the liveness record the agent writes for the local device
+type Heartbeat struct {
+	DeviceID   string    `json:"device_id"`
+	DeviceName string    `json:"device_name"`
+	Version    string    `json:"version"`
+	PID        int       `json:"pid"`
+	Time       time.Time `json:"time"`
+
+	// Restriction describes how the power policy limits transfers, empty when it does not
+	Restriction string `json:"restriction,omitempty"`
+}
+
+// Online reports whether the heartbeat is recent enough for the agent to be running
+func (h *Heartbeat) Online() bool {
+	return IsOnline(h.Time)
+}
+
+// IsOnline reports whether a device last seen at lastSeen counts as online
+func IsOnline(lastSeen time.Time) bool {
+	return !lastSeen.IsZero() && time.Since(lastSeen) < OnlineThreshold
+}
+
+// DefaultPath returns the default location of the heartbeat file
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "heartbeat.json"), nil
+}
+
+// Write stores the heartbeat at path
+func Write(path string, hb *Heartbeat) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create heartbeat directory: %w", err)
+	}
+
+	data, err := json.Marshal(hb)
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write heartbeat: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+// Read loads the heartbeat at path, returning nil if the agent never wrote one
+func Read(path string) (*Heartbeat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read heartbeat: %w", err)
+	}
+
+	var hb Heartbeat
+	if err := json.Unmarshal(data, &hb); err != nil {
+		return nil, fmt.Errorf("failed to parse heartbeat: %w", err)
+	}
+
+	return &hb, nil
+}
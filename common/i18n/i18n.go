@@ -0,0 +1,193 @@
+// Package i18n translates the messages the CLI and the agent show to the user. Messages are
+// written in English, which doubles as the key into the catalog of each other language; a
+// message missing from a catalog is shown in English.
+package i18n
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Supported languages
+const (
+	English    = "en"
+	Portuguese = "pt"
+)
+
+// catalogs maps each language other than English to its translations, keyed by the English message
+var catalogs = map[string]map[string]string{
+	Portuguese: portuguese,
+}
+
+var (
+	current = English
+	mu      sync.RWMutex
+)
+
+// Languages returns the supported languages, English first
+func Languages() []string {
+	return []string{English, Portuguese}
+}
+
+// Supported reports whether lang, once normalized, is a supported language
+func Supported(lang string) bool {
+	lang = Normalize(lang)
+	return lang == English || catalogs[lang] != nil
+}
+
+// Normalize reduces a language tag or locale such as "pt-BR" or "pt_BR.UTF-8" to its
+// language code. The C and POSIX locales, and empty values, give "".
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if lang == "c" || lang == "posix" {
+		return ""
+	}
+	return lang
+}
+
+// Resolve picks the language to use: the one asked for with --lang, then the SYNC_MANAGER_LANG
+// environment variable, the user's preference and the locale of the environment. Values naming
+// no supported language are passed over, and English is used when none is left.
+func Resolve(flag, preference string) string {
+	candidates := []string{flag, os.Getenv("SYNC_MANAGER_LANG"), preference}
+	// The first locale variable set wins, as in setlocale
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			candidates = append(candidates, value)
+			break
+		}
+	}
+
+	for _, candidate := range candidates {
+		if lang := Normalize(candidate); lang != "" && Supported(lang) {
+			return lang
+		}
+	}
+	return English
+}
+
+// LangFromArgs returns the value of a --lang flag in args, which must be known before the
+// commands are built so their help is translated
+func LangFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--lang="); ok {
+			return value
+		}
+		if arg == "--lang" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// Set changes the language messages are translated to
+func Set(lang string) {
+	lang = Normalize(lang)
+	if !Supported(lang) {
+		lang = English
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	current = lang
+}
+
+// Current returns the language messages are translated to
+func Current() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// T translates a message to the current language
+func T(msg string) string {
+	mu.RLock()
+	catalog := catalogs[current]
+	mu.RUnlock()
+
+	if translated, ok := catalog[msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Strings translates each of msgs, such as the headers of a table
+func Strings(msgs ...string) []string {
+	translated := make([]string, len(msgs))
+	for i, msg := range msgs {
+		translated[i] = T(msg)
+	}
+	return translated
+}
+
+// Yes reports whether the answer to a yes/no prompt means yes, in English or the current language
+func Yes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes" || answer == T("y") || answer == T("yes")
+}
+
+// No reports whether the answer to a yes/no prompt means no, in English or the current language
+func No(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "n" || answer == "no" || answer == T("n") || answer == T("no")
+}
+
+// Sprintf formats a message after translating its format
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Printf prints a message to stdout after translating its format
+func Printf(format string, args ...any) {
+	fmt.Printf(T(format), args...)
+}
+
+// Fprintf writes a message to w after translating its format
+func Fprintf(w io.Writer, format string, args ...any) {
+	fmt.Fprintf(w, T(format), args...)
+}
+
+// Print prints a translated message to stdout, such as a prompt
+func Print(msg string) {
+	fmt.Print(T(msg))
+}
+
+// Println prints a translated message to stdout, followed by a newline
+func Println(msg string) {
+	fmt.Println(T(msg))
+}
+
+// Fprintln writes a translated message to w, followed by a newline
+func Fprintln(w io.Writer, msg string) {
+	fmt.Fprintln(w, T(msg))
+}
+
+// NewError returns an error whose message is translated each time it is read, for sentinel
+// errors created before the language is known. Each call returns a distinct error.
+func NewError(msg string) error {
+	return &messageError{msg: msg}
+}
+
+// messageError is an error with a message translated when read
+type messageError struct {
+	msg string
+}
+
+func (e *messageError) Error() string {
+	return T(e.msg)
+}
+
+// Errorf returns an error whose message is translated before formatting. Wrapped errors are
+// kept, so errors.Is and errors.As see through it.
+func Errorf(format string, args ...any) error {
+	return fmt.Errorf(T(format), args...)
+}
@@ -0,0 +1,119 @@
+package i18n
+
+import (
+	"errors"
+	"io/fs"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// use switches to lang for the rest of the test
+func use(t *testing.T, lang string) {
+	Set(lang)
+	t.Cleanup(func() { Set(English) })
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"pt":          "pt",
+		"pt-BR":       "pt",
+		"pt_BR.UTF-8": "pt",
+		" EN_us ":     "en",
+		"de@euro":     "de",
+		"C":           "",
+		"POSIX":       "",
+		"":            "",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, Normalize(in), in)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	for _, name := range []string{"SYNC_MANAGER_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		t.Setenv(name, "")
+	}
+	assert.Equal(t, English, Resolve("", ""))
+
+	// The first locale variable set wins, even naming an unsupported language
+	t.Setenv("LANG", "pt_BR.UTF-8")
+	assert.Equal(t, Portuguese, Resolve("", ""))
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+	assert.Equal(t, English, Resolve("", ""))
+
+	// The preference beats the locale, the environment variable the preference and --lang all of them
+	assert.Equal(t, Portuguese, Resolve("", "pt"))
+	t.Setenv("SYNC_MANAGER_LANG", "en")
+	assert.Equal(t, English, Resolve("", "pt"))
+	assert.Equal(t, Portuguese, Resolve("pt-BR", "en"))
+
+	// An unsupported value is passed over
+	assert.Equal(t, English, Resolve("fr", "pt"))
+}
+
+func TestLangFromArgs(t *testing.T) {
+	assert.Equal(t, "pt", LangFromArgs([]string{"status", "--lang", "pt"}))
+	assert.Equal(t, "en", LangFromArgs([]string{"--lang=en", "status"}))
+	assert.Equal(t, "", LangFromArgs([]string{"status", "--lang"}))
+	assert.Equal(t, "", LangFromArgs([]string{"exec", "--", "--lang", "pt"}))
+}
+
+func TestTranslate(t *testing.T) {
+	assert.Equal(t, "Never", T("Never"))
+
+	use(t, "pt_BR")
+	assert.Equal(t, Portuguese, Current())
+	assert.Equal(t, "Nunca", T("Never"))
+	assert.Equal(t, []string{"Nome", "Caminho"}, Strings("Name", "Path"))
+	assert.Equal(t, "há 2 dias", Sprintf("%s ago", "2 dias"))
+
+	// A message missing from the catalog is shown in English
+	assert.Equal(t, "Not in any catalog", T("Not in any catalog"))
+
+	// An unsupported language falls back to English
+	Set("fr")
+	assert.Equal(t, English, Current())
+}
+
+func TestErrors(t *testing.T) {
+	use(t, Portuguese)
+
+	err := Errorf("failed to load config: %w", fs.ErrNotExist)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Equal(t, "falha ao carregar a configuração: file does not exist", err.Error())
+
+	// A sentinel is translated when read, after the language changed
+	notFound := NewError("user not found")
+	assert.Equal(t, "usuário não encontrado", notFound.Error())
+	Set(English)
+	assert.Equal(t, "user not found", notFound.Error())
+	assert.True(t, errors.Is(Errorf("lookup: %w", notFound), notFound))
+	assert.False(t, errors.Is(NewError("user not found"), notFound))
+}
+
+func TestYesNo(t *testing.T) {
+	assert.True(t, Yes("Y"))
+	assert.True(t, Yes(" yes "))
+	assert.False(t, Yes("sim"))
+	assert.True(t, No("n"))
+	assert.False(t, No(""))
+
+	// English answers are still understood in Portuguese
+	use(t, Portuguese)
+	assert.True(t, Yes("s"))
+	assert.True(t, Yes("Sim"))
+	assert.True(t, Yes("yes"))
+	assert.True(t, No("não"))
+	assert.True(t, No("no"))
+}
+
+func TestCatalogsKeepFormatVerbs(t *testing.T) {
+	verbs := regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+	for lang, catalog := range catalogs {
+		for msg, translated := range catalog {
+			assert.Equal(t, verbs.FindAllString(msg, -1), verbs.FindAllString(translated, -1), "%s: %q", lang, msg)
+		}
+	}
+}
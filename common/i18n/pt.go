@@ -0,0 +1,1270 @@
+package i18n
+
+// portuguese holds the Portuguese translations, keyed by the English message
+var portuguese = map[string]string{
+	"\nConfiguration complete!":                                 "\nConfiguração concluída!",
+	"\nConfiguring Amazon S3 storage:":                          "\nConfigurando o armazenamento Amazon S3:",
+	"\nConfiguring Google Cloud Storage:":                       "\nConfigurando o armazenamento Google Cloud Storage:",
+	"\nConfiguring MinIO storage:":                              "\nConfigurando o armazenamento MinIO:",
+	"\nConfiguring OneDrive storage:":                           "\nConfigurando o armazenamento OneDrive:",
+	"\nConfiguring local filesystem storage:":                   "\nConfigurando o armazenamento no sistema de arquivos local:",
+	"\nExcluded in %s on this device: %s\n":                     "\nExcluídos em %s neste dispositivo: %s\n",
+	"\nExtensions since the agent started:":                     "\nExtensões desde o início do agente:",
+	"\nFolders:":                                                "\nPastas:",
+	"\nGCS configuration complete!":                             "\nConfiguração do GCS concluída!",
+	"\nInitialization complete!":                                "\nInicialização concluída!",
+	"\nLargest files:":                                          "\nMaiores arquivos:",
+	"\nLocal storage configuration complete!":                   "\nConfiguração do armazenamento local concluída!",
+	"\nLongest:":                                                "\nMais demoradas:",
+	"\nMax Concurrency: %d\n":                                   "\nConcorrência máxima: %d\n",
+	"\nMinIO configuration complete!":                           "\nConfiguração do MinIO concluída!",
+	"\nMost bytes:":                                             "\nMais bytes:",
+	"\nOneDrive configuration complete!":                        "\nConfiguração do OneDrive concluída!",
+	"\nRepair complete.":                                        "\nReparo concluído.",
+	"\nS3 configuration complete!":                              "\nConfiguração do S3 concluída!",
+	"\nServer Connection: %s\n":                                 "\nConexão com o servidor: %s\n",
+	"\nSimulating log following (will exit after 3 entries)...": "\nSimulando o acompanhamento do log (encerra após 3 entradas)...",
+	"\nStep 2: Configure Sync Settings":                         "\nPasso 2: Configurar a sincronização",
+	"\nStep 3: Add Folders to Sync":                             "\nPasso 3: Adicionar pastas para sincronizar",
+	"\nStorage Target %s: %s\n":                                 "\nDestino de armazenamento %s: %s\n",
+	"\nSynced Folders:":                                         "\nPastas sincronizadas:",
+	"   Case collisions: %d, not downloaded (see 'sync-manager conflicts list')\n": "   Colisões de maiúsculas/minúsculas: %d, não baixadas (veja 'sync-manager conflicts list')\n",
+	"   Conflicts: %s\n":                              "   Conflitos: %s\n",
+	"   Interval: %s\n":                               "   Intervalo: %s\n",
+	"   Last error: %s\n":                             "   Último erro: %s\n",
+	"   Last sync: %s\n":                              "   Última sincronização: %s\n",
+	"   Next sync: %s\n":                              "   Próxima sincronização: %s\n",
+	"   Path: %s\n":                                   "   Caminho: %s\n",
+	"   Remote prefix: %s/\n":                         "   Prefixo remoto: %s/\n",
+	"   State: %s\n":                                  "   Estado: %s\n",
+	"   Subscribed: read-only, local changes %s\n":    "   Assinada: somente leitura, alterações locais %s\n",
+	"   Transferred today: %s\n":                      "   Transferido hoje: %s\n",
+	"   case collision  %s (not downloaded)\n":        "   colisão de maiúsculas/minúsculas  %s (não baixado)\n",
+	"   conflict copy   %s\n":                         "   cópia de conflito   %s\n",
+	"  %d failed":                                     "  %d com falha",
+	"  %s\n":                                          "  %s\n",
+	"  %s (targets: %s)\n":                            "  %s (destinos: %s)\n",
+	"  %s different on both sides\n":                  "  %s diferentes nos dois lados\n",
+	"  %s identical on both sides\n":                  "  %s idênticos nos dois lados\n",
+	"  %s only here, uploaded\n":                      "  %s só aqui, enviados\n",
+	"  %s only remote, downloaded\n":                  "  %s só no remoto, baixados\n",
+	"  App Folder: %v\n":                              "  Pasta do Aplicativo: %v\n",
+	"  Bucket: %s\n":                                  "  Bucket: %s\n",
+	"  Client ID: %s\n":                               "  ID do Cliente: %s\n",
+	"  Conflicts:  %d\n":                              "  Conflitos:   %d\n",
+	"  Contents are lost when the process exits":      "  O conteúdo se perde quando o processo termina",
+	"  Credentials File: %s\n":                        "  Arquivo de credenciais: %s\n",
+	"  Deleted:    %s\n":                              "  Removidos:   %s\n",
+	"  Downloaded: %s, %s\n":                          "  Baixados:    %s, %s\n",
+	"  Drive ID: %s\n":                                "  ID do Drive: %s\n",
+	"  Endpoint: %s\n":                                "  Endpoint: %s\n",
+	"  Error Rate: %g\n":                              "  Taxa de erros: %g\n",
+	"  Error:      %s\n":                              "  Erro:        %s\n",
+	"  Errors:     %d\n":                              "  Erros:       %d\n",
+	"  Failover Endpoints: %s\n":                      "  Endpoints de Failover: %s\n",
+	"  Fix: %s\n":                                     "  Correção: %s\n",
+	"  Keep it after reboots:  echo %s | sudo tee %s": "  Manter após reinicializações:  echo %s | sudo tee %s",
+	"  Latency: %s\n":                                 "  Latência: %s\n",
+	"  Name: %s\n":                                    "  Nome: %s\n",
+	"  Old versions are deleted after %s\n":           "  Versões antigas são excluídas após %s\n",
+	"  Old versions move to %s after %s\n":            "  Versões antigas vão para %s após %s\n",
+	"  Path Style: %v\n":                              "  Path Style: %v\n",
+	"  Plugin Settings: %s\n":                         "  Configurações do Plugin: %s\n",
+	"  Project ID: %s\n":                              "  ID do projeto: %s\n",
+	"  Queued:     %s for upload\n":                   "  Na fila:     %s para envio\n",
+	"  Raise the limit:        sudo sysctl %s":        "  Aumentar o limite:     sudo sysctl %s",
+	"  Region: %s\n":                                  "  Região: %s\n",
+	"  Removable Drive: volume %s\n":                  "  Disco Removível: volume %s\n",
+	"  Removable Drive: yes":                          "  Disco Removível: sim",
+	"  Root Directory: %s\n":                          "  Diretório raiz: %s\n",
+	"  Run 'sync-manager doctor --fix' to update the database from the configuration": "  Execute 'sync-manager doctor --fix' para atualizar o banco de dados a partir da configuração",
+	"  Scanned:    %s\n":              "  Verificados: %s\n",
+	"  Skipped:    %s\n":              "  Ignorados:   %s\n",
+	"  Started:    %s (took %s)\n":    "  Início:      %s (levou %s)\n",
+	"  Tenant: %s\n":                  "  Locatário: %s\n",
+	"  Transport: %s\n":               "  Transporte: %s\n",
+	"  Uploaded:   %s, %s\n":          "  Enviados:    %s, %s\n",
+	"  Use SSL: %v\n":                 "  Usar SSL: %v\n",
+	"  none yet":                      "  nenhuma ainda",
+	" (failover, primary %s is down)": " (failover, o primário %s está fora do ar)",
+	" (following)":                    " (acompanhando)",
+	"%d agents are running on this configuration and upload the same files:": "%d agentes estão rodando nesta configuração e enviam os mesmos arquivos:",
+	"%d bytes":                "%d bytes",
+	"%d bytes/sec":            "%d bytes/s",
+	"%d of %d watches in use": "%d de %d watches em uso",
+	"%d watches in use":       "%d watches em uso",
+	"%s %3.0f%%  %d/%d files  %s/%s  %s  ETA %s": "%s %3.0f%%  %d/%d arquivos  %s/%s  %s  ETA %s",
+	"%s (global)":            "%s (global)",
+	"%s ago":                 "há %s",
+	"%s does not answer: %v": "%s não responde: %v",
+	"%s does not exist":      "%s não existe",
+	"%s failed to transfer, see the agent logs": "%s não foram transferidos, veja os logs do agente",
+	"%s failed.\n":                      "%s falhou.\n",
+	"%s is a directory":                 "%s é um diretório",
+	"%s is already synced by folder %s": "%s já é sincronizado pela pasta %s",
+	"%s is also synced by folder %s, so the agent does not sync it": "%s também é sincronizado pela pasta %s, então o agente não o sincroniza",
+	"%s is inside %s of folder %s, so the agent does not sync it":   "%s está dentro de %s da pasta %s, então o agente não o sincroniza",
+	"%s is inside folder %s (%s), which already syncs its files; run 'sync-manager config set nested_folders exclude' to sync it as a separate folder that %s leaves out": "%s está dentro da pasta %s (%s), que já sincroniza seus arquivos; execute 'sync-manager config set nested_folders exclude' para sincronizá-lo como uma pasta separada que %s deixa de fora",
+	"%s is not a directory":                                              "%s não é um diretório",
+	"%s is not a directory or a regular file":                            "%s não é um diretório nem um arquivo comum",
+	"%s is not a subdirectory of the folder, give a path relative to %s": "%s não é um subdiretório da pasta, informe um caminho relativo a %s",
+	"%s is not an archived file":                                         "%s não é um arquivo arquivado",
+	"%s is not empty; restore into an empty directory":                   "%s não está vazio; restaure em um diretório vazio",
+	"%s is this device":                                                  "%s é este dispositivo",
+	"%s removed.\n":                                                      "%s removido.\n",
+	"%s storage does not support lifecycle policies":                     "o armazenamento %s não suporta políticas de ciclo de vida",
+	"%s would be removed.\n":                                             "%s seria removido.\n",
+	"%w: install secret-tool (libsecret) to use the Secret Service":      "%w: instale o secret-tool (libsecret) para usar o Secret Service",
+	"%w; restoring the previous key also failed: %v":                     "%w; restaurar a chave anterior também falhou: %v",
+	"(default)":      "(padrão)",
+	"(no extension)": "(sem extensão)",
+	"(this device)":  "(este dispositivo)",
+	"(transferring)": "(transferindo)",
+	", %d directories polled for changes instead:": ", %d diretórios verificados periodicamente em vez disso:",
+	", %s pending":                       ", %s pendentes",
+	", syncing %s":                       ", sincronizando %s",
+	"--dry-run requires --initial-merge": "--dry-run requer --initial-merge",
+	"--initial-merge cannot be used with --subscribe, subscribed folders always keep the published copy": "--initial-merge não pode ser usado com --subscribe, pastas assinadas sempre mantêm a cópia publicada",
+	"--initial-merge requires --folder-id, the ID of the remote folder to merge with":                    "--initial-merge requer --folder-id, o ID da pasta remota com a qual mesclar",
+	"--initial-merge requires a two-way mirror folder":                                                   "--initial-merge requer uma pasta espelhada nos dois sentidos",
+	"--local-changes requires --subscribe":                                                               "--local-changes requer --subscribe",
+	"--password-stdin requires --email":                                                                  "--password-stdin requer --email",
+	"--since must be a positive period":                                                                  "--since deve ser um período positivo",
+	"--subscribe already names the remote folder, drop --folder-id":                                      "--subscribe já indica a pasta remota, remova --folder-id",
+	"1. MinIO (local development)":                                                                       "1. MinIO (desenvolvimento local)",
+	"2. Amazon S3":                                                                                       "2. Amazon S3",
+	"3. Google Cloud Storage":                                                                            "3. Google Cloud Storage",
+	"4. Local filesystem":                                                                                "4. Sistema de arquivos local",
+	"5. Microsoft OneDrive":                                                                              "5. Microsoft OneDrive",
+	"API endpoint is not configured, use --endpoint":                                                     "o endpoint da API não está configurado, use --endpoint",
+	"API endpoint of the Sync Manager server":                                                            "Endpoint da API do servidor do Sync Manager",
+	`API tokens let scripts and CI jobs authenticate as the active user. Only a hash of
+each token is stored, so a token is shown once, when it is created.`: `Tokens de API permitem que scripts e jobs de CI se autentiquem como o usuário ativo. Só um hash
+de cada token é guardado, então o token é exibido uma única vez, ao ser criado.`,
+	"Account email":                                      "E-mail da conta",
+	"Activity update would be shown here...":             "A atualização da atividade seria exibida aqui...",
+	"Adaptive Concurrency: from %d, between %d and %d\n": "Concorrência adaptativa: a partir de %d, entre %d e %d\n",
+	"Add a folder, or a single file, to sync":            "Adicionar uma pasta, ou um único arquivo, para sincronizar",
+	`Add a folder, or a single file, to sync.
+
+To sync a folder that already has content with a folder synced from another device,
+pass that folder's ID with --folder-id along with --two-way. --initial-merge picks
+which copy wins for the files that differ on both sides during the first sync:
+keep-both, prefer-newest, prefer-local or prefer-remote. Files identical on both
+sides are left alone. The reconciliation plan is shown before the folder is added;
+--dry-run only shows it.
+
+--target picks the storage target the folder syncs to; without it the folder uses
+the first target in the configuration.
+
+--subscribe makes the folder a read-only copy of the remote folder another device
+publishes under the given prefix, its folder ID: published changes are downloaded
+and nothing is ever uploaded. --local-changes decides what happens to files changed
+here: revert (the default) restores the published copy and removes files added
+locally; flag keeps the change and records it until the publisher updates the file.`: `Adicionar uma pasta, ou um único arquivo, para sincronizar.
+
+Para sincronizar uma pasta que já tem conteúdo com uma pasta sincronizada de outro dispositivo,
+passe o ID dessa pasta com --folder-id junto com --two-way. --initial-merge escolhe
+qual cópia vence nos arquivos que diferem nos dois lados durante a primeira sincronização:
+keep-both, prefer-newest, prefer-local ou prefer-remote. Arquivos idênticos nos dois
+lados não são tocados. O plano de reconciliação é exibido antes de a pasta ser adicionada;
+--dry-run apenas o exibe.
+
+--target escolhe o destino de armazenamento da pasta; sem ele a pasta usa
+o primeiro destino da configuração.
+
+--subscribe torna a pasta uma cópia somente leitura da pasta remota que outro dispositivo
+publica sob o prefixo informado, o ID dela: as mudanças publicadas são baixadas
+e nada é enviado. --local-changes decide o que acontece com arquivos alterados
+aqui: revert (o padrão) restaura a cópia publicada e remove arquivos adicionados
+localmente; flag mantém a alteração e a registra até o publicador atualizar o arquivo.`,
+	`Add a lifecycle rule to the bucket that moves replaced versions of a folder's files to a
+cheaper storage class and optionally deletes them later. Running the command again for
+the same folder replaces its rule. The bucket must have versioning enabled for old
+versions to be kept.`: `Adiciona ao bucket uma regra de ciclo de vida que move as versões substituídas dos arquivos de uma
+pasta para uma classe de armazenamento mais barata e, opcionalmente, as exclui depois. Executar o
+comando de novo para a mesma pasta substitui a regra dela. O bucket precisa ter o versionamento
+ativado para que as versões antigas sejam mantidas.`,
+	"Add a local directory to the folder as PREFIX=PATH; its files are synced under PREFIX (can be specified multiple times)": "Adicionar um diretório local à pasta como PREFIXO=CAMINHO; seus arquivos são sincronizados sob PREFIXO (pode ser especificado várias vezes)",
+	"Add an exclude rule":  "Adicionar uma regra de exclusão",
+	"Added rule: %s\n":     "Regra adicionada: %s\n",
+	"Agent Version:  %s\n": "Versão do agente:  %s\n",
+	"Agent is not running. Start it with 'sync-manager start'.": "O agente não está em execução. Inicie-o com 'sync-manager start'.",
+	"Agent is running in the background.":                       "O agente está em execução em segundo plano.",
+	"Agent running since %s\n":                                  "Agente em execução desde %s\n",
+	"Agent started in the background.":                          "Agente iniciado em segundo plano.",
+	"Agent stopped.":                                            "Agente parado.",
+	"Algorithm hashing the files uploaded from now on: sha256 or blake3, which spreads large files over every core; empty uses sha256": "Algoritmo de hash dos arquivos enviados daqui em diante: sha256 ou blake3, que distribui arquivos grandes por todos os núcleos; vazio usa sha256",
+	"All folders are now in a consistent state.":                                      "Todas as pastas estão agora em um estado consistente.",
+	"Allow remote deletions held back by the deletion guard":                          "Permitir exclusões remotas retidas pela proteção contra exclusões",
+	"Allowing removal of %s in %s\n":                                                  "Permitindo a remoção de %s em %s\n",
+	"Another agent is already running on this configuration: %s, last seen %s ago.\n": "Outro agente já está rodando nesta configuração: %s, visto pela última vez há %s.\n",
+	`Apply a bundle created with 'config export'. Storage settings are replaced,
+folders are added or updated by ID and this device's identity is kept.`: `Aplica um pacote criado com 'config export'. As configurações de armazenamento são substituídas,
+as pastas são adicionadas ou atualizadas pelo ID e a identidade deste dispositivo é mantida.`,
+	"Apply a lifecycle policy to old versions of a folder's files": "Aplicar uma política de ciclo de vida às versões antigas dos arquivos de uma pasta",
+	"Apply the rule on this device only":                           "Aplicar a regra somente neste dispositivo",
+	"Apply the rule to every folder":                               "Aplicar a regra a todas as pastas",
+	"Apply the rule to one folder":                                 "Aplicar a regra a uma pasta",
+	"Archived: %s, %s freed\n":                                     "Arquivados: %s, %s liberados\n",
+	"Are you sure you want to unlink device %s (%s)? (y/n): ":      "Tem certeza de que deseja desvincular o dispositivo %s (%s)? (s/n): ",
+	`Ask the agent to sync one folder, or all of them, right away. Only one sync runs at a
+time: a request covered by the running sync joins it, any other runs once it ends.
+With --restart the running sync is cancelled and started over instead.
+
+The command shows the progress of the sync until it ends and its uploads are done, then
+prints the summary of each folder it synced and fails if any of them did not succeed.
+With --detach it returns as soon as the agent has the request.`: `Pede ao agente para sincronizar uma pasta, ou todas, imediatamente. Só uma sincronização roda
+por vez: um pedido coberto pela sincronização em andamento se junta a ela, qualquer outro roda quando
+ela termina. Com --restart a sincronização em andamento é cancelada e recomeçada.
+
+O comando mostra o progresso da sincronização até ela terminar e seus envios serem concluídos,
+depois imprime o resumo de cada pasta sincronizada e falha se alguma delas não tiver sucesso.
+Com --detach ele retorna assim que o agente recebe o pedido.`,
+	`Ask the agent to sync only a subdirectory of a folder: it walks that subtree for local
+changes, lists the remote files under it and reconciles them, leaving the rest of the folder
+for its next sync. Useful after restoring files into a directory or fixing its excludes,
+without walking the whole folder.
+
+Like sync-now, it follows the sync until it ends and its uploads are done, then prints its
+summary. With --detach it returns as soon as the agent has the request.`: `Pede ao agente para sincronizar só um subdiretório de uma pasta: ele percorre essa subárvore em busca
+de alterações locais, lista os arquivos remotos dentro dela e os reconcilia, deixando o resto da pasta
+para a próxima sincronização. Útil depois de restaurar arquivos em um diretório ou corrigir suas
+exclusões, sem percorrer a pasta inteira.
+
+Como o sync-now, acompanha a sincronização até ela terminar e seus envios serem concluídos, depois
+imprime o resumo. Com --detach ele retorna assim que o agente recebe o pedido.`,
+	"Average rate: %s\n": "Taxa média: %s\n",
+	"Backup mode: keep one snapshot for each of the last N days":   "Modo backup: manter um snapshot para cada um dos últimos N dias",
+	"Backup mode: keep one snapshot for each of the last N months": "Modo backup: manter um snapshot para cada um dos últimos N meses",
+	"Backup mode: keep one snapshot for each of the last N weeks":  "Modo backup: manter um snapshot para cada uma das últimas N semanas",
+	"Backup mode: keep the N most recent snapshots":                "Modo backup: manter os N snapshots mais recentes",
+	"Backup mode: pack files of up to N bytes together into shared objects, cutting the requests of folders with many tiny files; 0 stores each file alone": "Modo backup: empacota arquivos de até N bytes em objetos compartilhados, reduzindo as requisições de pastas com muitos arquivos pequenos; 0 armazena cada arquivo separadamente",
+	"Batch started %s: %d/%d files, %s/%s, %s\n": "Lote iniciado %s: %d/%d arquivos, %s/%s, %s\n",
+	"Break the month down by day":                "Detalhar o mês por dia",
+	"CA %s":                                      "CA %s",
+	"Cancel the sync the agent is running and start over":                  "Cancelar a sincronização em andamento no agente e recomeçar",
+	"Cancelling it to start over.":                                         "Cancelando-a para recomeçar.",
+	"Change the folder records in the database to match the configuration": "Alterar os registros de pastas no banco de dados para corresponder à configuração",
+	"Change the name of the current device.":                               "Alterar o nome do dispositivo atual.",
+	"Check and repair synchronization state":                               "Verificar e reparar o estado da sincronização",
+	`Check that the agent is running and that no other agent runs on the same configuration,
+that the folders it syncs exist, that the folder records in the database match the
+configuration, and that the system leaves the agent enough file watches. Directories past
+the watch limit are polled for changes, which is slower; doctor prints the sysctl commands
+that raise the limit.
+
+The configuration is what the agent syncs, so --fix changes the database to match it.`: `Verifica se o agente está em execução e se nenhum outro agente roda na mesma configuração,
+se as pastas que ele sincroniza existem, se os registros de pastas no banco de dados correspondem
+à configuração e se o sistema deixa ao agente watches de arquivos suficientes. Diretórios além do limite de watches são verificados periodicamente, o que é
+mais lento; o doctor exibe os comandos sysctl que aumentam o limite.
+
+A configuração é o que o agente sincroniza, então --fix altera o banco de dados para corresponder a ela.`,
+	"Check that the remote copy of a folder matches the local files": "Verificar se a cópia remota de uma pasta corresponde aos arquivos locais",
+	`Check the configuration beyond what loading it does: storage and API endpoints that do
+not answer, folders whose paths do not exist or overlap, exclude patterns that match no
+file or every file, and credentials written in plain text instead of ${VAR} or file:
+references. Each warning comes with the change that fixes it.
+
+Without an argument the configuration in use is checked. The command exits with 0 when
+there is nothing to report, 1 when there are warnings and 2 when the configuration
+cannot be loaded, so it can run in CI.`: `Verifica a configuração além do que o carregamento verifica: endpoints de armazenamento e
+da API que não respondem, pastas cujos caminhos não existem ou se sobrepõem, padrões de
+exclusão que não casam com nenhum arquivo ou casam com todos, e credenciais escritas em
+texto puro em vez de referências ${VAR} ou file:. Cada aviso traz a alteração que o corrige.
+
+Sem argumento, verifica a configuração em uso. O comando sai com 0 quando não há nada a
+relatar, 1 quando há avisos e 2 quando a configuração não pode ser carregada, para poder
+rodar em CI.`,
+	"Check the configuration for likely mistakes": "Verificar a configuração em busca de prováveis erros",
+	"Check the local setup and suggest fixes":     "Verificar a instalação local e sugerir correções",
+	"Cloud Placeholders: %s\n":                    "Placeholders de nuvem: %s\n",
+	`Compare the local files of a folder with their remote copies without downloading them.
+Each file is checked with a single metadata request; files missing remotely or whose
+size or content hash differ are reported. Pass paths relative to the folder to check
+only those files.`: `Compara os arquivos locais de uma pasta com suas cópias remotas sem baixá-los.
+Cada arquivo é verificado com uma única requisição de metadados; arquivos ausentes no remoto ou cujo
+tamanho ou hash de conteúdo diferem são relatados. Passe caminhos relativos à pasta para verificar
+somente esses arquivos.`,
+	"Configuration %s set to %s\n":                               "Configuração %s definida como %s\n",
+	"Configuration %s set to %s and applied by the agent\n":      "Configuração %s definida como %s e aplicada pelo agente\n",
+	"Configuration appears to be already initialized.":           "A configuração parece já estar inicializada.",
+	"Configuration exported to %s\n":                             "Configuração exportada para %s\n",
+	"Configuration profile to use (default: the active profile)": "Perfil de configuração a usar (padrão: o perfil ativo)",
+	"Configuration reset to default values.":                     "Configuração redefinida para os valores padrão.",
+	"Configure synchronization settings for a folder":            "Configurar a sincronização de uma pasta",
+	"Connected Devices:":                                         "Dispositivos conectados:",
+	"Copy it now, it is not shown again.":                        "Copie-o agora, ele não será exibido de novo.",
+	"Copy kept on the first sync for files that differ on both sides: keep-both, prefer-newest, prefer-local or prefer-remote; requires --folder-id": "Cópia mantida na primeira sincronização para arquivos que diferem nos dois lados: keep-both, prefer-newest, prefer-local ou prefer-remote; requer --folder-id",
+	"Copy kept when a file changed on both sides: keep-both, prefer-local, prefer-remote or prefer-newest; empty uses keep-both":                     "Cópia mantida quando um arquivo mudou nos dois lados: keep-both, prefer-local, prefer-remote ou prefer-newest; vazio usa keep-both",
+	"Create a configuration profile":                               "Criar um perfil de configuração",
+	"Create a default sync folder in your home directory? [Y/n]: ": "Criar uma pasta de sincronização padrão no seu diretório pessoal? [S/n]: ",
+	"Create a local user":                                          "Criar um usuário local",
+	`Create a profile with the default settings. It gets its own device identity
+the first time it is used; configure it with 'sync-manager --profile <name> config set'.`: `Cria um perfil com as configurações padrão. Ele ganha sua própria identidade de dispositivo
+na primeira vez em que é usado; configure-o com 'sync-manager --profile <nome> config set'.`,
+	"Create an API token":                "Criar um token de API",
+	"Created":                            "Criado",
+	"Created storage directory at: %s\n": "Diretório de armazenamento criado em: %s\n",
+	"Created sync directory at: %s\n":    "Diretório de sincronização criado em: %s\n",
+	"Cron expression of when the folder syncs (e.g. \"0 2 * * *\"), overriding the interval; empty syncs every interval": "Expressão cron de quando a pasta sincroniza (ex.: \"0 2 * * *\"), substituindo o intervalo; vazia sincroniza a cada intervalo",
+	"Current Configuration:":                                     "Configuração atual:",
+	"Database Encryption: %v\n":                                  "Criptografia do banco de dados: %v\n",
+	"Database encrypted with a new key (%d values rewritten).\n": "Banco de dados criptografado com uma nova chave (%d valores regravados).\n",
+	"Database: %s (%s)\n":                                        "Banco de dados: %s (%s)\n",
+	"Database: local SQLite file":                                "Banco de dados: arquivo SQLite local",
+	"Day":                                                        "Dia",
+	"Days after a version is replaced before it is deleted":      "Dias após uma versão ser substituída até ela ser excluída",
+	"Days after a version is replaced before it moves to --transition-class": "Dias após uma versão ser substituída até ela ir para --transition-class",
+	"Days soft-deleted rows are kept, 0 to keep them all":                    "Dias em que linhas excluídas logicamente são mantidas, 0 para manter todas",
+	"Days sync events are kept, 0 to keep them all":                          "Dias em que os eventos de sincronização são mantidos, 0 para manter todos",
+	"Delete snapshots outside the retention policy":                          "Excluir snapshots fora da política de retenção",
+	`Delete the snapshots that the folder's retention policy does not keep, along with
+any stored content no remaining snapshot uses. The --keep-* flags override the configured policy.`: `Exclui os snapshots que a política de retenção da pasta não mantém, junto com
+qualquer conteúdo armazenado que nenhum snapshot restante usa. As flags --keep-* substituem a política configurada.`,
+	"Deletion guard: %s would remove %d of %d remote files (%d%%), held back since %s; check the folder and run 'sync-manager sync --force' to allow": "Proteção contra exclusões: %s removeria %d de %d arquivos remotos (%d%%), retido desde %s; verifique a pasta e execute 'sync-manager sync --force' para permitir",
+	"Deletion guard: removal of %d remote files in %s allowed, applied on the next sync":                                                              "Proteção contra exclusões: remoção de %d arquivos remotos em %s permitida, aplicada na próxima sincronização",
+	"Device": "Dispositivo",
+	"Device %s is no longer trusted for LAN sync\n": "O dispositivo %s não é mais confiável para a sincronização na LAN\n",
+	"Device %s is trusted for LAN sync\n":           "O dispositivo %s é confiável para a sincronização na LAN\n",
+	"Device %s registered (token expires %s)\n":     "Dispositivo %s registrado (o token expira em %s)\n",
+	"Device ID":                          "ID do dispositivo",
+	"Device ID:      %s\n":               "ID do dispositivo: %s\n",
+	"Device ID: %s\n":                    "ID do dispositivo: %s\n",
+	"Device ID: %s\nFingerprint: %s\n\n": "ID do dispositivo: %s\nImpressão digital: %s\n\n",
+	"Device Information:":                "Informações do dispositivo:",
+	"Device Name: %s\n":                  "Nome do dispositivo: %s\n",
+	"Device not yet registered, generating device ID...": "Dispositivo ainda não registrado, gerando o ID do dispositivo...",
+	"Device renamed from '%s' to '%s'.\n":                "Dispositivo renomeado de '%s' para '%s'.\n",
+	"Device successfully unlinked.":                      "Dispositivo desvinculado com sucesso.",
+	`Devices on the same local network fetch files from each other over TLS. Each
+device has its own certificate, and only talks to the devices whose certificate
+fingerprints it trusts. Run 'lan id' on each device and 'lan trust' with the
+result on the others, then 'config set lan.enabled true'.`: `Dispositivos na mesma rede local buscam arquivos uns dos outros por TLS. Cada
+dispositivo tem seu próprio certificado e só conversa com os dispositivos cujas impressões
+digitais de certificado ele confia. Execute 'lan id' em cada dispositivo e 'lan trust' com o
+resultado nos outros, depois 'config set lan.enabled true'.`,
+	"Disable synchronization for a folder": "Desativar a sincronização de uma pasta",
+	"Disabled":                             "Desativado",
+	"Disabled synchronization for folder: %s (ID: %s)\n":                                               "Sincronização desativada para a pasta: %s (ID: %s)\n",
+	"Display a list of all devices connected to your account.":                                         "Exibe uma lista de todos os dispositivos conectados à sua conta.",
+	"Display current configuration":                                                                    "Exibir a configuração atual",
+	"Display detailed information about a specific device or the current device if no ID is provided.": "Exibe informações detalhadas sobre um dispositivo específico, ou sobre o dispositivo atual se nenhum ID for informado.",
+	"Display logs from the synchronization process.":                                                   "Exibe os logs do processo de sincronização.",
+	"Display name; defaults to the part of the email before '@'":                                       "Nome de exibição; o padrão é a parte do e-mail antes do '@'",
+	`Display the agent's current transfers: overall files and bytes, transfer rate and
+estimated time remaining, followed by the progress of each file being transferred.`: `Exibe as transferências atuais do agente: total de arquivos e bytes, taxa de transferência e
+tempo restante estimado, seguidos do progresso de cada arquivo sendo transferido.`,
+	`Display the current configuration. If a key is provided, only that setting is shown.
+storage.* keys belong to the first storage target unless --target names another one.`: `Exibe a configuração atual. Se uma chave for informada, só essa configuração é exibida.
+Chaves storage.* pertencem ao primeiro destino de armazenamento, a menos que --target indique outro.`,
+	"Displaying last %d log entries":                  "Exibindo as últimas %d entradas do log",
+	"Do you want to add another folder? [Y/n]: ":      "Deseja adicionar outra pasta? [S/n]: ",
+	"Download archived or remote files into a folder": "Baixar arquivos arquivados ou remotos para uma pasta",
+	`Download every file of a snapshot into the target directory, restoring permissions and modification times.
+With --hard-links, files that were hard links of each other are linked again instead of restored as copies.`: `Baixa todos os arquivos de um snapshot para o diretório de destino, restaurando permissões e datas de modificação.
+Com --hard-links, arquivos que eram hard links uns dos outros são ligados de novo em vez de restaurados como cópias.`,
+	`Download the complete remote contents of a folder into an empty directory, restoring
+modification times and permissions. Backup-mode folders restore their latest snapshot;
+--at restores the newest snapshot taken at or before the given time.
+
+An interrupted restore can be resumed by running the same command again.`: `Baixa todo o conteúdo remoto de uma pasta para um diretório vazio, restaurando
+datas de modificação e permissões. Pastas em modo backup restauram o snapshot mais recente;
+--at restaura o snapshot mais recente tirado até o momento informado.
+
+Uma restauração interrompida pode ser retomada executando o mesmo comando de novo.`,
+	"Downloaded": "Baixado",
+	"Downloads: %d parallel, %d bytes/sec limit, %d byte chunks\n": "Downloads: %d em paralelo, limite de %d bytes/s, blocos de %d bytes\n",
+	"Enable synchronization for a folder":                          "Ativar a sincronização de uma pasta",
+	"Enable two-way sync (changes on remote will be downloaded)":   "Ativar a sincronização nos dois sentidos (alterações no remoto serão baixadas)",
+	"Enabled": "Ativado",
+	"Enabled synchronization for folder: %s (ID: %s)\n":                      "Sincronização ativada para a pasta: %s (ID: %s)\n",
+	"Encrypt the database with a new key":                                    "Criptografar o banco de dados com uma nova chave",
+	"Enter AWS region [us-east-1]: ":                                         "Informe a região da AWS [us-east-1]: ",
+	"Enter GCS bucket name: ":                                                "Informe o nome do bucket do GCS: ",
+	"Enter GCS project ID: ":                                                 "Informe o ID do projeto do GCS: ",
+	"Enter MinIO access key [minioadmin]: ":                                  "Informe a access key do MinIO [minioadmin]: ",
+	"Enter MinIO bucket [sync-manager]: ":                                    "Informe o bucket do MinIO [sync-manager]: ",
+	"Enter MinIO endpoint [localhost:9000]: ":                                "Informe o endpoint do MinIO [localhost:9000]: ",
+	"Enter MinIO region [us-east-1]: ":                                       "Informe a região do MinIO [us-east-1]: ",
+	"Enter MinIO secret key [minioadmin]: ":                                  "Informe a secret key do MinIO [minioadmin]: ",
+	"Enter S3 bucket name (or press Enter to configure later): ":             "Informe o nome do bucket do S3 (ou pressione Enter para configurar depois): ",
+	"Enter S3 bucket name: ":                                                 "Informe o nome do bucket do S3: ",
+	"Enter a drive ID (leave empty for your own drive): ":                    "Digite o ID de um drive (deixe vazio para o seu próprio drive): ",
+	"Enter access key: ":                                                     "Informe a access key: ",
+	"Enter bandwidth limit in KB/s (0 for unlimited) [0]: ":                  "Informe o limite de banda em KB/s (0 para ilimitado) [0]: ",
+	"Enter choice [1]: ":                                                     "Informe a opção [1]: ",
+	"Enter endpoint URL: ":                                                   "Informe a URL do endpoint: ",
+	"Enter file patterns to exclude (comma-separated, e.g. *.tmp,*.bak): ":   "Informe os padrões de arquivos a excluir (separados por vírgula, ex.: *.tmp,*.bak): ",
+	"Enter folder path to sync: ":                                            "Informe o caminho da pasta a sincronizar: ",
+	"Enter max concurrent transfers [4]: ":                                   "Informe o máximo de transferências simultâneas [4]: ",
+	"Enter path to credentials file (leave empty for default credentials): ": "Informe o caminho do arquivo de credenciais (deixe vazio para as credenciais padrão): ",
+	"Enter root directory [%s]: ":                                            "Informe o diretório raiz [%s]: ",
+	"Enter secret key: ":                                                     "Informe a secret key: ",
+	"Enter sync interval in minutes [5]: ":                                   "Informe o intervalo de sincronização em minutos [5]: ",
+	"Enter the accounts to sign in with: consumers, organizations or a tenant ID [common]: ": "Digite as contas para login: consumers, organizations ou o ID de um locatário [common]: ",
+	"Enter the application (client) ID of your Microsoft Entra app registration: ":           "Digite o ID do aplicativo (cliente) do seu registro de aplicativo no Microsoft Entra: ",
+	"Errors: %d":       "Erros: %d",
+	"Exclude Patterns": "Padrões de exclusão",
+	"Exclude pattern (can be specified multiple times)": "Padrão de exclusão (pode ser especificado várias vezes)",
+	`Exclude rules are kept in the database and merged with the excludes of each
+folder when it is scanned. Global rules apply to every folder, folder rules to one
+folder. Add --device to limit a rule to this device, and --allow to keep syncing
+files matching a pattern that another rule excludes, on this device only.`: `As regras de exclusão ficam no banco de dados e são combinadas com as exclusões de cada
+pasta quando ela é varrida. Regras globais valem para todas as pastas, regras de pasta para uma
+pasta. Adicione --device para limitar uma regra a este dispositivo e --allow para continuar
+sincronizando arquivos que casam com um padrão que outra regra exclui, somente neste dispositivo.`,
+	"Expires": "Expira",
+	"Export configuration as a portable bundle":   "Exportar a configuração como um pacote portátil",
+	"Export sync events for external log systems": "Exportar eventos de sincronização para sistemas de log externos",
+	"Exported %d sync events to %s\n":             "%d eventos de sincronização exportados para %s\n",
+	"Failed to create folder: %v\n":               "Falha ao criar a pasta: %v\n",
+	"Failed to create sync directory: %v\n":       "Falha ao criar o diretório de sincronização: %v\n",
+	"Failed to fetch %s: %v\n":                    "Falha ao buscar %s: %v\n",
+	"Fetched %s (%s)\n":                           "Baixado %s (%s)\n",
+	"Fetched %s, %s downloaded\n":                 "Buscados %s, %s baixados\n",
+	"File added to sync list: %s\n":               "Arquivo adicionado à lista de sincronização: %s\n",
+	"File watches nearly exhausted: %d of %d in use; run 'sync-manager doctor' to raise the limit": "Watches de arquivos quase esgotados: %d de %d em uso; execute 'sync-manager doctor' para aumentar o limite",
+	"File watches: %s": "Watches de arquivos: %s",
+	"File watches: the agent has not reported its usage yet": "Watches de arquivos: o agente ainda não informou o uso",
+	"Files":                           "Arquivos",
+	"Files: %d (%s)\n":                "Arquivos: %d (%s)\n",
+	"Folder":                          "Pasta",
+	"Folder %s added successfully.\n": "Pasta %s adicionada com sucesso.\n",
+	"Folder %s contains it, so it leaves out %s\n":                "A pasta %s a contém, então deixa %s de fora\n",
+	"Folder %s does not exist. Do you want to create it? [Y/n]: ": "A pasta %s não existe. Deseja criá-la? [S/n]: ",
+	"Folder %s has not been synced yet.\n":                        "A pasta %s ainda não foi sincronizada.\n",
+	"Folder %s is inside it, so this folder leaves out %s\n":      "A pasta %s está dentro dela, então esta pasta deixa %s de fora\n",
+	"Folder %s: %s %s\n":              "Pasta %s: %s %s\n",
+	"Folder ID: %s\n":                 "ID da pasta: %s\n",
+	"Folder added to sync list: %s\n": "Pasta adicionada à lista de sincronização: %s\n",
+	"Folder created successfully.":    "Pasta criada com sucesso.",
+	"Folder creation skipped.":        "Criação da pasta ignorada.",
+	"Folder mode: mirror keeps the remote identical, backup stores a snapshot on every sync": "Modo da pasta: mirror mantém o remoto idêntico, backup guarda um snapshot a cada sincronização",
+	"Folder mode: mirror or backup":    "Modo da pasta: mirror ou backup",
+	"Folder name":                      "Nome da pasta",
+	"Folder synchronization complete.": "Sincronização da pasta concluída.",
+	"Folder: %s\n":                     "Pasta: %s\n",
+	`Folders with an archive policy (configure-folder --archive-after-days N) replace the
+files uploaded and left unmodified for N days with small placeholders, keeping their
+content only in storage. fetch downloads the given files back in place of their
+placeholders, or every archived file under a given directory. A fetched file counts
+as modified now, so it stays on disk for another N days.
+
+Given a folder ID followed by glob patterns, fetch downloads the remote files of that
+folder matching them right away, even into an upload-only folder. Patterns match paths
+relative to the folder, or file names when they have no slash, and a pattern matching a
+directory fetches everything under it. Quote them so the shell does not expand them.
+Local files that differ from the remote copy are kept unless --overwrite is given, and
+--dry-run only lists what would be downloaded.`: `Pastas com uma política de arquivamento (configure-folder --archive-after-days N) substituem os
+arquivos enviados e não modificados há N dias por pequenos marcadores, mantendo o
+conteúdo apenas no armazenamento. fetch baixa os arquivos informados de volta no lugar dos
+marcadores, ou todos os arquivos arquivados dentro de um diretório informado. Um arquivo
+buscado conta como modificado agora, e por isso fica no disco por mais N dias.
+
+Dado o ID de uma pasta seguido de padrões glob, fetch baixa na hora os arquivos remotos
+dessa pasta que correspondem a eles, mesmo para uma pasta só de envio. Os padrões
+correspondem a caminhos relativos à pasta, ou a nomes de arquivo quando não têm barra, e um
+padrão que corresponde a um diretório busca tudo dentro dele. Use aspas para que o shell não
+os expanda. Arquivos locais diferentes da cópia remota são mantidos, a menos que --overwrite
+seja usado, e --dry-run apenas lista o que seria baixado.`,
+	"Follow logs as they are written":                           "Acompanhar os logs à medida que são escritos",
+	"For a more detailed setup, run 'sync-manager wizard'.":     "Para uma configuração mais detalhada, execute 'sync-manager wizard'.",
+	"Force immediate synchronization of a specific folder.":     "Forçar a sincronização imediata de uma pasta específica.",
+	"Force immediate synchronization of all monitored folders.": "Forçar a sincronização imediata de todas as pastas monitoradas.",
+	"Force synchronization of all folders":                      "Forçar a sincronização de todas as pastas",
+	"Found and fixed 3 inconsistencies.":                        "3 inconsistências encontradas e corrigidas.",
+	"Full Scan Interval: %s\n":                                  "Intervalo de varredura completa: %s\n",
+	"Full Scan Interval: every sync":                            "Intervalo de varredura completa: a cada sincronização",
+	"Full sync":                                                 "Sincronização completa",
+	`Generates a new database key, encrypts the sensitive columns of every row with it,
+values still in plain text included, and replaces the key in the OS keychain.`: `Gera uma nova chave do banco de dados, criptografa com ela as colunas sensíveis de todas as linhas,
+incluindo valores ainda em texto puro, e substitui a chave no chaveiro do sistema.`,
+	"How long the token is valid, in days (90d) or as a duration (12h)": "Por quanto tempo o token é válido, em dias (90d) ou como duração (12h)",
+	"ID": "ID",
+	"ID of a folder synced from another device, to sync its remote content with this folder": "ID de uma pasta sincronizada de outro dispositivo, para sincronizar o conteúdo remoto dela com esta pasta",
+	"If it is stuck, start the agent with %s":                                                "Se ele estiver travado, inicie o agente com %s",
+	"Import configuration from a bundle":                                                     "Importar a configuração de um pacote",
+	"Imported configuration with %d folder(s) and storage targets %s\n":                      "Configuração importada com %d pasta(s) e destinos de armazenamento %s\n",
+	"Include the traffic of every device sharing the database":                               "Incluir o tráfego de todos os dispositivos que compartilham o banco de dados",
+	"Initial merge of %s (%s):\n":                                                            "Mesclagem inicial de %s (%s):\n",
+	"Initialize sync-manager configuration":                                                  "Inicializar a configuração do sync-manager",
+	"Initialize the basic configuration for sync-manager.":                                   "Inicializa a configuração básica do sync-manager.",
+	"Initializing sync-manager...":                                                           "Inicializando o sync-manager...",
+	"Initiating synchronization for all folders...":                                          "Iniciando a sincronização de todas as pastas...",
+	"Inspect sync conflicts":                                                                 "Inspecionar conflitos de sincronização",
+	"Instance lock held by an agent that does not answer: %s":                                "Trava de instância mantida por um agente que não responde: %s",
+	"Instance lock left by a stopped agent (%s), the next agent takes it over":               "Trava de instância deixada por um agente parado (%s), o próximo agente a assume",
+	"Integrity check: %s\n":                                                                  "Verificação de integridade: %s\n",
+	"Interactive configuration wizard":                                                       "Assistente de configuração interativo",
+	"Interval":                                                                               "Intervalo",
+	"Invalid choice. Using MinIO as default.":                                                "Opção inválida. Usando MinIO como padrão.",
+	"Just now": "Agora mesmo",
+	"Keep files in the app folder instead of the whole drive? [Y/n]: ": "Manter os arquivos na pasta do aplicativo em vez do drive inteiro? [S/n]: ",
+	"Keep refreshing until interrupted":                                "Continuar atualizando até ser interrompido",
+	"Keep syncing files matching the pattern on this device":           "Continuar sincronizando neste dispositivo os arquivos que casam com o padrão",
+	"Keep the N most recent snapshots":                                 "Manter os N snapshots mais recentes",
+	"Keep the newest snapshot of each of the last N days":              "Manter o snapshot mais recente de cada um dos últimos N dias",
+	"Keep the newest snapshot of each of the last N months":            "Manter o snapshot mais recente de cada um dos últimos N meses",
+	"Keep the newest snapshot of each of the last N weeks":             "Manter o snapshot mais recente de cada uma das últimas N semanas",
+	"Kept %s with local changes; use --overwrite to replace them\n":    "%s com alterações locais mantido(s); use --overwrite para substituí-los\n",
+	"LAN Sync: disabled":                             "Sincronização na LAN: desativada",
+	"LAN Sync: enabled on %s (%d trusted devices)\n": "Sincronização na LAN: ativada em %s (%d dispositivos confiáveis)\n",
+	"Language of the output: en or pt (default: from the environment or 'user language')": "Idioma da saída: en ou pt (padrão: o do ambiente ou o de 'user language')",
+	"Language set to %s.\n":                 "Idioma definido como %s.\n",
+	"Language: %s\n":                        "Idioma: %s\n",
+	"Language: %s (from the environment)\n": "Idioma: %s (do ambiente)\n",
+	"Last Change":                           "Última alteração",
+	"Last Seen":                             "Visto por último",
+	"Last Seen:      %s\n":                  "Visto por último: %s\n",
+	"Last Used":                             "Último uso",
+	"Last batch: %s transferred (%s), finished %s\n": "Último lote: %s transferidos (%s), concluído %s\n",
+	"Last change: %s\n":                   "Última alteração: %s\n",
+	"Last sync: %s":                       "Última sincronização: %s",
+	"Leave credentials out of the bundle": "Deixar as credenciais fora do pacote",
+	"Leave out files last modified before this date (YYYY-MM-DD or RFC 3339); empty for no limit": "Deixar de fora arquivos modificados pela última vez antes desta data (AAAA-MM-DD ou RFC 3339); vazio para sem limite",
+	"Leave out files last modified longer ago than this (e.g. 720h); 0 for no limit":              "Deixar de fora arquivos modificados pela última vez há mais tempo que isto (ex.: 720h); 0 para sem limite",
+	"Lifecycle policy applied to %s:\n":                                                           "Política de ciclo de vida aplicada a %s:\n",
+	"List API tokens":                                                                             "Listar os tokens de API",
+	"List all synchronized folders":                                                               "Listar todas as pastas sincronizadas",
+	"List configuration profiles":                                                                 "Listar os perfis de configuração",
+	"List conflict copies and files left out of the sync":                                         "Listar cópias de conflito e arquivos deixados de fora da sincronização",
+	"List connected devices":                                                                      "Listar os dispositivos conectados",
+	"List exclude rules":                                                                          "Listar as regras de exclusão",
+	"List local users":                                                                            "Listar os usuários locais",
+	"List only the global rules":                                                                  "Listar somente as regras globais",
+	`List the conflict copies kept next to files changed on two devices, and the remote files
+the agent does not download because their names differ only in case, which a case-insensitive
+filesystem such as the macOS or Windows default would store as one file. Rename all but one
+of the colliding files on the device that created them to sync them again.`: `Lista as cópias de conflito mantidas ao lado de arquivos alterados em dois dispositivos, e os arquivos
+remotos que o agente não baixa porque seus nomes diferem só em maiúsculas/minúsculas, que um sistema
+de arquivos sem distinção entre elas, como o padrão do macOS ou do Windows, guardaria como um só arquivo.
+Renomeie todos os arquivos em colisão menos um no dispositivo que os criou para sincronizá-los de novo.`,
+	"List the devices trusted for LAN sync": "Listar os dispositivos confiáveis para a sincronização na LAN",
+	`List the exclude rules. With --folder, only the rules used by that folder are
+listed, followed by the patterns it excludes on this device.`: `Lista as regras de exclusão. Com --folder, só as regras usadas por essa pasta são
+listadas, seguidas dos padrões que ela exclui neste dispositivo.`,
+	"List the installed storage plugins": "Lista os plugins de armazenamento instalados",
+	"List the rules used by one folder":  "Listar as regras usadas por uma pasta",
+	"List the snapshots of a folder":     "Listar os snapshots de uma pasta",
+	`List the storage plugins found in the plugins directory. A plugin is an executable named
+sync-manager-storage-<type>; targets of that type are served by it, with the settings of
+their plugin block (config set --target <name> storage.plugin.<setting> <value>).`: `Lista os plugins de armazenamento encontrados no diretório de plugins. Um plugin é um executável
+chamado sync-manager-storage-<tipo>; os destinos desse tipo são atendidos por ele, com as
+configurações do seu bloco plugin (config set --target <nome> storage.plugin.<config> <valor>).`,
+	"List, restore and prune the point-in-time snapshots of folders in backup mode.": "Lista, restaura e poda os snapshots pontuais das pastas em modo backup.",
+	"Log in and register this device":                                                "Entrar e registrar este dispositivo",
+	`Log in to the Sync Manager server and register this device.
+The device receives its own token, which is stored in the credentials file and
+rotated automatically by the agent before it expires.`: `Entra no servidor do Sync Manager e registra este dispositivo.
+O dispositivo recebe seu próprio token, que é guardado no arquivo de credenciais e
+renovado automaticamente pelo agente antes de expirar.`,
+	"Logged in as %s\n": "Conectado como %s\n",
+	"Logged out.":       "Desconectado.",
+	"Longest wait for files still being written before uploading them anyway (e.g. 30m); 0 uses %s, negative uploads them right away":                "Espera máxima por arquivos ainda sendo escritos antes de enviá-los mesmo assim (ex.: 30m); 0 usa %s, negativo os envia imediatamente",
+	"Low disk space: %s needs %s for remote changes but %s has only %s free, waiting since %s; free up space and the downloads resume automatically": "Pouco espaço em disco: %s precisa de %s para as alterações remotas, mas %s tem só %s livres, aguardando desde %s; libere espaço e os downloads continuam automaticamente",
+	"Maintain the local database":                "Manter o banco de dados local",
+	"Make a profile the default":                 "Tornar um perfil o padrão",
+	"Make a user the active one":                 "Tornar um usuário o ativo",
+	"Manage API tokens":                          "Gerenciar tokens de API",
+	"Manage application configuration":           "Gerenciar a configuração da aplicação",
+	"Manage backup snapshots":                    "Gerenciar snapshots de backup",
+	"Manage configuration profiles":              "Gerenciar perfis de configuração",
+	"Manage connected devices":                   "Gerenciar dispositivos conectados",
+	"Manage global and per-folder exclude rules": "Gerenciar regras de exclusão globais e por pasta",
+	"Manage local users":                         "Gerenciar usuários locais",
+	`Mark a local storage target, the first one unless another is named, as a removable drive
+and label the drive mounted at its root directory with a new volume UUID, so the target only
+syncs to that drive. While it is unplugged, syncs of its folders are skipped and uploads wait
+in the queue; once it is mounted again the folders are mirrored to it and a notification
+tells when the drive can be unplugged. With --uuid the drive is recognised by its filesystem
+UUID instead (as listed by lsblk -f on Linux) and nothing is written to it.`: `Marca um destino de armazenamento local, o primeiro a menos que outro seja indicado, como
+disco removível e rotula o disco montado em seu diretório raiz com um novo UUID de volume,
+para que o destino só sincronize com esse disco. Enquanto ele estiver desconectado, as
+sincronizações de suas pastas são puladas e os envios aguardam na fila; quando ele for
+montado de novo as pastas são espelhadas nele e uma notificação avisa quando o disco pode
+ser desconectado. Com --uuid o disco é reconhecido pelo UUID do sistema de arquivos (como
+listado por lsblk -f no Linux) e nada é gravado nele.`,
+	"Max File Size: %s\n": "Tamanho máximo de arquivo: %s\n",
+	"Mirror a local storage target onto a removable drive":                                                                                      "Espelha um destino de armazenamento local em um disco removível",
+	"Mirror mode: hold back orphan removal until forced when more than N remote files would go; 0 uses %d, negative removes any number":         "Modo mirror: reter a remoção de órfãos até ser forçada quando mais de N arquivos remotos seriam removidos; 0 usa %d, negativo remove qualquer quantidade",
+	"Mirror mode: hold back orphan removal until forced when more than N%% of the remote files would go; 0 uses %d, negative removes any share": "Modo mirror: reter a remoção de órfãos até ser forçada quando mais de N%% dos arquivos remotos seriam removidos; 0 usa %d, negativo remove qualquer proporção",
+	"Mirror mode: move removed remote files under .trash/<folder-id>/ instead of deleting them":                                                 "Modo mirror: mover os arquivos remotos removidos para .trash/<folder-id>/ em vez de excluí-los",
+	"Mirror mode: remove remote files that were deleted locally (one-way folders only)":                                                         "Modo mirror: remover os arquivos remotos que foram excluídos localmente (somente pastas de um sentido)",
+	"Mirror to %s complete, the drive can be unplugged":                                                                                         "Espelhamento em %s concluído, o disco pode ser desconectado",
+	"Mirror to %s failed: %v":                                    "O espelhamento em %s falhou: %v",
+	"Mode: backup (snapshots)":                                   "Modo: backup (snapshots)",
+	"Monitoring and syncing folders according to configuration.": "Monitorando e sincronizando as pastas conforme a configuração.",
+	"Monitoring sync activity...":                                "Monitorando a atividade de sincronização...",
+	"Month to report, as YYYY-MM (default: the current month)":   "Mês do relatório, como AAAA-MM (padrão: o mês atual)",
+	"Monthly Cap: %s\n":                                          "Limite mensal: %s\n",
+	"Monthly cap: %s of %s used (%.0f%%)\n":                      "Limite mensal: %s de %s usados (%.0f%%)\n",
+	"Moved %s from %s/ to %s/\n":                                 "%s movido(s) de %s/ para %s/\n",
+	"Name":                                                       "Nome",
+	"Name of the token, such as the job using it":                "Nome do token, como o do job que o usa",
+	"Name:           %s\n":                                       "Nome:           %s\n",
+	"Never":                                                      "Nunca",
+	"Never prompt, for scripts: questions take their default and confirmations are accepted (also --yes)": "Nunca pergunta, para scripts: as perguntas ficam com o padrão e as confirmações são aceitas (também --yes)",
+	"No API tokens.":           "Nenhum token de API.",
+	"No archived files found.": "Nenhum arquivo arquivado encontrado.",
+	"No bucket specified. You can configure it later with 'sync-manager config set storage.s3.bucket <name>'.": "Nenhum bucket informado. Você pode configurá-lo depois com 'sync-manager config set storage.s3.bucket <nome>'.",
+	"No conflicts.":                                          "Nenhum conflito.",
+	"No devices are trusted for LAN sync":                    "Nenhum dispositivo é confiável para a sincronização na LAN",
+	"No exclude rules.":                                      "Nenhuma regra de exclusão.",
+	"No folder path entered. Skipping folder addition.":      "Nenhum caminho de pasta informado. Pulando a adição da pasta.",
+	"No folders configured for synchronization.":             "Nenhuma pasta configurada para sincronização.",
+	"No folders configured.":                                 "Nenhuma pasta configurada.",
+	"No remote files of %s match %s\n":                       "Nenhum arquivo remoto de %s corresponde a %s\n",
+	"No snapshots found for this folder.":                    "Nenhum snapshot encontrado para esta pasta.",
+	"No storage plugins in %s\n":                             "Nenhum plugin de armazenamento em %s\n",
+	"No sync of %s recorded yet.\n":                          "Nenhuma sincronização de %s registrada ainda.\n",
+	"No sync recorded yet.":                                  "Nenhuma sincronização registrada ainda.",
+	"No traffic recorded for %s.\n":                          "Nenhum tráfego registrado em %s.\n",
+	"No transfers in progress.":                              "Nenhuma transferência em andamento.",
+	"Nothing to fetch.":                                      "Nada para buscar.",
+	"Now using profile %s. Restart the agent to apply it.\n": "Usando agora o perfil %s. Reinicie o agente para aplicá-lo.\n",
+	"Now using user %s.\n":                                   "Usando agora o usuário %s.\n",
+	"Number of log entries to display":                       "Número de entradas do log a exibir",
+	"Number of parallel downloads (default: download.max_concurrency from the configuration)": "Número de downloads em paralelo (padrão: download.max_concurrency da configuração)",
+	"Offline":                     "Offline",
+	"On Battery: %s\n":            "Na bateria: %s\n",
+	"On Metered Connection: %s\n": "Em conexão limitada: %s\n",
+	"On your other devices run:\n  sync-manager lan trust %s %s\n": "Nos seus outros dispositivos execute:\n  sync-manager lan trust %s %s\n",
+	"One agent holds the instance lock: %s":                        "Um agente mantém a trava de instância: %s",
+	"Online":                                                       "Online",
+	"Only export events recorded since then: days (30d), a duration (12h) or an RFC 3339 time": "Exportar apenas os eventos registrados desde então: dias (30d), uma duração (12h) ou um horário RFC 3339",
+	"Only export the events of this folder":                                                    "Exportar apenas os eventos desta pasta",
+	"Only list the files that would be downloaded":                                             "Apenas listar os arquivos que seriam baixados",
+	"Only show the initial merge plan, without adding the folder":                              "Apenas exibir o plano da mesclagem inicial, sem adicionar a pasta",
+	"Operation cancelled.": "Operação cancelada.",
+	"Other devices syncing this folder must be given the same remote prefix.":                                          "Outros dispositivos que sincronizam esta pasta precisam receber o mesmo prefixo remoto.",
+	"Out of file watches: %d directories are polled for changes instead; run 'sync-manager doctor' to raise the limit": "Sem watches de arquivos: %d diretórios são verificados periodicamente em vez disso; execute 'sync-manager doctor' para aumentar o limite",
+	"Output format: jsonl":               "Formato de saída: jsonl",
+	"Pair devices for LAN sync":          "Parear dispositivos para a sincronização na LAN",
+	"Path":                               "Caminho",
+	"Pattern":                            "Padrão",
+	"Pause synchronization":              "Pausar a sincronização",
+	"Pause synchronization for a folder": "Pausar a sincronização de uma pasta",
+	"Pause the synchronization process temporarily.": "Pausa o processo de sincronização temporariamente.",
+	"Paused": "Pausado",
+	"Paused synchronization for folder: %s (ID: %s)\n":     "Sincronização pausada para a pasta: %s (ID: %s)\n",
+	"Platform:       %s/%s\n":                              "Plataforma:     %s/%s\n",
+	"Press Ctrl+C at any time to exit.":                    "Pressione Ctrl+C a qualquer momento para sair.",
+	"Press Ctrl+C to exit.":                                "Pressione Ctrl+C para sair.",
+	"Press Ctrl+C to stop.":                                "Pressione Ctrl+C para parar.",
+	"Print the current view once instead of refreshing it": "Exibe a visão atual uma vez em vez de atualizá-la",
+	"Print the report as JSON":                             "Exibir o relatório como JSON",
+	"Print the summaries as JSON":                          "Imprime os resumos em JSON",
+	"Print the version information":                        "Exibir as informações de versão",
+	"Priority: %s, hashing limited to %d bytes/sec\n":      "Prioridade: %s, cálculo de hash limitado a %d bytes/s\n",
+	"Profile %s created at %s\n":                           "Perfil %s criado em %s\n",
+	"Prune old records and compact the database":           "Podar registros antigos e compactar o banco de dados",
+	"Pruned %d deleted rows and %d sync events.\n":         "%d linhas excluídas e %d eventos de sincronização podados.\n",
+	"Rate: %s": "Taxa: %s",
+	"Read the sync events kept by the server":                                                           "Ler os eventos de sincronização guardados pelo servidor",
+	"Recognise the drive by this filesystem UUID instead of labelling it":                               "Reconhece o disco por este UUID do sistema de arquivos em vez de rotulá-lo",
+	"Recreate files that were hard links of each other as hard links":                                   "Recriar como hard links os arquivos que eram hard links uns dos outros",
+	"Recreate files that were hard links of each other as hard links (snapshot restores only)":          "Recriar como hard links os arquivos que eram hard links uns dos outros (somente restaurações de snapshot)",
+	"Remote prefix (folder ID) of a folder published by another device, to keep a read-only copy of it": "Prefixo remoto (ID da pasta) de uma pasta publicada por outro dispositivo, para manter uma cópia somente leitura dela",
+	"Remove a folder from synchronization":                                                              "Remover uma pasta da sincronização",
+	"Remove an exclude rule":                                                                            "Remover uma regra de exclusão",
+	"Remove the connection between a device and your account.":                                          "Remove a ligação entre um dispositivo e a sua conta.",
+	"Remove the extra root with the given prefix (can be specified multiple times)":                     "Remover a raiz extra com o prefixo informado (pode ser especificado várias vezes)",
+	"Remove this device's stored token":                                                                 "Remover o token guardado deste dispositivo",
+	"Removed folder: %s (ID: %s)\n":                                                                     "Pasta removida: %s (ID: %s)\n",
+	"Removed rule: %s\n":                                                                                "Regra removida: %s\n",
+	"Removed snapshot %s (%s)\n":                                                                        "Snapshot %s removido (%s)\n",
+	`Removes soft-deleted rows and sync events past their retention. On SQLite it then
+runs VACUUM to give the freed space back and PRAGMA integrity_check to find corruption.`: `Remove as linhas excluídas logicamente e os eventos de sincronização além da retenção. No SQLite,
+executa em seguida VACUUM para devolver o espaço liberado e PRAGMA integrity_check para encontrar corrupção.`,
+	"Rename this device":                 "Renomear este dispositivo",
+	"Repairing synchronization state...": "Reparando o estado da sincronização...",
+	"Replace files uploaded and left unmodified for N days with placeholders to free disk space, fetched back with 'sync-manager fetch'; 0 keeps every file local": "Substituir por marcadores os arquivos enviados e não modificados há N dias para liberar espaço em disco, buscados de volta com 'sync-manager fetch'; 0 mantém todos os arquivos locais",
+	"Replace local files that differ from the remote copy":                                                "Substituir arquivos locais diferentes da cópia remota",
+	"Rescan and reconcile one subdirectory of a folder":                                                   "Reescanear e reconciliar um subdiretório de uma pasta",
+	"Reset all configuration settings to their default values.":                                           "Redefine todas as configurações para os valores padrão.",
+	"Reset configuration to defaults":                                                                     "Redefinir a configuração para o padrão",
+	"Reset local synchronization state":                                                                   "Redefinir o estado local da sincronização",
+	"Reset the local synchronization state while preserving files and configuration.":                     "Redefine o estado local da sincronização, preservando os arquivos e a configuração.",
+	"Resetting synchronization state...":                                                                  "Redefinindo o estado da sincronização...",
+	"Restore a snapshot into a directory":                                                                 "Restaurar um snapshot em um diretório",
+	"Restore the remote contents of a folder into a directory":                                            "Restaurar o conteúdo remoto de uma pasta em um diretório",
+	"Restore the snapshot taken at or before this time (RFC3339, \"2006-01-02 15:04\" or \"2006-01-02\")": "Restaurar o snapshot tirado até este momento (RFC3339, \"2006-01-02 15:04\" ou \"2006-01-02\")",
+	"Restored %s (%s).\n":                                                                                 "%s restaurado (%s).\n",
+	"Restored %s.\n":                                                                                      "%s restaurado.\n",
+	"Restored from snapshot %s.\n":                                                                        "Restaurado a partir do snapshot %s.\n",
+	"Restoring folder %s into %s...\n":                                                                    "Restaurando a pasta %s em %s...\n",
+	"Restoring snapshot %s into %s...\n":                                                                  "Restaurando o snapshot %s em %s...\n",
+	"Resume previously paused synchronization.":                                                           "Retoma a sincronização pausada anteriormente.",
+	"Resume synchronization":                                                                              "Retomar a sincronização",
+	"Resume synchronization for a paused folder":                                                          "Retomar a sincronização de uma pasta pausada",
+	"Resumed synchronization for folder: %s (ID: %s)\n":                                                   "Sincronização retomada para a pasta: %s (ID: %s)\n",
+	"Return once the sync is requested instead of following it":                                           "Retornar assim que a sincronização for pedida em vez de acompanhá-la",
+	"Revoke an API token":                                                                                 "Revogar um token de API",
+	"Rule already exists: %s\n":                                                                           "A regra já existe: %s\n",
+	"Scheduled sync":                                                                                      "Sincronização agendada",
+	"Scope":                                                                                               "Escopo",
+	"Select storage provider:":                                                                            "Selecione o provedor de armazenamento:",
+	`Set a specific configuration value, or several at once given as more key and value
+pairs. storage.* keys change the first storage target unless --target names another one;
+setting storage.provider on a target that does not exist yet adds it.
+
+A running agent, reached with the API token in SYNC_MANAGER_TOKEN, checks the change against
+the whole configuration, saves it and applies it right away; settings that only make sense
+together, like a new provider and its bucket, must be set at once. Without a running agent or
+a token the configuration file is changed, and a running agent applies it once it reads the
+file again.`: `Define um valor específico da configuração, ou vários de uma vez, dados como mais pares de
+chave e valor. Chaves storage.* alteram o primeiro destino de armazenamento, a menos que
+--target indique outro; definir storage.provider em um destino que ainda não existe o adiciona.
+
+Um agente em execução, alcançado com o token de API em SYNC_MANAGER_TOKEN, confere a alteração
+com a configuração inteira, salva e a aplica na hora; valores que só fazem sentido juntos, como
+um novo provedor e seu bucket, devem ser definidos de uma vez. Sem agente em execução ou sem
+token, o arquivo de configuração é alterado, e um agente em execução o aplica quando relê o
+arquivo.`,
+	"Set configuration value":           "Definir um valor da configuração",
+	"Setting up basic configuration...": "Preparando a configuração básica...",
+	"Shell command run in the folder before each sync, e.g. to dump a database into it; empty runs none":                 "Comando de shell executado na pasta antes de cada sincronização, por exemplo para gravar nela um dump de banco de dados; vazio não executa nenhum",
+	"Shell command run in the folder once each sync ended, with its result in SYNC_MANAGER_* variables; empty runs none": "Comando de shell executado na pasta ao fim de cada sincronização, com o resultado nas variáveis SYNC_MANAGER_*; vazio não executa nenhum",
+	"Show a summary of the last sync":             "Mostra um resumo da última sincronização",
+	"Show bandwidth usage":                        "Exibir o uso de banda",
+	"Show detailed information about a device":    "Exibir informações detalhadas sobre um dispositivo",
+	"Show detailed synchronization progress":      "Exibir o progresso detalhado da sincronização",
+	"Show folder statistics":                      "Exibir estatísticas das pastas",
+	"Show or set the language of the active user": "Exibir ou definir o idioma do usuário ativo",
+	`Show or set the language the CLI talks to the active user in: en (English) or
+pt (Portuguese). 'auto' forgets the choice, so the language comes from the locale of the
+environment. --lang and the SYNC_MANAGER_LANG variable override it for a single command.`: `Exibe ou define o idioma em que a CLI fala com o usuário ativo: en (inglês) ou
+pt (português). 'auto' esquece a escolha, e o idioma passa a vir da localidade do
+ambiente. --lang e a variável SYNC_MANAGER_LANG o substituem em um único comando.`,
+	"Show realtime sync activity":           "Exibir a atividade de sincronização em tempo real",
+	"Show sync status of monitored folders": "Exibir o estado de sincronização das pastas monitoradas",
+	"Show synchronization logs":             "Exibir os logs de sincronização",
+	"Show the heaviest transfers live":      "Mostra ao vivo as transferências mais pesadas",
+	`Show the state the agent reports for each folder (idle, scanning, syncing, paused or error)
+with its last sync, the files waiting to be transferred, its last error and the bytes transferred today.`: `Exibe o estado que o agente informa para cada pasta (ocioso, varrendo, sincronizando, pausado ou erro)
+com a última sincronização, os arquivos aguardando transferência, o último erro e os bytes transferidos hoje.`,
+	"Show this device's ID and certificate fingerprint":    "Exibir o ID deste dispositivo e a impressão digital do certificado",
+	"Show what would be removed without deleting anything": "Exibir o que seria removido sem excluir nada",
+	`Shows how many files each folder holds, their total size and when one last changed,
+or for a single folder its largest files too. The figures are those of the last sync,
+so the folders are not scanned again.`: `Exibe quantos arquivos cada pasta contém, o tamanho total e quando um deles mudou por último,
+ou, para uma única pasta, também os maiores arquivos. Os números são os da última sincronização,
+então as pastas não são varridas de novo.`,
+	`Shows how much each folder uploaded and downloaded in a month, as the agent recorded it.
+Use --daily to break the month down by day. With a monthly cap set (bandwidth.monthly_cap),
+the agent pauses syncing once the device's traffic reaches it, until the next month.`: `Exibe quanto cada pasta enviou e baixou em um mês, conforme registrado pelo agente.
+Use --daily para detalhar o mês por dia. Com um limite mensal definido (bandwidth.monthly_cap),
+o agente pausa a sincronização quando o tráfego do dispositivo o atinge, até o mês seguinte.`,
+	`Shows what the last sync of each folder did, as the agent recorded it when the sync
+ended: how long it took, the files scanned, uploaded, downloaded, deleted and skipped, the
+bytes transferred, errors and conflicts. Uploads finish in the background, so the files a
+sync queued for upload may still be on their way when it ends.`: `Mostra o que a última sincronização de cada pasta fez, como o agente registrou ao fim dela:
+quanto tempo levou, os arquivos verificados, enviados, baixados, removidos e ignorados, os
+bytes transferidos, erros e conflitos. Os envios terminam em segundo plano, então os arquivos
+que uma sincronização pôs na fila de envio podem ainda estar a caminho quando ela acaba.`,
+	`Shows, refreshed every second, the files of the current batch of transfers that moved
+the most bytes and took the longest, including those still transferring, the folders moving
+the most bytes, and the bytes and time of each file extension since the agent started.`: `Mostra, atualizados a cada segundo, os arquivos do lote atual de transferências que moveram
+mais bytes e mais demoraram, incluindo os que ainda estão sendo transferidos, as pastas que
+mais movem bytes e os bytes e o tempo de cada extensão de arquivo desde o início do agente.`,
+	"Sign in later with: sync-manager storage-login": "Faça login depois com: sync-manager storage-login",
+	"Sign in to a OneDrive storage target":           "Faz login em um destino de armazenamento OneDrive",
+	`Sign in to the OneDrive of a storage target, the first one unless another is named. The
+command shows a code to enter at a Microsoft page, in any browser; once signed in there the
+agent keeps the sign-in in the token file of the target and renews it on its own. Sign in
+again when it was revoked or unused for 90 days.`: `Faz login no OneDrive de um destino de armazenamento, o primeiro a menos que outro seja indicado. O
+comando mostra um código para digitar em uma página da Microsoft, em qualquer navegador; após o login lá, o
+agente guarda o login no arquivo de token do destino e o renova sozinho. Faça login
+de novo quando ele for revogado ou ficar 90 dias sem uso.`,
+	"Signed in to OneDrive for storage target %s\n": "Login no OneDrive feito para o destino de armazenamento %s\n",
+	"Size":                         "Tamanho",
+	"Size: %s before, %s after.\n": "Tamanho: %s antes, %s depois.\n",
+	"Skip the checks that reach storage and API endpoints":               "Pular as verificações que acessam os endpoints de armazenamento e da API",
+	"Skipped %s restored by an earlier run.\n":                           "%s ignorado, restaurado por uma execução anterior.\n",
+	"Skipping disabled folder: %s\n":                                     "Pulando a pasta desativada: %s\n",
+	"Snapshot ID":                                                        "ID do snapshot",
+	"Sparse Files: %s\n":                                                 "Arquivos esparsos: %s\n",
+	"Staging Area: %s, up to %s\n":                                       "Área de Staging: %s, até %s\n",
+	"Staging Area: up to %s\n":                                           "Área de Staging: até %s\n",
+	"Start an interactive configuration wizard to set up sync-manager.":  "Inicia um assistente de configuração interativo para preparar o sync-manager.",
+	"Start the sync agent":                                               "Iniciar o agente de sincronização",
+	"Starting Sync Manager agent...":                                     "Iniciando o agente do Sync Manager...",
+	"Status":                                                             "Estado",
+	"Status:         %s\n":                                               "Estado:         %s\n",
+	"Step 1/4: Checking local database...":                               "Passo 1/4: Verificando o banco de dados local...",
+	"Step 1: Configure Storage":                                          "Passo 1: Configurar o armazenamento",
+	"Step 2/4: Verifying against remote state...":                        "Passo 2/4: Comparando com o estado remoto...",
+	"Step 3/4: Reconciling differences...":                               "Passo 3/4: Reconciliando as diferenças...",
+	"Step 4/4: Updating local database...":                               "Passo 4/4: Atualizando o banco de dados local...",
+	"Stop all but one, or restart one with %s so the others stop":        "Pare todos menos um, ou reinicie um com %s para que os outros parem",
+	"Stop it first, or start this agent with %s if that one is stuck.\n": "Pare-o primeiro, ou inicie este agente com %s se aquele estiver travado.\n",
+	"Stop the sync agent":                                                "Parar o agente de sincronização",
+	"Stop trusting a device for LAN sync":                                "Deixar de confiar em um dispositivo para a sincronização na LAN",
+	"Stopping Sync Manager agent...":                                     "Parando o agente do Sync Manager...",
+	"Storage Plugins: %s\n":                                              "Plugins de Armazenamento: %s\n",
+	"Storage class for files uploaded from now on; empty uses the bucket's class":                                        "Classe de armazenamento dos arquivos enviados daqui em diante; vazio usa a classe do bucket",
+	"Storage class for uploaded files (e.g. STANDARD_IA, GLACIER_IR, NEARLINE, ARCHIVE); defaults to the bucket's class": "Classe de armazenamento dos arquivos enviados (ex.: STANDARD_IA, GLACIER_IR, NEARLINE, ARCHIVE); o padrão é a classe do bucket",
+	"Storage class old versions move to (e.g. GLACIER_IR, DEEP_ARCHIVE, COLDLINE)":                                       "Classe de armazenamento para onde vão as versões antigas (ex.: GLACIER_IR, DEEP_ARCHIVE, COLDLINE)",
+	"Storage plugins in %s:\n": "Plugins de armazenamento em %s:\n",
+	"Storage prefix the folder's files are kept under, moving the files already uploaded there; empty uses the folder ID": "Prefixo no armazenamento sob o qual ficam os arquivos da pasta, movendo para lá os arquivos já enviados; vazio usa o ID da pasta",
+	"Storage target %s mirrors to volume %s at %s\n":                                                                      "O destino de armazenamento %s espelha no volume %s em %s\n",
+	"Storage target the folder syncs to; defaults to the first configured target":                                         "Destino de armazenamento da pasta; o padrão é o primeiro destino configurado",
+	"Storage target the folder syncs to; empty uses the first configured target":                                          "Destino de armazenamento da pasta; vazio usa o primeiro destino configurado",
+	"Storage target the storage.* key belongs to (default: the first one)":                                                "Destino de armazenamento ao qual a chave storage.* pertence (padrão: o primeiro)",
+	"Storage target the storage.* key changes (default: the first one)":                                                   "Destino de armazenamento que a chave storage.* altera (padrão: o primeiro)",
+	"Storage:        %s\n": "Armazenamento:  %s\n",
+	"Subscribed folders: what happens to files changed locally, revert or flag": "Pastas assinadas: o que acontece com arquivos alterados localmente, revert ou flag",
+	"Sync Folders:   %d\n": "Pastas:         %d\n",
+	"Sync Interval:  %s\n": "Intervalo:      %s\n",
+	"Sync Interval: %s\n":  "Intervalo de sincronização: %s\n",
+	"Sync Manager":         "Sync Manager",
+	"Sync Manager - File synchronization and backup tool": "Sync Manager - Ferramenta de sincronização e backup de arquivos",
+	"Sync Manager Agent":                             "Agente do Sync Manager",
+	"Sync Manager has been successfully configured.": "O Sync Manager foi configurado com sucesso.",
+	`Sync Manager is a file synchronization and backup tool that allows you to
+securely store and sync your files across multiple devices using S3-compatible storage.
+
+It provides efficient, background synchronization with minimal resource usage.`: `O Sync Manager é uma ferramenta de sincronização e backup de arquivos que permite
+guardar e sincronizar seus arquivos com segurança entre vários dispositivos usando armazenamento compatível com S3.
+
+Ele oferece uma sincronização eficiente em segundo plano, com uso mínimo de recursos.`,
+	"Sync Manager v%s (built %s)\n":          "Sync Manager v%s (compilado em %s)\n",
+	"Sync complete.":                         "Sincronização concluída.",
+	"Sync directory already exists at: %s\n": "O diretório de sincronização já existe em: %s\n",
+	"Sync interval for this folder (e.g. 10m); 0 uses the global interval":      "Intervalo de sincronização desta pasta (ex.: 10m); 0 usa o intervalo global",
+	"Sync interval for this folder (e.g. 10m); defaults to the global interval": "Intervalo de sincronização desta pasta (ex.: 10m); o padrão é o intervalo global",
+	"Sync of %s":       "Sincronização de %s",
+	"Sync of %s in %s": "Sincronização de %s em %s",
+	"Sync priority (lower numbers are higher priority)":             "Prioridade de sincronização (números menores têm prioridade maior)",
+	"Sync requested, follow it with 'sync-manager status --watch'.": "Sincronização solicitada, acompanhe-a com 'sync-manager status --watch'.",
+	"Synchronization Status:":                                       "Estado da sincronização:",
+	"Synchronization complete.":                                     "Sincronização concluída.",
+	"Synchronization paused.":                                       "Sincronização pausada.",
+	"Synchronization resumed.":                                      "Sincronização retomada.",
+	"Synchronization state has been reset.":                         "O estado da sincronização foi redefinido.",
+	"Synchronize a specific folder":                                 "Sincronizar uma pasta específica",
+	"Synchronizing folder %d/%d: %s\n":                              "Sincronizando a pasta %d/%d: %s\n",
+	"Synchronizing folder: %s\n":                                    "Sincronizando a pasta: %s\n",
+	"Temporarily stop synchronizing a folder. Unlike disable-folder, the folder keeps its settings and is picked up again with resume-folder.": "Interrompe temporariamente a sincronização de uma pasta. Diferente de disable-folder, a pasta mantém suas configurações e volta a ser sincronizada com resume-folder.",
+	"The agent has not reported any transfers yet.":                                                                                              "O agente ainda não informou nenhuma transferência.",
+	"The agent has not reported its folders recently, so case collisions are not listed.":                                                        "O agente não informou suas pastas recentemente, então as colisões de maiúsculas/minúsculas não são listadas.",
+	"The agent has not reported the state of its folders yet.":                                                                                   "O agente ainda não informou o estado das suas pastas.",
+	"The agent is not running; the change takes effect when it starts ('sync-manager start').":                                                   "O agente não está em execução; a alteração vale quando ele iniciar ('sync-manager start').",
+	"The agent recorded no sync of the requested folders.":                                                                                       "O agente não registrou nenhuma sincronização das pastas pedidas.",
+	"The agent will perform a full scan on next start.":                                                                                          "O agente fará uma varredura completa na próxima inicialização.",
+	"The bundle has no credentials; set them with 'config set' before syncing.":                                                                  "O pacote não tem credenciais; defina-as com 'config set' antes de sincronizar.",
+	"The language now comes from the environment.":                                                                                               "O idioma agora vem do ambiente.",
+	"The limit was raised since; restart the agent to watch these directories":                                                                   "O limite foi aumentado desde então; reinicie o agente para observar esses diretórios",
+	"The request joins it or runs once it ends, use --restart to cancel it instead.":                                                             "O pedido se junta a ela ou roda quando ela terminar, use --restart para cancelá-la.",
+	"The running agent applies this change within a few seconds.":                                                                                "O agente em execução aplica esta alteração em poucos segundos.",
+	"Then restart the agent so every directory is watched":                                                                                       "Depois reinicie o agente para que todos os diretórios sejam observados",
+	"This device will no longer be able to access your account or synchronize files.":                                                            "Este dispositivo não poderá mais acessar sua conta nem sincronizar arquivos.",
+	"This will reset all configuration to default values. Continue? (y/n): ":                                                                     "Isto vai redefinir toda a configuração para os valores padrão. Continuar? (s/n): ",
+	"This will reset all synchronization state. Your files will not be deleted, but the agent will need to rescan everything. Continue? (y/n): ": "Isto vai redefinir todo o estado da sincronização. Seus arquivos não serão excluídos, mas o agente precisará varrer tudo de novo. Continuar? (s/n): ",
+	"This wizard will guide you through setting up Sync Manager.":                                                                                "Este assistente vai guiá-lo na configuração do Sync Manager.",
+	"Throttle Bandwidth: %d bytes/sec\n":                                                                                                         "Limitação de banda: %d bytes/s\n",
+	"Time the post-sync command gets before it is killed; 0 uses %s":                                                                             "Tempo que o comando post-sync tem antes de ser encerrado; 0 usa %s",
+	"Time the pre-sync command gets before it is killed; 0 uses %s":                                                                              "Tempo que o comando pre-sync tem antes de ser encerrado; 0 usa %s",
+	"To reset the configuration, use 'sync-manager config reset'.":                                                                               "Para redefinir a configuração, use 'sync-manager config reset'.",
+	"To sign in to OneDrive, open %s and enter the code %s\n":                                                                                    "Para fazer login no OneDrive, abra %s e digite o código %s\n",
+	"To start the sync agent, run 'sync-manager start'.":                                                                                         "Para iniciar o agente de sincronização, execute 'sync-manager start'.",
+	"Token %d revoked.\n":                        "Token %d revogado.\n",
+	"Token %s created (ID: %d), expires %s:\n\n": "Token %s criado (ID: %d), expira em %s:\n\n",
+	"Total":                             "Total",
+	"Transferred: ↑ %s (%s)  ↓ %s (%s)": "Transferido: ↑ %s (%s)  ↓ %s (%s)",
+	"Transfers %s":                      "Transferências %s",
+	"Trigger an immediate sync for one or all folders":                    "Disparar uma sincronização imediata de uma ou de todas as pastas",
+	"Trust another device for LAN sync":                                   "Confiar em outro dispositivo para a sincronização na LAN",
+	"Type":                                                                "Tipo",
+	"Unlink a device from your account":                                   "Desvincular um dispositivo da sua conta",
+	"Unlinking device %s...\n":                                            "Desvinculando o dispositivo %s...\n",
+	"Updated configuration for folder: %s (ID: %s)\n":                     "Configuração atualizada para a pasta: %s (ID: %s)\n",
+	"Upload Order: size weight %g, recency weight %g, folder weight %g\n": "Ordem dos Uploads: peso do tamanho %g, peso da recência %g, peso da pasta %g\n",
+	"Uploaded": "Enviado",
+	"Use 'sync-manager config' commands to modify settings or 'sync-manager wizard' for a guided setup.": "Use os comandos 'sync-manager config' para alterar as configurações ou 'sync-manager wizard' para uma configuração guiada.",
+	"Use 'sync-manager resume' to resume synchronization.":                                               "Use 'sync-manager resume' para retomar a sincronização.",
+	"Use 'sync-manager resume-folder %s' to resume it.\n":                                                "Use 'sync-manager resume-folder %s' para retomá-la.\n",
+	"Use SSL? [y/N]: ": "Usar SSL? [s/N]: ",
+	"Use a custom endpoint? (for compatible services) [y/N]: ":                  "Usar um endpoint personalizado? (para serviços compatíveis) [s/N]: ",
+	"Use path style? [y/N]: ":                                                   "Usar path style? [s/N]: ",
+	"Use the CLI to manage synced folders and view status.":                     "Use a CLI para gerenciar as pastas sincronizadas e ver o estado.",
+	"User %s created (ID: %d). Switch to it with 'sync-manager user use %s'.\n": "Usuário %s criado (ID: %d). Mude para ele com 'sync-manager user use %s'.\n",
+	`Users keep separate folder records and devices in the local database, so
+several people can share a machine. Commands act for the active user, chosen with
+'user use'. The configuration file is not per user: give each user a profile of their own
+('config profile create') to keep their folders and storage apart as well.`: `Os usuários mantêm registros de pastas e dispositivos separados no banco de dados local, para
+que várias pessoas possam compartilhar uma máquina. Os comandos agem pelo usuário ativo, escolhido com
+'user use'. O arquivo de configuração não é por usuário: dê a cada usuário um perfil próprio
+('config profile create') para manter também suas pastas e armazenamento separados.`,
+	"Vacuumed the database.":                "Banco de dados compactado com VACUUM.",
+	"Verified %s in %s: %d ok, %d differ\n": "Verificados %s em %s: %d ok, %d diferem\n",
+	"Verify the synchronization state and attempt to repair any inconsistencies.":                                             "Verifica o estado da sincronização e tenta reparar eventuais inconsistências.",
+	"View and manage devices connected to your account.":                                                                      "Ver e gerenciar os dispositivos conectados à sua conta.",
+	"View and modify application configuration settings.":                                                                     "Ver e alterar as configurações da aplicação.",
+	"Wait until files are this old since their last change before uploading them (e.g. 30s); 0 uploads them once they settle": "Esperar até que os arquivos tenham esta idade desde a última alteração antes de enviá-los (ex.: 30s); 0 os envia assim que se estabilizam",
+	"Waiting for the agent to start the sync...":                                                                              "Aguardando o agente iniciar a sincronização...",
+	"Warning: %s objects must be restored on the provider before they can be downloaded or restored.\n":                       "Aviso: objetos %s precisam ser restaurados no provedor antes de poderem ser baixados ou restaurados.\n",
+	"Warning: Failed to create directory: %v\n":                                                                               "Aviso: falha ao criar o diretório: %v\n",
+	"Warning: Failed to remove folder from database: %v\n":                                                                    "Aviso: falha ao remover a pasta do banco de dados: %v\n",
+	"Warning: Failed to update folder name in database: %v\n":                                                                 "Aviso: falha ao atualizar o nome da pasta no banco de dados: %v\n",
+	"Warning: Failed to update folder status in database: %v\n":                                                               "Aviso: falha ao atualizar o estado da pasta no banco de dados: %v\n",
+	"Warning: OneDrive sign-in failed: %v\n":                                                                                  "Aviso: o login no OneDrive falhou: %v\n",
+	"Warning: files already uploaded stay on the previous target; the next sync uploads the folder to %s.\n":                  "Aviso: os arquivos já enviados ficam no destino anterior; a próxima sincronização envia a pasta para %s.\n",
+	"Warning: folder %s (ID: %s) does not exist on this machine\n":                                                            "Aviso: a pasta %s (ID: %s) não existe nesta máquina\n",
+	"Warning: folder %s is not configured on this device\n":                                                                   "Aviso: a pasta %s não está configurada neste dispositivo\n",
+	"Watching transfers, press Ctrl+C to stop.":                                                                               "Acompanhando as transferências, pressione Ctrl+C para parar.",
+	"Welcome to the Sync Manager Configuration Wizard":                                                                        "Bem-vindo ao assistente de configuração do Sync Manager",
+	"What a failed post-sync command does: continue only logs it (the default), abort marks the sync failed":                  "O que uma falha do comando post-sync faz: continue apenas a registra (o padrão), abort marca a sincronização como falha",
+	"What a failed pre-sync command does: abort skips the sync (the default), continue syncs anyway":                          "O que uma falha do comando pre-sync faz: abort pula a sincronização (o padrão), continue sincroniza mesmo assim",
+	"What a subscribed folder does with files changed locally: revert or flag; defaults to revert":                            "O que uma pasta assinada faz com arquivos alterados localmente: revert ou flag; o padrão é revert",
+	"While Running %s: %s\n":                                       "Enquanto %s Executa: %s\n",
+	"Would fetch %s, %s to download\n":                             "Buscaria %s, %s a baixar\n",
+	"Would remove snapshot %s (%s)\n":                              "Removeria o snapshot %s (%s)\n",
+	"Write the bundle to a file instead of stdout":                 "Gravar o pacote em um arquivo em vez da saída padrão",
+	"Write the events to this file instead of the standard output": "Gravar os eventos neste arquivo em vez da saída padrão",
+	`Write the folder definitions, excludes and storage settings to a YAML bundle
+that can be imported on another machine. Credentials are included in plain text
+unless --redact-secrets is given or a passphrase is provided to encrypt them.`: `Grava as definições das pastas, as exclusões e as configurações de armazenamento em um pacote YAML
+que pode ser importado em outra máquina. As credenciais são incluídas em texto puro,
+a menos que --redact-secrets seja informado ou uma senha seja fornecida para criptografá-las.`,
+	`Writes the sync events the server keeps for your folders, such as conflicts, blocked
+deletions and files left out, one JSON object per line, the oldest first within each folder.
+--since limits them to a recent period, given in days (30d), as a duration (12h) or as an
+RFC 3339 time. The server removes events past the retention of each folder.`: `Grava os eventos de sincronização que o servidor guarda para as suas pastas, como conflitos,
+exclusões bloqueadas e arquivos deixados de fora, um objeto JSON por linha, os mais antigos
+primeiro em cada pasta. --since os limita a um período recente, dado em dias (30d), como duração
+(12h) ou como horário RFC 3339. O servidor remove os eventos além da retenção de cada pasta.`,
+	"You can now start the sync agent with: sync-manager start": "Agora você pode iniciar o agente de sincronização com: sync-manager start",
+	"agent is not running":                                     "o agente não está em execução",
+	"agent is not running, cannot monitor":                     "o agente não está em execução, não é possível monitorar",
+	"agent is not running, start it with 'sync-manager start'": "o agente não está em execução, inicie-o com 'sync-manager start'",
+	"agent is not running: %w":                                 "o agente não está em execução: %w",
+	"agent is running without a configuration file":            "o agente está sendo executado sem arquivo de configuração",
+	"agent is shutting down":                                   "o agente está sendo encerrado",
+	"all":                                                      "todos",
+	"allow":                                                    "permitir",
+	"an allow rule must be limited to a device":                "uma regra de permissão precisa ser limitada a um dispositivo",
+	"cancelled":                                                "cancelada",
+	"cannot access %s: %w":                                     "não foi possível acessar %s: %w",
+	"cannot access folder %s: %w":                              "não é possível acessar a pasta %s: %w",
+	"cannot unlink the current device. Use 'reset' command instead if you want to reconfigure this device": "não é possível desvincular o dispositivo atual. Use o comando 'reset' se quiser reconfigurar este dispositivo",
+	"certificate verification disabled":                                                      "verificação de certificado desativada",
+	"change --target and --remote-prefix separately":                                         "altere --target e --remote-prefix separadamente",
+	"check api_endpoint and the http proxy settings, or that the server is running":          "verifique api_endpoint e as configurações de proxy em http, ou se o servidor está em execução",
+	"check the endpoint and the proxy settings of the target, or that the server is running": "verifique o endpoint e as configurações de proxy do destino, ou se o servidor está em execução",
+	"configuration file is invalid: %w":                                                      "o arquivo de configuração é inválido: %w",
+	"create it, or remove the folder with 'sync-manager remove-folder %s'":                   "crie-o, ou remova a pasta com 'sync-manager remove-folder %s'",
+	"create the directory, or set removable: true if it is on a drive that can be unplugged": "crie o diretório, ou defina removable: true se ele estiver em uma unidade removível",
+	"credential is written in plain text in the configuration file":                          "a credencial está escrita em texto puro no arquivo de configuração",
+	"credentials and files are sent to %s without encryption":                                "credenciais e arquivos são enviados para %s sem criptografia",
+	"cron %s":                            "cron %s",
+	"database encryption is not enabled": "a criptografia do banco de dados não está ativada",
+	"database encryption is off; enable it with 'sync-manager config set database.encrypt true'": "a criptografia do banco de dados está desligada; ative-a com 'sync-manager config set database.encrypt true'",
+	"database value cannot be decrypted with the database key":                                   "o valor do banco de dados não pode ser descriptografado com a chave do banco de dados",
+	"device %s is not trusted":                                    "o dispositivo %s não é confiável",
+	"device name cannot be empty":                                 "o nome do dispositivo não pode ser vazio",
+	"device not found":                                            "dispositivo não encontrado",
+	"device with ID %s not found":                                 "dispositivo com ID %s não encontrado",
+	"downloaded and uploaded":                                     "baixados e enviados",
+	"email and password are required":                             "e-mail e senha são obrigatórios",
+	"error iterating folders: %w":                                 "erro ao percorrer as pastas: %w",
+	"exclude":                                                     "excluir",
+	"exclude pattern %q is invalid: %v":                           "o padrão de exclusão %q é inválido: %v",
+	"exclude pattern %q matches every file, so nothing is synced": "o padrão de exclusão %q casa com todos os arquivos, então nada é sincronizado",
+	"exclude pattern %q matches nothing":                          "o padrão de exclusão %q não casa com nada",
+	"exclude pattern is empty":                                    "o padrão de exclusão está vazio",
+	"exclude rule not found":                                      "regra de exclusão não encontrada",
+	"expected a key and a value, or several pairs of them":        "esperava uma chave e um valor, ou vários pares deles",
+	"failed":                                             "com falha",
+	"failed to add folder to device: %w":                 "falha ao adicionar a pasta ao dispositivo: %w",
+	"failed to allow deletions: %w":                      "falha ao permitir as exclusões: %w",
+	"failed to apply lifecycle policy: %w":               "falha ao aplicar a política de ciclo de vida: %w",
+	"failed to check agent status: %w":                   "falha ao verificar o estado do agente: %w",
+	"failed to check database integrity: %w":             "falha ao verificar a integridade do banco de dados: %w",
+	"failed to check the drive: %w":                      "falha ao verificar o disco: %w",
+	"failed to configure server connection: %w":          "falha ao configurar a conexão com o servidor: %w",
+	"failed to convert exclude patterns: %w":             "falha ao converter os padrões de exclusão: %w",
+	"failed to create bundle: %w":                        "falha ao criar o pacote: %w",
+	"failed to create database directory: %w":            "falha ao criar o diretório do banco de dados: %w",
+	"failed to create default user: %w":                  "erro ao criar usuário padrão: %w",
+	"failed to create exclude rule: %w":                  "erro ao criar regra de exclusão: %w",
+	"failed to create export file: %w":                   "falha ao criar o arquivo de exportação: %w",
+	"failed to create folder in database: %w":            "falha ao criar a pasta no banco de dados: %w",
+	"failed to create folder in the database: %w":        "erro ao criar pasta no banco de dados: %w",
+	"failed to create folder: %w":                        "falha ao criar a pasta: %w",
+	"failed to create keychain directory: %w":            "falha ao criar o diretório do chaveiro: %w",
+	"failed to create user: %w":                          "erro ao criar usuário: %w",
+	"failed to decrypt %s.%s of row %v: %w":              "falha ao descriptografar %s.%s da linha %v: %w",
+	"failed to decrypt the secret: %w":                   "falha ao descriptografar o segredo: %w",
+	"failed to delete device: %w":                        "erro ao excluir dispositivo: %w",
+	"failed to delete exclude rule: %w":                  "erro ao excluir regra de exclusão: %w",
+	"failed to delete folder from the database: %w":      "erro ao excluir pasta do banco de dados: %w",
+	"failed to delete folder: %w":                        "falha ao excluir a pasta: %w",
+	"failed to download %s: %w":                          "falha ao baixar %s: %w",
+	"failed to encrypt the secret: %w":                   "falha ao criptografar o segredo: %w",
+	"failed to export the events of folder %s: %w":       "falha ao exportar os eventos da pasta %s: %w",
+	"failed to fetch %s":                                 "falha ao buscar %s",
+	"failed to find bandwidth usage: %w":                 "erro ao buscar uso de banda: %w",
+	"failed to find current device: %w":                  "erro ao buscar dispositivo atual: %w",
+	"failed to find device: %w":                          "erro ao buscar dispositivo: %w",
+	"failed to find exclude rule: %w":                    "erro ao buscar regra de exclusão: %w",
+	"failed to find folder to associate: %w":             "erro ao buscar pasta para associação: %w",
+	"failed to find folder to delete: %w":                "erro ao buscar pasta para exclusão: %w",
+	"failed to find folder to pause: %w":                 "erro ao buscar pasta para pausa: %w",
+	"failed to find folder to update its status: %w":     "erro ao buscar pasta para atualização de status: %w",
+	"failed to find folder to update: %w":                "erro ao buscar pasta para atualização: %w",
+	"failed to find folders in the database: %w":         "erro ao buscar pastas do banco de dados: %w",
+	"failed to find sync runs: %w":                       "falha ao buscar as execuções de sincronização: %w",
+	"failed to find token: %w":                           "erro ao buscar token: %w",
+	"failed to find user preferences: %w":                "falha ao buscar as preferências do usuário: %w",
+	"failed to find user: %w":                            "erro ao buscar usuário: %w",
+	"failed to fix folder %s: %w":                        "erro ao corrigir pasta %s: %w",
+	"failed to generate database key: %w":                "falha ao gerar a chave do banco de dados: %w",
+	"failed to generate nonce: %w":                       "falha ao gerar o nonce: %w",
+	"failed to generate token: %w":                       "erro ao gerar token: %w",
+	"failed to get absolute path: %w":                    "falha ao obter o caminho absoluto: %w",
+	"failed to get default config path: %w":              "falha ao obter o caminho padrão da configuração: %w",
+	"failed to get device: %w":                           "falha ao obter o dispositivo: %w",
+	"failed to get folder ID: %w":                        "falha ao obter o ID da pasta: %w",
+	"failed to get folder: %w":                           "falha ao obter a pasta: %w",
+	"failed to get remote info for %s: %w":               "falha ao obter as informações remotas de %s: %w",
+	"failed to get user config directory: %w":            "falha ao obter o diretório de configuração do usuário: %w",
+	"failed to hash %s: %w":                              "falha ao calcular o hash de %s: %w",
+	"failed to label the drive: %w":                      "falha ao rotular o disco: %w",
+	"failed to list devices: %w":                         "erro ao listar dispositivos: %w",
+	"failed to list exclude rules: %w":                   "erro ao listar regras de exclusão: %w",
+	"failed to list folders: %w":                         "falha ao listar as pastas: %w",
+	"failed to list remote files: %w":                    "falha ao listar os arquivos remotos: %w",
+	"failed to list snapshots: %w":                       "falha ao listar os snapshots: %w",
+	"failed to list tokens: %w":                          "erro ao listar tokens: %w",
+	"failed to list users: %w":                           "erro ao listar usuários: %w",
+	"failed to load config: %w":                          "falha ao carregar a configuração: %w",
+	"failed to load database key: %w":                    "falha ao carregar a chave do banco de dados: %w",
+	"failed to load folder with preloads: %w":            "falha ao carregar pasta com preloads: %w",
+	"failed to migrate database schema: %w":              "falha ao migrar o esquema do banco de dados: %w",
+	"failed to move remote files: %w":                    "falha ao mover os arquivos remotos: %w",
+	"failed to open database: %w":                        "falha ao abrir o banco de dados: %w",
+	"failed to open storage: %w":                         "falha ao abrir o armazenamento: %w",
+	"failed to parse timestamp: %w":                      "falha ao interpretar a data: %w",
+	"failed to preview the initial merge: %w":            "falha ao pré-visualizar a mesclagem inicial: %w",
+	"failed to prune deleted rows: %w":                   "falha ao podar as linhas excluídas: %w",
+	"failed to prune snapshots: %w":                      "falha ao podar os snapshots: %w",
+	"failed to prune sync events: %w":                    "falha ao podar os eventos de sincronização: %w",
+	"failed to query folders: %w":                        "falha ao consultar as pastas: %w",
+	"failed to read %s: %w":                              "falha ao ler %s: %w",
+	"failed to read bundle: %w":                          "falha ao ler o pacote: %w",
+	"failed to read from the keychain: %s":               "falha ao ler do chaveiro: %s",
+	"failed to read from the keychain: %w":               "falha ao ler do chaveiro: %w",
+	"failed to register %s callback: %w":                 "falha ao registrar o callback %s: %w",
+	"failed to rekey database: %w":                       "falha ao trocar a chave do banco de dados: %w",
+	"failed to rename device: %w":                        "falha ao renomear o dispositivo: %w",
+	"failed to replace placeholder of %s: %w":            "falha ao substituir o marcador de %s: %w",
+	"failed to restore folder: %w":                       "falha ao restaurar a pasta: %w",
+	"failed to restore snapshot: %w":                     "falha ao restaurar o snapshot: %w",
+	"failed to revoke device tokens: %w":                 "erro ao revogar tokens do dispositivo: %w",
+	"failed to revoke token: %w":                         "erro ao revogar token: %w",
+	"failed to save configuration: %w":                   "falha ao salvar a configuração: %w",
+	"failed to save current device: %w":                  "erro ao salvar dispositivo atual: %w",
+	"failed to save database key: %w":                    "falha ao salvar a chave do banco de dados: %w",
+	"failed to save token: %w":                           "erro ao salvar token: %w",
+	"failed to save user preferences: %w":                "falha ao salvar as preferências do usuário: %w",
+	"failed to scan folder: %w":                          "falha ao varrer a pasta: %w",
+	"failed to select profile: %w":                       "falha ao selecionar o perfil: %w",
+	"failed to set permissions of %s: %w":                "falha ao definir as permissões de %s: %w",
+	"failed to stat %s: %w":                              "falha ao obter informações de %s: %w",
+	"failed to trigger sync for %s: %w":                  "falha ao disparar a sincronização de %s: %w",
+	"failed to trigger sync: %w":                         "falha ao disparar a sincronização: %w",
+	"failed to unlink device: %w":                        "falha ao desvincular o dispositivo: %w",
+	"failed to update %s: %w":                            "falha ao atualizar %s: %w",
+	"failed to update folder in the database: %w":        "erro ao atualizar pasta no banco de dados: %w",
+	"failed to update folder pause in the database: %w":  "erro ao atualizar pausa da pasta no banco de dados: %w",
+	"failed to update folder pause: %w":                  "falha ao atualizar a pausa da pasta: %w",
+	"failed to update folder status in the database: %w": "erro ao atualizar status da pasta no banco de dados: %w",
+	"failed to update folder status: %w":                 "falha ao atualizar o status da pasta: %w",
+	"failed to update folder: %w":                        "falha ao atualizar a pasta: %w",
+	"failed to update token usage: %w":                   "erro ao atualizar uso do token: %w",
+	"failed to vacuum database: %w":                      "falha ao compactar o banco de dados: %w",
+	"failed to verify token: %w":                         "erro ao verificar token: %w",
+	"failed to walk %s: %w":                              "falha ao percorrer %s: %w",
+	"failed to walk folder %s: %w":                       "falha ao percorrer a pasta %s: %w",
+	"failed to write bundle: %w":                         "falha ao gravar o pacote: %w",
+	"failed to write to the keychain: %s":                "falha ao gravar no chaveiro: %s",
+	"failed to write to the keychain: %w":                "falha ao gravar no chaveiro: %w",
+	"fix the pattern; the agent ignores it":              "corrija o padrão; o agente o ignora",
+	"flagged":                                            "sinalizadas",
+	"folder %s":                                          "pasta %s",
+	"folder %s (%s) is inside %s and already syncs part of it; run 'sync-manager config set nested_folders exclude' to have the new folder leave it out": "a pasta %s (%s) está dentro de %s e já sincroniza parte dela; execute 'sync-manager config set nested_folders exclude' para que a nova pasta a deixe de fora",
+	"folder %s has no root with prefix %s":                                          "a pasta %s não tem raiz com o prefixo %s",
+	"folder %s is %s in the database but %s in the configuration":                   "a pasta %s está %s no banco de dados, mas %s na configuração",
+	"folder %s is already configured":                                               "a pasta %s já está configurada",
+	"folder %s is configured but missing from the database":                         "a pasta %s está configurada, mas não está no banco de dados",
+	"folder %s is in backup mode; use 'snapshots %s' to inspect its snapshots":      "a pasta %s está em modo backup; use 'snapshots %s' para inspecionar seus snapshots",
+	"folder %s is in the database but no longer configured":                         "a pasta %s está no banco de dados, mas não está mais configurada",
+	"folder %s is not in the configuration":                                         "pasta %s não está na configuração",
+	"folder %s uses unknown storage target %s":                                      "a pasta %s usa o destino de armazenamento desconhecido %s",
+	"folder is disabled: %s":                                                        "a pasta está desativada: %s",
+	"folder not found in sync configuration: %s":                                    "pasta não encontrada na configuração de sincronização: %s",
+	"folder not found: %s":                                                          "pasta não encontrada: %s",
+	"folder sync":                                                                   "sincronização da pasta",
+	"folder with ID %s not found":                                                   "pasta com ID %s não encontrada",
+	"full sync":                                                                     "sincronização completa",
+	"global":                                                                        "global",
+	"interval cannot be negative":                                                   "o intervalo não pode ser negativo",
+	"invalid --since %q: use days (30d), a duration (12h) or an RFC 3339 time":      "--since inválido %q: use dias (30d), uma duração (12h) ou um horário RFC 3339",
+	"invalid age filter: %w":                                                        "filtro de idade inválido: %w",
+	"invalid archive policy: %w":                                                    "política de arquivamento inválida: %w",
+	"invalid bandwidth value: %s (must be a number)":                                "valor de banda inválido: %s (deve ser um número)",
+	"invalid bandwidth value: %s (must be a number, 0 for no limit)":                "valor de banda inválido: %s (deve ser um número, 0 para sem limite)",
+	"invalid bandwidth value: %s (must be a positive number of bytes/sec)":          "valor de banda inválido: %s (deve ser um número positivo de bytes/s)",
+	"invalid boolean value: %s":                                                     "valor booleano inválido: %s",
+	"invalid checksum: %w":                                                          "checksum inválido: %w",
+	"invalid chunk size: %s (must be at least 1048576 bytes)":                       "tamanho de bloco inválido: %s (deve ser de pelo menos 1048576 bytes)",
+	"invalid clock skew: %s (use a duration like 1m)":                               "diferença de relógio inválida: %s (use uma duração como 1m)",
+	"invalid concurrency: %s (must be between 1 and 32)":                            "concorrência inválida: %s (deve estar entre 1 e 32)",
+	"invalid configuration %s: %w":                                                  "configuração inválida %s: %w",
+	"invalid database key: %d bytes, expected %d":                                   "chave do banco de dados inválida: %d bytes, esperados %d",
+	"invalid database key: %w":                                                      "chave do banco de dados inválida: %w",
+	"invalid email %q":                                                              "e-mail inválido %q",
+	"invalid exclude pattern %q: %w":                                                "padrão de exclusão inválido %q: %w",
+	"invalid file size: %s (must be a positive number of bytes)":                    "tamanho de arquivo inválido: %s (deve ser um número positivo de bytes)",
+	"invalid folder mode %q: must be %s or %s":                                      "modo de pasta inválido %q: deve ser %s ou %s",
+	"invalid hashing bandwidth value: %s (must be a number, 0 for no limit)":        "valor de banda de hash inválido: %s (deve ser um número, 0 para sem limite)",
+	"invalid hooks: %w":                                                             "hooks inválidos: %w",
+	"invalid initial merge: %w":                                                     "mesclagem inicial inválida: %w",
+	"invalid listen address: %s (use host:port or :port)":                           "endereço de escuta inválido: %s (use host:porta ou :porta)",
+	"invalid max file size: %s (bytes, 0 for the storage limit, negative for none)": "tamanho máximo de arquivo inválido: %s (bytes, 0 para o limite do armazenamento, negativo para nenhum)",
+	"invalid memory limit: %s (must be a number of bytes, 0 for none)":              "limite de memória inválido: %s (deve ser um número de bytes, 0 para nenhum)",
+	"invalid month %q: use YYYY-MM":                                                 "mês inválido %q: use AAAA-MM",
+	"invalid monthly cap: %s (bytes, 0 for no cap)":                                 "limite mensal inválido: %s (bytes, 0 para sem limite)",
+	"invalid packing: %w":                                                           "empacotamento inválido: %w",
+	"invalid pattern %s: %w":                                                        "padrão inválido %s: %w",
+	"invalid remote prefix: %w":                                                     "prefixo remoto inválido: %w",
+	"invalid root %q, expected PREFIX=PATH":                                         "raiz inválida %q, esperado PREFIXO=CAMINHO",
+	"invalid schedule: %w":                                                          "agenda inválida: %w",
+	"invalid scheduling weight: %s (must be a number, 0 to ignore it)":              "peso de agendamento inválido: %s (deve ser um número, 0 para ignorá-lo)",
+	"invalid secret in the keychain: %w":                                            "segredo inválido no chaveiro: %w",
+	"invalid staging area size: %s (must be a positive number of bytes)":            "tamanho de área de staging inválido: %s (deve ser um número positivo de bytes)",
+	"invalid subscription: %w":                                                      "assinatura inválida: %w",
+	"invalid timeout: %s (use a duration like 5s)":                                  "tempo limite inválido: %s (use uma duração como 5s)",
+	"invalid token ID %q":                                                           "ID de token inválido %q",
+	"invalid token lifetime %q":                                                     "validade de token inválida %q",
+	"invalid token lifetime %q: use days (90d) or a duration (12h)":                 "validade de token inválida %q: use dias (90d) ou uma duração (12h)",
+	"invalid upload queue size: %s (must be a positive number)":                     "tamanho da fila de upload inválido: %s (deve ser um número positivo)",
+	"keep": "manter",
+	"narrow the pattern, or pause the folder with 'sync-manager pause-folder' instead": "restrinja o padrão, ou pause a pasta com 'sync-manager pause-folder'",
+	"never":                           "nunca",
+	"no API token to reach the agent": "nenhum token de API para falar com o agente",
+	"no database key stored":          "nenhuma chave do banco de dados guardada",
+	"no retention policy configured for folder %s; use configure-folder or the --keep-* flags": "nenhuma política de retenção configurada para a pasta %s; use configure-folder ou as flags --keep-*",
+	"no rule matches: %s":                    "nenhuma regra corresponde: %s",
+	"no supported keychain on this platform": "nenhum chaveiro suportado nesta plataforma",
+	"none":                                   "nenhum",
+	"not synced yet":                         "ainda não sincronizado",
+	"only files up to %d bytes":              "somente arquivos de até %d bytes",
+	"overwrite":                              "sobrescrever",
+	"path %s must be relative to the folder": "o caminho %s deve ser relativo à pasta",
+	"path sync":                              "sincronização de caminho",
+	"patterns match paths relative to the folder, like *.tmp or build/*; fix or remove it":     "os padrões casam com caminhos relativos à pasta, como *.tmp ou build/*; corrija-o ou remova-o",
+	"pause the folder ('pause-folder %s') or stop the agent before changing its remote prefix": "pause a pasta ('pause-folder %s') ou pare o agente antes de alterar seu prefixo remoto",
+	"periodic sync": "sincronização periódica",
+	"proxy %s":      "proxy %s",
+	"remote prefix %s must be the ID of a single remote folder":      "o prefixo remoto %s deve ser o ID de uma única pasta remota",
+	"remove one of the folders with 'sync-manager remove-folder %s'": "remova uma das pastas com 'sync-manager remove-folder %s'",
+	"restore interrupted; run the same command again to resume":      "restauração interrompida; execute o mesmo comando de novo para retomá-la",
+	"retention days cannot be negative":                              "os dias de retenção não podem ser negativos",
+	"reverted":                                                       "revertidas",
+	"run 'chmod 600 %s'":                                             "execute 'chmod 600 %s'",
+	"run 'sync-manager config set nested_folders exclude' to sync both, or remove the inner folder": "execute 'sync-manager config set nested_folders exclude' para sincronizar as duas, ou remova a pasta interna",
+	"secret not found in the keychain":                                                                       "segredo não encontrado no chaveiro",
+	"set %smax_file_size before using the small-files action":                                                "defina %smax_file_size antes de usar a ação small-files",
+	"set %sthrottle before using the throttle action":                                                        "defina %sthrottle antes de usar a ação throttle",
+	"set it to a ${VAR} reference to an environment variable, or to file:<path> of a file only you can read": "use uma referência ${VAR} a uma variável de ambiente, ou file:<caminho> de um arquivo que só você pode ler",
+	"set use_ssl: true, or use an https:// endpoint":                                                         "defina use_ssl: true, ou use um endpoint https://",
+	"single-file folders cannot be backup folders":                                                           "pastas de um único arquivo não podem ser pastas de backup",
+	"skipped":                                               "ignorados",
+	"snapshot %s not found for folder %s":                   "snapshot %s não encontrado para a pasta %s",
+	"specify either --global or --folder <id>":              "especifique --global ou --folder <id>",
+	"storage is not available to move the remote files":     "o armazenamento não está disponível para mover os arquivos remotos",
+	"storage is not available to preview the initial merge": "o armazenamento não está disponível para pré-visualizar a mesclagem inicial",
+	"storage limit":                                         "limite do armazenamento",
+	"storage target %s is %s, not a local directory":        "o destino de armazenamento %s é %s, não um diretório local",
+	"storage target %s is %s, which needs no sign-in":       "o destino de armazenamento %s é %s, que não precisa de login",
+	"storage target %s is not served by a plugin":           "o destino de armazenamento %s não é atendido por um plugin",
+	"storage target %s not found (configured: %s)":          "destino de armazenamento %s não encontrado (configurados: %s)",
+	"succeeded": "concluída",
+	"sync":      "sincronização",
+	"sync events are kept by the server; log in to it with 'sync-manager login' first": "os eventos de sincronização são guardados pelo servidor; entre nele primeiro com 'sync-manager login'",
+	"sync failed: %s": "a sincronização falhou: %s",
+	"the API token is sent to %s without encryption":                                     "o token da API é enviado para %s sem criptografia",
+	"the agent has not reported progress since %s; it may have stopped":                  "o agente não informa o progresso desde %s; ele pode ter parado",
+	"the agent refused the API token in %s; create one with 'sync-manager token create'": "o agente recusou o token de API em %s; crie um com 'sync-manager token create'",
+	"the agent stopped before the sync ended":                                            "o agente parou antes de a sincronização terminar",
+	"the configuration has %d warning(s)":                                                "a configuração tem %d aviso(s)",
+	"the database is corrupt; restore it from a backup":                                  "o banco de dados está corrompido; restaure-o de um backup",
+	"the file holds credentials and other users can read it (mode %v)":                   "o arquivo contém credenciais e outros usuários podem lê-lo (modo %v)",
+	"the remote copy of %s changed since it was archived":                                "a cópia remota de %s mudou desde que foi arquivada",
+	"the sync of %s did not succeed":                                                     "a sincronização de %s não teve sucesso",
+	"this device":                                                                        "este dispositivo",
+	"this device has no ID yet, run 'sync-manager init' first":                           "este dispositivo ainda não tem ID, execute 'sync-manager init' primeiro",
+	"this device is registered to another user; give each user a profile of its own with 'sync-manager config profile create'": "este dispositivo está registrado para outro usuário; dê a cada usuário um perfil próprio com 'sync-manager config profile create'",
+	"throttle to %d bytes/sec":                         "limitar a %d bytes/s",
+	"token lifetime must be positive":                  "a validade do token deve ser positiva",
+	"token name is empty":                              "o nome do token está vazio",
+	"unknown":                                          "desconhecido",
+	"unknown configuration key: %s":                    "chave de configuração desconhecida: %s",
+	"unsupported export format: %s (supported: jsonl)": "formato de exportação não suportado: %s (suportado: jsonl)",
+	"unsupported language %q (supported: %s)":          "idioma não suportado %q (suportados: %s)",
+	"unsupported power action: %s (supported: none, pause, throttle, small-files)":                                 "ação de energia não suportada: %s (suportadas: none, pause, throttle, small-files)",
+	"unsupported storage provider: %s (supported: s3, minio, gcs, local, onedrive or an installed storage plugin)": "provedor de armazenamento não suportado: %s (suportados: s3, minio, gcs, local, onedrive ou um plugin de armazenamento instalado)",
+	"use an https:// endpoint":         "use um endpoint https://",
+	"user %s already exists":           "o usuário %s já existe",
+	"user not found":                   "usuário não encontrado",
+	"verification failed for %s in %s": "a verificação falhou para %s em %s",
+	"⚠ Agent is not running. Start it with 'sync-manager start'.": "⚠ O agente não está em execução. Inicie-o com 'sync-manager start'.",
+	"⚠ File watches: %v\n":                       "⚠ Watches de arquivos: %v\n",
+	"⚠ Folder %s: %s cannot be read: %v\n":       "⚠ Pasta %s: %s não pode ser lida: %v\n",
+	"⚠ Folder records: %s\n":                     "⚠ Registros de pastas: %s\n",
+	"⚠ Instance lock: %v\n":                      "⚠ Trava de instância: %v\n",
+	"✓ Agent is running":                         "✓ O agente está em execução",
+	"✓ Folder records match the configuration":   "✓ Os registros de pastas correspondem à configuração",
+	"✓ No problems found":                        "✓ Nenhum problema encontrado",
+	"✓ Repaired %s to match the configuration\n": "✓ %s reparado para corresponder à configuração\n",
+	"🌐 Storage %s: endpoint %s":                  "🌐 Armazenamento %s: endpoint %s",
+	"🔄 %s running since %s: %d/%d folders done":  "🔄 %s em execução desde %s: %d/%d pastas concluídas",
+
+	// Labels put together at run time: folder states, units counted by pluralize, token
+	// states, initial merge actions and the answers to yes/no prompts
+	"Idle":           "Ocioso",
+	"Scanning":       "Varrendo",
+	"Syncing":        "Sincronizando",
+	"Error":          "Erro",
+	"Unknown":        "Desconhecido",
+	"file":           "arquivo",
+	"files":          "arquivos",
+	"day":            "dia",
+	"days":           "dias",
+	"snapshot":       "snapshot",
+	"snapshots":      "snapshots",
+	"minute":         "minuto",
+	"minutes":        "minutos",
+	"hour":           "hora",
+	"hours":          "horas",
+	"folder record":  "registro de pasta",
+	"folder records": "registros de pastas",
+	"remote file":    "arquivo remoto",
+	"remote files":   "arquivos remotos",
+	"active":         "ativo",
+	"expired":        "expirado",
+	"revoked":        "revogado",
+	"upload":         "enviar",
+	"download":       "baixar",
+	"identical":      "idêntico",
+	"keep local":     "manter local",
+	"keep remote":    "manter remoto",
+	"keep both":      "manter ambos",
+	"y":              "s",
+	"yes":            "sim",
+	"n":              "n",
+	"no":             "não",
+}
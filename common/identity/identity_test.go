@@ -0,0 +1,48 @@
+package identity
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadGeneratesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-manager", "device-identity.pem")
+
+	cert, err := Load(path, "laptop")
+	assert.NoError(t, err)
+	assert.Equal(t, "laptop", cert.Leaf.Subject.CommonName)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// The same identity is kept across loads
+	again, err := Load(path, "laptop")
+	assert.NoError(t, err)
+	assert.Equal(t, Fingerprint(cert.Certificate[0]), Fingerprint(again.Certificate[0]))
+
+	_, err = Load(path, "desktop")
+	assert.ErrorContains(t, err, "belongs to device laptop")
+}
+
+func TestNormalizeFingerprint(t *testing.T) {
+	fingerprint := strings.Repeat("ab", 32)
+
+	normalized, err := NormalizeFingerprint(strings.ToUpper(fingerprint))
+	assert.NoError(t, err)
+	assert.Equal(t, fingerprint, normalized)
+
+	colons := strings.TrimSuffix(strings.Repeat("AB:", 32), ":")
+	normalized, err = NormalizeFingerprint(colons)
+	assert.NoError(t, err)
+	assert.Equal(t, fingerprint, normalized)
+
+	for _, invalid := range []string{"", "abcd", strings.Repeat("zz", 32)} {
+		_, err := NormalizeFingerprint(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,176 @@
+// Package instance keeps two agents from running on the same configuration. The agent holds
+// a lock file next to the configuration and a row in the shared database, and refreshes the
+// heartbeat in both while it runs. A lock whose heartbeat stopped, or whose process is gone,
+// is taken over by the next agent, so a crash never leaves the configuration locked.
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HeartbeatInterval is how often the agent holding the lock refreshes its heartbeat
+const HeartbeatInterval = 10 * time.Second
+
+// StaleAfter is how old a heartbeat may be for its agent to still count as running
+const StaleAfter = 3 * HeartbeatInterval
+
+// TakeoverFlag makes the agent take the lock from the agent holding it, which stops once it
+// notices
+const TakeoverFlag = "--takeover"
+
+// ErrLost is returned by Lock.Run when another agent took the lock over
+var ErrLost = errors.New("another agent took over the instance lock")
+
+// Holder is the agent holding the lock
+type Holder struct {
+	ID        string    `json:"id"` // Unique to each run of the agent
+	Hostname  string    `json:"hostname"`
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+// Alive reports whether the agent holding the lock is still running: its heartbeat is recent
+// and, when it runs on this machine, its process exists
+func (h Holder) Alive() bool {
+	if time.Since(h.Heartbeat) >= StaleAfter {
+		return false
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname == h.Hostname {
+		return processExists(h.PID)
+	}
+	return true
+}
+
+// String describes the holder for messages
+func (h Holder) String() string {
+	return fmt.Sprintf("pid %d on %s, started %s", h.PID, h.Hostname, h.StartedAt.Local().Format(time.RFC3339))
+}
+
+// LockedError is returned by Acquire when another agent runs on the configuration
+type LockedError struct {
+	Holder Holder
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("another agent is already running on this configuration (%s, last seen %s ago); stop it first, or start this agent with %s if that one is stuck",
+		e.Holder, time.Since(e.Holder.Heartbeat).Round(time.Second), TakeoverFlag)
+}
+
+// TakeoverFromArgs reports whether the command line asks to take the lock over
+func TakeoverFromArgs(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == TakeoverFlag {
+			return true
+		}
+	}
+	return false
+}
+
+// LockPath returns the lock file of the configuration at configPath
+func LockPath(configPath string) string {
+	return configPath + ".lock"
+}
+
+// Holders returns the agents holding the lock of a configuration, in the lock file at path
+// and in the database at dsn when set, most recent heartbeat first. Holders that stopped are
+// included; more than one alive means two agents run on the configuration.
+func Holders(path, dsn, deviceID string) ([]Holder, error) {
+	var holders []Holder
+	fileHolder, err := readLock(path)
+	if err != nil {
+		return nil, err
+	}
+	if fileHolder != nil {
+		holders = append(holders, *fileHolder)
+	}
+	if dsn != "" {
+		registry := newRegistry(dsn, deviceID, false)
+		defer registry.close()
+		dbHolder, err := registry.read()
+		if err != nil {
+			return nil, err
+		}
+		if dbHolder != nil && (fileHolder == nil || dbHolder.ID != fileHolder.ID) {
+			holders = append(holders, *dbHolder)
+		}
+	}
+	sort.Slice(holders, func(i, j int) bool { return holders[i].Heartbeat.After(holders[j].Heartbeat) })
+	return holders, nil
+}
+
+// readLock reads the holder in the lock file at path, nil when there is none
+func readLock(path string) (*Holder, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read instance lock: %w", err)
+	}
+	var holder Holder
+	if err := json.Unmarshal(data, &holder); err != nil {
+		// A lock cut short by a crash holds nothing
+		return nil, nil
+	}
+	return &holder, nil
+}
+
+// writeLock replaces the lock file at path with holder
+func writeLock(path string, holder Holder) error {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return fmt.Errorf("failed to marshal instance lock: %w", err)
+	}
+	tempFile := path + "." + holder.ID + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write instance lock: %w", err)
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace instance lock: %w", err)
+	}
+	return nil
+}
+
+// createLock writes holder to the lock file at path, failing with os.ErrExist when another
+// agent created it first
+func createLock(path string, holder Holder) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(holder)
+	if err == nil {
+		_, err = file.Write(data)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write instance lock: %w", err)
+	}
+	return nil
+}
+
+// newHolder returns the holder for this run of the agent
+func newHolder() Holder {
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	return Holder{ID: uuid.New().String(), Hostname: hostname, PID: os.Getpid(), StartedAt: now, Heartbeat: now}
+}
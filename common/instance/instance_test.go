@@ -0,0 +1,93 @@
+package instance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquire(t *testing.T) {
+	dir := t.TempDir()
+	path := LockPath(filepath.Join(dir, "config.yaml"))
+	dsn := filepath.Join(dir, "sync-manager.db")
+
+	first, err := Acquire(path, dsn, "desktop", false)
+	assert.NoError(t, err)
+	assert.Nil(t, first.Previous)
+
+	// A second agent on the configuration is refused, naming the one running
+	_, err = Acquire(path, dsn, "desktop", false)
+	var locked *LockedError
+	if assert.True(t, errors.As(err, &locked)) {
+		assert.Equal(t, first.Holder().ID, locked.Holder.ID)
+		assert.Contains(t, err.Error(), "start this agent with --takeover")
+	}
+
+	// Both the file and the database name the holder
+	holders, err := Holders(path, dsn, "desktop")
+	assert.NoError(t, err)
+	if assert.Len(t, holders, 1) {
+		assert.Equal(t, first.Holder().ID, holders[0].ID)
+		assert.True(t, holders[0].Alive())
+	}
+
+	// Taking over makes the first agent lose the lock at its next heartbeat
+	second, err := Acquire(path, dsn, "desktop", true)
+	assert.NoError(t, err)
+	if assert.NotNil(t, second.Previous) {
+		assert.Equal(t, first.Holder().ID, second.Previous.ID)
+	}
+	assert.ErrorIs(t, first.beat(), ErrLost)
+	assert.NoError(t, second.beat())
+
+	// The first agent stopping leaves the lock of the second alone
+	assert.NoError(t, first.Release())
+	holders, err = Holders(path, dsn, "desktop")
+	assert.NoError(t, err)
+	if assert.Len(t, holders, 1) {
+		assert.Equal(t, second.Holder().ID, holders[0].ID)
+	}
+
+	assert.NoError(t, second.Release())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	holders, err = Holders(path, dsn, "desktop")
+	assert.NoError(t, err)
+	assert.Empty(t, holders)
+}
+
+func TestAcquireStaleLock(t *testing.T) {
+	path := LockPath(filepath.Join(t.TempDir(), "config.yaml"))
+
+	// A lock whose heartbeat stopped is taken without --takeover
+	stale := newHolder()
+	stale.Heartbeat = time.Now().Add(-StaleAfter)
+	assert.NoError(t, writeLock(path, stale))
+	lock, err := Acquire(path, "", "desktop", false)
+	assert.NoError(t, err)
+	assert.Nil(t, lock.Previous)
+	assert.NoError(t, lock.Release())
+
+	// So is one left by a process of this machine that is gone
+	crashed := newHolder()
+	crashed.PID = 0
+	assert.NoError(t, writeLock(path, crashed))
+	assert.False(t, crashed.Alive())
+	lock, err = Acquire(path, "", "desktop", false)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+
+	// Holders on other machines only go stale with their heartbeat
+	remote := Holder{ID: "remote", Hostname: "other-host.invalid", PID: 1, Heartbeat: time.Now()}
+	assert.True(t, remote.Alive())
+}
+
+func TestTakeoverFromArgs(t *testing.T) {
+	assert.True(t, TakeoverFromArgs([]string{"--profile", "work", "--takeover"}))
+	assert.False(t, TakeoverFromArgs([]string{"--profile", "work"}))
+	assert.False(t, TakeoverFromArgs([]string{"--", "--takeover"}))
+}
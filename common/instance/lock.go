@@ -0,0 +1,167 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Lock is the instance lock held by the running agent
+type Lock struct {
+	path     string
+	registry *registry // Nil without a database
+	holder   Holder
+
+	// Previous is the agent the lock was taken from, nil when the lock was free
+	Previous *Holder
+
+	mu       sync.Mutex
+	released bool
+}
+
+// Acquire takes the lock of a configuration, kept in the lock file at path and, when dsn is
+// set, in the database shared with other machines. It fails with a *LockedError while
+// another agent runs on the configuration, unless takeover is set: that agent then stops at
+// its next heartbeat. Failing to reach the database only loses the check across machines.
+func Acquire(path, dsn, deviceID string, takeover bool) (*Lock, error) {
+	l := &Lock{path: path, holder: newHolder()}
+	if dsn != "" {
+		l.registry = newRegistry(dsn, deviceID, true)
+	}
+
+	// The database sees agents on other machines sharing the configuration
+	if l.registry != nil {
+		current, err := l.registry.read()
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to read the agent instance from the database")
+		} else if current != nil && current.Alive() {
+			if !takeover {
+				l.registry.close()
+				return nil, &LockedError{Holder: *current}
+			}
+			l.Previous = current
+		}
+	}
+
+	err := createLock(path, l.holder)
+	if errors.Is(err, os.ErrExist) {
+		var current *Holder
+		if current, err = readLock(path); err == nil {
+			if current != nil && current.Alive() {
+				if !takeover {
+					l.close()
+					return nil, &LockedError{Holder: *current}
+				}
+				l.Previous = current
+			}
+			// A stopped agent left the lock, or it is taken over
+			err = writeLock(path, l.holder)
+		}
+	}
+	if err != nil {
+		l.close()
+		return nil, err
+	}
+
+	if l.registry != nil {
+		if err := l.registry.write(l.holder); err != nil {
+			log.Warn().Err(err).Msg("Failed to record the agent instance in the database")
+		}
+	}
+	return l, nil
+}
+
+// Holder returns the holder of the lock, this agent
+func (l *Lock) Holder() Holder {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.holder
+}
+
+// Run refreshes the heartbeat every HeartbeatInterval until ctx is cancelled, or until
+// another agent takes the lock over, when it returns an error wrapping ErrLost
+func (l *Lock) Run(ctx context.Context) error {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := l.beat(); err != nil {
+			if errors.Is(err, ErrLost) {
+				return err
+			}
+			log.Warn().Err(err).Msg("Failed to refresh the instance lock")
+		}
+	}
+}
+
+// beat checks the lock is still held by this agent and refreshes its heartbeat
+func (l *Lock) beat() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return nil
+	}
+
+	current, err := readLock(l.path)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.ID != l.holder.ID {
+		return fmt.Errorf("%w: %s", ErrLost, current)
+	}
+	if l.registry != nil {
+		if current, err := l.registry.read(); err == nil && current != nil && current.ID != l.holder.ID && current.Alive() {
+			return fmt.Errorf("%w: %s", ErrLost, current)
+		}
+	}
+
+	l.holder.Heartbeat = time.Now()
+	if err := writeLock(l.path, l.holder); err != nil {
+		return err
+	}
+	if l.registry != nil {
+		return l.registry.write(l.holder)
+	}
+	return nil
+}
+
+// Release gives the lock up, leaving it alone when another agent took it over
+func (l *Lock) Release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return nil
+	}
+	l.released = true
+	defer l.close()
+
+	current, err := readLock(l.path)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.ID == l.holder.ID {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove instance lock: %w", err)
+		}
+	}
+	if l.registry != nil {
+		return l.registry.remove(l.holder.ID)
+	}
+	return nil
+}
+
+// close closes the database
+func (l *Lock) close() {
+	if l.registry != nil {
+		l.registry.close()
+	}
+}
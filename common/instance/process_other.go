@@ -0,0 +1,8 @@
+//go:build !unix
+
+package instance
+
+// processExists cannot tell on this platform, so only the heartbeat decides
+func processExists(pid int) bool {
+	return true
+}
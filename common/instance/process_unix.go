@@ -0,0 +1,17 @@
+//go:build unix
+
+package instance
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processExists reports whether a process with the given pid runs on this machine
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,132 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/martinshumberto/sync-manager/common/database"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// registry keeps the holder of a device's configuration in the shared database
+type registry struct {
+	dsn      string
+	deviceID string
+	writable bool // Creates the table; a reader finds nothing until it exists
+	db       *gorm.DB
+}
+
+// newRegistry returns the registry of deviceID in the database at dsn
+func newRegistry(dsn, deviceID string, writable bool) *registry {
+	return &registry{dsn: dsn, deviceID: deviceID, writable: writable}
+}
+
+// read returns the holder recorded in the database, nil when there is none
+func (r *registry) read() (*Holder, error) {
+	db, err := r.open()
+	if err != nil || db == nil {
+		return nil, err
+	}
+	var row models.AgentInstance
+	if err := db.Where("device_id = ?", r.deviceID).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read agent instance: %w", err)
+	}
+	return &Holder{ID: row.HolderID, Hostname: row.Hostname, PID: row.PID, StartedAt: row.StartedAt, Heartbeat: row.HeartbeatAt}, nil
+}
+
+// write records holder in the database, in place of the one there
+func (r *registry) write(holder Holder) error {
+	db, err := r.open()
+	if err != nil || db == nil {
+		return err
+	}
+	row := models.AgentInstance{
+		DeviceID:    r.deviceID,
+		HolderID:    holder.ID,
+		Hostname:    holder.Hostname,
+		PID:         holder.PID,
+		StartedAt:   holder.StartedAt,
+		HeartbeatAt: holder.Heartbeat,
+	}
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "device_id"}},
+		UpdateAll: true,
+	}).Create(&row).Error
+	if err != nil {
+		return fmt.Errorf("failed to record agent instance: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the record of holderID, leaving one written by another agent
+func (r *registry) remove(holderID string) error {
+	db, err := r.open()
+	if err != nil || db == nil {
+		return err
+	}
+	err = db.Where("device_id = ? AND holder_id = ?", r.deviceID, holderID).Delete(&models.AgentInstance{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to remove agent instance: %w", err)
+	}
+	return nil
+}
+
+// close closes the database when it was opened
+func (r *registry) close() {
+	if r.db != nil {
+		closeDB(r.db)
+		r.db = nil
+	}
+}
+
+// open opens the database, creating the instances table when writable. A reader gets nil
+// while the database or the table does not exist.
+func (r *registry) open() (*gorm.DB, error) {
+	if r.db != nil {
+		return r.db, nil
+	}
+
+	dialector, driver, err := database.Dialector(r.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == database.SQLite {
+		if _, err := os.Stat(r.dsn); os.IsNotExist(err) && !r.writable {
+			return nil, nil
+		}
+		// The CLI may be reading at the same time
+		dialector = sqlite.Open("file:" + r.dsn + "?_busy_timeout=5000")
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if !r.writable && !db.Migrator().HasTable(&models.AgentInstance{}) {
+		closeDB(db)
+		return nil, nil
+	}
+	if r.writable {
+		if err := db.AutoMigrate(&models.AgentInstance{}); err != nil {
+			closeDB(db)
+			return nil, fmt.Errorf("failed to create agent instances table: %w", err)
+		}
+	}
+	r.db = db
+	return db, nil
+}
+
+// closeDB closes the connections of db
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
@@ -0,0 +1,44 @@
+// Package keychain keeps secrets of sync-manager in the keychain of the operating system: the
+// login keychain on macOS, the Secret Service (GNOME Keyring, KWallet) on Linux and
+// DPAPI-protected files on Windows.
+package keychain
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/common/i18n"
+)
+
+// Service names the secrets of sync-manager in the keychain
+const Service = "sync-manager"
+
+var (
+	// ErrNotFound is returned when the keychain holds no secret for the account
+	ErrNotFound = i18n.NewError("secret not found in the keychain")
+	// ErrUnsupported is returned on platforms without a supported keychain
+	ErrUnsupported = i18n.NewError("no supported keychain on this platform")
+)
+
+// Get returns the secret stored for account
+func Get(account string) ([]byte, error) {
+	encoded, err := get(account)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, i18n.Errorf("invalid secret in the keychain: %w", err)
+	}
+	return secret, nil
+}
+
+// Set stores secret for account, replacing the one stored before
+func Set(account string, secret []byte) error {
+	return set(account, base64.StdEncoding.EncodeToString(secret))
+}
+
+// Delete removes the secret stored for account, if any
+func Delete(account string) error {
+	return remove(account)
+}
@@ -0,0 +1,51 @@
+package keychain
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/common/i18n"
+)
+
+// get reads a generic password of the login keychain with the security tool
+func get(account string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "find-generic-password", "-s", Service, "-a", account, "-w")
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		// Exit status 44 is errSecItemNotFound
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+			return "", ErrNotFound
+		}
+		return "", i18n.Errorf("failed to read from the keychain: %s", strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// set adds a generic password to the login keychain, updating it when it exists
+func set(account, secret string) error {
+	output, err := exec.Command("security", "add-generic-password", "-U", "-s", Service, "-a", account, "-w", secret).CombinedOutput()
+	if err != nil {
+		return i18n.Errorf("failed to write to the keychain: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// remove deletes the generic password of the login keychain, if there is one
+func remove(account string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "delete-generic-password", "-s", Service, "-a", account)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+			return nil
+		}
+		return i18n.Errorf("failed to delete from the keychain: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package keychain
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/common/i18n"
+)
+
+// get looks the secret up in the Secret Service with secret-tool, from libsecret
+func get(account string) (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", i18n.Errorf("%w: install secret-tool (libsecret) to use the Secret Service", ErrUnsupported)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", Service, "account", account)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		// secret-tool exits with 1 and prints nothing when no item matches
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+		return "", i18n.Errorf("failed to read from the keychain: %s", strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// set stores the secret in the Secret Service, passing it on stdin so it never shows in the
+// process list
+func set(account, secret string) error {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return i18n.Errorf("%w: install secret-tool (libsecret) to use the Secret Service", ErrUnsupported)
+	}
+
+	cmd := exec.Command("secret-tool", "store", "--label", Service+" "+account, "service", Service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return i18n.Errorf("failed to write to the keychain: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// remove clears the secret from the Secret Service; clearing a missing item is not an error
+func remove(account string) error {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return i18n.Errorf("%w: install secret-tool (libsecret) to use the Secret Service", ErrUnsupported)
+	}
+
+	output, err := exec.Command("secret-tool", "clear", "service", Service, "account", account).CombinedOutput()
+	if err != nil {
+		return i18n.Errorf("failed to delete from the keychain: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
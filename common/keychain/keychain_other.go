@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !windows
+
+package keychain
+
+// get reports ErrUnsupported on platforms without a supported keychain
+func get(account string) (string, error) {
+	return "", ErrUnsupported
+}
+
+// set reports ErrUnsupported on platforms without a supported keychain
+func set(account, secret string) error {
+	return ErrUnsupported
+}
+
+// remove reports ErrUnsupported on platforms without a supported keychain
+func remove(account string) error {
+	return ErrUnsupported
+}
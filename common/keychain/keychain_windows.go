@@ -0,0 +1,80 @@
+package keychain
+
+import (
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/martinshumberto/sync-manager/common/i18n"
+)
+
+// path returns the file holding the secret of account, encrypted with DPAPI so only the
+// current Windows user can read it
+func path(account string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", i18n.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, Service, account+".dpapi"), nil
+}
+
+// get decrypts the secret of account with CryptUnprotectData
+func get(account string) (string, error) {
+	file, err := path(account)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", i18n.Errorf("failed to read from the keychain: %w", err)
+	}
+
+	in := windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return "", i18n.Errorf("failed to decrypt the secret: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return string(unsafe.Slice(out.Data, out.Size)), nil
+}
+
+// set encrypts the secret of account with CryptProtectData and writes it to its file
+func set(account, secret string) error {
+	file, err := path(account)
+	if err != nil {
+		return err
+	}
+
+	plain := []byte(secret)
+	in := windows.DataBlob{Size: uint32(len(plain)), Data: &plain[0]}
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return i18n.Errorf("failed to encrypt the secret: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return i18n.Errorf("failed to create keychain directory: %w", err)
+	}
+	if err := os.WriteFile(file, unsafe.Slice(out.Data, out.Size), 0600); err != nil {
+		return i18n.Errorf("failed to write to the keychain: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the file holding the secret of account
+func remove(account string) error {
+	file, err := path(account)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return i18n.Errorf("failed to delete from the keychain: %w", err)
+	}
+	return nil
+}
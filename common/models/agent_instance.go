@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// AgentInstance is the agent running on a device's configuration. The agent refreshes
+// HeartbeatAt while it runs, so another one started on the same configuration, on this
+// machine or on another sharing the database, sees it is taken.
+type AgentInstance struct {
+	DeviceID    string    `json:"device_id" gorm:"primaryKey;size:64"`
+	HolderID    string    `json:"holder_id" gorm:"size:64;not null"`
+	Hostname    string    `json:"hostname" gorm:"size:255"`
+	PID         int       `json:"pid"`
+	StartedAt   time.Time `json:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// BandwidthUsage is the traffic of a folder on a device during one local day. The agent
+// adds what it transferred since its last write, so a row grows over the day.
+type BandwidthUsage struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	Day             string    `json:"day" gorm:"uniqueIndex:idx_bandwidth_usage;size:10;not null"` // YYYY-MM-DD
+	FolderID        string    `json:"folder_id" gorm:"uniqueIndex:idx_bandwidth_usage;size:64;not null"`
+	DeviceID        string    `json:"device_id" gorm:"uniqueIndex:idx_bandwidth_usage;size:64;not null"`
+	BytesUploaded   int64     `json:"bytes_uploaded"`
+	BytesDownloaded int64     `json:"bytes_downloaded"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
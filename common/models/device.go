@@ -16,7 +16,7 @@ type Device struct {
 	DeviceID      string         `json:"device_id" gorm:"uniqueIndex;size:36"`
 	Name          string         `json:"name"`
 	LastSeenAt    time.Time      `json:"last_seen_at"`
-	Status        string         `json:"status" gorm:"default:active"`
+	Status        Status         `json:"status" gorm:"default:active"`
 	ClientVersion string         `json:"client_version"`
 	Platform      string         `json:"platform"`
 	OS            string         `json:"os"`
@@ -26,7 +26,8 @@ type Device struct {
 	DeviceFolders []DeviceFolder `json:"-" gorm:"foreignKey:DeviceID"`
 }
 
-// DeviceToken represents an authentication token for a device
+// DeviceToken represents an authentication token for a device. Token holds HashToken of the
+// token, which only the device has.
 type DeviceToken struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	DeviceID  uint           `json:"device_id" gorm:"index"`
@@ -59,15 +60,34 @@ func (dm *DeviceMetadata) Scan(value interface{}) error {
 
 // DeviceRegistrationRequest represents a request to register a new device
 type DeviceRegistrationRequest struct {
-	Name     string `json:"name" validate:"required"`
-	Platform string `json:"platform"`
-	OS       string `json:"os"`
+	DeviceID      string `json:"device_id,omitempty"`
+	Name          string `json:"name" validate:"required"`
+	Platform      string `json:"platform"`
+	OS            string `json:"os"`
+	ClientVersion string `json:"client_version,omitempty"`
 }
 
 // DeviceRegistrationResponse represents the response to a device registration
 type DeviceRegistrationResponse struct {
-	Device Device `json:"device"`
-	Token  string `json:"token"`
+	Device    Device    `json:"device"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DeviceTokenResponse represents a newly issued device token
+type DeviceTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DeviceHeartbeatRequest represents a periodic liveness report from a device
+type DeviceHeartbeatRequest struct {
+	ClientVersion string `json:"client_version,omitempty"`
+}
+
+// UpdateDeviceRequest represents a request to update a device
+type UpdateDeviceRequest struct {
+	Name string `json:"name" validate:"required"`
 }
 
 // DeviceResponse represents the response with device information
@@ -76,7 +96,7 @@ type DeviceResponse struct {
 	DeviceID      string    `json:"device_id"`
 	Name          string    `json:"name"`
 	LastSeenAt    time.Time `json:"last_seen_at"`
-	Status        string    `json:"status"`
+	Status        Status    `json:"status"`
 	ClientVersion string    `json:"client_version"`
 	Platform      string    `json:"platform"`
 	OS            string    `json:"os"`
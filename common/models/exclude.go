@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// ExcludeRule is an exclude pattern kept in the shared database and merged with the
+// excludes of each folder when it is scanned. A rule without FolderID applies to every
+// folder and one without DeviceID to every device. An Allow rule is a per-device override
+// that keeps syncing files matching a pattern excluded by the other rules.
+type ExcludeRule struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Pattern   string    `json:"pattern" gorm:"size:512;not null"`
+	FolderID  string    `json:"folder_id,omitempty" gorm:"index;size:64"`
+	DeviceID  string    `json:"device_id,omitempty" gorm:"index;size:64"`
+	Allow     bool      `json:"allow" gorm:"default:false"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -12,15 +12,19 @@ import (
 // Folder represents a synchronization folder in the system
 type Folder struct {
 	ID                uint           `json:"id" gorm:"primaryKey"`
-	UserID            uint           `json:"user_id" gorm:"index"`
-	FolderID          string         `json:"folder_id" gorm:"uniqueIndex;size:36"`
+	UserID            uint           `json:"user_id" gorm:"index;uniqueIndex:idx_folders_user_folder"`
+	FolderID          string         `json:"folder_id" gorm:"uniqueIndex:idx_folders_user_folder;size:36"`
 	Name              string         `json:"name"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
-	Status            string         `json:"status" gorm:"default:active"`
+	Status            Status         `json:"status" gorm:"default:active"`
 	EncryptionEnabled bool           `json:"encryption_enabled" gorm:"default:false"`
 	EncryptionKeyID   string         `json:"encryption_key_id,omitempty"`
+	// Sync events kept for the folder: their age in days and their number. Zero keeps the
+	// server default, a negative value keeps them all.
+	EventRetentionDays  int `json:"event_retention_days,omitempty"`
+	EventRetentionCount int `json:"event_retention_count,omitempty"`
 }
 
 // DeviceFolder represents the mapping between a device and a folder
@@ -34,7 +38,7 @@ type DeviceFolder struct {
 	SyncDirection   string         `json:"sync_direction" gorm:"default:bidirectional"`
 	ExcludePatterns StringArray    `json:"exclude_patterns" gorm:"type:text"`
 	LastSyncAt      *time.Time     `json:"last_sync_at,omitempty"`
-	Status          string         `json:"status" gorm:"default:active"`
+	Status          Status         `json:"status" gorm:"default:active"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
@@ -93,8 +97,89 @@ type SyncEvent struct {
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// SyncEventConflict is the event type recorded when a file changed on two devices
+const SyncEventConflict = "conflict"
+
+// ConflictDetails describes how a conflict was resolved, stored as JSON in SyncEvent.Details
+type ConflictDetails struct {
+	Policy        string `json:"policy"`
+	Resolution    string `json:"resolution"` // "local", "remote" or "both"
+	ConflictCopy  string `json:"conflict_copy,omitempty"`
+	LocalVersion  string `json:"local_version"`
+	RemoteVersion string `json:"remote_version"`
+	RemoteDevice  string `json:"remote_device,omitempty"`
+}
+
+// SyncEventTooLarge is the event type recorded when a file is above the upload size limit
+const SyncEventTooLarge = "too_large"
+
+// TooLargeDetails describes a file left out of the sync for its size, stored as JSON in SyncEvent.Details
+type TooLargeDetails struct {
+	Size  int64 `json:"size"`
+	Limit int64 `json:"limit"`
+}
+
+// SyncEventCloudPlaceholder is the event type recorded when the placeholder of a file kept
+// online by a cloud storage client is not uploaded, as its content is not on disk
+const SyncEventCloudPlaceholder = "cloud_placeholder"
+
+// CloudPlaceholderDetails describes a skipped placeholder, stored as JSON in SyncEvent.Details
+type CloudPlaceholderDetails struct {
+	Size int64 `json:"size"` // Of the content the placeholder stands for
+}
+
+// SyncEventDeletionBlocked is the event type recorded when the deletion guard withholds the
+// removal of remote files
+const SyncEventDeletionBlocked = "deletion_blocked"
+
+// DeletionBlockedDetails describes withheld deletions, stored as JSON in SyncEvent.Details
+type DeletionBlockedDetails struct {
+	Deletions int `json:"deletions"`
+	Total     int `json:"total"`
+}
+
+// SyncEventLowDiskSpace is the event type recorded when remote changes do not fit on the disk of a folder
+const SyncEventLowDiskSpace = "low_disk_space"
+
+// LowDiskSpaceDetails describes a disk space shortage, stored as JSON in SyncEvent.Details
+type LowDiskSpaceDetails struct {
+	Needed    uint64 `json:"needed"`
+	Available uint64 `json:"available"`
+}
+
+// SyncEventCaseCollision is the event type recorded when remote files whose names differ only
+// in case are not downloaded to a case-insensitive filesystem
+const SyncEventCaseCollision = "case_collision"
+
+// CaseCollisionDetails lists the colliding files, stored as JSON in SyncEvent.Details
+type CaseCollisionDetails struct {
+	Paths []string `json:"paths"`
+}
+
+// SyncEventIntegrityFailure is the event type recorded when downloaded content does not match
+// the hash stored with the file after every retry, so the local file is left unchanged
+const SyncEventIntegrityFailure = "integrity_failure"
+
+// IntegrityFailureDetails describes content rejected by the hash check, stored as JSON in SyncEvent.Details
+type IntegrityFailureDetails struct {
+	Key      string `json:"key"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// SyncEventLocalChange is the event type recorded when a file of a subscribed folder changed
+// on the device, which never uploads it
+const SyncEventLocalChange = "local_change"
+
+// LocalChangeDetails describes a local change to a subscribed folder, stored as JSON in SyncEvent.Details
+type LocalChangeDetails struct {
+	Change string `json:"change"` // "added", "modified" or "deleted"
+	Policy string `json:"policy"` // "revert" when the published copy was restored, "flag" when the change was kept
+}
+
 // CreateFolderRequest represents the request to create a new sync folder
 type CreateFolderRequest struct {
+	FolderID          string `json:"folder_id,omitempty"` // Generated when empty
 	Name              string `json:"name" validate:"required"`
 	EncryptionEnabled bool   `json:"encryption_enabled"`
 }
@@ -102,8 +187,11 @@ type CreateFolderRequest struct {
 // UpdateFolderRequest represents the request to update a sync folder
 type UpdateFolderRequest struct {
 	Name              string `json:"name"`
-	Status            string `json:"status" validate:"omitempty,oneof=active paused disabled"`
-	EncryptionEnabled bool   `json:"encryption_enabled"`
+	Status            Status `json:"status" validate:"omitempty,oneof=active paused disabled"`
+	EncryptionEnabled *bool  `json:"encryption_enabled,omitempty"`
+	// Retention of the sync events of the folder, see Folder
+	EventRetentionDays  *int `json:"event_retention_days,omitempty"`
+	EventRetentionCount *int `json:"event_retention_count,omitempty"`
 }
 
 // FolderResponse represents the response with folder information
@@ -112,8 +200,11 @@ type FolderResponse struct {
 	FolderID          string    `json:"folder_id"`
 	Name              string    `json:"name"`
 	CreatedAt         time.Time `json:"created_at"`
-	Status            string    `json:"status"`
+	Status            Status    `json:"status"`
 	EncryptionEnabled bool      `json:"encryption_enabled"`
+	// Retention of the sync events of the folder, see Folder
+	EventRetentionDays  int `json:"event_retention_days,omitempty"`
+	EventRetentionCount int `json:"event_retention_count,omitempty"`
 }
 
 // AddDeviceFolderRequest represents the request to add a folder to a device
@@ -131,3 +222,24 @@ type UpdateDeviceFolderRequest struct {
 	SyncDirection   string   `json:"sync_direction" validate:"omitempty,oneof=bidirectional upload download"`
 	ExcludePatterns []string `json:"exclude_patterns"`
 }
+
+// CreateFileVersionRequest represents a request to record a new version of a file
+type CreateFileVersionRequest struct {
+	RelativePath string    `json:"relative_path" validate:"required"`
+	VersionID    string    `json:"version_id"`
+	Size         int64     `json:"size"`
+	Hash         string    `json:"hash"`
+	ModifiedAt   time.Time `json:"modified_at"`
+	MimeType     string    `json:"mime_type,omitempty"`
+	Metadata     string    `json:"metadata,omitempty"`
+	Deleted      bool      `json:"deleted"`
+}
+
+// CreateSyncEventRequest represents a request to record a synchronization event
+type CreateSyncEventRequest struct {
+	FileVersionID uint      `json:"file_version_id,omitempty"`
+	EventType     string    `json:"event_type" validate:"required"`
+	RelativePath  string    `json:"relative_path"`
+	Timestamp     time.Time `json:"timestamp"`
+	Details       string    `json:"details,omitempty"`
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Status is the lifecycle state stored with folders, the folders of a device, devices and users
+type Status string
+
+// Statuses of the records that have one
+const (
+	StatusActive   Status = "active"
+	StatusPaused   Status = "paused" // Folders only: kept and watched, but not synced
+	StatusDisabled Status = "disabled"
+)
+
+// ErrInvalidStatus is returned for a status a kind of record cannot have
+var ErrInvalidStatus = errors.New("invalid status")
+
+// ErrInvalidTransition is returned for a status change a kind of record does not allow
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// StateMachine lists the statuses a kind of record can have and the changes between them.
+// Setting the status a record already has is always allowed.
+type StateMachine struct {
+	Kind        string              // Name of the records, for errors
+	Initial     Status              // Status of new records and of those stored without one
+	Transitions map[Status][]Status // Statuses each status may change to
+}
+
+// FolderStatuses is the lifecycle of a folder: a paused folder resumes or is disabled, and
+// enabling a disabled one makes it active again, or paused when its sync was paused
+var FolderStatuses = StateMachine{
+	Kind:    "folder",
+	Initial: StatusActive,
+	Transitions: map[Status][]Status{
+		StatusActive:   {StatusPaused, StatusDisabled},
+		StatusPaused:   {StatusActive, StatusDisabled},
+		StatusDisabled: {StatusActive, StatusPaused},
+	},
+}
+
+// DeviceStatuses is the lifecycle of a device
+var DeviceStatuses = StateMachine{
+	Kind:    "device",
+	Initial: StatusActive,
+	Transitions: map[Status][]Status{
+		StatusActive:   {StatusDisabled},
+		StatusDisabled: {StatusActive},
+	},
+}
+
+// UserStatuses is the lifecycle of a user
+var UserStatuses = StateMachine{
+	Kind:    "user",
+	Initial: StatusActive,
+	Transitions: map[Status][]Status{
+		StatusActive:   {StatusDisabled},
+		StatusDisabled: {StatusActive},
+	},
+}
+
+// Parse returns the status named s, the initial one when s is empty
+func (m StateMachine) Parse(s string) (Status, error) {
+	if s == "" {
+		return m.Initial, nil
+	}
+	status := Status(s)
+	if _, ok := m.Transitions[status]; !ok {
+		return "", fmt.Errorf("%w for a %s: %q", ErrInvalidStatus, m.Kind, s)
+	}
+	return status, nil
+}
+
+// Transition checks that a record with status from may change to status to. A record stored
+// without a status has the initial one.
+func (m StateMachine) Transition(from, to Status) error {
+	if _, err := m.Parse(string(to)); err != nil || to == "" {
+		return fmt.Errorf("%w for a %s: %q", ErrInvalidStatus, m.Kind, to)
+	}
+	from, err := m.Parse(string(from))
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	for _, allowed := range m.Transitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: a %s cannot go from %s to %s", ErrInvalidTransition, m.Kind, from, to)
+}
+
+// FolderStatusOf returns the status of a folder from the flags of its configuration
+func FolderStatusOf(enabled, paused bool) Status {
+	switch {
+	case !enabled:
+		return StatusDisabled
+	case paused:
+		return StatusPaused
+	default:
+		return StatusActive
+	}
+}
+
+// Flags returns the configuration flags of a folder with status s, the reverse of FolderStatusOf
+func (s Status) Flags() (enabled, paused bool) {
+	return s != StatusDisabled, s == StatusPaused
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolderStatusTransitions(t *testing.T) {
+	// A folder is paused, resumed, disabled and enabled again
+	assert.NoError(t, FolderStatuses.Transition(StatusActive, StatusPaused))
+	assert.NoError(t, FolderStatuses.Transition(StatusPaused, StatusActive))
+	assert.NoError(t, FolderStatuses.Transition(StatusPaused, StatusDisabled))
+	assert.NoError(t, FolderStatuses.Transition(StatusDisabled, StatusPaused))
+	assert.NoError(t, FolderStatuses.Transition(StatusPaused, StatusPaused))
+
+	// A record stored without a status is active
+	assert.NoError(t, FolderStatuses.Transition("", StatusPaused))
+	assert.ErrorIs(t, FolderStatuses.Transition(StatusActive, "archived"), ErrInvalidStatus)
+	assert.ErrorIs(t, FolderStatuses.Transition(StatusActive, ""), ErrInvalidStatus)
+
+	// Only folders pause
+	assert.ErrorIs(t, DeviceStatuses.Transition(StatusActive, StatusPaused), ErrInvalidStatus)
+	assert.NoError(t, UserStatuses.Transition(StatusActive, StatusDisabled))
+
+	status, err := FolderStatuses.Parse("")
+	assert.NoError(t, err)
+	assert.Equal(t, StatusActive, status)
+	_, err = UserStatuses.Parse("paused")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestFolderStatusFlags(t *testing.T) {
+	for _, flags := range [][2]bool{{true, false}, {true, true}, {false, false}} {
+		enabled, paused := FolderStatusOf(flags[0], flags[1]).Flags()
+		assert.Equal(t, flags, [2]bool{enabled, paused})
+	}
+	// Disabling wins over pausing
+	assert.Equal(t, StatusDisabled, FolderStatusOf(false, true))
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// SyncRun statuses
+const (
+	SyncRunSucceeded = "succeeded"
+	SyncRunFailed    = "failed"
+	SyncRunCancelled = "cancelled"
+)
+
+// SyncRun summarizes one sync of a folder on a device, written by the agent when the sync ends.
+// Uploads are queued during the sync and finish in the background, so FilesUploaded counts
+// those done while it ran and FilesQueued those still on their way.
+type SyncRun struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	FolderID        string    `json:"folder_id" gorm:"index:idx_sync_run_folder;size:64;not null"`
+	DeviceID        string    `json:"device_id" gorm:"index:idx_sync_run_folder;size:64;not null"`
+	Kind            string    `json:"kind" gorm:"size:16"` // status.FullSync, FolderSync or Periodic
+	Status          string    `json:"status" gorm:"size:16"`
+	Error           string    `json:"error,omitempty" gorm:"type:text"`
+	StartedAt       time.Time `json:"started_at" gorm:"index"`
+	FinishedAt      time.Time `json:"finished_at"`
+	FilesScanned    int64     `json:"files_scanned"`
+	FilesUploaded   int64     `json:"files_uploaded"`
+	FilesQueued     int64     `json:"files_queued"`
+	FilesDownloaded int64     `json:"files_downloaded"`
+	FilesDeleted    int64     `json:"files_deleted"`
+	FilesSkipped    int64     `json:"files_skipped"`
+	BytesUploaded   int64     `json:"bytes_uploaded"`
+	BytesDownloaded int64     `json:"bytes_downloaded"`
+	Errors          int64     `json:"errors"`
+	Conflicts       int64     `json:"conflicts"`
+}
+
+// Duration is how long the run took
+func (r SyncRun) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
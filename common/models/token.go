@@ -1,12 +1,16 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// ApiToken represents an API token in the system
+// ApiToken represents an API token in the system. Token holds HashToken of the token, both
+// for the tokens the CLI creates and for those the server issues at login, so the token
+// itself is only shown once.
 type ApiToken struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	UserID    uint           `json:"user_id" gorm:"index"`
@@ -21,6 +25,12 @@ type ApiToken struct {
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// HashToken returns the value stored in ApiToken.Token and DeviceToken.Token for a token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // CreateTokenRequest represents the request to create a new API token
 type CreateTokenRequest struct {
 	Name      string `json:"name" validate:"required"`
@@ -13,7 +13,7 @@ type User struct {
 	PasswordHash      string         `json:"-"`
 	Name              string         `json:"name"`
 	LastLoginAt       time.Time      `json:"last_login_at"`
-	Status            string         `json:"status" gorm:"default:active"`
+	Status            Status         `json:"status" gorm:"default:active"`
 	StorageQuota      int64          `json:"storage_quota" gorm:"default:10737418240"` // Default 10GB em bytes
 	StorageUsed       int64          `json:"storage_used" gorm:"default:0"`
 	VerificationToken string         `json:"-"`
@@ -45,7 +45,7 @@ type UserResponse struct {
 	Email        string    `json:"email"`
 	Name         string    `json:"name"`
 	LastLoginAt  time.Time `json:"last_login_at"`
-	Status       string    `json:"status"`
+	Status       Status    `json:"status"`
 	StorageQuota int64     `json:"storage_quota"`
 	StorageUsed  int64     `json:"storage_used"`
 	Verified     bool      `json:"verified"`
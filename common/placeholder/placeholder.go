@@ -0,0 +1,134 @@
+// Package placeholder reads and writes the small files left in place of archived files,
+// whose content only lives in the remote storage until it is fetched again
+package placeholder
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// header is the first line of every placeholder
+const header = "sync-manager placeholder v1"
+
+// MaxSize bounds the size of a placeholder, so larger files are never read to look for one
+const MaxSize = 4096
+
+// ErrNotPlaceholder is returned by Read for a file that is not a placeholder
+var ErrNotPlaceholder = errors.New("not a placeholder")
+
+// Placeholder describes the archived file it stands in for
+type Placeholder struct {
+	FolderID string
+	Path     string // Slash-separated path relative to the folder
+	Size     int64
+	Hash     string // Hash of the archived content, as the index records it
+	ModTime  time.Time
+}
+
+// Encode returns the content of the placeholder file, readable by whoever opens it
+func (p Placeholder) Encode() []byte {
+	var b bytes.Buffer
+	fmt.Fprintln(&b, header)
+	fmt.Fprintln(&b, "This file was moved to cloud storage to free disk space.")
+	fmt.Fprintln(&b, "Run 'sync-manager fetch <this file>' to download it again.")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "folder: %s\n", p.FolderID)
+	fmt.Fprintf(&b, "path: %s\n", p.Path)
+	fmt.Fprintf(&b, "size: %d\n", p.Size)
+	fmt.Fprintf(&b, "sha256: %s\n", p.Hash)
+	fmt.Fprintf(&b, "modified: %s\n", p.ModTime.UTC().Format(time.RFC3339Nano))
+	return b.Bytes()
+}
+
+// Write replaces the file at path with the placeholder p, keeping its permissions. The
+// placeholder is written to a temporary file first, so a failure leaves the file as it was.
+func Write(path string, p Placeholder) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".placeholder-*")
+	if err != nil {
+		return fmt.Errorf("failed to create placeholder: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(p.Encode()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write placeholder: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write placeholder: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set placeholder permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace file with its placeholder: %w", err)
+	}
+	return nil
+}
+
+// Read returns the placeholder at path, or ErrNotPlaceholder when the file is something else
+func Read(path string) (Placeholder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Placeholder{}, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxSize+1))
+	if err != nil {
+		return Placeholder{}, err
+	}
+	return Decode(data)
+}
+
+// Decode parses the content of a placeholder file
+func Decode(data []byte) (Placeholder, error) {
+	if len(data) > MaxSize {
+		return Placeholder{}, ErrNotPlaceholder
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() || strings.TrimRight(scanner.Text(), "\r") != header {
+		return Placeholder{}, ErrNotPlaceholder
+	}
+
+	var p Placeholder
+	var err error
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimRight(scanner.Text(), "\r"), ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "folder":
+			p.FolderID = value
+		case "path":
+			p.Path = value
+		case "size":
+			p.Size, err = strconv.ParseInt(value, 10, 64)
+		case "sha256":
+			p.Hash = value
+		case "modified":
+			p.ModTime, err = time.Parse(time.RFC3339Nano, value)
+		}
+		if err != nil {
+			return Placeholder{}, fmt.Errorf("invalid placeholder %s: %w", key, err)
+		}
+	}
+	if p.FolderID == "" || p.Path == "" || p.Hash == "" {
+		return Placeholder{}, ErrNotPlaceholder
+	}
+	return p, nil
+}
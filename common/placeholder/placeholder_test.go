@@ -0,0 +1,54 @@
+package placeholder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	assert.NoError(t, os.WriteFile(path, make([]byte, 10000), 0640))
+
+	p := Placeholder{FolderID: "docs", Path: "2023/report.pdf", Size: 10000, Hash: "abc123", ModTime: time.Date(2024, 3, 1, 12, 30, 0, 5, time.UTC)}
+	assert.NoError(t, Write(path, p))
+
+	read, err := Read(path)
+	assert.NoError(t, err)
+	assert.Equal(t, p.FolderID, read.FolderID)
+	assert.Equal(t, p.Path, read.Path)
+	assert.Equal(t, p.Size, read.Size)
+	assert.Equal(t, p.Hash, read.Hash)
+	assert.True(t, p.ModTime.Equal(read.ModTime))
+
+	// The placeholder is small and keeps the permissions of the file it replaced
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Less(t, info.Size(), int64(MaxSize))
+	if os.PathSeparator == '/' {
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestReadOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"notes.txt":  "sync-manager placeholder\nfolder: docs\n",
+		"empty.txt":  "",
+		"header.txt": header + "\nfolder: docs\n",
+	} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		_, err := Read(path)
+		assert.ErrorIs(t, err, ErrNotPlaceholder, name)
+	}
+
+	_, err := Decode(append(Placeholder{FolderID: "docs", Path: "a", Hash: "h"}.Encode(), make([]byte, MaxSize)...))
+	assert.ErrorIs(t, err, ErrNotPlaceholder)
+}
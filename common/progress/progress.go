@@ -0,0 +1,301 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// rateWindow is how far back the transfer rate looks
+const rateWindow = 5 * time.Second
+
+// sampleEvery is the minimum spacing between rate samples
+const sampleEvery = 250 * time.Millisecond
+
+// Tracker counts the files and bytes of a batch of transfers. It is safe for concurrent use.
+// A new batch starts when work is added after every queued file has finished.
+type Tracker struct {
+	mu          sync.Mutex
+	startedAt   time.Time
+	filesTotal  int
+	filesDone   int
+	filesFailed int
+	bytesTotal  int64
+	bytesDone   int64
+	active      map[*File]struct{}
+	samples     []sample
+	consumers   consumers
+	now         func() time.Time
+}
+
+// sample is the byte count at a point in time, used to compute the rate
+type sample struct {
+	at    time.Time
+	bytes int64
+}
+
+// File is a transfer in progress
+type File struct {
+	tracker   *Tracker
+	name      string
+	size      int64
+	done      int64
+	startedAt time.Time
+}
+
+// Snapshot is the state of a Tracker at a point in time
+type Snapshot struct {
+	StartedAt   time.Time      `json:"started_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	FilesTotal  int            `json:"files_total"`
+	FilesDone   int            `json:"files_done"`
+	FilesFailed int            `json:"files_failed"`
+	BytesTotal  int64          `json:"bytes_total"`
+	BytesDone   int64          `json:"bytes_done"`
+	Rate        float64        `json:"rate"` // Bytes per second over the last few seconds
+	Active      []FileProgress `json:"active"`
+
+	// The heaviest consumers among the finished transfers, for 'sync-manager top'
+	Heaviest   []Transfer `json:"heaviest,omitempty"`   // Transfers of the batch moving the most bytes
+	Slowest    []Transfer `json:"slowest,omitempty"`    // Transfers of the batch taking the longest
+	Folders    []Usage    `json:"folders,omitempty"`    // Folders of the batch moving the most bytes
+	Extensions []Usage    `json:"extensions,omitempty"` // Extensions moving the most bytes since the agent started
+}
+
+// FileProgress is the state of a single transfer
+type FileProgress struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Done      int64     `json:"done"`
+	StartedAt time.Time `json:"started_at"`
+	Rate      float64   `json:"rate"` // Average bytes per second since the transfer started
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	t := &Tracker{
+		active: make(map[*File]struct{}),
+		now:    time.Now,
+	}
+	t.reset()
+	return t
+}
+
+// Add queues files totalling size bytes
+func (t *Tracker) Add(files int, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.idle() {
+		t.reset()
+	}
+	t.filesTotal += files
+	t.bytesTotal += size
+}
+
+// Drop removes queued files that will not be transferred, counting them as failed
+func (t *Tracker) Drop(files int, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.filesFailed += files
+	t.bytesTotal -= size
+}
+
+// Start begins the transfer of a queued file
+func (t *Tracker) Start(name string, size int64) *File {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := &File{tracker: t, name: name, size: size, startedAt: t.now()}
+	t.active[f] = struct{}{}
+	return f
+}
+
+// Snapshot returns the current state of the tracker
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.record(now)
+
+	snap := Snapshot{
+		StartedAt:   t.startedAt,
+		UpdatedAt:   now,
+		FilesTotal:  t.filesTotal,
+		FilesDone:   t.filesDone,
+		FilesFailed: t.filesFailed,
+		BytesTotal:  t.bytesTotal,
+		BytesDone:   t.bytesDone,
+	}
+
+	if oldest := t.samples[0]; now.After(oldest.at) {
+		snap.Rate = float64(t.bytesDone-oldest.bytes) / now.Sub(oldest.at).Seconds()
+	}
+
+	for f := range t.active {
+		fp := FileProgress{Name: f.name, Size: f.size, Done: f.done, StartedAt: f.startedAt}
+		if elapsed := now.Sub(f.startedAt).Seconds(); elapsed > 0 {
+			fp.Rate = float64(f.done) / elapsed
+		}
+		snap.Active = append(snap.Active, fp)
+	}
+	sort.Slice(snap.Active, func(i, j int) bool { return snap.Active[i].StartedAt.Before(snap.Active[j].StartedAt) })
+	t.consumers.fill(&snap)
+
+	return snap
+}
+
+// idle reports whether every queued file has finished. Callers hold the lock.
+func (t *Tracker) idle() bool {
+	return len(t.active) == 0 && t.filesDone+t.filesFailed >= t.filesTotal
+}
+
+// reset starts a new batch. Callers hold the lock.
+func (t *Tracker) reset() {
+	t.startedAt = t.now()
+	t.filesTotal, t.filesDone, t.filesFailed = 0, 0, 0
+	t.bytesTotal, t.bytesDone = 0, 0
+	t.samples = []sample{{at: t.startedAt}}
+	t.consumers.reset()
+}
+
+// record adds a rate sample and forgets the ones outside the window. Callers hold the lock.
+func (t *Tracker) record(now time.Time) {
+	if n := len(t.samples); n == 0 || now.Sub(t.samples[n-1].at) >= sampleEvery {
+		t.samples = append(t.samples, sample{at: now, bytes: t.bytesDone})
+	}
+
+	cutoff := now.Add(-rateWindow)
+	drop := 0
+	for drop < len(t.samples)-1 && t.samples[drop].at.Before(cutoff) {
+		drop++
+	}
+	t.samples = t.samples[drop:]
+}
+
+// Add counts n transferred bytes
+func (f *File) Add(n int64) {
+	t := f.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f.done += n
+	t.bytesDone += n
+	t.record(t.now())
+}
+
+// Write counts the bytes written, so a File can be the target of an io.MultiWriter
+func (f *File) Write(p []byte) (int, error) {
+	f.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// Reader wraps r so every byte read is counted
+func (f *File) Reader(r io.Reader) io.Reader {
+	return &countingReader{reader: r, file: f}
+}
+
+// Finish ends the transfer. A failed transfer gives back its bytes so a retry starts from zero;
+// the file stays queued until it is started again or dropped.
+func (f *File) Finish(err error) {
+	t := f.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.active, f)
+	if err != nil {
+		t.bytesDone -= f.done
+		return
+	}
+
+	t.filesDone++
+	// The size may have changed since the file was queued
+	t.bytesTotal += f.done - f.size
+	t.consumers.add(Transfer{Name: f.name, Bytes: f.done, Duration: t.now().Sub(f.startedAt)})
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	file   *File
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	if n > 0 {
+		c.file.Add(int64(n))
+	}
+	return n, err
+}
+
+// ETA estimates the time left at the current rate, returning 0 when unknown
+func (s Snapshot) ETA() time.Duration {
+	remaining := s.BytesTotal - s.BytesDone
+	if remaining <= 0 || s.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(remaining) / s.Rate * float64(time.Second))
+}
+
+// Idle reports whether nothing is queued or transferring
+func (s Snapshot) Idle() bool {
+	return len(s.Active) == 0 && s.FilesDone+s.FilesFailed >= s.FilesTotal
+}
+
+// DefaultPath returns the default location of the file where the agent publishes its progress
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "progress.json"), nil
+}
+
+// Write stores the snapshot at path
+func Write(path string, snap Snapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create progress directory: %w", err)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write progress: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace progress: %w", err)
+	}
+
+	return nil
+}
+
+// Read loads the snapshot at path, returning nil if the agent never wrote one
+func Read(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read progress: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse progress: %w", err)
+	}
+
+	return &snap, nil
+}
@@ -0,0 +1,143 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock for the tracker
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestTracker() (*Tracker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	t := NewTracker()
+	t.now = clock.Now
+	t.reset()
+	return t, clock
+}
+
+func TestTrackerCountsTransfers(t *testing.T) {
+	tracker, clock := newTestTracker()
+	tracker.Add(2, 300)
+
+	first := tracker.Start("a.txt", 100)
+	_, err := io.Copy(io.Discard, first.Reader(bytes.NewReader(make([]byte, 100))))
+	assert.NoError(t, err)
+	first.Finish(nil)
+
+	second := tracker.Start("b.txt", 200)
+	clock.now = clock.now.Add(2 * time.Second)
+	second.Add(50)
+
+	snap := tracker.Snapshot()
+	assert.Equal(t, 2, snap.FilesTotal)
+	assert.Equal(t, 1, snap.FilesDone)
+	assert.Equal(t, int64(150), snap.BytesDone)
+	assert.Equal(t, 75.0, snap.Rate)
+	assert.Equal(t, 2*time.Second, snap.ETA())
+	assert.False(t, snap.Idle())
+
+	assert.Len(t, snap.Active, 1)
+	assert.Equal(t, "b.txt", snap.Active[0].Name)
+	assert.Equal(t, int64(50), snap.Active[0].Done)
+	assert.Equal(t, 25.0, snap.Active[0].Rate)
+
+	// A failed attempt gives its bytes back; dropping the file finishes the batch
+	second.Finish(errors.New("network down"))
+	tracker.Drop(1, 200)
+
+	snap = tracker.Snapshot()
+	assert.Equal(t, int64(100), snap.BytesDone)
+	assert.Equal(t, int64(100), snap.BytesTotal)
+	assert.Equal(t, 1, snap.FilesFailed)
+	assert.True(t, snap.Idle())
+
+	// New work after an idle tracker starts a new batch
+	tracker.Add(1, 10)
+	snap = tracker.Snapshot()
+	assert.Equal(t, 1, snap.FilesTotal)
+	assert.Equal(t, 0, snap.FilesDone)
+	assert.Equal(t, int64(0), snap.BytesDone)
+}
+
+func TestTrackerRateUsesRecentWindow(t *testing.T) {
+	tracker, clock := newTestTracker()
+	tracker.Add(1, 1000)
+	f := tracker.Start("big.bin", 1000)
+
+	f.Add(500)
+	clock.now = clock.now.Add(time.Minute)
+	tracker.Snapshot()
+
+	// Only the last few seconds count towards the rate
+	clock.now = clock.now.Add(2 * time.Second)
+	f.Add(100)
+	assert.Equal(t, 50.0, tracker.Snapshot().Rate)
+}
+
+func TestWriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+
+	snap, err := Read(path)
+	assert.NoError(t, err)
+	assert.Nil(t, snap)
+
+	tracker, _ := newTestTracker()
+	tracker.Add(3, 42)
+	assert.NoError(t, Write(path, tracker.Snapshot()))
+
+	snap, err = Read(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, snap.FilesTotal)
+	assert.Equal(t, int64(42), snap.BytesTotal)
+}
+
+func TestTrackerTopConsumers(t *testing.T) {
+	tracker, clock := newTestTracker()
+	tracker.Add(3, 600)
+
+	transfer := func(name string, size int64, took time.Duration) {
+		f := tracker.Start(name, size)
+		clock.now = clock.now.Add(took)
+		f.Add(size)
+		f.Finish(nil)
+	}
+	transfer("docs/report.PDF", 100, 5*time.Second)
+	transfer("docs/notes.txt", 200, time.Second)
+	transfer("photos/beach.jpg", 300, 2*time.Second)
+
+	snap := tracker.Snapshot()
+	assert.Equal(t, []Transfer{
+		{Name: "photos/beach.jpg", Bytes: 300, Duration: 2 * time.Second},
+		{Name: "docs/notes.txt", Bytes: 200, Duration: time.Second},
+		{Name: "docs/report.PDF", Bytes: 100, Duration: 5 * time.Second},
+	}, snap.Heaviest)
+	assert.Equal(t, "docs/report.PDF", snap.Slowest[0].Name)
+	assert.Equal(t, []Usage{
+		{Name: "docs", Files: 2, Bytes: 300, Duration: 6 * time.Second},
+		{Name: "photos", Files: 1, Bytes: 300, Duration: 2 * time.Second},
+	}, snap.Folders)
+	assert.Equal(t, ".jpg", snap.Extensions[0].Name)
+	assert.Equal(t, ".pdf", snap.Extensions[2].Name)
+
+	// A new batch starts its own top, while the extensions keep adding up
+	tracker.Add(TopCount+1, 0)
+	for i := 0; i <= TopCount; i++ {
+		transfer("music/track.mp3", int64(i), time.Second)
+	}
+	snap = tracker.Snapshot()
+	assert.Len(t, snap.Heaviest, TopCount)
+	assert.Equal(t, int64(TopCount), snap.Heaviest[0].Bytes)
+	assert.Equal(t, []Usage{{Name: "music", Files: TopCount + 1, Bytes: 55, Duration: 11 * time.Second}}, snap.Folders)
+	assert.Len(t, snap.Extensions, 4)
+}
@@ -0,0 +1,105 @@
+package progress
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TopCount is how many transfers, folders and extensions a snapshot lists, heaviest first
+const TopCount = 10
+
+// Transfer is a finished transfer of the batch
+type Transfer struct {
+	Name     string        `json:"name"`
+	Bytes    int64         `json:"bytes"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Usage totals the finished transfers of the files of a folder or with an extension
+type Usage struct {
+	Name     string        `json:"name"` // The folder, or the extension in lower case, empty for files without one
+	Files    int           `json:"files"`
+	Bytes    int64         `json:"bytes"`
+	Duration time.Duration `json:"duration"`
+}
+
+// consumers keeps the heaviest consumers of transfer time and bytes. The transfers and
+// folders are those of the batch; the extensions add up since the tracker was created.
+type consumers struct {
+	heaviest   []Transfer // By bytes, at most TopCount
+	slowest    []Transfer // By duration, at most TopCount
+	folders    map[string]*Usage
+	extensions map[string]*Usage
+}
+
+// reset forgets the transfers and folders of the previous batch
+func (c *consumers) reset() {
+	c.heaviest, c.slowest = nil, nil
+	c.folders = make(map[string]*Usage)
+	if c.extensions == nil {
+		c.extensions = make(map[string]*Usage)
+	}
+}
+
+// add counts a finished transfer
+func (c *consumers) add(transfer Transfer) {
+	c.heaviest = insertTop(c.heaviest, transfer, func(a, b Transfer) bool { return a.Bytes > b.Bytes })
+	c.slowest = insertTop(c.slowest, transfer, func(a, b Transfer) bool { return a.Duration > b.Duration })
+	count(c.folders, path.Dir(transfer.Name), transfer)
+	count(c.extensions, strings.ToLower(path.Ext(transfer.Name)), transfer)
+}
+
+// fill lists the consumers in snap
+func (c *consumers) fill(snap *Snapshot) {
+	snap.Heaviest = append([]Transfer(nil), c.heaviest...)
+	snap.Slowest = append([]Transfer(nil), c.slowest...)
+	snap.Folders = sortUsage(c.folders, TopCount)
+	snap.Extensions = sortUsage(c.extensions, TopCount)
+}
+
+// insertTop inserts transfer into top, kept ordered by before and at most TopCount long
+func insertTop(top []Transfer, transfer Transfer, before func(a, b Transfer) bool) []Transfer {
+	i := sort.Search(len(top), func(i int) bool { return before(transfer, top[i]) })
+	if i >= TopCount {
+		return top
+	}
+	top = append(top, Transfer{})
+	copy(top[i+1:], top[i:])
+	top[i] = transfer
+	if len(top) > TopCount {
+		top = top[:TopCount]
+	}
+	return top
+}
+
+// count adds transfer to the usage of name
+func count(usage map[string]*Usage, name string, transfer Transfer) {
+	u, ok := usage[name]
+	if !ok {
+		u = &Usage{Name: name}
+		usage[name] = u
+	}
+	u.Files++
+	u.Bytes += transfer.Bytes
+	u.Duration += transfer.Duration
+}
+
+// sortUsage returns the limit heaviest usages by bytes
+func sortUsage(usage map[string]*Usage, limit int) []Usage {
+	sorted := make([]Usage, 0, len(usage))
+	for _, u := range usage {
+		sorted = append(sorted, *u)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Bytes != sorted[j].Bytes {
+			return sorted[i].Bytes > sorted[j].Bytes
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
@@ -0,0 +1,411 @@
+package restore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/download"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/snapshot"
+	"github.com/martinshumberto/sync-manager/common/storage"
+)
+
+// StateFile is kept in the target directory while a restore is in progress
+// so an interrupted restore can be resumed by running it again
+const StateFile = ".sync-manager-restore.json"
+
+// saveEvery is how many restored files go by between state file writes
+const saveEvery = 50
+
+// ErrTargetNotEmpty is returned when the target has files but no restore to resume
+var ErrTargetNotEmpty = errors.New("target directory is not empty")
+
+// Options controls a restore
+type Options struct {
+	// At restores the newest snapshot taken at or before this time instead of the current contents
+	At time.Time
+	// Progress counts the downloaded files and bytes when set
+	Progress *progress.Tracker
+	// Downloader fetches the files, several at once; nil uses the default download settings
+	Downloader *download.Downloader
+	// HardLinks recreates the hard links recorded in snapshots instead of restoring copies
+	HardLinks bool
+}
+
+// Result summarizes a finished restore
+type Result struct {
+	Source  string // "current" or the snapshot ID
+	Files   int
+	Resumed int // Files already restored by an earlier, interrupted run
+	Bytes   int64
+}
+
+// state is the content of StateFile
+type state struct {
+	FolderID string          `json:"folder_id"`
+	Source   string          `json:"source"`
+	Done     map[string]bool `json:"done"`
+}
+
+// item is a file or directory to restore
+type item struct {
+	path     string // Slash-separated, relative to the folder root
+	size     int64
+	dir      bool
+	link     bool // Restored after the other files, so the original it links to exists
+	download func(ctx context.Context, target string, transfer *progress.File) error
+}
+
+// SourceCurrent is the Result.Source of a restore of the current remote contents
+const SourceCurrent = "current"
+
+// Folder downloads the remote contents of a folder into target, which must be
+// empty or hold an interrupted restore of the same folder and source.
+// Backup-mode folders and restores with Options.At come from snapshots.
+func Folder(ctx context.Context, store storage.Storage, folder config.SyncFolder, deviceID, target string, opts Options) (*Result, error) {
+	st, err := prepareTarget(target, folder.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	downloader := opts.Downloader
+	if downloader == nil {
+		downloader = download.NewDownloader(store, nil)
+	}
+
+	var (
+		source string
+		items  []item
+	)
+	if folder.Mode == config.FolderModeBackup || !opts.At.IsZero() {
+		source, items, err = snapshotItems(ctx, store, folder, deviceID, opts.At, opts.HardLinks)
+	} else {
+		source, items, err = currentItems(ctx, store, downloader, folder)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Source != "" && st.Source != source {
+		return nil, fmt.Errorf("target holds an interrupted restore of %s, not %s; use an empty directory", st.Source, source)
+	}
+	st.Source = source
+
+	tracker := opts.Progress
+	if tracker == nil {
+		tracker = progress.NewTracker()
+	}
+
+	result := &Result{Source: source}
+	var files, links, dirs []item
+	for _, it := range items {
+		if st.Done[it.path] {
+			result.Resumed++
+		} else if it.dir {
+			dirs = append(dirs, it)
+		} else if it.link {
+			links = append(links, it)
+		} else {
+			files = append(files, it)
+		}
+	}
+
+	var total int64
+	for _, it := range files {
+		total += it.size
+	}
+	for _, it := range links {
+		total += it.size
+	}
+	tracker.Add(len(files)+len(links)+len(dirs), total)
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		restored int
+	)
+	finish := func(it item) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		st.Done[it.path] = true
+		if !it.dir {
+			result.Files++
+		}
+		result.Bytes += it.size
+
+		restored++
+		if restored%saveEvery == 0 {
+			return saveState(target, st)
+		}
+		return nil
+	}
+	restoreItem := func(ctx context.Context, it item) error {
+		transfer := tracker.Start(it.path, it.size)
+		err := it.download(ctx, target, transfer)
+		transfer.Finish(err)
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", it.path, err)
+		}
+		return finish(it)
+	}
+
+	// Files download in parallel; the first failure stops the others
+	filesCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	downloader.Each(filesCtx, len(files), func(ctx context.Context, i int) {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := restoreItem(ctx, files[i]); err != nil {
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+			mu.Unlock()
+		}
+	})
+
+	// Hard links follow the files they link to
+	for _, it := range links {
+		if firstErr != nil {
+			break
+		}
+		firstErr = restoreItem(ctx, it)
+	}
+
+	// Directories go one at a time, deepest first, after the files inside them
+	for _, it := range dirs {
+		if firstErr != nil {
+			break
+		}
+		firstErr = restoreItem(ctx, it)
+	}
+
+	if ctx.Err() != nil {
+		saveState(target, st)
+		return nil, ctx.Err()
+	}
+	if firstErr != nil {
+		saveState(target, st)
+		return nil, firstErr
+	}
+
+	if err := os.Remove(filepath.Join(target, StateFile)); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove restore state: %w", err)
+	}
+
+	return result, nil
+}
+
+// ParseTime parses the --at value of a restore. A date without a time means the end of that day.
+func ParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339, \"2006-01-02 15:04\" or \"2006-01-02\"", value)
+}
+
+// prepareTarget creates the target or loads the state of an interrupted restore into it
+func prepareTarget(target, folderID string) (*state, error) {
+	st := &state{FolderID: folderID, Done: make(map[string]bool)}
+
+	entries, err := os.ReadDir(target)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create target directory: %w", err)
+		}
+		return st, saveState(target, st)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target directory: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return st, saveState(target, st)
+	}
+
+	data, err := os.ReadFile(filepath.Join(target, StateFile))
+	if os.IsNotExist(err) {
+		return nil, ErrTargetNotEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read restore state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("failed to parse restore state: %w", err)
+	}
+	if st.FolderID != folderID {
+		return nil, fmt.Errorf("target holds an interrupted restore of folder %s", st.FolderID)
+	}
+	if st.Done == nil {
+		st.Done = make(map[string]bool)
+	}
+	return st, nil
+}
+
+// saveState writes the restore state into the target directory
+func saveState(target string, st *state) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(target, StateFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save restore state: %w", err)
+	}
+	return nil
+}
+
+// snapshotItems lists the files of the snapshot to restore
+func snapshotItems(ctx context.Context, store storage.Storage, folder config.SyncFolder, deviceID string, at time.Time, hardLinks bool) (string, []item, error) {
+	repo := snapshot.NewRepository(store, folder.ID, deviceID)
+	repo.SetHardLinks(hardLinks)
+
+	summary, err := repo.FindAt(ctx, at)
+	if errors.Is(err, snapshot.ErrNotFound) {
+		if at.IsZero() {
+			return "", nil, fmt.Errorf("folder %s has no snapshots", folder.ID)
+		}
+		return "", nil, fmt.Errorf("folder %s has no snapshot at or before %s", folder.ID, at.Format(time.RFC3339))
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	manifest, err := repo.Load(ctx, summary.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	items := make([]item, 0, len(manifest.Files))
+	for _, file := range manifest.Files {
+		items = append(items, item{
+			path: file.Path,
+			size: file.Size,
+			link: hardLinks && file.LinkTo != "",
+			download: func(ctx context.Context, target string, transfer *progress.File) error {
+				return repo.RestoreFile(ctx, file, target, transfer)
+			},
+		})
+	}
+	return manifest.ID, items, nil
+}
+
+// currentItems lists the current remote files of a mirror-mode folder
+func currentItems(ctx context.Context, store storage.Storage, downloader *download.Downloader, folder config.SyncFolder) (string, []item, error) {
+	prefix := folder.KeyPrefix() + "/"
+
+	files, err := store.ListFiles(ctx, prefix)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list remote files: %w", err)
+	}
+
+	items := make([]item, 0, len(files))
+	var dirs []item
+	for _, file := range files {
+		key := filepath.ToSlash(file.Key)
+		relPath := strings.TrimPrefix(key, prefix)
+		if relPath == "" || relPath == key {
+			continue
+		}
+
+		if dir, ok := storage.MarkerDir(relPath); ok {
+			if dir != "" {
+				dirs = append(dirs, item{
+					path: dir + "/",
+					dir:  true,
+					download: func(ctx context.Context, target string, transfer *progress.File) error {
+						return restoreDir(ctx, store, key, filepath.Join(target, filepath.FromSlash(dir)), target)
+					},
+				})
+			}
+			continue
+		}
+
+		items = append(items, item{
+			path: relPath,
+			size: file.Size,
+			download: func(ctx context.Context, target string, transfer *progress.File) error {
+				return downloadFile(ctx, downloader, key, file.Size, filepath.Join(target, filepath.FromSlash(relPath)), target, transfer)
+			},
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].path < items[j].path })
+
+	// Directories go after the files, deepest first, so creating entries does not change their times
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].path > dirs[j].path })
+	return SourceCurrent, append(items, dirs...), nil
+}
+
+// restoreDir creates a mirrored directory with the permissions and modification time of its marker
+func restoreDir(ctx context.Context, store storage.Storage, key, localPath, target string) error {
+	if rel, err := filepath.Rel(target, localPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path escapes the target directory: %s", key)
+	}
+
+	_, metadata, err := store.GetFileInfo(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	mode := storage.DirMode(metadata)
+	if err := os.MkdirAll(localPath, mode); err != nil {
+		return err
+	}
+	if err := os.Chmod(localPath, mode); err != nil {
+		return err
+	}
+
+	if modified, err := time.Parse(time.RFC3339, metadata[storage.MetadataDirModTime]); err == nil {
+		return os.Chtimes(localPath, modified, modified)
+	}
+	return nil
+}
+
+// downloadFile downloads a mirrored object, whose hash the downloader verifies, and restores its modification time.
+// The partial download is kept when interrupted, so the next run continues it.
+func downloadFile(ctx context.Context, downloader *download.Downloader, key string, size int64, localPath, target string, transfer *progress.File) error {
+	if rel, err := filepath.Rel(target, localPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path escapes the target directory: %s", key)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+
+	tmpPath := localPath + ".restore"
+	metadata, err := downloader.Fetch(ctx, download.Task{Key: key, Size: size, Path: tmpPath, Resume: true}, transfer)
+	if err != nil {
+		if !download.Resumable(tmpPath) {
+			os.Remove(tmpPath)
+		}
+		return err
+	}
+
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		return err
+	}
+
+	if modified, err := time.Parse(time.RFC3339, metadata["modified_time"]); err == nil {
+		return os.Chtimes(localPath, modified, modified)
+	}
+	return nil
+}
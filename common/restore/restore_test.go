@@ -0,0 +1,229 @@
+package restore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/download"
+	"github.com/martinshumberto/sync-manager/common/progress"
+	"github.com/martinshumberto/sync-manager/common/snapshot"
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStore(t *testing.T) *storage.LocalStorage {
+	store, err := storage.NewLocalStorage(&storage.LocalConfig{RootDir: t.TempDir()})
+	assert.NoError(t, err)
+	return store
+}
+
+func upload(t *testing.T, store storage.Storage, key, content string, modified time.Time) {
+	_, err := store.UploadFile(context.Background(), key, bytes.NewReader([]byte(content)), map[string]string{
+		"modified_time": modified.UTC().Format(time.RFC3339),
+	})
+	assert.NoError(t, err)
+}
+
+func TestFolderRestoresCurrentContents(t *testing.T) {
+	store := newStore(t)
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	upload(t, store, "docs/a.txt", "alpha", modified)
+	upload(t, store, "docs/sub/b.txt", "beta", modified)
+	upload(t, store, "other/c.txt", "not this folder", modified)
+
+	folder := config.SyncFolder{ID: "docs"}
+	target := filepath.Join(t.TempDir(), "restore")
+
+	tracker := progress.NewTracker()
+	result, err := Folder(context.Background(), store, folder, "laptop", target, Options{Progress: tracker})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Files)
+	assert.Equal(t, int64(9), result.Bytes)
+
+	snap := tracker.Snapshot()
+	assert.Equal(t, 2, snap.FilesDone)
+	assert.Equal(t, int64(9), snap.BytesDone)
+	assert.Equal(t, int64(9), snap.BytesTotal)
+	assert.True(t, snap.Idle())
+
+	data, err := os.ReadFile(filepath.Join(target, "sub", "b.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "beta", string(data))
+
+	// The modification time comes from the stored metadata
+	metadata, err := store.DownloadFile(context.Background(), "docs/a.txt", io.Discard, "")
+	assert.NoError(t, err)
+	stored, err := time.Parse(time.RFC3339, metadata["modified_time"])
+	assert.NoError(t, err)
+	info, err := os.Stat(filepath.Join(target, "a.txt"))
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(stored))
+
+	// The state file is gone once the restore completes
+	_, err = os.Stat(filepath.Join(target, StateFile))
+	assert.True(t, os.IsNotExist(err))
+
+	// A finished restore leaves a non-empty directory
+	_, err = Folder(context.Background(), store, folder, "laptop", target, Options{})
+	assert.ErrorIs(t, err, ErrTargetNotEmpty)
+}
+
+func TestFolderResumesInterruptedRestore(t *testing.T) {
+	store := newStore(t)
+	upload(t, store, "docs/a.txt", "alpha", time.Now())
+	upload(t, store, "docs/b.txt", "beta", time.Now())
+
+	// Simulate a run that stopped after the first file
+	target := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(target, "a.txt"), []byte("alpha"), 0644))
+	assert.NoError(t, saveState(target, &state{FolderID: "docs", Source: SourceCurrent, Done: map[string]bool{"a.txt": true}}))
+
+	result, err := Folder(context.Background(), store, config.SyncFolder{ID: "docs"}, "laptop", target, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Files)
+	assert.Equal(t, 1, result.Resumed)
+
+	data, err := os.ReadFile(filepath.Join(target, "b.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "beta", string(data))
+
+	// State of another folder is not resumed
+	other := t.TempDir()
+	assert.NoError(t, saveState(other, &state{FolderID: "photos", Done: map[string]bool{}}))
+	_, err = Folder(context.Background(), store, config.SyncFolder{ID: "docs"}, "laptop", other, Options{})
+	assert.Error(t, err)
+}
+
+func TestFolderResumesPartialFile(t *testing.T) {
+	store := newStore(t)
+	upload(t, store, "docs/large.bin", "0123456789abcdef", time.Now())
+	upload(t, store, "docs/small.txt", "tiny", time.Now())
+
+	cfg := config.DefaultConfig()
+	cfg.Download.MaxConcurrency = 1
+	cfg.Download.ChunkSize = 4
+
+	// The first run is interrupted after two chunks of the large file
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupted := &rangeStore{Storage: store, after: func(n int) {
+		if n == 2 {
+			cancel()
+		}
+	}}
+	target := t.TempDir()
+	_, err := Folder(ctx, interrupted, config.SyncFolder{ID: "docs"}, "laptop", target, Options{Downloader: download.NewDownloader(interrupted, cfg)})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	resumed := &rangeStore{Storage: store}
+	result, err := Folder(context.Background(), resumed, config.SyncFolder{ID: "docs"}, "laptop", target, Options{Downloader: download.NewDownloader(resumed, cfg)})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, resumed.requests)
+	assert.Equal(t, int64(20), result.Bytes)
+
+	data, err := os.ReadFile(filepath.Join(target, "large.bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef", string(data))
+
+	entries, err := os.ReadDir(target)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+// rangeStore counts ranged downloads, calling after with the count once each one is done
+type rangeStore struct {
+	storage.Storage
+	requests int
+	after    func(n int)
+}
+
+func (r *rangeStore) DownloadRange(ctx context.Context, key string, offset, length int64, w io.Writer) error {
+	err := r.Storage.(storage.RangeDownloader).DownloadRange(ctx, key, offset, length, w)
+	r.requests++
+	if r.after != nil {
+		r.after(r.requests)
+	}
+	return err
+}
+
+func TestFolderRestoresSnapshotAtTime(t *testing.T) {
+	store := newStore(t)
+	root := t.TempDir()
+	repo := snapshot.NewRepository(store, "docs", "laptop")
+
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("v1"), 0644))
+	first, err := repo.Create(context.Background(), root, nil)
+	assert.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("v2"), 0644))
+	_, err = repo.Create(context.Background(), root, nil)
+	assert.NoError(t, err)
+
+	folder := config.SyncFolder{ID: "docs", Mode: config.FolderModeBackup}
+
+	// Backup folders restore the latest snapshot by default
+	latest := t.TempDir()
+	result, err := Folder(context.Background(), store, folder, "laptop", latest, Options{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, result.Source)
+	data, _ := os.ReadFile(filepath.Join(latest, "notes.txt"))
+	assert.Equal(t, "v2", string(data))
+
+	past := t.TempDir()
+	result, err = Folder(context.Background(), store, folder, "laptop", past, Options{At: first.CreatedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, result.Source)
+	data, _ = os.ReadFile(filepath.Join(past, "notes.txt"))
+	assert.Equal(t, "v1", string(data))
+
+	_, err = Folder(context.Background(), store, folder, "laptop", t.TempDir(), Options{At: first.CreatedAt.Add(-time.Hour)})
+	assert.Error(t, err)
+}
+
+func TestParseTime(t *testing.T) {
+	parsed, err := ParseTime("2026-03-01T10:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), parsed.UTC())
+
+	parsed, err = ParseTime("2026-03-01")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 23, 59, 59, 999999999, time.Local), parsed)
+
+	_, err = ParseTime("yesterday")
+	assert.Error(t, err)
+}
+
+func TestFolderRestoresDirectories(t *testing.T) {
+	store := newStore(t)
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	upload(t, store, "docs/sub/b.txt", "beta", modified)
+	for _, dir := range []string{"sub", "empty"} {
+		_, err := store.UploadFile(context.Background(), "docs/"+storage.DirMarkerKey(dir), bytes.NewReader(nil), map[string]string{
+			storage.MetadataDirMode:    "700",
+			storage.MetadataDirModTime: modified.Format(time.RFC3339),
+		})
+		assert.NoError(t, err)
+	}
+
+	target := filepath.Join(t.TempDir(), "restore")
+	result, err := Folder(context.Background(), store, config.SyncFolder{ID: "docs"}, "laptop", target, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Files)
+
+	for _, dir := range []string{"sub", "empty"} {
+		info, err := os.Stat(filepath.Join(target, dir))
+		assert.NoError(t, err)
+		assert.True(t, info.IsDir())
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+		assert.True(t, info.ModTime().Equal(modified))
+
+		_, err = os.Stat(filepath.Join(target, dir, storage.DirMarker))
+		assert.True(t, os.IsNotExist(err))
+	}
+}
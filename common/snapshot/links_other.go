@@ -0,0 +1,10 @@
+//go:build !unix
+
+package snapshot
+
+import "os"
+
+// inodeOf is not available on this platform, so hard links are stored as separate files
+func inodeOf(info os.FileInfo) (inode, bool) {
+	return inode{}, false
+}
@@ -0,0 +1,218 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/martinshumberto/sync-manager/common/storage"
+)
+
+// Small files are packed into tar objects rather than stored one object each, so a folder of
+// millions of tiny files costs a request per pack instead of one per file. The manifest is the
+// index of the packs: each packed file records its pack and the offset of its content, which
+// a restore reads with a ranged download. Packs are shared between snapshots like objects,
+// and pruned once no snapshot references any of their files.
+
+// PackSize is the size a pack is uploaded at, once its files add up to it
+const PackSize = 16 << 20
+
+// location is where the content of a packed file is stored
+type location struct {
+	pack   string
+	offset int64
+}
+
+// packer collects the small files of a snapshot into packs
+type packer struct {
+	repo  *Repository
+	known map[string]location // By hash, the contents already packed
+
+	id      string // Of the pack being written, empty when none is
+	staged  *os.File
+	written *countingWriter
+	tar     *tar.Writer
+}
+
+// newPacker returns a packer reusing the packed contents of the newest snapshot, so files
+// that did not change are not packed again
+func (r *Repository) newPacker(ctx context.Context) (*packer, error) {
+	p := &packer{repo: r, known: make(map[string]location)}
+	newest, err := r.FindAt(ctx, time.Time{})
+	if errors.Is(err, ErrNotFound) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := r.Load(ctx, newest.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range manifest.Files {
+		if file.Pack != "" {
+			p.known[file.Hash] = location{pack: file.Pack, offset: file.Offset}
+		}
+	}
+	return p, nil
+}
+
+// add packs the content of the file at filePath into file, reporting false when the file
+// grew past the packing limit and must be stored as its own object
+func (p *packer) add(ctx context.Context, filePath string, file *File) (bool, error) {
+	source, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	content, err := io.ReadAll(io.LimitReader(source, p.repo.packLimit+1))
+	source.Close()
+	if err != nil {
+		return false, err
+	}
+	if int64(len(content)) > p.repo.packLimit {
+		return false, nil
+	}
+
+	sum := sha256.Sum256(content)
+	file.Hash = hex.EncodeToString(sum[:])
+	file.Size = int64(len(content))
+	if at, ok := p.known[file.Hash]; ok {
+		file.Pack, file.Offset = at.pack, at.offset
+		return true, nil
+	}
+
+	if p.id == "" {
+		if err := p.open(); err != nil {
+			return false, err
+		}
+	}
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: file.Hash, Size: file.Size, Mode: 0644, ModTime: file.ModTime}
+	if err := p.tar.WriteHeader(header); err != nil {
+		return false, err
+	}
+	file.Pack, file.Offset = p.id, p.written.n
+	if _, err := p.tar.Write(content); err != nil {
+		return false, err
+	}
+	p.known[file.Hash] = location{pack: file.Pack, offset: file.Offset}
+
+	if p.written.n >= PackSize {
+		return true, p.flush(ctx)
+	}
+	return true, nil
+}
+
+// open starts a new pack in a temporary file
+func (p *packer) open() error {
+	staged, err := os.CreateTemp("", "snapshot-pack-*")
+	if err != nil {
+		return err
+	}
+	p.id = uuid.New().String()
+	p.staged = staged
+	p.written = &countingWriter{w: staged}
+	p.tar = tar.NewWriter(p.written)
+	return nil
+}
+
+// flush uploads the pack being written, if any
+func (p *packer) flush(ctx context.Context) error {
+	if p.id == "" {
+		return nil
+	}
+	defer p.close()
+
+	if err := p.tar.Close(); err != nil {
+		return err
+	}
+	if _, err := p.staged.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	metadata := map[string]string{"content-type": "application/x-tar"}
+	if p.repo.storageClass != "" {
+		metadata[storage.MetadataStorageClass] = p.repo.storageClass
+	}
+	if _, err := p.repo.store.UploadFile(ctx, p.repo.packKey(p.id), p.staged, metadata); err != nil {
+		return fmt.Errorf("failed to upload pack: %w", err)
+	}
+	return nil
+}
+
+// close discards the pack being written
+func (p *packer) close() {
+	if p.staged != nil {
+		p.staged.Close()
+		os.Remove(p.staged.Name())
+	}
+	p.id, p.staged, p.written, p.tar = "", nil, nil, nil
+}
+
+// downloadPacked writes the content of a packed file to writer, reading only its range of
+// the pack when the storage supports it
+func (r *Repository) downloadPacked(ctx context.Context, file File, writer io.Writer) error {
+	if file.Size == 0 {
+		return nil
+	}
+	key := r.packKey(file.Pack)
+	if ranged, ok := r.store.(storage.RangeDownloader); ok {
+		err := ranged.DownloadRange(ctx, key, file.Offset, file.Size, writer)
+		if !errors.Is(err, storage.ErrRangeUnsupported) {
+			return err
+		}
+	}
+	window := &windowWriter{w: writer, skip: file.Offset, left: file.Size}
+	if _, err := r.store.DownloadFile(ctx, key, window, ""); err != nil {
+		return err
+	}
+	if window.left > 0 {
+		return fmt.Errorf("pack %s ends before the file", file.Pack)
+	}
+	return nil
+}
+
+func (r *Repository) packKey(id string) string {
+	return path.Join(KeyPrefix, r.folderID, "packs", id+".tar")
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// windowWriter passes on the left bytes that follow the first skip ones written to it
+type windowWriter struct {
+	w    io.Writer
+	skip int64
+	left int64
+}
+
+func (w *windowWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if w.skip > 0 {
+		skipped := min(w.skip, int64(len(p)))
+		w.skip -= skipped
+		p = p[skipped:]
+	}
+	if take := min(w.left, int64(len(p))); take > 0 {
+		if _, err := w.w.Write(p[:take]); err != nil {
+			return 0, err
+		}
+		w.left -= take
+	}
+	return n, nil
+}
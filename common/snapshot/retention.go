@@ -0,0 +1,75 @@
+package snapshot
+
+import "fmt"
+
+// Policy decides which snapshots to keep. A snapshot is kept when any rule
+// selects it; daily, weekly and monthly rules keep the newest snapshot of
+// each of the last N days, weeks or months that have one.
+type Policy struct {
+	KeepLast    int
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+}
+
+// IsZero reports whether the policy has no rules, in which case everything is kept
+func (p Policy) IsZero() bool {
+	return p.KeepLast <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0 && p.KeepMonthly <= 0
+}
+
+// Apply splits snapshots into the ones to keep and the ones to remove.
+// Both results are ordered newest first.
+func (p Policy) Apply(summaries []Summary) (keep, remove []Summary) {
+	sorted := make([]Summary, len(summaries))
+	copy(sorted, summaries)
+	sortNewestFirst(sorted)
+
+	if p.IsZero() {
+		return sorted, nil
+	}
+
+	kept := make([]bool, len(sorted))
+	for i := 0; i < len(sorted) && i < p.KeepLast; i++ {
+		kept[i] = true
+	}
+
+	keepBuckets(sorted, kept, p.KeepDaily, func(s Summary) string {
+		return s.CreatedAt.Local().Format("2006-01-02")
+	})
+	keepBuckets(sorted, kept, p.KeepWeekly, func(s Summary) string {
+		year, week := s.CreatedAt.Local().ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+	keepBuckets(sorted, kept, p.KeepMonthly, func(s Summary) string {
+		return s.CreatedAt.Local().Format("2006-01")
+	})
+
+	for i, summary := range sorted {
+		if kept[i] {
+			keep = append(keep, summary)
+		} else {
+			remove = append(remove, summary)
+		}
+	}
+	return keep, remove
+}
+
+// keepBuckets marks the newest snapshot of each of the first n buckets as kept
+func keepBuckets(sorted []Summary, kept []bool, n int, bucket func(Summary) string) {
+	if n <= 0 {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i, summary := range sorted {
+		key := bucket(summary)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept[i] = true
+		if len(seen) == n {
+			return
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/storage"
+)
+
+// KeyPrefix is the storage prefix under which snapshots are kept
+const KeyPrefix = storage.SnapshotKeyPrefix
+
+// ErrNotFound is returned when a snapshot does not exist
+var ErrNotFound = errors.New("snapshot not found")
+
+// ObjectStore is the subset of storage operations snapshots need
+type ObjectStore interface {
+	UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error)
+	DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error)
+	DeleteFile(ctx context.Context, key string) error
+	FileExists(ctx context.Context, key string) (bool, error)
+}
+
+// File is a file captured in a snapshot
+type File struct {
+	Path    string      `json:"path"` // Slash-separated, relative to the folder root
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mod_time"`
+	Hash    string      `json:"hash"` // SHA-256 of the content, also the object key
+	// Pack is the pack holding the content of a small file, at Offset, empty when the content
+	// is stored as its own object
+	Pack   string `json:"pack,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+	// LinkTo is the path of an earlier file of the snapshot this one is a hard link of
+	LinkTo string `json:"link_to,omitempty"`
+}
+
+// inode identifies a file on disk, so hard links to it are recognized
+type inode struct {
+	dev uint64
+	ino uint64
+}
+
+// Manifest describes a point-in-time snapshot of a folder
+type Manifest struct {
+	ID        string    `json:"id"`
+	FolderID  string    `json:"folder_id"`
+	DeviceID  string    `json:"device_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Files     []File    `json:"files"`
+}
+
+// Summary is the index entry of a snapshot
+type Summary struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Files     int       `json:"files"`
+	Size      int64     `json:"size"`
+}
+
+// Summary returns the index entry of the manifest
+func (m *Manifest) Summary() Summary {
+	summary := Summary{ID: m.ID, CreatedAt: m.CreatedAt, Files: len(m.Files)}
+	for _, file := range m.Files {
+		summary.Size += file.Size
+	}
+	return summary
+}
+
+// Repository stores the snapshots of a single folder. File contents are
+// stored once per folder under their hash, so unchanged files cost nothing
+// in later snapshots.
+type Repository struct {
+	store        ObjectStore
+	folderID     string
+	deviceID     string
+	storageClass string
+	hardLinks    bool
+	packLimit    int64 // Largest file packed, zero to store every file as its own object
+}
+
+// NewRepository creates a snapshot repository for a folder
+func NewRepository(store ObjectStore, folderID, deviceID string) *Repository {
+	return &Repository{
+		store:    store,
+		folderID: folderID,
+		deviceID: deviceID,
+	}
+}
+
+// SetStorageClass selects the storage class of file contents. Manifests and the
+// index stay in the bucket default class since they are read on every listing.
+func (r *Repository) SetStorageClass(class string) {
+	r.storageClass = class
+}
+
+// SetHardLinks makes restores recreate hard links between files instead of
+// writing a separate copy of each one
+func (r *Repository) SetHardLinks(enabled bool) {
+	r.hardLinks = enabled
+}
+
+// SetPacking packs files of up to limit bytes into shared packs instead of storing each as
+// its own object. Zero, the default, packs nothing.
+func (r *Repository) SetPacking(limit int64) {
+	r.packLimit = limit
+}
+
+// Create snapshots every file under root that does not match an exclude pattern
+func (r *Repository) Create(ctx context.Context, root string, exclude []string) (*Manifest, error) {
+	now := time.Now().UTC()
+	manifest := &Manifest{
+		ID:        now.Format("20060102T150405Z") + "-" + uuid.New().String()[:8],
+		FolderID:  r.folderID,
+		DeviceID:  r.deviceID,
+		CreatedAt: now,
+	}
+
+	// Hard links to a file already in the snapshot are recorded, not stored again
+	linked := make(map[inode]File)
+
+	var packs *packer
+	if r.packLimit > 0 {
+		var err error
+		if packs, err = r.newPacker(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load the previous snapshot: %w", err)
+		}
+		defer packs.close()
+	}
+
+	err := filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		if excluded(relPath, exclude) {
+			return nil
+		}
+
+		file := File{
+			Path:    filepath.ToSlash(relPath),
+			Size:    info.Size(),
+			Mode:    info.Mode().Perm(),
+			ModTime: info.ModTime(),
+		}
+
+		id, isLink := inodeOf(info)
+		if first, ok := linked[id]; isLink && ok {
+			file.Hash, file.Pack, file.Offset = first.Hash, first.Pack, first.Offset
+			file.LinkTo = first.Path
+			manifest.Files = append(manifest.Files, file)
+			return nil
+		}
+
+		packed := false
+		if packs != nil && file.Size <= r.packLimit {
+			if packed, err = packs.add(ctx, filePath, &file); err != nil {
+				return fmt.Errorf("failed to pack %s: %w", relPath, err)
+			}
+		}
+		if !packed {
+			file.Hash, file.Size, err = r.storeObject(ctx, filePath)
+			if err != nil {
+				return fmt.Errorf("failed to store %s: %w", relPath, err)
+			}
+		}
+		if isLink {
+			linked[id] = file
+		}
+
+		manifest.Files = append(manifest.Files, file)
+		return nil
+	})
+	if err == nil && packs != nil {
+		err = packs.flush(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan folder: %w", err)
+	}
+
+	if err := r.putJSON(ctx, r.manifestKey(manifest.ID), manifest); err != nil {
+		return nil, fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	summaries, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summaries = append(summaries, manifest.Summary())
+	if err := r.saveIndex(ctx, summaries); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// List returns the snapshots of the folder, newest first
+func (r *Repository) List(ctx context.Context) ([]Summary, error) {
+	var summaries []Summary
+	if err := r.getJSON(ctx, r.indexKey(), &summaries); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load snapshot index: %w", err)
+	}
+
+	sortNewestFirst(summaries)
+	return summaries, nil
+}
+
+// Load returns the manifest of a snapshot
+func (r *Repository) Load(ctx context.Context, id string) (*Manifest, error) {
+	var manifest Manifest
+	if err := r.getJSON(ctx, r.manifestKey(id), &manifest); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to load manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// FindAt returns the newest snapshot taken at or before t. A zero t returns the newest snapshot.
+func (r *Repository) FindAt(ctx context.Context, t time.Time) (*Summary, error) {
+	summaries, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, summary := range summaries {
+		if t.IsZero() || !summary.CreatedAt.After(t) {
+			return &summary, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Restore writes the files of a snapshot into target and returns how many were restored
+func (r *Repository) Restore(ctx context.Context, id, target string) (int, error) {
+	manifest, err := r.Load(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, file := range manifest.Files {
+		if err := r.RestoreFile(ctx, file, target, nil); err != nil {
+			return i, fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+	}
+
+	return len(manifest.Files), nil
+}
+
+// Prune deletes the snapshots the policy does not keep, along with objects
+// no remaining snapshot references. It returns the removed snapshots.
+func (r *Repository) Prune(ctx context.Context, policy Policy) ([]Summary, error) {
+	summaries, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keep, remove := policy.Apply(summaries)
+	if len(remove) == 0 {
+		return nil, nil
+	}
+
+	// Collect the objects and packs still in use before deleting anything
+	referenced := make(map[string]bool)
+	referencedPacks := make(map[string]bool)
+	for _, summary := range keep {
+		manifest, err := r.Load(ctx, summary.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range manifest.Files {
+			if file.Pack != "" {
+				referencedPacks[file.Pack] = true
+			} else {
+				referenced[file.Hash] = true
+			}
+		}
+	}
+
+	// Update the index first so an interrupted prune never lists deleted snapshots
+	if err := r.saveIndex(ctx, keep); err != nil {
+		return nil, err
+	}
+
+	for _, summary := range remove {
+		manifest, err := r.Load(ctx, summary.ID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if manifest != nil {
+			for _, file := range manifest.Files {
+				if file.Pack != "" {
+					if referencedPacks[file.Pack] {
+						continue
+					}
+					referencedPacks[file.Pack] = true // Delete each pack once
+					if err := r.store.DeleteFile(ctx, r.packKey(file.Pack)); err != nil {
+						return nil, fmt.Errorf("failed to delete pack %s: %w", file.Pack, err)
+					}
+					continue
+				}
+				if referenced[file.Hash] {
+					continue
+				}
+				referenced[file.Hash] = true // Delete each object once
+				if err := r.store.DeleteFile(ctx, r.objectKey(file.Hash)); err != nil {
+					return nil, fmt.Errorf("failed to delete object %s: %w", file.Hash, err)
+				}
+			}
+		}
+		if err := r.store.DeleteFile(ctx, r.manifestKey(summary.ID)); err != nil {
+			return nil, fmt.Errorf("failed to delete manifest %s: %w", summary.ID, err)
+		}
+	}
+
+	return remove, nil
+}
+
+// storeObject uploads the content of a file unless the repository already has it.
+// The file is copied aside while it is hashed, so the uploaded bytes are exactly
+// the hashed ones even if the file changes meanwhile. It returns the hash and
+// the size of the stored content.
+func (r *Repository) storeObject(ctx context.Context, filePath string) (string, int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	staged, err := os.CreateTemp("", "snapshot-object-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(staged.Name())
+	defer staged.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(staged, io.TeeReader(file, hasher))
+	if err != nil {
+		return "", 0, err
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	key := r.objectKey(hash)
+	exists, err := r.store.FileExists(ctx, key)
+	if err != nil {
+		return "", 0, err
+	}
+	if exists {
+		return hash, size, nil
+	}
+
+	if _, err := staged.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	metadata := map[string]string{"hash_sha256": hash}
+	if r.storageClass != "" {
+		metadata[storage.MetadataStorageClass] = r.storageClass
+	}
+	if _, err := r.store.UploadFile(ctx, key, staged, metadata); err != nil {
+		return "", 0, err
+	}
+
+	return hash, size, nil
+}
+
+// RestoreFile downloads a snapshot file into target, restoring its mode and time.
+// The downloaded content is also written to counter when it is not nil. With
+// hard links enabled, a link whose original is already in target is linked to it.
+func (r *Repository) RestoreFile(ctx context.Context, file File, target string, counter io.Writer) error {
+	localPath := filepath.Join(target, filepath.FromSlash(file.Path))
+	if !isSubPath(target, localPath) {
+		return fmt.Errorf("path escapes the target directory")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+
+	if r.hardLinks && file.LinkTo != "" && r.restoreLink(file, target, localPath) {
+		return nil
+	}
+
+	tmpPath := localPath + ".restore"
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, file.Mode)
+	if err != nil {
+		return err
+	}
+
+	hasher := sha256.New()
+	writer := io.MultiWriter(out, hasher)
+	if counter != nil {
+		writer = io.MultiWriter(writer, counter)
+	}
+	if file.Pack != "" {
+		err = r.downloadPacked(ctx, file, writer)
+	} else {
+		_, err = r.store.DownloadFile(ctx, r.objectKey(file.Hash), writer, "")
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && hex.EncodeToString(hasher.Sum(nil)) != file.Hash {
+		err = fmt.Errorf("content does not match snapshot hash")
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		return err
+	}
+	if err := os.Chmod(localPath, file.Mode); err != nil {
+		return err
+	}
+	return os.Chtimes(localPath, file.ModTime, file.ModTime)
+}
+
+// restoreLink hard links localPath to the restored original of file. It
+// reports false when the original is missing or does not match, so the
+// content is downloaded instead.
+func (r *Repository) restoreLink(file File, target, localPath string) bool {
+	original := filepath.Join(target, filepath.FromSlash(file.LinkTo))
+	if !isSubPath(target, original) {
+		return false
+	}
+	info, err := os.Stat(original)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != file.Size {
+		return false
+	}
+
+	tmpPath := localPath + ".restore"
+	os.Remove(tmpPath)
+	if err := os.Link(original, tmpPath); err != nil {
+		return false
+	}
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		os.Remove(tmpPath)
+		return false
+	}
+	return true
+}
+
+// saveIndex stores the snapshot index of the folder
+func (r *Repository) saveIndex(ctx context.Context, summaries []Summary) error {
+	if summaries == nil {
+		summaries = []Summary{}
+	}
+	sortNewestFirst(summaries)
+	if err := r.putJSON(ctx, r.indexKey(), summaries); err != nil {
+		return fmt.Errorf("failed to save snapshot index: %w", err)
+	}
+	return nil
+}
+
+// putJSON uploads a value as a JSON object
+func (r *Repository) putJSON(ctx context.Context, key string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = r.store.UploadFile(ctx, key, bytes.NewReader(data), map[string]string{"content-type": "application/json"})
+	return err
+}
+
+// getJSON downloads a JSON object into value
+func (r *Repository) getJSON(ctx context.Context, key string, value interface{}) error {
+	exists, err := r.store.FileExists(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	var buf bytes.Buffer
+	if _, err := r.store.DownloadFile(ctx, key, &buf, ""); err != nil {
+		return err
+	}
+	return json.Unmarshal(buf.Bytes(), value)
+}
+
+func (r *Repository) indexKey() string {
+	return path.Join(KeyPrefix, r.folderID, "index.json")
+}
+
+func (r *Repository) manifestKey(id string) string {
+	return path.Join(KeyPrefix, r.folderID, "manifests", id+".json")
+}
+
+func (r *Repository) objectKey(hash string) string {
+	return path.Join(KeyPrefix, r.folderID, "objects", hash[:2], hash)
+}
+
+// excluded reports whether a relative path matches one of the exclude patterns
+func excluded(relPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if common_config.MatchExclude(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubPath reports whether path is inside root
+func isSubPath(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// sortNewestFirst orders snapshots by creation time, newest first
+func sortNewestFirst(summaries []Summary) {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].CreatedAt.After(summaries[j].CreatedAt)
+	})
+}
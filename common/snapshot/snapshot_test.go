@@ -0,0 +1,353 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore is an in-memory ObjectStore
+type memoryStore struct {
+	objects  map[string][]byte
+	metadata map[string]map[string]string
+	uploads  int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: make(map[string][]byte), metadata: make(map[string]map[string]string)}
+}
+
+func (m *memoryStore) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	data, err := io.ReadAll(reader)
+	m.objects[key] = data
+	m.metadata[key] = metadata
+	m.uploads++
+	return "", err
+}
+
+func (m *memoryStore) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	_, err := io.Copy(writer, bytes.NewReader(m.objects[key]))
+	return nil, err
+}
+
+func (m *memoryStore) DeleteFile(ctx context.Context, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memoryStore) FileExists(ctx context.Context, key string) (bool, error) {
+	_, ok := m.objects[key]
+	return ok, nil
+}
+
+func (m *memoryStore) count(prefix string) int {
+	n := 0
+	for key := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func writeFile(t *testing.T, root, name, content string) {
+	path := filepath.Join(root, name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0640))
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	repo := NewRepository(store, "docs", "laptop")
+
+	root := t.TempDir()
+	writeFile(t, root, "a.txt", "alpha")
+	writeFile(t, root, "sub/b.txt", "beta")
+	writeFile(t, root, "copy.txt", "alpha")
+	writeFile(t, root, "skip.tmp", "ignored")
+
+	first, err := repo.Create(ctx, root, []string{"*.tmp"})
+	assert.NoError(t, err)
+	assert.Len(t, first.Files, 3)
+
+	// Identical content is stored once
+	assert.Equal(t, 2, store.count(".snapshots/docs/objects/"))
+
+	// Changing one file only uploads the new content
+	writeFile(t, root, "a.txt", "alpha v2")
+	uploads := store.uploads
+	_, err = repo.Create(ctx, root, []string{"*.tmp"})
+	assert.NoError(t, err)
+	assert.Equal(t, uploads+3, store.uploads) // object, manifest and index
+	assert.Equal(t, 3, store.count(".snapshots/docs/objects/"))
+
+	summaries, err := repo.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, summaries, 2)
+
+	// The first snapshot still has the original content
+	target := t.TempDir()
+	restored, err := repo.Restore(ctx, first.ID, target)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, restored)
+
+	data, err := os.ReadFile(filepath.Join(target, "a.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "alpha", string(data))
+
+	info, err := os.Stat(filepath.Join(target, "sub", "b.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	_, err = repo.Restore(ctx, "missing", target)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// changingStore rewrites a file when the store is first asked whether an object exists
+type changingStore struct {
+	*memoryStore
+	path    string
+	content string
+}
+
+func (c *changingStore) FileExists(ctx context.Context, key string) (bool, error) {
+	if c.path != "" {
+		os.WriteFile(c.path, []byte(c.content), 0640)
+		c.path = ""
+	}
+	return c.memoryStore.FileExists(ctx, key)
+}
+
+func TestStoredObjectsMatchTheirHash(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	writeFile(t, root, "a.txt", "alpha")
+
+	// The file changes between hashing and uploading
+	store := &changingStore{memoryStore: newMemoryStore(), path: filepath.Join(root, "a.txt"), content: "changed content"}
+	repo := NewRepository(store, "docs", "laptop")
+
+	manifest, err := repo.Create(ctx, root, nil)
+	assert.NoError(t, err)
+	assert.Len(t, manifest.Files, 1)
+
+	file := manifest.Files[0]
+	assert.Equal(t, int64(len("alpha")), file.Size)
+	assert.Equal(t, []byte("alpha"), store.objects[repo.objectKey(file.Hash)])
+
+	target := t.TempDir()
+	_, err = repo.Restore(ctx, manifest.ID, target)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(target, "a.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "alpha", string(data))
+}
+
+func TestHardLinksAreStoredOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard links are only tracked on Unix")
+	}
+
+	ctx := context.Background()
+	store := newMemoryStore()
+	repo := NewRepository(store, "photos", "laptop")
+
+	root := t.TempDir()
+	writeFile(t, root, "a/photo.jpg", "pixels")
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "b"), 0755))
+	assert.NoError(t, os.Link(filepath.Join(root, "a", "photo.jpg"), filepath.Join(root, "b", "photo.jpg")))
+
+	manifest, err := repo.Create(ctx, root, nil)
+	assert.NoError(t, err)
+	assert.Len(t, manifest.Files, 2)
+	assert.Equal(t, "", manifest.Files[0].LinkTo)
+	assert.Equal(t, "a/photo.jpg", manifest.Files[1].LinkTo)
+	assert.Equal(t, manifest.Files[0].Hash, manifest.Files[1].Hash)
+	assert.Equal(t, 1, store.count(".snapshots/photos/objects/"))
+
+	// Without hard links the link is restored as a copy
+	copies := t.TempDir()
+	_, err = repo.Restore(ctx, manifest.ID, copies)
+	assert.NoError(t, err)
+	first, err := os.Stat(filepath.Join(copies, "a", "photo.jpg"))
+	assert.NoError(t, err)
+	second, err := os.Stat(filepath.Join(copies, "b", "photo.jpg"))
+	assert.NoError(t, err)
+	assert.False(t, os.SameFile(first, second))
+
+	repo.SetHardLinks(true)
+	linked := t.TempDir()
+	restored, err := repo.Restore(ctx, manifest.ID, linked)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, restored)
+	first, err = os.Stat(filepath.Join(linked, "a", "photo.jpg"))
+	assert.NoError(t, err)
+	second, err = os.Stat(filepath.Join(linked, "b", "photo.jpg"))
+	assert.NoError(t, err)
+	assert.True(t, os.SameFile(first, second))
+
+	data, err := os.ReadFile(filepath.Join(linked, "b", "photo.jpg"))
+	assert.NoError(t, err)
+	assert.Equal(t, "pixels", string(data))
+}
+
+func TestStorageClassAppliesToContents(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	repo := NewRepository(store, "docs", "laptop")
+	repo.SetStorageClass("GLACIER_IR")
+
+	root := t.TempDir()
+	writeFile(t, root, "a.txt", "alpha")
+
+	_, err := repo.Create(ctx, root, nil)
+	assert.NoError(t, err)
+
+	for key, metadata := range store.metadata {
+		if strings.HasPrefix(key, ".snapshots/docs/objects/") {
+			assert.Equal(t, "GLACIER_IR", metadata[storage.MetadataStorageClass], key)
+		} else {
+			assert.Empty(t, metadata[storage.MetadataStorageClass], key)
+		}
+	}
+}
+
+func TestPruneRemovesUnreferencedObjects(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	repo := NewRepository(store, "docs", "laptop")
+
+	root := t.TempDir()
+	writeFile(t, root, "same.txt", "unchanged")
+	writeFile(t, root, "a.txt", "v1")
+	_, err := repo.Create(ctx, root, nil)
+	assert.NoError(t, err)
+
+	writeFile(t, root, "a.txt", "v2")
+	latest, err := repo.Create(ctx, root, nil)
+	assert.NoError(t, err)
+
+	removed, err := repo.Prune(ctx, Policy{KeepLast: 1})
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+
+	summaries, err := repo.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, summaries, 1)
+	assert.Equal(t, latest.ID, summaries[0].ID)
+
+	// Only v1 is gone; the shared object is still referenced
+	assert.Equal(t, 2, store.count(".snapshots/docs/objects/"))
+	assert.Equal(t, 1, store.count(".snapshots/docs/manifests/"))
+
+	restored, err := repo.Restore(ctx, latest.ID, t.TempDir())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, restored)
+}
+
+func TestPackedSmallFiles(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	for i := 0; i < 20; i++ {
+		writeFile(t, root, filepath.Join("notes", fmt.Sprintf("%02d.txt", i)), fmt.Sprintf("note %d", i))
+	}
+	writeFile(t, root, "copy.txt", "note 3")
+	writeFile(t, root, "large.bin", strings.Repeat("x", 100))
+
+	// Restores read packs with ranged downloads when the storage has them, and whole otherwise
+	for name, store := range map[string]ObjectStore{
+		"ranged": storage.NewMemoryStorage(&storage.MemoryConfig{Name: t.Name()}),
+		"whole":  newMemoryStore(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := NewRepository(store, "notes", "laptop")
+			repo.SetPacking(64)
+
+			first, err := repo.Create(ctx, root, nil)
+			assert.NoError(t, err)
+			assert.Len(t, first.Files, 22)
+			packs := make(map[string]bool)
+			for _, file := range first.Files {
+				if file.Path == "large.bin" {
+					assert.Empty(t, file.Pack)
+					continue
+				}
+				assert.NotEmpty(t, file.Pack, file.Path)
+				packs[file.Pack] = true
+			}
+			assert.Len(t, packs, 1)
+
+			// Only the changed file goes into a new pack
+			writeFile(t, root, "notes/00.txt", "note 0 v2")
+			latest, err := repo.Create(ctx, root, nil)
+			assert.NoError(t, err)
+			for _, file := range latest.Files {
+				if file.Pack != "" && file.Path != "notes/00.txt" {
+					assert.True(t, packs[file.Pack], file.Path)
+				}
+			}
+
+			target := t.TempDir()
+			restored, err := repo.Restore(ctx, latest.ID, target)
+			assert.NoError(t, err)
+			assert.Equal(t, 22, restored)
+			for path, content := range map[string]string{"notes/00.txt": "note 0 v2", "notes/19.txt": "note 19", "copy.txt": "note 3"} {
+				data, err := os.ReadFile(filepath.Join(target, path))
+				assert.NoError(t, err)
+				assert.Equal(t, content, string(data))
+			}
+
+			// Pruning the first snapshot keeps the pack the latest one still reads from
+			_, err = repo.Prune(ctx, Policy{KeepLast: 1})
+			assert.NoError(t, err)
+			restored, err = repo.Restore(ctx, latest.ID, t.TempDir())
+			assert.NoError(t, err)
+			assert.Equal(t, 22, restored)
+			writeFile(t, root, "notes/00.txt", "note 0")
+		})
+	}
+}
+
+func TestPolicyApply(t *testing.T) {
+	base := time.Date(2026, 3, 31, 12, 0, 0, 0, time.Local)
+
+	var summaries []Summary
+	// Two snapshots a day for 60 days
+	for day := 0; day < 60; day++ {
+		for _, hour := range []int{0, 6} {
+			created := base.AddDate(0, 0, -day).Add(-time.Duration(hour) * time.Hour)
+			summaries = append(summaries, Summary{ID: created.Format(time.RFC3339), CreatedAt: created})
+		}
+	}
+
+	keep, remove := Policy{}.Apply(summaries)
+	assert.Len(t, keep, 120)
+	assert.Empty(t, remove)
+
+	keep, remove = Policy{KeepLast: 3}.Apply(summaries)
+	assert.Len(t, keep, 3)
+	assert.Len(t, remove, 117)
+	assert.Equal(t, base, keep[0].CreatedAt)
+
+	keep, _ = Policy{KeepDaily: 7}.Apply(summaries)
+	assert.Len(t, keep, 7)
+	for i, summary := range keep {
+		// The newest snapshot of each day
+		assert.Equal(t, base.AddDate(0, 0, -i), summary.CreatedAt)
+	}
+
+	// Rules overlap: the latest snapshot counts for every rule
+	keep, _ = Policy{KeepLast: 2, KeepDaily: 2, KeepMonthly: 3}.Apply(summaries)
+	assert.Len(t, keep, 5) // 2 latest, yesterday, and the newest of February and January
+}
@@ -0,0 +1,14 @@
+//go:build linux
+
+package sparse
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// punchHole releases the disk space of a range of a file, keeping its size
+func punchHole(file *os.File, offset, length int64) error {
+	return unix.Fallocate(int(file.Fd()), unix.FALLOC_FL_PUNCH_HOLE|unix.FALLOC_FL_KEEP_SIZE, offset, length)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sparse
+
+import (
+	"errors"
+	"os"
+)
+
+// punchHole is not supported on this platform, so downloaded files keep their zero ranges
+func punchHole(file *os.File, offset, length int64) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,85 @@
+// Package sparse detects sparse files and restores their holes after a download
+package sparse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MetadataKey marks uploads of sparse files, whose zero ranges are turned back into holes when downloaded
+const MetadataKey = "sparse"
+
+// MinHoleSize is how many bytes a file must have unallocated to be considered sparse, so
+// small differences from filesystem block rounding or inline data do not count
+const MinHoleSize = 1 << 20
+
+// blockSize is the granularity holes are punched at
+const blockSize = 4096
+
+// IsSparse reports whether a regular file has at least MinHoleSize bytes that take no disk space.
+// It is always false on platforms that do not report the allocated size of files.
+func IsSparse(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	allocated, ok := allocatedSize(info)
+	return ok && info.Size()-allocated >= MinHoleSize
+}
+
+// Punch turns the zero-filled blocks of a file back into holes and returns how many bytes
+// were released. Files on filesystems or platforms without hole punching are left as they are.
+func Punch(path string) (int64, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return punchZeros(file, punchHole)
+}
+
+// punchZeros calls punch for every run of zero blocks of file
+func punchZeros(file *os.File, punch func(file *os.File, offset, length int64) error) (int64, error) {
+	var (
+		buf      = make([]byte, 256*blockSize)
+		zero     = make([]byte, blockSize)
+		offset   int64
+		start    = int64(-1) // Start of the current zero run, -1 outside one
+		released int64
+	)
+	flush := func(end int64) error {
+		if start < 0 || end-start < MinHoleSize {
+			start = -1
+			return nil
+		}
+		if err := punch(file, start, end-start); err != nil {
+			return err
+		}
+		released += end - start
+		start = -1
+		return nil
+	}
+
+	for {
+		n, err := io.ReadFull(file, buf)
+		for i := 0; i < n; i += blockSize {
+			block := buf[i:min(i+blockSize, n)]
+			if bytes.Equal(block, zero[:len(block)]) {
+				if start < 0 {
+					start = offset + int64(i)
+				}
+			} else if err := flush(offset + int64(i)); err != nil {
+				return released, err
+			}
+		}
+		offset += int64(n)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return released, flush(offset)
+		}
+		if err != nil {
+			return released, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+}
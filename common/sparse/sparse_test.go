@@ -0,0 +1,62 @@
+package sparse
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPunchZerosFindsZeroRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.img")
+	data := make([]byte, 4*MinHoleSize)
+	copy(data, "header")
+	data[2*MinHoleSize+10] = 1                    // Splits the zeros into two runs
+	copy(data[len(data)-blockSize/2:], "trailer") // Short runs are not worth a hole
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var holes [][2]int64
+	released, err := punchZeros(file, func(_ *os.File, offset, length int64) error {
+		holes = append(holes, [2]int64{offset, length})
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]int64{
+		{blockSize, 2*MinHoleSize - blockSize},
+		{2*MinHoleSize + blockSize, 2*MinHoleSize - 2*blockSize},
+	}, holes)
+	assert.Equal(t, int64(4*MinHoleSize-3*blockSize), released)
+}
+
+func TestPunchRestoresSparseness(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("hole punching is only supported on Linux")
+	}
+
+	path := filepath.Join(t.TempDir(), "disk.img")
+	data := make([]byte, 4*MinHoleSize)
+	copy(data, "header")
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.False(t, IsSparse(info))
+
+	if _, err := Punch(path); err != nil {
+		t.Skipf("filesystem does not support hole punching: %v", err)
+	}
+	info, err = os.Stat(path)
+	assert.NoError(t, err)
+	assert.True(t, IsSparse(info))
+	assert.Equal(t, int64(len(data)), info.Size())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, data, content)
+}
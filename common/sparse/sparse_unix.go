@@ -0,0 +1,17 @@
+//go:build unix
+
+package sparse
+
+import (
+	"os"
+	"syscall"
+)
+
+// allocatedSize returns the bytes a file takes on disk, from its count of 512-byte blocks
+func allocatedSize(info os.FileInfo) (int64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int64(stat.Blocks) * 512, true
+}
@@ -0,0 +1,398 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Windows are the spans the transfer rates are averaged over
+var Windows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// sampleEvery is the minimum spacing between rate samples
+const sampleEvery = time.Second
+
+// Registry counts the transfers of the agent, globally and per folder. Counters are
+// updated atomically, so it is safe for concurrent use without holding other locks.
+type Registry struct {
+	startedAt time.Time
+	global    *counters
+	folders   map[string]*counters
+	summaries map[string]FolderSummary
+	foldersMu sync.RWMutex
+	samples   []sample
+	samplesMu sync.Mutex
+	now       func() time.Time
+}
+
+// counters are the totals of one folder, or of every folder
+type counters struct {
+	filesUploaded   atomic.Int64
+	filesDownloaded atomic.Int64
+	bytesUploaded   atomic.Int64
+	bytesDownloaded atomic.Int64
+	errors          atomic.Int64
+	filesDeleted    atomic.Int64
+	filesSkipped    atomic.Int64
+	conflicts       atomic.Int64
+	lastSync        atomic.Int64 // Unix nanoseconds, zero when never synced
+	day             atomic.Int64 // Local date of bytesToday as YYYYMMDD
+	bytesToday      atomic.Int64 // Bytes transferred in either direction on day
+}
+
+// sample holds the transferred bytes at a point in time, used to compute rates
+type sample struct {
+	at      time.Time
+	global  transferred
+	folders map[string]transferred
+}
+
+// transferred is a byte count in each direction
+type transferred struct {
+	up, down int64
+}
+
+// Snapshot is the state of a Registry at a point in time
+type Snapshot struct {
+	StartedAt time.Time         `json:"started_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Global    Totals            `json:"global"`
+	Folders   map[string]Totals `json:"folders"`
+
+	// Summaries are the file aggregates of each folder as of its last sync
+	Summaries map[string]FolderSummary `json:"summaries,omitempty"`
+}
+
+// Totals are the counters of one folder, or of every folder, with their recent rates
+type Totals struct {
+	FilesUploaded   int64     `json:"files_uploaded"`
+	FilesDownloaded int64     `json:"files_downloaded"`
+	BytesUploaded   int64     `json:"bytes_uploaded"`
+	BytesDownloaded int64     `json:"bytes_downloaded"`
+	Errors          int64     `json:"errors"`
+	FilesDeleted    int64     `json:"files_deleted,omitempty"` // Removed by sync, locally or remotely
+	FilesSkipped    int64     `json:"files_skipped,omitempty"` // Left out of the upload by policy
+	Conflicts       int64     `json:"conflicts,omitempty"`
+	BytesToday      int64     `json:"bytes_today"` // Uploaded and downloaded since local midnight
+	LastSync        time.Time `json:"last_sync,omitempty"`
+	Rates           []Rate    `json:"rates"` // One per entry of Windows
+}
+
+// Rate is the average transfer rate over a window
+type Rate struct {
+	Window   time.Duration `json:"window"`
+	Upload   float64       `json:"upload"`   // Bytes per second
+	Download float64       `json:"download"` // Bytes per second
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return newRegistry(time.Now)
+}
+
+// newRegistry creates an empty registry reading the time from now
+func newRegistry(now func() time.Time) *Registry {
+	r := &Registry{
+		global:    &counters{},
+		folders:   make(map[string]*counters),
+		summaries: make(map[string]FolderSummary),
+		now:       now,
+	}
+	r.startedAt = now()
+	r.samples = []sample{{at: r.startedAt, folders: map[string]transferred{}}}
+	return r
+}
+
+// StartedAt returns when the registry started counting
+func (r *Registry) StartedAt() time.Time {
+	return r.startedAt
+}
+
+// Uploaded counts an uploaded file of size bytes. An empty folder ID only counts globally.
+func (r *Registry) Uploaded(folderID string, size int64) {
+	today := dayOf(r.now())
+	for _, c := range r.counters(folderID) {
+		c.filesUploaded.Add(1)
+		c.bytesUploaded.Add(size)
+		c.addToday(today, size)
+	}
+}
+
+// Downloaded counts a downloaded file of size bytes
+func (r *Registry) Downloaded(folderID string, size int64) {
+	today := dayOf(r.now())
+	for _, c := range r.counters(folderID) {
+		c.filesDownloaded.Add(1)
+		c.bytesDownloaded.Add(size)
+		c.addToday(today, size)
+	}
+}
+
+// Failed counts an error
+func (r *Registry) Failed(folderID string) {
+	for _, c := range r.counters(folderID) {
+		c.errors.Add(1)
+	}
+}
+
+// Deleted counts a file the sync removed, locally or remotely
+func (r *Registry) Deleted(folderID string) {
+	for _, c := range r.counters(folderID) {
+		c.filesDeleted.Add(1)
+	}
+}
+
+// Skipped counts a file left out of the upload by policy
+func (r *Registry) Skipped(folderID string) {
+	for _, c := range r.counters(folderID) {
+		c.filesSkipped.Add(1)
+	}
+}
+
+// Conflicted counts a file changed on both sides
+func (r *Registry) Conflicted(folderID string) {
+	for _, c := range r.counters(folderID) {
+		c.conflicts.Add(1)
+	}
+}
+
+// Synced records that a folder finished syncing at t
+func (r *Registry) Synced(folderID string, t time.Time) {
+	for _, c := range r.counters(folderID) {
+		c.lastSync.Store(t.UnixNano())
+	}
+}
+
+// RemoveFolder forgets the counters and summary of a folder; the global totals keep its transfers
+func (r *Registry) RemoveFolder(folderID string) {
+	r.foldersMu.Lock()
+	defer r.foldersMu.Unlock()
+	delete(r.folders, folderID)
+	delete(r.summaries, folderID)
+}
+
+// counters returns the global counters and, for a non-empty ID, those of the folder
+func (r *Registry) counters(folderID string) []*counters {
+	if folderID == "" {
+		return []*counters{r.global}
+	}
+
+	r.foldersMu.RLock()
+	c := r.folders[folderID]
+	r.foldersMu.RUnlock()
+
+	if c == nil {
+		r.foldersMu.Lock()
+		if c = r.folders[folderID]; c == nil {
+			c = &counters{}
+			r.folders[folderID] = c
+		}
+		r.foldersMu.Unlock()
+	}
+
+	return []*counters{r.global, c}
+}
+
+// Folder returns the totals of a folder, without rates
+func (r *Registry) Folder(folderID string) Totals {
+	r.foldersMu.RLock()
+	defer r.foldersMu.RUnlock()
+	if c := r.folders[folderID]; c != nil {
+		return c.totals(dayOf(r.now()))
+	}
+	return Totals{}
+}
+
+// Snapshot returns the current totals and rates
+func (r *Registry) Snapshot() Snapshot {
+	now := r.now()
+
+	today := dayOf(now)
+	snap := Snapshot{
+		StartedAt: r.startedAt,
+		UpdatedAt: now,
+		Global:    r.global.totals(today),
+		Folders:   make(map[string]Totals),
+	}
+	r.foldersMu.RLock()
+	for id, c := range r.folders {
+		snap.Folders[id] = c.totals(today)
+	}
+	if len(r.summaries) > 0 {
+		snap.Summaries = make(map[string]FolderSummary, len(r.summaries))
+		for id, summary := range r.summaries {
+			snap.Summaries[id] = summary.Copy()
+		}
+	}
+	r.foldersMu.RUnlock()
+
+	r.samplesMu.Lock()
+	defer r.samplesMu.Unlock()
+
+	r.record(now, snap)
+
+	for _, window := range Windows {
+		base := r.base(now, window)
+		elapsed := now.Sub(base.at).Seconds()
+
+		snap.Global.Rates = append(snap.Global.Rates, rate(window, snap.Global, base.global, elapsed))
+		for id, totals := range snap.Folders {
+			totals.Rates = append(totals.Rates, rate(window, totals, base.folders[id], elapsed))
+			snap.Folders[id] = totals
+		}
+	}
+
+	return snap
+}
+
+// record adds a rate sample and forgets the ones no window needs. Callers hold samplesMu.
+func (r *Registry) record(now time.Time, snap Snapshot) {
+	if n := len(r.samples); n == 0 || now.Sub(r.samples[n-1].at) >= sampleEvery {
+		s := sample{
+			at:      now,
+			global:  transferred{snap.Global.BytesUploaded, snap.Global.BytesDownloaded},
+			folders: make(map[string]transferred, len(snap.Folders)),
+		}
+		for id, totals := range snap.Folders {
+			s.folders[id] = transferred{totals.BytesUploaded, totals.BytesDownloaded}
+		}
+		r.samples = append(r.samples, s)
+	}
+
+	// Keep the newest sample older than the longest window as its base
+	cutoff := now.Add(-Windows[len(Windows)-1])
+	drop := 0
+	for drop < len(r.samples)-1 && !r.samples[drop+1].at.After(cutoff) {
+		drop++
+	}
+	r.samples = r.samples[drop:]
+}
+
+// base returns the newest sample at least window old, or the oldest one. Callers hold samplesMu.
+func (r *Registry) base(now time.Time, window time.Duration) sample {
+	cutoff := now.Add(-window)
+	base := r.samples[0]
+	for _, s := range r.samples[1:] {
+		if s.at.After(cutoff) {
+			break
+		}
+		base = s
+	}
+	return base
+}
+
+// rate averages the bytes transferred since base over elapsed seconds
+func rate(window time.Duration, totals Totals, base transferred, elapsed float64) Rate {
+	rt := Rate{Window: window}
+	if elapsed > 0 {
+		rt.Upload = float64(totals.BytesUploaded-base.up) / elapsed
+		rt.Download = float64(totals.BytesDownloaded-base.down) / elapsed
+	}
+	return rt
+}
+
+// addToday counts bytes transferred on today, starting over when the day changed
+func (c *counters) addToday(today, size int64) {
+	if day := c.day.Load(); day != today && c.day.CompareAndSwap(day, today) {
+		c.bytesToday.Store(0)
+	}
+	c.bytesToday.Add(size)
+}
+
+// totals reads the counters; today is the current local date as YYYYMMDD
+func (c *counters) totals(today int64) Totals {
+	t := Totals{
+		FilesUploaded:   c.filesUploaded.Load(),
+		FilesDownloaded: c.filesDownloaded.Load(),
+		BytesUploaded:   c.bytesUploaded.Load(),
+		BytesDownloaded: c.bytesDownloaded.Load(),
+		Errors:          c.errors.Load(),
+		FilesDeleted:    c.filesDeleted.Load(),
+		FilesSkipped:    c.filesSkipped.Load(),
+		Conflicts:       c.conflicts.Load(),
+	}
+	if c.day.Load() == today {
+		t.BytesToday = c.bytesToday.Load()
+	}
+	if ns := c.lastSync.Load(); ns != 0 {
+		t.LastSync = time.Unix(0, ns)
+	}
+	return t
+}
+
+// dayOf returns the local date of t as YYYYMMDD
+func dayOf(t time.Time) int64 {
+	year, month, day := t.Local().Date()
+	return int64(year*10000 + int(month)*100 + day)
+}
+
+// Rate returns the rate averaged over window, or a zero rate when it is not tracked
+func (t Totals) Rate(window time.Duration) Rate {
+	for _, rt := range t.Rates {
+		if rt.Window == window {
+			return rt
+		}
+	}
+	return Rate{Window: window}
+}
+
+// Active reports whether anything was transferred during the shortest window
+func (t Totals) Active() bool {
+	rt := t.Rate(Windows[0])
+	return rt.Upload > 0 || rt.Download > 0
+}
+
+// DefaultPath returns the default location of the file where the agent publishes its stats
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "stats.json"), nil
+}
+
+// Write stores the snapshot at path
+func Write(path string, snap Snapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create stats directory: %w", err)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write stats: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace stats: %w", err)
+	}
+
+	return nil
+}
+
+// Read loads the snapshot at path, returning nil if the agent never wrote one
+func Read(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read stats: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse stats: %w", err)
+	}
+
+	return &snap, nil
+}
@@ -0,0 +1,147 @@
+package stats
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock for the registry
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestRegistry() (*Registry, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	return newRegistry(clock.Now), clock
+}
+
+func TestRegistryCountsPerFolder(t *testing.T) {
+	r, clock := newTestRegistry()
+
+	r.Uploaded("docs", 100)
+	r.Uploaded("photos", 300)
+	r.Downloaded("docs", 50)
+	r.Failed("photos")
+	r.Failed("")
+	r.Deleted("docs")
+	r.Skipped("photos")
+	r.Conflicted("docs")
+	r.Synced("docs", clock.now)
+
+	snap := r.Snapshot()
+	assert.Equal(t, int64(2), snap.Global.FilesUploaded)
+	assert.Equal(t, int64(400), snap.Global.BytesUploaded)
+	assert.Equal(t, int64(50), snap.Global.BytesDownloaded)
+	assert.Equal(t, int64(2), snap.Global.Errors)
+	assert.Equal(t, clock.now, snap.Global.LastSync.UTC())
+
+	docs := snap.Folders["docs"]
+	assert.Equal(t, int64(1), docs.FilesUploaded)
+	assert.Equal(t, int64(1), docs.FilesDownloaded)
+	assert.Equal(t, int64(0), docs.Errors)
+	assert.Equal(t, int64(1), docs.FilesDeleted)
+	assert.Equal(t, int64(1), docs.Conflicts)
+	assert.Equal(t, int64(1), snap.Folders["photos"].FilesSkipped)
+	assert.Equal(t, int64(1), snap.Global.FilesSkipped)
+	assert.Equal(t, int64(1), snap.Folders["photos"].Errors)
+	assert.Equal(t, docs.FilesDeleted, r.Folder("docs").FilesDeleted)
+	assert.Zero(t, r.Folder("music").FilesUploaded)
+	assert.True(t, snap.Folders["photos"].LastSync.IsZero())
+
+	// Removing a folder keeps its transfers in the global totals
+	r.RemoveFolder("photos")
+	snap = r.Snapshot()
+	assert.NotContains(t, snap.Folders, "photos")
+	assert.Equal(t, int64(400), snap.Global.BytesUploaded)
+}
+
+func TestRegistryCountsBytesToday(t *testing.T) {
+	r, clock := newTestRegistry()
+	clock.now = time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+
+	r.Uploaded("docs", 100)
+	r.Downloaded("docs", 50)
+	snap := r.Snapshot()
+	assert.Equal(t, int64(150), snap.Global.BytesToday)
+	assert.Equal(t, int64(150), snap.Folders["docs"].BytesToday)
+
+	// Yesterday's transfers are not reported once the day changes
+	clock.now = clock.now.Add(24 * time.Hour)
+	snap = r.Snapshot()
+	assert.Equal(t, int64(0), snap.Global.BytesToday)
+	assert.Equal(t, int64(150), snap.Global.BytesUploaded+snap.Global.BytesDownloaded)
+
+	r.Uploaded("docs", 30)
+	snap = r.Snapshot()
+	assert.Equal(t, int64(30), snap.Folders["docs"].BytesToday)
+}
+
+func TestRegistryRates(t *testing.T) {
+	r, clock := newTestRegistry()
+
+	// 600 bytes over the first minute, sampled every 10 seconds
+	for i := 0; i < 6; i++ {
+		clock.now = clock.now.Add(10 * time.Second)
+		r.Uploaded("docs", 100)
+		r.Snapshot()
+	}
+	snap := r.Snapshot()
+	assert.InDelta(t, 10.0, snap.Global.Rate(time.Minute).Upload, 0.001)
+	assert.InDelta(t, 10.0, snap.Folders["docs"].Rate(time.Minute).Upload, 0.001)
+	assert.Len(t, snap.Global.Rates, len(Windows))
+	assert.True(t, snap.Global.Active())
+
+	// A quiet minute empties the short window while the longer ones still average it
+	clock.now = clock.now.Add(time.Minute)
+	snap = r.Snapshot()
+	assert.Equal(t, 0.0, snap.Global.Rate(time.Minute).Upload)
+	assert.InDelta(t, 5.0, snap.Global.Rate(5*time.Minute).Upload, 0.001)
+	assert.False(t, snap.Global.Active())
+
+	// Old samples are dropped once no window needs them
+	clock.now = clock.now.Add(time.Hour)
+	r.Snapshot()
+	assert.LessOrEqual(t, len(r.samples), 3)
+}
+
+func TestRegistryConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				r.Uploaded("docs", 1)
+				r.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(8000), r.Snapshot().Folders["docs"].BytesUploaded)
+}
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+
+	snap, err := Read(path)
+	assert.NoError(t, err)
+	assert.Nil(t, snap)
+
+	r, _ := newTestRegistry()
+	r.Uploaded("docs", 42)
+	assert.NoError(t, Write(path, r.Snapshot()))
+
+	snap, err = Read(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), snap.Folders["docs"].BytesUploaded)
+	assert.Equal(t, time.Minute, snap.Global.Rates[0].Window)
+}
@@ -0,0 +1,39 @@
+package stats
+
+import "time"
+
+// TopFiles is how many of the largest files a FolderSummary lists
+const TopFiles = 10
+
+// FolderSummary are the aggregates of the files of a folder, kept by its index as syncs
+// record changes, so they are known without scanning the folder
+type FolderSummary struct {
+	Files      int64      `json:"files"`
+	Bytes      int64      `json:"bytes"`
+	Largest    []FileSize `json:"largest,omitempty"`     // Largest first, at most TopFiles
+	LastChange time.Time  `json:"last_change,omitempty"` // When a file was last added, changed or removed
+
+	// Archived counts the files replaced on disk by placeholders, and ArchivedBytes the
+	// space they took, freed by archiving them
+	Archived      int64 `json:"archived,omitempty"`
+	ArchivedBytes int64 `json:"archived_bytes,omitempty"`
+}
+
+// FileSize is a file of a folder, by its path relative to the folder root, and its size
+type FileSize struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// Copy returns the summary with its own list of largest files
+func (s FolderSummary) Copy() FolderSummary {
+	s.Largest = append([]FileSize(nil), s.Largest...)
+	return s
+}
+
+// SetSummary records the file aggregates of a folder, published with the next snapshot
+func (r *Registry) SetSummary(folderID string, summary FolderSummary) {
+	r.foldersMu.Lock()
+	defer r.foldersMu.Unlock()
+	r.summaries[folderID] = summary.Copy()
+}
@@ -0,0 +1,116 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// States of a folder in the agent
+const (
+	Idle     = "idle"
+	Scanning = "scanning"
+	Syncing  = "syncing"
+	Paused   = "paused"
+	Error    = "error"
+	Disabled = "disabled"
+)
+
+// Folder is the live state of a synced folder
+type Folder struct {
+	State      string     `json:"state"`
+	LastSync   time.Time  `json:"last_sync,omitempty"`
+	NextSync   time.Time  `json:"next_sync,omitempty"` // When the folder syncs on its own next, zero when it does not
+	Pending    int        `json:"pending"`             // Files waiting to be transferred
+	LastError  string     `json:"last_error,omitempty"`
+	BytesToday int64      `json:"bytes_today"`          // Uploaded and downloaded since local midnight
+	Collisions [][]string `json:"collisions,omitempty"` // Remote files not downloaded for differing only in case
+}
+
+// Kinds of sync operation
+const (
+	FullSync   = "full"     // Every enabled, unpaused folder
+	FolderSync = "folder"   // One folder, on request
+	Periodic   = "periodic" // The folders whose interval elapsed
+	PathSync   = "path"     // A subtree of one folder, on request
+)
+
+// Operation is the sync the agent is running. Only one runs at a time: requests
+// covered by it wait for it to end, and others start once it does.
+type Operation struct {
+	Kind      string    `json:"kind"`
+	Folders   []string  `json:"folders"`
+	Path      string    `json:"path,omitempty"`    // Subtree synced by a path sync, relative to its folder
+	Current   string    `json:"current,omitempty"` // Folder being synced
+	Done      int       `json:"done"`              // Folders finished, out of len(Folders)
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Request is the outcome of a sync requested through the CLI
+type Request struct {
+	RequestedAt time.Time `json:"requested_at"` // As recorded with the request, the latest of those merged into it
+	FinishedAt  time.Time `json:"finished_at"`
+	Error       string    `json:"error,omitempty"` // Why the sync of a requested folder could not run
+}
+
+// Snapshot is the state of every folder of the agent at a point in time
+type Snapshot struct {
+	UpdatedAt time.Time         `json:"updated_at"`
+	Folders   map[string]Folder `json:"folders"`
+	Operation *Operation        `json:"operation,omitempty"` // Nil while no sync runs
+	Endpoints map[string]string `json:"endpoints,omitempty"` // Endpoint in use by target, for targets with failover endpoints
+	Request   *Request          `json:"request,omitempty"`   // The last requested sync that ended, nil before any did
+}
+
+// DefaultPath returns the default location of the file where the agent publishes its folder states
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "status.json"), nil
+}
+
+// Write stores the snapshot at path
+func Write(path string, snap Snapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create status directory: %w", err)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write status: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace status: %w", err)
+	}
+
+	return nil
+}
+
+// Read loads the snapshot at path, returning nil if the agent never wrote one
+func Read(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read status: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse status: %w", err)
+	}
+
+	return &snap, nil
+}
@@ -0,0 +1,34 @@
+package status
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.json")
+
+	snap, err := Read(path)
+	assert.NoError(t, err)
+	assert.Nil(t, snap)
+
+	lastSync := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, Write(path, Snapshot{
+		UpdatedAt: lastSync,
+		Folders: map[string]Folder{
+			"docs":   {State: Syncing, LastSync: lastSync, Pending: 3, BytesToday: 42},
+			"photos": {State: Error, LastError: "permission denied"},
+		},
+	}))
+
+	snap, err = Read(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Syncing, snap.Folders["docs"].State)
+	assert.Equal(t, 3, snap.Folders["docs"].Pending)
+	assert.Equal(t, int64(42), snap.Folders["docs"].BytesToday)
+	assert.True(t, lastSync.Equal(snap.Folders["docs"].LastSync))
+	assert.Equal(t, "permission denied", snap.Folders["photos"].LastError)
+}
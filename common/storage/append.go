@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrAppendUnsupported is returned by an Appender that cannot extend a particular file,
+// which is then uploaded whole
+var ErrAppendUnsupported = errors.New("storage cannot append to this file")
+
+// minComposePart is the smallest object S3-compatible backends accept as a copied part
+// that is not the last one of a multipart upload
+const minComposePart = 5 << 20
+
+// Appender is implemented by backends that can extend a file server-side, so a file
+// that only grew uploads the bytes added since its last upload instead of all of it
+type Appender interface {
+	// AppendFile adds length bytes read from reader to the first offset bytes of the
+	// current version of a file, replacing its metadata, and returns the new version ID.
+	// It fails with ErrAppendUnsupported when the file cannot be extended that way.
+	AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error)
+}
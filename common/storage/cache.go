@@ -0,0 +1,330 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithCache answers repeated existence checks, file info requests and listings from memory for ttl.
+// Writes through the cache invalidate the entries they affect; changes made by
+// other devices show up once the entries expire.
+func WithCache(ttl time.Duration, maxEntries int) Middleware {
+	return func(next Storage) Storage {
+		return &cachingStorage{
+			next:       next,
+			ttl:        ttl,
+			maxEntries: maxEntries,
+			exists:     make(map[string]cachedExists),
+			infos:      make(map[string]cachedInfo),
+			lists:      make(map[string]cachedList),
+		}
+	}
+}
+
+// cachedExists is a cached FileExists result
+type cachedExists struct {
+	exists  bool
+	expires time.Time
+}
+
+// cachedInfo is a cached GetFileInfo result
+type cachedInfo struct {
+	info     FileInfo
+	metadata map[string]string
+	expires  time.Time
+}
+
+// cachedList is a cached ListFiles result
+type cachedList struct {
+	files   []FileInfo
+	expires time.Time
+}
+
+// cachingStorage caches the metadata requests of the wrapped storage
+type cachingStorage struct {
+	next       Storage
+	ttl        time.Duration
+	maxEntries int
+	exists     map[string]cachedExists
+	infos      map[string]cachedInfo
+	lists      map[string]cachedList
+	mu         sync.Mutex
+}
+
+// Unwrap returns the wrapped storage
+func (c *cachingStorage) Unwrap() Storage {
+	return c.next
+}
+
+// GetProvider returns the provider of the wrapped storage
+func (c *cachingStorage) GetProvider() StorageProvider {
+	return c.next.GetProvider()
+}
+
+// UploadFile uploads a file and records that it exists
+func (c *cachingStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	versionID, err := c.next.UploadFile(ctx, key, reader, metadata)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked(key)
+	if err == nil {
+		c.storeExistsLocked(key, true)
+	}
+
+	return versionID, err
+}
+
+// DownloadFile downloads a file
+func (c *cachingStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	return c.next.DownloadFile(ctx, key, writer, versionID)
+}
+
+// DeleteFile deletes a file and records that it is gone
+func (c *cachingStorage) DeleteFile(ctx context.Context, key string) error {
+	err := c.next.DeleteFile(ctx, key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked(key)
+	if err == nil {
+		c.storeExistsLocked(key, false)
+	}
+
+	return err
+}
+
+// ListFiles lists files under a prefix, from the cache when possible
+func (c *cachingStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	c.mu.Lock()
+	if entry, ok := c.lists[prefix]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return append([]FileInfo(nil), entry.files...), nil
+	}
+	c.mu.Unlock()
+
+	files, err := c.next.ListFiles(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.makeRoomLocked()
+	c.lists[prefix] = cachedList{files: append([]FileInfo(nil), files...), expires: time.Now().Add(c.ttl)}
+
+	return files, nil
+}
+
+// FileExists checks if a file exists, from the cache when possible
+func (c *cachingStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	if entry, ok := c.exists[key]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.exists, nil
+	}
+	c.mu.Unlock()
+
+	exists, err := c.next.FileExists(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storeExistsLocked(key, exists)
+
+	return exists, nil
+}
+
+// GetFileInfo returns the information and metadata of a file, from the cache when possible
+func (c *cachingStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	c.mu.Lock()
+	if entry, ok := c.infos[key]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.info, copyMetadata(entry.metadata), nil
+	}
+	c.mu.Unlock()
+
+	info, metadata, err := c.next.GetFileInfo(ctx, key)
+	if err != nil {
+		return info, metadata, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.makeRoomLocked()
+	c.infos[key] = cachedInfo{info: info, metadata: copyMetadata(metadata), expires: time.Now().Add(c.ttl)}
+
+	return info, metadata, nil
+}
+
+// AppendFile appends to a file and drops the entries it makes stale
+func (c *cachingStorage) AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error) {
+	appender, ok := c.next.(Appender)
+	if !ok {
+		return "", ErrAppendUnsupported
+	}
+	versionID, err := appender.AppendFile(ctx, key, offset, reader, length, metadata)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked(key)
+	if err == nil {
+		c.storeExistsLocked(key, true)
+	}
+
+	return versionID, err
+}
+
+// DownloadRange downloads part of a file
+func (c *cachingStorage) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	ranged, ok := c.next.(RangeDownloader)
+	if !ok {
+		return ErrRangeUnsupported
+	}
+	return ranged.DownloadRange(ctx, key, offset, length, writer)
+}
+
+// CreateMultipart starts a multipart upload
+func (c *cachingStorage) CreateMultipart(ctx context.Context, key string, metadata map[string]string) (string, error) {
+	multipart, ok := c.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	return multipart.CreateMultipart(ctx, key, metadata)
+}
+
+// UploadPart uploads a part of a multipart upload
+func (c *cachingStorage) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+	multipart, ok := c.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	return multipart.UploadPart(ctx, key, uploadID, number, reader, size)
+}
+
+// CompleteMultipart completes a multipart upload and drops the entries it makes stale
+func (c *cachingStorage) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) (string, error) {
+	multipart, ok := c.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	versionID, err := multipart.CompleteMultipart(ctx, key, uploadID, parts)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked(key)
+	if err == nil {
+		c.storeExistsLocked(key, true)
+	}
+
+	return versionID, err
+}
+
+// AbortMultipart aborts a multipart upload
+func (c *cachingStorage) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	multipart, ok := c.next.(MultipartUploader)
+	if !ok {
+		return ErrMultipartUnsupported
+	}
+	return multipart.AbortMultipart(ctx, key, uploadID)
+}
+
+// ListMultipart lists the multipart uploads in progress
+func (c *cachingStorage) ListMultipart(ctx context.Context, prefix string) ([]MultipartUpload, error) {
+	multipart, ok := c.next.(MultipartUploader)
+	if !ok {
+		return nil, ErrMultipartUnsupported
+	}
+	return multipart.ListMultipart(ctx, prefix)
+}
+
+// ApplyLifecycle adds a lifecycle rule to the bucket
+func (c *cachingStorage) ApplyLifecycle(ctx context.Context, rule LifecycleRule) error {
+	manager, ok := c.next.(LifecycleManager)
+	if !ok {
+		return ErrLifecycleUnsupported
+	}
+	return manager.ApplyLifecycle(ctx, rule)
+}
+
+// storeExistsLocked caches an existence result
+func (c *cachingStorage) storeExistsLocked(key string, exists bool) {
+	c.makeRoomLocked()
+	c.exists[key] = cachedExists{exists: exists, expires: time.Now().Add(c.ttl)}
+}
+
+// invalidateLocked drops the entries a write to key makes stale
+func (c *cachingStorage) invalidateLocked(key string) {
+	delete(c.exists, key)
+	delete(c.infos, key)
+	for prefix := range c.lists {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.lists, prefix)
+		}
+	}
+}
+
+// makeRoomLocked drops expired entries, then arbitrary ones, until a new entry fits
+func (c *cachingStorage) makeRoomLocked() {
+	if c.maxEntries <= 0 || c.entriesLocked() < c.maxEntries {
+		return
+	}
+
+	now := time.Now()
+	for key, entry := range c.exists {
+		if !now.Before(entry.expires) {
+			delete(c.exists, key)
+		}
+	}
+	for key, entry := range c.infos {
+		if !now.Before(entry.expires) {
+			delete(c.infos, key)
+		}
+	}
+	for prefix, entry := range c.lists {
+		if !now.Before(entry.expires) {
+			delete(c.lists, prefix)
+		}
+	}
+
+	for key := range c.exists {
+		if c.entriesLocked() < c.maxEntries {
+			return
+		}
+		delete(c.exists, key)
+	}
+	for key := range c.infos {
+		if c.entriesLocked() < c.maxEntries {
+			return
+		}
+		delete(c.infos, key)
+	}
+	for prefix := range c.lists {
+		if c.entriesLocked() < c.maxEntries {
+			return
+		}
+		delete(c.lists, prefix)
+	}
+}
+
+// entriesLocked returns the number of cached entries
+func (c *cachingStorage) entriesLocked() int {
+	return len(c.exists) + len(c.infos) + len(c.lists)
+}
+
+// copyMetadata copies a metadata map so cached entries cannot be changed by callers
+func copyMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
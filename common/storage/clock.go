@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/transport"
+)
+
+// ErrNoServerClock is returned for targets without an HTTP server to read the time of, such
+// as local folders, whose modification times come from the clock of this machine
+var ErrNoServerClock = errors.New("storage has no server clock")
+
+// clockTimeout bounds the request reading the time of a storage server
+const clockTimeout = 10 * time.Second
+
+// ClockSkew returns how far the clock of this machine is ahead of the clock of the server of
+// a target, negative when it is behind. The server's time is read from the Date header of a
+// request to its endpoint, which any answer carries, even one refusing the request.
+func ClockSkew(ctx context.Context, target *common_config.StorageTarget) (time.Duration, error) {
+	url, transportConfig := ServerURL(target)
+	if url == "" {
+		return 0, ErrNoServerClock
+	}
+	client, err := transport.NewClient(transportConfig, clockTimeout)
+	if err != nil {
+		return 0, err
+	}
+	return measureSkew(ctx, client, url)
+}
+
+// measureSkew reads the Date header of url and compares it with the local clock
+func measureSkew(ctx context.Context, client *http.Client, url string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach storage server: %w", err)
+	}
+	resp.Body.Close()
+	received := time.Now()
+
+	header := resp.Header.Get("Date")
+	if header == "" {
+		return 0, fmt.Errorf("storage server %s sent no date", url)
+	}
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, fmt.Errorf("invalid date from storage server: %w", err)
+	}
+
+	// The server read its clock during the round trip, taken as its middle, and truncated it
+	// to the second
+	local := sent.Add(received.Sub(sent) / 2)
+	return local.Sub(date.Add(time.Second / 2)), nil
+}
+
+// ServerURL returns the URL of the HTTP server of a target with the transport reaching it,
+// an empty URL when the target has none
+func ServerURL(target *common_config.StorageTarget) (string, common_config.TransportConfig) {
+	switch StorageProvider(target.Type) {
+	case ProviderS3:
+		if target.S3.Endpoint == "" {
+			region := target.S3.Region
+			if region == "" {
+				region = "us-east-1"
+			}
+			return fmt.Sprintf("https://s3.%s.amazonaws.com", region), target.S3.TransportConfig
+		}
+		return endpointURL(target.S3.Endpoint, target.S3.UseSSL), target.S3.TransportConfig
+	case ProviderMinio:
+		return endpointURL(target.Minio.Endpoint, target.Minio.UseSSL), target.Minio.TransportConfig
+	case ProviderGCS:
+		return "https://storage.googleapis.com", target.GCS.TransportConfig
+	default:
+		return "", common_config.TransportConfig{}
+	}
+}
+
+// endpointURL turns a host:port endpoint into a URL, leaving one that already is alone
+func endpointURL(endpoint string, useSSL bool) string {
+	if endpoint == "" || strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+	if useSSL {
+		return "https://" + endpoint
+	}
+	return "http://" + endpoint
+}
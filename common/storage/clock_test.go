@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasureSkew(t *testing.T) {
+	// The server's clock is an hour behind; requests it refuses still carry its date
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	skew, err := measureSkew(context.Background(), server.Client(), server.URL)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), skew.Seconds(), 1)
+
+	// Without a date there is nothing to compare with
+	silent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+	}))
+	defer silent.Close()
+	_, err = measureSkew(context.Background(), silent.Client(), silent.URL)
+	assert.Error(t, err)
+}
+
+func TestClockSkewTargets(t *testing.T) {
+	url, _ := ServerURL(&common_config.StorageTarget{Type: "s3", S3: common_config.S3Config{Region: "eu-west-1"}})
+	assert.Equal(t, "https://s3.eu-west-1.amazonaws.com", url)
+	url, _ = ServerURL(&common_config.StorageTarget{Type: "minio", Minio: common_config.MinioConfig{Endpoint: "nas:9000"}})
+	assert.Equal(t, "http://nas:9000", url)
+	url, _ = ServerURL(&common_config.StorageTarget{Type: "s3", S3: common_config.S3Config{Endpoint: "s3.example.com", UseSSL: true}})
+	assert.Equal(t, "https://s3.example.com", url)
+
+	_, err := ClockSkew(context.Background(), &common_config.StorageTarget{Type: "local"})
+	assert.ErrorIs(t, err, ErrNoServerClock)
+}
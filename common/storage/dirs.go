@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"os"
+	"path"
+	"strconv"
+)
+
+// DirMarker is the name of the empty object standing for a directory, so empty
+// directories and directory metadata survive in stores that only hold files
+const DirMarker = ".sync-manager-dir"
+
+// MetadataDirMode is the metadata key holding the permission bits of a directory, in octal
+const MetadataDirMode = "dir_mode"
+
+// MetadataDirModTime is the metadata key holding the modification time of a directory, in RFC 3339.
+// Providers overwrite modified_time with the upload time, so directories keep their own.
+const MetadataDirModTime = "dir_mod_time"
+
+// DirMarkerKey returns the key of the marker for a slash-separated directory key
+func DirMarkerKey(dir string) string {
+	return path.Join(dir, DirMarker)
+}
+
+// MarkerDir returns the directory key a marker key stands for, if key is a marker
+func MarkerDir(key string) (string, bool) {
+	if path.Base(key) != DirMarker {
+		return "", false
+	}
+	dir := path.Dir(key)
+	if dir == "." {
+		dir = ""
+	}
+	return dir, true
+}
+
+// DirMode returns the directory permissions stored in marker metadata, or 0755 if none were
+func DirMode(metadata map[string]string) os.FileMode {
+	mode, err := strconv.ParseUint(metadata[MetadataDirMode], 8, 32)
+	if err != nil || mode == 0 {
+		return 0755
+	}
+	return os.FileMode(mode).Perm()
+}
@@ -0,0 +1,480 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// FailoverCheckInterval is how often the endpoints of a FailoverStorage are checked while
+	// one of them is down, so requests go back to the primary once it recovers
+	FailoverCheckInterval = 30 * time.Second
+	// failoverProbeTimeout bounds the check of a single endpoint
+	failoverProbeTimeout = 10 * time.Second
+	// failoverProbeKey is looked up to check an endpoint; it does not need to exist
+	failoverProbeKey = ".sync-manager/probe"
+)
+
+// EndpointOpener creates the storage reaching a bucket through one of its endpoints
+type EndpointOpener func(address string) (Storage, error)
+
+// EndpointState is the health of an endpoint of a FailoverStorage
+type EndpointState struct {
+	Address string
+	Healthy bool
+	Active  bool // Requests go to this endpoint
+}
+
+// FailoverStorage reaches one bucket through several endpoints, such as the gateways of an
+// on-premises MinIO or S3-compatible cluster. Requests go to the first healthy endpoint in the
+// order given, the primary first. A request that fails there for a reason other than the file
+// missing marks the endpoint down and is tried on the next one, unless it already consumed
+// its upload reader or wrote part of a download. Endpoints that are down are checked every
+// FailoverCheckInterval, and requests go back to the primary once it answers again.
+type FailoverStorage struct {
+	target    string
+	provider  StorageProvider
+	open      EndpointOpener
+	endpoints []*failoverEndpoint
+	active    int
+	failovers uint64 // Times requests moved to another endpoint
+	checked   time.Time
+	checking  bool
+	mu        sync.Mutex
+}
+
+// failoverEndpoint is an endpoint of a FailoverStorage
+type failoverEndpoint struct {
+	address string
+	store   Storage // Nil until the endpoint could be opened
+	healthy bool
+}
+
+// NewFailoverStorage opens the endpoints of a target through open. Endpoints that cannot be
+// opened yet are retried by the health checks; it fails only when none of them can.
+func NewFailoverStorage(target string, provider StorageProvider, addresses []string, open EndpointOpener) (*FailoverStorage, error) {
+	f := &FailoverStorage{target: target, provider: provider, open: open, active: -1, checked: time.Now()}
+	var errs []error
+	for i, address := range addresses {
+		endpoint := &failoverEndpoint{address: address}
+		f.endpoints = append(f.endpoints, endpoint)
+
+		store, err := open(address)
+		if err != nil {
+			log.Warn().Err(err).Str("target", target).Str("endpoint", address).Msg("Storage endpoint unavailable")
+			errs = append(errs, fmt.Errorf("%s: %w", address, err))
+			continue
+		}
+		endpoint.store, endpoint.healthy = store, true
+		if f.active < 0 {
+			f.active = i
+		}
+	}
+	if f.active < 0 {
+		return nil, fmt.Errorf("no endpoint of storage target %s is reachable: %w", target, errors.Join(errs...))
+	}
+	if f.active > 0 {
+		log.Warn().Str("target", target).Str("endpoint", addresses[f.active]).Msg("Primary storage endpoint unavailable, using a failover endpoint")
+	}
+	return f, nil
+}
+
+// GetProvider returns the provider of the endpoints
+func (f *FailoverStorage) GetProvider() StorageProvider {
+	return f.provider
+}
+
+// Target returns the name of the storage target the endpoints serve
+func (f *FailoverStorage) Target() string {
+	return f.target
+}
+
+// Endpoint returns the address of the endpoint requests go to
+func (f *FailoverStorage) Endpoint() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.endpoints[f.active].address
+}
+
+// Endpoints returns the state of every endpoint, in order
+func (f *FailoverStorage) Endpoints() []EndpointState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	states := make([]EndpointState, len(f.endpoints))
+	for i, endpoint := range f.endpoints {
+		states[i] = EndpointState{Address: endpoint.address, Healthy: endpoint.healthy, Active: i == f.active}
+	}
+	return states
+}
+
+// Failovers returns how many times requests moved to another endpoint
+func (f *FailoverStorage) Failovers() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.failovers
+}
+
+// Check checks every endpoint, opening those that could not be opened yet, and sends
+// requests to the first healthy one
+func (f *FailoverStorage) Check(ctx context.Context) {
+	f.mu.Lock()
+	endpoints := append([]*failoverEndpoint{}, f.endpoints...)
+	f.mu.Unlock()
+
+	healthy := make([]bool, len(endpoints))
+	stores := make([]Storage, len(endpoints))
+	for i, endpoint := range endpoints {
+		f.mu.Lock()
+		store := endpoint.store
+		f.mu.Unlock()
+		if store == nil {
+			var err error
+			if store, err = f.open(endpoint.address); err != nil {
+				log.Debug().Err(err).Str("target", f.target).Str("endpoint", endpoint.address).Msg("Storage endpoint still unavailable")
+				continue
+			}
+		}
+		stores[i] = store
+
+		probeCtx, cancel := context.WithTimeout(ctx, failoverProbeTimeout)
+		_, err := store.FileExists(probeCtx, failoverProbeKey)
+		cancel()
+		healthy[i] = err == nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checked, f.checking = time.Now(), false
+	for i, endpoint := range endpoints {
+		if endpoint.store == nil {
+			endpoint.store = stores[i]
+		}
+	}
+	// A check cut short by shutdown says nothing about the endpoints
+	if ctx.Err() != nil {
+		return
+	}
+	for i, endpoint := range endpoints {
+		if healthy[i] != endpoint.healthy {
+			log.Info().Str("target", f.target).Str("endpoint", endpoint.address).Bool("healthy", healthy[i]).Msg("Storage endpoint health changed")
+		}
+		endpoint.healthy = healthy[i]
+	}
+	for i, endpoint := range endpoints {
+		if endpoint.healthy {
+			f.switchLocked(i)
+			break
+		}
+	}
+}
+
+// switchLocked sends requests to the endpoint at index i. mu must be held.
+func (f *FailoverStorage) switchLocked(i int) {
+	if i == f.active {
+		return
+	}
+	log.Warn().Str("target", f.target).Str("from", f.endpoints[f.active].address).Str("to", f.endpoints[i].address).Msg("Switching storage endpoint")
+	f.active = i
+	f.failovers++
+}
+
+// maybeCheck starts a health check in the background when an endpoint is down and the last
+// check is older than FailoverCheckInterval
+func (f *FailoverStorage) maybeCheck() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.checking || time.Since(f.checked) < FailoverCheckInterval {
+		return
+	}
+	down := false
+	for _, endpoint := range f.endpoints {
+		down = down || !endpoint.healthy
+	}
+	if !down {
+		return
+	}
+	f.checking = true
+	go f.Check(context.Background())
+}
+
+// failoverAttempt is an endpoint to try a request on, with its storage
+type failoverAttempt struct {
+	endpoint *failoverEndpoint
+	store    Storage
+}
+
+// order returns the opened endpoints to try a request on: the active one, then the others
+// in order, healthy ones first
+func (f *FailoverStorage) order() []failoverAttempt {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	active := f.endpoints[f.active]
+	order := []failoverAttempt{{active, active.store}}
+	for _, healthy := range []bool{true, false} {
+		for i, endpoint := range f.endpoints {
+			if i != f.active && endpoint.store != nil && endpoint.healthy == healthy {
+				order = append(order, failoverAttempt{endpoint, endpoint.store})
+			}
+		}
+	}
+	return order
+}
+
+// markDown records that a request failed on an endpoint, moving requests to the next healthy one
+func (f *FailoverStorage) markDown(down *failoverEndpoint, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if down.healthy {
+		log.Warn().Err(err).Str("target", f.target).Str("endpoint", down.address).Msg("Storage endpoint failed")
+	}
+	down.healthy = false
+	if f.endpoints[f.active] != down {
+		return
+	}
+	for i, endpoint := range f.endpoints {
+		if endpoint.healthy && endpoint.store != nil {
+			f.switchLocked(i)
+			return
+		}
+	}
+}
+
+// do runs request on the endpoints in order until one answers. retry tells whether the
+// request can run again after a failure.
+func (f *FailoverStorage) do(ctx context.Context, retry func() bool, request func(Storage) error) error {
+	f.maybeCheck()
+	var first error
+	for i, attempt := range f.order() {
+		if i > 0 && !retry() {
+			break
+		}
+		err := request(attempt.store)
+		if err == nil || ctx.Err() != nil || !failsOver(err) {
+			return err
+		}
+		f.markDown(attempt.endpoint, err)
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// failsOver reports whether a failed request may succeed on another endpoint. Missing files
+// and operations the backend does not support fail the same everywhere.
+func failsOver(err error) bool {
+	for _, same := range []error{ErrNotFound, ErrAppendUnsupported, ErrRangeUnsupported, ErrMultipartUnsupported, ErrLifecycleUnsupported} {
+		if errors.Is(err, same) {
+			return false
+		}
+	}
+	return true
+}
+
+// always lets a request without a body run again
+func always() bool {
+	return true
+}
+
+// rewind returns a retry check for a request reading reader: it can run again when reader
+// seeks back to where it started
+func rewind(reader io.Reader) func() bool {
+	seeker, ok := reader.(io.Seeker)
+	if !ok {
+		return func() bool { return false }
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	return func() bool {
+		if err != nil {
+			return false
+		}
+		_, seekErr := seeker.Seek(start, io.SeekStart)
+		return seekErr == nil
+	}
+}
+
+// UploadFile uploads a file through the active endpoint
+func (f *FailoverStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	var versionID string
+	err := f.do(ctx, rewind(reader), func(s Storage) (err error) {
+		versionID, err = s.UploadFile(ctx, key, reader, metadata)
+		return err
+	})
+	return versionID, err
+}
+
+// DownloadFile downloads a file through the active endpoint, moving on to another one only
+// while nothing was written
+func (f *FailoverStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	counter := &countingWriter{w: writer}
+	var metadata map[string]string
+	err := f.do(ctx, func() bool { return counter.n == 0 }, func(s Storage) (err error) {
+		metadata, err = s.DownloadFile(ctx, key, counter, versionID)
+		return err
+	})
+	return metadata, err
+}
+
+// DeleteFile deletes a file through the active endpoint
+func (f *FailoverStorage) DeleteFile(ctx context.Context, key string) error {
+	return f.do(ctx, always, func(s Storage) error {
+		return s.DeleteFile(ctx, key)
+	})
+}
+
+// ListFiles lists the files under a prefix through the active endpoint
+func (f *FailoverStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := f.do(ctx, always, func(s Storage) (err error) {
+		files, err = s.ListFiles(ctx, prefix)
+		return err
+	})
+	return files, err
+}
+
+// FileExists checks if a file exists through the active endpoint
+func (f *FailoverStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	err := f.do(ctx, always, func(s Storage) (err error) {
+		exists, err = s.FileExists(ctx, key)
+		return err
+	})
+	return exists, err
+}
+
+// GetFileInfo returns the information and metadata of a file through the active endpoint
+func (f *FailoverStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	var info FileInfo
+	var metadata map[string]string
+	err := f.do(ctx, always, func(s Storage) (err error) {
+		info, metadata, err = s.GetFileInfo(ctx, key)
+		return err
+	})
+	return info, metadata, err
+}
+
+// AppendFile appends to a file through the active endpoint
+func (f *FailoverStorage) AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error) {
+	var versionID string
+	err := f.do(ctx, rewind(reader), func(s Storage) (err error) {
+		appender, ok := s.(Appender)
+		if !ok {
+			return ErrAppendUnsupported
+		}
+		versionID, err = appender.AppendFile(ctx, key, offset, reader, length, metadata)
+		return err
+	})
+	return versionID, err
+}
+
+// DownloadRange downloads part of a file through the active endpoint
+func (f *FailoverStorage) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	counter := &countingWriter{w: writer}
+	return f.do(ctx, func() bool { return counter.n == 0 }, func(s Storage) error {
+		ranged, ok := s.(RangeDownloader)
+		if !ok {
+			return ErrRangeUnsupported
+		}
+		return ranged.DownloadRange(ctx, key, offset, length, counter)
+	})
+}
+
+// multipart runs a multipart request through the active endpoint. The endpoints serve the
+// same bucket, so an upload started through one continues through another.
+func (f *FailoverStorage) multipart(ctx context.Context, retry func() bool, request func(MultipartUploader) error) error {
+	return f.do(ctx, retry, func(s Storage) error {
+		multipart, ok := s.(MultipartUploader)
+		if !ok {
+			return ErrMultipartUnsupported
+		}
+		return request(multipart)
+	})
+}
+
+// CreateMultipart starts a multipart upload through the active endpoint
+func (f *FailoverStorage) CreateMultipart(ctx context.Context, key string, metadata map[string]string) (string, error) {
+	var uploadID string
+	err := f.multipart(ctx, always, func(m MultipartUploader) (err error) {
+		uploadID, err = m.CreateMultipart(ctx, key, metadata)
+		return err
+	})
+	return uploadID, err
+}
+
+// UploadPart uploads a part of a multipart upload through the active endpoint
+func (f *FailoverStorage) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+	var etag string
+	err := f.multipart(ctx, rewind(reader), func(m MultipartUploader) (err error) {
+		etag, err = m.UploadPart(ctx, key, uploadID, number, reader, size)
+		return err
+	})
+	return etag, err
+}
+
+// CompleteMultipart completes a multipart upload through the active endpoint
+func (f *FailoverStorage) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) (string, error) {
+	var versionID string
+	err := f.multipart(ctx, always, func(m MultipartUploader) (err error) {
+		versionID, err = m.CompleteMultipart(ctx, key, uploadID, parts)
+		return err
+	})
+	return versionID, err
+}
+
+// AbortMultipart aborts a multipart upload through the active endpoint
+func (f *FailoverStorage) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	return f.multipart(ctx, always, func(m MultipartUploader) error {
+		return m.AbortMultipart(ctx, key, uploadID)
+	})
+}
+
+// ListMultipart lists the multipart uploads in progress under prefix through the active endpoint
+func (f *FailoverStorage) ListMultipart(ctx context.Context, prefix string) ([]MultipartUpload, error) {
+	var uploads []MultipartUpload
+	err := f.multipart(ctx, always, func(m MultipartUploader) (err error) {
+		uploads, err = m.ListMultipart(ctx, prefix)
+		return err
+	})
+	return uploads, err
+}
+
+// ApplyLifecycle adds a lifecycle rule to the bucket through the active endpoint
+func (f *FailoverStorage) ApplyLifecycle(ctx context.Context, rule LifecycleRule) error {
+	return f.do(ctx, always, func(s Storage) error {
+		manager, ok := s.(LifecycleManager)
+		if !ok {
+			return ErrLifecycleUnsupported
+		}
+		return manager.ApplyLifecycle(ctx, rule)
+	})
+}
+
+// ActiveEndpoints returns the endpoint each storage target reached through several endpoints
+// uses, by target name
+func ActiveEndpoints(s Storage) map[string]string {
+	endpoints := make(map[string]string)
+	var walk func(s Storage)
+	walk = func(s Storage) {
+		for s != nil {
+			switch store := s.(type) {
+			case *Router:
+				for _, target := range store.targets {
+					walk(target)
+				}
+				return
+			case *FailoverStorage:
+				endpoints[store.target] = store.Endpoint()
+				return
+			}
+			s = Unwrap(s)
+		}
+	}
+	walk(s)
+	return endpoints
+}
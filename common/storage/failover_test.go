@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failoverCluster opens memory storage sharing one bucket for each endpoint address, and
+// fails the requests of the endpoints marked down
+type failoverCluster struct {
+	name     string
+	down     map[string]bool
+	unopened map[string]bool
+	mu       sync.Mutex
+}
+
+func newFailoverCluster(t *testing.T) *failoverCluster {
+	return &failoverCluster{name: t.Name(), down: make(map[string]bool), unopened: make(map[string]bool)}
+}
+
+func (c *failoverCluster) set(states map[string]bool, address string, value bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	states[address] = value
+}
+
+func (c *failoverCluster) open(address string) (Storage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.unopened[address] {
+		return nil, errors.New("no such host")
+	}
+	return NewMemoryStorage(&MemoryConfig{Name: c.name, Fault: func(op, key string) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.down[address] {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}), nil
+}
+
+func TestFailoverStorage(t *testing.T) {
+	ctx := context.Background()
+	cluster := newFailoverCluster(t)
+	cluster.set(cluster.unopened, "minio-3:9000", true)
+
+	store, err := NewFailoverStorage("backup", ProviderMinio, []string{"minio-1:9000", "minio-2:9000", "minio-3:9000"}, cluster.open)
+	assert.NoError(t, err)
+	assert.Equal(t, "minio-1:9000", store.Endpoint())
+	assert.Equal(t, []EndpointState{
+		{Address: "minio-1:9000", Healthy: true, Active: true},
+		{Address: "minio-2:9000", Healthy: true},
+		{Address: "minio-3:9000"},
+	}, store.Endpoints())
+
+	// Missing files are missing on every endpoint and do not fail over
+	_, err = store.DownloadFile(ctx, "docs/a.txt", io.Discard, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, "minio-1:9000", store.Endpoint())
+
+	// A request failing on the primary is retried on the secondary, which keeps the requests
+	cluster.set(cluster.down, "minio-1:9000", true)
+	_, err = store.UploadFile(ctx, "docs/a.txt", bytes.NewReader([]byte("hello")), map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "minio-2:9000", store.Endpoint())
+	assert.Equal(t, uint64(1), store.Failovers())
+	var buf bytes.Buffer
+	_, err = store.DownloadFile(ctx, "docs/a.txt", &buf, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", buf.String())
+
+	// An upload whose reader cannot be rewound is not sent twice
+	cluster.set(cluster.down, "minio-1:9000", false)
+	cluster.set(cluster.down, "minio-2:9000", true)
+	_, err = store.UploadFile(ctx, "docs/b.txt", io.MultiReader(strings.NewReader("world")), map[string]string{})
+	assert.ErrorContains(t, err, "connection refused")
+	exists, err := store.FileExists(ctx, "docs/b.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// Checks open the endpoints that were unreachable and go back to the primary
+	cluster.set(cluster.down, "minio-2:9000", false)
+	cluster.set(cluster.unopened, "minio-3:9000", false)
+	store.Check(ctx)
+	assert.Equal(t, []EndpointState{
+		{Address: "minio-1:9000", Healthy: true, Active: true},
+		{Address: "minio-2:9000", Healthy: true},
+		{Address: "minio-3:9000", Healthy: true},
+	}, store.Endpoints())
+
+	// Storage with no endpoint reachable cannot be opened
+	cluster.set(cluster.unopened, "minio-1:9000", true)
+	_, err = NewFailoverStorage("backup", ProviderMinio, []string{"minio-1:9000"}, cluster.open)
+	assert.ErrorContains(t, err, "no endpoint of storage target backup is reachable")
+}
+
+func TestFailoverEndpointsReported(t *testing.T) {
+	ctx := context.Background()
+	cluster := newFailoverCluster(t)
+	backup, err := NewFailoverStorage("backup", ProviderMinio, []string{"minio-1:9000", "minio-2:9000"}, cluster.open)
+	assert.NoError(t, err)
+	cluster.set(cluster.down, "minio-1:9000", true)
+	_, err = backup.ListFiles(ctx, "docs/")
+	assert.NoError(t, err)
+
+	// The endpoint in use is found behind middlewares and routers
+	router := NewRouter(map[string]Storage{
+		"default": NewMemoryStorage(&MemoryConfig{}),
+		"backup":  Chain(backup, WithRetry(RetryPolicy{})),
+	}, "default", map[string]string{"docs": "backup"})
+	assert.Equal(t, map[string]string{"backup": "minio-2:9000"}, ActiveEndpoints(router))
+	assert.Empty(t, ActiveEndpoints(NewMemoryStorage(&MemoryConfig{})))
+
+	metrics := NewMetrics()
+	metrics.addFailover(backup)
+	var out bytes.Buffer
+	assert.NoError(t, metrics.WritePrometheus(&out))
+	assert.Contains(t, out.String(), `sync_manager_storage_endpoint_up{target="backup",endpoint="minio-1:9000"} 0`)
+	assert.Contains(t, out.String(), `sync_manager_storage_endpoint_active{target="backup",endpoint="minio-2:9000"} 1`)
+	assert.Contains(t, out.String(), `sync_manager_storage_failovers_total{target="backup"} 1`)
+}
@@ -0,0 +1,374 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/transport"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// GCSConfig holds configuration for GCS
+type GCSConfig struct {
+	ProjectID       string
+	Bucket          string
+	CredentialsFile string
+	Transport       common_config.TransportConfig
+}
+
+// NewGCSConfigFromCommon converts a common.GCSConfig to storage.GCSConfig
+func NewGCSConfigFromCommon(commonCfg *common_config.GCSConfig) *GCSConfig {
+	return &GCSConfig{
+		ProjectID:       commonCfg.ProjectID,
+		Bucket:          commonCfg.Bucket,
+		CredentialsFile: commonCfg.CredentialsFile,
+		Transport:       commonCfg.TransportConfig,
+	}
+}
+
+// GCSStorage implements the Storage interface using Google Cloud Storage
+type GCSStorage struct {
+	client *storage.Client
+	bucket string
+	config *GCSConfig
+}
+
+// GetProvider returns the storage provider type
+func (g *GCSStorage) GetProvider() StorageProvider {
+	return ProviderGCS
+}
+
+// NewGCSStorage creates a new GCS storage client
+func NewGCSStorage(cfg *GCSConfig) (*GCSStorage, error) {
+	ctx := context.Background()
+
+	var opts []option.ClientOption
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+	}
+
+	if !transport.IsDefault(cfg.Transport) {
+		httpTransport, err := transport.New(cfg.Transport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure GCS transport: %w", err)
+		}
+		// A custom HTTP client replaces the authenticated one, so authenticate on top of the base transport
+		authenticated, err := htransport.NewTransport(ctx, httpTransport, append(opts, option.WithScopes(storage.ScopeFullControl))...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCS transport: %w", err)
+		}
+		opts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: authenticated})}
+	}
+
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	bucket := client.Bucket(cfg.Bucket)
+	_, err = bucket.Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access bucket: %w", err)
+	}
+
+	return &GCSStorage{
+		client: client,
+		bucket: cfg.Bucket,
+		config: cfg,
+	}, nil
+}
+
+// UploadFile uploads a file to GCS
+func (g *GCSStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+
+	bucket := g.client.Bucket(g.bucket)
+	obj := bucket.Object(key)
+	w := obj.NewWriter(ctx)
+
+	w.StorageClass, w.Metadata = splitStorageClass(metadata)
+
+	if _, err := io.Copy(w, reader); err != nil {
+		w.Close()
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize upload: %w", err)
+	}
+
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get object attributes: %w", err)
+	}
+
+	log.Debug().
+		Str("bucket", g.bucket).
+		Str("key", key).
+		Int64("generation", attrs.Generation).
+		Msg("Uploaded file to GCS")
+
+	return fmt.Sprintf("%d", attrs.Generation), nil
+}
+
+// AppendFile implements Appender by uploading the new bytes to a temporary object and
+// composing it after the current object. The current object must hold exactly offset bytes.
+func (g *GCSStorage) AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+
+	bucket := g.client.Bucket(g.bucket)
+	obj := bucket.Object(key)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get object attributes: %w", err)
+	}
+	if attrs.Size != offset {
+		// Compose takes whole objects, so a remote copy of another size cannot be extended
+		return "", ErrAppendUnsupported
+	}
+
+	tail := bucket.Object(fmt.Sprintf("%s.append-%d", key, time.Now().UnixNano()))
+	w := tail.NewWriter(ctx)
+	if _, err := io.Copy(w, reader); err != nil {
+		w.Close()
+		return "", fmt.Errorf("failed to upload appended content: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize appended content: %w", err)
+	}
+	defer func() {
+		if err := tail.Delete(context.Background()); err != nil {
+			log.Warn().Err(err).Str("key", tail.ObjectName()).Msg("Failed to remove temporary GCS object")
+		}
+	}()
+
+	// Only compose onto the generation that was checked, in case the object changed meanwhile
+	composer := obj.If(storage.Conditions{GenerationMatch: attrs.Generation}).ComposerFrom(obj.Generation(attrs.Generation), tail)
+	composer.StorageClass, composer.Metadata = splitStorageClass(metadata)
+	composed, err := composer.Run(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to compose appended file: %w", err)
+	}
+
+	log.Debug().
+		Str("bucket", g.bucket).
+		Str("key", key).
+		Int64("offset", offset).
+		Int64("appended", length).
+		Int64("generation", composed.Generation).
+		Msg("Appended to file in GCS")
+
+	return fmt.Sprintf("%d", composed.Generation), nil
+}
+
+// DownloadFile downloads a file from GCS
+func (g *GCSStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	key = strings.TrimPrefix(key, "/")
+
+	bucket := g.client.Bucket(g.bucket)
+	obj := bucket.Object(key)
+
+	if versionID != "" {
+		var generation int64
+		fmt.Sscanf(versionID, "%d", &generation)
+		if generation > 0 {
+			obj = obj.Generation(generation)
+		}
+	}
+
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object attributes: %w", err)
+	}
+
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer r.Close()
+
+	if _, err := io.Copy(writer, r); err != nil {
+		return nil, fmt.Errorf("failed to copy file content: %w", err)
+	}
+
+	log.Debug().
+		Str("bucket", g.bucket).
+		Str("key", key).
+		Int64("generation", attrs.Generation).
+		Msg("Downloaded file from GCS")
+
+	return attrs.Metadata, nil
+}
+
+// DownloadRange implements RangeDownloader
+func (g *GCSStorage) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	key = strings.TrimPrefix(key, "/")
+
+	r, err := g.client.Bucket(g.bucket).Object(key).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		return fmt.Errorf("failed to download file range: %w", err)
+	}
+	defer r.Close()
+
+	if _, err := io.Copy(writer, r); err != nil {
+		return fmt.Errorf("failed to copy file content: %w", err)
+	}
+	return nil
+}
+
+// DeleteFile deletes a file from GCS
+func (g *GCSStorage) DeleteFile(ctx context.Context, key string) error {
+	key = strings.TrimPrefix(key, "/")
+
+	bucket := g.client.Bucket(g.bucket)
+	obj := bucket.Object(key)
+
+	if err := obj.Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	log.Debug().
+		Str("bucket", g.bucket).
+		Str("key", key).
+		Msg("Deleted file from GCS")
+
+	return nil
+}
+
+// ListFiles lists files in GCS with the given prefix
+func (g *GCSStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	prefix = strings.TrimPrefix(prefix, "/")
+
+	bucket := g.client.Bucket(g.bucket)
+
+	var files []FileInfo
+	it := bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error listing objects: %w", err)
+		}
+
+		files = append(files, FileInfo{
+			Key:          attrs.Name,
+			Size:         attrs.Size,
+			LastModified: attrs.Updated,
+			ETag:         fmt.Sprintf("%d", attrs.Generation),
+		})
+	}
+
+	log.Debug().
+		Str("bucket", g.bucket).
+		Str("prefix", prefix).
+		Int("count", len(files)).
+		Msg("Listed files from GCS")
+
+	return files, nil
+}
+
+// GetFileInfo returns the information and metadata of a file in GCS
+func (g *GCSStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	key = strings.TrimPrefix(key, "/")
+
+	attrs, err := g.client.Bucket(g.bucket).Object(key).Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return FileInfo{}, nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return FileInfo{}, nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	// Composite objects only have a CRC32C
+	info := FileInfo{
+		Key:          attrs.Name,
+		Size:         attrs.Size,
+		LastModified: attrs.Updated,
+		ETag:         fmt.Sprintf("%d", attrs.Generation),
+		MD5:          hex.EncodeToString(attrs.MD5),
+		CRC32C:       fmt.Sprintf("%08x", attrs.CRC32C),
+	}
+	return info, attrs.Metadata, nil
+}
+
+// FileExists checks if a file exists in GCS
+func (g *GCSStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	key = strings.TrimPrefix(key, "/")
+
+	bucket := g.client.Bucket(g.bucket)
+	obj := bucket.Object(key)
+
+	_, err := obj.Attrs(ctx)
+	if err == storage.ErrObjectNotExist {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to check if file exists: %w", err)
+	}
+
+	return true, nil
+}
+
+// ApplyLifecycle adds rules for old object generations to the bucket's lifecycle.
+// GCS rules have no IDs, so earlier rules for the same prefix are replaced instead.
+func (g *GCSStorage) ApplyLifecycle(ctx context.Context, rule LifecycleRule) error {
+	if err := rule.Validate(ProviderGCS); err != nil {
+		return err
+	}
+
+	bucket := g.client.Bucket(g.bucket)
+	attrs, err := bucket.Attrs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get bucket attributes: %w", err)
+	}
+
+	prefix := strings.TrimPrefix(rule.Prefix, "/")
+	var rules []storage.LifecycleRule
+	for _, existing := range attrs.Lifecycle.Rules {
+		cond := existing.Condition
+		if cond.Liveness == storage.Archived && len(cond.MatchesPrefix) == 1 && cond.MatchesPrefix[0] == prefix {
+			continue
+		}
+		rules = append(rules, existing)
+	}
+
+	if rule.TransitionDays > 0 {
+		rules = append(rules, storage.LifecycleRule{
+			Action: storage.LifecycleAction{Type: storage.SetStorageClassAction, StorageClass: rule.TransitionClass},
+			Condition: storage.LifecycleCondition{
+				Liveness:                storage.Archived,
+				DaysSinceNoncurrentTime: int64(rule.TransitionDays),
+				MatchesPrefix:           []string{prefix},
+			},
+		})
+	}
+	if rule.ExpireDays > 0 {
+		rules = append(rules, storage.LifecycleRule{
+			Action: storage.LifecycleAction{Type: storage.DeleteAction},
+			Condition: storage.LifecycleCondition{
+				Liveness:                storage.Archived,
+				DaysSinceNoncurrentTime: int64(rule.ExpireDays),
+				MatchesPrefix:           []string{prefix},
+			},
+		})
+	}
+
+	if _, err := bucket.Update(ctx, storage.BucketAttrsToUpdate{Lifecycle: &storage.Lifecycle{Rules: rules}}); err != nil {
+		return fmt.Errorf("failed to update bucket lifecycle: %w", err)
+	}
+
+	log.Debug().Str("bucket", g.bucket).Str("prefix", prefix).Msg("Applied GCS lifecycle rules")
+	return nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrLifecycleUnsupported is returned by a LifecycleManager whose backend has no lifecycle policies
+var ErrLifecycleUnsupported = errors.New("storage does not support lifecycle policies")
+
+// MetadataStorageClass is the metadata key selecting the storage class of an upload.
+// Providers apply it to the object instead of storing it as user metadata.
+const MetadataStorageClass = "storage_class"
+
+// storageClasses lists the classes each provider accepts for uploads
+var storageClasses = map[StorageProvider][]string{
+	ProviderS3:    {"STANDARD", "STANDARD_IA", "ONEZONE_IA", "INTELLIGENT_TIERING", "GLACIER_IR", "GLACIER", "DEEP_ARCHIVE", "REDUCED_REDUNDANCY"},
+	ProviderMinio: {"STANDARD", "REDUCED_REDUNDANCY"},
+	ProviderGCS:   {"STANDARD", "NEARLINE", "COLDLINE", "ARCHIVE"},
+}
+
+// transitionClasses lists the classes lifecycle rules can move old versions to
+var transitionClasses = map[StorageProvider][]string{
+	ProviderS3:  {"STANDARD_IA", "ONEZONE_IA", "INTELLIGENT_TIERING", "GLACIER_IR", "GLACIER", "DEEP_ARCHIVE"},
+	ProviderGCS: {"NEARLINE", "COLDLINE", "ARCHIVE"},
+}
+
+// StorageClasses returns the storage classes a provider accepts, or nil if it has none
+func StorageClasses(provider StorageProvider) []string {
+	return storageClasses[provider]
+}
+
+// ValidateStorageClass checks that a provider supports a storage class. An empty class
+// means the bucket default and is always valid.
+func ValidateStorageClass(provider StorageProvider, class string) error {
+	if class == "" {
+		return nil
+	}
+	return checkClass(storageClasses[provider], provider, class)
+}
+
+// RequiresRestore reports whether objects in a class must be restored before they can be read
+func RequiresRestore(class string) bool {
+	return class == "GLACIER" || class == "DEEP_ARCHIVE"
+}
+
+// LifecycleRule archives and expires the old versions of the objects under a prefix.
+// Zero days leave that step out.
+type LifecycleRule struct {
+	ID              string // Identifies the rule so applying it again replaces it
+	Prefix          string
+	TransitionDays  int    // Days after a version is replaced before it moves to TransitionClass
+	TransitionClass string // Storage class old versions move to
+	ExpireDays      int    // Days after a version is replaced before it is deleted
+}
+
+// Validate checks the rule against what a provider supports
+func (r LifecycleRule) Validate(provider StorageProvider) error {
+	if r.ID == "" {
+		return fmt.Errorf("lifecycle rule ID is required")
+	}
+	if r.TransitionDays < 0 || r.ExpireDays < 0 {
+		return fmt.Errorf("lifecycle days cannot be negative")
+	}
+	if r.TransitionDays == 0 && r.ExpireDays == 0 {
+		return fmt.Errorf("lifecycle rule needs a transition or an expiration")
+	}
+	if r.TransitionDays > 0 {
+		if r.TransitionClass == "" {
+			return fmt.Errorf("lifecycle transition needs a storage class")
+		}
+		// MinIO transitions to remote tiers named on the server, so any name is accepted there
+		if classes, ok := transitionClasses[provider]; ok {
+			if err := checkClass(classes, provider, r.TransitionClass); err != nil {
+				return err
+			}
+		}
+		if r.ExpireDays > 0 && r.ExpireDays <= r.TransitionDays {
+			return fmt.Errorf("old versions must expire after they are transitioned")
+		}
+	}
+	return nil
+}
+
+// LifecycleManager is implemented by storage whose buckets support lifecycle policies
+type LifecycleManager interface {
+	// ApplyLifecycle adds the rule to the bucket, replacing an earlier rule with the same ID.
+	// It fails with ErrLifecycleUnsupported when the backend has no lifecycle policies.
+	ApplyLifecycle(ctx context.Context, rule LifecycleRule) error
+}
+
+// splitStorageClass separates the storage class from the user metadata of an upload
+func splitStorageClass(metadata map[string]string) (string, map[string]string) {
+	user := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if k != MetadataStorageClass {
+			user[k] = v
+		}
+	}
+	return metadata[MetadataStorageClass], user
+}
+
+// checkClass reports an error unless class is one of classes
+func checkClass(classes []string, provider StorageProvider, class string) error {
+	if len(classes) == 0 {
+		return fmt.Errorf("%s storage does not support storage classes", provider)
+	}
+	for _, c := range classes {
+		if c == class {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported %s storage class %q (supported: %s)", provider, class, strings.Join(classes, ", "))
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStorageClass(t *testing.T) {
+	assert.NoError(t, ValidateStorageClass(ProviderS3, ""))
+	assert.NoError(t, ValidateStorageClass(ProviderS3, "GLACIER_IR"))
+	assert.NoError(t, ValidateStorageClass(ProviderGCS, "NEARLINE"))
+	assert.Error(t, ValidateStorageClass(ProviderGCS, "GLACIER_IR"))
+	assert.Error(t, ValidateStorageClass(ProviderLocal, "STANDARD"))
+
+	assert.True(t, RequiresRestore("DEEP_ARCHIVE"))
+	assert.False(t, RequiresRestore("GLACIER_IR"))
+}
+
+func TestSplitStorageClass(t *testing.T) {
+	metadata := map[string]string{"hash": "abc", MetadataStorageClass: "STANDARD_IA"}
+
+	class, user := splitStorageClass(metadata)
+	assert.Equal(t, "STANDARD_IA", class)
+	assert.Equal(t, map[string]string{"hash": "abc"}, user)
+
+	// The caller's map is left untouched
+	assert.Len(t, metadata, 2)
+}
+
+func TestLifecycleRuleValidate(t *testing.T) {
+	rule := LifecycleRule{ID: "docs", Prefix: "docs/", TransitionDays: 30, TransitionClass: "GLACIER", ExpireDays: 365}
+	assert.NoError(t, rule.Validate(ProviderS3))
+	assert.Error(t, rule.Validate(ProviderGCS))
+
+	// MinIO tiers are configured on the server
+	rule.TransitionClass = "COLD-TIER"
+	assert.NoError(t, rule.Validate(ProviderMinio))
+
+	assert.Error(t, LifecycleRule{ID: "docs"}.Validate(ProviderS3))
+	assert.Error(t, LifecycleRule{ID: "docs", TransitionDays: 30}.Validate(ProviderS3))
+	assert.Error(t, LifecycleRule{ID: "docs", TransitionDays: 30, TransitionClass: "GLACIER", ExpireDays: 10}.Validate(ProviderS3))
+	assert.NoError(t, LifecycleRule{ID: "docs", ExpireDays: 90}.Validate(ProviderGCS))
+}
@@ -0,0 +1,23 @@
+package storage
+
+// Largest objects the backends accept from a single upload
+const (
+	maxS3PutSize     = 5 << 30 // PutObject, used for every S3 upload
+	maxMultipartSize = 5 << 40 // MinIO splits uploads into parts
+	maxGCSObjectSize = 5 << 40
+)
+
+// MaxObjectSize returns the largest file the backend behind store accepts in one upload,
+// or zero when it sets no limit
+func MaxObjectSize(store Storage) int64 {
+	switch store.GetProvider() {
+	case ProviderS3:
+		return maxS3PutSize
+	case ProviderMinio:
+		return maxMultipartSize
+	case ProviderGCS:
+		return maxGCSObjectSize
+	default:
+		return 0
+	}
+}
@@ -18,13 +18,17 @@ import (
 
 // LocalConfig holds configuration for local file storage
 type LocalConfig struct {
-	RootDir string
+	RootDir    string
+	Removable  bool   // RootDir is on a drive that can be unplugged
+	VolumeUUID string // Volume the drive must be, when set
 }
 
 // NewLocalConfigFromCommon converts a common.LocalConfig to storage.LocalConfig
 func NewLocalConfigFromCommon(commonCfg *common_config.LocalConfig) *LocalConfig {
 	return &LocalConfig{
-		RootDir: commonCfg.RootDir,
+		RootDir:    commonCfg.RootDir,
+		Removable:  commonCfg.Removable || commonCfg.VolumeUUID != "",
+		VolumeUUID: commonCfg.VolumeUUID,
 	}
 }
 
@@ -39,9 +43,14 @@ func (l *LocalStorage) GetProvider() StorageProvider {
 	return ProviderLocal
 }
 
-// NewLocalStorage creates a new local storage client
+// NewLocalStorage creates a new local storage client. The root directory of a removable
+// drive is not created, as an unplugged drive leaves an empty mount point on the system
+// disk; the storage is created anyway and fails with ErrDriveAbsent until the drive is there.
 func NewLocalStorage(cfg *LocalConfig) (*LocalStorage, error) {
 	rootDir := filepath.Clean(cfg.RootDir)
+	if cfg.Removable {
+		return &LocalStorage{rootDir: rootDir, config: cfg}, nil
+	}
 	if err := os.MkdirAll(rootDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create root directory: %w", err)
 	}
@@ -57,8 +66,20 @@ func NewLocalStorage(cfg *LocalConfig) (*LocalStorage, error) {
 	}, nil
 }
 
+// Present implements Detachable: storage on a removable drive is present while its root
+// directory is on a mounted drive, and the volume is the configured one
+func (l *LocalStorage) Present() error {
+	if !l.config.Removable {
+		return nil
+	}
+	return checkDrive(l.rootDir, l.config.VolumeUUID)
+}
+
 // UploadFile uploads a file to local storage
 func (l *LocalStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	if err := l.Present(); err != nil {
+		return "", err
+	}
 	key = strings.TrimPrefix(key, "/")
 
 	filePath := filepath.Join(l.rootDir, key)
@@ -130,6 +151,9 @@ func (l *LocalStorage) UploadFile(ctx context.Context, key string, reader io.Rea
 
 // DownloadFile downloads a file from local storage
 func (l *LocalStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	if err := l.Present(); err != nil {
+		return nil, err
+	}
 	key = strings.TrimPrefix(key, "/")
 
 	filePath := filepath.Join(l.rootDir, key)
@@ -156,8 +180,30 @@ func (l *LocalStorage) DownloadFile(ctx context.Context, key string, writer io.W
 	return metadata, nil
 }
 
+// DownloadRange implements RangeDownloader
+func (l *LocalStorage) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	if err := l.Present(); err != nil {
+		return err
+	}
+	key = strings.TrimPrefix(key, "/")
+
+	file, err := os.Open(filepath.Join(l.rootDir, key))
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(writer, io.NewSectionReader(file, offset, length)); err != nil {
+		return fmt.Errorf("failed to copy file content: %w", err)
+	}
+	return nil
+}
+
 // DeleteFile deletes a file from local storage
 func (l *LocalStorage) DeleteFile(ctx context.Context, key string) error {
+	if err := l.Present(); err != nil {
+		return err
+	}
 	key = strings.TrimPrefix(key, "/")
 
 	filePath := filepath.Join(l.rootDir, key)
@@ -178,6 +224,9 @@ func (l *LocalStorage) DeleteFile(ctx context.Context, key string) error {
 
 // ListFiles lists files in local storage with the given prefix
 func (l *LocalStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	if err := l.Present(); err != nil {
+		return nil, err
+	}
 	prefix = strings.TrimPrefix(prefix, "/")
 
 	dirPath := filepath.Join(l.rootDir, prefix)
@@ -222,7 +271,8 @@ func (l *LocalStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo
 			return err
 		}
 
-		if info.IsDir() || strings.HasPrefix(filepath.Base(path), ".") {
+		// Hidden files are metadata and partial uploads, except for directory markers
+		if name := filepath.Base(path); info.IsDir() || (strings.HasPrefix(name, ".") && name != DirMarker) {
 			return nil
 		}
 
@@ -264,6 +314,9 @@ func (l *LocalStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo
 
 // FileExists checks if a file exists in local storage
 func (l *LocalStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	if err := l.Present(); err != nil {
+		return false, err
+	}
 	key = strings.TrimPrefix(key, "/")
 
 	filePath := filepath.Join(l.rootDir, key)
@@ -279,6 +332,42 @@ func (l *LocalStorage) FileExists(ctx context.Context, key string) (bool, error)
 	return true, nil
 }
 
+// GetFileInfo returns the information and metadata of a file in local storage
+func (l *LocalStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	if err := l.Present(); err != nil {
+		return FileInfo{}, nil, err
+	}
+	key = strings.TrimPrefix(key, "/")
+
+	info, err := os.Stat(filepath.Join(l.rootDir, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return FileInfo{}, nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return FileInfo{}, nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.IsDir() {
+		return FileInfo{}, nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	metadata, err := l.readMetadata(key)
+	if err != nil {
+		metadata = make(map[string]string)
+	}
+
+	hash := metadata["hash_sha256"]
+	if hash == "" {
+		hash = "unknown"
+	}
+
+	return FileInfo{
+		Key:          key,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		ETag:         hash,
+	}, metadata, nil
+}
+
 // getMetadataPath returns the path to the metadata file for a key
 func (l *LocalStorage) getMetadataPath(key string) string {
 	return filepath.Join(l.rootDir, ".sync-manager", key+".meta")
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// WithLogging logs every request with its duration and outcome at debug level
+func WithLogging() Middleware {
+	return func(next Storage) Storage {
+		return &loggingStorage{next: next}
+	}
+}
+
+// loggingStorage logs the requests of the wrapped storage
+type loggingStorage struct {
+	next Storage
+}
+
+// Unwrap returns the wrapped storage
+func (l *loggingStorage) Unwrap() Storage {
+	return l.next
+}
+
+// GetProvider returns the provider of the wrapped storage
+func (l *loggingStorage) GetProvider() StorageProvider {
+	return l.next.GetProvider()
+}
+
+// UploadFile uploads a file
+func (l *loggingStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	reader, counter := countReader(reader)
+	start := time.Now()
+	versionID, err := l.next.UploadFile(ctx, key, reader, metadata)
+	l.log("upload", key, start, err).Int64("bytes", counter.n).Str("version", versionID).Msg("Storage request")
+	return versionID, err
+}
+
+// DownloadFile downloads a file
+func (l *loggingStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	counter := &countingWriter{w: writer}
+	start := time.Now()
+	metadata, err := l.next.DownloadFile(ctx, key, counter, versionID)
+	l.log("download", key, start, err).Int64("bytes", counter.n).Msg("Storage request")
+	return metadata, err
+}
+
+// DeleteFile deletes a file
+func (l *loggingStorage) DeleteFile(ctx context.Context, key string) error {
+	start := time.Now()
+	err := l.next.DeleteFile(ctx, key)
+	l.log("delete", key, start, err).Msg("Storage request")
+	return err
+}
+
+// ListFiles lists files under a prefix
+func (l *loggingStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	start := time.Now()
+	files, err := l.next.ListFiles(ctx, prefix)
+	l.log("list", prefix, start, err).Int("files", len(files)).Msg("Storage request")
+	return files, err
+}
+
+// FileExists checks if a file exists
+func (l *loggingStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	exists, err := l.next.FileExists(ctx, key)
+	l.log("exists", key, start, err).Bool("exists", exists).Msg("Storage request")
+	return exists, err
+}
+
+// GetFileInfo returns the information and metadata of a file
+func (l *loggingStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	start := time.Now()
+	info, metadata, err := l.next.GetFileInfo(ctx, key)
+	l.log("stat", key, start, err).Int64("size", info.Size).Msg("Storage request")
+	return info, metadata, err
+}
+
+// AppendFile appends to a file
+func (l *loggingStorage) AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error) {
+	appender, ok := l.next.(Appender)
+	if !ok {
+		return "", ErrAppendUnsupported
+	}
+	reader, counter := countReader(reader)
+	start := time.Now()
+	versionID, err := appender.AppendFile(ctx, key, offset, reader, length, metadata)
+	l.log("append", key, start, err).Int64("offset", offset).Int64("bytes", counter.n).Str("version", versionID).Msg("Storage request")
+	return versionID, err
+}
+
+// DownloadRange downloads part of a file
+func (l *loggingStorage) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	ranged, ok := l.next.(RangeDownloader)
+	if !ok {
+		return ErrRangeUnsupported
+	}
+	counter := &countingWriter{w: writer}
+	start := time.Now()
+	err := ranged.DownloadRange(ctx, key, offset, length, counter)
+	l.log("download_range", key, start, err).Int64("offset", offset).Int64("bytes", counter.n).Msg("Storage request")
+	return err
+}
+
+// CreateMultipart starts a multipart upload
+func (l *loggingStorage) CreateMultipart(ctx context.Context, key string, metadata map[string]string) (string, error) {
+	multipart, ok := l.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	start := time.Now()
+	uploadID, err := multipart.CreateMultipart(ctx, key, metadata)
+	l.log("create_multipart", key, start, err).Str("upload", uploadID).Msg("Storage request")
+	return uploadID, err
+}
+
+// UploadPart uploads a part of a multipart upload
+func (l *loggingStorage) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+	multipart, ok := l.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	reader, counter := countReader(reader)
+	start := time.Now()
+	etag, err := multipart.UploadPart(ctx, key, uploadID, number, reader, size)
+	l.log("upload_part", key, start, err).Str("upload", uploadID).Int("part", number).Int64("bytes", counter.n).Msg("Storage request")
+	return etag, err
+}
+
+// CompleteMultipart completes a multipart upload
+func (l *loggingStorage) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) (string, error) {
+	multipart, ok := l.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	start := time.Now()
+	versionID, err := multipart.CompleteMultipart(ctx, key, uploadID, parts)
+	l.log("complete_multipart", key, start, err).Str("upload", uploadID).Int("parts", len(parts)).Str("version", versionID).Msg("Storage request")
+	return versionID, err
+}
+
+// AbortMultipart aborts a multipart upload
+func (l *loggingStorage) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	multipart, ok := l.next.(MultipartUploader)
+	if !ok {
+		return ErrMultipartUnsupported
+	}
+	start := time.Now()
+	err := multipart.AbortMultipart(ctx, key, uploadID)
+	l.log("abort_multipart", key, start, err).Str("upload", uploadID).Msg("Storage request")
+	return err
+}
+
+// ListMultipart lists the multipart uploads in progress
+func (l *loggingStorage) ListMultipart(ctx context.Context, prefix string) ([]MultipartUpload, error) {
+	multipart, ok := l.next.(MultipartUploader)
+	if !ok {
+		return nil, ErrMultipartUnsupported
+	}
+	start := time.Now()
+	uploads, err := multipart.ListMultipart(ctx, prefix)
+	l.log("list_multipart", prefix, start, err).Int("uploads", len(uploads)).Msg("Storage request")
+	return uploads, err
+}
+
+// ApplyLifecycle adds a lifecycle rule to the bucket
+func (l *loggingStorage) ApplyLifecycle(ctx context.Context, rule LifecycleRule) error {
+	manager, ok := l.next.(LifecycleManager)
+	if !ok {
+		return ErrLifecycleUnsupported
+	}
+	start := time.Now()
+	err := manager.ApplyLifecycle(ctx, rule)
+	l.log("lifecycle", rule.Prefix, start, err).Str("rule", rule.ID).Msg("Storage request")
+	return err
+}
+
+// log starts a debug event describing a finished request
+func (l *loggingStorage) log(op, key string, start time.Time, err error) *zerolog.Event {
+	return log.Debug().
+		Err(err).
+		Str("provider", string(l.next.GetProvider())).
+		Str("operation", op).
+		Str("key", key).
+		Dur("duration", time.Since(start))
+}
@@ -0,0 +1,503 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+)
+
+// MemoryConfig holds configuration for in-memory storage
+type MemoryConfig struct {
+	Name      string        // Storages opened with the same name share their contents; empty for a private store
+	Latency   time.Duration // Delay added to every request
+	ErrorRate float64       // Fraction of requests that fail, from 0 to 1
+
+	// Fault, when set, is called before every request and fails it with the returned error.
+	// The operation is one of "upload", "append", "download", "delete", "list", "exists", "stat",
+	// "create_multipart", "upload_part", "complete_multipart", "abort_multipart" and "list_multipart".
+	Fault func(op, key string) error
+}
+
+// NewMemoryConfigFromCommon converts a common.MemoryConfig to storage.MemoryConfig
+func NewMemoryConfigFromCommon(commonCfg *common_config.MemoryConfig) *MemoryConfig {
+	return &MemoryConfig{
+		Name:      commonCfg.Name,
+		Latency:   commonCfg.Latency,
+		ErrorRate: commonCfg.ErrorRate,
+	}
+}
+
+// memoryBuckets holds the named in-memory buckets of the process
+var (
+	memoryBuckets   = make(map[string]*memoryBucket)
+	memoryBucketsMu sync.Mutex
+)
+
+// memoryBucket is the versioned contents of an in-memory storage
+type memoryBucket struct {
+	objects     map[string][]memoryVersion // Versions of each key, oldest first
+	uploads     map[string]*memoryUpload   // Multipart uploads in progress, by ID
+	nextVersion int
+	nextUpload  int
+	mu          sync.Mutex
+}
+
+// memoryUpload is a multipart upload in progress
+type memoryUpload struct {
+	key       string
+	metadata  map[string]string
+	parts     map[int][]byte
+	initiated time.Time
+}
+
+// memoryVersion is one version of an object; a delete adds a version marking the key as removed
+type memoryVersion struct {
+	id       string
+	data     []byte
+	metadata map[string]string
+	modified time.Time
+	deleted  bool
+}
+
+// MemoryStorage implements the Storage interface in memory, keeping every version of
+// every object like a versioned bucket. It is meant for hermetic integration tests.
+type MemoryStorage struct {
+	bucket *memoryBucket
+	config *MemoryConfig
+	rand   *rand.Rand
+	randMu sync.Mutex
+}
+
+// NewMemoryStorage creates a new in-memory storage
+func NewMemoryStorage(cfg *MemoryConfig) *MemoryStorage {
+	var bucket *memoryBucket
+	if cfg.Name == "" {
+		bucket = &memoryBucket{objects: make(map[string][]memoryVersion)}
+	} else {
+		memoryBucketsMu.Lock()
+		bucket = memoryBuckets[cfg.Name]
+		if bucket == nil {
+			bucket = &memoryBucket{objects: make(map[string][]memoryVersion)}
+			memoryBuckets[cfg.Name] = bucket
+		}
+		memoryBucketsMu.Unlock()
+	}
+
+	return &MemoryStorage{
+		bucket: bucket,
+		config: cfg,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// GetProvider returns the storage provider type
+func (m *MemoryStorage) GetProvider() StorageProvider {
+	return ProviderMemory
+}
+
+// UploadFile stores a new version of a file
+func (m *MemoryStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "upload", key); err != nil {
+		return "", err
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file content: %w", err)
+	}
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.put(key, data, metadata), nil
+}
+
+// AppendFile implements Appender, storing the first offset bytes of the latest version
+// followed by the new bytes as a new version
+func (m *MemoryStorage) AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "append", key); err != nil {
+		return "", err
+	}
+
+	tail, err := io.ReadAll(io.LimitReader(reader, length))
+	if err != nil {
+		return "", fmt.Errorf("failed to read file content: %w", err)
+	}
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	versions := b.objects[key]
+	if len(versions) == 0 || versions[len(versions)-1].deleted || int64(len(versions[len(versions)-1].data)) < offset {
+		return "", ErrAppendUnsupported
+	}
+	data := append(append([]byte(nil), versions[len(versions)-1].data[:offset]...), tail...)
+
+	return b.put(key, data, metadata), nil
+}
+
+// CreateMultipart implements MultipartUploader
+func (m *MemoryStorage) CreateMultipart(ctx context.Context, key string, metadata map[string]string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "create_multipart", key); err != nil {
+		return "", err
+	}
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.uploads == nil {
+		b.uploads = make(map[string]*memoryUpload)
+	}
+	b.nextUpload++
+	id := "upload-" + strconv.Itoa(b.nextUpload)
+	b.uploads[id] = &memoryUpload{key: key, metadata: copyMetadata(metadata), parts: make(map[int][]byte), initiated: time.Now()}
+	return id, nil
+}
+
+// UploadPart implements MultipartUploader, the entity tag being the SHA-256 of the part
+func (m *MemoryStorage) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "upload_part", key); err != nil {
+		return "", err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, size))
+	if err != nil {
+		return "", fmt.Errorf("failed to read part content: %w", err)
+	}
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	upload, err := b.upload(key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	upload.parts[number] = data
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// CompleteMultipart implements MultipartUploader
+func (m *MemoryStorage) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "complete_multipart", key); err != nil {
+		return "", err
+	}
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	upload, err := b.upload(key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	var data []byte
+	for i, part := range parts {
+		content, ok := upload.parts[part.Number]
+		if !ok || part.Number != i+1 {
+			return "", fmt.Errorf("invalid part %d of upload %s", part.Number, uploadID)
+		}
+		if hash := sha256.Sum256(content); hex.EncodeToString(hash[:]) != part.ETag {
+			return "", fmt.Errorf("part %d of upload %s does not match its entity tag", part.Number, uploadID)
+		}
+		data = append(data, content...)
+	}
+	delete(b.uploads, uploadID)
+
+	return b.put(key, data, upload.metadata), nil
+}
+
+// AbortMultipart implements MultipartUploader
+func (m *MemoryStorage) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "abort_multipart", key); err != nil {
+		return err
+	}
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.upload(key, uploadID); err != nil {
+		return err
+	}
+	delete(b.uploads, uploadID)
+	return nil
+}
+
+// ListMultipart implements MultipartUploader
+func (m *MemoryStorage) ListMultipart(ctx context.Context, prefix string) ([]MultipartUpload, error) {
+	prefix = strings.TrimPrefix(prefix, "/")
+	if err := m.inject(ctx, "list_multipart", prefix); err != nil {
+		return nil, err
+	}
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var uploads []MultipartUpload
+	for id, upload := range b.uploads {
+		if strings.HasPrefix(upload.key, prefix) {
+			uploads = append(uploads, MultipartUpload{Key: upload.key, ID: id, Initiated: upload.initiated})
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		if !uploads[i].Initiated.Equal(uploads[j].Initiated) {
+			return uploads[i].Initiated.Before(uploads[j].Initiated)
+		}
+		return uploads[i].ID < uploads[j].ID
+	})
+	return uploads, nil
+}
+
+// upload returns the multipart upload of key with the given ID. The caller holds b.mu.
+func (b *memoryBucket) upload(key, uploadID string) (*memoryUpload, error) {
+	upload, ok := b.uploads[uploadID]
+	if !ok || upload.key != key {
+		return nil, fmt.Errorf("%w: upload %s of %s", ErrNotFound, uploadID, key)
+	}
+	return upload, nil
+}
+
+// put stores data as the latest version of key and returns its ID. The caller holds b.mu.
+func (b *memoryBucket) put(key string, data []byte, metadata map[string]string) string {
+	hash := sha256.Sum256(data)
+	stored := copyMetadata(metadata)
+	if stored == nil {
+		stored = make(map[string]string)
+	}
+	stored["hash_sha256"] = hex.EncodeToString(hash[:])
+	stored["size"] = strconv.Itoa(len(data))
+	stored["modified_time"] = time.Now().UTC().Format(time.RFC3339)
+
+	b.nextVersion++
+	version := memoryVersion{
+		id:       strconv.Itoa(b.nextVersion),
+		data:     data,
+		metadata: stored,
+		modified: time.Now(),
+	}
+	b.objects[key] = append(b.objects[key], version)
+
+	return version.id
+}
+
+// DownloadFile writes the latest version of a file, or the given version, to writer
+func (m *MemoryStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "download", key); err != nil {
+		return nil, err
+	}
+
+	version, ok := m.version(key, versionID)
+	if !ok {
+		if versionID != "" {
+			return nil, fmt.Errorf("%w: %s (version %s)", ErrNotFound, key, versionID)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	if _, err := io.Copy(writer, bytes.NewReader(version.data)); err != nil {
+		return nil, fmt.Errorf("failed to copy file content: %w", err)
+	}
+
+	return copyMetadata(version.metadata), nil
+}
+
+// DownloadRange implements RangeDownloader for the latest version of a file
+func (m *MemoryStorage) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "download", key); err != nil {
+		return err
+	}
+
+	version, ok := m.version(key, "")
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if offset < 0 || offset > int64(len(version.data)) {
+		return fmt.Errorf("invalid range %d-%d of %s", offset, offset+length-1, key)
+	}
+
+	end := min(offset+length, int64(len(version.data)))
+	if _, err := writer.Write(version.data[offset:end]); err != nil {
+		return fmt.Errorf("failed to copy file content: %w", err)
+	}
+	return nil
+}
+
+// DeleteFile marks a file as deleted, keeping its previous versions. Deleting a
+// missing file succeeds, as it does on S3.
+func (m *MemoryStorage) DeleteFile(ctx context.Context, key string) error {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "delete", key); err != nil {
+		return err
+	}
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	versions := b.objects[key]
+	if len(versions) == 0 || versions[len(versions)-1].deleted {
+		return nil
+	}
+
+	b.nextVersion++
+	b.objects[key] = append(versions, memoryVersion{
+		id:       strconv.Itoa(b.nextVersion),
+		modified: time.Now(),
+		deleted:  true,
+	})
+
+	return nil
+}
+
+// ListFiles lists the files whose key starts with prefix, sorted by key
+func (m *MemoryStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	prefix = strings.TrimPrefix(prefix, "/")
+	if err := m.inject(ctx, "list", prefix); err != nil {
+		return nil, err
+	}
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	files := []FileInfo{}
+	for key, versions := range b.objects {
+		latest := versions[len(versions)-1]
+		if strings.HasPrefix(key, prefix) && !latest.deleted {
+			files = append(files, latest.info(key))
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Key < files[j].Key })
+
+	return files, nil
+}
+
+// FileExists checks if a file exists
+func (m *MemoryStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "exists", key); err != nil {
+		return false, err
+	}
+
+	_, ok := m.version(key, "")
+	return ok, nil
+}
+
+// GetFileInfo returns the information and metadata of the latest version of a file
+func (m *MemoryStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if err := m.inject(ctx, "stat", key); err != nil {
+		return FileInfo{}, nil, err
+	}
+
+	version, ok := m.version(key, "")
+	if !ok {
+		return FileInfo{}, nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	return version.info(key), copyMetadata(version.metadata), nil
+}
+
+// Versions returns the IDs of the stored versions of a file, oldest first, skipping deletions
+func (m *MemoryStorage) Versions(key string) []string {
+	key = strings.TrimPrefix(key, "/")
+
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var ids []string
+	for _, version := range b.objects[key] {
+		if !version.deleted {
+			ids = append(ids, version.id)
+		}
+	}
+	return ids
+}
+
+// version returns the latest version of a key, or the version with the given ID
+func (m *MemoryStorage) version(key, versionID string) (memoryVersion, bool) {
+	b := m.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	versions := b.objects[key]
+	if len(versions) == 0 {
+		return memoryVersion{}, false
+	}
+	if versionID == "" {
+		latest := versions[len(versions)-1]
+		return latest, !latest.deleted
+	}
+	for _, version := range versions {
+		if version.id == versionID && !version.deleted {
+			return version, true
+		}
+	}
+	return memoryVersion{}, false
+}
+
+// inject applies the configured latency and fails the request when a fault is due
+func (m *MemoryStorage) inject(ctx context.Context, op, key string) error {
+	if m.config.Latency > 0 {
+		timer := time.NewTimer(m.config.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if m.config.Fault != nil {
+		if err := m.config.Fault(op, key); err != nil {
+			return err
+		}
+	}
+
+	if m.config.ErrorRate > 0 {
+		m.randMu.Lock()
+		fail := m.rand.Float64() < m.config.ErrorRate
+		m.randMu.Unlock()
+		if fail {
+			return fmt.Errorf("injected %s failure for %s", op, key)
+		}
+	}
+
+	return ctx.Err()
+}
+
+// info describes a stored version
+func (v memoryVersion) info(key string) FileInfo {
+	return FileInfo{
+		Key:          key,
+		Size:         int64(len(v.data)),
+		LastModified: v.modified,
+		ETag:         v.metadata["hash_sha256"],
+	}
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStorageVersions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage(&MemoryConfig{})
+
+	first, err := store.UploadFile(ctx, "docs/a.txt", strings.NewReader("one"), map[string]string{"device_id": "laptop"})
+	assert.NoError(t, err)
+	second, err := store.UploadFile(ctx, "docs/a.txt", strings.NewReader("two!"), nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, []string{first, second}, store.Versions("docs/a.txt"))
+
+	var buf bytes.Buffer
+	metadata, err := store.DownloadFile(ctx, "docs/a.txt", &buf, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "two!", buf.String())
+	assert.Equal(t, "4", metadata["size"])
+
+	buf.Reset()
+	metadata, err = store.DownloadFile(ctx, "docs/a.txt", &buf, first)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", buf.String())
+	assert.Equal(t, "laptop", metadata["device_id"])
+
+	info, metadata, err := store.GetFileInfo(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), info.Size)
+	assert.Equal(t, metadata["hash_sha256"], info.ETag)
+
+	_, err = store.UploadFile(ctx, "docs/sub/b.txt", strings.NewReader("b"), nil)
+	assert.NoError(t, err)
+	_, err = store.UploadFile(ctx, "other/c.txt", strings.NewReader("c"), nil)
+	assert.NoError(t, err)
+	files, err := store.ListFiles(ctx, "docs/")
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, "docs/a.txt", files[0].Key)
+	assert.Equal(t, "docs/sub/b.txt", files[1].Key)
+
+	// Deleting hides the file but keeps its old versions
+	assert.NoError(t, store.DeleteFile(ctx, "docs/a.txt"))
+	assert.NoError(t, store.DeleteFile(ctx, "docs/a.txt"))
+	exists, err := store.FileExists(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	_, _, err = store.GetFileInfo(ctx, "docs/a.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.DownloadFile(ctx, "docs/a.txt", &buf, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+	files, _ = store.ListFiles(ctx, "docs/")
+	assert.Len(t, files, 1)
+
+	buf.Reset()
+	_, err = store.DownloadFile(ctx, "docs/a.txt", &buf, second)
+	assert.NoError(t, err)
+	assert.Equal(t, "two!", buf.String())
+}
+
+func TestMemoryStorageAppend(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage(&MemoryConfig{})
+
+	_, err := store.AppendFile(ctx, "logs/app.log", 0, strings.NewReader("line 1\n"), 7, nil)
+	assert.ErrorIs(t, err, ErrAppendUnsupported)
+
+	_, err = store.UploadFile(ctx, "logs/app.log", strings.NewReader("line 1\n"), nil)
+	assert.NoError(t, err)
+	_, err = store.AppendFile(ctx, "logs/app.log", 7, strings.NewReader("line 2\n"), 7, map[string]string{"device_id": "laptop"})
+	assert.NoError(t, err)
+	assert.Len(t, store.Versions("logs/app.log"), 2)
+
+	var buf bytes.Buffer
+	metadata, err := store.DownloadFile(ctx, "logs/app.log", &buf, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2\n", buf.String())
+	assert.Equal(t, "14", metadata["size"])
+	assert.Equal(t, "laptop", metadata["device_id"])
+
+	// The new bytes replace whatever followed the offset
+	_, err = store.AppendFile(ctx, "logs/app.log", 7, strings.NewReader("line 3\n"), 7, nil)
+	assert.NoError(t, err)
+	buf.Reset()
+	_, err = store.DownloadFile(ctx, "logs/app.log", &buf, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\nline 3\n", buf.String())
+
+	_, err = store.AppendFile(ctx, "logs/app.log", 100, strings.NewReader("x"), 1, nil)
+	assert.ErrorIs(t, err, ErrAppendUnsupported)
+}
+
+func TestMemoryStorageFaults(t *testing.T) {
+	ctx := context.Background()
+
+	failUploads := errors.New("connection reset by peer")
+	store := NewMemoryStorage(&MemoryConfig{Fault: func(op, key string) error {
+		if op == "upload" && strings.HasSuffix(key, ".bad") {
+			return failUploads
+		}
+		return nil
+	}})
+	_, err := store.UploadFile(ctx, "docs/a.bad", strings.NewReader("x"), nil)
+	assert.ErrorIs(t, err, failUploads)
+	_, err = store.UploadFile(ctx, "docs/a.txt", strings.NewReader("x"), nil)
+	assert.NoError(t, err)
+
+	store = NewMemoryStorage(&MemoryConfig{ErrorRate: 1})
+	_, err = store.FileExists(ctx, "docs/a.txt")
+	assert.ErrorContains(t, err, "injected exists failure")
+	assert.True(t, Retryable(err))
+
+	// Latency honours cancellation
+	store = NewMemoryStorage(&MemoryConfig{Latency: time.Hour})
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = store.ListFiles(cancelled, "docs/")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestMemoryStorageFromConfig(t *testing.T) {
+	ctx := context.Background()
+	cfg := common_config.DefaultConfig()
+	cfg.Targets = []common_config.StorageTarget{{Name: "default", Type: "memory", Memory: common_config.MemoryConfig{Name: t.Name()}}}
+
+	// Storages opened with the same name share their contents
+	writer, err := StorageFactory(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderMemory, writer.GetProvider())
+	_, err = writer.UploadFile(ctx, "docs/a.txt", strings.NewReader("hello"), nil)
+	assert.NoError(t, err)
+
+	reader, err := StorageFactory(cfg)
+	assert.NoError(t, err)
+	exists, err := reader.FileExists(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	private := NewMemoryStorage(&MemoryConfig{})
+	exists, _ = private.FileExists(ctx, "docs/a.txt")
+	assert.False(t, exists)
+}
@@ -0,0 +1,423 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMetrics collects the metrics of storage built by StorageFactory
+var DefaultMetrics = NewMetrics()
+
+// durationBuckets are the upper bounds in seconds of the request duration histogram
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics counts storage requests and serves them in the Prometheus text format
+type Metrics struct {
+	requests  map[requestLabels]uint64
+	durations map[operationLabels]*histogram
+	bytes     map[operationLabels]uint64
+	failovers map[string]*FailoverStorage // By target
+	gauges    map[string]gaugeFunc        // By name
+	mu        sync.Mutex
+}
+
+// gaugeFunc reads the current value of a gauge added with AddGauge
+type gaugeFunc struct {
+	help  string
+	value func() int64
+}
+
+// requestLabels identify a request counter
+type requestLabels struct {
+	provider, operation, result string
+}
+
+// operationLabels identify a duration histogram or byte counter
+type operationLabels struct {
+	provider, operation string
+}
+
+// histogram is a cumulative Prometheus histogram
+type histogram struct {
+	counts []uint64 // One per bucket, plus +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewMetrics creates an empty metrics collector
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:  make(map[requestLabels]uint64),
+		durations: make(map[operationLabels]*histogram),
+		bytes:     make(map[operationLabels]uint64),
+		failovers: make(map[string]*FailoverStorage),
+		gauges:    make(map[string]gaugeFunc),
+	}
+}
+
+// AddGauge serves a gauge whose value is read from value at every scrape, in place of an
+// earlier gauge of the same name. It lets the agent report its heap and upload queue beside
+// the storage metrics.
+func (m *Metrics) AddGauge(name, help string, value func() int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] = gaugeFunc{help: help, value: value}
+}
+
+// addFailover reports the endpoints of f, in place of earlier storage of the same target
+func (m *Metrics) addFailover(f *FailoverStorage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failovers[f.Target()] = f
+}
+
+// WithMetrics records the count, duration and transferred bytes of every request in m
+func WithMetrics(m *Metrics) Middleware {
+	return func(next Storage) Storage {
+		return &metricsStorage{next: next, metrics: m}
+	}
+}
+
+// observe records a finished request
+func (m *Metrics) observe(provider StorageProvider, op string, start time.Time, bytes int64, err error) {
+	result := "ok"
+	if errors.Is(err, ErrNotFound) {
+		result = "not_found"
+	} else if err != nil {
+		result = "error"
+	}
+	seconds := time.Since(start).Seconds()
+	labels := operationLabels{string(provider), op}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestLabels{string(provider), op, result}]++
+
+	h := m.durations[labels]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(durationBuckets)+1)}
+		m.durations[labels] = h
+	}
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.counts[len(durationBuckets)]++
+	h.sum += seconds
+	h.count++
+
+	if bytes > 0 {
+		m.bytes[labels] += uint64(bytes)
+	}
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []string
+
+	out = append(out,
+		"# HELP sync_manager_storage_requests_total Storage requests by operation and result.",
+		"# TYPE sync_manager_storage_requests_total counter")
+	requests := make([]requestLabels, 0, len(m.requests))
+	for labels := range m.requests {
+		requests = append(requests, labels)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+		if a.provider != b.provider {
+			return a.provider < b.provider
+		}
+		if a.operation != b.operation {
+			return a.operation < b.operation
+		}
+		return a.result < b.result
+	})
+	for _, labels := range requests {
+		out = append(out, fmt.Sprintf("sync_manager_storage_requests_total{provider=%q,operation=%q,result=%q} %d",
+			labels.provider, labels.operation, labels.result, m.requests[labels]))
+	}
+
+	out = append(out,
+		"# HELP sync_manager_storage_request_duration_seconds Storage request duration.",
+		"# TYPE sync_manager_storage_request_duration_seconds histogram")
+	for _, labels := range sortedOperations(m.durations) {
+		h := m.durations[labels]
+		prefix := fmt.Sprintf("provider=%q,operation=%q", labels.provider, labels.operation)
+		for i, bound := range durationBuckets {
+			out = append(out, fmt.Sprintf("sync_manager_storage_request_duration_seconds_bucket{%s,le=%q} %d",
+				prefix, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i]))
+		}
+		out = append(out,
+			fmt.Sprintf("sync_manager_storage_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d", prefix, h.counts[len(durationBuckets)]),
+			fmt.Sprintf("sync_manager_storage_request_duration_seconds_sum{%s} %s", prefix, strconv.FormatFloat(h.sum, 'g', -1, 64)),
+			fmt.Sprintf("sync_manager_storage_request_duration_seconds_count{%s} %d", prefix, h.count))
+	}
+
+	out = append(out,
+		"# HELP sync_manager_storage_bytes_total Bytes uploaded and downloaded.",
+		"# TYPE sync_manager_storage_bytes_total counter")
+	for _, labels := range sortedOperations(m.bytes) {
+		out = append(out, fmt.Sprintf("sync_manager_storage_bytes_total{provider=%q,operation=%q} %d",
+			labels.provider, labels.operation, m.bytes[labels]))
+	}
+
+	if len(m.failovers) > 0 {
+		out = append(out, m.failoverLines()...)
+	}
+
+	names := make([]string, 0, len(m.gauges))
+	for name := range m.gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g := m.gauges[name]
+		out = append(out,
+			fmt.Sprintf("# HELP %s %s", name, g.help),
+			fmt.Sprintf("# TYPE %s gauge", name),
+			fmt.Sprintf("%s %d", name, g.value()))
+	}
+
+	for _, line := range out {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failoverLines returns the state of the endpoints of every failover storage
+func (m *Metrics) failoverLines() []string {
+	targets := make([]string, 0, len(m.failovers))
+	for target := range m.failovers {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	up := []string{
+		"# HELP sync_manager_storage_endpoint_up Whether a storage endpoint passed its last health check.",
+		"# TYPE sync_manager_storage_endpoint_up gauge"}
+	active := []string{
+		"# HELP sync_manager_storage_endpoint_active Whether requests go to a storage endpoint.",
+		"# TYPE sync_manager_storage_endpoint_active gauge"}
+	failovers := []string{
+		"# HELP sync_manager_storage_failovers_total Times requests moved to another storage endpoint.",
+		"# TYPE sync_manager_storage_failovers_total counter"}
+	for _, target := range targets {
+		f := m.failovers[target]
+		for _, endpoint := range f.Endpoints() {
+			up = append(up, fmt.Sprintf("sync_manager_storage_endpoint_up{target=%q,endpoint=%q} %d",
+				target, endpoint.Address, gauge(endpoint.Healthy)))
+			active = append(active, fmt.Sprintf("sync_manager_storage_endpoint_active{target=%q,endpoint=%q} %d",
+				target, endpoint.Address, gauge(endpoint.Active)))
+		}
+		failovers = append(failovers, fmt.Sprintf("sync_manager_storage_failovers_total{target=%q} %d", target, f.Failovers()))
+	}
+	return append(append(up, active...), failovers...)
+}
+
+// gauge returns 1 for true and 0 for false
+func gauge(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ServeHTTP serves the metrics to a Prometheus scraper
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+// sortedOperations returns the keys of a per-operation map in a stable order
+func sortedOperations[V any](values map[operationLabels]V) []operationLabels {
+	keys := make([]operationLabels, 0, len(values))
+	for labels := range values {
+		keys = append(keys, labels)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		return keys[i].operation < keys[j].operation
+	})
+	return keys
+}
+
+// metricsStorage records the requests of the wrapped storage
+type metricsStorage struct {
+	next    Storage
+	metrics *Metrics
+}
+
+// Unwrap returns the wrapped storage
+func (s *metricsStorage) Unwrap() Storage {
+	return s.next
+}
+
+// GetProvider returns the provider of the wrapped storage
+func (s *metricsStorage) GetProvider() StorageProvider {
+	return s.next.GetProvider()
+}
+
+// UploadFile uploads a file
+func (s *metricsStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	reader, counter := countReader(reader)
+	start := time.Now()
+	versionID, err := s.next.UploadFile(ctx, key, reader, metadata)
+	s.metrics.observe(s.next.GetProvider(), "upload", start, counter.n, err)
+	return versionID, err
+}
+
+// DownloadFile downloads a file
+func (s *metricsStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	counter := &countingWriter{w: writer}
+	start := time.Now()
+	metadata, err := s.next.DownloadFile(ctx, key, counter, versionID)
+	s.metrics.observe(s.next.GetProvider(), "download", start, counter.n, err)
+	return metadata, err
+}
+
+// DeleteFile deletes a file
+func (s *metricsStorage) DeleteFile(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.DeleteFile(ctx, key)
+	s.metrics.observe(s.next.GetProvider(), "delete", start, 0, err)
+	return err
+}
+
+// ListFiles lists files under a prefix
+func (s *metricsStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	start := time.Now()
+	files, err := s.next.ListFiles(ctx, prefix)
+	s.metrics.observe(s.next.GetProvider(), "list", start, 0, err)
+	return files, err
+}
+
+// FileExists checks if a file exists
+func (s *metricsStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	exists, err := s.next.FileExists(ctx, key)
+	s.metrics.observe(s.next.GetProvider(), "exists", start, 0, err)
+	return exists, err
+}
+
+// GetFileInfo returns the information and metadata of a file
+func (s *metricsStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	start := time.Now()
+	info, metadata, err := s.next.GetFileInfo(ctx, key)
+	s.metrics.observe(s.next.GetProvider(), "stat", start, 0, err)
+	return info, metadata, err
+}
+
+// AppendFile appends to a file
+func (s *metricsStorage) AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error) {
+	appender, ok := s.next.(Appender)
+	if !ok {
+		return "", ErrAppendUnsupported
+	}
+	reader, counter := countReader(reader)
+	start := time.Now()
+	versionID, err := appender.AppendFile(ctx, key, offset, reader, length, metadata)
+	s.metrics.observe(s.next.GetProvider(), "append", start, counter.n, err)
+	return versionID, err
+}
+
+// DownloadRange downloads part of a file
+func (s *metricsStorage) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	ranged, ok := s.next.(RangeDownloader)
+	if !ok {
+		return ErrRangeUnsupported
+	}
+	counter := &countingWriter{w: writer}
+	start := time.Now()
+	err := ranged.DownloadRange(ctx, key, offset, length, counter)
+	s.metrics.observe(s.next.GetProvider(), "download_range", start, counter.n, err)
+	return err
+}
+
+// CreateMultipart starts a multipart upload
+func (s *metricsStorage) CreateMultipart(ctx context.Context, key string, metadata map[string]string) (string, error) {
+	multipart, ok := s.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	start := time.Now()
+	uploadID, err := multipart.CreateMultipart(ctx, key, metadata)
+	s.metrics.observe(s.next.GetProvider(), "create_multipart", start, 0, err)
+	return uploadID, err
+}
+
+// UploadPart uploads a part of a multipart upload
+func (s *metricsStorage) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+	multipart, ok := s.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	reader, counter := countReader(reader)
+	start := time.Now()
+	etag, err := multipart.UploadPart(ctx, key, uploadID, number, reader, size)
+	s.metrics.observe(s.next.GetProvider(), "upload_part", start, counter.n, err)
+	return etag, err
+}
+
+// CompleteMultipart completes a multipart upload
+func (s *metricsStorage) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) (string, error) {
+	multipart, ok := s.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	start := time.Now()
+	versionID, err := multipart.CompleteMultipart(ctx, key, uploadID, parts)
+	s.metrics.observe(s.next.GetProvider(), "complete_multipart", start, 0, err)
+	return versionID, err
+}
+
+// AbortMultipart aborts a multipart upload
+func (s *metricsStorage) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	multipart, ok := s.next.(MultipartUploader)
+	if !ok {
+		return ErrMultipartUnsupported
+	}
+	start := time.Now()
+	err := multipart.AbortMultipart(ctx, key, uploadID)
+	s.metrics.observe(s.next.GetProvider(), "abort_multipart", start, 0, err)
+	return err
+}
+
+// ListMultipart lists the multipart uploads in progress
+func (s *metricsStorage) ListMultipart(ctx context.Context, prefix string) ([]MultipartUpload, error) {
+	multipart, ok := s.next.(MultipartUploader)
+	if !ok {
+		return nil, ErrMultipartUnsupported
+	}
+	start := time.Now()
+	uploads, err := multipart.ListMultipart(ctx, prefix)
+	s.metrics.observe(s.next.GetProvider(), "list_multipart", start, 0, err)
+	return uploads, err
+}
+
+// ApplyLifecycle adds a lifecycle rule to the bucket
+func (s *metricsStorage) ApplyLifecycle(ctx context.Context, rule LifecycleRule) error {
+	manager, ok := s.next.(LifecycleManager)
+	if !ok {
+		return ErrLifecycleUnsupported
+	}
+	start := time.Now()
+	err := manager.ApplyLifecycle(ctx, rule)
+	s.metrics.observe(s.next.GetProvider(), "lifecycle", start, 0, err)
+	return err
+}
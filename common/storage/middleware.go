@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"io"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+)
+
+// Middleware wraps a storage backend to add behavior around its requests
+type Middleware func(Storage) Storage
+
+// Chain wraps s with the middlewares; the first middleware is the outermost
+func Chain(s Storage, middlewares ...Middleware) Storage {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		s = middlewares[i](s)
+	}
+	return s
+}
+
+// Middlewares returns the middlewares enabled in cfg in the order they wrap the backend
+func Middlewares(cfg common_config.StorageMiddlewareConfig) []Middleware {
+	var middlewares []Middleware
+
+	if cfg.Logging {
+		middlewares = append(middlewares, WithLogging())
+	}
+	if cfg.Metrics.Enabled {
+		middlewares = append(middlewares, WithMetrics(DefaultMetrics))
+	}
+	if cfg.Cache.Enabled {
+		middlewares = append(middlewares, WithCache(cfg.Cache.TTL, cfg.Cache.MaxEntries))
+	}
+	if cfg.Retry.Enabled {
+		middlewares = append(middlewares, WithRetry(RetryPolicy{
+			MaxAttempts:    cfg.Retry.MaxAttempts,
+			InitialBackoff: cfg.Retry.InitialBackoff,
+			MaxBackoff:     cfg.Retry.MaxBackoff,
+		}))
+	}
+
+	return middlewares
+}
+
+// Unwrapper is implemented by middlewares to expose the storage they wrap
+type Unwrapper interface {
+	Unwrap() Storage
+}
+
+// Unwrap returns the storage s wraps, or nil when s is not a middleware. Middlewares
+// forward the optional interfaces (Appender, RangeDownloader, LifecycleManager, MultipartUploader)
+// themselves, so callers do not need to unwrap to reach them.
+func Unwrap(s Storage) Storage {
+	u, ok := s.(Unwrapper)
+	if !ok {
+		return nil
+	}
+	return u.Unwrap()
+}
+
+// countReader wraps r to count the bytes read, keeping it seekable when r is
+func countReader(r io.Reader) (io.Reader, *countingReader) {
+	counter := &countingReader{r: r}
+	if seeker, ok := r.(io.Seeker); ok {
+		return &countingReadSeeker{countingReader: counter, seeker: seeker}, counter
+	}
+	return counter, counter
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingReadSeeker is a countingReader over a seekable reader
+type countingReadSeeker struct {
+	*countingReader
+	seeker io.Seeker
+}
+
+func (c *countingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return c.seeker.Seek(offset, whence)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
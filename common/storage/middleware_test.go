@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyStorage is local storage that fails its first requests and counts calls
+type flakyStorage struct {
+	*LocalStorage
+	failures int
+	calls    map[string]int
+}
+
+func newFlakyStorage(t *testing.T, failures int) *flakyStorage {
+	local, err := NewLocalStorage(&LocalConfig{RootDir: t.TempDir()})
+	assert.NoError(t, err)
+	return &flakyStorage{LocalStorage: local, failures: failures, calls: make(map[string]int)}
+}
+
+func (f *flakyStorage) fail(op string) error {
+	f.calls[op]++
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("connection reset by peer")
+	}
+	return nil
+}
+
+func (f *flakyStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	if err := f.fail("upload"); err != nil {
+		io.CopyN(io.Discard, reader, 2) // A partial read before the failure
+		return "", err
+	}
+	return f.LocalStorage.UploadFile(ctx, key, reader, metadata)
+}
+
+func (f *flakyStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	if err := f.fail("exists"); err != nil {
+		return false, err
+	}
+	return f.LocalStorage.FileExists(ctx, key)
+}
+
+func (f *flakyStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	if err := f.fail("list"); err != nil {
+		return nil, err
+	}
+	return f.LocalStorage.ListFiles(ctx, prefix)
+}
+
+func (f *flakyStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	if err := f.fail("stat"); err != nil {
+		return FileInfo{}, nil, err
+	}
+	return f.LocalStorage.GetFileInfo(ctx, key)
+}
+
+// plainStorage hides the optional interfaces of the storage it embeds
+type plainStorage struct {
+	Storage
+}
+
+func TestChainOrderAndUnwrap(t *testing.T) {
+	backend := newFlakyStorage(t, 0)
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next Storage) Storage {
+			order = append(order, name)
+			return WithLogging()(next)
+		}
+	}
+
+	store := Chain(backend, tag("outer"), tag("inner"))
+	assert.Equal(t, []string{"inner", "outer"}, order) // Wrapped from the inside out
+	assert.Equal(t, ProviderLocal, store.GetProvider())
+	inner := Unwrap(store)
+	assert.NotNil(t, inner)
+	assert.NotSame(t, backend, inner)
+	assert.Same(t, backend, Unwrap(inner))
+	assert.Nil(t, Unwrap(backend))
+}
+
+func TestMiddlewaresForwardCapabilities(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetrics()
+	middlewares := []Middleware{
+		WithLogging(),
+		WithMetrics(metrics),
+		WithCache(time.Minute, 0),
+		WithRetry(RetryPolicy{MaxAttempts: 2}),
+	}
+
+	memory := NewMemoryStorage(&MemoryConfig{})
+	store := Chain(memory, middlewares...)
+	_, err := store.UploadFile(ctx, "docs/a.txt", strings.NewReader("hello world"), nil)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, store.(RangeDownloader).DownloadRange(ctx, "docs/a.txt", 6, 5, &buf))
+	assert.Equal(t, "world", buf.String())
+
+	var out strings.Builder
+	assert.NoError(t, metrics.WritePrometheus(&out))
+	assert.Contains(t, out.String(), `operation="download_range"} 5`)
+
+	// Every layer reports what the backend beneath it lacks
+	plain := Chain(plainStorage{memory}, middlewares...)
+	err = plain.(RangeDownloader).DownloadRange(ctx, "docs/a.txt", 0, 1, &buf)
+	assert.ErrorIs(t, err, ErrRangeUnsupported)
+	err = plain.(LifecycleManager).ApplyLifecycle(ctx, LifecycleRule{ID: "rule", Prefix: "docs/"})
+	assert.ErrorIs(t, err, ErrLifecycleUnsupported)
+	for _, middleware := range middlewares {
+		_, err = middleware(plainStorage{memory}).(Appender).AppendFile(ctx, "docs/a.txt", 0, strings.NewReader("x"), 1, nil)
+		assert.ErrorIs(t, err, ErrAppendUnsupported)
+		_, err = middleware(plainStorage{memory}).(MultipartUploader).CreateMultipart(ctx, "docs/a.txt", nil)
+		assert.ErrorIs(t, err, ErrMultipartUnsupported)
+	}
+}
+
+func TestMultipartThroughMiddlewares(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetrics()
+	store := Chain(NewMemoryStorage(&MemoryConfig{}), WithLogging(), WithMetrics(metrics), WithCache(time.Minute, 0), WithRetry(RetryPolicy{MaxAttempts: 2}))
+	_, err := store.UploadFile(ctx, "docs/big.bin", strings.NewReader("old"), nil)
+	assert.NoError(t, err)
+	_, _, err = store.GetFileInfo(ctx, "docs/big.bin")
+	assert.NoError(t, err)
+
+	multipart := store.(MultipartUploader)
+	uploadID, err := multipart.CreateMultipart(ctx, "docs/big.bin", map[string]string{"device_id": "laptop"})
+	assert.NoError(t, err)
+	var parts []Part
+	for i, content := range []string{"hello ", "world"} {
+		etag, err := multipart.UploadPart(ctx, "docs/big.bin", uploadID, i+1, strings.NewReader(content), int64(len(content)))
+		assert.NoError(t, err)
+		parts = append(parts, Part{Number: i + 1, ETag: etag, Size: int64(len(content))})
+	}
+	uploads, err := multipart.ListMultipart(ctx, "docs/")
+	assert.NoError(t, err)
+	if assert.Len(t, uploads, 1) {
+		assert.Equal(t, MultipartUpload{Key: "docs/big.bin", ID: uploadID, Initiated: uploads[0].Initiated}, uploads[0])
+	}
+
+	// Completing the upload replaces the file, and the cached information with it
+	_, err = multipart.CompleteMultipart(ctx, "docs/big.bin", uploadID, parts)
+	assert.NoError(t, err)
+	info, metadata, err := store.GetFileInfo(ctx, "docs/big.bin")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), info.Size)
+	assert.Equal(t, "laptop", metadata["device_id"])
+	uploads, err = multipart.ListMultipart(ctx, "docs/")
+	assert.NoError(t, err)
+	assert.Empty(t, uploads)
+
+	var out strings.Builder
+	assert.NoError(t, metrics.WritePrometheus(&out))
+	assert.Contains(t, out.String(), `operation="upload_part"} 11`)
+
+	// An upload no longer there is not found
+	_, err = multipart.UploadPart(ctx, "docs/big.bin", uploadID, 3, strings.NewReader("x"), 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, multipart.AbortMultipart(ctx, "docs/big.bin", uploadID), ErrNotFound)
+}
+
+func TestCacheInvalidatedByAppend(t *testing.T) {
+	ctx := context.Background()
+	store := WithCache(time.Minute, 100)(NewMemoryStorage(&MemoryConfig{}))
+	_, err := store.UploadFile(ctx, "docs/a.txt", strings.NewReader("hello"), nil)
+	assert.NoError(t, err)
+
+	info, _, err := store.GetFileInfo(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), info.Size)
+	files, err := store.ListFiles(ctx, "docs/")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), files[0].Size)
+
+	_, err = store.(Appender).AppendFile(ctx, "docs/a.txt", 5, strings.NewReader(" world"), 6, map[string]string{"device_id": "laptop"})
+	assert.NoError(t, err)
+
+	info, metadata, err := store.GetFileInfo(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), info.Size)
+	assert.Equal(t, "laptop", metadata["device_id"])
+	files, err = store.ListFiles(ctx, "docs/")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), files[0].Size)
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	// A seekable upload is rewound and retried
+	backend := newFlakyStorage(t, 2)
+	store := WithRetry(policy)(backend)
+	_, err := store.UploadFile(ctx, "docs/a.txt", strings.NewReader("hello"), map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, backend.calls["upload"])
+
+	var buf bytes.Buffer
+	_, err = store.DownloadFile(ctx, "docs/a.txt", &buf, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", buf.String())
+
+	// A stream cannot be replayed, so it gets a single attempt
+	backend = newFlakyStorage(t, 1)
+	store = WithRetry(policy)(backend)
+	_, err = store.UploadFile(ctx, "docs/b.txt", io.MultiReader(strings.NewReader("hello")), map[string]string{})
+	assert.Error(t, err)
+	assert.Equal(t, 1, backend.calls["upload"])
+
+	// Attempts are bounded
+	backend = newFlakyStorage(t, 5)
+	store = WithRetry(policy)(backend)
+	_, err = store.FileExists(ctx, "docs/a.txt")
+	assert.Error(t, err)
+	assert.Equal(t, 3, backend.calls["exists"])
+
+	// A missing file is an answer, not a failure
+	backend = newFlakyStorage(t, 0)
+	store = WithRetry(policy)(backend)
+	_, _, err = store.GetFileInfo(ctx, "docs/a.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 1, backend.calls["stat"])
+
+	assert.False(t, Retryable(context.Canceled))
+	assert.False(t, Retryable(errors.New("NoSuchKey: the specified key does not exist")))
+	assert.True(t, Retryable(errors.New("connection reset by peer")))
+
+	assert.True(t, Throttled(errors.New("SlowDown: Please reduce your request rate. status code: 503")))
+	assert.True(t, Throttled(errors.New("googleapi: Error 429: rateLimitExceeded")))
+	assert.False(t, Throttled(errors.New("connection reset by peer")))
+	assert.False(t, Throttled(nil))
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	backend := newFlakyStorage(t, 0)
+	store := WithCache(time.Minute, 100)(backend)
+
+	exists, err := store.FileExists(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	files, err := store.ListFiles(ctx, "docs/")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	// Repeated requests are answered from memory
+	store.FileExists(ctx, "docs/a.txt")
+	store.ListFiles(ctx, "docs/")
+	assert.Equal(t, 1, backend.calls["exists"])
+	assert.Equal(t, 1, backend.calls["list"])
+
+	// An upload through the cache updates what it knows
+	_, err = store.UploadFile(ctx, "docs/a.txt", strings.NewReader("hello"), map[string]string{})
+	assert.NoError(t, err)
+	exists, _ = store.FileExists(ctx, "docs/a.txt")
+	assert.True(t, exists)
+	assert.Equal(t, 1, backend.calls["exists"])
+	files, _ = store.ListFiles(ctx, "docs/")
+	assert.Len(t, files, 1)
+	assert.Equal(t, 2, backend.calls["list"])
+
+	info, metadata, err := store.GetFileInfo(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), info.Size)
+	metadata["hash_sha256"] = "changed" // Callers cannot alter the cached entry
+	_, metadata, _ = store.GetFileInfo(ctx, "docs/a.txt")
+	assert.NotEqual(t, "changed", metadata["hash_sha256"])
+	assert.Equal(t, 1, backend.calls["stat"])
+
+	assert.NoError(t, store.DeleteFile(ctx, "docs/a.txt"))
+	exists, _ = store.FileExists(ctx, "docs/a.txt")
+	assert.False(t, exists)
+	_, _, err = store.GetFileInfo(ctx, "docs/a.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 2, backend.calls["stat"])
+
+	// Entries expire
+	store = WithCache(time.Nanosecond, 100)(backend)
+	store.FileExists(ctx, "docs/a.txt")
+	time.Sleep(time.Millisecond)
+	store.FileExists(ctx, "docs/a.txt")
+	assert.Equal(t, 3, backend.calls["exists"])
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetrics()
+	store := WithMetrics(metrics)(newFlakyStorage(t, 1))
+
+	_, err := store.FileExists(ctx, "docs/a.txt")
+	assert.Error(t, err)
+	_, err = store.UploadFile(ctx, "docs/a.txt", strings.NewReader("hello"), map[string]string{})
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, body, `sync_manager_storage_requests_total{provider="local",operation="exists",result="error"} 1`)
+	assert.Contains(t, body, `sync_manager_storage_requests_total{provider="local",operation="upload",result="ok"} 1`)
+	assert.Contains(t, body, `sync_manager_storage_request_duration_seconds_count{provider="local",operation="upload"} 1`)
+	assert.Contains(t, body, `sync_manager_storage_request_duration_seconds_bucket{provider="local",operation="upload",le="+Inf"} 1`)
+	assert.Contains(t, body, `sync_manager_storage_bytes_total{provider="local",operation="upload"} 5`)
+
+	// Gauges are read at every scrape
+	queued := int64(3)
+	metrics.AddGauge("sync_manager_upload_queue_length", "Uploads waiting for a worker.", func() int64 { return queued })
+	queued = 7
+	recorder = httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), "# TYPE sync_manager_upload_queue_length gauge\nsync_manager_upload_queue_length 7\n")
+}
+
+func TestMiddlewaresFromConfig(t *testing.T) {
+	cfg := common_config.DefaultConfig().StorageMiddleware
+	assert.Empty(t, Middlewares(cfg))
+
+	cfg.Logging = true
+	cfg.Cache.Enabled = true
+	cfg.Retry.Enabled = true
+	cfg.Retry.InitialBackoff = time.Millisecond
+	assert.Len(t, Middlewares(cfg), 3)
+
+	// Retrying below a logging wrapper still sees a seekable reader
+	backend := newFlakyStorage(t, 1)
+	store := Chain(backend, Middlewares(cfg)...)
+	_, err := store.UploadFile(context.Background(), "docs/a.txt", bytes.NewReader([]byte("hello")), map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, backend.calls["upload"])
+}
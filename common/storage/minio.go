@@ -0,0 +1,361 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/transport"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/rs/zerolog/log"
+)
+
+// MinioConfig holds configuration for MinIO
+type MinioConfig struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	Transport common_config.TransportConfig
+}
+
+// NewMinioConfigFromCommon converts a common.MinioConfig to storage.MinioConfig
+func NewMinioConfigFromCommon(commonCfg *common_config.MinioConfig) *MinioConfig {
+	return &MinioConfig{
+		Endpoint:  commonCfg.Endpoint,
+		Region:    commonCfg.Region,
+		Bucket:    commonCfg.Bucket,
+		AccessKey: commonCfg.AccessKey,
+		SecretKey: commonCfg.SecretKey,
+		UseSSL:    commonCfg.UseSSL,
+		Transport: commonCfg.TransportConfig,
+	}
+}
+
+// MinioStorage implements the Storage interface using MinIO
+type MinioStorage struct {
+	client *minio.Client
+	bucket string
+	config *MinioConfig
+}
+
+// GetProvider returns the storage provider type
+func (m *MinioStorage) GetProvider() StorageProvider {
+	return ProviderMinio
+}
+
+// NewMinioStorage creates a new MinIO storage client
+func NewMinioStorage(cfg *MinioConfig) (*MinioStorage, error) {
+	opts := &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	}
+
+	if !transport.IsDefault(cfg.Transport) {
+		httpTransport, err := transport.New(cfg.Transport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure MinIO transport: %w", err)
+		}
+		opts.Transport = httpTransport
+	}
+
+	client, err := minio.New(cfg.Endpoint, opts)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
+	}
+
+	exists, err := client.BucketExists(context.Background(), cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if bucket exists: %w", err)
+	}
+
+	if !exists {
+		err = client.MakeBucket(context.Background(), cfg.Bucket, minio.MakeBucketOptions{
+			Region: cfg.Region,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bucket: %w", err)
+		}
+		log.Info().Str("bucket", cfg.Bucket).Msg("Created MinIO bucket")
+	}
+
+	return &MinioStorage{
+		client: client,
+		bucket: cfg.Bucket,
+		config: cfg,
+	}, nil
+}
+
+// UploadFile uploads a file to MinIO
+func (m *MinioStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+
+	storageClass, userMetadata := splitStorageClass(metadata)
+
+	info, err := m.client.PutObject(ctx, m.bucket, key, reader, -1, minio.PutObjectOptions{
+		UserMetadata: userMetadata,
+		ContentType:  metadata["content_type"],
+		StorageClass: storageClass,
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	log.Debug().
+		Str("bucket", m.bucket).
+		Str("key", key).
+		Str("etag", info.ETag).
+		Msg("Uploaded file to MinIO")
+
+	// Return the ETag as version ID
+	return info.ETag, nil
+}
+
+// AppendFile implements Appender by uploading the new bytes to a temporary object and
+// composing it after the first offset bytes of the current object. Like S3, MinIO only
+// composes sources of at least 5 MiB before the last one, so smaller files are not appended.
+func (m *MinioStorage) AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error) {
+	if offset < minComposePart {
+		return "", ErrAppendUnsupported
+	}
+	key = strings.TrimPrefix(key, "/")
+
+	tail := fmt.Sprintf("%s.append-%d", key, time.Now().UnixNano())
+	if _, err := m.client.PutObject(ctx, m.bucket, tail, reader, length, minio.PutObjectOptions{}); err != nil {
+		return "", fmt.Errorf("failed to upload appended content: %w", err)
+	}
+	defer func() {
+		if err := m.client.RemoveObject(context.Background(), m.bucket, tail, minio.RemoveObjectOptions{}); err != nil {
+			log.Warn().Err(err).Str("key", tail).Msg("Failed to remove temporary MinIO object")
+		}
+	}()
+
+	storageClass, userMetadata := splitStorageClass(metadata)
+	if storageClass != "" {
+		userMetadata["X-Amz-Storage-Class"] = storageClass
+	}
+	info, err := m.client.ComposeObject(ctx,
+		minio.CopyDestOptions{Bucket: m.bucket, Object: key, UserMetadata: userMetadata, ReplaceMetadata: true},
+		minio.CopySrcOptions{Bucket: m.bucket, Object: key, MatchRange: true, Start: 0, End: offset - 1},
+		minio.CopySrcOptions{Bucket: m.bucket, Object: tail},
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to compose appended file: %w", err)
+	}
+
+	log.Debug().
+		Str("bucket", m.bucket).
+		Str("key", key).
+		Int64("offset", offset).
+		Int64("appended", length).
+		Str("etag", info.ETag).
+		Msg("Appended to file in MinIO")
+
+	return info.ETag, nil
+}
+
+// DownloadFile downloads a file from MinIO
+func (m *MinioStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	key = strings.TrimPrefix(key, "/")
+
+	opts := minio.GetObjectOptions{}
+	if versionID != "" {
+		opts.VersionID = versionID
+	}
+
+	obj, err := m.client.GetObject(ctx, m.bucket, key, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer obj.Close()
+
+	stat, err := obj.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object info: %w", err)
+	}
+
+	if _, err := io.Copy(writer, obj); err != nil {
+		return nil, fmt.Errorf("failed to copy file content: %w", err)
+	}
+
+	metadata := make(map[string]string)
+	for k, v := range stat.UserMetadata {
+		metadata[k] = v
+	}
+
+	log.Debug().
+		Str("bucket", m.bucket).
+		Str("key", key).
+		Str("etag", stat.ETag).
+		Msg("Downloaded file from MinIO")
+
+	return metadata, nil
+}
+
+// DownloadRange implements RangeDownloader
+func (m *MinioStorage) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	key = strings.TrimPrefix(key, "/")
+
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(offset, offset+length-1); err != nil {
+		return fmt.Errorf("invalid range: %w", err)
+	}
+
+	obj, err := m.client.GetObject(ctx, m.bucket, key, opts)
+	if err != nil {
+		return fmt.Errorf("failed to download file range: %w", err)
+	}
+	defer obj.Close()
+
+	if _, err := io.Copy(writer, obj); err != nil {
+		return fmt.Errorf("failed to copy file content: %w", err)
+	}
+	return nil
+}
+
+// DeleteFile deletes a file from MinIO
+func (m *MinioStorage) DeleteFile(ctx context.Context, key string) error {
+	key = strings.TrimPrefix(key, "/")
+
+	err := m.client.RemoveObject(ctx, m.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	log.Debug().
+		Str("bucket", m.bucket).
+		Str("key", key).
+		Msg("Deleted file from MinIO")
+
+	return nil
+}
+
+// ListFiles lists files in MinIO with the given prefix
+func (m *MinioStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	prefix = strings.TrimPrefix(prefix, "/")
+
+	objectCh := m.client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+
+	var files []FileInfo
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("error listing objects: %w", object.Err)
+		}
+
+		files = append(files, FileInfo{
+			Key:          object.Key,
+			Size:         object.Size,
+			LastModified: object.LastModified,
+			ETag:         strings.Trim(object.ETag, "\""),
+		})
+	}
+
+	log.Debug().
+		Str("bucket", m.bucket).
+		Str("prefix", prefix).
+		Int("count", len(files)).
+		Msg("Listed files from MinIO")
+
+	return files, nil
+}
+
+// FileExists checks if a file exists in MinIO
+func (m *MinioStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	key = strings.TrimPrefix(key, "/")
+
+	_, err := m.client.StatObject(ctx, m.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "404") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check if file exists: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetFileInfo returns the information and metadata of a file in MinIO
+func (m *MinioStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	key = strings.TrimPrefix(key, "/")
+
+	stat, err := m.client.StatObject(ctx, m.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "404") {
+			return FileInfo{}, nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return FileInfo{}, nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	metadata := make(map[string]string)
+	for k, v := range stat.UserMetadata {
+		metadata[k] = v
+	}
+
+	return FileInfo{
+		Key:          stat.Key,
+		Size:         stat.Size,
+		LastModified: stat.LastModified,
+		ETag:         strings.Trim(stat.ETag, "\""),
+		MD5:          ETagMD5(stat.ETag),
+	}, metadata, nil
+}
+
+// ApplyLifecycle adds a rule for old object versions to the bucket's lifecycle configuration.
+// MinIO only transitions to remote tiers configured on the server, named by TransitionClass.
+func (m *MinioStorage) ApplyLifecycle(ctx context.Context, rule LifecycleRule) error {
+	if err := rule.Validate(ProviderMinio); err != nil {
+		return err
+	}
+
+	config := lifecycle.NewConfiguration()
+	current, err := m.client.GetBucketLifecycle(ctx, m.bucket)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code != "NoSuchLifecycleConfiguration" {
+			return fmt.Errorf("failed to get lifecycle configuration: %w", err)
+		}
+	} else {
+		for _, existing := range current.Rules {
+			if existing.ID != rule.ID {
+				config.Rules = append(config.Rules, existing)
+			}
+		}
+	}
+
+	minioRule := lifecycle.Rule{
+		ID:         rule.ID,
+		Status:     "Enabled",
+		RuleFilter: lifecycle.Filter{Prefix: strings.TrimPrefix(rule.Prefix, "/")},
+	}
+	if rule.TransitionDays > 0 {
+		minioRule.NoncurrentVersionTransition = lifecycle.NoncurrentVersionTransition{
+			NoncurrentDays: lifecycle.ExpirationDays(rule.TransitionDays),
+			StorageClass:   rule.TransitionClass,
+		}
+	}
+	if rule.ExpireDays > 0 {
+		minioRule.NoncurrentVersionExpiration = lifecycle.NoncurrentVersionExpiration{
+			NoncurrentDays: lifecycle.ExpirationDays(rule.ExpireDays),
+		}
+	}
+	config.Rules = append(config.Rules, minioRule)
+
+	if err := m.client.SetBucketLifecycle(ctx, m.bucket, config); err != nil {
+		return fmt.Errorf("failed to set lifecycle configuration: %w", err)
+	}
+
+	log.Debug().Str("bucket", m.bucket).Str("rule", rule.ID).Msg("Applied MinIO lifecycle rule")
+	return nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/martinshumberto/sync-manager/common/checksum"
+)
+
+// MovePrefix moves every object under the from prefix to the same path under the to prefix,
+// with its metadata. Every object is copied before any is deleted, and objects already
+// copied are skipped, so an interrupted move can simply be run again. An object of another
+// content already under to stops the move before anything is deleted. It returns the number
+// of objects moved.
+func MovePrefix(ctx context.Context, store Storage, from, to string) (int, error) {
+	from = strings.TrimSuffix(from, "/") + "/"
+	to = strings.TrimSuffix(to, "/") + "/"
+	if from == to {
+		return 0, nil
+	}
+
+	files, err := store.ListFiles(ctx, from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %w", from, err)
+	}
+
+	for _, file := range files {
+		target := to + strings.TrimPrefix(file.Key, from)
+		copied, err := sameObject(ctx, store, file.Key, target)
+		if err != nil {
+			return 0, err
+		}
+		if copied {
+			continue
+		}
+		if err := copyObject(ctx, store, file.Key, target); err != nil {
+			return 0, fmt.Errorf("failed to copy %s: %w", file.Key, err)
+		}
+	}
+
+	for i, file := range files {
+		if err := store.DeleteFile(ctx, file.Key); err != nil && !errors.Is(err, ErrNotFound) {
+			return i, fmt.Errorf("failed to delete %s: %w", file.Key, err)
+		}
+	}
+	return len(files), nil
+}
+
+// sameObject reports whether target already holds the content of key. A target holding
+// something else is an error, as moving there would overwrite it.
+func sameObject(ctx context.Context, store Storage, key, target string) (bool, error) {
+	_, targetMetadata, err := store.GetFileInfo(ctx, target)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get info of %s: %w", target, err)
+	}
+	_, metadata, err := store.GetFileInfo(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to get info of %s: %w", key, err)
+	}
+
+	hash := checksum.FromMetadata(metadata)
+	if hash == "" || !strings.EqualFold(hash, checksum.FromMetadata(targetMetadata)) {
+		return false, fmt.Errorf("%s already exists with other content", target)
+	}
+	return true, nil
+}
+
+// copyObject copies an object and its metadata to another key through a temporary file
+func copyObject(ctx context.Context, store Storage, from, to string) error {
+	tmpFile, err := os.CreateTemp("", "sync-manager-move-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	metadata, err := store.DownloadFile(ctx, from, tmpFile, "")
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+	if _, err := store.UploadFile(ctx, to, tmpFile, metadata); err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMovePrefix(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage(&MemoryConfig{})
+	for key, content := range map[string]string{
+		"docs/a.txt":                      "a",
+		"docs/sub/b.txt":                  "b",
+		"docs/empty/" + DirMarker:         "",
+		"docs-archive/c.txt":              "c",
+		"team-docs/sub/b.txt":             "b",
+		"shared/a.txt":                    "other",
+		SnapshotKeyPrefix + "/docs/index": "{}",
+	} {
+		_, err := store.UploadFile(ctx, key, strings.NewReader(content), map[string]string{"device_id": "laptop"})
+		assert.NoError(t, err)
+	}
+
+	// Another file already under the target stops the move before anything is deleted
+	_, err := MovePrefix(ctx, store, "docs", "shared")
+	assert.ErrorContains(t, err, "already exists")
+	found, err := store.FileExists(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	// Files copied by an earlier attempt are skipped; other prefixes are left alone
+	moved, err := MovePrefix(ctx, store, "docs/", "team-docs")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, moved)
+
+	files, err := store.ListFiles(ctx, "docs/")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+	files, err = store.ListFiles(ctx, "team-docs/")
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	var buf bytes.Buffer
+	metadata, err := store.DownloadFile(ctx, "team-docs/a.txt", &buf, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", buf.String())
+	assert.Equal(t, "laptop", metadata["device_id"])
+	for _, key := range []string{"docs-archive/c.txt", SnapshotKeyPrefix + "/docs/index"} {
+		found, err := store.FileExists(ctx, key)
+		assert.NoError(t, err)
+		assert.True(t, found, key)
+	}
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrMultipartUnsupported is returned by a MultipartUploader whose backend cannot upload a
+// file in parts, which is then uploaded whole
+var ErrMultipartUnsupported = errors.New("storage cannot upload files in parts")
+
+const (
+	// MinPartSize is the smallest part S3-compatible backends accept, but for the last one
+	MinPartSize = minComposePart
+	// MaxParts is the most parts a multipart upload can have
+	MaxParts = 10000
+)
+
+// Part is a part of a multipart upload, numbered from 1
+type Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// MultipartUpload is a multipart upload started and neither completed nor aborted
+type MultipartUpload struct {
+	Key       string
+	ID        string
+	Initiated time.Time
+}
+
+// MultipartUploader is implemented by backends that upload a file in parts kept server-side
+// until the upload is completed, so an upload cut short, even by a restart, continues from
+// the last part uploaded instead of starting over. The parts of an upload never completed
+// take up space until it is aborted. An upload ID the backend no longer knows, as after it
+// was aborted, fails with an error wrapping ErrNotFound.
+type MultipartUploader interface {
+	// CreateMultipart starts a multipart upload of a file with its metadata and returns its ID.
+	// It fails with ErrMultipartUnsupported when the backend cannot upload in parts.
+	CreateMultipart(ctx context.Context, key string, metadata map[string]string) (string, error)
+
+	// UploadPart uploads size bytes read from reader as a part of an upload and returns its entity tag
+	UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error)
+
+	// CompleteMultipart assembles the parts of an upload, in order, into a new version of the
+	// file and returns its version ID
+	CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) (string, error)
+
+	// AbortMultipart drops an upload and the parts uploaded to it
+	AbortMultipart(ctx context.Context, key, uploadID string) error
+
+	// ListMultipart returns the uploads of files under prefix still in progress
+	ListMultipart(ctx context.Context, prefix string) ([]MultipartUpload, error)
+}
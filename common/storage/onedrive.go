@@ -0,0 +1,658 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/martinshumberto/sync-manager/common/transport"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// oneDriveSimpleUploadLimit is the largest file uploaded in a single request; larger
+	// files go through an upload session
+	oneDriveSimpleUploadLimit = 4 << 20
+	// oneDriveChunkSize is the size of the fragments of an upload session, a multiple of
+	// the 320 KiB Graph requires
+	oneDriveChunkSize = 32 * 320 << 10
+	// oneDriveMetadataDir holds the metadata of the files, which drive items cannot carry
+	oneDriveMetadataDir = ".sync-manager-metadata"
+)
+
+// OneDriveConfig holds configuration for OneDrive
+type OneDriveConfig struct {
+	ClientID  string
+	Tenant    string
+	DriveID   string
+	AppFolder bool
+	TokenFile string
+	Transport common_config.TransportConfig
+
+	GraphURL string // Microsoft Graph API, https://graph.microsoft.com/v1.0 when empty
+	LoginURL string // Microsoft identity platform, https://login.microsoftonline.com when empty
+}
+
+// NewOneDriveConfigFromCommon converts a common.OneDriveConfig of the named target to storage.OneDriveConfig
+func NewOneDriveConfigFromCommon(commonCfg *common_config.OneDriveConfig, target string) (*OneDriveConfig, error) {
+	tokenFile, err := commonCfg.TokenPath(target)
+	if err != nil {
+		return nil, err
+	}
+	return &OneDriveConfig{
+		ClientID:  commonCfg.ClientID,
+		Tenant:    commonCfg.Tenant,
+		DriveID:   commonCfg.DriveID,
+		AppFolder: commonCfg.AppFolder,
+		TokenFile: tokenFile,
+		Transport: commonCfg.TransportConfig,
+	}, nil
+}
+
+// oauth returns the OAuth settings of the app the agent signs in as
+func (c *OneDriveConfig) oauth() *oauth2.Config {
+	tenant := c.Tenant
+	if tenant == "" {
+		tenant = "common"
+	}
+	login := c.LoginURL
+	if login == "" {
+		login = "https://login.microsoftonline.com"
+	}
+	login += "/" + url.PathEscape(tenant) + "/oauth2/v2.0"
+
+	scope := "Files.ReadWrite"
+	if c.DriveID != "" {
+		scope = "Files.ReadWrite.All"
+	} else if c.AppFolder {
+		scope = "Files.ReadWrite.AppFolder"
+	}
+	return &oauth2.Config{
+		ClientID: c.ClientID,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:       login + "/authorize",
+			TokenURL:      login + "/token",
+			DeviceAuthURL: login + "/devicecode",
+			AuthStyle:     oauth2.AuthStyleInParams,
+		},
+		Scopes: []string{scope, "offline_access"},
+	}
+}
+
+// OneDriveLogin signs in to OneDrive with a device code and keeps the sign-in in the token
+// file. prompt shows the user the page to open and the code to enter there; the call returns
+// once they did, or the code expired.
+func OneDriveLogin(ctx context.Context, cfg *OneDriveConfig, prompt func(verificationURI, userCode string)) error {
+	client, err := transport.NewClient(cfg.Transport, 30*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to configure OneDrive transport: %w", err)
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+
+	oauth := cfg.oauth()
+	device, err := oauth.DeviceAuth(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start OneDrive sign-in: %w", err)
+	}
+	prompt(device.VerificationURI, device.UserCode)
+
+	token, err := oauth.DeviceAccessToken(ctx, device)
+	if err != nil {
+		return fmt.Errorf("OneDrive sign-in failed: %w", err)
+	}
+	return saveOneDriveToken(cfg.TokenFile, token)
+}
+
+// OneDriveStorage implements the Storage interface on a OneDrive through Microsoft Graph
+type OneDriveStorage struct {
+	config    *OneDriveConfig
+	api       *http.Client // Signed in, and not following redirects to download URLs
+	plain     *http.Client // For upload session and download URLs, which must not get the token
+	graph     string
+	rootID    string
+	chunkSize int64
+
+	// Files of the drive as of deltaLink, by item ID. Listings only fetch the changes since.
+	items     map[string]oneDriveNode
+	deltaLink string
+	mu        sync.Mutex
+}
+
+// oneDriveNode is a file or folder seen in the delta of the drive
+type oneDriveNode struct {
+	name   string
+	parent string
+	file   bool
+	info   FileInfo
+}
+
+// oneDriveItem is a drive item as Graph returns it
+type oneDriveItem struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"eTag"`
+	LastModified time.Time `json:"lastModifiedDateTime"`
+	File         *struct{} `json:"file"`
+	Deleted      *struct{} `json:"deleted"`
+	Parent       struct {
+		ID string `json:"id"`
+	} `json:"parentReference"`
+}
+
+// NewOneDriveStorage creates a OneDrive storage client, signed in with the token file
+func NewOneDriveStorage(cfg *OneDriveConfig) (*OneDriveStorage, error) {
+	token, err := loadOneDriveToken(cfg.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+	base, err := transport.NewClient(cfg.Transport, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure OneDrive transport: %w", err)
+	}
+
+	source := oauth2.ReuseTokenSource(token, &savingTokenSource{
+		path:    cfg.TokenFile,
+		refresh: token.RefreshToken,
+		next:    cfg.oauth().TokenSource(context.WithValue(context.Background(), oauth2.HTTPClient, base), token),
+	})
+	graph := cfg.GraphURL
+	if graph == "" {
+		graph = "https://graph.microsoft.com/v1.0"
+	}
+	s := &OneDriveStorage{
+		config: cfg,
+		api: &http.Client{
+			Transport: &oauth2.Transport{Source: source, Base: base.Transport},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		plain:     base,
+		graph:     graph,
+		chunkSize: oneDriveChunkSize,
+		items:     make(map[string]oneDriveNode),
+	}
+
+	var root oneDriveItem
+	if err := s.getJSON(context.Background(), s.folderURL(), &root); err != nil {
+		return nil, fmt.Errorf("failed to access OneDrive: %w", err)
+	}
+	s.rootID = root.ID
+	return s, nil
+}
+
+// GetProvider returns the storage provider type
+func (s *OneDriveStorage) GetProvider() StorageProvider {
+	return ProviderOneDrive
+}
+
+// folderURL returns the folder files are kept in: the app folder or the root of the drive
+func (s *OneDriveStorage) folderURL() string {
+	drive := s.graph + "/me/drive"
+	if s.config.DriveID != "" {
+		drive = s.graph + "/drives/" + url.PathEscape(s.config.DriveID)
+	}
+	if s.config.AppFolder {
+		return drive + "/special/approot"
+	}
+	return drive + "/root"
+}
+
+// itemURL returns the address of the item at key, to which actions such as /content are appended
+func (s *OneDriveStorage) itemURL(key string) string {
+	segments := strings.Split(strings.Trim(key, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return s.folderURL() + ":/" + strings.Join(segments, "/") + ":"
+}
+
+// oneDriveMetadataKey returns the key the metadata of a file is kept at
+func oneDriveMetadataKey(key string) string {
+	return oneDriveMetadataDir + "/" + strings.TrimPrefix(key, "/") + ".json"
+}
+
+// UploadFile uploads a file to OneDrive, through an upload session when it is larger than 4 MB
+func (s *OneDriveStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	body, size, cleanup, err := sizedReader(reader, oneDriveSimpleUploadLimit)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	defer cleanup()
+
+	var item oneDriveItem
+	if size <= oneDriveSimpleUploadLimit {
+		err = s.doJSON(ctx, s.api, http.MethodPut, s.itemURL(key)+"/content", body, size, nil, &item)
+	} else {
+		item, err = s.uploadSession(ctx, key, body, size)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+
+	// Metadata left from an earlier version must not outlive it
+	_, metadata = splitStorageClass(metadata)
+	if len(metadata) > 0 {
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode metadata of %s: %w", key, err)
+		}
+		err = s.doJSON(ctx, s.api, http.MethodPut, s.itemURL(oneDriveMetadataKey(key))+"/content", bytes.NewReader(data), int64(len(data)), nil, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to upload metadata of %s: %w", key, err)
+		}
+	} else if err := s.deleteItem(ctx, oneDriveMetadataKey(key)); err != nil {
+		return "", fmt.Errorf("failed to delete metadata of %s: %w", key, err)
+	}
+
+	log.Debug().Str("key", key).Str("id", item.ID).Int64("size", size).Msg("Uploaded file to OneDrive")
+	return "", nil
+}
+
+// uploadSession uploads a large file in fragments, cancelling the session when one fails
+func (s *OneDriveStorage) uploadSession(ctx context.Context, key string, body io.Reader, size int64) (oneDriveItem, error) {
+	request := strings.NewReader(`{"item":{"@microsoft.graph.conflictBehavior":"replace"}}`)
+	var session struct {
+		UploadURL string `json:"uploadUrl"`
+	}
+	if err := s.doJSON(ctx, s.api, http.MethodPost, s.itemURL(key)+"/createUploadSession", request, int64(request.Len()), nil, &session); err != nil {
+		return oneDriveItem{}, err
+	}
+
+	var item oneDriveItem
+	for offset := int64(0); offset < size; offset += s.chunkSize {
+		length := min(s.chunkSize, size-offset)
+		header := http.Header{"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size)}}
+		if err := s.doJSON(ctx, s.plain, http.MethodPut, session.UploadURL, io.LimitReader(body, length), length, header, &item); err != nil {
+			s.doJSON(context.Background(), s.plain, http.MethodDelete, session.UploadURL, nil, 0, nil, nil)
+			return oneDriveItem{}, err
+		}
+	}
+	return item, nil
+}
+
+// DownloadFile downloads a file, or one of its versions, from OneDrive
+func (s *OneDriveStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	content := s.itemURL(key) + "/content"
+	if versionID != "" {
+		content = s.itemURL(key) + "/versions/" + url.PathEscape(versionID) + "/content"
+	}
+	if err := s.download(ctx, content, writer); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+
+	metadata, err := s.metadata(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// download writes the content at address to writer, following the redirect Graph answers
+// with to a pre-authenticated download URL
+func (s *OneDriveStorage) download(ctx context.Context, address string, writer io.Writer) error {
+	resp, err := s.send(ctx, s.api, http.MethodGet, address, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	if location := resp.Header.Get("Location"); resp.StatusCode/100 == 3 && location != "" {
+		resp.Body.Close()
+		if resp, err = s.send(ctx, s.plain, http.MethodGet, location, nil, 0, nil); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(writer, resp.Body)
+	return err
+}
+
+// metadata returns the metadata kept for a file, empty when it has none
+func (s *OneDriveStorage) metadata(ctx context.Context, key string) (map[string]string, error) {
+	var data bytes.Buffer
+	err := s.download(ctx, s.itemURL(oneDriveMetadataKey(key))+"/content", &data)
+	if errors.Is(err, ErrNotFound) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download metadata of %s: %w", key, err)
+	}
+
+	metadata := map[string]string{}
+	if err := json.Unmarshal(data.Bytes(), &metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata of %s: %w", key, err)
+	}
+	return metadata, nil
+}
+
+// DeleteFile deletes a file and its metadata from OneDrive. Deleted items go to the recycle bin.
+func (s *OneDriveStorage) DeleteFile(ctx context.Context, key string) error {
+	if err := s.deleteItem(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	if err := s.deleteItem(ctx, oneDriveMetadataKey(key)); err != nil {
+		return fmt.Errorf("failed to delete metadata of %s: %w", key, err)
+	}
+	return nil
+}
+
+// deleteItem deletes the item at key, if there is one
+func (s *OneDriveStorage) deleteItem(ctx context.Context, key string) error {
+	err := s.doJSON(ctx, s.api, http.MethodDelete, s.itemURL(key), nil, 0, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ListFiles lists the files under a prefix. The first listing walks the whole folder with a
+// delta query; later ones only fetch what changed since.
+func (s *OneDriveStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.syncDelta(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var files []FileInfo
+	for id, node := range s.items {
+		if !node.file {
+			continue
+		}
+		key, ok := s.path(id)
+		if !ok || strings.HasPrefix(key, oneDriveMetadataDir+"/") || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		info := node.info
+		info.Key = key
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Key < files[j].Key })
+	return files, nil
+}
+
+// syncDelta applies the changes to the folder since the last delta query. An expired
+// delta link starts over with the whole folder.
+func (s *OneDriveStorage) syncDelta(ctx context.Context) error {
+	next := s.deltaLink
+	if next == "" {
+		next = s.folderURL() + "/delta"
+	}
+	for next != "" {
+		var page struct {
+			Value     []oneDriveItem `json:"value"`
+			NextLink  string         `json:"@odata.nextLink"`
+			DeltaLink string         `json:"@odata.deltaLink"`
+		}
+		err := s.getJSON(ctx, next, &page)
+		var graphErr *oneDriveError
+		if errors.As(err, &graphErr) && graphErr.status == http.StatusGone && s.deltaLink != "" {
+			s.items = make(map[string]oneDriveNode)
+			s.deltaLink = ""
+			next = s.folderURL() + "/delta"
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, item := range page.Value {
+			if item.Deleted != nil {
+				delete(s.items, item.ID)
+				continue
+			}
+			s.items[item.ID] = oneDriveNode{
+				name:   item.Name,
+				parent: item.Parent.ID,
+				file:   item.File != nil,
+				info:   FileInfo{Size: item.Size, LastModified: item.LastModified, ETag: item.ETag},
+			}
+		}
+		next = page.NextLink
+		if next == "" {
+			s.deltaLink = page.DeltaLink
+		}
+	}
+	return nil
+}
+
+// path returns the key of an item from the chain of its parents up to the folder. Delta
+// responses carry no paths, as renaming a folder does not list its contents again.
+func (s *OneDriveStorage) path(id string) (string, bool) {
+	var names []string
+	for id != s.rootID {
+		node, ok := s.items[id]
+		if !ok || len(names) > 256 {
+			return "", false
+		}
+		names = append(names, node.name)
+		id = node.parent
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, "/"), true
+}
+
+// FileExists checks if a file exists in OneDrive
+func (s *OneDriveStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	var item oneDriveItem
+	err := s.getJSON(ctx, s.itemURL(key), &item)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", key, err)
+	}
+	return item.File != nil, nil
+}
+
+// GetFileInfo returns the information and metadata of a file in OneDrive
+func (s *OneDriveStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	var item oneDriveItem
+	if err := s.getJSON(ctx, s.itemURL(key), &item); err != nil {
+		return FileInfo{}, nil, fmt.Errorf("failed to get info of %s: %w", key, err)
+	}
+	if item.File == nil {
+		return FileInfo{}, nil, fmt.Errorf("failed to get info of %s: %w", key, ErrNotFound)
+	}
+
+	metadata, err := s.metadata(ctx, key)
+	if err != nil {
+		return FileInfo{}, nil, err
+	}
+	return FileInfo{Key: key, Size: item.Size, LastModified: item.LastModified, ETag: item.ETag}, metadata, nil
+}
+
+// oneDriveError is an error response of Graph
+type oneDriveError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *oneDriveError) Error() string {
+	if e.code == "" {
+		return fmt.Sprintf("OneDrive returned %d", e.status)
+	}
+	return fmt.Sprintf("OneDrive returned %d: %s: %s", e.status, e.code, e.message)
+}
+
+// Unwrap makes missing items match ErrNotFound
+func (e *oneDriveError) Unwrap() error {
+	if e.status == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// getJSON decodes the item or page at address into out
+func (s *OneDriveStorage) getJSON(ctx context.Context, address string, out interface{}) error {
+	return s.doJSON(ctx, s.api, http.MethodGet, address, nil, 0, nil, out)
+}
+
+// doJSON sends a request and decodes its response into out, when out is not nil
+func (s *OneDriveStorage) doJSON(ctx context.Context, client *http.Client, method, address string, body io.Reader, size int64, header http.Header, out interface{}) error {
+	resp, err := s.send(ctx, client, method, address, body, size, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid OneDrive response: %w", err)
+	}
+	return nil
+}
+
+// send sends a request, turning error responses into a oneDriveError
+func (s *OneDriveStorage) send(ctx context.Context, client *http.Client, method, address string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, address, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+		if method == http.MethodPost {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	var reply struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&reply)
+	return nil, &oneDriveError{status: resp.StatusCode, code: reply.Error.Code, message: reply.Error.Message}
+}
+
+// sizedReader returns reader with the number of bytes left in it. Readers that cannot seek
+// are read up to limit into memory, and spooled to a temporary file past it.
+func sizedReader(reader io.Reader, limit int64) (io.Reader, int64, func(), error) {
+	if seeker, ok := reader.(io.Seeker); ok {
+		current, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			end, err := seeker.Seek(0, io.SeekEnd)
+			if err == nil {
+				if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+					return nil, 0, nil, err
+				}
+				return reader, end - current, func() {}, nil
+			}
+		}
+	}
+
+	var head bytes.Buffer
+	if _, err := io.CopyN(&head, reader, limit+1); err == io.EOF {
+		return &head, int64(head.Len()), func() {}, nil
+	} else if err != nil {
+		return nil, 0, nil, err
+	}
+
+	spool, err := os.CreateTemp("", "sync-manager-onedrive-*")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	cleanup := func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+	size, err := io.Copy(spool, io.MultiReader(&head, reader))
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	return spool, size, cleanup, nil
+}
+
+// savingTokenSource refreshes the sign-in and keeps the refreshed tokens in the token file,
+// as Microsoft replaces the refresh token on every refresh
+type savingTokenSource struct {
+	path    string
+	refresh string
+	next    oauth2.TokenSource
+	mu      sync.Mutex
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.next.Token()
+	if err != nil {
+		return nil, fmt.Errorf("OneDrive sign-in expired, sign in again with storage-login: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.RefreshToken != s.refresh {
+		if err := saveOneDriveToken(s.path, token); err != nil {
+			log.Warn().Err(err).Msg("Failed to keep the refreshed OneDrive sign-in")
+		}
+		s.refresh = token.RefreshToken
+	}
+	return token, nil
+}
+
+// loadOneDriveToken reads the sign-in kept in path
+func loadOneDriveToken(path string) (*oauth2.Token, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("OneDrive is not signed in: run sync-manager storage-login first")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OneDrive sign-in: %w", err)
+	}
+
+	token := &oauth2.Token{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, fmt.Errorf("invalid OneDrive sign-in in %s: %w", path, err)
+	}
+	return token, nil
+}
+
+// saveOneDriveToken keeps a sign-in in path, readable only by the user
+func saveOneDriveToken(path string, token *oauth2.Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to encode OneDrive sign-in: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to save OneDrive sign-in: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save OneDrive sign-in: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// fakeGraph serves the part of Microsoft Graph the OneDrive storage uses, for an app folder
+type fakeGraph struct {
+	t        *testing.T
+	server   *httptest.Server
+	items    map[string]*fakeDriveItem // By path in the app folder
+	changes  []fakeDriveItem           // Every change, for delta links
+	sessions map[string]*fakeUploadSession
+	deltas   int  // Delta requests that started over
+	expire   bool // Answer the next delta link with 410 Gone
+	mu       sync.Mutex
+}
+
+type fakeDriveItem struct {
+	path     string
+	data     []byte
+	folder   bool
+	deleted  bool
+	modified time.Time
+}
+
+type fakeUploadSession struct {
+	path string
+	data []byte
+}
+
+func newFakeGraph(t *testing.T) *fakeGraph {
+	g := &fakeGraph{t: t, items: make(map[string]*fakeDriveItem), sessions: make(map[string]*fakeUploadSession)}
+	g.server = httptest.NewServer(http.HandlerFunc(g.serve))
+	t.Cleanup(g.server.Close)
+	return g
+}
+
+func (g *fakeGraph) serve(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Upload session and download URLs are pre-authenticated, the rest needs the token
+	signedIn := r.Header.Get("Authorization") == "Bearer access-1"
+	preAuthenticated := strings.HasPrefix(r.URL.Path, "/upload/") || strings.HasPrefix(r.URL.Path, "/download/")
+	if signedIn == preAuthenticated {
+		http.Error(w, "unexpected authorization", http.StatusUnauthorized)
+		return
+	}
+
+	const approot = "/v1.0/me/drive/special/approot"
+	switch {
+	case r.URL.Path == approot:
+		g.writeJSON(w, http.StatusOK, map[string]string{"id": "approot"})
+	case r.URL.Path == approot+"/delta":
+		g.delta(w, r)
+	case strings.HasPrefix(r.URL.Path, approot+":/"):
+		item, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, approot+":/"), ":")
+		g.item(w, r, item, action)
+	case strings.HasPrefix(r.URL.Path, "/upload/"):
+		g.uploadFragment(w, r, strings.TrimPrefix(r.URL.Path, "/upload/"))
+	case strings.HasPrefix(r.URL.Path, "/download/"):
+		w.Write(g.items[strings.TrimPrefix(r.URL.Path, "/download/")].data)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (g *fakeGraph) item(w http.ResponseWriter, r *http.Request, key, action string) {
+	item := g.items[key]
+	switch {
+	case r.Method == http.MethodPut && action == "/content":
+		data, _ := io.ReadAll(r.Body)
+		g.writeJSON(w, http.StatusCreated, g.put(key, data))
+	case r.Method == http.MethodPost && action == "/createUploadSession":
+		id := strconv.Itoa(len(g.sessions) + 1)
+		g.sessions[id] = &fakeUploadSession{path: key}
+		g.writeJSON(w, http.StatusOK, map[string]string{"uploadUrl": g.server.URL + "/upload/" + id})
+	case item == nil || item.deleted:
+		g.writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": map[string]string{"code": "itemNotFound", "message": "The resource could not be found."}})
+	case r.Method == http.MethodGet && action == "":
+		g.writeJSON(w, http.StatusOK, g.json(item))
+	case r.Method == http.MethodGet && action == "/content":
+		http.Redirect(w, r, g.server.URL+"/download/"+key, http.StatusFound)
+	case r.Method == http.MethodDelete && action == "":
+		item.deleted = true
+		g.changes = append(g.changes, *item)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (g *fakeGraph) uploadFragment(w http.ResponseWriter, r *http.Request, id string) {
+	session := g.sessions[id]
+	var start, end, total int
+	fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total)
+	data, _ := io.ReadAll(r.Body)
+	if start != len(session.data) || end-start+1 != len(data) {
+		http.Error(w, "fragment out of order", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	session.data = append(session.data, data...)
+	if len(session.data) < total {
+		g.writeJSON(w, http.StatusAccepted, map[string]interface{}{"nextExpectedRanges": []string{fmt.Sprintf("%d-", len(session.data))}})
+		return
+	}
+	g.writeJSON(w, http.StatusCreated, g.put(session.path, session.data))
+}
+
+// delta lists every item on the first call, three per page, and the changes since a delta link
+func (g *fakeGraph) delta(w http.ResponseWriter, r *http.Request) {
+	var items []fakeDriveItem
+	next := ""
+	switch token := r.URL.Query().Get("token"); {
+	case token != "" && g.expire:
+		g.expire = false
+		g.writeJSON(w, http.StatusGone, map[string]interface{}{"error": map[string]string{"code": "resyncRequired"}})
+		return
+	case token != "":
+		seen, _ := strconv.Atoi(token)
+		items = g.changes[seen:]
+	default:
+		var paths []string
+		for key, item := range g.items {
+			if !item.deleted {
+				paths = append(paths, key)
+			}
+		}
+		sort.Strings(paths)
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		if skip == 0 {
+			g.deltas++
+		}
+		for _, key := range paths[skip:min(skip+3, len(paths))] {
+			items = append(items, *g.items[key])
+		}
+		if skip+3 < len(paths) {
+			next = fmt.Sprintf("%s/v1.0/me/drive/special/approot/delta?skip=%d", g.server.URL, skip+3)
+		}
+	}
+
+	page := map[string]interface{}{"value": []interface{}{}}
+	for i := range items {
+		page["value"] = append(page["value"].([]interface{}), g.json(&items[i]))
+	}
+	if next != "" {
+		page["@odata.nextLink"] = next
+	} else {
+		page["@odata.deltaLink"] = fmt.Sprintf("%s/v1.0/me/drive/special/approot/delta?token=%d", g.server.URL, len(g.changes))
+	}
+	g.writeJSON(w, http.StatusOK, page)
+}
+
+// put stores a file, creating the folders above it
+func (g *fakeGraph) put(key string, data []byte) map[string]interface{} {
+	for dir := path.Dir(key); dir != "."; dir = path.Dir(dir) {
+		if g.items[dir] == nil || g.items[dir].deleted {
+			g.items[dir] = &fakeDriveItem{path: dir, folder: true}
+			g.changes = append(g.changes, *g.items[dir])
+		}
+	}
+	g.items[key] = &fakeDriveItem{path: key, data: data, modified: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	g.changes = append(g.changes, *g.items[key])
+	return g.json(g.items[key])
+}
+
+// json returns an item as Graph does, its parent by ID only
+func (g *fakeGraph) json(item *fakeDriveItem) map[string]interface{} {
+	parent := "approot"
+	if dir := path.Dir(item.path); dir != "." {
+		parent = "id:" + dir
+	}
+	out := map[string]interface{}{
+		"id":                   "id:" + item.path,
+		"name":                 path.Base(item.path),
+		"size":                 len(item.data),
+		"eTag":                 fmt.Sprintf("etag-%d", len(item.data)),
+		"lastModifiedDateTime": item.modified,
+		"parentReference":      map[string]string{"id": parent},
+	}
+	if item.deleted {
+		out["deleted"] = map[string]string{"state": "deleted"}
+	} else if item.folder {
+		out["folder"] = map[string]int{"childCount": 1}
+	} else {
+		out["file"] = map[string]string{"mimeType": "application/octet-stream"}
+	}
+	return out
+}
+
+func (g *fakeGraph) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func newTestOneDrive(t *testing.T, graph *fakeGraph) *OneDriveStorage {
+	tokenFile := filepath.Join(t.TempDir(), "onedrive.json")
+	assert.NoError(t, saveOneDriveToken(tokenFile, &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(time.Hour)}))
+	store, err := NewOneDriveStorage(&OneDriveConfig{ClientID: "app", AppFolder: true, TokenFile: tokenFile, GraphURL: graph.server.URL + "/v1.0"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return store
+}
+
+func TestOneDriveStorage(t *testing.T) {
+	ctx := context.Background()
+	graph := newFakeGraph(t)
+	store := newTestOneDrive(t, graph)
+	store.chunkSize = 1 << 20
+	assert.Equal(t, ProviderOneDrive, store.GetProvider())
+
+	// Small files go up in one request, larger ones in an upload session, even when unseekable
+	_, err := store.UploadFile(ctx, "docs/a b.txt", strings.NewReader("small"), map[string]string{"hash": "1", MetadataStorageClass: "COOL"})
+	assert.NoError(t, err)
+	large := bytes.Repeat([]byte("0123456789abcdef"), (oneDriveSimpleUploadLimit+(1<<19))/16)
+	_, err = store.UploadFile(ctx, "docs/nested/large.bin", bytes.NewBuffer(large), map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, large, graph.items["docs/nested/large.bin"].data)
+	assert.Len(t, graph.sessions, 1)
+
+	var downloaded bytes.Buffer
+	metadata, err := store.DownloadFile(ctx, "docs/a b.txt", &downloaded, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "small", downloaded.String())
+	assert.Equal(t, map[string]string{"hash": "1"}, metadata)
+	info, metadata, err := store.GetFileInfo(ctx, "docs/nested/large.bin")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(large)), info.Size)
+	assert.Empty(t, metadata)
+
+	// Listings come from delta queries, paged, and leave the metadata out
+	keys := func(prefix string) []string {
+		files, err := store.ListFiles(ctx, prefix)
+		assert.NoError(t, err)
+		var keys []string
+		for _, file := range files {
+			keys = append(keys, file.Key)
+		}
+		return keys
+	}
+	assert.Equal(t, []string{"docs/a b.txt", "docs/nested/large.bin"}, keys(""))
+	assert.Equal(t, []string{"docs/nested/large.bin"}, keys("docs/nested/"))
+
+	// Later listings only apply what changed, and start over when the delta link expired
+	assert.NoError(t, store.DeleteFile(ctx, "docs/a b.txt"))
+	_, err = store.UploadFile(ctx, "photos/c.jpg", strings.NewReader("jpeg"), map[string]string{"hash": "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/nested/large.bin", "photos/c.jpg"}, keys(""))
+	assert.Equal(t, 1, graph.deltas)
+	graph.expire = true
+	assert.Equal(t, []string{"docs/nested/large.bin", "photos/c.jpg"}, keys(""))
+	assert.Equal(t, 2, graph.deltas)
+
+	exists, err := store.FileExists(ctx, "docs/a b.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	_, _, err = store.GetFileInfo(ctx, "docs/a b.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.DeleteFile(ctx, "docs/a b.txt"))
+}
+
+func TestOneDriveLogin(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests = append(requests, r.URL.Path+" "+r.Form.Get("scope"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/consumers/oauth2/v2.0/devicecode":
+			fmt.Fprint(w, `{"device_code":"device-1","user_code":"ABCD-1234","verification_uri":"https://microsoft.com/devicelogin","expires_in":900,"interval":1}`)
+		case "/consumers/oauth2/v2.0/token":
+			fmt.Fprint(w, `{"access_token":"access-1","refresh_token":"refresh-1","token_type":"Bearer","expires_in":3600}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &OneDriveConfig{ClientID: "app", Tenant: "consumers", AppFolder: true, TokenFile: filepath.Join(t.TempDir(), "onedrive", "work.json"), LoginURL: server.URL}
+	var shown string
+	err := OneDriveLogin(context.Background(), cfg, func(verificationURI, userCode string) {
+		shown = verificationURI + " " + userCode
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://microsoft.com/devicelogin ABCD-1234", shown)
+	assert.Equal(t, []string{
+		"/consumers/oauth2/v2.0/devicecode Files.ReadWrite.AppFolder offline_access",
+		"/consumers/oauth2/v2.0/token Files.ReadWrite.AppFolder offline_access",
+	}, requests)
+
+	token, err := loadOneDriveToken(cfg.TokenFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh-1", token.RefreshToken)
+}
@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// PluginProtocolVersion is the version of the protocol spoken with storage plugins. Plugins
+// answer Storage.Configure with the version they speak, and are refused when it differs.
+//
+// A plugin is an executable serving JSON-RPC 1.0 on its standard input and output, as
+// net/rpc/jsonrpc does, with these methods of the Storage service:
+//
+//	Configure(PluginConfigureArgs) PluginConfigureReply  called once, before any other
+//	StartUpload(PluginUploadArgs) PluginHandle
+//	WriteUpload(PluginChunk) bool                        Data holds the next bytes of the file
+//	FinishUpload(PluginFinishArgs) string                returns the version ID, empty if none
+//	StartDownload(PluginDownloadArgs) PluginHandle
+//	ReadDownload(PluginHandle) PluginChunk               EOF is set with the metadata once done
+//	CloseDownload(PluginHandle) bool
+//	Delete(PluginKeyArgs) bool
+//	List(PluginKeyArgs) []FileInfo                        Key holds the prefix
+//	Exists(PluginKeyArgs) bool
+//	Stat(PluginKeyArgs) PluginStatReply
+//
+// Byte slices travel as base64 strings. A missing file fails with an error starting with
+// "not found:". Go plugins only need to implement Storage and call ServePlugin.
+const PluginProtocolVersion = 1
+
+// pluginNotFound starts the errors of plugins for files that do not exist
+const pluginNotFound = "not found:"
+
+// pluginChunkSize is the most data a single upload or download call carries
+const pluginChunkSize = 1 << 20
+
+// PluginConfigureArgs carries the settings of the target to a plugin
+type PluginConfigureArgs struct {
+	Version  int
+	Settings map[string]string
+}
+
+// PluginConfigureReply tells which protocol version a plugin speaks and the provider it serves
+type PluginConfigureReply struct {
+	Version  int
+	Provider string
+}
+
+// PluginUploadArgs starts an upload
+type PluginUploadArgs struct {
+	Key      string
+	Metadata map[string]string
+}
+
+// PluginDownloadArgs starts a download
+type PluginDownloadArgs struct {
+	Key       string
+	VersionID string
+}
+
+// PluginHandle identifies an upload or download in progress
+type PluginHandle struct {
+	Handle int64
+}
+
+// PluginChunk is a piece of the contents of a file. The last chunk of a download has EOF set
+// and carries the metadata of the file.
+type PluginChunk struct {
+	Handle   int64
+	Data     []byte
+	EOF      bool
+	Metadata map[string]string
+}
+
+// PluginFinishArgs completes an upload, or drops it when Abort is set
+type PluginFinishArgs struct {
+	Handle int64
+	Abort  bool
+}
+
+// PluginKeyArgs names a file, or a prefix for List
+type PluginKeyArgs struct {
+	Key string
+}
+
+// PluginStatReply is the information and metadata of a file
+type PluginStatReply struct {
+	Info     FileInfo
+	Metadata map[string]string
+}
+
+// PluginStorage is a storage served by a plugin process. The process is started when the
+// storage is created and stops when it is closed, or when the agent exits.
+type PluginStorage struct {
+	name     string
+	provider StorageProvider
+	cmd      *exec.Cmd
+	client   *rpc.Client
+	closed   sync.Once
+}
+
+// NewPluginStorage starts the plugin at path and configures it with the settings of a target
+func NewPluginStorage(name, path string, settings map[string]string) (*PluginStorage, error) {
+	cmd := exec.Command(path)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start storage plugin %s: %w", name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start storage plugin %s: %w", name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start storage plugin %s: %w", name, err)
+	}
+
+	s, err := newPluginClient(name, pluginConn{stdout, stdin}, settings)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	s.cmd = cmd
+	return s, nil
+}
+
+// newPluginClient configures the plugin on the other end of conn
+func newPluginClient(name string, conn io.ReadWriteCloser, settings map[string]string) (*PluginStorage, error) {
+	s := &PluginStorage{name: name, client: jsonrpc.NewClient(conn)}
+	var reply PluginConfigureReply
+	if err := s.call(context.Background(), "Configure", PluginConfigureArgs{Version: PluginProtocolVersion, Settings: settings}, &reply); err != nil {
+		s.client.Close()
+		return nil, fmt.Errorf("failed to configure storage plugin %s: %w", name, err)
+	}
+	if reply.Version != PluginProtocolVersion {
+		s.client.Close()
+		return nil, fmt.Errorf("storage plugin %s speaks protocol version %d, expected %d", name, reply.Version, PluginProtocolVersion)
+	}
+	s.provider = StorageProvider(reply.Provider)
+	if s.provider == "" {
+		s.provider = StorageProvider(name)
+	}
+	return s, nil
+}
+
+// Close stops the plugin
+func (s *PluginStorage) Close() error {
+	var err error
+	s.closed.Do(func() {
+		err = s.client.Close()
+		if s.cmd != nil {
+			// Closing stdin ends the plugin; its exit status no longer matters
+			s.cmd.Wait()
+		}
+	})
+	return err
+}
+
+// UploadFile streams a file to the plugin in chunks
+func (s *PluginStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	var upload PluginHandle
+	if err := s.call(ctx, "StartUpload", PluginUploadArgs{Key: key, Metadata: metadata}, &upload); err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+
+	buf := make([]byte, pluginChunkSize)
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			var ok bool
+			if callErr := s.call(ctx, "WriteUpload", PluginChunk{Handle: upload.Handle, Data: buf[:n]}, &ok); callErr != nil {
+				s.abortUpload(upload)
+				return "", fmt.Errorf("failed to upload %s: %w", key, callErr)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			s.abortUpload(upload)
+			return "", fmt.Errorf("failed to read %s: %w", key, err)
+		}
+	}
+
+	var versionID string
+	if err := s.call(ctx, "FinishUpload", PluginFinishArgs{Handle: upload.Handle}, &versionID); err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return versionID, nil
+}
+
+// abortUpload drops an upload that failed halfway
+func (s *PluginStorage) abortUpload(upload PluginHandle) {
+	var versionID string
+	s.call(context.Background(), "FinishUpload", PluginFinishArgs{Handle: upload.Handle, Abort: true}, &versionID)
+}
+
+// DownloadFile streams a file from the plugin in chunks
+func (s *PluginStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	var download PluginHandle
+	if err := s.call(ctx, "StartDownload", PluginDownloadArgs{Key: key, VersionID: versionID}, &download); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+
+	for {
+		var chunk PluginChunk
+		if err := s.call(ctx, "ReadDownload", download, &chunk); err != nil {
+			s.closeDownload(download)
+			return nil, fmt.Errorf("failed to download %s: %w", key, err)
+		}
+		if _, err := writer.Write(chunk.Data); err != nil {
+			s.closeDownload(download)
+			return nil, fmt.Errorf("failed to write %s: %w", key, err)
+		}
+		if chunk.EOF {
+			return chunk.Metadata, nil
+		}
+	}
+}
+
+// closeDownload drops a download that is not read to the end
+func (s *PluginStorage) closeDownload(download PluginHandle) {
+	var ok bool
+	s.call(context.Background(), "CloseDownload", download, &ok)
+}
+
+// DeleteFile deletes a file through the plugin
+func (s *PluginStorage) DeleteFile(ctx context.Context, key string) error {
+	var ok bool
+	if err := s.call(ctx, "Delete", PluginKeyArgs{Key: key}, &ok); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// ListFiles lists the files under a prefix through the plugin
+func (s *PluginStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	var files []FileInfo
+	if err := s.call(ctx, "List", PluginKeyArgs{Key: prefix}, &files); err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	return files, nil
+}
+
+// FileExists checks if a file exists through the plugin
+func (s *PluginStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	if err := s.call(ctx, "Exists", PluginKeyArgs{Key: key}, &exists); err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", key, err)
+	}
+	return exists, nil
+}
+
+// GetFileInfo returns the information and metadata of a file through the plugin
+func (s *PluginStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	var reply PluginStatReply
+	if err := s.call(ctx, "Stat", PluginKeyArgs{Key: key}, &reply); err != nil {
+		return FileInfo{}, nil, fmt.Errorf("failed to get info of %s: %w", key, err)
+	}
+	return reply.Info, reply.Metadata, nil
+}
+
+// GetProvider returns the provider the plugin reported, or its name
+func (s *PluginStorage) GetProvider() StorageProvider {
+	return s.provider
+}
+
+// call calls a method of the plugin, giving up when ctx is done. Errors for missing files
+// wrap ErrNotFound.
+func (s *PluginStorage) call(ctx context.Context, method string, args, reply interface{}) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case call := <-s.client.Go("Storage."+method, args, reply, make(chan *rpc.Call, 1)).Done:
+		var serverErr rpc.ServerError
+		if errors.As(call.Error, &serverErr) {
+			if message, ok := strings.CutPrefix(string(serverErr), pluginNotFound); ok {
+				return fmt.Errorf("%w: %s", ErrNotFound, strings.TrimSpace(message))
+			}
+			return fmt.Errorf("storage plugin %s: %s", s.name, serverErr)
+		}
+		if errors.Is(call.Error, rpc.ErrShutdown) || errors.Is(call.Error, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("storage plugin %s stopped", s.name)
+		}
+		return call.Error
+	}
+}
+
+// pluginConn joins the output and input of a plugin process into one connection
+type pluginConn struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+// Close closes both ends; the plugin exits once its input is closed
+func (c pluginConn) Close() error {
+	err := c.WriteCloser.Close()
+	c.ReadCloser.Close()
+	return err
+}
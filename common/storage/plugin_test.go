@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain lets the test binary stand in for a storage plugin, serving in-memory storage
+func TestMain(m *testing.M) {
+	if os.Getenv("SYNC_MANAGER_TEST_PLUGIN") != "" {
+		err := ServePlugin(func(settings map[string]string) (Storage, error) {
+			if settings["fail"] != "" {
+				return nil, errors.New(settings["fail"])
+			}
+			return NewMemoryStorage(&MemoryConfig{}), nil
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestPluginStorage(t *testing.T) {
+	ctx := context.Background()
+	agent, plugin := net.Pipe()
+	go servePlugin(plugin, func(settings map[string]string) (Storage, error) {
+		return NewMemoryStorage(&MemoryConfig{Name: settings["bucket"]}), nil
+	})
+	store, err := newPluginClient("memtest", agent, map[string]string{"bucket": t.Name()})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+	assert.Equal(t, ProviderMemory, store.GetProvider())
+
+	// Files larger than a chunk go through in several calls, both ways
+	content := strings.Repeat("sync-manager ", pluginChunkSize/5)
+	versionID, err := store.UploadFile(ctx, "docs/big.txt", strings.NewReader(content), map[string]string{"hash": "abc"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, versionID)
+	var downloaded bytes.Buffer
+	metadata, err := store.DownloadFile(ctx, "docs/big.txt", &downloaded, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", metadata["hash"])
+	assert.Equal(t, content, downloaded.String())
+
+	info, metadata, err := store.GetFileInfo(ctx, "docs/big.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), info.Size)
+	assert.Equal(t, "abc", metadata["hash"])
+	files, err := store.ListFiles(ctx, "docs/")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// Missing files keep wrapping ErrNotFound across the process boundary
+	assert.NoError(t, store.DeleteFile(ctx, "docs/big.txt"))
+	exists, err := store.FileExists(ctx, "docs/big.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	_, _, err = store.GetFileInfo(ctx, "docs/big.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.DownloadFile(ctx, "docs/big.txt", &downloaded, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStorageFactoryStartsPlugins(t *testing.T) {
+	executable, err := os.Executable()
+	if !assert.NoError(t, err) {
+		return
+	}
+	dir := t.TempDir()
+	name := common_config.PluginPrefix + "memtest"
+	if filepath.Ext(executable) == ".exe" {
+		name += ".exe"
+	}
+	if err := os.Symlink(executable, filepath.Join(dir, name)); err != nil {
+		t.Skipf("cannot link the test binary: %v", err)
+	}
+	t.Setenv("SYNC_MANAGER_TEST_PLUGIN", "1")
+
+	cfg := common_config.DefaultConfig()
+	cfg.Plugins.Dir = dir
+	cfg.Targets = []common_config.StorageTarget{{Name: "default", Type: "memtest"}}
+	store, err := StorageFactory(cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = store.UploadFile(context.Background(), "docs/a.txt", strings.NewReader("a"), map[string]string{})
+	assert.NoError(t, err)
+	exists, err := store.FileExists(context.Background(), "docs/a.txt")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	for inner := Unwrap(store); inner != nil; inner = Unwrap(store) {
+		store = inner
+	}
+	plugin, ok := store.(*PluginStorage)
+	if assert.True(t, ok) {
+		assert.NoError(t, plugin.Close())
+	}
+
+	// Settings the plugin refuses fail the storage, and unknown types name the directory
+	cfg.Targets[0].Plugin = map[string]string{"fail": "missing token"}
+	_, err = StorageFactory(cfg)
+	assert.ErrorContains(t, err, "missing token")
+	cfg.Targets[0].Type = "dropbox"
+	_, err = StorageFactory(cfg)
+	assert.ErrorContains(t, err, "no storage plugin dropbox in "+dir)
+}
@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"sync"
+)
+
+// PluginOpener creates the storage a plugin serves from the settings of its target
+type PluginOpener func(settings map[string]string) (Storage, error)
+
+// ServePlugin serves the storage opened by open on the standard input and output, until the
+// agent closes them. It is all the main function of a Go storage plugin has to call:
+//
+//	func main() {
+//		if err := storage.ServePlugin(openDropbox); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// Anything the plugin logs must go to the standard error, as the output carries the protocol.
+func ServePlugin(open PluginOpener) error {
+	return servePlugin(pluginConn{os.Stdin, os.Stdout}, open)
+}
+
+// servePlugin serves the protocol on conn until it is closed
+func servePlugin(conn io.ReadWriteCloser, open PluginOpener) error {
+	server := rpc.NewServer()
+	service := &pluginService{
+		open:      open,
+		uploads:   make(map[int64]*pluginUpload),
+		downloads: make(map[int64]*pluginDownload),
+	}
+	if err := server.RegisterName("Storage", service); err != nil {
+		return fmt.Errorf("failed to register storage plugin: %w", err)
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	service.close()
+	return nil
+}
+
+// pluginService answers the calls of the agent with the storage of the plugin
+type pluginService struct {
+	open       PluginOpener
+	storage    Storage
+	uploads    map[int64]*pluginUpload
+	downloads  map[int64]*pluginDownload
+	nextHandle int64
+	mu         sync.Mutex
+}
+
+// pluginUpload feeds the chunks of an upload to the UploadFile call of the storage
+type pluginUpload struct {
+	writer *io.PipeWriter
+	done   chan struct{}
+	id     string
+	err    error
+}
+
+// pluginDownload hands out the output of the DownloadFile call of the storage in chunks
+type pluginDownload struct {
+	reader   *io.PipeReader
+	done     chan struct{}
+	metadata map[string]string
+	err      error
+}
+
+// Configure opens the storage with the settings of the target
+func (p *pluginService) Configure(args PluginConfigureArgs, reply *PluginConfigureReply) error {
+	if args.Version != PluginProtocolVersion {
+		reply.Version = PluginProtocolVersion
+		return nil
+	}
+	store, err := p.open(args.Settings)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.storage = store
+	p.mu.Unlock()
+	reply.Version = PluginProtocolVersion
+	reply.Provider = string(store.GetProvider())
+	return nil
+}
+
+// StartUpload starts uploading a file whose contents follow in WriteUpload calls
+func (p *pluginService) StartUpload(args PluginUploadArgs, reply *PluginHandle) error {
+	store, err := p.backend()
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+	upload := &pluginUpload{writer: writer, done: make(chan struct{})}
+	go func() {
+		defer close(upload.done)
+		upload.id, upload.err = store.UploadFile(context.Background(), args.Key, reader, args.Metadata)
+		reader.CloseWithError(upload.err)
+	}()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextHandle++
+	p.uploads[p.nextHandle] = upload
+	reply.Handle = p.nextHandle
+	return nil
+}
+
+// WriteUpload passes the next chunk of a file to its upload
+func (p *pluginService) WriteUpload(args PluginChunk, reply *bool) error {
+	p.mu.Lock()
+	upload, ok := p.uploads[args.Handle]
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown upload %d", args.Handle)
+	}
+	if _, err := upload.writer.Write(args.Data); err != nil {
+		return pluginError(err)
+	}
+	*reply = true
+	return nil
+}
+
+// FinishUpload waits for an upload to complete, or aborts it
+func (p *pluginService) FinishUpload(args PluginFinishArgs, reply *string) error {
+	p.mu.Lock()
+	upload, ok := p.uploads[args.Handle]
+	delete(p.uploads, args.Handle)
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown upload %d", args.Handle)
+	}
+
+	if args.Abort {
+		upload.writer.CloseWithError(errors.New("upload aborted"))
+	} else {
+		upload.writer.Close()
+	}
+	<-upload.done
+	if upload.err != nil {
+		return pluginError(upload.err)
+	}
+	*reply = upload.id
+	return nil
+}
+
+// StartDownload starts downloading a file whose contents are read with ReadDownload calls
+func (p *pluginService) StartDownload(args PluginDownloadArgs, reply *PluginHandle) error {
+	store, err := p.backend()
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+	download := &pluginDownload{reader: reader, done: make(chan struct{})}
+	go func() {
+		defer close(download.done)
+		download.metadata, download.err = store.DownloadFile(context.Background(), args.Key, writer, args.VersionID)
+		writer.CloseWithError(download.err)
+	}()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextHandle++
+	p.downloads[p.nextHandle] = download
+	reply.Handle = p.nextHandle
+	return nil
+}
+
+// ReadDownload returns the next chunk of a download, with the metadata once it is complete
+func (p *pluginService) ReadDownload(args PluginHandle, reply *PluginChunk) error {
+	p.mu.Lock()
+	download, ok := p.downloads[args.Handle]
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown download %d", args.Handle)
+	}
+
+	var buf bytes.Buffer
+	_, err := io.CopyN(&buf, download.reader, pluginChunkSize)
+	reply.Handle = args.Handle
+	reply.Data = buf.Bytes()
+	if err == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	delete(p.downloads, args.Handle)
+	p.mu.Unlock()
+	<-download.done
+	if download.err != nil {
+		return pluginError(download.err)
+	}
+	if err != io.EOF {
+		return pluginError(err)
+	}
+	reply.EOF = true
+	reply.Metadata = download.metadata
+	return nil
+}
+
+// CloseDownload drops a download that is not read to the end
+func (p *pluginService) CloseDownload(args PluginHandle, reply *bool) error {
+	p.mu.Lock()
+	download, ok := p.downloads[args.Handle]
+	delete(p.downloads, args.Handle)
+	p.mu.Unlock()
+	if ok {
+		download.reader.Close()
+		<-download.done
+	}
+	*reply = true
+	return nil
+}
+
+// Delete deletes a file
+func (p *pluginService) Delete(args PluginKeyArgs, reply *bool) error {
+	store, err := p.backend()
+	if err != nil {
+		return err
+	}
+	if err := store.DeleteFile(context.Background(), args.Key); err != nil {
+		return pluginError(err)
+	}
+	*reply = true
+	return nil
+}
+
+// List lists the files under a prefix
+func (p *pluginService) List(args PluginKeyArgs, reply *[]FileInfo) error {
+	store, err := p.backend()
+	if err != nil {
+		return err
+	}
+	files, err := store.ListFiles(context.Background(), args.Key)
+	if err != nil {
+		return pluginError(err)
+	}
+	*reply = files
+	return nil
+}
+
+// Exists checks if a file exists
+func (p *pluginService) Exists(args PluginKeyArgs, reply *bool) error {
+	store, err := p.backend()
+	if err != nil {
+		return err
+	}
+	exists, err := store.FileExists(context.Background(), args.Key)
+	if err != nil {
+		return pluginError(err)
+	}
+	*reply = exists
+	return nil
+}
+
+// Stat returns the information and metadata of a file
+func (p *pluginService) Stat(args PluginKeyArgs, reply *PluginStatReply) error {
+	store, err := p.backend()
+	if err != nil {
+		return err
+	}
+	info, metadata, err := store.GetFileInfo(context.Background(), args.Key)
+	if err != nil {
+		return pluginError(err)
+	}
+	reply.Info = info
+	reply.Metadata = metadata
+	return nil
+}
+
+// backend returns the storage opened by Configure
+func (p *pluginService) backend() (Storage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.storage == nil {
+		return nil, errors.New("storage plugin is not configured")
+	}
+	return p.storage, nil
+}
+
+// close aborts the transfers left when the agent went away
+func (p *pluginService) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for handle, upload := range p.uploads {
+		upload.writer.CloseWithError(errors.New("upload aborted"))
+		delete(p.uploads, handle)
+	}
+	for handle, download := range p.downloads {
+		download.reader.Close()
+		delete(p.downloads, handle)
+	}
+}
+
+// pluginError marks the errors for missing files so the agent sees ErrNotFound
+func pluginError(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%s %w", pluginNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrRangeUnsupported is returned by a RangeDownloader whose backend cannot read part of
+// a file, which is then downloaded whole
+var ErrRangeUnsupported = errors.New("storage cannot download part of a file")
+
+// RangeDownloader is implemented by backends that can read part of a file, so large
+// files are downloaded in parallel chunks and failed chunks are fetched again alone
+type RangeDownloader interface {
+	// DownloadRange writes length bytes of the current version of a file, starting at offset, to writer.
+	// It fails with ErrRangeUnsupported when the backend cannot read ranges.
+	DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrDriveAbsent is returned, wrapped, by storage on a removable drive that is not mounted
+var ErrDriveAbsent = errors.New("drive is not mounted")
+
+// VolumeMarker is the file in the metadata directory of a local target holding the UUID the
+// drive was labelled with, for drives whose filesystem UUID cannot be read
+const VolumeMarker = "volume-id"
+
+// Detachable is implemented by backends on drives that can be unplugged. Requests to them
+// fail with ErrDriveAbsent while the drive is away, so transfers wait for it to come back.
+type Detachable interface {
+	// Present checks that the drive is mounted, failing with an error wrapping ErrDriveAbsent when not
+	Present() error
+}
+
+// Present checks that the drive of the storage holding key is mounted. Storage that cannot
+// be unplugged is always present.
+func Present(s Storage, key string) error {
+	for {
+		if router, ok := s.(*Router); ok {
+			s = router.route(key)
+			continue
+		}
+		if detachable, ok := s.(Detachable); ok {
+			return detachable.Present()
+		}
+		if s = Unwrap(s); s == nil {
+			return nil
+		}
+	}
+}
+
+// LabelVolume writes a new volume UUID to the drive mounted at rootDir and returns it, so a
+// target with that UUID only syncs to this drive
+func LabelVolume(rootDir string) (string, error) {
+	if err := checkDrive(rootDir, ""); err != nil {
+		return "", err
+	}
+	metadataDir := filepath.Join(rootDir, ".sync-manager")
+	if err := os.MkdirAll(metadataDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create metadata directory: %w", err)
+	}
+	id := uuid.New().String()
+	if err := os.WriteFile(filepath.Join(metadataDir, VolumeMarker), []byte(id+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write volume marker: %w", err)
+	}
+	return id, nil
+}
+
+// onSeparateVolume is separateVolume, replaced by tests whose directories are on the system disk
+var onSeparateVolume = separateVolume
+
+// checkDrive checks that rootDir is on a mounted drive, and on the volume with the given UUID
+// when one is set
+func checkDrive(rootDir, volumeUUID string) error {
+	info, err := os.Stat(rootDir)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("%w: %s does not exist", ErrDriveAbsent, rootDir)
+	}
+	separate, err := onSeparateVolume(rootDir)
+	if err != nil {
+		return fmt.Errorf("failed to check the drive of %s: %w", rootDir, err)
+	}
+	if !separate {
+		return fmt.Errorf("%w: %s is on the system disk", ErrDriveAbsent, rootDir)
+	}
+	if volumeUUID == "" {
+		return nil
+	}
+
+	if data, err := os.ReadFile(filepath.Join(rootDir, ".sync-manager", VolumeMarker)); err == nil && strings.EqualFold(strings.TrimSpace(string(data)), volumeUUID) {
+		return nil
+	}
+	if filesystemUUID(rootDir, volumeUUID) {
+		return nil
+	}
+	return fmt.Errorf("%w: the drive at %s is not volume %s", ErrDriveAbsent, rootDir, volumeUUID)
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemovableLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	mounted := false
+	onSeparateVolume = func(string) (bool, error) { return mounted, nil }
+	defer func() { onSeparateVolume = separateVolume }()
+
+	// Nothing is created on the mount point while the drive is away
+	root := filepath.Join(t.TempDir(), "usb")
+	local, err := NewLocalStorage(&LocalConfig{RootDir: root, Removable: true})
+	assert.NoError(t, err)
+	assert.NoDirExists(t, root)
+	_, err = local.FileExists(ctx, "docs/a.txt")
+	assert.ErrorIs(t, err, ErrDriveAbsent)
+	_, err = local.UploadFile(ctx, "docs/a.txt", bytes.NewReader([]byte("a")), map[string]string{})
+	assert.ErrorIs(t, err, ErrDriveAbsent)
+	_, err = LabelVolume(root)
+	assert.ErrorIs(t, err, ErrDriveAbsent)
+
+	// An empty mount point on the system disk is not the drive either
+	assert.NoError(t, os.Mkdir(root, 0755))
+	assert.ErrorContains(t, local.Present(), root+" is on the system disk")
+
+	mounted = true
+	assert.NoError(t, local.Present())
+	_, err = local.UploadFile(ctx, "docs/a.txt", bytes.NewReader([]byte("a")), map[string]string{})
+	assert.NoError(t, err)
+
+	// A volume UUID only matches the drive labelled with it
+	id, err := LabelVolume(root)
+	assert.NoError(t, err)
+	labelled, err := NewLocalStorage(NewLocalConfigFromCommon(&common_config.LocalConfig{RootDir: root, VolumeUUID: id}))
+	assert.NoError(t, err)
+	assert.NoError(t, labelled.Present())
+	other, err := NewLocalStorage(&LocalConfig{RootDir: root, Removable: true, VolumeUUID: "0f3a-55c1"})
+	assert.NoError(t, err)
+	assert.ErrorContains(t, other.Present(), "the drive at "+root+" is not volume 0f3a-55c1")
+
+	// Present looks through middlewares and routes the key to its target
+	router := NewRouter(map[string]Storage{
+		"default": NewMemoryStorage(&MemoryConfig{}),
+		"usb":     Chain(other, WithLogging(), WithCache(time.Minute, 10)),
+	}, "default", map[string]string{"docs": "usb"})
+	assert.ErrorIs(t, Present(router, "docs/"), ErrDriveAbsent)
+	assert.NoError(t, Present(router, "music/"))
+	assert.NoError(t, Present(labelled, "docs/"))
+
+	if runtime.GOOS != "windows" {
+		separate, err := separateVolume("/")
+		assert.NoError(t, err)
+		assert.False(t, separate)
+	}
+}
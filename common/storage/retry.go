@@ -0,0 +1,333 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetryPolicy controls how failed storage requests are retried
+type RetryPolicy struct {
+	MaxAttempts    int           // Attempts including the first one
+	InitialBackoff time.Duration // Wait before the first retry, doubled on each further retry
+	MaxBackoff     time.Duration // Longest wait between attempts
+}
+
+// WithRetry retries failed requests with exponential backoff. Uploads are only
+// retried when the reader can seek back to where it started, and downloads only
+// when nothing has been written yet.
+func WithRetry(policy RetryPolicy) Middleware {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		policy.MaxBackoff = policy.InitialBackoff
+	}
+
+	return func(next Storage) Storage {
+		return &retryStorage{next: next, policy: policy}
+	}
+}
+
+// retryStorage retries the requests of the wrapped storage
+type retryStorage struct {
+	next   Storage
+	policy RetryPolicy
+}
+
+// Unwrap returns the wrapped storage
+func (r *retryStorage) Unwrap() Storage {
+	return r.next
+}
+
+// GetProvider returns the provider of the wrapped storage
+func (r *retryStorage) GetProvider() StorageProvider {
+	return r.next.GetProvider()
+}
+
+// UploadFile uploads a file, rewinding the reader between attempts
+func (r *retryStorage) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	seeker, ok := reader.(io.Seeker)
+	if !ok {
+		return r.next.UploadFile(ctx, key, reader, metadata)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return r.next.UploadFile(ctx, key, reader, metadata)
+	}
+
+	var versionID string
+	err = r.do(ctx, "upload", key, func(attempt int) (bool, error) {
+		if attempt > 1 {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return false, err
+			}
+		}
+		var err error
+		versionID, err = r.next.UploadFile(ctx, key, reader, metadata)
+		return true, err
+	})
+	return versionID, err
+}
+
+// DownloadFile downloads a file, retrying only while nothing has been written
+func (r *retryStorage) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	counter := &countingWriter{w: writer}
+
+	var metadata map[string]string
+	err := r.do(ctx, "download", key, func(attempt int) (bool, error) {
+		var err error
+		metadata, err = r.next.DownloadFile(ctx, key, counter, versionID)
+		return counter.n == 0, err
+	})
+	return metadata, err
+}
+
+// DeleteFile deletes a file
+func (r *retryStorage) DeleteFile(ctx context.Context, key string) error {
+	return r.do(ctx, "delete", key, func(int) (bool, error) {
+		return true, r.next.DeleteFile(ctx, key)
+	})
+}
+
+// ListFiles lists files under a prefix
+func (r *retryStorage) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := r.do(ctx, "list", prefix, func(int) (bool, error) {
+		var err error
+		files, err = r.next.ListFiles(ctx, prefix)
+		return true, err
+	})
+	return files, err
+}
+
+// FileExists checks if a file exists
+func (r *retryStorage) FileExists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	err := r.do(ctx, "exists", key, func(int) (bool, error) {
+		var err error
+		exists, err = r.next.FileExists(ctx, key)
+		return true, err
+	})
+	return exists, err
+}
+
+// GetFileInfo returns the information and metadata of a file
+func (r *retryStorage) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	var info FileInfo
+	var metadata map[string]string
+	err := r.do(ctx, "stat", key, func(int) (bool, error) {
+		var err error
+		info, metadata, err = r.next.GetFileInfo(ctx, key)
+		return true, err
+	})
+	return info, metadata, err
+}
+
+// AppendFile appends to a file, rewinding the reader between attempts
+func (r *retryStorage) AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error) {
+	appender, ok := r.next.(Appender)
+	if !ok {
+		return "", ErrAppendUnsupported
+	}
+	seeker, ok := reader.(io.Seeker)
+	if !ok {
+		return appender.AppendFile(ctx, key, offset, reader, length, metadata)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return appender.AppendFile(ctx, key, offset, reader, length, metadata)
+	}
+
+	var versionID string
+	err = r.do(ctx, "append", key, func(attempt int) (bool, error) {
+		if attempt > 1 {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return false, err
+			}
+		}
+		var err error
+		versionID, err = appender.AppendFile(ctx, key, offset, reader, length, metadata)
+		return true, err
+	})
+	return versionID, err
+}
+
+// DownloadRange downloads part of a file, retrying only while nothing has been written
+func (r *retryStorage) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	ranged, ok := r.next.(RangeDownloader)
+	if !ok {
+		return ErrRangeUnsupported
+	}
+	counter := &countingWriter{w: writer}
+
+	return r.do(ctx, "download_range", key, func(int) (bool, error) {
+		return counter.n == 0, ranged.DownloadRange(ctx, key, offset, length, counter)
+	})
+}
+
+// CreateMultipart starts a multipart upload
+func (r *retryStorage) CreateMultipart(ctx context.Context, key string, metadata map[string]string) (string, error) {
+	multipart, ok := r.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	var uploadID string
+	err := r.do(ctx, "create_multipart", key, func(int) (bool, error) {
+		var err error
+		uploadID, err = multipart.CreateMultipart(ctx, key, metadata)
+		return true, err
+	})
+	return uploadID, err
+}
+
+// UploadPart uploads a part of a multipart upload, rewinding the reader between attempts.
+// A reader that cannot be rewound gets a single attempt; the upload resumes from that part.
+func (r *retryStorage) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+	multipart, ok := r.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	seeker, ok := reader.(io.Seeker)
+	if !ok {
+		return multipart.UploadPart(ctx, key, uploadID, number, reader, size)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return multipart.UploadPart(ctx, key, uploadID, number, reader, size)
+	}
+
+	var etag string
+	err = r.do(ctx, "upload_part", key, func(attempt int) (bool, error) {
+		if attempt > 1 {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return false, err
+			}
+		}
+		var err error
+		etag, err = multipart.UploadPart(ctx, key, uploadID, number, reader, size)
+		return true, err
+	})
+	return etag, err
+}
+
+// CompleteMultipart completes a multipart upload
+func (r *retryStorage) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) (string, error) {
+	multipart, ok := r.next.(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	var versionID string
+	err := r.do(ctx, "complete_multipart", key, func(int) (bool, error) {
+		var err error
+		versionID, err = multipart.CompleteMultipart(ctx, key, uploadID, parts)
+		return true, err
+	})
+	return versionID, err
+}
+
+// AbortMultipart aborts a multipart upload
+func (r *retryStorage) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	multipart, ok := r.next.(MultipartUploader)
+	if !ok {
+		return ErrMultipartUnsupported
+	}
+	return r.do(ctx, "abort_multipart", key, func(int) (bool, error) {
+		return true, multipart.AbortMultipart(ctx, key, uploadID)
+	})
+}
+
+// ListMultipart lists the multipart uploads in progress
+func (r *retryStorage) ListMultipart(ctx context.Context, prefix string) ([]MultipartUpload, error) {
+	multipart, ok := r.next.(MultipartUploader)
+	if !ok {
+		return nil, ErrMultipartUnsupported
+	}
+	var uploads []MultipartUpload
+	err := r.do(ctx, "list_multipart", prefix, func(int) (bool, error) {
+		var err error
+		uploads, err = multipart.ListMultipart(ctx, prefix)
+		return true, err
+	})
+	return uploads, err
+}
+
+// ApplyLifecycle adds a lifecycle rule to the bucket
+func (r *retryStorage) ApplyLifecycle(ctx context.Context, rule LifecycleRule) error {
+	manager, ok := r.next.(LifecycleManager)
+	if !ok {
+		return ErrLifecycleUnsupported
+	}
+	return r.do(ctx, "lifecycle", rule.Prefix, func(int) (bool, error) {
+		return true, manager.ApplyLifecycle(ctx, rule)
+	})
+}
+
+// do runs attempt until it succeeds, fails permanently or runs out of attempts.
+// attempt reports whether the request may be repeated after it failed.
+func (r *retryStorage) do(ctx context.Context, op, key string, attempt func(n int) (bool, error)) error {
+	backoff := r.policy.InitialBackoff
+
+	for n := 1; ; n++ {
+		repeatable, err := attempt(n)
+		if err == nil || !repeatable || n >= r.policy.MaxAttempts || !Retryable(err) {
+			return err
+		}
+
+		log.Debug().
+			Err(err).
+			Str("operation", op).
+			Str("key", key).
+			Int("attempt", n).
+			Dur("backoff", backoff).
+			Msg("Retrying storage request")
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+
+		backoff *= 2
+		if backoff > r.policy.MaxBackoff {
+			backoff = r.policy.MaxBackoff
+		}
+	}
+}
+
+// Retryable reports whether a storage error may succeed when the request is repeated
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrAppendUnsupported) || errors.Is(err, ErrRangeUnsupported) || errors.Is(err, ErrLifecycleUnsupported) {
+		return false
+	}
+
+	msg := err.Error()
+	for _, permanent := range []string{"NoSuchKey", "NoSuchBucket", "NotFound", "not found", "404", "AccessDenied", "403", "InvalidAccessKeyId", "SignatureDoesNotMatch"} {
+		if strings.Contains(msg, permanent) {
+			return false
+		}
+	}
+	return true
+}
+
+// Throttled reports whether a storage error is the backend asking for fewer requests, such as
+// the 503 SlowDown of S3 or an HTTP 429
+func Throttled(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := err.Error()
+	for _, signal := range []string{"SlowDown", "503", "Service Unavailable", "ServiceUnavailable", "429", "Too Many Requests", "TooManyRequests", "RequestLimitExceeded", "rateLimitExceeded"} {
+		if strings.Contains(msg, signal) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+)
+
+// SnapshotKeyPrefix is the prefix of the keys of backup snapshots, stored under
+// <prefix>/<folder-id>/ rather than <folder-id>/
+const SnapshotKeyPrefix = ".snapshots"
+
+// Router sends the requests of each folder to the storage target the folder syncs to. The
+// folder is taken from the key, whose first segment is the folder ID or its remote prefix;
+// keys of no known folder, such as the connectivity probe, go to the default target.
+type Router struct {
+	targets  map[string]Storage // By target name
+	fallback string             // Target of folders without one
+	folders  map[string]string  // Target name by folder ID and remote prefix
+	mu       sync.RWMutex
+}
+
+// NewRouter returns a storage routing the requests of folders to targets, by target name.
+// folders maps the ID of each folder that does not use the default target to its target.
+func NewRouter(targets map[string]Storage, defaultTarget string, folders map[string]string) *Router {
+	return &Router{targets: targets, fallback: defaultTarget, folders: folders}
+}
+
+// FolderTargets returns the target of each folder of cfg that names one, by folder ID and
+// by remote prefix
+func FolderTargets(cfg *common_config.Config) map[string]string {
+	folders := make(map[string]string)
+	for _, folder := range cfg.SyncFolders {
+		if folder.Target != "" {
+			folders[folder.ID] = folder.Target
+			folders[folder.KeyPrefix()] = folder.Target
+		}
+	}
+	return folders
+}
+
+// SetFolders replaces the targets of the folders, as after a configuration change
+func (r *Router) SetFolders(folders map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.folders = folders
+}
+
+// Target returns the storage of a target, or nil when there is none of that name
+func (r *Router) Target(name string) Storage {
+	return r.targets[name]
+}
+
+// route returns the storage holding key
+func (r *Router) route(key string) Storage {
+	key = strings.TrimPrefix(key, SnapshotKeyPrefix+"/")
+	folderID, _, _ := strings.Cut(key, "/")
+
+	r.mu.RLock()
+	name, ok := r.folders[folderID]
+	r.mu.RUnlock()
+	if target, found := r.targets[name]; ok && found {
+		return target
+	}
+	return r.targets[r.fallback]
+}
+
+// GetProvider returns the provider of the default target
+func (r *Router) GetProvider() StorageProvider {
+	return r.targets[r.fallback].GetProvider()
+}
+
+// UploadFile uploads a file to the target of its folder
+func (r *Router) UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error) {
+	return r.route(key).UploadFile(ctx, key, reader, metadata)
+}
+
+// DownloadFile downloads a file from the target of its folder
+func (r *Router) DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error) {
+	return r.route(key).DownloadFile(ctx, key, writer, versionID)
+}
+
+// DeleteFile deletes a file from the target of its folder
+func (r *Router) DeleteFile(ctx context.Context, key string) error {
+	return r.route(key).DeleteFile(ctx, key)
+}
+
+// ListFiles lists the files under a prefix in the target of its folder
+func (r *Router) ListFiles(ctx context.Context, prefix string) ([]FileInfo, error) {
+	return r.route(prefix).ListFiles(ctx, prefix)
+}
+
+// FileExists checks if a file exists in the target of its folder
+func (r *Router) FileExists(ctx context.Context, key string) (bool, error) {
+	return r.route(key).FileExists(ctx, key)
+}
+
+// GetFileInfo returns the information and metadata of a file from the target of its folder
+func (r *Router) GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error) {
+	return r.route(key).GetFileInfo(ctx, key)
+}
+
+// AppendFile appends to a file in the target of its folder
+func (r *Router) AppendFile(ctx context.Context, key string, offset int64, reader io.Reader, length int64, metadata map[string]string) (string, error) {
+	appender, ok := r.route(key).(Appender)
+	if !ok {
+		return "", ErrAppendUnsupported
+	}
+	return appender.AppendFile(ctx, key, offset, reader, length, metadata)
+}
+
+// DownloadRange downloads part of a file from the target of its folder
+func (r *Router) DownloadRange(ctx context.Context, key string, offset, length int64, writer io.Writer) error {
+	ranged, ok := r.route(key).(RangeDownloader)
+	if !ok {
+		return ErrRangeUnsupported
+	}
+	return ranged.DownloadRange(ctx, key, offset, length, writer)
+}
+
+// CreateMultipart starts a multipart upload in the target of the folder of a file
+func (r *Router) CreateMultipart(ctx context.Context, key string, metadata map[string]string) (string, error) {
+	multipart, ok := r.route(key).(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	return multipart.CreateMultipart(ctx, key, metadata)
+}
+
+// UploadPart uploads a part of a multipart upload in the target of the folder of a file
+func (r *Router) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+	multipart, ok := r.route(key).(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	return multipart.UploadPart(ctx, key, uploadID, number, reader, size)
+}
+
+// CompleteMultipart completes a multipart upload in the target of the folder of a file
+func (r *Router) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) (string, error) {
+	multipart, ok := r.route(key).(MultipartUploader)
+	if !ok {
+		return "", ErrMultipartUnsupported
+	}
+	return multipart.CompleteMultipart(ctx, key, uploadID, parts)
+}
+
+// AbortMultipart aborts a multipart upload in the target of the folder of a file
+func (r *Router) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	multipart, ok := r.route(key).(MultipartUploader)
+	if !ok {
+		return ErrMultipartUnsupported
+	}
+	return multipart.AbortMultipart(ctx, key, uploadID)
+}
+
+// ListMultipart lists the multipart uploads in progress in the target of the folder under prefix
+func (r *Router) ListMultipart(ctx context.Context, prefix string) ([]MultipartUpload, error) {
+	multipart, ok := r.route(prefix).(MultipartUploader)
+	if !ok {
+		return nil, ErrMultipartUnsupported
+	}
+	return multipart.ListMultipart(ctx, prefix)
+}
+
+// ApplyLifecycle adds a lifecycle rule to the bucket of the target of the folder under its prefix
+func (r *Router) ApplyLifecycle(ctx context.Context, rule LifecycleRule) error {
+	manager, ok := r.route(rule.Prefix).(LifecycleManager)
+	if !ok {
+		return ErrLifecycleUnsupported
+	}
+	return manager.ApplyLifecycle(ctx, rule)
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterSendsFoldersToTheirTargets(t *testing.T) {
+	ctx := context.Background()
+	cfg := common_config.DefaultConfig()
+	cfg.Targets = []common_config.StorageTarget{
+		{Name: "default", Type: "memory", Memory: common_config.MemoryConfig{Name: t.Name() + "-default"}},
+		{Name: "offsite", Type: "local", Local: common_config.LocalConfig{RootDir: t.TempDir()}},
+	}
+	cfg.SyncFolders = []common_config.SyncFolder{{ID: "docs"}, {ID: "photos", Target: "offsite"}}
+
+	store, err := StorageFactory(cfg)
+	assert.NoError(t, err)
+	router, ok := store.(*Router)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, ProviderMemory, router.GetProvider())
+
+	for _, key := range []string{"docs/a.txt", "photos/b.jpg", SnapshotKeyPrefix + "/photos/index.json", ".sync-manager/probe"} {
+		_, err := router.UploadFile(ctx, key, strings.NewReader(key), map[string]string{})
+		assert.NoError(t, err)
+	}
+
+	// Each target only holds the files of its folders; keys of no folder go to the default one
+	exists := func(target, key string) bool {
+		found, err := router.Target(target).FileExists(ctx, key)
+		assert.NoError(t, err)
+		return found
+	}
+	assert.True(t, exists("default", "docs/a.txt"))
+	assert.True(t, exists("default", ".sync-manager/probe"))
+	assert.False(t, exists("default", "photos/b.jpg"))
+	assert.True(t, exists("offsite", "photos/b.jpg"))
+	assert.True(t, exists("offsite", SnapshotKeyPrefix+"/photos/index.json"))
+	assert.False(t, exists("offsite", "docs/a.txt"))
+
+	files, err := router.ListFiles(ctx, "photos/")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// Moving a folder to another target routes its next requests there
+	router.SetFolders(map[string]string{"docs": "offsite"})
+	found, err := router.FileExists(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.False(t, found)
+	found, err = router.FileExists(ctx, "photos/b.jpg")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// Folders with a remote prefix are routed by it too
+	cfg.SyncFolders[1].RemotePrefix = "pictures"
+	assert.Equal(t, map[string]string{"photos": "offsite", "pictures": "offsite"}, FolderTargets(cfg))
+
+	// Optional interfaces are only available when the folder's target has them
+	assert.ErrorIs(t, router.ApplyLifecycle(ctx, LifecycleRule{Prefix: "photos/"}), ErrLifecycleUnsupported)
+}
+
+func TestStorageFactorySingleTarget(t *testing.T) {
+	cfg := common_config.DefaultConfig()
+	cfg.Targets = []common_config.StorageTarget{{Name: "default", Type: "memory", Memory: common_config.MemoryConfig{Name: t.Name()}}}
+
+	store, err := StorageFactory(cfg)
+	assert.NoError(t, err)
+	_, routed := store.(*Router)
+	assert.False(t, routed)
+
+	cfg.Targets = append(cfg.Targets, common_config.StorageTarget{Name: "broken", Type: "ftp"})
+	_, err = StorageFactory(cfg)
+	assert.ErrorContains(t, err, "storage target broken")
+}
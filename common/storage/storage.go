@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	common_config "github.com/martinshumberto/sync-manager/common/config"
+)
+
+// FileInfo represents information about a file in storage
+type FileInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string // Entity tag (unique identifier)
+	// Checksums of the content computed by the backend, hex-encoded, set by GetFileInfo when
+	// the backend has them: the MD5 of S3 and MinIO objects uploaded in one part and of GCS
+	// objects, and the CRC32C of GCS objects
+	MD5    string
+	CRC32C string
+}
+
+// ETagMD5 returns the MD5 of the content held in an S3 ETag, empty for objects uploaded in
+// parts, whose ETag is not the MD5 of their content
+func ETagMD5(etag string) string {
+	etag = strings.Trim(etag, "\"")
+	if len(etag) != 32 {
+		return ""
+	}
+	if _, err := hex.DecodeString(etag); err != nil {
+		return ""
+	}
+	return strings.ToLower(etag)
+}
+
+// ErrNotFound is returned, wrapped, when a requested file does not exist
+var ErrNotFound = errors.New("file not found")
+
+// StorageProvider identifies the type of storage provider
+type StorageProvider string
+
+const (
+	ProviderS3       StorageProvider = "s3"
+	ProviderGCS      StorageProvider = "gcs"
+	ProviderMinio    StorageProvider = "minio" // local development
+	ProviderLocal    StorageProvider = "local"
+	ProviderMemory   StorageProvider = "memory" // integration tests
+	ProviderOneDrive StorageProvider = "onedrive"
+)
+
+// Storage defines the interface for file storage operations
+type Storage interface {
+	// UploadFile uploads a file to storage and returns the version ID (if available)
+	UploadFile(ctx context.Context, key string, reader io.Reader, metadata map[string]string) (string, error)
+
+	// DownloadFile downloads a file from storage and returns its metadata
+	DownloadFile(ctx context.Context, key string, writer io.Writer, versionID string) (map[string]string, error)
+
+	// DeleteFile deletes a file from storage
+	DeleteFile(ctx context.Context, key string) error
+
+	// ListFiles lists files in storage with the given prefix
+	ListFiles(ctx context.Context, prefix string) ([]FileInfo, error)
+
+	// FileExists checks if a file exists in storage
+	FileExists(ctx context.Context, key string) (bool, error)
+
+	// GetFileInfo returns the information and metadata of a single file without
+	// downloading it. A missing file returns an error wrapping ErrNotFound.
+	GetFileInfo(ctx context.Context, key string) (FileInfo, map[string]string, error)
+
+	// GetProvider returns the storage provider type
+	GetProvider() StorageProvider
+}
+
+// StorageFactory creates the configured storage backend wrapped in the configured middlewares
+func StorageFactory(cfg *common_config.Config) (Storage, error) {
+	if len(cfg.Targets) == 1 {
+		return TargetStorage(cfg, &cfg.Targets[0])
+	}
+
+	targets := make(map[string]Storage, len(cfg.Targets))
+	for i := range cfg.Targets {
+		target, err := TargetStorage(cfg, &cfg.Targets[i])
+		if err != nil {
+			return nil, fmt.Errorf("storage target %s: %w", cfg.Targets[i].Name, err)
+		}
+		targets[cfg.Targets[i].Name] = target
+	}
+	return NewRouter(targets, cfg.DefaultTarget().Name, FolderTargets(cfg)), nil
+}
+
+// TargetStorage creates the storage of a single target, wrapped in the configured middlewares
+func TargetStorage(cfg *common_config.Config, target *common_config.StorageTarget) (Storage, error) {
+	backend, err := newBackend(cfg, target)
+	if err != nil {
+		return nil, err
+	}
+	return Chain(backend, Middlewares(cfg.StorageMiddleware)...), nil
+}
+
+// newBackend creates the storage implementation for the type of a target, starting its
+// plugin for types that are not built in
+func newBackend(cfg *common_config.Config, target *common_config.StorageTarget) (Storage, error) {
+	if target.IsPlugin() {
+		path, err := cfg.Plugins.Find(target.Type)
+		if err != nil {
+			return nil, err
+		}
+		return NewPluginStorage(target.Type, path, target.Plugin)
+	}
+
+	switch StorageProvider(target.Type) {
+	case ProviderS3:
+		s3cfg := NewS3ConfigFromCommon(&target.S3)
+		if len(target.S3.Failover) > 0 {
+			return newFailover(target.Name, ProviderS3, s3cfg.Endpoint, target.S3.Failover, func(address string) (Storage, error) {
+				endpointCfg := *s3cfg
+				endpointCfg.Endpoint = address
+				return NewS3Storage(&endpointCfg)
+			})
+		}
+		return NewS3Storage(s3cfg)
+	case ProviderMinio:
+		minioCfg := NewMinioConfigFromCommon(&target.Minio)
+		if len(target.Minio.Failover) > 0 {
+			return newFailover(target.Name, ProviderMinio, minioCfg.Endpoint, target.Minio.Failover, func(address string) (Storage, error) {
+				endpointCfg := *minioCfg
+				endpointCfg.Endpoint = address
+				return NewMinioStorage(&endpointCfg)
+			})
+		}
+		return NewMinioStorage(minioCfg)
+	case ProviderGCS:
+		gcsCfg := NewGCSConfigFromCommon(&target.GCS)
+		return NewGCSStorage(gcsCfg)
+	case ProviderLocal:
+		localCfg := NewLocalConfigFromCommon(&target.Local)
+		return NewLocalStorage(localCfg)
+	case ProviderMemory:
+		memoryCfg := NewMemoryConfigFromCommon(&target.Memory)
+		return NewMemoryStorage(memoryCfg), nil
+	case ProviderOneDrive:
+		oneDriveCfg, err := NewOneDriveConfigFromCommon(&target.OneDrive, target.Name)
+		if err != nil {
+			return nil, err
+		}
+		return NewOneDriveStorage(oneDriveCfg)
+	default:
+		return nil, fmt.Errorf("unsupported storage provider: %s", target.Type)
+	}
+}
+
+// newFailover creates the storage of a target with failover endpoints, reporting them in
+// DefaultMetrics
+func newFailover(target string, provider StorageProvider, primary string, failover []string, open EndpointOpener) (Storage, error) {
+	f, err := NewFailoverStorage(target, provider, append([]string{primary}, failover...), open)
+	if err != nil {
+		return nil, err
+	}
+	DefaultMetrics.addFailover(f)
+	return f, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileInfo(t *testing.T) {
+	info := FileInfo{
+		Key:  "test-key",
+		Size: 1024,
+		ETag: "test-etag",
+	}
+
+	assert.Equal(t, "test-key", info.Key)
+	assert.Equal(t, int64(1024), info.Size)
+	assert.Equal(t, "test-etag", info.ETag)
+}
+
+func TestETagMD5(t *testing.T) {
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", ETagMD5(`"5D41402ABC4B2A76B9719D911017C592"`))
+	// Multipart uploads end their ETag with the number of parts
+	assert.Empty(t, ETagMD5("5d41402abc4b2a76b9719d911017c592-3"))
+	assert.Empty(t, ETagMD5("not-an-md5-etag-but-32-chars-lng"))
+}
+
+func TestS3Config(t *testing.T) {
+	cfg := S3Config{
+		Endpoint:  "localhost:9000",
+		Region:    "us-east-1",
+		Bucket:    "test-bucket",
+		AccessKey: "test-access",
+		SecretKey: "test-secret",
+		UseSSL:    true,
+		PathStyle: false,
+	}
+
+	assert.Equal(t, "localhost:9000", cfg.Endpoint)
+	assert.Equal(t, "us-east-1", cfg.Region)
+	assert.Equal(t, "test-bucket", cfg.Bucket)
+	assert.Equal(t, "test-access", cfg.AccessKey)
+	assert.Equal(t, "test-secret", cfg.SecretKey)
+	assert.True(t, cfg.UseSSL)
+	assert.False(t, cfg.PathStyle)
+}
+
+func TestLocalGetFileInfo(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(&LocalConfig{RootDir: t.TempDir()})
+	assert.NoError(t, err)
+
+	_, err = store.UploadFile(ctx, "docs/a.txt", strings.NewReader("hello"), map[string]string{})
+	assert.NoError(t, err)
+
+	info, metadata, err := store.GetFileInfo(ctx, "docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "docs/a.txt", info.Key)
+	assert.Equal(t, int64(5), info.Size)
+	hello := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" // sha256("hello")
+	assert.Equal(t, hello, info.ETag)
+	assert.Equal(t, hello, metadata["hash_sha256"])
+
+	_, _, err = store.GetFileInfo(ctx, "docs/missing.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, _, err = store.GetFileInfo(ctx, "docs")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDirMarkers(t *testing.T) {
+	assert.Equal(t, "docs/empty/"+DirMarker, DirMarkerKey("docs/empty"))
+
+	dir, ok := MarkerDir("docs/empty/" + DirMarker)
+	assert.True(t, ok)
+	assert.Equal(t, "docs/empty", dir)
+
+	dir, ok = MarkerDir(DirMarker)
+	assert.True(t, ok)
+	assert.Equal(t, "", dir)
+
+	_, ok = MarkerDir("docs/notes.txt")
+	assert.False(t, ok)
+
+	assert.Equal(t, os.FileMode(0700), DirMode(map[string]string{MetadataDirMode: "700"}))
+	assert.Equal(t, os.FileMode(0755), DirMode(nil))
+	assert.Equal(t, os.FileMode(0755), DirMode(map[string]string{MetadataDirMode: "bad"}))
+}
+
+func TestDownloadRange(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewLocalStorage(&LocalConfig{RootDir: t.TempDir()})
+	assert.NoError(t, err)
+
+	for _, store := range []Storage{local, NewMemoryStorage(&MemoryConfig{})} {
+		_, err := store.UploadFile(ctx, "docs/a.txt", strings.NewReader("0123456789"), map[string]string{})
+		assert.NoError(t, err)
+
+		ranged, ok := store.(RangeDownloader)
+		assert.True(t, ok)
+
+		var buf bytes.Buffer
+		assert.NoError(t, ranged.DownloadRange(ctx, "docs/a.txt", 3, 4, &buf))
+		assert.Equal(t, "3456", buf.String())
+
+		buf.Reset()
+		assert.NoError(t, ranged.DownloadRange(ctx, "docs/a.txt", 8, 4, &buf))
+		assert.Equal(t, "89", buf.String())
+	}
+}
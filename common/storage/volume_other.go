@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package storage
+
+// separateVolume reports true: drives get a letter of their own, so a root directory that
+// exists is on a mounted drive
+func separateVolume(path string) (bool, error) {
+	return true, nil
+}
+
+// filesystemUUID reports false, as filesystem UUIDs are not read on this platform and
+// drives are recognised by their volume marker
+func filesystemUUID(path, uuid string) bool {
+	return false
+}
@@ -0,0 +1,43 @@
+//go:build linux || darwin
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// separateVolume reports whether path is on another filesystem than the root one, which an
+// empty mount point left by an unplugged drive is not
+func separateVolume(path string) (bool, error) {
+	dev, err := device(path)
+	if err != nil {
+		return false, err
+	}
+	root, err := device("/")
+	if err != nil {
+		return false, err
+	}
+	return dev != root, nil
+}
+
+// filesystemUUID reports whether path is on the filesystem with the given UUID, as listed in
+// /dev/disk/by-uuid on Linux
+func filesystemUUID(path, uuid string) bool {
+	var disk syscall.Stat_t
+	if err := syscall.Stat(filepath.Join("/dev/disk/by-uuid", uuid), &disk); err != nil {
+		return false
+	}
+	dev, err := device(path)
+	return err == nil && uint64(disk.Rdev) == dev
+}
+
+// device returns the ID of the device holding path
+func device(path string) (uint64, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return 0, &os.PathError{Op: "stat", Path: path, Err: err}
+	}
+	return uint64(stat.Dev), nil
+}
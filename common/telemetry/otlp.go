@@ -0,0 +1,34 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+)
+
+// tracesPath is where OTLP/HTTP collectors receive spans
+const tracesPath = "/v1/traces"
+
+// NewExporter creates an OTLP/HTTP exporter for the collector at endpoint, e.g. http://localhost:4318
+func NewExporter(ctx context.Context, endpoint string, headers map[string]string) (*otlptrace.Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid telemetry endpoint %q", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, tracesPath) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + tracesPath
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(u.String()),
+		otlptracehttp.WithHeaders(headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telemetry exporter: %w", err)
+	}
+	return exporter, nil
+}
@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by sync-manager
+const instrumentationName = "github.com/martinshumberto/sync-manager"
+
+// Attribute keys shared by the sync pipeline spans
+const (
+	FolderIDKey = attribute.Key("sync.folder.id")
+	FileKey     = attribute.Key("sync.file.key")
+	FileSizeKey = attribute.Key("sync.file.size")
+	FilesKey    = attribute.Key("sync.files")
+	AttemptKey  = attribute.Key("sync.upload.attempt")
+)
+
+// Setup installs a global tracer provider exporting to the configured OTLP collector.
+// When telemetry is disabled the global no-op provider is left in place.
+// The returned function flushes pending spans and must be called on shutdown.
+func Setup(cfg config.TelemetryConfig, version string) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := NewExporter(context.Background(), cfg.Endpoint, cfg.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "sync-manager-agent"
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build telemetry resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer for sync-manager spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End finishes a span, marking it as failed when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExporterSendsOTLP(t *testing.T) {
+	var received coltracepb.ExportTraceServiceRequest
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		headers = r.Header
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, proto.Unmarshal(body, &received))
+	}))
+	defer server.Close()
+
+	exporter, err := NewExporter(context.Background(), server.URL, map[string]string{"Authorization": "Bearer token"})
+	assert.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "sync.folder")
+	parent.SetAttributes(FolderIDKey.String("docs"), FilesKey.Int(3))
+	_, child := tracer.Start(ctx, "upload.file")
+	End(child, errors.New("connection reset"))
+	End(parent, nil)
+
+	assert.NoError(t, exporter.ExportSpans(context.Background(), recorder.Ended()))
+	assert.NoError(t, exporter.Shutdown(context.Background()))
+	assert.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", headers.Get("Authorization"))
+
+	assert.Len(t, received.ResourceSpans, 1)
+	assert.Len(t, received.ResourceSpans[0].ScopeSpans, 1)
+	assert.Equal(t, "test", received.ResourceSpans[0].ScopeSpans[0].Scope.Name)
+
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	assert.Len(t, spans, 2)
+
+	upload, folder := spans[0], spans[1]
+	assert.Equal(t, "upload.file", upload.Name)
+	assert.Equal(t, folder.SpanId, upload.ParentSpanId)
+	assert.Equal(t, folder.TraceId, upload.TraceId)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, upload.Status.Code)
+	assert.Equal(t, "connection reset", upload.Status.Message)
+	assert.Len(t, upload.Events, 1) // The recorded error
+
+	assert.Empty(t, folder.ParentSpanId)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_UNSET, folder.Status.Code)
+	assert.Equal(t, "sync.folder.id", folder.Attributes[0].Key)
+	assert.Equal(t, "docs", folder.Attributes[0].Value.GetStringValue())
+	assert.Equal(t, int64(3), folder.Attributes[1].Value.GetIntValue())
+}
+
+func TestExporterReportsRejectedSpans(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	exporter, err := NewExporter(context.Background(), server.URL+"/", nil)
+	assert.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	_, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(context.Background(), "scan")
+	span.End()
+
+	assert.Error(t, exporter.ExportSpans(context.Background(), recorder.Ended()))
+
+	_, err = NewExporter(context.Background(), "localhost:4318", nil)
+	assert.Error(t, err)
+}
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(config.TelemetryConfig{Enabled: false}, "dev")
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	// Without a provider spans are no-ops
+	_, span := Tracer().Start(context.Background(), "noop")
+	assert.False(t, span.IsRecording())
+	span.End()
+
+	_, err = Setup(config.TelemetryConfig{Enabled: true, Endpoint: "not a url"}, "dev")
+	assert.Error(t, err)
+}
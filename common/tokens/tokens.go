@@ -0,0 +1,126 @@
+// Package tokens checks the API tokens the CLI creates. Only the hash of each token is kept in
+// the shared database, so the agent verifies a token by hashing it the same way.
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/database"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Prefix marks the API tokens the CLI creates, so they are recognized in logs and settings
+const Prefix = "smt_"
+
+// ErrInvalid is returned for a token that is unknown, revoked or expired
+var ErrInvalid = errors.New("invalid or expired token")
+
+// Authenticate returns the record of a valid token, recording that it was used at now
+func Authenticate(db *gorm.DB, plain string, now time.Time) (*models.ApiToken, error) {
+	if !strings.HasPrefix(plain, Prefix) {
+		return nil, ErrInvalid
+	}
+
+	var token models.ApiToken
+	err := db.Where("token = ? AND revoked = ? AND expires_at > ?", models.HashToken(plain), false, now).
+		First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify token: %w", err)
+	}
+
+	if err := db.Model(&token).UpdateColumn("last_used", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to update token usage: %w", err)
+	}
+	token.LastUsed = now
+	return &token, nil
+}
+
+// Verifier checks tokens against the shared database. The database is opened the first time
+// a token is checked, so tokens created after the agent started are accepted.
+type Verifier struct {
+	dsn string
+
+	mu sync.Mutex
+	db *gorm.DB
+}
+
+// NewVerifier creates a verifier reading the database at dsn, the path of a SQLite file or a
+// postgres:// or mysql:// DSN
+func NewVerifier(dsn string) *Verifier {
+	return &Verifier{dsn: dsn}
+}
+
+// Authenticate returns ErrInvalid unless plain is a valid token
+func (v *Verifier) Authenticate(plain string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	db, err := v.open()
+	if err != nil {
+		return err
+	}
+	if db == nil {
+		// No token was ever created
+		return ErrInvalid
+	}
+	_, err = Authenticate(db, plain, time.Now())
+	return err
+}
+
+// Close closes the database when it was opened
+func (v *Verifier) Close() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.db == nil {
+		return nil
+	}
+	sqlDB, err := v.db.DB()
+	if err != nil {
+		return err
+	}
+	v.db = nil
+	return sqlDB.Close()
+}
+
+// open opens the database, returning nil while it does not exist or has no tokens table.
+// mu must be held.
+func (v *Verifier) open() (*gorm.DB, error) {
+	if v.db != nil {
+		return v.db, nil
+	}
+
+	dialector, driver, err := database.Dialector(v.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == database.SQLite {
+		if _, err := os.Stat(v.dsn); os.IsNotExist(err) {
+			return nil, nil
+		}
+		// The CLI may be writing at the same time
+		dialector = sqlite.Open("file:" + v.dsn + "?_busy_timeout=5000")
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if !db.Migrator().HasTable(&models.ApiToken{}) {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		return nil, nil
+	}
+	v.db = db
+	return db, nil
+}
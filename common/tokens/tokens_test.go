@@ -0,0 +1,58 @@
+package tokens
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAuthenticate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "sync-manager.db")), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.ApiToken{}))
+
+	now := time.Now()
+	assert.NoError(t, db.Create(&[]models.ApiToken{
+		{UserID: 1, Name: "ci", Token: models.HashToken(Prefix + "valid"), ExpiresAt: now.Add(time.Hour)},
+		{UserID: 1, Name: "old", Token: models.HashToken(Prefix + "expired"), ExpiresAt: now.Add(-time.Hour)},
+		{UserID: 1, Name: "gone", Token: models.HashToken(Prefix + "revoked"), ExpiresAt: now.Add(time.Hour), Revoked: true},
+	}).Error)
+
+	token, err := Authenticate(db, Prefix+"valid", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "ci", token.Name)
+	var stored models.ApiToken
+	assert.NoError(t, db.First(&stored, token.ID).Error)
+	assert.WithinDuration(t, now, stored.LastUsed, time.Second)
+
+	// The stored hash is not a token itself
+	for _, plain := range []string{Prefix + "expired", Prefix + "revoked", Prefix + "unknown", models.HashToken(Prefix + "valid")} {
+		_, err := Authenticate(db, plain, now)
+		assert.ErrorIs(t, err, ErrInvalid, plain)
+	}
+}
+
+func TestVerifierOpensDatabaseOnceItExists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-manager.db")
+	verifier := NewVerifier(path)
+	defer verifier.Close()
+
+	// Without a database no token is valid, and none is created
+	assert.ErrorIs(t, verifier.Authenticate(Prefix+"valid"), ErrInvalid)
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.ApiToken{}))
+	assert.NoError(t, db.Create(&models.ApiToken{Name: "ci", Token: models.HashToken(Prefix + "valid"), ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	assert.NoError(t, verifier.Authenticate(Prefix+"valid"))
+	assert.ErrorIs(t, verifier.Authenticate(Prefix+"other"), ErrInvalid)
+}
@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/rs/zerolog/log"
+)
+
+// IsDefault reports whether cfg leaves the standard transport unchanged
+func IsDefault(cfg config.TransportConfig) bool {
+	return cfg == config.TransportConfig{}
+}
+
+// New creates an HTTP transport using the proxy and TLS settings in cfg. Without an
+// explicit proxy it honors the HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables.
+func New(cfg config.TransportConfig) (*http.Transport, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+		}
+		t.Proxy = http.ProxyURL(proxyURL)
+	} else {
+		t.Proxy = http.ProxyFromEnvironment
+	}
+
+	if cfg.CACertFile != "" || cfg.InsecureSkipVerify {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if cfg.CACertFile != "" {
+			pool, err := loadCertPool(cfg.CACertFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		if cfg.InsecureSkipVerify {
+			log.Warn().Msg("TLS certificate verification is disabled; connections can be intercepted")
+			tlsConfig.InsecureSkipVerify = true
+		}
+
+		t.TLSClientConfig = tlsConfig
+	}
+
+	return t, nil
+}
+
+// NewClient creates an HTTP client with the transport from cfg
+func NewClient(cfg config.TransportConfig, timeout time.Duration) (*http.Client, error) {
+	t, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: t, Timeout: timeout}, nil
+}
+
+// loadCertPool returns the system roots plus the certificates in a PEM file
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+
+	return pool, nil
+}
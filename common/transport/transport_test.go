@@ -0,0 +1,72 @@
+package transport
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/martinshumberto/sync-manager/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxy(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://storage.example.com/bucket", nil)
+
+	tr, err := New(config.TransportConfig{ProxyURL: "http://proxy.corp:3128"})
+	assert.NoError(t, err)
+	proxy, err := tr.Proxy(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "proxy.corp:3128", proxy.Host)
+
+	// Without an explicit proxy the environment decides
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:8080")
+	tr, err = New(config.TransportConfig{})
+	assert.NoError(t, err)
+	assert.NotNil(t, tr.Proxy)
+
+	_, err = New(config.TransportConfig{ProxyURL: "proxy.corp:3128"})
+	assert.Error(t, err)
+}
+
+func TestCACertFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server's certificate is not trusted by default
+	client, err := NewClient(config.TransportConfig{}, 0)
+	assert.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(caFile, certPEM, 0600))
+
+	client, err = NewClient(config.TransportConfig{CACertFile: caFile}, 0)
+	assert.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+
+	// Skipping verification also works, as a last resort
+	client, err = NewClient(config.TransportConfig{InsecureSkipVerify: true}, 0)
+	assert.NoError(t, err)
+	resp, err = client.Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+
+	// A file without certificates is rejected
+	assert.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+	_, err = New(config.TransportConfig{CACertFile: caFile})
+	assert.Error(t, err)
+}
+
+func TestIsDefault(t *testing.T) {
+	assert.True(t, IsDefault(config.TransportConfig{}))
+	assert.False(t, IsDefault(config.TransportConfig{InsecureSkipVerify: true}))
+}
@@ -0,0 +1,137 @@
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Request asks the agent to sync now. Requests the agent has not picked up yet are merged,
+// so triggering several folders in a row syncs all of them.
+type Request struct {
+	All         bool                `json:"all,omitempty"`     // Every enabled, unpaused folder
+	Folders     []string            `json:"folders,omitempty"` // Folders to sync, when not all of them
+	Paths       map[string][]string `json:"paths,omitempty"`   // Subtrees to sync on their own, by folder
+	Restart     bool                `json:"restart,omitempty"` // Cancel a sync that is running instead of joining it
+	RequestedAt time.Time           `json:"requested_at"`
+}
+
+// DefaultPath returns the default location of the file through which the CLI asks the agent to sync
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "sync-request.json"), nil
+}
+
+// Add records a request to sync a folder, or every folder when folderID is empty
+func Add(path, folderID string, restart bool, now time.Time) error {
+	req, err := read(path)
+	if err != nil {
+		return err
+	}
+	if req == nil {
+		req = &Request{}
+	}
+
+	if folderID == "" {
+		req.All, req.Folders = true, nil
+	} else if !req.All && !contains(req.Folders, folderID) {
+		req.Folders = append(req.Folders, folderID)
+	}
+	req.Restart = req.Restart || restart
+	req.RequestedAt = now
+
+	return write(path, req)
+}
+
+// AddPath records a request to sync only the subtree at relPath of a folder
+func AddPath(path, folderID, relPath string, restart bool, now time.Time) error {
+	req, err := read(path)
+	if err != nil {
+		return err
+	}
+	if req == nil {
+		req = &Request{}
+	}
+
+	if req.Paths == nil {
+		req.Paths = make(map[string][]string)
+	}
+	if !contains(req.Paths[folderID], relPath) {
+		req.Paths[folderID] = append(req.Paths[folderID], relPath)
+	}
+	req.Restart = req.Restart || restart
+	req.RequestedAt = now
+
+	return write(path, req)
+}
+
+// Take returns the pending request and removes it, or returns nil when there is none
+func Take(path string) (*Request, error) {
+	// Move the request aside first, so one the CLI adds meanwhile is kept for the next call
+	taken := path + ".taken"
+	if err := os.Rename(path, taken); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to take sync request: %w", err)
+	}
+	defer os.Remove(taken)
+
+	return read(taken)
+}
+
+// read loads the request at path, returning nil if there is none
+func read(path string) (*Request, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read sync request: %w", err)
+	}
+
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse sync request: %w", err)
+	}
+	return &req, nil
+}
+
+// write stores the request at path
+func write(path string, req *Request) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create sync request directory: %w", err)
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync request: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sync request: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace sync request: %w", err)
+	}
+
+	return nil
+}
+
+// contains reports whether ids holds id
+func contains(ids []string, id string) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
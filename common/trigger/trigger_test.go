@@ -0,0 +1,62 @@
+package trigger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddTake(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-request.json")
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	req, err := Take(path)
+	assert.NoError(t, err)
+	assert.Nil(t, req)
+
+	// Folders requested before the agent picks them up are merged
+	assert.NoError(t, Add(path, "docs", false, now))
+	assert.NoError(t, Add(path, "photos", false, now))
+	assert.NoError(t, Add(path, "docs", true, now))
+
+	req, err = Take(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs", "photos"}, req.Folders)
+	assert.False(t, req.All)
+	assert.True(t, req.Restart)
+
+	req, err = Take(path)
+	assert.NoError(t, err)
+	assert.Nil(t, req)
+
+	// A request for every folder covers the single folders
+	assert.NoError(t, Add(path, "docs", false, now))
+	assert.NoError(t, Add(path, "", false, now))
+	assert.NoError(t, Add(path, "photos", false, now))
+
+	req, err = Take(path)
+	assert.NoError(t, err)
+	assert.True(t, req.All)
+	assert.Empty(t, req.Folders)
+	assert.False(t, req.Restart)
+}
+
+func TestAddPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-request.json")
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Subtrees are kept apart from the folders synced as a whole
+	assert.NoError(t, Add(path, "docs", false, now))
+	assert.NoError(t, AddPath(path, "photos", "2024/june", false, now))
+	assert.NoError(t, AddPath(path, "photos", "2024/june", false, now))
+	assert.NoError(t, AddPath(path, "photos", "2023", true, now.Add(time.Minute)))
+
+	req, err := Take(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs"}, req.Folders)
+	assert.Equal(t, map[string][]string{"photos": {"2024/june", "2023"}}, req.Paths)
+	assert.True(t, req.Restart)
+	assert.Equal(t, now.Add(time.Minute), req.RequestedAt)
+}
@@ -0,0 +1,24 @@
+package watchlimit
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// limitPath is where the kernel exposes Sysctl
+const limitPath = "/proc/sys/fs/inotify/max_user_watches"
+
+// limit reads Sysctl from procfs
+func limit() (int, error) {
+	data, err := os.ReadFile(limitPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", Sysctl, err)
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", Sysctl, err)
+	}
+	return value, nil
+}
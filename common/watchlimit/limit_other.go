@@ -0,0 +1,8 @@
+//go:build !linux
+
+package watchlimit
+
+// limit reports ErrUnsupported: other platforms do not cap watches per user
+func limit() (int, error) {
+	return 0, ErrUnsupported
+}
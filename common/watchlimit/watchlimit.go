@@ -0,0 +1,99 @@
+package watchlimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Sysctl is the kernel setting capping the inotify watches of a user
+const Sysctl = "fs.inotify.max_user_watches"
+
+// NearPercent is the share of the limit from which the agent warns that watches are running out
+const NearPercent = 90
+
+// ErrUnsupported is returned by Limit on platforms without a per-user watch limit
+var ErrUnsupported = errors.New("the watch limit cannot be read on this platform")
+
+// State is the watch usage the agent last recorded. The agent writes it to the file at
+// DefaultPath and the CLI reads it to warn the user.
+type State struct {
+	Watches   int       `json:"watches"`          // Directories the agent watches
+	Limit     int       `json:"limit,omitempty"`  // Watches allowed per user, 0 when unknown
+	Polled    []string  `json:"polled,omitempty"` // Directories polled for changes because no watch was left for them
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Near reports whether the agent uses NearPercent of the limit or more
+func (s *State) Near() bool {
+	return s.Limit > 0 && s.Watches*100 >= s.Limit*NearPercent
+}
+
+// Suggested returns a limit leaving room for the watches in use to double, at least
+// twice the current one
+func (s *State) Suggested() int {
+	suggested := 524288
+	for suggested < s.Limit*2 || suggested < s.Watches*2 {
+		suggested *= 2
+	}
+	return suggested
+}
+
+// Limit returns the inotify watches allowed per user
+func Limit() (int, error) {
+	return limit()
+}
+
+// DefaultPath returns the default location of the watch state
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "sync-manager", "watches.json"), nil
+}
+
+// Read loads the state at path, returning an empty state if none was written
+func Read(path string) (*State, error) {
+	state := &State{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse watch state: %w", err)
+	}
+	return state, nil
+}
+
+// Write stores the state at path
+func Write(path string, state *State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create watch state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal watch state: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace watch state: %w", err)
+	}
+
+	return nil
+}
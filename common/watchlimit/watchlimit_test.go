@@ -0,0 +1,34 @@
+package watchlimit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateNearLimit(t *testing.T) {
+	assert.False(t, (&State{Watches: 1000}).Near())
+	assert.False(t, (&State{Watches: 899, Limit: 1000}).Near())
+	assert.True(t, (&State{Watches: 900, Limit: 1000}).Near())
+
+	// The suggestion doubles the limit at least, from a floor that suits most trees
+	assert.Equal(t, 524288, (&State{Watches: 7000, Limit: 8192}).Suggested())
+	assert.Equal(t, 1048576, (&State{Watches: 520000, Limit: 524288}).Suggested())
+}
+
+func TestReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watches.json")
+
+	state, err := Read(path)
+	assert.NoError(t, err)
+	assert.Zero(t, state.Watches)
+
+	written := &State{Watches: 10, Limit: 8192, Polled: []string{"/home/docs/node_modules"}, UpdatedAt: time.Now().Round(time.Second)}
+	assert.NoError(t, Write(path, written))
+	state, err = Read(path)
+	assert.NoError(t, err)
+	assert.Equal(t, written.Polled, state.Polled)
+	assert.True(t, written.UpdatedAt.Equal(state.UpdatedAt))
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/server/internal/api"
+	"github.com/martinshumberto/sync-manager/server/internal/database"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Version information (will be set during build)
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+)
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	if strings.EqualFold(os.Getenv("LOG_LEVEL"), "debug") {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+
+	log.Info().
+		Str("version", Version).
+		Str("build_time", BuildTime).
+		Msg("Starting Sync Manager Server")
+
+	db, err := database.Open(database.ConfigFromEnv())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatal().Err(err).Msg("Failed to migrate database")
+	}
+
+	retention, err := database.EventRetentionFromEnv()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid sync event retention")
+	}
+
+	addr := os.Getenv("SERVER_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           api.NewServer(db).Router(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go pruneSyncEvents(ctx, db, retention)
+
+	go func() {
+		log.Info().Str("addr", addr).Msg("API server listening")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Err(err).Msg("API server failed")
+		}
+	}()
+
+	<-ctx.Done()
+
+	log.Info().Msg("Shutting down API server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to shut down API server")
+	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	log.Info().Msg("Shutdown complete")
+}
+
+// pruneInterval is how often sync events past their retention are removed
+const pruneInterval = time.Hour
+
+// pruneSyncEvents removes the sync events past their retention at startup, then every
+// pruneInterval until ctx is cancelled
+func pruneSyncEvents(ctx context.Context, db *gorm.DB, retention database.EventRetention) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := database.PruneSyncEvents(db, retention)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to prune sync events")
+		} else if removed > 0 {
+			log.Info().Int64("removed", removed).Msg("Pruned sync events")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// contextKey is the type of the request context keys set by the API
+type contextKey string
+
+const (
+	userKey   contextKey = "user"
+	deviceKey contextKey = "device"
+)
+
+// authenticate resolves the bearer token to a user and, for device tokens, a device
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			writeError(w, http.StatusUnauthorized, "missing bearer token", nil)
+			return
+		}
+
+		now := time.Now()
+		ctx := r.Context()
+		hash := models.HashToken(token)
+
+		var user *models.User
+		var apiToken models.ApiToken
+		err := s.db.Preload("User").
+			Where("token = ? AND revoked = ? AND expires_at > ?", hash, false, now).
+			First(&apiToken).Error
+		switch {
+		case err == nil:
+			s.db.Model(&apiToken).UpdateColumn("last_used", now)
+			user = &apiToken.User
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			var deviceToken models.DeviceToken
+			err = s.db.Where("token = ? AND revoked = ? AND expires_at > ?", hash, false, now).
+				First(&deviceToken).Error
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, "invalid or expired token", nil)
+				return
+			}
+
+			// Device is loaded by primary key, the DeviceID column names clash for Preload
+			var device models.Device
+			user = &models.User{}
+			if err := s.db.First(&device, deviceToken.DeviceID).Error; err != nil {
+				writeError(w, http.StatusUnauthorized, "invalid or expired token", nil)
+				return
+			}
+			if err := s.db.First(user, device.UserID).Error; err != nil {
+				writeError(w, http.StatusUnauthorized, "invalid or expired token", nil)
+				return
+			}
+
+			s.db.Model(&deviceToken).UpdateColumn("last_used", now)
+			s.db.Model(&device).UpdateColumn("last_seen_at", now)
+			device.LastSeenAt = now
+			ctx = context.WithValue(ctx, deviceKey, &device)
+		default:
+			writeError(w, http.StatusInternalServerError, "failed to verify token", err)
+			return
+		}
+
+		// Tokens of a suspended or disabled user stop working with the account, as login does
+		if user.Status != models.StatusActive {
+			writeError(w, http.StatusForbidden, "user account is "+string(user.Status), nil)
+			return
+		}
+		ctx = context.WithValue(ctx, userKey, user)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireDevice rejects requests that were not authenticated with a device token
+func requireDevice(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if currentDevice(r) == nil {
+			writeError(w, http.StatusForbidden, "a device token is required", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// currentUser returns the authenticated user
+func currentUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(userKey).(*models.User)
+	return user
+}
+
+// currentDevice returns the authenticated device, or nil for user tokens
+func currentDevice(r *http.Request) *models.Device {
+	device, _ := r.Context().Value(deviceKey).(*models.Device)
+	return device
+}
+
+// bearerToken extracts the token from the Authorization header
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+}
+
+// generateToken returns a random hex token
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// createUser registers a new user account
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateUserRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Email == "" || !strings.Contains(req.Email, "@") {
+		writeError(w, http.StatusBadRequest, "a valid email is required", nil)
+		return
+	}
+	if len(req.Password) < 8 {
+		writeError(w, http.StatusBadRequest, "password must have at least 8 characters", nil)
+		return
+	}
+
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("email = ?", req.Email).Count(&count).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create user", err)
+		return
+	}
+	if count > 0 {
+		writeError(w, http.StatusConflict, "a user with this email already exists", nil)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create user", err)
+		return
+	}
+
+	user := models.User{
+		Email:        req.Email,
+		Name:         req.Name,
+		PasswordHash: string(hash),
+		Status:       models.StatusActive,
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create user", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, userResponse(&user))
+}
+
+// login exchanges an email and password for an API token
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	var user models.User
+	err := s.db.Where("email = ?", strings.ToLower(strings.TrimSpace(req.Email))).First(&user).Error
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		writeError(w, http.StatusUnauthorized, "invalid email or password", nil)
+		return
+	}
+	if user.Status != models.StatusActive {
+		writeError(w, http.StatusForbidden, "user account is "+string(user.Status), nil)
+		return
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to issue token", err)
+		return
+	}
+
+	now := time.Now()
+	apiToken := models.ApiToken{
+		UserID:    user.ID,
+		Token:     models.HashToken(token),
+		Name:      "login",
+		ExpiresAt: now.Add(ApiTokenLifetime),
+		LastUsed:  now,
+	}
+	if err := s.db.Create(&apiToken).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to issue token", err)
+		return
+	}
+
+	user.LastLoginAt = now
+	s.db.Model(&user).UpdateColumn("last_login_at", now)
+
+	writeJSON(w, http.StatusOK, models.LoginResponse{
+		User: userResponse(&user),
+		Token: models.ApiTokenResponse{
+			ID:        apiToken.ID,
+			Name:      apiToken.Name,
+			Token:     token,
+			ExpiresAt: apiToken.ExpiresAt,
+			LastUsed:  apiToken.LastUsed,
+			CreatedAt: apiToken.CreatedAt,
+		},
+	})
+}
+
+// getCurrentUser returns the authenticated user
+func (s *Server) getCurrentUser(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, userResponse(currentUser(r)))
+}
+
+// updateCurrentUser changes the name or password of the authenticated user
+func (s *Server) updateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateUserRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user := currentUser(r)
+	updates := map[string]interface{}{}
+	if req.Name != "" {
+		updates["name"] = req.Name
+		user.Name = req.Name
+	}
+	if req.Password != "" {
+		if len(req.Password) < 8 {
+			writeError(w, http.StatusBadRequest, "password must have at least 8 characters", nil)
+			return
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to update user", err)
+			return
+		}
+		updates["password_hash"] = string(hash)
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(user).Updates(updates).Error; err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to update user", err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, userResponse(user))
+}
+
+// userResponse converts a user to its API representation
+func userResponse(user *models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:           user.ID,
+		Email:        user.Email,
+		Name:         user.Name,
+		LastLoginAt:  user.LastLoginAt,
+		Status:       user.Status,
+		StorageQuota: user.StorageQuota,
+		StorageUsed:  user.StorageUsed,
+		Verified:     user.Verified,
+	}
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/gorm"
+)
+
+// registerDevice registers a device for the user (or updates it) and issues a device token
+func (s *Server) registerDevice(w http.ResponseWriter, r *http.Request) {
+	var req models.DeviceRegistrationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "device name is required", nil)
+		return
+	}
+	if req.DeviceID == "" {
+		req.DeviceID = uuid.New().String()
+	}
+
+	user := currentUser(r)
+	now := time.Now()
+
+	var device models.Device
+	err := s.db.Where("device_id = ?", req.DeviceID).First(&device).Error
+	switch {
+	case err == nil:
+		if device.UserID != user.ID {
+			writeError(w, http.StatusConflict, "device is registered to another user", nil)
+			return
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		device = models.Device{UserID: user.ID, DeviceID: req.DeviceID}
+	default:
+		writeError(w, http.StatusInternalServerError, "failed to register device", err)
+		return
+	}
+
+	device.Name = req.Name
+	device.Platform = req.Platform
+	device.OS = req.OS
+	device.ClientVersion = req.ClientVersion
+	device.Status = models.StatusActive
+	device.LastSeenAt = now
+
+	var token string
+	var deviceToken *models.DeviceToken
+	err = s.db.Transaction(func(tx *gorm.DB) (err error) {
+		if err := tx.Save(&device).Error; err != nil {
+			return err
+		}
+		token, deviceToken, err = s.issueDeviceToken(tx, &device)
+		return err
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to register device", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.DeviceRegistrationResponse{
+		Device:    device,
+		Token:     token,
+		ExpiresAt: deviceToken.ExpiresAt,
+	})
+}
+
+// refreshDeviceToken rotates the token of the calling device
+func (s *Server) refreshDeviceToken(w http.ResponseWriter, r *http.Request) {
+	device := currentDevice(r)
+
+	var token string
+	var deviceToken *models.DeviceToken
+	err := s.db.Transaction(func(tx *gorm.DB) (err error) {
+		token, deviceToken, err = s.issueDeviceToken(tx, device)
+		return err
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to refresh token", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.DeviceTokenResponse{
+		Token:     token,
+		ExpiresAt: deviceToken.ExpiresAt,
+	})
+}
+
+// issueDeviceToken revokes the device's current tokens and creates a new one, returning the
+// token and its record, which only keeps its hash
+func (s *Server) issueDeviceToken(tx *gorm.DB, device *models.Device) (string, *models.DeviceToken, error) {
+	if err := tx.Model(&models.DeviceToken{}).
+		Where("device_id = ? AND revoked = ?", device.ID, false).
+		Update("revoked", true).Error; err != nil {
+		return "", nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	deviceToken := &models.DeviceToken{
+		DeviceID:  device.ID,
+		Token:     models.HashToken(token),
+		ExpiresAt: now.Add(DeviceTokenLifetime),
+		LastUsed:  now,
+	}
+	if err := tx.Create(deviceToken).Error; err != nil {
+		return "", nil, err
+	}
+
+	return token, deviceToken, nil
+}
+
+// listDevices returns the devices of the user
+func (s *Server) listDevices(w http.ResponseWriter, r *http.Request) {
+	var devices []models.Device
+	if err := s.db.Where("user_id = ?", currentUser(r).ID).Order("name").Find(&devices).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list devices", err)
+		return
+	}
+
+	responses := make([]models.DeviceResponse, 0, len(devices))
+	for i := range devices {
+		responses = append(responses, deviceResponse(&devices[i]))
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// getDevice returns a single device of the user
+func (s *Server) getDevice(w http.ResponseWriter, r *http.Request) {
+	device, ok := s.loadDevice(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, deviceResponse(device))
+}
+
+// updateDevice renames a device
+func (s *Server) updateDevice(w http.ResponseWriter, r *http.Request) {
+	device, ok := s.loadDevice(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateDeviceRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "device name is required", nil)
+		return
+	}
+
+	if err := s.db.Model(device).Update("name", req.Name).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update device", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deviceResponse(device))
+}
+
+// deviceHeartbeat records that the calling device is alive
+func (s *Server) deviceHeartbeat(w http.ResponseWriter, r *http.Request) {
+	var req models.DeviceHeartbeatRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// authenticate already updated last_seen_at
+	device := currentDevice(r)
+	updates := map[string]interface{}{"status": models.StatusActive}
+	if req.ClientVersion != "" {
+		updates["client_version"] = req.ClientVersion
+	}
+	if err := s.db.Model(device).Updates(updates).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record heartbeat", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deviceResponse(device))
+}
+
+// deleteDevice unlinks a device from the user, revoking its tokens
+func (s *Server) deleteDevice(w http.ResponseWriter, r *http.Request) {
+	device, ok := s.loadDevice(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DeviceToken{}).Where("device_id = ?", device.ID).Update("revoked", true).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("device_id = ?", device.ID).Delete(&models.DeviceFolder{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(device).Error
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete device", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// loadDevice finds the device in the URL among the user's devices
+func (s *Server) loadDevice(w http.ResponseWriter, r *http.Request) (*models.Device, bool) {
+	var device models.Device
+	err := s.db.Where("device_id = ? AND user_id = ?", chi.URLParam(r, "deviceID"), currentUser(r).ID).First(&device).Error
+	if err != nil {
+		writeDBError(w, "device", err)
+		return nil, false
+	}
+	return &device, true
+}
+
+// deviceResponse converts a device to its API representation
+func deviceResponse(device *models.Device) models.DeviceResponse {
+	return models.DeviceResponse{
+		ID:            device.ID,
+		DeviceID:      device.DeviceID,
+		Name:          device.Name,
+		LastSeenAt:    device.LastSeenAt,
+		Status:        device.Status,
+		ClientVersion: device.ClientVersion,
+		Platform:      device.Platform,
+		OS:            device.OS,
+	}
+}
@@ -0,0 +1,334 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/gorm"
+)
+
+// listFolders returns the folders of the user
+func (s *Server) listFolders(w http.ResponseWriter, r *http.Request) {
+	var folders []models.Folder
+	if err := s.db.Where("user_id = ?", currentUser(r).ID).Order("name").Find(&folders).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list folders", err)
+		return
+	}
+
+	responses := make([]models.FolderResponse, 0, len(folders))
+	for i := range folders {
+		responses = append(responses, folderResponse(&folders[i]))
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// createFolder creates a new sync folder for the user
+func (s *Server) createFolder(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateFolderRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "folder name is required", nil)
+		return
+	}
+	if req.FolderID == "" {
+		req.FolderID = uuid.New().String()
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Folder{}).Where("folder_id = ? AND user_id = ?", req.FolderID, currentUser(r).ID).Count(&count).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create folder", err)
+		return
+	}
+	if count > 0 {
+		writeError(w, http.StatusConflict, "a folder with this ID already exists", nil)
+		return
+	}
+
+	folder := models.Folder{
+		UserID:            currentUser(r).ID,
+		FolderID:          req.FolderID,
+		Name:              req.Name,
+		Status:            models.StatusActive,
+		EncryptionEnabled: req.EncryptionEnabled,
+	}
+	if err := s.db.Create(&folder).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create folder", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, folderResponse(&folder))
+}
+
+// getFolder returns a single folder of the user
+func (s *Server) getFolder(w http.ResponseWriter, r *http.Request) {
+	folder, ok := s.loadFolder(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, folderResponse(folder))
+}
+
+// updateFolder changes the name, status, encryption or event retention of a folder
+func (s *Server) updateFolder(w http.ResponseWriter, r *http.Request) {
+	folder, ok := s.loadFolder(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateFolderRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.Status != "" {
+		if err := models.FolderStatuses.Transition(folder.Status, req.Status); err != nil {
+			writeError(w, http.StatusBadRequest, "status must be one of active, paused or disabled, reachable from the current one", err)
+			return
+		}
+	}
+
+	if req.Name != "" {
+		folder.Name = req.Name
+	}
+	if req.Status != "" {
+		folder.Status = req.Status
+	}
+	if req.EncryptionEnabled != nil {
+		folder.EncryptionEnabled = *req.EncryptionEnabled
+	}
+	if req.EventRetentionDays != nil {
+		folder.EventRetentionDays = *req.EventRetentionDays
+	}
+	if req.EventRetentionCount != nil {
+		folder.EventRetentionCount = *req.EventRetentionCount
+	}
+
+	if err := s.db.Save(folder).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update folder", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, folderResponse(folder))
+}
+
+// deleteFolder removes a folder and its device mappings
+func (s *Server) deleteFolder(w http.ResponseWriter, r *http.Request) {
+	folder, ok := s.loadFolder(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("folder_id = ?", folder.ID).Delete(&models.DeviceFolder{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(folder).Error
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete folder", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// listFolderDevices returns the device mappings of a folder
+func (s *Server) listFolderDevices(w http.ResponseWriter, r *http.Request) {
+	folder, ok := s.loadFolder(w, r)
+	if !ok {
+		return
+	}
+
+	var mappings []models.DeviceFolder
+	if err := s.db.Where("folder_id = ?", folder.ID).Find(&mappings).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list folder devices", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mappings)
+}
+
+// listDeviceFolders returns the folders mapped to a device
+func (s *Server) listDeviceFolders(w http.ResponseWriter, r *http.Request) {
+	device, ok := s.loadDevice(w, r)
+	if !ok {
+		return
+	}
+
+	var mappings []models.DeviceFolder
+	if err := s.db.Where("device_id = ?", device.ID).Find(&mappings).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list device folders", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mappings)
+}
+
+// addDeviceFolder maps one of the user's folders to a device
+func (s *Server) addDeviceFolder(w http.ResponseWriter, r *http.Request) {
+	device, ok := s.loadDevice(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AddDeviceFolderRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.LocalPath == "" {
+		writeError(w, http.StatusBadRequest, "local path is required", nil)
+		return
+	}
+	if !validSyncDirection(req.SyncDirection) {
+		writeError(w, http.StatusBadRequest, "sync direction must be one of bidirectional, upload or download", nil)
+		return
+	}
+
+	var folder models.Folder
+	if err := s.db.Where("id = ? AND user_id = ?", req.FolderID, currentUser(r).ID).First(&folder).Error; err != nil {
+		writeDBError(w, "folder", err)
+		return
+	}
+
+	var count int64
+	if err := s.db.Model(&models.DeviceFolder{}).Where("device_id = ? AND folder_id = ?", device.ID, folder.ID).Count(&count).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to add folder to device", err)
+		return
+	}
+	if count > 0 {
+		writeError(w, http.StatusConflict, "folder is already mapped to this device", nil)
+		return
+	}
+
+	direction := req.SyncDirection
+	if direction == "" {
+		direction = "bidirectional"
+	}
+
+	mapping := models.DeviceFolder{
+		DeviceID:        device.ID,
+		FolderID:        folder.ID,
+		LocalPath:       req.LocalPath,
+		SyncEnabled:     true,
+		SyncDirection:   direction,
+		ExcludePatterns: models.StringArray(req.ExcludePatterns),
+		Status:          models.StatusActive,
+	}
+	if err := s.db.Create(&mapping).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to add folder to device", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mapping)
+}
+
+// updateDeviceFolder changes a device-folder mapping
+func (s *Server) updateDeviceFolder(w http.ResponseWriter, r *http.Request) {
+	mapping, ok := s.loadDeviceFolder(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateDeviceFolderRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !validSyncDirection(req.SyncDirection) {
+		writeError(w, http.StatusBadRequest, "sync direction must be one of bidirectional, upload or download", nil)
+		return
+	}
+
+	if req.LocalPath != "" {
+		mapping.LocalPath = req.LocalPath
+	}
+	if req.SyncDirection != "" {
+		mapping.SyncDirection = req.SyncDirection
+	}
+	if req.ExcludePatterns != nil {
+		mapping.ExcludePatterns = models.StringArray(req.ExcludePatterns)
+	}
+	mapping.SyncEnabled = req.SyncEnabled
+
+	if err := s.db.Save(mapping).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update device folder", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapping)
+}
+
+// removeDeviceFolder removes a folder from a device
+func (s *Server) removeDeviceFolder(w http.ResponseWriter, r *http.Request) {
+	mapping, ok := s.loadDeviceFolder(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.db.Delete(mapping).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to remove device folder", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// loadFolder finds the folder in the URL among the user's folders
+func (s *Server) loadFolder(w http.ResponseWriter, r *http.Request) (*models.Folder, bool) {
+	var folder models.Folder
+	err := s.db.Where("folder_id = ? AND user_id = ?", chi.URLParam(r, "folderID"), currentUser(r).ID).First(&folder).Error
+	if err != nil {
+		writeDBError(w, "folder", err)
+		return nil, false
+	}
+	return &folder, true
+}
+
+// loadDeviceFolder finds the device-folder mapping in the URL
+func (s *Server) loadDeviceFolder(w http.ResponseWriter, r *http.Request) (*models.DeviceFolder, bool) {
+	device, ok := s.loadDevice(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid device folder ID", err)
+		return nil, false
+	}
+
+	var mapping models.DeviceFolder
+	if err := s.db.Where("id = ? AND device_id = ?", id, device.ID).First(&mapping).Error; err != nil {
+		writeDBError(w, "device folder", err)
+		return nil, false
+	}
+	return &mapping, true
+}
+
+// validSyncDirection reports whether direction is empty or a known sync direction
+func validSyncDirection(direction string) bool {
+	switch direction {
+	case "", "bidirectional", "upload", "download":
+		return true
+	}
+	return false
+}
+
+// folderResponse converts a folder to its API representation
+func folderResponse(folder *models.Folder) models.FolderResponse {
+	return models.FolderResponse{
+		ID:                  folder.ID,
+		FolderID:            folder.FolderID,
+		Name:                folder.Name,
+		CreatedAt:           folder.CreatedAt,
+		Status:              folder.Status,
+		EncryptionEnabled:   folder.EncryptionEnabled,
+		EventRetentionDays:  folder.EventRetentionDays,
+		EventRetentionCount: folder.EventRetentionCount,
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+)
+
+// listFileVersions returns the recorded versions of a folder, optionally for a single path
+func (s *Server) listFileVersions(w http.ResponseWriter, r *http.Request) {
+	folder, ok := s.loadFolder(w, r)
+	if !ok {
+		return
+	}
+
+	query := s.db.Model(&models.FileVersion{}).Where("folder_id = ?", folder.ID)
+	if path := r.URL.Query().Get("path"); path != "" {
+		query = query.Where("relative_path = ?", path)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list file versions", err)
+		return
+	}
+
+	page := pagination(r)
+	var versions []models.FileVersion
+	err := query.Order("created_at DESC, id DESC").
+		Offset((page.Page - 1) * page.PageSize).
+		Limit(page.PageSize).
+		Find(&versions).Error
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list file versions", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.NewPaginatedResponse(versions, int(total), page.Page, page.PageSize))
+}
+
+// createFileVersion records a new version of a file, attributed to the calling device
+func (s *Server) createFileVersion(w http.ResponseWriter, r *http.Request) {
+	folder, ok := s.loadFolder(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateFileVersionRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.RelativePath == "" {
+		writeError(w, http.StatusBadRequest, "relative path is required", nil)
+		return
+	}
+
+	version := models.FileVersion{
+		FolderID:     folder.ID,
+		RelativePath: req.RelativePath,
+		VersionID:    req.VersionID,
+		Size:         req.Size,
+		Hash:         req.Hash,
+		ModifiedAt:   req.ModifiedAt,
+		MimeType:     req.MimeType,
+		Metadata:     req.Metadata,
+		Deleted:      req.Deleted,
+	}
+	if device := currentDevice(r); device != nil {
+		version.DeviceID = device.ID
+	}
+
+	if err := s.db.Create(&version).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record file version", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, version)
+}
+
+// listSyncEvents returns the sync events of a folder, the most recent first unless order is asc,
+// optionally of one type or recorded since an RFC 3339 time
+func (s *Server) listSyncEvents(w http.ResponseWriter, r *http.Request) {
+	folder, ok := s.loadFolder(w, r)
+	if !ok {
+		return
+	}
+
+	query := s.db.Model(&models.SyncEvent{}).Where("folder_id = ?", folder.ID)
+	if eventType := r.URL.Query().Get("type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if value := r.URL.Query().Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be an RFC 3339 time", err)
+			return
+		}
+		query = query.Where("timestamp >= ?", since.UTC())
+	}
+	order := "timestamp DESC, id DESC"
+	if r.URL.Query().Get("order") == "asc" {
+		order = "timestamp, id"
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list sync events", err)
+		return
+	}
+
+	page := pagination(r)
+	var events []models.SyncEvent
+	err := query.Order(order).
+		Offset((page.Page - 1) * page.PageSize).
+		Limit(page.PageSize).
+		Find(&events).Error
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list sync events", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.NewPaginatedResponse(events, int(total), page.Page, page.PageSize))
+}
+
+// createSyncEvent records a sync event, attributed to the calling device
+func (s *Server) createSyncEvent(w http.ResponseWriter, r *http.Request) {
+	folder, ok := s.loadFolder(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateSyncEventRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.EventType == "" {
+		writeError(w, http.StatusBadRequest, "event type is required", nil)
+		return
+	}
+	if req.Timestamp.IsZero() {
+		req.Timestamp = time.Now()
+	}
+	// Stored in UTC so that times sent from any zone compare in order
+	req.Timestamp = req.Timestamp.UTC()
+
+	event := models.SyncEvent{
+		FolderID:      folder.ID,
+		FileVersionID: req.FileVersionID,
+		EventType:     req.EventType,
+		RelativePath:  req.RelativePath,
+		Timestamp:     req.Timestamp,
+		Details:       req.Details,
+	}
+	if device := currentDevice(r); device != nil {
+		event.DeviceID = device.ID
+	}
+
+	if err := s.db.Create(&event).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record sync event", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, event)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Token lifetimes issued by the server
+const (
+	ApiTokenLifetime    = 30 * 24 * time.Hour
+	DeviceTokenLifetime = 30 * 24 * time.Hour
+)
+
+// Server exposes the coordination REST API used by agents and the CLI
+type Server struct {
+	db *gorm.DB
+}
+
+// NewServer creates a new API server backed by db
+func NewServer(db *gorm.DB) *Server {
+	return &Server{db: db}
+}
+
+// Router returns the HTTP handler with all API routes
+func (s *Server) Router() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP)
+	r.Use(requestLogger)
+	r.Use(middleware.Recoverer)
+
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	r.Route("/api/v1", func(r chi.Router) {
+		// Public endpoints
+		r.Post("/users", s.createUser)
+		r.Post("/auth/login", s.login)
+
+		// Endpoints for users and devices
+		r.Group(func(r chi.Router) {
+			r.Use(s.authenticate)
+
+			r.Get("/users/me", s.getCurrentUser)
+			r.Put("/users/me", s.updateCurrentUser)
+
+			r.Post("/devices/register", s.registerDevice)
+			r.Get("/devices", s.listDevices)
+			r.Get("/devices/{deviceID}", s.getDevice)
+			r.Put("/devices/{deviceID}", s.updateDevice)
+			r.Delete("/devices/{deviceID}", s.deleteDevice)
+
+			r.Get("/devices/{deviceID}/folders", s.listDeviceFolders)
+			r.Post("/devices/{deviceID}/folders", s.addDeviceFolder)
+			r.Put("/devices/{deviceID}/folders/{id}", s.updateDeviceFolder)
+			r.Delete("/devices/{deviceID}/folders/{id}", s.removeDeviceFolder)
+
+			r.Get("/folders", s.listFolders)
+			r.Post("/folders", s.createFolder)
+			r.Get("/folders/{folderID}", s.getFolder)
+			r.Put("/folders/{folderID}", s.updateFolder)
+			r.Delete("/folders/{folderID}", s.deleteFolder)
+			r.Get("/folders/{folderID}/devices", s.listFolderDevices)
+
+			r.Get("/folders/{folderID}/versions", s.listFileVersions)
+			r.Post("/folders/{folderID}/versions", s.createFileVersion)
+			r.Get("/folders/{folderID}/events", s.listSyncEvents)
+			r.Post("/folders/{folderID}/events", s.createSyncEvent)
+		})
+
+		// Endpoints only devices may call
+		r.Group(func(r chi.Router) {
+			r.Use(s.authenticate)
+			r.Use(requireDevice)
+
+			r.Post("/devices/token/refresh", s.refreshDeviceToken)
+			r.Post("/devices/heartbeat", s.deviceHeartbeat)
+		})
+	})
+
+	return r
+}
+
+// requestLogger logs each request with zerolog
+func requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		log.Debug().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", ww.Status()).
+			Dur("duration", time.Since(start)).
+			Msg("Request handled")
+	})
+}
+
+// writeJSON writes data wrapped in a success response
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.NewSuccessResponse(status, http.StatusText(status), data))
+}
+
+// writeError writes an error response
+func writeError(w http.ResponseWriter, status int, message string, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Error().Err(err).Msg(message)
+		// Internal details are not exposed to clients
+		err = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.NewErrorResponse(status, message, err))
+}
+
+// writeDBError maps a database error to a response
+func writeDBError(w http.ResponseWriter, what string, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, http.StatusNotFound, what+" not found", nil)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "failed to load "+what, err)
+}
+
+// decodeJSON reads the request body into v
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body", err)
+		return false
+	}
+	return true
+}
+
+// pagination reads page and page_size query parameters
+func pagination(r *http.Request) models.Pagination {
+	p := models.Pagination{Page: 1, PageSize: 20}
+	if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && page > 0 {
+		p.Page = page
+	}
+	if size, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil && size > 0 && size <= 500 {
+		p.PageSize = size
+	}
+	return p
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/apiclient"
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/martinshumberto/sync-manager/server/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestServer starts the API on a temporary sqlite database
+func newTestServer(t *testing.T) *httptest.Server {
+	db, err := database.Open(database.Config{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "server.db")})
+	assert.NoError(t, err)
+	assert.NoError(t, database.Migrate(db))
+
+	server := httptest.NewServer(NewServer(db).Router())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// doJSON sends a request and decodes the data of the response envelope into out
+func doJSON(t *testing.T, method, url, token string, body, out interface{}) int {
+	var payload bytes.Buffer
+	if body != nil {
+		assert.NoError(t, json.NewEncoder(&payload).Encode(body))
+	}
+
+	req, err := http.NewRequest(method, url, &payload)
+	assert.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode < 400 {
+		envelope := struct {
+			Data interface{} `json:"data"`
+		}{Data: out}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&envelope))
+	}
+
+	return resp.StatusCode
+}
+
+func TestDeviceRegistrationAndTokenRefresh(t *testing.T) {
+	server := newTestServer(t)
+
+	status := doJSON(t, http.MethodPost, server.URL+"/api/v1/users", "", models.CreateUserRequest{
+		Email: "user@example.com", Password: "password123", Name: "User",
+	}, nil)
+	assert.Equal(t, http.StatusCreated, status)
+
+	// The agent client speaks the same protocol as the server
+	client, err := apiclient.NewClient(server.URL, filepath.Join(t.TempDir(), "credentials.json"))
+	assert.NoError(t, err)
+
+	_, err = client.Login(context.Background(), "user@example.com", "wrong-password")
+	assert.Error(t, err)
+
+	login, err := client.Login(context.Background(), "user@example.com", "password123")
+	assert.NoError(t, err)
+
+	_, err = client.RegisterDevice(context.Background(), login.Token.Token, "user@example.com", models.DeviceRegistrationRequest{
+		DeviceID: "laptop-1", Name: "Laptop", OS: "linux",
+	})
+	assert.NoError(t, err)
+	firstToken := client.Credentials().DeviceToken
+
+	assert.NoError(t, client.RefreshToken(context.Background()))
+	assert.NotEqual(t, firstToken, client.Credentials().DeviceToken)
+
+	// The rotated-out token no longer works
+	status = doJSON(t, http.MethodGet, server.URL+"/api/v1/devices", firstToken, nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	var devices []models.DeviceResponse
+	assert.NoError(t, client.Do(context.Background(), http.MethodGet, "/api/v1/devices", nil, &devices))
+	assert.Len(t, devices, 1)
+	assert.Equal(t, "laptop-1", devices[0].DeviceID)
+
+	assert.NoError(t, client.Heartbeat(context.Background(), "1.2.3"))
+	assert.NoError(t, client.RenameDevice(context.Background(), "laptop-1", "Work Laptop"))
+	device, err := client.GetDevice(context.Background(), "laptop-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Work Laptop", device.Name)
+	assert.Equal(t, "1.2.3", device.ClientVersion)
+
+	// User tokens cannot refresh device tokens
+	status = doJSON(t, http.MethodPost, server.URL+apiclient.PathRefreshDeviceToken, login.Token.Token, nil, nil)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestDisabledUserTokensAreRejected(t *testing.T) {
+	db, err := database.Open(database.Config{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "server.db")})
+	assert.NoError(t, err)
+	assert.NoError(t, database.Migrate(db))
+	server := httptest.NewServer(NewServer(db).Router())
+	t.Cleanup(server.Close)
+
+	status := doJSON(t, http.MethodPost, server.URL+"/api/v1/users", "", models.CreateUserRequest{
+		Email: "user@example.com", Password: "password123", Name: "User",
+	}, nil)
+	assert.Equal(t, http.StatusCreated, status)
+
+	client, err := apiclient.NewClient(server.URL, filepath.Join(t.TempDir(), "credentials.json"))
+	assert.NoError(t, err)
+	login, err := client.Login(context.Background(), "user@example.com", "password123")
+	assert.NoError(t, err)
+	_, err = client.RegisterDevice(context.Background(), login.Token.Token, "user@example.com", models.DeviceRegistrationRequest{
+		DeviceID: "laptop-1", Name: "Laptop", OS: "linux",
+	})
+	assert.NoError(t, err)
+	deviceToken := client.Credentials().DeviceToken
+
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodGet, server.URL+"/api/v1/devices", deviceToken, nil, nil))
+
+	// Neither the device nor the user token outlives the account
+	assert.NoError(t, db.Model(&models.User{}).Where("email = ?", "user@example.com").Update("status", models.StatusDisabled).Error)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, http.MethodGet, server.URL+"/api/v1/devices", deviceToken, nil, nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(t, http.MethodGet, server.URL+"/api/v1/users/me", login.Token.Token, nil, nil))
+}
+
+func TestFolderMembershipAndHistory(t *testing.T) {
+	server := newTestServer(t)
+	base := server.URL + "/api/v1"
+
+	doJSON(t, http.MethodPost, base+"/users", "", models.CreateUserRequest{
+		Email: "user@example.com", Password: "password123",
+	}, nil)
+
+	var login models.LoginResponse
+	doJSON(t, http.MethodPost, base+"/auth/login", "", models.LoginRequest{
+		Email: "user@example.com", Password: "password123",
+	}, &login)
+	userToken := login.Token.Token
+
+	var registration models.DeviceRegistrationResponse
+	status := doJSON(t, http.MethodPost, base+"/devices/register", userToken, models.DeviceRegistrationRequest{
+		DeviceID: "desktop-1", Name: "Desktop",
+	}, &registration)
+	assert.Equal(t, http.StatusOK, status)
+	deviceToken := registration.Token
+
+	var folder models.FolderResponse
+	status = doJSON(t, http.MethodPost, base+"/folders", userToken, models.CreateFolderRequest{
+		FolderID: "docs", Name: "Documents", EncryptionEnabled: true,
+	}, &folder)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "docs", folder.FolderID)
+
+	// A rename alone leaves encryption untouched
+	status = doJSON(t, http.MethodPut, base+"/folders/docs", userToken, map[string]string{"name": "Docs"}, &folder)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Docs", folder.Name)
+	assert.True(t, folder.EncryptionEnabled)
+
+	// The folder can be paused, but not given a status folders do not have
+	status = doJSON(t, http.MethodPut, base+"/folders/docs", userToken, map[string]string{"status": "paused"}, &folder)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, models.StatusPaused, folder.Status)
+	status = doJSON(t, http.MethodPut, base+"/folders/docs", userToken, map[string]string{"status": "archived"}, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status = doJSON(t, http.MethodPost, base+"/folders", userToken, models.CreateFolderRequest{
+		FolderID: "docs", Name: "Again",
+	}, nil)
+	assert.Equal(t, http.StatusConflict, status)
+
+	var mapping models.DeviceFolder
+	status = doJSON(t, http.MethodPost, base+"/devices/desktop-1/folders", userToken, models.AddDeviceFolderRequest{
+		FolderID: folder.ID, LocalPath: "/home/user/docs", ExcludePatterns: []string{"*.tmp"},
+	}, &mapping)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "bidirectional", mapping.SyncDirection)
+
+	var members []models.DeviceFolder
+	doJSON(t, http.MethodGet, base+"/folders/docs/devices", userToken, nil, &members)
+	assert.Len(t, members, 1)
+	assert.Equal(t, models.StringArray{"*.tmp"}, members[0].ExcludePatterns)
+
+	var version models.FileVersion
+	status = doJSON(t, http.MethodPost, base+"/folders/docs/versions", deviceToken, models.CreateFileVersionRequest{
+		RelativePath: "a.txt", Size: 3, Hash: "abc",
+	}, &version)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, registration.Device.ID, version.DeviceID)
+
+	status = doJSON(t, http.MethodPost, base+"/folders/docs/events", deviceToken, models.CreateSyncEventRequest{
+		FileVersionID: version.ID, EventType: "upload", RelativePath: "a.txt",
+	}, nil)
+	assert.Equal(t, http.StatusCreated, status)
+
+	var events models.PaginatedResponse
+	doJSON(t, http.MethodGet, base+"/folders/docs/events", userToken, nil, &events)
+	assert.Equal(t, 1, events.TotalItems)
+
+	// Events can be read oldest first from a point in time, given in any zone
+	dayAgo := time.Now().Add(-24 * time.Hour).In(time.FixedZone("UTC-3", -3*60*60))
+	status = doJSON(t, http.MethodPost, base+"/folders/docs/events", deviceToken, models.CreateSyncEventRequest{
+		EventType: models.SyncEventConflict, RelativePath: "b.txt", Timestamp: dayAgo.Add(-time.Hour),
+	}, nil)
+	assert.Equal(t, http.StatusCreated, status)
+	var recent struct {
+		Items []models.SyncEvent `json:"items"`
+	}
+	doJSON(t, http.MethodGet, base+"/folders/docs/events?order=asc&since="+url.QueryEscape(dayAgo.Add(-2*time.Hour).Format(time.RFC3339)), userToken, nil, &recent)
+	if assert.Len(t, recent.Items, 2) {
+		assert.Equal(t, "b.txt", recent.Items[0].RelativePath)
+		assert.Equal(t, "a.txt", recent.Items[1].RelativePath)
+	}
+	doJSON(t, http.MethodGet, base+"/folders/docs/events?since="+url.QueryEscape(dayAgo.Format(time.RFC3339)), userToken, nil, &recent)
+	if assert.Len(t, recent.Items, 1) {
+		assert.Equal(t, "a.txt", recent.Items[0].RelativePath)
+	}
+	status = doJSON(t, http.MethodGet, base+"/folders/docs/events?since=yesterday", userToken, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// The folder can keep its events longer than the server default
+	status = doJSON(t, http.MethodPut, base+"/folders/docs", userToken, map[string]int{"event_retention_days": 365, "event_retention_count": -1}, &folder)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 365, folder.EventRetentionDays)
+	assert.Equal(t, -1, folder.EventRetentionCount)
+	assert.Equal(t, "Docs", folder.Name)
+
+	// Other users cannot see the folder
+	doJSON(t, http.MethodPost, base+"/users", "", models.CreateUserRequest{
+		Email: "other@example.com", Password: "password123",
+	}, nil)
+	var otherLogin models.LoginResponse
+	doJSON(t, http.MethodPost, base+"/auth/login", "", models.LoginRequest{
+		Email: "other@example.com", Password: "password123",
+	}, &otherLogin)
+	status = doJSON(t, http.MethodGet, base+"/folders/docs", otherLogin.Token.Token, nil, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Folder IDs only have to be unique per user
+	status = doJSON(t, http.MethodPost, base+"/folders", otherLogin.Token.Token, models.CreateFolderRequest{
+		FolderID: "docs", Name: "Other Documents",
+	}, nil)
+	assert.Equal(t, http.StatusCreated, status)
+
+	status = doJSON(t, http.MethodDelete, base+"/devices/desktop-1", userToken, nil, nil)
+	assert.Equal(t, http.StatusOK, status)
+	status = doJSON(t, http.MethodGet, base+"/devices", deviceToken, nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Config holds the database connection settings of the server
+type Config struct {
+	Driver   string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+	// Path is the database file used by the sqlite driver
+	Path string
+	// Debug logs every SQL statement
+	Debug bool
+}
+
+// dialectors maps a driver name to a function building its gorm dialector
+var dialectors = map[string]func(cfg Config) gorm.Dialector{
+	"sqlite": func(cfg Config) gorm.Dialector {
+		return sqlite.Open(cfg.Path)
+	},
+	"postgres": func(cfg Config) gorm.Dialector {
+		return postgres.Open(cfg.DSN())
+	},
+}
+
+// ConfigFromEnv reads the database settings from the environment.
+// Postgres is used when DB_HOST is set, otherwise a local sqlite file.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Driver:   os.Getenv("DB_DRIVER"),
+		Host:     os.Getenv("DB_HOST"),
+		Port:     envOrDefault("DB_PORT", "5432"),
+		User:     os.Getenv("DB_USER"),
+		Password: os.Getenv("DB_PASSWORD"),
+		Name:     envOrDefault("DB_NAME", "sync_manager"),
+		SSLMode:  envOrDefault("DB_SSLMODE", "disable"),
+		Path:     envOrDefault("DB_PATH", "sync-manager-server.db"),
+		Debug:    os.Getenv("SYNC_MANAGER_DEBUG") == "true",
+	}
+
+	if cfg.Driver == "" {
+		if cfg.Host != "" {
+			cfg.Driver = "postgres"
+		} else {
+			cfg.Driver = "sqlite"
+		}
+	}
+
+	return cfg
+}
+
+// DSN returns the Postgres connection string for the configuration
+func (c Config) DSN() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode)
+}
+
+// Open connects to the configured database
+func Open(cfg Config) (*gorm.DB, error) {
+	dialector, ok := dialectors[cfg.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver %q (available: %s)", cfg.Driver, strings.Join(Drivers(), ", "))
+	}
+
+	if cfg.Driver == "sqlite" {
+		if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	gormLogger := logger.Default.LogMode(logger.Silent)
+	if cfg.Debug {
+		gormLogger = logger.Default.LogMode(logger.Info)
+	}
+
+	db, err := gorm.Open(dialector(cfg), &gorm.Config{
+		Logger: gormLogger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	log.Info().Str("driver", cfg.Driver).Msg("Connected to database")
+
+	return db, nil
+}
+
+// Migrate creates or updates the server schema
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&models.User{},
+		&models.UserPreference{},
+		&models.Device{},
+		&models.DeviceToken{},
+		&models.ApiToken{},
+		&models.Folder{},
+		&models.DeviceFolder{},
+		&models.FileVersion{},
+		&models.SyncEvent{},
+		&schemaMigration{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+
+	// Folder IDs used to be unique across all users; they are now unique per user
+	if db.Migrator().HasIndex(&models.Folder{}, "idx_folders_folder_id") {
+		if err := db.Migrator().DropIndex(&models.Folder{}, "idx_folders_folder_id"); err != nil {
+			return fmt.Errorf("failed to migrate database schema: %w", err)
+		}
+	}
+
+	// Tokens used to be stored as issued; only their hash is kept now
+	if err := migrateOnce(db, "hash_tokens", hashStoredTokens); err != nil {
+		return fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+
+	return nil
+}
+
+// schemaMigration records a data migration applied to the database
+type schemaMigration struct {
+	Name      string `gorm:"primaryKey"`
+	AppliedAt time.Time
+}
+
+// migrateOnce applies a data migration unless it was applied before, recording it in the
+// same transaction
+func migrateOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&schemaMigration{}).Where("name = ?", name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		if err := migrate(tx); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return tx.Create(&schemaMigration{Name: name, AppliedAt: time.Now()}).Error
+	})
+}
+
+// hashStoredTokens replaces every API and device token stored as issued by its hash
+func hashStoredTokens(tx *gorm.DB) error {
+	update := tx.Session(&gorm.Session{NewDB: true})
+
+	var apiTokens []models.ApiToken
+	err := tx.Unscoped().Select("id", "token").FindInBatches(&apiTokens, 500, func(*gorm.DB, int) error {
+		for _, token := range apiTokens {
+			err := update.Unscoped().Model(&models.ApiToken{}).Where("id = ?", token.ID).
+				UpdateColumn("token", models.HashToken(token.Token)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	var deviceTokens []models.DeviceToken
+	return tx.Unscoped().Select("id", "token").FindInBatches(&deviceTokens, 500, func(*gorm.DB, int) error {
+		for _, token := range deviceTokens {
+			err := update.Unscoped().Model(&models.DeviceToken{}).Where("id = ?", token.ID).
+				UpdateColumn("token", models.HashToken(token.Token)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// Drivers returns the names of the compiled-in database drivers
+func Drivers() []string {
+	names := make([]string, 0, len(dialectors))
+	for name := range dialectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envOrDefault returns the environment variable or a default value
+func envOrDefault(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
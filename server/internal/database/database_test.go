@@ -0,0 +1,54 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("DB_HOST", "")
+	assert.Equal(t, "sqlite", ConfigFromEnv().Driver)
+
+	// A database host means Postgres, which every build can open
+	t.Setenv("DB_HOST", "db")
+	cfg := ConfigFromEnv()
+	assert.Equal(t, "postgres", cfg.Driver)
+	assert.Equal(t, "host=db port=5432 user= password= dbname=sync_manager sslmode=disable", cfg.DSN())
+	assert.Contains(t, Drivers(), cfg.Driver)
+
+	_, err := Open(Config{Driver: "oracle"})
+	assert.ErrorContains(t, err, "postgres, sqlite")
+}
+
+func TestMigrateHashesStoredTokens(t *testing.T) {
+	db, err := Open(Config{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "server.db")})
+	assert.NoError(t, err)
+
+	// A database from before tokens were hashed
+	assert.NoError(t, db.AutoMigrate(&models.ApiToken{}, &models.DeviceToken{}))
+	expires := time.Now().Add(time.Hour)
+	assert.NoError(t, db.Create(&models.ApiToken{Token: "login-token", ExpiresAt: expires}).Error)
+	assert.NoError(t, db.Create(&models.DeviceToken{Token: "device-token", ExpiresAt: expires}).Error)
+	assert.NoError(t, db.Create(&models.DeviceToken{Token: "revoked-token", ExpiresAt: expires, Revoked: true}).Error)
+
+	stored := func() []string {
+		var tokens []string
+		assert.NoError(t, db.Model(&models.ApiToken{}).Pluck("token", &tokens).Error)
+		var deviceTokens []string
+		assert.NoError(t, db.Model(&models.DeviceToken{}).Order("id").Pluck("token", &deviceTokens).Error)
+		return append(tokens, deviceTokens...)
+	}
+	hashed := []string{models.HashToken("login-token"), models.HashToken("device-token"), models.HashToken("revoked-token")}
+
+	assert.NoError(t, Migrate(db))
+	assert.Equal(t, hashed, stored())
+
+	// Hashes are not hashed again
+	assert.NoError(t, Migrate(db))
+	assert.Equal(t, hashed, stored())
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"gorm.io/gorm"
+)
+
+// Default retention of sync events, for folders that do not set their own
+const (
+	DefaultEventRetentionDays  = 90
+	DefaultEventRetentionCount = 10000
+)
+
+// EventRetention bounds the sync events kept for each folder. Zero keeps them all.
+type EventRetention struct {
+	Days  int // Events older than this many days are removed
+	Count int // Only this many of the newest events of a folder are kept
+}
+
+// EventRetentionFromEnv reads the default retention of sync events from EVENT_RETENTION_DAYS
+// and EVENT_RETENTION_COUNT, 0 keeping them all
+func EventRetentionFromEnv() (EventRetention, error) {
+	days, err := envInt("EVENT_RETENTION_DAYS", DefaultEventRetentionDays)
+	if err != nil {
+		return EventRetention{}, err
+	}
+	count, err := envInt("EVENT_RETENTION_COUNT", DefaultEventRetentionCount)
+	if err != nil {
+		return EventRetention{}, err
+	}
+	return EventRetention{Days: days, Count: count}, nil
+}
+
+// For returns the retention of the events of folder: its own settings where it has them,
+// the defaults otherwise. folder may be nil for events of a folder removed for good.
+func (r EventRetention) For(folder *models.Folder) EventRetention {
+	if folder == nil {
+		return r
+	}
+	if folder.EventRetentionDays != 0 {
+		r.Days = max(folder.EventRetentionDays, 0)
+	}
+	if folder.EventRetentionCount != 0 {
+		r.Count = max(folder.EventRetentionCount, 0)
+	}
+	return r
+}
+
+// PruneSyncEvents removes the sync events of every folder past the retention of the folder,
+// defaults applying to those that do not set their own, and returns how many it removed
+func PruneSyncEvents(db *gorm.DB, defaults EventRetention) (int64, error) {
+	var folderIDs []uint
+	if err := db.Unscoped().Model(&models.SyncEvent{}).Distinct("folder_id").Pluck("folder_id", &folderIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to list folders with sync events: %w", err)
+	}
+	if len(folderIDs) == 0 {
+		return 0, nil
+	}
+
+	var folders []models.Folder
+	if err := db.Unscoped().Where("id IN ?", folderIDs).Find(&folders).Error; err != nil {
+		return 0, fmt.Errorf("failed to load folders: %w", err)
+	}
+	byID := make(map[uint]*models.Folder, len(folders))
+	for i := range folders {
+		byID[folders[i].ID] = &folders[i]
+	}
+
+	var removed int64
+	now := time.Now()
+	for _, folderID := range folderIDs {
+		retention := defaults.For(byID[folderID])
+		events := func() *gorm.DB {
+			return db.Unscoped().Where("folder_id = ?", folderID)
+		}
+
+		if retention.Days > 0 {
+			cutoff := now.AddDate(0, 0, -retention.Days).UTC()
+			result := events().Where("timestamp < ?", cutoff).Delete(&models.SyncEvent{})
+			if result.Error != nil {
+				return removed, fmt.Errorf("failed to prune sync events: %w", result.Error)
+			}
+			removed += result.RowsAffected
+		}
+
+		if retention.Count > 0 {
+			// The newest event past the count and every older one go
+			var oldest models.SyncEvent
+			result := events().Order("timestamp DESC, id DESC").Offset(retention.Count).Limit(1).Find(&oldest)
+			if result.Error != nil {
+				return removed, fmt.Errorf("failed to prune sync events: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			result = events().
+				Where("timestamp < ? OR (timestamp = ? AND id <= ?)", oldest.Timestamp, oldest.Timestamp, oldest.ID).
+				Delete(&models.SyncEvent{})
+			if result.Error != nil {
+				return removed, fmt.Errorf("failed to prune sync events: %w", result.Error)
+			}
+			removed += result.RowsAffected
+		}
+	}
+	return removed, nil
+}
+
+// envInt returns the environment variable as a number that cannot be negative, or a default value
+func envInt(key string, def int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a number, 0 for no limit", key, value)
+	}
+	return n, nil
+}
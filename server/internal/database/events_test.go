@@ -0,0 +1,77 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martinshumberto/sync-manager/common/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// recordEvents records count events of folder, one a day back from now, the newest first
+func recordEvents(t *testing.T, db *gorm.DB, folder uint, count int) {
+	now := time.Now().UTC()
+	for i := 0; i < count; i++ {
+		assert.NoError(t, db.Create(&models.SyncEvent{FolderID: folder, EventType: models.SyncEventConflict, Timestamp: now.AddDate(0, 0, -i)}).Error)
+	}
+}
+
+// eventCount returns how many events of folder are left
+func eventCount(t *testing.T, db *gorm.DB, folder uint) int64 {
+	var count int64
+	assert.NoError(t, db.Unscoped().Model(&models.SyncEvent{}).Where("folder_id = ?", folder).Count(&count).Error)
+	return count
+}
+
+func TestPruneSyncEvents(t *testing.T) {
+	db, err := Open(Config{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "server.db")})
+	assert.NoError(t, err)
+	assert.NoError(t, Migrate(db))
+
+	defaults := &models.Folder{FolderID: "defaults", Name: "Defaults"}
+	recent := &models.Folder{FolderID: "recent", Name: "Recent", EventRetentionDays: 3, EventRetentionCount: -1}
+	everything := &models.Folder{FolderID: "everything", Name: "Everything", EventRetentionDays: -1, EventRetentionCount: -1}
+	for _, folder := range []*models.Folder{defaults, recent, everything} {
+		assert.NoError(t, db.Create(folder).Error)
+		recordEvents(t, db, folder.ID, 20)
+	}
+	// Events of a folder removed for good follow the defaults
+	recordEvents(t, db, 999, 20)
+
+	removed, err := PruneSyncEvents(db, EventRetention{Days: 15, Count: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10+17+10), removed)
+
+	// The defaults keep the 10 newest of the 15 days
+	assert.Equal(t, int64(10), eventCount(t, db, defaults.ID))
+	assert.Equal(t, int64(10), eventCount(t, db, 999))
+	// A folder of its own keeps 3 days with no count
+	assert.Equal(t, int64(3), eventCount(t, db, recent.ID))
+	assert.Equal(t, int64(20), eventCount(t, db, everything.ID))
+
+	var oldest models.SyncEvent
+	assert.NoError(t, db.Where("folder_id = ?", defaults.ID).Order("timestamp").First(&oldest).Error)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -9), oldest.Timestamp, time.Hour)
+
+	// Nothing is left to remove, and a zero retention keeps everything
+	removed, err = PruneSyncEvents(db, EventRetention{Days: 15, Count: 10})
+	assert.NoError(t, err)
+	assert.Zero(t, removed)
+	removed, err = PruneSyncEvents(db, EventRetention{})
+	assert.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+func TestEventRetentionFromEnv(t *testing.T) {
+	t.Setenv("EVENT_RETENTION_DAYS", "")
+	t.Setenv("EVENT_RETENTION_COUNT", "0")
+	retention, err := EventRetentionFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, EventRetention{Days: DefaultEventRetentionDays, Count: 0}, retention)
+
+	t.Setenv("EVENT_RETENTION_DAYS", "-1")
+	_, err = EventRetentionFromEnv()
+	assert.Error(t, err)
+}